@@ -0,0 +1,31 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import "io"
+
+// AEADOpts contains options for authenticated encryption in GCM mode,
+// i.e. AES-GCM for AES keys and SM4-GCM for SM4 keys.
+// The nonce is prepended to the ciphertext, so decryption only needs
+// AdditionalData and TagSize to match the values used for encryption.
+// Notice that either Nonce or PRNG can be different from nil, or both nil.
+type AEADOpts struct {
+	// Nonce is the nonce to be used by the underlying cipher.
+	// Its length must be the standard GCM nonce size of 12 bytes.
+	// It is used only if different from nil and it must never be
+	// reused with the same key.
+	Nonce []byte
+	// AdditionalData is authenticated but not encrypted, e.g. the ID
+	// of the transaction the ciphertext is bound to.
+	AdditionalData []byte
+	// TagSize is the size in bytes of the authentication tag, between
+	// 12 and 16. If zero, 16 is used.
+	TagSize int
+	// PRNG is an instance of a PRNG to be used to sample the nonce.
+	// It is used only if different from nil.
+	PRNG io.Reader
+}
@@ -0,0 +1,220 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_audit")
+
+// AuditOpts configures the audit trail of the private key signing
+// operations.
+type AuditOpts struct {
+	// Enabled turns the audit trail on
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+	// Path of the audit log file
+	Path string `mapstructure:"path,omitempty" json:"path,omitempty" yaml:"Path"`
+	// AnchorInterval is the period at which the head of the log is handed
+	// to the Anchorer. The log is not anchored if zero.
+	AnchorInterval time.Duration `mapstructure:"anchorinterval,omitempty" json:"anchorinterval,omitempty" yaml:"AnchorInterval"`
+
+	// Anchorer records the head of the log into the ledger, it can also be
+	// set later with SetAnchorer
+	Anchorer Anchorer `json:"-" yaml:"-"`
+}
+
+// Anchorer records checkpoints of the audit log outside of the node, e.g.
+// in a transaction committed to the ledger, so that rewriting the whole
+// log after an anchor is detected.
+type Anchorer interface {
+	// Anchor records cp and returns a reference of the record, e.g. the
+	// ID of the transaction.
+	Anchor(cp Checkpoint) (string, error)
+}
+
+// AnchorFunc adapts a function to an Anchorer.
+type AnchorFunc func(cp Checkpoint) (string, error)
+
+// Anchor calls f(cp).
+func (f AnchorFunc) Anchor(cp Checkpoint) (string, error) {
+	return f(cp)
+}
+
+// CSP is a BCCSP that records every signing operation of its underlying
+// BCCSP, successful or not, in an SM3 hash-chained audit log: the SKI of
+// the key, the digest of the signed input, the purpose, channel and
+// transaction of the bccsp.SignRequestMetadata of the context, and the
+// time. A signature is not returned unless it was recorded.
+type CSP struct {
+	// csp serves all operations but signing
+	bccsp.BCCSP
+
+	log *Log
+
+	anchorMutex sync.Mutex
+	anchorer    Anchorer
+	anchored    uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New returns a CSP recording the signatures of csp in the audit log
+// configured by opts.
+func New(csp bccsp.BCCSP, opts AuditOpts) (*CSP, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid BCCSP instance. It must be different from nil")
+	}
+	if opts.AnchorInterval < 0 {
+		return nil, errors.Errorf("Invalid anchor interval [%s]. It must not be negative", opts.AnchorInterval)
+	}
+
+	log, err := OpenLog(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CSP{
+		BCCSP:    csp,
+		log:      log,
+		anchorer: opts.Anchorer,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if opts.AnchorInterval > 0 {
+		go c.anchorPeriodically(opts.AnchorInterval)
+	} else {
+		close(c.done)
+	}
+	return c, nil
+}
+
+// Unwrap returns the audited BCCSP.
+func (c *CSP) Unwrap() bccsp.BCCSP {
+	return c.BCCSP
+}
+
+// Sign signs digest using key k and records the signing operation.
+func (c *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return c.SignCtx(context.Background(), k, digest, opts)
+}
+
+// SignCtx signs digest using key k, handing ctx to the underlying BCCSP
+// when it implements bccsp.ContextSigner, and records the signing
+// operation with the request metadata of ctx.
+func (c *CSP) SignCtx(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	signature, err := bccsp.SignCtx(ctx, c.BCCSP, k, digest, opts)
+
+	e := &Entry{Type: EntrySign}
+	if k != nil {
+		e.SKI = hex.EncodeToString(k.SKI())
+	}
+	d := sm3.Sum(digest)
+	e.Digest = hex.EncodeToString(d[:])
+	if md, ok := bccsp.SignRequestMetadataFromContext(ctx); ok {
+		e.Purpose = md.Purpose
+		e.ChannelID = md.ChannelID
+		e.TxID = md.TxID
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if aerr := c.log.Append(e); aerr != nil {
+		logger.Errorf("Signature with key [%s] not recorded in the audit log: %s", e.SKI, aerr)
+		return nil, errors.WithMessage(aerr, "Failed recording the signature in the audit log")
+	}
+
+	return signature, err
+}
+
+// Head returns the checkpoint of the last entry of the audit log.
+func (c *CSP) Head() Checkpoint {
+	return c.log.Head()
+}
+
+// SetAnchorer sets the Anchorer of the audit log, for nodes able to
+// submit transactions only once the BCCSP was initialized.
+func (c *CSP) SetAnchorer(a Anchorer) {
+	c.anchorMutex.Lock()
+	defer c.anchorMutex.Unlock()
+	c.anchorer = a
+}
+
+// Anchor hands the head of the audit log to the Anchorer, unless there is
+// no Anchorer or the head was already anchored, and records the anchoring
+// in the log.
+func (c *CSP) Anchor() error {
+	c.anchorMutex.Lock()
+	defer c.anchorMutex.Unlock()
+
+	head := c.log.Head()
+	if c.anchorer == nil || head.Seq == c.anchored {
+		return nil
+	}
+	// The anchored entries must survive a crash of the node
+	if err := c.log.Sync(); err != nil {
+		return errors.Wrap(err, "Failed syncing audit log")
+	}
+
+	ref, err := c.anchorer.Anchor(head)
+	e := &Entry{
+		Type:        EntryAnchor,
+		Digest:      head.Hash,
+		TxID:        ref,
+		AnchoredSeq: head.Seq,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if aerr := c.log.Append(e); aerr != nil {
+		return errors.WithMessage(aerr, "Failed recording the anchor in the audit log")
+	}
+	if err != nil {
+		return errors.WithMessagef(err, "Failed anchoring audit entry %d", head.Seq)
+	}
+
+	// The anchor entry itself is anchored with the next entries
+	c.anchored = head.Seq + 1
+	return nil
+}
+
+func (c *CSP) anchorPeriodically(interval time.Duration) {
+	defer close(c.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if err := c.Anchor(); err != nil {
+				logger.Warningf("Audit log not anchored: %s", err)
+			}
+		}
+	}
+}
+
+// Close stops anchoring and closes the audit log. Signing fails afterwards.
+func (c *CSP) Close() error {
+	select {
+	case <-c.stop:
+	default:
+		close(c.stop)
+	}
+	<-c.done
+	return c.log.Close()
+}
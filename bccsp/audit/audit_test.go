@@ -0,0 +1,230 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCSP(t *testing.T, dir string, opts AuditOpts) (*CSP, string) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	opts.Path = filepath.Join(dir, "audit", "audit.log")
+	c, err := New(csp, opts)
+	require.NoError(t, err)
+	return c, opts.Path
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	return dir
+}
+
+func readEntries(t *testing.T, path string) []Entry {
+	raw, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	var entries []Entry
+	for _, line := range bytes.Split(bytes.TrimSpace(raw), []byte("\n")) {
+		var e Entry
+		require.NoError(t, json.Unmarshal(line, &e))
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestNewInvalidArgs(t *testing.T) {
+	_, err := New(nil, AuditOpts{})
+	assert.EqualError(t, err, "Invalid BCCSP instance. It must be different from nil")
+
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	_, err = New(csp, AuditOpts{})
+	assert.EqualError(t, err, "Invalid audit log path. It must not be empty")
+	_, err = New(csp, AuditOpts{Path: "audit.log", AnchorInterval: -1})
+	assert.EqualError(t, err, "Invalid anchor interval [-1ns]. It must not be negative")
+}
+
+func TestSign(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c, path := newTestCSP(t, dir, AuditOpts{Enabled: true})
+	defer c.Close()
+
+	k, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	msg := []byte("proposal response")
+
+	md := &bccsp.SignRequestMetadata{ChannelID: "mychannel", TxID: "tx1", Purpose: "endorsement"}
+	sig, err := c.SignCtx(bccsp.WithSignRequestMetadata(context.Background(), md), k, msg, nil)
+	require.NoError(t, err)
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	valid, err := c.Verify(pub, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	_, err = c.Sign(nil, msg, nil)
+	assert.Error(t, err)
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+	digest := sm3.Sum(msg)
+	assert.Equal(t, uint64(1), entries[0].Seq)
+	assert.Equal(t, EntrySign, entries[0].Type)
+	assert.Equal(t, hex.EncodeToString(k.SKI()), entries[0].SKI)
+	assert.Equal(t, hex.EncodeToString(digest[:]), entries[0].Digest)
+	assert.Equal(t, "endorsement", entries[0].Purpose)
+	assert.Equal(t, "mychannel", entries[0].ChannelID)
+	assert.Equal(t, "tx1", entries[0].TxID)
+	assert.Empty(t, entries[0].Error)
+	assert.Equal(t, genesisHash, entries[0].Prev)
+
+	assert.Equal(t, uint64(2), entries[1].Seq)
+	assert.Empty(t, entries[1].SKI)
+	assert.NotEmpty(t, entries[1].Error)
+	assert.Equal(t, entries[0].Hash, entries[1].Prev)
+
+	head, err := VerifyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoint{Seq: 2, Hash: entries[1].Hash}, head)
+	assert.Equal(t, head, c.Head())
+
+	// Signing fails once the signature cannot be recorded
+	require.NoError(t, c.Close())
+	_, err = c.Sign(k, msg, nil)
+	assert.EqualError(t, err, "Failed recording the signature in the audit log: audit log closed")
+}
+
+func TestReopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c, path := newTestCSP(t, dir, AuditOpts{Enabled: true})
+	k, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = c.Sign(k, []byte("block"), nil)
+	require.NoError(t, err)
+	require.NoError(t, c.Close())
+
+	l, err := OpenLog(path)
+	require.NoError(t, err)
+	require.NoError(t, l.Append(&Entry{Type: EntrySign}))
+	require.NoError(t, l.Close())
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 2)
+	assert.Equal(t, entries[0].Hash, entries[1].Prev)
+	_, err = VerifyFile(path)
+	assert.NoError(t, err)
+}
+
+func TestVerifyTampered(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	l, err := OpenLog(filepath.Join(dir, "audit.log"))
+	require.NoError(t, err)
+	defer l.Close()
+	for _, purpose := range []string{"endorsement", "block", "tls"} {
+		require.NoError(t, l.Append(&Entry{Type: EntrySign, Purpose: purpose}))
+	}
+	raw, err := ioutil.ReadFile(l.f.Name())
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(raw), "\n")[:3]
+
+	_, err = Verify(strings.NewReader(strings.Replace(string(raw), "block", "admin", 1)))
+	assert.EqualError(t, err, "audit entry 2 was altered")
+
+	_, err = Verify(strings.NewReader(lines[0] + lines[2]))
+	assert.EqualError(t, err, "audit entry 3 follows entry 1")
+
+	_, err = Verify(strings.NewReader(lines[1] + lines[2]))
+	assert.EqualError(t, err, "audit entry 2 follows entry 0")
+
+	_, err = Verify(strings.NewReader(lines[0] + strings.TrimSuffix(lines[1], "\n")))
+	assert.EqualError(t, err, "truncated audit entry after entry 1")
+
+	head, err := Verify(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Equal(t, Checkpoint{}, head)
+
+	// Rewriting an entry with its hash does not mend the chain
+	var e Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &e))
+	e.Purpose = "admin"
+	e.Hash, err = e.computeHash()
+	require.NoError(t, err)
+	forged, err := json.Marshal(e)
+	require.NoError(t, err)
+	_, err = Verify(strings.NewReader(lines[0] + string(forged) + "\n" + lines[2]))
+	assert.EqualError(t, err, "audit entry 3 is not chained to entry 2")
+
+	path := filepath.Join(dir, "forged.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte(lines[1]), 0600))
+	_, err = OpenLog(path)
+	assert.EqualError(t, err, "Invalid audit log ["+path+"]: audit entry 2 follows entry 0")
+}
+
+func TestAnchor(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	c, path := newTestCSP(t, dir, AuditOpts{Enabled: true})
+	defer c.Close()
+
+	// Nothing happens without an Anchorer
+	require.NoError(t, c.Anchor())
+
+	var anchored []Checkpoint
+	fail := false
+	c.SetAnchorer(AnchorFunc(func(cp Checkpoint) (string, error) {
+		if fail {
+			return "", errors.New("orderer unavailable")
+		}
+		anchored = append(anchored, cp)
+		return "anchortx", nil
+	}))
+
+	k, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	_, err = c.Sign(k, []byte("block"), nil)
+	require.NoError(t, err)
+	head := c.Head()
+
+	require.NoError(t, c.Anchor())
+	require.NoError(t, c.Anchor())
+	assert.Equal(t, []Checkpoint{head}, anchored)
+
+	_, err = c.Sign(k, []byte("block"), nil)
+	require.NoError(t, err)
+	fail = true
+	assert.EqualError(t, c.Anchor(), "Failed anchoring audit entry 3: orderer unavailable")
+
+	entries := readEntries(t, path)
+	require.Len(t, entries, 4)
+	assert.Equal(t, EntryAnchor, entries[1].Type)
+	assert.Equal(t, head.Hash, entries[1].Digest)
+	assert.Equal(t, uint64(1), entries[1].AnchoredSeq)
+	assert.Equal(t, "anchortx", entries[1].TxID)
+	assert.Equal(t, "orderer unavailable", entries[3].Error)
+	_, err = VerifyFile(path)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/pkg/errors"
+)
+
+// Types of the entries of the audit log.
+const (
+	// EntrySign records a private key signing operation
+	EntrySign = "sign"
+	// EntryAnchor records the anchoring of the log head into the ledger
+	EntryAnchor = "anchor"
+)
+
+// genesisHash is the previous hash of the first entry.
+var genesisHash = strings.Repeat("0", 2*sm3.Size)
+
+// Entry is a record of the audit log, stored as a line of JSON. Hash is the
+// hex encoded SM3 digest of the JSON encoding of the entry with an empty
+// Hash, which includes the hash of the previous entry, so that altering,
+// inserting or removing an entry breaks the chain.
+type Entry struct {
+	Seq  uint64 `json:"seq"`
+	Time string `json:"time"`
+	Type string `json:"type"`
+
+	// SKI is the hex encoded SKI of the signing key
+	SKI string `json:"ski,omitempty"`
+	// Digest is the hex encoded SM3 digest of the signed input, which is
+	// the message itself for SM2 signatures, or of the anchored head
+	Digest    string `json:"digest,omitempty"`
+	Purpose   string `json:"purpose,omitempty"`
+	ChannelID string `json:"channel,omitempty"`
+	// TxID is the transaction the signature was produced for, or the
+	// reference of the anchoring transaction
+	TxID string `json:"txid,omitempty"`
+	// AnchoredSeq is the sequence number of the anchored head
+	AnchoredSeq uint64 `json:"anchoredseq,omitempty"`
+	// Error is the error of a failed operation
+	Error string `json:"error,omitempty"`
+
+	Prev string `json:"prev"`
+	Hash string `json:"hash"`
+}
+
+// computeHash returns the hash of e.
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	digest := sm3.Sum(raw)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// Checkpoint identifies the head of an audit log.
+type Checkpoint struct {
+	// Seq is the sequence number of the last entry, 0 for an empty log
+	Seq uint64
+	// Hash is the hash of the last entry
+	Hash string
+}
+
+// Log is an append-only, SM3 hash-chained log of audit entries.
+type Log struct {
+	mutex sync.Mutex
+	f     *os.File
+	head  Checkpoint
+	now   func() time.Time
+}
+
+// OpenLog opens the audit log at path, creating it if needed. The chain of
+// an existing log is verified first, so that a node does not append to a
+// log that was tampered with.
+func OpenLog(path string) (*Log, error) {
+	if path == "" {
+		return nil, errors.New("Invalid audit log path. It must not be empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.Wrapf(err, "Failed creating the directory of audit log [%s]", path)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed opening audit log [%s]", path)
+	}
+	head, err := Verify(f)
+	if err != nil {
+		f.Close()
+		return nil, errors.WithMessagef(err, "Invalid audit log [%s]", path)
+	}
+
+	return &Log{f: f, head: head, now: time.Now}, nil
+}
+
+// Append chains e to the log, setting its sequence number, time and
+// hashes, and writes it.
+func (l *Log) Append(e *Entry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.f == nil {
+		return errors.New("audit log closed")
+	}
+
+	e.Seq = l.head.Seq + 1
+	e.Time = l.now().UTC().Format(time.RFC3339Nano)
+	e.Prev = l.head.Hash
+	if e.Prev == "" {
+		e.Prev = genesisHash
+	}
+	hash, err := e.computeHash()
+	if err != nil {
+		return errors.Wrap(err, "Failed hashing audit entry")
+	}
+	e.Hash = hash
+
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "Failed encoding audit entry")
+	}
+	if _, err := l.f.Write(append(raw, '\n')); err != nil {
+		return errors.Wrap(err, "Failed writing audit entry")
+	}
+
+	l.head = Checkpoint{Seq: e.Seq, Hash: e.Hash}
+	return nil
+}
+
+// Head returns the checkpoint of the last entry.
+func (l *Log) Head() Checkpoint {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.head
+}
+
+// Sync commits the log to stable storage.
+func (l *Log) Sync() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.f == nil {
+		return errors.New("audit log closed")
+	}
+	return l.f.Sync()
+}
+
+// Close syncs and closes the log.
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Sync()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// Verify reads an audit log from r and checks its hash chain, returning the
+// checkpoint of its last entry or the first broken entry.
+func Verify(r io.Reader) (Checkpoint, error) {
+	head := Checkpoint{Hash: genesisHash}
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err == io.EOF {
+			return Checkpoint{}, errors.Errorf("truncated audit entry after entry %d", head.Seq)
+		}
+		if err != nil {
+			return Checkpoint{}, errors.Wrap(err, "Failed reading audit log")
+		}
+
+		var e Entry
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&e); err != nil {
+			return Checkpoint{}, errors.Wrapf(err, "malformed audit entry after entry %d", head.Seq)
+		}
+		if e.Seq != head.Seq+1 {
+			return Checkpoint{}, errors.Errorf("audit entry %d follows entry %d", e.Seq, head.Seq)
+		}
+		if e.Prev != head.Hash {
+			return Checkpoint{}, errors.Errorf("audit entry %d is not chained to entry %d", e.Seq, head.Seq)
+		}
+		hash, err := e.computeHash()
+		if err != nil {
+			return Checkpoint{}, errors.Wrapf(err, "Failed hashing audit entry %d", e.Seq)
+		}
+		if hash != e.Hash {
+			return Checkpoint{}, errors.Errorf("audit entry %d was altered", e.Seq)
+		}
+		head = Checkpoint{Seq: e.Seq, Hash: e.Hash}
+	}
+
+	if head.Seq == 0 {
+		return Checkpoint{}, nil
+	}
+	return head, nil
+}
+
+// VerifyFile checks the hash chain of the audit log at path.
+func VerifyFile(path string) (Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Checkpoint{}, errors.Wrapf(err, "Failed opening audit log [%s]", path)
+	}
+	defer f.Close()
+	return Verify(f)
+}
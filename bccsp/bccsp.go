@@ -34,6 +34,56 @@ type Key interface {
 	PublicKey() (Key, error)
 }
 
+// Names of the attributes returned by AttributedKey.KeyAttributes.
+const (
+	// KeyAttrCreated is the creation time of the key, in RFC 3339 format.
+	KeyAttrCreated = "created"
+	// KeyAttrOrigin tells how the key entered the provider, one of
+	// KeyOriginGenerated, KeyOriginImported or KeyOriginDerived.
+	KeyAttrOrigin = "origin"
+	// KeyAttrProvider is the name of the provider holding the key, e.g. SW or PKCS11.
+	KeyAttrProvider = "provider"
+	// KeyAttrExportable is "true" if the private material can leave the provider.
+	KeyAttrExportable = "exportable"
+	// KeyAttrUsage is the SM2KeyUsage of the key, "sign" or "encrypt".
+	KeyAttrUsage = "usage"
+	// KeyAttrPairedKey is the hex encoded SKI of the other key of a dual
+	// certificate identity, see PairedKeyStore.
+	KeyAttrPairedKey = "paired"
+)
+
+// Values of the KeyAttrOrigin attribute.
+const (
+	KeyOriginGenerated = "generated"
+	KeyOriginImported  = "imported"
+	KeyOriginDerived   = "derived"
+)
+
+// AttributedKey is implemented by keys carrying attestation metadata,
+// enabling policy checks such as requiring non-exportable HSM keys.
+type AttributedKey interface {
+	// KeyAttributes returns a copy of the attributes of this key.
+	// Attributes unknown to the provider are absent from the map.
+	KeyAttributes() map[string]string
+}
+
+// CryptoPublicKeyer is implemented by asymmetric keys able to expose their
+// public part as a standard library crypto.PublicKey, e.g. *ecdsa.PublicKey
+// or *sm2.PublicKey. It lets a private key held by a CSP be wrapped as a
+// crypto.Signer without exporting any private material.
+type CryptoPublicKeyer interface {
+	// CryptoPublicKey returns the public part of this key.
+	CryptoPublicKey() (crypto.PublicKey, error)
+}
+
+// AlgorithmKey is implemented by keys whose algorithm cannot be told from
+// their public part, such as symmetric keys, e.g. to label the metrics of
+// the operations using them.
+type AlgorithmKey interface {
+	// Algorithm returns the key algorithm identifier, e.g. AES or SM4.
+	Algorithm() string
+}
+
 // KeyGenOpts contains options for key-generation with a CSP.
 type KeyGenOpts interface {
 
@@ -100,7 +100,7 @@ func TestSM2Opts(t *testing.T) {
 
 	test := func(ephemeral bool) {
 		for _, opts := range []KeyGenOpts{
-			&SM2KeyGenOpts{ephemeral},
+			&SM2KeyGenOpts{Temporary: ephemeral},
 			&SM2PKIXPublicKeyImportOpts{ephemeral},
 			&SM2PrivateKeyImportOpts{ephemeral},
 			&SM2GoPublicKeyImportOpts{ephemeral},
@@ -178,3 +178,12 @@ func TestKeyGenOpts(t *testing.T) {
 	test(true)
 	test(false)
 }
+
+func TestSM2SignatureEncodingOf(t *testing.T) {
+	var nilOpts *SM2SignerOpts
+	assert.Equal(t, SM2SignatureDER, SM2SignatureEncodingOf(nil))
+	assert.Equal(t, SM2SignatureDER, SM2SignatureEncodingOf(nilOpts))
+	assert.Equal(t, SM2SignatureDER, SM2SignatureEncodingOf(&SM2SignerOpts{}))
+	assert.Equal(t, SM2SignatureRaw, SM2SignatureEncodingOf(&SM2SignerOpts{Encoding: SM2SignatureRaw}))
+	assert.Equal(t, SM2SignatureAny, SM2SignatureEncodingOf(&SM2SignerOpts{Encoding: SM2SignatureAny}))
+}
@@ -0,0 +1,241 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package benchmarks is a suite of benchmarks of the operations of a BCCSP,
+// KeyGen, Sign, Verify, Hash, Encrypt and Decrypt, over payloads of various
+// sizes. A benchmark has the same name whatever the provider it runs
+// against, e.g. SM2/Verify or SM4/Encrypt/1KiB, so that the results of
+// several providers, or of a provider before and after a change, compare
+// with benchstat.
+//
+//	func BenchmarkVendor(b *testing.B) {
+//		benchmarks.Run(b, newVendorCSP(b), benchmarks.Options{})
+//	}
+//
+// The bccspbench command runs the suite against the SW, GM and PKCS11
+// providers outside of go test, and profiles them.
+package benchmarks
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+// Families of the benchmarks.
+const (
+	FamilySM2   = bccsp.SM2
+	FamilySM3   = bccsp.SM3
+	FamilySM4   = bccsp.SM4
+	FamilyECDSA = bccsp.ECDSA
+	FamilySHA2  = bccsp.SHA2
+	FamilyAES   = bccsp.AES
+)
+
+// DefaultPayloads are the sizes, in bytes, of the payloads hashed and
+// encrypted when Options do not list any.
+var DefaultPayloads = []int{64, 1024, 16 * 1024, 1024 * 1024}
+
+// Prepare sets a benchmark up against csp, e.g. generating the key and the
+// signature to verify, and returns the operation measured, which processes
+// payload if the benchmark has one.
+type Prepare func(csp bccsp.BCCSP, payload []byte) (op func() error, err error)
+
+// Benchmark is a benchmark of an operation of a provider.
+type Benchmark struct {
+	// Name of the benchmark, e.g. SM2/Sign or SM3/Hash/1KiB, also the name
+	// of its sub-benchmark
+	Name string
+	// Family is the algorithm family the benchmark exercises
+	Family string
+	// Payload is the size of the payload processed by an operation, zero
+	// for operations on a fixed input
+	Payload int
+	// Prepare sets the benchmark up
+	Prepare Prepare
+}
+
+// Options selects the benchmarks run against a provider.
+type Options struct {
+	// Families restricts the benchmarks to those of the families, all of
+	// them are run when empty
+	Families []string
+	// Skip lists the names of the benchmarks not run, payload excluded,
+	// e.g. SM2/KeyGen for providers whose key generation is slow
+	Skip []string
+	// Payloads are the sizes of the payloads, DefaultPayloads when empty
+	Payloads []int
+}
+
+// Benchmarks returns the benchmarks selected by opts, one per payload size
+// for the operations processing a payload.
+func Benchmarks(opts Options) []Benchmark {
+	payloads := opts.Payloads
+	if len(payloads) == 0 {
+		payloads = DefaultPayloads
+	}
+
+	var benchmarks []Benchmark
+	for _, d := range definitions {
+		if !selected(d, opts) {
+			continue
+		}
+		name := d.family + "/" + d.operation
+		if !d.sized {
+			benchmarks = append(benchmarks, Benchmark{Name: name, Family: d.family, Prepare: d.prepare})
+			continue
+		}
+		for _, size := range payloads {
+			benchmarks = append(benchmarks, Benchmark{
+				Name:    name + "/" + formatSize(size),
+				Family:  d.family,
+				Payload: size,
+				Prepare: d.prepare,
+			})
+		}
+	}
+	return benchmarks
+}
+
+func selected(d definition, opts Options) bool {
+	for _, name := range opts.Skip {
+		if strings.EqualFold(name, d.family+"/"+d.operation) {
+			return false
+		}
+	}
+	if len(opts.Families) == 0 {
+		return true
+	}
+	for _, family := range opts.Families {
+		if strings.EqualFold(family, d.family) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSize returns the size of a payload in the unit it is a multiple of.
+func formatSize(size int) string {
+	switch {
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dMiB", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dKiB", size>>10)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
+
+// prepare sets the benchmark up and runs its operation once, so that
+// operations the provider does not support are reported before measuring.
+func (bm Benchmark) prepare(csp bccsp.BCCSP) (func() error, error) {
+	payload := make([]byte, bm.Payload)
+	if _, err := io.ReadFull(rand.Reader, payload); err != nil {
+		return nil, err
+	}
+	op, err := bm.Prepare(csp, payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := op(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+// measure runs op b.N times, returning the first error.
+func (bm Benchmark) measure(b *testing.B, op func() error) error {
+	b.SetBytes(int64(bm.Payload))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := op(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run runs the benchmarks selected by opts against csp, each in a
+// sub-benchmark. Those of the operations csp does not support are skipped.
+func Run(b *testing.B, csp bccsp.BCCSP, opts Options) {
+	if csp == nil {
+		b.Fatal("Invalid BCCSP instance. It must be different from nil")
+	}
+
+	for _, bm := range Benchmarks(opts) {
+		bm := bm
+		b.Run(bm.Name, func(b *testing.B) {
+			op, err := bm.prepare(csp)
+			if err != nil {
+				b.Skipf("Not supported: %s", err)
+			}
+			if err := bm.measure(b, op); err != nil {
+				b.Fatalf("%s failed: %s", bm.Name, err)
+			}
+		})
+	}
+}
+
+// Result is the measure of a benchmark against a provider.
+type Result struct {
+	Benchmark
+	testing.BenchmarkResult
+	// Err is the reason why the benchmark was not measured, the provider
+	// not supporting the operation or the operation failing
+	Err error
+}
+
+// Measure runs the benchmarks selected by opts against csp outside of go
+// test, for the duration of -test.benchtime each.
+func Measure(csp bccsp.BCCSP, opts Options) []Result {
+	var results []Result
+	for _, bm := range Benchmarks(opts) {
+		op, err := bm.prepare(csp)
+		if err != nil {
+			results = append(results, Result{Benchmark: bm, Err: err})
+			continue
+		}
+
+		var opErr error
+		r := testing.Benchmark(func(b *testing.B) {
+			if err := bm.measure(b, op); err != nil {
+				opErr = err
+				b.FailNow()
+			}
+		})
+		results = append(results, Result{Benchmark: bm, BenchmarkResult: r, Err: opErr})
+	}
+	return results
+}
+
+// WriteResults writes the results of the provider in the format of go test
+// -bench, which benchstat reads, naming them Benchmark<provider>/<name>.
+// The benchmarks not measured are written as comments.
+func WriteResults(w io.Writer, provider string, results []Result) error {
+	suffix := ""
+	if procs := runtime.GOMAXPROCS(0); procs > 1 {
+		suffix = fmt.Sprintf("-%d", procs)
+	}
+
+	for _, r := range results {
+		var err error
+		if r.Err != nil {
+			_, err = fmt.Fprintf(w, "# Benchmark%s/%s not measured: %s\n", provider, r.Name, r.Err)
+		} else {
+			_, err = fmt.Fprintf(w, "Benchmark%s/%s%s\t%s\t%s\n", provider, r.Name, suffix, r.BenchmarkResult.String(), r.MemString())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package benchmarks
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/mocks"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkSoftwareProvider(b *testing.B) {
+	csp, err := sw.NewWithParams(256, "SHA2", sw.NewInMemoryKeyStore())
+	require.NoError(b, err)
+
+	Run(b, csp, Options{})
+}
+
+func BenchmarkSoftwareProviderSM2FastVerify(b *testing.B) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore(), sw.WithSM2FastVerify())
+	require.NoError(b, err)
+
+	Run(b, csp, Options{Families: []string{FamilySM2}})
+}
+
+func TestBenchmarkNames(t *testing.T) {
+	names := map[string]bool{}
+	for _, bm := range Benchmarks(Options{}) {
+		assert.False(t, names[bm.Name], "duplicate benchmark %s", bm.Name)
+		names[bm.Name] = true
+		assert.Contains(t, []string{FamilySM2, FamilySM3, FamilySM4, FamilyECDSA, FamilySHA2, FamilyAES}, bm.Family)
+		assert.True(t, strings.HasPrefix(bm.Name, bm.Family+"/"), bm.Name)
+		assert.NotNil(t, bm.Prepare)
+	}
+
+	assert.True(t, names["SM2/Verify"])
+	assert.True(t, names["SM3/Hash/64B"])
+	assert.True(t, names["SM4/Encrypt/16KiB"])
+	assert.True(t, names["AES/Decrypt/1MiB"])
+}
+
+func TestBenchmarksSelection(t *testing.T) {
+	var names []string
+	for _, bm := range Benchmarks(Options{Families: []string{"sm3", FamilyECDSA}, Skip: []string{"ecdsa/keygen"}, Payloads: []int{100, 2048}}) {
+		names = append(names, bm.Name)
+	}
+	assert.Equal(t, []string{"SM3/Hash/100B", "SM3/Hash/2KiB", "ECDSA/Sign", "ECDSA/Verify"}, names)
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0B", formatSize(0))
+	assert.Equal(t, "1000B", formatSize(1000))
+	assert.Equal(t, "1KiB", formatSize(1024))
+	assert.Equal(t, "1025B", formatSize(1025))
+	assert.Equal(t, "1536KiB", formatSize(1536*1024))
+	assert.Equal(t, "4MiB", formatSize(4<<20))
+}
+
+func TestPrepare(t *testing.T) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+
+	// Every operation is supported by the software provider
+	for _, bm := range Benchmarks(Options{Payloads: []int{64}}) {
+		op, err := bm.prepare(csp)
+		assert.NoError(t, err, bm.Name)
+		assert.NoError(t, op(), bm.Name)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+
+	results := Measure(csp, Options{Families: []string{FamilySM3}, Payloads: []int{64}})
+	require.Len(t, results, 1)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "SM3/Hash/64B", results[0].Name)
+	assert.True(t, results[0].N > 0)
+	assert.Equal(t, int64(64), results[0].Bytes)
+
+	unsupported := &mocks.MockBCCSP{HashErr: errors.New("no hash")}
+	results = append(results, Measure(unsupported, Options{Families: []string{FamilySHA2}, Payloads: []int{64}})...)
+	require.Len(t, results, 2)
+	assert.EqualError(t, results[1].Err, "no hash")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteResults(&buf, "SW", results))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Regexp(t, `^BenchmarkSW/SM3/Hash/64B(-\d+)?\t *\d+\t *[\d.]+ ns/op\t *[\d.]+ MB/s\t *\d+ B/op\t *\d+ allocs/op$`, lines[0])
+	assert.Equal(t, "# BenchmarkSW/SHA2/Hash/64B not measured: no hash", lines[1])
+}
@@ -0,0 +1,183 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package benchmarks
+
+import (
+	"errors"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+// message is the input of the signature benchmarks. SM2 signs it as is,
+// computing SM3(Z_A || M) itself, ECDSA signs its SHA-256 digest.
+var message = []byte("fabric bccsp benchmarks")
+
+// definition is an operation benchmarked, once per payload size if sized.
+type definition struct {
+	family    string
+	operation string
+	sized     bool
+	prepare   Prepare
+}
+
+var definitions = []definition{
+	{FamilySM2, "KeyGen", false, keyGen(&bccsp.SM2KeyGenOpts{Temporary: true})},
+	{FamilySM2, "Sign", false, sign(&bccsp.SM2KeyGenOpts{Temporary: true}, nil)},
+	{FamilySM2, "Verify", false, verify(&bccsp.SM2KeyGenOpts{Temporary: true}, nil)},
+	{FamilySM2, "Encrypt", true, encryptSM2},
+	{FamilySM2, "Decrypt", true, decryptSM2},
+	{FamilySM3, "Hash", true, hash(&bccsp.SM3Opts{})},
+	{FamilySM4, "Encrypt", true, encrypt(&bccsp.SM4KeyGenOpts{Temporary: true})},
+	{FamilySM4, "Decrypt", true, decrypt(&bccsp.SM4KeyGenOpts{Temporary: true})},
+	{FamilyECDSA, "KeyGen", false, keyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})},
+	{FamilyECDSA, "Sign", false, sign(&bccsp.ECDSAP256KeyGenOpts{Temporary: true}, &bccsp.SHA256Opts{})},
+	{FamilyECDSA, "Verify", false, verify(&bccsp.ECDSAP256KeyGenOpts{Temporary: true}, &bccsp.SHA256Opts{})},
+	{FamilySHA2, "Hash", true, hash(&bccsp.SHA256Opts{})},
+	{FamilyAES, "Encrypt", true, encrypt(&bccsp.AES256KeyGenOpts{Temporary: true})},
+	{FamilyAES, "Decrypt", true, decrypt(&bccsp.AES256KeyGenOpts{Temporary: true})},
+}
+
+func keyGen(opts bccsp.KeyGenOpts) Prepare {
+	return func(csp bccsp.BCCSP, _ []byte) (func() error, error) {
+		return func() error {
+			_, err := csp.KeyGen(opts)
+			return err
+		}, nil
+	}
+}
+
+// digest returns the input of the signature of message, message itself if
+// hashOpts is nil.
+func digest(csp bccsp.BCCSP, hashOpts bccsp.HashOpts) ([]byte, error) {
+	if hashOpts == nil {
+		return message, nil
+	}
+	return csp.Hash(message, hashOpts)
+}
+
+func sign(keyGenOpts bccsp.KeyGenOpts, hashOpts bccsp.HashOpts) Prepare {
+	return func(csp bccsp.BCCSP, _ []byte) (func() error, error) {
+		k, err := csp.KeyGen(keyGenOpts)
+		if err != nil {
+			return nil, err
+		}
+		d, err := digest(csp, hashOpts)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := csp.Sign(k, d, nil)
+			return err
+		}, nil
+	}
+}
+
+func verify(keyGenOpts bccsp.KeyGenOpts, hashOpts bccsp.HashOpts) Prepare {
+	return func(csp bccsp.BCCSP, _ []byte) (func() error, error) {
+		k, err := csp.KeyGen(keyGenOpts)
+		if err != nil {
+			return nil, err
+		}
+		pk, err := k.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		d, err := digest(csp, hashOpts)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := csp.Sign(k, d, nil)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			valid, err := csp.Verify(pk, signature, d, nil)
+			if err != nil {
+				return err
+			}
+			if !valid {
+				return errors.New("signature is invalid")
+			}
+			return nil
+		}, nil
+	}
+}
+
+func hash(opts bccsp.HashOpts) Prepare {
+	return func(csp bccsp.BCCSP, payload []byte) (func() error, error) {
+		return func() error {
+			_, err := csp.Hash(payload, opts)
+			return err
+		}, nil
+	}
+}
+
+// encrypt and decrypt benchmark the GCM mode of the symmetric keys, the
+// one that processes payloads of any size.
+func encrypt(keyGenOpts bccsp.KeyGenOpts) Prepare {
+	return func(csp bccsp.BCCSP, payload []byte) (func() error, error) {
+		k, err := csp.KeyGen(keyGenOpts)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := csp.Encrypt(k, payload, &bccsp.AEADOpts{})
+			return err
+		}, nil
+	}
+}
+
+func decrypt(keyGenOpts bccsp.KeyGenOpts) Prepare {
+	return func(csp bccsp.BCCSP, payload []byte) (func() error, error) {
+		k, err := csp.KeyGen(keyGenOpts)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, err := csp.Encrypt(k, payload, &bccsp.AEADOpts{})
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			_, err := csp.Decrypt(k, ciphertext, &bccsp.AEADOpts{})
+			return err
+		}, nil
+	}
+}
+
+func encryptSM2(csp bccsp.BCCSP, payload []byte) (func() error, error) {
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	if err != nil {
+		return nil, err
+	}
+	pk, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := csp.Encrypt(pk, payload, &bccsp.SM2EncrypterOpts{})
+		return err
+	}, nil
+}
+
+func decryptSM2(csp bccsp.BCCSP, payload []byte) (func() error, error) {
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	if err != nil {
+		return nil, err
+	}
+	pk, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := csp.Encrypt(pk, payload, &bccsp.SM2EncrypterOpts{})
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := csp.Decrypt(k, ciphertext, &bccsp.SM2EncrypterOpts{})
+		return err
+	}, nil
+}
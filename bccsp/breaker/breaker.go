@@ -0,0 +1,445 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package breaker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_breaker")
+
+const (
+	defaultMaxConcurrency   = 16
+	defaultMaxQueue         = 64
+	defaultQueueTimeout     = time.Second
+	defaultTimeout          = 5 * time.Second
+	defaultFailureThreshold = 5
+	defaultOpenInterval     = 30 * time.Second
+)
+
+// Reasons of the rejections of operations.
+const (
+	reasonOpen         = "open"
+	reasonOverloaded   = "overloaded"
+	reasonQueueTimeout = "queue_timeout"
+)
+
+// BreakerOpts configures the concurrency limit, the timeouts and the
+// circuit breaker of a provider backed by an external device or service.
+type BreakerOpts struct {
+	// Enabled turns the breaker on
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+	// MaxConcurrency is the number of operations served by the provider at
+	// once, 16 by default
+	MaxConcurrency int `mapstructure:"maxconcurrency,omitempty" json:"maxconcurrency,omitempty" yaml:"MaxConcurrency"`
+	// MaxQueue is the number of operations waiting for the provider beyond
+	// which operations are shed, 64 by default
+	MaxQueue int `mapstructure:"maxqueue,omitempty" json:"maxqueue,omitempty" yaml:"MaxQueue"`
+	// QueueTimeout bounds the wait of an operation for the provider, 1s by
+	// default
+	QueueTimeout time.Duration `mapstructure:"queuetimeout,omitempty" json:"queuetimeout,omitempty" yaml:"QueueTimeout"`
+	// Timeout bounds an operation of the provider, 5s by default
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty" yaml:"Timeout"`
+	// FailureThreshold is the number of consecutive failures opening the
+	// circuit, 5 by default
+	FailureThreshold int `mapstructure:"failurethreshold,omitempty" json:"failurethreshold,omitempty" yaml:"FailureThreshold"`
+	// OpenInterval is how long operations are rejected once the circuit
+	// opened, before one is let through to probe the provider, 30s by
+	// default
+	OpenInterval time.Duration `mapstructure:"openinterval,omitempty" json:"openinterval,omitempty" yaml:"OpenInterval"`
+
+	// MetricsProvider receives the breaker metrics, they are disabled if nil
+	MetricsProvider metrics.Provider `json:"-" yaml:"-"`
+}
+
+type state int
+
+const (
+	stateClosed state = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// CSP is a BCCSP that protects its callers from a slow or failing
+// underlying BCCSP, typically an HSM, SDF device, KMS or remote signer:
+//
+// - at most MaxConcurrency operations are served at once, at most MaxQueue
+// more wait for QueueTimeout at most, and the others are shed;
+//
+// - callers wait for an operation for Timeout at most. The operation keeps
+// its slot until the provider returns, so that a stalled device does not
+// accumulate goroutines;
+//
+// - after FailureThreshold consecutive failures or timeouts, the circuit
+// opens: operations are rejected for OpenInterval, then a single operation
+// probes the provider and closes the circuit if it succeeds.
+//
+// Rejected operations fail with a bccsp.ErrCodeUnavailable error. Errors
+// with the code of an error of the caller, e.g. an invalid argument or an
+// unknown key, are not failures of the provider.
+//
+// Verify and Hash go to the underlying BCCSP directly: their outcome
+// decides the validity of transactions, and a rejection would make the
+// peers shedding load disagree with the others.
+type CSP struct {
+	// csp serves all operations that are not overridden
+	bccsp.BCCSP
+
+	provider         string
+	slots            chan struct{}
+	maxQueue         int
+	queueTimeout     time.Duration
+	timeout          time.Duration
+	failureThreshold int
+	openInterval     time.Duration
+	now              func() time.Time
+
+	metricsMutex sync.RWMutex
+	metrics      *Metrics
+
+	mutex     sync.Mutex
+	queued    int
+	state     state
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// New returns a CSP protecting the callers of csp, the provider named
+// provider, as configured by opts.
+func New(csp bccsp.BCCSP, provider string, opts BreakerOpts) (*CSP, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid BCCSP instance. It must be different from nil")
+	}
+	if provider == "" {
+		return nil, errors.New("Invalid provider name. It must not be empty")
+	}
+	if opts.MaxConcurrency < 0 || opts.MaxQueue < 0 || opts.FailureThreshold < 0 {
+		return nil, errors.Errorf("Invalid breaker limits [%d, %d, %d]. They must not be negative", opts.MaxConcurrency, opts.MaxQueue, opts.FailureThreshold)
+	}
+	if opts.QueueTimeout < 0 || opts.Timeout < 0 || opts.OpenInterval < 0 {
+		return nil, errors.Errorf("Invalid breaker durations [%s, %s, %s]. They must not be negative", opts.QueueTimeout, opts.Timeout, opts.OpenInterval)
+	}
+
+	c := &CSP{
+		BCCSP:            csp,
+		provider:         provider,
+		maxQueue:         opts.MaxQueue,
+		queueTimeout:     opts.QueueTimeout,
+		timeout:          opts.Timeout,
+		failureThreshold: opts.FailureThreshold,
+		openInterval:     opts.OpenInterval,
+		now:              time.Now,
+	}
+	maxConcurrency := opts.MaxConcurrency
+	if maxConcurrency == 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	c.slots = make(chan struct{}, maxConcurrency)
+	if c.maxQueue == 0 {
+		c.maxQueue = defaultMaxQueue
+	}
+	if c.queueTimeout == 0 {
+		c.queueTimeout = defaultQueueTimeout
+	}
+	if c.timeout == 0 {
+		c.timeout = defaultTimeout
+	}
+	if c.failureThreshold == 0 {
+		c.failureThreshold = defaultFailureThreshold
+	}
+	if c.openInterval == 0 {
+		c.openInterval = defaultOpenInterval
+	}
+
+	p := opts.MetricsProvider
+	if p == nil {
+		p = &disabled.Provider{}
+	}
+	c.metrics = NewMetrics(p)
+
+	return c, nil
+}
+
+// SetMetricsProvider redirects the metrics to p, for processes creating
+// their metrics provider after the BCCSP.
+func (c *CSP) SetMetricsProvider(p metrics.Provider) {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+	c.metrics = NewMetrics(p)
+}
+
+func (c *CSP) getMetrics() *Metrics {
+	c.metricsMutex.RLock()
+	defer c.metricsMutex.RUnlock()
+	return c.metrics
+}
+
+// Unwrap returns the BCCSP guarded by the breaker.
+func (c *CSP) Unwrap() bccsp.BCCSP {
+	return c.BCCSP
+}
+
+// KeyGen generates a key using opts.
+func (c *CSP) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	k, err := c.do(context.Background(), "keygen", func(context.Context) (interface{}, error) {
+		return c.BCCSP.KeyGen(opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	key, _ := k.(bccsp.Key)
+	return key, nil
+}
+
+// KeyDeriv derives a key from k using opts.
+func (c *CSP) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	dk, err := c.do(context.Background(), "keyderiv", func(context.Context) (interface{}, error) {
+		return c.BCCSP.KeyDeriv(k, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	key, _ := dk.(bccsp.Key)
+	return key, nil
+}
+
+// KeyImport imports a key from its raw representation using opts.
+func (c *CSP) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	k, err := c.do(context.Background(), "keyimport", func(context.Context) (interface{}, error) {
+		return c.BCCSP.KeyImport(raw, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	key, _ := k.(bccsp.Key)
+	return key, nil
+}
+
+// GetKey returns the key this CSP associates to the Subject Key Identifier
+// ski.
+func (c *CSP) GetKey(ski []byte) (bccsp.Key, error) {
+	k, err := c.do(context.Background(), "getkey", func(context.Context) (interface{}, error) {
+		return c.BCCSP.GetKey(ski)
+	})
+	if err != nil {
+		return nil, err
+	}
+	key, _ := k.(bccsp.Key)
+	return key, nil
+}
+
+// Sign signs digest using key k.
+func (c *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return c.SignCtx(context.Background(), k, digest, opts)
+}
+
+// SignCtx signs digest using key k, handing ctx, bounded by the timeout,
+// to the underlying BCCSP when it implements bccsp.ContextSigner.
+func (c *CSP) SignCtx(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	signature, err := c.do(ctx, "sign", func(ctx context.Context) (interface{}, error) {
+		return bccsp.SignCtx(ctx, c.BCCSP, k, digest, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signature.([]byte), nil
+}
+
+// Encrypt encrypts plaintext using key k.
+func (c *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	ciphertext, err := c.do(context.Background(), "encrypt", func(context.Context) (interface{}, error) {
+		return c.BCCSP.Encrypt(k, plaintext, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ciphertext.([]byte), nil
+}
+
+// Decrypt decrypts ciphertext using key k.
+func (c *CSP) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	plaintext, err := c.do(context.Background(), "decrypt", func(context.Context) (interface{}, error) {
+		return c.BCCSP.Decrypt(k, ciphertext, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plaintext.([]byte), nil
+}
+
+// result is the outcome of an operation.
+type result struct {
+	value interface{}
+	err   error
+}
+
+// do runs call, the operation named operation, in a slot of the provider
+// unless the circuit is open or the operation is shed, and waits for its
+// result until the timeout or until ctx is done.
+func (c *CSP) do(ctx context.Context, operation string, call func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	probe, err := c.allow(operation)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.acquire(ctx, operation); err != nil {
+		c.endProbe(probe)
+		return nil, err
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		defer c.release()
+		value, err := call(callCtx)
+		done <- result{value: value, err: err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-callCtx.Done():
+		if ctx.Err() != nil {
+			// The caller gave up, the provider did not fail
+			c.endProbe(probe)
+			return nil, ctx.Err()
+		}
+		c.getMetrics().Timeouts.With("provider", c.provider, "operation", operation).Add(1)
+		r.err = bccsp.Errorf(bccsp.ErrCodeUnavailable, "%s of the %s BCCSP timed out after %s", operation, c.provider, c.timeout)
+	}
+
+	c.record(r.err, probe)
+	return r.value, r.err
+}
+
+// allow rejects the operation if the circuit is open, and returns whether
+// it is the probe of a half-open circuit.
+func (c *CSP) allow(operation string) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.state == stateOpen && !c.now().Before(c.openUntil) {
+		c.state = stateHalfOpen
+	}
+	switch {
+	case c.state == stateClosed:
+		return false, nil
+	case c.state == stateHalfOpen && !c.probing:
+		c.probing = true
+		return true, nil
+	}
+
+	c.getMetrics().Rejections.With("provider", c.provider, "reason", reasonOpen).Add(1)
+	return false, bccsp.Errorf(bccsp.ErrCodeUnavailable, "%s rejected, the circuit of the %s BCCSP is open after %d consecutive failures", operation, c.provider, c.failures)
+}
+
+// acquire takes a slot of the provider, waiting in the queue when there is
+// room, until the queue timeout or until ctx is done.
+func (c *CSP) acquire(ctx context.Context, operation string) error {
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	m := c.getMetrics()
+	c.mutex.Lock()
+	if c.queued >= c.maxQueue {
+		c.mutex.Unlock()
+		m.Rejections.With("provider", c.provider, "reason", reasonOverloaded).Add(1)
+		return bccsp.Errorf(bccsp.ErrCodeUnavailable, "%s shed, the %s BCCSP is overloaded", operation, c.provider)
+	}
+	c.queued++
+	m.Queued.With("provider", c.provider).Set(float64(c.queued))
+	c.mutex.Unlock()
+
+	defer func() {
+		c.mutex.Lock()
+		c.queued--
+		m.Queued.With("provider", c.provider).Set(float64(c.queued))
+		c.mutex.Unlock()
+	}()
+
+	timer := time.NewTimer(c.queueTimeout)
+	defer timer.Stop()
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		m.Rejections.With("provider", c.provider, "reason", reasonQueueTimeout).Add(1)
+		return bccsp.Errorf(bccsp.ErrCodeUnavailable, "%s shed after waiting %s for the %s BCCSP", operation, c.queueTimeout, c.provider)
+	}
+}
+
+func (c *CSP) release() {
+	<-c.slots
+}
+
+// endProbe lets another operation probe the provider when probe did not
+// reach it.
+func (c *CSP) endProbe(probe bool) {
+	if !probe {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.probing = false
+}
+
+// record updates the circuit with the outcome err of an operation.
+func (c *CSP) record(err error, probe bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if probe {
+		c.probing = false
+	}
+
+	if !isFailure(err) {
+		c.failures = 0
+		if c.state != stateClosed {
+			c.state = stateClosed
+			c.getMetrics().Open.With("provider", c.provider).Set(0)
+			logger.Infof("Circuit of the %s BCCSP closed", c.provider)
+		}
+		return
+	}
+
+	c.failures++
+	if c.state == stateOpen || (!probe && c.failures < c.failureThreshold) {
+		return
+	}
+	c.state = stateOpen
+	c.openUntil = c.now().Add(c.openInterval)
+	c.getMetrics().Open.With("provider", c.provider).Set(1)
+	logger.Warningf("Circuit of the %s BCCSP open for %s after %d consecutive failures, the last one: %s", c.provider, c.openInterval, c.failures, err)
+}
+
+// isFailure returns whether err is a failure of the provider, rather than
+// of the caller, such as an invalid argument or an unknown key.
+func isFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch bccsp.ErrorCodeOf(err) {
+	case bccsp.ErrCodeUnknown, bccsp.ErrCodeUnavailable:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,210 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDevice = errors.New("device error")
+
+// device is a BCCSP whose signing blocks until released and whose
+// decryption fails with err.
+type device struct {
+	bccsp.BCCSP
+	entered  chan struct{}
+	release  chan struct{}
+	err      error
+	calls    int
+	verifies int
+}
+
+func newDevice() *device {
+	return &device{entered: make(chan struct{}, 16), release: make(chan struct{})}
+}
+
+func (d *device) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	d.entered <- struct{}{}
+	<-d.release
+	return []byte("signature"), nil
+}
+
+func (d *device) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	d.calls++
+	return []byte("plaintext"), d.err
+}
+
+func (d *device) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	d.verifies++
+	return true, nil
+}
+
+type fakeMetrics struct {
+	rejections *metricsfakes.Counter
+	timeouts   *metricsfakes.Counter
+	open       *metricsfakes.Gauge
+}
+
+func newTestCSP(t *testing.T, d *device, opts BreakerOpts) (*CSP, *fakeMetrics) {
+	c, err := New(d, "PKCS11", opts)
+	require.NoError(t, err)
+
+	m := &fakeMetrics{
+		rejections: &metricsfakes.Counter{},
+		timeouts:   &metricsfakes.Counter{},
+		open:       &metricsfakes.Gauge{},
+	}
+	m.rejections.WithReturns(m.rejections)
+	m.timeouts.WithReturns(m.timeouts)
+	m.open.WithReturns(m.open)
+	queued := &metricsfakes.Gauge{}
+	queued.WithReturns(queued)
+	p := &metricsfakes.Provider{}
+	p.NewCounterReturnsOnCall(0, m.rejections)
+	p.NewCounterReturnsOnCall(1, m.timeouts)
+	p.NewGaugeReturnsOnCall(0, m.open)
+	p.NewGaugeReturnsOnCall(1, queued)
+	c.SetMetricsProvider(p)
+
+	return c, m
+}
+
+func TestNewInvalidArgs(t *testing.T) {
+	_, err := New(nil, "PKCS11", BreakerOpts{})
+	assert.EqualError(t, err, "Invalid BCCSP instance. It must be different from nil")
+	_, err = New(newDevice(), "", BreakerOpts{})
+	assert.EqualError(t, err, "Invalid provider name. It must not be empty")
+	_, err = New(newDevice(), "PKCS11", BreakerOpts{MaxQueue: -1})
+	assert.EqualError(t, err, "Invalid breaker limits [0, -1, 0]. They must not be negative")
+	_, err = New(newDevice(), "PKCS11", BreakerOpts{Timeout: -time.Second})
+	assert.EqualError(t, err, "Invalid breaker durations [0s, -1s, 0s]. They must not be negative")
+
+	c, err := New(newDevice(), "PKCS11", BreakerOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultMaxConcurrency, cap(c.slots))
+	assert.Equal(t, defaultMaxQueue, c.maxQueue)
+	assert.Equal(t, defaultTimeout, c.timeout)
+}
+
+func TestCircuit(t *testing.T) {
+	d := newDevice()
+	defer close(d.release)
+	c, m := newTestCSP(t, d, BreakerOpts{Timeout: 10 * time.Millisecond, FailureThreshold: 2, OpenInterval: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Sign(nil, []byte("digest"), nil)
+		assert.EqualError(t, err, "sign of the PKCS11 BCCSP timed out after 10ms")
+		assert.Equal(t, bccsp.ErrCodeUnavailable, bccsp.ErrorCodeOf(err))
+	}
+	assert.Equal(t, 2, m.timeouts.AddCallCount())
+	assert.Equal(t, []string{"provider", "PKCS11", "operation", "sign"}, m.timeouts.WithArgsForCall(0))
+	require.Equal(t, 1, m.open.SetCallCount())
+	assert.Equal(t, float64(1), m.open.SetArgsForCall(0))
+
+	// The open circuit rejects operations without reaching the device
+	_, err := c.Decrypt(nil, nil, nil)
+	assert.EqualError(t, err, "decrypt rejected, the circuit of the PKCS11 BCCSP is open after 2 consecutive failures")
+	assert.True(t, errors.Is(err, bccsp.ErrUnavailable))
+	assert.Zero(t, d.calls)
+	assert.Equal(t, []string{"provider", "PKCS11", "reason", "open"}, m.rejections.WithArgsForCall(0))
+
+	// but verifications always reach it
+	valid, err := c.Verify(nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 1, d.verifies)
+	assert.Equal(t, 1, m.rejections.AddCallCount())
+
+	// A failed probe opens the circuit again
+	now = now.Add(time.Minute)
+	d.err = errDevice
+	_, err = c.Decrypt(nil, nil, nil)
+	assert.Equal(t, errDevice, err)
+	_, err = c.Decrypt(nil, nil, nil)
+	assert.EqualError(t, err, "decrypt rejected, the circuit of the PKCS11 BCCSP is open after 3 consecutive failures")
+	assert.Equal(t, 1, d.calls)
+
+	// A successful probe closes it
+	now = now.Add(time.Minute)
+	d.err = nil
+	_, err = c.Decrypt(nil, nil, nil)
+	assert.NoError(t, err)
+	require.Equal(t, 3, m.open.SetCallCount())
+	assert.Equal(t, float64(0), m.open.SetArgsForCall(2))
+
+	// Errors of the caller are not failures of the device
+	d.err = bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "invalid key")
+	for i := 0; i < 3; i++ {
+		_, err = c.Decrypt(nil, nil, nil)
+		assert.Equal(t, d.err, err)
+	}
+	assert.Equal(t, stateClosed, c.state)
+}
+
+func TestShedding(t *testing.T) {
+	d := newDevice()
+	c, m := newTestCSP(t, d, BreakerOpts{MaxConcurrency: 1, MaxQueue: 1, QueueTimeout: 50 * time.Millisecond})
+
+	signed := make(chan error, 1)
+	go func() {
+		_, err := c.Sign(nil, []byte("digest"), nil)
+		signed <- err
+	}()
+	<-d.entered
+
+	queued := make(chan error, 1)
+	go func() {
+		_, err := c.Sign(nil, []byte("digest"), nil)
+		queued <- err
+	}()
+	require.Eventually(t, func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return c.queued == 1
+	}, time.Second, time.Millisecond)
+
+	// The queue is full
+	_, err := c.Sign(nil, []byte("digest"), nil)
+	assert.EqualError(t, err, "sign shed, the PKCS11 BCCSP is overloaded")
+	assert.Equal(t, []string{"provider", "PKCS11", "reason", "overloaded"}, m.rejections.WithArgsForCall(0))
+
+	assert.EqualError(t, <-queued, "sign shed after waiting 50ms for the PKCS11 BCCSP")
+	assert.Equal(t, []string{"provider", "PKCS11", "reason", "queue_timeout"}, m.rejections.WithArgsForCall(1))
+
+	close(d.release)
+	assert.NoError(t, <-signed)
+
+	// Shedding does not open the circuit
+	assert.Equal(t, stateClosed, c.state)
+	assert.Zero(t, m.timeouts.AddCallCount())
+}
+
+func TestCallerCancellation(t *testing.T) {
+	d := newDevice()
+	defer close(d.release)
+	c, m := newTestCSP(t, d, BreakerOpts{FailureThreshold: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-d.entered
+		cancel()
+	}()
+	_, err := c.SignCtx(ctx, nil, []byte("digest"), nil)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, stateClosed, c.state)
+	assert.Zero(t, m.timeouts.AddCallCount())
+}
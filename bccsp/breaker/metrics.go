@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package breaker
+
+import (
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+var (
+	rejections = metrics.CounterOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "breaker",
+		Name:         "rejections",
+		Help:         "The number of operations rejected without reaching the provider, because its circuit was open, its queue was full or the wait for a slot timed out.",
+		LabelNames:   []string{"provider", "reason"},
+		StatsdFormat: "%{#fqname}.%{provider}.%{reason}",
+	}
+	timeouts = metrics.CounterOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "breaker",
+		Name:         "timeouts",
+		Help:         "The number of operations the provider did not complete in time.",
+		LabelNames:   []string{"provider", "operation"},
+		StatsdFormat: "%{#fqname}.%{provider}.%{operation}",
+	}
+	open = metrics.GaugeOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "breaker",
+		Name:         "open",
+		Help:         "Whether the circuit of the provider is open (1) or not (0).",
+		LabelNames:   []string{"provider"},
+		StatsdFormat: "%{#fqname}.%{provider}",
+	}
+	queued = metrics.GaugeOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "breaker",
+		Name:         "queued",
+		Help:         "The number of operations waiting for the provider.",
+		LabelNames:   []string{"provider"},
+		StatsdFormat: "%{#fqname}.%{provider}",
+	}
+)
+
+type Metrics struct {
+	Rejections metrics.Counter
+	Timeouts   metrics.Counter
+	Open       metrics.Gauge
+	Queued     metrics.Gauge
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		Rejections: p.NewCounter(rejections),
+		Timeouts:   p.NewCounter(timeouts),
+		Open:       p.NewGauge(open),
+		Queued:     p.NewGauge(queued),
+	}
+}
@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cipher modes reported in Capabilities.CipherModes.
+const (
+	// CipherModeAESCBCPKCS7 is AES in CBC mode with PKCS7 padding.
+	CipherModeAESCBCPKCS7 = "AES-CBC-PKCS7"
+	// CipherModeAESGCM is AES in GCM mode, see AEADOpts.
+	CipherModeAESGCM = "AES-GCM"
+	// CipherModeSM4 is the SM4 block cipher applied without chaining.
+	CipherModeSM4 = "SM4"
+	// CipherModeSM4CBCPKCS7 is SM4 in CBC mode with PKCS7 padding, the
+	// mode of the SM4 keys of PKCS11 tokens.
+	CipherModeSM4CBCPKCS7 = "SM4-CBC-PKCS7"
+	// CipherModeSM4GCM is SM4 in GCM mode, see AEADOpts.
+	CipherModeSM4GCM = "SM4-GCM"
+	// CipherModeSM2 is SM2 public key encryption, see SM2EncrypterOpts.
+	CipherModeSM2 = "SM2"
+)
+
+// Capabilities describes the algorithms offered by a CSP, so that callers
+// such as MSP setup and channel config validation can fail fast when a
+// node's provider cannot honor what a channel demands.
+type Capabilities struct {
+	// Provider is the name of the provider, e.g. SW or PKCS11.
+	Provider string
+	// DefaultHashFamily is the hash family used when no explicit
+	// algorithm is requested, i.e. one of SHA2, SHA3 or SM3.
+	DefaultHashFamily string
+	// HashAlgorithms lists the hash algorithms, e.g. SHA256 or SM3.
+	HashAlgorithms []string
+	// KeyGenAlgorithms lists the key generation algorithms, e.g. ECDSAP256 or SM2.
+	KeyGenAlgorithms []string
+	// SignatureAlgorithms lists the signature algorithms, e.g. ECDSA or SM2.
+	SignatureAlgorithms []string
+	// Curves lists the elliptic curves keys can be generated on.
+	Curves []string
+	// CipherModes lists the supported encryption schemes, e.g. SM4-GCM.
+	CipherModes []string
+}
+
+// CapabilitiesProvider is implemented by a BCCSP able to describe the
+// algorithms it offers.
+type CapabilitiesProvider interface {
+	// Capabilities returns the algorithms offered by this CSP.
+	Capabilities() *Capabilities
+}
+
+// Wrapper is implemented by a BCCSP decorating another one, e.g. with
+// metrics or a circuit breaker, without changing the algorithms offered.
+type Wrapper interface {
+	// Unwrap returns the decorated BCCSP.
+	Unwrap() BCCSP
+}
+
+// GetCapabilities returns the capabilities of csp, or of the BCCSP it
+// wraps, or false if none reports them.
+func GetCapabilities(csp BCCSP) (*Capabilities, bool) {
+	for csp != nil {
+		if cp, ok := csp.(CapabilitiesProvider); ok {
+			return cp.Capabilities(), true
+		}
+		w, ok := csp.(Wrapper)
+		if !ok {
+			break
+		}
+		csp = w.Unwrap()
+	}
+	return nil, false
+}
+
+// RequireHash returns an error if the hash algorithm is not offered.
+func (c *Capabilities) RequireHash(algorithm string) error {
+	return c.require("hash algorithm", algorithm, c.HashAlgorithms)
+}
+
+// RequireSignatureAlgorithm returns an error if the signature algorithm is not offered.
+func (c *Capabilities) RequireSignatureAlgorithm(algorithm string) error {
+	return c.require("signature algorithm", algorithm, c.SignatureAlgorithms)
+}
+
+// RequireCurve returns an error if keys cannot be generated on the curve.
+func (c *Capabilities) RequireCurve(curve string) error {
+	return c.require("curve", curve, c.Curves)
+}
+
+// RequireCipherMode returns an error if the cipher mode is not offered.
+func (c *Capabilities) RequireCipherMode(mode string) error {
+	return c.require("cipher mode", mode, c.CipherModes)
+}
+
+func (c *Capabilities) require(kind, name string, supported []string) error {
+	for _, s := range supported {
+		if strings.EqualFold(s, name) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s provider does not support %s [%s], supported: [%s]",
+		c.Provider, kind, name, strings.Join(supported, ", "))
+}
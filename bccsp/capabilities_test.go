@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type capableCSP struct {
+	BCCSP
+}
+
+func (c *capableCSP) Capabilities() *Capabilities {
+	return &Capabilities{Provider: "PKCS11", HashAlgorithms: []string{SHA256}}
+}
+
+type wrapperCSP struct {
+	BCCSP
+}
+
+func (w *wrapperCSP) Unwrap() BCCSP {
+	return w.BCCSP
+}
+
+func TestGetCapabilities(t *testing.T) {
+	_, ok := GetCapabilities(&wrapperCSP{})
+	assert.False(t, ok)
+
+	// Wrappers report the capabilities of the BCCSP they decorate
+	c, ok := GetCapabilities(&wrapperCSP{&wrapperCSP{&capableCSP{}}})
+	assert.True(t, ok)
+	assert.Equal(t, "PKCS11", c.Provider)
+	assert.NoError(t, c.RequireHash(SHA256))
+	assert.EqualError(t, c.RequireHash(SM3), "PKCS11 provider does not support hash algorithm [SM3], supported: [SHA256]")
+}
@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// bccspbench runs the benchmarks of the benchmarks package against the SW,
+// GM and PKCS11 providers and writes the results in the format of go test
+// -bench, so that the runs before and after a change compare with
+// benchstat:
+//
+//	bccspbench -providers SW,GM -count 5 > old.txt
+//	bccspbench -providers SW,GM -count 5 -sm2fastverify > new.txt
+//	benchstat old.txt new.txt
+//
+// Built with the gmssl tag, -gmimplementation gmssl measures the GM
+// provider on GmSSL.
+//
+// The CPU and memory profiles of a run are written with -cpuprofile and
+// -memprofile, for go tool pprof.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/benchmarks"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/pkcs11"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+)
+
+func main() {
+	testing.Init()
+	providers := flag.String("providers", "SW,GM", "comma-separated providers benchmarked, among SW, GM and PKCS11")
+	families := flag.String("families", "", "comma-separated algorithm families benchmarked, e.g. SM2,SM3, all if empty")
+	skip := flag.String("skip", "", "comma-separated benchmarks not run, e.g. SM2/KeyGen")
+	payloads := flag.String("payloads", "", "comma-separated payload sizes in bytes, 64,1024,16384,1048576 if empty")
+	benchtime := flag.String("benchtime", "1s", "run time of each benchmark, or count of operations as Nx")
+	count := flag.Int("count", 1, "number of runs of each benchmark")
+	sm2FastVerify := flag.Bool("sm2fastverify", false, "verify SM2 signatures with a precomputed multi-scalar multiplication in SW and GM")
+	gmImplementation := flag.String("gmimplementation", "", "implementation of SM2 and SM3 in GM, go or gmssl (requires the gmssl build tag)")
+	lib := flag.String("lib", "", "path of the PKCS#11 library, found in the usual places if empty")
+	label := flag.String("label", "", "PKCS#11 token label")
+	pin := flag.String("pin", "", "PKCS#11 user PIN")
+	out := flag.String("out", "", "file the results are written to, stdout if empty")
+	cpuProfile := flag.String("cpuprofile", "", "file the CPU profile is written to")
+	memProfile := flag.String("memprofile", "", "file the memory profile is written to")
+	flag.Parse()
+
+	if err := flag.Set("test.benchtime", *benchtime); err != nil {
+		log.Fatalf("Invalid benchtime [%s]: %s", *benchtime, err)
+	}
+	opts, err := options(*families, *skip, *payloads)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var csps []namedCSP
+	for _, name := range split(*providers) {
+		csp, err := newCSP(strings.ToUpper(name), *sm2FastVerify, *gmImplementation, pkcs11.PKCS11Opts{Library: *lib, Label: *label, Pin: *pin})
+		if err != nil {
+			log.Fatalf("Failed initializing provider %s: %s", name, err)
+		}
+		csps = append(csps, namedCSP{name: strings.ToUpper(name), csp: csp})
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("Failed creating %s: %s", *out, err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			log.Fatalf("Failed creating %s: %s", *cpuProfile, err)
+		}
+		defer f.Close()
+		if err := pprof.StartCPUProfile(f); err != nil {
+			log.Fatalf("Failed starting the CPU profile: %s", err)
+		}
+	}
+
+	err = run(w, csps, opts, *count)
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+	if err != nil {
+		log.Fatalf("Failed writing the results: %s", err)
+	}
+
+	if *memProfile != "" {
+		if err := writeMemProfile(*memProfile); err != nil {
+			log.Fatalf("Failed writing the memory profile: %s", err)
+		}
+	}
+}
+
+type namedCSP struct {
+	name string
+	csp  bccsp.BCCSP
+}
+
+func run(out io.Writer, csps []namedCSP, opts benchmarks.Options, count int) error {
+	w := bufio.NewWriter(out)
+	fmt.Fprintf(w, "goos: %s\ngoarch: %s\npkg: github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/benchmarks\n", runtime.GOOS, runtime.GOARCH)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for i := 0; i < count; i++ {
+		for _, c := range csps {
+			results := benchmarks.Measure(c.csp, opts)
+			if err := benchmarks.WriteResults(w, c.name, results); err != nil {
+				return err
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func newCSP(name string, sm2FastVerify bool, gmImplementation string, pkcs11Opts pkcs11.PKCS11Opts) (bccsp.BCCSP, error) {
+	switch name {
+	case factory.SoftwareBasedFactoryName:
+		return (&factory.SWFactory{}).Get(&factory.FactoryOpts{SwOpts: &factory.SwOpts{
+			SecLevel:      256,
+			HashFamily:    "SHA2",
+			InmemKeystore: &factory.InmemKeystoreOpts{},
+			SM2FastVerify: sm2FastVerify,
+		}})
+	case factory.GMBasedFactoryName:
+		return (&factory.GMFactory{}).Get(&factory.FactoryOpts{GmOpts: &factory.GmOpts{
+			SecLevel:       256,
+			HashFamily:     "SM3",
+			KeyStore:       &factory.GMKeyStoreOpts{Backend: factory.GMKeyStoreInmem},
+			SM2FastVerify:  sm2FastVerify,
+			Implementation: gmImplementation,
+		}})
+	case "PKCS11":
+		if pkcs11Opts.Library == "" {
+			pkcs11Opts.Library, pkcs11Opts.Pin, pkcs11Opts.Label = pkcs11.FindPKCS11Lib()
+		}
+		pkcs11Opts.SecLevel = 256
+		pkcs11Opts.HashFamily = "SHA2"
+		pkcs11Opts.Ephemeral = true
+		return pkcs11.New(pkcs11Opts, sw.NewInMemoryKeyStore())
+	default:
+		return nil, fmt.Errorf("unknown provider [%s]", name)
+	}
+}
+
+func options(families, skip, payloads string) (benchmarks.Options, error) {
+	opts := benchmarks.Options{
+		Families: split(families),
+		Skip:     split(skip),
+	}
+	for _, p := range split(payloads) {
+		size, err := strconv.Atoi(p)
+		if err != nil || size < 0 {
+			return opts, fmt.Errorf("invalid payload size [%s]", p)
+		}
+		opts.Payloads = append(opts.Payloads, size)
+	}
+	return opts, nil
+}
+
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	runtime.GC()
+	return pprof.WriteHeapProfile(f)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// hsmkeys lists the signing keys pre-provisioned on a PKCS#11 token or an
+// SDF device, computes their Fabric SKIs and writes the BCCSP configuration
+// fragment that lets the provider find them: KeyIDs for PKCS11, KeyIndexes
+// for SDF.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/pkcs11"
+)
+
+func main() {
+	provider := flag.String("provider", "PKCS11", "PKCS11 or SDF")
+	lib := flag.String("lib", "", "path of the PKCS#11 or SDF library")
+	label := flag.String("label", "", "PKCS#11 token label")
+	pin := flag.String("pin", "", "PKCS#11 user PIN")
+	maxIndex := flag.Uint("max-index", 64, "highest SDF internal key index probed")
+	out := flag.String("out", "", "file the configuration fragment is written to, stdout if empty")
+	flag.Parse()
+
+	if *lib == "" {
+		log.Fatal("-lib is required")
+	}
+
+	var buf bytes.Buffer
+	switch strings.ToUpper(*provider) {
+	case "PKCS11":
+		keys, err := pkcs11.DiscoverKeys(pkcs11.PKCS11Opts{Library: *lib, Label: *label, Pin: *pin})
+		if err != nil {
+			log.Fatalf("Failed discovering keys: %s", err)
+		}
+		writePKCS11(&buf, keys)
+	case "SDF":
+		if err := discoverSDF(&buf, *lib, *maxIndex); err != nil {
+			log.Fatalf("Failed discovering keys: %s", err)
+		}
+	default:
+		log.Fatalf("Unknown provider [%s]", *provider)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := ioutil.WriteFile(*out, buf.Bytes(), 0600); err != nil {
+		log.Fatalf("Failed writing %s: %s", *out, err)
+	}
+}
+
+func writePKCS11(buf *bytes.Buffer, keys []*pkcs11.DiscoveredKey) {
+	var mapped []*pkcs11.DiscoveredKey
+	for _, k := range keys {
+		private := "public key only"
+		if k.Private {
+			private = "private key found"
+		}
+		fmt.Fprintf(buf, "# %s key, SKI %x, CKA_ID %x, CKA_LABEL %q, %s\n", k.Algorithm, k.SKI, k.ID, k.Label, private)
+		if k.NeedsMapping() {
+			mapped = append(mapped, k)
+		}
+	}
+	if len(mapped) == 0 {
+		fmt.Fprintln(buf, "# all keys are found by SKI, no mapping needed")
+		return
+	}
+
+	fmt.Fprintln(buf, "KeyIDs:")
+	for _, k := range mapped {
+		m := k.Mapping()
+		fmt.Fprintf(buf, "  - SKI: %s\n", m.SKI)
+		if m.ID != "" {
+			fmt.Fprintf(buf, "    ID: %s\n", m.ID)
+		} else {
+			fmt.Fprintf(buf, "    Label: %q\n", m.Label)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+// +build !sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"errors"
+)
+
+func discoverSDF(buf *bytes.Buffer, lib string, maxIndex uint) error {
+	return errors.New("SDF support not compiled in, rebuild with -tags sdf")
+}
@@ -0,0 +1,33 @@
+// +build sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sdf"
+)
+
+func discoverSDF(buf *bytes.Buffer, lib string, maxIndex uint) error {
+	keys, err := sdf.DiscoverKeys(sdf.SDFOpts{Library: lib}, maxIndex)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(buf, "# no SM2 signing key found")
+		return nil
+	}
+
+	fmt.Fprintln(buf, "KeyIndexes:")
+	for _, k := range keys {
+		fmt.Fprintf(buf, "  - %d # SKI %x\n", k.Index, k.SKI)
+	}
+	return nil
+}
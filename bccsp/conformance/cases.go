@@ -0,0 +1,522 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package conformance
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// Vectors of GB/T 32905 (SM3), GB/T 32907 (SM4) and GB/T 32918 (SM2, the
+// key pair of its examples on the recommended curve, with the default
+// user ID).
+var (
+	sm3Vectors = []struct {
+		message []byte
+		digest  []byte
+	}{
+		{[]byte("abc"), mustDecodeHex("66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0")},
+		{bytes.Repeat([]byte("abcd"), 16), mustDecodeHex("debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732")},
+	}
+
+	sm4Key        = mustDecodeHex("0123456789abcdeffedcba9876543210")
+	sm4Plaintext  = mustDecodeHex("0123456789abcdeffedcba9876543210")
+	sm4Ciphertext = mustDecodeHex("681edf34d206965e86b3e94f536e4246")
+
+	sm2PublicKey = mustDecodeHex("09f9df311e5421a150dd7d161e4bc5c672179fad1833fc076bb08ff356f35020" +
+		"ccea490ce26775a52dc6ea718cc1aa600aed05fbf35e084a6632f6072da9ad13")
+	sm2Message   = []byte("fabric bccsp conformance")
+	sm2Signature = mustDecodeHex("3046022100e515ce6d79f7c47eb0802d8905cd725d18741fb2644ae3445d2781907b50c5ee" +
+		"02210096b0584cfc4c6020f580efb9c679cc3cbb5731bd93af245eda681b0d0ef59b92")
+)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// sm2PublicKeyFromRaw returns the SM2 public key whose coordinates are raw,
+// X || Y.
+func sm2PublicKeyFromRaw(raw []byte) (*sm2.PublicKey, error) {
+	if len(raw) != 64 {
+		return nil, errors.New("SM2 public keys are 64 bytes long")
+	}
+	return &sm2.PublicKey{
+		Curve: sm2.GetSm2P256V1(),
+		X:     new(big.Int).SetBytes(raw[:32]),
+		Y:     new(big.Int).SetBytes(raw[32:]),
+	}, nil
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("Failed reading random bytes: %s", err)
+	}
+	return b
+}
+
+var sm3Cases = []Case{
+	{
+		Name:   "SM3/KnownAnswer",
+		Family: FamilySM3,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			for _, v := range sm3Vectors {
+				digest, err := csp.Hash(v.message, &bccsp.SM3Opts{})
+				if err != nil {
+					t.Fatalf("Hash failed: %s", err)
+				}
+				if !bytes.Equal(digest, v.digest) {
+					t.Errorf("Wrong SM3 digest of %q: got %x, expected %x", v.message, digest, v.digest)
+				}
+			}
+		},
+	},
+	{
+		Name:   "SM3/GetHash",
+		Family: FamilySM3,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			for _, v := range sm3Vectors {
+				h, err := csp.GetHash(&bccsp.SM3Opts{})
+				if err != nil {
+					t.Fatalf("GetHash failed: %s", err)
+				}
+				if h.Size() != 32 || h.BlockSize() != 64 {
+					t.Errorf("Wrong SM3 sizes: got %d and %d, expected 32 and 64", h.Size(), h.BlockSize())
+				}
+				// Written in two parts to exercise the buffering
+				h.Write(v.message[:1])
+				h.Write(v.message[1:])
+				if digest := h.Sum(nil); !bytes.Equal(digest, v.digest) {
+					t.Errorf("Wrong SM3 digest of %q: got %x, expected %x", v.message, digest, v.digest)
+				}
+			}
+		},
+	},
+	{
+		Name:   "SM3/CrossCheck",
+		Family: FamilySM3,
+		Run: func(t *testing.T, csp, reference bccsp.BCCSP) {
+			// Lengths around the padding boundaries
+			for _, n := range []int{0, 1, 55, 56, 63, 64, 65, 1000} {
+				msg := randomBytes(t, n)
+				digest, err := csp.Hash(msg, &bccsp.SM3Opts{})
+				if err != nil {
+					t.Fatalf("Hash of %d bytes failed: %s", n, err)
+				}
+				expected, err := reference.Hash(msg, &bccsp.SM3Opts{})
+				if err != nil {
+					t.Fatalf("Reference hash of %d bytes failed: %s", n, err)
+				}
+				if !bytes.Equal(digest, expected) {
+					t.Errorf("SM3 digest of %d bytes differs from the reference: got %x, expected %x", n, digest, expected)
+				}
+			}
+		},
+	},
+}
+
+var sm4Cases = []Case{
+	{
+		Name:   "SM4/KnownAnswer",
+		Family: FamilySM4,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			k, err := csp.KeyImport(sm4Key, &bccsp.SM4ImportKeyOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyImport failed: %s", err)
+			}
+			ciphertext, err := csp.Encrypt(k, sm4Plaintext, nil)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %s", err)
+			}
+			if !bytes.Equal(ciphertext, sm4Ciphertext) {
+				t.Errorf("Wrong SM4 ciphertext: got %x, expected %x", ciphertext, sm4Ciphertext)
+			}
+			plaintext, err := csp.Decrypt(k, sm4Ciphertext, nil)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %s", err)
+			}
+			if !bytes.Equal(plaintext, sm4Plaintext) {
+				t.Errorf("Wrong SM4 plaintext: got %x, expected %x", plaintext, sm4Plaintext)
+			}
+		},
+	},
+	{
+		Name:   "SM4/KeyGen",
+		Family: FamilySM4,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			k, err := csp.KeyGen(&bccsp.SM4KeyGenOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyGen failed: %s", err)
+			}
+			if !k.Symmetric() || !k.Private() || len(k.SKI()) == 0 {
+				t.Errorf("SM4 keys must be symmetric and private, and have an SKI")
+			}
+			block := randomBytes(t, 16)
+			ciphertext, err := csp.Encrypt(k, block, nil)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %s", err)
+			}
+			plaintext, err := csp.Decrypt(k, ciphertext, nil)
+			if err != nil {
+				t.Fatalf("Decrypt failed: %s", err)
+			}
+			if !bytes.Equal(plaintext, block) {
+				t.Errorf("Decrypted %x, expected %x", plaintext, block)
+			}
+		},
+	},
+	{
+		Name:   "SM4/CrossCheck",
+		Family: FamilySM4,
+		Run: func(t *testing.T, csp, reference bccsp.BCCSP) {
+			raw := randomBytes(t, 16)
+			k, err := csp.KeyImport(raw, &bccsp.SM4ImportKeyOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyImport failed: %s", err)
+			}
+			refKey, err := reference.KeyImport(raw, &bccsp.SM4ImportKeyOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("Reference KeyImport failed: %s", err)
+			}
+			if !bytes.Equal(k.SKI(), refKey.SKI()) {
+				t.Errorf("SKI of the SM4 key differs from the reference: got %x, expected %x", k.SKI(), refKey.SKI())
+			}
+
+			block := randomBytes(t, 16)
+			ciphertext, err := csp.Encrypt(k, block, nil)
+			if err != nil {
+				t.Fatalf("Encrypt failed: %s", err)
+			}
+			plaintext, err := reference.Decrypt(refKey, ciphertext, nil)
+			if err != nil {
+				t.Fatalf("Reference Decrypt failed: %s", err)
+			}
+			if !bytes.Equal(plaintext, block) {
+				t.Errorf("The reference decrypted %x, expected %x", plaintext, block)
+			}
+		},
+	},
+	{
+		Name:   "SM4/InvalidKey",
+		Family: FamilySM4,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			for _, n := range []int{0, 15, 17, 32} {
+				if _, err := csp.KeyImport(make([]byte, n), &bccsp.SM4ImportKeyOpts{Temporary: true}); err == nil {
+					t.Errorf("Importing an SM4 key of %d bytes must fail", n)
+				}
+			}
+		},
+	},
+}
+
+// importPublicKey imports the public key of k, a key of another provider,
+// into csp.
+func importPublicKey(t *testing.T, csp bccsp.BCCSP, k bccsp.Key) bccsp.Key {
+	pub, err := k.PublicKey()
+	if err != nil {
+		t.Fatalf("PublicKey failed: %s", err)
+	}
+
+	var raw interface{}
+	if cpk, ok := pub.(bccsp.CryptoPublicKeyer); ok {
+		if raw, err = cpk.CryptoPublicKey(); err != nil {
+			t.Fatalf("CryptoPublicKey failed: %s", err)
+		}
+	} else {
+		b, err := pub.Bytes()
+		if err != nil {
+			t.Fatalf("Bytes of the public key failed: %s", err)
+		}
+		if raw, err = sm2PublicKeyFromRaw(b); err != nil {
+			t.Fatalf("Bytes of the public key are not X || Y: %s", err)
+		}
+	}
+
+	imported, err := csp.KeyImport(raw, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		t.Fatalf("KeyImport of the public key failed: %s", err)
+	}
+	if !bytes.Equal(imported.SKI(), pub.SKI()) {
+		t.Errorf("SKI of the imported public key differs: got %x, expected %x", imported.SKI(), pub.SKI())
+	}
+	return imported
+}
+
+// verify fails t unless signature is valid for msg.
+func verify(t *testing.T, csp bccsp.BCCSP, k bccsp.Key, signature, msg []byte, opts bccsp.SignerOpts, who string) {
+	valid, err := csp.Verify(k, signature, msg, opts)
+	if err != nil {
+		t.Fatalf("%s failed verifying: %s", who, err)
+	}
+	if !valid {
+		t.Errorf("%s rejected a valid signature", who)
+	}
+}
+
+// reject fails t if signature is valid for msg. Providers may report an
+// invalid signature with an error.
+func reject(t *testing.T, csp bccsp.BCCSP, k bccsp.Key, signature, msg []byte, what string) {
+	if valid, _ := csp.Verify(k, signature, msg, nil); valid {
+		t.Errorf("%s accepted", what)
+	}
+}
+
+func altered(b []byte) []byte {
+	a := append([]byte{}, b...)
+	a[len(a)-1] ^= 0x01
+	return a
+}
+
+var sm2Cases = []Case{
+	{
+		Name:   "SM2/KnownSignature",
+		Family: FamilySM2,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			pub, err := sm2PublicKeyFromRaw(sm2PublicKey)
+			if err != nil {
+				t.Fatalf("Invalid vector: %s", err)
+			}
+			k, err := csp.KeyImport(pub, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyImport failed: %s", err)
+			}
+			if k.Private() || k.Symmetric() {
+				t.Errorf("Imported SM2 public keys must be neither private nor symmetric")
+			}
+
+			verify(t, csp, k, sm2Signature, sm2Message, nil, "Verify")
+			reject(t, csp, k, sm2Signature, altered(sm2Message), "Signature of another message")
+			reject(t, csp, k, altered(sm2Signature), sm2Message, "Altered signature")
+		},
+	},
+	{
+		Name:   "SM2/SignVerify",
+		Family: FamilySM2,
+		Run: func(t *testing.T, csp, reference bccsp.BCCSP) {
+			k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyGen failed: %s", err)
+			}
+			if !k.Private() || k.Symmetric() || len(k.SKI()) == 0 {
+				t.Errorf("SM2 private keys must be private and asymmetric, and have an SKI")
+			}
+			pub, err := k.PublicKey()
+			if err != nil {
+				t.Fatalf("PublicKey failed: %s", err)
+			}
+			if pub.Private() || !bytes.Equal(pub.SKI(), k.SKI()) {
+				t.Errorf("The public key of an SM2 key must not be private and must have the same SKI")
+			}
+
+			signature, err := csp.Sign(k, sm2Message, nil)
+			if err != nil {
+				t.Fatalf("Sign failed: %s", err)
+			}
+			verify(t, csp, k, signature, sm2Message, nil, "Verify with the private key")
+			verify(t, csp, pub, signature, sm2Message, nil, "Verify with the public key")
+			verify(t, reference, importPublicKey(t, reference, k), signature, sm2Message, nil, "The reference")
+			reject(t, csp, pub, signature, altered(sm2Message), "Signature of another message")
+		},
+	},
+	{
+		Name:   "SM2/VerifyReference",
+		Family: FamilySM2,
+		Run: func(t *testing.T, csp, reference bccsp.BCCSP) {
+			refKey, err := reference.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("Reference KeyGen failed: %s", err)
+			}
+			signature, err := reference.Sign(refKey, sm2Message, nil)
+			if err != nil {
+				t.Fatalf("Reference Sign failed: %s", err)
+			}
+
+			pub := importPublicKey(t, csp, refKey)
+			verify(t, csp, pub, signature, sm2Message, nil, "Verify")
+			reject(t, csp, pub, signature, altered(sm2Message), "Signature of another message")
+		},
+	},
+	{
+		Name:   "SM2/RawSignature",
+		Family: FamilySM2,
+		Run: func(t *testing.T, csp, reference bccsp.BCCSP) {
+			k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyGen failed: %s", err)
+			}
+			opts := &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureRaw}
+			signature, err := csp.Sign(k, sm2Message, opts)
+			if err != nil {
+				t.Fatalf("Sign failed: %s", err)
+			}
+			if len(signature) != 64 {
+				t.Fatalf("Raw SM2 signatures are r || s, got %d bytes", len(signature))
+			}
+			verify(t, reference, importPublicKey(t, reference, k), signature, sm2Message, opts, "The reference")
+		},
+	},
+	{
+		Name:   "SM2/Encrypt",
+		Family: FamilySM2,
+		Run: func(t *testing.T, csp, reference bccsp.BCCSP) {
+			plaintext := randomBytes(t, 100)
+
+			// Encrypted by csp, decrypted by the reference
+			refKey, err := reference.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("Reference KeyGen failed: %s", err)
+			}
+			ciphertext, err := csp.Encrypt(importPublicKey(t, csp, refKey), plaintext, &bccsp.SM2EncrypterOpts{})
+			if err != nil {
+				t.Fatalf("Encrypt failed: %s", err)
+			}
+			decrypted, err := reference.Decrypt(refKey, ciphertext, &bccsp.SM2EncrypterOpts{})
+			if err != nil {
+				t.Fatalf("Reference Decrypt failed: %s", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("The reference decrypted %x, expected %x", decrypted, plaintext)
+			}
+
+			// Encrypted by the reference, decrypted by csp
+			k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyGen failed: %s", err)
+			}
+			ciphertext, err = reference.Encrypt(importPublicKey(t, reference, k), plaintext, &bccsp.SM2EncrypterOpts{})
+			if err != nil {
+				t.Fatalf("Reference Encrypt failed: %s", err)
+			}
+			decrypted, err = csp.Decrypt(k, ciphertext, &bccsp.SM2EncrypterOpts{})
+			if err != nil {
+				t.Fatalf("Decrypt failed: %s", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Decrypted %x, expected %x", decrypted, plaintext)
+			}
+		},
+	},
+	{
+		Name:   "SM2/GetKey",
+		Family: FamilySM2,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+			if err != nil {
+				t.Fatalf("KeyGen failed: %s", err)
+			}
+			got, err := csp.GetKey(k.SKI())
+			if err != nil {
+				t.Fatalf("GetKey of a generated key failed: %s", err)
+			}
+			if !got.Private() || !bytes.Equal(got.SKI(), k.SKI()) {
+				t.Errorf("GetKey returned another key")
+			}
+		},
+	},
+}
+
+var errorCases = []Case{
+	{
+		Name:   "Errors/NilOpts",
+		Family: FamilyErrors,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			if _, err := csp.KeyGen(nil); err == nil {
+				t.Error("KeyGen with nil opts must fail")
+			}
+			if _, err := csp.KeyImport(sm4Key, nil); err == nil {
+				t.Error("KeyImport with nil opts must fail")
+			}
+			if _, err := csp.Hash(sm2Message, nil); err == nil {
+				t.Error("Hash with nil opts must fail")
+			}
+			if _, err := csp.GetHash(nil); err == nil {
+				t.Error("GetHash with nil opts must fail")
+			}
+		},
+	},
+	{
+		Name:   "Errors/NilKey",
+		Family: FamilyErrors,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			if _, err := csp.Sign(nil, sm2Message, nil); err == nil {
+				t.Error("Sign with a nil key must fail")
+			}
+			if _, err := csp.Verify(nil, sm2Signature, sm2Message, nil); err == nil {
+				t.Error("Verify with a nil key must fail")
+			}
+			if _, err := csp.Encrypt(nil, sm4Plaintext, nil); err == nil {
+				t.Error("Encrypt with a nil key must fail")
+			}
+			if _, err := csp.Decrypt(nil, sm4Ciphertext, nil); err == nil {
+				t.Error("Decrypt with a nil key must fail")
+			}
+			if _, err := csp.KeyDeriv(nil, &bccsp.SM2ReRandKeyOpts{Temporary: true}); err == nil {
+				t.Error("KeyDeriv with a nil key must fail")
+			}
+		},
+	},
+	{
+		Name:   "Errors/EmptyInput",
+		Family: FamilyErrors,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyGen failed: %s", err)
+			}
+			if _, err := csp.Sign(k, nil, nil); err == nil {
+				t.Error("Sign of an empty message must fail")
+			}
+			if _, err := csp.Verify(k, nil, sm2Message, nil); err == nil {
+				t.Error("Verify of an empty signature must fail")
+			}
+			if _, err := csp.Verify(k, sm2Signature, nil, nil); err == nil {
+				t.Error("Verify of an empty message must fail")
+			}
+		},
+	},
+	{
+		Name:   "Errors/SignWithPublicKey",
+		Family: FamilyErrors,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			pub, err := sm2PublicKeyFromRaw(sm2PublicKey)
+			if err != nil {
+				t.Fatalf("Invalid vector: %s", err)
+			}
+			k, err := csp.KeyImport(pub, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+			if err != nil {
+				t.Fatalf("KeyImport failed: %s", err)
+			}
+			if _, err := csp.Sign(k, sm2Message, nil); err == nil {
+				t.Error("Sign with a public key must fail")
+			}
+		},
+	},
+	{
+		Name:   "Errors/KeyNotFound",
+		Family: FamilyErrors,
+		Run: func(t *testing.T, csp, _ bccsp.BCCSP) {
+			_, err := csp.GetKey(randomBytes(t, 32))
+			if err == nil {
+				t.Fatal("GetKey of an unknown SKI must fail")
+			}
+			if !errors.Is(err, bccsp.ErrKeyNotFound) {
+				t.Errorf("GetKey of an unknown SKI must report bccsp.ErrCodeKeyNotFound, got [%s]", err)
+			}
+		},
+	},
+}
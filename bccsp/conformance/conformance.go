@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package conformance is a suite of tests any BCCSP implementation can run
+// from its own tests, so that third-party GM providers can be checked
+// against what the rest of this fork expects from a provider: the
+// published SM2, SM3 and SM4 vectors, results interoperable with the
+// software provider and errors for invalid arguments.
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, newVendorCSP(t), conformance.Options{})
+//	}
+package conformance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+)
+
+// Families of the cases.
+const (
+	FamilySM2    = bccsp.SM2
+	FamilySM3    = bccsp.SM3
+	FamilySM4    = bccsp.SM4
+	FamilyErrors = "ERRORS"
+)
+
+// Case is a conformance test of a provider.
+type Case struct {
+	// Name of the case, e.g. SM3/KnownAnswer, also the name of its subtest
+	Name string
+	// Family is the algorithm family the case exercises
+	Family string
+	// Run runs the case against csp, whose results are cross-checked
+	// against those of reference
+	Run func(t *testing.T, csp, reference bccsp.BCCSP)
+}
+
+// Options selects the cases run against a provider.
+type Options struct {
+	// Families restricts the cases to those of the families, all cases are
+	// run when empty
+	Families []string
+	// Skip lists the names of the cases not run, e.g. SM2/GetKey for
+	// providers without a key store
+	Skip []string
+	// Reference is the provider results are cross-checked against, the
+	// software provider when nil
+	Reference bccsp.BCCSP
+}
+
+// Cases returns all the cases of the suite.
+func Cases() []Case {
+	var cases []Case
+	cases = append(cases, sm3Cases...)
+	cases = append(cases, sm4Cases...)
+	cases = append(cases, sm2Cases...)
+	cases = append(cases, errorCases...)
+	return cases
+}
+
+// Run runs the cases selected by opts against csp, each in a subtest.
+func Run(t *testing.T, csp bccsp.BCCSP, opts Options) {
+	if csp == nil {
+		t.Fatal("Invalid BCCSP instance. It must be different from nil")
+	}
+
+	reference := opts.Reference
+	if reference == nil {
+		var err error
+		reference, err = sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+		if err != nil {
+			t.Fatalf("Failed initializing reference SW BCCSP: %s", err)
+		}
+	}
+
+	for _, c := range Cases() {
+		c := c
+		if !selected(c, opts) {
+			continue
+		}
+		t.Run(c.Name, func(t *testing.T) {
+			c.Run(t, csp, reference)
+		})
+	}
+}
+
+func selected(c Case, opts Options) bool {
+	for _, name := range opts.Skip {
+		if strings.EqualFold(name, c.Name) {
+			return false
+		}
+	}
+	if len(opts.Families) == 0 {
+		return true
+	}
+	for _, family := range opts.Families {
+		if strings.EqualFold(family, c.Family) {
+			return true
+		}
+	}
+	return false
+}
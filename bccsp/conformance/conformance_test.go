@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package conformance
+
+import (
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftwareProvider(t *testing.T) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+
+	Run(t, csp, Options{})
+}
+
+func TestCaseNames(t *testing.T) {
+	names := map[string]bool{}
+	for _, c := range Cases() {
+		assert.False(t, names[c.Name], "duplicate case %s", c.Name)
+		names[c.Name] = true
+		assert.Contains(t, []string{FamilySM2, FamilySM3, FamilySM4, FamilyErrors}, c.Family)
+		assert.NotNil(t, c.Run)
+	}
+}
+
+func TestSelected(t *testing.T) {
+	c := Case{Name: "SM2/GetKey", Family: FamilySM2}
+
+	assert.True(t, selected(c, Options{}))
+	assert.True(t, selected(c, Options{Families: []string{"sm2"}}))
+	assert.False(t, selected(c, Options{Families: []string{FamilySM3, FamilySM4}}))
+	assert.False(t, selected(c, Options{Skip: []string{"sm2/getkey"}}))
+}
@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import "context"
+
+// ContextSigner is implemented by a BCCSP whose signing operation can
+// honor the cancellation and deadline of a context, e.g. a remote or
+// HSM-backed provider.
+type ContextSigner interface {
+	// SignCtx signs digest using key k, as Sign does. Providers should
+	// abort and return ctx.Err() once ctx is done.
+	SignCtx(ctx context.Context, k Key, digest []byte, opts SignerOpts) (signature []byte, err error)
+}
+
+// SignRequestMetadata describes the request a signature is produced for.
+// It is carried by the context passed to SignCtx so that audit hooks can
+// attribute each signature.
+type SignRequestMetadata struct {
+	ChannelID string
+	TxID      string
+	// Purpose labels what the signature is for, e.g. endorsement or block
+	Purpose    string
+	Attributes map[string]string
+}
+
+type signRequestMetadataKey struct{}
+
+// WithSignRequestMetadata returns a copy of ctx carrying md.
+func WithSignRequestMetadata(ctx context.Context, md *SignRequestMetadata) context.Context {
+	return context.WithValue(ctx, signRequestMetadataKey{}, md)
+}
+
+// SignRequestMetadataFromContext returns the metadata attached to ctx, if any.
+func SignRequestMetadataFromContext(ctx context.Context) (*SignRequestMetadata, bool) {
+	md, ok := ctx.Value(signRequestMetadataKey{}).(*SignRequestMetadata)
+	return md, ok && md != nil
+}
+
+// SignCtx signs digest using csp. If csp implements ContextSigner the
+// context is handed to the provider, otherwise it is only checked before
+// the call is made.
+func SignCtx(ctx context.Context, csp BCCSP, k Key, digest []byte, opts SignerOpts) ([]byte, error) {
+	if cs, ok := csp.(ContextSigner); ok {
+		return cs.SignCtx(ctx, k, digest, opts)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return csp.Sign(k, digest, opts)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dualcontrol
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/pkg/errors"
+)
+
+// DualControlOpts configures the approval of private key operations by a
+// second person.
+type DualControlOpts struct {
+	// Enabled turns dual control on
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+	// Keys are the hex encoded SKIs of the keys requiring approval. All
+	// private keys require it when empty.
+	Keys []string `mapstructure:"keys,omitempty" json:"keys,omitempty" yaml:"Keys"`
+	// Operations are the operations requiring approval, e.g. sign or an
+	// operation set by the caller through ApprovalSignerOpts. All
+	// operations require it when empty.
+	Operations []string `mapstructure:"operations,omitempty" json:"operations,omitempty" yaml:"Operations"`
+	// URL of the approval service
+	URL string `mapstructure:"url,omitempty" json:"url,omitempty" yaml:"URL"`
+	// RootCertFiles, CertFile and KeyFile configure the TLS connection to
+	// the approval service
+	RootCertFiles []string `mapstructure:"rootcertfiles,omitempty" json:"rootcertfiles,omitempty" yaml:"RootCertFiles"`
+	CertFile      string   `mapstructure:"certfile,omitempty" json:"certfile,omitempty" yaml:"CertFile"`
+	KeyFile       string   `mapstructure:"keyfile,omitempty" json:"keyfile,omitempty" yaml:"KeyFile"`
+	// Approvers are the PEM files of the public keys allowed to approve
+	Approvers []string `mapstructure:"approvers,omitempty" json:"approvers,omitempty" yaml:"Approvers"`
+	// Timeout bounds the wait for an approval
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty" yaml:"Timeout"`
+}
+
+// NewFromOpts returns the BCCSP of New, requesting approvals from the
+// HTTP approval service and trusting the approvers configured in opts.
+func NewFromOpts(csp bccsp.BCCSP, opts DualControlOpts) (bccsp.BCCSP, error) {
+	if opts.URL == "" {
+		return nil, errors.New("approval service URL is required")
+	}
+
+	approvers, err := loadApprovers(opts.Approvers)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := clientTLSConfig(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed configuring TLS to the approval service")
+	}
+
+	return New(csp, NewHTTPTransport(opts.URL, tlsConfig), approvers, opts)
+}
+
+func loadApprovers(files []string) ([]interface{}, error) {
+	var approvers []interface{}
+	for _, f := range files {
+		raw, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed reading approver key %s", f)
+		}
+		pub, err := utils.PEMtoPublicKey(raw, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed parsing approver key %s", f)
+		}
+		approvers = append(approvers, pub)
+	}
+	return approvers, nil
+}
+
+// clientTLSConfig returns the TLS configuration of the connection to the
+// approval service, nil for plain HTTP.
+func clientTLSConfig(opts DualControlOpts) (*tls.Config, error) {
+	if len(opts.RootCertFiles) == 0 {
+		return nil, nil
+	}
+
+	roots := x509.NewCertPool()
+	for _, f := range opts.RootCertFiles {
+		pem, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in %s", f)
+		}
+	}
+
+	config := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
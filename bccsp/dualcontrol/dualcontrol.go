@@ -0,0 +1,241 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dualcontrol
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_dualcontrol")
+
+const defaultTimeout = 5 * time.Minute
+
+// Operations subject to approval.
+const (
+	OperationSign    = "sign"
+	OperationDecrypt = "decrypt"
+)
+
+// ApprovalSignerOpts names the operation a signature is requested for, so
+// that approvers know what they approve and DualControlOpts.Operations can
+// select it, e.g. "orderer-config".
+type ApprovalSignerOpts struct {
+	bccsp.SignerOpts
+	Operation string
+}
+
+// New returns a BCCSP that asks for approval through transport before
+// using the designated private keys of csp, typically an HSM or SDF
+// device provider, to sign or decrypt. The device is not invoked unless
+// an approval signed by one of approvers was received. Approvers are
+// *sm2.PublicKey or *ecdsa.PublicKey.
+func New(csp bccsp.BCCSP, transport Transport, approvers []interface{}, opts DualControlOpts) (bccsp.BCCSP, error) {
+	if csp == nil || transport == nil {
+		return nil, errors.New("Invalid arguments. BCCSP and transport must be different from nil")
+	}
+	if len(approvers) == 0 {
+		return nil, errors.New("Invalid arguments. At least one approver is required")
+	}
+
+	verifier, err := sw.NewWithParams(256, "SHA2", sw.NewDummyKeyStore())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed initializing approval verifier")
+	}
+
+	dc := &impl{
+		BCCSP:      csp,
+		transport:  transport,
+		verifier:   verifier,
+		approvers:  map[string]approver{},
+		keys:       map[string]bool{},
+		operations: map[string]bool{},
+		timeout:    opts.Timeout,
+		now:        time.Now,
+	}
+	if dc.timeout <= 0 {
+		dc.timeout = defaultTimeout
+	}
+
+	for _, pub := range approvers {
+		var a approver
+		switch pub := pub.(type) {
+		case *sm2.PublicKey:
+			a.sm2 = true
+			a.key, err = verifier.KeyImport(pub, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+		case *ecdsa.PublicKey:
+			a.key, err = verifier.KeyImport(pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+		default:
+			return nil, errors.Errorf("Unsupported approver key type [%T]", pub)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed importing approver key")
+		}
+		dc.approvers[hex.EncodeToString(a.key.SKI())] = a
+	}
+
+	for _, ski := range opts.Keys {
+		raw, err := hex.DecodeString(ski)
+		if err != nil || len(raw) == 0 {
+			return nil, errors.Errorf("Invalid SKI [%s]", ski)
+		}
+		dc.keys[hex.EncodeToString(raw)] = true
+	}
+	for _, op := range opts.Operations {
+		dc.operations[op] = true
+	}
+
+	return dc, nil
+}
+
+// approver is a public key allowed to approve requests.
+type approver struct {
+	key bccsp.Key
+	sm2 bool
+}
+
+type impl struct {
+	// BCCSP serves all operations that are not overridden
+	bccsp.BCCSP
+
+	transport Transport
+	verifier  bccsp.BCCSP
+	// approvers maps the hex encoded SKI to the approver keys
+	approvers  map[string]approver
+	keys       map[string]bool
+	operations map[string]bool
+	timeout    time.Duration
+	now        func() time.Time
+}
+
+// designated reports whether op with k requires approval.
+func (dc *impl) designated(k bccsp.Key, op string) bool {
+	if !k.Private() || k.Symmetric() {
+		return false
+	}
+	if len(dc.keys) != 0 && !dc.keys[hex.EncodeToString(k.SKI())] {
+		return false
+	}
+	return len(dc.operations) == 0 || dc.operations[op]
+}
+
+// approve requests and checks the approval of op on digest with k.
+func (dc *impl) approve(k bccsp.Key, op string, digest []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "Failed generating request ID")
+	}
+	req := &Request{
+		ID:        hex.EncodeToString(nonce),
+		Provider:  keyProvider(k),
+		Operation: op,
+		SKI:       k.SKI(),
+		Digest:    digest,
+		Time:      dc.now(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dc.timeout)
+	defer cancel()
+
+	logger.Infof("Requesting approval [%s] of %s with key %x", req.ID, op, req.SKI)
+	approval, err := dc.transport.RequestApproval(ctx, req)
+	if err != nil {
+		return errors.Wrapf(err, "Approval [%s] of %s with key %x failed", req.ID, op, req.SKI)
+	}
+	if err := dc.checkApproval(req, approval); err != nil {
+		return errors.Wrapf(err, "Approval [%s] of %s with key %x rejected", req.ID, op, req.SKI)
+	}
+	logger.Infof("Request [%s] approved by %s", req.ID, approval.Approver)
+	return nil
+}
+
+// keyProvider returns the name of the provider holding k, if known.
+func keyProvider(k bccsp.Key) string {
+	if ak, ok := k.(bccsp.AttributedKey); ok {
+		return ak.KeyAttributes()[bccsp.KeyAttrProvider]
+	}
+	return ""
+}
+
+// checkApproval verifies the signature of approval over req.
+func (dc *impl) checkApproval(req *Request, approval *Approval) error {
+	if approval == nil || len(approval.Signature) == 0 {
+		return errors.New("approval is empty")
+	}
+	a, ok := dc.approvers[approval.Approver]
+	if !ok {
+		return errors.Errorf("unknown approver [%s]", approval.Approver)
+	}
+
+	// SM2 signatures are verified over the message, ECDSA over its digest
+	msg := req.Bytes()
+	if !a.sm2 {
+		h := sha256.Sum256(msg)
+		msg = h[:]
+	}
+	valid, err := dc.verifier.Verify(a.key, approval.Signature, msg, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed verifying approval signature")
+	}
+	if !valid {
+		return errors.Errorf("invalid signature of approver [%s]", approval.Approver)
+	}
+	return nil
+}
+
+// Unwrap returns the BCCSP whose operations require approval.
+func (dc *impl) Unwrap() bccsp.BCCSP {
+	return dc.BCCSP
+}
+
+// Sign signs digest using key k once the signature was approved.
+// The opts argument should be appropriate for the primitive used.
+func (dc *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+
+	op := OperationSign
+	if o, ok := opts.(*ApprovalSignerOpts); ok {
+		if o.Operation != "" {
+			op = o.Operation
+		}
+		opts = o.SignerOpts
+	}
+	if dc.designated(k, op) {
+		if err := dc.approve(k, op, digest); err != nil {
+			return nil, err
+		}
+	}
+	return dc.BCCSP.Sign(k, digest, opts)
+}
+
+// Decrypt decrypts ciphertext using key k once the decryption was
+// approved. The opts argument should be appropriate for the algorithm used.
+func (dc *impl) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+
+	if dc.designated(k, OperationDecrypt) {
+		h := sha256.Sum256(ciphertext)
+		if err := dc.approve(k, OperationDecrypt, h[:]); err != nil {
+			return nil, err
+		}
+	}
+	return dc.BCCSP.Decrypt(k, ciphertext, opts)
+}
@@ -0,0 +1,246 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dualcontrol
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// device counts the signatures reaching the wrapped BCCSP.
+type device struct {
+	bccsp.BCCSP
+	signs int
+}
+
+func (d *device) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	d.signs++
+	return d.BCCSP.Sign(k, digest, opts)
+}
+
+// approverFunc is a Transport calling a function.
+type approverFunc func(req *Request) (*Approval, error)
+
+func (f approverFunc) RequestApproval(ctx context.Context, req *Request) (*Approval, error) {
+	return f(req)
+}
+
+func newDevice(t *testing.T) *device {
+	csp, err := sw.NewWithParams(256, "SHA2", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	return &device{BCCSP: csp}
+}
+
+func sm2Approver(t *testing.T) (*sm2.PrivateKey, string) {
+	priv, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	csp, err := sw.NewWithParams(256, "SHA2", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	k, err := csp.KeyImport(&priv.PublicKey, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	return priv, hex.EncodeToString(k.SKI())
+}
+
+func TestNew(t *testing.T) {
+	d := newDevice(t)
+	priv, _ := sm2Approver(t)
+	transport := approverFunc(func(*Request) (*Approval, error) { return nil, nil })
+
+	_, err := New(nil, transport, []interface{}{&priv.PublicKey}, DualControlOpts{})
+	assert.Error(t, err)
+	_, err = New(d, transport, nil, DualControlOpts{})
+	assert.EqualError(t, err, "Invalid arguments. At least one approver is required")
+	_, err = New(d, transport, []interface{}{"key"}, DualControlOpts{})
+	assert.EqualError(t, err, "Unsupported approver key type [string]")
+	_, err = New(d, transport, []interface{}{&priv.PublicKey}, DualControlOpts{Keys: []string{"zz"}})
+	assert.EqualError(t, err, "Invalid SKI [zz]")
+}
+
+func TestSignRequiresApproval(t *testing.T) {
+	d := newDevice(t)
+	priv, approverSKI := sm2Approver(t)
+
+	var requests []*Request
+	var deny bool
+	transport := approverFunc(func(req *Request) (*Approval, error) {
+		requests = append(requests, req)
+		if deny {
+			return nil, errors.New("denied")
+		}
+		sig, err := sm2.Sign(priv, nil, req.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		return &Approval{Approver: approverSKI, Signature: sig}, nil
+	})
+
+	csp, err := New(d, transport, []interface{}{&priv.PublicKey}, DualControlOpts{})
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte("config update"))
+
+	sig, err := csp.Sign(k, digest[:], &ApprovalSignerOpts{Operation: "orderer-config"})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "orderer-config", requests[0].Operation)
+	assert.Equal(t, "SW", requests[0].Provider)
+	assert.Equal(t, k.SKI(), requests[0].SKI)
+	assert.Equal(t, digest[:], requests[0].Digest)
+	assert.Equal(t, 1, d.signs)
+
+	pk, err := k.PublicKey()
+	require.NoError(t, err)
+	valid, err := csp.Verify(pk, sig, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	deny = true
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "denied")
+	assert.Len(t, requests, 2)
+	assert.Equal(t, OperationSign, requests[1].Operation)
+	assert.Equal(t, 1, d.signs, "the device must not be invoked without approval")
+}
+
+func TestRejectedApprovals(t *testing.T) {
+	d := newDevice(t)
+	priv, approverSKI := sm2Approver(t)
+	other, otherSKI := sm2Approver(t)
+
+	var approval func(req *Request) *Approval
+	transport := approverFunc(func(req *Request) (*Approval, error) { return approval(req), nil })
+
+	csp, err := New(d, transport, []interface{}{&priv.PublicKey}, DualControlOpts{})
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	for name, f := range map[string]func(req *Request) *Approval{
+		"empty": func(*Request) *Approval { return nil },
+		"unknown approver": func(req *Request) *Approval {
+			sig, _ := sm2.Sign(other, nil, req.Bytes())
+			return &Approval{Approver: otherSKI, Signature: sig}
+		},
+		"wrong signer": func(req *Request) *Approval {
+			sig, _ := sm2.Sign(other, nil, req.Bytes())
+			return &Approval{Approver: approverSKI, Signature: sig}
+		},
+		"other request": func(req *Request) *Approval {
+			replay := *req
+			replay.ID = "replayed"
+			sig, _ := sm2.Sign(priv, nil, replay.Bytes())
+			return &Approval{Approver: approverSKI, Signature: sig}
+		},
+	} {
+		approval = f
+		_, err := csp.Sign(k, []byte("message"), nil)
+		assert.Error(t, err, name)
+	}
+	assert.Equal(t, 0, d.signs)
+}
+
+func TestDesignatedKeysAndOperations(t *testing.T) {
+	d := newDevice(t)
+	approverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	requests := 0
+	var approver string
+	transport := approverFunc(func(req *Request) (*Approval, error) {
+		requests++
+		h := sha256.Sum256(req.Bytes())
+		r, s, err := ecdsa.Sign(rand.Reader, approverKey, h[:])
+		if err != nil {
+			return nil, err
+		}
+		s, _ = utils.ToLowS(&approverKey.PublicKey, s)
+		sig, err := utils.MarshalECDSASignature(r, s)
+		return &Approval{Approver: approver, Signature: sig}, err
+	})
+
+	designated, err := d.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	other, err := d.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	csp, err := New(d, transport, []interface{}{&approverKey.PublicKey}, DualControlOpts{
+		Keys:       []string{hex.EncodeToString(designated.SKI())},
+		Operations: []string{"orderer-config"},
+	})
+	require.NoError(t, err)
+	approverSKI, err := d.KeyImport(&approverKey.PublicKey, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	approver = hex.EncodeToString(approverSKI.SKI())
+
+	digest := sha256.Sum256([]byte("block"))
+	_, err = csp.Sign(other, digest[:], &ApprovalSignerOpts{Operation: "orderer-config"})
+	assert.NoError(t, err)
+	_, err = csp.Sign(designated, digest[:], nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, requests)
+
+	_, err = csp.Sign(designated, digest[:], &ApprovalSignerOpts{Operation: "orderer-config"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 3, d.signs)
+}
+
+func TestHTTPTransport(t *testing.T) {
+	priv, approverSKI := sm2Approver(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &Request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Operation != OperationSign {
+			http.Error(w, "operation not allowed", http.StatusForbidden)
+			return
+		}
+		sig, err := sm2.Sign(priv, nil, req.Bytes())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(&Approval{Approver: approverSKI, Signature: sig})
+	}))
+	defer server.Close()
+
+	d := newDevice(t)
+	csp, err := New(d, NewHTTPTransport(server.URL, nil), []interface{}{&priv.PublicKey}, DualControlOpts{})
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	_, err = csp.Sign(k, []byte("message"), nil)
+	assert.NoError(t, err)
+
+	_, err = csp.Sign(k, []byte("message"), &ApprovalSignerOpts{Operation: "orderer-config"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "denied: operation not allowed")
+	assert.Equal(t, 1, d.signs)
+}
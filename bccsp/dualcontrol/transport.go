@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dualcontrol
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Request describes a private key operation waiting for approval.
+type Request struct {
+	// ID is a random identifier, making every request unique
+	ID        string    `json:"id"`
+	Provider  string    `json:"provider"`
+	Operation string    `json:"operation"`
+	SKI       []byte    `json:"ski"`
+	Digest    []byte    `json:"digest"`
+	Time      time.Time `json:"time"`
+}
+
+// Bytes returns the encoding of the request signed by approvers.
+func (r *Request) Bytes() []byte {
+	return []byte(fmt.Sprintf("fabric-dual-control-v1\n%s\n%s\n%s\n%s\n%s\n%s\n",
+		r.ID, r.Provider, r.Operation, hex.EncodeToString(r.SKI), hex.EncodeToString(r.Digest),
+		r.Time.UTC().Format(time.RFC3339Nano)))
+}
+
+// Approval is the answer of an approver to a Request. Signature is the
+// approver's signature of Request.Bytes, DER encoded: SM2 over the bytes,
+// ECDSA over their SHA-256 digest.
+type Approval struct {
+	// Approver is the hex encoded SKI of the approver's public key
+	Approver  string `json:"approver"`
+	Signature []byte `json:"signature"`
+}
+
+// Transport carries approval requests to the approvers and returns the
+// approval, or an error if the request was denied or timed out.
+type Transport interface {
+	RequestApproval(ctx context.Context, req *Request) (*Approval, error)
+}
+
+// NewHTTPTransport returns a Transport posting requests as JSON to url,
+// which answers with an Approval once a second person approved, or with
+// 403 Forbidden if the request was denied.
+func NewHTTPTransport(url string, tlsConfig *tls.Config) Transport {
+	return &httpTransport{
+		url:    url,
+		client: &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}},
+	}
+}
+
+type httpTransport struct {
+	url    string
+	client *http.Client
+}
+
+func (t *httpTransport) RequestApproval(ctx context.Context, req *Request) (*Approval, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(httpReq.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed requesting approval")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed reading approval")
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden:
+		return nil, errors.Errorf("request %s denied: %s", req.ID, bytes.TrimSpace(respBody))
+	default:
+		return nil, errors.Errorf("approval service returned %s", resp.Status)
+	}
+
+	approval := &Approval{}
+	if err := json.Unmarshal(respBody, approval); err != nil {
+		return nil, errors.Wrap(err, "Failed decoding approval")
+	}
+	return approval, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode classifies the errors returned by BCCSP providers and key stores.
+type ErrorCode int
+
+const (
+	// ErrCodeUnknown is reported for errors not produced by this package.
+	ErrCodeUnknown ErrorCode = iota
+	// ErrCodeInvalidArgument is reported for nil or malformed arguments.
+	ErrCodeInvalidArgument
+	// ErrCodeUnsupportedAlgorithm is reported when no implementation is
+	// registered for the requested options or key.
+	ErrCodeUnsupportedAlgorithm
+	// ErrCodeUnsupportedKeyType is reported when a key store cannot handle a key type.
+	ErrCodeUnsupportedKeyType
+	// ErrCodeKeyNotFound is reported when no key matches the requested SKI.
+	ErrCodeKeyNotFound
+	// ErrCodeKeyAlreadyExists is reported when storing a key whose SKI is already present.
+	ErrCodeKeyAlreadyExists
+	// ErrCodeReadOnlyKeyStore is reported when storing a key into a read only key store.
+	ErrCodeReadOnlyKeyStore
+	// ErrCodeKeyNotExportable is reported when the private material of a
+	// key marked as non-exportable would have to leave its provider.
+	ErrCodeKeyNotExportable
+	// ErrCodeUnavailable is reported when a provider sheds an operation
+	// because its device is overloaded, too slow or failing.
+	ErrCodeUnavailable
+)
+
+// Error is a BCCSP error carrying an ErrorCode. Callers should branch on it
+// with errors.Is against the ErrXXX values below, or with ErrorCodeOf,
+// rather than matching on the message.
+type Error struct {
+	Code     ErrorCode
+	ErrorMsg string
+	Cause    error
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s", e.ErrorMsg, e.Cause)
+	}
+
+	return e.ErrorMsg
+}
+
+// Unwrap returns the cause of this error, if any.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is a BCCSP error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrInvalidArgument      = &Error{Code: ErrCodeInvalidArgument, ErrorMsg: "invalid argument"}
+	ErrUnsupportedAlgorithm = &Error{Code: ErrCodeUnsupportedAlgorithm, ErrorMsg: "unsupported algorithm"}
+	ErrUnsupportedKeyType   = &Error{Code: ErrCodeUnsupportedKeyType, ErrorMsg: "unsupported key type"}
+	ErrKeyNotFound          = &Error{Code: ErrCodeKeyNotFound, ErrorMsg: "key not found"}
+	ErrKeyAlreadyExists     = &Error{Code: ErrCodeKeyAlreadyExists, ErrorMsg: "key already exists"}
+	ErrReadOnlyKeyStore     = &Error{Code: ErrCodeReadOnlyKeyStore, ErrorMsg: "read only KeyStore"}
+	ErrKeyNotExportable     = &Error{Code: ErrCodeKeyNotExportable, ErrorMsg: "key not exportable"}
+	ErrUnavailable          = &Error{Code: ErrCodeUnavailable, ErrorMsg: "provider unavailable"}
+)
+
+// IsNonExportable reports whether k is a private key whose attributes mark
+// its private material as non-exportable.
+func IsNonExportable(k Key) bool {
+	ak, ok := k.(AttributedKey)
+	return ok && k.Private() && ak.KeyAttributes()[KeyAttrExportable] == "false"
+}
+
+// Errorf returns an error with the given code and formatted message.
+func Errorf(code ErrorCode, format string, args ...interface{}) error {
+	return &Error{Code: code, ErrorMsg: fmt.Sprintf(format, args...)}
+}
+
+// ErrorCodeOf returns the code of the first BCCSP error in the chain of err,
+// or ErrCodeUnknown.
+func ErrorCodeOf(err error) ErrorCode {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return ErrCodeUnknown
+}
@@ -0,0 +1,39 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bccsp
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	err := Errorf(ErrCodeKeyNotFound, "no key found for ski %x", []byte{1, 2})
+	assert.EqualError(t, err, "no key found for ski 0102")
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+	assert.False(t, errors.Is(err, ErrReadOnlyKeyStore))
+	assert.Equal(t, ErrCodeKeyNotFound, ErrorCodeOf(err))
+
+	// The code survives wrapping
+	wrapped := pkgerrors.Wrapf(err, "Failed getting key for SKI [%v]", []byte{1, 2})
+	assert.True(t, errors.Is(wrapped, ErrKeyNotFound))
+	assert.Equal(t, ErrCodeKeyNotFound, ErrorCodeOf(wrapped))
+	wrapped = fmt.Errorf("outer: %w", wrapped)
+	assert.True(t, errors.Is(wrapped, ErrKeyNotFound))
+
+	assert.Equal(t, ErrCodeUnknown, ErrorCodeOf(errors.New("other")))
+
+	cause := errors.New("cause")
+	err = &Error{Code: ErrCodeReadOnlyKeyStore, ErrorMsg: "cannot store", Cause: cause}
+	assert.EqualError(t, err, "cannot store: cause")
+	assert.True(t, errors.Is(err, cause))
+	assert.True(t, errors.Is(err, ErrReadOnlyKeyStore))
+}
@@ -11,6 +11,14 @@ import (
 
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/audit"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/breaker"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/dualcontrol"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/fallback"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/instrumented"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/selftest"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/verifycache"
 	"github.com/pkg/errors"
 )
 
@@ -24,6 +32,27 @@ var (
 	bootBCCSP         bccsp.BCCSP
 	bootBCCSPInitOnce sync.Once
 
+	// selfTester runs the self-tests of the default BCCSP, if enabled
+	selfTester *selftest.Tester
+
+	// auditTrail records the signatures of the default BCCSP, if enabled
+	auditTrail *audit.CSP
+
+	// verifyCache caches the verifications of the default BCCSP, if enabled
+	verifyCache *verifycache.Cache
+
+	// operationMetrics reports the operations of the default BCCSP, if enabled
+	operationMetrics *instrumented.CSP
+
+	// circuitBreakers protect the callers of the hardware providers created
+	// by the factory, if enabled
+	circuitBreakersMutex sync.Mutex
+	circuitBreakers      []*breaker.CSP
+
+	// keyStoreReady is closed once the key store of the default BCCSP has
+	// indexed its keys, nil if it does not index them
+	keyStoreReady <-chan struct{}
+
 	logger = flogging.MustGetLogger("bccsp")
 )
 
@@ -54,11 +83,193 @@ func GetDefault() bccsp.BCCSP {
 	return defaultBCCSP
 }
 
+// GetSelfTest returns the self-tests of the default BCCSP, nil unless
+// they are enabled. It is a healthz.HealthChecker.
+func GetSelfTest() *selftest.Tester {
+	return selfTester
+}
+
+// GetAudit returns the audit trail of the signatures of the default BCCSP,
+// nil unless it is enabled.
+func GetAudit() *audit.CSP {
+	return auditTrail
+}
+
+// GetVerifyCache returns the verification cache of the default BCCSP, nil
+// unless it is enabled.
+func GetVerifyCache() *verifycache.Cache {
+	return verifyCache
+}
+
+// GetMetrics returns the operation metrics of the default BCCSP, nil unless
+// they are enabled.
+func GetMetrics() *instrumented.CSP {
+	return operationMetrics
+}
+
+// GetBreakers returns the circuit breakers of the hardware providers
+// created by the factory, those routed to by the default BCCSP included.
+func GetBreakers() []*breaker.CSP {
+	circuitBreakersMutex.Lock()
+	defer circuitBreakersMutex.Unlock()
+	return append([]*breaker.CSP(nil), circuitBreakers...)
+}
+
+// KeyStoreReady returns a channel closed once the key store of the default
+// BCCSP has indexed its keys. Keys are served before, more slowly, so that
+// waiting on it is only needed by the nodes which must not serve requests
+// until lookups are fast.
+func KeyStoreReady() <-chan struct{} {
+	if keyStoreReady == nil {
+		ready := make(chan struct{})
+		close(ready)
+		return ready
+	}
+	return keyStoreReady
+}
+
 func initBCCSP(f BCCSPFactory, config *FactoryOpts) (bccsp.BCCSP, error) {
 	csp, err := f.Get(config)
 	if err != nil {
 		return nil, errors.Errorf("Could not initialize BCCSP %s [%s]", f.Name(), err)
 	}
 
+	if r, ok := csp.(interface{ KeyStoreReady() <-chan struct{} }); ok {
+		keyStoreReady = r.KeyStoreReady()
+	}
 	return csp, nil
 }
+
+// withFallback wraps csp with a software verifier when the fallback is
+// enabled for a hardware provider. The verifier has no key store, so
+// it can never serve private keys.
+func withFallback(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config.FallbackOpts == nil || !config.FallbackOpts.Enabled || isSoftware(config.ProviderName) {
+		return csp, nil
+	}
+
+	swOpts := config.SwOpts
+	if swOpts == nil {
+		swOpts = GetDefaultOpts().SwOpts
+	}
+	verifier, err := sw.NewWithParams(swOpts.SecLevel, swOpts.HashFamily, sw.NewDummyKeyStore())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed initializing software verifier")
+	}
+
+	logger.Infof("Software fallback for verification enabled for the %s BCCSP", config.ProviderName)
+	return fallback.New(csp, verifier, *config.FallbackOpts)
+}
+
+// isSoftware returns whether the provider named providerName is one of the
+// software-based BCCSPs.
+func isSoftware(providerName string) bool {
+	return providerName == SoftwareBasedFactoryName || providerName == GMBasedFactoryName
+}
+
+// withBreaker wraps a hardware provider with a concurrency limit, timeouts
+// and a circuit breaker when the breaker is enabled. The routing provider
+// is not wrapped: each hardware provider it routes to has its own breaker,
+// so that a slow device does not hold back the others.
+func withBreaker(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config.BreakerOpts == nil || !config.BreakerOpts.Enabled || isSoftware(config.ProviderName) || config.ProviderName == RoutingFactoryName {
+		return csp, nil
+	}
+
+	c, err := breaker.New(csp, config.ProviderName, *config.BreakerOpts)
+	if err != nil {
+		return nil, err
+	}
+	circuitBreakersMutex.Lock()
+	circuitBreakers = append(circuitBreakers, c)
+	circuitBreakersMutex.Unlock()
+
+	logger.Infof("Circuit breaker enabled for the %s BCCSP", config.ProviderName)
+	return c, nil
+}
+
+// withDualControl wraps csp so that its designated private key operations
+// require the approval of a second person when dual control is enabled.
+func withDualControl(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config.DualControlOpts == nil || !config.DualControlOpts.Enabled {
+		return csp, nil
+	}
+
+	logger.Infof("Dual control enabled for the %s BCCSP", config.ProviderName)
+	return dualcontrol.NewFromOpts(csp, *config.DualControlOpts)
+}
+
+// withAudit wraps csp so that its signing operations are recorded in an
+// audit log when the audit trail is enabled.
+func withAudit(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config.AuditOpts == nil || !config.AuditOpts.Enabled {
+		return csp, nil
+	}
+
+	c, err := audit.New(csp, *config.AuditOpts)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Audit trail of the signatures of the %s BCCSP enabled in [%s]", config.ProviderName, config.AuditOpts.Path)
+	return c, nil
+}
+
+// withVerifyCache wraps csp with a cache of its successful signature
+// verifications when the cache is enabled.
+func withVerifyCache(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config.VerifyCacheOpts == nil || !config.VerifyCacheOpts.Enabled {
+		return csp, nil
+	}
+
+	c, err := verifycache.New(csp, *config.VerifyCacheOpts)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Verification cache enabled for the %s BCCSP", config.ProviderName)
+	return c, nil
+}
+
+// withMetrics wraps csp so that its operations are counted and timed when
+// the metrics are enabled.
+func withMetrics(csp bccsp.BCCSP, config *FactoryOpts) (bccsp.BCCSP, error) {
+	if config.MetricsOpts == nil || !config.MetricsOpts.Enabled {
+		return csp, nil
+	}
+
+	c, err := instrumented.New(csp, config.ProviderName, *config.MetricsOpts)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Operation metrics enabled for the %s BCCSP", config.ProviderName)
+	return c, nil
+}
+
+// startSelfTest runs the self-tests of csp once, failing if they do, and
+// then periodically when they are enabled for a hardware provider. Power-on
+// self-tests run once whatever the provider.
+func startSelfTest(csp bccsp.BCCSP, config *FactoryOpts) error {
+	opts := config.SelfTestOpts
+	if opts == nil {
+		return nil
+	}
+	periodic := opts.Enabled && !isSoftware(config.ProviderName)
+	if !periodic && !opts.PowerOn {
+		return nil
+	}
+
+	t, err := selftest.New(csp, *opts)
+	if err != nil {
+		return err
+	}
+	if err := t.Run(); err != nil {
+		return err
+	}
+	logger.Infof("Self-tests %v of the %s BCCSP passed", t.Tests(), config.ProviderName)
+
+	if periodic {
+		t.Start()
+		logger.Infof("Self-tests enabled for the %s BCCSP", config.ProviderName)
+	}
+	selfTester = t
+	return nil
+}
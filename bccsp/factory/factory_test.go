@@ -7,12 +7,22 @@ SPDX-License-Identifier: Apache-2.0
 package factory
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/hyperledger/fabric/bccsp/pkcs11"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/audit"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/breaker"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/dualcontrol"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/fallback"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/instrumented"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/selftest"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/verifycache"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 )
@@ -84,3 +94,138 @@ func TestGetDefault(t *testing.T) {
 	bccsp := GetDefault()
 	require.NotNil(t, bccsp, "Failed getting default BCCSP. Nil instance.")
 }
+
+func TestWithFallback(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+
+	// Not enabled, or nothing to fall back from
+	wrapped, err := withFallback(csp, &FactoryOpts{ProviderName: "PKCS11"})
+	require.NoError(t, err)
+	require.Equal(t, csp, wrapped)
+
+	wrapped, err = withFallback(csp, &FactoryOpts{ProviderName: "SW", FallbackOpts: &fallback.FallbackOpts{Enabled: true}})
+	require.NoError(t, err)
+	require.Equal(t, csp, wrapped)
+
+	wrapped, err = withFallback(csp, &FactoryOpts{ProviderName: "PKCS11", FallbackOpts: &fallback.FallbackOpts{Enabled: true}})
+	require.NoError(t, err)
+	require.NotEqual(t, csp, wrapped)
+}
+
+func TestWithDualControl(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+
+	wrapped, err := withDualControl(csp, &FactoryOpts{ProviderName: "PKCS11"})
+	require.NoError(t, err)
+	require.Equal(t, csp, wrapped)
+
+	_, err = withDualControl(csp, &FactoryOpts{ProviderName: "PKCS11", DualControlOpts: &dualcontrol.DualControlOpts{Enabled: true}})
+	require.EqualError(t, err, "approval service URL is required")
+}
+
+func TestWithVerifyCache(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+
+	wrapped, err := withVerifyCache(csp, &FactoryOpts{ProviderName: "SW", VerifyCacheOpts: &verifycache.VerifyCacheOpts{}})
+	require.NoError(t, err)
+	require.Equal(t, csp, wrapped)
+
+	_, err = withVerifyCache(csp, &FactoryOpts{ProviderName: "SW", VerifyCacheOpts: &verifycache.VerifyCacheOpts{Enabled: true, Size: -1}})
+	require.EqualError(t, err, "Invalid cache size [-1]. It must be positive")
+
+	wrapped, err = withVerifyCache(csp, &FactoryOpts{ProviderName: "SW", VerifyCacheOpts: &verifycache.VerifyCacheOpts{Enabled: true}})
+	require.NoError(t, err)
+	require.IsType(t, &verifycache.Cache{}, wrapped)
+}
+
+func TestWithAudit(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+
+	wrapped, err := withAudit(csp, &FactoryOpts{ProviderName: "SW"})
+	require.NoError(t, err)
+	require.Equal(t, csp, wrapped)
+
+	_, err = withAudit(csp, &FactoryOpts{ProviderName: "SW", AuditOpts: &audit.AuditOpts{Enabled: true}})
+	require.EqualError(t, err, "Invalid audit log path. It must not be empty")
+
+	dir, err := ioutil.TempDir("", "bccsp-audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	wrapped, err = withAudit(csp, &FactoryOpts{ProviderName: "SW", AuditOpts: &audit.AuditOpts{Enabled: true, Path: filepath.Join(dir, "audit.log")}})
+	require.NoError(t, err)
+	require.IsType(t, &audit.CSP{}, wrapped)
+	require.NoError(t, wrapped.(*audit.CSP).Close())
+}
+
+func TestWithMetrics(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+
+	wrapped, err := withMetrics(csp, &FactoryOpts{ProviderName: "SW"})
+	require.NoError(t, err)
+	require.Equal(t, csp, wrapped)
+
+	_, err = withMetrics(csp, &FactoryOpts{MetricsOpts: &instrumented.MetricsOpts{Enabled: true}})
+	require.EqualError(t, err, "Invalid provider name. It must not be empty")
+
+	wrapped, err = withMetrics(csp, &FactoryOpts{ProviderName: "SW", MetricsOpts: &instrumented.MetricsOpts{Enabled: true}})
+	require.NoError(t, err)
+	require.IsType(t, &instrumented.CSP{}, wrapped)
+}
+
+func TestWithBreaker(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+	defer func() { circuitBreakers = nil }()
+
+	opts := &breaker.BreakerOpts{Enabled: true}
+	for _, name := range []string{"SW", "GM", "ROUTING"} {
+		wrapped, err := withBreaker(csp, &FactoryOpts{ProviderName: name, BreakerOpts: opts})
+		require.NoError(t, err)
+		require.Equal(t, csp, wrapped)
+	}
+	wrapped, err := withBreaker(csp, &FactoryOpts{ProviderName: "PKCS11"})
+	require.NoError(t, err)
+	require.Equal(t, csp, wrapped)
+	require.Empty(t, GetBreakers())
+
+	_, err = withBreaker(csp, &FactoryOpts{ProviderName: "PKCS11", BreakerOpts: &breaker.BreakerOpts{Enabled: true, MaxQueue: -1}})
+	require.EqualError(t, err, "Invalid breaker limits [0, -1, 0]. They must not be negative")
+
+	wrapped, err = withBreaker(csp, &FactoryOpts{ProviderName: "PKCS11", BreakerOpts: opts})
+	require.NoError(t, err)
+	require.IsType(t, &breaker.CSP{}, wrapped)
+	require.Equal(t, []*breaker.CSP{wrapped.(*breaker.CSP)}, GetBreakers())
+}
+
+func TestStartSelfTest(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+	defer func() { selfTester = nil }()
+
+	require.NoError(t, startSelfTest(csp, &FactoryOpts{ProviderName: "SW", SelfTestOpts: &selftest.SelfTestOpts{Enabled: true}}))
+	require.Nil(t, GetSelfTest())
+
+	require.NoError(t, startSelfTest(csp, &FactoryOpts{ProviderName: "PKCS11", SelfTestOpts: &selftest.SelfTestOpts{Enabled: true}}))
+	require.NotNil(t, GetSelfTest())
+	defer GetSelfTest().Stop()
+	require.NoError(t, GetSelfTest().HealthCheck(context.Background()))
+}
+
+func TestStartPowerOnSelfTest(t *testing.T) {
+	csp, err := (&SWFactory{}).Get(GetDefaultOpts())
+	require.NoError(t, err)
+	defer func() { selfTester = nil }()
+
+	require.NoError(t, startSelfTest(csp, &FactoryOpts{ProviderName: "SW", SelfTestOpts: &selftest.SelfTestOpts{PowerOn: true}}))
+	require.NotNil(t, GetSelfTest())
+	require.NoError(t, GetSelfTest().HealthCheck(context.Background()))
+	require.Equal(t, []string{selftest.TestSM3, selftest.TestSM4, selftest.TestSM2, selftest.TestAES, selftest.TestECDSA}, GetSelfTest().Tests())
+
+	err = startSelfTest(csp, &FactoryOpts{ProviderName: "SW", SelfTestOpts: &selftest.SelfTestOpts{PowerOn: true, Tests: []string{"RSA"}}})
+	require.EqualError(t, err, "Unknown self-test [RSA]")
+}
@@ -0,0 +1,171 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// GMBasedFactoryName is the name of the factory of the software-based
+	// BCCSP restricted to the GM (SM2, SM3, SM4) algorithms
+	GMBasedFactoryName = "GM"
+)
+
+// GMFactory is the factory of the software-based BCCSP configured for the
+// GM algorithms only, so that deployments select national crypto in their
+// configuration.
+type GMFactory struct{}
+
+// Name returns the name of this factory
+func (f *GMFactory) Name() string {
+	return GMBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *GMFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.GmOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	gmOpts := config.GmOpts
+	if err := gmOpts.Validate(); err != nil {
+		return nil, err
+	}
+
+	var ks bccsp.KeyStore
+	switch gmOpts.keyStoreBackend() {
+	case GMKeyStoreFile:
+		fks, err := sw.NewFileBasedKeyStore(nil, gmOpts.KeyStore.Path, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to initialize GM key store")
+		}
+		ks = fks
+	case GMKeyStoreInmem:
+		ks = sw.NewInMemoryKeyStore()
+	case GMKeyStoreEphemeral:
+		ks = sw.NewDummyKeyStore()
+	}
+
+	var swOptions []sw.Option
+	if gmOpts.SKIConvention != "" {
+		skiConvention, _ := utils.ParseSKIConvention(gmOpts.SKIConvention)
+		swOptions = append(swOptions, sw.WithSKIConvention(skiConvention))
+	}
+	if gmOpts.SM2FastVerify {
+		swOptions = append(swOptions, sw.WithSM2FastVerify())
+	}
+	if gmOpts.DisableRandPool {
+		swOptions = append(swOptions, sw.WithoutRandPool())
+	}
+	if gmOpts.Implementation != "" {
+		swOptions = append(swOptions, sw.WithGMBackend(gmOpts.Implementation))
+	}
+
+	return sw.NewWithParams(gmOpts.SecLevel, gmOpts.HashFamily, ks, swOptions...)
+}
+
+const (
+	// GMKeyStoreFile stores keys in files of a directory (default)
+	GMKeyStoreFile = "file"
+	// GMKeyStoreInmem keeps keys in memory for the lifetime of the process
+	GMKeyStoreInmem = "inmem"
+	// GMKeyStoreEphemeral never stores keys
+	GMKeyStoreEphemeral = "ephemeral"
+)
+
+// GmOpts contains options for the GMFactory
+type GmOpts struct {
+	// Security level and hash family, which must be 256 and SM3
+	SecLevel   int    `mapstructure:"security" json:"security" yaml:"Security"`
+	HashFamily string `mapstructure:"hash" json:"hash" yaml:"Hash"`
+
+	// Keystore Options
+	KeyStore *GMKeyStoreOpts `mapstructure:"keystore,omitempty" json:"keystore,omitempty" yaml:"KeyStore"`
+
+	// Derivation of the SKIs of the keys of this provider, as the SKIConvention
+	// of SwOpts, the convention of the process when empty. sm3 avoids SHA-2
+	// altogether.
+	SKIConvention string `mapstructure:"skiconvention,omitempty" json:"skiconvention,omitempty" yaml:"SKIConvention"`
+
+	// Verify SM2 signatures with a precomputed multi-scalar multiplication,
+	// as the SM2FastVerify of SwOpts
+	SM2FastVerify bool `mapstructure:"sm2fastverify,omitempty" json:"sm2fastverify,omitempty" yaml:"SM2FastVerify"`
+
+	// Read nonces and IVs from crypto/rand instead of the pooled CSPRNG, as
+	// the DisableRandPool of SwOpts
+	DisableRandPool bool `mapstructure:"disablerandpool,omitempty" json:"disablerandpool,omitempty" yaml:"DisableRandPool"`
+
+	// Implementation of SM2 signing and verification and of SM3, go (the
+	// default, free of cgo) or gmssl, which binds GmSSL 3 through cgo and
+	// requires a build with the gmssl tag
+	Implementation string `mapstructure:"implementation,omitempty" json:"implementation,omitempty" yaml:"Implementation"`
+}
+
+// GMKeyStoreOpts selects where the GMFactory stores keys. Path is the
+// directory of the file backend.
+type GMKeyStoreOpts struct {
+	Backend string `mapstructure:"backend" json:"backend" yaml:"Backend"`
+	Path    string `mapstructure:"path,omitempty" json:"path,omitempty" yaml:"Path"`
+}
+
+// GetDefaultGMOpts returns the options of the GMFactory storing keys in
+// the files of keyStorePath.
+func GetDefaultGMOpts(keyStorePath string) *GmOpts {
+	return &GmOpts{
+		SecLevel:   256,
+		HashFamily: "SM3",
+		KeyStore: &GMKeyStoreOpts{
+			Backend: GMKeyStoreFile,
+			Path:    keyStorePath,
+		},
+	}
+}
+
+// Validate checks that the options select the GM algorithms and a
+// supported key store, so that misconfigurations fail at startup.
+func (o *GmOpts) Validate() error {
+	if o.HashFamily != "SM3" {
+		return errors.Errorf("Invalid GM hash family [%s], it must be SM3", o.HashFamily)
+	}
+	if o.SecLevel != 256 {
+		return errors.Errorf("Invalid GM security level [%d], it must be 256", o.SecLevel)
+	}
+
+	if _, err := utils.ParseSKIConvention(o.SKIConvention); err != nil {
+		return errors.Wrap(err, "Invalid GM SKI convention")
+	}
+
+	switch o.Implementation {
+	case "", sw.GMBackendGo, sw.GMBackendGmSSL:
+	default:
+		return errors.Errorf("Invalid GM implementation [%s], it must be %s or %s", o.Implementation, sw.GMBackendGo, sw.GMBackendGmSSL)
+	}
+
+	switch o.keyStoreBackend() {
+	case GMKeyStoreFile:
+		if o.KeyStore == nil || o.KeyStore.Path == "" {
+			return errors.New("Invalid GM key store, the file backend requires a path")
+		}
+	case GMKeyStoreInmem, GMKeyStoreEphemeral:
+	default:
+		return errors.Errorf("Invalid GM key store backend [%s], it must be one of %s, %s or %s",
+			o.KeyStore.Backend, GMKeyStoreFile, GMKeyStoreInmem, GMKeyStoreEphemeral)
+	}
+	return nil
+}
+
+func (o *GmOpts) keyStoreBackend() string {
+	if o.KeyStore == nil || o.KeyStore.Backend == "" {
+		return GMKeyStoreFile
+	}
+	return o.KeyStore.Backend
+}
@@ -0,0 +1,168 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGMFactoryName(t *testing.T) {
+	f := &GMFactory{}
+	assert.Equal(t, f.Name(), GMBasedFactoryName)
+}
+
+func TestGMFactoryGetInvalidArgs(t *testing.T) {
+	f := &GMFactory{}
+
+	_, err := f.Get(nil)
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{GmOpts: &GmOpts{SecLevel: 256, HashFamily: "SHA2"}})
+	assert.EqualError(t, err, "Invalid GM hash family [SHA2], it must be SM3")
+}
+
+func TestGMFactoryGet(t *testing.T) {
+	keyStorePath, err := ioutil.TempDir("", "gmkeystore")
+	require.NoError(t, err)
+	defer os.RemoveAll(keyStorePath)
+
+	f := &GMFactory{}
+	for _, opts := range []*GmOpts{
+		GetDefaultGMOpts(keyStorePath),
+		{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Path: keyStorePath}},
+		{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Backend: GMKeyStoreInmem}},
+		{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Backend: GMKeyStoreEphemeral}},
+	} {
+		csp, err := f.Get(&FactoryOpts{GmOpts: opts})
+		assert.NoError(t, err)
+		assert.NotNil(t, csp)
+	}
+}
+
+func TestGmOptsValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		opts GmOpts
+		err  string
+	}{
+		{
+			name: "HashFamily",
+			opts: GmOpts{SecLevel: 256, HashFamily: "SHA3"},
+			err:  "Invalid GM hash family [SHA3], it must be SM3",
+		},
+		{
+			name: "SecLevel",
+			opts: GmOpts{SecLevel: 384, HashFamily: "SM3"},
+			err:  "Invalid GM security level [384], it must be 256",
+		},
+		{
+			name: "MissingPath",
+			opts: GmOpts{SecLevel: 256, HashFamily: "SM3"},
+			err:  "Invalid GM key store, the file backend requires a path",
+		},
+		{
+			name: "Backend",
+			opts: GmOpts{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Backend: "pkcs11"}},
+			err:  "Invalid GM key store backend [pkcs11], it must be one of file, inmem or ephemeral",
+		},
+		{
+			name: "SKIConvention",
+			opts: GmOpts{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Backend: GMKeyStoreInmem}, SKIConvention: "md5"},
+			err:  "Invalid GM SKI convention: unknown SKI convention [md5]",
+		},
+		{
+			name: "Implementation",
+			opts: GmOpts{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Backend: GMKeyStoreInmem}, Implementation: "openssl"},
+			err:  "Invalid GM implementation [openssl], it must be go or gmssl",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, tt.opts.Validate(), tt.err)
+		})
+	}
+}
+
+func TestGMFactoryGetWithSKIConvention(t *testing.T) {
+	f := &GMFactory{}
+	csp, err := f.Get(&FactoryOpts{GmOpts: &GmOpts{
+		SecLevel:      256,
+		HashFamily:    "SM3",
+		KeyStore:      &GMKeyStoreOpts{Backend: GMKeyStoreInmem},
+		SKIConvention: "sm3",
+	}})
+	require.NoError(t, err)
+
+	// The convention of the provider applies, not the one of the process
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pub, err := k.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+	require.NoError(t, err)
+	ski, err := utils.ComputeSKI(pub, utils.SKISM3)
+	require.NoError(t, err)
+	assert.Equal(t, ski, k.SKI())
+	assert.Equal(t, utils.SKIFabric, utils.GetSKIConvention())
+}
+
+func TestGMFactoryOptsFromYAML(t *testing.T) {
+	yamlCFG := `
+BCCSP:
+    Default: GM
+    GM:
+        Hash: SM3
+        Security: 256
+        KeyStore:
+            Backend: inmem
+        SM2FastVerify: true
+`
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(yamlCFG)))
+
+	var opts *FactoryOpts
+	require.NoError(t, v.UnmarshalKey("bccsp", &opts))
+	assert.Equal(t, "GM", opts.ProviderName)
+	assert.Equal(t, &GmOpts{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Backend: GMKeyStoreInmem}, SM2FastVerify: true}, opts.GmOpts)
+
+	csp, err := GetBCCSPFromOpts(opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, csp)
+}
+
+func TestGMFactoryGetWithImplementation(t *testing.T) {
+	f := &GMFactory{}
+	opts := &GmOpts{
+		SecLevel:       256,
+		HashFamily:     "SM3",
+		KeyStore:       &GMKeyStoreOpts{Backend: GMKeyStoreInmem},
+		Implementation: sw.GMBackendGo,
+	}
+	csp, err := f.Get(&FactoryOpts{GmOpts: opts})
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	msg := []byte("Hello World")
+	signature, err := csp.Sign(k, msg, nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, signature, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/kms"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// KMSBasedFactoryName is the name of the factory of the cloud KMS based BCCSP implementation
+	KMSBasedFactoryName = "KMS"
+)
+
+// KMSFactory is the factory of the BCCSP signing with cloud KMS keys.
+type KMSFactory struct{}
+
+// Name returns the name of this factory
+func (f *KMSFactory) Name() string {
+	return KMSBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *KMSFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.KmsOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	kmsOpts := config.KmsOpts
+	ks := sw.NewDummyKeyStore()
+
+	return kms.New(*kmsOpts, ks)
+}
@@ -0,0 +1,32 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/kms"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKMSFactoryName(t *testing.T) {
+	f := &KMSFactory{}
+	assert.Equal(t, f.Name(), KMSBasedFactoryName)
+}
+
+func TestKMSFactoryGetInvalidArgs(t *testing.T) {
+	f := &KMSFactory{}
+
+	_, err := f.Get(nil)
+	assert.Error(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.Error(t, err, "Invalid config. It must not be nil.")
+
+	_, err = GetBCCSPFromOpts(&FactoryOpts{ProviderName: "KMS", KmsOpts: &kms.KMSOpts{Provider: "unknown", Region: "cn-hangzhou"}})
+	assert.EqualError(t, err, "Could not initialize BCCSP KMS: Unsupported KMS provider [unknown]")
+}
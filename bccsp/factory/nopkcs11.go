@@ -10,6 +10,19 @@ package factory
 
 import (
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/audit"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/breaker"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/dualcontrol"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/fallback"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/instrumented"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/kms"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/routing"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sdf"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/selftest"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/skf"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/tee"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/verifycache"
 	"github.com/pkg/errors"
 )
 
@@ -17,8 +30,35 @@ const pkcs11Enabled = false
 
 // FactoryOpts holds configuration information used to initialize factory implementations
 type FactoryOpts struct {
-	ProviderName string  `mapstructure:"default" json:"default" yaml:"Default"`
-	SwOpts       *SwOpts `mapstructure:"SW,omitempty" json:"SW,omitempty" yaml:"SwOpts"`
+	ProviderName string             `mapstructure:"default" json:"default" yaml:"Default"`
+	SwOpts       *SwOpts            `mapstructure:"SW,omitempty" json:"SW,omitempty" yaml:"SwOpts"`
+	GmOpts       *GmOpts            `mapstructure:"GM,omitempty" json:"GM,omitempty" yaml:"GM"`
+	SdfOpts      *sdf.SDFOpts       `mapstructure:"SDF,omitempty" json:"SDF,omitempty" yaml:"SDF"`
+	SkfOpts      *skf.SKFOpts       `mapstructure:"SKF,omitempty" json:"SKF,omitempty" yaml:"SKF"`
+	RemoteOpts   *remote.RemoteOpts `mapstructure:"REMOTE,omitempty" json:"REMOTE,omitempty" yaml:"REMOTE"`
+	KmsOpts      *kms.KMSOpts       `mapstructure:"KMS,omitempty" json:"KMS,omitempty" yaml:"KMS"`
+	TeeOpts      *tee.TEEOpts       `mapstructure:"TEE,omitempty" json:"TEE,omitempty" yaml:"TEE"`
+
+	// Provider loaded from a Go plugin or served by a sidecar process
+	PluginOpts *PluginOpts `mapstructure:"PLUGIN,omitempty" json:"PLUGIN,omitempty" yaml:"Plugin"`
+
+	// Dispatch of algorithm families to the providers above
+	RoutingOpts *routing.RoutingOpts `mapstructure:"ROUTING,omitempty" json:"ROUTING,omitempty" yaml:"Routing"`
+
+	// Concurrency limit, timeouts and circuit breaker of a hardware provider
+	BreakerOpts *breaker.BreakerOpts `mapstructure:"BREAKER,omitempty" json:"BREAKER,omitempty" yaml:"Breaker"`
+	// Software fallback for verification when a hardware provider fails
+	FallbackOpts *fallback.FallbackOpts `mapstructure:"FALLBACK,omitempty" json:"FALLBACK,omitempty" yaml:"Fallback"`
+	// Second person approval of private key operations
+	DualControlOpts *dualcontrol.DualControlOpts `mapstructure:"DUALCONTROL,omitempty" json:"DUALCONTROL,omitempty" yaml:"DualControl"`
+	// Hash-chained log of the private key signing operations
+	AuditOpts *audit.AuditOpts `mapstructure:"AUDIT,omitempty" json:"AUDIT,omitempty" yaml:"Audit"`
+	// Periodic known-answer tests of the default provider
+	SelfTestOpts *selftest.SelfTestOpts `mapstructure:"SELFTEST,omitempty" json:"SELFTEST,omitempty" yaml:"SelfTest"`
+	// Cache of successful signature verifications
+	VerifyCacheOpts *verifycache.VerifyCacheOpts `mapstructure:"VERIFYCACHE,omitempty" json:"VERIFYCACHE,omitempty" yaml:"VerifyCache"`
+	// Counters and latencies of the cryptographic operations
+	MetricsOpts *instrumented.MetricsOpts `mapstructure:"METRICS,omitempty" json:"METRICS,omitempty" yaml:"Metrics"`
 }
 
 // InitFactories must be called before using factory interfaces
@@ -57,10 +97,122 @@ func initFactories(config *FactoryOpts) error {
 		}
 	}
 
+	// GM Software-Based BCCSP
+	if config.ProviderName == "GM" && config.GmOpts != nil {
+		f := &GMFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing GM.BCCSP")
+		}
+	}
+
+	// SDF-Based BCCSP
+	if config.ProviderName == "SDF" && config.SdfOpts != nil {
+		f := &SDFFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing SDF.BCCSP")
+		}
+	}
+
+	// SKF-Based BCCSP
+	if config.ProviderName == "SKF" && config.SkfOpts != nil {
+		f := &SKFFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing SKF.BCCSP")
+		}
+	}
+
+	// Remote signer BCCSP
+	if config.ProviderName == "REMOTE" && config.RemoteOpts != nil {
+		f := &RemoteFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing REMOTE.BCCSP")
+		}
+	}
+
+	// Cloud KMS-Based BCCSP
+	if config.ProviderName == "KMS" && config.KmsOpts != nil {
+		f := &KMSFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing KMS.BCCSP")
+		}
+	}
+
+	// Enclave-Based BCCSP
+	if config.ProviderName == "TEE" && config.TeeOpts != nil {
+		f := &TEEFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing TEE.BCCSP")
+		}
+	}
+
+	// Plugin or sidecar BCCSP
+	if config.ProviderName == "PLUGIN" && config.PluginOpts != nil {
+		f := &PluginFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing PLUGIN.BCCSP")
+		}
+	}
+
+	// Routing BCCSP, dispatching to the providers above
+	if config.ProviderName == "ROUTING" && config.RoutingOpts != nil {
+		f := &RoutingFactory{}
+		var err error
+		defaultBCCSP, err = initBCCSP(f, config)
+		if err != nil {
+			return errors.Wrapf(err, "Failed initializing ROUTING.BCCSP")
+		}
+	}
+
 	if defaultBCCSP == nil {
 		return errors.Errorf("Could not find default `%s` BCCSP", config.ProviderName)
 	}
 
+	var err error
+	defaultBCCSP, err = withBreaker(defaultBCCSP, config)
+	if err != nil {
+		return errors.Wrapf(err, "Failed initializing circuit breaker")
+	}
+	if err = startSelfTest(defaultBCCSP, config); err != nil {
+		return errors.Wrapf(err, "Failed self-testing %s.BCCSP", config.ProviderName)
+	}
+	defaultBCCSP, err = withDualControl(defaultBCCSP, config)
+	if err != nil {
+		return errors.Wrapf(err, "Failed initializing dual control")
+	}
+	defaultBCCSP, err = withAudit(defaultBCCSP, config)
+	if err != nil {
+		return errors.Wrapf(err, "Failed initializing audit trail")
+	}
+	auditTrail, _ = defaultBCCSP.(*audit.CSP)
+	defaultBCCSP, err = withFallback(defaultBCCSP, config)
+	if err != nil {
+		return errors.Wrapf(err, "Failed initializing software fallback")
+	}
+	defaultBCCSP, err = withVerifyCache(defaultBCCSP, config)
+	if err != nil {
+		return errors.Wrapf(err, "Failed initializing verification cache")
+	}
+	verifyCache, _ = defaultBCCSP.(*verifycache.Cache)
+	defaultBCCSP, err = withMetrics(defaultBCCSP, config)
+	if err != nil {
+		return errors.Wrapf(err, "Failed initializing operation metrics")
+	}
+	operationMetrics, _ = defaultBCCSP.(*instrumented.CSP)
+
 	return nil
 }
 
@@ -70,6 +222,22 @@ func GetBCCSPFromOpts(config *FactoryOpts) (bccsp.BCCSP, error) {
 	switch config.ProviderName {
 	case "SW":
 		f = &SWFactory{}
+	case "GM":
+		f = &GMFactory{}
+	case "SDF":
+		f = &SDFFactory{}
+	case "SKF":
+		f = &SKFFactory{}
+	case "REMOTE":
+		f = &RemoteFactory{}
+	case "KMS":
+		f = &KMSFactory{}
+	case "TEE":
+		f = &TEEFactory{}
+	case "PLUGIN":
+		f = &PluginFactory{}
+	case "ROUTING":
+		f = &RoutingFactory{}
 	default:
 		return nil, errors.Errorf("Could not find BCCSP, no '%s' provider", config.ProviderName)
 	}
@@ -78,5 +246,20 @@ func GetBCCSPFromOpts(config *FactoryOpts) (bccsp.BCCSP, error) {
 	if err != nil {
 		return nil, errors.Wrapf(err, "Could not initialize BCCSP %s", f.Name())
 	}
-	return csp, nil
+	if csp, err = withBreaker(csp, config); err != nil {
+		return nil, errors.Wrapf(err, "Could not initialize circuit breaker for BCCSP %s", f.Name())
+	}
+	if csp, err = withDualControl(csp, config); err != nil {
+		return nil, errors.Wrapf(err, "Could not initialize dual control for BCCSP %s", f.Name())
+	}
+	if csp, err = withAudit(csp, config); err != nil {
+		return nil, errors.Wrapf(err, "Could not initialize audit trail for BCCSP %s", f.Name())
+	}
+	if csp, err = withFallback(csp, config); err != nil {
+		return nil, err
+	}
+	if csp, err = withVerifyCache(csp, config); err != nil {
+		return nil, err
+	}
+	return withMetrics(csp, config)
 }
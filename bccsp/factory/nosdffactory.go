@@ -0,0 +1,33 @@
+// +build !sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SDFBasedFactoryName is the name of the factory of the SDF device based BCCSP implementation
+	SDFBasedFactoryName = "SDF"
+)
+
+// SDFFactory is the factory of the SDF (GM/T 0018) device based BCCSP.
+// This build does not include SDF support.
+type SDFFactory struct{}
+
+// Name returns the name of this factory
+func (f *SDFFactory) Name() string {
+	return SDFBasedFactoryName
+}
+
+// Get always fails, since the SDF provider requires the sdf build tag.
+func (f *SDFFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	return nil, errors.New("SDF support is not available, rebuild with the sdf build tag")
+}
@@ -0,0 +1,24 @@
+// +build !sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sdf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSDFFactoryNotAvailable(t *testing.T) {
+	f := &SDFFactory{}
+	assert.Equal(t, SDFBasedFactoryName, f.Name())
+
+	_, err := GetBCCSPFromOpts(&FactoryOpts{ProviderName: "SDF", SdfOpts: &sdf.SDFOpts{}})
+	assert.EqualError(t, err, "Could not initialize BCCSP SDF: SDF support is not available, rebuild with the sdf build tag")
+}
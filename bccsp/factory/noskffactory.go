@@ -0,0 +1,33 @@
+// +build !skf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SKFBasedFactoryName is the name of the factory of the SKF token based BCCSP implementation
+	SKFBasedFactoryName = "SKF"
+)
+
+// SKFFactory is the factory of the SKF (GM/T 0016) token based BCCSP.
+// This build does not include SKF support.
+type SKFFactory struct{}
+
+// Name returns the name of this factory
+func (f *SKFFactory) Name() string {
+	return SKFBasedFactoryName
+}
+
+// Get always fails, since the SKF provider requires the skf build tag.
+func (f *SKFFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	return nil, errors.New("SKF support is not available, rebuild with the skf build tag")
+}
@@ -0,0 +1,24 @@
+// +build !skf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/skf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSKFFactoryNotAvailable(t *testing.T) {
+	f := &SKFFactory{}
+	assert.Equal(t, SKFBasedFactoryName, f.Name())
+
+	_, err := GetBCCSPFromOpts(&FactoryOpts{ProviderName: "SKF", SkfOpts: &skf.SKFOpts{}})
+	assert.EqualError(t, err, "Could not initialize BCCSP SKF: SKF support is not available, rebuild with the skf build tag")
+}
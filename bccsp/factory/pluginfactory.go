@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"os"
+	"plugin"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// PluginFactoryName is the name of the factory of the BCCSP
+	// implementations shipped outside of this repository
+	PluginFactoryName = "PLUGIN"
+
+	// PluginNewSymbol is the symbol a Go plugin exports to create its BCCSP,
+	// a func(config map[string]interface{}) (bccsp.BCCSP, error)
+	PluginNewSymbol = "New"
+)
+
+// PluginOpts contains options for the PluginFactory. Exactly one of Library
+// and Sidecar must be set.
+type PluginOpts struct {
+	// Path of a Go plugin exporting New, and the configuration passed to it
+	Library string                 `mapstructure:"library,omitempty" json:"library,omitempty" yaml:"Library"`
+	Config  map[string]interface{} `mapstructure:"config,omitempty" json:"config,omitempty" yaml:"Config"`
+
+	// Sidecar process serving the signer service of the REMOTE BCCSP
+	Sidecar *remote.SidecarOpts `mapstructure:"sidecar,omitempty" json:"sidecar,omitempty" yaml:"Sidecar"`
+}
+
+// PluginFactory is the factory of the BCCSP loaded from a Go plugin or
+// served by a sidecar process, so that vendors can ship certified GM
+// implementations without patching this repository.
+type PluginFactory struct{}
+
+// Name returns the name of this factory
+func (f *PluginFactory) Name() string {
+	return PluginFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *PluginFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.PluginOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	pluginOpts := config.PluginOpts
+	switch {
+	case pluginOpts.Library != "" && pluginOpts.Sidecar != nil:
+		return nil, errors.New("Invalid config. Library and Sidecar are mutually exclusive.")
+	case pluginOpts.Sidecar != nil:
+		return remote.NewSidecar(*pluginOpts.Sidecar, sw.NewDummyKeyStore())
+	case pluginOpts.Library != "":
+		return loadPlugin(pluginOpts.Library, pluginOpts.Config)
+	default:
+		return nil, errors.New("Invalid config. Either Library or Sidecar must be set.")
+	}
+}
+
+// loadPlugin opens the Go plugin at library and creates its BCCSP. The
+// plugin must be built with the same Go toolchain and dependency versions
+// as the node.
+func loadPlugin(library string, config map[string]interface{}) (bccsp.BCCSP, error) {
+	if _, err := os.Stat(library); err != nil {
+		return nil, errors.Wrapf(err, "Could not find library '%s'", library)
+	}
+
+	p, err := plugin.Open(library)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed loading plugin '%s'", library)
+	}
+	sym, err := p.Lookup(PluginNewSymbol)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Could not find required symbol '%s'", PluginNewSymbol)
+	}
+	newCSP, ok := sym.(func(config map[string]interface{}) (bccsp.BCCSP, error))
+	if !ok {
+		return nil, errors.Errorf("Plugin does not implement the required function signature for '%s'", PluginNewSymbol)
+	}
+
+	csp, err := newCSP(config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing plugin '%s'", library)
+	}
+	if csp == nil {
+		return nil, errors.Errorf("Plugin '%s' returned a nil BCCSP", library)
+	}
+	return csp, nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPluginFactoryName(t *testing.T) {
+	f := &PluginFactory{}
+	assert.Equal(t, f.Name(), PluginFactoryName)
+}
+
+func TestPluginFactoryGetInvalidArgs(t *testing.T) {
+	f := &PluginFactory{}
+
+	_, err := f.Get(nil)
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{PluginOpts: &PluginOpts{}})
+	assert.EqualError(t, err, "Invalid config. Either Library or Sidecar must be set.")
+
+	_, err = f.Get(&FactoryOpts{PluginOpts: &PluginOpts{Library: "bccsp.so", Sidecar: &remote.SidecarOpts{}}})
+	assert.EqualError(t, err, "Invalid config. Library and Sidecar are mutually exclusive.")
+
+	_, err = f.Get(&FactoryOpts{PluginOpts: &PluginOpts{Library: "/nonexistent/bccsp.so"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not find library '/nonexistent/bccsp.so'")
+
+	_, err = f.Get(&FactoryOpts{PluginOpts: &PluginOpts{Sidecar: &remote.SidecarOpts{}}})
+	assert.EqualError(t, err, "Invalid options. Path of the sidecar must be set")
+}
+
+func TestPluginFactoryOptsFromYAML(t *testing.T) {
+	yamlCFG := `
+BCCSP:
+    Default: PLUGIN
+    Plugin:
+        Sidecar:
+            Path: /opt/vendor/bin/gmsigner
+            Args:
+              - --config
+              - /etc/vendor/gmsigner.yaml
+            StartTimeout: 5s
+            Address: 127.0.0.1:7070
+            RootCerts:
+              - /etc/vendor/tls/ca.pem
+            Cert: /etc/vendor/tls/client.pem
+            Key: /etc/vendor/tls/client.key
+            Hash: SM3
+            Security: 256
+`
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(yamlCFG)))
+
+	var opts *FactoryOpts
+	require.NoError(t, v.UnmarshalKey("bccsp", &opts))
+	assert.Equal(t, "PLUGIN", opts.ProviderName)
+	require.NotNil(t, opts.PluginOpts)
+	sidecar := opts.PluginOpts.Sidecar
+	require.NotNil(t, sidecar)
+	assert.Equal(t, "/opt/vendor/bin/gmsigner", sidecar.Path)
+	assert.Equal(t, []string{"--config", "/etc/vendor/gmsigner.yaml"}, sidecar.Args)
+	assert.Equal(t, "127.0.0.1:7070", sidecar.Address)
+	assert.Equal(t, []string{"/etc/vendor/tls/ca.pem"}, sidecar.RootCertFiles)
+	assert.Equal(t, "SM3", sidecar.HashFamily)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RemoteBasedFactoryName is the name of the factory of the remote signer based BCCSP implementation
+	RemoteBasedFactoryName = "REMOTE"
+)
+
+// RemoteFactory is the factory of the BCCSP forwarding to an external signer service.
+type RemoteFactory struct{}
+
+// Name returns the name of this factory
+func (f *RemoteFactory) Name() string {
+	return RemoteBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *RemoteFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.RemoteOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	remoteOpts := config.RemoteOpts
+	ks := sw.NewDummyKeyStore()
+
+	return remote.New(*remoteOpts, ks)
+}
@@ -0,0 +1,33 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteFactoryName(t *testing.T) {
+	f := &RemoteFactory{}
+	assert.Equal(t, f.Name(), RemoteBasedFactoryName)
+}
+
+func TestRemoteFactoryGetInvalidArgs(t *testing.T) {
+	f := &RemoteFactory{}
+
+	_, err := f.Get(nil)
+	assert.Error(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.Error(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{RemoteOpts: &remote.RemoteOpts{Address: "localhost:7070"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "required for mutual TLS")
+}
@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"strings"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/routing"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RoutingFactoryName is the name of the factory of the BCCSP dispatching
+	// operations to other providers by algorithm
+	RoutingFactoryName = "ROUTING"
+)
+
+// RoutingFactory is the factory of the BCCSP routing each algorithm family
+// to one of the providers configured in the same FactoryOpts, e.g. SM2 and
+// SM3 to GM and ECDSA to PKCS11, for nodes using several kinds of crypto.
+type RoutingFactory struct{}
+
+// Name returns the name of this factory
+func (f *RoutingFactory) Name() string {
+	return RoutingFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *RoutingFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.RoutingOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	routingOpts := config.RoutingOpts
+	if routingOpts.Default == "" {
+		return nil, errors.New("Invalid config. A default provider must be set.")
+	}
+
+	// Each provider is created once, even when it serves several families
+	providers := map[string]bccsp.BCCSP{}
+	provider := func(name string) (bccsp.BCCSP, error) {
+		if csp, ok := providers[name]; ok {
+			return csp, nil
+		}
+		if name == RoutingFactoryName {
+			return nil, errors.New("Invalid config. The routing provider cannot route to itself.")
+		}
+
+		// Dual control, the audit trail and the software fallback apply to
+		// the routing BCCSP
+		providerConfig := *config
+		providerConfig.ProviderName = name
+		providerConfig.RoutingOpts = nil
+		providerConfig.DualControlOpts = nil
+		providerConfig.AuditOpts = nil
+		providerConfig.FallbackOpts = nil
+		csp, err := GetBCCSPFromOpts(&providerConfig)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Failed initializing provider %s", name)
+		}
+		providers[name] = csp
+		return csp, nil
+	}
+
+	defaultCSP, err := provider(routingOpts.Default)
+	if err != nil {
+		return nil, err
+	}
+	routes := map[string]bccsp.BCCSP{}
+	for family, name := range routingOpts.Routes {
+		csp, err := provider(name)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Invalid route of algorithm family %s", family)
+		}
+		// Configuration keys are case insensitive
+		routes[strings.ToUpper(family)] = csp
+	}
+
+	return routing.New(defaultCSP, routes)
+}
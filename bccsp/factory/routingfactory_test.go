@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/routing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingFactoryName(t *testing.T) {
+	f := &RoutingFactory{}
+	assert.Equal(t, f.Name(), RoutingFactoryName)
+}
+
+func TestRoutingFactoryGetInvalidArgs(t *testing.T) {
+	f := &RoutingFactory{}
+
+	_, err := f.Get(nil)
+	assert.EqualError(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{RoutingOpts: &routing.RoutingOpts{}})
+	assert.EqualError(t, err, "Invalid config. A default provider must be set.")
+
+	_, err = f.Get(&FactoryOpts{RoutingOpts: &routing.RoutingOpts{Default: "ROUTING"}})
+	assert.EqualError(t, err, "Invalid config. The routing provider cannot route to itself.")
+
+	_, err = f.Get(&FactoryOpts{
+		SwOpts:      GetDefaultOpts().SwOpts,
+		RoutingOpts: &routing.RoutingOpts{Default: "SW", Routes: map[string]string{"SM2": "GM"}},
+	})
+	assert.EqualError(t, err, "Invalid route of algorithm family SM2: Failed initializing provider GM: Could not initialize BCCSP GM: Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{
+		SwOpts:      GetDefaultOpts().SwOpts,
+		RoutingOpts: &routing.RoutingOpts{Default: "SW", Routes: map[string]string{"RSA": "SW"}},
+	})
+	assert.EqualError(t, err, "Unknown algorithm family [RSA]")
+}
+
+func TestRoutingFactoryGet(t *testing.T) {
+	f := &RoutingFactory{}
+
+	csp, err := f.Get(&FactoryOpts{
+		ProviderName: "ROUTING",
+		SwOpts:       &SwOpts{SecLevel: 256, HashFamily: "SHA2", Ephemeral: true},
+		GmOpts:       &GmOpts{SecLevel: 256, HashFamily: "SM3", KeyStore: &GMKeyStoreOpts{Backend: GMKeyStoreEphemeral}},
+		RoutingOpts: &routing.RoutingOpts{
+			Default: "SW",
+			Routes:  map[string]string{"sm2": "GM", "sm3": "GM", "sm4": "GM"},
+		},
+	})
+	require.NoError(t, err)
+
+	// SHA is the hash family of the default SW provider, SM3 that of GM
+	msg := []byte("message")
+	digest, err := csp.Hash(msg, &bccsp.SHAOpts{})
+	require.NoError(t, err)
+	sha256Digest, err := csp.Hash(msg, &bccsp.SHA256Opts{})
+	require.NoError(t, err)
+	assert.Equal(t, sha256Digest, digest)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	sig, err := csp.Sign(k, msg, nil)
+	require.NoError(t, err)
+	valid, err := csp.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
@@ -0,0 +1,42 @@
+// +build sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sdf"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SDFBasedFactoryName is the name of the factory of the SDF device based BCCSP implementation
+	SDFBasedFactoryName = "SDF"
+)
+
+// SDFFactory is the factory of the SDF (GM/T 0018) device based BCCSP.
+type SDFFactory struct{}
+
+// Name returns the name of this factory
+func (f *SDFFactory) Name() string {
+	return SDFBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *SDFFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.SdfOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	sdfOpts := config.SdfOpts
+	ks := sw.NewDummyKeyStore()
+
+	return sdf.New(*sdfOpts, ks)
+}
@@ -0,0 +1,42 @@
+// +build skf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/skf"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SKFBasedFactoryName is the name of the factory of the SKF token based BCCSP implementation
+	SKFBasedFactoryName = "SKF"
+)
+
+// SKFFactory is the factory of the SKF (GM/T 0016) token based BCCSP.
+type SKFFactory struct{}
+
+// Name returns the name of this factory
+func (f *SKFFactory) Name() string {
+	return SKFBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *SKFFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.SkfOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	skfOpts := config.SkfOpts
+	ks := sw.NewDummyKeyStore()
+
+	return skf.New(*skfOpts, ks)
+}
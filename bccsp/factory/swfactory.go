@@ -16,8 +16,11 @@ limitations under the License.
 package factory
 
 import (
+	"io"
+
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/pkg/errors"
 )
 
@@ -60,7 +63,63 @@ func (f *SWFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
 		ks = sw.NewDummyKeyStore()
 	}
 
-	return sw.NewWithParams(swOpts.SecLevel, swOpts.HashFamily, ks)
+	rng, err := newRandSource(swOpts.RandSource)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to initialize random source")
+	}
+
+	swOptions := []sw.Option{sw.WithRand(rng)}
+	if swOpts.ConstantTimeSM4 {
+		swOptions = append(swOptions, sw.WithConstantTimeSM4())
+	}
+	if swOpts.SM2Precompute {
+		swOptions = append(swOptions, sw.WithSM2Precomputation())
+	}
+	if swOpts.SM2FastVerify {
+		swOptions = append(swOptions, sw.WithSM2FastVerify())
+	}
+	if swOpts.DisableRandPool {
+		swOptions = append(swOptions, sw.WithoutRandPool())
+	}
+	if swOpts.ASN1Mode != "" {
+		asn1Mode, err := utils.ParseASN1Mode(swOpts.ASN1Mode)
+		if err != nil {
+			return nil, err
+		}
+		swOptions = append(swOptions, sw.WithASN1Mode(asn1Mode))
+	}
+	if swOpts.SKIConvention != "" {
+		skiConvention, err := utils.ParseSKIConvention(swOpts.SKIConvention)
+		if err != nil {
+			return nil, err
+		}
+		swOptions = append(swOptions, sw.WithSKIConvention(skiConvention))
+	}
+
+	return sw.NewWithParams(swOpts.SecLevel, swOpts.HashFamily, ks, swOptions...)
+}
+
+// newRandSource returns the entropy source described by opts,
+// or nil when crypto/rand has to be used.
+func newRandSource(opts *RandSourceOpts) (io.Reader, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	switch opts.Type {
+	case "", RandSourceCrypto:
+		return nil, nil
+	case RandSourceDevice:
+		return sw.NewDeviceRandReader(opts.DevicePath)
+	case RandSourceDRBG:
+		seed, err := sw.NewDeviceRandReader(opts.DevicePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "DRBG requires a seed device")
+		}
+		return sw.NewSM3DRBG(seed)
+	default:
+		return nil, errors.Errorf("Unsupported random source type [%s]", opts.Type)
+	}
 }
 
 // SwOpts contains options for the SWFactory
@@ -74,6 +133,47 @@ type SwOpts struct {
 	FileKeystore  *FileKeystoreOpts  `mapstructure:"filekeystore,omitempty" json:"filekeystore,omitempty" yaml:"FileKeyStore"`
 	DummyKeystore *DummyKeystoreOpts `mapstructure:"dummykeystore,omitempty" json:"dummykeystore,omitempty"`
 	InmemKeystore *InmemKeystoreOpts `mapstructure:"inmemkeystore,omitempty" json:"inmemkeystore,omitempty"`
+
+	// Entropy source used for key generation and signing
+	RandSource *RandSourceOpts `mapstructure:"randsource,omitempty" json:"randsource,omitempty" yaml:"RandSource"`
+
+	// Use the constant-time SM4 implementation instead of the table-based one
+	ConstantTimeSM4 bool `mapstructure:"constanttimesm4,omitempty" json:"constanttimesm4,omitempty" yaml:"ConstantTimeSM4"`
+
+	// Precompute and cache SM2 signing values of private keys loaded from the keystore
+	SM2Precompute bool `mapstructure:"sm2precompute,omitempty" json:"sm2precompute,omitempty" yaml:"SM2Precompute"`
+
+	// Verify SM2 signatures with a precomputed multi-scalar multiplication
+	SM2FastVerify bool `mapstructure:"sm2fastverify,omitempty" json:"sm2fastverify,omitempty" yaml:"SM2FastVerify"`
+
+	// Read nonces and IVs from crypto/rand instead of the pooled CSPRNG
+	DisableRandPool bool `mapstructure:"disablerandpool,omitempty" json:"disablerandpool,omitempty" yaml:"DisableRandPool"`
+
+	// ASN.1 parsing mode of the signatures this provider verifies, strict
+	// (default) or lenient
+	ASN1Mode string `mapstructure:"asn1mode,omitempty" json:"asn1mode,omitempty" yaml:"ASN1Mode"`
+
+	// Derivation of the SKIs of keys, fabric (default), sha1, sha1-spki, sm3,
+	// sm3-spki, sha256 or sha256-160, to match externally issued certificates.
+	// It applies to the keys of this provider only.
+	SKIConvention string `mapstructure:"skiconvention,omitempty" json:"skiconvention,omitempty" yaml:"SKIConvention"`
+}
+
+const (
+	// RandSourceCrypto selects crypto/rand (default)
+	RandSourceCrypto = "crypto"
+	// RandSourceDevice selects a hardware TRNG exposed as a device file
+	RandSourceDevice = "device"
+	// RandSourceDRBG selects an SM3 HMAC_DRBG seeded from a device file,
+	// e.g. the entropy interface of an HSM
+	RandSourceDRBG = "drbg"
+)
+
+// RandSourceOpts selects the entropy source of the software-based BCCSP,
+// so that deployments can document where their randomness comes from.
+type RandSourceOpts struct {
+	Type       string `mapstructure:"type" json:"type" yaml:"Type"`
+	DevicePath string `mapstructure:"device,omitempty" json:"device,omitempty" yaml:"Device"`
 }
 
 // Pluggable Keystores, could add JKS, P12, etc..
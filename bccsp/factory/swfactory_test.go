@@ -19,7 +19,10 @@ import (
 	"os"
 	"testing"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSWFactoryName(t *testing.T) {
@@ -79,3 +82,113 @@ func TestSWFactoryGet(t *testing.T) {
 	assert.NotNil(t, csp)
 
 }
+
+func TestSWFactoryGetWithRandSource(t *testing.T) {
+	f := &SWFactory{}
+
+	for _, randOpts := range []*RandSourceOpts{
+		{Type: RandSourceCrypto},
+		{Type: RandSourceDevice, DevicePath: "/dev/urandom"},
+		{Type: RandSourceDRBG, DevicePath: "/dev/urandom"},
+	} {
+		opts := &FactoryOpts{
+			SwOpts: &SwOpts{
+				SecLevel:   256,
+				HashFamily: "SM3",
+				RandSource: randOpts,
+			},
+		}
+		csp, err := f.Get(opts)
+		assert.NoError(t, err)
+		assert.NotNil(t, csp)
+	}
+
+	opts := &FactoryOpts{
+		SwOpts: &SwOpts{
+			SecLevel:   256,
+			HashFamily: "SM3",
+			RandSource: &RandSourceOpts{Type: RandSourceDRBG},
+		},
+	}
+	_, err := f.Get(opts)
+	assert.EqualError(t, err, "Failed to initialize random source: DRBG requires a seed device: Invalid device path. It must not be empty.")
+
+	opts.SwOpts.RandSource = &RandSourceOpts{Type: "foo"}
+	_, err = f.Get(opts)
+	assert.EqualError(t, err, "Failed to initialize random source: Unsupported random source type [foo]")
+}
+
+func TestSWFactoryGetWithConstantTimeSM4(t *testing.T) {
+	f := &SWFactory{}
+
+	opts := &FactoryOpts{
+		SwOpts: &SwOpts{
+			SecLevel:        256,
+			HashFamily:      "SM3",
+			ConstantTimeSM4: true,
+		},
+	}
+	csp, err := f.Get(opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, csp)
+}
+
+func TestSWFactoryGetWithSM2Precompute(t *testing.T) {
+	f := &SWFactory{}
+
+	opts := &FactoryOpts{
+		SwOpts: &SwOpts{
+			SecLevel:      256,
+			HashFamily:    "SM3",
+			SM2Precompute: true,
+			InmemKeystore: &InmemKeystoreOpts{},
+		},
+	}
+	csp, err := f.Get(opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, csp)
+}
+
+func TestSWFactoryGetWithSM2FastVerify(t *testing.T) {
+	f := &SWFactory{}
+
+	opts := &FactoryOpts{
+		SwOpts: &SwOpts{
+			SecLevel:      256,
+			HashFamily:    "SM3",
+			SM2FastVerify: true,
+			InmemKeystore: &InmemKeystoreOpts{},
+		},
+	}
+	csp, err := f.Get(opts)
+	assert.NoError(t, err)
+	assert.NotNil(t, csp)
+}
+
+func TestSWFactoryGetWithSKIConvention(t *testing.T) {
+	f := &SWFactory{}
+
+	opts := &FactoryOpts{
+		SwOpts: &SwOpts{
+			SecLevel:      256,
+			HashFamily:    "SM3",
+			SKIConvention: "sha1",
+		},
+	}
+	csp, err := f.Get(opts)
+	require.NoError(t, err)
+
+	// The convention of the provider applies, the one of the process is untouched
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pub, err := k.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+	require.NoError(t, err)
+	ski, err := utils.ComputeSKI(pub, utils.SKISHA1)
+	require.NoError(t, err)
+	assert.Equal(t, ski, k.SKI())
+	assert.Equal(t, utils.SKIFabric, utils.GetSKIConvention())
+
+	opts.SwOpts.SKIConvention = "md5"
+	_, err = f.Get(opts)
+	assert.EqualError(t, err, "unknown SKI convention [md5]")
+}
@@ -0,0 +1,40 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/tee"
+	"github.com/pkg/errors"
+)
+
+const (
+	// TEEBasedFactoryName is the name of the factory of the enclave based BCCSP implementation
+	TEEBasedFactoryName = "TEE"
+)
+
+// TEEFactory is the factory of the BCCSP signing with keys sealed by an enclave.
+type TEEFactory struct{}
+
+// Name returns the name of this factory
+func (f *TEEFactory) Name() string {
+	return TEEBasedFactoryName
+}
+
+// Get returns an instance of BCCSP using Opts.
+func (f *TEEFactory) Get(config *FactoryOpts) (bccsp.BCCSP, error) {
+	// Validate arguments
+	if config == nil || config.TeeOpts == nil {
+		return nil, errors.New("Invalid config. It must not be nil.")
+	}
+
+	teeOpts := config.TeeOpts
+	ks := sw.NewDummyKeyStore()
+
+	return tee.New(*teeOpts, ks)
+}
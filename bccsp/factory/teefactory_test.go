@@ -0,0 +1,32 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package factory
+
+import (
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/tee"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTEEFactoryName(t *testing.T) {
+	f := &TEEFactory{}
+	assert.Equal(t, f.Name(), TEEBasedFactoryName)
+}
+
+func TestTEEFactoryGetInvalidArgs(t *testing.T) {
+	f := &TEEFactory{}
+
+	_, err := f.Get(nil)
+	assert.Error(t, err, "Invalid config. It must not be nil.")
+
+	_, err = f.Get(&FactoryOpts{})
+	assert.Error(t, err, "Invalid config. It must not be nil.")
+
+	_, err = GetBCCSPFromOpts(&FactoryOpts{ProviderName: "TEE", TeeOpts: &tee.TEEOpts{SecLevel: 256, HashFamily: "SM3"}})
+	assert.EqualError(t, err, "Could not initialize BCCSP TEE: Invalid options. Address must be set")
+}
@@ -0,0 +1,225 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fallback
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"hash"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_fallback")
+
+const defaultRetryInterval = 30 * time.Second
+
+// FallbackOpts configures the software fallback of a hardware provider.
+type FallbackOpts struct {
+	// Enabled turns the fallback on
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+	// RetryInterval is how long the device is bypassed for verification
+	// after it failed, before it is tried again
+	RetryInterval time.Duration `mapstructure:"retryinterval,omitempty" json:"retryinterval,omitempty" yaml:"RetryInterval"`
+}
+
+// New returns a BCCSP that serves every operation with primary, typically
+// an HSM or SDF device provider. When primary fails, operations which do
+// not involve private or secret keys, i.e. public key import, hashing and
+// signature verification, are served by verifier instead. Key generation,
+// derivation, retrieval, signing and encryption never fall back.
+func New(primary, verifier bccsp.BCCSP, opts FallbackOpts) (bccsp.BCCSP, error) {
+	if primary == nil || verifier == nil {
+		return nil, errors.New("Invalid BCCSP instances. They must be different from nil")
+	}
+
+	retryInterval := opts.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = defaultRetryInterval
+	}
+
+	return &impl{
+		BCCSP:         primary,
+		verifier:      verifier,
+		retryInterval: retryInterval,
+		now:           time.Now,
+	}, nil
+}
+
+type impl struct {
+	// primary serves all operations that are not overridden
+	bccsp.BCCSP
+
+	verifier      bccsp.BCCSP
+	retryInterval time.Duration
+	now           func() time.Time
+
+	mutex     sync.Mutex
+	downUntil time.Time
+}
+
+// Unwrap returns the primary BCCSP.
+func (csp *impl) Unwrap() bccsp.BCCSP {
+	return csp.BCCSP
+}
+
+// SignCtx signs digest using key k, handing ctx to the primary BCCSP when
+// it implements bccsp.ContextSigner.
+func (csp *impl) SignCtx(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return bccsp.SignCtx(ctx, csp.BCCSP, k, digest, opts)
+}
+
+// primaryDown reports whether the primary failed within the retry interval.
+func (csp *impl) primaryDown() bool {
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+	return csp.now().Before(csp.downUntil)
+}
+
+// markDown records that the primary failed where the verifier succeeded.
+func (csp *impl) markDown(op string, err error) {
+	csp.mutex.Lock()
+	defer csp.mutex.Unlock()
+	if !csp.now().Before(csp.downUntil) {
+		logger.Warningf("Primary BCCSP failed %s [%s], falling back to software for verification for %s", op, err, csp.retryInterval)
+	}
+	csp.downUntil = csp.now().Add(csp.retryInterval)
+}
+
+// isPublicKeyImport reports whether opts imports public key material only.
+func isPublicKeyImport(opts bccsp.KeyImportOpts) bool {
+	switch opts.(type) {
+	case *bccsp.X509PublicKeyImportOpts,
+		*bccsp.ECDSAPKIXPublicKeyImportOpts,
+		*bccsp.ECDSAGoPublicKeyImportOpts,
+		*bccsp.SM2PKIXPublicKeyImportOpts,
+		*bccsp.SM2GoPublicKeyImportOpts:
+		return true
+	default:
+		return false
+	}
+}
+
+// KeyImport imports a key from its raw representation using opts.
+// Public keys are imported into the verifier when the primary fails.
+func (csp *impl) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	if !isPublicKeyImport(opts) {
+		return csp.BCCSP.KeyImport(raw, opts)
+	}
+	if csp.primaryDown() {
+		return csp.verifier.KeyImport(raw, opts)
+	}
+
+	k, err := csp.BCCSP.KeyImport(raw, opts)
+	if err == nil {
+		return k, nil
+	}
+	k, verr := csp.verifier.KeyImport(raw, opts)
+	if verr != nil {
+		return nil, err
+	}
+	csp.markDown("KeyImport", err)
+	return k, nil
+}
+
+// Hash hashes messages msg using options opts.
+func (csp *impl) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	if csp.primaryDown() {
+		return csp.verifier.Hash(msg, opts)
+	}
+
+	digest, err := csp.BCCSP.Hash(msg, opts)
+	if err == nil {
+		return digest, nil
+	}
+	digest, verr := csp.verifier.Hash(msg, opts)
+	if verr != nil {
+		return nil, err
+	}
+	csp.markDown("Hash", err)
+	return digest, nil
+}
+
+// GetHash returns and instance of hash.Hash using options opts.
+func (csp *impl) GetHash(opts bccsp.HashOpts) (hash.Hash, error) {
+	if csp.primaryDown() {
+		return csp.verifier.GetHash(opts)
+	}
+	return csp.BCCSP.GetHash(opts)
+}
+
+// Verify verifies signature against key k and digest. If the primary
+// fails, the public part of k is verified against in software.
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if k == nil || k.Symmetric() {
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+	if csp.primaryDown() {
+		return csp.verifySoftware(k, signature, digest, opts)
+	}
+
+	valid, err := csp.BCCSP.Verify(k, signature, digest, opts)
+	if err == nil {
+		return valid, nil
+	}
+	valid, verr := csp.verifySoftware(k, signature, digest, opts)
+	if verr != nil {
+		return false, err
+	}
+	csp.markDown("Verify", err)
+	return valid, nil
+}
+
+func (csp *impl) verifySoftware(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	pk, err := csp.softwarePublicKey(k)
+	if err != nil {
+		return false, err
+	}
+	return csp.verifier.Verify(pk, signature, digest, opts)
+}
+
+// softwarePublicKey imports the public part of k into the verifier.
+func (csp *impl) softwarePublicKey(k bccsp.Key) (bccsp.Key, error) {
+	if k.Private() {
+		var err error
+		if k, err = k.PublicKey(); err != nil {
+			return nil, errors.Wrap(err, "Failed getting public key")
+		}
+	}
+
+	var pub crypto.PublicKey
+	if cpk, ok := k.(bccsp.CryptoPublicKeyer); ok {
+		var err error
+		if pub, err = cpk.CryptoPublicKey(); err != nil {
+			return nil, err
+		}
+	} else {
+		raw, err := k.Bytes()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed marshalling public key")
+		}
+		if pub, err = utils.DERToPublicKey(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	switch pub.(type) {
+	case *ecdsa.PublicKey:
+		return csp.verifier.KeyImport(pub, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	case *sm2.PublicKey:
+		return csp.verifier.KeyImport(pub, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	default:
+		return nil, errors.Errorf("Unsupported public key type [%T]", pub)
+	}
+}
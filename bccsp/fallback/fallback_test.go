@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fallback
+
+import (
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDevice = errors.New("device unavailable")
+
+// device is a primary BCCSP that can be switched off.
+type device struct {
+	bccsp.BCCSP
+	down  bool
+	calls int
+}
+
+func (d *device) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	d.calls++
+	if d.down {
+		return nil, errDevice
+	}
+	return d.BCCSP.KeyImport(raw, opts)
+}
+
+func (d *device) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	d.calls++
+	if d.down {
+		return nil, errDevice
+	}
+	return d.BCCSP.Sign(k, digest, opts)
+}
+
+func (d *device) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	d.calls++
+	if d.down {
+		return false, errDevice
+	}
+	return d.BCCSP.Verify(k, signature, digest, opts)
+}
+
+func (d *device) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	d.calls++
+	if d.down {
+		return nil, errDevice
+	}
+	return d.BCCSP.Hash(msg, opts)
+}
+
+func newTestCSP(t *testing.T) (*impl, *device) {
+	primary, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	verifier, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	d := &device{BCCSP: primary}
+	csp, err := New(d, verifier, FallbackOpts{Enabled: true, RetryInterval: time.Minute})
+	require.NoError(t, err)
+	return csp.(*impl), d
+}
+
+func TestNewInvalidArgs(t *testing.T) {
+	_, err := New(nil, nil, FallbackOpts{})
+	assert.EqualError(t, err, "Invalid BCCSP instances. They must be different from nil")
+}
+
+func TestVerifyFallback(t *testing.T) {
+	csp, d := newTestCSP(t)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+	msg := []byte("block data")
+	sig, err := csp.Sign(k, msg, nil)
+	require.NoError(t, err)
+
+	d.down = true
+
+	// Private key operations never fall back
+	_, err = csp.Sign(k, msg, nil)
+	assert.Equal(t, errDevice, err)
+
+	valid, err := csp.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	valid, err = csp.Verify(pub, sig, []byte("tampered"), nil)
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	digest, err := csp.Hash(msg, &bccsp.SM3Opts{})
+	require.NoError(t, err)
+	assert.Len(t, digest, 32)
+
+	// Still down, the device is bypassed
+	calls := d.calls
+	_, err = csp.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.Equal(t, calls, d.calls)
+	_, err = csp.Sign(k, msg, nil)
+	assert.Equal(t, errDevice, err)
+	assert.Equal(t, calls+1, d.calls)
+}
+
+func TestRetryPrimary(t *testing.T) {
+	csp, d := newTestCSP(t)
+	now := time.Now()
+	csp.now = func() time.Time { return now }
+
+	d.down = true
+	_, err := csp.Hash([]byte("msg"), &bccsp.SM3Opts{})
+	require.NoError(t, err)
+	assert.True(t, csp.primaryDown())
+
+	d.down = false
+	now = now.Add(2 * time.Minute)
+	assert.False(t, csp.primaryDown())
+
+	calls := d.calls
+	_, err = csp.Hash([]byte("msg"), &bccsp.SM3Opts{})
+	require.NoError(t, err)
+	assert.Equal(t, calls+1, d.calls)
+}
+
+func TestKeyImportFallback(t *testing.T) {
+	csp, d := newTestCSP(t)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	der, err := pub.Bytes()
+	require.NoError(t, err)
+	_, err = x509.ParsePKIXPublicKey(der)
+	require.NoError(t, err)
+
+	d.down = true
+	imported, err := csp.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.Equal(t, pub.SKI(), imported.SKI())
+
+	// Private material is never imported into the verifier
+	_, err = csp.KeyImport([]byte{1, 2, 3}, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true})
+	assert.Equal(t, errDevice, err)
+
+	// Errors of both providers surface the primary error
+	csp.downUntil = time.Time{}
+	_, err = csp.KeyImport([]byte("garbage"), &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	assert.Equal(t, errDevice, err)
+	assert.False(t, csp.primaryDown())
+}
@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmtls
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	ccmNonceLen = 12
+	ccmTagLen   = 16
+
+	// ccmLengthLen is the size of the message length of the counter
+	// blocks, L in RFC 3610, which the nonce size leaves
+	ccmLengthLen = 15 - ccmNonceLen
+)
+
+var errCCMOpen = errors.New("gmtls: message authentication failed")
+
+// ccm is the CCM mode of RFC 3610 over a 128-bit block cipher, with
+// 12-byte nonces, as TLS_SM4_CCM_SM3 uses it.
+type ccm struct {
+	block  cipher.Block
+	tagLen int
+}
+
+// newCCM returns the CCM mode of block with tags of tagLen bytes.
+func newCCM(block cipher.Block, tagLen int) (cipher.AEAD, error) {
+	if block.BlockSize() != 16 {
+		return nil, errors.New("gmtls: CCM requires a 128-bit block cipher")
+	}
+	if tagLen < 4 || tagLen > 16 || tagLen%2 != 0 {
+		return nil, errors.New("gmtls: invalid CCM tag size")
+	}
+	return &ccm{block: block, tagLen: tagLen}, nil
+}
+
+func (c *ccm) NonceSize() int {
+	return ccmNonceLen
+}
+
+func (c *ccm) Overhead() int {
+	return c.tagLen
+}
+
+// counter returns the counter block i of nonce.
+func (c *ccm) counter(nonce []byte, i uint32) []byte {
+	a := make([]byte, 16)
+	a[0] = ccmLengthLen - 1
+	copy(a[1:], nonce)
+	a[13], a[14], a[15] = byte(i>>16), byte(i>>8), byte(i)
+	return a
+}
+
+// ctr XORs the key stream of nonce, from counter block 1, into dst.
+func (c *ccm) ctr(dst, src, nonce []byte) {
+	stream := make([]byte, 16)
+	for i := 0; len(src) > 0; i++ {
+		c.block.Encrypt(stream, c.counter(nonce, uint32(i+1)))
+		n := xorBytes(dst, src, stream)
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// mac returns the CBC-MAC of plaintext and additionalData, encrypted with
+// counter block 0.
+func (c *ccm) mac(nonce, plaintext, additionalData []byte) []byte {
+	b := make([]byte, 16)
+	b[0] = byte((c.tagLen-2)/2<<3 | (ccmLengthLen - 1))
+	if len(additionalData) > 0 {
+		b[0] |= 0x40
+	}
+	copy(b[1:], nonce)
+	n := len(plaintext)
+	b[13], b[14], b[15] = byte(n>>16), byte(n>>8), byte(n)
+
+	mac := make([]byte, 16)
+	c.block.Encrypt(mac, b)
+	update := func(data []byte) {
+		for len(data) > 0 {
+			n := xorBytes(mac, mac, data)
+			c.block.Encrypt(mac, mac)
+			data = data[n:]
+		}
+	}
+	if len(additionalData) > 0 {
+		// Additional data is shorter than 2^16 - 2^8 bytes in records
+		header := make([]byte, 2, 2+len(additionalData))
+		binary.BigEndian.PutUint16(header, uint16(len(additionalData)))
+		update(append(header, additionalData...))
+	}
+	update(plaintext)
+
+	s0 := make([]byte, 16)
+	c.block.Encrypt(s0, c.counter(nonce, 0))
+	xorBytes(mac, mac, s0)
+	return mac[:c.tagLen]
+}
+
+func (c *ccm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != ccmNonceLen {
+		panic("gmtls: incorrect nonce length given to CCM")
+	}
+	if len(plaintext) >= 1<<(8*ccmLengthLen) || len(additionalData) >= 1<<16-1<<8 {
+		panic("gmtls: message too large for CCM")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+c.tagLen)
+	tag := c.mac(nonce, plaintext, additionalData)
+	c.ctr(out, plaintext, nonce)
+	copy(out[len(plaintext):], tag)
+	return ret
+}
+
+func (c *ccm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != ccmNonceLen {
+		panic("gmtls: incorrect nonce length given to CCM")
+	}
+	if len(ciphertext) < c.tagLen || len(ciphertext)-c.tagLen >= 1<<(8*ccmLengthLen) || len(additionalData) >= 1<<16-1<<8 {
+		return nil, errCCMOpen
+	}
+	tag := ciphertext[len(ciphertext)-c.tagLen:]
+	ciphertext = ciphertext[:len(ciphertext)-c.tagLen]
+	plaintext := make([]byte, len(ciphertext))
+	c.ctr(plaintext, ciphertext, nonce)
+	if subtle.ConstantTimeCompare(c.mac(nonce, plaintext, additionalData), tag) != 1 {
+		return nil, errCCMOpen
+	}
+	ret, out := sliceForAppend(dst, len(plaintext))
+	copy(out, plaintext)
+	return ret, nil
+}
+
+// xorBytes sets dst to the XOR of the common prefix of a and b, and
+// returns its length.
+func xorBytes(dst, a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	for i := 0; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+	return n
+}
+
+// sliceForAppend extends in by n bytes, returning the whole slice and the
+// extension.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,346 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package gmtls implements the GMTLS protocol of GM/T 0024, the TLS profile
+// of the Chinese national cryptographic standards, with its ECC_SM4_CBC_SM3
+// cipher suite: peers authenticate with SM2 signing certificates, clients
+// encrypt the premaster secret for the SM2 encryption certificate of the
+// server, and records are protected with SM4 in CBC mode and HMAC-SM3.
+//
+// Each peer holds two certificates, as GM/T 0024 requires: a signing
+// certificate and an encryption certificate, sent in this order followed
+// by the intermediates of the signing certificate.
+//
+// The package also implements TLS 1.3 with the SM cipher suites of RFC
+// 8998, TLS_SM4_GCM_SM3 and TLS_SM4_CCM_SM3, the standards track
+// alternative to GM/T 0024: peers exchange ephemeral keys on curveSM2 and
+// authenticate with the sm2sig_sm3 signatures of a single SM2
+// certificate. Resumption, early data and HelloRetryRequest are not
+// supported.
+package gmtls
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm4"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+const (
+	// VersionGMTLS is the protocol version of GM/T 0024
+	VersionGMTLS uint16 = 0x0101
+
+	// ECC_SM4_CBC_SM3 is the cipher suite of GM/T 0024 this package
+	// implements
+	ECC_SM4_CBC_SM3 uint16 = 0xe013
+
+	// VersionTLS13 is the protocol version of TLS 1.3
+	VersionTLS13 uint16 = 0x0304
+
+	// TLS_SM4_GCM_SM3 and TLS_SM4_CCM_SM3 are the TLS 1.3 cipher suites of
+	// RFC 8998
+	TLS_SM4_GCM_SM3 uint16 = 0x00c6
+	TLS_SM4_CCM_SM3 uint16 = 0x00c7
+)
+
+const (
+	recordTypeChangeCipherSpec uint8 = 20
+	recordTypeAlert            uint8 = 21
+	recordTypeHandshake        uint8 = 22
+	recordTypeApplicationData  uint8 = 23
+
+	typeClientHello         uint8 = 1
+	typeServerHello         uint8 = 2
+	typeNewSessionTicket    uint8 = 4
+	typeEncryptedExtensions uint8 = 8
+	typeCertificate         uint8 = 11
+	typeServerKeyExchange   uint8 = 12
+	typeCertificateRequest  uint8 = 13
+	typeServerHelloDone     uint8 = 14
+	typeCertificateVerify   uint8 = 15
+	typeClientKeyExchange   uint8 = 16
+	typeFinished            uint8 = 20
+	typeKeyUpdate           uint8 = 24
+
+	// certTypeECDSASign is the certificate type of the certificate
+	// requests, ecdsa_sign in GM/T 0024 as in RFC 4492
+	certTypeECDSASign uint8 = 64
+
+	maxPlaintext       = 16384
+	maxCiphertext      = maxPlaintext + 2048
+	maxHandshake       = 65536
+	recordHeaderLen    = 5
+	randomLen          = 32
+	premasterLen       = 48
+	masterSecretLen    = 48
+	finishedVerifyLen  = 12
+	macKeyLen          = 32
+	sm4KeyLen          = 16
+	sm4BlockLen        = 16
+	sm3Len             = 32
+	aeadNonceLen       = 12
+	maxPeerCertificate = 16
+)
+
+type alert uint8
+
+const (
+	alertCloseNotify         alert = 0
+	alertUnexpectedMessage   alert = 10
+	alertBadRecordMAC        alert = 20
+	alertRecordOverflow      alert = 22
+	alertHandshakeFailure    alert = 40
+	alertBadCertificate      alert = 42
+	alertUnsupportedCert     alert = 43
+	alertCertificateUnknown  alert = 46
+	alertIllegalParameter    alert = 47
+	alertUnknownCA           alert = 48
+	alertDecodeError         alert = 50
+	alertDecryptError        alert = 51
+	alertProtocolVersion     alert = 70
+	alertInternalError       alert = 80
+	alertMissingExtension    alert = 109
+	alertCertificateRequired alert = 116
+)
+
+var alertText = map[alert]string{
+	alertCloseNotify:         "close notify",
+	alertUnexpectedMessage:   "unexpected message",
+	alertBadRecordMAC:        "bad record MAC",
+	alertRecordOverflow:      "record overflow",
+	alertHandshakeFailure:    "handshake failure",
+	alertBadCertificate:      "bad certificate",
+	alertUnsupportedCert:     "unsupported certificate",
+	alertCertificateUnknown:  "unknown certificate",
+	alertIllegalParameter:    "illegal parameter",
+	alertUnknownCA:           "unknown certificate authority",
+	alertDecodeError:         "error decoding message",
+	alertDecryptError:        "error decrypting message",
+	alertProtocolVersion:     "protocol version not supported",
+	alertInternalError:       "internal error",
+	alertMissingExtension:    "missing extension",
+	alertCertificateRequired: "certificate required",
+}
+
+func (e alert) String() string {
+	if s, ok := alertText[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("alert(%d)", uint8(e))
+}
+
+func (e alert) Error() string {
+	return "gmtls: " + e.String()
+}
+
+// Certificate is an SM2 certificate chain and its private key.
+type Certificate struct {
+	// Certificate holds the DER certificate, followed by its
+	// intermediates
+	Certificate [][]byte
+	// PrivateKey is the private key of the first certificate
+	PrivateKey *sm2.PrivateKey
+	// Leaf is the parsed first certificate
+	Leaf *x509.Certificate
+}
+
+// X509KeyPair parses an SM2 certificate chain and its private key from
+// PEM, like tls.X509KeyPair.
+func X509KeyPair(certPEMBlock, keyPEMBlock []byte) (Certificate, error) {
+	var cert Certificate
+	for rest := certPEMBlock; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			cert.Certificate = append(cert.Certificate, block.Bytes)
+		}
+	}
+	if len(cert.Certificate) == 0 {
+		return Certificate{}, errors.New("gmtls: failed to find any PEM certificate")
+	}
+
+	leaf, err := gmx509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return Certificate{}, fmt.Errorf("gmtls: failed to parse certificate [%s]", err)
+	}
+	pub, ok := leaf.PublicKey.(*sm2.PublicKey)
+	if !ok {
+		return Certificate{}, fmt.Errorf("gmtls: certificate has a %T public key, not an SM2 key", leaf.PublicKey)
+	}
+	key, err := utils.PEMtoSM2PrivateKey(keyPEMBlock, nil)
+	if err != nil {
+		return Certificate{}, fmt.Errorf("gmtls: failed to parse private key [%s]", err)
+	}
+	if key.X.Cmp(pub.X) != 0 || key.Y.Cmp(pub.Y) != 0 {
+		return Certificate{}, errors.New("gmtls: private key does not match public key")
+	}
+	cert.PrivateKey = key
+	cert.Leaf = leaf
+	return cert, nil
+}
+
+// Config configures a GMTLS client or server. A Config must not be
+// modified once passed to Client or Server.
+type Config struct {
+	// SignCertificate is the signing certificate peers authenticate with.
+	// Servers must have one, as must clients presenting a certificate.
+	SignCertificate *Certificate
+
+	// EncCertificate is the encryption certificate clients encrypt the
+	// premaster secret for. Over GM/T 0024, servers must have one, and
+	// clients presenting a SignCertificate too. TLS 1.3 ignores it.
+	EncCertificate *Certificate
+
+	// Version is the protocol of connections: VersionGMTLS, the default,
+	// or VersionTLS13
+	Version uint16
+
+	// CipherSuites are the TLS 1.3 cipher suites of connections, in order
+	// of preference, TLS_SM4_GCM_SM3 then TLS_SM4_CCM_SM3 if empty
+	CipherSuites []uint16
+
+	// RootCAs are the authorities clients verify servers against
+	RootCAs *gmx509.CertPool
+
+	// ClientCAs are the authorities servers verify clients against
+	ClientCAs *gmx509.CertPool
+
+	// ClientAuth is the policy of servers for client authentication
+	ClientAuth tls.ClientAuthType
+
+	// ServerName is the host name clients check the signing certificate
+	// of the server against, if not empty
+	ServerName string
+
+	// InsecureSkipVerify makes clients skip the verification of the
+	// certificates of the server
+	InsecureSkipVerify bool
+
+	// VerifyPeerCertificate, if not nil, is called after the normal
+	// verification of the certificates of the peer, which rawCerts holds.
+	// If it returns an error, the handshake fails with it.
+	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+
+	// Time returns the current time, time.Now if nil
+	Time func() time.Time
+
+	// Rand is the source of entropy, crypto/rand.Reader if nil
+	Rand io.Reader
+}
+
+// Clone returns a shallow copy of c.
+func (c *Config) Clone() *Config {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	return &clone
+}
+
+func (c *Config) rand() io.Reader {
+	if c.Rand == nil {
+		return rand.Reader
+	}
+	return c.Rand
+}
+
+func (c *Config) time() time.Time {
+	if c.Time == nil {
+		return time.Now()
+	}
+	return c.Time()
+}
+
+func (c *Config) version() uint16 {
+	if c.Version == 0 {
+		return VersionGMTLS
+	}
+	return c.Version
+}
+
+// cipherSuites13 returns the TLS 1.3 cipher suites of the configuration.
+func (c *Config) cipherSuites13() ([]uint16, error) {
+	if len(c.CipherSuites) == 0 {
+		return defaultCipherSuites13, nil
+	}
+	for _, suite := range c.CipherSuites {
+		if CipherSuiteName(suite) == "" || suite == ECC_SM4_CBC_SM3 {
+			return nil, fmt.Errorf("gmtls: unsupported TLS 1.3 cipher suite %#04x", suite)
+		}
+	}
+	return c.CipherSuites, nil
+}
+
+var defaultCipherSuites13 = []uint16{TLS_SM4_GCM_SM3, TLS_SM4_CCM_SM3}
+
+var cipherSuiteNames = map[uint16]string{
+	ECC_SM4_CBC_SM3: "ECC_SM4_CBC_SM3",
+	TLS_SM4_GCM_SM3: "TLS_SM4_GCM_SM3",
+	TLS_SM4_CCM_SM3: "TLS_SM4_CCM_SM3",
+}
+
+// CipherSuiteName returns the name of a cipher suite of the package, or
+// an empty string.
+func CipherSuiteName(id uint16) string {
+	return cipherSuiteNames[id]
+}
+
+// CipherSuiteID returns the cipher suite of the package named name.
+func CipherSuiteID(name string) (uint16, bool) {
+	for id, n := range cipherSuiteNames {
+		if n == name {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// newAEAD returns the AEAD of a TLS 1.3 cipher suite keyed with key.
+func newAEAD(suite uint16, key []byte) (cipher.AEAD, error) {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	switch suite {
+	case TLS_SM4_GCM_SM3:
+		return cipher.NewGCM(block)
+	case TLS_SM4_CCM_SM3:
+		return newCCM(block, ccmTagLen)
+	}
+	return nil, fmt.Errorf("gmtls: unsupported TLS 1.3 cipher suite %#04x", suite)
+}
+
+// ConnectionState records basic GMTLS details about a connection.
+type ConnectionState struct {
+	// Version is the protocol version of the connection
+	Version uint16
+	// HandshakeComplete is true once the handshake has concluded
+	HandshakeComplete bool
+	// CipherSuite is the cipher suite of the connection
+	CipherSuite uint16
+	// ServerName is the host name the client checked
+	ServerName string
+	// PeerCertificates are the certificates the peer sent: the signing
+	// certificate, the encryption certificate and intermediates, or over
+	// TLS 1.3 the certificate and its intermediates
+	PeerCertificates []*x509.Certificate
+	// VerifiedChains are the chains of the signing certificate of the
+	// peer, if verified
+	VerifiedChains [][]*x509.Certificate
+}
@@ -0,0 +1,642 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmtls
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm4"
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// Conn is a GMTLS connection, implementing net.Conn.
+type Conn struct {
+	conn     net.Conn
+	isClient bool
+	config   *Config
+
+	handshakeMutex sync.Mutex
+	handshakeErr   error
+	handshakeDone  bool
+	state          ConnectionState
+
+	// in, input, hand and readErr are guarded by inMutex
+	inMutex sync.Mutex
+	in      halfConn
+	input   []byte
+	hand    []byte
+	readErr error
+
+	// out and writeErr are guarded by outMutex
+	outMutex sync.Mutex
+	out      halfConn
+	writeErr error
+}
+
+// Client returns a new GMTLS client side connection over conn. The
+// configuration must hold RootCAs or set InsecureSkipVerify.
+func Client(conn net.Conn, config *Config) *Conn {
+	return &Conn{conn: conn, isClient: true, config: config}
+}
+
+// Server returns a new GMTLS server side connection over conn. The
+// configuration must hold a SignCertificate and an EncCertificate.
+func Server(conn net.Conn, config *Config) *Conn {
+	return &Conn{conn: conn, config: config}
+}
+
+type listener struct {
+	net.Listener
+	config *Config
+}
+
+// Accept waits for the next connection and returns it as a GMTLS server
+// side connection, whose handshake runs on its first Read or Write.
+func (l *listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return Server(conn, l.config), nil
+}
+
+// NewListener returns a listener accepting the connections of inner as GMTLS
+// server side connections. The configuration must be valid for Server.
+func NewListener(inner net.Listener, config *Config) net.Listener {
+	return &listener{Listener: inner, config: config}
+}
+
+// halfConn is the protection state of one direction of a connection.
+type halfConn struct {
+	block cipher.Block
+	mac   hash.Hash
+	seq   [8]byte
+
+	nextBlock cipher.Block
+	nextMAC   hash.Hash
+
+	// aead, iv, suite and secret protect the records of TLS 1.3
+	aead   cipher.AEAD
+	iv     []byte
+	suite  uint16
+	secret []byte
+}
+
+// prepareCipherSpec sets the keys the next ChangeCipherSpec enables.
+func (hc *halfConn) prepareCipherSpec(macKey, key []byte) error {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	hc.nextBlock = block
+	hc.nextMAC = hmac.New(sm3.New, macKey)
+	return nil
+}
+
+// changeCipherSpec enables the prepared keys and resets the sequence
+// number.
+func (hc *halfConn) changeCipherSpec() error {
+	if hc.nextBlock == nil {
+		return alertUnexpectedMessage
+	}
+	hc.block, hc.mac = hc.nextBlock, hc.nextMAC
+	hc.nextBlock, hc.nextMAC = nil, nil
+	hc.seq = [8]byte{}
+	return nil
+}
+
+// setTrafficSecret protects the next TLS 1.3 records with the keys of a
+// traffic secret and resets the sequence number.
+func (hc *halfConn) setTrafficSecret(suite uint16, secret []byte) error {
+	key, iv := trafficKey(secret)
+	aead, err := newAEAD(suite, key)
+	if err != nil {
+		return err
+	}
+	hc.aead, hc.iv, hc.suite, hc.secret = aead, iv, suite, secret
+	hc.seq = [8]byte{}
+	return nil
+}
+
+func (hc *halfConn) incSeq() {
+	for i := 7; i >= 0; i-- {
+		hc.seq[i]++
+		if hc.seq[i] != 0 {
+			return
+		}
+	}
+	panic("gmtls: sequence number wraparound")
+}
+
+// macOf returns the MAC of a record of type typ holding payload.
+func (hc *halfConn) macOf(typ uint8, payload []byte) []byte {
+	var header [13]byte
+	copy(header[:8], hc.seq[:])
+	header[8] = typ
+	binary.BigEndian.PutUint16(header[9:], VersionGMTLS)
+	binary.BigEndian.PutUint16(header[11:], uint16(len(payload)))
+	hc.mac.Reset()
+	hc.mac.Write(header[:])
+	hc.mac.Write(payload)
+	return hc.mac.Sum(nil)
+}
+
+// encrypt returns the fragment of a record of type typ holding payload:
+// the explicit IV followed by the SM4-CBC encryption of payload, its MAC
+// and the padding.
+func (hc *halfConn) encrypt(rand io.Reader, typ uint8, payload []byte) ([]byte, error) {
+	if hc.block == nil {
+		return payload, nil
+	}
+	mac := hc.macOf(typ, payload)
+	n := len(payload) + len(mac) + 1
+	padLen := (sm4BlockLen - n%sm4BlockLen) % sm4BlockLen
+	out := make([]byte, sm4BlockLen, sm4BlockLen+n+padLen)
+	if _, err := io.ReadFull(rand, out); err != nil {
+		return nil, err
+	}
+	out = append(out, payload...)
+	out = append(out, mac...)
+	for i := 0; i <= padLen; i++ {
+		out = append(out, byte(padLen))
+	}
+	cipher.NewCBCEncrypter(hc.block, out[:sm4BlockLen]).CryptBlocks(out[sm4BlockLen:], out[sm4BlockLen:])
+	hc.incSeq()
+	return out, nil
+}
+
+// decrypt returns the payload of a record of type typ whose fragment is
+// fragment.
+func (hc *halfConn) decrypt(typ uint8, fragment []byte) ([]byte, error) {
+	if hc.block == nil {
+		return fragment, nil
+	}
+	macLen := hc.mac.Size()
+	if len(fragment)%sm4BlockLen != 0 || len(fragment) < sm4BlockLen+roundUp(macLen+1) {
+		return nil, alertBadRecordMAC
+	}
+	plain := make([]byte, len(fragment)-sm4BlockLen)
+	cipher.NewCBCDecrypter(hc.block, fragment[:sm4BlockLen]).CryptBlocks(plain, fragment[sm4BlockLen:])
+
+	padLen := int(plain[len(plain)-1])
+	good := padLen+1+macLen <= len(plain)
+	if good {
+		for _, b := range plain[len(plain)-1-padLen:] {
+			good = good && int(b) == padLen
+		}
+	}
+	if !good {
+		return nil, alertBadRecordMAC
+	}
+	payload := plain[:len(plain)-1-padLen-macLen]
+	mac := plain[len(payload) : len(payload)+macLen]
+	if !hmac.Equal(hc.macOf(typ, payload), mac) {
+		return nil, alertBadRecordMAC
+	}
+	hc.incSeq()
+	return payload, nil
+}
+
+// nonce returns the nonce of the next TLS 1.3 record, the IV XORed with
+// the sequence number.
+func (hc *halfConn) nonce() []byte {
+	nonce := make([]byte, aeadNonceLen)
+	copy(nonce, hc.iv)
+	for i, b := range hc.seq {
+		nonce[aeadNonceLen-8+i] ^= b
+	}
+	return nonce
+}
+
+// seal returns the fragment of a TLS 1.3 record holding payload of type
+// typ, which the encryption hides behind the application_data type.
+func (hc *halfConn) seal(typ uint8, payload []byte) []byte {
+	inner := make([]byte, 0, len(payload)+1+hc.aead.Overhead())
+	inner = append(inner, payload...)
+	inner = append(inner, typ)
+	header := []byte{recordTypeApplicationData, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(header[1:], legacyVersionTLS12)
+	binary.BigEndian.PutUint16(header[3:], uint16(len(inner)+hc.aead.Overhead()))
+	fragment := hc.aead.Seal(inner[:0], hc.nonce(), inner, header)
+	hc.incSeq()
+	return fragment
+}
+
+// open returns the type and the payload of a TLS 1.3 record whose header
+// and fragment are header and fragment.
+func (hc *halfConn) open(header, fragment []byte) (uint8, []byte, error) {
+	inner, err := hc.aead.Open(fragment[:0], hc.nonce(), fragment, header)
+	if err != nil {
+		return 0, nil, alertBadRecordMAC
+	}
+	hc.incSeq()
+	// The type is the last non-zero byte, followed by the padding
+	i := len(inner) - 1
+	for i >= 0 && inner[i] == 0 {
+		i--
+	}
+	if i < 0 {
+		return 0, nil, alertUnexpectedMessage
+	}
+	return inner[i], inner[:i], nil
+}
+
+func roundUp(n int) int {
+	return (n + sm4BlockLen - 1) / sm4BlockLen * sm4BlockLen
+}
+
+// readRecord reads the next record and returns its type and payload.
+// Alerts are turned into errors. inMutex must be held.
+func (c *Conn) readRecord() (uint8, []byte, error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	typ, payload, err := c.readRawRecord()
+	if err != nil {
+		if a, ok := err.(alert); ok {
+			c.sendAlert(a)
+		}
+		c.readErr = err
+		return 0, nil, err
+	}
+	if typ != recordTypeAlert {
+		return typ, payload, nil
+	}
+
+	if len(payload) != 2 {
+		c.sendAlert(alertDecodeError)
+		c.readErr = alertDecodeError
+	} else if a := alert(payload[1]); a == alertCloseNotify {
+		c.readErr = io.EOF
+	} else {
+		c.readErr = fmt.Errorf("gmtls: remote error: %s", a.String())
+	}
+	return 0, nil, c.readErr
+}
+
+// recordVersion is the version of the records of the connection, which
+// TLS 1.3 leaves at that of TLS 1.2.
+func (c *Conn) recordVersion() uint16 {
+	if c.config.version() == VersionTLS13 {
+		return legacyVersionTLS12
+	}
+	return VersionGMTLS
+}
+
+// readRawRecord reads a record from the underlying connection and removes
+// its protection.
+func (c *Conn) readRawRecord() (uint8, []byte, error) {
+	tls13 := c.config.version() == VersionTLS13
+	for {
+		header := make([]byte, recordHeaderLen)
+		if _, err := io.ReadFull(c.conn, header); err != nil {
+			return 0, nil, err
+		}
+		typ := header[0]
+		// The ClientHello of TLS 1.3 may carry the version of TLS 1.0
+		if v := binary.BigEndian.Uint16(header[1:]); v != c.recordVersion() && !(tls13 && v == legacyVersionTLS10) {
+			return 0, nil, alertProtocolVersion
+		}
+		n := int(binary.BigEndian.Uint16(header[3:]))
+		if n > maxCiphertext {
+			return 0, nil, alertRecordOverflow
+		}
+		fragment := make([]byte, n)
+		if _, err := io.ReadFull(c.conn, fragment); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, nil, err
+		}
+
+		var payload []byte
+		var err error
+		switch {
+		case tls13 && typ == recordTypeChangeCipherSpec:
+			// TLS 1.3 peers in middlebox compatibility mode send an
+			// unprotected ChangeCipherSpec, which is dropped
+			if n != 1 || fragment[0] != 1 {
+				return 0, nil, alertUnexpectedMessage
+			}
+			continue
+		case c.in.aead != nil:
+			if typ != recordTypeApplicationData {
+				return 0, nil, alertUnexpectedMessage
+			}
+			typ, payload, err = c.in.open(header, fragment)
+		default:
+			payload, err = c.in.decrypt(typ, fragment)
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		if len(payload) > maxPlaintext {
+			return 0, nil, alertRecordOverflow
+		}
+		return typ, payload, nil
+	}
+}
+
+// writeRecord writes data in records of type typ.
+func (c *Conn) writeRecord(typ uint8, data []byte) (int, error) {
+	c.outMutex.Lock()
+	defer c.outMutex.Unlock()
+	return c.writeRecordLocked(typ, data)
+}
+
+func (c *Conn) writeRecordLocked(typ uint8, data []byte) (int, error) {
+	if c.writeErr != nil {
+		return 0, c.writeErr
+	}
+	var n int
+	for len(data) > 0 || n == 0 {
+		m := len(data)
+		if m > maxPlaintext {
+			m = maxPlaintext
+		}
+		recordType := typ
+		var fragment []byte
+		if c.out.aead != nil {
+			recordType = recordTypeApplicationData
+			fragment = c.out.seal(typ, data[:m])
+		} else {
+			var err error
+			if fragment, err = c.out.encrypt(c.config.rand(), typ, data[:m]); err != nil {
+				c.writeErr = err
+				return n, err
+			}
+		}
+		record := make([]byte, recordHeaderLen, recordHeaderLen+len(fragment))
+		record[0] = recordType
+		binary.BigEndian.PutUint16(record[1:], c.recordVersion())
+		binary.BigEndian.PutUint16(record[3:], uint16(len(fragment)))
+		record = append(record, fragment...)
+		if _, err := c.conn.Write(record); err != nil {
+			c.writeErr = err
+			return n, err
+		}
+		n += m
+		data = data[m:]
+		if m == 0 {
+			break
+		}
+	}
+	return n, nil
+}
+
+// sendAlert sends a fatal alert, or a close_notify warning, and fails
+// further writes.
+func (c *Conn) sendAlert(a alert) {
+	c.outMutex.Lock()
+	defer c.outMutex.Unlock()
+	level := byte(2)
+	if a == alertCloseNotify {
+		level = 1
+	}
+	c.writeRecordLocked(recordTypeAlert, []byte{level, byte(a)})
+	if c.writeErr == nil {
+		c.writeErr = errors.New("gmtls: use of closed connection")
+	}
+}
+
+// readHandshake returns the next handshake message, header included.
+func (c *Conn) readHandshake() ([]byte, error) {
+	c.inMutex.Lock()
+	defer c.inMutex.Unlock()
+
+	for len(c.hand) < 4 || len(c.hand) < 4+handshakeLen(c.hand) {
+		if len(c.hand) >= 4 && handshakeLen(c.hand) > maxHandshake {
+			c.sendAlert(alertIllegalParameter)
+			return nil, alertIllegalParameter
+		}
+		typ, payload, err := c.readRecord()
+		if err != nil {
+			return nil, err
+		}
+		if typ != recordTypeHandshake {
+			c.sendAlert(alertUnexpectedMessage)
+			return nil, alertUnexpectedMessage
+		}
+		c.hand = append(c.hand, payload...)
+	}
+	n := 4 + handshakeLen(c.hand)
+	msg := c.hand[:n:n]
+	c.hand = c.hand[n:]
+	return msg, nil
+}
+
+func handshakeLen(msg []byte) int {
+	return int(msg[1])<<16 | int(msg[2])<<8 | int(msg[3])
+}
+
+// writeHandshake writes a handshake message of type typ and body, and
+// returns the message with its header.
+func (c *Conn) writeHandshake(typ uint8, body []byte) ([]byte, error) {
+	msg := make([]byte, 4, 4+len(body))
+	msg[0] = typ
+	msg[1], msg[2], msg[3] = byte(len(body)>>16), byte(len(body)>>8), byte(len(body))
+	msg = append(msg, body...)
+	_, err := c.writeRecord(recordTypeHandshake, msg)
+	return msg, err
+}
+
+// readChangeCipherSpec reads a ChangeCipherSpec and enables the prepared
+// keys for reading.
+func (c *Conn) readChangeCipherSpec() error {
+	c.inMutex.Lock()
+	defer c.inMutex.Unlock()
+
+	if len(c.hand) > 0 {
+		c.sendAlert(alertUnexpectedMessage)
+		return alertUnexpectedMessage
+	}
+	typ, payload, err := c.readRecord()
+	if err != nil {
+		return err
+	}
+	if typ != recordTypeChangeCipherSpec || len(payload) != 1 || payload[0] != 1 {
+		c.sendAlert(alertUnexpectedMessage)
+		return alertUnexpectedMessage
+	}
+	return c.in.changeCipherSpec()
+}
+
+// writeChangeCipherSpec writes a ChangeCipherSpec and enables the prepared
+// keys for writing.
+func (c *Conn) writeChangeCipherSpec() error {
+	c.outMutex.Lock()
+	defer c.outMutex.Unlock()
+
+	if _, err := c.writeRecordLocked(recordTypeChangeCipherSpec, []byte{1}); err != nil {
+		return err
+	}
+	return c.out.changeCipherSpec()
+}
+
+// setReadSecret protects the records read next with a TLS 1.3 traffic
+// secret. Handshake messages must not span the change of keys.
+func (c *Conn) setReadSecret(suite uint16, secret []byte) error {
+	c.inMutex.Lock()
+	defer c.inMutex.Unlock()
+
+	if len(c.hand) > 0 {
+		c.sendAlert(alertUnexpectedMessage)
+		return alertUnexpectedMessage
+	}
+	if err := c.in.setTrafficSecret(suite, secret); err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	return nil
+}
+
+// setWriteSecret protects the records written next with a TLS 1.3
+// traffic secret.
+func (c *Conn) setWriteSecret(suite uint16, secret []byte) error {
+	c.outMutex.Lock()
+	err := c.out.setTrafficSecret(suite, secret)
+	c.outMutex.Unlock()
+	if err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	return nil
+}
+
+// fail sends a fatal alert and returns err.
+func (c *Conn) fail(a alert, err error) error {
+	c.sendAlert(a)
+	return err
+}
+
+// Handshake runs the handshake if it has not yet been run. Read and Write
+// run it on their first call.
+func (c *Conn) Handshake() error {
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+
+	if c.handshakeDone || c.handshakeErr != nil {
+		return c.handshakeErr
+	}
+	switch version := c.config.version(); {
+	case version == VersionTLS13 && c.isClient:
+		c.handshakeErr = c.clientHandshake13()
+	case version == VersionTLS13:
+		c.handshakeErr = c.serverHandshake13()
+	case version != VersionGMTLS:
+		c.handshakeErr = fmt.Errorf("gmtls: unsupported protocol version %#04x", version)
+	case c.isClient:
+		c.handshakeErr = c.clientHandshake()
+	default:
+		c.handshakeErr = c.serverHandshake()
+	}
+	c.handshakeDone = c.handshakeErr == nil
+	return c.handshakeErr
+}
+
+// ConnectionState returns basic GMTLS details about the connection.
+func (c *Conn) ConnectionState() ConnectionState {
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+	state := c.state
+	state.HandshakeComplete = c.handshakeDone
+	return state
+}
+
+// Read reads application data from the connection.
+func (c *Conn) Read(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	c.inMutex.Lock()
+	defer c.inMutex.Unlock()
+	for len(c.input) == 0 {
+		typ, payload, err := c.readRecord()
+		if err != nil {
+			return 0, err
+		}
+		// TLS 1.3 sends session tickets and key updates after the
+		// handshake
+		if typ == recordTypeHandshake && c.in.aead != nil {
+			if err := c.handlePostHandshake(payload); err != nil {
+				c.readErr = err
+				return 0, err
+			}
+			continue
+		}
+		// Renegotiation is not supported
+		if typ != recordTypeApplicationData {
+			c.sendAlert(alertUnexpectedMessage)
+			c.readErr = alertUnexpectedMessage
+			return 0, c.readErr
+		}
+		c.input = payload
+	}
+	n := copy(b, c.input)
+	c.input = c.input[n:]
+	return n, nil
+}
+
+// Write writes application data to the connection.
+func (c *Conn) Write(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	return c.writeRecord(recordTypeApplicationData, b)
+}
+
+// Close sends a close_notify alert if the handshake completed, and closes
+// the underlying connection.
+func (c *Conn) Close() error {
+	c.handshakeMutex.Lock()
+	done := c.handshakeDone
+	c.handshakeMutex.Unlock()
+	if done {
+		c.sendAlert(alertCloseNotify)
+	}
+	return c.conn.Close()
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read and write deadlines of the underlying
+// connection.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the underlying connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,447 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmtls
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert   *x509.Certificate
+	key    *sm2.PrivateKey
+	serial int64
+}
+
+func newTestCA(t *testing.T, cn string) *testCA {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := gmx509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, serial: 1}
+}
+
+func (ca *testCA) pool() *gmx509.CertPool {
+	pool := gmx509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// issue returns the certificate of usage for host and its PEM encoding.
+func (ca *testCA) issue(t *testing.T, host string, usage x509.KeyUsage) (*Certificate, []byte, []byte) {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     usage,
+		DNSNames:     []string{host},
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	leaf, err := gmx509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyPEM, err := utils.SM2PrivateKeyToSEC1PEM(key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return &Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, certPEM, keyPEM
+}
+
+// issuePair returns the signing and the encryption certificates of host.
+func (ca *testCA) issuePair(t *testing.T, host string) (*Certificate, *Certificate) {
+	sign, _, _ := ca.issue(t, host, x509.KeyUsageDigitalSignature)
+	enc, _, _ := ca.issue(t, host, x509.KeyUsageKeyEncipherment|x509.KeyUsageDataEncipherment)
+	return sign, enc
+}
+
+// handshake runs the handshake of a client and a server over a TCP
+// connection and returns both connections, and the errors of the client
+// and of the server.
+func handshake(t *testing.T, clientConfig, serverConfig *Config) (*Conn, *Conn, error, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	accepted := make(chan result, 1)
+	go func() {
+		raw, err := lis.Accept()
+		if err != nil {
+			accepted <- result{err: err}
+			return
+		}
+		conn := Server(raw, serverConfig)
+		accepted <- result{conn: conn, err: conn.Handshake()}
+	}()
+
+	raw, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	client := Client(raw, clientConfig)
+	clientErr := client.Handshake()
+	server := <-accepted
+	// The failing side sends an alert the other side must read before
+	// the connections are closed
+	if clientErr != nil || server.err != nil {
+		client.Close()
+		if server.conn != nil {
+			server.conn.Close()
+		}
+	}
+	return client, server.conn, clientErr, server.err
+}
+
+func TestHandshake(t *testing.T) {
+	ca := newTestCA(t, "ca.org1")
+	serverSign, serverEnc := ca.issuePair(t, "peer0.org1")
+	clientSign, clientEnc := ca.issuePair(t, "user1.org1")
+
+	var verified [][]byte
+	client, server, clientErr, serverErr := handshake(t,
+		&Config{
+			SignCertificate: clientSign,
+			EncCertificate:  clientEnc,
+			RootCAs:         ca.pool(),
+			ServerName:      "peer0.org1",
+		},
+		&Config{
+			SignCertificate: serverSign,
+			EncCertificate:  serverEnc,
+			ClientCAs:       ca.pool(),
+			ClientAuth:      tls.RequireAndVerifyClientCert,
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				verified = rawCerts
+				return nil
+			},
+		})
+	require.NoError(t, clientErr)
+	require.NoError(t, serverErr)
+	defer client.Close()
+	defer server.Close()
+
+	state := client.ConnectionState()
+	assert.True(t, state.HandshakeComplete)
+	assert.Equal(t, VersionGMTLS, state.Version)
+	assert.Equal(t, ECC_SM4_CBC_SM3, state.CipherSuite)
+	assert.Equal(t, "peer0.org1", state.ServerName)
+	require.Len(t, state.PeerCertificates, 2)
+	assert.Equal(t, serverSign.Leaf.Raw, state.PeerCertificates[0].Raw)
+	assert.Equal(t, serverEnc.Leaf.Raw, state.PeerCertificates[1].Raw)
+	assert.NotEmpty(t, state.VerifiedChains)
+	state = server.ConnectionState()
+	require.Len(t, state.PeerCertificates, 2)
+	assert.Equal(t, clientSign.Leaf.Raw, state.PeerCertificates[0].Raw)
+	assert.Equal(t, [][]byte{clientSign.Leaf.Raw, clientEnc.Leaf.Raw}, verified)
+
+	// Application data larger than a record is fragmented
+	msg := bytes.Repeat([]byte("proposal"), 3*maxPlaintext/8)
+	go func() {
+		_, err := client.Write(msg)
+		assert.NoError(t, err)
+	}()
+	received := make([]byte, len(msg))
+	_, err := io.ReadFull(server, received)
+	require.NoError(t, err)
+	assert.Equal(t, msg, received)
+
+	// Closing the connection sends a close_notify alert
+	require.NoError(t, client.Close())
+	_, err = server.Read(received)
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestListener(t *testing.T) {
+	ca := newTestCA(t, "ca.org1")
+	serverSign, serverEnc := ca.issuePair(t, "peer0.org1")
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	lis := NewListener(inner, &Config{SignCertificate: serverSign, EncCertificate: serverEnc})
+	defer lis.Close()
+	assert.Equal(t, inner.Addr(), lis.Addr())
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		assert.IsType(t, &Conn{}, conn)
+		b := make([]byte, 5)
+		_, err = io.ReadFull(conn, b)
+		assert.NoError(t, err)
+		received <- b
+	}()
+
+	raw, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	client := Client(raw, &Config{RootCAs: ca.pool(), ServerName: "peer0.org1"})
+	defer client.Close()
+	_, err = client.Write([]byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hello"), <-received)
+}
+
+func TestHandshakeFailures(t *testing.T) {
+	ca := newTestCA(t, "ca.org1")
+	serverSign, serverEnc := ca.issuePair(t, "peer0.org1")
+	serverConfig := &Config{SignCertificate: serverSign, EncCertificate: serverEnc}
+
+	// The server is not issued by the roots of the client
+	_, _, clientErr, serverErr := handshake(t, &Config{RootCAs: newTestCA(t, "ca.org2").pool()}, serverConfig)
+	assert.Error(t, clientErr)
+	assert.Contains(t, clientErr.Error(), "gmtls: failed to verify the signing certificate")
+	assert.EqualError(t, serverErr, "gmtls: remote error: unknown certificate authority")
+
+	// The host name does not match
+	_, _, clientErr, _ = handshake(t, &Config{RootCAs: ca.pool(), ServerName: "orderer0.org1"}, serverConfig)
+	assert.Error(t, clientErr)
+	assert.Contains(t, clientErr.Error(), "orderer0.org1")
+
+	// The server requires a client certificate
+	requireCert := serverConfig.Clone()
+	requireCert.ClientAuth = tls.RequireAndVerifyClientCert
+	requireCert.ClientCAs = ca.pool()
+	_, _, clientErr, serverErr = handshake(t, &Config{RootCAs: ca.pool()}, requireCert)
+	assert.EqualError(t, serverErr, "gmtls: client didn't provide a certificate")
+	assert.EqualError(t, clientErr, "gmtls: remote error: bad certificate")
+
+	// The server lacks an encryption certificate
+	conn, _ := net.Pipe()
+	err := Server(conn, &Config{SignCertificate: serverSign}).Handshake()
+	assert.EqualError(t, err, "gmtls: a server requires both a signing and an encryption certificate")
+}
+
+func TestRecordProtection(t *testing.T) {
+	var out, in halfConn
+	macKey, key := make([]byte, macKeyLen), make([]byte, sm4KeyLen)
+	require.NoError(t, out.prepareCipherSpec(macKey, key))
+	require.NoError(t, out.changeCipherSpec())
+	require.NoError(t, in.prepareCipherSpec(macKey, key))
+	require.NoError(t, in.changeCipherSpec())
+
+	for _, payload := range [][]byte{{}, []byte("block"), bytes.Repeat([]byte{1}, sm4BlockLen)} {
+		fragment, err := out.encrypt(rand.Reader, recordTypeApplicationData, payload)
+		require.NoError(t, err)
+		assert.Equal(t, 0, len(fragment)%sm4BlockLen)
+		decrypted, err := in.decrypt(recordTypeApplicationData, fragment)
+		require.NoError(t, err)
+		assert.Equal(t, payload, decrypted)
+	}
+
+	// Tampered, replayed and retyped records are rejected
+	fragment, err := out.encrypt(rand.Reader, recordTypeApplicationData, []byte("proposal"))
+	require.NoError(t, err)
+	tampered := append([]byte{}, fragment...)
+	tampered[len(tampered)-sm4BlockLen-1] ^= 1
+	_, err = in.decrypt(recordTypeApplicationData, tampered)
+	assert.Equal(t, alertBadRecordMAC, err)
+	_, err = in.decrypt(recordTypeHandshake, fragment)
+	assert.Equal(t, alertBadRecordMAC, err)
+	_, err = in.decrypt(recordTypeApplicationData, fragment)
+	require.NoError(t, err)
+	_, err = in.decrypt(recordTypeApplicationData, fragment)
+	assert.Equal(t, alertBadRecordMAC, err)
+}
+
+func TestX509KeyPair(t *testing.T) {
+	ca := newTestCA(t, "ca.org1")
+	_, certPEM, keyPEM := ca.issue(t, "peer0.org1", x509.KeyUsageDigitalSignature)
+	_, _, otherKeyPEM := ca.issue(t, "peer1.org1", x509.KeyUsageDigitalSignature)
+
+	cert, err := X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	assert.Equal(t, "peer0.org1", cert.Leaf.Subject.CommonName)
+	assert.Len(t, cert.Certificate, 1)
+
+	_, err = X509KeyPair(certPEM, otherKeyPEM)
+	assert.EqualError(t, err, "gmtls: private key does not match public key")
+	_, err = X509KeyPair(keyPEM, keyPEM)
+	assert.EqualError(t, err, "gmtls: failed to find any PEM certificate")
+}
+
+func TestHandshake13(t *testing.T) {
+	ca := newTestCA(t, "ca.org1")
+	serverCert, _, _ := ca.issue(t, "peer0.org1", x509.KeyUsageDigitalSignature)
+	clientCert, _, _ := ca.issue(t, "user1.org1", x509.KeyUsageDigitalSignature)
+
+	for _, suite := range []uint16{TLS_SM4_GCM_SM3, TLS_SM4_CCM_SM3} {
+		t.Run(CipherSuiteName(suite), func(t *testing.T) {
+			client, server, clientErr, serverErr := handshake(t,
+				&Config{
+					Version:         VersionTLS13,
+					CipherSuites:    []uint16{suite},
+					SignCertificate: clientCert,
+					RootCAs:         ca.pool(),
+					ServerName:      "peer0.org1",
+				},
+				&Config{
+					Version:         VersionTLS13,
+					SignCertificate: serverCert,
+					ClientCAs:       ca.pool(),
+					ClientAuth:      tls.RequireAndVerifyClientCert,
+				})
+			require.NoError(t, clientErr)
+			require.NoError(t, serverErr)
+			defer client.Close()
+			defer server.Close()
+
+			state := client.ConnectionState()
+			assert.True(t, state.HandshakeComplete)
+			assert.Equal(t, VersionTLS13, state.Version)
+			assert.Equal(t, suite, state.CipherSuite)
+			require.Len(t, state.PeerCertificates, 1)
+			assert.Equal(t, serverCert.Leaf.Raw, state.PeerCertificates[0].Raw)
+			assert.NotEmpty(t, state.VerifiedChains)
+			state = server.ConnectionState()
+			assert.Equal(t, "peer0.org1", state.ServerName)
+			require.Len(t, state.PeerCertificates, 1)
+			assert.Equal(t, clientCert.Leaf.Raw, state.PeerCertificates[0].Raw)
+
+			// Application data larger than a record is fragmented
+			msg := bytes.Repeat([]byte("proposal"), 3*maxPlaintext/8)
+			go func() {
+				_, err := client.Write(msg)
+				assert.NoError(t, err)
+			}()
+			received := make([]byte, len(msg))
+			_, err := io.ReadFull(server, received)
+			require.NoError(t, err)
+			assert.Equal(t, msg, received)
+
+			// The client updates its keys and requests the server to
+			// update its own
+			client.outMutex.Lock()
+			_, err = client.writeRecordLocked(recordTypeHandshake, []byte{typeKeyUpdate, 0, 0, 1, 1})
+			require.NoError(t, err)
+			require.NoError(t, client.out.setTrafficSecret(suite, nextTrafficSecret(client.out.secret)))
+			client.outMutex.Unlock()
+			go func() {
+				_, err := client.Write([]byte("after update"))
+				assert.NoError(t, err)
+			}()
+			received = make([]byte, len("after update"))
+			_, err = io.ReadFull(server, received)
+			require.NoError(t, err)
+			assert.Equal(t, "after update", string(received))
+			go func() {
+				_, err := server.Write([]byte("response"))
+				assert.NoError(t, err)
+			}()
+			received = make([]byte, len("response"))
+			_, err = io.ReadFull(client, received)
+			require.NoError(t, err)
+			assert.Equal(t, "response", string(received))
+
+			// Closing the connection sends a close_notify alert
+			require.NoError(t, client.Close())
+			_, err = server.Read(received)
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestHandshake13Failures(t *testing.T) {
+	ca := newTestCA(t, "ca.org1")
+	serverCert, _, _ := ca.issue(t, "peer0.org1", x509.KeyUsageDigitalSignature)
+	serverConfig := &Config{
+		Version:         VersionTLS13,
+		CipherSuites:    []uint16{TLS_SM4_GCM_SM3},
+		SignCertificate: serverCert,
+	}
+
+	// GM/T 0024 clients cannot connect to TLS 1.3 servers
+	serverSign, serverEnc := ca.issuePair(t, "peer0.org1")
+	_, _, clientErr, serverErr := handshake(t, &Config{RootCAs: ca.pool()}, serverConfig)
+	assert.Error(t, clientErr)
+	assert.EqualError(t, serverErr, "gmtls: protocol version not supported")
+	_, _, clientErr, serverErr = handshake(t, &Config{Version: VersionTLS13, RootCAs: ca.pool()}, &Config{SignCertificate: serverSign, EncCertificate: serverEnc})
+	assert.Error(t, clientErr)
+	assert.Error(t, serverErr)
+
+	// The client and the server share no cipher suite
+	_, _, clientErr, serverErr = handshake(t, &Config{Version: VersionTLS13, CipherSuites: []uint16{TLS_SM4_CCM_SM3}, RootCAs: ca.pool()}, serverConfig)
+	assert.EqualError(t, serverErr, "gmtls: no cipher suite supported by both client and server")
+	assert.EqualError(t, clientErr, "gmtls: remote error: handshake failure")
+
+	// The server is not issued by the roots of the client
+	_, _, clientErr, _ = handshake(t, &Config{Version: VersionTLS13, RootCAs: newTestCA(t, "ca.org2").pool()}, serverConfig)
+	assert.Error(t, clientErr)
+	assert.Contains(t, clientErr.Error(), "gmtls: failed to verify the signing certificate")
+
+	// The server requires a client certificate
+	requireCert := serverConfig.Clone()
+	requireCert.ClientAuth = tls.RequireAndVerifyClientCert
+	requireCert.ClientCAs = ca.pool()
+	_, _, _, serverErr = handshake(t, &Config{Version: VersionTLS13, RootCAs: ca.pool()}, requireCert)
+	assert.EqualError(t, serverErr, "gmtls: client didn't provide a certificate")
+
+	// GM/T 0024 cipher suites are not TLS 1.3 ones
+	conn, _ := net.Pipe()
+	err := Client(conn, &Config{Version: VersionTLS13, CipherSuites: []uint16{ECC_SM4_CBC_SM3}, RootCAs: ca.pool()}).Handshake()
+	assert.EqualError(t, err, "gmtls: unsupported TLS 1.3 cipher suite 0xe013")
+}
+
+func TestCCM(t *testing.T) {
+	// Example 3 of NIST SP 800-38C, with AES
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		require.NoError(t, err)
+		return b
+	}
+	block, err := aes.NewCipher(decode("404142434445464748494a4b4c4d4e4f"))
+	require.NoError(t, err)
+	aead, err := newCCM(block, 8)
+	require.NoError(t, err)
+	nonce := decode("101112131415161718191a1b")
+	additionalData := decode("000102030405060708090a0b0c0d0e0f10111213")
+	plaintext := decode("202122232425262728292a2b2c2d2e2f3031323334353637")
+
+	ciphertext := aead.Seal(nil, nonce, plaintext, additionalData)
+	assert.Equal(t, decode("e3b201a9f5b71a7a9b1ceaeccd97e70b6176aad9a4428aa5484392fbc1b09951"), ciphertext)
+	opened, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, opened)
+
+	ciphertext[0] ^= 1
+	_, err = aead.Open(nil, nonce, ciphertext, additionalData)
+	assert.Error(t, err)
+}
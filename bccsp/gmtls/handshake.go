@@ -0,0 +1,549 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmtls
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// parser reads the fields of handshake messages. Reading past the end of
+// the message sets bad.
+type parser struct {
+	b   []byte
+	bad bool
+}
+
+func (p *parser) bytes(n int) []byte {
+	if p.bad || n > len(p.b) {
+		p.bad = true
+		return nil
+	}
+	v := p.b[:n]
+	p.b = p.b[n:]
+	return v
+}
+
+func (p *parser) uint(n int) int {
+	var v int
+	for _, b := range p.bytes(n) {
+		v = v<<8 | int(b)
+	}
+	return v
+}
+
+// vector reads a vector whose length is encoded in n bytes.
+func (p *parser) vector(n int) []byte {
+	return p.bytes(p.uint(n))
+}
+
+func appendUint(b []byte, n, v int) []byte {
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, byte(v>>(8*uint(i))))
+	}
+	return b
+}
+
+func appendVector(b []byte, n int, v []byte) []byte {
+	return append(appendUint(b, n, len(v)), v...)
+}
+
+// expectHandshake reads the next handshake message, which must be of type
+// typ, and returns it along with its body.
+func (c *Conn) expectHandshake(typ uint8) ([]byte, []byte, error) {
+	msg, err := c.readHandshake()
+	if err != nil {
+		return nil, nil, err
+	}
+	if msg[0] != typ {
+		return nil, nil, c.fail(alertUnexpectedMessage, fmt.Errorf("gmtls: unexpected handshake message of type %d, expected %d", msg[0], typ))
+	}
+	return msg, msg[4:], nil
+}
+
+// certificateMessage returns the body of the Certificate message of the
+// signing and encryption certificates.
+func certificateMessage(sign, enc *Certificate) []byte {
+	var list []byte
+	if sign != nil && enc != nil {
+		list = appendVector(list, 3, sign.Certificate[0])
+		list = appendVector(list, 3, enc.Certificate[0])
+		for _, der := range sign.Certificate[1:] {
+			list = appendVector(list, 3, der)
+		}
+	}
+	return appendVector(nil, 3, list)
+}
+
+// parseCertificateMessage parses the certificates of a Certificate
+// message, which are none or at least the signing and the encryption
+// certificates, both with SM2 keys.
+func (c *Conn) parseCertificateMessage(body []byte) ([][]byte, []*x509.Certificate, error) {
+	p := &parser{b: body}
+	list := &parser{b: p.vector(3)}
+	var raw [][]byte
+	for !p.bad && !list.bad && len(list.b) > 0 {
+		raw = append(raw, list.vector(3))
+	}
+	if p.bad || list.bad || len(p.b) != 0 {
+		return nil, nil, c.fail(alertDecodeError, errors.New("gmtls: malformed Certificate message"))
+	}
+	if len(raw) == 0 {
+		return nil, nil, nil
+	}
+	if len(raw) < 2 || len(raw) > maxPeerCertificate {
+		return nil, nil, c.fail(alertBadCertificate, fmt.Errorf("gmtls: peer sent %d certificates, expected a signing and an encryption certificate", len(raw)))
+	}
+
+	certs, err := c.parsePeerCertificates(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := certs[1].PublicKey.(*sm2.PublicKey); !ok {
+		return nil, nil, c.fail(alertUnsupportedCert, fmt.Errorf("gmtls: peer certificate 1 has a %T public key, not an SM2 key", certs[1].PublicKey))
+	}
+	if ku := certs[1].KeyUsage; ku != 0 && ku&(x509.KeyUsageKeyEncipherment|x509.KeyUsageDataEncipherment|x509.KeyUsageKeyAgreement) == 0 {
+		return nil, nil, c.fail(alertBadCertificate, errors.New("gmtls: the encryption certificate of the peer is not valid for encryption"))
+	}
+	return raw, certs, nil
+}
+
+// parsePeerCertificates parses the certificates of the peer, the first of
+// which must be an SM2 signing certificate.
+func (c *Conn) parsePeerCertificates(raw [][]byte) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, len(raw))
+	for i, der := range raw {
+		cert, err := gmx509.ParseCertificate(der)
+		if err != nil {
+			return nil, c.fail(alertBadCertificate, fmt.Errorf("gmtls: failed to parse peer certificate [%s]", err))
+		}
+		certs[i] = cert
+	}
+	if _, ok := certs[0].PublicKey.(*sm2.PublicKey); !ok {
+		return nil, c.fail(alertUnsupportedCert, fmt.Errorf("gmtls: peer certificate 0 has a %T public key, not an SM2 key", certs[0].PublicKey))
+	}
+	if ku := certs[0].KeyUsage; ku != 0 && ku&x509.KeyUsageDigitalSignature == 0 {
+		return nil, c.fail(alertBadCertificate, errors.New("gmtls: the signing certificate of the peer is not valid for signatures"))
+	}
+	return certs, nil
+}
+
+// verifyPeerCertificates verifies the signing and the encryption
+// certificates of the peer against roots, or over TLS 1.3 its only
+// certificate, and records them in the connection state.
+func (c *Conn) verifyPeerCertificates(raw [][]byte, certs []*x509.Certificate, roots *gmx509.CertPool, verify bool) error {
+	leaves := []string{"signing", "encryption"}
+	if c.config.version() == VersionTLS13 {
+		leaves = leaves[:1]
+	}
+	var chains [][]*x509.Certificate
+	if verify {
+		opts := gmx509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: gmx509.NewCertPool(),
+			CurrentTime:   c.config.time(),
+		}
+		for _, cert := range certs[len(leaves):] {
+			opts.Intermediates.AddCert(cert)
+		}
+		for i, leaf := range leaves {
+			leafChains, err := gmx509.Verify(certs[i], opts)
+			if err != nil {
+				return c.fail(alertUnknownCA, fmt.Errorf("gmtls: failed to verify the %s certificate [%s]", leaf, err))
+			}
+			if i == 0 {
+				chains = leafChains
+			}
+		}
+	}
+	if c.config.VerifyPeerCertificate != nil {
+		if err := c.config.VerifyPeerCertificate(raw, chains); err != nil {
+			return c.fail(alertBadCertificate, err)
+		}
+	}
+	c.state.PeerCertificates = certs
+	c.state.VerifiedChains = chains
+	return nil
+}
+
+// keyExchangeParams returns the parameters the ServerKeyExchange signs:
+// the randoms and the encryption certificate of the server.
+func keyExchangeParams(clientRandom, serverRandom, encCert []byte) []byte {
+	params := make([]byte, 0, 2*randomLen+3+len(encCert))
+	params = append(params, clientRandom...)
+	params = append(params, serverRandom...)
+	return appendVector(params, 3, encCert)
+}
+
+// newRandom returns the random of a hello message, whose first 4 bytes
+// are the time.
+func (c *Conn) newRandom() ([]byte, error) {
+	random := make([]byte, randomLen)
+	binary.BigEndian.PutUint32(random, uint32(c.config.time().Unix()))
+	if _, err := io.ReadFull(c.config.rand(), random[4:]); err != nil {
+		return nil, c.fail(alertInternalError, err)
+	}
+	return random, nil
+}
+
+// establishKeys derives the master secret and prepares the keys of both
+// directions of the connection.
+func (c *Conn) establishKeys(premaster, clientRandom, serverRandom []byte) ([]byte, error) {
+	master := masterFromPremaster(premaster, clientRandom, serverRandom)
+	clientMAC, serverMAC, clientKey, serverKey := keysFromMaster(master, clientRandom, serverRandom)
+	var err error
+	if c.isClient {
+		if err = c.in.prepareCipherSpec(serverMAC, serverKey); err == nil {
+			err = c.out.prepareCipherSpec(clientMAC, clientKey)
+		}
+	} else {
+		if err = c.in.prepareCipherSpec(clientMAC, clientKey); err == nil {
+			err = c.out.prepareCipherSpec(serverMAC, serverKey)
+		}
+	}
+	if err != nil {
+		return nil, c.fail(alertInternalError, err)
+	}
+	return master, nil
+}
+
+// readFinished reads the ChangeCipherSpec and Finished messages of the
+// peer and checks the verify data against expected.
+func (c *Conn) readFinished(expected []byte) ([]byte, error) {
+	if err := c.readChangeCipherSpec(); err != nil {
+		return nil, err
+	}
+	msg, body, err := c.expectHandshake(typeFinished)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(body, expected) {
+		return nil, c.fail(alertDecryptError, errors.New("gmtls: invalid Finished message"))
+	}
+	return msg, nil
+}
+
+// writeFinished writes the ChangeCipherSpec and Finished messages.
+func (c *Conn) writeFinished(verifyData []byte) ([]byte, error) {
+	if err := c.writeChangeCipherSpec(); err != nil {
+		return nil, err
+	}
+	return c.writeHandshake(typeFinished, verifyData)
+}
+
+func (c *Conn) clientHandshake() error {
+	config := c.config
+	if config.RootCAs == nil && !config.InsecureSkipVerify {
+		return errors.New("gmtls: either RootCAs or InsecureSkipVerify must be set")
+	}
+	if (config.SignCertificate == nil) != (config.EncCertificate == nil) {
+		return errors.New("gmtls: a client certificate requires both a signing and an encryption certificate")
+	}
+	var transcript finishedHash
+
+	clientRandom, err := c.newRandom()
+	if err != nil {
+		return err
+	}
+	hello := appendUint(nil, 2, int(VersionGMTLS))
+	hello = append(hello, clientRandom...)
+	hello = appendVector(hello, 1, nil)
+	hello = appendVector(hello, 2, appendUint(nil, 2, int(ECC_SM4_CBC_SM3)))
+	hello = appendVector(hello, 1, []byte{0})
+	msg, err := c.writeHandshake(typeClientHello, hello)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	msg, body, err := c.expectHandshake(typeServerHello)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	p := &parser{b: body}
+	version := p.uint(2)
+	serverRandom := p.bytes(randomLen)
+	p.vector(1)
+	suite := p.uint(2)
+	compression := p.uint(1)
+	if p.bad {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed ServerHello message"))
+	}
+	if uint16(version) != VersionGMTLS {
+		return c.fail(alertProtocolVersion, fmt.Errorf("gmtls: server selected unsupported protocol version %x", version))
+	}
+	if uint16(suite) != ECC_SM4_CBC_SM3 || compression != 0 {
+		return c.fail(alertIllegalParameter, errors.New("gmtls: server selected an unoffered cipher suite or compression method"))
+	}
+
+	msg, body, err = c.expectHandshake(typeCertificate)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	raw, certs, err := c.parseCertificateMessage(body)
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return c.fail(alertHandshakeFailure, errors.New("gmtls: server sent no certificate"))
+	}
+	if !config.InsecureSkipVerify && config.ServerName != "" {
+		if err := certs[0].VerifyHostname(config.ServerName); err != nil {
+			return c.fail(alertBadCertificate, fmt.Errorf("gmtls: %s", err))
+		}
+	}
+	if err := c.verifyPeerCertificates(raw, certs, config.RootCAs, !config.InsecureSkipVerify); err != nil {
+		return err
+	}
+	c.state.ServerName = config.ServerName
+
+	msg, body, err = c.expectHandshake(typeServerKeyExchange)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	p = &parser{b: body}
+	signature := p.vector(2)
+	if p.bad || len(p.b) != 0 {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed ServerKeyExchange message"))
+	}
+	if err := gmx509.VerifySM2(certs[0].PublicKey, keyExchangeParams(clientRandom, serverRandom, raw[1]), signature); err != nil {
+		return c.fail(alertDecryptError, errors.New("gmtls: invalid signature of the ServerKeyExchange message"))
+	}
+
+	msg, err = c.readHandshake()
+	if err != nil {
+		return err
+	}
+	certRequested := msg[0] == typeCertificateRequest
+	if certRequested {
+		transcript.add(msg)
+		p = &parser{b: msg[4:]}
+		p.vector(1)
+		p.vector(2)
+		if p.bad {
+			return c.fail(alertDecodeError, errors.New("gmtls: malformed CertificateRequest message"))
+		}
+		if msg, err = c.readHandshake(); err != nil {
+			return err
+		}
+	}
+	if msg[0] != typeServerHelloDone || len(msg) != 4 {
+		return c.fail(alertUnexpectedMessage, errors.New("gmtls: expected a ServerHelloDone message"))
+	}
+	transcript.add(msg)
+
+	sendCert := certRequested && config.SignCertificate != nil
+	if certRequested {
+		if config.SignCertificate == nil {
+			msg, err = c.writeHandshake(typeCertificate, certificateMessage(nil, nil))
+		} else {
+			msg, err = c.writeHandshake(typeCertificate, certificateMessage(config.SignCertificate, config.EncCertificate))
+		}
+		if err != nil {
+			return err
+		}
+		transcript.add(msg)
+	}
+
+	premaster := make([]byte, premasterLen)
+	binary.BigEndian.PutUint16(premaster, VersionGMTLS)
+	if _, err := io.ReadFull(config.rand(), premaster[2:]); err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	encrypted, err := gmx509.EncryptSM2(config.rand(), certs[1].PublicKey.(*sm2.PublicKey), premaster)
+	if err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	if msg, err = c.writeHandshake(typeClientKeyExchange, appendVector(nil, 2, encrypted)); err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	if sendCert {
+		signature, err := gmx509.SignSM2(config.rand(), config.SignCertificate.PrivateKey, transcript.sum())
+		if err != nil {
+			return c.fail(alertInternalError, err)
+		}
+		if msg, err = c.writeHandshake(typeCertificateVerify, appendVector(nil, 2, signature)); err != nil {
+			return err
+		}
+		transcript.add(msg)
+	}
+
+	master, err := c.establishKeys(premaster, clientRandom, serverRandom)
+	if err != nil {
+		return err
+	}
+	if msg, err = c.writeFinished(transcript.clientSum(master)); err != nil {
+		return err
+	}
+	transcript.add(msg)
+	if _, err := c.readFinished(transcript.serverSum(master)); err != nil {
+		return err
+	}
+
+	c.state.Version = VersionGMTLS
+	c.state.CipherSuite = ECC_SM4_CBC_SM3
+	return nil
+}
+
+func (c *Conn) serverHandshake() error {
+	config := c.config
+	if config.SignCertificate == nil || config.EncCertificate == nil {
+		return errors.New("gmtls: a server requires both a signing and an encryption certificate")
+	}
+	var transcript finishedHash
+
+	msg, body, err := c.expectHandshake(typeClientHello)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	p := &parser{b: body}
+	version := p.uint(2)
+	clientRandom := p.bytes(randomLen)
+	p.vector(1)
+	suites := &parser{b: p.vector(2)}
+	compressions := p.vector(1)
+	if p.bad {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed ClientHello message"))
+	}
+	if uint16(version) != VersionGMTLS {
+		return c.fail(alertProtocolVersion, fmt.Errorf("gmtls: client offered unsupported protocol version %x", version))
+	}
+	supported := false
+	for len(suites.b) >= 2 {
+		if uint16(suites.uint(2)) == ECC_SM4_CBC_SM3 {
+			supported = true
+		}
+	}
+	if !supported || bytes.IndexByte(compressions, 0) < 0 {
+		return c.fail(alertHandshakeFailure, errors.New("gmtls: no cipher suite or compression method supported by both client and server"))
+	}
+
+	serverRandom, err := c.newRandom()
+	if err != nil {
+		return err
+	}
+	hello := appendUint(nil, 2, int(VersionGMTLS))
+	hello = append(hello, serverRandom...)
+	hello = appendVector(hello, 1, nil)
+	hello = appendUint(hello, 2, int(ECC_SM4_CBC_SM3))
+	hello = append(hello, 0)
+	if msg, err = c.writeHandshake(typeServerHello, hello); err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	if msg, err = c.writeHandshake(typeCertificate, certificateMessage(config.SignCertificate, config.EncCertificate)); err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	params := keyExchangeParams(clientRandom, serverRandom, config.EncCertificate.Certificate[0])
+	signature, err := gmx509.SignSM2(config.rand(), config.SignCertificate.PrivateKey, params)
+	if err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	if msg, err = c.writeHandshake(typeServerKeyExchange, appendVector(nil, 2, signature)); err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	if config.ClientAuth != tls.NoClientCert {
+		request := appendVector(nil, 1, []byte{certTypeECDSASign})
+		request = appendVector(request, 2, nil)
+		if msg, err = c.writeHandshake(typeCertificateRequest, request); err != nil {
+			return err
+		}
+		transcript.add(msg)
+	}
+	if msg, err = c.writeHandshake(typeServerHelloDone, nil); err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	var certs []*x509.Certificate
+	if config.ClientAuth != tls.NoClientCert {
+		if msg, body, err = c.expectHandshake(typeCertificate); err != nil {
+			return err
+		}
+		transcript.add(msg)
+		var raw [][]byte
+		if raw, certs, err = c.parseCertificateMessage(body); err != nil {
+			return err
+		}
+		if len(certs) == 0 {
+			if config.ClientAuth == tls.RequireAnyClientCert || config.ClientAuth == tls.RequireAndVerifyClientCert {
+				return c.fail(alertBadCertificate, errors.New("gmtls: client didn't provide a certificate"))
+			}
+		} else {
+			verify := config.ClientAuth == tls.VerifyClientCertIfGiven || config.ClientAuth == tls.RequireAndVerifyClientCert
+			if err := c.verifyPeerCertificates(raw, certs, config.ClientCAs, verify); err != nil {
+				return err
+			}
+		}
+	}
+
+	if msg, body, err = c.expectHandshake(typeClientKeyExchange); err != nil {
+		return err
+	}
+	transcript.add(msg)
+	p = &parser{b: body}
+	encrypted := p.vector(2)
+	if p.bad || len(p.b) != 0 {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed ClientKeyExchange message"))
+	}
+	premaster, err := gmx509.DecryptSM2(config.EncCertificate.PrivateKey, encrypted)
+	if err != nil || len(premaster) != premasterLen || binary.BigEndian.Uint16(premaster) != VersionGMTLS {
+		return c.fail(alertDecryptError, errors.New("gmtls: invalid premaster secret"))
+	}
+
+	if len(certs) > 0 {
+		digest := transcript.sum()
+		if msg, body, err = c.expectHandshake(typeCertificateVerify); err != nil {
+			return err
+		}
+		transcript.add(msg)
+		p = &parser{b: body}
+		signature := p.vector(2)
+		if p.bad || len(p.b) != 0 {
+			return c.fail(alertDecodeError, errors.New("gmtls: malformed CertificateVerify message"))
+		}
+		if err := gmx509.VerifySM2(certs[0].PublicKey, digest, signature); err != nil {
+			return c.fail(alertDecryptError, errors.New("gmtls: invalid signature of the CertificateVerify message"))
+		}
+	}
+
+	master, err := c.establishKeys(premaster, clientRandom, serverRandom)
+	if err != nil {
+		return err
+	}
+	if msg, err = c.readFinished(transcript.clientSum(master)); err != nil {
+		return err
+	}
+	transcript.add(msg)
+	if _, err = c.writeFinished(transcript.serverSum(master)); err != nil {
+		return err
+	}
+
+	c.state.Version = VersionGMTLS
+	c.state.CipherSuite = ECC_SM4_CBC_SM3
+	return nil
+}
@@ -0,0 +1,633 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmtls
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+const (
+	// legacyVersionTLS12 is the version of TLS 1.3 records and hello
+	// messages, that of TLS 1.2, and legacyVersionTLS10 that of the
+	// records of some ClientHello messages
+	legacyVersionTLS12 uint16 = 0x0303
+	legacyVersionTLS10 uint16 = 0x0301
+
+	extensionServerName          uint16 = 0
+	extensionSupportedGroups     uint16 = 10
+	extensionSignatureAlgorithms uint16 = 13
+	extensionSupportedVersions   uint16 = 43
+	extensionKeyShare            uint16 = 51
+
+	// curveSM2 and sm2sigSM3 are the key exchange group and the signature
+	// scheme of RFC 8998
+	curveSM2  uint16 = 41
+	sm2sigSM3 uint16 = 0x0708
+
+	serverSignatureContext = "TLS 1.3, server CertificateVerify"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify"
+)
+
+// sm2SignatureID is the distinguishing identifier of the SM2 signatures of
+// TLS 1.3, as RFC 8998 sets it.
+var sm2SignatureID = []byte("TLSv1.3+GM+Cipher+Suite")
+
+// helloRetryRequestRandom is the random of the ServerHello messages which
+// are HelloRetryRequests.
+var helloRetryRequestRandom = []byte{
+	0xcf, 0x21, 0xad, 0x74, 0xe5, 0x9a, 0x61, 0x11,
+	0xbe, 0x1d, 0x8c, 0x02, 0x1e, 0x65, 0xb8, 0x91,
+	0xc2, 0xa2, 0x11, 0x16, 0x7a, 0xbb, 0x8c, 0x5e,
+	0x07, 0x9e, 0x09, 0xe2, 0xc8, 0xa8, 0x33, 0x9c,
+}
+
+func appendExtension(b []byte, typ uint16, data []byte) []byte {
+	return appendVector(appendUint(b, 2, int(typ)), 2, data)
+}
+
+// parseExtensions returns the extensions of a hello message by type. It
+// fails on malformed or repeated extensions.
+func parseExtensions(b []byte) (map[uint16][]byte, bool) {
+	p := &parser{b: b}
+	exts := make(map[uint16][]byte)
+	for !p.bad && len(p.b) > 0 {
+		typ := uint16(p.uint(2))
+		data := p.vector(2)
+		if _, ok := exts[typ]; ok {
+			return nil, false
+		}
+		exts[typ] = data
+	}
+	return exts, !p.bad
+}
+
+// extensionVector returns the vector the data of an extension holds,
+// whose length is encoded in n bytes, or nil if malformed.
+func extensionVector(data []byte, n int) []byte {
+	p := &parser{b: data}
+	v := p.vector(n)
+	if p.bad || len(p.b) != 0 {
+		return nil
+	}
+	return v
+}
+
+// containsUint16 reports whether list, a list of 16-bit values, holds v.
+func containsUint16(list []byte, v uint16) bool {
+	for p := (&parser{b: list}); len(p.b) >= 2; {
+		if uint16(p.uint(2)) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// keyShare returns the key_share entry of an ephemeral SM2 key.
+func keyShare(key *sm2.PrivateKey) []byte {
+	entry := appendUint(nil, 2, int(curveSM2))
+	return appendVector(entry, 2, elliptic.Marshal(key.Curve, key.X, key.Y))
+}
+
+// sharedSecret returns the ECDHE shared secret of key and of the public
+// key of the peer, the x-coordinate of their product.
+func sharedSecret(key *sm2.PrivateKey, peerKey []byte) ([]byte, error) {
+	x, y := elliptic.Unmarshal(key.Curve, peerKey)
+	if x == nil {
+		return nil, errors.New("gmtls: invalid SM2 key share")
+	}
+	x, y = key.Curve.ScalarMult(x, y, key.D.Bytes())
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("gmtls: invalid SM2 key share")
+	}
+	shared := make([]byte, (key.Curve.Params().BitSize+7)/8)
+	xBytes := x.Bytes()
+	copy(shared[len(shared)-len(xBytes):], xBytes)
+	return shared, nil
+}
+
+// signatureContent returns the content CertificateVerify messages sign.
+func signatureContent(context string, transcriptHash []byte) []byte {
+	content := bytes.Repeat([]byte{0x20}, 64)
+	content = append(content, context...)
+	content = append(content, 0)
+	return append(content, transcriptHash...)
+}
+
+// certificateMessage13 returns the body of the TLS 1.3 Certificate
+// message of cert, or of no certificate if cert is nil.
+func certificateMessage13(cert *Certificate) []byte {
+	var list []byte
+	if cert != nil {
+		for _, der := range cert.Certificate {
+			list = appendVector(list, 3, der)
+			list = appendVector(list, 2, nil)
+		}
+	}
+	return appendVector(appendVector(nil, 1, nil), 3, list)
+}
+
+// parseCertificateMessage13 parses the certificates of a TLS 1.3
+// Certificate message, an SM2 signing certificate and its intermediates,
+// if any.
+func (c *Conn) parseCertificateMessage13(body []byte) ([][]byte, []*x509.Certificate, error) {
+	p := &parser{b: body}
+	context := p.vector(1)
+	list := &parser{b: p.vector(3)}
+	var raw [][]byte
+	for !p.bad && !list.bad && len(list.b) > 0 {
+		raw = append(raw, list.vector(3))
+		list.vector(2)
+	}
+	if p.bad || list.bad || len(p.b) != 0 || len(context) != 0 {
+		return nil, nil, c.fail(alertDecodeError, errors.New("gmtls: malformed Certificate message"))
+	}
+	if len(raw) == 0 {
+		return nil, nil, nil
+	}
+	if len(raw) > maxPeerCertificate {
+		return nil, nil, c.fail(alertBadCertificate, fmt.Errorf("gmtls: peer sent %d certificates", len(raw)))
+	}
+	certs, err := c.parsePeerCertificates(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, certs, nil
+}
+
+// writeCertificateVerify13 signs the transcript with key and writes the
+// CertificateVerify message.
+func (c *Conn) writeCertificateVerify13(key *sm2.PrivateKey, context string, transcript *finishedHash) error {
+	r, s, err := utils.SM2SignWithRand(c.config.rand(), key, sm2SignatureID, signatureContent(context, transcript.sum()))
+	if err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	signature, err := utils.MarshalECDSASignature(r, s)
+	if err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	verify := appendVector(appendUint(nil, 2, int(sm2sigSM3)), 2, signature)
+	msg, err := c.writeHandshake(typeCertificateVerify, verify)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	return nil
+}
+
+// readCertificateVerify13 reads the CertificateVerify message of the peer
+// and checks its signature of the transcript with pub.
+func (c *Conn) readCertificateVerify13(pub *sm2.PublicKey, context string, transcript *finishedHash) error {
+	content := signatureContent(context, transcript.sum())
+	msg, body, err := c.expectHandshake(typeCertificateVerify)
+	if err != nil {
+		return err
+	}
+	p := &parser{b: body}
+	scheme := p.uint(2)
+	signature := p.vector(2)
+	if p.bad || len(p.b) != 0 {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed CertificateVerify message"))
+	}
+	if uint16(scheme) != sm2sigSM3 {
+		return c.fail(alertIllegalParameter, fmt.Errorf("gmtls: peer used unsupported signature scheme %#04x", scheme))
+	}
+	signature, err = utils.NormalizeSignature(signature)
+	if err != nil || !sm2.Verify(pub, sm2SignatureID, content, signature) {
+		return c.fail(alertDecryptError, errors.New("gmtls: invalid signature of the CertificateVerify message"))
+	}
+	transcript.add(msg)
+	return nil
+}
+
+// writeFinished13 writes the Finished message under the handshake traffic
+// secret base.
+func (c *Conn) writeFinished13(base []byte, transcript *finishedHash) error {
+	msg, err := c.writeHandshake(typeFinished, finishedData(base, transcript.sum()))
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	return nil
+}
+
+// readFinished13 reads the Finished message of the peer, sent under the
+// handshake traffic secret base.
+func (c *Conn) readFinished13(base []byte, transcript *finishedHash) error {
+	expected := finishedData(base, transcript.sum())
+	msg, body, err := c.expectHandshake(typeFinished)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(body, expected) {
+		return c.fail(alertDecryptError, errors.New("gmtls: invalid Finished message"))
+	}
+	transcript.add(msg)
+	return nil
+}
+
+func (c *Conn) clientHandshake13() error {
+	config := c.config
+	if config.RootCAs == nil && !config.InsecureSkipVerify {
+		return errors.New("gmtls: either RootCAs or InsecureSkipVerify must be set")
+	}
+	suites, err := config.cipherSuites13()
+	if err != nil {
+		return err
+	}
+	var transcript finishedHash
+
+	key, err := utils.SM2GenerateKey(config.rand(), sm2.GetSm2P256V1())
+	if err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	clientRandom := make([]byte, randomLen)
+	if _, err := io.ReadFull(config.rand(), clientRandom); err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	var suiteList, exts []byte
+	for _, suite := range suites {
+		suiteList = appendUint(suiteList, 2, int(suite))
+	}
+	// IP addresses are no host names
+	if config.ServerName != "" && net.ParseIP(config.ServerName) == nil {
+		name := appendVector([]byte{0}, 2, []byte(config.ServerName))
+		exts = appendExtension(exts, extensionServerName, appendVector(nil, 2, name))
+	}
+	exts = appendExtension(exts, extensionSupportedVersions, appendVector(nil, 1, appendUint(nil, 2, int(VersionTLS13))))
+	exts = appendExtension(exts, extensionSupportedGroups, appendVector(nil, 2, appendUint(nil, 2, int(curveSM2))))
+	exts = appendExtension(exts, extensionSignatureAlgorithms, appendVector(nil, 2, appendUint(nil, 2, int(sm2sigSM3))))
+	exts = appendExtension(exts, extensionKeyShare, appendVector(nil, 2, keyShare(key)))
+	hello := appendUint(nil, 2, int(legacyVersionTLS12))
+	hello = append(hello, clientRandom...)
+	hello = appendVector(hello, 1, nil)
+	hello = appendVector(hello, 2, suiteList)
+	hello = appendVector(hello, 1, []byte{0})
+	hello = appendVector(hello, 2, exts)
+	msg, err := c.writeHandshake(typeClientHello, hello)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	msg, body, err := c.expectHandshake(typeServerHello)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	p := &parser{b: body}
+	p.uint(2)
+	serverRandom := p.bytes(randomLen)
+	sessionID := p.vector(1)
+	suite := uint16(p.uint(2))
+	compression := p.uint(1)
+	serverExts, ok := parseExtensions(p.vector(2))
+	if p.bad || len(p.b) != 0 || !ok {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed ServerHello message"))
+	}
+	if bytes.Equal(serverRandom, helloRetryRequestRandom) {
+		return c.fail(alertHandshakeFailure, errors.New("gmtls: HelloRetryRequest is not supported"))
+	}
+	if v := serverExts[extensionSupportedVersions]; len(v) != 2 || uint16(v[0])<<8|uint16(v[1]) != VersionTLS13 {
+		return c.fail(alertProtocolVersion, errors.New("gmtls: server did not select TLS 1.3"))
+	}
+	offered := false
+	for _, s := range suites {
+		offered = offered || s == suite
+	}
+	if !offered || len(sessionID) != 0 || compression != 0 {
+		return c.fail(alertIllegalParameter, errors.New("gmtls: server selected an unoffered cipher suite, session or compression method"))
+	}
+	share := &parser{b: serverExts[extensionKeyShare]}
+	group := uint16(share.uint(2))
+	peerKey := share.vector(2)
+	if share.bad || len(share.b) != 0 {
+		return c.fail(alertMissingExtension, errors.New("gmtls: server sent no key share"))
+	}
+	if group != curveSM2 {
+		return c.fail(alertIllegalParameter, fmt.Errorf("gmtls: server selected unoffered group %d", group))
+	}
+	shared, err := sharedSecret(key, peerKey)
+	if err != nil {
+		return c.fail(alertIllegalParameter, err)
+	}
+
+	handshake := handshakeSecret(shared)
+	clientHSSecret := deriveSecret(handshake, "c hs traffic", transcript.sum())
+	serverHSSecret := deriveSecret(handshake, "s hs traffic", transcript.sum())
+	if err := c.setReadSecret(suite, serverHSSecret); err != nil {
+		return err
+	}
+	if err := c.setWriteSecret(suite, clientHSSecret); err != nil {
+		return err
+	}
+
+	if msg, body, err = c.expectHandshake(typeEncryptedExtensions); err != nil {
+		return err
+	}
+	transcript.add(msg)
+	p = &parser{b: body}
+	if _, ok := parseExtensions(p.vector(2)); p.bad || len(p.b) != 0 || !ok {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed EncryptedExtensions message"))
+	}
+
+	if msg, err = c.readHandshake(); err != nil {
+		return err
+	}
+	certRequested := msg[0] == typeCertificateRequest
+	if certRequested {
+		transcript.add(msg)
+		p = &parser{b: msg[4:]}
+		p.vector(1)
+		if _, ok := parseExtensions(p.vector(2)); p.bad || len(p.b) != 0 || !ok {
+			return c.fail(alertDecodeError, errors.New("gmtls: malformed CertificateRequest message"))
+		}
+		if msg, err = c.readHandshake(); err != nil {
+			return err
+		}
+	}
+	if msg[0] != typeCertificate {
+		return c.fail(alertUnexpectedMessage, fmt.Errorf("gmtls: unexpected handshake message of type %d, expected %d", msg[0], typeCertificate))
+	}
+	transcript.add(msg)
+	raw, certs, err := c.parseCertificateMessage13(msg[4:])
+	if err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return c.fail(alertDecodeError, errors.New("gmtls: server sent no certificate"))
+	}
+	if !config.InsecureSkipVerify && config.ServerName != "" {
+		if err := certs[0].VerifyHostname(config.ServerName); err != nil {
+			return c.fail(alertBadCertificate, fmt.Errorf("gmtls: %s", err))
+		}
+	}
+	if err := c.verifyPeerCertificates(raw, certs, config.RootCAs, !config.InsecureSkipVerify); err != nil {
+		return err
+	}
+	c.state.ServerName = config.ServerName
+	if err := c.readCertificateVerify13(certs[0].PublicKey.(*sm2.PublicKey), serverSignatureContext, &transcript); err != nil {
+		return err
+	}
+	if err := c.readFinished13(serverHSSecret, &transcript); err != nil {
+		return err
+	}
+
+	master := masterSecret13(handshake)
+	clientAppSecret := deriveSecret(master, "c ap traffic", transcript.sum())
+	serverAppSecret := deriveSecret(master, "s ap traffic", transcript.sum())
+	if certRequested {
+		if msg, err = c.writeHandshake(typeCertificate, certificateMessage13(config.SignCertificate)); err != nil {
+			return err
+		}
+		transcript.add(msg)
+		if config.SignCertificate != nil {
+			if err := c.writeCertificateVerify13(config.SignCertificate.PrivateKey, clientSignatureContext, &transcript); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.writeFinished13(clientHSSecret, &transcript); err != nil {
+		return err
+	}
+	if err := c.setWriteSecret(suite, clientAppSecret); err != nil {
+		return err
+	}
+	if err := c.setReadSecret(suite, serverAppSecret); err != nil {
+		return err
+	}
+
+	c.state.Version = VersionTLS13
+	c.state.CipherSuite = suite
+	return nil
+}
+
+func (c *Conn) serverHandshake13() error {
+	config := c.config
+	if config.SignCertificate == nil {
+		return errors.New("gmtls: a TLS 1.3 server requires a signing certificate")
+	}
+	suites, err := config.cipherSuites13()
+	if err != nil {
+		return err
+	}
+	var transcript finishedHash
+
+	msg, body, err := c.expectHandshake(typeClientHello)
+	if err != nil {
+		return err
+	}
+	transcript.add(msg)
+	p := &parser{b: body}
+	p.uint(2)
+	p.bytes(randomLen)
+	sessionID := p.vector(1)
+	offered := p.vector(2)
+	compressions := p.vector(1)
+	clientExts, ok := parseExtensions(p.vector(2))
+	if p.bad || len(p.b) != 0 || !ok || len(sessionID) > 32 {
+		return c.fail(alertDecodeError, errors.New("gmtls: malformed ClientHello message"))
+	}
+	if !containsUint16(extensionVector(clientExts[extensionSupportedVersions], 1), VersionTLS13) {
+		return c.fail(alertProtocolVersion, errors.New("gmtls: client does not support TLS 1.3"))
+	}
+	if !bytes.Equal(compressions, []byte{0}) {
+		return c.fail(alertIllegalParameter, errors.New("gmtls: client offered compression methods"))
+	}
+	var suite uint16
+	for _, s := range suites {
+		if containsUint16(offered, s) {
+			suite = s
+			break
+		}
+	}
+	if suite == 0 {
+		return c.fail(alertHandshakeFailure, errors.New("gmtls: no cipher suite supported by both client and server"))
+	}
+	if !containsUint16(extensionVector(clientExts[extensionSignatureAlgorithms], 2), sm2sigSM3) {
+		return c.fail(alertHandshakeFailure, errors.New("gmtls: client does not support SM2 signatures"))
+	}
+	var peerKey []byte
+	for shares := (&parser{b: extensionVector(clientExts[extensionKeyShare], 2)}); !shares.bad && len(shares.b) > 0; {
+		group := uint16(shares.uint(2))
+		if data := shares.vector(2); group == curveSM2 && !shares.bad && peerKey == nil {
+			peerKey = data
+		}
+	}
+	if peerKey == nil {
+		return c.fail(alertHandshakeFailure, errors.New("gmtls: client sent no SM2 key share"))
+	}
+	for names := (&parser{b: extensionVector(clientExts[extensionServerName], 2)}); !names.bad && len(names.b) > 0; {
+		typ := names.uint(1)
+		if name := names.vector(2); typ == 0 && !names.bad {
+			c.state.ServerName = string(name)
+		}
+	}
+
+	key, err := utils.SM2GenerateKey(config.rand(), sm2.GetSm2P256V1())
+	if err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	shared, err := sharedSecret(key, peerKey)
+	if err != nil {
+		return c.fail(alertIllegalParameter, err)
+	}
+	serverRandom := make([]byte, randomLen)
+	if _, err := io.ReadFull(config.rand(), serverRandom); err != nil {
+		return c.fail(alertInternalError, err)
+	}
+	var exts []byte
+	exts = appendExtension(exts, extensionSupportedVersions, appendUint(nil, 2, int(VersionTLS13)))
+	exts = appendExtension(exts, extensionKeyShare, keyShare(key))
+	hello := appendUint(nil, 2, int(legacyVersionTLS12))
+	hello = append(hello, serverRandom...)
+	hello = appendVector(hello, 1, sessionID)
+	hello = appendUint(hello, 2, int(suite))
+	hello = append(hello, 0)
+	hello = appendVector(hello, 2, exts)
+	if msg, err = c.writeHandshake(typeServerHello, hello); err != nil {
+		return err
+	}
+	transcript.add(msg)
+
+	handshake := handshakeSecret(shared)
+	clientHSSecret := deriveSecret(handshake, "c hs traffic", transcript.sum())
+	serverHSSecret := deriveSecret(handshake, "s hs traffic", transcript.sum())
+	if err := c.setWriteSecret(suite, serverHSSecret); err != nil {
+		return err
+	}
+	if err := c.setReadSecret(suite, clientHSSecret); err != nil {
+		return err
+	}
+
+	if msg, err = c.writeHandshake(typeEncryptedExtensions, appendVector(nil, 2, nil)); err != nil {
+		return err
+	}
+	transcript.add(msg)
+	if config.ClientAuth != tls.NoClientCert {
+		request := appendVector(nil, 1, nil)
+		request = appendVector(request, 2, appendExtension(nil, extensionSignatureAlgorithms, appendVector(nil, 2, appendUint(nil, 2, int(sm2sigSM3)))))
+		if msg, err = c.writeHandshake(typeCertificateRequest, request); err != nil {
+			return err
+		}
+		transcript.add(msg)
+	}
+	if msg, err = c.writeHandshake(typeCertificate, certificateMessage13(config.SignCertificate)); err != nil {
+		return err
+	}
+	transcript.add(msg)
+	if err := c.writeCertificateVerify13(config.SignCertificate.PrivateKey, serverSignatureContext, &transcript); err != nil {
+		return err
+	}
+	if err := c.writeFinished13(serverHSSecret, &transcript); err != nil {
+		return err
+	}
+
+	master := masterSecret13(handshake)
+	clientAppSecret := deriveSecret(master, "c ap traffic", transcript.sum())
+	serverAppSecret := deriveSecret(master, "s ap traffic", transcript.sum())
+	if err := c.setWriteSecret(suite, serverAppSecret); err != nil {
+		return err
+	}
+
+	if config.ClientAuth != tls.NoClientCert {
+		if msg, body, err = c.expectHandshake(typeCertificate); err != nil {
+			return err
+		}
+		transcript.add(msg)
+		raw, certs, err := c.parseCertificateMessage13(body)
+		if err != nil {
+			return err
+		}
+		if len(certs) == 0 {
+			if config.ClientAuth == tls.RequireAnyClientCert || config.ClientAuth == tls.RequireAndVerifyClientCert {
+				return c.fail(alertCertificateRequired, errors.New("gmtls: client didn't provide a certificate"))
+			}
+		} else {
+			verify := config.ClientAuth == tls.VerifyClientCertIfGiven || config.ClientAuth == tls.RequireAndVerifyClientCert
+			if err := c.verifyPeerCertificates(raw, certs, config.ClientCAs, verify); err != nil {
+				return err
+			}
+			if err := c.readCertificateVerify13(certs[0].PublicKey.(*sm2.PublicKey), clientSignatureContext, &transcript); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.readFinished13(clientHSSecret, &transcript); err != nil {
+		return err
+	}
+	if err := c.setReadSecret(suite, clientAppSecret); err != nil {
+		return err
+	}
+
+	c.state.Version = VersionTLS13
+	c.state.CipherSuite = suite
+	return nil
+}
+
+// handlePostHandshake processes the handshake messages TLS 1.3 peers send
+// after the handshake: session tickets are ignored, as resumption is not
+// supported, and key updates are applied. inMutex must be held.
+func (c *Conn) handlePostHandshake(payload []byte) error {
+	c.hand = append(c.hand, payload...)
+	for len(c.hand) >= 4 && len(c.hand) >= 4+handshakeLen(c.hand) {
+		n := 4 + handshakeLen(c.hand)
+		msg := c.hand[:n]
+		c.hand = c.hand[n:]
+		switch msg[0] {
+		case typeNewSessionTicket:
+		case typeKeyUpdate:
+			if n != 5 || msg[4] > 1 {
+				return c.fail(alertDecodeError, errors.New("gmtls: malformed KeyUpdate message"))
+			}
+			// The next messages are protected with the next keys
+			if len(c.hand) > 0 {
+				return c.fail(alertUnexpectedMessage, errors.New("gmtls: KeyUpdate message not at a record boundary"))
+			}
+			if err := c.in.setTrafficSecret(c.in.suite, nextTrafficSecret(c.in.secret)); err != nil {
+				return c.fail(alertInternalError, err)
+			}
+			if msg[4] == 1 {
+				if err := c.updateWriteKeys(); err != nil {
+					return err
+				}
+			}
+		default:
+			return c.fail(alertUnexpectedMessage, fmt.Errorf("gmtls: unexpected handshake message of type %d after the handshake", msg[0]))
+		}
+	}
+	if len(c.hand) >= 4 && handshakeLen(c.hand) > maxHandshake {
+		return c.fail(alertIllegalParameter, alertIllegalParameter)
+	}
+	return nil
+}
+
+// updateWriteKeys answers a KeyUpdate requesting an update with a
+// KeyUpdate, and protects the next records with the next traffic secret.
+func (c *Conn) updateWriteKeys() error {
+	c.outMutex.Lock()
+	defer c.outMutex.Unlock()
+
+	if _, err := c.writeRecordLocked(recordTypeHandshake, []byte{typeKeyUpdate, 0, 0, 1, 0}); err != nil {
+		return err
+	}
+	if err := c.out.setTrafficSecret(c.out.suite, nextTrafficSecret(c.out.secret)); err != nil {
+		c.writeErr = err
+		return err
+	}
+	return nil
+}
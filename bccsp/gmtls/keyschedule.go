@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmtls
+
+import (
+	"crypto/hmac"
+
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// hkdfExtract is HKDF-Extract of RFC 5869 over HMAC-SM3. A nil salt or
+// ikm stands for a string of zeros as long as a digest.
+func hkdfExtract(salt, ikm []byte) []byte {
+	if salt == nil {
+		salt = make([]byte, sm3Len)
+	}
+	if ikm == nil {
+		ikm = make([]byte, sm3Len)
+	}
+	h := hmac.New(sm3.New, salt)
+	h.Write(ikm)
+	return h.Sum(nil)
+}
+
+// hkdfExpand is HKDF-Expand of RFC 5869 over HMAC-SM3.
+func hkdfExpand(prk, info []byte, n int) []byte {
+	h := hmac.New(sm3.New, prk)
+	var out, t []byte
+	for i := byte(1); len(out) < n; i++ {
+		h.Reset()
+		h.Write(t)
+		h.Write(info)
+		h.Write([]byte{i})
+		t = h.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:n]
+}
+
+// expandLabel is HKDF-Expand-Label of RFC 8446.
+func expandLabel(secret []byte, label string, context []byte, n int) []byte {
+	info := appendUint(nil, 2, n)
+	info = appendVector(info, 1, []byte("tls13 "+label))
+	info = appendVector(info, 1, context)
+	return hkdfExpand(secret, info, n)
+}
+
+// deriveSecret is Derive-Secret of RFC 8446, given the digest of the
+// transcript.
+func deriveSecret(secret []byte, label string, transcriptHash []byte) []byte {
+	return expandLabel(secret, label, transcriptHash, sm3Len)
+}
+
+func emptyHash() []byte {
+	return sm3.New().Sum(nil)
+}
+
+// handshakeSecret returns the handshake secret of the shared secret of
+// the key exchange. There is no pre-shared key, so the early secret is
+// derived from zeros.
+func handshakeSecret(shared []byte) []byte {
+	early := hkdfExtract(nil, nil)
+	return hkdfExtract(deriveSecret(early, "derived", emptyHash()), shared)
+}
+
+// masterSecret13 returns the master secret of the handshake secret.
+func masterSecret13(handshake []byte) []byte {
+	return hkdfExtract(deriveSecret(handshake, "derived", emptyHash()), nil)
+}
+
+// trafficKey returns the SM4 key and the IV of a traffic secret.
+func trafficKey(secret []byte) (key, iv []byte) {
+	return expandLabel(secret, "key", nil, sm4KeyLen), expandLabel(secret, "iv", nil, aeadNonceLen)
+}
+
+// nextTrafficSecret returns the traffic secret that follows secret after
+// a KeyUpdate.
+func nextTrafficSecret(secret []byte) []byte {
+	return expandLabel(secret, "traffic upd", nil, sm3Len)
+}
+
+// finishedData returns the verify data of the Finished message sent under
+// the handshake traffic secret base.
+func finishedData(base, transcriptHash []byte) []byte {
+	h := hmac.New(sm3.New, expandLabel(base, "finished", nil, sm3Len))
+	h.Write(transcriptHash)
+	return h.Sum(nil)
+}
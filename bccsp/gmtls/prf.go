@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmtls
+
+import (
+	"crypto/hmac"
+
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// pHash fills result with the P_hash expansion of RFC 5246 over HMAC-SM3.
+func pHash(result, secret, seed []byte) {
+	h := hmac.New(sm3.New, secret)
+	h.Write(seed)
+	a := h.Sum(nil)
+
+	for j := 0; j < len(result); {
+		h.Reset()
+		h.Write(a)
+		h.Write(seed)
+		b := h.Sum(nil)
+		j += copy(result[j:], b)
+
+		h.Reset()
+		h.Write(a)
+		a = h.Sum(nil)
+	}
+}
+
+// prf is the pseudorandom function of GM/T 0024, the PRF of TLS 1.2
+// instantiated with SM3.
+func prf(secret []byte, label string, seed []byte, n int) []byte {
+	labelAndSeed := make([]byte, 0, len(label)+len(seed))
+	labelAndSeed = append(labelAndSeed, label...)
+	labelAndSeed = append(labelAndSeed, seed...)
+	result := make([]byte, n)
+	pHash(result, secret, labelAndSeed)
+	return result
+}
+
+// masterFromPremaster derives the master secret of a connection.
+func masterFromPremaster(premaster, clientRandom, serverRandom []byte) []byte {
+	seed := make([]byte, 0, 2*randomLen)
+	seed = append(seed, clientRandom...)
+	seed = append(seed, serverRandom...)
+	return prf(premaster, "master secret", seed, masterSecretLen)
+}
+
+// keysFromMaster derives the MAC keys and the SM4 keys of the client and
+// of the server from the master secret. Records carry explicit IVs, so
+// the IVs that follow in the key block are not derived.
+func keysFromMaster(master, clientRandom, serverRandom []byte) (clientMAC, serverMAC, clientKey, serverKey []byte) {
+	seed := make([]byte, 0, 2*randomLen)
+	seed = append(seed, serverRandom...)
+	seed = append(seed, clientRandom...)
+	block := prf(master, "key expansion", seed, 2*macKeyLen+2*sm4KeyLen)
+
+	clientMAC, block = block[:macKeyLen], block[macKeyLen:]
+	serverMAC, block = block[:macKeyLen], block[macKeyLen:]
+	clientKey, serverKey = block[:sm4KeyLen], block[sm4KeyLen:]
+	return
+}
+
+// finishedHash computes the verify data of the Finished messages over the
+// transcript of the handshake.
+type finishedHash struct {
+	transcript []byte
+}
+
+// add appends a handshake message to the transcript.
+func (h *finishedHash) add(msg []byte) {
+	h.transcript = append(h.transcript, msg...)
+}
+
+// sum returns the SM3 digest of the handshake messages so far.
+func (h *finishedHash) sum() []byte {
+	d := sm3.New()
+	d.Write(h.transcript)
+	return d.Sum(nil)
+}
+
+func (h *finishedHash) clientSum(master []byte) []byte {
+	return prf(master, "client finished", h.sum(), finishedVerifyLen)
+}
+
+func (h *finishedHash) serverSum(master []byte) []byte {
+	return prf(master, "server finished", h.sum(), finishedVerifyLen)
+}
@@ -0,0 +1,191 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// SignatureAlgorithm is a set of families of signature algorithms.
+type SignatureAlgorithm uint8
+
+const (
+	// SignatureSM2 is SM2 with SM3
+	SignatureSM2 SignatureAlgorithm = 1 << iota
+	// SignatureECDSA is ECDSA with any hash function
+	SignatureECDSA
+	// SignatureRSA is RSA PKCS #1 v1.5 or PSS with any hash function
+	SignatureRSA
+	// SignatureEd25519 is pure Ed25519
+	SignatureEd25519
+)
+
+var signatureAlgorithmNames = []struct {
+	algorithm SignatureAlgorithm
+	name      string
+}{
+	{SignatureSM2, "SM2"},
+	{SignatureECDSA, "ECDSA"},
+	{SignatureRSA, "RSA"},
+	{SignatureEd25519, "ED25519"},
+}
+
+// ParseSignatureAlgorithm returns the family of signature algorithms
+// named name, i.e. SM2, ECDSA, RSA or ED25519, in any case.
+func ParseSignatureAlgorithm(name string) (SignatureAlgorithm, error) {
+	for _, a := range signatureAlgorithmNames {
+		if strings.EqualFold(name, a.name) {
+			return a.algorithm, nil
+		}
+	}
+	return 0, fmt.Errorf("gmx509: unknown signature algorithm %s", name)
+}
+
+// String returns the names of the families of s, separated by commas.
+func (s SignatureAlgorithm) String() string {
+	var names []string
+	for _, a := range signatureAlgorithmNames {
+		if s&a.algorithm != 0 {
+			names = append(names, a.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// AlgorithmPolicy restricts the algorithms of the keys and signatures of
+// certificates, for channels subject to compliance requirements. The zero
+// AlgorithmPolicy allows every algorithm.
+type AlgorithmPolicy struct {
+	// SM2Only requires SM2 keys, and SM2 with SM3 signatures
+	SM2Only bool
+	// NoSHA1 rejects signatures over SHA-1
+	NoSHA1 bool
+	// MinECKeySize is the minimum size in bits of the curves of SM2 and
+	// ECDSA keys
+	MinECKeySize int
+	// MinRSAKeySize is the minimum size in bits of RSA moduli
+	MinRSAKeySize int
+	// SignatureAlgorithms are the families of signature algorithms
+	// allowed to keys and certificates, or zero to allow every family
+	SignatureAlgorithms SignatureAlgorithm
+}
+
+// Enabled reports whether p restricts any algorithm.
+func (p AlgorithmPolicy) Enabled() bool {
+	return p != AlgorithmPolicy{}
+}
+
+// CheckCertificate returns an error if the key or the signature of cert
+// uses an algorithm p forbids.
+func (p AlgorithmPolicy) CheckCertificate(cert *x509.Certificate) error {
+	if err := p.checkPublicKey(cert.PublicKey); err != nil {
+		return fmt.Errorf("gmx509: certificate %q: %s", cert.Subject.CommonName, err)
+	}
+
+	sm2Signed := IsSM2Signed(cert)
+	if p.SM2Only && !sm2Signed {
+		return fmt.Errorf("gmx509: certificate %q is signed with %s, not SM2 with SM3", cert.Subject.CommonName, cert.SignatureAlgorithm)
+	}
+	if p.NoSHA1 && !sm2Signed {
+		switch cert.SignatureAlgorithm {
+		case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+			return fmt.Errorf("gmx509: certificate %q is signed over SHA-1", cert.Subject.CommonName)
+		}
+	}
+	if p.SignatureAlgorithms != 0 && p.SignatureAlgorithms&certificateSignatureAlgorithm(cert, sm2Signed) == 0 {
+		return fmt.Errorf("gmx509: certificate %q is signed with %s, not %s", cert.Subject.CommonName, cert.SignatureAlgorithm, p.SignatureAlgorithms)
+	}
+	return nil
+}
+
+// certificateSignatureAlgorithm returns the family of the signature
+// algorithm of cert, or zero when it is unknown.
+func certificateSignatureAlgorithm(cert *x509.Certificate, sm2Signed bool) SignatureAlgorithm {
+	if sm2Signed {
+		return SignatureSM2
+	}
+	switch cert.SignatureAlgorithm {
+	case x509.ECDSAWithSHA1, x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512:
+		return SignatureECDSA
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA,
+		x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS:
+		return SignatureRSA
+	case x509.PureEd25519:
+		return SignatureEd25519
+	default:
+		return 0
+	}
+}
+
+// CheckPublicKey returns an error if p forbids the algorithm or the size
+// of pub.
+func (p AlgorithmPolicy) CheckPublicKey(pub interface{}) error {
+	if err := p.checkPublicKey(pub); err != nil {
+		return fmt.Errorf("gmx509: %s", err)
+	}
+	return nil
+}
+
+func (p AlgorithmPolicy) checkPublicKey(pub interface{}) error {
+	switch k := pub.(type) {
+	case *sm2.PublicKey:
+		if err := p.checkSignatureAlgorithm(pub, SignatureSM2); err != nil {
+			return err
+		}
+		return p.checkECKeySize("SM2", k.Curve.Params().BitSize)
+	case *ecdsa.PublicKey:
+		if p.SM2Only {
+			return fmt.Errorf("%T keys are not allowed, only SM2", pub)
+		}
+		if err := p.checkSignatureAlgorithm(pub, SignatureECDSA); err != nil {
+			return err
+		}
+		return p.checkECKeySize("ECDSA", k.Curve.Params().BitSize)
+	case *rsa.PublicKey:
+		if p.SM2Only {
+			return fmt.Errorf("%T keys are not allowed, only SM2", pub)
+		}
+		if err := p.checkSignatureAlgorithm(pub, SignatureRSA); err != nil {
+			return err
+		}
+		if size := k.N.BitLen(); size < p.MinRSAKeySize {
+			return fmt.Errorf("RSA key of %d bits is smaller than %d bits", size, p.MinRSAKeySize)
+		}
+		return nil
+	case ed25519.PublicKey:
+		if p.SM2Only {
+			return fmt.Errorf("%T keys are not allowed, only SM2", pub)
+		}
+		return p.checkSignatureAlgorithm(pub, SignatureEd25519)
+	default:
+		if p.Enabled() {
+			return fmt.Errorf("unsupported %T key", pub)
+		}
+		return nil
+	}
+}
+
+func (p AlgorithmPolicy) checkSignatureAlgorithm(pub interface{}, algorithm SignatureAlgorithm) error {
+	if p.SignatureAlgorithms != 0 && p.SignatureAlgorithms&algorithm == 0 {
+		return fmt.Errorf("%T keys are not allowed, only %s", pub, p.SignatureAlgorithms)
+	}
+	return nil
+}
+
+func (p AlgorithmPolicy) checkECKeySize(algorithm string, size int) error {
+	if size < p.MinECKeySize {
+		return fmt.Errorf("%s key of %d bits is smaller than %d bits", algorithm, size, p.MinECKeySize)
+	}
+	return nil
+}
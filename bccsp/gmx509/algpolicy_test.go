@@ -0,0 +1,94 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlgorithmPolicy(t *testing.T) {
+	sm2Key := newSM2Key(t)
+	der, err := CreateCertificate(rand.Reader, caTemplate("gm"), caTemplate("gm"), &sm2Key.PublicKey, sm2Key)
+	require.NoError(t, err)
+	gmCert, err := ParseCertificate(der)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.CreateCertificate(rand.Reader, caTemplate("p224"), caTemplate("p224"), &ecKey.PublicKey, ecKey)
+	require.NoError(t, err)
+	ecCert, err := ParseCertificate(der)
+	require.NoError(t, err)
+
+	var policy AlgorithmPolicy
+	assert.False(t, policy.Enabled())
+	assert.NoError(t, policy.CheckCertificate(gmCert))
+	assert.NoError(t, policy.CheckCertificate(ecCert))
+
+	policy = AlgorithmPolicy{SM2Only: true}
+	assert.True(t, policy.Enabled())
+	assert.NoError(t, policy.CheckCertificate(gmCert))
+	assert.EqualError(t, policy.CheckCertificate(ecCert), `gmx509: certificate "p224": *ecdsa.PublicKey keys are not allowed, only SM2`)
+
+	policy = AlgorithmPolicy{MinECKeySize: 256}
+	assert.NoError(t, policy.CheckCertificate(gmCert))
+	assert.EqualError(t, policy.CheckCertificate(ecCert), `gmx509: certificate "p224": ECDSA key of 224 bits is smaller than 256 bits`)
+	assert.EqualError(t, policy.CheckPublicKey(&ecKey.PublicKey), "gmx509: ECDSA key of 224 bits is smaller than 256 bits")
+
+	policy = AlgorithmPolicy{NoSHA1: true}
+	assert.NoError(t, policy.CheckCertificate(ecCert))
+	sha1Cert := *ecCert
+	sha1Cert.SignatureAlgorithm = x509.ECDSAWithSHA1
+	assert.EqualError(t, policy.CheckCertificate(&sha1Cert), `gmx509: certificate "p224" is signed over SHA-1`)
+}
+
+func TestSignatureAlgorithms(t *testing.T) {
+	sm2Key := newSM2Key(t)
+	der, err := CreateCertificate(rand.Reader, caTemplate("gm"), caTemplate("gm"), &sm2Key.PublicKey, sm2Key)
+	require.NoError(t, err)
+	gmCert, err := ParseCertificate(der)
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err = x509.CreateCertificate(rand.Reader, caTemplate("p256"), caTemplate("p256"), &ecKey.PublicKey, ecKey)
+	require.NoError(t, err)
+	ecCert, err := ParseCertificate(der)
+	require.NoError(t, err)
+
+	policy := AlgorithmPolicy{SignatureAlgorithms: SignatureSM2 | SignatureECDSA}
+	assert.True(t, policy.Enabled())
+	assert.NoError(t, policy.CheckCertificate(gmCert))
+	assert.NoError(t, policy.CheckCertificate(ecCert))
+
+	policy = AlgorithmPolicy{SignatureAlgorithms: SignatureSM2}
+	assert.NoError(t, policy.CheckCertificate(gmCert))
+	assert.EqualError(t, policy.CheckCertificate(ecCert), `gmx509: certificate "p256": *ecdsa.PublicKey keys are not allowed, only SM2`)
+
+	// An ECDSA key certified by an SM2 CA
+	der, err = CreateCertificate(rand.Reader, caTemplate("issued"), gmCert, &ecKey.PublicKey, sm2Key)
+	require.NoError(t, err)
+	issuedCert, err := ParseCertificate(der)
+	require.NoError(t, err)
+	policy = AlgorithmPolicy{SignatureAlgorithms: SignatureECDSA}
+	assert.NoError(t, policy.CheckPublicKey(&ecKey.PublicKey))
+	assert.Error(t, policy.CheckCertificate(issuedCert))
+
+	a, err := ParseSignatureAlgorithm("ed25519")
+	require.NoError(t, err)
+	assert.Equal(t, SignatureEd25519, a)
+	_, err = ParseSignatureAlgorithm("DSA")
+	assert.EqualError(t, err, "gmx509: unknown signature algorithm DSA")
+	assert.Equal(t, "SM2,RSA", (SignatureSM2 | SignatureRSA).String())
+}
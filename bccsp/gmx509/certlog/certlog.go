@@ -0,0 +1,183 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package certlog implements an append-only log of issued certificates,
+// hashed into an SM3 Merkle tree in the manner of Certificate Transparency
+// (RFC 6962), so that the consortium members can audit the identities
+// an internal CA issued.
+package certlog
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is a certificate recorded by a Log.
+type Entry struct {
+	// Index is the position of the entry in the log
+	Index uint64
+	// Timestamp is when the entry was appended
+	Timestamp time.Time
+	// Raw is the DER encoding of the certificate
+	Raw []byte
+}
+
+// Leaf returns the data hashed into the Merkle tree for e: the timestamp
+// in milliseconds since the epoch, followed by the certificate.
+func (e *Entry) Leaf() []byte {
+	leaf := make([]byte, 8, 8+len(e.Raw))
+	binary.BigEndian.PutUint64(leaf, uint64(e.Timestamp.UnixNano()/int64(time.Millisecond)))
+	return append(leaf, e.Raw...)
+}
+
+// Log is an append-only log of certificates kept in a file. Each record
+// of the file holds the length of the leaf data on 4 bytes, followed by
+// the leaf data of the entry.
+type Log struct {
+	mutex  sync.RWMutex
+	file   *os.File
+	leaves [][]byte
+	hashes [][]byte
+}
+
+// Open opens the log kept at path, creating it if needed.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("certlog: failed to open log [%s]", err)
+	}
+
+	l := &Log{file: file}
+	r := bufio.NewReader(file)
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("certlog: truncated record %d [%s]", len(l.leaves), err)
+		}
+		leaf := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, leaf); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("certlog: truncated record %d [%s]", len(l.leaves), err)
+		}
+		if len(leaf) < 8 {
+			file.Close()
+			return nil, fmt.Errorf("certlog: malformed record %d", len(l.leaves))
+		}
+		l.leaves = append(l.leaves, leaf)
+		l.hashes = append(l.hashes, LeafHash(leaf))
+	}
+	return l, nil
+}
+
+// Close closes the file of the log.
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.file.Close()
+}
+
+// Append records cert and returns its index. The record is synced to disk
+// before Append returns.
+func (l *Log) Append(cert *x509.Certificate) (uint64, error) {
+	if cert == nil || len(cert.Raw) == 0 {
+		return 0, errors.New("certlog: invalid certificate")
+	}
+
+	e := &Entry{Timestamp: time.Now(), Raw: cert.Raw}
+	leaf := e.Leaf()
+	record := make([]byte, 4, 4+len(leaf))
+	binary.BigEndian.PutUint32(record, uint32(len(leaf)))
+	record = append(record, leaf...)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, err := l.file.Seek(0, io.SeekEnd); err != nil {
+		return 0, fmt.Errorf("certlog: failed to append certificate [%s]", err)
+	}
+	if _, err := l.file.Write(record); err != nil {
+		return 0, fmt.Errorf("certlog: failed to append certificate [%s]", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return 0, fmt.Errorf("certlog: failed to append certificate [%s]", err)
+	}
+	l.leaves = append(l.leaves, leaf)
+	l.hashes = append(l.hashes, LeafHash(leaf))
+	return uint64(len(l.leaves) - 1), nil
+}
+
+// Size returns the number of entries of the log.
+func (l *Log) Size() uint64 {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	return uint64(len(l.hashes))
+}
+
+// Entry returns the entry at index.
+func (l *Log) Entry(index uint64) (*Entry, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if index >= uint64(len(l.leaves)) {
+		return nil, fmt.Errorf("certlog: no entry at index %d", index)
+	}
+	leaf := l.leaves[index]
+	ms := int64(binary.BigEndian.Uint64(leaf[:8]))
+	return &Entry{
+		Index:     index,
+		Timestamp: time.Unix(0, ms*int64(time.Millisecond)),
+		Raw:       append([]byte{}, leaf[8:]...),
+	}, nil
+}
+
+// RootHash returns the root hash of the tree of the first size entries.
+func (l *Log) RootHash(size uint64) ([]byte, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if size > uint64(len(l.hashes)) {
+		return nil, fmt.Errorf("certlog: tree size %d exceeds the log size %d", size, len(l.hashes))
+	}
+	return rootHash(l.hashes[:size]), nil
+}
+
+// InclusionProof returns the audit path proving that the entry at index is
+// included in the tree of the first size entries.
+func (l *Log) InclusionProof(index, size uint64) ([][]byte, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if size > uint64(len(l.hashes)) {
+		return nil, fmt.Errorf("certlog: tree size %d exceeds the log size %d", size, len(l.hashes))
+	}
+	if index >= size {
+		return nil, fmt.Errorf("certlog: index %d out of a tree of size %d", index, size)
+	}
+	return inclusionPath(int(index), l.hashes[:size]), nil
+}
+
+// ConsistencyProof returns the proof that the tree of the first second
+// entries extends the tree of the first first entries.
+func (l *Log) ConsistencyProof(first, second uint64) ([][]byte, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	if second > uint64(len(l.hashes)) {
+		return nil, fmt.Errorf("certlog: tree size %d exceeds the log size %d", second, len(l.hashes))
+	}
+	if first > second {
+		return nil, fmt.Errorf("certlog: tree of size %d cannot extend a tree of size %d", second, first)
+	}
+	if first == 0 || first == second {
+		return nil, nil
+	}
+	return consistencySubproof(int(first), l.hashes[:second], true), nil
+}
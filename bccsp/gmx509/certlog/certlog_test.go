@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certlog
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogProofs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certlog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	l, err := Open(path)
+	require.NoError(t, err)
+	root, err := l.RootHash(0)
+	require.NoError(t, err)
+	assert.Equal(t, emptyRoot(), root)
+
+	const n = 13
+	for i := 0; i < n; i++ {
+		index, err := l.Append(&x509.Certificate{Raw: []byte(fmt.Sprintf("cert%d", i))})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i), index)
+	}
+	_, err = l.Append(&x509.Certificate{})
+	assert.EqualError(t, err, "certlog: invalid certificate")
+	require.NoError(t, l.Close())
+
+	// The log survives reopening
+	l, err = Open(path)
+	require.NoError(t, err)
+	defer l.Close()
+	require.Equal(t, uint64(n), l.Size())
+	e, err := l.Entry(5)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("cert5"), e.Raw)
+
+	roots := make([][]byte, n+1)
+	for size := uint64(0); size <= n; size++ {
+		roots[size], err = l.RootHash(size)
+		require.NoError(t, err)
+	}
+	for size := uint64(1); size <= n; size++ {
+		for index := uint64(0); index < size; index++ {
+			e, err := l.Entry(index)
+			require.NoError(t, err)
+			proof, err := l.InclusionProof(index, size)
+			require.NoError(t, err)
+			assert.NoError(t, VerifyInclusion(LeafHash(e.Leaf()), index, size, proof, roots[size]), "index %d size %d", index, size)
+
+			other, err := l.Entry((index + 1) % n)
+			require.NoError(t, err)
+			assert.Error(t, VerifyInclusion(LeafHash(other.Leaf()), index, size, proof, roots[size]))
+		}
+	}
+	for second := uint64(0); second <= n; second++ {
+		for first := uint64(0); first <= second; first++ {
+			proof, err := l.ConsistencyProof(first, second)
+			require.NoError(t, err)
+			assert.NoError(t, VerifyConsistency(first, second, roots[first], roots[second], proof), "first %d second %d", first, second)
+			if first > 0 && first < second {
+				assert.Error(t, VerifyConsistency(first, second, roots[first-1], roots[second], proof))
+			}
+		}
+	}
+
+	_, err = l.InclusionProof(n, n)
+	assert.EqualError(t, err, "certlog: index 13 out of a tree of size 13")
+	_, err = l.ConsistencyProof(1, n+1)
+	assert.EqualError(t, err, "certlog: tree size 14 exceeds the log size 13")
+}
+
+func TestOpenTruncatedLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certlog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	l, err := Open(path)
+	require.NoError(t, err)
+	_, err = l.Append(&x509.Certificate{Raw: []byte("cert")})
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-1))
+	_, err = Open(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package certlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// The Merkle tree of RFC 6962 section 2.1, with SM3 as the hash function.
+
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// LeafHash returns the Merkle tree hash of the leaf data.
+func LeafHash(data []byte) []byte {
+	h := sm3.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sm3.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// emptyRoot is the hash of the empty tree.
+func emptyRoot() []byte {
+	return sm3.New().Sum(nil)
+}
+
+// splitPoint returns the largest power of two smaller than n, n > 1.
+func splitPoint(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// rootHash returns MTH(leaves), the hash of the tree of leaf hashes.
+func rootHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		return emptyRoot()
+	case 1:
+		return leaves[0]
+	}
+	k := splitPoint(len(leaves))
+	return nodeHash(rootHash(leaves[:k]), rootHash(leaves[k:]))
+}
+
+// inclusionPath returns PATH(m, leaves).
+func inclusionPath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := splitPoint(len(leaves))
+	if m < k {
+		return append(inclusionPath(m, leaves[:k]), rootHash(leaves[k:]))
+	}
+	return append(inclusionPath(m-k, leaves[k:]), rootHash(leaves[:k]))
+}
+
+// consistencySubproof returns SUBPROOF(m, leaves, b).
+func consistencySubproof(m int, leaves [][]byte, b bool) [][]byte {
+	if m == len(leaves) {
+		if b {
+			return nil
+		}
+		return [][]byte{rootHash(leaves)}
+	}
+	k := splitPoint(len(leaves))
+	if m <= k {
+		return append(consistencySubproof(m, leaves[:k], b), rootHash(leaves[k:]))
+	}
+	return append(consistencySubproof(m-k, leaves[k:], false), rootHash(leaves[:k]))
+}
+
+// VerifyInclusion checks that proof proves that the leaf hash leafHash is
+// at index in the tree of size treeSize whose root hash is root, as in
+// RFC 9162 section 2.1.3.2.
+func VerifyInclusion(leafHash []byte, index, treeSize uint64, proof [][]byte, root []byte) error {
+	if index >= treeSize {
+		return fmt.Errorf("certlog: index %d out of a tree of size %d", index, treeSize)
+	}
+
+	fn, sn := index, treeSize-1
+	r := leafHash
+	for _, p := range proof {
+		if sn == 0 {
+			return errors.New("certlog: inclusion proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("certlog: inclusion proof too short")
+	}
+	if !bytes.Equal(r, root) {
+		return errors.New("certlog: inclusion proof does not match the root hash")
+	}
+	return nil
+}
+
+// VerifyConsistency checks that proof proves that the tree of size
+// secondSize and root hash secondRoot extends the tree of size firstSize
+// and root hash firstRoot, as in RFC 9162 section 2.1.4.2.
+func VerifyConsistency(firstSize, secondSize uint64, firstRoot, secondRoot []byte, proof [][]byte) error {
+	switch {
+	case firstSize > secondSize:
+		return fmt.Errorf("certlog: tree of size %d cannot extend a tree of size %d", secondSize, firstSize)
+	case firstSize == secondSize:
+		if len(proof) != 0 {
+			return errors.New("certlog: consistency proof of a tree with itself must be empty")
+		}
+		if !bytes.Equal(firstRoot, secondRoot) {
+			return errors.New("certlog: root hashes of the same tree differ")
+		}
+		return nil
+	case firstSize == 0:
+		// The empty tree is consistent with any tree
+		if len(proof) != 0 {
+			return errors.New("certlog: consistency proof from the empty tree must be empty")
+		}
+		return nil
+	}
+
+	if len(proof) == 0 {
+		return errors.New("certlog: empty consistency proof")
+	}
+	if firstSize&(firstSize-1) == 0 {
+		proof = append([][]byte{firstRoot}, proof...)
+	}
+
+	fn, sn := firstSize-1, secondSize-1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr, sr := proof[0], proof[0]
+	for _, c := range proof[1:] {
+		if sn == 0 {
+			return errors.New("certlog: consistency proof too long")
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = nodeHash(c, fr)
+			sr = nodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = nodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	if sn != 0 {
+		return errors.New("certlog: consistency proof too short")
+	}
+	if !bytes.Equal(fr, firstRoot) || !bytes.Equal(sr, secondRoot) {
+		return errors.New("certlog: consistency proof does not match the root hashes")
+	}
+	return nil
+}
@@ -0,0 +1,570 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/paul-lee-attorney/gm/sm4"
+)
+
+// The PKCS#7 structures of GM/T 0010, signed with SM2 over SM3 and
+// encrypted with SM4-CBC under SM2 encrypted keys.
+
+var (
+	// OIDGMData, OIDGMSignedData and OIDGMEnvelopedData are the GM/T 0010
+	// content types
+	OIDGMData          = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 1}
+	OIDGMSignedData    = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 2}
+	OIDGMEnvelopedData = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 3}
+
+	// The PKCS#7 content types, also accepted when parsing
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+
+	oidSM2Sign    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 1}
+	oidSM2Encrypt = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 3}
+	oidSM4CBC     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 2}
+
+	oidAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttributeSigningTime   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type issuerAndSerial struct {
+	IssuerName   asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type  asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerial
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type recipientInfo struct {
+	Version                int
+	IssuerAndSerialNumber  issuerAndSerial
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []recipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+// sm3DigestSize is the size in bytes of an SM3 digest.
+const sm3DigestSize = 32
+
+// sm2Cipher is the GM/T 0009 encoding of SM2 ciphertexts.
+type sm2Cipher struct {
+	XCoordinate *big.Int
+	YCoordinate *big.Int
+	Hash        []byte
+	CipherText  []byte
+}
+
+// explicit wraps der in the [0] EXPLICIT tag of contentInfo.
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content interface{}) ([]byte, error) {
+	der, err := asn1.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(contentInfo{ContentType: contentType, Content: explicit(der)})
+}
+
+func newIssuerAndSerial(cert *x509.Certificate) issuerAndSerial {
+	return issuerAndSerial{IssuerName: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber}
+}
+
+func (ias issuerAndSerial) matches(cert *x509.Certificate) bool {
+	return bytes.Equal(ias.IssuerName.FullBytes, cert.RawIssuer) && ias.SerialNumber.Cmp(cert.SerialNumber) == 0
+}
+
+// CMSSignOpts are the options of SignCMS.
+type CMSSignOpts struct {
+	// Detached leaves the content out of the SignedData
+	Detached bool
+	// Certificates are embedded next to the signer certificate, such as
+	// the intermediate CAs of its chain
+	Certificates []*x509.Certificate
+	// OmitCertificates leaves all certificates out, the signer one included
+	OmitCertificates bool
+	// SigningTime is the signing time attribute, now if zero
+	SigningTime time.Time
+}
+
+// SignCMS creates a GM/T 0010 SignedData of content, signed by the holder
+// of cert and of its private key priv with SM2 over SM3.
+func SignCMS(rand io.Reader, content []byte, cert *x509.Certificate, priv *sm2.PrivateKey, opts CMSSignOpts) ([]byte, error) {
+	return signCMS(rand, OIDGMData, content, cert, priv, opts)
+}
+
+// signCMS signs content of type contentType.
+func signCMS(rand io.Reader, contentType asn1.ObjectIdentifier, content []byte, cert *x509.Certificate, priv *sm2.PrivateKey, opts CMSSignOpts) ([]byte, error) {
+	if cert == nil || priv == nil {
+		return nil, errors.New("gmx509: signer certificate and key must be different from nil")
+	}
+	signingTime := opts.SigningTime
+	if signingTime.IsZero() {
+		signingTime = time.Now()
+	}
+
+	digest := sm3Sum(content)
+	attrs, err := marshalAttributes([]attribute{
+		newAttribute(oidAttributeContentType, contentType),
+		newAttribute(oidAttributeMessageDigest, digest),
+		newAttribute(oidAttributeSigningTime, signingTime.UTC()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := SignSM2(rand, priv, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	var attrsSet asn1.RawValue
+	if _, err := asn1.Unmarshal(attrs, &attrsSet); err != nil {
+		return nil, err
+	}
+	si := signerInfo{
+		Version:                   1,
+		IssuerAndSerialNumber:     newIssuerAndSerial(cert),
+		DigestAlgorithm:           pkix.AlgorithmIdentifier{Algorithm: OIDSM3},
+		AuthenticatedAttributes:   asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: attrsSet.Bytes},
+		DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSM2Sign},
+		EncryptedDigest:           signature,
+	}
+
+	ci := contentInfo{ContentType: contentType}
+	if !opts.Detached {
+		data, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		ci.Content = explicit(data)
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: OIDSM3}},
+		ContentInfo:      ci,
+		SignerInfos:      []signerInfo{si},
+	}
+	if !opts.OmitCertificates {
+		var certs []byte
+		for _, c := range append([]*x509.Certificate{cert}, opts.Certificates...) {
+			certs = append(certs, c.Raw...)
+		}
+		sd.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs}
+	}
+	return marshalContentInfo(OIDGMSignedData, sd)
+}
+
+func newAttribute(oid asn1.ObjectIdentifier, value interface{}) attribute {
+	der, err := asn1.Marshal(value)
+	if err != nil {
+		// the values of the attributes of SignCMS always marshal
+		panic(err)
+	}
+	return attribute{Type: oid, Value: asn1.RawValue{Tag: asn1.TagSet, IsCompound: true, Bytes: der}}
+}
+
+func sm3Sum(data []byte) []byte {
+	h := sm3.New()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// marshalAttributes returns the DER encoded SET OF attrs, which is signed.
+func marshalAttributes(attrs []attribute) ([]byte, error) {
+	der, err := asn1.Marshal(struct {
+		A []attribute `asn1:"set"`
+	}{A: attrs})
+	if err != nil {
+		return nil, err
+	}
+	var seq asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &seq); err != nil {
+		return nil, err
+	}
+	return seq.Bytes, nil
+}
+
+// CMSSignedData is a parsed SignedData.
+type CMSSignedData struct {
+	// ContentType is the type of the signed content, such as OIDGMData
+	ContentType asn1.ObjectIdentifier
+	// Content is the signed content, nil if detached: set it to the
+	// detached content before calling Verify
+	Content      []byte
+	Certificates []*x509.Certificate
+
+	signerInfos []signerInfo
+}
+
+// ParseCMSSignedData parses a GM/T 0010 or PKCS#7 SignedData. Its content
+// is expected to be encoded in an OCTET STRING, like data and timestamp
+// token contents.
+func ParseCMSSignedData(der []byte) (*CMSSignedData, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("gmx509: failed to parse CMS content info [%s]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("gmx509: trailing data after CMS content info")
+	}
+	if !ci.ContentType.Equal(OIDGMSignedData) && !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("gmx509: CMS content type %s is not SignedData", ci.ContentType)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("gmx509: failed to parse CMS SignedData [%s]", err)
+	}
+
+	parsed := &CMSSignedData{ContentType: sd.ContentInfo.ContentType, signerInfos: sd.SignerInfos}
+	if len(sd.ContentInfo.Content.Bytes) != 0 {
+		if _, err := asn1.Unmarshal(sd.ContentInfo.Content.Bytes, &parsed.Content); err != nil {
+			return nil, fmt.Errorf("gmx509: failed to parse CMS signed content [%s]", err)
+		}
+	}
+	for rest := sd.Certificates.Bytes; len(rest) != 0; {
+		var raw asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &raw); err != nil {
+			return nil, fmt.Errorf("gmx509: failed to parse CMS certificates [%s]", err)
+		}
+		cert, err := ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, fmt.Errorf("gmx509: failed to parse CMS certificate [%s]", err)
+		}
+		parsed.Certificates = append(parsed.Certificates, cert)
+	}
+	return parsed, nil
+}
+
+// Verify checks the signatures of sd over its content and returns the
+// certificates of the signers. If opts is not nil, the certificates of the
+// signers must also chain up to opts.Roots, the certificates of sd serving
+// as intermediates.
+func (sd *CMSSignedData) Verify(opts *VerifyOptions) ([]*x509.Certificate, error) {
+	if len(sd.signerInfos) == 0 {
+		return nil, errors.New("gmx509: the CMS SignedData has no signer")
+	}
+
+	var signers []*x509.Certificate
+	for _, si := range sd.signerInfos {
+		cert := sd.signerCertificate(si)
+		if cert == nil {
+			return nil, fmt.Errorf("gmx509: no certificate for the CMS signer with serial number %s", si.IssuerAndSerialNumber.SerialNumber)
+		}
+		if err := sd.verifySignerInfo(si, cert); err != nil {
+			return nil, err
+		}
+		if opts != nil {
+			o := *opts
+			o.Intermediates = NewCertPool()
+			for _, c := range opts.Intermediates.Certificates() {
+				o.Intermediates.AddCert(c)
+			}
+			for _, c := range sd.Certificates {
+				o.Intermediates.AddCert(c)
+			}
+			if _, err := Verify(cert, o); err != nil {
+				return nil, err
+			}
+		}
+		signers = append(signers, cert)
+	}
+	return signers, nil
+}
+
+func (sd *CMSSignedData) signerCertificate(si signerInfo) *x509.Certificate {
+	for _, cert := range sd.Certificates {
+		if si.IssuerAndSerialNumber.matches(cert) {
+			return cert
+		}
+	}
+	return nil
+}
+
+func (sd *CMSSignedData) verifySignerInfo(si signerInfo, cert *x509.Certificate) error {
+	if !si.DigestAlgorithm.Algorithm.Equal(OIDSM3) {
+		return fmt.Errorf("gmx509: unsupported CMS digest algorithm %s", si.DigestAlgorithm.Algorithm)
+	}
+	if alg := si.DigestEncryptionAlgorithm.Algorithm; !alg.Equal(oidSM2Sign) && !alg.Equal(OIDSignatureSM2WithSM3) {
+		return fmt.Errorf("gmx509: unsupported CMS signature algorithm %s", alg)
+	}
+	if sd.Content == nil {
+		return errors.New("gmx509: the content of the detached CMS signature is missing")
+	}
+
+	// Without authenticated attributes, the content itself is signed
+	signed := sd.Content
+	if len(si.AuthenticatedAttributes.FullBytes) != 0 {
+		digest, err := messageDigest(si.AuthenticatedAttributes.Bytes)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(digest, sm3Sum(sd.Content)) {
+			return errors.New("gmx509: the CMS message digest does not match the content")
+		}
+		// The attributes are signed with their SET OF tag
+		signed = append([]byte{}, si.AuthenticatedAttributes.FullBytes...)
+		signed[0] = 0x31
+	}
+	return VerifySM2(cert.PublicKey, signed, si.EncryptedDigest)
+}
+
+// messageDigest returns the value of the message digest attribute among
+// the encoded attributes.
+func messageDigest(attrs []byte) ([]byte, error) {
+	for rest := attrs; len(rest) != 0; {
+		var attr attribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return nil, fmt.Errorf("gmx509: failed to parse CMS attribute [%s]", err)
+		}
+		if !attr.Type.Equal(oidAttributeMessageDigest) {
+			continue
+		}
+		var digest []byte
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &digest); err != nil {
+			return nil, fmt.Errorf("gmx509: failed to parse CMS message digest [%s]", err)
+		}
+		return digest, nil
+	}
+	return nil, errors.New("gmx509: the CMS authenticated attributes have no message digest")
+}
+
+// EncryptCMS creates a GM/T 0010 EnvelopedData of content, encrypted with
+// SM4-CBC under a random key encrypted with SM2 for each of the SM2
+// certificates of recipients.
+func EncryptCMS(rand io.Reader, content []byte, recipients []*x509.Certificate) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("gmx509: no CMS recipient")
+	}
+
+	key := make([]byte, sm4.BlockSize)
+	iv := make([]byte, sm4.BlockSize)
+	if _, err := io.ReadFull(rand, key); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return nil, err
+	}
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	pad := sm4.BlockSize - len(content)%sm4.BlockSize
+	encrypted := append(append([]byte{}, content...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+
+	var ris []recipientInfo
+	for _, cert := range recipients {
+		pub, ok := cert.PublicKey.(*sm2.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("gmx509: CMS recipient %q has no SM2 key", cert.Subject.CommonName)
+		}
+		encryptedKey, err := EncryptSM2(rand, pub, key)
+		if err != nil {
+			return nil, err
+		}
+		ris = append(ris, recipientInfo{
+			IssuerAndSerialNumber:  newIssuerAndSerial(cert),
+			KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSM2Encrypt},
+			EncryptedKey:           encryptedKey,
+		})
+	}
+
+	params, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, err
+	}
+	return marshalContentInfo(OIDGMEnvelopedData, envelopedData{
+		RecipientInfos: ris,
+		EncryptedContentInfo: encryptedContentInfo{
+			ContentType:                OIDGMData,
+			ContentEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSM4CBC, Parameters: asn1.RawValue{FullBytes: params}},
+			EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: encrypted},
+		},
+	})
+}
+
+// DecryptCMS decrypts a GM/T 0010 or PKCS#7 EnvelopedData for the
+// recipient holding cert and its private key priv.
+func DecryptCMS(der []byte, cert *x509.Certificate, priv *sm2.PrivateKey) ([]byte, error) {
+	if cert == nil || priv == nil {
+		return nil, errors.New("gmx509: recipient certificate and key must be different from nil")
+	}
+	var ci contentInfo
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil, fmt.Errorf("gmx509: failed to parse CMS content info [%s]", err)
+	}
+	if !ci.ContentType.Equal(OIDGMEnvelopedData) && !ci.ContentType.Equal(oidEnvelopedData) {
+		return nil, fmt.Errorf("gmx509: CMS content type %s is not EnvelopedData", ci.ContentType)
+	}
+	var ed envelopedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &ed); err != nil {
+		return nil, fmt.Errorf("gmx509: failed to parse CMS EnvelopedData [%s]", err)
+	}
+
+	var ri *recipientInfo
+	for i := range ed.RecipientInfos {
+		if ed.RecipientInfos[i].IssuerAndSerialNumber.matches(cert) {
+			ri = &ed.RecipientInfos[i]
+			break
+		}
+	}
+	if ri == nil {
+		return nil, errors.New("gmx509: the certificate is not a CMS recipient")
+	}
+	if !ri.KeyEncryptionAlgorithm.Algorithm.Equal(oidSM2Encrypt) {
+		return nil, fmt.Errorf("gmx509: unsupported CMS key encryption algorithm %s", ri.KeyEncryptionAlgorithm.Algorithm)
+	}
+	key, err := DecryptSM2(priv, ri.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	eci := ed.EncryptedContentInfo
+	if !eci.ContentEncryptionAlgorithm.Algorithm.Equal(oidSM4CBC) {
+		return nil, fmt.Errorf("gmx509: unsupported CMS content encryption algorithm %s", eci.ContentEncryptionAlgorithm.Algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(eci.ContentEncryptionAlgorithm.Parameters.FullBytes, &iv); err != nil || len(iv) != sm4.BlockSize {
+		return nil, errors.New("gmx509: invalid SM4-CBC parameters")
+	}
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := encryptedContent(eci.EncryptedContent)
+	if err != nil {
+		return nil, err
+	}
+	if len(encrypted) == 0 || len(encrypted)%sm4.BlockSize != 0 {
+		return nil, errors.New("gmx509: invalid CMS encrypted content length")
+	}
+	content := make([]byte, len(encrypted))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(content, encrypted)
+
+	pad := int(content[len(content)-1])
+	if pad == 0 || pad > sm4.BlockSize {
+		return nil, errors.New("gmx509: invalid CMS content padding")
+	}
+	for _, b := range content[len(content)-pad:] {
+		if int(b) != pad {
+			return nil, errors.New("gmx509: invalid CMS content padding")
+		}
+	}
+	return content[:len(content)-pad], nil
+}
+
+// encryptedContent returns the encrypted content, which BER encoders may
+// split into constructed OCTET STRINGs.
+func encryptedContent(raw asn1.RawValue) ([]byte, error) {
+	if !raw.IsCompound {
+		return raw.Bytes, nil
+	}
+	var content []byte
+	for rest := raw.Bytes; len(rest) != 0; {
+		var part []byte
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &part); err != nil {
+			return nil, fmt.Errorf("gmx509: failed to parse CMS encrypted content [%s]", err)
+		}
+		content = append(content, part...)
+	}
+	return content, nil
+}
+
+// EncryptSM2 encrypts msg for pub and returns the ciphertext in its
+// GM/T 0009 encoding, as carried by CMS and GMTLS.
+func EncryptSM2(rand io.Reader, pub *sm2.PublicKey, msg []byte) ([]byte, error) {
+	ct, err := utils.SM2Encrypt(rand, pub, msg, nil)
+	if err != nil {
+		return nil, err
+	}
+	c1Len := 1 + 2*((pub.Curve.Params().BitSize+7)/8)
+	x, y := elliptic.Unmarshal(pub.Curve, ct[:c1Len])
+	return asn1.Marshal(sm2Cipher{
+		XCoordinate: x,
+		YCoordinate: y,
+		Hash:        ct[c1Len : c1Len+sm3DigestSize],
+		CipherText:  ct[c1Len+sm3DigestSize:],
+	})
+}
+
+// DecryptSM2 decrypts a GM/T 0009 encoded SM2 ciphertext.
+func DecryptSM2(priv *sm2.PrivateKey, der []byte) ([]byte, error) {
+	var c sm2Cipher
+	if _, err := asn1.Unmarshal(der, &c); err != nil {
+		return nil, fmt.Errorf("gmx509: failed to parse SM2 ciphertext [%s]", err)
+	}
+	if c.XCoordinate == nil || c.YCoordinate == nil || c.XCoordinate.Sign() < 0 || c.YCoordinate.Sign() < 0 {
+		return nil, errors.New("gmx509: invalid SM2 ciphertext")
+	}
+	ct := elliptic.Marshal(priv.Curve, c.XCoordinate, c.YCoordinate)
+	ct = append(ct, c.Hash...)
+	ct = append(ct, c.CipherText...)
+	key, err := utils.SM2Decrypt(priv, ct, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gmx509: failed to decrypt the SM2 ciphertext [%s]", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerifyCMS(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+	key := newSM2Key(t)
+	certDER, err := CreateCertificate(rand.Reader, leafTemplate("signer"), ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	cert, err := ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	content := []byte("chaincode package")
+	der, err := SignCMS(rand.Reader, content, cert, key, CMSSignOpts{})
+	require.NoError(t, err)
+	sd, err := ParseCMSSignedData(der)
+	require.NoError(t, err)
+	assert.Equal(t, content, sd.Content)
+	signers, err := sd.Verify(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*x509.Certificate{cert}, signers)
+
+	opts := &VerifyOptions{Roots: NewCertPool()}
+	opts.Roots.AddCert(ca)
+	_, err = sd.Verify(opts)
+	assert.NoError(t, err)
+	otherKey := newSM2Key(t)
+	otherDER, err := CreateCertificate(rand.Reader, caTemplate("other"), caTemplate("other"), &otherKey.PublicKey, otherKey)
+	require.NoError(t, err)
+	other, err := ParseCertificate(otherDER)
+	require.NoError(t, err)
+	opts.Roots = NewCertPool()
+	opts.Roots.AddCert(other)
+	_, err = sd.Verify(opts)
+	assert.Error(t, err)
+
+	sd.Content = []byte("tampered")
+	_, err = sd.Verify(nil)
+	assert.EqualError(t, err, "gmx509: the CMS message digest does not match the content")
+
+	// Detached signatures carry no content
+	der, err = SignCMS(rand.Reader, content, cert, key, CMSSignOpts{Detached: true, Certificates: []*x509.Certificate{ca}})
+	require.NoError(t, err)
+	sd, err = ParseCMSSignedData(der)
+	require.NoError(t, err)
+	assert.Nil(t, sd.Content)
+	assert.Len(t, sd.Certificates, 2)
+	_, err = sd.Verify(nil)
+	assert.EqualError(t, err, "gmx509: the content of the detached CMS signature is missing")
+	sd.Content = content
+	_, err = sd.Verify(nil)
+	assert.NoError(t, err)
+
+	_, err = ParseCMSSignedData(caDER)
+	assert.Error(t, err)
+}
+
+func TestEncryptAndDecryptCMS(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	keys := []*sm2.PrivateKey{newSM2Key(t), newSM2Key(t)}
+	var recipients []*x509.Certificate
+	for i, k := range keys {
+		template := leafTemplate("recipient")
+		template.SerialNumber = big.NewInt(int64(10 + i))
+		template.KeyUsage = x509.KeyUsageKeyEncipherment
+		der, err := CreateCertificate(rand.Reader, template, ca, &k.PublicKey, caKey)
+		require.NoError(t, err)
+		cert, err := ParseCertificate(der)
+		require.NoError(t, err)
+		recipients = append(recipients, cert)
+	}
+
+	for _, content := range [][]byte{[]byte("document"), make([]byte, 32)} {
+		der, err := EncryptCMS(rand.Reader, content, recipients)
+		require.NoError(t, err)
+		for i, cert := range recipients {
+			plain, err := DecryptCMS(der, cert, keys[i])
+			require.NoError(t, err)
+			assert.Equal(t, content, plain)
+		}
+
+		_, err = DecryptCMS(der, ca, caKey)
+		assert.EqualError(t, err, "gmx509: the certificate is not a CMS recipient")
+		_, err = DecryptCMS(der, recipients[0], keys[1])
+		assert.Error(t, err)
+	}
+
+	_, err = EncryptCMS(rand.Reader, []byte("document"), nil)
+	assert.EqualError(t, err, "gmx509: no CMS recipient")
+}
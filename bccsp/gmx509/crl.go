@@ -0,0 +1,227 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+var (
+	oidExtensionAuthorityKeyID    = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionCRLNumber         = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtensionReasonCode        = asn1.ObjectIdentifier{2, 5, 29, 21}
+	oidExtensionDeltaCRLIndicator = asn1.ObjectIdentifier{2, 5, 29, 27}
+
+	oidSignatureECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// CRLReasonRemoveFromCRL is the reason code of the entries of delta CRLs
+// releasing certificates put on hold, RFC 5280 section 5.3.1.
+const CRLReasonRemoveFromCRL = 8
+
+// RevocationList is the template of a CRL.
+type RevocationList struct {
+	// Number is the CRL number, required
+	Number *big.Int
+	// BaseCRLNumber makes the list a delta CRL of the complete CRL of that
+	// number
+	BaseCRLNumber *big.Int
+	ThisUpdate    time.Time
+	// NextUpdate is when relying parties should fetch a newer list
+	NextUpdate          time.Time
+	RevokedCertificates []pkix.RevokedCertificate
+	ExtraExtensions     []pkix.Extension
+}
+
+type authKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
+// CreateRevocationList creates a v2 CRL issued by issuer, with the
+// authority key identifier of issuer, the CRL number and, for delta CRLs,
+// the delta CRL indicator. An *sm2.PrivateKey signs it with SM2 over SM3,
+// an *ecdsa.PrivateKey or other ECDSA crypto.Signer with ECDSA over
+// SHA-256.
+func CreateRevocationList(rand io.Reader, template *RevocationList, issuer *x509.Certificate, priv interface{}) ([]byte, error) {
+	if template == nil || issuer == nil {
+		return nil, errors.New("gmx509: template and issuer must be different from nil")
+	}
+	if template.Number == nil {
+		return nil, errors.New("gmx509: template contains nil Number field")
+	}
+	if !template.NextUpdate.IsZero() && template.NextUpdate.Before(template.ThisUpdate) {
+		return nil, errors.New("gmx509: template.ThisUpdate is after template.NextUpdate")
+	}
+	if template.BaseCRLNumber != nil && template.BaseCRLNumber.Cmp(template.Number) >= 0 {
+		return nil, errors.New("gmx509: the base CRL number of a delta CRL must be lower than its number")
+	}
+
+	algo := pkix.AlgorithmIdentifier{Algorithm: OIDSignatureSM2WithSM3}
+	sm2Priv, isSM2 := priv.(*sm2.PrivateKey)
+	if !isSM2 {
+		signer, ok := priv.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("gmx509: CRL private key does not implement crypto.Signer")
+		}
+		if _, ok := signer.Public().(*ecdsa.PublicKey); !ok {
+			return nil, fmt.Errorf("gmx509: unsupported CRL key type %T", signer.Public())
+		}
+		algo = pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA256}
+	}
+
+	var extensions []pkix.Extension
+	if len(issuer.SubjectKeyId) != 0 {
+		aki, err := asn1.Marshal(authKeyID{ID: issuer.SubjectKeyId})
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionAuthorityKeyID, Value: aki})
+	}
+	number, err := asn1.Marshal(template.Number)
+	if err != nil {
+		return nil, err
+	}
+	extensions = append(extensions, pkix.Extension{Id: oidExtensionCRLNumber, Value: number})
+	if template.BaseCRLNumber != nil {
+		base, err := asn1.Marshal(template.BaseCRLNumber)
+		if err != nil {
+			return nil, err
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidExtensionDeltaCRLIndicator, Critical: true, Value: base})
+	}
+	extensions = append(extensions, template.ExtraExtensions...)
+
+	var issuerName pkix.RDNSequence
+	if _, err := asn1.Unmarshal(issuer.RawSubject, &issuerName); err != nil {
+		return nil, err
+	}
+
+	tbs := pkix.TBSCertificateList{
+		Version:             1,
+		Signature:           algo,
+		Issuer:              issuerName,
+		ThisUpdate:          template.ThisUpdate.UTC(),
+		NextUpdate:          template.NextUpdate.UTC(),
+		RevokedCertificates: template.RevokedCertificates,
+		Extensions:          extensions,
+	}
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	var signature []byte
+	if isSM2 {
+		signature, err = SignSM2(rand, sm2Priv, tbsDER)
+	} else {
+		h := crypto.SHA256.New()
+		h.Write(tbsDER)
+		signature, err = priv.(crypto.Signer).Sign(rand, h.Sum(nil), crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tbs.Raw = tbsDER
+	return asn1.Marshal(pkix.CertificateList{
+		TBSCertList:        tbs,
+		SignatureAlgorithm: algo,
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+}
+
+// CheckCRLSignature verifies that crl is signed by issuer, like
+// x509.Certificate.CheckCRLSignature, and supports SM2 with SM3.
+func CheckCRLSignature(crl *pkix.CertificateList, issuer *x509.Certificate) error {
+	if !crl.SignatureAlgorithm.Algorithm.Equal(OIDSignatureSM2WithSM3) {
+		return issuer.CheckCRLSignature(crl)
+	}
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return x509.ConstraintViolationError{}
+	}
+	return VerifySM2(issuer.PublicKey, crl.TBSCertList.Raw, crl.SignatureValue.RightAlign())
+}
+
+// CRLNumber returns the CRL number of crl, nil if it has none.
+func CRLNumber(crl *pkix.CertificateList) (*big.Int, error) {
+	return crlIntegerExtension(crl, oidExtensionCRLNumber)
+}
+
+// DeltaCRLBase returns the number of the complete CRL crl is a delta of,
+// nil if crl is a complete CRL.
+func DeltaCRLBase(crl *pkix.CertificateList) (*big.Int, error) {
+	return crlIntegerExtension(crl, oidExtensionDeltaCRLIndicator)
+}
+
+// IsDeltaCRL reports whether crl carries the delta CRL indicator.
+func IsDeltaCRL(crl *pkix.CertificateList) bool {
+	for _, ext := range crl.TBSCertList.Extensions {
+		if ext.Id.Equal(oidExtensionDeltaCRLIndicator) {
+			return true
+		}
+	}
+	return false
+}
+
+func crlIntegerExtension(crl *pkix.CertificateList, oid asn1.ObjectIdentifier) (*big.Int, error) {
+	for _, ext := range crl.TBSCertList.Extensions {
+		if !ext.Id.Equal(oid) {
+			continue
+		}
+		n := new(big.Int)
+		if rest, err := asn1.Unmarshal(ext.Value, &n); err != nil {
+			return nil, fmt.Errorf("gmx509: invalid CRL extension %s [%s]", oid, err)
+		} else if len(rest) != 0 {
+			return nil, fmt.Errorf("gmx509: trailing data after CRL extension %s", oid)
+		}
+		return n, nil
+	}
+	return nil, nil
+}
+
+// RevocationReason returns the reason code of a CRL entry, 0
+// (unspecified) if it has none.
+func RevocationReason(rc pkix.RevokedCertificate) (int, error) {
+	for _, ext := range rc.Extensions {
+		if !ext.Id.Equal(oidExtensionReasonCode) {
+			continue
+		}
+		var reason asn1.Enumerated
+		if _, err := asn1.Unmarshal(ext.Value, &reason); err != nil {
+			return 0, fmt.Errorf("gmx509: invalid CRL reason code [%s]", err)
+		}
+		return int(reason), nil
+	}
+	return 0, nil
+}
+
+// ReasonCodeExtension returns the CRL entry extension of a reason code.
+func ReasonCodeExtension(reason int) (pkix.Extension, error) {
+	value, err := asn1.Marshal(asn1.Enumerated(reason))
+	if err != nil {
+		return pkix.Extension{}, err
+	}
+	return pkix.Extension{Id: oidExtensionReasonCode, Value: value}, nil
+}
+
+// IsCRLExpired reports whether now is past the next update of crl. CRLs
+// without a next update never expire.
+func IsCRLExpired(crl *pkix.CertificateList, now time.Time) bool {
+	next := crl.TBSCertList.NextUpdate
+	return !next.IsZero() && now.After(next)
+}
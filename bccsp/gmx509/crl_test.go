@@ -0,0 +1,123 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM2RevocationList(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	now := time.Now()
+	der, err := CreateRevocationList(rand.Reader, &RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: now,
+		NextUpdate: now.Add(time.Hour),
+		RevokedCertificates: []pkix.RevokedCertificate{
+			{SerialNumber: big.NewInt(2), RevocationTime: now},
+		},
+	}, ca, caKey)
+	require.NoError(t, err)
+
+	crl, err := x509.ParseCRL(der)
+	require.NoError(t, err)
+	assert.NoError(t, CheckCRLSignature(crl, ca))
+	assert.Equal(t, ca.Subject.String(), crl.TBSCertList.Issuer.String())
+	require.Len(t, crl.TBSCertList.RevokedCertificates, 1)
+	assert.Equal(t, big.NewInt(2), crl.TBSCertList.RevokedCertificates[0].SerialNumber)
+
+	number, err := CRLNumber(crl)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(1), number)
+	assert.False(t, IsDeltaCRL(crl))
+	base, err := DeltaCRLBase(crl)
+	assert.NoError(t, err)
+	assert.Nil(t, base)
+
+	assert.False(t, IsCRLExpired(crl, now))
+	assert.True(t, IsCRLExpired(crl, now.Add(2*time.Hour)))
+
+	// Signed by another key
+	otherKey := newSM2Key(t)
+	otherDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &otherKey.PublicKey, otherKey)
+	require.NoError(t, err)
+	other, err := ParseCertificate(otherDER)
+	require.NoError(t, err)
+	assert.Error(t, CheckCRLSignature(crl, other))
+
+	// Issuers must be allowed to sign CRLs
+	ca.KeyUsage = x509.KeyUsageCertSign
+	assert.Equal(t, x509.ConstraintViolationError{}, CheckCRLSignature(crl, ca))
+}
+
+func TestDeltaRevocationList(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	remove, err := ReasonCodeExtension(CRLReasonRemoveFromCRL)
+	require.NoError(t, err)
+	der, err := CreateRevocationList(rand.Reader, &RevocationList{
+		Number:        big.NewInt(3),
+		BaseCRLNumber: big.NewInt(2),
+		ThisUpdate:    time.Now(),
+		RevokedCertificates: []pkix.RevokedCertificate{
+			{SerialNumber: big.NewInt(5), RevocationTime: time.Now(), Extensions: []pkix.Extension{remove}},
+		},
+	}, ca, caKey)
+	require.NoError(t, err)
+
+	crl, err := x509.ParseCRL(der)
+	require.NoError(t, err)
+	assert.NoError(t, CheckCRLSignature(crl, ca))
+	assert.True(t, IsDeltaCRL(crl))
+	base, err := DeltaCRLBase(crl)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(2), base)
+	reason, err := RevocationReason(crl.TBSCertList.RevokedCertificates[0])
+	require.NoError(t, err)
+	assert.Equal(t, CRLReasonRemoveFromCRL, reason)
+	assert.False(t, IsCRLExpired(crl, time.Now().Add(time.Hour)))
+
+	_, err = CreateRevocationList(rand.Reader, &RevocationList{Number: big.NewInt(2), BaseCRLNumber: big.NewInt(2)}, ca, caKey)
+	assert.EqualError(t, err, "gmx509: the base CRL number of a delta CRL must be lower than its number")
+	_, err = CreateRevocationList(rand.Reader, &RevocationList{}, ca, caKey)
+	assert.EqualError(t, err, "gmx509: template contains nil Number field")
+}
+
+func TestECDSARevocationList(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	der, err := CreateRevocationList(rand.Reader, &RevocationList{Number: big.NewInt(1), ThisUpdate: time.Now()}, ca, key)
+	require.NoError(t, err)
+	crl, err := x509.ParseCRL(der)
+	require.NoError(t, err)
+	assert.NoError(t, CheckCRLSignature(crl, ca))
+	assert.NoError(t, ca.CheckCRLSignature(crl))
+}
@@ -0,0 +1,176 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// tbsCertificateRequest is the CertificationRequestInfo of RFC 2986.
+type tbsCertificateRequest struct {
+	Version       int
+	Subject       asn1.RawValue
+	PublicKey     asn1.RawValue
+	RawAttributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+// CreateCertificateRequest creates a certificate signing request from
+// template, like x509.CreateCertificateRequest. An *sm2.PrivateKey, or a
+// crypto.Signer of an SM2 key such as BCCSP signers, signs the request with
+// SM2 over SM3 and its public key is encoded as an id-ecPublicKey on the
+// sm2p256v1 curve, as GM CAs and fabric-ca expect. Other keys are handed to
+// crypto/x509.
+func CreateCertificateRequest(rand io.Reader, template *x509.CertificateRequest, priv interface{}) ([]byte, error) {
+	key, ok := priv.(*sm2.PrivateKey)
+	var sm2Signer crypto.Signer
+	var pub *sm2.PublicKey
+	if s, isSigner := priv.(crypto.Signer); isSigner && !ok {
+		pub, ok = s.Public().(*sm2.PublicKey)
+		sm2Signer = s
+	}
+	if !ok {
+		return x509.CreateCertificateRequest(rand, template, priv)
+	}
+	if key == nil && pub == nil || template == nil {
+		return nil, errors.New("gmx509: template and SM2 key must be different from nil")
+	}
+	if key != nil {
+		pub = &key.PublicKey
+	}
+
+	// crypto/x509 encodes the template with a stand-in key
+	tmpl := *template
+	tmpl.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
+	standIn, err := ecdsa.GenerateKey(elliptic.P256(), rand)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.CreateCertificateRequest(rand, &tmpl, standIn)
+	if err != nil {
+		return nil, err
+	}
+
+	var csr certificate
+	var tbs tbsCertificateRequest
+	if err := unmarshalCertificateRequest(der, &csr, &tbs); err != nil {
+		return nil, err
+	}
+
+	spki, err := utils.MarshalPKIXSM2PublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	tbs.PublicKey = asn1.RawValue{FullBytes: spki}
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if sm2Signer != nil {
+		signature, err = sm2Signer.Sign(rand, tbsDER, crypto.Hash(0))
+	} else {
+		signature, err = SignSM2(rand, key, tbsDER)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(certificate{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbsDER},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: OIDSignatureSM2WithSM3},
+		SignatureValue:     asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	})
+}
+
+// unmarshalCertificateRequest parses the outer structure and the
+// CertificationRequestInfo of der.
+func unmarshalCertificateRequest(der []byte, csr *certificate, tbs *tbsCertificateRequest) error {
+	if rest, err := asn1.Unmarshal(der, csr); err != nil {
+		return err
+	} else if len(rest) != 0 {
+		return errors.New("gmx509: trailing data after certificate request")
+	}
+	if rest, err := asn1.Unmarshal(csr.TBSCertificate.FullBytes, tbs); err != nil {
+		return err
+	} else if len(rest) != 0 {
+		return errors.New("gmx509: trailing data after CertificationRequestInfo")
+	}
+	return nil
+}
+
+// ParseCertificateRequest parses a DER encoded certificate signing request
+// like x509.ParseCertificateRequest, accepting SM2 public keys. Raw,
+// RawTBSCertificateRequest and RawSubjectPublicKeyInfo are those of der.
+func ParseCertificateRequest(der []byte) (*x509.CertificateRequest, error) {
+	csr, err := x509.ParseCertificateRequest(der)
+	if err == nil {
+		if csr.PublicKey == nil {
+			if pub, err := utils.ParseGmSSLSM2PublicKey(csr.RawSubjectPublicKeyInfo); err == nil {
+				csr.PublicKey = pub
+			}
+		}
+		return csr, nil
+	}
+
+	var c certificate
+	var tbs tbsCertificateRequest
+	if unmarshalCertificateRequest(der, &c, &tbs) != nil {
+		return nil, err
+	}
+	pub, perr := utils.ParseGmSSLSM2PublicKey(tbs.PublicKey.FullBytes)
+	if perr != nil {
+		return nil, err
+	}
+
+	// Let crypto/x509 parse the request with a stand-in key
+	spki := tbs.PublicKey.FullBytes
+	tbs.PublicKey = asn1.RawValue{FullBytes: standInSPKI}
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	rawTBS := c.TBSCertificate.FullBytes
+	c.TBSCertificate = asn1.RawValue{FullBytes: tbsDER}
+	standIn, err := asn1.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	if csr, err = x509.ParseCertificateRequest(standIn); err != nil {
+		return nil, err
+	}
+
+	csr.Raw = der
+	csr.RawTBSCertificateRequest = rawTBS
+	csr.RawSubjectPublicKeyInfo = spki
+	csr.PublicKey = pub
+	csr.PublicKeyAlgorithm = x509.ECDSA
+	return csr, nil
+}
+
+// CheckCertificateRequestSignature verifies that the signature of csr is
+// valid, like x509.CertificateRequest.CheckSignature, and supports SM2
+// with SM3.
+func CheckCertificateRequestSignature(csr *x509.CertificateRequest) error {
+	oid, err := signatureAlgorithmOID(csr.Raw)
+	if err != nil {
+		return err
+	}
+	if !oid.Equal(OIDSignatureSM2WithSM3) {
+		return csr.CheckSignature()
+	}
+	return VerifySM2(csr.PublicKey, csr.RawTBSCertificateRequest, csr.Signature)
+}
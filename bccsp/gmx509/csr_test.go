@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM2CertificateRequest(t *testing.T) {
+	key := newSM2Key(t)
+	template := &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "peer0.org1", Organization: []string{"org1"}},
+		DNSNames: []string{"peer0.org1"},
+	}
+
+	der, err := CreateCertificateRequest(rand.Reader, template, key)
+	require.NoError(t, err)
+
+	_, err = x509.ParseCertificateRequest(der)
+	assert.Error(t, err)
+
+	csr, err := ParseCertificateRequest(der)
+	require.NoError(t, err)
+	assert.Equal(t, der, csr.Raw)
+	assert.Equal(t, &key.PublicKey, csr.PublicKey)
+	assert.Equal(t, "peer0.org1", csr.Subject.CommonName)
+	assert.Equal(t, []string{"peer0.org1"}, csr.DNSNames)
+	assert.NoError(t, CheckCertificateRequestSignature(csr))
+
+	// A CA issues the certificate of the request
+	caKey := newSM2Key(t)
+	ca := caTemplate("ca")
+	certDER, err := CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: ca.SerialNumber,
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    ca.NotBefore,
+		NotAfter:     ca.NotAfter,
+	}, ca, csr.PublicKey, caKey)
+	require.NoError(t, err)
+	cert, err := ParseCertificate(certDER)
+	require.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, cert.PublicKey)
+
+	csr.Signature[len(csr.Signature)-1] ^= 1
+	assert.Error(t, CheckCertificateRequestSignature(csr))
+}
+
+func TestSM2SignerCertificateRequest(t *testing.T) {
+	key := newSM2Key(t)
+
+	der, err := CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "peer0.org1"}}, &testSM2Signer{key: key})
+	require.NoError(t, err)
+	csr, err := ParseCertificateRequest(der)
+	require.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, csr.PublicKey)
+	assert.NoError(t, CheckCertificateRequestSignature(csr))
+}
+
+func TestECDSACertificateRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "client"}}, key)
+	require.NoError(t, err)
+	csr, err := ParseCertificateRequest(der)
+	require.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, csr.PublicKey)
+	assert.NoError(t, CheckCertificateRequestSignature(csr))
+}
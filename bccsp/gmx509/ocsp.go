@@ -0,0 +1,427 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// The ASN.1 structures of RFC 6960.
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type tbsRequest struct {
+	Version           int              `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName     pkix.RDNSequence `asn1:"explicit,tag:1,optional"`
+	RequestList       []request
+	RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type request struct {
+	Cert certID
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Version            int `asn1:"optional,default:0,explicit,tag:0"`
+	RawResponderID     asn1.RawValue
+	ProducedAt         time.Time `asn1:"generalized"`
+	Responses          []singleResponse
+	ResponseExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type singleResponse struct {
+	CertID           certID
+	Good             asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked          revokedInfo      `asn1:"tag:1,optional"`
+	Unknown          asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate       time.Time        `asn1:"generalized"`
+	NextUpdate       time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	SingleExtensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+var (
+	oidOCSPBasic = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidOCSPNonce = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+	oidHashSHA1  = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// responderIDByKey is the tag of the ResponderID holding the SHA-1 hash of
+// the responder public key.
+const responderIDByKey = 2
+
+// OCSP certificate statuses.
+const (
+	OCSPGood = iota
+	OCSPRevoked
+	OCSPUnknown
+)
+
+// OCSPResponseStatus is the status of an OCSP response, other than
+// successful, RFC 6960 section 4.2.1.
+type OCSPResponseStatus int
+
+func (s OCSPResponseStatus) Error() string {
+	switch s {
+	case 1:
+		return "gmx509: OCSP responder: malformed request"
+	case 2:
+		return "gmx509: OCSP responder: internal error"
+	case 3:
+		return "gmx509: OCSP responder: try later"
+	case 5:
+		return "gmx509: OCSP responder: signature required"
+	case 6:
+		return "gmx509: OCSP responder: unauthorized"
+	default:
+		return fmt.Sprintf("gmx509: OCSP responder: status %d", int(s))
+	}
+}
+
+// OCSPRequestOpts are the options of an OCSP request.
+type OCSPRequestOpts struct {
+	// SM3 hashes the issuer name and key of the CertID with SM3 instead of
+	// SHA-1, for GM responders
+	SM3 bool
+	// Nonce binds the response to the request, RFC 8954
+	Nonce []byte
+}
+
+// OCSPResponse is the status of a certificate in an OCSP response.
+type OCSPResponse struct {
+	// Status is OCSPGood, OCSPRevoked or OCSPUnknown
+	Status           int
+	SerialNumber     *big.Int
+	ProducedAt       time.Time
+	ThisUpdate       time.Time
+	NextUpdate       time.Time
+	RevokedAt        time.Time
+	RevocationReason int
+	Nonce            []byte
+	// Certificate is the delegated responder certificate, if any
+	Certificate *x509.Certificate
+}
+
+func certIDHash(sm bool) asn1.ObjectIdentifier {
+	if sm {
+		return OIDSM3
+	}
+	return oidHashSHA1
+}
+
+// newCertID identifies cert by the hashes of the name and public key of
+// its issuer, with the hash algorithm of oid.
+func newCertID(oid asn1.ObjectIdentifier, serial *big.Int, issuer *x509.Certificate) (certID, error) {
+	var newHash func() hash.Hash
+	switch {
+	case oid.Equal(OIDSM3):
+		newHash = sm3.New
+	case oid.Equal(oidHashSHA1):
+		newHash = sha1.New
+	default:
+		return certID{}, fmt.Errorf("gmx509: unsupported OCSP hash algorithm %s", oid)
+	}
+
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return certID{}, err
+	}
+
+	h := newHash()
+	h.Write(issuer.RawSubject)
+	nameHash := h.Sum(nil)
+	h = newHash()
+	h.Write(spki.PublicKey.RightAlign())
+	return certID{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+		NameHash:      nameHash,
+		IssuerKeyHash: h.Sum(nil),
+		SerialNumber:  serial,
+	}, nil
+}
+
+// CreateOCSPRequest returns the DER encoded OCSP request of the status of
+// cert, issued by issuer.
+func CreateOCSPRequest(cert, issuer *x509.Certificate, opts *OCSPRequestOpts) ([]byte, error) {
+	if opts == nil {
+		opts = &OCSPRequestOpts{}
+	}
+	id, err := newCertID(certIDHash(opts.SM3), cert.SerialNumber, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	tbs := tbsRequest{RequestList: []request{{Cert: id}}}
+	if len(opts.Nonce) != 0 {
+		nonce, err := asn1.Marshal(opts.Nonce)
+		if err != nil {
+			return nil, err
+		}
+		tbs.RequestExtensions = []pkix.Extension{{Id: oidOCSPNonce, Value: nonce}}
+	}
+	return asn1.Marshal(ocspRequest{TBSRequest: tbs})
+}
+
+// ParseOCSPResponse parses the OCSP response der for cert, issued by
+// issuer, and verifies its signature. The response must be signed by
+// issuer or by a responder certificate it issued for OCSP signing, with
+// SM2 over SM3 or ECDSA.
+func ParseOCSPResponse(der []byte, cert, issuer *x509.Certificate) (*OCSPResponse, error) {
+	var resp ocspResponse
+	if rest, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, errors.New("gmx509: trailing data after OCSP response")
+	}
+	if resp.Status != 0 {
+		return nil, OCSPResponseStatus(resp.Status)
+	}
+	if !resp.Response.ResponseType.Equal(oidOCSPBasic) {
+		return nil, errors.New("gmx509: unsupported OCSP response type")
+	}
+
+	var basic basicResponse
+	if _, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		return nil, err
+	}
+	var data responseData
+	if _, err := asn1.Unmarshal(basic.TBSResponseData.FullBytes, &data); err != nil {
+		return nil, err
+	}
+
+	var single *singleResponse
+	for i := range data.Responses {
+		r := &data.Responses[i]
+		if r.CertID.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			continue
+		}
+		id, err := newCertID(r.CertID.HashAlgorithm.Algorithm, cert.SerialNumber, issuer)
+		if err == nil && bytes.Equal(id.NameHash, r.CertID.NameHash) && bytes.Equal(id.IssuerKeyHash, r.CertID.IssuerKeyHash) {
+			single = r
+			break
+		}
+	}
+	if single == nil {
+		return nil, errors.New("gmx509: no status of the certificate in OCSP response")
+	}
+
+	signer := issuer
+	var responder *x509.Certificate
+	if len(basic.Certificates) != 0 {
+		var err error
+		if responder, err = ParseCertificate(basic.Certificates[0].FullBytes); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(responder.Raw, issuer.Raw) {
+			if err := checkOCSPResponder(responder, issuer); err != nil {
+				return nil, err
+			}
+			signer = responder
+		}
+	}
+	if err := checkSignature(signer, basic.SignatureAlgorithm.Algorithm, basic.TBSResponseData.FullBytes, basic.Signature.RightAlign()); err != nil {
+		return nil, err
+	}
+
+	r := &OCSPResponse{
+		SerialNumber: cert.SerialNumber,
+		ProducedAt:   data.ProducedAt,
+		ThisUpdate:   single.ThisUpdate,
+		NextUpdate:   single.NextUpdate,
+		Certificate:  responder,
+	}
+	switch {
+	case bool(single.Good):
+		r.Status = OCSPGood
+	case bool(single.Unknown):
+		r.Status = OCSPUnknown
+	default:
+		r.Status = OCSPRevoked
+		r.RevokedAt = single.Revoked.RevocationTime
+		r.RevocationReason = int(single.Revoked.Reason)
+	}
+	for _, ext := range data.ResponseExtensions {
+		if ext.Id.Equal(oidOCSPNonce) {
+			if _, err := asn1.Unmarshal(ext.Value, &r.Nonce); err != nil {
+				// Some responders put the nonce as is
+				r.Nonce = ext.Value
+			}
+		}
+	}
+	return r, nil
+}
+
+// checkOCSPResponder checks that responder is a delegated OCSP responder
+// certificate of issuer.
+func checkOCSPResponder(responder, issuer *x509.Certificate) error {
+	if err := CheckSignatureFrom(responder, issuer); err != nil {
+		return fmt.Errorf("gmx509: OCSP responder certificate not issued by the issuer [%s]", err)
+	}
+	for _, eku := range responder.ExtKeyUsage {
+		if eku == x509.ExtKeyUsageOCSPSigning {
+			return nil
+		}
+	}
+	return errors.New("gmx509: OCSP responder certificate not authorized for OCSP signing")
+}
+
+// checkSignature verifies a signature of SM2 over SM3 or ECDSA of signer.
+func checkSignature(signer *x509.Certificate, algo asn1.ObjectIdentifier, signed, signature []byte) error {
+	if algo.Equal(OIDSignatureSM2WithSM3) {
+		return VerifySM2(signer.PublicKey, signed, signature)
+	}
+	for _, a := range []struct {
+		oid  asn1.ObjectIdentifier
+		algo x509.SignatureAlgorithm
+	}{
+		{oidSignatureECDSAWithSHA256, x509.ECDSAWithSHA256},
+		{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}, x509.ECDSAWithSHA384},
+		{asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}, x509.ECDSAWithSHA512},
+	} {
+		if algo.Equal(a.oid) {
+			return signer.CheckSignature(a.algo, signed, signature)
+		}
+	}
+	return fmt.Errorf("gmx509: unsupported signature algorithm %s", algo)
+}
+
+// CreateOCSPResponse returns a successful OCSP response of template, for a
+// certificate of issuer, signed by priv, the key of responder. responder
+// is embedded in the response if it is not issuer. Meant for tests and
+// small CAs.
+func CreateOCSPResponse(rand io.Reader, issuer, responder *x509.Certificate, template *OCSPResponse, priv interface{}, opts *OCSPRequestOpts) ([]byte, error) {
+	if opts == nil {
+		opts = &OCSPRequestOpts{}
+	}
+	id, err := newCertID(certIDHash(opts.SM3), template.SerialNumber, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	single := singleResponse{
+		CertID:     id,
+		ThisUpdate: template.ThisUpdate.UTC(),
+		NextUpdate: template.NextUpdate.UTC(),
+	}
+	switch template.Status {
+	case OCSPGood:
+		single.Good = true
+	case OCSPUnknown:
+		single.Unknown = true
+	case OCSPRevoked:
+		single.Revoked = revokedInfo{RevocationTime: template.RevokedAt.UTC(), Reason: asn1.Enumerated(template.RevocationReason)}
+	default:
+		return nil, fmt.Errorf("gmx509: invalid OCSP status %d", template.Status)
+	}
+
+	keyID, err := newCertID(oidHashSHA1, nil, responder)
+	if err != nil {
+		return nil, err
+	}
+	responderID, err := asn1.Marshal(keyID.IssuerKeyHash)
+	if err != nil {
+		return nil, err
+	}
+	data := responseData{
+		RawResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: responderIDByKey, IsCompound: true, Bytes: responderID},
+		ProducedAt:     template.ProducedAt.UTC().Truncate(time.Second),
+		Responses:      []singleResponse{single},
+	}
+	if len(opts.Nonce) != 0 {
+		nonce, err := asn1.Marshal(opts.Nonce)
+		if err != nil {
+			return nil, err
+		}
+		data.ResponseExtensions = []pkix.Extension{{Id: oidOCSPNonce, Value: nonce}}
+	}
+	tbs, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var algo asn1.ObjectIdentifier
+	var signature []byte
+	switch k := priv.(type) {
+	case *sm2.PrivateKey:
+		algo = OIDSignatureSM2WithSM3
+		signature, err = SignSM2(rand, k, tbs)
+	case *ecdsa.PrivateKey:
+		algo = oidSignatureECDSAWithSHA256
+		h := crypto.SHA256.New()
+		h.Write(tbs)
+		signature, err = k.Sign(rand, h.Sum(nil), crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("gmx509: unsupported OCSP key type %T", priv)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	basic := basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: algo},
+		Signature:          asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)},
+	}
+	if !bytes.Equal(responder.Raw, issuer.Raw) {
+		basic.Certificates = []asn1.RawValue{{FullBytes: responder.Raw}}
+	}
+	basicDER, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ocspResponse{
+		Response: responseBytes{ResponseType: oidOCSPBasic, Response: basicDER},
+	})
+}
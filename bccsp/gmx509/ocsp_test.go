@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSM2OCSPResponse(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	opts := &OCSPRequestOpts{SM3: true, Nonce: []byte("nonce")}
+	_, err = CreateOCSPRequest(leaf, ca, opts)
+	require.NoError(t, err)
+
+	now := time.Now()
+	der, err := CreateOCSPResponse(rand.Reader, ca, ca, &OCSPResponse{
+		Status:           OCSPRevoked,
+		SerialNumber:     leaf.SerialNumber,
+		ProducedAt:       now,
+		ThisUpdate:       now,
+		NextUpdate:       now.Add(time.Hour),
+		RevokedAt:        now.Add(-time.Hour),
+		RevocationReason: 1,
+	}, caKey, opts)
+	require.NoError(t, err)
+
+	resp, err := ParseOCSPResponse(der, leaf, ca)
+	require.NoError(t, err)
+	assert.Equal(t, OCSPRevoked, resp.Status)
+	assert.Equal(t, 1, resp.RevocationReason)
+	assert.Equal(t, now.Add(-time.Hour).Unix(), resp.RevokedAt.Unix())
+	assert.Equal(t, []byte("nonce"), resp.Nonce)
+	assert.Nil(t, resp.Certificate)
+
+	// Not a status of a certificate of another issuer
+	otherKey := newSM2Key(t)
+	otherDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &otherKey.PublicKey, otherKey)
+	require.NoError(t, err)
+	other, err := ParseCertificate(otherDER)
+	require.NoError(t, err)
+	_, err = ParseOCSPResponse(der, leaf, other)
+	assert.EqualError(t, err, "gmx509: no status of the certificate in OCSP response")
+
+	// Signed by another key
+	der, err = CreateOCSPResponse(rand.Reader, ca, ca, &OCSPResponse{Status: OCSPGood, SerialNumber: leaf.SerialNumber, ThisUpdate: now}, otherKey, nil)
+	require.NoError(t, err)
+	_, err = ParseOCSPResponse(der, leaf, ca)
+	assert.Error(t, err)
+}
+
+func TestECDSAOCSPResponder(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	// ECDSA responder delegated by the SM2 CA
+	responderKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := leafTemplate("ocsp")
+	template.SerialNumber = big.NewInt(3)
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning}
+	responderDER, err := CreateCertificate(rand.Reader, template, ca, &responderKey.PublicKey, caKey)
+	require.NoError(t, err)
+	responder, err := ParseCertificate(responderDER)
+	require.NoError(t, err)
+
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	now := time.Now()
+	der, err := CreateOCSPResponse(rand.Reader, ca, responder, &OCSPResponse{
+		Status:       OCSPGood,
+		SerialNumber: leaf.SerialNumber,
+		ProducedAt:   now,
+		ThisUpdate:   now,
+	}, responderKey, nil)
+	require.NoError(t, err)
+
+	resp, err := ParseOCSPResponse(der, leaf, ca)
+	require.NoError(t, err)
+	assert.Equal(t, OCSPGood, resp.Status)
+	assert.Equal(t, responder.Raw, resp.Certificate.Raw)
+
+	// The responder is not authorized for OCSP signing
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	responderDER, err = CreateCertificate(rand.Reader, template, ca, &responderKey.PublicKey, caKey)
+	require.NoError(t, err)
+	responder, err = ParseCertificate(responderDER)
+	require.NoError(t, err)
+	der, err = CreateOCSPResponse(rand.Reader, ca, responder, &OCSPResponse{Status: OCSPGood, SerialNumber: leaf.SerialNumber, ThisUpdate: now}, responderKey, nil)
+	require.NoError(t, err)
+	_, err = ParseOCSPResponse(der, leaf, ca)
+	assert.EqualError(t, err, "gmx509: OCSP responder certificate not authorized for OCSP signing")
+}
+
+func TestOCSPChecker(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	status := OCSPGood
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		now := time.Now()
+		der, err := CreateOCSPResponse(rand.Reader, ca, ca, &OCSPResponse{
+			Status:       status,
+			SerialNumber: leaf.SerialNumber,
+			ProducedAt:   now,
+			ThisUpdate:   now,
+			NextUpdate:   now.Add(time.Hour),
+		}, caKey, &OCSPRequestOpts{SM3: true})
+		require.NoError(t, err)
+		w.Write(der)
+	}))
+	defer server.Close()
+
+	checker := NewOCSPChecker(OCSPCheckerOpts{Responder: server.URL, SM3: true})
+	assert.NoError(t, checker.CheckRevocation(leaf, ca))
+	assert.NoError(t, checker.CheckRevocation(leaf, ca))
+	assert.Equal(t, 1, requests, "fresh statuses are cached")
+	assert.NoError(t, checker.VerifyPeerCertificate([][]byte{leafDER, caDER}, nil))
+
+	checker = NewOCSPChecker(OCSPCheckerOpts{Responder: server.URL, SM3: true})
+	status = OCSPRevoked
+	assert.Error(t, checker.CheckRevocation(leaf, ca))
+
+	// The responder does not echo nonces
+	checker = NewOCSPChecker(OCSPCheckerOpts{Responder: server.URL, SM3: true, Nonce: true})
+	assert.EqualError(t, checker.CheckRevocation(leaf, ca), "gmx509: OCSP response nonce does not match the request")
+
+	checker = NewOCSPChecker(OCSPCheckerOpts{SM3: true})
+	assert.EqualError(t, checker.CheckRevocation(leaf, ca), "gmx509: certificate has no OCSP server")
+}
@@ -0,0 +1,205 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultOCSPTimeout = 5 * time.Second
+	nonceSize          = 16
+	maxOCSPResponse    = 1 << 20
+)
+
+// OCSPCheckerOpts configure an OCSPChecker.
+type OCSPCheckerOpts struct {
+	// Responder overrides the OCSP server URLs of the certificates
+	Responder string
+	// Client sends the requests, with a 5 seconds timeout by default
+	Client *http.Client
+	// MaxAge bounds the age of the statuses accepted as fresh, from their
+	// this update time. Zero only requires the next update not to be past.
+	MaxAge time.Duration
+	// Nonce requires responses to echo the nonce of their request
+	Nonce bool
+	// SM3 identifies certificates with SM3 instead of SHA-1 hashes
+	SM3 bool
+}
+
+// OCSPChecker obtains fresh revocation statuses over OCSP. Statuses are
+// cached for as long as they are fresh.
+type OCSPChecker struct {
+	opts OCSPCheckerOpts
+	now  func() time.Time
+
+	mutex sync.Mutex
+	cache map[string]*OCSPResponse
+}
+
+// NewOCSPChecker returns an OCSPChecker configured by opts.
+func NewOCSPChecker(opts OCSPCheckerOpts) *OCSPChecker {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: defaultOCSPTimeout}
+	}
+	return &OCSPChecker{
+		opts:  opts,
+		now:   time.Now,
+		cache: map[string]*OCSPResponse{},
+	}
+}
+
+// Check returns the fresh OCSP status of cert, issued by issuer.
+func (c *OCSPChecker) Check(ctx context.Context, cert, issuer *x509.Certificate) (*OCSPResponse, error) {
+	key := string(issuer.RawSubjectPublicKeyInfo) + cert.SerialNumber.String()
+	c.mutex.Lock()
+	cached, ok := c.cache[key]
+	c.mutex.Unlock()
+	if ok && c.fresh(cached) == nil {
+		return cached, nil
+	}
+
+	url := c.opts.Responder
+	if url == "" {
+		if len(cert.OCSPServer) == 0 {
+			return nil, errors.New("gmx509: certificate has no OCSP server")
+		}
+		url = cert.OCSPServer[0]
+	}
+
+	opts := &OCSPRequestOpts{SM3: c.opts.SM3}
+	if c.opts.Nonce {
+		opts.Nonce = make([]byte, nonceSize)
+		if _, err := rand.Read(opts.Nonce); err != nil {
+			return nil, err
+		}
+	}
+	req, err := CreateOCSPRequest(cert, issuer, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := c.post(ctx, url, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ParseOCSPResponse(der, cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Nonce && !bytes.Equal(resp.Nonce, opts.Nonce) {
+		return nil, errors.New("gmx509: OCSP response nonce does not match the request")
+	}
+	if err := c.fresh(resp); err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.cache[key] = resp
+	c.mutex.Unlock()
+	return resp, nil
+}
+
+func (c *OCSPChecker) post(ctx context.Context, url string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+	req.Header.Set("Accept", "application/ocsp-response")
+
+	resp, err := c.opts.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("gmx509: OCSP request to %s failed [%s]", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gmx509: OCSP responder %s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(&limitedReader{r: resp.Body, n: maxOCSPResponse, what: "OCSP response"})
+}
+
+// fresh checks that resp may still be relied upon.
+func (c *OCSPChecker) fresh(resp *OCSPResponse) error {
+	now := c.now()
+	if now.Before(resp.ThisUpdate) {
+		return fmt.Errorf("gmx509: OCSP status is not valid before %s", resp.ThisUpdate)
+	}
+	if !resp.NextUpdate.IsZero() && now.After(resp.NextUpdate) {
+		return fmt.Errorf("gmx509: OCSP status expired at %s", resp.NextUpdate)
+	}
+	if c.opts.MaxAge > 0 && now.Sub(resp.ThisUpdate) > c.opts.MaxAge {
+		return fmt.Errorf("gmx509: OCSP status from %s is older than %s", resp.ThisUpdate, c.opts.MaxAge)
+	}
+	return nil
+}
+
+// CheckRevocation returns an error unless the OCSP status of cert, issued
+// by issuer, is fresh and good.
+func (c *OCSPChecker) CheckRevocation(cert, issuer *x509.Certificate) error {
+	resp, err := c.Check(context.Background(), cert, issuer)
+	if err != nil {
+		return err
+	}
+	switch resp.Status {
+	case OCSPGood:
+		return nil
+	case OCSPRevoked:
+		return fmt.Errorf("gmx509: certificate %s was revoked at %s", cert.SerialNumber, resp.RevokedAt)
+	default:
+		return fmt.Errorf("gmx509: OCSP status of certificate %s is unknown", cert.SerialNumber)
+	}
+}
+
+// VerifyPeerCertificate can be set as the tls.Config hook of the same name
+// to require a fresh and good OCSP status of the peer certificate. The
+// issuer is taken from the verified chains or, for SM2 chains that
+// crypto/tls cannot verify, from the certificates sent by the peer.
+func (c *OCSPChecker) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) != 0 && len(verifiedChains[0]) > 1 {
+		return c.CheckRevocation(verifiedChains[0][0], verifiedChains[0][1])
+	}
+	if len(rawCerts) < 2 {
+		return errors.New("gmx509: issuer of the peer certificate is unknown, cannot check its OCSP status")
+	}
+	cert, err := ParseCertificate(rawCerts[0])
+	if err != nil {
+		return err
+	}
+	issuer, err := ParseCertificate(rawCerts[1])
+	if err != nil {
+		return err
+	}
+	return c.CheckRevocation(cert, issuer)
+}
+
+// limitedReader fails reads past n bytes, instead of truncating them like
+// io.LimitedReader.
+type limitedReader struct {
+	r    io.Reader
+	n    int64
+	what string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		return n, fmt.Errorf("gmx509: %s too large", l.what)
+	}
+	return n, err
+}
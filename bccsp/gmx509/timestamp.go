@@ -0,0 +1,327 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// The time-stamp protocol of RFC 3161 and GM/T 0033, with tokens signed
+// with SM2 over SM3.
+
+var (
+	// OIDSHA256 is the identifier of the SHA-256 hash algorithm, which
+	// time-stamp requests may use instead of SM3
+	OIDSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+
+	oidTSTInfo              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidExtensionExtKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+	Extensions     []pkix.Extension      `asn1:"optional,tag:0"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time `asn1:"generalized"`
+	Accuracy       accuracy  `asn1:"optional"`
+	Ordering       bool      `asn1:"optional"`
+	Nonce          *big.Int  `asn1:"optional"`
+}
+
+// PKI statuses of time-stamp responses, RFC 3161 section 2.4.2.
+const (
+	TimestampGranted = iota
+	TimestampGrantedWithMods
+	TimestampRejection
+	TimestampWaiting
+)
+
+// TimestampStatusError is the error of the time-stamp responses which do
+// not grant a token.
+type TimestampStatusError struct {
+	Status int
+	Text   string
+}
+
+func (e *TimestampStatusError) Error() string {
+	if e.Text == "" {
+		return fmt.Sprintf("gmx509: time-stamp request refused with status %d", e.Status)
+	}
+	return fmt.Sprintf("gmx509: time-stamp request refused with status %d: %s", e.Status, e.Text)
+}
+
+// TimestampRequest is a time-stamp request.
+type TimestampRequest struct {
+	// HashAlgorithm is OIDSM3 or OIDSHA256
+	HashAlgorithm asn1.ObjectIdentifier
+	// HashedMessage is the digest to time-stamp
+	HashedMessage []byte
+	// Policy is the TSA policy requested, if any
+	Policy asn1.ObjectIdentifier
+	Nonce  *big.Int
+	// CertReq asks for the TSA certificate to be embedded in the token
+	CertReq bool
+}
+
+// Timestamp is the content of a time-stamp token.
+type Timestamp struct {
+	HashAlgorithm asn1.ObjectIdentifier
+	HashedMessage []byte
+	Time          time.Time
+	// Accuracy is the accuracy of Time, zero if unknown
+	Accuracy     time.Duration
+	SerialNumber *big.Int
+	Policy       asn1.ObjectIdentifier
+	Nonce        *big.Int
+	// Certificates are the certificates embedded in the token
+	Certificates []*x509.Certificate
+	// Token is the DER encoded time-stamp token
+	Token []byte
+}
+
+func checkHashedMessage(algo asn1.ObjectIdentifier, hashed []byte) error {
+	if !algo.Equal(OIDSM3) && !algo.Equal(OIDSHA256) {
+		return fmt.Errorf("gmx509: unsupported time-stamp hash algorithm %s", algo)
+	}
+	if len(hashed) != sm3DigestSize {
+		return fmt.Errorf("gmx509: invalid %s digest length %d", algo, len(hashed))
+	}
+	return nil
+}
+
+// Marshal returns the DER encoding of r.
+func (r *TimestampRequest) Marshal() ([]byte, error) {
+	if err := checkHashedMessage(r.HashAlgorithm, r.HashedMessage); err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(timeStampReq{
+		Version:        1,
+		MessageImprint: messageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: r.HashAlgorithm}, HashedMessage: r.HashedMessage},
+		ReqPolicy:      r.Policy,
+		Nonce:          r.Nonce,
+		CertReq:        r.CertReq,
+	})
+}
+
+// ParseTimestampRequest parses a DER encoded time-stamp request.
+func ParseTimestampRequest(der []byte) (*TimestampRequest, error) {
+	var req timeStampReq
+	if rest, err := asn1.Unmarshal(der, &req); err != nil {
+		return nil, fmt.Errorf("gmx509: failed to parse time-stamp request [%s]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("gmx509: trailing data after time-stamp request")
+	}
+	if req.Version != 1 {
+		return nil, fmt.Errorf("gmx509: unsupported time-stamp request version %d", req.Version)
+	}
+	mi := req.MessageImprint
+	if err := checkHashedMessage(mi.HashAlgorithm.Algorithm, mi.HashedMessage); err != nil {
+		return nil, err
+	}
+	return &TimestampRequest{
+		HashAlgorithm: mi.HashAlgorithm.Algorithm,
+		HashedMessage: mi.HashedMessage,
+		Policy:        req.ReqPolicy,
+		Nonce:         req.Nonce,
+		CertReq:       req.CertReq,
+	}, nil
+}
+
+// CreateTimestampResponse creates a response granting req a time-stamp
+// token signed by tsa, whose private key is priv. The serial number,
+// policy, time and accuracy of the token are those of template; the
+// policy defaults to the one requested and the time to now.
+func CreateTimestampResponse(rand io.Reader, req *TimestampRequest, template *Timestamp, tsa *x509.Certificate, priv *sm2.PrivateKey) ([]byte, error) {
+	if req == nil || template == nil || template.SerialNumber == nil {
+		return nil, errors.New("gmx509: request, template and its serial number must be different from nil")
+	}
+	policy := template.Policy
+	if policy == nil {
+		policy = req.Policy
+	}
+	if policy == nil {
+		return nil, errors.New("gmx509: the time-stamp policy must be set")
+	}
+	genTime := template.Time
+	if genTime.IsZero() {
+		genTime = time.Now()
+	}
+
+	info := tstInfo{
+		Version:        1,
+		Policy:         policy,
+		MessageImprint: messageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: req.HashAlgorithm}, HashedMessage: req.HashedMessage},
+		SerialNumber:   template.SerialNumber,
+		GenTime:        genTime.UTC().Truncate(time.Second),
+		Nonce:          req.Nonce,
+	}
+	if template.Accuracy > 0 {
+		info.Accuracy = accuracy{
+			Seconds: int(template.Accuracy / time.Second),
+			Millis:  int(template.Accuracy % time.Second / time.Millisecond),
+		}
+	}
+	content, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	token, err := signCMS(rand, oidTSTInfo, content, tsa, priv, CMSSignOpts{SigningTime: genTime, OmitCertificates: !req.CertReq})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(timeStampResp{
+		Status:         pkiStatusInfo{Status: TimestampGranted},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+}
+
+// ParseTimestampResponse parses a DER encoded time-stamp response and the
+// token it grants, which is not verified.
+func ParseTimestampResponse(der []byte) (*Timestamp, error) {
+	var resp timeStampResp
+	if rest, err := asn1.Unmarshal(der, &resp); err != nil {
+		return nil, fmt.Errorf("gmx509: failed to parse time-stamp response [%s]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("gmx509: trailing data after time-stamp response")
+	}
+	if s := resp.Status.Status; s != TimestampGranted && s != TimestampGrantedWithMods {
+		return nil, &TimestampStatusError{Status: s, Text: strings.Join(resp.Status.StatusString, "; ")}
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, errors.New("gmx509: the time-stamp response has no token")
+	}
+	return ParseTimestampToken(resp.TimeStampToken.FullBytes)
+}
+
+// ParseTimestampToken parses a DER encoded time-stamp token, without
+// verifying it.
+func ParseTimestampToken(token []byte) (*Timestamp, error) {
+	ts, _, err := parseTimestampToken(token)
+	return ts, err
+}
+
+func parseTimestampToken(token []byte) (*Timestamp, *CMSSignedData, error) {
+	sd, err := ParseCMSSignedData(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !sd.ContentType.Equal(oidTSTInfo) {
+		return nil, nil, fmt.Errorf("gmx509: signed content type %s is not TSTInfo", sd.ContentType)
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.Content, &info); err != nil {
+		return nil, nil, fmt.Errorf("gmx509: failed to parse TSTInfo [%s]", err)
+	}
+	if info.Version != 1 {
+		return nil, nil, fmt.Errorf("gmx509: unsupported TSTInfo version %d", info.Version)
+	}
+	return &Timestamp{
+		HashAlgorithm: info.MessageImprint.HashAlgorithm.Algorithm,
+		HashedMessage: info.MessageImprint.HashedMessage,
+		Time:          info.GenTime,
+		Accuracy: time.Duration(info.Accuracy.Seconds)*time.Second +
+			time.Duration(info.Accuracy.Millis)*time.Millisecond +
+			time.Duration(info.Accuracy.Micros)*time.Microsecond,
+		SerialNumber: info.SerialNumber,
+		Policy:       info.Policy,
+		Nonce:        info.Nonce,
+		Certificates: sd.Certificates,
+		Token:        token,
+	}, sd, nil
+}
+
+// VerifyTimestampToken verifies that token time-stamps hashedMessage and
+// is signed by a TSA certificate, critically restricted to time-stamping.
+// The TSA certificate is looked up in the token, then in
+// opts.Intermediates. If opts is not nil, it must also chain up to
+// opts.Roots.
+func VerifyTimestampToken(token, hashedMessage []byte, opts *VerifyOptions) (*Timestamp, error) {
+	ts, sd, err := parseTimestampToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(ts.HashedMessage, hashedMessage) {
+		return nil, errors.New("gmx509: the time-stamp token is not for this digest")
+	}
+
+	var o *VerifyOptions
+	if opts != nil {
+		sd.Certificates = append(append([]*x509.Certificate{}, sd.Certificates...), opts.Intermediates.Certificates()...)
+		o = &VerifyOptions{}
+		*o = *opts
+		o.CurrentTime = ts.Time
+		o.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}
+	}
+	signers, err := sd.Verify(o)
+	if err != nil {
+		return nil, err
+	}
+	for _, signer := range signers {
+		if err := checkTSACertificate(signer); err != nil {
+			return nil, err
+		}
+	}
+	return ts, nil
+}
+
+// checkTSACertificate checks that cert has the critical extended key usage
+// extension of TSA certificates, RFC 3161 section 2.3.
+func checkTSACertificate(cert *x509.Certificate) error {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidExtensionExtKeyUsage) {
+			if ext.Critical && len(cert.ExtKeyUsage) == 1 && len(cert.UnknownExtKeyUsage) == 0 &&
+				cert.ExtKeyUsage[0] == x509.ExtKeyUsageTimeStamping {
+				return nil
+			}
+			break
+		}
+	}
+	return fmt.Errorf("gmx509: certificate %q is not a TSA certificate", cert.Subject.CommonName)
+}
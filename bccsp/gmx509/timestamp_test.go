@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTSAPolicy = asn1.ObjectIdentifier{1, 2, 3, 4}
+
+func newTestTSA(t *testing.T) (ca, tsa *x509.Certificate, tsaKey *sm2.PrivateKey) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err = ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	eku, err := asn1.Marshal([]asn1.ObjectIdentifier{{1, 3, 6, 1, 5, 5, 7, 3, 8}})
+	require.NoError(t, err)
+	template := leafTemplate("tsa")
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}
+	template.ExtraExtensions = []pkix.Extension{{Id: oidExtensionExtKeyUsage, Critical: true, Value: eku}}
+	tsaKey = newSM2Key(t)
+	tsaDER, err := CreateCertificate(rand.Reader, template, ca, &tsaKey.PublicKey, caKey)
+	require.NoError(t, err)
+	tsa, err = ParseCertificate(tsaDER)
+	require.NoError(t, err)
+	return ca, tsa, tsaKey
+}
+
+func TestTimestampToken(t *testing.T) {
+	ca, tsa, tsaKey := newTestTSA(t)
+	digest := sm3Sum([]byte("transaction"))
+
+	der, err := (&TimestampRequest{HashAlgorithm: OIDSM3, HashedMessage: digest, Nonce: big.NewInt(42)}).Marshal()
+	require.NoError(t, err)
+	req, err := ParseTimestampRequest(der)
+	require.NoError(t, err)
+	assert.False(t, req.CertReq)
+
+	genTime := time.Now().Add(-time.Minute)
+	der, err = CreateTimestampResponse(rand.Reader, req, &Timestamp{
+		SerialNumber: big.NewInt(7),
+		Policy:       testTSAPolicy,
+		Time:         genTime,
+		Accuracy:     1500 * time.Millisecond,
+	}, tsa, tsaKey)
+	require.NoError(t, err)
+	ts, err := ParseTimestampResponse(der)
+	require.NoError(t, err)
+	assert.Equal(t, genTime.Unix(), ts.Time.Unix())
+	assert.Equal(t, 1500*time.Millisecond, ts.Accuracy)
+	assert.Equal(t, int64(42), ts.Nonce.Int64())
+	assert.Equal(t, testTSAPolicy, ts.Policy)
+	assert.Empty(t, ts.Certificates)
+
+	// The TSA certificate was not requested
+	_, err = VerifyTimestampToken(ts.Token, digest, nil)
+	assert.Error(t, err)
+	opts := &VerifyOptions{Roots: NewCertPool(), Intermediates: NewCertPool()}
+	opts.Roots.AddCert(ca)
+	opts.Intermediates.AddCert(tsa)
+	_, err = VerifyTimestampToken(ts.Token, digest, opts)
+	assert.NoError(t, err)
+	_, err = VerifyTimestampToken(ts.Token, sm3Sum([]byte("other")), opts)
+	assert.EqualError(t, err, "gmx509: the time-stamp token is not for this digest")
+
+	// Only TSA certificates sign tokens
+	req.CertReq = true
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), leafTemplate("peer0"), &leafKey.PublicKey, leafKey)
+	require.NoError(t, err)
+	leaf, err := ParseCertificate(leafDER)
+	require.NoError(t, err)
+	der, err = CreateTimestampResponse(rand.Reader, req, &Timestamp{SerialNumber: big.NewInt(8), Policy: testTSAPolicy}, leaf, leafKey)
+	require.NoError(t, err)
+	ts, err = ParseTimestampResponse(der)
+	require.NoError(t, err)
+	_, err = VerifyTimestampToken(ts.Token, digest, nil)
+	assert.EqualError(t, err, `gmx509: certificate "peer0" is not a TSA certificate`)
+
+	der, err = asn1.Marshal(timeStampResp{Status: pkiStatusInfo{Status: TimestampRejection, StatusString: []string{"bad policy"}}})
+	require.NoError(t, err)
+	_, err = ParseTimestampResponse(der)
+	assert.Equal(t, &TimestampStatusError{Status: TimestampRejection, Text: "bad policy"}, err)
+}
+
+func TestTSAClient(t *testing.T) {
+	ca, tsa, tsaKey := newTestTSA(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/timestamp-query", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := ParseTimestampRequest(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		der, err := CreateTimestampResponse(rand.Reader, req, &Timestamp{SerialNumber: big.NewInt(1), Policy: testTSAPolicy}, tsa, tsaKey)
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(der)
+	}))
+	defer server.Close()
+
+	opts := &VerifyOptions{Roots: NewCertPool()}
+	opts.Roots.AddCert(ca)
+	client := NewTSAClient(TSAClientOpts{URL: server.URL, VerifyOptions: opts})
+	digest := sm3Sum([]byte("transaction"))
+	ts, err := client.Timestamp(context.Background(), digest)
+	require.NoError(t, err)
+	assert.Equal(t, digest, ts.HashedMessage)
+	assert.Equal(t, []*x509.Certificate{tsa}, ts.Certificates)
+
+	_, err = client.Timestamp(context.Background(), []byte("short"))
+	assert.EqualError(t, err, "gmx509: invalid 1.2.156.10197.1.401 digest length 5")
+
+	client = NewTSAClient(TSAClientOpts{URL: server.URL, Policy: asn1.ObjectIdentifier{1, 2, 3, 5}})
+	_, err = client.Timestamp(context.Background(), digest)
+	assert.EqualError(t, err, "gmx509: time-stamp policy 1.2.3.4 does not match the request")
+}
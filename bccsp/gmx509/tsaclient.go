@@ -0,0 +1,114 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultTSATimeout = 10 * time.Second
+	maxTSAResponse    = 1 << 20
+)
+
+// TSAClientOpts configure a TSAClient.
+type TSAClientOpts struct {
+	// URL is the address of the time-stamp authority
+	URL string
+	// Client sends the requests, with a 10 seconds timeout by default
+	Client *http.Client
+	// HashAlgorithm identifies the algorithm of the digests to time-stamp,
+	// OIDSM3 by default
+	HashAlgorithm asn1.ObjectIdentifier
+	// Policy is the TSA policy to request, if any
+	Policy asn1.ObjectIdentifier
+	// VerifyOptions, if set, verify the chains of the TSA certificates
+	VerifyOptions *VerifyOptions
+}
+
+// TSAClient obtains time-stamp tokens from a time-stamp authority over
+// HTTP, RFC 3161 section 3.4.
+type TSAClient struct {
+	opts TSAClientOpts
+}
+
+// NewTSAClient returns a TSAClient configured by opts.
+func NewTSAClient(opts TSAClientOpts) *TSAClient {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: defaultTSATimeout}
+	}
+	if opts.HashAlgorithm == nil {
+		opts.HashAlgorithm = OIDSM3
+	}
+	return &TSAClient{opts: opts}
+}
+
+// Timestamp obtains a verified time-stamp token of digest.
+func (c *TSAClient) Timestamp(ctx context.Context, digest []byte) (*Timestamp, error) {
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	req := &TimestampRequest{
+		HashAlgorithm: c.opts.HashAlgorithm,
+		HashedMessage: digest,
+		Policy:        c.opts.Policy,
+		Nonce:         nonce,
+		CertReq:       true,
+	}
+	body, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := c.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := ParseTimestampResponse(der)
+	if err != nil {
+		return nil, err
+	}
+	if ts.Nonce == nil || ts.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("gmx509: time-stamp nonce does not match the request")
+	}
+	if !ts.HashAlgorithm.Equal(c.opts.HashAlgorithm) {
+		return nil, fmt.Errorf("gmx509: time-stamp hash algorithm %s does not match the request", ts.HashAlgorithm)
+	}
+	if c.opts.Policy != nil && !ts.Policy.Equal(c.opts.Policy) {
+		return nil, fmt.Errorf("gmx509: time-stamp policy %s does not match the request", ts.Policy)
+	}
+	return VerifyTimestampToken(ts.Token, digest, c.opts.VerifyOptions)
+}
+
+func (c *TSAClient) post(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, c.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/timestamp-query")
+	req.Header.Set("Accept", "application/timestamp-reply")
+
+	resp, err := c.opts.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("gmx509: time-stamp request to %s failed [%s]", c.opts.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gmx509: time-stamp authority %s returned %s", c.opts.URL, resp.Status)
+	}
+	return ioutil.ReadAll(&limitedReader{r: resp.Body, n: maxTSAResponse, what: "time-stamp response"})
+}
@@ -0,0 +1,300 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// maxChainLength bounds the length of the chains built by Verify.
+const maxChainLength = 10
+
+// ChainPolicy restricts how the algorithms of the certificates of a chain
+// may be mixed.
+type ChainPolicy int
+
+const (
+	// ChainMixed accepts chains mixing SM2 and other algorithms, such as
+	// GM intermediates cross-signed by an ECDSA root.
+	ChainMixed ChainPolicy = iota
+	// ChainUniform requires the certificates of a chain to be either all
+	// GM or all non-GM.
+	ChainUniform
+	// ChainGMOnly requires every certificate of a chain to have an SM2
+	// key and an SM2 signature, for full GM compliance.
+	ChainGMOnly
+)
+
+// String returns the name of p.
+func (p ChainPolicy) String() string {
+	switch p {
+	case ChainMixed:
+		return "mixed"
+	case ChainUniform:
+		return "uniform"
+	case ChainGMOnly:
+		return "gm-only"
+	default:
+		return fmt.Sprintf("ChainPolicy(%d)", int(p))
+	}
+}
+
+// CertPool is a set of certificates which, unlike x509.CertPool, can be
+// used to build chains of SM2 certificates.
+type CertPool struct {
+	certs     []*x509.Certificate
+	bySubject map[string][]*x509.Certificate
+}
+
+// NewCertPool returns an empty CertPool.
+func NewCertPool() *CertPool {
+	return &CertPool{bySubject: map[string][]*x509.Certificate{}}
+}
+
+// AddCert adds cert to the pool.
+func (p *CertPool) AddCert(cert *x509.Certificate) {
+	if cert == nil || p.contains(cert) {
+		return
+	}
+	p.certs = append(p.certs, cert)
+	p.bySubject[string(cert.RawSubject)] = append(p.bySubject[string(cert.RawSubject)], cert)
+}
+
+// Certificates returns the certificates of the pool.
+func (p *CertPool) Certificates() []*x509.Certificate {
+	if p == nil {
+		return nil
+	}
+	return append([]*x509.Certificate{}, p.certs...)
+}
+
+func (p *CertPool) contains(cert *x509.Certificate) bool {
+	if p == nil {
+		return false
+	}
+	for _, c := range p.bySubject[string(cert.RawSubject)] {
+		if bytes.Equal(c.Raw, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
+
+// issuers returns the certificates of the pool whose subject is the
+// issuer of cert.
+func (p *CertPool) issuers(cert *x509.Certificate) []*x509.Certificate {
+	if p == nil {
+		return nil
+	}
+	return p.bySubject[string(cert.RawIssuer)]
+}
+
+// VerifyOptions are the options of Verify.
+type VerifyOptions struct {
+	Roots         *CertPool
+	Intermediates *CertPool
+	// CurrentTime is the time the chains must be valid at, now if zero
+	CurrentTime time.Time
+	// KeyUsages lists the extended key usages the leaf may have one of.
+	// Unlike crypto/x509, an empty list places no restriction.
+	KeyUsages []x509.ExtKeyUsage
+	// Policy restricts the mixing of algorithms in the chains
+	Policy ChainPolicy
+}
+
+// Verify builds the chains of cert up to one of opts.Roots like
+// x509.Certificate.Verify, and supports the certificates with SM2 keys or
+// SM2 signatures anywhere in the chain. Chains that opts.Policy forbids
+// are discarded.
+func Verify(cert *x509.Certificate, opts VerifyOptions) ([][]*x509.Certificate, error) {
+	if opts.Roots == nil || len(opts.Roots.certs) == 0 {
+		return nil, errors.New("gmx509: no root certificates to verify against")
+	}
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if err := checkValidity(cert, now); err != nil {
+		return nil, err
+	}
+	if !checkExtKeyUsage(cert, opts.KeyUsages) {
+		return nil, x509.CertificateInvalidError{Cert: cert, Reason: x509.IncompatibleUsage}
+	}
+
+	var chains [][]*x509.Certificate
+	if opts.Roots.contains(cert) {
+		chains = append(chains, []*x509.Certificate{cert})
+	} else {
+		chains = buildChains(cert, []*x509.Certificate{cert}, &opts, now)
+	}
+	if len(chains) == 0 {
+		return nil, x509.UnknownAuthorityError{Cert: cert}
+	}
+
+	var allowed [][]*x509.Certificate
+	var policyErr error
+	for _, chain := range chains {
+		if err := CheckChainPolicy(chain, opts.Policy); err != nil {
+			policyErr = err
+			continue
+		}
+		allowed = append(allowed, chain)
+	}
+	if len(allowed) == 0 {
+		return nil, policyErr
+	}
+	return allowed, nil
+}
+
+// buildChains extends chain, ending with cert, with the issuers of cert.
+func buildChains(cert *x509.Certificate, chain []*x509.Certificate, opts *VerifyOptions, now time.Time) [][]*x509.Certificate {
+	if len(chain) >= maxChainLength {
+		return nil
+	}
+
+	var chains [][]*x509.Certificate
+	for _, root := range opts.Roots.issuers(cert) {
+		if isParent(cert, root, chain, now) {
+			chains = append(chains, append(append([]*x509.Certificate{}, chain...), root))
+		}
+	}
+	for _, intermediate := range opts.Intermediates.issuers(cert) {
+		if opts.Roots.contains(intermediate) || !isParent(cert, intermediate, chain, now) {
+			continue
+		}
+		next := append(append([]*x509.Certificate{}, chain...), intermediate)
+		chains = append(chains, buildChains(intermediate, next, opts, now)...)
+	}
+	return chains
+}
+
+// isParent reports whether parent issued cert, the last certificate of
+// chain, and may extend chain.
+func isParent(cert, parent *x509.Certificate, chain []*x509.Certificate, now time.Time) bool {
+	for _, c := range chain {
+		if bytes.Equal(c.Raw, parent.Raw) {
+			return false
+		}
+	}
+	if checkValidity(parent, now) != nil {
+		return false
+	}
+	// RFC 5280, 4.2.1.9: the intermediates below parent in the chain
+	if parent.BasicConstraintsValid && (parent.MaxPathLen > 0 || parent.MaxPathLenZero) &&
+		len(chain)-1 > parent.MaxPathLen {
+		return false
+	}
+	return CheckSignatureFrom(cert, parent) == nil
+}
+
+func checkValidity(cert *x509.Certificate, now time.Time) error {
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return x509.CertificateInvalidError{Cert: cert, Reason: x509.Expired}
+	}
+	return nil
+}
+
+func checkExtKeyUsage(cert *x509.Certificate, usages []x509.ExtKeyUsage) bool {
+	if len(usages) == 0 || len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+		return true
+	}
+	for _, eku := range cert.ExtKeyUsage {
+		if eku == x509.ExtKeyUsageAny {
+			return true
+		}
+		for _, usage := range usages {
+			if usage == x509.ExtKeyUsageAny || usage == eku {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasSM2Key(cert *x509.Certificate) bool {
+	_, ok := cert.PublicKey.(*sm2.PublicKey)
+	return ok
+}
+
+// IsGM reports whether cert has an SM2 public key and an SM2 signature.
+func IsGM(cert *x509.Certificate) bool {
+	return hasSM2Key(cert) && IsSM2Signed(cert)
+}
+
+// IsMixedChain reports whether chain mixes SM2 and other algorithms, in
+// the keys or in the signatures of its certificates.
+func IsMixedChain(chain []*x509.Certificate) bool {
+	if len(chain) == 0 {
+		return false
+	}
+	gm := hasSM2Key(chain[0])
+	for _, cert := range chain {
+		if hasSM2Key(cert) != gm || IsSM2Signed(cert) != gm {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckChainPolicy returns an error if policy forbids chain.
+func CheckChainPolicy(chain []*x509.Certificate, policy ChainPolicy) error {
+	switch policy {
+	case ChainMixed:
+		return nil
+	case ChainUniform:
+		if IsMixedChain(chain) {
+			return errors.New("gmx509: the certificate chain mixes GM and non-GM algorithms")
+		}
+		return nil
+	case ChainGMOnly:
+		for _, cert := range chain {
+			if !IsGM(cert) {
+				return fmt.Errorf("gmx509: certificate %q of the chain does not use SM2 with SM3", cert.Subject.CommonName)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("gmx509: unknown chain policy %d", int(policy))
+	}
+}
+
+// PeerCertificateVerifier returns a tls.Config VerifyPeerCertificate hook
+// verifying the certificates sent by the peer with Verify. crypto/tls
+// cannot verify SM2 certificates itself: the hook is meant for configs
+// with InsecureSkipVerify on clients, or RequireAnyClientCert on servers.
+func PeerCertificateVerifier(opts VerifyOptions) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("gmx509: no peer certificate")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := ParseCertificate(raw)
+			if err != nil {
+				return fmt.Errorf("gmx509: failed to parse peer certificate [%s]", err)
+			}
+			certs[i] = cert
+		}
+
+		o := opts
+		o.Intermediates = NewCertPool()
+		for _, cert := range opts.Intermediates.Certificates() {
+			o.Intermediates.AddCert(cert)
+		}
+		for _, cert := range certs[1:] {
+			o.Intermediates.AddCert(cert)
+		}
+		_, err := Verify(certs[0], o)
+		return err
+	}
+}
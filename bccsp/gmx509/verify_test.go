@@ -0,0 +1,131 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyMixedChains(t *testing.T) {
+	// ECDSA root cross-signing an SM2 intermediate issuing an SM2 leaf
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecRootDER, err := x509.CreateCertificate(rand.Reader, caTemplate("ecroot"), caTemplate("ecroot"), &ecKey.PublicKey, ecKey)
+	require.NoError(t, err)
+	ecRoot, err := ParseCertificate(ecRootDER)
+	require.NoError(t, err)
+
+	smKey := newSM2Key(t)
+	interTemplate := caTemplate("sminter")
+	interTemplate.SerialNumber = big.NewInt(3)
+	interDER, err := CreateCertificate(rand.Reader, interTemplate, ecRoot, &smKey.PublicKey, ecKey)
+	require.NoError(t, err)
+	inter, err := ParseCertificate(interDER)
+	require.NoError(t, err)
+
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), inter, &leafKey.PublicKey, smKey)
+	require.NoError(t, err)
+	leaf, err := ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	// crypto/x509 cannot verify the SM2 signature of the leaf
+	xroots := x509.NewCertPool()
+	xroots.AddCert(ecRoot)
+	xinters := x509.NewCertPool()
+	xinters.AddCert(inter)
+	_, err = leaf.Verify(x509.VerifyOptions{Roots: xroots, Intermediates: xinters})
+	assert.Error(t, err)
+
+	opts := VerifyOptions{Roots: NewCertPool(), Intermediates: NewCertPool()}
+	opts.Roots.AddCert(ecRoot)
+	opts.Intermediates.AddCert(inter)
+	chains, err := Verify(leaf, opts)
+	require.NoError(t, err)
+	require.Len(t, chains, 1)
+	assert.Equal(t, []*x509.Certificate{leaf, inter, ecRoot}, chains[0])
+	assert.True(t, IsMixedChain(chains[0]))
+
+	opts.Policy = ChainUniform
+	_, err = Verify(leaf, opts)
+	assert.EqualError(t, err, "gmx509: the certificate chain mixes GM and non-GM algorithms")
+	opts.Policy = ChainGMOnly
+	_, err = Verify(leaf, opts)
+	assert.EqualError(t, err, `gmx509: certificate "sminter" of the chain does not use SM2 with SM3`)
+
+	// The same intermediate self-signed is a GM root
+	smRootDER, err := CreateCertificate(rand.Reader, interTemplate, interTemplate, &smKey.PublicKey, smKey)
+	require.NoError(t, err)
+	smRoot, err := ParseCertificate(smRootDER)
+	require.NoError(t, err)
+	opts.Roots = NewCertPool()
+	opts.Roots.AddCert(smRoot)
+	chains, err = Verify(leaf, opts)
+	require.NoError(t, err)
+	assert.Equal(t, []*x509.Certificate{leaf, smRoot}, chains[0])
+	assert.False(t, IsMixedChain(chains[0]))
+
+	// ECDSA leaf under the GM root
+	ecLeafDER, err := CreateCertificate(rand.Reader, leafTemplate("client"), smRoot, &ecKey.PublicKey, smKey)
+	require.NoError(t, err)
+	ecLeaf, err := ParseCertificate(ecLeafDER)
+	require.NoError(t, err)
+	_, err = Verify(ecLeaf, opts)
+	assert.Error(t, err)
+	opts.Policy = ChainMixed
+	_, err = Verify(ecLeaf, opts)
+	assert.NoError(t, err)
+
+	// Untrusted and expired
+	opts.Roots = NewCertPool()
+	opts.Roots.AddCert(ecRoot)
+	opts.Intermediates = nil
+	_, err = Verify(leaf, opts)
+	assert.IsType(t, x509.UnknownAuthorityError{}, err)
+	opts.CurrentTime = time.Now().Add(2 * time.Hour)
+	_, err = Verify(leaf, opts)
+	assert.IsType(t, x509.CertificateInvalidError{}, err)
+}
+
+func TestPeerCertificateVerifier(t *testing.T) {
+	rootKey := newSM2Key(t)
+	rootDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	root, err := ParseCertificate(rootDER)
+	require.NoError(t, err)
+
+	interKey := newSM2Key(t)
+	interTemplate := caTemplate("inter")
+	interTemplate.SerialNumber = big.NewInt(3)
+	interDER, err := CreateCertificate(rand.Reader, interTemplate, root, &interKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	inter, err := ParseCertificate(interDER)
+	require.NoError(t, err)
+
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), inter, &leafKey.PublicKey, interKey)
+	require.NoError(t, err)
+
+	opts := VerifyOptions{Roots: NewCertPool(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}
+	opts.Roots.AddCert(root)
+	verify := PeerCertificateVerifier(opts)
+	assert.NoError(t, verify([][]byte{leafDER, interDER}, nil))
+	assert.Error(t, verify([][]byte{leafDER}, nil))
+	assert.EqualError(t, verify(nil, nil), "gmx509: no peer certificate")
+
+	opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	assert.Error(t, PeerCertificateVerifier(opts)([][]byte{leafDER, interDER}, nil))
+}
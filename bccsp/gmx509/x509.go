@@ -0,0 +1,349 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package gmx509 creates, parses and verifies X.509 structures carrying
+// SM2 keys and SM2 with SM3 signatures, which crypto/x509 refuses. Parsed
+// certificates are *x509.Certificate values whose PublicKey is an
+// *sm2.PublicKey, so that the rest of Fabric can keep using crypto/x509
+// types.
+package gmx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+var (
+	// OIDSignatureSM2WithSM3 is the GM/T 0006 identifier of SM2 signatures
+	// over SM3 digests
+	OIDSignatureSM2WithSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+	// OIDSM3 is the GM/T 0006 identifier of the SM3 hash algorithm
+	OIDSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}
+)
+
+var errSM2Verification = errors.New("gmx509: SM2 verification failure")
+
+// certificate and tbsCertificate are the ASN.1 structures of RFC 5280,
+// with the parts SM2 support does not touch kept as is.
+type certificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+type tbsCertificate struct {
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	UniqueID           asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+// standInKey, the P-256 base point, and its SubjectPublicKeyInfo take
+// the place of SM2 keys in the structures handed to crypto/x509.
+var (
+	standInKey  = &ecdsa.PublicKey{Curve: elliptic.P256(), X: elliptic.P256().Params().Gx, Y: elliptic.P256().Params().Gy}
+	standInSPKI []byte
+)
+
+func init() {
+	var err error
+	if standInSPKI, err = x509.MarshalPKIXPublicKey(standInKey); err != nil {
+		panic(err)
+	}
+}
+
+// CreateCertificate creates a certificate from template, issued by parent,
+// like x509.CreateCertificate. pub is the public key of the subject and
+// priv the private key of the issuer. With an *sm2.PrivateKey, the
+// certificate is signed with SM2 over SM3 (OID 1.2.156.10197.1.501), and
+// an *sm2.PublicKey is encoded as an id-ecPublicKey on the sm2p256v1
+// curve. So is it with a crypto.Signer of an *sm2.PublicKey, such as a
+// BCCSP signer, which must sign the message it is given with SM3 and the
+// default user identity. Other keys are handed to crypto/x509.
+//
+// The subject key identifier of SM2 CA certificates is derived with the
+// SKI convention of the process when the template does not set one.
+func CreateCertificate(rand io.Reader, template, parent *x509.Certificate, pub, priv interface{}) ([]byte, error) {
+	sm2Pub, pubIsSM2 := pub.(*sm2.PublicKey)
+	sm2Priv, privIsSM2 := priv.(*sm2.PrivateKey)
+	var sm2Signer crypto.Signer
+	if s, ok := priv.(crypto.Signer); ok && !privIsSM2 {
+		if _, ok := s.Public().(*sm2.PublicKey); ok {
+			sm2Signer, privIsSM2 = s, true
+		}
+	}
+	if !pubIsSM2 && !privIsSM2 {
+		return x509.CreateCertificate(rand, template, parent, pub, priv)
+	}
+	if template == nil || parent == nil {
+		return nil, errors.New("gmx509: template and parent must be different from nil")
+	}
+	if pubIsSM2 && sm2Pub == nil || privIsSM2 && sm2Priv == nil && sm2Signer == nil {
+		return nil, errors.New("gmx509: SM2 keys must be different from nil")
+	}
+
+	// crypto/x509 encodes the template with stand-in keys, which are then
+	// replaced by the SM2 ones
+	tmpl := *template
+	issuer := *parent
+	issuer.PublicKey = nil
+	if pubIsSM2 {
+		if len(tmpl.SubjectKeyId) == 0 && tmpl.IsCA {
+			tmpl.SubjectKeyId = utils.SKI(sm2Pub)
+		}
+		pub = standInKey
+	}
+
+	var signer crypto.Signer
+	if privIsSM2 {
+		tmpl.SignatureAlgorithm = x509.UnknownSignatureAlgorithm
+		standIn, err := ecdsa.GenerateKey(elliptic.P256(), rand)
+		if err != nil {
+			return nil, err
+		}
+		signer = standIn
+	} else {
+		var ok bool
+		if signer, ok = priv.(crypto.Signer); !ok {
+			return nil, errors.New("gmx509: certificate private key does not implement crypto.Signer")
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand, &tmpl, &issuer, pub, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	var cert certificate
+	var tbs tbsCertificate
+	if err := unmarshalCertificate(der, &cert, &tbs); err != nil {
+		return nil, err
+	}
+
+	if pubIsSM2 {
+		spki, err := utils.MarshalPKIXSM2PublicKey(sm2Pub)
+		if err != nil {
+			return nil, err
+		}
+		tbs.PublicKey = asn1.RawValue{FullBytes: spki}
+	}
+
+	if privIsSM2 {
+		tbs.SignatureAlgorithm = pkix.AlgorithmIdentifier{Algorithm: OIDSignatureSM2WithSM3}
+		cert.SignatureAlgorithm = tbs.SignatureAlgorithm
+	}
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+
+	var signature []byte
+	if sm2Signer != nil {
+		signature, err = sm2Signer.Sign(rand, tbsDER, crypto.Hash(0))
+	} else if privIsSM2 {
+		signature, err = SignSM2(rand, sm2Priv, tbsDER)
+	} else {
+		signature, err = signWith(rand, signer, der, tbsDER)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cert.TBSCertificate = asn1.RawValue{FullBytes: tbsDER}
+	cert.SignatureValue = asn1.BitString{Bytes: signature, BitLength: 8 * len(signature)}
+	return asn1.Marshal(cert)
+}
+
+// unmarshalCertificate parses the outer structure and the TBSCertificate
+// of der.
+func unmarshalCertificate(der []byte, cert *certificate, tbs *tbsCertificate) error {
+	if rest, err := asn1.Unmarshal(der, cert); err != nil {
+		return err
+	} else if len(rest) != 0 {
+		return errors.New("gmx509: trailing data after certificate")
+	}
+	if rest, err := asn1.Unmarshal(cert.TBSCertificate.FullBytes, tbs); err != nil {
+		return err
+	} else if len(rest) != 0 {
+		return errors.New("gmx509: trailing data after TBSCertificate")
+	}
+	return nil
+}
+
+// signWith signs tbs with signer, using the signature algorithm
+// crypto/x509 picked for the certificate cert.
+func signWith(rand io.Reader, signer crypto.Signer, cert, tbs []byte) ([]byte, error) {
+	c, err := x509.ParseCertificate(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	var hash crypto.Hash
+	switch c.SignatureAlgorithm {
+	case x509.ECDSAWithSHA256, x509.SHA256WithRSA:
+		hash = crypto.SHA256
+	case x509.ECDSAWithSHA384, x509.SHA384WithRSA:
+		hash = crypto.SHA384
+	case x509.ECDSAWithSHA512, x509.SHA512WithRSA:
+		hash = crypto.SHA512
+	case x509.PureEd25519:
+		return signer.Sign(rand, tbs, crypto.Hash(0))
+	default:
+		return nil, fmt.Errorf("gmx509: unsupported signature algorithm %s", c.SignatureAlgorithm)
+	}
+
+	h := hash.New()
+	h.Write(tbs)
+	return signer.Sign(rand, h.Sum(nil), hash)
+}
+
+// SignSM2 signs msg with priv, with SM3 and the default user identity, and
+// returns the DER encoded signature.
+func SignSM2(rand io.Reader, priv *sm2.PrivateKey, msg []byte) ([]byte, error) {
+	r, s, err := utils.SM2SignWithRand(rand, priv, nil, msg)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MarshalECDSASignature(r, s)
+}
+
+// ParseCertificate parses a DER encoded certificate like
+// x509.ParseCertificate, accepting SM2 public keys. Raw,
+// RawTBSCertificate and RawSubjectPublicKeyInfo are those of der, and the
+// SignatureAlgorithm of certificates signed with SM2 is
+// x509.UnknownSignatureAlgorithm: use IsSM2Signed to recognize them.
+func ParseCertificate(der []byte) (*x509.Certificate, error) {
+	cert, err := x509.ParseCertificate(der)
+	if err == nil {
+		// Unknown key algorithms such as id-sm2 are left unparsed
+		if cert.PublicKey == nil {
+			if pub, err := utils.ParseGmSSLSM2PublicKey(cert.RawSubjectPublicKeyInfo); err == nil {
+				cert.PublicKey = pub
+			}
+		}
+		return cert, nil
+	}
+
+	var c certificate
+	var tbs tbsCertificate
+	if unmarshalCertificate(der, &c, &tbs) != nil {
+		return nil, err
+	}
+	pub, perr := utils.ParseGmSSLSM2PublicKey(tbs.PublicKey.FullBytes)
+	if perr != nil {
+		return nil, err
+	}
+
+	// Let crypto/x509 parse the certificate with a stand-in key
+	spki := tbs.PublicKey.FullBytes
+	tbs.PublicKey = asn1.RawValue{FullBytes: standInSPKI}
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	rawTBS := c.TBSCertificate.FullBytes
+	c.TBSCertificate = asn1.RawValue{FullBytes: tbsDER}
+	standIn, err := asn1.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	if cert, err = x509.ParseCertificate(standIn); err != nil {
+		return nil, err
+	}
+
+	cert.Raw = der
+	cert.RawTBSCertificate = rawTBS
+	cert.RawSubjectPublicKeyInfo = spki
+	cert.PublicKey = pub
+	cert.PublicKeyAlgorithm = x509.ECDSA
+	return cert, nil
+}
+
+// SignatureAlgorithmOID returns the identifier of the signature algorithm
+// of cert.
+func SignatureAlgorithmOID(cert *x509.Certificate) (asn1.ObjectIdentifier, error) {
+	return signatureAlgorithmOID(cert.Raw)
+}
+
+// signatureAlgorithmOID returns the identifier of the signature algorithm
+// of a certificate, CSR or CRL, which all are a SEQUENCE of the signed
+// data, the algorithm and the signature.
+func signatureAlgorithmOID(der []byte) (asn1.ObjectIdentifier, error) {
+	var c certificate
+	if _, err := asn1.Unmarshal(der, &c); err != nil {
+		return nil, err
+	}
+	return c.SignatureAlgorithm.Algorithm, nil
+}
+
+// IsSM2Signed reports whether cert is signed with SM2 over SM3.
+func IsSM2Signed(cert *x509.Certificate) bool {
+	oid, err := SignatureAlgorithmOID(cert)
+	return err == nil && oid.Equal(OIDSignatureSM2WithSM3)
+}
+
+// CheckSignatureFrom verifies that the signature of cert is a valid
+// signature of parent, like x509.Certificate.CheckSignatureFrom, and
+// supports SM2 with SM3.
+func CheckSignatureFrom(cert, parent *x509.Certificate) error {
+	if !IsSM2Signed(cert) {
+		return cert.CheckSignatureFrom(parent)
+	}
+
+	// RFC 5280, 4.2.1.9 and 4.2.1.3, as enforced by crypto/x509
+	if parent.Version == 3 && !parent.BasicConstraintsValid ||
+		parent.BasicConstraintsValid && !parent.IsCA {
+		return x509.ConstraintViolationError{}
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return x509.ConstraintViolationError{}
+	}
+	return VerifySM2(parent.PublicKey, cert.RawTBSCertificate, cert.Signature)
+}
+
+// CheckSignatureBy verifies that the signature of cert is a valid
+// signature of the subject of signer, which unlike the parent of
+// CheckSignatureFrom needs not be a CA, and supports SM2 with SM3.
+func CheckSignatureBy(cert, signer *x509.Certificate) error {
+	if IsSM2Signed(cert) {
+		return VerifySM2(signer.PublicKey, cert.RawTBSCertificate, cert.Signature)
+	}
+	return signer.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature)
+}
+
+// VerifySM2 verifies the DER encoded SM2 signature of signed, with SM3
+// and the default user identity, against an *sm2.PublicKey.
+func VerifySM2(pub interface{}, signed, signature []byte) error {
+	sm2Pub, ok := pub.(*sm2.PublicKey)
+	if !ok || sm2Pub == nil {
+		return fmt.Errorf("gmx509: SM2 signature with a %T public key", pub)
+	}
+	sig, err := utils.NormalizeSignature(signature)
+	if err != nil {
+		return err
+	}
+	if !sm2.Verify(sm2Pub, nil, signed, sig) {
+		return errSM2Verification
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gmx509
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func caTemplate(cn string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"org1"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+}
+
+func leafTemplate(cn string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"peer0.org1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+}
+
+func newSM2Key(t *testing.T) *sm2.PrivateKey {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	return key
+}
+
+func TestCreateSelfSignedSM2Certificate(t *testing.T) {
+	key := newSM2Key(t)
+	template := caTemplate("ca.org1")
+
+	der, err := CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	// crypto/x509 refuses SM2 keys
+	_, err = x509.ParseCertificate(der)
+	assert.Error(t, err)
+
+	cert, err := ParseCertificate(der)
+	require.NoError(t, err)
+	assert.Equal(t, der, cert.Raw)
+	assert.Equal(t, &key.PublicKey, cert.PublicKey)
+	assert.Equal(t, "ca.org1", cert.Subject.CommonName)
+	assert.Equal(t, []string{"org1"}, cert.Subject.Organization)
+	assert.True(t, cert.IsCA)
+	assert.Equal(t, template.KeyUsage, cert.KeyUsage)
+	assert.Equal(t, utils.SKI(&key.PublicKey), cert.SubjectKeyId)
+	assert.True(t, IsSM2Signed(cert))
+
+	spki, err := utils.MarshalPKIXSM2PublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	assert.Equal(t, spki, cert.RawSubjectPublicKeyInfo)
+
+	assert.NoError(t, CheckSignatureFrom(cert, cert))
+	cert.Signature[len(cert.Signature)-1] ^= 1
+	assert.Error(t, CheckSignatureFrom(cert, cert))
+}
+
+func TestCreateSM2Chain(t *testing.T) {
+	caKey := newSM2Key(t)
+	caDER, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	// SM2 leaf issued by the SM2 CA
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), ca, &leafKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := ParseCertificate(leafDER)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"peer0.org1"}, leaf.DNSNames)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, leaf.ExtKeyUsage)
+	assert.Equal(t, ca.SubjectKeyId, leaf.AuthorityKeyId)
+	assert.Equal(t, ca.RawSubject, leaf.RawIssuer)
+	assert.NoError(t, CheckSignatureFrom(leaf, ca))
+
+	// The leaf is not a CA
+	assert.Equal(t, x509.ConstraintViolationError{}, CheckSignatureFrom(leaf, leaf))
+
+	// ECDSA key certified by the SM2 CA
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := CreateCertificate(rand.Reader, leafTemplate("client"), ca, &ecKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ecCert, err := x509.ParseCertificate(ecDER)
+	require.NoError(t, err)
+	assert.Equal(t, &ecKey.PublicKey, ecCert.PublicKey)
+	assert.True(t, IsSM2Signed(ecCert))
+	assert.NoError(t, CheckSignatureFrom(ecCert, ca))
+
+	// SM2 key certified by an ECDSA CA
+	ecCADER, err := x509.CreateCertificate(rand.Reader, caTemplate("ecca"), caTemplate("ecca"), &ecKey.PublicKey, ecKey)
+	require.NoError(t, err)
+	ecCA, err := x509.ParseCertificate(ecCADER)
+	require.NoError(t, err)
+	mixedDER, err := CreateCertificate(rand.Reader, leafTemplate("peer1"), ecCA, &leafKey.PublicKey, ecKey)
+	require.NoError(t, err)
+	mixed, err := ParseCertificate(mixedDER)
+	require.NoError(t, err)
+	assert.Equal(t, &leafKey.PublicKey, mixed.PublicKey)
+	assert.False(t, IsSM2Signed(mixed))
+	assert.Equal(t, x509.ECDSAWithSHA256, mixed.SignatureAlgorithm)
+	assert.NoError(t, CheckSignatureFrom(mixed, ecCA))
+	assert.Error(t, CheckSignatureFrom(mixed, ca))
+}
+
+func TestCreateCertificateWithoutSM2(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := CreateCertificate(rand.Reader, caTemplate("ca"), caTemplate("ca"), &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := ParseCertificate(der)
+	require.NoError(t, err)
+	assert.Equal(t, x509.ECDSAWithSHA256, cert.SignatureAlgorithm)
+	assert.NoError(t, CheckSignatureFrom(cert, cert))
+}
+
+// testSM2Signer is a crypto.Signer of an SM2 key, like BCCSP signers
+type testSM2Signer struct {
+	key *sm2.PrivateKey
+}
+
+func (s *testSM2Signer) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *testSM2Signer) Sign(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return SignSM2(rand, s.key, msg)
+}
+
+func TestCheckSignatureBy(t *testing.T) {
+	leafKey := newSM2Key(t)
+	leafDER, err := CreateCertificate(rand.Reader, leafTemplate("peer0"), leafTemplate("peer0"), &leafKey.PublicKey, leafKey)
+	require.NoError(t, err)
+	leaf, err := ParseCertificate(leafDER)
+	require.NoError(t, err)
+
+	// The leaf signs with an SM2 crypto.Signer, and is not a CA
+	key := newSM2Key(t)
+	der, err := CreateCertificate(rand.Reader, leafTemplate("delegate"), leaf, &key.PublicKey, &testSM2Signer{key: leafKey})
+	require.NoError(t, err)
+	cert, err := ParseCertificate(der)
+	require.NoError(t, err)
+	assert.True(t, IsSM2Signed(cert))
+	assert.NoError(t, CheckSignatureBy(cert, leaf))
+	assert.Equal(t, x509.ConstraintViolationError{}, CheckSignatureFrom(cert, leaf))
+	assert.Error(t, CheckSignatureBy(leaf, cert))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.CreateCertificate(rand.Reader, leafTemplate("client"), leafTemplate("client"), &ecKey.PublicKey, ecKey)
+	require.NoError(t, err)
+	ecLeaf, err := x509.ParseCertificate(ecDER)
+	require.NoError(t, err)
+	der, err = CreateCertificate(rand.Reader, leafTemplate("delegate"), ecLeaf, &key.PublicKey, ecKey)
+	require.NoError(t, err)
+	cert, err = ParseCertificate(der)
+	require.NoError(t, err)
+	assert.NoError(t, CheckSignatureBy(cert, ecLeaf))
+	assert.Error(t, CheckSignatureBy(cert, leaf))
+}
+
+func TestVerifySM2(t *testing.T) {
+	key := newSM2Key(t)
+	sig, err := SignSM2(rand.Reader, key, []byte("message"))
+	require.NoError(t, err)
+
+	assert.NoError(t, VerifySM2(&key.PublicKey, []byte("message"), sig))
+	assert.Equal(t, errSM2Verification, VerifySM2(&key.PublicKey, []byte("other"), sig))
+	assert.EqualError(t, VerifySM2("key", []byte("message"), sig), "gmx509: SM2 signature with a string public key")
+}
@@ -17,8 +17,9 @@ require (
 	github.com/paul-lee-attorney/gm v0.0.0-20201014053731-c3ade66b8a26
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/viper v1.7.1
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
 	github.com/sykesm/zap-logfmt v0.0.4 // indirect
 	go.uber.org/zap v1.16.0
 	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897
+	google.golang.org/grpc v1.33.1
 )
@@ -0,0 +1,232 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package instrumented
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+// Names of the operations reported in the operation label.
+const (
+	OperationKeyGen  = "keygen"
+	OperationSign    = "sign"
+	OperationVerify  = "verify"
+	OperationHash    = "hash"
+	OperationEncrypt = "encrypt"
+	OperationDecrypt = "decrypt"
+)
+
+// Values of the result label.
+const (
+	ResultSuccess = "success"
+	// ResultInvalid is a verification which found the signature invalid
+	ResultInvalid = "invalid"
+	ResultFailure = "failure"
+)
+
+// unknownAlgorithm labels operations whose algorithm cannot be told from
+// their key or options.
+const unknownAlgorithm = "unknown"
+
+// MetricsOpts configures the metrics of the cryptographic operations.
+type MetricsOpts struct {
+	// Enabled turns the metrics on
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+
+	// MetricsProvider receives the metrics, they are disabled if nil
+	MetricsProvider metrics.Provider `json:"-" yaml:"-"`
+}
+
+// CSP is a BCCSP that counts and times the KeyGen, Sign, Verify, Hash,
+// Encrypt and Decrypt operations of its underlying BCCSP, labeled by the
+// name of the provider, the operation and the algorithm of its key or
+// options, e.g. SM2, ECDSA, SM3 or SM4.
+type CSP struct {
+	// csp serves all operations
+	bccsp.BCCSP
+
+	provider string
+
+	metricsMutex sync.RWMutex
+	metrics      *Metrics
+}
+
+// New returns a CSP reporting the operations of csp, labeled with the
+// provider name.
+func New(csp bccsp.BCCSP, provider string, opts MetricsOpts) (*CSP, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid BCCSP instance. It must be different from nil")
+	}
+	if provider == "" {
+		return nil, errors.New("Invalid provider name. It must not be empty")
+	}
+
+	p := opts.MetricsProvider
+	if p == nil {
+		p = &disabled.Provider{}
+	}
+	return &CSP{
+		BCCSP:    csp,
+		provider: provider,
+		metrics:  NewMetrics(p),
+	}, nil
+}
+
+// SetMetricsProvider redirects the metrics to p, for processes creating
+// their metrics provider after the BCCSP.
+func (c *CSP) SetMetricsProvider(p metrics.Provider) {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+	c.metrics = NewMetrics(p)
+}
+
+func (c *CSP) getMetrics() *Metrics {
+	c.metricsMutex.RLock()
+	defer c.metricsMutex.RUnlock()
+	return c.metrics
+}
+
+// observe records an operation started at start.
+func (c *CSP) observe(operation, algorithm string, start time.Time, result string) {
+	m := c.getMetrics()
+	m.OperationDuration.With(
+		"provider", c.provider,
+		"operation", operation,
+		"algorithm", algorithm,
+	).Observe(time.Since(start).Seconds())
+	m.Operations.With(
+		"provider", c.provider,
+		"operation", operation,
+		"algorithm", algorithm,
+		"result", result,
+	).Add(1)
+}
+
+// Unwrap returns the measured BCCSP.
+func (c *CSP) Unwrap() bccsp.BCCSP {
+	return c.BCCSP
+}
+
+// KeyGen generates a key using opts.
+func (c *CSP) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	start := time.Now()
+	k, err := c.BCCSP.KeyGen(opts)
+	c.observe(OperationKeyGen, optsAlgorithm(opts), start, errorResult(err))
+	return k, err
+}
+
+// Hash hashes msg using opts.
+func (c *CSP) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	start := time.Now()
+	digest, err := c.BCCSP.Hash(msg, opts)
+	c.observe(OperationHash, optsAlgorithm(opts), start, errorResult(err))
+	return digest, err
+}
+
+// Sign signs digest using key k.
+func (c *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	start := time.Now()
+	signature, err := c.BCCSP.Sign(k, digest, opts)
+	c.observe(OperationSign, keyAlgorithm(k), start, errorResult(err))
+	return signature, err
+}
+
+// SignCtx signs digest using key k, handing ctx to the underlying BCCSP
+// when it implements bccsp.ContextSigner.
+func (c *CSP) SignCtx(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	start := time.Now()
+	signature, err := bccsp.SignCtx(ctx, c.BCCSP, k, digest, opts)
+	c.observe(OperationSign, keyAlgorithm(k), start, errorResult(err))
+	return signature, err
+}
+
+// Verify verifies signature against key k and digest.
+func (c *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	start := time.Now()
+	valid, err := c.BCCSP.Verify(k, signature, digest, opts)
+	result := errorResult(err)
+	if err == nil && !valid {
+		result = ResultInvalid
+	}
+	c.observe(OperationVerify, keyAlgorithm(k), start, result)
+	return valid, err
+}
+
+// Encrypt encrypts plaintext using key k.
+func (c *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	start := time.Now()
+	ciphertext, err := c.BCCSP.Encrypt(k, plaintext, opts)
+	c.observe(OperationEncrypt, keyAlgorithm(k), start, errorResult(err))
+	return ciphertext, err
+}
+
+// Decrypt decrypts ciphertext using key k.
+func (c *CSP) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	start := time.Now()
+	plaintext, err := c.BCCSP.Decrypt(k, ciphertext, opts)
+	c.observe(OperationDecrypt, keyAlgorithm(k), start, errorResult(err))
+	return plaintext, err
+}
+
+func errorResult(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// optsAlgorithm returns the algorithm of the options of a key generation
+// or hash.
+func optsAlgorithm(opts interface{ Algorithm() string }) string {
+	if opts == nil {
+		return unknownAlgorithm
+	}
+	if algorithm := opts.Algorithm(); algorithm != "" {
+		return algorithm
+	}
+	return unknownAlgorithm
+}
+
+// keyAlgorithm returns the algorithm of k, told by the key itself when it
+// implements bccsp.AlgorithmKey, by its public part otherwise.
+func keyAlgorithm(k bccsp.Key) string {
+	if k == nil {
+		return unknownAlgorithm
+	}
+	if ak, ok := k.(bccsp.AlgorithmKey); ok {
+		return ak.Algorithm()
+	}
+	cpk, ok := k.(bccsp.CryptoPublicKeyer)
+	if !ok {
+		return unknownAlgorithm
+	}
+	pub, err := cpk.CryptoPublicKey()
+	if err != nil {
+		return unknownAlgorithm
+	}
+	switch pub := pub.(type) {
+	case *sm2.PublicKey:
+		return bccsp.SM2
+	case *ecdsa.PublicKey:
+		// Some x509 implementations surface SM2 keys as ECDSA keys on the SM2 curve
+		if pub.Curve != nil && pub.Curve.Params().P.Cmp(sm2.GetSm2P256V1().Params().P) == 0 {
+			return bccsp.SM2
+		}
+		return bccsp.ECDSA
+	default:
+		return unknownAlgorithm
+	}
+}
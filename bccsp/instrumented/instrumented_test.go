@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDevice = errors.New("device error")
+
+// device fails its signatures when down.
+type device struct {
+	bccsp.BCCSP
+	down bool
+}
+
+func (d *device) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if d.down {
+		return nil, errDevice
+	}
+	return d.BCCSP.Sign(k, digest, opts)
+}
+
+type fakeMetrics struct {
+	counter   *metricsfakes.Counter
+	histogram *metricsfakes.Histogram
+}
+
+func newTestCSP(t *testing.T) (*CSP, *device, *fakeMetrics) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	d := &device{BCCSP: csp}
+	c, err := New(d, "GM", MetricsOpts{Enabled: true})
+	require.NoError(t, err)
+
+	m := &fakeMetrics{
+		counter:   &metricsfakes.Counter{},
+		histogram: &metricsfakes.Histogram{},
+	}
+	m.counter.WithReturns(m.counter)
+	m.histogram.WithReturns(m.histogram)
+	p := &metricsfakes.Provider{}
+	p.NewCounterReturns(m.counter)
+	p.NewHistogramReturns(m.histogram)
+	c.SetMetricsProvider(p)
+
+	return c, d, m
+}
+
+// lastLabels returns the labels of the last operation counted.
+func (m *fakeMetrics) lastLabels(t *testing.T) []string {
+	require.NotZero(t, m.counter.WithCallCount())
+	return m.counter.WithArgsForCall(m.counter.WithCallCount() - 1)
+}
+
+func TestNewInvalidArgs(t *testing.T) {
+	_, err := New(nil, "SW", MetricsOpts{})
+	assert.EqualError(t, err, "Invalid BCCSP instance. It must be different from nil")
+
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	_, err = New(csp, "", MetricsOpts{})
+	assert.EqualError(t, err, "Invalid provider name. It must not be empty")
+}
+
+func TestOperations(t *testing.T) {
+	c, d, m := newTestCSP(t)
+
+	k, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"provider", "GM", "operation", "keygen", "algorithm", "SM2", "result", "success"}, m.lastLabels(t))
+
+	digest, err := c.Hash([]byte("block data"), &bccsp.SM3Opts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"provider", "GM", "operation", "hash", "algorithm", "SM3", "result", "success"}, m.lastLabels(t))
+
+	sig, err := c.SignCtx(context.Background(), k, digest, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"provider", "GM", "operation", "sign", "algorithm", "SM2", "result", "success"}, m.lastLabels(t))
+
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	valid, err := c.Verify(pub, sig, digest, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, []string{"provider", "GM", "operation", "verify", "algorithm", "SM2", "result", "success"}, m.lastLabels(t))
+
+	valid, err = c.Verify(pub, sig, []byte("forged digest"), nil)
+	require.NoError(t, err)
+	assert.False(t, valid)
+	assert.Equal(t, []string{"provider", "GM", "operation", "verify", "algorithm", "SM2", "result", "invalid"}, m.lastLabels(t))
+
+	d.down = true
+	_, err = c.Sign(k, digest, nil)
+	assert.Equal(t, errDevice, err)
+	assert.Equal(t, []string{"provider", "GM", "operation", "sign", "algorithm", "SM2", "result", "failure"}, m.lastLabels(t))
+
+	assert.Equal(t, 6, m.counter.AddCallCount())
+	assert.Equal(t, 6, m.histogram.ObserveCallCount())
+	assert.Equal(t, []string{"provider", "GM", "operation", "sign", "algorithm", "SM2"}, m.histogram.WithArgsForCall(5))
+}
+
+func TestSymmetricOperations(t *testing.T) {
+	c, _, m := newTestCSP(t)
+
+	k, err := c.KeyGen(&bccsp.SM4KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"provider", "GM", "operation", "keygen", "algorithm", "SM4", "result", "success"}, m.lastLabels(t))
+
+	ct, err := c.Encrypt(k, []byte("private data"), &bccsp.AEADOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"provider", "GM", "operation", "encrypt", "algorithm", "SM4", "result", "success"}, m.lastLabels(t))
+
+	pt, err := c.Decrypt(k, ct, &bccsp.AEADOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("private data"), pt)
+	assert.Equal(t, []string{"provider", "GM", "operation", "decrypt", "algorithm", "SM4", "result", "success"}, m.lastLabels(t))
+
+	_, err = c.Encrypt(nil, []byte("private data"), nil)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"provider", "GM", "operation", "encrypt", "algorithm", "unknown", "result", "failure"}, m.lastLabels(t))
+}
@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package instrumented
+
+import (
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+var (
+	operations = metrics.CounterOpts{
+		Namespace:    "bccsp",
+		Name:         "operations",
+		Help:         "The number of cryptographic operations served by the BCCSP.",
+		LabelNames:   []string{"provider", "operation", "algorithm", "result"},
+		StatsdFormat: "%{#fqname}.%{provider}.%{operation}.%{algorithm}.%{result}",
+	}
+	operationDuration = metrics.HistogramOpts{
+		Namespace:    "bccsp",
+		Name:         "operation_duration",
+		Help:         "The time taken by the BCCSP to serve a cryptographic operation, in seconds.",
+		LabelNames:   []string{"provider", "operation", "algorithm"},
+		StatsdFormat: "%{#fqname}.%{provider}.%{operation}.%{algorithm}",
+		Buckets:      []float64{0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}
+)
+
+type Metrics struct {
+	Operations        metrics.Counter
+	OperationDuration metrics.Histogram
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		Operations:        p.NewCounter(operations),
+		OperationDuration: p.NewHistogram(operationDuration),
+	}
+}
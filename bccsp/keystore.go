@@ -32,3 +32,14 @@ type KeyStore interface {
 	// If this KeyStore is read only then the method will fail.
 	StoreKey(k Key) (err error)
 }
+
+// IndexedKeyStore is implemented by KeyStores which index their keys in the
+// background once opened, so that opening a KeyStore holding many keys does
+// not stall. Keys can be retrieved before the index is built, more slowly.
+type IndexedKeyStore interface {
+	KeyStore
+
+	// Ready returns a channel closed once the keys the KeyStore held
+	// when opened are indexed.
+	Ready() <-chan struct{}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+const aliyunAPIVersion = "2016-01-20"
+
+// aliyunClient calls the RPC style API of Alibaba Cloud KMS.
+type aliyunClient struct {
+	endpoint string
+	keyID    string
+	secret   string
+	http     *http.Client
+
+	// overridden by tests
+	now   func() time.Time
+	nonce func() string
+}
+
+func newAliyunClient(opts KMSOpts, keyID, secret string) *aliyunClient {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.aliyuncs.com", opts.Region)
+	}
+	return &aliyunClient{
+		endpoint: endpoint,
+		keyID:    keyID,
+		secret:   secret,
+		http:     &http.Client{Timeout: opts.Timeout},
+		now:      time.Now,
+		nonce:    randomNonce,
+	}
+}
+
+func randomNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// aliyunSignature computes the signature version 1.0 of params.
+func aliyunSignature(method string, params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, percentEncode(k)+"="+percentEncode(params.Get(k)))
+	}
+	stringToSign := method + "&" + percentEncode("/") + "&" + percentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *aliyunClient) call(action string, params url.Values, out interface{}) error {
+	params.Set("Action", action)
+	params.Set("Format", "JSON")
+	params.Set("Version", aliyunAPIVersion)
+	params.Set("AccessKeyId", c.keyID)
+	params.Set("SignatureMethod", "HMAC-SHA1")
+	params.Set("SignatureVersion", "1.0")
+	params.Set("SignatureNonce", c.nonce())
+	params.Set("Timestamp", c.now().UTC().Format("2006-01-02T15:04:05Z"))
+	params.Set("Signature", aliyunSignature(http.MethodPost, params, c.secret))
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if err := doJSON(c.http, req, out); err != nil {
+		return fmt.Errorf("Alibaba Cloud KMS %s failed [%s]", action, err)
+	}
+	return nil
+}
+
+func (c *aliyunClient) publicKey(ref KeyRef) (crypto.PublicKey, error) {
+	if ref.Version == "" {
+		return nil, fmt.Errorf("Key version of [%s] is required by Alibaba Cloud KMS", ref.ID)
+	}
+
+	var resp struct {
+		PublicKey string `json:"PublicKey"`
+	}
+	params := url.Values{}
+	params.Set("KeyId", ref.ID)
+	params.Set("KeyVersionId", ref.Version)
+	if err := c.call("GetPublicKey", params, &resp); err != nil {
+		return nil, err
+	}
+
+	pub, err := utils.PEMtoPublicKey([]byte(resp.PublicKey), nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing public key of [%s] [%s]", ref.ID, err)
+	}
+	return pub, nil
+}
+
+func (c *aliyunClient) sign(ref KeyRef, pub crypto.PublicKey, digest []byte) ([]byte, error) {
+	var algorithm string
+	switch k := pub.(type) {
+	case *sm2.PublicKey:
+		algorithm = "SM2DSA"
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().BitSize != 256 {
+			return nil, fmt.Errorf("Unsupported curve [%s]", curveName(pub))
+		}
+		algorithm = "ECDSA_SHA_256"
+	default:
+		return nil, fmt.Errorf("Unsupported key type [%T]", pub)
+	}
+
+	var resp struct {
+		Value string `json:"Value"`
+	}
+	params := url.Values{}
+	params.Set("KeyId", ref.ID)
+	params.Set("KeyVersionId", ref.Version)
+	params.Set("Algorithm", algorithm)
+	params.Set("Digest", base64.StdEncoding.EncodeToString(digest))
+	if err := c.call("AsymmetricSign", params, &resp); err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(resp.Value)
+}
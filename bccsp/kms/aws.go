@@ -0,0 +1,203 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+)
+
+// awsClient calls the JSON API of AWS KMS, signing requests with
+// signature version 4.
+type awsClient struct {
+	endpoint string
+	region   string
+	keyID    string
+	secret   string
+	token    string
+	http     *http.Client
+
+	// overridden by tests
+	now func() time.Time
+}
+
+func newAWSClient(opts KMSOpts, keyID, secret, token string) *awsClient {
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com/", opts.Region)
+	}
+	return &awsClient{
+		endpoint: endpoint,
+		region:   opts.Region,
+		keyID:    keyID,
+		secret:   secret,
+		token:    token,
+		http:     &http.Client{Timeout: opts.Timeout},
+		now:      time.Now,
+	}
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// signV4 adds the X-Amz-Date and Authorization headers of a signature
+// version 4 to req. All headers set on req, plus Host, are signed.
+func signV4(req *http.Request, body []byte, service, region, keyID, secret string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vs := query[k]
+		sort.Strings(vs)
+		for _, v := range vs {
+			pairs = append(pairs, percentEncode(k)+"="+percentEncode(v))
+		}
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(pairs, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	k = hmacSHA256(k, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(k, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		keyID, scope, signedHeaders, signature))
+}
+
+func (c *awsClient) call(action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	if c.token != "" {
+		req.Header.Set("X-Amz-Security-Token", c.token)
+	}
+	signV4(req, body, "kms", c.region, c.keyID, c.secret, c.now())
+
+	if err := doJSON(c.http, req, out); err != nil {
+		return fmt.Errorf("AWS KMS %s failed [%s]", action, err)
+	}
+	return nil
+}
+
+func (c *awsClient) publicKey(ref KeyRef) (crypto.PublicKey, error) {
+	var resp struct {
+		PublicKey []byte `json:"PublicKey"`
+		KeyUsage  string `json:"KeyUsage"`
+	}
+	if err := c.call("GetPublicKey", map[string]string{"KeyId": ref.ID}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.KeyUsage != "" && resp.KeyUsage != "SIGN_VERIFY" {
+		return nil, fmt.Errorf("Key [%s] is not a signing key, usage [%s]", ref.ID, resp.KeyUsage)
+	}
+
+	pub, err := utils.DERToPublicKey(resp.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing public key of [%s] [%s]", ref.ID, err)
+	}
+	return pub, nil
+}
+
+func (c *awsClient) sign(ref KeyRef, pub crypto.PublicKey, digest []byte) ([]byte, error) {
+	k, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported key type [%T]", pub)
+	}
+
+	var algorithm string
+	switch k.Curve.Params().BitSize {
+	case 256:
+		algorithm = "ECDSA_SHA_256"
+	case 384:
+		algorithm = "ECDSA_SHA_384"
+	default:
+		return nil, fmt.Errorf("Unsupported curve [%s]", curveName(pub))
+	}
+
+	var resp struct {
+		Signature []byte `json:"Signature"`
+	}
+	req := map[string]interface{}{
+		"KeyId":            ref.ID,
+		"Message":          digest,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": algorithm,
+	}
+	if err := c.call("Sign", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// client is the part of a key management service used by the provider.
+type client interface {
+	// publicKey returns the public key of ref, an *ecdsa.PublicKey or an *sm2.PublicKey.
+	publicKey(ref KeyRef) (crypto.PublicKey, error)
+	// sign signs digest with ref and returns a DER encoded signature.
+	// For SM2 keys digest is e = SM3(Z_A || M).
+	sign(ref KeyRef, pub crypto.PublicKey, digest []byte) ([]byte, error)
+}
+
+// doJSON sends req and decodes the JSON response into out. Responses other
+// than 200 are turned into errors carrying the service error code.
+func doJSON(c *http.Client, req *http.Request, out interface{}) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Code    string `json:"Code"`
+			Type    string `json:"__type"`
+			Message string `json:"Message"`
+			Msg     string `json:"message"`
+		}
+		json.Unmarshal(body, &e)
+		code, msg := e.Code, e.Message
+		if code == "" {
+			code = e.Type
+		}
+		if msg == "" {
+			msg = e.Msg
+		}
+		return fmt.Errorf("KMS error, status [%d] code [%s] message [%s]", resp.StatusCode, code, msg)
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+func curveName(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case *sm2.PublicKey:
+		return "SM2"
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().Name
+	default:
+		return fmt.Sprintf("%T", pub)
+	}
+}
+
+// percentEncode is the RFC 3986 percent encoding used by both request
+// signatures, only unreserved characters are left as is.
+func percentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.Replace(s, "+", "%20", -1)
+	s = strings.Replace(s, "*", "%2A", -1)
+	return strings.Replace(s, "%7E", "~", -1)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+import "time"
+
+// Names of the supported key management services.
+const (
+	// Alibaba Cloud KMS, signs with SM2 (SM2DSA) or ECDSA keys
+	Aliyun = "ALIYUN"
+	// AWS KMS, signs with ECDSA keys on NIST P-256 and P-384
+	AWS = "AWS"
+)
+
+// KMSOpts contains options for the KMSFactory, whose BCCSP signs with
+// asymmetric keys held by a cloud key management service.
+type KMSOpts struct {
+	// Default algorithms of the software fallback
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// Provider is either ALIYUN or AWS
+	Provider string `mapstructure:"provider" json:"provider"`
+	Region   string `mapstructure:"region" json:"region"`
+	// Endpoint overrides the regional endpoint of the service, e.g. a VPC endpoint
+	Endpoint string `mapstructure:"endpoint,omitempty" json:"endpoint,omitempty"`
+
+	// Credentials. If unset they are read from the environment,
+	// ALIBABA_CLOUD_ACCESS_KEY_ID/ALIBABA_CLOUD_ACCESS_KEY_SECRET or
+	// AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY/AWS_SESSION_TOKEN.
+	AccessKeyID     string `mapstructure:"accesskeyid,omitempty" json:"accesskeyid,omitempty"`
+	AccessKeySecret string `mapstructure:"accesskeysecret,omitempty" json:"accesskeysecret,omitempty"`
+	SessionToken    string `mapstructure:"sessiontoken,omitempty" json:"sessiontoken,omitempty"`
+
+	// Keys lists the KMS keys to bind. Their public keys are fetched once
+	// and cached, signing requests are the only remote calls.
+	Keys []KeyRef `mapstructure:"keys" json:"keys"`
+
+	// Timeout bounds each call to the service
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// KeyRef identifies a KMS key.
+type KeyRef struct {
+	// ID is the key id, ARN or alias
+	ID string `mapstructure:"id" json:"id"`
+	// Version is the key version id, required by Alibaba Cloud KMS
+	Version string `mapstructure:"version,omitempty" json:"version,omitempty"`
+}
+
+// KeyAttrKeyID is the key attribute holding the KMS key id.
+const KeyAttrKeyID = "kmskeyid"
+
+const defaultTimeout = 5 * time.Second
@@ -0,0 +1,190 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"os"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_kms")
+
+// New returns a new instance of the KMS-based BCCSP. The keys listed in
+// opts are bound at start up; signing with them is forwarded to the key
+// management service, all other operations are served by a software BCCSP
+// set at the passed security level, hash family and KeyStore.
+func New(opts KMSOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.Region == "" && opts.Endpoint == "" {
+		return nil, errors.New("Invalid options. Region or Endpoint must be set")
+	}
+
+	var c client
+	switch opts.Provider {
+	case Aliyun:
+		keyID, secret := credentials(opts.AccessKeyID, "ALIBABA_CLOUD_ACCESS_KEY_ID"), credentials(opts.AccessKeySecret, "ALIBABA_CLOUD_ACCESS_KEY_SECRET")
+		if keyID == "" || secret == "" {
+			return nil, errors.New("No Alibaba Cloud access key set")
+		}
+		c = newAliyunClient(opts, keyID, secret)
+	case AWS:
+		keyID, secret := credentials(opts.AccessKeyID, "AWS_ACCESS_KEY_ID"), credentials(opts.AccessKeySecret, "AWS_SECRET_ACCESS_KEY")
+		if keyID == "" || secret == "" {
+			return nil, errors.New("No AWS access key set")
+		}
+		c = newAWSClient(opts, keyID, secret, credentials(opts.SessionToken, "AWS_SESSION_TOKEN"))
+	default:
+		return nil, errors.Errorf("Unsupported KMS provider [%s]", opts.Provider)
+	}
+
+	return newWithClient(opts, keyStore, c)
+}
+
+func credentials(value, env string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(env)
+}
+
+func newWithClient(opts KMSOpts, keyStore bccsp.KeyStore, c client) (*impl, error) {
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	csp := &impl{
+		BCCSP:    swCSP,
+		client:   c,
+		provider: opts.Provider,
+		keys:     map[string]*kmsKey{},
+	}
+	for _, ref := range opts.Keys {
+		if err := csp.bindKey(ref); err != nil {
+			return nil, errors.Wrapf(err, "Failed binding KMS key [%s]", ref.ID)
+		}
+	}
+	return csp, nil
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	client   client
+	provider string
+
+	// keys maps the hex encoded SKI to the bound KMS keys. It is only
+	// written while the provider is constructed.
+	keys map[string]*kmsKey
+}
+
+// bindKey fetches and caches the public key of ref. The SKI is derived
+// the same way the software provider derives it from the public key.
+func (csp *impl) bindKey(ref KeyRef) error {
+	pub, err := csp.client.publicKey(ref)
+	if err != nil {
+		return err
+	}
+
+	var opts bccsp.KeyImportOpts
+	switch pub.(type) {
+	case *sm2.PublicKey:
+		opts = &bccsp.SM2GoPublicKeyImportOpts{Temporary: true}
+	case *ecdsa.PublicKey:
+		opts = &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true}
+	default:
+		return errors.Errorf("Unsupported public key type [%T]", pub)
+	}
+	pk, err := csp.BCCSP.KeyImport(pub, opts)
+	if err != nil {
+		return errors.Wrap(err, "Failed importing public key")
+	}
+
+	k := &kmsKey{ref: ref, provider: csp.provider, pub: pk, cryptoPub: pub}
+	csp.keys[hex.EncodeToString(pk.SKI())] = k
+	logger.Infof("Bound %s KMS key [%s], %s, SKI %x", csp.provider, ref.ID, curveName(pub), pk.SKI())
+	return nil
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	if k, ok := csp.keys[hex.EncodeToString(ski)]; ok {
+		return k, nil
+	}
+	return csp.BCCSP.GetKey(ski)
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	key, ok := k.(*kmsKey)
+	if !ok {
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+
+	switch pub := key.cryptoPub.(type) {
+	case *sm2.PublicKey:
+		za, err := utils.SM2ZA(pub, nil)
+		if err != nil {
+			return nil, err
+		}
+		h := sm3.New()
+		h.Write(za)
+		h.Write(digest)
+
+		sig, err := csp.client.sign(key.ref, pub, h.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		if bccsp.SM2SignatureEncodingOf(opts) == bccsp.SM2SignatureRaw {
+			return utils.SM2SignatureDERToRaw(sig)
+		}
+		return sig, nil
+	case *ecdsa.PublicKey:
+		sig, err := csp.client.sign(key.ref, pub, digest)
+		if err != nil {
+			return nil, err
+		}
+		return utils.SignatureToLowS(pub, sig)
+	default:
+		return nil, errors.Errorf("Unsupported public key type [%T]", key.cryptoPub)
+	}
+}
+
+// Verify verifies signature against key k and digest. Verification
+// always happens locally with the cached public key.
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if key, ok := k.(*kmsKey); ok {
+		k = key.pub
+	}
+	return csp.BCCSP.Verify(k, signature, digest, opts)
+}
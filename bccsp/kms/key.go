@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+import (
+	"crypto"
+	"errors"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+// kmsKey is a private key held by a key management service, described
+// by its cached public key.
+type kmsKey struct {
+	ref       KeyRef
+	provider  string
+	pub       bccsp.Key
+	cryptoPub crypto.PublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *kmsKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *kmsKey) SKI() []byte {
+	return k.pub.SKI()
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *kmsKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *kmsKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *kmsKey) PublicKey() (bccsp.Key, error) {
+	return k.pub, nil
+}
+
+// CryptoPublicKey returns the cached public key, an *ecdsa.PublicKey or an *sm2.PublicKey.
+func (k *kmsKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.cryptoPub, nil
+}
+
+// KeyAttributes returns the attributes of this key. KMS keys are never exportable.
+func (k *kmsKey) KeyAttributes() map[string]string {
+	return map[string]string{
+		bccsp.KeyAttrProvider:   "KMS/" + k.provider,
+		bccsp.KeyAttrExportable: "false",
+		KeyAttrKeyID:            k.ref.ID,
+	}
+}
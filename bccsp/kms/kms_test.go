@@ -0,0 +1,256 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliyunSignature(t *testing.T) {
+	// Example of the Alibaba Cloud RPC signature documentation
+	params := url.Values{}
+	params.Set("AccessKeyId", "testid")
+	params.Set("Action", "DescribeRegions")
+	params.Set("Format", "XML")
+	params.Set("SignatureMethod", "HMAC-SHA1")
+	params.Set("SignatureNonce", "3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf")
+	params.Set("SignatureVersion", "1.0")
+	params.Set("Timestamp", "2016-02-23T12:46:24Z")
+	params.Set("Version", "2014-05-26")
+
+	assert.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", aliyunSignature(http.MethodGet, params, "testsecret"))
+}
+
+func TestSignV4(t *testing.T) {
+	// Example of the AWS signature version 4 documentation
+	req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	signV4(req, nil, "iam", "us-east-1", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", now)
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-date, "+
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7", req.Header.Get("Authorization"))
+}
+
+// signSM2Digest signs e = SM3(Z_A || M) as a KMS does.
+func signSM2Digest(priv *sm2.PrivateKey, e []byte) ([]byte, error) {
+	n := priv.Curve.Params().N
+	one := big.NewInt(1)
+	for {
+		k, err := rand.Int(rand.Reader, new(big.Int).Sub(n, one))
+		if err != nil {
+			return nil, err
+		}
+		k.Add(k, one)
+		x1, _ := priv.Curve.ScalarBaseMult(k.Bytes())
+
+		r := new(big.Int).Add(new(big.Int).SetBytes(e), x1)
+		r.Mod(r, n)
+		if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+			continue
+		}
+
+		s := new(big.Int).Mul(r, priv.D)
+		s.Sub(k, s)
+		s.Mul(s, new(big.Int).ModInverse(new(big.Int).Add(priv.D, one), n))
+		s.Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+		return utils.MarshalECDSASignature(r, s)
+	}
+}
+
+func newAliyunServer(t *testing.T, priv *sm2.PrivateKey) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "key-id", r.Form.Get("KeyId"))
+		assert.Equal(t, "key-version", r.Form.Get("KeyVersionId"))
+
+		signature := r.Form.Get("Signature")
+		r.Form.Del("Signature")
+		assert.Equal(t, aliyunSignature(http.MethodPost, r.Form, "secret"), signature)
+
+		switch r.Form.Get("Action") {
+		case "GetPublicKey":
+			der, err := utils.MarshalPKIXSM2PublicKey(&priv.PublicKey)
+			require.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string]string{
+				"PublicKey": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+			})
+		case "AsymmetricSign":
+			assert.Equal(t, "SM2DSA", r.Form.Get("Algorithm"))
+			e, err := base64.StdEncoding.DecodeString(r.Form.Get("Digest"))
+			require.NoError(t, err)
+			sig, err := signSM2Digest(priv, e)
+			require.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string]string{"Value": base64.StdEncoding.EncodeToString(sig)})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"Code": "InvalidAction", "Message": "unknown action"})
+		}
+	}))
+}
+
+func TestAliyunSM2(t *testing.T) {
+	priv, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	server := newAliyunServer(t, priv)
+	defer server.Close()
+
+	opts := KMSOpts{
+		SecLevel:        256,
+		HashFamily:      "SM3",
+		Provider:        Aliyun,
+		Endpoint:        server.URL,
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+		Keys:            []KeyRef{{ID: "key-id", Version: "key-version"}},
+	}
+	csp, err := New(opts, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	ski := sm2SKI(t, &priv.PublicKey)
+	k, err := csp.GetKey(ski)
+	require.NoError(t, err)
+	assert.True(t, k.Private())
+	attrs := k.(bccsp.AttributedKey).KeyAttributes()
+	assert.Equal(t, "false", attrs[bccsp.KeyAttrExportable])
+	assert.Equal(t, "key-id", attrs[KeyAttrKeyID])
+
+	msg := []byte("hello kms")
+	sig, err := csp.Sign(k, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, sm2.Verify(&priv.PublicKey, nil, msg, sig))
+
+	valid, err := csp.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	raw, err := csp.Sign(k, msg, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureRaw})
+	require.NoError(t, err)
+	assert.Len(t, raw, 64)
+
+	opts.Keys = []KeyRef{{ID: "key-id"}}
+	_, err = New(opts, sw.NewDummyKeyStore())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Key version of [key-id] is required")
+}
+
+func sm2SKI(t *testing.T, pub *sm2.PublicKey) []byte {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	k, err := csp.KeyImport(pub, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	return k.SKI()
+}
+
+func TestAWSECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/kms/aws4_request")
+		assert.Equal(t, "token", r.Header.Get("X-Amz-Security-Token"))
+
+		var req struct {
+			KeyId            string
+			Message          []byte
+			MessageType      string
+			SigningAlgorithm string
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "alias/peer0", req.KeyId)
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GetPublicKey":
+			der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+			require.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string]interface{}{"PublicKey": der, "KeyUsage": "SIGN_VERIFY"})
+		case "TrentService.Sign":
+			assert.Equal(t, "DIGEST", req.MessageType)
+			assert.Equal(t, "ECDSA_SHA_256", req.SigningAlgorithm)
+			sigR, sigS, err := ecdsa.Sign(rand.Reader, priv, req.Message)
+			require.NoError(t, err)
+			// KMS does not normalize signatures
+			if ok, _ := utils.IsLowS(&priv.PublicKey, sigS); ok {
+				sigS.Sub(priv.Params().N, sigS)
+			}
+			sig, err := utils.MarshalECDSASignature(sigR, sigS)
+			require.NoError(t, err)
+			json.NewEncoder(w).Encode(map[string][]byte{"Signature": sig})
+		}
+	}))
+	defer server.Close()
+
+	csp, err := New(KMSOpts{
+		SecLevel:        256,
+		HashFamily:      "SHA2",
+		Provider:        AWS,
+		Region:          "eu-west-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+		SessionToken:    "token",
+		Keys:            []KeyRef{{ID: "alias/peer0"}},
+	}, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	pub, err := csp.KeyImport(&priv.PublicKey, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	k, err := csp.GetKey(pub.SKI())
+	require.NoError(t, err)
+	assert.IsType(t, &kmsKey{}, k)
+
+	digest := sha256.Sum256([]byte("hello kms"))
+	sig, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	_, s, err := utils.UnmarshalECDSASignature(sig)
+	require.NoError(t, err)
+	lowS, err := utils.IsLowS(&priv.PublicKey, s)
+	require.NoError(t, err)
+	assert.True(t, lowS)
+
+	valid, err := csp.Verify(k, sig, digest[:], nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestNewInvalidOpts(t *testing.T) {
+	_, err := New(KMSOpts{Provider: "GCP", Region: "x"}, sw.NewDummyKeyStore())
+	assert.EqualError(t, err, "Unsupported KMS provider [GCP]")
+
+	_, err = New(KMSOpts{Provider: AWS}, sw.NewDummyKeyStore())
+	assert.EqualError(t, err, "Invalid options. Region or Endpoint must be set")
+}
@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/miekg/pkcs11"
+)
+
+const defaultKeyCacheTTL = 10 * time.Minute
+
+// keyCacheEntry holds what was read from the token about a key: its public
+// part and the handles of its objects. A zero handle is not known yet.
+type keyCacheEntry struct {
+	pub     interface{}
+	isPriv  bool
+	handles [2]pkcs11.ObjectHandle
+	expires time.Time
+}
+
+// keyCache caches public keys and object handles by SKI, so that lookups
+// and verifications do not have to search the token every time. Entries
+// expire after ttl and are dropped when the token reports an error for
+// the key. A nil *keyCache caches nothing.
+type keyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mutex   sync.Mutex
+	entries map[string]*keyCacheEntry
+}
+
+func newKeyCache(ttl time.Duration) *keyCache {
+	if ttl < 0 {
+		return nil
+	}
+	if ttl == 0 {
+		ttl = defaultKeyCacheTTL
+	}
+	return &keyCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]*keyCacheEntry{},
+	}
+}
+
+// get returns a copy of the unexpired entry of ski.
+func (c *keyCache) get(ski []byte) (keyCacheEntry, bool) {
+	if c == nil {
+		return keyCacheEntry{}, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	id := hex.EncodeToString(ski)
+	e, ok := c.entries[id]
+	if ok && c.now().After(e.expires) {
+		delete(c.entries, id)
+		ok = false
+	}
+	if !ok {
+		return keyCacheEntry{}, false
+	}
+	return *e, true
+}
+
+// putKey records the public part of ski, keeping known handles.
+func (c *keyCache) putKey(ski []byte, pub interface{}, isPriv bool) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	e := c.entry(ski)
+	e.pub = pub
+	e.isPriv = isPriv
+}
+
+// putHandle records the handle of the public or private object of ski.
+func (c *keyCache) putHandle(ski []byte, kt keyType, handle pkcs11.ObjectHandle) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entry(ski).handles[kt] = handle
+}
+
+// entry returns the entry of ski, creating it if needed. The caller holds
+// the mutex.
+func (c *keyCache) entry(ski []byte) *keyCacheEntry {
+	id := hex.EncodeToString(ski)
+	e, ok := c.entries[id]
+	if !ok || c.now().After(e.expires) {
+		e = &keyCacheEntry{expires: c.now().Add(c.ttl)}
+		c.entries[id] = e
+	}
+	return e
+}
+
+// invalidate drops the entry of ski, or all entries if the error says the
+// token or the session itself went away.
+func (c *keyCache) invalidate(ski []byte, err error) {
+	if c == nil {
+		return
+	}
+	p11err, ok := err.(pkcs11.Error)
+	if !ok {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	switch p11err {
+	case pkcs11.CKR_DEVICE_ERROR, pkcs11.CKR_DEVICE_REMOVED, pkcs11.CKR_TOKEN_NOT_PRESENT,
+		pkcs11.CKR_SESSION_HANDLE_INVALID, pkcs11.CKR_CRYPTOKI_NOT_INITIALIZED:
+		c.entries = map[string]*keyCacheEntry{}
+	default:
+		delete(c.entries, hex.EncodeToString(ski))
+	}
+}
@@ -0,0 +1,109 @@
+// +build pkcs11
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package pkcs11
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/pkcs11"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyCacheExpiry(t *testing.T) {
+	now := time.Now()
+	c := newKeyCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	pub := &ecdsa.PublicKey{}
+	c.putKey([]byte{1}, pub, true)
+	c.putHandle([]byte{1}, publicKeyType, 7)
+
+	e, ok := c.get([]byte{1})
+	assert.True(t, ok)
+	assert.Equal(t, pub, e.pub)
+	assert.True(t, e.isPriv)
+	assert.Equal(t, pkcs11.ObjectHandle(7), e.handles[publicKeyType])
+	assert.Equal(t, pkcs11.ObjectHandle(0), e.handles[privateKeyType])
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.get([]byte{1})
+	assert.False(t, ok)
+}
+
+func TestKeyCacheInvalidate(t *testing.T) {
+	c := newKeyCache(0)
+	assert.Equal(t, defaultKeyCacheTTL, c.ttl)
+	c.putKey([]byte{1}, &ecdsa.PublicKey{}, false)
+	c.putKey([]byte{2}, &ecdsa.PublicKey{}, false)
+
+	// Errors not reported by the token keep the entry
+	c.invalidate([]byte{1}, errors.New("boom"))
+	_, ok := c.get([]byte{1})
+	assert.True(t, ok)
+
+	c.invalidate([]byte{1}, pkcs11.Error(pkcs11.CKR_KEY_HANDLE_INVALID))
+	_, ok = c.get([]byte{1})
+	assert.False(t, ok)
+	_, ok = c.get([]byte{2})
+	assert.True(t, ok)
+
+	c.invalidate([]byte{1}, pkcs11.Error(pkcs11.CKR_DEVICE_REMOVED))
+	_, ok = c.get([]byte{2})
+	assert.False(t, ok)
+}
+
+func TestKeyCacheDisabled(t *testing.T) {
+	c := newKeyCache(-1)
+	assert.Nil(t, c)
+
+	c.putKey([]byte{1}, &ecdsa.PublicKey{}, false)
+	c.putHandle([]byte{1}, publicKeyType, 7)
+	c.invalidate([]byte{1}, pkcs11.Error(pkcs11.CKR_KEY_HANDLE_INVALID))
+	_, ok := c.get([]byte{1})
+	assert.False(t, ok)
+}
+
+func TestGetKeyCached(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestGetKeyCached")
+	}
+	csp := currentBCCSP.(*impl)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("Hello World"))
+	_, err = csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+
+	e, ok := csp.keyCache.get(k.SKI())
+	require.True(t, ok)
+	assert.True(t, e.isPriv)
+	assert.NotEqual(t, pkcs11.ObjectHandle(0), e.handles[privateKeyType])
+
+	k2, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.True(t, k2.Private())
+	assert.Equal(t, k.SKI(), k2.SKI())
+
+	// A stale handle is dropped once the token rejects it
+	csp.keyCache.putHandle(k.SKI(), privateKeyType, 0x7ffffff0)
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.Error(t, err)
+	_, ok = csp.keyCache.get(k.SKI())
+	assert.False(t, ok)
+
+	_, err = csp.Sign(k, digest[:], nil)
+	assert.NoError(t, err)
+}
@@ -12,7 +12,10 @@ import (
 	"encoding/asn1"
 	"fmt"
 	"hash"
+	"time"
 
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/paul-lee-attorney/gm/sm3"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -28,6 +31,8 @@ func (conf *config) setSecurityLevel(securityLevel int, hashFamily string) (err
 		err = conf.setSecurityLevelSHA2(securityLevel)
 	case "SHA3":
 		err = conf.setSecurityLevelSHA3(securityLevel)
+	case "SM3":
+		err = conf.setSecurityLevelSM3(securityLevel)
 	default:
 		err = fmt.Errorf("Hash Family not supported [%s]", hashFamily)
 	}
@@ -66,6 +71,19 @@ func (conf *config) setSecurityLevelSHA3(level int) (err error) {
 	return
 }
 
+// ECDSA keys keep using P-256, SM2 keys always use the SM2 recommended curve.
+func (conf *config) setSecurityLevelSM3(level int) (err error) {
+	switch level {
+	case 256:
+		conf.ellipticCurve = oidNamedCurveP256
+		conf.hashFunction = sm3.New
+		conf.aesBitLength = 16
+	default:
+		err = fmt.Errorf("Security level not supported [%d]", level)
+	}
+	return
+}
+
 // PKCS11Opts contains options for the P11Factory
 type PKCS11Opts struct {
 	// Default algorithms when not specified (Deprecated?)
@@ -81,4 +99,69 @@ type PKCS11Opts struct {
 	Pin        string `mapstructure:"pin" json:"pin"`
 	SoftVerify bool   `mapstructure:"softwareverify,omitempty" json:"softwareverify,omitempty"`
 	Immutable  bool   `mapstructure:"immutable,omitempty" json:"immutable,omitempty"`
+
+	// NonExportable requires signing keys to be generated on the token and
+	// checks that the token marked their private part as non-extractable
+	// and sensitive, instead of falling back to software keys.
+	NonExportable bool `mapstructure:"nonexportable,omitempty" json:"nonexportable,omitempty"`
+
+	// Session pool options. MaxSessions bounds the number of sessions in
+	// use at once, a caller waits up to SessionWaitTimeout for a free one.
+	// Failed logins are retried LoginRetries times, LoginRetryInterval apart.
+	MaxSessions        int           `mapstructure:"maxsessions,omitempty" json:"maxsessions,omitempty"`
+	SessionWaitTimeout time.Duration `mapstructure:"sessionwaittimeout,omitempty" json:"sessionwaittimeout,omitempty"`
+	LoginRetries       int           `mapstructure:"loginretries,omitempty" json:"loginretries,omitempty"`
+	LoginRetryInterval time.Duration `mapstructure:"loginretryinterval,omitempty" json:"loginretryinterval,omitempty"`
+
+	// KeyCacheTTL is how long public keys and object handles read from
+	// the token are cached, 10 minutes by default. A negative value
+	// disables the cache.
+	KeyCacheTTL time.Duration `mapstructure:"keycachettl,omitempty" json:"keycachettl,omitempty"`
+
+	// KeyIDs maps the SKIs of pre-provisioned keys to the objects holding
+	// them on the token, for keys whose CKA_ID is not their SKI.
+	KeyIDs []KeyIDMapping `mapstructure:"keyids,omitempty" json:"keyids,omitempty"`
+
+	// SKIConvention names the derivation of the SKIs of keys, as parsed by
+	// utils.ParseSKIConvention. It sets the CKA_ID of generated keys. The
+	// convention of the process applies if empty.
+	SKIConvention string `mapstructure:"skiconvention,omitempty" json:"skiconvention,omitempty"`
+
+	// MetricsProvider receives the session pool metrics, they are disabled if nil
+	MetricsProvider metrics.Provider `json:"-" yaml:"-"`
+
+	// GM mechanism options
+	GM *GMOpts `mapstructure:"gm,omitempty" json:"gm,omitempty"`
+}
+
+// KeyIDMapping maps the hex encoded SKI of a key to the hex encoded CKA_ID
+// or, if ID is empty, the CKA_LABEL of its objects on the token.
+type KeyIDMapping struct {
+	SKI   string `mapstructure:"ski" json:"ski"`
+	ID    string `mapstructure:"id,omitempty" json:"id,omitempty"`
+	Label string `mapstructure:"label,omitempty" json:"label,omitempty"`
+}
+
+// GMOpts selects the vendor profile of the token and overrides the
+// vendor-defined identifiers it uses for the SM2, SM3 and SM4 key types
+// and mechanisms. Zero values select the values of the profile.
+type GMOpts struct {
+	// Vendor names a profile registered with RegisterVendor, DEFAULT if empty
+	Vendor string `mapstructure:"vendor,omitempty" json:"vendor,omitempty"`
+
+	SM2KeyType    uint `mapstructure:"sm2keytype,omitempty" json:"sm2keytype,omitempty"`
+	SM4KeyType    uint `mapstructure:"sm4keytype,omitempty" json:"sm4keytype,omitempty"`
+	SM2KeyPairGen uint `mapstructure:"sm2keypairgen,omitempty" json:"sm2keypairgen,omitempty"`
+	SM2           uint `mapstructure:"sm2,omitempty" json:"sm2,omitempty"`
+	SM3           uint `mapstructure:"sm3,omitempty" json:"sm3,omitempty"`
+	SM4KeyGen     uint `mapstructure:"sm4keygen,omitempty" json:"sm4keygen,omitempty"`
+	SM4CBC        uint `mapstructure:"sm4cbc,omitempty" json:"sm4cbc,omitempty"`
+
+	// SM2 parameter encodings, see VendorProfile
+	SM2Input           string `mapstructure:"sm2input,omitempty" json:"sm2input,omitempty"`
+	SM2SignatureFormat string `mapstructure:"sm2signatureformat,omitempty" json:"sm2signatureformat,omitempty"`
+	SM2UserIDParam     bool   `mapstructure:"sm2useridparam,omitempty" json:"sm2useridparam,omitempty"`
+
+	// HashOnToken computes SM3 digests on the token instead of in software.
+	HashOnToken bool `mapstructure:"hashontoken,omitempty" json:"hashontoken,omitempty"`
 }
@@ -0,0 +1,224 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/miekg/pkcs11"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+)
+
+// DiscoveredKey describes an EC or SM2 key pair found on a token.
+type DiscoveredKey struct {
+	// SKI is the identifier Fabric derives from the public key
+	SKI []byte
+	// ID and Label are the CKA_ID and CKA_LABEL of the public key object
+	ID    []byte
+	Label string
+	// Algorithm is bccsp.SM2 or bccsp.ECDSA
+	Algorithm string
+	// Private reports whether the matching private key object was found
+	Private bool
+}
+
+// NeedsMapping reports whether the key cannot be found by its SKI alone,
+// because its CKA_ID is not the SKI.
+func (k *DiscoveredKey) NeedsMapping() bool {
+	return !bytes.Equal(k.ID, k.SKI)
+}
+
+// Mapping returns the key ID mapping locating the key by its CKA_ID or,
+// for objects without one, by its CKA_LABEL.
+func (k *DiscoveredKey) Mapping() KeyIDMapping {
+	m := KeyIDMapping{SKI: hex.EncodeToString(k.SKI)}
+	if len(k.ID) != 0 {
+		m.ID = hex.EncodeToString(k.ID)
+	} else {
+		m.Label = k.Label
+	}
+	return m
+}
+
+// DiscoverKeys lists the EC and SM2 public key objects on the token
+// selected by opts, along with the SKIs Fabric computes for them. It
+// finalizes the PKCS#11 library when done, and is meant for tools rather
+// than processes that keep a provider open.
+func DiscoverKeys(opts PKCS11Opts) ([]*DiscoveredKey, error) {
+	skiConvention := utils.GetSKIConvention()
+	if opts.SKIConvention != "" {
+		var err error
+		if skiConvention, err = utils.ParseSKIConvention(opts.SKIConvention); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, _, session, err := loadLib(opts.Library, opts.Pin, opts.Label)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		ctx.Logout(*session)
+		ctx.CloseSession(*session)
+		ctx.Finalize()
+		ctx.Destroy()
+	}()
+
+	return discoverKeys(ctx, *session, skiConvention)
+}
+
+func (csp *impl) discoverKeys() ([]*DiscoveredKey, error) {
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(session)
+
+	return discoverKeys(csp.ctx, session, csp.skiConvention)
+}
+
+func discoverKeys(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, c utils.SKIConvention) ([]*DiscoveredKey, error) {
+	objs, err := findObjects(ctx, session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*DiscoveredKey
+	for _, obj := range objs {
+		k, err := describeKey(ctx, session, obj, c)
+		if err != nil {
+			logger.Debugf("Skipping object [%d]: %s", obj, err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func describeKey(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, obj pkcs11.ObjectHandle, c utils.SKIConvention) (*DiscoveredKey, error) {
+	ecpt, marshaledOid, err := ecPoint(ctx, session, obj)
+	if err != nil {
+		return nil, err
+	}
+	curveOid := new(asn1.ObjectIdentifier)
+	if _, err := asn1.Unmarshal(marshaledOid, curveOid); err != nil {
+		return nil, fmt.Errorf("Failed Unmarshaling Curve OID [%s]", err)
+	}
+
+	k := &DiscoveredKey{}
+	// SKIs are computed the same way as for keys generated by the provider
+	k.SKI, k.Algorithm, err = pointSKI(ecpt, *curveOid, c)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := ctx.GetAttributeValue(session, obj, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ID, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, nil),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("PKCS11: get(ID, label) [%s]", err)
+	}
+	for _, a := range attrs {
+		switch a.Type {
+		case pkcs11.CKA_ID:
+			k.ID = a.Value
+		case pkcs11.CKA_LABEL:
+			k.Label = string(a.Value)
+		}
+	}
+
+	id := pkcs11.NewAttribute(pkcs11.CKA_ID, k.ID)
+	if len(k.ID) == 0 {
+		id = pkcs11.NewAttribute(pkcs11.CKA_LABEL, k.Label)
+	}
+	if _, err := findKeyObject(ctx, session, id, privateKeyType); err == nil {
+		k.Private = true
+	}
+	return k, nil
+}
+
+// pointSKI returns the SKI of the public point ecpt on the curve of
+// curveOid, with the SKI convention c, and the algorithm of the key.
+func pointSKI(ecpt []byte, curveOid asn1.ObjectIdentifier, c utils.SKIConvention) ([]byte, string, error) {
+	if curveOid.Equal(utils.OIDNamedCurveSM2) {
+		pub, err := sm2PublicKeyFromPoint(ecpt)
+		if err != nil {
+			return nil, "", err
+		}
+		ski, err := utils.ComputeSKI(pub, c)
+		return ski, bccsp.SM2, err
+	}
+
+	curve := namedCurveFromOID(curveOid)
+	if curve == nil {
+		return nil, "", fmt.Errorf("Unsupported curve [%v]", curveOid)
+	}
+	x, y := elliptic.Unmarshal(curve, ecpt)
+	if x == nil {
+		return nil, "", fmt.Errorf("Failed Unmarshaling Public Key")
+	}
+	ski, err := utils.ComputeSKI(&ecdsa.PublicKey{Curve: curve, X: x, Y: y}, c)
+	return ski, bccsp.ECDSA, err
+}
+
+func findObjects(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, template []*pkcs11.Attribute) ([]pkcs11.ObjectHandle, error) {
+	if err := ctx.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+
+	var objs []pkcs11.ObjectHandle
+	for {
+		batch, _, err := ctx.FindObjects(session, 64)
+		if err != nil {
+			ctx.FindObjectsFinal(session)
+			return nil, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		objs = append(objs, batch...)
+	}
+	if err := ctx.FindObjectsFinal(session); err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// parseKeyIDs turns the configured key ID mappings into the attributes
+// locating the key objects, indexed by hex encoded SKI.
+func parseKeyIDs(mappings []KeyIDMapping) (map[string]*pkcs11.Attribute, error) {
+	keyIDs := map[string]*pkcs11.Attribute{}
+	for _, m := range mappings {
+		ski, err := hex.DecodeString(m.SKI)
+		if err != nil || len(ski) == 0 {
+			return nil, fmt.Errorf("Invalid SKI [%s]", m.SKI)
+		}
+
+		switch {
+		case m.ID != "":
+			id, err := hex.DecodeString(m.ID)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid ID [%s] for SKI [%s]", m.ID, m.SKI)
+			}
+			keyIDs[hex.EncodeToString(ski)] = pkcs11.NewAttribute(pkcs11.CKA_ID, id)
+		case m.Label != "":
+			keyIDs[hex.EncodeToString(ski)] = pkcs11.NewAttribute(pkcs11.CKA_LABEL, m.Label)
+		default:
+			return nil, fmt.Errorf("Neither ID nor label set for SKI [%s]", m.SKI)
+		}
+	}
+	return keyIDs, nil
+}
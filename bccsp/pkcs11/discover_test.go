@@ -0,0 +1,99 @@
+// +build pkcs11
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+package pkcs11
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyIDs(t *testing.T) {
+	keyIDs, err := parseKeyIDs([]KeyIDMapping{
+		{SKI: "0102", ID: "6b6579"},
+		{SKI: "0304", Label: "signing key"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, pkcs11.NewAttribute(pkcs11.CKA_ID, []byte("key")), keyIDs["0102"])
+	assert.Equal(t, pkcs11.NewAttribute(pkcs11.CKA_LABEL, "signing key"), keyIDs["0304"])
+
+	_, err = parseKeyIDs([]KeyIDMapping{{SKI: "zz", ID: "01"}})
+	assert.EqualError(t, err, "Invalid SKI [zz]")
+	_, err = parseKeyIDs([]KeyIDMapping{{SKI: "01", ID: "zz"}})
+	assert.EqualError(t, err, "Invalid ID [zz] for SKI [01]")
+	_, err = parseKeyIDs([]KeyIDMapping{{SKI: "01"}})
+	assert.EqualError(t, err, "Neither ID nor label set for SKI [01]")
+}
+
+func TestDiscoverAndMapKeys(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestDiscoverAndMapKeys")
+	}
+	csp := currentBCCSP.(*impl)
+
+	ski, _, err := csp.generateECKey(oidNamedCurveP256, false)
+	require.NoError(t, err)
+
+	session, err := csp.getSession()
+	require.NoError(t, err)
+	defer csp.returnSession(session)
+	pub, err := findKeyPairFromSKI(csp.ctx, session, ski, publicKeyType)
+	require.NoError(t, err)
+	prv, err := findKeyPairFromSKI(csp.ctx, session, ski, privateKeyType)
+	require.NoError(t, err)
+	defer func() {
+		csp.ctx.DestroyObject(session, *pub)
+		csp.ctx.DestroyObject(session, *prv)
+	}()
+
+	find := func() *DiscoveredKey {
+		keys, err := csp.discoverKeys()
+		require.NoError(t, err)
+		for _, k := range keys {
+			if bytes.Equal(k.SKI, ski) {
+				return k
+			}
+		}
+		return nil
+	}
+
+	k := find()
+	require.NotNil(t, k)
+	assert.Equal(t, bccsp.ECDSA, k.Algorithm)
+	assert.True(t, k.Private)
+	assert.False(t, k.NeedsMapping())
+
+	// Simulate a pre-provisioned key whose CKA_ID is not its SKI
+	legacyID := []byte("legacy-key")
+	setID := []*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_ID, legacyID)}
+	require.NoError(t, csp.ctx.SetAttributeValue(session, *pub, setID))
+	require.NoError(t, csp.ctx.SetAttributeValue(session, *prv, setID))
+
+	k = find()
+	require.NotNil(t, k)
+	assert.True(t, k.NeedsMapping())
+	assert.Equal(t, KeyIDMapping{SKI: hex.EncodeToString(ski), ID: hex.EncodeToString(legacyID)}, k.Mapping())
+
+	_, _, err = csp.getECKey(ski)
+	assert.Error(t, err)
+
+	keyIDs, err := parseKeyIDs([]KeyIDMapping{k.Mapping()})
+	require.NoError(t, err)
+	oldKeyIDs := csp.keyIDs
+	csp.keyIDs = keyIDs
+	defer func() { csp.keyIDs = oldKeyIDs }()
+
+	_, isPriv, err := csp.getECKey(ski)
+	assert.NoError(t, err)
+	assert.True(t, isPriv)
+}
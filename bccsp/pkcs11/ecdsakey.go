@@ -16,6 +16,7 @@ limitations under the License.
 package pkcs11
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/x509"
 	"errors"
@@ -95,3 +96,22 @@ func (k *ecdsaPublicKey) Private() bool {
 func (k *ecdsaPublicKey) PublicKey() (bccsp.Key, error) {
 	return k, nil
 }
+
+// CryptoPublicKey returns the public part of this key as *ecdsa.PublicKey.
+func (k *ecdsaPrivateKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pub.pub, nil
+}
+
+// CryptoPublicKey returns this key as *ecdsa.PublicKey.
+func (k *ecdsaPublicKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pub, nil
+}
+
+// KeyAttributes returns the attributes of this key. Keys held by the
+// token never leave it, so they are reported as non-exportable.
+func (k *ecdsaPrivateKey) KeyAttributes() map[string]string {
+	return map[string]string{
+		bccsp.KeyAttrProvider:   "PKCS11",
+		bccsp.KeyAttrExportable: "false",
+	}
+}
@@ -0,0 +1,611 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/miekg/pkcs11"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// PKCS#11 does not standardise the GM algorithms, so HSM vendors assign
+// their key types and mechanisms from the vendor-defined range. The
+// defaults below form the DEFAULT vendor profile, other vendors are
+// selected and overridden through GMOpts.
+const (
+	defaultCKKSM2 = pkcs11.CKK_VENDOR_DEFINED + 0x00000002
+	defaultCKKSM4 = pkcs11.CKK_VENDOR_DEFINED + 0x00000106
+
+	defaultCKMSM2KeyPairGen = pkcs11.CKM_VENDOR_DEFINED + 0x00010001
+	defaultCKMSM2           = pkcs11.CKM_VENDOR_DEFINED + 0x00010003
+	defaultCKMSM3           = pkcs11.CKM_VENDOR_DEFINED + 0x00020001
+	defaultCKMSM4KeyGen     = pkcs11.CKM_VENDOR_DEFINED + 0x00040001
+	defaultCKMSM4CBC        = pkcs11.CKM_VENDOR_DEFINED + 0x00040003
+)
+
+// sm4BlockSize is the block size of SM4 in bytes.
+const sm4BlockSize = 16
+
+// gmMechanisms holds the resolved GM identifiers of the token and
+// which of the GM algorithms the token actually offers.
+type gmMechanisms struct {
+	sm2KeyType    uint
+	sm4KeyType    uint
+	sm2KeyPairGen uint
+	sm2           uint
+	sm3           uint
+	sm4KeyGen     uint
+	sm4CBC        uint
+
+	sm2Input       string
+	sm2SigFormat   string
+	sm2UserIDParam bool
+
+	hashOnToken bool
+
+	sm2Supported bool
+	sm3Supported bool
+	sm4Supported bool
+}
+
+func orDefault(v, def uint) uint {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+func newGMMechanisms(opts *GMOpts) (*gmMechanisms, error) {
+	if opts == nil {
+		opts = &GMOpts{}
+	}
+	profile, err := lookupVendor(opts.Vendor)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &gmMechanisms{
+		sm2KeyType:     orDefault(opts.SM2KeyType, profile.SM2KeyType),
+		sm4KeyType:     orDefault(opts.SM4KeyType, profile.SM4KeyType),
+		sm2KeyPairGen:  orDefault(opts.SM2KeyPairGen, profile.SM2KeyPairGen),
+		sm2:            orDefault(opts.SM2, profile.SM2),
+		sm3:            orDefault(opts.SM3, profile.SM3),
+		sm4KeyGen:      orDefault(opts.SM4KeyGen, profile.SM4KeyGen),
+		sm4CBC:         orDefault(opts.SM4CBC, profile.SM4CBC),
+		sm2Input:       profile.SM2Input,
+		sm2SigFormat:   profile.SM2SignatureFormat,
+		sm2UserIDParam: profile.SM2UserIDParam || opts.SM2UserIDParam,
+		hashOnToken:    opts.HashOnToken,
+	}
+	if opts.SM2Input != "" {
+		m.sm2Input = opts.SM2Input
+	}
+	if opts.SM2SignatureFormat != "" {
+		m.sm2SigFormat = opts.SM2SignatureFormat
+	}
+
+	check := VendorProfile{SM2Input: m.sm2Input, SM2SignatureFormat: m.sm2SigFormat}
+	if err := check.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// sm2Mechanism returns the SM2 signing mechanism with the parameter the
+// vendor expects.
+func (m *gmMechanisms) sm2Mechanism() *pkcs11.Mechanism {
+	if m.sm2Input == SM2InputMessage && m.sm2UserIDParam {
+		return pkcs11.NewMechanism(m.sm2, sm2DefaultUID)
+	}
+	return pkcs11.NewMechanism(m.sm2, nil)
+}
+
+// sm2SignInput returns what the SM2 mechanism is passed to sign or verify
+// msg: msg itself, or e = SM3(Z_A || msg).
+func (m *gmMechanisms) sm2SignInput(pub *sm2.PublicKey, msg []byte) ([]byte, error) {
+	if m.sm2Input == SM2InputMessage {
+		return msg, nil
+	}
+	return sm2Digest(pub, msg)
+}
+
+func (m *gmMechanisms) marshalSM2Signature(r, s *big.Int) ([]byte, error) {
+	if m.sm2SigFormat == SM2SignatureFormatDER {
+		return utils.MarshalECDSASignature(r, s)
+	}
+	return utils.MarshalSM2RawSignature(r, s)
+}
+
+func (m *gmMechanisms) unmarshalSM2Signature(sig []byte) (r, s *big.Int, err error) {
+	if m.sm2SigFormat == SM2SignatureFormatDER {
+		return utils.UnmarshalECDSASignature(sig)
+	}
+	return utils.UnmarshalSM2RawSignature(sig)
+}
+
+// probe queries the mechanisms of slot. A token that cannot list its
+// mechanisms is treated as offering none of the GM algorithms, so that
+// the corresponding operations are served by the software fallback.
+func (m *gmMechanisms) probe(ctx *pkcs11.Ctx, slot uint) {
+	mechs, err := ctx.GetMechanismList(slot)
+	if err != nil {
+		logger.Warningf("Could not get mechanism list of slot %d [%s], GM mechanisms disabled", slot, err)
+		mechs = nil
+	}
+	m.setSupported(mechs)
+	logger.Infof("PKCS11 GM mechanisms on slot %d: SM2 [%t], SM3 [%t], SM4 [%t]",
+		slot, m.sm2Supported, m.sm3Supported, m.sm4Supported)
+}
+
+func (m *gmMechanisms) setSupported(mechs []*pkcs11.Mechanism) {
+	available := make(map[uint]bool, len(mechs))
+	for _, mech := range mechs {
+		available[mech.Mechanism] = true
+	}
+	m.sm2Supported = available[m.sm2KeyPairGen] && available[m.sm2]
+	m.sm3Supported = available[m.sm3]
+	m.sm4Supported = available[m.sm4KeyGen] && available[m.sm4CBC]
+}
+
+// restrict removes from c, the capabilities of the software fallback, what
+// the token makes unavailable. Without the SM2 mechanisms, SM2 signing keys
+// are generated in software, which nonExportable forbids. With the SM4
+// mechanisms, SM4 keys are generated on the token, which only encrypts in
+// CBC mode.
+func (m *gmMechanisms) restrict(c *bccsp.Capabilities, nonExportable bool) {
+	if !m.sm2Supported && nonExportable {
+		c.SignatureAlgorithms = without(c.SignatureAlgorithms, bccsp.SM2)
+	}
+	if m.sm4Supported {
+		c.CipherModes = without(c.CipherModes, bccsp.CipherModeSM4, bccsp.CipherModeSM4GCM)
+		c.CipherModes = append(c.CipherModes, bccsp.CipherModeSM4CBCPKCS7)
+		sort.Strings(c.CipherModes)
+	}
+}
+
+func without(list []string, values ...string) []string {
+	var kept []string
+	for _, l := range list {
+		found := false
+		for _, v := range values {
+			if l == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// sm2OnTokenCurve reports whether the passed options select the curve the
+// token generates SM2 keys on.
+func sm2OnTokenCurve(curve elliptic.Curve, oid asn1.ObjectIdentifier) bool {
+	if curve != nil {
+		return curve == sm2.GetSm2P256V1()
+	}
+	return len(oid) == 0 || oid.Equal(utils.OIDNamedCurveSM2)
+}
+
+func (csp *impl) generateSM2Key(ephemeral bool) (ski []byte, pubKey *sm2.PublicKey, err error) {
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer csp.returnSession(session)
+
+	id := nextIDCtr()
+	publabel := fmt.Sprintf("BCPUB%s", id.Text(16))
+	prvlabel := fmt.Sprintf("BCPRV%s", id.Text(16))
+
+	marshaledOID, err := asn1.Marshal(utils.OIDNamedCurveSM2)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Could not marshal OID [%s]", err.Error())
+	}
+
+	pubkeyT := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, csp.gm.sm2KeyType),
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PUBLIC_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, !ephemeral),
+		pkcs11.NewAttribute(pkcs11.CKA_VERIFY, true),
+		pkcs11.NewAttribute(pkcs11.CKA_EC_PARAMS, marshaledOID),
+
+		pkcs11.NewAttribute(pkcs11.CKA_ID, publabel),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, publabel),
+	}
+
+	prvkeyT := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, csp.gm.sm2KeyType),
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, !ephemeral),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_SIGN, true),
+
+		pkcs11.NewAttribute(pkcs11.CKA_ID, prvlabel),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, prvlabel),
+
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+	}
+
+	pub, prv, err := p11lib.GenerateKeyPair(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(csp.gm.sm2KeyPairGen, nil)},
+		pubkeyT, prvkeyT)
+	if err != nil {
+		return nil, nil, fmt.Errorf("P11: SM2 keypair generate failed [%s]", err)
+	}
+	if csp.nonExportable {
+		if err := enforceNonExportable(p11lib, session, pub, prv); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ecpt, _, err := ecPoint(p11lib, session, pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error querying EC-point: [%s]", err)
+	}
+
+	// SM2的SKI与软件实现保持一致, 默认为公钥点的SM3摘要
+	ski, _, err = pointSKI(ecpt, utils.OIDNamedCurveSM2, csp.skiConvention)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// set CKA_ID of the both keys to SKI(public key) and CKA_LABEL to hex string of SKI
+	setskiT := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ID, ski),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, hex.EncodeToString(ski)),
+	}
+
+	logger.Infof("Generated new P11 SM2 key, SKI %x\n", ski)
+	err = p11lib.SetAttributeValue(session, pub, setskiT)
+	if err != nil {
+		return nil, nil, fmt.Errorf("P11: set-ID-to-SKI[public] failed [%s]", err)
+	}
+
+	err = p11lib.SetAttributeValue(session, prv, setskiT)
+	if err != nil {
+		return nil, nil, fmt.Errorf("P11: set-ID-to-SKI[private] failed [%s]", err)
+	}
+
+	pubKey, err = sm2PublicKeyFromPoint(ecpt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ski, pubKey, nil
+}
+
+func sm2PublicKeyFromPoint(ecpt []byte) (*sm2.PublicKey, error) {
+	curve := sm2.GetSm2P256V1()
+	x, y := elliptic.Unmarshal(curve, ecpt)
+	if x == nil {
+		return nil, fmt.Errorf("Failed Unmarshaling Public Key")
+	}
+	return &sm2.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// Look for an SM2 key by SKI, stored in CKA_ID
+func (csp *impl) getSM2Key(ski []byte) (pubKey *sm2.PublicKey, isPriv bool, err error) {
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, false, err
+	}
+	defer csp.returnSession(session)
+	isPriv = true
+	_, err = csp.findKeyPair(session, ski, privateKeyType)
+	if err != nil {
+		isPriv = false
+		logger.Debugf("Private key not found [%s] for SKI [%s], looking for Public key", err, hex.EncodeToString(ski))
+	}
+
+	publicKey, err := csp.findKeyPair(session, ski, publicKeyType)
+	if err != nil {
+		return nil, false, fmt.Errorf("Public key not found [%s] for SKI [%s]", err, hex.EncodeToString(ski))
+	}
+
+	ecpt, marshaledOid, err := ecPoint(p11lib, session, *publicKey)
+	if err != nil {
+		return nil, false, fmt.Errorf("Public key not found [%s] for SKI [%s]", err, hex.EncodeToString(ski))
+	}
+
+	curveOid := new(asn1.ObjectIdentifier)
+	_, err = asn1.Unmarshal(marshaledOid, curveOid)
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed Unmarshaling Curve OID [%s]\n%s", err.Error(), hex.EncodeToString(marshaledOid))
+	}
+	if !curveOid.Equal(utils.OIDNamedCurveSM2) {
+		return nil, false, fmt.Errorf("Key [%s] is not an SM2 key", hex.EncodeToString(ski))
+	}
+
+	pubKey, err = sm2PublicKeyFromPoint(ecpt)
+	if err != nil {
+		return nil, false, err
+	}
+	csp.keyCache.putKey(ski, pubKey, isPriv)
+	return pubKey, isPriv, nil
+}
+
+// sm2Digest computes e = SM3(Z_A || msg), the value signed by the
+// raw SM2 mechanism of the token.
+func sm2Digest(pub *sm2.PublicKey, msg []byte) ([]byte, error) {
+	za, err := utils.SM2ZA(pub, nil)
+	if err != nil {
+		return nil, err
+	}
+	h := sm3.New()
+	h.Write(za)
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (csp *impl) signP11SM2(ski []byte, e []byte) (R, S *big.Int, err error) {
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer csp.returnSession(session)
+
+	privateKey, err := csp.findKeyPair(session, ski, privateKeyType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Private key not found [%s]", err)
+	}
+
+	err = p11lib.SignInit(session, []*pkcs11.Mechanism{csp.gm.sm2Mechanism()}, *privateKey)
+	if err != nil {
+		csp.keyCache.invalidate(ski, err)
+		return nil, nil, fmt.Errorf("Sign-initialize  failed [%s]", err)
+	}
+
+	sig, err := p11lib.Sign(session, e)
+	if err != nil {
+		csp.keyCache.invalidate(ski, err)
+		return nil, nil, fmt.Errorf("P11: sign failed [%s]", err)
+	}
+
+	return utils.UnmarshalSM2RawSignature(sig)
+}
+
+func (csp *impl) verifyP11SM2(ski []byte, e []byte, R, S *big.Int) (bool, error) {
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return false, err
+	}
+	defer csp.returnSession(session)
+
+	logger.Debugf("Verify SM2\n")
+
+	publicKey, err := csp.findKeyPair(session, ski, publicKeyType)
+	if err != nil {
+		return false, fmt.Errorf("Public key not found [%s]", err)
+	}
+
+	sig, err := csp.gm.marshalSM2Signature(R, S)
+	if err != nil {
+		return false, err
+	}
+
+	err = p11lib.VerifyInit(session, []*pkcs11.Mechanism{csp.gm.sm2Mechanism()},
+		*publicKey)
+	if err != nil {
+		csp.keyCache.invalidate(ski, err)
+		return false, fmt.Errorf("PKCS11: Verify-initialize [%s]", err)
+	}
+	err = p11lib.Verify(session, e, sig)
+	if err == pkcs11.Error(pkcs11.CKR_SIGNATURE_INVALID) {
+		return false, nil
+	}
+	if err != nil {
+		csp.keyCache.invalidate(ski, err)
+		return false, fmt.Errorf("PKCS11: Verify failed [%s]", err)
+	}
+
+	return true, nil
+}
+
+func (csp *impl) hashP11SM3(msg []byte) ([]byte, error) {
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(session)
+
+	err = p11lib.DigestInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(csp.gm.sm3, nil)})
+	if err != nil {
+		return nil, fmt.Errorf("PKCS11: Digest-initialize [%s]", err)
+	}
+	digest, err := p11lib.Digest(session, msg)
+	if err != nil {
+		return nil, fmt.Errorf("PKCS11: Digest failed [%s]", err)
+	}
+	return digest, nil
+}
+
+// generateSM4Key generates an SM4 secret key on the token. Since the
+// key material never leaves the token, the SKI is a random identifier
+// drawn from the token and stored in CKA_ID.
+func (csp *impl) generateSM4Key(ephemeral bool) (ski []byte, err error) {
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(session)
+
+	ski, err = p11lib.GenerateRandom(session, 32)
+	if err != nil {
+		return nil, fmt.Errorf("P11: generate SKI failed [%s]", err)
+	}
+
+	keyT := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, csp.gm.sm4KeyType),
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, !ephemeral),
+		pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_ENCRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_DECRYPT, true),
+		pkcs11.NewAttribute(pkcs11.CKA_VALUE_LEN, sm4BlockSize),
+
+		pkcs11.NewAttribute(pkcs11.CKA_ID, ski),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, hex.EncodeToString(ski)),
+
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, false),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, true),
+		pkcs11.NewAttribute(pkcs11.CKA_MODIFIABLE, !csp.immutable),
+	}
+
+	_, err = p11lib.GenerateKey(session,
+		[]*pkcs11.Mechanism{pkcs11.NewMechanism(csp.gm.sm4KeyGen, nil)}, keyT)
+	if err != nil {
+		return nil, fmt.Errorf("P11: SM4 key generate failed [%s]", err)
+	}
+
+	logger.Infof("Generated new P11 SM4 key, SKI %x\n", ski)
+	return ski, nil
+}
+
+func findSecretKeyFromSKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byte) (*pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_SECRET_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_ID, ski),
+	}
+	if err := mod.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+
+	objs, _, err := mod.FindObjects(session, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err = mod.FindObjectsFinal(session); err != nil {
+		return nil, err
+	}
+
+	if len(objs) == 0 {
+		return nil, fmt.Errorf("Key not found [%s]", hex.Dump(ski))
+	}
+
+	return &objs[0], nil
+}
+
+// checkP11SM4Opts returns an error unless opts is nil. The token offers
+// SM4 in CBC mode only: honouring neither AEADOpts nor the modes of the
+// software provider, it must not return ciphertexts they cannot open.
+func checkP11SM4Opts(opts interface{}) error {
+	if opts == nil {
+		return nil
+	}
+	return bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported options [%T] for an SM4 key on the token. It only encrypts in CBC mode with PKCS#7 padding, with nil options", opts)
+}
+
+// encryptP11SM4 encrypts plaintext in CBC mode with PKCS#7 padding.
+// The random IV is drawn from the token and prepended to the ciphertext.
+func (csp *impl) encryptP11SM4(ski []byte, plaintext []byte) ([]byte, error) {
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(session)
+
+	key, err := findSecretKeyFromSKI(p11lib, session, ski)
+	if err != nil {
+		return nil, fmt.Errorf("Secret key not found [%s]", err)
+	}
+
+	iv, err := p11lib.GenerateRandom(session, sm4BlockSize)
+	if err != nil {
+		return nil, fmt.Errorf("P11: generate IV failed [%s]", err)
+	}
+
+	err = p11lib.EncryptInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(csp.gm.sm4CBC, iv)}, *key)
+	if err != nil {
+		return nil, fmt.Errorf("PKCS11: Encrypt-initialize [%s]", err)
+	}
+	ct, err := p11lib.Encrypt(session, pkcs7Padding(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("PKCS11: Encrypt failed [%s]", err)
+	}
+
+	return append(iv, ct...), nil
+}
+
+func (csp *impl) decryptP11SM4(ski []byte, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 2*sm4BlockSize || len(ciphertext)%sm4BlockSize != 0 {
+		return nil, fmt.Errorf("Invalid ciphertext. It must be a multiple of the block size and carry an IV")
+	}
+
+	p11lib := csp.ctx
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(session)
+
+	key, err := findSecretKeyFromSKI(p11lib, session, ski)
+	if err != nil {
+		return nil, fmt.Errorf("Secret key not found [%s]", err)
+	}
+
+	iv := ciphertext[:sm4BlockSize]
+	err = p11lib.DecryptInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(csp.gm.sm4CBC, iv)}, *key)
+	if err != nil {
+		return nil, fmt.Errorf("PKCS11: Decrypt-initialize [%s]", err)
+	}
+	pt, err := p11lib.Decrypt(session, ciphertext[sm4BlockSize:])
+	if err != nil {
+		return nil, fmt.Errorf("PKCS11: Decrypt failed [%s]", err)
+	}
+
+	return pkcs7UnPadding(pt)
+}
+
+func pkcs7Padding(src []byte) []byte {
+	padding := sm4BlockSize - len(src)%sm4BlockSize
+	padtext := make([]byte, len(src)+padding)
+	copy(padtext, src)
+	for i := len(src); i < len(padtext); i++ {
+		padtext[i] = byte(padding)
+	}
+	return padtext
+}
+
+func pkcs7UnPadding(src []byte) ([]byte, error) {
+	length := len(src)
+	if length == 0 {
+		return nil, fmt.Errorf("Invalid pkcs7 padding (len(padding) == 0)")
+	}
+	unpadding := int(src[length-1])
+	if unpadding > sm4BlockSize || unpadding == 0 {
+		return nil, fmt.Errorf("Invalid pkcs7 padding (unpadding > sm4BlockSize || unpadding == 0)")
+	}
+
+	pad := src[length-unpadding:]
+	for i := 0; i < unpadding; i++ {
+		if pad[i] != byte(unpadding) {
+			return nil, fmt.Errorf("Invalid pkcs7 padding (pad[i] != unpadding)")
+		}
+	}
+
+	return src[:length-unpadding], nil
+}
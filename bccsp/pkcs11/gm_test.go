@@ -0,0 +1,194 @@
+// +build pkcs11
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/miekg/pkcs11"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGMMechanisms(t *testing.T) {
+	m, err := newGMMechanisms(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(defaultCKMSM2), m.sm2)
+	assert.Equal(t, uint(defaultCKKSM2), m.sm2KeyType)
+
+	m, err = newGMMechanisms(&GMOpts{SM2: 0x80001234, SM3: 0x80005678})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0x80001234), m.sm2)
+	assert.Equal(t, uint(0x80005678), m.sm3)
+	assert.Equal(t, uint(defaultCKMSM4CBC), m.sm4CBC)
+
+	m.setSupported(nil)
+	assert.False(t, m.sm2Supported)
+	assert.False(t, m.sm3Supported)
+	assert.False(t, m.sm4Supported)
+
+	m.setSupported([]*pkcs11.Mechanism{
+		pkcs11.NewMechanism(m.sm2KeyPairGen, nil),
+		pkcs11.NewMechanism(m.sm2, nil),
+		pkcs11.NewMechanism(m.sm3, nil),
+		pkcs11.NewMechanism(m.sm4KeyGen, nil),
+	})
+	assert.True(t, m.sm2Supported)
+	assert.True(t, m.sm3Supported)
+	assert.False(t, m.sm4Supported, "SM4 requires both key generation and CBC")
+}
+
+func TestVendorProfiles(t *testing.T) {
+	err := RegisterVendor("acme", VendorProfile{
+		SM2:                0x80001001,
+		SM2Input:           SM2InputMessage,
+		SM2SignatureFormat: SM2SignatureFormatDER,
+		SM2UserIDParam:     true,
+	})
+	assert.NoError(t, err)
+
+	m, err := newGMMechanisms(&GMOpts{Vendor: "ACME", SM3: 0x80005678})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0x80001001), m.sm2)
+	assert.Equal(t, uint(0x80005678), m.sm3)
+	assert.Equal(t, uint(defaultCKMSM2KeyPairGen), m.sm2KeyPairGen)
+	assert.Equal(t, pkcs11.NewMechanism(0x80001001, []byte("1234567812345678")), m.sm2Mechanism())
+
+	// The token is passed the message itself
+	input, err := m.sm2SignInput(nil, []byte("msg"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("msg"), input)
+
+	r, s := big.NewInt(1), big.NewInt(2)
+	sig, err := m.marshalSM2Signature(r, s)
+	assert.NoError(t, err)
+	der, _ := utils.MarshalECDSASignature(r, s)
+	assert.Equal(t, der, sig)
+	r2, s2, err := m.unmarshalSM2Signature(sig)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+	assert.Equal(t, s, s2)
+
+	// Options override the encodings of the profile
+	m, err = newGMMechanisms(&GMOpts{Vendor: "acme", SM2SignatureFormat: SM2SignatureFormatRaw})
+	assert.NoError(t, err)
+	sig, err = m.marshalSM2Signature(r, s)
+	assert.NoError(t, err)
+	assert.Len(t, sig, 64)
+
+	m, err = newGMMechanisms(&GMOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, pkcs11.NewMechanism(defaultCKMSM2, nil), m.sm2Mechanism())
+
+	_, err = newGMMechanisms(&GMOpts{Vendor: "unknown"})
+	assert.EqualError(t, err, "Unknown PKCS11 vendor [unknown]")
+	_, err = newGMMechanisms(&GMOpts{SM2Input: "hash"})
+	assert.EqualError(t, err, "Invalid SM2 input [hash]. It must be digest or message")
+
+	assert.EqualError(t, RegisterVendor("default", VendorProfile{}), "Invalid vendor name [DEFAULT]")
+	assert.EqualError(t, RegisterVendor("other", VendorProfile{SM2Input: SM2InputDigest, SM2SignatureFormat: "ber"}), "Invalid SM2 signature format [ber]")
+
+	// The input of the SM2 mechanism is never implied
+	assert.EqualError(t, RegisterVendor("other", VendorProfile{SM2: 0x80001001}), "Invalid SM2 input []. It must be digest or message")
+}
+
+func TestSM2KeyGenFallback(t *testing.T) {
+	csp := currentBCCSP.(*impl)
+	if csp.gm.sm2Supported {
+		t.Skip("token offers SM2, no fallback to test")
+	}
+
+	k, err := currentBCCSP.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, onToken := k.(*sm2PrivateKey)
+	assert.False(t, onToken, "SM2 key must be generated by the software fallback")
+
+	digest := []byte("hello world")
+	signature, err := currentBCCSP.Sign(k, digest, nil)
+	assert.NoError(t, err)
+	valid, err := currentBCCSP.Verify(k, signature, digest, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// Non-exportable keys never fall back to software
+	csp.nonExportable = true
+	defer func() { csp.nonExportable = false }()
+	_, err = currentBCCSP.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	assert.Error(t, err)
+
+	// SM2 encryption keys are not signing keys and may still be generated in software
+	_, err = currentBCCSP.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true, Usage: bccsp.SM2KeyUsageEncrypt})
+	assert.NoError(t, err)
+}
+
+func TestNonExportableECKeyGen(t *testing.T) {
+	csp := currentBCCSP.(*impl)
+	csp.nonExportable = true
+	defer func() { csp.nonExportable = false }()
+
+	k, err := currentBCCSP.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.True(t, bccsp.IsNonExportable(k))
+}
+
+func TestPKCS7PaddingSM4(t *testing.T) {
+	for _, l := range []int{0, 1, 15, 16, 17} {
+		padded := pkcs7Padding(make([]byte, l))
+		assert.Zero(t, len(padded)%sm4BlockSize)
+		unpadded, err := pkcs7UnPadding(padded)
+		assert.NoError(t, err)
+		assert.Len(t, unpadded, l)
+	}
+
+	_, err := pkcs7UnPadding(make([]byte, sm4BlockSize))
+	assert.Error(t, err)
+}
+
+func TestP11SM4Opts(t *testing.T) {
+	assert.NoError(t, checkP11SM4Opts(nil))
+
+	err := checkP11SM4Opts(&bccsp.AEADOpts{})
+	assert.EqualError(t, err, "Unsupported options [*bccsp.AEADOpts] for an SM4 key on the token. It only encrypts in CBC mode with PKCS#7 padding, with nil options")
+	assert.Equal(t, bccsp.ErrCodeUnsupportedAlgorithm, bccsp.ErrorCodeOf(err))
+}
+
+func TestGMCapabilities(t *testing.T) {
+	swCaps := func() *bccsp.Capabilities {
+		return &bccsp.Capabilities{
+			SignatureAlgorithms: []string{bccsp.ECDSA, bccsp.SM2},
+			CipherModes:         []string{bccsp.CipherModeAESGCM, bccsp.CipherModeSM4, bccsp.CipherModeSM4GCM},
+		}
+	}
+	m, err := newGMMechanisms(nil)
+	assert.NoError(t, err)
+
+	// Keys generated in software fill in for the token
+	c := swCaps()
+	m.restrict(c, false)
+	assert.Equal(t, swCaps(), c)
+
+	// unless they must not leave it
+	c = swCaps()
+	m.restrict(c, true)
+	assert.Equal(t, []string{bccsp.ECDSA}, c.SignatureAlgorithms)
+
+	// SM4 keys on the token only encrypt in CBC mode
+	m.setSupported([]*pkcs11.Mechanism{
+		pkcs11.NewMechanism(m.sm2KeyPairGen, nil),
+		pkcs11.NewMechanism(m.sm2, nil),
+		pkcs11.NewMechanism(m.sm4KeyGen, nil),
+		pkcs11.NewMechanism(m.sm4CBC, nil),
+	})
+	c = swCaps()
+	m.restrict(c, true)
+	assert.Equal(t, []string{bccsp.ECDSA, bccsp.SM2}, c.SignatureAlgorithms)
+	assert.Equal(t, []string{bccsp.CipherModeAESGCM, bccsp.CipherModeSM4CBCPKCS7}, c.CipherModes)
+}
@@ -0,0 +1,137 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"crypto"
+	"errors"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+type sm2PrivateKey struct {
+	ski []byte
+	pub sm2PublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *sm2PrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *sm2PrivateKey) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *sm2PrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *sm2PrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *sm2PrivateKey) PublicKey() (bccsp.Key, error) {
+	return &k.pub, nil
+}
+
+// CryptoPublicKey returns the public part of this key as *sm2.PublicKey.
+func (k *sm2PrivateKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pub.pub, nil
+}
+
+// KeyAttributes returns the attributes of this key. Keys held by the
+// token never leave it, so they are reported as non-exportable.
+func (k *sm2PrivateKey) KeyAttributes() map[string]string {
+	return map[string]string{
+		bccsp.KeyAttrProvider:   "PKCS11",
+		bccsp.KeyAttrExportable: "false",
+	}
+}
+
+type sm2PublicKey struct {
+	ski []byte
+	pub *sm2.PublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *sm2PublicKey) Bytes() ([]byte, error) {
+	return utils.MarshalPKIXSM2PublicKey(k.pub)
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *sm2PublicKey) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *sm2PublicKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *sm2PublicKey) Private() bool {
+	return false
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *sm2PublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
+
+// CryptoPublicKey returns this key as *sm2.PublicKey.
+func (k *sm2PublicKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pub, nil
+}
+
+// sm4Key is an SM4 secret key held by the token.
+type sm4Key struct {
+	ski []byte
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *sm4Key) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *sm4Key) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *sm4Key) Symmetric() bool {
+	return true
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *sm4Key) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *sm4Key) PublicKey() (bccsp.Key, error) {
+	return nil, errors.New("Cannot call this method on a symmetric key.")
+}
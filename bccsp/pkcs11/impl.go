@@ -10,17 +10,26 @@ import (
 	"crypto/ecdsa"
 	"crypto/x509"
 	"os"
+	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
 	"github.com/miekg/pkcs11"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/pkg/errors"
 )
 
 var (
 	logger           = flogging.MustGetLogger("bccsp_p11")
 	sessionCacheSize = 10
+
+	defaultMaxSessions        = 64
+	defaultSessionWaitTimeout = 5 * time.Second
+	defaultLoginRetries       = 3
+	defaultLoginRetryInterval = time.Second
 )
 
 // New WithParams returns a new instance of the software-based BCCSP
@@ -38,11 +47,33 @@ func New(opts PKCS11Opts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
 		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
 	}
 
-	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	// Keys generated by the provider take the SKI convention of the
+	// options, the one of the process if none
+	skiConvention := utils.GetSKIConvention()
+	var swOptions []sw.Option
+	if opts.SKIConvention != "" {
+		skiConvention, err = utils.ParseSKIConvention(opts.SKIConvention)
+		if err != nil {
+			return nil, err
+		}
+		swOptions = append(swOptions, sw.WithSKIConvention(skiConvention))
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore, swOptions...)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
 	}
 
+	keyIDs, err := parseKeyIDs(opts.KeyIDs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing key ID mappings")
+	}
+
+	gm, err := newGMMechanisms(opts.GM)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing GM mechanisms")
+	}
+
 	lib := opts.Library
 	pin := opts.Pin
 	label := opts.Label
@@ -52,9 +83,53 @@ func New(opts PKCS11Opts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
 			lib, label)
 	}
 
+	maxSessions := opts.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+	sessionWait := opts.SessionWaitTimeout
+	if sessionWait <= 0 {
+		sessionWait = defaultSessionWaitTimeout
+	}
+	loginRetries := opts.LoginRetries
+	if loginRetries <= 0 {
+		loginRetries = defaultLoginRetries
+	}
+	loginRetryInterval := opts.LoginRetryInterval
+	if loginRetryInterval <= 0 {
+		loginRetryInterval = defaultLoginRetryInterval
+	}
+	metricsProvider := opts.MetricsProvider
+	if metricsProvider == nil {
+		metricsProvider = &disabled.Provider{}
+	}
+
 	sessions := make(chan pkcs11.SessionHandle, sessionCacheSize)
-	csp := &impl{swCSP, conf, ctx, sessions, slot, pin, lib, opts.SoftVerify, opts.Immutable}
-	csp.returnSession(*session)
+	gm.probe(ctx, slot)
+
+	csp := &impl{
+		BCCSP:              swCSP,
+		conf:               conf,
+		ctx:                ctx,
+		sessions:           sessions,
+		sessionSlots:       make(chan struct{}, maxSessions),
+		sessionWait:        sessionWait,
+		loginRetries:       loginRetries,
+		loginRetryInterval: loginRetryInterval,
+		metrics:            NewMetrics(metricsProvider),
+		slot:               slot,
+		pin:                pin,
+		lib:                lib,
+		softVerify:         opts.SoftVerify,
+		immutable:          opts.Immutable,
+		nonExportable:      opts.NonExportable,
+		keyIDs:             keyIDs,
+		keyCache:           newKeyCache(opts.KeyCacheTTL),
+		gm:                 gm,
+		skiConvention:      skiConvention,
+	}
+	// the login session is not checked out, cache it directly
+	csp.sessions <- *session
 	return csp, nil
 }
 
@@ -68,10 +143,34 @@ type impl struct {
 	slot     uint
 	pin      string
 
+	// sessionSlots bounds the sessions checked out at once
+	sessionSlots       chan struct{}
+	sessionWait        time.Duration
+	loginRetries       int
+	loginRetryInterval time.Duration
+	metrics            *Metrics
+
 	lib        string
 	softVerify bool
 	//Immutable flag makes object immutable
 	immutable bool
+	// nonExportable requires asymmetric keys to be generated on the token
+	// with their private part protected against export
+	nonExportable bool
+
+	// keyIDs maps hex encoded SKIs to the attribute locating the objects
+	// of pre-provisioned keys
+	keyIDs map[string]*pkcs11.Attribute
+
+	// keyCache holds the public keys and object handles read from the
+	// token, nil if disabled
+	keyCache *keyCache
+
+	// GM mechanisms offered by the token
+	gm *gmMechanisms
+
+	// skiConvention derives the SKIs of the keys on the token
+	skiConvention utils.SKIConvention
 }
 
 // KeyGen generates a key using opts.
@@ -82,12 +181,13 @@ func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 	}
 
 	// Parse algorithm
-	switch opts.(type) {
+	switch o := opts.(type) {
 	case *bccsp.ECDSAKeyGenOpts:
 		ski, pub, err := csp.generateECKey(csp.conf.ellipticCurve, opts.Ephemeral())
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed generating ECDSA key")
 		}
+		csp.keyCache.putKey(ski, pub, true)
 		k = &ecdsaPrivateKey{ski, ecdsaPublicKey{ski, pub}}
 
 	case *bccsp.ECDSAP256KeyGenOpts:
@@ -95,6 +195,7 @@ func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed generating ECDSA P256 key")
 		}
+		csp.keyCache.putKey(ski, pub, true)
 
 		k = &ecdsaPrivateKey{ski, ecdsaPublicKey{ski, pub}}
 
@@ -103,9 +204,37 @@ func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 		if err != nil {
 			return nil, errors.Wrapf(err, "Failed generating ECDSA P384 key")
 		}
+		csp.keyCache.putKey(ski, pub, true)
 
 		k = &ecdsaPrivateKey{ski, ecdsaPublicKey{ski, pub}}
 
+	case *bccsp.SM2KeyGenOpts:
+		// Keys on other curves or for encryption are not generated on the token
+		if !csp.gm.sm2Supported || !sm2OnTokenCurve(o.Curve, o.CurveOID) || o.Usage == bccsp.SM2KeyUsageEncrypt {
+			if csp.nonExportable && o.Usage != bccsp.SM2KeyUsageEncrypt {
+				return nil, errors.New("Failed generating SM2 key. Non-exportable keys must be generated on the token, which cannot generate this key")
+			}
+			return csp.BCCSP.KeyGen(opts)
+		}
+		ski, pub, err := csp.generateSM2Key(opts.Ephemeral())
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed generating SM2 key")
+		}
+		csp.keyCache.putKey(ski, pub, true)
+
+		k = &sm2PrivateKey{ski, sm2PublicKey{ski, pub}}
+
+	case *bccsp.SM4KeyGenOpts:
+		if !csp.gm.sm4Supported {
+			return csp.BCCSP.KeyGen(opts)
+		}
+		ski, err := csp.generateSM4Key(opts.Ephemeral())
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed generating SM4 key")
+		}
+
+		k = &sm4Key{ski}
+
 	default:
 		return csp.BCCSP.KeyGen(opts)
 	}
@@ -148,9 +277,29 @@ func (csp *impl) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.K
 	}
 }
 
+// Capabilities returns the algorithms offered by this CSP: those of the
+// software fallback, restricted by the GM mechanisms probed on the token.
+func (csp *impl) Capabilities() *bccsp.Capabilities {
+	c := &bccsp.Capabilities{}
+	if swCaps, ok := bccsp.GetCapabilities(csp.BCCSP); ok {
+		c = swCaps
+	}
+	c.Provider = "PKCS11"
+	csp.gm.restrict(c, csp.nonExportable)
+	return c
+}
+
 // GetKey returns the key this CSP associates to
 // the Subject Key Identifier ski.
 func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	if csp.keyCache != nil {
+		if e, ok := csp.keyCache.get(ski); ok && e.pub != nil {
+			csp.metrics.KeyCacheHits.Add(1)
+			return cachedKey(ski, e), nil
+		}
+		csp.metrics.KeyCacheMisses.Add(1)
+	}
+
 	pubKey, isPriv, err := csp.getECKey(ski)
 	if err == nil {
 		if isPriv {
@@ -158,9 +307,43 @@ func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
 		}
 		return &ecdsaPublicKey{ski, pubKey}, nil
 	}
+	if csp.gm.sm2Supported {
+		sm2Pub, isPriv, err := csp.getSM2Key(ski)
+		if err == nil {
+			if isPriv {
+				return &sm2PrivateKey{ski, sm2PublicKey{ski, sm2Pub}}, nil
+			}
+			return &sm2PublicKey{ski, sm2Pub}, nil
+		}
+	}
+	if csp.gm.sm4Supported {
+		if session, err := csp.getSession(); err == nil {
+			_, err = findSecretKeyFromSKI(csp.ctx, session, ski)
+			csp.returnSession(session)
+			if err == nil {
+				return &sm4Key{ski}, nil
+			}
+		}
+	}
 	return csp.BCCSP.GetKey(ski)
 }
 
+func cachedKey(ski []byte, e keyCacheEntry) bccsp.Key {
+	switch pub := e.pub.(type) {
+	case *sm2.PublicKey:
+		if e.isPriv {
+			return &sm2PrivateKey{ski, sm2PublicKey{ski, pub}}
+		}
+		return &sm2PublicKey{ski, pub}
+	default:
+		ecPub := pub.(*ecdsa.PublicKey)
+		if e.isPriv {
+			return &ecdsaPrivateKey{ski, ecdsaPublicKey{ski, ecPub}}
+		}
+		return &ecdsaPublicKey{ski, ecPub}
+	}
+}
+
 // Sign signs digest using key k.
 // The opts argument should be appropriate for the primitive used.
 //
@@ -180,6 +363,8 @@ func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte
 	switch key := k.(type) {
 	case *ecdsaPrivateKey:
 		return csp.signECDSA(*key, digest, opts)
+	case *sm2PrivateKey:
+		return csp.signSM2(*key, digest, opts)
 	default:
 		return csp.BCCSP.Sign(key, digest, opts)
 	}
@@ -204,6 +389,10 @@ func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.Signer
 		return csp.verifyECDSA(key.pub, signature, digest, opts)
 	case *ecdsaPublicKey:
 		return csp.verifyECDSA(*key, signature, digest, opts)
+	case *sm2PrivateKey:
+		return csp.verifySM2(key.pub, signature, digest, opts)
+	case *sm2PublicKey:
+		return csp.verifySM2(*key, signature, digest, opts)
 	default:
 		return csp.BCCSP.Verify(k, signature, digest, opts)
 	}
@@ -211,17 +400,40 @@ func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.Signer
 
 // Encrypt encrypts plaintext using key k.
 // The opts argument should be appropriate for the primitive used.
+// SM4 keys on the token only accept nil options, see checkP11SM4Opts.
 func (csp *impl) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
 	// TODO: Add PKCS11 support for encryption, when fabric starts requiring it
+	if key, ok := k.(*sm4Key); ok {
+		if err := checkP11SM4Opts(opts); err != nil {
+			return nil, err
+		}
+		return csp.encryptP11SM4(key.ski, plaintext)
+	}
 	return csp.BCCSP.Encrypt(k, plaintext, opts)
 }
 
 // Decrypt decrypts ciphertext using key k.
 // The opts argument should be appropriate for the primitive used.
+// SM4 keys on the token only accept nil options, see checkP11SM4Opts.
 func (csp *impl) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	if key, ok := k.(*sm4Key); ok {
+		if err := checkP11SM4Opts(opts); err != nil {
+			return nil, err
+		}
+		return csp.decryptP11SM4(key.ski, ciphertext)
+	}
 	return csp.BCCSP.Decrypt(k, ciphertext, opts)
 }
 
+// Hash hashes messages msg using options opts. SM3 digests are computed
+// on the token when it offers SM3 and HashOnToken is set.
+func (csp *impl) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	if _, ok := opts.(*bccsp.SM3Opts); ok && csp.gm.hashOnToken && csp.gm.sm3Supported {
+		return csp.hashP11SM3(msg)
+	}
+	return csp.BCCSP.Hash(msg, opts)
+}
+
 // FindPKCS11Lib IS ONLY USED FOR TESTING
 // This is a convenience function. Useful to self-configure, for tests where usual configuration is not
 // available
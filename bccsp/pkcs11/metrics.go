@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+var (
+	sessionsOpened = metrics.CounterOpts{
+		Namespace: "bccsp",
+		Subsystem: "pkcs11",
+		Name:      "sessions_opened",
+		Help:      "The number of PKCS#11 sessions that have been opened.",
+	}
+	sessionsClosed = metrics.CounterOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "pkcs11",
+		Name:         "sessions_closed",
+		Help:         "The number of PKCS#11 sessions that have been closed, because they failed the health check or were not needed.",
+		LabelNames:   []string{"reason"},
+		StatsdFormat: "%{#fqname}.%{reason}",
+	}
+	sessionsInUse = metrics.GaugeOpts{
+		Namespace: "bccsp",
+		Subsystem: "pkcs11",
+		Name:      "sessions_in_use",
+		Help:      "The number of PKCS#11 sessions currently checked out of the pool.",
+	}
+	sessionErrors = metrics.CounterOpts{
+		Namespace: "bccsp",
+		Subsystem: "pkcs11",
+		Name:      "session_errors",
+		Help:      "The number of times a PKCS#11 session could not be opened or logged in.",
+	}
+	poolExhausted = metrics.CounterOpts{
+		Namespace: "bccsp",
+		Subsystem: "pkcs11",
+		Name:      "session_pool_exhausted",
+		Help:      "The number of times no PKCS#11 session became available within the wait timeout.",
+	}
+	relogins = metrics.CounterOpts{
+		Namespace: "bccsp",
+		Subsystem: "pkcs11",
+		Name:      "relogins",
+		Help:      "The number of times a cached PKCS#11 session had to be logged in again.",
+	}
+	keyCacheHits = metrics.CounterOpts{
+		Namespace: "bccsp",
+		Subsystem: "pkcs11",
+		Name:      "key_cache_hits",
+		Help:      "The number of key lookups served from the cache without searching the token.",
+	}
+	keyCacheMisses = metrics.CounterOpts{
+		Namespace: "bccsp",
+		Subsystem: "pkcs11",
+		Name:      "key_cache_misses",
+		Help:      "The number of key lookups that had to search the token.",
+	}
+)
+
+type Metrics struct {
+	SessionsOpened metrics.Counter
+	SessionsClosed metrics.Counter
+	SessionsInUse  metrics.Gauge
+	SessionErrors  metrics.Counter
+	PoolExhausted  metrics.Counter
+	Relogins       metrics.Counter
+	KeyCacheHits   metrics.Counter
+	KeyCacheMisses metrics.Counter
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		SessionsOpened: p.NewCounter(sessionsOpened),
+		SessionsClosed: p.NewCounter(sessionsClosed),
+		SessionsInUse:  p.NewGauge(sessionsInUse),
+		SessionErrors:  p.NewCounter(sessionErrors),
+		PoolExhausted:  p.NewCounter(poolExhausted),
+		Relogins:       p.NewCounter(relogins),
+		KeyCacheHits:   p.NewCounter(keyCacheHits),
+		KeyCacheMisses: p.NewCounter(keyCacheMisses),
+	}
+}
@@ -9,7 +9,6 @@ package pkcs11
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/sha256"
 	"encoding/asn1"
 	"encoding/hex"
 	"fmt"
@@ -55,43 +54,107 @@ func loadLib(lib, pin, label string) (*pkcs11.Ctx, uint, *pkcs11.SessionHandle,
 		return nil, slot, nil, fmt.Errorf("Could not find token with label %s", label)
 	}
 
-	session := createSession(ctx, slot, pin)
-
-	logger.Debugf("Created new pkcs11 session %+v on slot %d\n", session, slot)
-
 	if pin == "" {
 		return nil, slot, nil, fmt.Errorf("No PIN set")
 	}
-	err = ctx.Login(session, pkcs11.CKU_USER, pin)
+
+	session, err := createSession(ctx, slot, pin, 0, 0)
 	if err != nil {
-		if err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
-			return nil, slot, nil, fmt.Errorf("Login failed [%s]", err)
-		}
+		return nil, slot, nil, err
 	}
 
 	return ctx, slot, &session, nil
 }
 
-func (csp *impl) getSession() (session pkcs11.SessionHandle) {
-	select {
-	case session = <-csp.sessions:
-		_, err := csp.ctx.GetSessionInfo(session)
-		if err != nil {
-			logger.Warningf("Get session info failed [%s], closing existing session and getting a new session\n", err)
-			csp.ctx.CloseSession(session)
-			session = createSession(csp.ctx, csp.slot, csp.pin)
-		} else {
+// getSession checks a session out of the pool. Cached sessions are health
+// checked first, and logged in again if the token dropped the login. When
+// maxSessions sessions are in use, it waits up to sessionWait for one to be
+// returned.
+func (csp *impl) getSession() (pkcs11.SessionHandle, error) {
+	if err := csp.acquireSessionSlot(); err != nil {
+		return 0, err
+	}
+
+	for {
+		select {
+		case session := <-csp.sessions:
+			if err := csp.checkSession(session); err != nil {
+				logger.Warningf("Session %+v failed health check [%s], closing existing session and getting a new session\n", session, err)
+				csp.ctx.CloseSession(session)
+				csp.metrics.SessionsClosed.With("reason", "unhealthy").Add(1)
+				continue
+			}
 			logger.Debugf("Reusing existing pkcs11 session %+v on slot %d\n", session, csp.slot)
+			csp.metrics.SessionsInUse.Add(1)
+			return session, nil
+
+		default:
+			// cache is empty (or completely in use), create a new session
+			session, err := createSession(csp.ctx, csp.slot, csp.pin, csp.loginRetries, csp.loginRetryInterval)
+			if err != nil {
+				csp.releaseSessionSlot()
+				csp.metrics.SessionErrors.Add(1)
+				return 0, err
+			}
+			csp.metrics.SessionsOpened.Add(1)
+			csp.metrics.SessionsInUse.Add(1)
+			return session, nil
 		}
+	}
+}
+
+// Session states from the PKCS#11 specification. miekg/pkcs11 v1.0.3 does
+// not define them.
+const (
+	cksROUserFunctions = 1
+	cksRWUserFunctions = 3
+)
 
+// checkSession verifies that session is still valid and logged in.
+func (csp *impl) checkSession(session pkcs11.SessionHandle) error {
+	info, err := csp.ctx.GetSessionInfo(session)
+	if err != nil {
+		return err
+	}
+	if info.State == cksROUserFunctions || info.State == cksRWUserFunctions {
+		return nil
+	}
+
+	// The token was reset or logged out behind our back
+	logger.Warningf("Session %+v on slot %d is not logged in, state [%d], logging in again\n", session, csp.slot, info.State)
+	csp.metrics.Relogins.Add(1)
+	return login(csp.ctx, session, csp.pin, csp.loginRetries, csp.loginRetryInterval)
+}
+
+func (csp *impl) acquireSessionSlot() error {
+	if csp.sessionSlots == nil {
+		return nil
+	}
+	select {
+	case csp.sessionSlots <- struct{}{}:
+		return nil
 	default:
-		// cache is empty (or completely in use), create a new session
-		session = createSession(csp.ctx, csp.slot, csp.pin)
 	}
-	return session
+
+	timer := time.NewTimer(csp.sessionWait)
+	defer timer.Stop()
+	select {
+	case csp.sessionSlots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		csp.metrics.PoolExhausted.Add(1)
+		return fmt.Errorf("PKCS11: session pool exhausted, %d sessions in use for more than %s", cap(csp.sessionSlots), csp.sessionWait)
+	}
 }
 
-func createSession(ctx *pkcs11.Ctx, slot uint, pin string) pkcs11.SessionHandle {
+func (csp *impl) releaseSessionSlot() {
+	if csp.sessionSlots != nil {
+		<-csp.sessionSlots
+	}
+}
+
+// createSession opens a logged in session on slot.
+func createSession(ctx *pkcs11.Ctx, slot uint, pin string, loginRetries int, loginRetryInterval time.Duration) (pkcs11.SessionHandle, error) {
 	var s pkcs11.SessionHandle
 	var err error
 	// attempt 10 times to open a session with a 100ms delay after each attempt
@@ -105,43 +168,92 @@ func createSession(ctx *pkcs11.Ctx, slot uint, pin string) pkcs11.SessionHandle
 		time.Sleep(100 * time.Millisecond)
 	}
 	if err != nil {
-		logger.Fatalf("OpenSession failed [%s]", err)
+		return 0, fmt.Errorf("OpenSession failed [%s]", err)
 	}
 	logger.Debugf("Created new pkcs11 session %+v on slot %d\n", s, slot)
-	session := s
 
-	err = ctx.Login(session, pkcs11.CKU_USER, pin)
-	if err != nil {
-		if err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
-			logger.Errorf("Login failed [%s]", err)
+	if err := login(ctx, s, pin, loginRetries, loginRetryInterval); err != nil {
+		ctx.CloseSession(s)
+		return 0, err
+	}
+	return s, nil
+}
+
+// login logs the user in on session, retrying up to retries times. Wrong
+// or locked PINs are not retried, since every attempt counts towards the
+// token lockout.
+func login(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, pin string, retries int, interval time.Duration) error {
+	var err error
+	for i := 0; i <= retries; i++ {
+		if i > 0 {
+			time.Sleep(interval)
 		}
+		err = ctx.Login(session, pkcs11.CKU_USER, pin)
+		switch err {
+		case nil, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN):
+			return nil
+		case pkcs11.Error(pkcs11.CKR_PIN_INCORRECT), pkcs11.Error(pkcs11.CKR_PIN_LOCKED):
+			return fmt.Errorf("Login failed [%s]", err)
+		}
+		logger.Warningf("Login failed, attempt %d of %d [%s]\n", i+1, retries+1, err)
 	}
-	return session
+	return fmt.Errorf("Login failed [%s]", err)
 }
 
 func (csp *impl) returnSession(session pkcs11.SessionHandle) {
+	csp.metrics.SessionsInUse.Add(-1)
+	defer csp.releaseSessionSlot()
+
 	select {
 	case csp.sessions <- session:
 		// returned session back to session cache
 	default:
 		// have plenty of sessions in cache, dropping
 		csp.ctx.CloseSession(session)
+		csp.metrics.SessionsClosed.With("reason", "surplus").Add(1)
+	}
+}
+
+// enforceNonExportable checks that the token honoured CKA_EXTRACTABLE=false
+// and CKA_SENSITIVE=true on the generated private key. Otherwise the key
+// pair is destroyed, as its private material could leave the token.
+func enforceNonExportable(p11lib *pkcs11.Ctx, session pkcs11.SessionHandle, pub, prv pkcs11.ObjectHandle) error {
+	attrs, err := p11lib.GetAttributeValue(session, prv, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EXTRACTABLE, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_SENSITIVE, nil),
+	})
+	if err == nil && len(attrs) == 2 && !isTrue(attrs[0]) && isTrue(attrs[1]) {
+		return nil
 	}
+	if err == nil {
+		err = fmt.Errorf("token did not enforce CKA_EXTRACTABLE=false and CKA_SENSITIVE=true")
+	}
+
+	p11lib.DestroyObject(session, prv)
+	p11lib.DestroyObject(session, pub)
+	return fmt.Errorf("P11: private key is not protected against export, key pair destroyed [%s]", err)
+}
+
+func isTrue(attr *pkcs11.Attribute) bool {
+	return len(attr.Value) > 0 && attr.Value[0] != 0
 }
 
 // Look for an EC key by SKI, stored in CKA_ID
 func (csp *impl) getECKey(ski []byte) (pubKey *ecdsa.PublicKey, isPriv bool, err error) {
 	p11lib := csp.ctx
-	session := csp.getSession()
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, false, err
+	}
 	defer csp.returnSession(session)
 	isPriv = true
-	_, err = findKeyPairFromSKI(p11lib, session, ski, privateKeyType)
+	_, err = csp.findKeyPair(session, ski, privateKeyType)
 	if err != nil {
 		isPriv = false
 		logger.Debugf("Private key not found [%s] for SKI [%s], looking for Public key", err, hex.EncodeToString(ski))
 	}
 
-	publicKey, err := findKeyPairFromSKI(p11lib, session, ski, publicKeyType)
+	publicKey, err := csp.findKeyPair(session, ski, publicKeyType)
 	if err != nil {
 		return nil, false, fmt.Errorf("Public key not found [%s] for SKI [%s]", err, hex.EncodeToString(ski))
 	}
@@ -167,6 +279,7 @@ func (csp *impl) getECKey(ski []byte) (pubKey *ecdsa.PublicKey, isPriv bool, err
 	}
 
 	pubKey = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	csp.keyCache.putKey(ski, pubKey, isPriv)
 	return pubKey, isPriv, nil
 }
 
@@ -208,7 +321,10 @@ func namedCurveFromOID(oid asn1.ObjectIdentifier) elliptic.Curve {
 
 func (csp *impl) generateECKey(curve asn1.ObjectIdentifier, ephemeral bool) (ski []byte, pubKey *ecdsa.PublicKey, err error) {
 	p11lib := csp.ctx
-	session := csp.getSession()
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, nil, err
+	}
 	defer csp.returnSession(session)
 
 	id := nextIDCtr()
@@ -252,13 +368,20 @@ func (csp *impl) generateECKey(curve asn1.ObjectIdentifier, ephemeral bool) (ski
 	if err != nil {
 		return nil, nil, fmt.Errorf("P11: keypair generate failed [%s]", err)
 	}
+	if csp.nonExportable {
+		if err := enforceNonExportable(p11lib, session, pub, prv); err != nil {
+			return nil, nil, err
+		}
+	}
 
 	ecpt, _, err := ecPoint(p11lib, session, pub)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Error querying EC-point: [%s]", err)
 	}
-	hash := sha256.Sum256(ecpt)
-	ski = hash[:]
+	ski, _, err = pointSKI(ecpt, curve, csp.skiConvention)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// set CKA_ID of the both keys to SKI(public key) and CKA_LABEL to hex string of SKI
 	setskiT := []*pkcs11.Attribute{
@@ -324,16 +447,20 @@ func (csp *impl) generateECKey(curve asn1.ObjectIdentifier, ephemeral bool) (ski
 
 func (csp *impl) signP11ECDSA(ski []byte, msg []byte) (R, S *big.Int, err error) {
 	p11lib := csp.ctx
-	session := csp.getSession()
+	session, err := csp.getSession()
+	if err != nil {
+		return nil, nil, err
+	}
 	defer csp.returnSession(session)
 
-	privateKey, err := findKeyPairFromSKI(p11lib, session, ski, privateKeyType)
+	privateKey, err := csp.findKeyPair(session, ski, privateKeyType)
 	if err != nil {
 		return nil, nil, fmt.Errorf("Private key not found [%s]", err)
 	}
 
 	err = p11lib.SignInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)}, *privateKey)
 	if err != nil {
+		csp.keyCache.invalidate(ski, err)
 		return nil, nil, fmt.Errorf("Sign-initialize  failed [%s]", err)
 	}
 
@@ -341,6 +468,7 @@ func (csp *impl) signP11ECDSA(ski []byte, msg []byte) (R, S *big.Int, err error)
 
 	sig, err = p11lib.Sign(session, msg)
 	if err != nil {
+		csp.keyCache.invalidate(ski, err)
 		return nil, nil, fmt.Errorf("P11: sign failed [%s]", err)
 	}
 
@@ -354,12 +482,15 @@ func (csp *impl) signP11ECDSA(ski []byte, msg []byte) (R, S *big.Int, err error)
 
 func (csp *impl) verifyP11ECDSA(ski []byte, msg []byte, R, S *big.Int, byteSize int) (bool, error) {
 	p11lib := csp.ctx
-	session := csp.getSession()
+	session, err := csp.getSession()
+	if err != nil {
+		return false, err
+	}
 	defer csp.returnSession(session)
 
 	logger.Debugf("Verify ECDSA\n")
 
-	publicKey, err := findKeyPairFromSKI(p11lib, session, ski, publicKeyType)
+	publicKey, err := csp.findKeyPair(session, ski, publicKeyType)
 	if err != nil {
 		return false, fmt.Errorf("Public key not found [%s]", err)
 	}
@@ -375,6 +506,7 @@ func (csp *impl) verifyP11ECDSA(ski []byte, msg []byte, R, S *big.Int, byteSize
 	err = p11lib.VerifyInit(session, []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil)},
 		*publicKey)
 	if err != nil {
+		csp.keyCache.invalidate(ski, err)
 		return false, fmt.Errorf("PKCS11: Verify-initialize [%s]", err)
 	}
 	err = p11lib.Verify(session, msg, sig)
@@ -382,6 +514,7 @@ func (csp *impl) verifyP11ECDSA(ski []byte, msg []byte, R, S *big.Int, byteSize
 		return false, nil
 	}
 	if err != nil {
+		csp.keyCache.invalidate(ski, err)
 		return false, fmt.Errorf("PKCS11: Verify failed [%s]", err)
 	}
 
@@ -396,6 +529,33 @@ const (
 )
 
 func findKeyPairFromSKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byte, keyType keyType) (*pkcs11.ObjectHandle, error) {
+	return findKeyObject(mod, session, pkcs11.NewAttribute(pkcs11.CKA_ID, ski), keyType)
+}
+
+// findKeyPair looks up a key by SKI, following the configured key ID
+// mappings for pre-provisioned keys.
+// Handles found are cached.
+func (csp *impl) findKeyPair(session pkcs11.SessionHandle, ski []byte, keyType keyType) (*pkcs11.ObjectHandle, error) {
+	if e, ok := csp.keyCache.get(ski); ok && e.handles[keyType] != 0 {
+		handle := e.handles[keyType]
+		return &handle, nil
+	}
+
+	var handle *pkcs11.ObjectHandle
+	var err error
+	if id, ok := csp.keyIDs[hex.EncodeToString(ski)]; ok {
+		handle, err = findKeyObject(csp.ctx, session, id, keyType)
+	} else {
+		handle, err = findKeyPairFromSKI(csp.ctx, session, ski, keyType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	csp.keyCache.putHandle(ski, keyType, *handle)
+	return handle, nil
+}
+
+func findKeyObject(mod *pkcs11.Ctx, session pkcs11.SessionHandle, id *pkcs11.Attribute, keyType keyType) (*pkcs11.ObjectHandle, error) {
 	ktype := pkcs11.CKO_PUBLIC_KEY
 	if keyType == privateKeyType {
 		ktype = pkcs11.CKO_PRIVATE_KEY
@@ -403,7 +563,7 @@ func findKeyPairFromSKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byt
 
 	template := []*pkcs11.Attribute{
 		pkcs11.NewAttribute(pkcs11.CKA_CLASS, ktype),
-		pkcs11.NewAttribute(pkcs11.CKA_ID, ski),
+		id,
 	}
 	if err := mod.FindObjectsInit(session, template); err != nil {
 		return nil, err
@@ -419,7 +579,7 @@ func findKeyPairFromSKI(mod *pkcs11.Ctx, session pkcs11.SessionHandle, ski []byt
 	}
 
 	if len(objs) == 0 {
-		return nil, fmt.Errorf("Key not found [%s]", hex.Dump(ski))
+		return nil, fmt.Errorf("Key not found [%s]", hex.Dump(id.Value))
 	}
 
 	return &objs[0], nil
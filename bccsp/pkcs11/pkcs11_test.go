@@ -12,6 +12,7 @@ import (
 	"crypto/elliptic"
 	"encoding/asn1"
 	"testing"
+	"time"
 
 	"github.com/miekg/pkcs11"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
@@ -90,7 +91,9 @@ func TestPKCS11GetSession(t *testing.T) {
 	}
 	var sessions []pkcs11.SessionHandle
 	for i := 0; i < 3*sessionCacheSize; i++ {
-		sessions = append(sessions, currentBCCSP.(*impl).getSession())
+		session, err := currentBCCSP.(*impl).getSession()
+		assert.NoError(t, err)
+		sessions = append(sessions, session)
 	}
 
 	// Return all sessions, should leave sessionCacheSize cached
@@ -105,13 +108,14 @@ func TestPKCS11GetSession(t *testing.T) {
 
 	// Should be able to get sessionCacheSize cached sessions
 	for i := 0; i < sessionCacheSize; i++ {
-		sessions = append(sessions, currentBCCSP.(*impl).getSession())
+		session, err := currentBCCSP.(*impl).getSession()
+		assert.NoError(t, err)
+		sessions = append(sessions, session)
 	}
 
 	// This one should fail
-	assert.Panics(t, func() {
-		currentBCCSP.(*impl).getSession()
-	}, "Should not been able to create another session")
+	_, err := currentBCCSP.(*impl).getSession()
+	assert.Error(t, err, "Should not been able to create another session")
 
 	// Cleanup
 	for _, session := range sessions {
@@ -120,6 +124,58 @@ func TestPKCS11GetSession(t *testing.T) {
 	currentBCCSP.(*impl).slot = oldSlot
 }
 
+func TestPKCS11SessionPoolBound(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestPKCS11SessionPoolBound")
+	}
+	csp := currentBCCSP.(*impl)
+	oldSlots, oldWait := csp.sessionSlots, csp.sessionWait
+	csp.sessionSlots = make(chan struct{}, 2)
+	csp.sessionWait = 50 * time.Millisecond
+	defer func() {
+		csp.sessionSlots, csp.sessionWait = oldSlots, oldWait
+	}()
+
+	s1, err := csp.getSession()
+	assert.NoError(t, err)
+	s2, err := csp.getSession()
+	assert.NoError(t, err)
+
+	_, err = csp.getSession()
+	assert.EqualError(t, err, "PKCS11: session pool exhausted, 2 sessions in use for more than 50ms")
+
+	// A returned session unblocks a waiting caller
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		csp.returnSession(s1)
+	}()
+	s3, err := csp.getSession()
+	assert.NoError(t, err)
+
+	csp.returnSession(s2)
+	csp.returnSession(s3)
+}
+
+func TestPKCS11SessionRelogin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping TestPKCS11SessionRelogin")
+	}
+	csp := currentBCCSP.(*impl)
+
+	session, err := csp.getSession()
+	assert.NoError(t, err)
+	// Logging out affects all sessions of the application
+	assert.NoError(t, csp.ctx.Logout(session))
+	csp.returnSession(session)
+
+	session, err = csp.getSession()
+	assert.NoError(t, err)
+	info, err := csp.ctx.GetSessionInfo(session)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(cksRWUserFunctions), info.State)
+	csp.returnSession(session)
+}
+
 func TestPKCS11ECKeySignVerify(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping TestPKCS11ECKeySignVerify")
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"fmt"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+func (csp *impl) signSM2(k sm2PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	e, err := csp.gm.sm2SignInput(k.pub.pub, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	r, s, err := csp.signP11SM2(k.ski, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if bccsp.SM2SignatureEncodingOf(opts) == bccsp.SM2SignatureRaw {
+		return utils.MarshalSM2RawSignature(r, s)
+	}
+	return utils.MarshalECDSASignature(r, s)
+}
+
+func (csp *impl) verifySM2(k sm2PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	der := signature
+	switch bccsp.SM2SignatureEncodingOf(opts) {
+	case bccsp.SM2SignatureRaw:
+		var err error
+		if der, err = utils.SM2SignatureRawToDER(signature); err != nil {
+			return false, err
+		}
+	case bccsp.SM2SignatureAny:
+		if len(signature) == 2*sm2.KeyBytes {
+			der, _ = utils.SM2SignatureRawToDER(signature)
+		}
+	}
+
+	if csp.softVerify {
+		return sm2.Verify(k.pub, nil, digest, der), nil
+	}
+
+	r, s, err := utils.UnmarshalECDSASignature(der)
+	if err != nil {
+		return false, fmt.Errorf("Failed unmashalling signature [%s]", err)
+	}
+
+	e, err := csp.gm.sm2SignInput(k.pub, digest)
+	if err != nil {
+		return false, err
+	}
+	return csp.verifyP11SM2(k.ski, e, r, s)
+}
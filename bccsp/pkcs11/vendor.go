@@ -0,0 +1,139 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pkcs11
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Inputs of the SM2 signing mechanism of a token.
+const (
+	// SM2InputDigest passes e = SM3(Z_A || M), the token signs it as is
+	SM2InputDigest = "digest"
+	// SM2InputMessage passes M, the token computes Z_A and the SM3 digest
+	SM2InputMessage = "message"
+)
+
+// Formats of the SM2 signatures produced and expected by a token.
+const (
+	// SM2SignatureFormatRaw is r || s, 32 bytes each
+	SM2SignatureFormatRaw = "raw"
+	// SM2SignatureFormatDER is the ASN.1 DER SEQUENCE of r and s
+	SM2SignatureFormatDER = "der"
+)
+
+// sm2DefaultUID is the default user identity of GB/T 32918.
+var sm2DefaultUID = []byte("1234567812345678")
+
+// DefaultVendor is the name of the profile used when GMOpts.Vendor is empty.
+const DefaultVendor = "DEFAULT"
+
+// VendorProfile describes how the tokens of a vendor expose the GM
+// algorithms: the vendor-defined key types and mechanisms, and the
+// encoding of the SM2 mechanism parameters, input and signature.
+type VendorProfile struct {
+	SM2KeyType    uint
+	SM4KeyType    uint
+	SM2KeyPairGen uint
+	SM2           uint
+	SM3           uint
+	SM4KeyGen     uint
+	SM4CBC        uint
+
+	// SM2Input is SM2InputDigest or SM2InputMessage. It is never taken
+	// from the default profile: a token passed the digest when it expects
+	// the message, or conversely, produces signatures nobody verifies.
+	SM2Input string
+	// SM2SignatureFormat is SM2SignatureFormatRaw or SM2SignatureFormatDER
+	SM2SignatureFormat string
+	// SM2UserIDParam passes the signer ID 1234567812345678 of GM/T 0009 as
+	// parameter of the SM2 mechanism. Only used with SM2InputMessage.
+	SM2UserIDParam bool
+}
+
+var (
+	vendorsMutex sync.RWMutex
+	vendors      = map[string]VendorProfile{
+		DefaultVendor: {
+			SM2KeyType:         defaultCKKSM2,
+			SM4KeyType:         defaultCKKSM4,
+			SM2KeyPairGen:      defaultCKMSM2KeyPairGen,
+			SM2:                defaultCKMSM2,
+			SM3:                defaultCKMSM3,
+			SM4KeyGen:          defaultCKMSM4KeyGen,
+			SM4CBC:             defaultCKMSM4CBC,
+			SM2Input:           SM2InputDigest,
+			SM2SignatureFormat: SM2SignatureFormatRaw,
+		},
+	}
+)
+
+// RegisterVendor makes profile selectable by name through GMOpts.Vendor.
+// Unset identifiers and signature formats of profile take the values of
+// the default profile, its SM2Input must be set. Names are case insensitive.
+func RegisterVendor(name string, profile VendorProfile) error {
+	name = strings.ToUpper(name)
+	if name == "" || name == DefaultVendor {
+		return fmt.Errorf("Invalid vendor name [%s]", name)
+	}
+	if err := profile.validate(); err != nil {
+		return err
+	}
+
+	vendorsMutex.Lock()
+	defer vendorsMutex.Unlock()
+	vendors[name] = profile
+	return nil
+}
+
+func (p *VendorProfile) validate() error {
+	switch p.SM2Input {
+	case SM2InputDigest, SM2InputMessage:
+	default:
+		return fmt.Errorf("Invalid SM2 input [%s]. It must be %s or %s", p.SM2Input, SM2InputDigest, SM2InputMessage)
+	}
+	switch p.SM2SignatureFormat {
+	case "", SM2SignatureFormatRaw, SM2SignatureFormatDER:
+	default:
+		return fmt.Errorf("Invalid SM2 signature format [%s]", p.SM2SignatureFormat)
+	}
+	return nil
+}
+
+// lookupVendor returns the profile registered under name, completed with
+// the defaults.
+func lookupVendor(name string) (VendorProfile, error) {
+	vendorsMutex.RLock()
+	defer vendorsMutex.RUnlock()
+
+	def := vendors[DefaultVendor]
+	if name == "" {
+		return def, nil
+	}
+	p, ok := vendors[strings.ToUpper(name)]
+	if !ok {
+		return VendorProfile{}, fmt.Errorf("Unknown PKCS11 vendor [%s]", name)
+	}
+	p.fillDefaults(&def)
+	return p, nil
+}
+
+// fillDefaults sets the unset fields of p, but SM2Input, to those of def.
+func (p *VendorProfile) fillDefaults(def *VendorProfile) {
+	p.SM2KeyType = orDefault(p.SM2KeyType, def.SM2KeyType)
+	p.SM4KeyType = orDefault(p.SM4KeyType, def.SM4KeyType)
+	p.SM2KeyPairGen = orDefault(p.SM2KeyPairGen, def.SM2KeyPairGen)
+	p.SM2 = orDefault(p.SM2, def.SM2)
+	p.SM3 = orDefault(p.SM3, def.SM3)
+	p.SM4KeyGen = orDefault(p.SM4KeyGen, def.SM4KeyGen)
+	p.SM4CBC = orDefault(p.SM4CBC, def.SM4CBC)
+	if p.SM2SignatureFormat == "" {
+		p.SM2SignatureFormat = def.SM2SignatureFormat
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// remotesigner is a reference signer service for the REMOTE BCCSP. It
+// serves the keys of a software BCCSP with a file based key store over
+// mutually authenticated TLS.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"io/ioutil"
+	"log"
+	"net"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote/remotepb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:7070", "address to listen on")
+	keyStore := flag.String("keystore", "", "directory of the key store")
+	hash := flag.String("hash", "SM3", "hash family, SHA2, SHA3 or SM3")
+	security := flag.Int("security", 256, "security level")
+	cert := flag.String("tls-cert", "", "TLS certificate of the service")
+	key := flag.String("tls-key", "", "TLS private key of the service")
+	clientCA := flag.String("tls-ca", "", "CA certificate accepted for client certificates")
+	flag.Parse()
+
+	if *keyStore == "" || *cert == "" || *key == "" || *clientCA == "" {
+		log.Fatal("-keystore, -tls-cert, -tls-key and -tls-ca are required")
+	}
+
+	ks, err := sw.NewFileBasedKeyStore(nil, *keyStore, false)
+	if err != nil {
+		log.Fatalf("Failed opening key store: %s", err)
+	}
+	csp, err := sw.NewWithParams(*security, *hash, ks)
+	if err != nil {
+		log.Fatalf("Failed initializing BCCSP: %s", err)
+	}
+
+	serverCert, err := tls.LoadX509KeyPair(*cert, *key)
+	if err != nil {
+		log.Fatalf("Failed loading TLS key pair: %s", err)
+	}
+	pem, err := ioutil.ReadFile(*clientCA)
+	if err != nil {
+		log.Fatalf("Failed reading client CA: %s", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		log.Fatalf("No certificate found in %s", *clientCA)
+	}
+
+	creds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		log.Fatalf("Failed listening on %s: %s", *listen, err)
+	}
+
+	server := grpc.NewServer(grpc.Creds(creds))
+	remotepb.RegisterSignerServer(server, remote.NewServer(csp))
+
+	log.Printf("Signer service listening on %s", lis.Addr())
+	if err := server.Serve(lis); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import "time"
+
+// RemoteOpts contains options for the RemoteFactory, whose BCCSP forwards
+// key generation, signing and verification to an external signer service.
+type RemoteOpts struct {
+	// Default algorithms of the software fallback
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// Address of the signer service, host:port
+	Address string `mapstructure:"address" json:"address"`
+
+	// Mutual TLS options, all of them are required
+	RootCertFiles      []string `mapstructure:"rootcerts" json:"rootcerts"`
+	CertFile           string   `mapstructure:"cert" json:"cert"`
+	KeyFile            string   `mapstructure:"key" json:"key"`
+	ServerNameOverride string   `mapstructure:"servernameoverride,omitempty" json:"servernameoverride,omitempty"`
+
+	// Timeout bounds each attempt, LatencyBudget bounds a call including
+	// its retries. Retries are attempted on transient failures only.
+	Timeout       time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+	LatencyBudget time.Duration `mapstructure:"latencybudget,omitempty" json:"latencybudget,omitempty"`
+	Retries       int           `mapstructure:"retries,omitempty" json:"retries,omitempty"`
+	RetryBackoff  time.Duration `mapstructure:"retrybackoff,omitempty" json:"retrybackoff,omitempty"`
+
+	// LocalVerify verifies signatures of remote keys locally with the
+	// public key instead of calling the signer service
+	LocalVerify bool `mapstructure:"localverify,omitempty" json:"localverify,omitempty"`
+}
+
+const (
+	defaultTimeout      = 3 * time.Second
+	defaultRetryBackoff = 100 * time.Millisecond
+)
@@ -0,0 +1,349 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote/remotepb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+var logger = flogging.MustGetLogger("bccsp_remote")
+
+// New returns a new instance of the remote BCCSP. Key generation, signing
+// and verification with keys held by the signer service at opts.Address
+// are forwarded over a mutually authenticated TLS connection; all other
+// operations are served by a software BCCSP set at the passed security
+// level, hash family and KeyStore.
+func New(opts RemoteOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	if opts.Address == "" {
+		return nil, errors.New("Invalid options. Address of the signer service must be set")
+	}
+
+	creds, err := clientCredentials(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed loading TLS credentials")
+	}
+
+	conn, err := grpc.Dial(opts.Address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed connecting to signer service %s", opts.Address)
+	}
+
+	csp, err := newWithConn(opts, keyStore, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return csp, nil
+}
+
+func newWithConn(opts RemoteOpts, keyStore bccsp.KeyStore, conn *grpc.ClientConn) (*impl, error) {
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	budget := opts.LatencyBudget
+	if budget <= 0 {
+		budget = time.Duration(opts.Retries+1) * (timeout + backoff)
+	}
+
+	return &impl{
+		BCCSP:       swCSP,
+		conn:        conn,
+		client:      remotepb.NewSignerClient(conn),
+		timeout:     timeout,
+		budget:      budget,
+		retries:     opts.Retries,
+		backoff:     backoff,
+		localVerify: opts.LocalVerify,
+		keys:        map[string]*remoteKey{},
+	}, nil
+}
+
+func clientCredentials(opts RemoteOpts) (credentials.TransportCredentials, error) {
+	if len(opts.RootCertFiles) == 0 || opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("root certificates, client certificate and key are required for mutual TLS")
+	}
+
+	roots := x509.NewCertPool()
+	for _, f := range opts.RootCertFiles {
+		pem, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificate found in %s", f)
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{cert},
+		ServerName:   opts.ServerNameOverride,
+		MinVersion:   tls.VersionTLS12,
+	}), nil
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	conn   *grpc.ClientConn
+	client remotepb.SignerClient
+
+	timeout time.Duration
+	budget  time.Duration
+	retries int
+	backoff time.Duration
+
+	localVerify bool
+
+	// keys maps the hex encoded SKI to the remote keys already resolved
+	keysMutex sync.RWMutex
+	keys      map[string]*remoteKey
+}
+
+// retryable reports whether a failed call may succeed when attempted again.
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+// invoke runs call with a per attempt timeout, retrying transient failures
+// with a linear backoff until the retries or the latency budget run out.
+func (csp *impl) invoke(method string, call func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), csp.budget)
+	defer cancel()
+
+	var err error
+	for attempt := 0; attempt <= csp.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * csp.backoff):
+			case <-ctx.Done():
+				return errors.Wrapf(err, "remote %s failed, latency budget exceeded after %d attempts", method, attempt)
+			}
+		}
+
+		actx, acancel := context.WithTimeout(ctx, csp.timeout)
+		err = call(actx)
+		acancel()
+		if err == nil || !retryable(err) {
+			break
+		}
+		logger.Debugf("remote %s attempt %d failed [%s]", method, attempt+1, err)
+	}
+	if err != nil {
+		return errors.Wrapf(codeOf(err), "remote %s failed", method)
+	}
+	return nil
+}
+
+// codeOf maps the status returned by the signer service back to a typed
+// BCCSP error, so that callers can branch on bccsp.ErrorCodeOf.
+func codeOf(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "%s", st.Message())
+	case codes.InvalidArgument:
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "%s", st.Message())
+	case codes.Unimplemented:
+		return bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "%s", st.Message())
+	default:
+		return err
+	}
+}
+
+// bindKey imports the public part of a remote key and records the key under its SKI.
+func (csp *impl) bindKey(resp *remotepb.KeyResponse) (*remoteKey, error) {
+	var opts bccsp.KeyImportOpts
+	switch resp.Algorithm {
+	case bccsp.SM2:
+		opts = &bccsp.SM2PKIXPublicKeyImportOpts{Temporary: true}
+	case bccsp.ECDSA:
+		opts = &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true}
+	default:
+		return nil, errors.Errorf("Unsupported remote key algorithm [%s]", resp.Algorithm)
+	}
+
+	pub, err := csp.BCCSP.KeyImport(resp.PublicKey, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed importing remote public key")
+	}
+
+	k := &remoteKey{ski: resp.Ski, algorithm: resp.Algorithm, pub: pub}
+	csp.keysMutex.Lock()
+	csp.keys[hex.EncodeToString(k.ski)] = k
+	csp.keysMutex.Unlock()
+	return k, nil
+}
+
+// KeyGen generates a key using opts. Asymmetric signing keys are generated
+// by the signer service, all other keys by the software fallback.
+func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	// Validate arguments
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil")
+	}
+
+	switch o := opts.(type) {
+	case *bccsp.SM2KeyGenOpts:
+		if o.Curve != nil || o.CurveOID != nil || o.Usage == bccsp.SM2KeyUsageEncrypt {
+			return csp.BCCSP.KeyGen(opts)
+		}
+	case *bccsp.ECDSAKeyGenOpts, *bccsp.ECDSAP256KeyGenOpts, *bccsp.ECDSAP384KeyGenOpts:
+	default:
+		return csp.BCCSP.KeyGen(opts)
+	}
+
+	var resp *remotepb.KeyResponse
+	err := csp.invoke("KeyGen", func(ctx context.Context) (err error) {
+		resp, err = csp.client.KeyGen(ctx, &remotepb.KeyGenRequest{
+			Algorithm: opts.Algorithm(),
+			Ephemeral: opts.Ephemeral(),
+		})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return csp.bindKey(resp)
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski. Keys unknown to the signer service are
+// looked up in the local KeyStore.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	csp.keysMutex.RLock()
+	k, ok := csp.keys[hex.EncodeToString(ski)]
+	csp.keysMutex.RUnlock()
+	if ok {
+		return k, nil
+	}
+
+	var resp *remotepb.KeyResponse
+	err := csp.invoke("GetKey", func(ctx context.Context) (err error) {
+		resp, err = csp.client.GetKey(ctx, &remotepb.KeyRequest{Ski: ski})
+		return
+	})
+	if err == nil {
+		return csp.bindKey(resp)
+	}
+	if bccsp.ErrorCodeOf(err) != bccsp.ErrCodeKeyNotFound {
+		logger.Warningf("Failed looking up key %x on signer service [%s], trying local key store", ski, err)
+	}
+	return csp.BCCSP.GetKey(ski)
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	key, ok := k.(*remoteKey)
+	if !ok {
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+
+	var resp *remotepb.SignResponse
+	err := csp.invoke("Sign", func(ctx context.Context) (err error) {
+		resp, err = csp.client.Sign(ctx, &remotepb.SignRequest{
+			Ski:      key.ski,
+			Digest:   digest,
+			Encoding: uint32(bccsp.SM2SignatureEncodingOf(opts)),
+		})
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Signature, nil
+}
+
+// Verify verifies signature against key k and digest
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	// Validate arguments
+	if k == nil {
+		return false, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(signature) == 0 {
+		return false, errors.New("Invalid signature. Cannot be empty")
+	}
+	if len(digest) == 0 {
+		return false, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	key, ok := k.(*remoteKey)
+	if !ok {
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+	if csp.localVerify {
+		return csp.BCCSP.Verify(key.pub, signature, digest, opts)
+	}
+
+	var resp *remotepb.VerifyResponse
+	err := csp.invoke("Verify", func(ctx context.Context) (err error) {
+		resp, err = csp.client.Verify(ctx, &remotepb.VerifyRequest{
+			Ski:       key.ski,
+			Signature: signature,
+			Digest:    digest,
+			Encoding:  uint32(bccsp.SM2SignatureEncodingOf(opts)),
+		})
+		return
+	})
+	if err != nil {
+		return false, err
+	}
+	return resp.Valid, nil
+}
@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote/remotepb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyServer fails the first failures Sign calls with codes.Unavailable.
+type flakyServer struct {
+	remotepb.SignerServer
+	failures int
+	calls    int
+}
+
+func (s *flakyServer) Sign(ctx context.Context, req *remotepb.SignRequest) (*remotepb.SignResponse, error) {
+	s.calls++
+	if s.calls <= s.failures {
+		return nil, status.Error(codes.Unavailable, "signer busy")
+	}
+	return s.SignerServer.Sign(ctx, req)
+}
+
+func newTestCSP(t *testing.T, srv remotepb.SignerServer, opts RemoteOpts) (*impl, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	gs := grpc.NewServer()
+	remotepb.RegisterSignerServer(gs, srv)
+	go gs.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+
+	opts.SecLevel = 256
+	opts.HashFamily = "SM3"
+	csp, err := newWithConn(opts, sw.NewDummyKeyStore(), conn)
+	require.NoError(t, err)
+
+	return csp, func() {
+		conn.Close()
+		gs.Stop()
+	}
+}
+
+func newBackend(t *testing.T) bccsp.BCCSP {
+	backend, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	return backend
+}
+
+func TestNewRequiresMutualTLS(t *testing.T) {
+	_, err := New(RemoteOpts{}, sw.NewDummyKeyStore())
+	assert.EqualError(t, err, "Invalid options. Address of the signer service must be set")
+
+	_, err = New(RemoteOpts{Address: "127.0.0.1:7070"}, sw.NewDummyKeyStore())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "required for mutual TLS")
+}
+
+func TestSM2SignVerify(t *testing.T) {
+	backend := newBackend(t)
+	csp, cleanup := newTestCSP(t, NewServer(backend), RemoteOpts{})
+	defer cleanup()
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+	assert.True(t, k.Private())
+	_, err = k.Bytes()
+	assert.Error(t, err)
+
+	// The private key never leaves the signer service
+	local, err := backend.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.True(t, local.Private())
+
+	msg := []byte("hello remote signer")
+	sig, err := csp.Sign(k, msg, nil)
+	require.NoError(t, err)
+
+	valid, err := csp.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	valid, err = csp.Verify(pub, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = csp.Verify(k, sig, []byte("tampered"), nil)
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	raw, err := csp.Sign(k, msg, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureRaw})
+	require.NoError(t, err)
+	assert.Len(t, raw, 64)
+}
+
+func TestLocalVerify(t *testing.T) {
+	srv := &flakyServer{SignerServer: NewServer(newBackend(t))}
+	csp, cleanup := newTestCSP(t, srv, RemoteOpts{LocalVerify: true})
+	defer cleanup()
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+	sig, err := csp.Sign(k, []byte("msg"), nil)
+	require.NoError(t, err)
+
+	valid, err := csp.Verify(k, sig, []byte("msg"), nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestGetKey(t *testing.T) {
+	backend := newBackend(t)
+	csp, cleanup := newTestCSP(t, NewServer(backend), RemoteOpts{})
+	defer cleanup()
+
+	k, err := backend.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+
+	rk, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.IsType(t, &remoteKey{}, rk)
+	assert.Equal(t, k.SKI(), rk.SKI())
+
+	_, err = csp.GetKey([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestRetries(t *testing.T) {
+	srv := &flakyServer{SignerServer: NewServer(newBackend(t)), failures: 2}
+	csp, cleanup := newTestCSP(t, srv, RemoteOpts{Retries: 2, RetryBackoff: time.Millisecond})
+	defer cleanup()
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+
+	_, err = csp.Sign(k, []byte("msg"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, srv.calls)
+
+	srv.calls, srv.failures = 0, 5
+	_, err = csp.Sign(k, []byte("msg"), nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "remote Sign failed")
+	assert.Equal(t, 3, srv.calls)
+}
+
+func TestErrorMapping(t *testing.T) {
+	assert.Equal(t, codes.NotFound, status.Code(toStatus(bccsp.ErrKeyNotFound)))
+	assert.Equal(t, codes.Unimplemented, status.Code(toStatus(bccsp.Errorf(bccsp.ErrCodeUnsupportedKeyType, "x"))))
+	assert.Equal(t, codes.Internal, status.Code(toStatus(assert.AnError)))
+
+	err := codeOf(status.Error(codes.NotFound, "no such key"))
+	assert.Equal(t, bccsp.ErrCodeKeyNotFound, bccsp.ErrorCodeOf(err))
+}
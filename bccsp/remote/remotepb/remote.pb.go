@@ -0,0 +1,586 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: remote.proto
+
+package remotepb
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	grpc "google.golang.org/grpc"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+// KeyGenRequest asks the signer to generate a key pair for the algorithm
+// of the BCCSP key generation options, e.g. SM2 or ECDSAP256.
+type KeyGenRequest struct {
+	Algorithm            string   `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	Ephemeral            bool     `protobuf:"varint,2,opt,name=ephemeral,proto3" json:"ephemeral,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyGenRequest) Reset()         { *m = KeyGenRequest{} }
+func (m *KeyGenRequest) String() string { return proto.CompactTextString(m) }
+func (*KeyGenRequest) ProtoMessage()    {}
+func (*KeyGenRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eefc82927d57d89b, []int{0}
+}
+
+func (m *KeyGenRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyGenRequest.Unmarshal(m, b)
+}
+func (m *KeyGenRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyGenRequest.Marshal(b, m, deterministic)
+}
+func (m *KeyGenRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyGenRequest.Merge(m, src)
+}
+func (m *KeyGenRequest) XXX_Size() int {
+	return xxx_messageInfo_KeyGenRequest.Size(m)
+}
+func (m *KeyGenRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyGenRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyGenRequest proto.InternalMessageInfo
+
+func (m *KeyGenRequest) GetAlgorithm() string {
+	if m != nil {
+		return m.Algorithm
+	}
+	return ""
+}
+
+func (m *KeyGenRequest) GetEphemeral() bool {
+	if m != nil {
+		return m.Ephemeral
+	}
+	return false
+}
+
+type KeyRequest struct {
+	Ski                  []byte   `protobuf:"bytes,1,opt,name=ski,proto3" json:"ski,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyRequest) Reset()         { *m = KeyRequest{} }
+func (m *KeyRequest) String() string { return proto.CompactTextString(m) }
+func (*KeyRequest) ProtoMessage()    {}
+func (*KeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eefc82927d57d89b, []int{1}
+}
+
+func (m *KeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyRequest.Unmarshal(m, b)
+}
+func (m *KeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyRequest.Marshal(b, m, deterministic)
+}
+func (m *KeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyRequest.Merge(m, src)
+}
+func (m *KeyRequest) XXX_Size() int {
+	return xxx_messageInfo_KeyRequest.Size(m)
+}
+func (m *KeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyRequest proto.InternalMessageInfo
+
+func (m *KeyRequest) GetSki() []byte {
+	if m != nil {
+		return m.Ski
+	}
+	return nil
+}
+
+// KeyResponse describes a key held by the signer. The public key is
+// encoded in PKIX, ASN.1 DER form.
+type KeyResponse struct {
+	Ski                  []byte   `protobuf:"bytes,1,opt,name=ski,proto3" json:"ski,omitempty"`
+	PublicKey            []byte   `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Private              bool     `protobuf:"varint,3,opt,name=private,proto3" json:"private,omitempty"`
+	Algorithm            string   `protobuf:"bytes,4,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyResponse) Reset()         { *m = KeyResponse{} }
+func (m *KeyResponse) String() string { return proto.CompactTextString(m) }
+func (*KeyResponse) ProtoMessage()    {}
+func (*KeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eefc82927d57d89b, []int{2}
+}
+
+func (m *KeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyResponse.Unmarshal(m, b)
+}
+func (m *KeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyResponse.Marshal(b, m, deterministic)
+}
+func (m *KeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyResponse.Merge(m, src)
+}
+func (m *KeyResponse) XXX_Size() int {
+	return xxx_messageInfo_KeyResponse.Size(m)
+}
+func (m *KeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyResponse proto.InternalMessageInfo
+
+func (m *KeyResponse) GetSki() []byte {
+	if m != nil {
+		return m.Ski
+	}
+	return nil
+}
+
+func (m *KeyResponse) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+func (m *KeyResponse) GetPrivate() bool {
+	if m != nil {
+		return m.Private
+	}
+	return false
+}
+
+func (m *KeyResponse) GetAlgorithm() string {
+	if m != nil {
+		return m.Algorithm
+	}
+	return ""
+}
+
+// SignRequest asks for a signature of digest with the key identified by
+// ski. For SM2 keys, encoding selects the bccsp.SM2SignatureEncoding.
+type SignRequest struct {
+	Ski                  []byte   `protobuf:"bytes,1,opt,name=ski,proto3" json:"ski,omitempty"`
+	Digest               []byte   `protobuf:"bytes,2,opt,name=digest,proto3" json:"digest,omitempty"`
+	Encoding             uint32   `protobuf:"varint,3,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignRequest) Reset()         { *m = SignRequest{} }
+func (m *SignRequest) String() string { return proto.CompactTextString(m) }
+func (*SignRequest) ProtoMessage()    {}
+func (*SignRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eefc82927d57d89b, []int{3}
+}
+
+func (m *SignRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignRequest.Unmarshal(m, b)
+}
+func (m *SignRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignRequest.Marshal(b, m, deterministic)
+}
+func (m *SignRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignRequest.Merge(m, src)
+}
+func (m *SignRequest) XXX_Size() int {
+	return xxx_messageInfo_SignRequest.Size(m)
+}
+func (m *SignRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignRequest proto.InternalMessageInfo
+
+func (m *SignRequest) GetSki() []byte {
+	if m != nil {
+		return m.Ski
+	}
+	return nil
+}
+
+func (m *SignRequest) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+func (m *SignRequest) GetEncoding() uint32 {
+	if m != nil {
+		return m.Encoding
+	}
+	return 0
+}
+
+type SignResponse struct {
+	Signature            []byte   `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignResponse) Reset()         { *m = SignResponse{} }
+func (m *SignResponse) String() string { return proto.CompactTextString(m) }
+func (*SignResponse) ProtoMessage()    {}
+func (*SignResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eefc82927d57d89b, []int{4}
+}
+
+func (m *SignResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignResponse.Unmarshal(m, b)
+}
+func (m *SignResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignResponse.Marshal(b, m, deterministic)
+}
+func (m *SignResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignResponse.Merge(m, src)
+}
+func (m *SignResponse) XXX_Size() int {
+	return xxx_messageInfo_SignResponse.Size(m)
+}
+func (m *SignResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignResponse proto.InternalMessageInfo
+
+func (m *SignResponse) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type VerifyRequest struct {
+	Ski                  []byte   `protobuf:"bytes,1,opt,name=ski,proto3" json:"ski,omitempty"`
+	Signature            []byte   `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	Digest               []byte   `protobuf:"bytes,3,opt,name=digest,proto3" json:"digest,omitempty"`
+	Encoding             uint32   `protobuf:"varint,4,opt,name=encoding,proto3" json:"encoding,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VerifyRequest) Reset()         { *m = VerifyRequest{} }
+func (m *VerifyRequest) String() string { return proto.CompactTextString(m) }
+func (*VerifyRequest) ProtoMessage()    {}
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eefc82927d57d89b, []int{5}
+}
+
+func (m *VerifyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VerifyRequest.Unmarshal(m, b)
+}
+func (m *VerifyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VerifyRequest.Marshal(b, m, deterministic)
+}
+func (m *VerifyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VerifyRequest.Merge(m, src)
+}
+func (m *VerifyRequest) XXX_Size() int {
+	return xxx_messageInfo_VerifyRequest.Size(m)
+}
+func (m *VerifyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VerifyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VerifyRequest proto.InternalMessageInfo
+
+func (m *VerifyRequest) GetSki() []byte {
+	if m != nil {
+		return m.Ski
+	}
+	return nil
+}
+
+func (m *VerifyRequest) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *VerifyRequest) GetDigest() []byte {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+func (m *VerifyRequest) GetEncoding() uint32 {
+	if m != nil {
+		return m.Encoding
+	}
+	return 0
+}
+
+type VerifyResponse struct {
+	Valid                bool     `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *VerifyResponse) Reset()         { *m = VerifyResponse{} }
+func (m *VerifyResponse) String() string { return proto.CompactTextString(m) }
+func (*VerifyResponse) ProtoMessage()    {}
+func (*VerifyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_eefc82927d57d89b, []int{6}
+}
+
+func (m *VerifyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VerifyResponse.Unmarshal(m, b)
+}
+func (m *VerifyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VerifyResponse.Marshal(b, m, deterministic)
+}
+func (m *VerifyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VerifyResponse.Merge(m, src)
+}
+func (m *VerifyResponse) XXX_Size() int {
+	return xxx_messageInfo_VerifyResponse.Size(m)
+}
+func (m *VerifyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_VerifyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_VerifyResponse proto.InternalMessageInfo
+
+func (m *VerifyResponse) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func init() {
+	proto.RegisterType((*KeyGenRequest)(nil), "remotepb.KeyGenRequest")
+	proto.RegisterType((*KeyRequest)(nil), "remotepb.KeyRequest")
+	proto.RegisterType((*KeyResponse)(nil), "remotepb.KeyResponse")
+	proto.RegisterType((*SignRequest)(nil), "remotepb.SignRequest")
+	proto.RegisterType((*SignResponse)(nil), "remotepb.SignResponse")
+	proto.RegisterType((*VerifyRequest)(nil), "remotepb.VerifyRequest")
+	proto.RegisterType((*VerifyResponse)(nil), "remotepb.VerifyResponse")
+}
+
+func init() { proto.RegisterFile("remote.proto", fileDescriptor_eefc82927d57d89b) }
+
+var fileDescriptor_eefc82927d57d89b = []byte{
+	// 398 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x85, 0x53, 0x4d, 0x4b, 0xc3, 0x40,
+	0x10, 0xa5, 0x1f, 0xc6, 0x74, 0x9a, 0x8a, 0x2c, 0xb5, 0x86, 0xa0, 0x22, 0x39, 0x88, 0x07, 0x9b,
+	0x60, 0x45, 0x10, 0xbc, 0x48, 0x2f, 0x3d, 0xf4, 0x96, 0x82, 0x07, 0x2f, 0x92, 0xa4, 0xd3, 0x74,
+	0x69, 0xbe, 0xdc, 0x6c, 0x0a, 0xfd, 0xd1, 0xfe, 0x07, 0xf3, 0xd9, 0x26, 0x29, 0xc5, 0x5b, 0xde,
+	0xbc, 0x9d, 0xf7, 0xde, 0xec, 0x4e, 0x40, 0x62, 0xe8, 0x05, 0x1c, 0xb5, 0x90, 0x05, 0x3c, 0x20,
+	0x62, 0x8e, 0x42, 0x4b, 0x9d, 0xc3, 0x60, 0x8e, 0xbb, 0x19, 0xfa, 0x06, 0xfe, 0xc4, 0x18, 0x71,
+	0x72, 0x03, 0x3d, 0xd3, 0x75, 0x02, 0x46, 0xf9, 0xda, 0x93, 0x5b, 0xf7, 0xad, 0xc7, 0x9e, 0x71,
+	0x28, 0xa4, 0x2c, 0x86, 0x6b, 0xf4, 0x90, 0x99, 0xae, 0xdc, 0x4e, 0x58, 0xd1, 0x38, 0x14, 0xd4,
+	0x3b, 0x80, 0x44, 0xac, 0x54, 0xba, 0x84, 0x4e, 0xb4, 0xa1, 0x99, 0x86, 0x64, 0xa4, 0x9f, 0xea,
+	0x16, 0xfa, 0x19, 0x1f, 0x85, 0x81, 0x1f, 0xe1, 0xf1, 0x01, 0x72, 0x0b, 0x10, 0xc6, 0x96, 0x4b,
+	0xed, 0xef, 0x0d, 0xee, 0x32, 0x7d, 0xc9, 0xe8, 0xe5, 0x95, 0xa4, 0x91, 0xc8, 0x70, 0x1e, 0x32,
+	0xba, 0x35, 0x39, 0xca, 0x9d, 0xcc, 0xbb, 0x84, 0xf5, 0xd4, 0xdd, 0x46, 0x6a, 0x75, 0x01, 0xfd,
+	0x05, 0x75, 0xfc, 0x93, 0xc1, 0xc8, 0x08, 0x84, 0x25, 0x75, 0x12, 0xae, 0xf0, 0x2c, 0x10, 0x51,
+	0x40, 0x44, 0xdf, 0x0e, 0x96, 0xd4, 0x77, 0x32, 0xc7, 0x81, 0xb1, 0xc7, 0xea, 0x13, 0x48, 0xb9,
+	0x68, 0x31, 0x4d, 0x12, 0x21, 0x4a, 0xb0, 0xc9, 0x63, 0x86, 0x85, 0xf6, 0xa1, 0xa0, 0x46, 0x30,
+	0xf8, 0x44, 0x46, 0x57, 0xa7, 0x6f, 0xa7, 0x2e, 0xd0, 0x6e, 0x08, 0x54, 0x22, 0x76, 0x4e, 0x46,
+	0xec, 0x36, 0x22, 0x3e, 0xc0, 0x45, 0x69, 0x5a, 0x84, 0x1c, 0xc2, 0xd9, 0xd6, 0x74, 0xe9, 0x32,
+	0xf3, 0x15, 0x8d, 0x1c, 0x4c, 0x7e, 0x5b, 0x20, 0xa4, 0xb3, 0x20, 0x23, 0x6f, 0x20, 0xe4, 0xfb,
+	0x40, 0xae, 0xb5, 0x72, 0x49, 0xb4, 0xda, 0x86, 0x28, 0x57, 0x35, 0x62, 0x2f, 0xfd, 0x0a, 0xc2,
+	0x0c, 0x79, 0xfa, 0x4c, 0xc3, 0xc6, 0x81, 0x7f, 0xda, 0xba, 0xa9, 0x35, 0xa9, 0xd0, 0x95, 0xb7,
+	0x52, 0x46, 0xcd, 0x72, 0xd1, 0xf6, 0x0e, 0x42, 0x3e, 0x5a, 0x35, 0x67, 0xed, 0x86, 0x15, 0xf9,
+	0x98, 0xc8, 0x9b, 0xa7, 0xd3, 0xaf, 0x0f, 0x27, 0x59, 0x8c, 0xd8, 0xd2, 0xec, 0xc0, 0xd3, 0x43,
+	0x33, 0x76, 0xc7, 0x2e, 0xe2, 0xd8, 0xe4, 0x3c, 0x60, 0x3e, 0xee, 0xf4, 0x95, 0x69, 0x31, 0x6a,
+	0x8f, 0x27, 0xda, 0xf3, 0xd8, 0xf1, 0x74, 0xcb, 0xb6, 0xa3, 0x50, 0xcf, 0xb5, 0xf4, 0x52, 0xd2,
+	0x12, 0xb2, 0x3f, 0xe9, 0xe5, 0x0f, 0x2c, 0xb8, 0x7c, 0xaa, 0x59, 0x03, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// SignerClient is the client API for Signer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SignerClient interface {
+	KeyGen(ctx context.Context, in *KeyGenRequest, opts ...grpc.CallOption) (*KeyResponse, error)
+	GetKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error)
+	Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error)
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error)
+}
+
+type signerClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewSignerClient(cc *grpc.ClientConn) SignerClient {
+	return &signerClient{cc}
+}
+
+func (c *signerClient) KeyGen(ctx context.Context, in *KeyGenRequest, opts ...grpc.CallOption) (*KeyResponse, error) {
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, "/remotepb.Signer/KeyGen", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signerClient) GetKey(ctx context.Context, in *KeyRequest, opts ...grpc.CallOption) (*KeyResponse, error) {
+	out := new(KeyResponse)
+	err := c.cc.Invoke(ctx, "/remotepb.Signer/GetKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signerClient) Sign(ctx context.Context, in *SignRequest, opts ...grpc.CallOption) (*SignResponse, error) {
+	out := new(SignResponse)
+	err := c.cc.Invoke(ctx, "/remotepb.Signer/Sign", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signerClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*VerifyResponse, error) {
+	out := new(VerifyResponse)
+	err := c.cc.Invoke(ctx, "/remotepb.Signer/Verify", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SignerServer is the server API for Signer service.
+type SignerServer interface {
+	KeyGen(context.Context, *KeyGenRequest) (*KeyResponse, error)
+	GetKey(context.Context, *KeyRequest) (*KeyResponse, error)
+	Sign(context.Context, *SignRequest) (*SignResponse, error)
+	Verify(context.Context, *VerifyRequest) (*VerifyResponse, error)
+}
+
+func RegisterSignerServer(s *grpc.Server, srv SignerServer) {
+	s.RegisterService(&_Signer_serviceDesc, srv)
+}
+
+func _Signer_KeyGen_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyGenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).KeyGen(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remotepb.Signer/KeyGen",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).KeyGen(ctx, req.(*KeyGenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Signer_GetKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).GetKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remotepb.Signer/GetKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).GetKey(ctx, req.(*KeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Signer_Sign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).Sign(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remotepb.Signer/Sign",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).Sign(ctx, req.(*SignRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Signer_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SignerServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/remotepb.Signer/Verify",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SignerServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _Signer_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "remotepb.Signer",
+	HandlerType: (*SignerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "KeyGen",
+			Handler:    _Signer_KeyGen_Handler,
+		},
+		{
+			MethodName: "GetKey",
+			Handler:    _Signer_GetKey_Handler,
+		},
+		{
+			MethodName: "Sign",
+			Handler:    _Signer_Sign_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _Signer_Verify_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "remote.proto",
+}
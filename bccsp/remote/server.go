@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/remote/remotepb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// server is the reference signer service, it serves the keys of a local CSP.
+type server struct {
+	csp bccsp.BCCSP
+}
+
+// NewServer returns a signer service backed by csp, typically a software
+// BCCSP with a file based KeyStore.
+func NewServer(csp bccsp.BCCSP) remotepb.SignerServer {
+	return &server{csp: csp}
+}
+
+func keyGenOpts(req *remotepb.KeyGenRequest) (bccsp.KeyGenOpts, error) {
+	switch req.Algorithm {
+	case bccsp.SM2:
+		return &bccsp.SM2KeyGenOpts{Temporary: req.Ephemeral}, nil
+	case bccsp.ECDSA:
+		return &bccsp.ECDSAKeyGenOpts{Temporary: req.Ephemeral}, nil
+	case bccsp.ECDSAP256:
+		return &bccsp.ECDSAP256KeyGenOpts{Temporary: req.Ephemeral}, nil
+	case bccsp.ECDSAP384:
+		return &bccsp.ECDSAP384KeyGenOpts{Temporary: req.Ephemeral}, nil
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported algorithm [%s]", req.Algorithm)
+	}
+}
+
+// signerOpts returns the signer options carrying the requested SM2 encoding.
+func signerOpts(encoding uint32) bccsp.SignerOpts {
+	if bccsp.SM2SignatureEncoding(encoding) == bccsp.SM2SignatureDER {
+		return nil
+	}
+	return &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureEncoding(encoding)}
+}
+
+// toStatus maps a BCCSP error to the corresponding gRPC status.
+func toStatus(err error) error {
+	var code codes.Code
+	switch bccsp.ErrorCodeOf(err) {
+	case bccsp.ErrCodeKeyNotFound:
+		code = codes.NotFound
+	case bccsp.ErrCodeInvalidArgument:
+		code = codes.InvalidArgument
+	case bccsp.ErrCodeUnsupportedAlgorithm, bccsp.ErrCodeUnsupportedKeyType:
+		code = codes.Unimplemented
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+func keyResponse(k bccsp.Key) (*remotepb.KeyResponse, error) {
+	pk, ok := k.(bccsp.CryptoPublicKeyer)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "key does not expose its public key")
+	}
+	pub, err := pk.CryptoPublicKey()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &remotepb.KeyResponse{Ski: k.SKI(), Private: k.Private()}
+	switch p := pub.(type) {
+	case *sm2.PublicKey:
+		resp.Algorithm = bccsp.SM2
+		resp.PublicKey, err = utils.MarshalPKIXSM2PublicKey(p)
+	case *ecdsa.PublicKey:
+		resp.Algorithm = bccsp.ECDSA
+		resp.PublicKey, err = x509.MarshalPKIXPublicKey(p)
+	default:
+		return nil, status.Errorf(codes.Unimplemented, "unsupported public key type [%T]", pub)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed marshalling public key [%s]", err)
+	}
+	return resp, nil
+}
+
+func (s *server) privateKey(ski []byte) (bccsp.Key, error) {
+	k, err := s.csp.GetKey(ski)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return k, nil
+}
+
+// KeyGen generates a key pair and returns its public part.
+func (s *server) KeyGen(ctx context.Context, req *remotepb.KeyGenRequest) (*remotepb.KeyResponse, error) {
+	opts, err := keyGenOpts(req)
+	if err != nil {
+		return nil, err
+	}
+	k, err := s.csp.KeyGen(opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return keyResponse(k)
+}
+
+// GetKey returns the public part of the key identified by the SKI.
+func (s *server) GetKey(ctx context.Context, req *remotepb.KeyRequest) (*remotepb.KeyResponse, error) {
+	k, err := s.privateKey(req.Ski)
+	if err != nil {
+		return nil, err
+	}
+	return keyResponse(k)
+}
+
+// Sign signs the digest with the key identified by the SKI.
+func (s *server) Sign(ctx context.Context, req *remotepb.SignRequest) (*remotepb.SignResponse, error) {
+	k, err := s.privateKey(req.Ski)
+	if err != nil {
+		return nil, err
+	}
+	if !k.Private() {
+		return nil, status.Errorf(codes.InvalidArgument, "key %x is not a private key", req.Ski)
+	}
+	sig, err := s.csp.Sign(k, req.Digest, signerOpts(req.Encoding))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &remotepb.SignResponse{Signature: sig}, nil
+}
+
+// Verify verifies the signature against the key identified by the SKI.
+func (s *server) Verify(ctx context.Context, req *remotepb.VerifyRequest) (*remotepb.VerifyResponse, error) {
+	k, err := s.privateKey(req.Ski)
+	if err != nil {
+		return nil, err
+	}
+	valid, err := s.csp.Verify(k, req.Signature, req.Digest, signerOpts(req.Encoding))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &remotepb.VerifyResponse{Valid: valid}, nil
+}
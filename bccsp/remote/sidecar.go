@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+)
+
+const defaultStartTimeout = 10 * time.Second
+
+// SidecarOpts contains the options of a signer service run as a sidecar
+// process of the node, e.g. a vendor's certified GM implementation. The
+// sidecar must serve the Signer gRPC service of remotepb on Address, with
+// the mutual TLS settings of RemoteOpts.
+type SidecarOpts struct {
+	RemoteOpts `mapstructure:",squash"`
+
+	// Path of the executable of the sidecar, its arguments and the
+	// additional environment variables, as KEY=VALUE, it is started with
+	Path string   `mapstructure:"path" json:"path"`
+	Args []string `mapstructure:"args,omitempty" json:"args,omitempty"`
+	Env  []string `mapstructure:"env,omitempty" json:"env,omitempty"`
+
+	// StartTimeout bounds the time the sidecar takes to listen on Address
+	StartTimeout time.Duration `mapstructure:"starttimeout,omitempty" json:"starttimeout,omitempty"`
+}
+
+// NewSidecar starts the sidecar process of opts, waits until it listens on
+// its address and returns a BCCSP forwarding to it. The sidecar is stopped
+// when the node exits.
+func NewSidecar(opts SidecarOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	if opts.Path == "" {
+		return nil, errors.New("Invalid options. Path of the sidecar must be set")
+	}
+	if opts.Address == "" {
+		return nil, errors.New("Invalid options. Address of the signer service must be set")
+	}
+
+	cmd := exec.Command(opts.Path, opts.Args...)
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = sidecarProcAttr()
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "Failed starting sidecar %s", opts.Path)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		logger.Errorf("Sidecar %s exited [%v]", opts.Path, err)
+		exited <- err
+	}()
+
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = defaultStartTimeout
+	}
+	if err := waitListening(opts.Address, timeout, exited); err != nil {
+		cmd.Process.Kill()
+		return nil, errors.WithMessagef(err, "Sidecar %s is not serving on %s", opts.Path, opts.Address)
+	}
+	logger.Infof("Sidecar %s [pid %d] serving on %s", opts.Path, cmd.Process.Pid, opts.Address)
+
+	csp, err := New(opts.RemoteOpts, keyStore)
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, err
+	}
+	return csp, nil
+}
+
+// waitListening polls address until it accepts connections, the process
+// exits or timeout elapses.
+func waitListening(address string, timeout time.Duration, exited <-chan error) error {
+	deadline := time.After(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+
+		select {
+		case err := <-exited:
+			return errors.Errorf("the process exited [%v]", err)
+		case <-deadline:
+			return errors.Errorf("timed out after %s", timeout)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
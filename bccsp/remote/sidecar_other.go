@@ -0,0 +1,17 @@
+// +build !linux
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import "syscall"
+
+// sidecarProcAttr returns nil, the sidecar outlives a node killed abruptly
+// on platforms without a parent death signal.
+func sidecarProcAttr() *syscall.SysProcAttr {
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package remote
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSidecarInvalidArgs(t *testing.T) {
+	_, err := NewSidecar(SidecarOpts{}, sw.NewDummyKeyStore())
+	assert.EqualError(t, err, "Invalid options. Path of the sidecar must be set")
+
+	_, err = NewSidecar(SidecarOpts{Path: "/bin/true"}, sw.NewDummyKeyStore())
+	assert.EqualError(t, err, "Invalid options. Address of the signer service must be set")
+
+	_, err = NewSidecar(SidecarOpts{Path: "/nonexistent/signer", RemoteOpts: RemoteOpts{Address: "127.0.0.1:0"}}, sw.NewDummyKeyStore())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed starting sidecar /nonexistent/signer")
+}
+
+func TestNewSidecarNotServing(t *testing.T) {
+	// A free port nothing listens on
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := lis.Addr().String()
+	lis.Close()
+
+	_, err = NewSidecar(SidecarOpts{
+		RemoteOpts: RemoteOpts{Address: address},
+		Path:       "/bin/false",
+	}, sw.NewDummyKeyStore())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the process exited")
+
+	_, err = NewSidecar(SidecarOpts{
+		RemoteOpts:   RemoteOpts{Address: address},
+		Path:         "/bin/sleep",
+		Args:         []string{"10"},
+		StartTimeout: 300 * time.Millisecond,
+	}, sw.NewDummyKeyStore())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out after 300ms")
+}
+
+func TestNewSidecarServing(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	// The sidecar is ready once its address accepts connections, the
+	// signer service is then dialed with the mutual TLS settings
+	_, err = NewSidecar(SidecarOpts{
+		RemoteOpts: RemoteOpts{Address: lis.Addr().String()},
+		Path:       "/bin/sleep",
+		Args:       []string{"10"},
+	}, sw.NewDummyKeyStore())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "required for mutual TLS")
+}
@@ -0,0 +1,295 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"hash"
+	"sync"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+// Algorithm families operations are routed by.
+const (
+	FamilyECDSA = bccsp.ECDSA
+	FamilySM2   = bccsp.SM2
+	FamilySHA2  = bccsp.SHA2
+	FamilySHA3  = bccsp.SHA3
+	FamilySM3   = bccsp.SM3
+	FamilyAES   = bccsp.AES
+	FamilySM4   = bccsp.SM4
+)
+
+// RoutingOpts configures the providers of a routing BCCSP.
+type RoutingOpts struct {
+	// Default is the name of the provider serving the algorithm families
+	// without a route, and the keys whose family is unknown
+	Default string `mapstructure:"default" json:"default" yaml:"Default"`
+	// Routes maps algorithm families, i.e. ECDSA, SM2, SHA2, SHA3, SM3,
+	// AES and SM4, to the names of the providers serving them
+	Routes map[string]string `mapstructure:"routes,omitempty" json:"routes,omitempty" yaml:"Routes"`
+}
+
+// New returns a BCCSP that dispatches every operation to one of several
+// providers, so that a node can, for instance, sign with SM2 keys of a GM
+// provider and ECDSA keys of an HSM. Key generation, import and hashing
+// are routed by the algorithm of their options, the other operations by
+// the type of their key. routes maps algorithm families to their
+// providers, the other families are served by defaultCSP.
+func New(defaultCSP bccsp.BCCSP, routes map[string]bccsp.BCCSP) (bccsp.BCCSP, error) {
+	if defaultCSP == nil {
+		return nil, errors.New("Invalid default BCCSP. It must be different from nil")
+	}
+
+	csp := &impl{
+		defaultCSP: defaultCSP,
+		routes:     map[string]bccsp.BCCSP{},
+		providers:  []bccsp.BCCSP{defaultCSP},
+	}
+	for family, provider := range routes {
+		if !isFamily(family) {
+			return nil, errors.Errorf("Unknown algorithm family [%s]", family)
+		}
+		if provider == nil {
+			return nil, errors.Errorf("Invalid BCCSP for algorithm family [%s]. It must be different from nil", family)
+		}
+		csp.routes[family] = provider
+		if !csp.hasProvider(provider) {
+			csp.providers = append(csp.providers, provider)
+		}
+	}
+	return csp, nil
+}
+
+type impl struct {
+	defaultCSP bccsp.BCCSP
+	routes     map[string]bccsp.BCCSP
+	// providers are the distinct providers, the default one first
+	providers []bccsp.BCCSP
+
+	// symmetricKeys maps the SKIs of symmetric keys, whose family cannot
+	// be told from the key, to their provider
+	symmetricKeys sync.Map
+}
+
+func isFamily(family string) bool {
+	switch family {
+	case FamilyECDSA, FamilySM2, FamilySHA2, FamilySHA3, FamilySM3, FamilyAES, FamilySM4:
+		return true
+	default:
+		return false
+	}
+}
+
+func (csp *impl) hasProvider(provider bccsp.BCCSP) bool {
+	for _, p := range csp.providers {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
+
+// route returns the provider of an algorithm family.
+func (csp *impl) route(family string) bccsp.BCCSP {
+	if provider, ok := csp.routes[family]; ok {
+		return provider
+	}
+	return csp.defaultCSP
+}
+
+// algorithmFamily returns the family of an algorithm of key generation,
+// derivation, import or hashing options. SHA, the hash family a provider
+// is configured with, has none.
+func algorithmFamily(algorithm string) string {
+	switch algorithm {
+	case bccsp.ECDSA, bccsp.ECDSAP256, bccsp.ECDSAP384, bccsp.ECDSAReRand:
+		return FamilyECDSA
+	case bccsp.SM2, bccsp.SM2ReRand:
+		return FamilySM2
+	case bccsp.SHA2, bccsp.SHA256, bccsp.SHA384:
+		return FamilySHA2
+	case bccsp.SHA3, bccsp.SHA3_256, bccsp.SHA3_384:
+		return FamilySHA3
+	case bccsp.SM3:
+		return FamilySM3
+	case bccsp.AES, bccsp.AES128, bccsp.AES192, bccsp.AES256, bccsp.HMAC, bccsp.HMACTruncated256:
+		return FamilyAES
+	case bccsp.SM4:
+		return FamilySM4
+	default:
+		return ""
+	}
+}
+
+// publicKeyFamily returns the family of a public key.
+func publicKeyFamily(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case *sm2.PublicKey:
+		return FamilySM2
+	case *ecdsa.PublicKey:
+		// Some x509 implementations surface SM2 keys as ECDSA keys on the SM2 curve
+		if k.Curve != nil && k.Curve.Params().P.Cmp(sm2.GetSm2P256V1().Params().P) == 0 {
+			return FamilySM2
+		}
+		return FamilyECDSA
+	default:
+		return ""
+	}
+}
+
+// importFamily returns the family of the key imported from raw with opts.
+func importFamily(raw interface{}, opts bccsp.KeyImportOpts) string {
+	if opts.Algorithm() != bccsp.X509Certificate {
+		return algorithmFamily(opts.Algorithm())
+	}
+	if cert, ok := raw.(*x509.Certificate); ok {
+		return publicKeyFamily(cert.PublicKey)
+	}
+	return ""
+}
+
+// keyFamily returns the family of an asymmetric key.
+func keyFamily(k bccsp.Key) string {
+	if k.Private() {
+		var err error
+		if k, err = k.PublicKey(); err != nil {
+			return ""
+		}
+	}
+	if cpk, ok := k.(bccsp.CryptoPublicKeyer); ok {
+		pub, err := cpk.CryptoPublicKey()
+		if err != nil {
+			return ""
+		}
+		return publicKeyFamily(pub)
+	}
+	raw, err := k.Bytes()
+	if err != nil {
+		return ""
+	}
+	pub, err := utils.DERToPublicKey(raw)
+	if err != nil {
+		return ""
+	}
+	return publicKeyFamily(pub)
+}
+
+// keyProvider returns the provider of key k.
+func (csp *impl) keyProvider(k bccsp.Key) bccsp.BCCSP {
+	if k == nil {
+		return csp.defaultCSP
+	}
+	if k.Symmetric() {
+		if provider, ok := csp.symmetricKeys.Load(string(k.SKI())); ok {
+			return provider.(bccsp.BCCSP)
+		}
+		return csp.defaultCSP
+	}
+	if family := keyFamily(k); family != "" {
+		return csp.route(family)
+	}
+	return csp.defaultCSP
+}
+
+// owned records the provider of k when it is a symmetric key.
+func (csp *impl) owned(k bccsp.Key, provider bccsp.BCCSP, err error) (bccsp.Key, error) {
+	if err == nil && k != nil && k.Symmetric() {
+		csp.symmetricKeys.Store(string(k.SKI()), provider)
+	}
+	return k, err
+}
+
+// KeyGen generates a key with the provider of the algorithm of opts.
+func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil.")
+	}
+	provider := csp.route(algorithmFamily(opts.Algorithm()))
+	k, err := provider.KeyGen(opts)
+	return csp.owned(k, provider, err)
+}
+
+// KeyDeriv derives a key from k with the provider of k.
+func (csp *impl) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (bccsp.Key, error) {
+	provider := csp.keyProvider(k)
+	dk, err := provider.KeyDeriv(k, opts)
+	return csp.owned(dk, provider, err)
+}
+
+// KeyImport imports a key with the provider of the algorithm of opts, or
+// of the public key of the certificate for X509Certificate options.
+func (csp *impl) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid opts. It must not be nil.")
+	}
+	provider := csp.route(importFamily(raw, opts))
+	k, err := provider.KeyImport(raw, opts)
+	return csp.owned(k, provider, err)
+}
+
+// GetKey returns the key of ski from the first provider that has it, the
+// default provider first.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	if provider, ok := csp.symmetricKeys.Load(string(ski)); ok {
+		return provider.(bccsp.BCCSP).GetKey(ski)
+	}
+
+	var firstErr error
+	for _, provider := range csp.providers {
+		k, err := provider.GetKey(ski)
+		if err == nil {
+			return csp.owned(k, provider, nil)
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return nil, firstErr
+}
+
+// Hash hashes msg with the provider of the algorithm of opts.
+func (csp *impl) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid opts. It must not be nil.")
+	}
+	return csp.route(algorithmFamily(opts.Algorithm())).Hash(msg, opts)
+}
+
+// GetHash returns a hash.Hash of the provider of the algorithm of opts.
+func (csp *impl) GetHash(opts bccsp.HashOpts) (hash.Hash, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid opts. It must not be nil.")
+	}
+	return csp.route(algorithmFamily(opts.Algorithm())).GetHash(opts)
+}
+
+// Sign signs digest with the provider of k.
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return csp.keyProvider(k).Sign(k, digest, opts)
+}
+
+// Verify verifies signature with the provider of k.
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	return csp.keyProvider(k).Verify(k, signature, digest, opts)
+}
+
+// Encrypt encrypts plaintext with the provider of k.
+func (csp *impl) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	return csp.keyProvider(k).Encrypt(k, plaintext, opts)
+}
+
+// Decrypt decrypts ciphertext with the provider of k.
+func (csp *impl) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	return csp.keyProvider(k).Decrypt(k, ciphertext, opts)
+}
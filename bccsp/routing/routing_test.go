@@ -0,0 +1,210 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package routing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// provider counts the operations a BCCSP serves.
+type provider struct {
+	bccsp.BCCSP
+	calls map[string]int
+}
+
+func newProvider(t *testing.T, hashFamily string) *provider {
+	csp, err := sw.NewWithParams(256, hashFamily, sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	return &provider{BCCSP: csp, calls: map[string]int{}}
+}
+
+func (p *provider) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	p.calls["KeyGen"]++
+	return p.BCCSP.KeyGen(opts)
+}
+
+func (p *provider) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	p.calls["KeyImport"]++
+	return p.BCCSP.KeyImport(raw, opts)
+}
+
+func (p *provider) GetKey(ski []byte) (bccsp.Key, error) {
+	p.calls["GetKey"]++
+	return p.BCCSP.GetKey(ski)
+}
+
+func (p *provider) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	p.calls["Hash"]++
+	return p.BCCSP.Hash(msg, opts)
+}
+
+func (p *provider) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	p.calls["Sign"]++
+	return p.BCCSP.Sign(k, digest, opts)
+}
+
+func (p *provider) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	p.calls["Verify"]++
+	return p.BCCSP.Verify(k, signature, digest, opts)
+}
+
+func (p *provider) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	p.calls["Encrypt"]++
+	return p.BCCSP.Encrypt(k, plaintext, opts)
+}
+
+// newTestCSP routes the SM algorithms to gm and the others to sw.
+func newTestCSP(t *testing.T) (bccsp.BCCSP, *provider, *provider) {
+	swCSP, gmCSP := newProvider(t, "SHA2"), newProvider(t, "SM3")
+	csp, err := New(swCSP, map[string]bccsp.BCCSP{
+		FamilySM2: gmCSP,
+		FamilySM3: gmCSP,
+		FamilySM4: gmCSP,
+	})
+	require.NoError(t, err)
+	return csp, swCSP, gmCSP
+}
+
+func TestNewInvalidArgs(t *testing.T) {
+	_, err := New(nil, nil)
+	assert.EqualError(t, err, "Invalid default BCCSP. It must be different from nil")
+
+	swCSP := newProvider(t, "SHA2")
+	_, err = New(swCSP, map[string]bccsp.BCCSP{"RSA": swCSP})
+	assert.EqualError(t, err, "Unknown algorithm family [RSA]")
+
+	_, err = New(swCSP, map[string]bccsp.BCCSP{FamilySM2: nil})
+	assert.EqualError(t, err, "Invalid BCCSP for algorithm family [SM2]. It must be different from nil")
+}
+
+func TestSignAndVerifyByKeyType(t *testing.T) {
+	csp, swCSP, gmCSP := newTestCSP(t)
+	digest := []byte("digest of the block, of 32 bytes")
+
+	sm2Key, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+	ecdsaKey, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, gmCSP.calls["KeyGen"])
+	assert.Equal(t, 1, swCSP.calls["KeyGen"])
+
+	sig, err := csp.Sign(sm2Key, digest, nil)
+	require.NoError(t, err)
+	pub, err := sm2Key.PublicKey()
+	require.NoError(t, err)
+	valid, err := csp.Verify(pub, sig, digest, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 1, gmCSP.calls["Sign"])
+	assert.Equal(t, 1, gmCSP.calls["Verify"])
+
+	sig, err = csp.Sign(ecdsaKey, digest, nil)
+	require.NoError(t, err)
+	valid, err = csp.Verify(ecdsaKey, sig, digest, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 1, swCSP.calls["Sign"])
+	assert.Equal(t, 1, swCSP.calls["Verify"])
+}
+
+func TestHashByAlgorithm(t *testing.T) {
+	csp, swCSP, gmCSP := newTestCSP(t)
+	msg := []byte("message")
+
+	digest, err := csp.Hash(msg, &bccsp.SHA256Opts{})
+	require.NoError(t, err)
+	expected := sha256.Sum256(msg)
+	assert.Equal(t, expected[:], digest)
+	assert.Equal(t, 1, swCSP.calls["Hash"])
+
+	_, err = csp.Hash(msg, &bccsp.SM3Opts{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, gmCSP.calls["Hash"])
+
+	// SHA is the hash family of the default provider
+	digest, err = csp.Hash(msg, &bccsp.SHAOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, expected[:], digest)
+	assert.Equal(t, 2, swCSP.calls["Hash"])
+
+	h, err := csp.GetHash(&bccsp.SM3Opts{})
+	require.NoError(t, err)
+	assert.Equal(t, 32, h.Size())
+}
+
+func TestKeyImportByAlgorithm(t *testing.T) {
+	csp, swCSP, gmCSP := newTestCSP(t)
+
+	sm2Priv, err := sm2.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = csp.KeyImport(&sm2Priv.PublicKey, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, gmCSP.calls["KeyImport"])
+
+	ecdsaPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, err = csp.KeyImport(&ecdsaPriv.PublicKey, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.Equal(t, 1, swCSP.calls["KeyImport"])
+
+	// Certificates are routed by their public key
+	_, err = csp.KeyImport(&x509.Certificate{PublicKey: &sm2Priv.PublicKey}, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, gmCSP.calls["KeyImport"])
+	_, err = csp.KeyImport(&x509.Certificate{PublicKey: &ecdsaPriv.PublicKey}, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, swCSP.calls["KeyImport"])
+}
+
+func TestSymmetricKeys(t *testing.T) {
+	csp, swCSP, gmCSP := newTestCSP(t)
+
+	sm4Key, err := csp.KeyGen(&bccsp.SM4KeyGenOpts{})
+	require.NoError(t, err)
+	_, err = csp.Encrypt(sm4Key, []byte("0123456789abcdef"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, gmCSP.calls["Encrypt"])
+
+	aesKey, err := csp.KeyGen(&bccsp.AES256KeyGenOpts{})
+	require.NoError(t, err)
+	_, err = csp.Encrypt(aesKey, []byte("plaintext"), &bccsp.AESCBCPKCS7ModeOpts{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, swCSP.calls["Encrypt"])
+
+	// Keys are retrieved from the provider that generated them
+	k, err := csp.GetKey(sm4Key.SKI())
+	require.NoError(t, err)
+	assert.Equal(t, sm4Key.SKI(), k.SKI())
+	assert.Equal(t, 0, swCSP.calls["GetKey"])
+	assert.Equal(t, 1, gmCSP.calls["GetKey"])
+}
+
+func TestGetKey(t *testing.T) {
+	csp, swCSP, gmCSP := newTestCSP(t)
+
+	k, err := gmCSP.BCCSP.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+	got, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.Equal(t, k.SKI(), got.SKI())
+	assert.Equal(t, 1, swCSP.calls["GetKey"])
+	assert.Equal(t, 1, gmCSP.calls["GetKey"])
+
+	_, err = csp.GetKey([]byte("unknown"))
+	assert.Error(t, err)
+}
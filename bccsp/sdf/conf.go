@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdf
+
+// SDFOpts contains options for the SDFFactory, which drives crypto
+// cards and servers through the GM/T 0018 SDF interface.
+type SDFOpts struct {
+	// Default algorithms of the software fallback
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// SDF options
+	Library string `mapstructure:"library" json:"library"`
+	// Password grants access to the internal private keys of the device
+	Password string `mapstructure:"password" json:"password"`
+	// KeyIndexes lists the internal SM2 signing keys bound at startup
+	KeyIndexes   []uint `mapstructure:"keyindexes,omitempty" json:"keyindexes,omitempty"`
+	Sessions     int    `mapstructure:"sessions,omitempty" json:"sessions,omitempty"`
+	SoftVerify   bool   `mapstructure:"softwareverify,omitempty" json:"softwareverify,omitempty"`
+	HashOnDevice bool   `mapstructure:"hashondevice,omitempty" json:"hashondevice,omitempty"`
+
+	// NonExportable refuses to generate signing keys in software. Signing
+	// keys then have to be created at an internal key index of the device
+	// and bound through KeyIndexes or KeyIndexImportOpts.
+	NonExportable bool `mapstructure:"nonexportable,omitempty" json:"nonexportable,omitempty"`
+}
+
+// KeyAttrKeyIndex is the key attribute holding the index of an internal key.
+const KeyAttrKeyIndex = "keyindex"
+
+// KeyIndexImportOpts contains options for binding an internal SM2 signing
+// key of the device. The raw material passed to KeyImport is the key
+// index, as uint.
+type KeyIndexImportOpts struct {
+	Temporary bool
+}
+
+// Algorithm returns the key importation algorithm identifier (to be used).
+func (opts *KeyIndexImportOpts) Algorithm() string {
+	return "SDF_KEY_INDEX"
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *KeyIndexImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
@@ -0,0 +1,49 @@
+// +build sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdf
+
+import (
+	"github.com/pkg/errors"
+)
+
+// DiscoveredKey describes an internal SM2 signing key of an SDF device.
+type DiscoveredKey struct {
+	// Index is the internal key index, to be listed in KeyIndexes
+	Index uint
+	// SKI is the identifier Fabric derives from the public key
+	SKI []byte
+}
+
+// DiscoverKeys probes the internal key indexes 1 to maxIndex of the device
+// loaded from opts.Library and returns the SM2 signing keys found, along
+// with their SKIs. Indexes without a signing key are skipped.
+func DiscoverKeys(opts SDFOpts, maxIndex uint) ([]*DiscoveredKey, error) {
+	c, err := loadLib(opts.Library)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing SDF library %s", opts.Library)
+	}
+	defer c.close()
+
+	s, err := c.openSession()
+	if err != nil {
+		return nil, err
+	}
+	defer c.closeSession(s)
+
+	var keys []*DiscoveredKey
+	for index := uint(1); index <= maxIndex; index++ {
+		pub, err := c.exportSignPublicKey(s, index)
+		if err != nil {
+			logger.Debugf("Skipping key index [%d]: %s", index, err)
+			continue
+		}
+		keys = append(keys, &DiscoveredKey{Index: index, SKI: sm2SKI(pub)})
+	}
+	return keys, nil
+}
@@ -0,0 +1,331 @@
+// +build sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdf
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+var (
+	logger           = flogging.MustGetLogger("bccsp_sdf")
+	sessionCacheSize = 10
+)
+
+// New returns a new instance of the SDF-based BCCSP. Operations the
+// device does not cover are served by a software BCCSP set at the
+// passed security level, hash family and KeyStore.
+func New(opts SDFOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	ctx, err := loadLib(opts.Library)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing SDF library %s", opts.Library)
+	}
+
+	size := opts.Sessions
+	if size <= 0 {
+		size = sessionCacheSize
+	}
+
+	csp := &impl{
+		BCCSP:         swCSP,
+		ctx:           ctx,
+		sessions:      make(chan session, size),
+		password:      opts.Password,
+		softVerify:    opts.SoftVerify,
+		hashOnDevice:  opts.HashOnDevice,
+		nonExportable: opts.NonExportable,
+		keys:          map[string]*sm2PrivateKey{},
+	}
+
+	for _, index := range opts.KeyIndexes {
+		if _, err := csp.bindKey(index); err != nil {
+			ctx.close()
+			return nil, errors.Wrapf(err, "Failed binding key index [%d]", index)
+		}
+	}
+
+	return csp, nil
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	ctx      *ctx
+	sessions chan session
+	password string
+
+	softVerify   bool
+	hashOnDevice bool
+
+	// nonExportable refuses signing keys generated outside the device
+	nonExportable bool
+
+	// keys maps the hex encoded SKI to the bound internal keys
+	keysMutex sync.RWMutex
+	keys      map[string]*sm2PrivateKey
+}
+
+func (csp *impl) getSession() (session, error) {
+	select {
+	case s := <-csp.sessions:
+		return s, nil
+	default:
+		// cache is empty (or completely in use), create a new session
+		return csp.ctx.openSession()
+	}
+}
+
+func (csp *impl) returnSession(s session) {
+	select {
+	case csp.sessions <- s:
+		// returned session back to session cache
+	default:
+		// have plenty of sessions in cache, dropping
+		csp.ctx.closeSession(s)
+	}
+}
+
+// bindKey exports the public key of the internal signing key at index and
+// records it under its SKI, the SM3 digest of the public point.
+func (csp *impl) bindKey(index uint) (*sm2PrivateKey, error) {
+	s, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(s)
+
+	pub, err := csp.ctx.exportSignPublicKey(s, index)
+	if err != nil {
+		return nil, err
+	}
+
+	k := &sm2PrivateKey{index: index, pub: sm2PublicKey{pub: pub}}
+	k.pub.ski = sm2SKI(pub)
+	k.ski = k.pub.ski
+
+	csp.keysMutex.Lock()
+	csp.keys[hex.EncodeToString(k.ski)] = k
+	csp.keysMutex.Unlock()
+
+	logger.Infof("Bound SDF key index [%d], SKI %x", index, k.ski)
+	return k, nil
+}
+
+// KeyGen generates a key using opts. SM4 key material is drawn from the
+// device random generator and handed to the software fallback; all other
+// keys are generated by the software fallback, since the SDF interface
+// cannot create persistent internal keys. With NonExportable set, signing
+// keys are refused instead.
+func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	// Validate arguments
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil")
+	}
+
+	switch opts.(type) {
+	case *bccsp.SM4KeyGenOpts:
+		s, err := csp.getSession()
+		if err != nil {
+			return nil, err
+		}
+		defer csp.returnSession(s)
+
+		raw, err := csp.ctx.generateRandom(s, sm4KeySize)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed generating SM4 key")
+		}
+		return csp.BCCSP.KeyImport(raw, &bccsp.SM4ImportKeyOpts{Temporary: opts.Ephemeral()})
+	case *bccsp.SM2KeyGenOpts, *bccsp.ECDSAKeyGenOpts, *bccsp.ECDSAP256KeyGenOpts, *bccsp.ECDSAP384KeyGenOpts:
+		if o, ok := opts.(*bccsp.SM2KeyGenOpts); csp.nonExportable && !(ok && o.Usage == bccsp.SM2KeyUsageEncrypt) {
+			return nil, bccsp.Errorf(bccsp.ErrCodeKeyNotExportable, "non-exportable signing keys must be created at an internal key index of the SDF device")
+		}
+		return csp.BCCSP.KeyGen(opts)
+	default:
+		return csp.BCCSP.KeyGen(opts)
+	}
+}
+
+// KeyImport imports a key from its raw representation using opts.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	// Validate arguments
+	if raw == nil {
+		return nil, errors.New("Invalid raw. Cannot be nil")
+	}
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil")
+	}
+
+	switch opts.(type) {
+	case *KeyIndexImportOpts:
+		index, ok := raw.(uint)
+		if !ok {
+			return nil, errors.New("[KeyIndexImportOpts] Invalid raw material. Expected uint")
+		}
+		return csp.bindKey(index)
+	default:
+		return csp.BCCSP.KeyImport(raw, opts)
+	}
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	csp.keysMutex.RLock()
+	k, ok := csp.keys[hex.EncodeToString(ski)]
+	csp.keysMutex.RUnlock()
+	if ok {
+		return k, nil
+	}
+	return csp.BCCSP.GetKey(ski)
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	switch key := k.(type) {
+	case *sm2PrivateKey:
+		return csp.signSM2(key, digest, opts)
+	default:
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+}
+
+// Verify verifies signature against key k and digest
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	// Validate arguments
+	if k == nil {
+		return false, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(signature) == 0 {
+		return false, errors.New("Invalid signature. Cannot be empty")
+	}
+	if len(digest) == 0 {
+		return false, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	switch key := k.(type) {
+	case *sm2PrivateKey:
+		return csp.verifySM2(&key.pub, signature, digest, opts)
+	case *sm2PublicKey:
+		return csp.verifySM2(key, signature, digest, opts)
+	default:
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+}
+
+// Hash hashes messages msg using options opts. SM3 digests are computed
+// on the device when HashOnDevice is set.
+func (csp *impl) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	if _, ok := opts.(*bccsp.SM3Opts); !ok || !csp.hashOnDevice {
+		return csp.BCCSP.Hash(msg, opts)
+	}
+
+	s, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(s)
+
+	return csp.ctx.hash(s, nil, nil, msg)
+}
+
+func (csp *impl) signSM2(k *sm2PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	s, err := csp.getSession()
+	if err != nil {
+		return nil, err
+	}
+	defer csp.returnSession(s)
+
+	e, err := csp.sm2Digest(s, k.pub.pub, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := csp.ctx.getAccessRight(s, k.index, csp.password); err != nil {
+		return nil, err
+	}
+	defer csp.ctx.releaseAccessRight(s, k.index)
+
+	r, sig, err := csp.ctx.internalSign(s, k.index, e)
+	if err != nil {
+		return nil, err
+	}
+
+	if bccsp.SM2SignatureEncodingOf(opts) == bccsp.SM2SignatureRaw {
+		return utils.MarshalSM2RawSignature(r, sig)
+	}
+	return utils.MarshalECDSASignature(r, sig)
+}
+
+func (csp *impl) verifySM2(k *sm2PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	der := signature
+	switch bccsp.SM2SignatureEncodingOf(opts) {
+	case bccsp.SM2SignatureRaw:
+		var err error
+		if der, err = utils.SM2SignatureRawToDER(signature); err != nil {
+			return false, err
+		}
+	case bccsp.SM2SignatureAny:
+		if len(signature) == 2*sm2.KeyBytes {
+			der, _ = utils.SM2SignatureRawToDER(signature)
+		}
+	}
+
+	if csp.softVerify {
+		return sm2.Verify(k.pub, nil, digest, der), nil
+	}
+
+	r, sig, err := utils.UnmarshalECDSASignature(der)
+	if err != nil {
+		return false, errors.Errorf("Failed unmashalling signature [%s]", err)
+	}
+
+	s, err := csp.getSession()
+	if err != nil {
+		return false, err
+	}
+	defer csp.returnSession(s)
+
+	e, err := csp.sm2Digest(s, k.pub, digest)
+	if err != nil {
+		return false, err
+	}
+	return csp.ctx.externalVerify(s, k.pub, e, r, sig)
+}
+
+// sm2Digest computes e = SM3(Z_A || msg) on the device.
+func (csp *impl) sm2Digest(s session, pub *sm2.PublicKey, msg []byte) ([]byte, error) {
+	return csp.ctx.hash(s, pub, sm2DefaultUID, msg)
+}
@@ -0,0 +1,320 @@
+// +build sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdf
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <stdlib.h>
+#include <dlfcn.h>
+
+#define ECCref_MAX_BITS 512
+#define ECCref_MAX_LEN ((ECCref_MAX_BITS + 7) / 8)
+
+typedef struct {
+	unsigned int bits;
+	unsigned char x[ECCref_MAX_LEN];
+	unsigned char y[ECCref_MAX_LEN];
+} ECCrefPublicKey;
+
+typedef struct {
+	unsigned char r[ECCref_MAX_LEN];
+	unsigned char s[ECCref_MAX_LEN];
+} ECCSignature;
+
+typedef struct {
+	void *lib;
+	int (*OpenDevice)(void **);
+	int (*CloseDevice)(void *);
+	int (*OpenSession)(void *, void **);
+	int (*CloseSession)(void *);
+	int (*GenerateRandom)(void *, unsigned int, unsigned char *);
+	int (*GetPrivateKeyAccessRight)(void *, unsigned int, unsigned char *, unsigned int);
+	int (*ReleasePrivateKeyAccessRight)(void *, unsigned int);
+	int (*ExportSignPublicKey_ECC)(void *, unsigned int, ECCrefPublicKey *);
+	int (*InternalSign_ECC)(void *, unsigned int, unsigned char *, unsigned int, ECCSignature *);
+	int (*ExternalVerify_ECC)(void *, unsigned int, ECCrefPublicKey *, unsigned char *, unsigned int, ECCSignature *);
+	int (*HashInit)(void *, unsigned int, ECCrefPublicKey *, unsigned char *, unsigned int);
+	int (*HashUpdate)(void *, unsigned char *, unsigned int);
+	int (*HashFinal)(void *, unsigned char *, unsigned int *);
+} sdf_funcs;
+
+#define SDF_LOAD(f, name) \
+	if ((*(void **)(&f->name) = dlsym(f->lib, "SDF_" #name)) == NULL) { \
+		*missing = "SDF_" #name; \
+		dlclose(f->lib); \
+		free(f); \
+		return NULL; \
+	}
+
+static sdf_funcs *sdf_load(const char *path, const char **missing) {
+	sdf_funcs *f = calloc(1, sizeof(sdf_funcs));
+	if (f == NULL) {
+		return NULL;
+	}
+	f->lib = dlopen(path, RTLD_NOW);
+	if (f->lib == NULL) {
+		*missing = dlerror();
+		free(f);
+		return NULL;
+	}
+	SDF_LOAD(f, OpenDevice)
+	SDF_LOAD(f, CloseDevice)
+	SDF_LOAD(f, OpenSession)
+	SDF_LOAD(f, CloseSession)
+	SDF_LOAD(f, GenerateRandom)
+	SDF_LOAD(f, GetPrivateKeyAccessRight)
+	SDF_LOAD(f, ReleasePrivateKeyAccessRight)
+	SDF_LOAD(f, ExportSignPublicKey_ECC)
+	SDF_LOAD(f, InternalSign_ECC)
+	SDF_LOAD(f, ExternalVerify_ECC)
+	SDF_LOAD(f, HashInit)
+	SDF_LOAD(f, HashUpdate)
+	SDF_LOAD(f, HashFinal)
+	return f;
+}
+
+static void sdf_unload(sdf_funcs *f) {
+	dlclose(f->lib);
+	free(f);
+}
+
+static int sdf_open_device(sdf_funcs *f, void **dev) { return f->OpenDevice(dev); }
+static int sdf_close_device(sdf_funcs *f, void *dev) { return f->CloseDevice(dev); }
+static int sdf_open_session(sdf_funcs *f, void *dev, void **s) { return f->OpenSession(dev, s); }
+static int sdf_close_session(sdf_funcs *f, void *s) { return f->CloseSession(s); }
+static int sdf_generate_random(sdf_funcs *f, void *s, unsigned int l, unsigned char *out) {
+	return f->GenerateRandom(s, l, out);
+}
+static int sdf_get_access_right(sdf_funcs *f, void *s, unsigned int idx, unsigned char *pwd, unsigned int l) {
+	return f->GetPrivateKeyAccessRight(s, idx, pwd, l);
+}
+static int sdf_release_access_right(sdf_funcs *f, void *s, unsigned int idx) {
+	return f->ReleasePrivateKeyAccessRight(s, idx);
+}
+static int sdf_export_sign_public_key(sdf_funcs *f, void *s, unsigned int idx, ECCrefPublicKey *pub) {
+	return f->ExportSignPublicKey_ECC(s, idx, pub);
+}
+static int sdf_internal_sign(sdf_funcs *f, void *s, unsigned int idx, unsigned char *d, unsigned int l, ECCSignature *sig) {
+	return f->InternalSign_ECC(s, idx, d, l, sig);
+}
+static int sdf_external_verify(sdf_funcs *f, void *s, unsigned int alg, ECCrefPublicKey *pub, unsigned char *d, unsigned int l, ECCSignature *sig) {
+	return f->ExternalVerify_ECC(s, alg, pub, d, l, sig);
+}
+static int sdf_hash_init(sdf_funcs *f, void *s, unsigned int alg, ECCrefPublicKey *pub, unsigned char *id, unsigned int l) {
+	return f->HashInit(s, alg, pub, id, l);
+}
+static int sdf_hash_update(sdf_funcs *f, void *s, unsigned char *d, unsigned int l) {
+	return f->HashUpdate(s, d, l);
+}
+static int sdf_hash_final(sdf_funcs *f, void *s, unsigned char *out, unsigned int *l) {
+	return f->HashFinal(s, out, l);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"math/big"
+	"unsafe"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// Algorithm identifiers of GM/T 0006
+const (
+	sgdSM2_1 = 0x00020100
+	sgdSM3   = 0x00000001
+)
+
+const eccRefMaxLen = C.ECCref_MAX_LEN
+
+// Error is an error code returned by the SDF library.
+type Error uint32
+
+func (e Error) Error() string {
+	return fmt.Sprintf("SDF error 0x%08X", uint32(e))
+}
+
+func toError(rv C.int) error {
+	if rv == 0 {
+		return nil
+	}
+	return Error(uint32(rv))
+}
+
+// ctx holds the loaded SDF library and the opened device.
+type ctx struct {
+	f   *C.sdf_funcs
+	dev unsafe.Pointer
+}
+
+type session unsafe.Pointer
+
+func loadLib(lib string) (*ctx, error) {
+	logger.Debugf("Loading SDF library [%s]\n", lib)
+	if lib == "" {
+		return nil, fmt.Errorf("No SDF library default")
+	}
+
+	clib := C.CString(lib)
+	defer C.free(unsafe.Pointer(clib))
+
+	var missing *C.char
+	f := C.sdf_load(clib, &missing)
+	if f == nil {
+		return nil, fmt.Errorf("Instantiate failed [%s] [%s]", lib, C.GoString(missing))
+	}
+
+	c := &ctx{f: f}
+	if err := toError(C.sdf_open_device(f, &c.dev)); err != nil {
+		C.sdf_unload(f)
+		return nil, fmt.Errorf("SDF: open device failed [%s]", err)
+	}
+	return c, nil
+}
+
+func (c *ctx) close() {
+	C.sdf_close_device(c.f, c.dev)
+	C.sdf_unload(c.f)
+}
+
+func (c *ctx) openSession() (session, error) {
+	var s unsafe.Pointer
+	if err := toError(C.sdf_open_session(c.f, c.dev, &s)); err != nil {
+		return nil, fmt.Errorf("SDF: open session failed [%s]", err)
+	}
+	return session(s), nil
+}
+
+func (c *ctx) closeSession(s session) {
+	C.sdf_close_session(c.f, unsafe.Pointer(s))
+}
+
+func (c *ctx) generateRandom(s session, n int) ([]byte, error) {
+	out := make([]byte, n)
+	rv := C.sdf_generate_random(c.f, unsafe.Pointer(s), C.uint(n), (*C.uchar)(unsafe.Pointer(&out[0])))
+	if err := toError(rv); err != nil {
+		return nil, fmt.Errorf("SDF: generate random failed [%s]", err)
+	}
+	return out, nil
+}
+
+func (c *ctx) getAccessRight(s session, index uint, password string) error {
+	pwd := C.CString(password)
+	defer C.free(unsafe.Pointer(pwd))
+	rv := C.sdf_get_access_right(c.f, unsafe.Pointer(s), C.uint(index), (*C.uchar)(unsafe.Pointer(pwd)), C.uint(len(password)))
+	if err := toError(rv); err != nil {
+		return fmt.Errorf("SDF: get private key access right [%d] failed [%s]", index, err)
+	}
+	return nil
+}
+
+func (c *ctx) releaseAccessRight(s session, index uint) {
+	C.sdf_release_access_right(c.f, unsafe.Pointer(s), C.uint(index))
+}
+
+func (c *ctx) exportSignPublicKey(s session, index uint) (*sm2.PublicKey, error) {
+	var ref C.ECCrefPublicKey
+	if err := toError(C.sdf_export_sign_public_key(c.f, unsafe.Pointer(s), C.uint(index), &ref)); err != nil {
+		return nil, fmt.Errorf("SDF: export sign public key [%d] failed [%s]", index, err)
+	}
+	if ref.bits != 256 {
+		return nil, fmt.Errorf("SDF: key [%d] is not an SM2 key, bits [%d]", index, ref.bits)
+	}
+
+	x := C.GoBytes(unsafe.Pointer(&ref.x[0]), eccRefMaxLen)
+	y := C.GoBytes(unsafe.Pointer(&ref.y[0]), eccRefMaxLen)
+	return &sm2.PublicKey{
+		Curve: sm2.GetSm2P256V1(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
+
+func eccRefPublicKey(pub *sm2.PublicKey) *C.ECCrefPublicKey {
+	ref := &C.ECCrefPublicKey{bits: 256}
+	x := padBytes(pub.X.Bytes(), eccRefMaxLen)
+	y := padBytes(pub.Y.Bytes(), eccRefMaxLen)
+	for i := 0; i < eccRefMaxLen; i++ {
+		ref.x[i] = C.uchar(x[i])
+		ref.y[i] = C.uchar(y[i])
+	}
+	return ref
+}
+
+func (c *ctx) internalSign(s session, index uint, e []byte) (r, sig *big.Int, err error) {
+	var ref C.ECCSignature
+	rv := C.sdf_internal_sign(c.f, unsafe.Pointer(s), C.uint(index), (*C.uchar)(unsafe.Pointer(&e[0])), C.uint(len(e)), &ref)
+	if err := toError(rv); err != nil {
+		return nil, nil, fmt.Errorf("SDF: internal sign [%d] failed [%s]", index, err)
+	}
+
+	r = new(big.Int).SetBytes(C.GoBytes(unsafe.Pointer(&ref.r[0]), eccRefMaxLen))
+	sig = new(big.Int).SetBytes(C.GoBytes(unsafe.Pointer(&ref.s[0]), eccRefMaxLen))
+	return r, sig, nil
+}
+
+func (c *ctx) externalVerify(s session, pub *sm2.PublicKey, e []byte, r, sig *big.Int) (bool, error) {
+	var ref C.ECCSignature
+	rb := padBytes(r.Bytes(), eccRefMaxLen)
+	sb := padBytes(sig.Bytes(), eccRefMaxLen)
+	for i := 0; i < eccRefMaxLen; i++ {
+		ref.r[i] = C.uchar(rb[i])
+		ref.s[i] = C.uchar(sb[i])
+	}
+
+	rv := C.sdf_external_verify(c.f, unsafe.Pointer(s), sgdSM2_1, eccRefPublicKey(pub), (*C.uchar)(unsafe.Pointer(&e[0])), C.uint(len(e)), &ref)
+	if rv != 0 {
+		// the SDF API does not distinguish an invalid signature from other failures
+		logger.Debugf("SDF: external verify returned [%s]", Error(uint32(rv)))
+		return false, nil
+	}
+	return true, nil
+}
+
+// hash computes SM3 of msg on the device. If pub is not nil, the device
+// prepends Z_A computed from pub and the default user identity.
+func (c *ctx) hash(s session, pub *sm2.PublicKey, uid, msg []byte) ([]byte, error) {
+	var ref *C.ECCrefPublicKey
+	var id *C.uchar
+	if pub != nil {
+		ref = eccRefPublicKey(pub)
+		cid := C.CBytes(uid)
+		defer C.free(cid)
+		id = (*C.uchar)(cid)
+	}
+	rv := C.sdf_hash_init(c.f, unsafe.Pointer(s), sgdSM3, ref, id, C.uint(len(uid)))
+	if err := toError(rv); err != nil {
+		return nil, fmt.Errorf("SDF: hash init failed [%s]", err)
+	}
+
+	if len(msg) > 0 {
+		data := C.CBytes(msg)
+		defer C.free(data)
+		if err := toError(C.sdf_hash_update(c.f, unsafe.Pointer(s), (*C.uchar)(data), C.uint(len(msg)))); err != nil {
+			return nil, fmt.Errorf("SDF: hash update failed [%s]", err)
+		}
+	}
+
+	out := make([]byte, 32)
+	outLen := C.uint(len(out))
+	if err := toError(C.sdf_hash_final(c.f, unsafe.Pointer(s), (*C.uchar)(unsafe.Pointer(&out[0])), &outLen)); err != nil {
+		return nil, fmt.Errorf("SDF: hash final failed [%s]", err)
+	}
+	return out[:outLen], nil
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
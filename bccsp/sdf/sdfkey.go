@@ -0,0 +1,119 @@
+// +build sdf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sdf
+
+import (
+	"crypto"
+	"errors"
+	"strconv"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// sm4KeySize is the size of SM4 keys in bytes.
+const sm4KeySize = 16
+
+// sm2DefaultUID is the default user identity of GB/T 32918.
+var sm2DefaultUID = []byte("1234567812345678")
+
+func sm2SKI(pub *sm2.PublicKey) []byte {
+	return utils.SKI(pub)
+}
+
+// sm2PrivateKey is an internal SM2 signing key of the device.
+type sm2PrivateKey struct {
+	ski   []byte
+	index uint
+	pub   sm2PublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *sm2PrivateKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *sm2PrivateKey) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *sm2PrivateKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *sm2PrivateKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *sm2PrivateKey) PublicKey() (bccsp.Key, error) {
+	return &k.pub, nil
+}
+
+// CryptoPublicKey returns the public part of this key as *sm2.PublicKey.
+func (k *sm2PrivateKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pub.pub, nil
+}
+
+// KeyAttributes returns the attributes of this key. Internal keys
+// never leave the device, so they are reported as non-exportable.
+func (k *sm2PrivateKey) KeyAttributes() map[string]string {
+	return map[string]string{
+		bccsp.KeyAttrProvider:   "SDF",
+		bccsp.KeyAttrExportable: "false",
+		KeyAttrKeyIndex:         strconv.FormatUint(uint64(k.index), 10),
+	}
+}
+
+type sm2PublicKey struct {
+	ski []byte
+	pub *sm2.PublicKey
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *sm2PublicKey) Bytes() ([]byte, error) {
+	return utils.MarshalPKIXSM2PublicKey(k.pub)
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *sm2PublicKey) SKI() []byte {
+	return k.ski
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *sm2PublicKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *sm2PublicKey) Private() bool {
+	return false
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *sm2PublicKey) PublicKey() (bccsp.Key, error) {
+	return k, nil
+}
+
+// CryptoPublicKey returns this key as *sm2.PublicKey.
+func (k *sm2PublicKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pub, nil
+}
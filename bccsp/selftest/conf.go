@@ -0,0 +1,44 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selftest
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+// Names of the known-answer tests.
+const (
+	TestSM2   = "SM2"
+	TestSM3   = "SM3"
+	TestSM4   = "SM4"
+	TestAES   = "AES"
+	TestECDSA = "ECDSA"
+)
+
+// SelfTestOpts configures the periodic self-tests of a provider.
+type SelfTestOpts struct {
+	// Enabled turns the self-tests of hardware providers on, at startup
+	// and then periodically
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+	// PowerOn runs the self-tests once at startup whatever the provider,
+	// software ones included, and refuses to start if one fails
+	PowerOn bool `mapstructure:"poweron,omitempty" json:"poweron,omitempty" yaml:"PowerOn"`
+	// Interval between two runs, 10 minutes by default
+	Interval time.Duration `mapstructure:"interval,omitempty" json:"interval,omitempty" yaml:"Interval"`
+	// Tests are the tests to run. When empty, the SM2, SM3 and SM4 tests,
+	// and the AES and ECDSA ones if the provider reports offering them
+	Tests []string `mapstructure:"tests,omitempty" json:"tests,omitempty" yaml:"Tests"`
+	// SignKey is the hex encoded SKI of the SM2 key signing in the SM2
+	// test. An ephemeral key is generated when empty, which devices unable
+	// to generate keys do not support.
+	SignKey string `mapstructure:"signkey,omitempty" json:"signkey,omitempty" yaml:"SignKey"`
+
+	// MetricsProvider receives the self-test metrics, they are disabled if nil
+	MetricsProvider metrics.Provider `json:"-" yaml:"-"`
+}
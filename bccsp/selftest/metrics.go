@@ -0,0 +1,50 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selftest
+
+import (
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+var (
+	runs = metrics.CounterOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "selftest",
+		Name:         "runs",
+		Help:         "The number of known-answer tests run against the provider.",
+		LabelNames:   []string{"test", "result"},
+		StatsdFormat: "%{#fqname}.%{test}.%{result}",
+	}
+	duration = metrics.HistogramOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "selftest",
+		Name:         "duration",
+		Help:         "The time taken by a known-answer test, in seconds.",
+		LabelNames:   []string{"test"},
+		StatsdFormat: "%{#fqname}.%{test}",
+	}
+	healthy = metrics.GaugeOpts{
+		Namespace: "bccsp",
+		Subsystem: "selftest",
+		Name:      "healthy",
+		Help:      "Whether all known-answer tests passed in the last run, 1 if so and 0 otherwise.",
+	}
+)
+
+type Metrics struct {
+	Runs     metrics.Counter
+	Duration metrics.Histogram
+	Healthy  metrics.Gauge
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		Runs:     p.NewCounter(runs),
+		Duration: p.NewHistogram(duration),
+		Healthy:  p.NewGauge(healthy),
+	}
+}
@@ -0,0 +1,460 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_selftest")
+
+const defaultInterval = 10 * time.Minute
+
+// Known answers of GB/T 32905 (SM3), GB/T 32907 (SM4), FIPS 197 (AES-256)
+// and of a P-256 signature with the key of RFC 6979 A.2.5.
+var (
+	sm3Message = []byte("abc")
+	sm3Digest  = mustDecodeHex("66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0")
+
+	sm4Key        = mustDecodeHex("0123456789abcdeffedcba9876543210")
+	sm4Plaintext  = mustDecodeHex("0123456789abcdeffedcba9876543210")
+	sm4Ciphertext = mustDecodeHex("681edf34d206965e86b3e94f536e4246")
+
+	sm2Message = []byte("fabric bccsp self-test")
+
+	aesKey       = mustDecodeHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	aesPlaintext = mustDecodeHex("00112233445566778899aabbccddeeff")
+	// Zero IV || AES-256(plaintext) || AES-256 of the PKCS7 padding block in CBC mode
+	aesCiphertext = mustDecodeHex("00000000000000000000000000000000" +
+		"8ea2b7ca516745bfeafc49904b496089" + "56423350859cf424d4459534a8f5aaf2")
+
+	ecdsaX         = mustDecodeHex("60fed4ba255a9d31c961eb74c6356d68c049b8923b61fa6ce669622e60f29fb6")
+	ecdsaY         = mustDecodeHex("7903fe1008b8bc99a41ae9e95628bc64f2f1b20c2d7e9f5177a3c294d4462299")
+	ecdsaDigest    = sha256.Sum256(sm2Message)
+	ecdsaSignature = mustDecodeHex("304402207d89f8512cebc29b959d447c2036907aa71052a018b61fd9a2154720b0224b1f" +
+		"0220705ab30cd719c034e3416ad357b77c83631795d325606dc3ac3faf1217cda634")
+)
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Tester runs known-answer tests against a provider, typically a crypto
+// card or HSM, so that a device silently returning wrong results is
+// detected before its signatures reach the ledger. Its HealthCheck method
+// makes it a healthz.HealthChecker.
+type Tester struct {
+	csp       bccsp.BCCSP
+	reference bccsp.BCCSP
+	tests     []string
+	signKey   []byte
+	interval  time.Duration
+
+	metricsMutex sync.RWMutex
+	metrics      *Metrics
+
+	mutex   sync.RWMutex
+	lastErr error
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// New returns a Tester of csp. Signatures of csp are checked against the
+// software provider.
+func New(csp bccsp.BCCSP, opts SelfTestOpts) (*Tester, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid BCCSP instance. It must be different from nil")
+	}
+
+	reference, err := sw.NewWithParams(256, "SHA2", sw.NewDummyKeyStore())
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed initializing reference SW BCCSP")
+	}
+
+	t := &Tester{
+		csp:       csp,
+		reference: reference,
+		tests:     defaultTests(csp),
+		interval:  opts.Interval,
+		stop:      make(chan struct{}),
+		lastErr:   errors.New("self-tests have not run yet"),
+	}
+	if t.interval <= 0 {
+		t.interval = defaultInterval
+	}
+
+	if len(opts.Tests) != 0 {
+		t.tests = nil
+		for _, name := range opts.Tests {
+			name = strings.ToUpper(name)
+			switch name {
+			case TestSM2, TestSM3, TestSM4, TestAES, TestECDSA:
+				t.tests = append(t.tests, name)
+			default:
+				return nil, errors.Errorf("Unknown self-test [%s]", name)
+			}
+		}
+	}
+
+	if opts.SignKey != "" {
+		if t.signKey, err = hex.DecodeString(opts.SignKey); err != nil || len(t.signKey) == 0 {
+			return nil, errors.Errorf("Invalid SKI [%s]", opts.SignKey)
+		}
+	}
+
+	p := opts.MetricsProvider
+	if p == nil {
+		p = &disabled.Provider{}
+	}
+	t.metrics = NewMetrics(p)
+
+	return t, nil
+}
+
+// defaultTests returns the tests of the GM algorithms, and those of AES
+// and ECDSA if csp reports offering them.
+func defaultTests(csp bccsp.BCCSP) []string {
+	tests := []string{TestSM3, TestSM4, TestSM2}
+	c, ok := bccsp.GetCapabilities(csp)
+	if !ok {
+		return tests
+	}
+	if c.RequireCipherMode(bccsp.CipherModeAESCBCPKCS7) == nil {
+		tests = append(tests, TestAES)
+	}
+	if c.RequireSignatureAlgorithm(bccsp.ECDSA) == nil {
+		tests = append(tests, TestECDSA)
+	}
+	return tests
+}
+
+// Tests returns the names of the tests run.
+func (t *Tester) Tests() []string {
+	return t.tests
+}
+
+// SetMetricsProvider redirects the metrics to p, for processes creating
+// their metrics provider after the BCCSP.
+func (t *Tester) SetMetricsProvider(p metrics.Provider) {
+	t.metricsMutex.Lock()
+	defer t.metricsMutex.Unlock()
+	t.metrics = NewMetrics(p)
+}
+
+func (t *Tester) getMetrics() *Metrics {
+	t.metricsMutex.RLock()
+	defer t.metricsMutex.RUnlock()
+	return t.metrics
+}
+
+// Run runs the tests once and returns the first failure.
+func (t *Tester) Run() error {
+	m := t.getMetrics()
+
+	var first error
+	for _, name := range t.tests {
+		start := time.Now()
+		err := t.runTest(name)
+		m.Duration.With("test", name).Observe(time.Since(start).Seconds())
+
+		result := "success"
+		if err != nil {
+			result = "failure"
+			logger.Errorf("Self-test %s failed: %s", name, err)
+			if first == nil {
+				first = errors.Wrapf(err, "self-test %s failed", name)
+			}
+		}
+		m.Runs.With("test", name, "result", result).Add(1)
+	}
+
+	if first == nil {
+		m.Healthy.Set(1)
+		logger.Debugf("Self-tests %v passed", t.tests)
+	} else {
+		m.Healthy.Set(0)
+	}
+
+	t.mutex.Lock()
+	t.lastErr = first
+	t.mutex.Unlock()
+
+	return first
+}
+
+func (t *Tester) runTest(name string) error {
+	switch name {
+	case TestSM3:
+		return t.testSM3()
+	case TestSM4:
+		return t.testSM4()
+	case TestAES:
+		return t.testAES()
+	case TestECDSA:
+		return t.testECDSA()
+	default:
+		return t.testSM2()
+	}
+}
+
+// Start runs the tests every interval until Stop is called.
+func (t *Tester) Start() {
+	go func() {
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.Run()
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic runs.
+func (t *Tester) Stop() {
+	t.stopOnce.Do(func() { close(t.stop) })
+}
+
+// HealthCheck returns the failure of the last run, nil if all tests passed.
+func (t *Tester) HealthCheck(ctx context.Context) error {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.lastErr
+}
+
+func (t *Tester) testSM3() error {
+	digest, err := t.csp.Hash(sm3Message, &bccsp.SM3Opts{})
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(digest, sm3Digest) {
+		return errors.Errorf("wrong digest %x", digest)
+	}
+	return nil
+}
+
+func (t *Tester) testSM4() error {
+	k, err := t.csp.KeyImport(sm4Key, &bccsp.SM4ImportKeyOpts{Temporary: true})
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := t.csp.Encrypt(k, sm4Plaintext, nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ciphertext, sm4Ciphertext) {
+		return errors.Errorf("wrong ciphertext %x", ciphertext)
+	}
+
+	// Providers may decrypt in place, keep the test vector intact
+	plaintext, err := t.csp.Decrypt(k, append([]byte(nil), sm4Ciphertext...), nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, sm4Plaintext) {
+		return errors.Errorf("wrong plaintext %x", plaintext)
+	}
+	return nil
+}
+
+// testSM2 checks that the signatures of the device verify in software,
+// and that the device verifies software signatures and rejects altered
+// ones. SM2 signatures are randomized, so there is no fixed answer.
+func (t *Tester) testSM2() error {
+	k, err := t.sm2SignKey()
+	if err != nil {
+		return err
+	}
+
+	signature, err := t.csp.Sign(k, sm2Message, nil)
+	if err != nil {
+		return errors.Wrap(err, "signing")
+	}
+	if err := t.verifyInSoftware(k, signature, sm2Message); err != nil {
+		return err
+	}
+	valid, err := t.csp.Verify(k, signature, sm2Message, nil)
+	if err != nil {
+		return errors.Wrap(err, "verifying own signature")
+	}
+	if !valid {
+		return errors.New("own signature rejected")
+	}
+
+	ref, err := t.reference.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	if err != nil {
+		return err
+	}
+	refSignature, err := t.reference.Sign(ref, sm2Message, nil)
+	if err != nil {
+		return err
+	}
+	refPub, err := ref.PublicKey()
+	if err != nil {
+		return err
+	}
+	raw, err := refPub.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+	if err != nil {
+		return err
+	}
+	pub, err := t.csp.KeyImport(raw, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return errors.Wrap(err, "importing reference key")
+	}
+
+	valid, err = t.csp.Verify(pub, refSignature, sm2Message, nil)
+	if err != nil {
+		return errors.Wrap(err, "verifying reference signature")
+	}
+	if !valid {
+		return errors.New("reference signature rejected")
+	}
+	valid, _ = t.csp.Verify(pub, refSignature, append([]byte("altered "), sm2Message...), nil)
+	if valid {
+		return errors.New("signature of another message accepted")
+	}
+	return nil
+}
+
+func (t *Tester) sm2SignKey() (bccsp.Key, error) {
+	if t.signKey != nil {
+		k, err := t.csp.GetKey(t.signKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "getting signing key %x", t.signKey)
+		}
+		return k, nil
+	}
+	k, err := t.csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "generating signing key")
+	}
+	return k, nil
+}
+
+// verifyInSoftware verifies signature of msg, an SM2 message or an ECDSA
+// digest, of the device with the reference provider.
+func (t *Tester) verifyInSoftware(k bccsp.Key, signature, msg []byte) error {
+	cpk, ok := k.(bccsp.CryptoPublicKeyer)
+	if !ok {
+		return errors.Errorf("key of type %T does not expose its public key", k)
+	}
+	raw, err := cpk.CryptoPublicKey()
+	if err != nil {
+		return err
+	}
+
+	var opts bccsp.KeyImportOpts
+	switch raw.(type) {
+	case *sm2.PublicKey:
+		opts = &bccsp.SM2GoPublicKeyImportOpts{Temporary: true}
+	case *ecdsa.PublicKey:
+		opts = &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true}
+	default:
+		return errors.Errorf("signing key is neither an SM2 nor an ECDSA key but %T", raw)
+	}
+	pub, err := t.reference.KeyImport(raw, opts)
+	if err != nil {
+		return err
+	}
+
+	valid, err := t.reference.Verify(pub, signature, msg, nil)
+	if err != nil {
+		return errors.Wrap(err, "verifying device signature in software")
+	}
+	if !valid {
+		return errors.New("device signature does not verify in software")
+	}
+	return nil
+}
+
+func (t *Tester) testAES() error {
+	k, err := t.csp.KeyImport(aesKey, &bccsp.AES256ImportKeyOpts{Temporary: true})
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := t.csp.Encrypt(k, aesPlaintext, &bccsp.AESCBCPKCS7ModeOpts{IV: make([]byte, 16)})
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ciphertext, aesCiphertext) {
+		return errors.Errorf("wrong ciphertext %x", ciphertext)
+	}
+
+	// Providers may decrypt in place, keep the test vector intact
+	plaintext, err := t.csp.Decrypt(k, append([]byte(nil), aesCiphertext...), &bccsp.AESCBCPKCS7ModeOpts{})
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, aesPlaintext) {
+		return errors.Errorf("wrong plaintext %x", plaintext)
+	}
+	return nil
+}
+
+// testECDSA checks that the device verifies a known P-256 signature and
+// rejects it for another digest, and that the signatures of the device
+// verify in software.
+func (t *Tester) testECDSA() error {
+	known := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(ecdsaX),
+		Y:     new(big.Int).SetBytes(ecdsaY),
+	}
+	pub, err := t.csp.KeyImport(known, &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return errors.Wrap(err, "importing known key")
+	}
+	valid, err := t.csp.Verify(pub, ecdsaSignature, ecdsaDigest[:], nil)
+	if err != nil {
+		return errors.Wrap(err, "verifying known signature")
+	}
+	if !valid {
+		return errors.New("known signature rejected")
+	}
+	otherDigest := sha256.Sum256(append([]byte("altered "), sm2Message...))
+	valid, _ = t.csp.Verify(pub, ecdsaSignature, otherDigest[:], nil)
+	if valid {
+		return errors.New("signature of another digest accepted")
+	}
+
+	k, err := t.csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	if err != nil {
+		return errors.Wrap(err, "generating signing key")
+	}
+	signature, err := t.csp.Sign(k, ecdsaDigest[:], nil)
+	if err != nil {
+		return errors.Wrap(err, "signing")
+	}
+	return t.verifyInSoftware(k, signature, ecdsaDigest[:])
+}
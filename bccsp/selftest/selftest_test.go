@@ -0,0 +1,180 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package selftest
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// faultyDevice corrupts the results of the operations selected.
+type faultyDevice struct {
+	bccsp.BCCSP
+	badHash, badEncrypt, badSign, acceptAll bool
+}
+
+func (d *faultyDevice) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
+	digest, err := d.BCCSP.Hash(msg, opts)
+	if err == nil && d.badHash {
+		digest[0] ^= 1
+	}
+	return digest, err
+}
+
+func (d *faultyDevice) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	ciphertext, err := d.BCCSP.Encrypt(k, plaintext, opts)
+	if err == nil && d.badEncrypt {
+		ciphertext[len(ciphertext)-1] ^= 1
+	}
+	return ciphertext, err
+}
+
+func (d *faultyDevice) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if d.badSign {
+		// Sign another message
+		digest = append([]byte{0}, digest...)
+	}
+	return d.BCCSP.Sign(k, digest, opts)
+}
+
+func (d *faultyDevice) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if d.acceptAll {
+		return true, nil
+	}
+	return d.BCCSP.Verify(k, signature, digest, opts)
+}
+
+func newFaultyDevice(t *testing.T) *faultyDevice {
+	csp, err := sw.NewWithParams(256, "SHA2", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	return &faultyDevice{BCCSP: csp}
+}
+
+func TestNew(t *testing.T) {
+	d := newFaultyDevice(t)
+
+	_, err := New(nil, SelfTestOpts{})
+	assert.Error(t, err)
+	_, err = New(d, SelfTestOpts{Tests: []string{"sm3", "RSA"}})
+	assert.EqualError(t, err, "Unknown self-test [RSA]")
+	_, err = New(d, SelfTestOpts{SignKey: "zz"})
+	assert.EqualError(t, err, "Invalid SKI [zz]")
+
+	tester, err := New(d, SelfTestOpts{Tests: []string{"sm3", "sm4"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{TestSM3, TestSM4}, tester.tests)
+	assert.EqualError(t, tester.HealthCheck(context.Background()), "self-tests have not run yet")
+}
+
+func TestRun(t *testing.T) {
+	d := newFaultyDevice(t)
+	tester, err := New(d, SelfTestOpts{})
+	require.NoError(t, err)
+
+	require.NoError(t, tester.Run())
+	assert.NoError(t, tester.HealthCheck(context.Background()))
+	// The test vectors survive a run
+	require.NoError(t, tester.Run())
+
+	for name, fault := range map[string]*bool{
+		"self-test SM3 failed: wrong digest":                                 &d.badHash,
+		"self-test SM4 failed: wrong ciphertext":                             &d.badEncrypt,
+		"self-test SM2 failed: device signature does not verify in software": &d.badSign,
+		"self-test SM2 failed: signature of another message accepted":        &d.acceptAll,
+	} {
+		*fault = true
+		err := tester.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), name)
+		assert.Equal(t, err, tester.HealthCheck(context.Background()))
+		*fault = false
+	}
+
+	require.NoError(t, tester.Run())
+	assert.NoError(t, tester.HealthCheck(context.Background()))
+}
+
+func TestAESAndECDSA(t *testing.T) {
+	d := newFaultyDevice(t)
+	tester, err := New(d, SelfTestOpts{Tests: []string{"aes", "ecdsa"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{TestAES, TestECDSA}, tester.Tests())
+	require.NoError(t, tester.Run())
+	// The test vectors survive a run
+	require.NoError(t, tester.Run())
+
+	for name, fault := range map[string]*bool{
+		"self-test AES failed: wrong ciphertext":                               &d.badEncrypt,
+		"self-test ECDSA failed: device signature does not verify in software": &d.badSign,
+		"self-test ECDSA failed: signature of another digest accepted":         &d.acceptAll,
+	} {
+		*fault = true
+		err := tester.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), name)
+		*fault = false
+	}
+}
+
+func TestDefaultTests(t *testing.T) {
+	// The faulty device does not report its capabilities
+	assert.Equal(t, []string{TestSM3, TestSM4, TestSM2}, defaultTests(newFaultyDevice(t)))
+
+	csp, err := sw.NewWithParams(256, "SHA2", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	assert.Equal(t, []string{TestSM3, TestSM4, TestSM2, TestAES, TestECDSA}, defaultTests(csp))
+}
+
+func TestSignKey(t *testing.T) {
+	d := newFaultyDevice(t)
+	k, err := d.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+
+	tester, err := New(d, SelfTestOpts{Tests: []string{TestSM2}, SignKey: hex.EncodeToString(k.SKI())})
+	require.NoError(t, err)
+	assert.NoError(t, tester.Run())
+
+	tester, err = New(d, SelfTestOpts{Tests: []string{TestSM2}, SignKey: "0102"})
+	require.NoError(t, err)
+	assert.Error(t, tester.Run())
+}
+
+func TestMetrics(t *testing.T) {
+	d := newFaultyDevice(t)
+	tester, err := New(d, SelfTestOpts{Tests: []string{TestSM3}})
+	require.NoError(t, err)
+
+	p := &metricsfakes.Provider{}
+	counter := &metricsfakes.Counter{}
+	counter.WithReturns(counter)
+	histogram := &metricsfakes.Histogram{}
+	histogram.WithReturns(histogram)
+	gauge := &metricsfakes.Gauge{}
+	p.NewCounterReturns(counter)
+	p.NewHistogramReturns(histogram)
+	p.NewGaugeReturns(gauge)
+	tester.SetMetricsProvider(p)
+
+	require.NoError(t, tester.Run())
+	d.badHash = true
+	require.Error(t, tester.Run())
+
+	require.Equal(t, 2, counter.WithCallCount())
+	assert.Equal(t, []string{"test", TestSM3, "result", "success"}, counter.WithArgsForCall(0))
+	assert.Equal(t, []string{"test", TestSM3, "result", "failure"}, counter.WithArgsForCall(1))
+	assert.Equal(t, 2, histogram.ObserveCallCount())
+	require.Equal(t, 2, gauge.SetCallCount())
+	assert.Equal(t, float64(1), gauge.SetArgsForCall(0))
+	assert.Equal(t, float64(0), gauge.SetArgsForCall(1))
+}
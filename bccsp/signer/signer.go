@@ -36,6 +36,15 @@ func New(csp bccsp.BCCSP, key bccsp.Key) (crypto.Signer, error) {
 		return nil, errors.New("key must be asymmetric.")
 	}
 
+	// Keys able to expose their public part directly need no marshalling
+	if k, ok := key.(bccsp.CryptoPublicKeyer); ok {
+		pk, err := k.CryptoPublicKey()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed getting public key")
+		}
+		return &bccspCryptoSigner{csp, key, pk}, nil
+	}
+
 	// Marshall the bccsp public key as a crypto.PublicKey
 	pub, err := key.PublicKey()
 	if err != nil {
@@ -76,3 +85,13 @@ func (s *bccspCryptoSigner) Public() crypto.PublicKey {
 func (s *bccspCryptoSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
 	return s.csp.Sign(s.key, digest, opts)
 }
+
+// Key returns the bccsp.Key backing a crypto.Signer returned by New.
+// It fails for any other crypto.Signer.
+func Key(s crypto.Signer) (bccsp.Key, error) {
+	cs, ok := s.(*bccspCryptoSigner)
+	if !ok {
+		return nil, errors.Errorf("signer [%T] is not backed by a bccsp key", s)
+	}
+	return cs.key, nil
+}
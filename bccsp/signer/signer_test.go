@@ -16,6 +16,7 @@ limitations under the License.
 package signer
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -137,3 +138,35 @@ func TestSign(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, err.Error(), "invalid opts")
 }
+
+type cryptoPublicKeyer struct {
+	mocks.MockKey
+	pk crypto.PublicKey
+}
+
+func (k *cryptoPublicKeyer) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pk, nil
+}
+
+func TestInitWithCryptoPublicKey(t *testing.T) {
+	sm2PrivKey, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	// The public key is not marshalled
+	key := &cryptoPublicKeyer{
+		MockKey: mocks.MockKey{PK: &mocks.MockKey{BytesErr: errors.New("No bytes")}},
+		pk:      &sm2PrivKey.PublicKey,
+	}
+	signer, err := New(&mocks.MockBCCSP{}, key)
+	assert.NoError(t, err)
+	assert.Equal(t, &sm2PrivKey.PublicKey, signer.Public())
+
+	k, err := Key(signer)
+	assert.NoError(t, err)
+	assert.Equal(t, key, k)
+
+	ecdsaPrivKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	_, err = Key(ecdsaPrivKey)
+	assert.EqualError(t, err, "signer [*ecdsa.PrivateKey] is not backed by a bccsp key")
+}
@@ -0,0 +1,29 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package skf
+
+// SKFOpts contains options for the SKFFactory, which signs with SM2 keys
+// held in the containers of a GM/T 0016 SKF USB token.
+type SKFOpts struct {
+	// Default algorithms of the software fallback
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// SKF options
+	Library string `mapstructure:"library" json:"library"`
+	// Device selects the token by name, the first present token is used if empty
+	Device      string `mapstructure:"device,omitempty" json:"device,omitempty"`
+	Application string `mapstructure:"application" json:"application"`
+	Pin         string `mapstructure:"pin" json:"pin"`
+	// Containers restricts the containers whose signing keys are bound,
+	// all containers of the application are bound if empty
+	Containers []string `mapstructure:"containers,omitempty" json:"containers,omitempty"`
+}
+
+// KeyAttrContainer is the key attribute holding the name of the token
+// container of a key.
+const KeyAttrContainer = "container"
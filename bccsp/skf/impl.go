@@ -0,0 +1,216 @@
+// +build skf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package skf
+
+import (
+	"encoding/hex"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_skf")
+
+// ListDevices returns the names of the SKF tokens present, as reported
+// by the passed SKF library.
+func ListDevices(library string) ([]string, error) {
+	l, err := loadLib(library)
+	if err != nil {
+		return nil, err
+	}
+	defer l.unload()
+
+	return l.enumDevices()
+}
+
+// New returns a new instance of the SKF-based BCCSP. The SM2 signing keys
+// of the token containers are used for signing, every other operation is
+// served by a software BCCSP set at the passed security level, hash family
+// and KeyStore.
+func New(opts SKFOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+	if opts.Pin == "" {
+		return nil, errors.New("No PIN set")
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	l, err := loadLib(opts.Library)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing SKF library %s", opts.Library)
+	}
+
+	device := opts.Device
+	if device == "" {
+		devices, err := l.enumDevices()
+		if err != nil {
+			l.unload()
+			return nil, err
+		}
+		if len(devices) == 0 {
+			l.unload()
+			return nil, errors.New("No SKF device present")
+		}
+		device = devices[0]
+		logger.Debugf("Using first present SKF device [%s] of %v", device, devices)
+	}
+
+	t, err := l.openToken(device, opts.Application, opts.Pin)
+	if err != nil {
+		l.unload()
+		return nil, err
+	}
+
+	csp := &impl{BCCSP: swCSP, token: t, keys: map[string]*sm2PrivateKey{}}
+	if err := csp.bindContainers(opts.Containers); err != nil {
+		t.close()
+		l.unload()
+		return nil, err
+	}
+
+	return csp, nil
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	// USB tokens serve one request at a time
+	tokenMutex sync.Mutex
+	token      *token
+
+	// keys maps the hex encoded SKI to the signing keys of the containers
+	keys map[string]*sm2PrivateKey
+}
+
+// bindContainers records the signing keys of the passed containers, or of
+// every container holding an SM2 signing key if none is passed.
+func (csp *impl) bindContainers(containers []string) error {
+	explicit := len(containers) > 0
+	if !explicit {
+		var err error
+		if containers, err = csp.token.enumContainers(); err != nil {
+			return err
+		}
+	}
+
+	for _, container := range containers {
+		pub, err := csp.token.signPublicKey(container)
+		if err != nil {
+			if explicit {
+				return errors.Wrapf(err, "Failed binding container [%s]", container)
+			}
+			logger.Warningf("Skipping container [%s]: %s", container, err)
+			continue
+		}
+
+		ski := sm2SKI(pub)
+		k := &sm2PrivateKey{ski: ski, container: container, pub: sm2PublicKey{ski: ski, pub: pub}}
+		csp.keys[hex.EncodeToString(ski)] = k
+		logger.Infof("Bound SKF container [%s], SKI %x", container, ski)
+	}
+	return nil
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	if k, ok := csp.keys[hex.EncodeToString(ski)]; ok {
+		return k, nil
+	}
+	return csp.BCCSP.GetKey(ski)
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	key, ok := k.(*sm2PrivateKey)
+	if !ok {
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+
+	// the token signs e = SM3(Z_A || M)
+	za, err := utils.SM2ZA(key.pub.pub, nil)
+	if err != nil {
+		return nil, err
+	}
+	h := sm3.New()
+	h.Write(za)
+	h.Write(digest)
+
+	csp.tokenMutex.Lock()
+	r, s, err := csp.token.sign(key.container, h.Sum(nil))
+	csp.tokenMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if bccsp.SM2SignatureEncodingOf(opts) == bccsp.SM2SignatureRaw {
+		return utils.MarshalSM2RawSignature(r, s)
+	}
+	return utils.MarshalECDSASignature(r, s)
+}
+
+// Verify verifies signature against key k and digest. Verification only
+// needs the public key, so it is always performed in software.
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	// Validate arguments
+	if k == nil {
+		return false, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(signature) == 0 {
+		return false, errors.New("Invalid signature. Cannot be empty")
+	}
+	if len(digest) == 0 {
+		return false, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	var pub *sm2.PublicKey
+	switch key := k.(type) {
+	case *sm2PrivateKey:
+		pub = key.pub.pub
+	case *sm2PublicKey:
+		pub = key.pub
+	default:
+		return csp.BCCSP.Verify(k, signature, digest, opts)
+	}
+
+	der := signature
+	switch bccsp.SM2SignatureEncodingOf(opts) {
+	case bccsp.SM2SignatureRaw:
+		var err error
+		if der, err = utils.SM2SignatureRawToDER(signature); err != nil {
+			return false, err
+		}
+	case bccsp.SM2SignatureAny:
+		if len(signature) == 2*sm2.KeyBytes {
+			der, _ = utils.SM2SignatureRawToDER(signature)
+		}
+	}
+	return sm2.Verify(pub, nil, digest, der), nil
+}
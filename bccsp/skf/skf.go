@@ -0,0 +1,315 @@
+// +build skf
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package skf
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <stdlib.h>
+#include <dlfcn.h>
+
+typedef unsigned int ULONG;
+typedef int BOOL;
+typedef void *HANDLE;
+
+#define ECC_MAX_COORDINATE_LEN 64
+
+typedef struct {
+	ULONG BitLen;
+	unsigned char XCoordinate[ECC_MAX_COORDINATE_LEN];
+	unsigned char YCoordinate[ECC_MAX_COORDINATE_LEN];
+} ECCPUBLICKEYBLOB;
+
+typedef struct {
+	unsigned char r[ECC_MAX_COORDINATE_LEN];
+	unsigned char s[ECC_MAX_COORDINATE_LEN];
+} ECCSIGNATUREBLOB;
+
+typedef struct {
+	void *lib;
+	ULONG (*EnumDev)(BOOL, char *, ULONG *);
+	ULONG (*ConnectDev)(char *, HANDLE *);
+	ULONG (*DisConnectDev)(HANDLE);
+	ULONG (*OpenApplication)(HANDLE, char *, HANDLE *);
+	ULONG (*CloseApplication)(HANDLE);
+	ULONG (*VerifyPIN)(HANDLE, ULONG, char *, ULONG *);
+	ULONG (*EnumContainer)(HANDLE, char *, ULONG *);
+	ULONG (*OpenContainer)(HANDLE, char *, HANDLE *);
+	ULONG (*CloseContainer)(HANDLE);
+	ULONG (*ExportPublicKey)(HANDLE, BOOL, unsigned char *, ULONG *);
+	ULONG (*ECCSignData)(HANDLE, unsigned char *, ULONG, ECCSIGNATUREBLOB *);
+} skf_funcs;
+
+#define SKF_LOAD(f, name) \
+	if ((*(void **)(&f->name) = dlsym(f->lib, "SKF_" #name)) == NULL) { \
+		*missing = "SKF_" #name; \
+		dlclose(f->lib); \
+		free(f); \
+		return NULL; \
+	}
+
+static skf_funcs *skf_load(const char *path, const char **missing) {
+	skf_funcs *f = calloc(1, sizeof(skf_funcs));
+	if (f == NULL) {
+		return NULL;
+	}
+	f->lib = dlopen(path, RTLD_NOW);
+	if (f->lib == NULL) {
+		*missing = dlerror();
+		free(f);
+		return NULL;
+	}
+	SKF_LOAD(f, EnumDev)
+	SKF_LOAD(f, ConnectDev)
+	SKF_LOAD(f, DisConnectDev)
+	SKF_LOAD(f, OpenApplication)
+	SKF_LOAD(f, CloseApplication)
+	SKF_LOAD(f, VerifyPIN)
+	SKF_LOAD(f, EnumContainer)
+	SKF_LOAD(f, OpenContainer)
+	SKF_LOAD(f, CloseContainer)
+	SKF_LOAD(f, ExportPublicKey)
+	SKF_LOAD(f, ECCSignData)
+	return f;
+}
+
+static void skf_unload(skf_funcs *f) {
+	dlclose(f->lib);
+	free(f);
+}
+
+static ULONG skf_enum_dev(skf_funcs *f, char *names, ULONG *size) { return f->EnumDev(1, names, size); }
+static ULONG skf_connect_dev(skf_funcs *f, char *name, HANDLE *dev) { return f->ConnectDev(name, dev); }
+static ULONG skf_disconnect_dev(skf_funcs *f, HANDLE dev) { return f->DisConnectDev(dev); }
+static ULONG skf_open_application(skf_funcs *f, HANDLE dev, char *name, HANDLE *app) {
+	return f->OpenApplication(dev, name, app);
+}
+static ULONG skf_close_application(skf_funcs *f, HANDLE app) { return f->CloseApplication(app); }
+static ULONG skf_verify_pin(skf_funcs *f, HANDLE app, char *pin, ULONG *retry) {
+	// USER_TYPE
+	return f->VerifyPIN(app, 1, pin, retry);
+}
+static ULONG skf_enum_container(skf_funcs *f, HANDLE app, char *names, ULONG *size) {
+	return f->EnumContainer(app, names, size);
+}
+static ULONG skf_open_container(skf_funcs *f, HANDLE app, char *name, HANDLE *c) {
+	return f->OpenContainer(app, name, c);
+}
+static ULONG skf_close_container(skf_funcs *f, HANDLE c) { return f->CloseContainer(c); }
+static ULONG skf_export_sign_public_key(skf_funcs *f, HANDLE c, ECCPUBLICKEYBLOB *blob) {
+	ULONG size = sizeof(ECCPUBLICKEYBLOB);
+	return f->ExportPublicKey(c, 1, (unsigned char *)blob, &size);
+}
+static ULONG skf_ecc_sign_data(skf_funcs *f, HANDLE c, unsigned char *d, ULONG l, ECCSIGNATUREBLOB *sig) {
+	return f->ECCSignData(c, d, l, sig);
+}
+*/
+import "C"
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"unsafe"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// sarPinIncorrect is the SKF error code of a wrong PIN.
+const sarPinIncorrect = 0x0A000024
+
+const eccMaxCoordinateLen = C.ECC_MAX_COORDINATE_LEN
+
+// Error is an error code returned by the SKF library.
+type Error uint32
+
+func (e Error) Error() string {
+	return fmt.Sprintf("SKF error 0x%08X", uint32(e))
+}
+
+func toError(rv C.ULONG) error {
+	if rv == 0 {
+		return nil
+	}
+	return Error(uint32(rv))
+}
+
+type lib struct {
+	f *C.skf_funcs
+}
+
+func loadLib(path string) (*lib, error) {
+	logger.Debugf("Loading SKF library [%s]\n", path)
+	if path == "" {
+		return nil, fmt.Errorf("No SKF library default")
+	}
+
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+
+	var missing *C.char
+	f := C.skf_load(cpath, &missing)
+	if f == nil {
+		return nil, fmt.Errorf("Instantiate failed [%s] [%s]", path, C.GoString(missing))
+	}
+	return &lib{f: f}, nil
+}
+
+func (l *lib) unload() {
+	C.skf_unload(l.f)
+}
+
+// nameList splits the double NUL terminated name list returned by the
+// SKF enumeration functions.
+func nameList(raw []byte) []string {
+	var names []string
+	for _, n := range bytes.Split(raw, []byte{0}) {
+		if len(n) > 0 {
+			names = append(names, string(n))
+		}
+	}
+	return names
+}
+
+func (l *lib) enumDevices() ([]string, error) {
+	var size C.ULONG
+	if err := toError(C.skf_enum_dev(l.f, nil, &size)); err != nil {
+		return nil, fmt.Errorf("SKF: enumerate devices failed [%s]", err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := (*C.char)(C.malloc(C.size_t(size)))
+	defer C.free(unsafe.Pointer(buf))
+	if err := toError(C.skf_enum_dev(l.f, buf, &size)); err != nil {
+		return nil, fmt.Errorf("SKF: enumerate devices failed [%s]", err)
+	}
+	return nameList(C.GoBytes(unsafe.Pointer(buf), C.int(size))), nil
+}
+
+// token is an SKF application opened on a connected device.
+type token struct {
+	l   *lib
+	dev C.HANDLE
+	app C.HANDLE
+}
+
+func (l *lib) openToken(device, application, pin string) (*token, error) {
+	cdev := C.CString(device)
+	defer C.free(unsafe.Pointer(cdev))
+
+	t := &token{l: l}
+	if err := toError(C.skf_connect_dev(l.f, cdev, &t.dev)); err != nil {
+		return nil, fmt.Errorf("SKF: connect device [%s] failed [%s]", device, err)
+	}
+
+	capp := C.CString(application)
+	defer C.free(unsafe.Pointer(capp))
+	if err := toError(C.skf_open_application(l.f, t.dev, capp, &t.app)); err != nil {
+		C.skf_disconnect_dev(l.f, t.dev)
+		return nil, fmt.Errorf("SKF: open application [%s] failed [%s]", application, err)
+	}
+
+	if err := t.verifyPIN(pin); err != nil {
+		t.close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *token) verifyPIN(pin string) error {
+	cpin := C.CString(pin)
+	defer C.free(unsafe.Pointer(cpin))
+
+	var retry C.ULONG
+	rv := C.skf_verify_pin(t.l.f, t.app, cpin, &retry)
+	if rv == sarPinIncorrect {
+		return fmt.Errorf("SKF: PIN verification failed, [%d] retries left", uint32(retry))
+	}
+	if err := toError(rv); err != nil {
+		return fmt.Errorf("SKF: PIN verification failed [%s]", err)
+	}
+	return nil
+}
+
+func (t *token) close() {
+	C.skf_close_application(t.l.f, t.app)
+	C.skf_disconnect_dev(t.l.f, t.dev)
+}
+
+func (t *token) enumContainers() ([]string, error) {
+	var size C.ULONG
+	if err := toError(C.skf_enum_container(t.l.f, t.app, nil, &size)); err != nil {
+		return nil, fmt.Errorf("SKF: enumerate containers failed [%s]", err)
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := (*C.char)(C.malloc(C.size_t(size)))
+	defer C.free(unsafe.Pointer(buf))
+	if err := toError(C.skf_enum_container(t.l.f, t.app, buf, &size)); err != nil {
+		return nil, fmt.Errorf("SKF: enumerate containers failed [%s]", err)
+	}
+	return nameList(C.GoBytes(unsafe.Pointer(buf), C.int(size))), nil
+}
+
+// signPublicKey returns the public key of the signing key pair of container.
+func (t *token) signPublicKey(container string) (*sm2.PublicKey, error) {
+	c, err := t.openContainer(container)
+	if err != nil {
+		return nil, err
+	}
+	defer C.skf_close_container(t.l.f, c)
+
+	var blob C.ECCPUBLICKEYBLOB
+	if err := toError(C.skf_export_sign_public_key(t.l.f, c, &blob)); err != nil {
+		return nil, fmt.Errorf("SKF: export public key of container [%s] failed [%s]", container, err)
+	}
+	if blob.BitLen != 256 {
+		return nil, fmt.Errorf("SKF: container [%s] does not hold an SM2 key, bits [%d]", container, blob.BitLen)
+	}
+
+	x := C.GoBytes(unsafe.Pointer(&blob.XCoordinate[0]), eccMaxCoordinateLen)
+	y := C.GoBytes(unsafe.Pointer(&blob.YCoordinate[0]), eccMaxCoordinateLen)
+	return &sm2.PublicKey{
+		Curve: sm2.GetSm2P256V1(),
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}, nil
+}
+
+// sign signs e = SM3(Z_A || M) with the signing key of container.
+func (t *token) sign(container string, e []byte) (r, s *big.Int, err error) {
+	c, err := t.openContainer(container)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer C.skf_close_container(t.l.f, c)
+
+	var sig C.ECCSIGNATUREBLOB
+	if err := toError(C.skf_ecc_sign_data(t.l.f, c, (*C.uchar)(unsafe.Pointer(&e[0])), C.ULONG(len(e)), &sig)); err != nil {
+		return nil, nil, fmt.Errorf("SKF: sign with container [%s] failed [%s]", container, err)
+	}
+
+	r = new(big.Int).SetBytes(C.GoBytes(unsafe.Pointer(&sig.r[0]), eccMaxCoordinateLen))
+	s = new(big.Int).SetBytes(C.GoBytes(unsafe.Pointer(&sig.s[0]), eccMaxCoordinateLen))
+	return r, s, nil
+}
+
+func (t *token) openContainer(container string) (C.HANDLE, error) {
+	cname := C.CString(container)
+	defer C.free(unsafe.Pointer(cname))
+
+	var c C.HANDLE
+	if err := toError(C.skf_open_container(t.l.f, t.app, cname, &c)); err != nil {
+		return nil, fmt.Errorf("SKF: open container [%s] failed [%s]", container, err)
+	}
+	return c, nil
+}
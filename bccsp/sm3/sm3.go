@@ -0,0 +1,125 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package sm3 implements the SM3 hash algorithm defined in GB/T 32905-2016.
+//
+// The compression function is implemented in assembly on amd64 and arm64,
+// selected at runtime according to the features of the CPU, with a pure Go
+// implementation as fallback. The digests are identical to those of
+// github.com/paul-lee-attorney/gm/sm3.
+package sm3
+
+import (
+	"encoding/binary"
+	"hash"
+)
+
+// Size SM3摘要长度, 256位.
+const Size = 32
+
+// BlockSize SM3分组长度, 512位.
+const BlockSize = 64
+
+const (
+	chunk = 64
+	init0 = 0x7380166f
+	init1 = 0x4914b2b9
+	init2 = 0x172442d7
+	init3 = 0xda8a0600
+	init4 = 0xa96f30bc
+	init5 = 0x163138aa
+	init6 = 0xe38dee4d
+	init7 = 0xb0fb0e4e
+)
+
+// digest represents the partial evaluation of a checksum.
+type digest struct {
+	h   [8]uint32
+	x   [chunk]byte
+	nx  int
+	len uint64
+}
+
+// New returns a new hash.Hash computing the SM3 checksum.
+func New() hash.Hash {
+	d := &digest{}
+	d.Reset()
+	return d
+}
+
+// Sum returns the SM3 checksum of data.
+func Sum(data []byte) [Size]byte {
+	d := digest{}
+	d.Reset()
+	d.Write(data)
+	return d.checkSum()
+}
+
+func (d *digest) Reset() {
+	d.h = [8]uint32{init0, init1, init2, init3, init4, init5, init6, init7}
+	d.nx = 0
+	d.len = 0
+}
+
+func (d *digest) Size() int { return Size }
+
+func (d *digest) BlockSize() int { return BlockSize }
+
+func (d *digest) Write(p []byte) (nn int, err error) {
+	nn = len(p)
+	d.len += uint64(nn)
+	if d.nx > 0 {
+		n := copy(d.x[d.nx:], p)
+		d.nx += n
+		if d.nx == chunk {
+			block(&d.h, d.x[:])
+			d.nx = 0
+		}
+		p = p[n:]
+	}
+	if len(p) >= chunk {
+		n := len(p) &^ (chunk - 1)
+		block(&d.h, p[:n])
+		p = p[n:]
+	}
+	if len(p) > 0 {
+		d.nx = copy(d.x[:], p)
+	}
+	return
+}
+
+func (d *digest) Sum(in []byte) []byte {
+	// Make a copy of d so that caller can keep writing and summing.
+	d0 := *d
+	hash := d0.checkSum()
+	return append(in, hash[:]...)
+}
+
+func (d *digest) checkSum() [Size]byte {
+	len := d.len
+	// Padding. Add a 1 bit and 0 bits until 56 bytes mod 64.
+	var tmp [64]byte
+	tmp[0] = 0x80
+	if len%64 < 56 {
+		d.Write(tmp[0 : 56-len%64])
+	} else {
+		d.Write(tmp[0 : 64+56-len%64])
+	}
+
+	// Length in bits.
+	binary.BigEndian.PutUint64(tmp[:], len<<3)
+	d.Write(tmp[0:8])
+
+	if d.nx != 0 {
+		panic("d.nx != 0")
+	}
+
+	var digest [Size]byte
+	for i, s := range d.h {
+		binary.BigEndian.PutUint32(digest[i*4:], s)
+	}
+	return digest
+}
@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm3
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSum(t *testing.T) {
+	t.Logf("SM3 compression function: %s", implementation)
+
+	tests := []struct {
+		in  string
+		out string
+	}{
+		// GB/T 32905-2016 Appendix A
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+		{"", "1ab21d8355cfa17f8e61194831e81a8f22bec8c728fefb747ed035eb5082aa2b"},
+	}
+	for _, test := range tests {
+		sum := Sum([]byte(test.in))
+		assert.Equal(t, test.out, hex.EncodeToString(sum[:]))
+
+		h := New()
+		for i := 0; i < len(test.in); i++ {
+			h.Write([]byte{test.in[i]})
+		}
+		assert.Equal(t, test.out, hex.EncodeToString(h.Sum(nil)))
+		// Sum does not change the state of the hash
+		assert.Equal(t, test.out, hex.EncodeToString(h.Sum(nil)))
+
+		h.Reset()
+		h.Write([]byte(test.in))
+		assert.Equal(t, test.out, hex.EncodeToString(h.Sum(nil)))
+	}
+}
+
+func TestBlock(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for blocks := 1; blocks <= 17; blocks++ {
+		p := make([]byte, blocks*chunk)
+		r.Read(p)
+
+		h := [8]uint32{init0, init1, init2, init3, init4, init5, init6, init7}
+		expected := h
+		block(&h, p)
+		blockGeneric(&expected, p)
+		require.Equal(t, expected, h, "%d blocks", blocks)
+	}
+
+	// trailing bytes of partial blocks are ignored
+	h := [8]uint32{init0, init1, init2, init3, init4, init5, init6, init7}
+	expected := h
+	block(&h, bytes.Repeat([]byte{0x5a}, chunk+chunk/2))
+	blockGeneric(&expected, bytes.Repeat([]byte{0x5a}, chunk))
+	assert.Equal(t, expected, h)
+}
+
+func TestSize(t *testing.T) {
+	h := New()
+	assert.Equal(t, Size, h.Size())
+	assert.Equal(t, BlockSize, h.BlockSize())
+}
+
+var buf = make([]byte, 8192)
+
+func benchmarkSize(b *testing.B, size int) {
+	h := New()
+	sum := make([]byte, 0, Size)
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Reset()
+		h.Write(buf[:size])
+		h.Sum(sum[:0])
+	}
+}
+
+func BenchmarkHash8Bytes(b *testing.B) {
+	benchmarkSize(b, 8)
+}
+
+func BenchmarkHash1K(b *testing.B) {
+	benchmarkSize(b, 1024)
+}
+
+func BenchmarkHash8K(b *testing.B) {
+	benchmarkSize(b, 8192)
+}
+
+func BenchmarkBlockGeneric(b *testing.B) {
+	h := [8]uint32{init0, init1, init2, init3, init4, init5, init6, init7}
+	b.SetBytes(int64(len(buf)))
+	for i := 0; i < b.N; i++ {
+		blockGeneric(&h, buf)
+	}
+}
@@ -0,0 +1,66 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm3
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+const (
+	t0 = 0x79cc4519 // T_j, 0 <= j < 16
+	t1 = 0x7a879d8a // T_j, 16 <= j < 64
+)
+
+// blockGeneric compresses the 64 byte blocks of p into h in pure Go.
+func blockGeneric(h *[8]uint32, p []byte) {
+	var w [68]uint32
+	a, b, c, d, e, f, g, hh := h[0], h[1], h[2], h[3], h[4], h[5], h[6], h[7]
+	for len(p) >= chunk {
+		// 消息扩展
+		for i := 0; i < 16; i++ {
+			w[i] = binary.BigEndian.Uint32(p[i*4:])
+		}
+		for i := 16; i < 68; i++ {
+			x := w[i-16] ^ w[i-9] ^ bits.RotateLeft32(w[i-3], 15)
+			w[i] = x ^ bits.RotateLeft32(x, 15) ^ bits.RotateLeft32(x, 23) ^ bits.RotateLeft32(w[i-13], 7) ^ w[i-6]
+		}
+
+		// 压缩函数
+		a1, b1, c1, d1, e1, f1, g1, h1 := a, b, c, d, e, f, g, hh
+		for i := 0; i < 16; i++ {
+			a12 := bits.RotateLeft32(a1, 12)
+			ss1 := bits.RotateLeft32(a12+e1+bits.RotateLeft32(t0, i), 7)
+			ss2 := ss1 ^ a12
+			tt1 := (a1 ^ b1 ^ c1) + d1 + ss2 + (w[i] ^ w[i+4])
+			tt2 := (e1 ^ f1 ^ g1) + h1 + ss1 + w[i]
+			d1, c1, b1, a1 = c1, bits.RotateLeft32(b1, 9), a1, tt1
+			h1, g1, f1, e1 = g1, bits.RotateLeft32(f1, 19), e1, tt2^bits.RotateLeft32(tt2, 9)^bits.RotateLeft32(tt2, 17)
+		}
+		for i := 16; i < 64; i++ {
+			a12 := bits.RotateLeft32(a1, 12)
+			ss1 := bits.RotateLeft32(a12+e1+bits.RotateLeft32(t1, i), 7)
+			ss2 := ss1 ^ a12
+			tt1 := ((a1 & b1) | (a1 & c1) | (b1 & c1)) + d1 + ss2 + (w[i] ^ w[i+4])
+			tt2 := ((e1 & f1) | (^e1 & g1)) + h1 + ss1 + w[i]
+			d1, c1, b1, a1 = c1, bits.RotateLeft32(b1, 9), a1, tt1
+			h1, g1, f1, e1 = g1, bits.RotateLeft32(f1, 19), e1, tt2^bits.RotateLeft32(tt2, 9)^bits.RotateLeft32(tt2, 17)
+		}
+
+		a ^= a1
+		b ^= b1
+		c ^= c1
+		d ^= d1
+		e ^= e1
+		f ^= f1
+		g ^= g1
+		hh ^= h1
+
+		p = p[chunk:]
+	}
+	h[0], h[1], h[2], h[3], h[4], h[5], h[6], h[7] = a, b, c, d, e, f, g, hh
+}
@@ -0,0 +1,47 @@
+// +build amd64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm3
+
+// useBMI detects the BMI1 and BMI2 instructions the assembly compression
+// function is written with, available on Intel CPUs since Haswell and AMD
+// CPUs since Excavator, Zen and Hygon Dhyana included.
+var useBMI = hasBMI()
+
+var implementation = "generic"
+
+func init() {
+	if useBMI {
+		implementation = "amd64-bmi2"
+	}
+}
+
+//go:noescape
+func blockAMD64(h *[8]uint32, p []byte)
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+func hasBMI() bool {
+	if maxID, _, _, _ := cpuid(0, 0); maxID < 7 {
+		return false
+	}
+	_, ebx7, _, _ := cpuid(7, 0)
+	const (
+		bmi1 = 1 << 3
+		bmi2 = 1 << 8
+	)
+	return ebx7&bmi1 != 0 && ebx7&bmi2 != 0
+}
+
+func block(h *[8]uint32, p []byte) {
+	if useBMI {
+		blockAMD64(h, p)
+		return
+	}
+	blockGeneric(h, p)
+}
@@ -0,0 +1,291 @@
+// +build amd64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+#include "textflag.h"
+
+// SM3 compression function using the rotations of BMI2 and ANDN of BMI1.
+//
+// The state A-H is kept in R8-R15 and the message words W[0..67] on the
+// stack; W'[j] = W[j] ^ W[j+4] is computed in the rounds. Instead of moving
+// the state words at the end of a round, the registers are renamed in the
+// arguments of the next round: after rounds j and j+4 the mapping repeats.
+//
+// AX, BX, CX, DX, SI and DI are scratch registers in the rounds, so the
+// pointers to the current block and to the end of the blocks are kept on
+// the stack. The message expansion of W[j+16] is interleaved with round j.
+
+// W[i] = bswap(p[4*i:4*i+4]), 0 <= i < 16
+#define LOAD(i) \
+	MOVL  ((i)*4)(SI), AX; \
+	BSWAPL AX;             \
+	MOVL  AX, ((i)*4)(SP)
+
+// W[i] = P1(W[i-16] ^ W[i-9] ^ (W[i-3] <<< 15)) ^ (W[i-13] <<< 7) ^ W[i-6], 16 <= i < 68
+#define EXPAND(i) \
+	MOVL  (((i)-3)*4)(SP), AX;  \
+	RORXL $17, AX, AX;          \
+	XORL  (((i)-16)*4)(SP), AX; \
+	XORL  (((i)-9)*4)(SP), AX;  \
+	RORXL $17, AX, BX;          \
+	RORXL $9, AX, CX;           \
+	XORL  BX, AX;               \
+	XORL  CX, AX;               \
+	MOVL  (((i)-13)*4)(SP), BX; \
+	RORXL $25, BX, BX;          \
+	XORL  BX, AX;               \
+	XORL  (((i)-6)*4)(SP), AX;  \
+	MOVL  AX, ((i)*4)(SP)
+
+// CX = W[j] + H, DX = W'[j] + D
+#define ROUND_W(j, d, h) \
+	MOVL  ((j)*4)(SP), CX;      \
+	MOVL  (((j)+4)*4)(SP), DX;  \
+	XORL  CX, DX;               \
+	ADDL  h, CX;                \
+	ADDL  d, DX
+
+// SS1 = ((A <<< 12) + E + (T[j] <<< j)) <<< 7, SS2 = SS1 ^ (A <<< 12),
+// D = TT1 (the next A), H = P0(TT2) (the next E), B = B <<< 9, F = F <<< 19
+#define ROUND_SS(tj, a, b, d, e, f, h) \
+	RORXL $20, a, AX;           \
+	LEAL  tj(AX)(e*1), BX;      \
+	RORXL $25, BX, BX;          \
+	XORL  BX, AX;               \
+	ADDL  BX, CX;               \
+	ADDL  AX, DX;               \
+	RORXL $23, b, b;            \
+	RORXL $13, f, f;            \
+	MOVL  DX, d;                \
+	RORXL $23, CX, h;           \
+	RORXL $15, CX, AX;          \
+	XORL  CX, h;                \
+	XORL  AX, h
+
+// Rounds 0 <= j < 16: FF = GG = X ^ Y ^ Z
+#define ROUND0(j, tj, a, b, c, d, e, f, g, h) \
+	ROUND_W(j, d, h);           \
+	MOVL  e, SI;                \
+	XORL  f, SI;                \
+	XORL  g, SI;                \
+	ADDL  SI, CX;               \
+	MOVL  a, DI;                \
+	XORL  b, DI;                \
+	XORL  c, DI;                \
+	ADDL  DI, DX;               \
+	ROUND_SS(tj, a, b, d, e, f, h)
+
+// Rounds 16 <= j < 64: FF = (X & Y) | (X & Z) | (Y & Z), GG = (X & Y) | (^X & Z)
+#define ROUND1(j, tj, a, b, c, d, e, f, g, h) \
+	ROUND_W(j, d, h);           \
+	ANDNL g, e, SI;             \
+	MOVL  e, DI;                \
+	ANDL  f, DI;                \
+	ORL   DI, SI;               \
+	ADDL  SI, CX;               \
+	MOVL  a, SI;                \
+	MOVL  a, DI;                \
+	ORL   b, SI;                \
+	ANDL  b, DI;                \
+	ANDL  c, SI;                \
+	ORL   SI, DI;               \
+	ADDL  DI, DX;               \
+	ROUND_SS(tj, a, b, d, e, f, h)
+
+// func blockAMD64(h *[8]uint32, p []byte)
+TEXT ·blockAMD64(SB), 0, $288-32
+	MOVQ p_base+8(FP), SI
+	MOVQ p_len+16(FP), DX
+	SHRQ $6, DX
+	SHLQ $6, DX
+	LEAQ (SI)(DX*1), DI
+	CMPQ SI, DI
+	JEQ  end
+	MOVQ DI, end-8(SP)
+
+	MOVQ h+0(FP), BX
+	MOVL (0*4)(BX), R8
+	MOVL (1*4)(BX), R9
+	MOVL (2*4)(BX), R10
+	MOVL (3*4)(BX), R11
+	MOVL (4*4)(BX), R12
+	MOVL (5*4)(BX), R13
+	MOVL (6*4)(BX), R14
+	MOVL (7*4)(BX), R15
+
+loop:
+	LOAD(0)
+	LOAD(1)
+	LOAD(2)
+	LOAD(3)
+	LOAD(4)
+	LOAD(5)
+	LOAD(6)
+	LOAD(7)
+	LOAD(8)
+	LOAD(9)
+	LOAD(10)
+	LOAD(11)
+	LOAD(12)
+	LOAD(13)
+	LOAD(14)
+	LOAD(15)
+	MOVQ SI, p-16(SP)
+
+	EXPAND(16)
+	ROUND0(0, 2043430169, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(17)
+	ROUND0(1, -208106958, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(18)
+	ROUND0(2, -416213915, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(19)
+	ROUND0(3, -832427829, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(20)
+	ROUND0(4, -1664855657, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(21)
+	ROUND0(5, 965255983, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(22)
+	ROUND0(6, 1930511966, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(23)
+	ROUND0(7, -433943364, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(24)
+	ROUND0(8, -867886727, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(25)
+	ROUND0(9, -1735773453, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(26)
+	ROUND0(10, 823420391, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(27)
+	ROUND0(11, 1646840782, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(28)
+	ROUND0(12, -1001285732, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(29)
+	ROUND0(13, -2002571463, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(30)
+	ROUND0(14, 289824371, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(31)
+	ROUND0(15, 579648742, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(32)
+	ROUND1(16, -1651869049, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(33)
+	ROUND1(17, 991229199, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(34)
+	ROUND1(18, 1982458398, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(35)
+	ROUND1(19, -330050500, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(36)
+	ROUND1(20, -660100999, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(37)
+	ROUND1(21, -1320201997, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(38)
+	ROUND1(22, 1654563303, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(39)
+	ROUND1(23, -985840690, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(40)
+	ROUND1(24, -1971681379, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(41)
+	ROUND1(25, 351604539, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(42)
+	ROUND1(26, 703209078, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(43)
+	ROUND1(27, 1406418156, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(44)
+	ROUND1(28, -1482130984, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(45)
+	ROUND1(29, 1330705329, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(46)
+	ROUND1(30, -1633556638, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(47)
+	ROUND1(31, 1027854021, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(48)
+	ROUND1(32, 2055708042, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(49)
+	ROUND1(33, -183551212, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(50)
+	ROUND1(34, -367102423, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(51)
+	ROUND1(35, -734204845, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(52)
+	ROUND1(36, -1468409689, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(53)
+	ROUND1(37, 1358147919, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(54)
+	ROUND1(38, -1578671458, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(55)
+	ROUND1(39, 1137624381, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(56)
+	ROUND1(40, -2019718534, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(57)
+	ROUND1(41, 255530229, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(58)
+	ROUND1(42, 511060458, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(59)
+	ROUND1(43, 1022120916, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(60)
+	ROUND1(44, 2044241832, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(61)
+	ROUND1(45, -206483632, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(62)
+	ROUND1(46, -412967263, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(63)
+	ROUND1(47, -825934525, R9, R10, R11, R8, R13, R14, R15, R12)
+	EXPAND(64)
+	ROUND1(48, -1651869049, R8, R9, R10, R11, R12, R13, R14, R15)
+	EXPAND(65)
+	ROUND1(49, 991229199, R11, R8, R9, R10, R15, R12, R13, R14)
+	EXPAND(66)
+	ROUND1(50, 1982458398, R10, R11, R8, R9, R14, R15, R12, R13)
+	EXPAND(67)
+	ROUND1(51, -330050500, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND1(52, -660100999, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND1(53, -1320201997, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND1(54, 1654563303, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND1(55, -985840690, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND1(56, -1971681379, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND1(57, 351604539, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND1(58, 703209078, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND1(59, 1406418156, R9, R10, R11, R8, R13, R14, R15, R12)
+	ROUND1(60, -1482130984, R8, R9, R10, R11, R12, R13, R14, R15)
+	ROUND1(61, 1330705329, R11, R8, R9, R10, R15, R12, R13, R14)
+	ROUND1(62, -1633556638, R10, R11, R8, R9, R14, R15, R12, R13)
+	ROUND1(63, 1027854021, R9, R10, R11, R8, R13, R14, R15, R12)
+
+	MOVQ h+0(FP), BX
+	XORL (0*4)(BX), R8
+	MOVL R8, (0*4)(BX)
+	XORL (1*4)(BX), R9
+	MOVL R9, (1*4)(BX)
+	XORL (2*4)(BX), R10
+	MOVL R10, (2*4)(BX)
+	XORL (3*4)(BX), R11
+	MOVL R11, (3*4)(BX)
+	XORL (4*4)(BX), R12
+	MOVL R12, (4*4)(BX)
+	XORL (5*4)(BX), R13
+	MOVL R13, (5*4)(BX)
+	XORL (6*4)(BX), R14
+	MOVL R14, (6*4)(BX)
+	XORL (7*4)(BX), R15
+	MOVL R15, (7*4)(BX)
+
+	MOVQ p-16(SP), SI
+	ADDQ $64, SI
+	CMPQ SI, end-8(SP)
+	JB   loop
+
+end:
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
@@ -0,0 +1,20 @@
+// +build arm64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm3
+
+// The assembly compression function only uses instructions of the base
+// ARMv8 instruction set, available on every arm64 CPU.
+var implementation = "arm64"
+
+//go:noescape
+func blockARM64(h *[8]uint32, p []byte)
+
+func block(h *[8]uint32, p []byte) {
+	blockARM64(h, p)
+}
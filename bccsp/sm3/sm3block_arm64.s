@@ -0,0 +1,279 @@
+// +build arm64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+#include "textflag.h"
+
+// SM3 compression function for ARMv8.
+//
+// The state A-H is kept in R0-R7 and the message words W[0..67] on the
+// stack; W'[j] = W[j] ^ W[j+4] is computed in the rounds. The rotations of
+// P0 and P1 are folded into the shifted register operands of EORW. Instead
+// of moving the state words at the end of a round, the registers are
+// renamed in the arguments of the next round: after rounds j and j+4 the
+// mapping repeats. The message expansion of W[j+16] is interleaved with
+// round j.
+//
+// R8-R14 are scratch registers, R19 points to the current block, R20 to the
+// end of the blocks and R21 to the state.
+
+#define W(i) (8+(i)*4)(RSP)
+
+// W[i] = bswap(p[4*i:4*i+4]), 0 <= i < 16
+#define LOAD(i) \
+	MOVWU ((i)*4)(R19), R8; \
+	REVW  R8, R8;           \
+	MOVW  R8, W(i)
+
+// W[i] = P1(W[i-16] ^ W[i-9] ^ (W[i-3] <<< 15)) ^ (W[i-13] <<< 7) ^ W[i-6], 16 <= i < 68
+#define EXPAND(i) \
+	MOVWU W((i)-16), R10;       \
+	MOVWU W((i)-9), R11;        \
+	MOVWU W((i)-3), R12;        \
+	EORW  R11, R10, R10;        \
+	EORW  R12@>17, R10, R10;    \
+	EORW  R10@>17, R10, R11;    \
+	EORW  R10@>9, R11, R11;     \
+	MOVWU W((i)-13), R10;       \
+	MOVWU W((i)-6), R12;        \
+	EORW  R10@>25, R11, R11;    \
+	EORW  R12, R11, R11;        \
+	MOVW  R11, W(i)
+
+// R8 = W[j] + H, R9 = W'[j] + D, R14 = T[j] <<< j
+#define ROUND_W(j, tj, d, h) \
+	MOVWU W(j), R8;             \
+	MOVWU W((j)+4), R9;         \
+	MOVW  $(tj), R14;           \
+	EORW  R8, R9, R9;           \
+	ADDW  h, R8, R8;            \
+	ADDW  d, R9, R9
+
+// SS1 = ((A <<< 12) + E + (T[j] <<< j)) <<< 7, SS2 = SS1 ^ (A <<< 12),
+// D = TT1 (the next A), H = P0(TT2) (the next E), B = B <<< 9, F = F <<< 19
+#define ROUND_SS(a, b, d, e, f, h) \
+	RORW  $20, a, R12;          \
+	ADDW  R14, R12, R13;        \
+	ADDW  e, R13, R13;          \
+	RORW  $25, R13, R13;        \
+	EORW  R13, R12, R12;        \
+	ADDW  R13, R8, R8;          \
+	ADDW  R12, R9, d;           \
+	RORW  $23, b, b;            \
+	RORW  $13, f, f;            \
+	EORW  R8@>23, R8, h;        \
+	EORW  R8@>15, h, h
+
+// Rounds 0 <= j < 16: FF = GG = X ^ Y ^ Z
+#define ROUND0(j, tj, a, b, c, d, e, f, g, h) \
+	ROUND_W(j, tj, d, h);       \
+	EORW  f, e, R10;            \
+	EORW  b, a, R11;            \
+	EORW  g, R10, R10;          \
+	EORW  c, R11, R11;          \
+	ADDW  R10, R8, R8;          \
+	ADDW  R11, R9, R9;          \
+	ROUND_SS(a, b, d, e, f, h)
+
+// Rounds 16 <= j < 64: FF = (X & Y) | (X & Z) | (Y & Z), GG = (X & Y) | (^X & Z)
+#define ROUND1(j, tj, a, b, c, d, e, f, g, h) \
+	ROUND_W(j, tj, d, h);       \
+	ANDW  f, e, R10;            \
+	BICW  e, g, R11;            \
+	ORRW  R11, R10, R10;        \
+	ADDW  R10, R8, R8;          \
+	ORRW  b, a, R10;            \
+	ANDW  b, a, R11;            \
+	ANDW  c, R10, R10;          \
+	ORRW  R11, R10, R10;        \
+	ADDW  R10, R9, R9;          \
+	ROUND_SS(a, b, d, e, f, h)
+
+// func blockARM64(h *[8]uint32, p []byte)
+TEXT ·blockARM64(SB), NOSPLIT, $272-32
+	MOVD h+0(FP), R21
+	MOVD p_base+8(FP), R19
+	MOVD p_len+16(FP), R20
+	AND  $~63, R20
+	CBZ  R20, end
+	ADD  R19, R20
+
+	MOVWU (0*4)(R21), R0
+	MOVWU (1*4)(R21), R1
+	MOVWU (2*4)(R21), R2
+	MOVWU (3*4)(R21), R3
+	MOVWU (4*4)(R21), R4
+	MOVWU (5*4)(R21), R5
+	MOVWU (6*4)(R21), R6
+	MOVWU (7*4)(R21), R7
+
+loop:
+	LOAD(0)
+	LOAD(1)
+	LOAD(2)
+	LOAD(3)
+	LOAD(4)
+	LOAD(5)
+	LOAD(6)
+	LOAD(7)
+	LOAD(8)
+	LOAD(9)
+	LOAD(10)
+	LOAD(11)
+	LOAD(12)
+	LOAD(13)
+	LOAD(14)
+	LOAD(15)
+
+	EXPAND(16)
+	ROUND0(0, 0x79cc4519, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(17)
+	ROUND0(1, 0xf3988a32, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(18)
+	ROUND0(2, 0xe7311465, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(19)
+	ROUND0(3, 0xce6228cb, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(20)
+	ROUND0(4, 0x9cc45197, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(21)
+	ROUND0(5, 0x3988a32f, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(22)
+	ROUND0(6, 0x7311465e, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(23)
+	ROUND0(7, 0xe6228cbc, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(24)
+	ROUND0(8, 0xcc451979, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(25)
+	ROUND0(9, 0x988a32f3, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(26)
+	ROUND0(10, 0x311465e7, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(27)
+	ROUND0(11, 0x6228cbce, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(28)
+	ROUND0(12, 0xc451979c, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(29)
+	ROUND0(13, 0x88a32f39, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(30)
+	ROUND0(14, 0x11465e73, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(31)
+	ROUND0(15, 0x228cbce6, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(32)
+	ROUND1(16, 0x9d8a7a87, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(33)
+	ROUND1(17, 0x3b14f50f, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(34)
+	ROUND1(18, 0x7629ea1e, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(35)
+	ROUND1(19, 0xec53d43c, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(36)
+	ROUND1(20, 0xd8a7a879, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(37)
+	ROUND1(21, 0xb14f50f3, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(38)
+	ROUND1(22, 0x629ea1e7, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(39)
+	ROUND1(23, 0xc53d43ce, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(40)
+	ROUND1(24, 0x8a7a879d, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(41)
+	ROUND1(25, 0x14f50f3b, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(42)
+	ROUND1(26, 0x29ea1e76, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(43)
+	ROUND1(27, 0x53d43cec, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(44)
+	ROUND1(28, 0xa7a879d8, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(45)
+	ROUND1(29, 0x4f50f3b1, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(46)
+	ROUND1(30, 0x9ea1e762, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(47)
+	ROUND1(31, 0x3d43cec5, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(48)
+	ROUND1(32, 0x7a879d8a, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(49)
+	ROUND1(33, 0xf50f3b14, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(50)
+	ROUND1(34, 0xea1e7629, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(51)
+	ROUND1(35, 0xd43cec53, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(52)
+	ROUND1(36, 0xa879d8a7, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(53)
+	ROUND1(37, 0x50f3b14f, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(54)
+	ROUND1(38, 0xa1e7629e, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(55)
+	ROUND1(39, 0x43cec53d, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(56)
+	ROUND1(40, 0x879d8a7a, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(57)
+	ROUND1(41, 0x0f3b14f5, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(58)
+	ROUND1(42, 0x1e7629ea, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(59)
+	ROUND1(43, 0x3cec53d4, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(60)
+	ROUND1(44, 0x79d8a7a8, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(61)
+	ROUND1(45, 0xf3b14f50, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(62)
+	ROUND1(46, 0xe7629ea1, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(63)
+	ROUND1(47, 0xcec53d43, R1, R2, R3, R0, R5, R6, R7, R4)
+	EXPAND(64)
+	ROUND1(48, 0x9d8a7a87, R0, R1, R2, R3, R4, R5, R6, R7)
+	EXPAND(65)
+	ROUND1(49, 0x3b14f50f, R3, R0, R1, R2, R7, R4, R5, R6)
+	EXPAND(66)
+	ROUND1(50, 0x7629ea1e, R2, R3, R0, R1, R6, R7, R4, R5)
+	EXPAND(67)
+	ROUND1(51, 0xec53d43c, R1, R2, R3, R0, R5, R6, R7, R4)
+	ROUND1(52, 0xd8a7a879, R0, R1, R2, R3, R4, R5, R6, R7)
+	ROUND1(53, 0xb14f50f3, R3, R0, R1, R2, R7, R4, R5, R6)
+	ROUND1(54, 0x629ea1e7, R2, R3, R0, R1, R6, R7, R4, R5)
+	ROUND1(55, 0xc53d43ce, R1, R2, R3, R0, R5, R6, R7, R4)
+	ROUND1(56, 0x8a7a879d, R0, R1, R2, R3, R4, R5, R6, R7)
+	ROUND1(57, 0x14f50f3b, R3, R0, R1, R2, R7, R4, R5, R6)
+	ROUND1(58, 0x29ea1e76, R2, R3, R0, R1, R6, R7, R4, R5)
+	ROUND1(59, 0x53d43cec, R1, R2, R3, R0, R5, R6, R7, R4)
+	ROUND1(60, 0xa7a879d8, R0, R1, R2, R3, R4, R5, R6, R7)
+	ROUND1(61, 0x4f50f3b1, R3, R0, R1, R2, R7, R4, R5, R6)
+	ROUND1(62, 0x9ea1e762, R2, R3, R0, R1, R6, R7, R4, R5)
+	ROUND1(63, 0x3d43cec5, R1, R2, R3, R0, R5, R6, R7, R4)
+
+	MOVWU (0*4)(R21), R8
+	MOVWU (1*4)(R21), R9
+	MOVWU (2*4)(R21), R10
+	MOVWU (3*4)(R21), R11
+	EORW  R8, R0, R0
+	EORW  R9, R1, R1
+	EORW  R10, R2, R2
+	EORW  R11, R3, R3
+	MOVW  R0, (0*4)(R21)
+	MOVW  R1, (1*4)(R21)
+	MOVW  R2, (2*4)(R21)
+	MOVW  R3, (3*4)(R21)
+	MOVWU (4*4)(R21), R8
+	MOVWU (5*4)(R21), R9
+	MOVWU (6*4)(R21), R10
+	MOVWU (7*4)(R21), R11
+	EORW  R8, R4, R4
+	EORW  R9, R5, R5
+	EORW  R10, R6, R6
+	EORW  R11, R7, R7
+	MOVW  R4, (4*4)(R21)
+	MOVW  R5, (5*4)(R21)
+	MOVW  R6, (6*4)(R21)
+	MOVW  R7, (7*4)(R21)
+
+	ADD  $64, R19
+	CMP  R20, R19
+	BLO  loop
+
+end:
+	RET
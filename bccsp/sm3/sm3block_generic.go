@@ -0,0 +1,15 @@
+// +build !amd64,!arm64 purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm3
+
+var implementation = "generic"
+
+func block(h *[8]uint32, p []byte) {
+	blockGeneric(h, p)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+// batchBlocks is the number of counter blocks CTR and GCM encrypt at once.
+const batchBlocks = 16
+
+// ctr is the CTR mode of crypto/cipher.NewCTR, where the counter is the
+// whole block incremented as a big-endian integer, generating the key
+// stream of several blocks at once.
+type ctr struct {
+	rk      *[32]uint32
+	counter [BlockSize]byte
+	in      [batchBlocks * BlockSize]byte
+	out     [batchBlocks * BlockSize]byte
+	used    int
+}
+
+// NewCTR returns the CTR mode of c with iv, which crypto/cipher.NewCTR
+// calls instead of its generic implementation.
+func (c *sm4Cipher) NewCTR(iv []byte) cipher.Stream {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCTR: IV length must equal block size")
+	}
+	x := &ctr{rk: &c.enc, used: batchBlocks * BlockSize}
+	copy(x.counter[:], iv)
+	return x
+}
+
+func (x *ctr) refill() {
+	for i := 0; i < batchBlocks; i++ {
+		copy(x.in[i*BlockSize:], x.counter[:])
+		hi := binary.BigEndian.Uint64(x.counter[:8])
+		lo := binary.BigEndian.Uint64(x.counter[8:]) + 1
+		if lo == 0 {
+			hi++
+		}
+		binary.BigEndian.PutUint64(x.counter[:8], hi)
+		binary.BigEndian.PutUint64(x.counter[8:], lo)
+	}
+	cryptBlocks(x.rk, x.out[:], x.in[:])
+	x.used = 0
+}
+
+func (x *ctr) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("crypto/cipher: output smaller than input")
+	}
+	for len(src) > 0 {
+		if x.used == len(x.out) {
+			x.refill()
+		}
+		n := xorBytes(dst, src, x.out[x.used:])
+		x.used += n
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// xorBytes sets dst[i] = a[i] ^ b[i] for the length of the shorter of a
+// and b, and returns that length.
+func xorBytes(dst, a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	i := 0
+	for ; i+8 <= n; i += 8 {
+		binary.LittleEndian.PutUint64(dst[i:], binary.LittleEndian.Uint64(a[i:])^binary.LittleEndian.Uint64(b[i:]))
+	}
+	for ; i < n; i++ {
+		dst[i] = a[i] ^ b[i]
+	}
+	return n
+}
@@ -0,0 +1,263 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	gcmStandardNonceSize = 12
+	gcmMinTagSize        = 12
+	gcmMaxTagSize        = 16
+)
+
+var errOpen = errors.New("cipher: message authentication failed")
+
+// gcm is the GCM mode of NIST SP 800-38D. The counter blocks are
+// encrypted several at a time, and GHASH multiplies by the hash key with
+// the 4-bit tables of Shoup's method, or with carry-less multiplication
+// instructions where available.
+type gcm struct {
+	rk        *[32]uint32
+	nonceSize int
+	tagSize   int
+	// key is the hash key H, the encryption of the zero block
+	key [BlockSize]byte
+	// table holds the products of the hash key by the 16 polynomials of
+	// degree less than 4, indexed by their bit-reversed coefficients
+	table [16]fieldElement
+}
+
+// fieldElement is an element of GF(2^128) in the bit order of GCM: hi
+// holds the coefficients of x^0 to x^63, most significant bit first.
+type fieldElement struct {
+	hi, lo uint64
+}
+
+// NewGCM returns the GCM mode of c, which crypto/cipher.NewGCM and its
+// variants call instead of their generic implementation.
+func (c *sm4Cipher) NewGCM(nonceSize, tagSize int) (cipher.AEAD, error) {
+	if tagSize < gcmMinTagSize || tagSize > gcmMaxTagSize {
+		return nil, errors.New("cipher: incorrect tag size given to GCM")
+	}
+	if nonceSize <= 0 {
+		return nil, errors.New("cipher: the nonce can't have zero length, or the security of the key will be immediately compromised")
+	}
+
+	g := &gcm{rk: &c.enc, nonceSize: nonceSize, tagSize: tagSize}
+	cryptBlock(g.rk, g.key[:], g.key[:])
+	h := fieldElement{binary.BigEndian.Uint64(g.key[:8]), binary.BigEndian.Uint64(g.key[8:])}
+	g.table[reverse4(1)] = h
+	for i := 2; i < 16; i += 2 {
+		g.table[reverse4(i)] = double(g.table[reverse4(i/2)])
+		g.table[reverse4(i+1)] = add(g.table[reverse4(i)], h)
+	}
+	return g, nil
+}
+
+func (g *gcm) NonceSize() int { return g.nonceSize }
+
+func (g *gcm) Overhead() int { return g.tagSize }
+
+func (g *gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != g.nonceSize {
+		panic("crypto/cipher: incorrect nonce length given to GCM")
+	}
+	if uint64(len(plaintext)) > ((1<<32)-2)*BlockSize {
+		panic("crypto/cipher: message too large for GCM")
+	}
+
+	ret, out := sliceForAppend(dst, len(plaintext)+g.tagSize)
+
+	var counter, tagMask [BlockSize]byte
+	g.deriveCounter(&counter, nonce)
+	cryptBlock(g.rk, tagMask[:], counter[:])
+	inc32(&counter)
+
+	g.counterCrypt(out, plaintext, &counter)
+
+	var tag [BlockSize]byte
+	g.auth(&tag, out[:len(plaintext)], additionalData, &tagMask)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (g *gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != g.nonceSize {
+		panic("crypto/cipher: incorrect nonce length given to GCM")
+	}
+	if len(ciphertext) < g.tagSize {
+		return nil, errOpen
+	}
+	if uint64(len(ciphertext)) > ((1<<32)-2)*BlockSize+uint64(g.tagSize) {
+		return nil, errOpen
+	}
+
+	tag := ciphertext[len(ciphertext)-g.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-g.tagSize]
+
+	var counter, tagMask [BlockSize]byte
+	g.deriveCounter(&counter, nonce)
+	cryptBlock(g.rk, tagMask[:], counter[:])
+	inc32(&counter)
+
+	var expectedTag [BlockSize]byte
+	g.auth(&expectedTag, ciphertext, additionalData, &tagMask)
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	if subtle.ConstantTimeCompare(expectedTag[:g.tagSize], tag) != 1 {
+		// The plaintext is not released when the tag does not match.
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, errOpen
+	}
+
+	g.counterCrypt(out, ciphertext, &counter)
+	return ret, nil
+}
+
+// deriveCounter sets counter to the pre-counter block J0 of nonce.
+func (g *gcm) deriveCounter(counter *[BlockSize]byte, nonce []byte) {
+	if len(nonce) == gcmStandardNonceSize {
+		copy(counter[:], nonce)
+		counter[BlockSize-1] = 1
+		return
+	}
+	var y fieldElement
+	g.update(&y, nonce)
+	y.lo ^= uint64(len(nonce)) * 8
+	g.mul(&y)
+	binary.BigEndian.PutUint64(counter[:8], y.hi)
+	binary.BigEndian.PutUint64(counter[8:], y.lo)
+}
+
+// counterCrypt XORs src with the key stream starting at counter into out,
+// incrementing the last 32 bits of counter.
+func (g *gcm) counterCrypt(out, src []byte, counter *[BlockSize]byte) {
+	var in, stream [batchBlocks * BlockSize]byte
+	for len(src) > 0 {
+		n := (len(src) + BlockSize - 1) / BlockSize
+		if n > batchBlocks {
+			n = batchBlocks
+		}
+		for i := 0; i < n; i++ {
+			copy(in[i*BlockSize:], counter[:])
+			inc32(counter)
+		}
+		cryptBlocks(g.rk, stream[:n*BlockSize], in[:n*BlockSize])
+		m := xorBytes(out, src, stream[:n*BlockSize])
+		out, src = out[m:], src[m:]
+	}
+}
+
+// auth sets tag to the GHASH of additionalData and ciphertext, masked
+// with tagMask.
+func (g *gcm) auth(tag *[BlockSize]byte, ciphertext, additionalData []byte, tagMask *[BlockSize]byte) {
+	var y fieldElement
+	g.update(&y, additionalData)
+	g.update(&y, ciphertext)
+	y.hi ^= uint64(len(additionalData)) * 8
+	y.lo ^= uint64(len(ciphertext)) * 8
+	g.mul(&y)
+	binary.BigEndian.PutUint64(tag[:8], y.hi)
+	binary.BigEndian.PutUint64(tag[8:], y.lo)
+	xorBytes(tag[:], tag[:], tagMask[:])
+}
+
+// update absorbs data, padded with zeros to a multiple of the block size,
+// into y.
+func (g *gcm) update(y *fieldElement, data []byte) {
+	full := len(data) &^ (BlockSize - 1)
+	if full > 0 {
+		ghashBlocks(g, y, data[:full])
+	}
+	if len(data) > full {
+		var block [BlockSize]byte
+		copy(block[:], data[full:])
+		ghashBlocks(g, y, block[:])
+	}
+}
+
+// ghashBlocksGeneric absorbs the len(blocks)/BlockSize blocks of blocks
+// into y.
+func (g *gcm) ghashBlocksGeneric(y *fieldElement, blocks []byte) {
+	for len(blocks) >= BlockSize {
+		y.hi ^= binary.BigEndian.Uint64(blocks)
+		y.lo ^= binary.BigEndian.Uint64(blocks[8:])
+		g.mul(y)
+		blocks = blocks[BlockSize:]
+	}
+}
+
+// reduction holds the multiples of the GCM polynomial to add when 4 bits
+// are shifted out of x^127, in the top 16 bits of the high word.
+var reduction = [16]uint16{
+	0x0000, 0x1c20, 0x3840, 0x2460, 0x7080, 0x6ca0, 0x48c0, 0x54e0,
+	0xe100, 0xfd20, 0xd940, 0xc560, 0x9180, 0x8da0, 0xa9c0, 0xb5e0,
+}
+
+// mul sets y to y times the hash key, processing 4 bits of y at a time
+// from the coefficient of x^127 down.
+func (g *gcm) mul(y *fieldElement) {
+	var z fieldElement
+	for _, word := range [2]uint64{y.lo, y.hi} {
+		for i := 0; i < 64; i += 4 {
+			carry := z.lo & 0xf
+			z.lo = z.lo>>4 | z.hi<<60
+			z.hi = z.hi>>4 ^ uint64(reduction[carry])<<48
+			p := &g.table[word&0xf]
+			z.hi ^= p.hi
+			z.lo ^= p.lo
+			word >>= 4
+		}
+	}
+	*y = z
+}
+
+// double returns the product of x by the polynomial x in GF(2^128).
+func double(x fieldElement) fieldElement {
+	carry := x.lo&1 == 1
+	d := fieldElement{hi: x.hi >> 1, lo: x.lo>>1 | x.hi<<63}
+	if carry {
+		d.hi ^= 0xe100000000000000
+	}
+	return d
+}
+
+func add(x, y fieldElement) fieldElement {
+	return fieldElement{x.hi ^ y.hi, x.lo ^ y.lo}
+}
+
+// reverse4 reverses the 4 low bits of i.
+func reverse4(i int) int {
+	i = (i<<2)&0xc | (i>>2)&0x3
+	return (i<<1)&0xa | (i>>1)&0x5
+}
+
+// inc32 increments the last 32 bits of counter, as a big-endian integer.
+func inc32(counter *[BlockSize]byte) {
+	c := counter[BlockSize-4:]
+	binary.BigEndian.PutUint32(c, binary.BigEndian.Uint32(c)+1)
+}
+
+// sliceForAppend extends in by n bytes, reallocating it when its capacity
+// is not sufficient, and returns the extended slice and its last n bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,36 @@
+// +build amd64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+// usePCLMUL detects the PCLMULQDQ and SSSE3 instructions GHASH is computed
+// with, available on Intel CPUs since Westmere and AMD CPUs since Bulldozer.
+var usePCLMUL = hasPCLMUL()
+
+// ghashPCLMUL absorbs the len(blocks)/BlockSize blocks of blocks into y,
+// with the hash key key.
+//
+//go:noescape
+func ghashPCLMUL(key *[BlockSize]byte, y *fieldElement, blocks []byte)
+
+func hasPCLMUL() bool {
+	if maxID, _, _, _ := cpuid(0, 0); maxID < 1 {
+		return false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	return ecx1&cpuid1SSSE3 != 0 && ecx1&cpuid1PCLMULQDQ != 0
+}
+
+// ghashBlocks absorbs the len(blocks)/BlockSize blocks of blocks into y.
+func ghashBlocks(g *gcm, y *fieldElement, blocks []byte) {
+	if usePCLMUL {
+		ghashPCLMUL(&g.key, y, blocks)
+		return
+	}
+	g.ghashBlocksGeneric(y, blocks)
+}
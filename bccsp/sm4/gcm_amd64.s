@@ -0,0 +1,110 @@
+// +build amd64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+#include "textflag.h"
+
+// GHASH with PCLMULQDQ. The field elements are byte-reversed, so that
+// their coefficients are in the bit order of carry-less multiplication,
+// the hash key is multiplied by x to compensate for the bit shift of the
+// product, and the product is reduced by two folds with the polynomial
+// x^128 + x^127 + x^126 + x^121 + 1 in the representation of the
+// reflected bits.
+
+DATA ghashBswap<>+0x00(SB)/8, $0x08090a0b0c0d0e0f
+DATA ghashBswap<>+0x08(SB)/8, $0x0001020304050607
+GLOBL ghashBswap<>(SB), (NOPTR+RODATA), $16
+DATA ghashPoly<>+0x00(SB)/8, $0x0000000000000001
+DATA ghashPoly<>+0x08(SB)/8, $0xc200000000000000
+GLOBL ghashPoly<>(SB), (NOPTR+RODATA), $16
+
+#define BSWAP X15
+#define POLY X14
+#define H X13
+#define HK X12
+#define ACC X0
+#define ACC0 X1
+#define ACC1 X2
+#define ACCM X3
+#define T0 X4
+#define T1 X5
+
+// a = a * x^-64 mod POLY, folding the low 64 bits of a
+#define REDUCE(a) \
+	MOVOU     POLY, T0;       \
+	PCLMULQDQ $0x01, a, T0;   \
+	PSHUFD    $78, a, a;      \
+	PXOR      T0, a
+
+// func ghashPCLMUL(key *[BlockSize]byte, y *fieldElement, blocks []byte)
+TEXT ·ghashPCLMUL(SB), NOSPLIT, $0-40
+	MOVQ key+0(FP), AX
+	MOVQ y+8(FP), BX
+	MOVQ blocks_base+16(FP), SI
+	MOVQ blocks_len+24(FP), CX
+	SHRQ $4, CX
+	JZ   done
+
+	MOVOU ghashBswap<>(SB), BSWAP
+	MOVOU ghashPoly<>(SB), POLY
+
+	// H = key * x
+	MOVOU  (AX), H
+	PSHUFB BSWAP, H
+	PSHUFD $0xff, H, T0
+	MOVOU  H, T1
+	PSRAL  $31, T0
+	PAND   POLY, T0
+	PSRLL  $31, T1
+	PSLLDQ $4, T1
+	PSLLL  $1, H
+	PXOR   T0, H
+	PXOR   T1, H
+
+	// HK holds the xor of the halves of H, for the Karatsuba method
+	PSHUFD $78, H, HK
+	PXOR   H, HK
+
+	// fieldElement holds the high and low halves of the block, each a
+	// big-endian word loaded in little-endian order
+	MOVOU  (BX), ACC
+	PSHUFD $78, ACC, ACC
+
+loop:
+	MOVOU  (SI), T1
+	PSHUFB BSWAP, T1
+	PXOR   T1, ACC
+
+	MOVOU     ACC, ACC0
+	MOVOU     ACC, ACC1
+	PCLMULQDQ $0x00, H, ACC0
+	PCLMULQDQ $0x11, H, ACC1
+	PSHUFD    $78, ACC, ACCM
+	PXOR      ACC, ACCM
+	PCLMULQDQ $0x00, HK, ACCM
+
+	PXOR   ACC0, ACCM
+	PXOR   ACC1, ACCM
+	MOVOU  ACCM, T1
+	PSRLDQ $8, ACCM
+	PSLLDQ $8, T1
+	PXOR   ACCM, ACC1
+	PXOR   T1, ACC0
+	REDUCE(ACC0)
+	REDUCE(ACC0)
+	PXOR   ACC1, ACC0
+	MOVOU  ACC0, ACC
+
+	ADDQ $16, SI
+	DECQ CX
+	JNZ  loop
+
+	PSHUFD $78, ACC, ACC
+	MOVOU  ACC, (BX)
+
+done:
+	RET
@@ -0,0 +1,14 @@
+// +build !amd64 purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+// ghashBlocks absorbs the len(blocks)/BlockSize blocks of blocks into y.
+func ghashBlocks(g *gcm, y *fieldElement, blocks []byte) {
+	g.ghashBlocksGeneric(y, blocks)
+}
@@ -0,0 +1,189 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// genericBlock hides the NewCTR and NewGCM methods of the cipher, so that
+// crypto/cipher falls back to its generic CTR and GCM modes.
+type genericBlock struct {
+	cipher.Block
+}
+
+func TestCipher(t *testing.T) {
+	t.Logf("SM4 implementation: %s", implementation)
+
+	// GB/T 32907-2016 Appendix A
+	key, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+
+	dst := make([]byte, BlockSize)
+	c.Encrypt(dst, key)
+	assert.Equal(t, "681edf34d206965e86b3e94f536e4246", hex.EncodeToString(dst))
+	c.Decrypt(dst, dst)
+	assert.Equal(t, key, dst)
+
+	copy(dst, key)
+	for i := 0; i < 1000000; i++ {
+		c.Encrypt(dst, dst)
+	}
+	assert.Equal(t, "595298c7c6fd271f0402f804c33d3f66", hex.EncodeToString(dst))
+
+	_, err = NewCipher(key[:15])
+	assert.EqualError(t, err, "sm4: invalid key size 15")
+}
+
+func TestCryptBlocks(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	key := make([]byte, 16)
+	r.Read(key)
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+	sc := c.(*sm4Cipher)
+
+	for blocks := 1; blocks <= 19; blocks++ {
+		src := make([]byte, blocks*BlockSize)
+		r.Read(src)
+
+		expected := make([]byte, len(src))
+		cryptBlocksGeneric(&sc.enc, expected, src)
+		dst := make([]byte, len(src))
+		cryptBlocks(&sc.enc, dst, src)
+		require.Equal(t, expected, dst, "%d blocks", blocks)
+
+		cryptBlocks(&sc.dec, dst, dst)
+		require.Equal(t, src, dst, "%d blocks", blocks)
+	}
+}
+
+func TestCTR(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	key := make([]byte, 16)
+	r.Read(key)
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+
+	ivs := [][]byte{
+		make([]byte, BlockSize),
+		bytes.Repeat([]byte{0xff}, BlockSize),
+		// the low 64 bits of the counter wrap around within a batch
+		append(make([]byte, 8), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfd),
+	}
+	for _, iv := range ivs {
+		src := make([]byte, 3*batchBlocks*BlockSize+7)
+		r.Read(src)
+		expected := make([]byte, len(src))
+		cipher.NewCTR(genericBlock{c}, iv).XORKeyStream(expected, src)
+
+		dst := make([]byte, len(src))
+		stream := cipher.NewCTR(c, iv)
+		// the key stream carries over between calls of any length
+		for i, n := 0, 1; i < len(src); i, n = i+n, n+5 {
+			if i+n > len(src) {
+				n = len(src) - i
+			}
+			stream.XORKeyStream(dst[i:i+n], src[i:i+n])
+		}
+		assert.Equal(t, expected, dst, "iv %x", iv)
+	}
+}
+
+func TestGCM(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	key := make([]byte, 16)
+	r.Read(key)
+	c, err := NewCipher(key)
+	require.NoError(t, err)
+
+	for _, sizes := range []struct{ nonce, tag int }{{12, 16}, {12, 12}, {12, 14}, {8, 16}, {60, 16}} {
+		aead, err := newGCM(c, sizes.nonce, sizes.tag)
+		require.NoError(t, err)
+		generic, err := newGCM(genericBlock{c}, sizes.nonce, sizes.tag)
+		require.NoError(t, err)
+		assert.Equal(t, sizes.nonce, aead.NonceSize())
+		assert.Equal(t, sizes.tag, aead.Overhead())
+
+		for _, length := range []int{0, 1, 15, 16, 17, batchBlocks * BlockSize, batchBlocks*BlockSize + 1, 1000} {
+			nonce := make([]byte, sizes.nonce)
+			r.Read(nonce)
+			plaintext := make([]byte, length)
+			r.Read(plaintext)
+			ad := make([]byte, length%23)
+			r.Read(ad)
+
+			expected := generic.Seal([]byte("prefix"), nonce, plaintext, ad)
+			ciphertext := aead.Seal([]byte("prefix"), nonce, plaintext, ad)
+			require.Equal(t, expected, ciphertext, "sizes %v, length %d", sizes, length)
+
+			opened, err := aead.Open(nil, nonce, ciphertext[len("prefix"):], ad)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(plaintext, opened))
+
+			ciphertext[len(ciphertext)-1] ^= 1
+			_, err = aead.Open(nil, nonce, ciphertext[len("prefix"):], ad)
+			assert.EqualError(t, err, "cipher: message authentication failed")
+		}
+	}
+
+	_, err = c.(*sm4Cipher).NewGCM(12, 11)
+	assert.EqualError(t, err, "cipher: incorrect tag size given to GCM")
+	_, err = c.(*sm4Cipher).NewGCM(0, 16)
+	assert.Error(t, err)
+}
+
+// newGCM returns the GCM mode of c with a nonce or tag of non-standard
+// size, which crypto/cipher does not combine.
+func newGCM(c cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if nonceSize != 12 {
+		return cipher.NewGCMWithNonceSize(c, nonceSize)
+	}
+	return cipher.NewGCMWithTagSize(c, tagSize)
+}
+
+func benchmarkStream(b *testing.B, size int, newStream func(cipher.Block) func(dst, src []byte)) {
+	c, _ := NewCipher(make([]byte, 16))
+	xor := newStream(c)
+	buf := make([]byte, size)
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		xor(buf, buf)
+	}
+}
+
+func BenchmarkEncrypt(b *testing.B) {
+	benchmarkStream(b, BlockSize, func(c cipher.Block) func(dst, src []byte) {
+		return c.Encrypt
+	})
+}
+
+func BenchmarkCTR8K(b *testing.B) {
+	benchmarkStream(b, 8192, func(c cipher.Block) func(dst, src []byte) {
+		return cipher.NewCTR(c, make([]byte, BlockSize)).XORKeyStream
+	})
+}
+
+func BenchmarkGCMSeal8K(b *testing.B) {
+	benchmarkStream(b, 8192, func(c cipher.Block) func(dst, src []byte) {
+		aead, _ := cipher.NewGCM(c)
+		nonce := make([]byte, aead.NonceSize())
+		out := make([]byte, 0, 8192+aead.Overhead())
+		return func(dst, src []byte) {
+			aead.Seal(out[:0], nonce, src, nil)
+		}
+	})
+}
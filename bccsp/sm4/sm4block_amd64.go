@@ -0,0 +1,98 @@
+//go:build amd64 && !purego
+// +build amd64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+var (
+	// useAESNI detects the AES-NI and SSSE3 instructions the S-box of 4
+	// blocks is computed at once with, available on Intel CPUs since
+	// Westmere and AMD CPUs since Bulldozer, Zen and Hygon Dhyana included.
+	useAESNI = hasAESNI()
+	// useGFNI detects the AVX2 and GFNI instructions the S-box of 8 blocks
+	// is computed at once with, available on Intel CPUs since Ice Lake and
+	// Tremont and AMD CPUs since Zen 4.
+	useGFNI = hasGFNI()
+)
+
+var implementation = "generic"
+
+func init() {
+	switch {
+	case useGFNI:
+		implementation = "amd64-gfni"
+	case useAESNI:
+		implementation = "amd64-aesni"
+	}
+}
+
+// cryptBlocksAESNI encrypts or decrypts the len(src)/64 groups of 4 blocks
+// of src into dst.
+//
+//go:noescape
+func cryptBlocksAESNI(rk *[32]uint32, dst, src []byte)
+
+// cryptBlocksGFNI encrypts or decrypts the len(src)/128 groups of 8 blocks
+// of src into dst.
+//
+//go:noescape
+func cryptBlocksGFNI(rk *[32]uint32, dst, src []byte)
+
+func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+
+func xgetbv() (eax, edx uint32)
+
+const (
+	cpuid1PCLMULQDQ = 1 << 1
+	cpuid1SSSE3     = 1 << 9
+	cpuid1AES       = 1 << 25
+	cpuid1OSXSAVE   = 1 << 27
+	cpuid1AVX       = 1 << 28
+	cpuid7AVX2      = 1 << 5
+	cpuid7GFNI      = 1 << 8
+)
+
+func hasAESNI() bool {
+	if maxID, _, _, _ := cpuid(0, 0); maxID < 1 {
+		return false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	return ecx1&cpuid1SSSE3 != 0 && ecx1&cpuid1AES != 0
+}
+
+func hasGFNI() bool {
+	if maxID, _, _, _ := cpuid(0, 0); maxID < 7 {
+		return false
+	}
+	_, _, ecx1, _ := cpuid(1, 0)
+	if ecx1&cpuid1OSXSAVE == 0 || ecx1&cpuid1AVX == 0 {
+		return false
+	}
+	// the operating system saves the XMM and YMM registers
+	if xcr0, _ := xgetbv(); xcr0&6 != 6 {
+		return false
+	}
+	_, ebx7, ecx7, _ := cpuid(7, 0)
+	return ebx7&cpuid7AVX2 != 0 && ecx7&cpuid7GFNI != 0
+}
+
+// cryptBlocks encrypts or decrypts, depending on the order of rk, the
+// len(src)/BlockSize blocks of src into dst.
+func cryptBlocks(rk *[32]uint32, dst, src []byte) {
+	if useGFNI && len(src) >= 8*BlockSize {
+		n := len(src) &^ (8*BlockSize - 1)
+		cryptBlocksGFNI(rk, dst[:n], src[:n])
+		dst, src = dst[n:], src[n:]
+	}
+	if useAESNI && len(src) >= 4*BlockSize {
+		n := len(src) &^ (4*BlockSize - 1)
+		cryptBlocksAESNI(rk, dst[:n], src[:n])
+		dst, src = dst[n:], src[n:]
+	}
+	cryptBlocksGeneric(rk, dst, src)
+}
@@ -0,0 +1,355 @@
+// +build amd64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+#include "textflag.h"
+
+// SM4 encryption of 4 blocks at once with SSSE3 and AES-NI.
+//
+// The blocks are transposed so that X0-X3 hold the words 0-3 of the 4
+// blocks, one block per 32-bit lane. The S-box is computed as
+// post(AES-S(pre(x))), where pre and post are affine transformations over
+// GF(2) applied with PSHUFB lookups of the low and high nibbles, and the
+// AES S-box is applied by AESENCLAST with a zero round key, whose
+// ShiftRows is undone by permuting the bytes beforehand.
+
+DATA bswap32<>+0x00(SB)/8, $0x0405060700010203
+DATA bswap32<>+0x08(SB)/8, $0x0c0d0e0f08090a0b
+GLOBL bswap32<>(SB), (NOPTR+RODATA), $16
+DATA invShiftRows<>+0x00(SB)/8, $0x0b0e0104070a0d00
+DATA invShiftRows<>+0x08(SB)/8, $0x0306090c0f020508
+GLOBL invShiftRows<>(SB), (NOPTR+RODATA), $16
+DATA nibbleMask<>+0x00(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA nibbleMask<>+0x08(SB)/8, $0x0f0f0f0f0f0f0f0f
+GLOBL nibbleMask<>(SB), (NOPTR+RODATA), $16
+DATA preLo<>+0x00(SB)/8, $0x078b37bb820eb23e
+DATA preLo<>+0x08(SB)/8, $0x9814a8241d912da1
+GLOBL preLo<>(SB), (NOPTR+RODATA), $16
+DATA preHi<>+0x00(SB)/8, $0x37eb19c5f22edc00
+DATA preHi<>+0x08(SB)/8, $0x3fe311cdfa26d408
+GLOBL preHi<>(SB), (NOPTR+RODATA), $16
+DATA postLo<>+0x00(SB)/8, $0x2098ea521ea6d46c
+DATA postLo<>+0x08(SB)/8, $0x47ff8d3579c1b30b
+GLOBL postLo<>(SB), (NOPTR+RODATA), $16
+DATA postHi<>+0x00(SB)/8, $0x2dcd7d9db050e000
+DATA postHi<>+0x08(SB)/8, $0xed0dbd5d709020c0
+GLOBL postHi<>(SB), (NOPTR+RODATA), $16
+DATA rotl8<>+0x00(SB)/8, $0x0605040702010003
+DATA rotl8<>+0x08(SB)/8, $0x0e0d0c0f0a09080b
+GLOBL rotl8<>(SB), (NOPTR+RODATA), $16
+DATA rotl16<>+0x00(SB)/8, $0x0504070601000302
+DATA rotl16<>+0x08(SB)/8, $0x0d0c0f0e09080b0a
+GLOBL rotl16<>(SB), (NOPTR+RODATA), $16
+DATA rotl24<>+0x00(SB)/8, $0x0407060500030201
+DATA rotl24<>+0x08(SB)/8, $0x0c0f0e0d080b0a09
+GLOBL rotl24<>(SB), (NOPTR+RODATA), $16
+DATA zero<>+0x00(SB)/8, $0
+DATA zero<>+0x08(SB)/8, $0
+GLOBL zero<>(SB), (NOPTR+RODATA), $16
+
+// Constants kept in registers
+#define INV_SHIFT_ROWS X8
+#define NIBBLE_MASK X9
+#define PRE_LO X10
+#define PRE_HI X11
+#define POST_LO X12
+#define POST_HI X13
+#define ROTL8 X14
+#define ROTL16 X15
+
+// x = (lo[x & 0xf] ^ hi[x >> 4]) for each byte of x, t1 and t2 are scratch
+#define AFFINE(lo, hi, x, t1, t2) \
+	MOVOU  x, t1;           \
+	PSRLQ  $4, t1;          \
+	PAND   NIBBLE_MASK, x;  \
+	PAND   NIBBLE_MASK, t1; \
+	MOVOU  lo, t2;          \
+	PSHUFB x, t2;           \
+	MOVOU  hi, x;           \
+	PSHUFB t1, x;           \
+	PXOR   t2, x
+
+// x0 ^= L(tau(x1 ^ x2 ^ x3 ^ rk[i])), with X4-X7 as scratch
+#define ROUND(i, x0, x1, x2, x3) \
+	MOVL    ((i)*4)(AX), X4;            \
+	PSHUFD  $0, X4, X4;                 \
+	PXOR    x1, X4;                     \
+	PXOR    x2, X4;                     \
+	PXOR    x3, X4;                     \
+	PSHUFB  INV_SHIFT_ROWS, X4;         \
+	AFFINE(PRE_LO, PRE_HI, X4, X5, X6); \
+	AESENCLAST zero<>(SB), X4;          \
+	AFFINE(POST_LO, POST_HI, X4, X5, X6); \
+	MOVOU   X4, X5;                     \
+	MOVOU   X4, X6;                     \
+	PSHUFB  ROTL8, X5;                  \
+	PSHUFB  ROTL16, X6;                 \
+	PXOR    X4, X5;                     \
+	PXOR    X6, X5;                     \
+	MOVOU   X5, X6;                     \
+	PSLLL   $2, X5;                     \
+	PSRLL   $30, X6;                    \
+	PXOR    X6, X5;                     \
+	MOVOU   X4, X7;                     \
+	PSHUFB  rotl24<>(SB), X7;           \
+	PXOR    X7, X5;                     \
+	PXOR    X4, X5;                     \
+	PXOR    X5, x0
+
+// Transposes the 4x4 matrix of 32-bit words in x0-x3, with X4-X5 as scratch
+#define TRANSPOSE(x0, x1, x2, x3) \
+	MOVOU      x0, X4;  \
+	PUNPCKLLQ  x1, x0;  \
+	PUNPCKHLQ  x1, X4;  \
+	MOVOU      x2, X5;  \
+	PUNPCKLLQ  x3, x2;  \
+	PUNPCKHLQ  x3, X5;  \
+	MOVOU      x0, x1;  \
+	PUNPCKLQDQ x2, x0;  \
+	PUNPCKHQDQ x2, x1;  \
+	MOVOU      X4, x3;  \
+	PUNPCKLQDQ X5, X4;  \
+	PUNPCKHQDQ X5, x3;  \
+	MOVOU      X4, x2
+
+// func cryptBlocksAESNI(rk *[32]uint32, dst, src []byte)
+TEXT ·cryptBlocksAESNI(SB), NOSPLIT, $0-56
+	MOVQ rk+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	SHRQ $6, CX
+	JZ   done
+
+	MOVOU invShiftRows<>(SB), INV_SHIFT_ROWS
+	MOVOU nibbleMask<>(SB), NIBBLE_MASK
+	MOVOU preLo<>(SB), PRE_LO
+	MOVOU preHi<>(SB), PRE_HI
+	MOVOU postLo<>(SB), POST_LO
+	MOVOU postHi<>(SB), POST_HI
+	MOVOU rotl8<>(SB), ROTL8
+	MOVOU rotl16<>(SB), ROTL16
+
+loop:
+	MOVOU  0(SI), X0
+	MOVOU  16(SI), X1
+	MOVOU  32(SI), X2
+	MOVOU  48(SI), X3
+	MOVOU  bswap32<>(SB), X4
+	PSHUFB X4, X0
+	PSHUFB X4, X1
+	PSHUFB X4, X2
+	PSHUFB X4, X3
+	TRANSPOSE(X0, X1, X2, X3)
+
+	ROUND(0, X0, X1, X2, X3)
+	ROUND(1, X1, X2, X3, X0)
+	ROUND(2, X2, X3, X0, X1)
+	ROUND(3, X3, X0, X1, X2)
+	ROUND(4, X0, X1, X2, X3)
+	ROUND(5, X1, X2, X3, X0)
+	ROUND(6, X2, X3, X0, X1)
+	ROUND(7, X3, X0, X1, X2)
+	ROUND(8, X0, X1, X2, X3)
+	ROUND(9, X1, X2, X3, X0)
+	ROUND(10, X2, X3, X0, X1)
+	ROUND(11, X3, X0, X1, X2)
+	ROUND(12, X0, X1, X2, X3)
+	ROUND(13, X1, X2, X3, X0)
+	ROUND(14, X2, X3, X0, X1)
+	ROUND(15, X3, X0, X1, X2)
+	ROUND(16, X0, X1, X2, X3)
+	ROUND(17, X1, X2, X3, X0)
+	ROUND(18, X2, X3, X0, X1)
+	ROUND(19, X3, X0, X1, X2)
+	ROUND(20, X0, X1, X2, X3)
+	ROUND(21, X1, X2, X3, X0)
+	ROUND(22, X2, X3, X0, X1)
+	ROUND(23, X3, X0, X1, X2)
+	ROUND(24, X0, X1, X2, X3)
+	ROUND(25, X1, X2, X3, X0)
+	ROUND(26, X2, X3, X0, X1)
+	ROUND(27, X3, X0, X1, X2)
+	ROUND(28, X0, X1, X2, X3)
+	ROUND(29, X1, X2, X3, X0)
+	ROUND(30, X2, X3, X0, X1)
+	ROUND(31, X3, X0, X1, X2)
+
+	TRANSPOSE(X3, X2, X1, X0)
+	MOVOU  bswap32<>(SB), X4
+	PSHUFB X4, X0
+	PSHUFB X4, X1
+	PSHUFB X4, X2
+	PSHUFB X4, X3
+	MOVOU  X3, 0(DI)
+	MOVOU  X2, 16(DI)
+	MOVOU  X1, 32(DI)
+	MOVOU  X0, 48(DI)
+
+	ADDQ $64, SI
+	ADDQ $64, DI
+	DECQ CX
+	JNZ  loop
+
+done:
+	RET
+
+// SM4 encryption of 8 blocks at once with AVX2 and GFNI. Each 128-bit lane
+// of Y0-Y3 holds the words of 4 blocks as in cryptBlocksAESNI, and the
+// S-box is computed as an affine transformation followed by the affine
+// transformation of the inverse in the field of AES, which are
+// isomorphic to the affine transformations and the field of SM4.
+DATA gfniPre<>+0x00(SB)/8, $0x4c287db91a22505d
+GLOBL gfniPre<>(SB), (NOPTR+RODATA), $8
+DATA gfniPost<>+0x00(SB)/8, $0xf3ab34a974a6b589
+GLOBL gfniPost<>(SB), (NOPTR+RODATA), $8
+
+#define GFNI_PRE Y8
+#define GFNI_POST Y9
+#define ROTL8_Y Y10
+#define ROTL16_Y Y11
+#define ROTL24_Y Y12
+#define BSWAP32_Y Y13
+
+// x0 ^= L(tau(x1 ^ x2 ^ x3 ^ rk[i])), with Y4-Y6 as scratch
+#define ROUND_GFNI(i, x0, x1, x2, x3) \
+	VPBROADCASTD ((i)*4)(AX), Y4;                \
+	VPXOR        x1, Y4, Y4;                     \
+	VPXOR        x2, Y4, Y4;                     \
+	VPXOR        x3, Y4, Y4;                     \
+	VGF2P8AFFINEQB    $0x3e, GFNI_PRE, Y4, Y4;  \
+	VGF2P8AFFINEINVQB $0xd3, GFNI_POST, Y4, Y4; \
+	VPSHUFB      ROTL8_Y, Y4, Y5;                \
+	VPSHUFB      ROTL16_Y, Y4, Y6;               \
+	VPXOR        Y4, Y5, Y5;                     \
+	VPXOR        Y6, Y5, Y5;                     \
+	VPSLLD       $2, Y5, Y6;                     \
+	VPSRLD       $30, Y5, Y5;                    \
+	VPXOR        Y6, Y5, Y5;                     \
+	VPSHUFB      ROTL24_Y, Y4, Y6;               \
+	VPXOR        Y6, Y5, Y5;                     \
+	VPXOR        Y4, Y5, Y5;                     \
+	VPXOR        Y5, x0, x0
+
+// Transposes the 4x4 matrices of 32-bit words in each lane of x0-x3, with
+// Y4-Y7 as scratch
+#define TRANSPOSE_Y(x0, x1, x2, x3) \
+	VPUNPCKLDQ  x1, x0, Y4; \
+	VPUNPCKHDQ  x1, x0, Y5; \
+	VPUNPCKLDQ  x3, x2, Y6; \
+	VPUNPCKHDQ  x3, x2, Y7; \
+	VPUNPCKLQDQ Y6, Y4, x0; \
+	VPUNPCKHQDQ Y6, Y4, x1; \
+	VPUNPCKLQDQ Y7, Y5, x2; \
+	VPUNPCKHQDQ Y7, Y5, x3
+
+// func cryptBlocksGFNI(rk *[32]uint32, dst, src []byte)
+TEXT ·cryptBlocksGFNI(SB), NOSPLIT, $0-56
+	MOVQ rk+0(FP), AX
+	MOVQ dst_base+8(FP), DI
+	MOVQ src_base+32(FP), SI
+	MOVQ src_len+40(FP), CX
+	SHRQ $7, CX
+	JZ   doneGFNI
+
+	VPBROADCASTQ gfniPre<>(SB), GFNI_PRE
+	VPBROADCASTQ gfniPost<>(SB), GFNI_POST
+	VBROADCASTI128 rotl8<>(SB), ROTL8_Y
+	VBROADCASTI128 rotl16<>(SB), ROTL16_Y
+	VBROADCASTI128 rotl24<>(SB), ROTL24_Y
+	VBROADCASTI128 bswap32<>(SB), BSWAP32_Y
+
+loopGFNI:
+	VMOVDQU    0(SI), X0
+	VMOVDQU    16(SI), X1
+	VMOVDQU    32(SI), X2
+	VMOVDQU    48(SI), X3
+	VINSERTI128 $1, 64(SI), Y0, Y0
+	VINSERTI128 $1, 80(SI), Y1, Y1
+	VINSERTI128 $1, 96(SI), Y2, Y2
+	VINSERTI128 $1, 112(SI), Y3, Y3
+	VPSHUFB    BSWAP32_Y, Y0, Y0
+	VPSHUFB    BSWAP32_Y, Y1, Y1
+	VPSHUFB    BSWAP32_Y, Y2, Y2
+	VPSHUFB    BSWAP32_Y, Y3, Y3
+	TRANSPOSE_Y(Y0, Y1, Y2, Y3)
+
+	ROUND_GFNI(0, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(1, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(2, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(3, Y3, Y0, Y1, Y2)
+	ROUND_GFNI(4, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(5, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(6, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(7, Y3, Y0, Y1, Y2)
+	ROUND_GFNI(8, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(9, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(10, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(11, Y3, Y0, Y1, Y2)
+	ROUND_GFNI(12, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(13, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(14, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(15, Y3, Y0, Y1, Y2)
+	ROUND_GFNI(16, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(17, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(18, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(19, Y3, Y0, Y1, Y2)
+	ROUND_GFNI(20, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(21, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(22, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(23, Y3, Y0, Y1, Y2)
+	ROUND_GFNI(24, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(25, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(26, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(27, Y3, Y0, Y1, Y2)
+	ROUND_GFNI(28, Y0, Y1, Y2, Y3)
+	ROUND_GFNI(29, Y1, Y2, Y3, Y0)
+	ROUND_GFNI(30, Y2, Y3, Y0, Y1)
+	ROUND_GFNI(31, Y3, Y0, Y1, Y2)
+
+	TRANSPOSE_Y(Y3, Y2, Y1, Y0)
+	VPSHUFB    BSWAP32_Y, Y0, Y0
+	VPSHUFB    BSWAP32_Y, Y1, Y1
+	VPSHUFB    BSWAP32_Y, Y2, Y2
+	VPSHUFB    BSWAP32_Y, Y3, Y3
+	VMOVDQU    X3, 0(DI)
+	VMOVDQU    X2, 16(DI)
+	VMOVDQU    X1, 32(DI)
+	VMOVDQU    X0, 48(DI)
+	VEXTRACTI128 $1, Y3, 64(DI)
+	VEXTRACTI128 $1, Y2, 80(DI)
+	VEXTRACTI128 $1, Y1, 96(DI)
+	VEXTRACTI128 $1, Y0, 112(DI)
+
+	ADDQ $128, SI
+	ADDQ $128, DI
+	DECQ CX
+	JNZ  loopGFNI
+
+	VZEROUPPER
+
+doneGFNI:
+	RET
+
+// func cpuid(eaxArg, ecxArg uint32) (eax, ebx, ecx, edx uint32)
+TEXT ·cpuid(SB), NOSPLIT, $0-24
+	MOVL eaxArg+0(FP), AX
+	MOVL ecxArg+4(FP), CX
+	CPUID
+	MOVL AX, eax+8(FP)
+	MOVL BX, ebx+12(FP)
+	MOVL CX, ecx+16(FP)
+	MOVL DX, edx+20(FP)
+	RET
+
+// func xgetbv() (eax, edx uint32)
+TEXT ·xgetbv(SB), NOSPLIT, $0-8
+	MOVL $0, CX
+	XGETBV
+	MOVL AX, eax+0(FP)
+	MOVL DX, edx+4(FP)
+	RET
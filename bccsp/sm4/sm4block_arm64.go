@@ -0,0 +1,72 @@
+// +build arm64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"runtime"
+)
+
+// useAES detects the AES instructions of the ARMv8 Cryptography Extensions
+// the S-box of 8 blocks is computed at once with, available on most arm64
+// CPUs, Kunpeng and Phytium included, but optional on some Cortex-A cores.
+var useAES = hasAES()
+
+var implementation = "generic"
+
+func init() {
+	if useAES {
+		implementation = "arm64-aes"
+	}
+}
+
+// cryptBlocksNEON encrypts or decrypts the len(src)/128 groups of 8 blocks
+// of src into dst.
+//
+//go:noescape
+func cryptBlocksNEON(rk *[32]uint32, dst, src []byte)
+
+func hasAES() bool {
+	switch runtime.GOOS {
+	case "darwin", "ios":
+		return true
+	case "linux", "android":
+	default:
+		return false
+	}
+
+	// The hardware capabilities are read from the auxiliary vector, as a
+	// list of pairs of 64-bit type and value.
+	auxv, err := ioutil.ReadFile("/proc/self/auxv")
+	if err != nil {
+		return false
+	}
+	const (
+		atHWCap  = 16
+		hwCapAES = 1 << 3
+	)
+	for i := 0; i+16 <= len(auxv); i += 16 {
+		if binary.LittleEndian.Uint64(auxv[i:]) == atHWCap {
+			return binary.LittleEndian.Uint64(auxv[i+8:])&hwCapAES != 0
+		}
+	}
+	return false
+}
+
+// cryptBlocks encrypts or decrypts, depending on the order of rk, the
+// len(src)/BlockSize blocks of src into dst.
+func cryptBlocks(rk *[32]uint32, dst, src []byte) {
+	if useAES && len(src) >= 8*BlockSize {
+		n := len(src) &^ (8*BlockSize - 1)
+		cryptBlocksNEON(rk, dst[:n], src[:n])
+		dst, src = dst[n:], src[n:]
+	}
+	cryptBlocksGeneric(rk, dst, src)
+}
@@ -0,0 +1,1883 @@
+// +build arm64,!purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+#include "textflag.h"
+
+// SM4 encryption of 8 blocks at once with the ARMv8 Cryptography
+// Extensions, in two interleaved groups of 4 blocks.
+//
+// The blocks of a group are transposed so that 4 registers hold the words
+// 0-3 of the 4 blocks, one block per 32-bit lane. The S-box is computed as
+// post(AES-S(pre(x))), where pre and post are affine transformations over
+// GF(2) applied with TBL lookups of the low and high nibbles, and the AES
+// S-box is applied by AESE with a zero round key, whose ShiftRows is undone
+// by permuting the bytes beforehand.
+
+// invShiftRows, nibbleMask, preLo, preHi, postLo, postHi, rotl8, rotl24
+DATA sm4Consts<>+0x00(SB)/8, $0x0b0e0104070a0d00
+DATA sm4Consts<>+0x08(SB)/8, $0x0306090c0f020508
+DATA sm4Consts<>+0x10(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA sm4Consts<>+0x18(SB)/8, $0x0f0f0f0f0f0f0f0f
+DATA sm4Consts<>+0x20(SB)/8, $0x078b37bb820eb23e
+DATA sm4Consts<>+0x28(SB)/8, $0x9814a8241d912da1
+DATA sm4Consts<>+0x30(SB)/8, $0x37eb19c5f22edc00
+DATA sm4Consts<>+0x38(SB)/8, $0x3fe311cdfa26d408
+DATA sm4Consts<>+0x40(SB)/8, $0x2098ea521ea6d46c
+DATA sm4Consts<>+0x48(SB)/8, $0x47ff8d3579c1b30b
+DATA sm4Consts<>+0x50(SB)/8, $0x2dcd7d9db050e000
+DATA sm4Consts<>+0x58(SB)/8, $0xed0dbd5d709020c0
+DATA sm4Consts<>+0x60(SB)/8, $0x0605040702010003
+DATA sm4Consts<>+0x68(SB)/8, $0x0e0d0c0f0a09080b
+DATA sm4Consts<>+0x70(SB)/8, $0x0407060500030201
+DATA sm4Consts<>+0x78(SB)/8, $0x0c0f0e0d080b0a09
+GLOBL sm4Consts<>(SB), (NOPTR+RODATA), $128
+
+#define INV_SHIFT_ROWS V16
+#define NIBBLE_MASK V17
+#define PRE_LO V18
+#define PRE_HI V19
+#define POST_LO V20
+#define POST_HI V21
+#define ROTL8 V22
+#define ROTL24 V23
+#define ZERO V31
+
+// func cryptBlocksNEON(rk *[32]uint32, dst, src []byte)
+TEXT ·cryptBlocksNEON(SB), NOSPLIT, $0-56
+	MOVD rk+0(FP), R0
+	MOVD dst_base+8(FP), R1
+	MOVD src_base+32(FP), R2
+	MOVD src_len+40(FP), R3
+	LSR  $7, R3
+	CBZ  R3, done
+
+	MOVD  $sm4Consts<>(SB), R5
+	VLD1.P 64(R5), [INV_SHIFT_ROWS.B16, NIBBLE_MASK.B16, PRE_LO.B16, PRE_HI.B16]
+	VLD1  (R5), [POST_LO.B16, POST_HI.B16, ROTL8.B16, ROTL24.B16]
+	VEOR  ZERO.B16, ZERO.B16, ZERO.B16
+
+loop:
+	VLD1.P 64(R2), [V0.S4, V1.S4, V2.S4, V3.S4]
+	VLD1.P 64(R2), [V8.S4, V9.S4, V10.S4, V11.S4]
+	VREV32 V0.B16, V0.B16
+	VREV32 V1.B16, V1.B16
+	VREV32 V2.B16, V2.B16
+	VREV32 V3.B16, V3.B16
+	VREV32 V8.B16, V8.B16
+	VREV32 V9.B16, V9.B16
+	VREV32 V10.B16, V10.B16
+	VREV32 V11.B16, V11.B16
+	VZIP1  V1.S4, V0.S4, V4.S4
+	VZIP2  V1.S4, V0.S4, V5.S4
+	VZIP1  V3.S4, V2.S4, V6.S4
+	VZIP2  V3.S4, V2.S4, V7.S4
+	VZIP1  V6.D2, V4.D2, V0.D2
+	VZIP2  V6.D2, V4.D2, V1.D2
+	VZIP1  V7.D2, V5.D2, V2.D2
+	VZIP2  V7.D2, V5.D2, V3.D2
+	VZIP1  V9.S4, V8.S4, V12.S4
+	VZIP2  V9.S4, V8.S4, V13.S4
+	VZIP1  V11.S4, V10.S4, V14.S4
+	VZIP2  V11.S4, V10.S4, V15.S4
+	VZIP1  V14.D2, V12.D2, V8.D2
+	VZIP2  V14.D2, V12.D2, V9.D2
+	VZIP1  V15.D2, V13.D2, V10.D2
+	VZIP2  V15.D2, V13.D2, V11.D2
+
+	// round 0
+	MOVWU  0(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 1
+	MOVWU  4(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 2
+	MOVWU  8(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 3
+	MOVWU  12(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	// round 4
+	MOVWU  16(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 5
+	MOVWU  20(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 6
+	MOVWU  24(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 7
+	MOVWU  28(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	// round 8
+	MOVWU  32(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 9
+	MOVWU  36(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 10
+	MOVWU  40(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 11
+	MOVWU  44(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	// round 12
+	MOVWU  48(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 13
+	MOVWU  52(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 14
+	MOVWU  56(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 15
+	MOVWU  60(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	// round 16
+	MOVWU  64(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 17
+	MOVWU  68(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 18
+	MOVWU  72(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 19
+	MOVWU  76(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	// round 20
+	MOVWU  80(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 21
+	MOVWU  84(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 22
+	MOVWU  88(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 23
+	MOVWU  92(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	// round 24
+	MOVWU  96(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 25
+	MOVWU  100(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 26
+	MOVWU  104(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 27
+	MOVWU  108(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	// round 28
+	MOVWU  112(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V0.B16, V0.B16
+	VEOR   V14.B16, V8.B16, V8.B16
+
+	// round 29
+	MOVWU  116(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V1.B16, V1.B16
+	VEOR   V14.B16, V9.B16, V9.B16
+
+	// round 30
+	MOVWU  120(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V3.B16, V4.B16, V4.B16
+	VEOR   V11.B16, V12.B16, V12.B16
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V2.B16, V2.B16
+	VEOR   V14.B16, V10.B16, V10.B16
+
+	// round 31
+	MOVWU  124(R0), R4
+	VDUP   R4, V4.S4
+	VDUP   R4, V12.S4
+	VEOR   V0.B16, V4.B16, V4.B16
+	VEOR   V8.B16, V12.B16, V12.B16
+	VEOR   V1.B16, V4.B16, V4.B16
+	VEOR   V9.B16, V12.B16, V12.B16
+	VEOR   V2.B16, V4.B16, V4.B16
+	VEOR   V10.B16, V12.B16, V12.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V4.B16], V4.B16
+	VTBL   INV_SHIFT_ROWS.B16, [V12.B16], V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [PRE_HI.B16], V5.B16
+	VTBL   V13.B16, [PRE_HI.B16], V13.B16
+	VTBL   V6.B16, [PRE_LO.B16], V6.B16
+	VTBL   V14.B16, [PRE_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	AESE   ZERO.B16, V4.B16
+	AESE   ZERO.B16, V12.B16
+	VUSHR  $4, V4.B16, V5.B16
+	VUSHR  $4, V12.B16, V13.B16
+	VAND   NIBBLE_MASK.B16, V4.B16, V6.B16
+	VAND   NIBBLE_MASK.B16, V12.B16, V14.B16
+	VTBL   V5.B16, [POST_HI.B16], V5.B16
+	VTBL   V13.B16, [POST_HI.B16], V13.B16
+	VTBL   V6.B16, [POST_LO.B16], V6.B16
+	VTBL   V14.B16, [POST_LO.B16], V14.B16
+	VEOR   V5.B16, V6.B16, V4.B16
+	VEOR   V13.B16, V14.B16, V12.B16
+	VTBL   ROTL8.B16, [V4.B16], V5.B16
+	VTBL   ROTL8.B16, [V12.B16], V13.B16
+	VREV32 V4.H8, V6.H8
+	VREV32 V12.H8, V14.H8
+	VEOR   V4.B16, V5.B16, V5.B16
+	VEOR   V12.B16, V13.B16, V13.B16
+	VEOR   V6.B16, V5.B16, V5.B16
+	VEOR   V14.B16, V13.B16, V13.B16
+	VSHL   $2, V5.S4, V6.S4
+	VSHL   $2, V13.S4, V14.S4
+	VSRI   $30, V5.S4, V6.S4
+	VSRI   $30, V13.S4, V14.S4
+	VTBL   ROTL24.B16, [V4.B16], V7.B16
+	VTBL   ROTL24.B16, [V12.B16], V15.B16
+	VEOR   V4.B16, V6.B16, V6.B16
+	VEOR   V12.B16, V14.B16, V14.B16
+	VEOR   V7.B16, V6.B16, V6.B16
+	VEOR   V15.B16, V14.B16, V14.B16
+	VEOR   V6.B16, V3.B16, V3.B16
+	VEOR   V14.B16, V11.B16, V11.B16
+
+	VZIP1  V2.S4, V3.S4, V4.S4
+	VZIP2  V2.S4, V3.S4, V5.S4
+	VZIP1  V0.S4, V1.S4, V6.S4
+	VZIP2  V0.S4, V1.S4, V7.S4
+	VZIP1  V6.D2, V4.D2, V0.D2
+	VZIP2  V6.D2, V4.D2, V1.D2
+	VZIP1  V7.D2, V5.D2, V2.D2
+	VZIP2  V7.D2, V5.D2, V3.D2
+	VZIP1  V10.S4, V11.S4, V12.S4
+	VZIP2  V10.S4, V11.S4, V13.S4
+	VZIP1  V8.S4, V9.S4, V14.S4
+	VZIP2  V8.S4, V9.S4, V15.S4
+	VZIP1  V14.D2, V12.D2, V8.D2
+	VZIP2  V14.D2, V12.D2, V9.D2
+	VZIP1  V15.D2, V13.D2, V10.D2
+	VZIP2  V15.D2, V13.D2, V11.D2
+	VREV32 V0.B16, V0.B16
+	VREV32 V1.B16, V1.B16
+	VREV32 V2.B16, V2.B16
+	VREV32 V3.B16, V3.B16
+	VREV32 V8.B16, V8.B16
+	VREV32 V9.B16, V9.B16
+	VREV32 V10.B16, V10.B16
+	VREV32 V11.B16, V11.B16
+	VST1.P [V0.S4, V1.S4, V2.S4, V3.S4], 64(R1)
+	VST1.P [V8.S4, V9.S4, V10.S4, V11.S4], 64(R1)
+
+	SUB $1, R3
+	CBNZ R3, loop
+
+done:
+	RET
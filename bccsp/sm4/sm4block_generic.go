@@ -0,0 +1,17 @@
+// +build !amd64,!arm64 purego
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sm4
+
+var implementation = "generic"
+
+// cryptBlocks encrypts or decrypts, depending on the order of rk, the
+// len(src)/BlockSize blocks of src into dst.
+func cryptBlocks(rk *[32]uint32, dst, src []byte) {
+	cryptBlocksGeneric(rk, dst, src)
+}
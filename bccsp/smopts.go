@@ -16,6 +16,13 @@ limitations under the License.
 
 package bccsp
 
+import (
+	"crypto"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"io"
+)
+
 // 国密商密系列算法选项类别
 
 const (
@@ -43,9 +50,80 @@ const (
  ************************************
  */
 
+// SM2KeyUsage tags an SM2 key with the purpose it was generated for.
+type SM2KeyUsage int
+
+const (
+	// SM2KeyUsageAny places no restriction on the key.
+	SM2KeyUsageAny SM2KeyUsage = iota
+	// SM2KeyUsageSign marks a key of the signing certificate.
+	SM2KeyUsageSign
+	// SM2KeyUsageEncrypt marks a key of the encryption certificate;
+	// it must not be used to produce signatures.
+	SM2KeyUsageEncrypt
+)
+
+// String returns the value of the KeyAttrUsage attribute of usage.
+func (u SM2KeyUsage) String() string {
+	switch u {
+	case SM2KeyUsageSign:
+		return "sign"
+	case SM2KeyUsageEncrypt:
+		return "encrypt"
+	default:
+		return ""
+	}
+}
+
+// ParseSM2KeyUsage parses the value of a KeyAttrUsage attribute.
+// Unknown values yield SM2KeyUsageAny.
+func ParseSM2KeyUsage(s string) SM2KeyUsage {
+	switch s {
+	case "sign":
+		return SM2KeyUsageSign
+	case "encrypt":
+		return SM2KeyUsageEncrypt
+	default:
+		return SM2KeyUsageAny
+	}
+}
+
+// SM2UsageKey is implemented by SM2 keys that carry an intended usage.
+type SM2UsageKey interface {
+	Key
+
+	// Usage returns the usage the key was generated for.
+	Usage() SM2KeyUsage
+}
+
+// PairedKeyStore is implemented by key stores able to pair the signing
+// and the encryption key of a dual certificate identity, as required by
+// GM/T 0024, so that either key can be found from the other.
+type PairedKeyStore interface {
+	KeyStore
+
+	// PairKeys records that signKey and encKey are the signing and the
+	// encryption key of the same identity. Both keys must be stored.
+	PairKeys(signKey, encKey Key) error
+
+	// GetPairedKey returns the key with the given usage of the identity
+	// owning the key whose SKI is ski. That is the key itself if it has
+	// this usage.
+	GetPairedKey(ski []byte, usage SM2KeyUsage) (Key, error)
+}
+
 // SM2KeyGenOpts contains options for SM2 key generation.
 type SM2KeyGenOpts struct {
 	Temporary bool
+
+	// Curve selects a non-default curve, e.g. a test curve.
+	// If nil, the curve identified by CurveOID is used.
+	Curve elliptic.Curve
+	// CurveOID selects the curve by its object identifier.
+	// If both Curve and CurveOID are unset, sm2p256v1 is used.
+	CurveOID asn1.ObjectIdentifier
+	// Usage tags the generated key with its intended usage.
+	Usage SM2KeyUsage
 }
 
 // Algorithm returns the key generation algorithm identifier (to be used).
@@ -91,6 +169,27 @@ func (opts *SM2PrivateKeyImportOpts) Ephemeral() bool {
 	return opts.Temporary
 }
 
+// SM2PrivateKeyRawImportOpts contains options for SM2 private key importation
+// from the raw 32-byte private scalar D, as exported by hardware tokens and wallets.
+type SM2PrivateKeyRawImportOpts struct {
+	Temporary bool
+
+	// PublicKey optionally carries the uncompressed public point (0x04||X||Y).
+	// When set, it must match the public key derived from D.
+	PublicKey []byte
+}
+
+// Algorithm returns the key importation algorithm identifier (to be used).
+func (opts *SM2PrivateKeyRawImportOpts) Algorithm() string {
+	return SM2
+}
+
+// Ephemeral returns true if the key to generate has to be ephemeral,
+// false otherwise.
+func (opts *SM2PrivateKeyRawImportOpts) Ephemeral() bool {
+	return opts.Temporary
+}
+
 // SM2GoPublicKeyImportOpts contains options for SM2 key importation from SM2.PublicKey
 type SM2GoPublicKeyImportOpts struct {
 	Temporary bool
@@ -129,6 +228,53 @@ func (opts *SM2ReRandKeyOpts) ExpansionValue() []byte {
 	return opts.Expansion
 }
 
+// SM2SignatureEncoding identifies how an SM2 signature is serialized.
+type SM2SignatureEncoding int
+
+const (
+	// SM2SignatureDER is the ASN.1 DER encoding of SEQUENCE { r, s }, the default.
+	SM2SignatureDER SM2SignatureEncoding = iota
+	// SM2SignatureRaw is the 64-byte concatenation r||s, as produced by
+	// many JavaScript and Java GM SDKs.
+	SM2SignatureRaw
+	// SM2SignatureAny accepts either encoding on verification. Signing with
+	// it produces DER.
+	SM2SignatureAny
+)
+
+// SM2SignerOpts contains options for creating and verifying SM2 signatures.
+type SM2SignerOpts struct {
+	Encoding SM2SignatureEncoding
+}
+
+// HashFunc returns 0: SM2 signs the message together with the Z_A user digest.
+func (opts *SM2SignerOpts) HashFunc() crypto.Hash {
+	return 0
+}
+
+// SM2SignatureEncodingOf returns the SM2 signature encoding requested by
+// opts: the Encoding of *SM2SignerOpts, and SM2SignatureDER for any other
+// options, nil included.
+func SM2SignatureEncodingOf(opts SignerOpts) SM2SignatureEncoding {
+	if o, ok := opts.(*SM2SignerOpts); ok && o != nil {
+		return o.Encoding
+	}
+	return SM2SignatureDER
+}
+
+// SM2EncrypterOpts contains options for SM2 public key encryption and
+// decryption (GB/T 32918.4). The ciphertext is encoded as C1 || C3 || C2.
+type SM2EncrypterOpts struct {
+	// Label is mixed into the key derivation, binding the ciphertext to a
+	// purpose (e.g. private-data transfer) so that it cannot be replayed to
+	// another consumer. Decryption must use the same label. An empty label
+	// yields standard GB/T 32918.4 ciphertexts.
+	Label []byte
+	// PRNG is an instance of a PRNG to be used for encryption.
+	// It is used only if different from nil.
+	PRNG io.Reader
+}
+
 /************************************
  ****	        SM3                ****
  ************************************
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+const gcmStandardNonceSize = 12
+
+func newGCM(block cipher.Block, opts *bccsp.AEADOpts) (cipher.AEAD, error) {
+	tagSize := opts.TagSize
+	if tagSize == 0 {
+		tagSize = 16
+	}
+	if tagSize < 12 || tagSize > 16 {
+		return nil, fmt.Errorf("Invalid tag size [%d]. It must be between 12 and 16.", tagSize)
+	}
+	return cipher.NewGCMWithTagSize(block, tagSize)
+}
+
+// gcmSeal encrypts and authenticates plaintext, returning nonce || ciphertext || tag.
+// Unless opts set them, the nonce is read from prng, or crypto/rand if nil.
+func gcmSeal(block cipher.Block, plaintext []byte, opts *bccsp.AEADOpts, prng io.Reader) ([]byte, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid options. It must not be nil.")
+	}
+	if len(opts.Nonce) != 0 && opts.PRNG != nil {
+		return nil, errors.New("Invalid options. Either Nonce or PRNG should be different from nil, or both nil.")
+	}
+
+	aead, err := newGCM(block, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := opts.Nonce
+	if len(nonce) == 0 {
+		nonce = make([]byte, gcmStandardNonceSize)
+		if opts.PRNG != nil {
+			prng = opts.PRNG
+		}
+		if _, err := io.ReadFull(randOrDefault(prng), nonce); err != nil {
+			return nil, fmt.Errorf("Failed sampling nonce [%s]", err)
+		}
+	} else if len(nonce) != gcmStandardNonceSize {
+		return nil, fmt.Errorf("Invalid nonce length [%d]. It must be %d bytes.", len(nonce), gcmStandardNonceSize)
+	}
+
+	out := make([]byte, len(nonce), len(nonce)+len(plaintext)+aead.Overhead())
+	copy(out, nonce)
+	return aead.Seal(out, nonce, plaintext, opts.AdditionalData), nil
+}
+
+// gcmOpen authenticates and decrypts src, which must be nonce || ciphertext || tag.
+func gcmOpen(block cipher.Block, src []byte, opts *bccsp.AEADOpts) ([]byte, error) {
+	if opts == nil {
+		return nil, errors.New("Invalid options. It must not be nil.")
+	}
+	aead, err := newGCM(block, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(src) < gcmStandardNonceSize+aead.Overhead() {
+		return nil, errors.New("Invalid ciphertext. It is too short.")
+	}
+
+	plaintext, err := aead.Open(nil, src[:gcmStandardNonceSize], src[gcmStandardNonceSize:], opts.AdditionalData)
+	if err != nil {
+		return nil, errors.New("Failed authenticating ciphertext")
+	}
+	return plaintext, nil
+}
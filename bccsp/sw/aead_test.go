@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAEAD(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 16)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name string
+		key  bccsp.Key
+		enc  Encryptor
+		dec  Decryptor
+	}{
+		{"AES-GCM", &aesPrivateKey{key, false}, &aescbcpkcs7Encryptor{}, &aescbcpkcs7Decryptor{}},
+		{"SM4-GCM", &sm4PrivateKey{key, false}, &sm4Encryptor{}, &sm4Decryptor{}},
+		{"SM4-GCM constant time", &sm4PrivateKey{key, false}, &sm4Encryptor{newCipher: newSM4ConstantTimeCipher}, &sm4Decryptor{newCipher: newSM4ConstantTimeCipher}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ptext := []byte("a private data collection entry")
+			opts := &bccsp.AEADOpts{AdditionalData: []byte("txid")}
+
+			ct, err := tc.enc.Encrypt(tc.key, ptext, opts)
+			assert.NoError(t, err)
+			assert.Len(t, ct, 12+len(ptext)+16)
+
+			pt, err := tc.dec.Decrypt(tc.key, ct, opts)
+			assert.NoError(t, err)
+			assert.Equal(t, ptext, pt)
+
+			// Binding to the additional data
+			_, err = tc.dec.Decrypt(tc.key, ct, &bccsp.AEADOpts{AdditionalData: []byte("other")})
+			assert.EqualError(t, err, "Failed authenticating ciphertext")
+
+			// Tampering
+			ct[len(ct)-1] ^= 1
+			_, err = tc.dec.Decrypt(tc.key, ct, opts)
+			assert.EqualError(t, err, "Failed authenticating ciphertext")
+
+			// Passed nonce and tag size
+			nonce := bytes.Repeat([]byte{1}, 12)
+			ct, err = tc.enc.Encrypt(tc.key, ptext, bccsp.AEADOpts{Nonce: nonce, TagSize: 12})
+			assert.NoError(t, err)
+			assert.Equal(t, nonce, ct[:12])
+			assert.Len(t, ct, 12+len(ptext)+12)
+			pt, err = tc.dec.Decrypt(tc.key, ct, bccsp.AEADOpts{TagSize: 12})
+			assert.NoError(t, err)
+			assert.Equal(t, ptext, pt)
+
+			_, err = tc.enc.Encrypt(tc.key, ptext, &bccsp.AEADOpts{Nonce: nonce, PRNG: rand.Reader})
+			assert.Error(t, err)
+			_, err = tc.enc.Encrypt(tc.key, ptext, &bccsp.AEADOpts{Nonce: nonce[:8]})
+			assert.EqualError(t, err, "Invalid nonce length [8]. It must be 12 bytes.")
+			_, err = tc.enc.Encrypt(tc.key, ptext, &bccsp.AEADOpts{TagSize: 8})
+			assert.EqualError(t, err, "Invalid tag size [8]. It must be between 12 and 16.")
+			_, err = tc.dec.Decrypt(tc.key, ct[:20], &bccsp.AEADOpts{})
+			assert.EqualError(t, err, "Invalid ciphertext. It is too short.")
+			_, err = tc.enc.Encrypt(tc.key, ptext, (*bccsp.AEADOpts)(nil))
+			assert.EqualError(t, err, "Invalid options. It must not be nil.")
+			_, err = tc.dec.Decrypt(tc.key, ct, (*bccsp.AEADOpts)(nil))
+			assert.EqualError(t, err, "Invalid options. It must not be nil.")
+		})
+	}
+}
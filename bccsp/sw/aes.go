@@ -30,13 +30,19 @@ import (
 
 // GetRandomBytes returns len random looking bytes
 func GetRandomBytes(len int) ([]byte, error) {
+	return getRandomBytesFrom(rand.Reader, len)
+}
+
+// getRandomBytesFrom returns len random looking bytes read from prng,
+// or from crypto/rand if prng is nil.
+func getRandomBytesFrom(prng io.Reader, len int) ([]byte, error) {
 	if len < 0 {
 		return nil, errors.New("Len must be larger than 0")
 	}
 
 	buffer := make([]byte, len)
 
-	n, err := rand.Read(buffer)
+	n, err := io.ReadFull(randOrDefault(prng), buffer)
 	if err != nil {
 		return nil, err
 	}
@@ -180,7 +186,9 @@ func AESCBCPKCS7Decrypt(key, src []byte) ([]byte, error) {
 	return nil, err
 }
 
-type aescbcpkcs7Encryptor struct{}
+type aescbcpkcs7Encryptor struct {
+	rand io.Reader // IV与nonce的随机数源, 为nil时使用crypto/rand
+}
 
 func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
 	switch o := opts.(type) {
@@ -197,11 +205,22 @@ func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp
 		} else if o.PRNG != nil {
 			// Encrypt with PRNG
 			return AESCBCPKCS7EncryptWithRand(o.PRNG, k.(*aesPrivateKey).privKey, plaintext)
+		} else if e.rand != nil {
+			return AESCBCPKCS7EncryptWithRand(e.rand, k.(*aesPrivateKey).privKey, plaintext)
 		}
 		// AES in CBC mode with PKCS7 padding
 		return AESCBCPKCS7Encrypt(k.(*aesPrivateKey).privKey, plaintext)
 	case bccsp.AESCBCPKCS7ModeOpts:
 		return e.Encrypt(k, plaintext, &o)
+	case *bccsp.AEADOpts:
+		// AES in GCM mode
+		block, err := aes.NewCipher(k.(*aesPrivateKey).privKey)
+		if err != nil {
+			return nil, err
+		}
+		return gcmSeal(block, plaintext, o, e.rand)
+	case bccsp.AEADOpts:
+		return e.Encrypt(k, plaintext, &o)
 	default:
 		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
 	}
@@ -209,12 +228,21 @@ func (e *aescbcpkcs7Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp
 
 type aescbcpkcs7Decryptor struct{}
 
-func (*aescbcpkcs7Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+func (d *aescbcpkcs7Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
 	// check for mode
-	switch opts.(type) {
+	switch o := opts.(type) {
 	case *bccsp.AESCBCPKCS7ModeOpts, bccsp.AESCBCPKCS7ModeOpts:
 		// AES in CBC mode with PKCS7 padding
 		return AESCBCPKCS7Decrypt(k.(*aesPrivateKey).privKey, ciphertext)
+	case *bccsp.AEADOpts:
+		// AES in GCM mode
+		block, err := aes.NewCipher(k.(*aesPrivateKey).privKey)
+		if err != nil {
+			return nil, err
+		}
+		return gcmOpen(block, ciphertext, o)
+	case bccsp.AEADOpts:
+		return d.Decrypt(k, ciphertext, &o)
 	default:
 		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
 	}
@@ -62,3 +62,8 @@ func (k *aesPrivateKey) Private() bool {
 func (k *aesPrivateKey) PublicKey() (bccsp.Key, error) {
 	return nil, errors.New("Cannot call this method on a symmetric key.")
 }
+
+// Algorithm returns the key algorithm identifier.
+func (k *aesPrivateKey) Algorithm() string {
+	return bccsp.AES
+}
@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+// sm2CurveName is the name of the SM2 recommended curve of GB/T 32918.5.
+const sm2CurveName = "SM2P256V1"
+
+// Capabilities returns the algorithms offered by this CSP, derived from
+// the registered wrappers.
+func (csp *CSP) Capabilities() *bccsp.Capabilities {
+	c := &bccsp.Capabilities{
+		Provider:          "SW",
+		DefaultHashFamily: csp.hashFamily,
+	}
+
+	for t := range csp.Hashers {
+		if opts, ok := newOpts(t).(bccsp.HashOpts); ok && opts.Algorithm() != bccsp.SHA {
+			c.HashAlgorithms = appendUnique(c.HashAlgorithms, opts.Algorithm())
+		}
+	}
+
+	for t, kg := range csp.KeyGenerators {
+		if opts, ok := newOpts(t).(bccsp.KeyGenOpts); ok {
+			c.KeyGenAlgorithms = appendUnique(c.KeyGenAlgorithms, opts.Algorithm())
+		}
+		switch g := kg.(type) {
+		case *ecdsaKeyGenerator:
+			if g.curve != nil {
+				c.Curves = appendUnique(c.Curves, g.curve.Params().Name)
+			}
+		case *sm2KeyGenerator:
+			c.Curves = appendUnique(c.Curves, sm2CurveName)
+		}
+	}
+
+	for t := range csp.Signers {
+		switch t {
+		case reflect.TypeOf(&ecdsaPrivateKey{}):
+			c.SignatureAlgorithms = appendUnique(c.SignatureAlgorithms, bccsp.ECDSA)
+		case reflect.TypeOf(&sm2PrivateKey{}):
+			c.SignatureAlgorithms = appendUnique(c.SignatureAlgorithms, bccsp.SM2)
+		}
+	}
+
+	for t := range csp.Encryptors {
+		switch t {
+		case reflect.TypeOf(&aesPrivateKey{}):
+			c.CipherModes = appendUnique(c.CipherModes, bccsp.CipherModeAESCBCPKCS7, bccsp.CipherModeAESGCM)
+		case reflect.TypeOf(&sm4PrivateKey{}):
+			c.CipherModes = appendUnique(c.CipherModes, bccsp.CipherModeSM4, bccsp.CipherModeSM4GCM)
+		case reflect.TypeOf(&sm2PublicKey{}), reflect.TypeOf(&sm2PrivateKey{}):
+			c.CipherModes = appendUnique(c.CipherModes, bccsp.CipherModeSM2)
+		}
+	}
+
+	// Map iteration order is random, keep the result stable
+	for _, s := range [][]string{c.HashAlgorithms, c.KeyGenAlgorithms, c.SignatureAlgorithms, c.Curves, c.CipherModes} {
+		sort.Strings(s)
+	}
+
+	return c
+}
+
+// newOpts returns a zero value of the option type t, which is expected
+// to be a pointer type as registered by AddWrapper.
+func newOpts(t reflect.Type) interface{} {
+	if t.Kind() != reflect.Ptr {
+		return nil
+	}
+	return reflect.New(t.Elem()).Interface()
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, l := range list {
+			if l == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,43 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	c, ok := bccsp.GetCapabilities(csp)
+	assert.True(t, ok)
+	assert.Equal(t, "SW", c.Provider)
+	assert.Equal(t, "SM3", c.DefaultHashFamily)
+	assert.Equal(t, []string{bccsp.SHA256, bccsp.SHA384, bccsp.SHA3_256, bccsp.SHA3_384, bccsp.SM3}, c.HashAlgorithms)
+	assert.Equal(t, []string{bccsp.ECDSA, bccsp.SM2}, c.SignatureAlgorithms)
+	assert.Equal(t, []string{"P-256", "P-384", "SM2P256V1"}, c.Curves)
+	assert.Equal(t, []string{bccsp.CipherModeAESCBCPKCS7, bccsp.CipherModeAESGCM, bccsp.CipherModeSM2, bccsp.CipherModeSM4, bccsp.CipherModeSM4GCM}, c.CipherModes)
+	assert.Contains(t, c.KeyGenAlgorithms, bccsp.SM2)
+	assert.Contains(t, c.KeyGenAlgorithms, bccsp.SM4)
+
+	assert.NoError(t, c.RequireHash("SM3"))
+	assert.NoError(t, c.RequireSignatureAlgorithm(bccsp.SM2))
+	assert.NoError(t, c.RequireCurve("sm2p256v1"))
+	assert.NoError(t, c.RequireCipherMode(bccsp.CipherModeSM4GCM))
+
+	// A provider without SM3 fails with a clear error
+	delete(csp.(*CSP).Hashers, reflect.TypeOf(&bccsp.SM3Opts{}))
+	c = csp.(*CSP).Capabilities()
+	assert.EqualError(t, c.RequireHash("SM3"), "SW provider does not support hash algorithm [SM3], supported: [SHA256, SHA384, SHA3_256, SHA3_384]")
+}
@@ -7,14 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 package sw
 
 import (
+	"crypto/cipher"
 	"crypto/elliptic"
 	"crypto/sha256"
 	"crypto/sha512"
 	"fmt"
 	"hash"
+	"io"
 
-	"github.com/paul-lee-attorney/gm/sm2"
-	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -22,6 +24,103 @@ type config struct {
 	ellipticCurve elliptic.Curve   // 椭圆曲线配置
 	hashFunction  func() hash.Hash // 哈希函数配置
 	aesBitLength  int              // AES随机秘钥的字节长度， SM4直接在new.go中赋值(16字节)
+	rand          io.Reader        // 秘钥生成与签名使用的随机数源, 为nil时使用crypto/rand
+	noRandPool    bool             // 为true时nonce与IV直接读取crypto/rand, 不使用NewPooledRandReader
+
+	sm4NewCipher  func([]byte) (cipher.Block, error) // SM4实现, 为nil时使用默认的查表实现
+	sm2Precomp    bool                               // 是否对SM2私钥进行签名预计算
+	sm2FastVerify bool                               // 是否使用预计算的多标量乘法验证SM2签名
+	gmBackend     string                             // SM2与SM3的实现, 为空时使用纯Go实现
+
+	skiConvention *utils.SKIConvention // 秘钥SKI的约定, 为nil时使用进程的约定
+	asn1Mode      *utils.ASN1Mode      // 签名的ASN.1解析模式, 为nil时使用进程的模式
+}
+
+// Option configures optional behaviour of the software-based BCCSP.
+type Option func(*config)
+
+// WithRand sets the entropy source used uniformly by key generation
+// and signing. If r is nil, crypto/rand is used.
+func WithRand(r io.Reader) Option {
+	return func(conf *config) {
+		conf.rand = r
+	}
+}
+
+// WithoutRandPool reads the nonces and IVs of SM2 signatures and
+// encryptions and of SM4 and AES encryptions from crypto/rand, instead of
+// the pooled CSPRNG returned by NewPooledRandReader. It has no effect with
+// WithRand, whose source is used for everything.
+func WithoutRandPool() Option {
+	return func(conf *config) {
+		conf.noRandPool = true
+	}
+}
+
+// WithConstantTimeSM4 selects the constant-time SM4 implementation instead
+// of the faster table-based default, for hosts where cache-timing side
+// channels matter.
+func WithConstantTimeSM4() Option {
+	return func(conf *config) {
+		conf.sm4NewCipher = newSM4ConstantTimeCipher
+	}
+}
+
+// WithSM2Precomputation enables caching, by SKI, of the per-key values used
+// by SM2 signing when a private key is first loaded from the KeyStore, for
+// the 1024 most recently used keys. This trades memory for lower
+// per-signature latency on busy endorsing peers.
+func WithSM2Precomputation() Option {
+	return func(conf *config) {
+		conf.sm2Precomp = true
+	}
+}
+
+// WithSM2FastVerify verifies SM2 signatures computing the multi-scalar
+// multiplication of the verify equation with the precomputed fixed-base
+// table of the generator and Jacobian coordinates, in less than half the
+// time of the generic curve arithmetic of the gm library.
+func WithSM2FastVerify() Option {
+	return func(conf *config) {
+		conf.sm2FastVerify = true
+	}
+}
+
+// WithGMBackend selects the implementation of SM2 signing and verification
+// and of SM3, GMBackendGo (the default) or GMBackendGmSSL, which binds
+// GmSSL 3 through cgo and requires the gmssl build tag. SM2 precomputation
+// and fast verification are pure-Go paths, ignored with GmSSL.
+func WithGMBackend(name string) Option {
+	return func(conf *config) {
+		conf.gmBackend = name
+	}
+}
+
+// WithSKIConvention derives the SKIs of the SM2 and ECDSA keys of the
+// provider with convention c instead of the convention of the process, so
+// that providers of the same node can, for instance, hash with SM3 only or
+// with SHA-256 only. Keys stored under another convention are still found
+// by GetKey.
+func WithSKIConvention(c utils.SKIConvention) Option {
+	return func(conf *config) {
+		conf.skiConvention = &c
+	}
+}
+
+// WithASN1Mode parses the signatures the provider verifies in mode instead
+// of the mode of the process.
+func WithASN1Mode(mode utils.ASN1Mode) Option {
+	return func(conf *config) {
+		conf.asn1Mode = &mode
+	}
+}
+
+// asn1ModeOrDefault 返回mode, mode为nil时返回进程的解析模式
+func asn1ModeOrDefault(mode *utils.ASN1Mode) utils.ASN1Mode {
+	if mode == nil {
+		return utils.GetASN1Mode()
+	}
+	return *mode
 }
 
 // setSecurityLevel 为设置安全等级的方法。
@@ -72,11 +171,14 @@ func (conf *config) setSecurityLevelSHA3(level int) (err error) {
 }
 
 // SM3 security level setting
+// ECDSA keys keep using P-256 so that the provider can still generate and
+// verify keys of organizations which have not migrated to SM2 yet;
+// SM2 keys always use the SM2 recommended curve.
 func (conf *config) setSecurityLevelSM3(level int) (err error) {
 	if level == 256 {
-		conf.ellipticCurve = sm2.GetSm2P256V1() //将SM2推荐椭圆曲线实例赋值给配置
-		conf.hashFunction = sm3.New             // 将SM3哈希摘要实例初始化函数赋值给配置
-		conf.aesBitLength = 16                  // SM4为128位秘钥，即16字节
+		conf.ellipticCurve = elliptic.P256() // ECDSA采用P-256曲线, SM2秘钥由sm2KeyGenerator生成
+		conf.hashFunction = sm3.New          // 将SM3哈希摘要实例初始化函数赋值给配置
+		conf.aesBitLength = 16               // SM4为128位秘钥，即16字节
 	} else {
 		err = fmt.Errorf("Security level not supported [%d]", level)
 	}
@@ -16,8 +16,6 @@ limitations under the License.
 package sw
 
 import (
-	"errors"
-
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 )
 
@@ -39,11 +37,11 @@ func (ks *dummyKeyStore) ReadOnly() bool {
 
 // GetKey returns a key object whose SKI is the one passed.
 func (ks *dummyKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
-	return nil, errors.New("Key not found. This is a dummy KeyStore")
+	return nil, bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "Key not found. This is a dummy KeyStore")
 }
 
 // StoreKey stores the key k in this KeyStore.
 // If this KeyStore is read only then the method will fail.
 func (ks *dummyKeyStore) StoreKey(k bccsp.Key) error {
-	return errors.New("Cannot store key. This is a dummy read-only KeyStore")
+	return bccsp.Errorf(bccsp.ErrCodeReadOnlyKeyStore, "Cannot store key. This is a dummy read-only KeyStore")
 }
@@ -19,13 +19,18 @@ import (
 	"crypto/ecdsa"
 	"crypto/rand"
 	"fmt"
+	"io"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 )
 
 func signECDSA(k *ecdsa.PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
-	r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+	return signECDSAWithRand(rand.Reader, k, digest, opts)
+}
+
+func signECDSAWithRand(prng io.Reader, k *ecdsa.PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	r, s, err := ecdsa.Sign(prng, k, digest)
 	if err != nil {
 		return nil, err
 	}
@@ -38,8 +43,8 @@ func signECDSA(k *ecdsa.PrivateKey, digest []byte, opts bccsp.SignerOpts) ([]byt
 	return utils.MarshalECDSASignature(r, s)
 }
 
-func verifyECDSA(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
-	r, s, err := utils.UnmarshalECDSASignature(signature)
+func verifyECDSA(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.SignerOpts, mode utils.ASN1Mode) (bool, error) {
+	r, s, err := utils.UnmarshalECDSASignatureWithMode(signature, mode)
 	if err != nil {
 		return false, fmt.Errorf("Failed unmashalling signature [%s]", err)
 	}
@@ -56,20 +61,26 @@ func verifyECDSA(k *ecdsa.PublicKey, signature, digest []byte, opts bccsp.Signer
 	return ecdsa.Verify(k, digest, r, s), nil
 }
 
-type ecdsaSigner struct{}
+type ecdsaSigner struct {
+	rand io.Reader
+}
 
 func (s *ecdsaSigner) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
-	return signECDSA(k.(*ecdsaPrivateKey).privKey, digest, opts)
+	return signECDSAWithRand(randOrDefault(s.rand), k.(*ecdsaPrivateKey).privKey, digest, opts)
 }
 
-type ecdsaPrivateKeyVerifier struct{}
+type ecdsaPrivateKeyVerifier struct {
+	asn1Mode *utils.ASN1Mode
+}
 
 func (v *ecdsaPrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
-	return verifyECDSA(&(k.(*ecdsaPrivateKey).privKey.PublicKey), signature, digest, opts)
+	return verifyECDSA(&(k.(*ecdsaPrivateKey).privKey.PublicKey), signature, digest, opts, asn1ModeOrDefault(v.asn1Mode))
 }
 
-type ecdsaPublicKeyKeyVerifier struct{}
+type ecdsaPublicKeyKeyVerifier struct {
+	asn1Mode *utils.ASN1Mode
+}
 
 func (v *ecdsaPublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
-	return verifyECDSA(k.(*ecdsaPublicKey).pubKey, signature, digest, opts)
+	return verifyECDSA(k.(*ecdsaPublicKey).pubKey, signature, digest, opts, asn1ModeOrDefault(v.asn1Mode))
 }
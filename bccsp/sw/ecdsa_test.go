@@ -25,8 +25,10 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSignECDSABadParameter(t *testing.T) {
@@ -56,15 +58,15 @@ func TestVerifyECDSA(t *testing.T) {
 	sigma, err := signECDSA(lowLevelKey, msg, nil)
 	assert.NoError(t, err)
 
-	valid, err := verifyECDSA(&lowLevelKey.PublicKey, sigma, msg, nil)
+	valid, err := verifyECDSA(&lowLevelKey.PublicKey, sigma, msg, nil, utils.ASN1Strict)
 	assert.NoError(t, err)
 	assert.True(t, valid)
 
-	_, err = verifyECDSA(&lowLevelKey.PublicKey, nil, msg, nil)
+	_, err = verifyECDSA(&lowLevelKey.PublicKey, nil, msg, nil, utils.ASN1Strict)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Failed unmashalling signature [")
 
-	_, err = verifyECDSA(&lowLevelKey.PublicKey, nil, msg, nil)
+	_, err = verifyECDSA(&lowLevelKey.PublicKey, nil, msg, nil, utils.ASN1Strict)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Failed unmashalling signature [")
 
@@ -73,11 +75,35 @@ func TestVerifyECDSA(t *testing.T) {
 	S.Add(utils.GetCurveHalfOrdersAt(elliptic.P256()), big.NewInt(1))
 	sigmaWrongS, err := utils.MarshalECDSASignature(R, S)
 	assert.NoError(t, err)
-	_, err = verifyECDSA(&lowLevelKey.PublicKey, sigmaWrongS, msg, nil)
+	_, err = verifyECDSA(&lowLevelKey.PublicKey, sigmaWrongS, msg, nil, utils.ASN1Strict)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Invalid S. Must be smaller than half the order [")
 }
 
+func TestVerifyECDSAWithASN1Mode(t *testing.T) {
+	csp, err := NewWithParams(256, "SHA2", NewDummyKeyStore(), WithASN1Mode(utils.ASN1Lenient))
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+
+	digest := sha256.Sum256([]byte("hello world"))
+	der, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	// The sequence length in long form
+	ber := append([]byte{0x30, 0x81, der[1]}, der[2:]...)
+
+	valid, err := csp.Verify(k, ber, digest[:], nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, utils.ASN1Strict, utils.GetASN1Mode())
+
+	// Providers without the option parse in the mode of the process
+	strict, err := NewWithParams(256, "SHA2", NewDummyKeyStore())
+	require.NoError(t, err)
+	_, err = strict.Verify(k, ber, digest[:], nil)
+	assert.Error(t, err)
+}
+
 func TestEcdsaSignerSign(t *testing.T) {
 	t.Parallel()
 
@@ -88,7 +114,7 @@ func TestEcdsaSignerSign(t *testing.T) {
 	// Generate a key
 	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
-	k := &ecdsaPrivateKey{lowLevelKey}
+	k := &ecdsaPrivateKey{privKey: lowLevelKey}
 	pk, err := k.PublicKey()
 	assert.NoError(t, err)
 
@@ -99,7 +125,7 @@ func TestEcdsaSignerSign(t *testing.T) {
 	assert.NotNil(t, sigma)
 
 	// Verify
-	valid, err := verifyECDSA(&lowLevelKey.PublicKey, sigma, msg, nil)
+	valid, err := verifyECDSA(&lowLevelKey.PublicKey, sigma, msg, nil, utils.ASN1Strict)
 	assert.NoError(t, err)
 	assert.True(t, valid)
 
@@ -117,7 +143,7 @@ func TestEcdsaPrivateKey(t *testing.T) {
 
 	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
-	k := &ecdsaPrivateKey{lowLevelKey}
+	k := &ecdsaPrivateKey{privKey: lowLevelKey}
 
 	assert.False(t, k.Symmetric())
 	assert.True(t, k.Private())
@@ -151,7 +177,7 @@ func TestEcdsaPublicKey(t *testing.T) {
 
 	lowLevelKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
-	k := &ecdsaPublicKey{&lowLevelKey.PublicKey}
+	k := &ecdsaPublicKey{pubKey: &lowLevelKey.PublicKey}
 
 	assert.False(t, k.Symmetric())
 	assert.False(t, k.Private())
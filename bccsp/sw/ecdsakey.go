@@ -16,18 +16,21 @@ limitations under the License.
 package sw
 
 import (
+	"crypto"
 	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/sha256"
 	"crypto/x509"
 	"errors"
 	"fmt"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 )
 
 type ecdsaPrivateKey struct {
 	privKey *ecdsa.PrivateKey
+	attrs   map[string]string
+	skiConv *utils.SKIConvention
+	ski     skiMemo
 }
 
 // Bytes converts this key to its byte representation,
@@ -42,13 +45,7 @@ func (k *ecdsaPrivateKey) SKI() []byte {
 		return nil
 	}
 
-	// Marshall the public key
-	raw := elliptic.Marshal(k.privKey.Curve, k.privKey.PublicKey.X, k.privKey.PublicKey.Y)
-
-	// Hash it
-	hash := sha256.New()
-	hash.Write(raw)
-	return hash.Sum(nil)
+	return k.ski.get(&k.privKey.PublicKey, k.skiConv)
 }
 
 // Symmetric returns true if this key is a symmetric key,
@@ -66,11 +63,13 @@ func (k *ecdsaPrivateKey) Private() bool {
 // PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
 // This method returns an error in symmetric key schemes.
 func (k *ecdsaPrivateKey) PublicKey() (bccsp.Key, error) {
-	return &ecdsaPublicKey{&k.privKey.PublicKey}, nil
+	return &ecdsaPublicKey{pubKey: &k.privKey.PublicKey, skiConv: k.skiConv}, nil
 }
 
 type ecdsaPublicKey struct {
-	pubKey *ecdsa.PublicKey
+	pubKey  *ecdsa.PublicKey
+	skiConv *utils.SKIConvention
+	ski     skiMemo
 }
 
 // Bytes converts this key to its byte representation,
@@ -89,13 +88,7 @@ func (k *ecdsaPublicKey) SKI() []byte {
 		return nil
 	}
 
-	// Marshall the public key
-	raw := elliptic.Marshal(k.pubKey.Curve, k.pubKey.X, k.pubKey.Y)
-
-	// Hash it
-	hash := sha256.New()
-	hash.Write(raw)
-	return hash.Sum(nil)
+	return k.ski.get(k.pubKey, k.skiConv)
 }
 
 // Symmetric returns true if this key is a symmetric key,
@@ -115,3 +108,25 @@ func (k *ecdsaPublicKey) Private() bool {
 func (k *ecdsaPublicKey) PublicKey() (bccsp.Key, error) {
 	return k, nil
 }
+
+// CryptoPublicKey returns the public part of this key as *ecdsa.PublicKey.
+func (k *ecdsaPrivateKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return &k.privKey.PublicKey, nil
+}
+
+// CryptoPublicKey returns this key as *ecdsa.PublicKey.
+func (k *ecdsaPublicKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pubKey, nil
+}
+
+func (k *ecdsaPrivateKey) initSKIConvention(c utils.SKIConvention) {
+	if k.skiConv == nil {
+		k.skiConv = &c
+	}
+}
+
+func (k *ecdsaPublicKey) initSKIConvention(c utils.SKIConvention) {
+	if k.skiConv == nil {
+		k.skiConv = &c
+	}
+}
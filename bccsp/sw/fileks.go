@@ -7,7 +7,6 @@ SPDX-License-Identifier: Apache-2.0
 package sw
 
 import (
-	"bytes"
 	"crypto/ecdsa"
 	"encoding/hex"
 	"errors"
@@ -16,8 +15,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
@@ -40,6 +41,10 @@ func NewFileBasedKeyStore(pwd []byte, path string, readOnly bool) (bccsp.KeyStor
 // The KeyStore can be initialized with a password, this password
 // is used to encrypt and decrypt the files storing the keys.
 // A KeyStore can be read only to avoid the overwriting of keys.
+// The keys are indexed in the background once the KeyStore is opened, so
+// that opening a folder holding many keys does not stall. Until then, and
+// for the keys added to the folder by another process, keys are looked up
+// by scanning the folder.
 type fileBasedKeyStore struct {
 	path string
 
@@ -48,6 +53,9 @@ type fileBasedKeyStore struct {
 
 	pwd []byte
 
+	index *keyIndex
+	ready chan struct{}
+
 	// Sync
 	m sync.Mutex
 }
@@ -110,6 +118,18 @@ func (ks *fileBasedKeyStore) Init(pwd []byte, path string, readOnly bool) error
 	return ks.openKeyStore()
 }
 
+// Ready returns a channel closed once the keys found in the folder when the
+// KeyStore was opened are indexed.
+func (ks *fileBasedKeyStore) Ready() <-chan struct{} {
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	if ks.ready == nil {
+		ks.ready = make(chan struct{})
+	}
+	return ks.ready
+}
+
 // ReadOnly returns true if this KeyStore is read only, false otherwise.
 // If ReadOnly is true then StoreKey will fail.
 func (ks *fileBasedKeyStore) ReadOnly() bool {
@@ -120,23 +140,24 @@ func (ks *fileBasedKeyStore) ReadOnly() bool {
 func (ks *fileBasedKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
 	// Validate arguments
 	if len(ski) == 0 {
-		return nil, errors.New("invalid SKI. Cannot be of zero length")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "invalid SKI. Cannot be of zero length")
 	}
 
 	// 将SKI编码转换为ASCII编码并获取尾缀
-	suffix := ks.getSuffix(hex.EncodeToString(ski))
+	alias := hex.EncodeToString(ski)
+	suffix := ks.getSuffix(alias)
 
 	switch suffix {
 	case "key": // 对称密码算法的秘钥
 		// Load the key
 		// 载入对称密码算法的秘钥，就PEM消息加密解密算法进行SM4改造
-		key, err := ks.loadKey(hex.EncodeToString(ski))
+		key, err := ks.loadKey(alias)
 		if err != nil {
 			return nil, fmt.Errorf("failed loading key [%x] [%s]", ski, err)
 		}
 		return &aesPrivateKey{key, false}, nil
 	case "sm4key":
-		key, err := ks.loadSM4Key(hex.EncodeToString(ski))
+		key, err := ks.loadSM4Key(alias)
 		if err != nil {
 			return nil, fmt.Errorf("failed loading sm4key [%x] [%s]", ski, err)
 		}
@@ -144,34 +165,35 @@ func (ks *fileBasedKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
 	case "sk":
 		// Load the private key
 		// 载入不对称算法的私钥
-		key, err := ks.loadPrivateKey(hex.EncodeToString(ski))
+		key, err := ks.loadPrivateKey(alias)
 		if err != nil {
 			return nil, fmt.Errorf("failed loading secret key [%x] [%s]", ski, err)
 		}
 
+		attrs := ks.loadKeyAttributes(alias)
 		switch k := key.(type) {
 		case *ecdsa.PrivateKey:
-			return &ecdsaPrivateKey{k}, nil
+			return storedSKIConvention(&ecdsaPrivateKey{privKey: k, attrs: attrs}, &k.PublicKey, ski), nil
 		case *sm2.PrivateKey: // private key of sm2
-			return &sm2PrivateKey{k}, nil
+			return storedSKIConvention(&sm2PrivateKey{privKey: k, usage: bccsp.ParseSM2KeyUsage(attrs[bccsp.KeyAttrUsage]), attrs: attrs}, &k.PublicKey, ski), nil
 		default:
-			return nil, errors.New("secret key type not recognized")
+			return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedKeyType, "secret key type not recognized")
 		}
 	case "pk":
 		// Load the public key
 		// 载入不对称算法的公钥
-		key, err := ks.loadPublicKey(hex.EncodeToString(ski))
+		key, err := ks.loadPublicKey(alias)
 		if err != nil {
 			return nil, fmt.Errorf("failed loading public key [%x] [%s]", ski, err)
 		}
 
 		switch k := key.(type) {
 		case *ecdsa.PublicKey:
-			return &ecdsaPublicKey{k}, nil
+			return storedSKIConvention(&ecdsaPublicKey{pubKey: k}, k, ski), nil
 		case *sm2.PublicKey: // public key of sm2
-			return &sm2PublicKey{k}, nil
+			return storedSKIConvention(&sm2PublicKey{pubKey: k}, k, ski), nil
 		default:
-			return nil, errors.New("public key type not recognized")
+			return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedKeyType, "public key type not recognized")
 		}
 	default:
 		return ks.searchKeystoreForSKI(ski)
@@ -182,56 +204,91 @@ func (ks *fileBasedKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
 // If this KeyStore is read only then the method will fail.
 func (ks *fileBasedKeyStore) StoreKey(k bccsp.Key) (err error) {
 	if ks.readOnly {
-		return errors.New("read only KeyStore")
+		return bccsp.Errorf(bccsp.ErrCodeReadOnlyKeyStore, "read only KeyStore")
 	}
 
 	if k == nil {
-		return errors.New("invalid key. It must be different from nil")
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "invalid key. It must be different from nil")
 	}
+	if bccsp.IsNonExportable(k) {
+		return bccsp.Errorf(bccsp.ErrCodeKeyNotExportable, "refusing to store non-exportable key %x", k.SKI())
+	}
+	alias := hex.EncodeToString(k.SKI())
 	switch kk := k.(type) {
 	case *ecdsaPrivateKey:
-		err = ks.storePrivateKey(hex.EncodeToString(k.SKI()), kk.privKey)
+		err = ks.storePrivateKey(alias, kk.privKey)
 		if err != nil {
 			return fmt.Errorf("failed storing ECDSA private key [%s]", err)
 		}
+		err = ks.storeKeyAttributes(alias, k)
+		if err != nil {
+			return fmt.Errorf("failed storing ECDSA private key attributes [%s]", err)
+		}
 	case *sm2PrivateKey:
-		err = ks.storePrivateKey(hex.EncodeToString(k.SKI()), kk.privKey)
+		err = ks.storePrivateKey(alias, kk.privKey)
 		if err != nil {
 			return fmt.Errorf("failed storing SM2 private key [%s]", err)
 		}
+		err = ks.storeKeyAttributes(alias, k)
+		if err != nil {
+			return fmt.Errorf("failed storing SM2 private key attributes [%s]", err)
+		}
 
 	case *ecdsaPublicKey:
-		err = ks.storePublicKey(hex.EncodeToString(k.SKI()), kk.pubKey)
+		err = ks.storePublicKey(alias, kk.pubKey)
 		if err != nil {
 			return fmt.Errorf("failed storing ECDSA public key [%s]", err)
 		}
 
 	case *sm2PublicKey:
-		err = ks.storePublicKey(hex.EncodeToString(k.SKI()), kk.pubKey)
+		err = ks.storePublicKey(alias, kk.pubKey)
 		if err != nil {
 			return fmt.Errorf("failed storing SM2 public key [%s]", err)
 		}
 
 	case *aesPrivateKey:
-		err = ks.storeKey(hex.EncodeToString(k.SKI()), kk.privKey)
+		err = ks.storeKey(alias, kk.privKey)
 		if err != nil {
 			return fmt.Errorf("failed storing AES key [%s]", err)
 		}
 
 	case *sm4PrivateKey:
-		err = ks.storeSm4Key(hex.EncodeToString(k.SKI()), kk.privKey)
+		err = ks.storeSm4Key(alias, kk.privKey)
 		if err != nil {
 			return fmt.Errorf("failed storing SM4 key [%s]", err)
 		}
 
 	default:
-		return fmt.Errorf("key type not reconigned [%s]", k)
+		return bccsp.Errorf(bccsp.ErrCodeUnsupportedKeyType, "key type not reconigned [%s]", k)
 	}
 
+	ks.index.add(alias, keyFileSuffix(k))
 	return
 }
 
+// keyFileSuffix returns the suffix of the name of the file storing k.
+func keyFileSuffix(k bccsp.Key) string {
+	switch k.(type) {
+	case *aesPrivateKey:
+		return "key"
+	case *sm4PrivateKey:
+		return "sm4key"
+	}
+	if k.Private() {
+		return "sk"
+	}
+	return "pk"
+}
+
+// searchKeystoreForSKI looks for the key whose SKI is ski under any SKI
+// convention, so that keys stored before the convention of the provider
+// changed are still found.
 func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err error) {
+	if name, ok := ks.index.file(hex.EncodeToString(ski)); ok {
+		if k, ok := ks.keyFromFile(name, ski); ok {
+			return k, nil
+		}
+	}
 
 	files, _ := ioutil.ReadDir(ks.path)
 	for _, f := range files {
@@ -243,48 +300,86 @@ func (ks *fileBasedKeyStore) searchKeystoreForSKI(ski []byte) (k bccsp.Key, err
 			continue
 		}
 
-		raw, err := ioutil.ReadFile(filepath.Join(ks.path, f.Name()))
-		if err != nil {
-			continue
+		if k, ok := ks.keyFromFile(f.Name(), ski); ok {
+			return k, nil
 		}
+	}
+	return nil, bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "key with SKI %x not found in %s", ski, ks.path)
+}
 
-		key, err := utils.PEMtoPrivateKey(raw, ks.pwd)
-		if err != nil {
-			continue
-		}
+// keyFromFile returns the asymmetric key stored in the file name if its SKI
+// is ski under any SKI convention.
+func (ks *fileBasedKeyStore) keyFromFile(name string, ski []byte) (bccsp.Key, bool) {
+	k, pub, ok := ks.readAsymmetricKey(name)
+	if !ok {
+		return nil, false
+	}
 
+	c, ok := utils.MatchSKI(pub, ski)
+	if !ok {
+		return nil, false
+	}
+	if c != utils.GetSKIConvention() {
+		logger.Debugf("Found key [%x] stored as [%s] under SKI convention [%s]", ski, name, c)
+	}
+
+	attrs := ks.loadKeyAttributes(strings.TrimSuffix(name, "_sk"))
+	switch kk := k.(type) {
+	case *ecdsaPrivateKey:
+		kk.attrs = attrs
+	case *sm2PrivateKey:
+		kk.usage = bccsp.ParseSM2KeyUsage(attrs[bccsp.KeyAttrUsage])
+		kk.attrs = attrs
+	}
+	return withSKIConvention(k, &c), true
+}
+
+// readAsymmetricKey returns the asymmetric key stored in the file name and
+// its public key, if the file holds one.
+func (ks *fileBasedKeyStore) readAsymmetricKey(name string) (k bccsp.Key, pub interface{}, ok bool) {
+	raw, err := ioutil.ReadFile(filepath.Join(ks.path, name))
+	if err != nil {
+		return nil, nil, false
+	}
+
+	if key, err := utils.PEMtoPrivateKey(raw, ks.pwd); err == nil {
 		switch kk := key.(type) {
 		case *ecdsa.PrivateKey:
-			k = &ecdsaPrivateKey{kk}
+			return &ecdsaPrivateKey{privKey: kk}, &kk.PublicKey, true
 		case *sm2.PrivateKey: // SM2 private key
-			k = &sm2PrivateKey{kk}
-		default:
-			continue
+			return &sm2PrivateKey{privKey: kk}, &kk.PublicKey, true
 		}
-
-		if !bytes.Equal(k.SKI(), ski) {
-			continue
+	} else if key, err := utils.PEMtoPublicKey(raw, ks.pwd); err == nil {
+		switch kk := key.(type) {
+		case *ecdsa.PublicKey:
+			return &ecdsaPublicKey{pubKey: kk}, kk, true
+		case *sm2.PublicKey: // SM2 public key
+			return &sm2PublicKey{pubKey: kk}, kk, true
 		}
-
-		return k, nil
 	}
-	return nil, fmt.Errorf("key with SKI %x not found in %s", ski, ks.path)
+	return nil, nil, false
 }
 
 func (ks *fileBasedKeyStore) getSuffix(alias string) string {
+	if suffix, ok := ks.index.suffix(alias); ok {
+		return suffix
+	}
+
 	files, _ := ioutil.ReadDir(ks.path)
 	for _, f := range files {
 		if strings.HasPrefix(f.Name(), alias) {
-			if strings.HasSuffix(f.Name(), "sk") {
-				return "sk"
-			}
-			if strings.HasSuffix(f.Name(), "pk") {
-				return "pk"
-			}
-			if strings.HasSuffix(f.Name(), "key") {
-				return "key"
-			}
-			break
+			return fileSuffix(f.Name())
+		}
+	}
+	return ""
+}
+
+// fileSuffix returns the suffix of the name of a key file, empty if the
+// name has none.
+func fileSuffix(name string) string {
+	for _, suffix := range []string{"sk", "pk", "sm4key", "key"} {
+		if strings.HasSuffix(name, suffix) {
+			return suffix
 		}
 	}
 	return ""
@@ -457,13 +552,143 @@ func (ks *fileBasedKeyStore) openKeyStore() error {
 	ks.isOpen = true
 	logger.Debugf("KeyStore opened at [%s]...done", ks.path)
 
+	ks.index = newKeyIndex()
+	if ks.ready == nil {
+		ks.ready = make(chan struct{})
+	}
+	go ks.buildIndex(ks.ready)
+
 	return nil
 }
 
+// buildIndex indexes the keys of the folder, then closes ready.
+func (ks *fileBasedKeyStore) buildIndex(ready chan struct{}) {
+	defer close(ready)
+	start := time.Now()
+
+	names, err := readDirNames(ks.path)
+	if err != nil {
+		logger.Warningf("Failed indexing KeyStore at [%s], keys will be looked up by scanning it: [%s]", ks.path, err)
+		return
+	}
+
+	for _, name := range names {
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		alias := ""
+		if i := strings.LastIndex(name, "_"); i > 0 && name[i+1:] == fileSuffix(name) {
+			alias = name[:i]
+			ks.index.add(alias, name[i+1:])
+			if !strings.HasSuffix(name, "_sk") && !strings.HasSuffix(name, "_pk") {
+				continue
+			}
+		}
+
+		// Asymmetric keys are also indexed under the SKIs of the other
+		// conventions, which searchKeystoreForSKI looks them up by
+		fi, err := os.Stat(filepath.Join(ks.path, name))
+		if err != nil || fi.IsDir() || fi.Size() > (1<<16) {
+			continue
+		}
+		_, pub, ok := ks.readAsymmetricKey(name)
+		if !ok {
+			continue
+		}
+		skis, err := utils.AllSKIs(pub)
+		if err != nil {
+			continue
+		}
+		for _, ski := range skis {
+			if s := hex.EncodeToString(ski); s != alias {
+				ks.index.addFile(s, name)
+			}
+		}
+	}
+
+	logger.Debugf("KeyStore at [%s] indexed: %d files in %s", ks.path, len(names), time.Since(start))
+}
+
 func (ks *fileBasedKeyStore) getPathForAlias(alias, suffix string) string {
 	return filepath.Join(ks.path, alias+"_"+suffix)
 }
 
+// getAttributesPathForAlias returns the path of the sidecar file holding the
+// attributes of a key. It is hidden so that it is not mistaken for a key file.
+func (ks *fileBasedKeyStore) getAttributesPathForAlias(alias string) string {
+	return filepath.Join(ks.path, "."+alias+".attrs")
+}
+
+// keyIndex maps the aliases of the keys of a fileBasedKeyStore to the
+// suffixes of their files, and the SKIs, hex encoded, of the asymmetric keys
+// stored under another alias to their files. The nil index is empty.
+type keyIndex struct {
+	lock     sync.RWMutex
+	suffixes map[string]string
+	files    map[string]string
+}
+
+func newKeyIndex() *keyIndex {
+	return &keyIndex{suffixes: map[string]string{}, files: map[string]string{}}
+}
+
+func (i *keyIndex) add(alias, suffix string) {
+	if i == nil {
+		return
+	}
+	i.lock.Lock()
+	i.suffixes[alias] = suffix
+	i.lock.Unlock()
+}
+
+// addFile records that the key whose SKI is ski is stored in the file name,
+// unless another file was recorded first.
+func (i *keyIndex) addFile(ski, name string) {
+	i.lock.Lock()
+	if _, ok := i.files[ski]; !ok {
+		i.files[ski] = name
+	}
+	i.lock.Unlock()
+}
+
+func (i *keyIndex) suffix(alias string) (string, bool) {
+	if i == nil {
+		return "", false
+	}
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	suffix, ok := i.suffixes[alias]
+	return suffix, ok
+}
+
+func (i *keyIndex) file(ski string) (string, bool) {
+	if i == nil {
+		return "", false
+	}
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	name, ok := i.files[ski]
+	return name, ok
+}
+
+// readDirNames returns the sorted names of the entries of the folder at
+// path, without reading their metadata.
+func readDirNames(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func dirExists(path string) (bool, error) {
 	_, err := os.Stat(path)
 	if err == nil {
@@ -10,12 +10,16 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -37,22 +41,22 @@ func TestInvalidStoreKey(t *testing.T) {
 		t.Fatal("Error should be different from nil in this case")
 	}
 
-	err = ks.StoreKey(&ecdsaPrivateKey{nil})
+	err = ks.StoreKey(&ecdsaPrivateKey{privKey: nil})
 	if err == nil {
 		t.Fatal("Error should be different from nil in this case")
 	}
 
-	err = ks.StoreKey(&sm2PrivateKey{nil})
+	err = ks.StoreKey(&sm2PrivateKey{privKey: nil})
 	if err == nil {
 		t.Fatal("Error should be different from nil in this case")
 	}
 
-	err = ks.StoreKey(&ecdsaPublicKey{nil})
+	err = ks.StoreKey(&ecdsaPublicKey{pubKey: nil})
 	if err == nil {
 		t.Fatal("Error should be different from nil in this case")
 	}
 
-	err = ks.StoreKey(&sm2PublicKey{nil})
+	err = ks.StoreKey(&sm2PublicKey{pubKey: nil})
 	if err == nil {
 		t.Fatal("Error should be different from nil in this case")
 	}
@@ -81,7 +85,7 @@ func TestBigKeyFile(t *testing.T) {
 	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
 
-	cspKey := &ecdsaPrivateKey{privKey}
+	cspKey := &ecdsaPrivateKey{privKey: privKey}
 	ski := cspKey.SKI()
 	rawKey, err := utils.PrivateKeyToPEM(privKey, nil)
 	assert.NoError(t, err)
@@ -108,6 +112,44 @@ func TestBigKeyFile(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestKeyAttributesSidecar(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+
+	k, err := (&sm2KeyGenerator{}).KeyGen(&bccsp.SM2KeyGenOpts{})
+	assert.NoError(t, err)
+	attrs := k.(bccsp.AttributedKey).KeyAttributes()
+	assert.Equal(t, bccsp.KeyOriginGenerated, attrs[bccsp.KeyAttrOrigin])
+	assert.Equal(t, "SW", attrs[bccsp.KeyAttrProvider])
+	assert.Equal(t, "true", attrs[bccsp.KeyAttrExportable])
+	assert.NotEmpty(t, attrs[bccsp.KeyAttrCreated])
+
+	err = ks.StoreKey(k)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(ksPath, "."+hex.EncodeToString(k.SKI())+".attrs"))
+
+	k2, err := ks.GetKey(k.SKI())
+	assert.NoError(t, err)
+	assert.Equal(t, attrs, k2.(bccsp.AttributedKey).KeyAttributes())
+
+	// The returned map is a copy
+	attrs[bccsp.KeyAttrOrigin] = bccsp.KeyOriginImported
+	assert.Equal(t, bccsp.KeyOriginGenerated, k2.(bccsp.AttributedKey).KeyAttributes()[bccsp.KeyAttrOrigin])
+
+	// Keys stored without a sidecar have no attributes
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	err = ks.StoreKey(&ecdsaPrivateKey{privKey: privKey})
+	assert.NoError(t, err)
+	k3, err := ks.GetKey((&ecdsaPrivateKey{privKey: privKey}).SKI())
+	assert.NoError(t, err)
+	assert.Nil(t, k3.(bccsp.AttributedKey).KeyAttributes())
+}
+
 func TestReInitKeyStore(t *testing.T) {
 	ksPath, err := ioutil.TempDir("", "bccspks")
 	assert.NoError(t, err)
@@ -150,3 +192,133 @@ func TestDirEmpty(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, false, r)
 }
+
+func TestFileKeyStoreErrorCodes(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	ks, err := NewFileBasedKeyStore(nil, ksPath, true)
+	assert.NoError(t, err)
+
+	_, err = ks.GetKey([]byte{1, 2, 3})
+	assert.True(t, errors.Is(err, bccsp.ErrKeyNotFound))
+
+	err = ks.StoreKey(&aesPrivateKey{nil, false})
+	assert.EqualError(t, err, "read only KeyStore")
+	assert.True(t, errors.Is(err, bccsp.ErrReadOnlyKeyStore))
+}
+
+// nonExportableKey is a private key held by a hardware provider.
+type nonExportableKey struct{}
+
+func (k *nonExportableKey) Bytes() ([]byte, error)        { return nil, errors.New("Not supported.") }
+func (k *nonExportableKey) SKI() []byte                   { return []byte{1, 2, 3} }
+func (k *nonExportableKey) Symmetric() bool               { return false }
+func (k *nonExportableKey) Private() bool                 { return true }
+func (k *nonExportableKey) PublicKey() (bccsp.Key, error) { return nil, errors.New("Not supported.") }
+func (k *nonExportableKey) KeyAttributes() map[string]string {
+	return map[string]string{bccsp.KeyAttrExportable: "false"}
+}
+
+func TestStoreNonExportableKey(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+
+	err = ks.StoreKey(&nonExportableKey{})
+	assert.EqualError(t, err, "refusing to store non-exportable key 010203")
+	assert.True(t, errors.Is(err, bccsp.ErrKeyNotExportable))
+
+	err = NewInMemoryKeyStore().StoreKey(&nonExportableKey{})
+	assert.True(t, errors.Is(err, bccsp.ErrKeyNotExportable))
+}
+
+func TestFileKeyStoreIndex(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	assert.NoError(t, err)
+	<-ks.(bccsp.IndexedKeyStore).Ready()
+
+	sm2Key, err := (&sm2KeyGenerator{}).KeyGen(&bccsp.SM2KeyGenOpts{})
+	assert.NoError(t, err)
+	sm2PubKey, err := sm2Key.PublicKey()
+	assert.NoError(t, err)
+	sm4Key, err := (&sm4KeyGenerator{length: 16}).KeyGen(&bccsp.SM4KeyGenOpts{})
+	assert.NoError(t, err)
+	for _, k := range []bccsp.Key{sm2Key, sm2PubKey, sm4Key} {
+		assert.NoError(t, ks.StoreKey(k))
+	}
+
+	// A key stored under a legacy file name
+	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	rawKey, err := utils.PrivateKeyToPEM(privKey, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(ksPath, "legacy.pem"), rawKey, 0600))
+	legacySKI, err := utils.ComputeSKI(&privKey.PublicKey, utils.SKISHA1)
+	assert.NoError(t, err)
+
+	// The keys stored are indexed on open
+	reopened, err := NewFileBasedKeyStore(nil, ksPath, true)
+	assert.NoError(t, err)
+	fks := reopened.(*fileBasedKeyStore)
+	select {
+	case <-fks.Ready():
+	case <-time.After(10 * time.Second):
+		t.Fatal("KeyStore should be indexed")
+	}
+
+	for _, k := range []bccsp.Key{sm2Key, sm4Key} {
+		suffix, ok := fks.index.suffix(hex.EncodeToString(k.SKI()))
+		assert.True(t, ok)
+		assert.Equal(t, keyFileSuffix(k), suffix)
+
+		k2, err := reopened.GetKey(k.SKI())
+		assert.NoError(t, err)
+		assert.Equal(t, k.SKI(), k2.SKI())
+	}
+	name, ok := fks.index.file(hex.EncodeToString(legacySKI))
+	assert.True(t, ok)
+	assert.Equal(t, "legacy.pem", name)
+	k, err := reopened.GetKey(legacySKI)
+	assert.NoError(t, err)
+	assert.Equal(t, legacySKI, k.SKI())
+
+	// Keys written after the index was built are still found
+	assert.NoError(t, ks.StoreKey(&sm4PrivateKey{[]byte("0123456789abcdef"), false}))
+	_, err = reopened.GetKey((&sm4PrivateKey{[]byte("0123456789abcdef"), false}).SKI())
+	assert.NoError(t, err)
+}
+
+func TestFileKeyStoreReadyBeforeInit(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	assert.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	ks := &fileBasedKeyStore{}
+	ready := ks.Ready()
+	select {
+	case <-ready:
+		t.Fatal("KeyStore should not be ready before it is opened")
+	default:
+	}
+
+	assert.NoError(t, ks.Init(nil, ksPath, false))
+	<-ready
+	assert.Equal(t, ready, ks.Ready())
+
+	csp, err := NewWithParams(256, "SM3", ks, WithSKIConvention(utils.SKISM3))
+	assert.NoError(t, err)
+	assert.Equal(t, ready, csp.(*CSP).KeyStoreReady())
+
+	csp, err = NewWithParams(256, "SM3", NewInMemoryKeyStore())
+	assert.NoError(t, err)
+	<-csp.(*CSP).KeyStoreReady()
+}
@@ -0,0 +1,68 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+const (
+	// GMBackendGo 为纯Go实现的GM算法, 不依赖cgo, 可交叉编译, 为默认实现
+	GMBackendGo = "go"
+	// GMBackendGmSSL 为通过cgo绑定GmSSL 3.x的GM算法实现, 需使用gmssl构建标签编译
+	GMBackendGmSSL = "gmssl"
+)
+
+// gmBackend 为GM算法中计算量最大的原语, 即SM2签名、验签与SM3杂凑的实现。
+// SM4在两种实现中均使用sm4包: 逐分组的cgo调用开销高于其汇编实现。
+type gmBackend interface {
+	// sm2Sign 使用默认用户识别码对msg生成DER编码的SM2签名, prng为nil时
+	// 使用实现自身的随机数源
+	sm2Sign(prng io.Reader, k *sm2.PrivateKey, msg []byte) ([]byte, error)
+
+	// sm2Verify 使用默认用户识别码验证msg的DER编码的SM2签名
+	sm2Verify(k *sm2.PublicKey, der, msg []byte) bool
+
+	// newSM3 返回SM3杂凑函数
+	newSM3() hash.Hash
+}
+
+// newGMBackend 返回名为name的GM算法实现, name为空时返回纯Go实现。
+func newGMBackend(name string) (gmBackend, error) {
+	switch name {
+	case "", GMBackendGo:
+		return goGMBackend{}, nil
+	case GMBackendGmSSL:
+		return newGmSSLBackend()
+	default:
+		return nil, fmt.Errorf("GM backend not supported [%s], it must be %s or %s", name, GMBackendGo, GMBackendGmSSL)
+	}
+}
+
+// goGMBackend 为纯Go实现, 基于gm库及sm3包。
+type goGMBackend struct{}
+
+func (goGMBackend) sm2Sign(prng io.Reader, k *sm2.PrivateKey, msg []byte) ([]byte, error) {
+	if prng != nil {
+		return signSM2WithRand(prng, k, msg, nil)
+	}
+	return signSM2(k, msg, nil)
+}
+
+func (goGMBackend) sm2Verify(k *sm2.PublicKey, der, msg []byte) bool {
+	valid, _ := verifySM2(k, der, msg, nil)
+	return valid
+}
+
+func (goGMBackend) newSM3() hash.Hash {
+	return sm3.New()
+}
@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"go/build"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// availableGMBackends returns the GM backends of this build.
+func availableGMBackends(t *testing.T) map[string]gmBackend {
+	backends := map[string]gmBackend{}
+	for _, name := range []string{GMBackendGo, GMBackendGmSSL} {
+		if gm, err := newGMBackend(name); err == nil {
+			backends[name] = gm
+		}
+	}
+	require.Contains(t, backends, GMBackendGo)
+	return backends
+}
+
+func TestNewGMBackend(t *testing.T) {
+	gm, err := newGMBackend("")
+	assert.NoError(t, err)
+	assert.Equal(t, goGMBackend{}, gm)
+
+	_, err = newGMBackend("openssl")
+	assert.EqualError(t, err, "GM backend not supported [openssl], it must be go or gmssl")
+
+	_, err = NewWithParams(256, "SM3", NewDummyKeyStore(), WithGMBackend("openssl"))
+	assert.EqualError(t, err, "Failed initializing GM backend: GM backend not supported [openssl], it must be go or gmssl")
+}
+
+func TestGMBackendsInteroperate(t *testing.T) {
+	backends := availableGMBackends(t)
+	k, err := (&sm2KeyGenerator{}).KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	priv := k.(*sm2PrivateKey).privKey
+	msg := []byte("Hello World")
+
+	for signerName, signer := range backends {
+		der, err := signer.sm2Sign(nil, priv, msg)
+		require.NoError(t, err, signerName)
+		for verifierName, verifier := range backends {
+			assert.True(t, verifier.sm2Verify(&priv.PublicKey, der, msg), "%s signature verified by %s", signerName, verifierName)
+			assert.False(t, verifier.sm2Verify(&priv.PublicKey, der, []byte("Hello World!")), verifierName)
+		}
+	}
+
+	for name, gm := range backends {
+		h := gm.newSM3()
+		h.Write(msg[:5])
+		h.Write(msg[5:])
+		sum := sm3.Sum(msg)
+		assert.Equal(t, sum[:], h.Sum(nil), name)
+		h.Reset()
+		sum = sm3.Sum(nil)
+		assert.Equal(t, sum[:], h.Sum(nil), name)
+	}
+}
+
+// TestPureGoBuild checks that the packages of the default GM provider do
+// not use cgo unless a build tag selects it, so that they cross-compile.
+func TestPureGoBuild(t *testing.T) {
+	ctxt := build.Default
+	ctxt.CgoEnabled = true
+	ctxt.BuildTags = nil
+
+	for _, dir := range []string{".", "../sm3", "../sm4", "../utils"} {
+		pkg, err := ctxt.ImportDir(dir, 0)
+		require.NoError(t, err, dir)
+		assert.Empty(t, pkg.CgoFiles, dir)
+	}
+}
@@ -0,0 +1,186 @@
+// +build gmssl,cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+/*
+#cgo LDFLAGS: -lgmssl
+#include <string.h>
+#include <gmssl/sm2.h>
+#include <gmssl/sm3.h>
+
+static int gmssl_sm2_sign(const uint8_t priv[32], const uint8_t dgst[32], uint8_t r[32], uint8_t s[32]) {
+	SM2_KEY key;
+	SM2_SIGNATURE sig;
+	int ret = -1;
+
+	if (sm2_key_set_private_key(&key, priv) == 1) {
+		ret = sm2_do_sign(&key, dgst, &sig);
+	}
+	if (ret == 1) {
+		memcpy(r, sig.r, 32);
+		memcpy(s, sig.s, 32);
+	}
+	gmssl_secure_clear(&key, sizeof(key));
+	return ret;
+}
+
+static int gmssl_sm2_verify(const uint8_t x[32], const uint8_t y[32], const uint8_t dgst[32], const uint8_t r[32], const uint8_t s[32]) {
+	SM2_KEY key;
+	SM2_POINT point;
+	SM2_SIGNATURE sig;
+
+	memcpy(point.x, x, 32);
+	memcpy(point.y, y, 32);
+	if (sm2_key_set_public_key(&key, &point) != 1) {
+		return -1;
+	}
+	memcpy(sig.r, r, 32);
+	memcpy(sig.s, s, 32);
+	return sm2_do_verify(&key, dgst, &sig);
+}
+*/
+import "C"
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"hash"
+	"io"
+	"math/big"
+	"unsafe"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// gmsslBackend 通过cgo调用GmSSL 3.x (libgmssl) 的SM2与SM3实现。GmSSL仅支持
+// sm2p256v1曲线, 其他曲线上的密钥使用纯Go实现; 签名使用GmSSL自身的随机数源,
+// 忽略prng。
+type gmsslBackend struct{}
+
+func newGmSSLBackend() (gmBackend, error) {
+	return gmsslBackend{}, nil
+}
+
+// isSM2P256V1 返回curve是否为GmSSL支持的sm2p256v1曲线。
+func isSM2P256V1(curve elliptic.Curve) bool {
+	if curve == nil {
+		return false
+	}
+	params, sm2Params := curve.Params(), sm2.GetSm2P256V1().Params()
+	return params.P.Cmp(sm2Params.P) == 0 && params.N.Cmp(sm2Params.N) == 0 &&
+		params.Gx.Cmp(sm2Params.Gx) == 0 && params.Gy.Cmp(sm2Params.Gy) == 0
+}
+
+// sm2Digest 返回SM3(Z_A || msg), 即GmSSL所签名的杂凑值e。
+func (b gmsslBackend) sm2Digest(pub *sm2.PublicKey, msg []byte) ([]byte, error) {
+	za, err := utils.SM2ZA(pub, nil)
+	if err != nil {
+		return nil, err
+	}
+	h := b.newSM3()
+	h.Write(za)
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (b gmsslBackend) sm2Sign(prng io.Reader, k *sm2.PrivateKey, msg []byte) ([]byte, error) {
+	if !isSM2P256V1(k.Curve) {
+		return goGMBackend{}.sm2Sign(prng, k, msg)
+	}
+	dgst, err := b.sm2Digest(&k.PublicKey, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var priv, r, s [32]byte
+	if !fillBytes(&priv, k.D) {
+		return nil, errors.New("invalid SM2 private key")
+	}
+	ret := C.gmssl_sm2_sign(cBytes(priv[:]), cBytes(dgst), cBytes(r[:]), cBytes(s[:]))
+	for i := range priv {
+		priv[i] = 0
+	}
+	if ret != 1 {
+		return nil, errors.New("GmSSL failed signing with the SM2 key")
+	}
+	return utils.MarshalECDSASignature(new(big.Int).SetBytes(r[:]), new(big.Int).SetBytes(s[:]))
+}
+
+func (b gmsslBackend) sm2Verify(k *sm2.PublicKey, der, msg []byte) bool {
+	if !isSM2P256V1(k.Curve) {
+		return goGMBackend{}.sm2Verify(k, der, msg)
+	}
+	rInt, sInt, err := utils.UnmarshalECDSASignature(der)
+	if err != nil {
+		return false
+	}
+	var x, y, r, s [32]byte
+	if !fillBytes(&x, k.X) || !fillBytes(&y, k.Y) || !fillBytes(&r, rInt) || !fillBytes(&s, sInt) {
+		return false
+	}
+	dgst, err := b.sm2Digest(k, msg)
+	if err != nil {
+		return false
+	}
+
+	return C.gmssl_sm2_verify(cBytes(x[:]), cBytes(y[:]), cBytes(dgst), cBytes(r[:]), cBytes(s[:])) == 1
+}
+
+// fillBytes 将非负整数v以大端序写入dst, v超过32字节时返回false。
+func fillBytes(dst *[32]byte, v *big.Int) bool {
+	if v == nil || v.Sign() < 0 || v.BitLen() > 256 {
+		return false
+	}
+	b := v.Bytes()
+	copy(dst[len(dst)-len(b):], b)
+	return true
+}
+
+// cBytes 返回指向b首字节的C指针, b不能为空。
+func cBytes(b []byte) *C.uint8_t {
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
+}
+
+func (gmsslBackend) newSM3() hash.Hash {
+	h := &gmsslSM3{}
+	h.Reset()
+	return h
+}
+
+// gmsslSM3 为GmSSL的SM3实现。
+type gmsslSM3 struct {
+	ctx C.SM3_CTX
+}
+
+func (h *gmsslSM3) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		C.sm3_update(&h.ctx, cBytes(p), C.size_t(len(p)))
+	}
+	return len(p), nil
+}
+
+func (h *gmsslSM3) Sum(b []byte) []byte {
+	ctx := h.ctx
+	var dgst [32]byte
+	C.sm3_finish(&ctx, cBytes(dgst[:]))
+	return append(b, dgst[:]...)
+}
+
+func (h *gmsslSM3) Reset() {
+	C.sm3_init(&h.ctx)
+}
+
+func (h *gmsslSM3) Size() int {
+	return 32
+}
+
+func (h *gmsslSM3) BlockSize() int {
+	return 64
+}
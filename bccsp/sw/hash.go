@@ -18,20 +18,44 @@ package sw
 
 import (
 	"hash"
+	"sync"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 )
 
 type hasher struct {
 	hash func() hash.Hash
+	pool *sync.Pool // 可选的哈希状态池, 为nil时每次调用都新建哈希实例
+}
+
+// newPooledHasher returns a hasher whose one-shot Hash calls reuse
+// hash states from a sync.Pool instead of allocating a new one each time.
+func newPooledHasher(f func() hash.Hash) *hasher {
+	return &hasher{
+		hash: f,
+		pool: &sync.Pool{
+			New: func() interface{} { return f() },
+		},
+	}
 }
 
 func (c *hasher) Hash(msg []byte, opts bccsp.HashOpts) ([]byte, error) {
-	h := c.hash()
+	if c.pool == nil {
+		h := c.hash()
+		h.Write(msg)
+		return h.Sum(nil), nil
+	}
+
+	h := c.pool.Get().(hash.Hash)
+	defer c.pool.Put(h)
+
+	h.Reset()
 	h.Write(msg)
 	return h.Sum(nil), nil
 }
 
+// GetHash returns a fresh hash.Hash which callers can stream large payloads
+// into. Ownership passes to the caller, so it is never taken from the pool.
 func (c *hasher) GetHash(opts bccsp.HashOpts) (hash.Hash, error) {
 	return c.hash(), nil
 }
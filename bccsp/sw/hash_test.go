@@ -157,3 +157,54 @@ func TestSM3Hasher(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, hf, sm3.New())
 }
+
+func TestPooledSM3Hasher(t *testing.T) {
+	t.Parallel()
+
+	hasher := newPooledHasher(sm3.New)
+
+	msg1 := []byte("Hello World")
+	msg2 := []byte("Hello Again")
+	h := sm3.New()
+	h.Write(msg1)
+	expected1 := h.Sum(nil)
+	h = sm3.New()
+	h.Write(msg2)
+	expected2 := h.Sum(nil)
+
+	// Repeated calls must not leak state between pooled instances.
+	for i := 0; i < 10; i++ {
+		out, err := hasher.Hash(msg1, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected1, out)
+
+		out, err = hasher.Hash(msg2, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected2, out)
+	}
+
+	hf, err := hasher.GetHash(nil)
+	assert.NoError(t, err)
+	hf.Write(msg1[:5])
+	hf.Write(msg1[5:])
+	assert.Equal(t, expected1, hf.Sum(nil))
+}
+
+func TestPooledHasher(t *testing.T) {
+	t.Parallel()
+
+	unpooled := &hasher{hash: sha256.New}
+	hasher := newPooledHasher(sha256.New)
+	for _, msg := range [][]byte{[]byte("Hello World"), []byte("Hello Again"), nil} {
+		expected := sha256.Sum256(msg)
+		out, err := hasher.Hash(msg, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected[:], out)
+	}
+
+	// Hash states are not allocated for every digest
+	msg := []byte("Hello World")
+	allocs := testing.AllocsPerRun(100, func() { hasher.Hash(msg, nil) })
+	unpooledAllocs := testing.AllocsPerRun(100, func() { unpooled.Hash(msg, nil) })
+	assert.Less(t, allocs, unpooledAllocs)
+}
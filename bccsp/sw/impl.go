@@ -16,10 +16,13 @@ limitations under the License.
 package sw
 
 import (
+	"context"
 	"hash"
 	"reflect"
+	"sync"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/common/flogging"
 	"github.com/pkg/errors"
 )
@@ -44,11 +47,16 @@ type CSP struct {
 	Signers       map[reflect.Type]Signer
 	Verifiers     map[reflect.Type]Verifier
 	Hashers       map[reflect.Type]Hasher
+
+	signHooksMutex sync.RWMutex
+	signHooks      []SignHook
+	hashFamily     string
+	skiConvention  *utils.SKIConvention
 }
 
 func New(keyStore bccsp.KeyStore) (*CSP, error) {
 	if keyStore == nil {
-		return nil, errors.Errorf("Invalid bccsp.KeyStore instance. It must be different from nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid bccsp.KeyStore instance. It must be different from nil.")
 	}
 
 	encryptors := make(map[reflect.Type]Encryptor)
@@ -60,9 +68,17 @@ func New(keyStore bccsp.KeyStore) (*CSP, error) {
 	keyDerivers := make(map[reflect.Type]KeyDeriver)
 	keyImporters := make(map[reflect.Type]KeyImporter)
 
-	csp := &CSP{keyStore,
-		keyGenerators, keyDerivers, keyImporters, encryptors,
-		decryptors, signers, verifiers, hashers}
+	csp := &CSP{
+		ks:            keyStore,
+		KeyGenerators: keyGenerators,
+		KeyDerivers:   keyDerivers,
+		KeyImporters:  keyImporters,
+		Encryptors:    encryptors,
+		Decryptors:    decryptors,
+		Signers:       signers,
+		Verifiers:     verifiers,
+		Hashers:       hashers,
+	}
 
 	return csp, nil
 }
@@ -71,18 +87,19 @@ func New(keyStore bccsp.KeyStore) (*CSP, error) {
 func (csp *CSP) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 	// Validate arguments
 	if opts == nil {
-		return nil, errors.New("Invalid Opts parameter. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid Opts parameter. It must not be nil.")
 	}
 
 	keyGenerator, found := csp.KeyGenerators[reflect.TypeOf(opts)]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'KeyGenOpts' provided [%v]", opts)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'KeyGenOpts' provided [%v]", opts)
 	}
 
 	k, err = keyGenerator.KeyGen(opts)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed generating key with opts [%v]", opts)
 	}
+	k = withSKIConvention(k, csp.skiConvention)
 
 	// If the key is not Ephemeral, store it.
 	if !opts.Ephemeral() {
@@ -101,21 +118,22 @@ func (csp *CSP) KeyGen(opts bccsp.KeyGenOpts) (k bccsp.Key, err error) {
 func (csp *CSP) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (dk bccsp.Key, err error) {
 	// Validate arguments
 	if k == nil {
-		return nil, errors.New("Invalid Key. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid Key. It must not be nil.")
 	}
 	if opts == nil {
-		return nil, errors.New("Invalid opts. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid opts. It must not be nil.")
 	}
 
 	keyDeriver, found := csp.KeyDerivers[reflect.TypeOf(k)]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'Key' provided [%v]", k)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'Key' provided [%v]", k)
 	}
 
 	k, err = keyDeriver.KeyDeriv(k, opts)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed deriving key with opts [%v]", opts)
 	}
+	k = withSKIConvention(k, csp.skiConvention)
 
 	// If the key is not Ephemeral, store it.
 	if !opts.Ephemeral() {
@@ -134,21 +152,22 @@ func (csp *CSP) KeyDeriv(k bccsp.Key, opts bccsp.KeyDerivOpts) (dk bccsp.Key, er
 func (csp *CSP) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (k bccsp.Key, err error) {
 	// Validate arguments
 	if raw == nil {
-		return nil, errors.New("Invalid raw. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid raw. It must not be nil.")
 	}
 	if opts == nil {
-		return nil, errors.New("Invalid opts. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid opts. It must not be nil.")
 	}
 
 	keyImporter, found := csp.KeyImporters[reflect.TypeOf(opts)]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'KeyImportOpts' provided [%v]", opts)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'KeyImportOpts' provided [%v]", opts)
 	}
 
 	k, err = keyImporter.KeyImport(raw, opts)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed importing key with opts [%v]", opts)
 	}
+	k = withSKIConvention(k, csp.skiConvention)
 
 	// If the key is not Ephemeral, store it.
 	if !opts.Ephemeral() {
@@ -173,16 +192,38 @@ func (csp *CSP) GetKey(ski []byte) (k bccsp.Key, err error) {
 	return
 }
 
+// KeyStoreReady returns a channel closed once the KeyStore of this CSP has
+// indexed its keys, see bccsp.IndexedKeyStore. The channel is closed
+// already for the KeyStores which do not index their keys.
+func (csp *CSP) KeyStoreReady() <-chan struct{} {
+	ks := csp.ks
+	for {
+		switch w := ks.(type) {
+		case *skiConventionKeyStore:
+			ks = w.KeyStore
+			continue
+		case *sm2PrecompKeyStore:
+			ks = w.KeyStore
+			continue
+		case bccsp.IndexedKeyStore:
+			return w.Ready()
+		}
+		ready := make(chan struct{})
+		close(ready)
+		return ready
+	}
+}
+
 // Hash hashes messages msg using options opts.
 func (csp *CSP) Hash(msg []byte, opts bccsp.HashOpts) (digest []byte, err error) {
 	// Validate arguments
 	if opts == nil {
-		return nil, errors.New("Invalid opts. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid opts. It must not be nil.")
 	}
 
 	hasher, found := csp.Hashers[reflect.TypeOf(opts)]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'HashOpt' provided [%v]", opts)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'HashOpt' provided [%v]", opts)
 	}
 
 	digest, err = hasher.Hash(msg, opts)
@@ -198,12 +239,12 @@ func (csp *CSP) Hash(msg []byte, opts bccsp.HashOpts) (digest []byte, err error)
 func (csp *CSP) GetHash(opts bccsp.HashOpts) (h hash.Hash, err error) {
 	// Validate arguments
 	if opts == nil {
-		return nil, errors.New("Invalid opts. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid opts. It must not be nil.")
 	}
 
 	hasher, found := csp.Hashers[reflect.TypeOf(opts)]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'HashOpt' provided [%v]", opts)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'HashOpt' provided [%v]", opts)
 	}
 
 	h, err = hasher.GetHash(opts)
@@ -221,22 +262,48 @@ func (csp *CSP) GetHash(opts bccsp.HashOpts) (h hash.Hash, err error) {
 // the caller is responsible for hashing the larger message and passing
 // the hash (as digest).
 func (csp *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
+	return csp.SignCtx(context.Background(), k, digest, opts)
+}
+
+// SignCtx signs digest using key k like Sign, but hands ctx to signers
+// implementing bccsp.ContextSigner and to the registered sign hooks.
+func (csp *CSP) SignCtx(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
 	// Validate arguments
+	if ctx == nil {
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid context. It must not be nil.")
+	}
 	if k == nil {
-		return nil, errors.New("Invalid Key. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid Key. It must not be nil.")
 	}
 	if len(digest) == 0 {
-		return nil, errors.New("Invalid digest. Cannot be empty.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid digest. Cannot be empty.")
 	}
 
 	keyType := reflect.TypeOf(k)
 	signer, found := csp.Signers[keyType]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'SignKey' provided [%s]", keyType)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'SignKey' provided [%s]", keyType)
+	}
+
+	csp.signHooksMutex.RLock()
+	signHooks := csp.signHooks
+	csp.signHooksMutex.RUnlock()
+	defer func() {
+		for _, hook := range signHooks {
+			hook(ctx, k, digest, opts, signature, err)
+		}
+	}()
+
+	if err = ctx.Err(); err != nil {
+		return nil, errors.Wrap(err, "Signing request aborted")
 	}
 
 	// SM2签名算法直接针对原始数据，不需要事先取哈希值
-	signature, err = signer.Sign(k, digest, opts)
+	if cs, ok := signer.(bccsp.ContextSigner); ok {
+		signature, err = cs.SignCtx(ctx, k, digest, opts)
+	} else {
+		signature, err = signer.Sign(k, digest, opts)
+	}
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed signing with opts [%v]", opts)
 	}
@@ -244,22 +311,37 @@ func (csp *CSP) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signatu
 	return
 }
 
+// AddSignHook registers a hook invoked after every signing request,
+// for example to audit signatures together with their request metadata.
+func (csp *CSP) AddSignHook(hook SignHook) error {
+	if hook == nil {
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "hook cannot be nil")
+	}
+	csp.signHooksMutex.Lock()
+	defer csp.signHooksMutex.Unlock()
+	// Copied on write, so that signing iterates the hooks without the lock
+	signHooks := make([]SignHook, len(csp.signHooks), len(csp.signHooks)+1)
+	copy(signHooks, csp.signHooks)
+	csp.signHooks = append(signHooks, hook)
+	return nil
+}
+
 // Verify verifies signature against key k and digest
 func (csp *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (valid bool, err error) {
 	// Validate arguments
 	if k == nil {
-		return false, errors.New("Invalid Key. It must not be nil.")
+		return false, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid Key. It must not be nil.")
 	}
 	if len(signature) == 0 {
-		return false, errors.New("Invalid signature. Cannot be empty.")
+		return false, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid signature. Cannot be empty.")
 	}
 	if len(digest) == 0 {
-		return false, errors.New("Invalid digest. Cannot be empty.")
+		return false, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid digest. Cannot be empty.")
 	}
 
 	verifier, found := csp.Verifiers[reflect.TypeOf(k)]
 	if !found {
-		return false, errors.Errorf("Unsupported 'VerifyKey' provided [%v]", k)
+		return false, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'VerifyKey' provided [%v]", k)
 	}
 
 	valid, err = verifier.Verify(k, signature, digest, opts)
@@ -275,12 +357,12 @@ func (csp *CSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerO
 func (csp *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
 	// Validate arguments
 	if k == nil {
-		return nil, errors.New("Invalid Key. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid Key. It must not be nil.")
 	}
 
 	encryptor, found := csp.Encryptors[reflect.TypeOf(k)]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'EncryptKey' provided [%v]", k)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'EncryptKey' provided [%v]", k)
 	}
 
 	return encryptor.Encrypt(k, plaintext, opts)
@@ -291,12 +373,12 @@ func (csp *CSP) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts)
 func (csp *CSP) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error) {
 	// Validate arguments
 	if k == nil {
-		return nil, errors.New("Invalid Key. It must not be nil.")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "Invalid Key. It must not be nil.")
 	}
 
 	decryptor, found := csp.Decryptors[reflect.TypeOf(k)]
 	if !found {
-		return nil, errors.Errorf("Unsupported 'DecryptKey' provided [%v]", k)
+		return nil, bccsp.Errorf(bccsp.ErrCodeUnsupportedAlgorithm, "Unsupported 'DecryptKey' provided [%v]", k)
 	}
 
 	plaintext, err = decryptor.Decrypt(k, ciphertext, opts)
@@ -7,11 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package sw
 
 import (
-	"encoding/hex"
 	"sync"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
-	"github.com/pkg/errors"
 )
 
 // NewInMemoryKeyStore instantiates an ephemeral in-memory keystore
@@ -22,9 +20,11 @@ func NewInMemoryKeyStore() bccsp.KeyStore {
 }
 
 type inmemoryKeyStore struct {
-	// keys maps the hex-encoded SKI to keys
+	// keys maps the SKI, as a string of its raw bytes, to keys
 	keys map[string]bccsp.Key
-	m    sync.RWMutex
+	// pairs maps the SKI of paired keys to their pair
+	pairs map[string]inmemoryKeyPair
+	m     sync.RWMutex
 }
 
 // ReadOnly returns false - the key store is not read-only
@@ -35,32 +35,34 @@ func (ks *inmemoryKeyStore) ReadOnly() bool {
 // GetKey returns a key object whose SKI is the one passed.
 func (ks *inmemoryKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
 	if len(ski) == 0 {
-		return nil, errors.New("ski is nil or empty")
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "ski is nil or empty")
 	}
 
-	skiStr := hex.EncodeToString(ski)
-
 	ks.m.RLock()
 	defer ks.m.RUnlock()
-	if key, found := ks.keys[skiStr]; found {
+	// indexing with the conversion of ski does not allocate
+	if key, found := ks.keys[string(ski)]; found {
 		return key, nil
 	}
-	return nil, errors.Errorf("no key found for ski %x", ski)
+	return nil, bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "no key found for ski %x", ski)
 }
 
 // StoreKey stores the key k in this KeyStore.
 func (ks *inmemoryKeyStore) StoreKey(k bccsp.Key) error {
 	if k == nil {
-		return errors.New("key is nil")
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "key is nil")
+	}
+	if bccsp.IsNonExportable(k) {
+		return bccsp.Errorf(bccsp.ErrCodeKeyNotExportable, "refusing to store non-exportable key %x", k.SKI())
 	}
 
-	ski := hex.EncodeToString(k.SKI())
+	ski := string(k.SKI())
 
 	ks.m.Lock()
 	defer ks.m.Unlock()
 
 	if _, found := ks.keys[ski]; found {
-		return errors.Errorf("ski %x already exists in the keystore", k.SKI())
+		return bccsp.Errorf(bccsp.ErrCodeKeyAlreadyExists, "ski %x already exists in the keystore", k.SKI())
 	}
 	ks.keys[ski] = k
 
@@ -10,9 +10,11 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"testing"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +44,7 @@ func TestNoKeyFound(t *testing.T) {
 	ski := []byte("foo")
 	_, err := ks.GetKey(ski)
 	assert.EqualError(t, err, fmt.Sprintf("no key found for ski %x", ski))
+	assert.True(t, errors.Is(err, bccsp.ErrKeyNotFound))
 }
 
 func TestStoreLoad(t *testing.T) {
@@ -52,7 +55,7 @@ func TestStoreLoad(t *testing.T) {
 	// generate a key for the keystore to find
 	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
-	cspKey := &ecdsaPrivateKey{privKey}
+	cspKey := &ecdsaPrivateKey{privKey: privKey}
 
 	// store key
 	err = ks.StoreKey(cspKey)
@@ -80,7 +83,7 @@ func TestStoreExisting(t *testing.T) {
 	// generate a key for the keystore to find
 	privKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	assert.NoError(t, err)
-	cspKey := &ecdsaPrivateKey{privKey}
+	cspKey := &ecdsaPrivateKey{privKey: privKey}
 
 	// store key
 	err = ks.StoreKey(cspKey)
@@ -89,4 +92,5 @@ func TestStoreExisting(t *testing.T) {
 	// store key a second time
 	err = ks.StoreKey(cspKey)
 	assert.EqualError(t, err, fmt.Sprintf("ski %x already exists in the keystore", cspKey.SKI()))
+	assert.True(t, errors.Is(err, bccsp.ErrKeyAlreadyExists))
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package sw
 
 import (
+	"context"
 	"hash"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
@@ -73,6 +74,11 @@ type Signer interface {
 	Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error)
 }
 
+// SignHook is invoked by the CSP after every signing request, successful
+// or not. The context carries the request metadata, if any was attached
+// with bccsp.WithSignRequestMetadata.
+type SignHook func(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts, signature []byte, err error)
+
 // Verifier is a BCCSP-like interface that provides verifying algorithms
 type Verifier interface {
 
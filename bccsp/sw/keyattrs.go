@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// newKeyAttributes returns the attributes of a software key entering
+// the provider with the given origin.
+func newKeyAttributes(origin string) map[string]string {
+	return map[string]string{
+		bccsp.KeyAttrCreated:    time.Now().UTC().Format(time.RFC3339),
+		bccsp.KeyAttrOrigin:     origin,
+		bccsp.KeyAttrProvider:   "SW",
+		bccsp.KeyAttrExportable: strconv.FormatBool(true),
+	}
+}
+
+// newSM2PrivateKey returns a generated SM2 key tagged with usage, which
+// is recorded in its attributes so that it survives the key store.
+func newSM2PrivateKey(privKey *sm2.PrivateKey, usage bccsp.SM2KeyUsage) *sm2PrivateKey {
+	attrs := newKeyAttributes(bccsp.KeyOriginGenerated)
+	if usage != bccsp.SM2KeyUsageAny {
+		attrs[bccsp.KeyAttrUsage] = usage.String()
+	}
+	return &sm2PrivateKey{privKey: privKey, usage: usage, attrs: attrs}
+}
+
+func copyKeyAttributes(attrs map[string]string) map[string]string {
+	if attrs == nil {
+		return nil
+	}
+	c := make(map[string]string, len(attrs))
+	for k, v := range attrs {
+		c[k] = v
+	}
+	return c
+}
+
+// KeyAttributes returns a copy of the attributes of this key.
+func (k *ecdsaPrivateKey) KeyAttributes() map[string]string {
+	return copyKeyAttributes(k.attrs)
+}
+
+// KeyAttributes returns a copy of the attributes of this key.
+func (k *sm2PrivateKey) KeyAttributes() map[string]string {
+	return copyKeyAttributes(k.attrs)
+}
+
+// storeKeyAttributes persists the attributes of k, if any, in a sidecar
+// file next to the key file.
+func (ks *fileBasedKeyStore) storeKeyAttributes(alias string, k bccsp.Key) error {
+	ak, ok := k.(bccsp.AttributedKey)
+	if !ok {
+		return nil
+	}
+	attrs := ak.KeyAttributes()
+	if len(attrs) == 0 {
+		return nil
+	}
+	return ks.writeKeyAttributes(alias, attrs)
+}
+
+// writeKeyAttributes replaces the sidecar attributes of the key with the
+// given alias.
+func (ks *fileBasedKeyStore) writeKeyAttributes(alias string, attrs map[string]string) error {
+	raw, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(ks.getAttributesPathForAlias(alias), raw, 0600)
+	if err != nil {
+		logger.Errorf("Failed storing key attributes [%s]: [%s]", alias, err)
+		return err
+	}
+
+	return nil
+}
+
+// loadKeyAttributes loads the sidecar attributes of the key with the
+// given alias. Keys stored without attributes yield nil.
+func (ks *fileBasedKeyStore) loadKeyAttributes(alias string) map[string]string {
+	raw, err := ioutil.ReadFile(ks.getAttributesPathForAlias(alias))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warningf("Failed loading key attributes [%s]: [%s]", alias, err)
+		}
+		return nil
+	}
+
+	var attrs map[string]string
+	if err := json.Unmarshal(raw, &attrs); err != nil {
+		logger.Warningf("Failed parsing key attributes [%s]: [%s]", alias, err)
+		return nil
+	}
+	return attrs
+}
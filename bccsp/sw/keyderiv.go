@@ -67,7 +67,7 @@ func (kd *ecdsaPublicKeyKeyDeriver) KeyDeriv(key bccsp.Key, opts bccsp.KeyDerivO
 		return nil, errors.New("Failed temporary public key IsOnCurve check.")
 	}
 
-	return &ecdsaPublicKey{tempSK}, nil
+	return &ecdsaPublicKey{pubKey: tempSK}, nil
 }
 
 type sm2PublicKeyKeyDeriver struct{}
@@ -124,7 +124,7 @@ func (kd *ecdsaPrivateKeyKeyDeriver) KeyDeriv(key bccsp.Key, opts bccsp.KeyDeriv
 		return nil, errors.New("Failed temporary public key IsOnCurve check.")
 	}
 
-	return &ecdsaPrivateKey{tempSK}, nil
+	return &ecdsaPrivateKey{privKey: tempSK, attrs: newKeyAttributes(bccsp.KeyOriginDerived)}, nil
 }
 
 type sm2PrivateKeyKeyDeriver struct{}
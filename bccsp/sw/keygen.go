@@ -19,46 +19,70 @@ package sw
 import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
-	"crypto/rand"
 	"fmt"
+	"io"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/paul-lee-attorney/gm/sm2"
 )
 
 type ecdsaKeyGenerator struct {
 	curve elliptic.Curve
+	rand  io.Reader
 }
 
 func (kg *ecdsaKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
-	privKey, err := ecdsa.GenerateKey(kg.curve, rand.Reader)
+	privKey, err := ecdsa.GenerateKey(kg.curve, randOrDefault(kg.rand))
 	if err != nil {
 		return nil, fmt.Errorf("Failed generating ECDSA key for [%v]: [%s]", kg.curve, err)
 	}
 
-	return &ecdsaPrivateKey{privKey}, nil
+	return &ecdsaPrivateKey{privKey: privKey, attrs: newKeyAttributes(bccsp.KeyOriginGenerated)}, nil
 }
 
 type sm2KeyGenerator struct {
 	curve elliptic.Curve
+	rand  io.Reader
 }
 
 func (kg *sm2KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	sm2Opts, _ := opts.(*bccsp.SM2KeyGenOpts)
+	if sm2Opts != nil && (sm2Opts.Curve != nil || len(sm2Opts.CurveOID) != 0) {
+		curve := sm2Opts.Curve
+		if curve == nil {
+			var err error
+			if curve, err = utils.SM2CurveByOID(sm2Opts.CurveOID); err != nil {
+				return nil, fmt.Errorf("Failed generating SM2 key: [%s]", err)
+			}
+		}
+
+		privKey, err := utils.SM2GenerateKey(randOrDefault(kg.rand), curve)
+		if err != nil {
+			return nil, fmt.Errorf("Failed generating SM2 key for [%v]: [%s]", curve, err)
+		}
+		return newSM2PrivateKey(privKey, sm2Opts.Usage), nil
+	}
+
 	sm2.GetSm2P256V1()
-	privKey, err := sm2.GenerateKey(rand.Reader)
+	privKey, err := sm2.GenerateKey(randOrDefault(kg.rand))
 	if err != nil {
 		return nil, fmt.Errorf("Failed generating SM2 key for [%v]: [%s]", kg.curve, err)
 	}
 
-	return &sm2PrivateKey{privKey}, nil
+	if sm2Opts != nil {
+		return newSM2PrivateKey(privKey, sm2Opts.Usage), nil
+	}
+	return newSM2PrivateKey(privKey, bccsp.SM2KeyUsageAny), nil
 }
 
 type aesKeyGenerator struct {
 	length int
+	rand   io.Reader
 }
 
 func (kg *aesKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
-	lowLevelKey, err := GetRandomBytes(int(kg.length))
+	lowLevelKey, err := getRandomBytesFrom(kg.rand, int(kg.length))
 	if err != nil {
 		return nil, fmt.Errorf("Failed generating AES %d key [%s]", kg.length, err)
 	}
@@ -68,10 +92,11 @@ func (kg *aesKeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
 
 type sm4KeyGenerator struct {
 	length int
+	rand   io.Reader
 }
 
 func (kg *sm4KeyGenerator) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
-	lowLevelKey, err := GetRandomBytes(int(kg.length))
+	lowLevelKey, err := getRandomBytesFrom(kg.rand, int(kg.length))
 	if err != nil {
 		return nil, fmt.Errorf("Failed generating AES %d key [%s]", kg.length, err)
 	}
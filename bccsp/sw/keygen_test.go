@@ -8,12 +8,15 @@ package sw
 
 import (
 	"crypto/elliptic"
+	"encoding/asn1"
 	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	mocks2 "github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/mocks"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw/mocks"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/stretchr/testify/assert"
 )
@@ -76,6 +79,38 @@ func TestSM2KeyGenerator(t *testing.T) {
 	assert.Equal(t, sm2K.privKey.Curve, sm2.GetSm2P256V1())
 }
 
+func TestSM2KeyGeneratorOpts(t *testing.T) {
+	t.Parallel()
+
+	kg := &sm2KeyGenerator{curve: sm2.GetSm2P256V1()}
+
+	k, err := kg.KeyGen(&bccsp.SM2KeyGenOpts{Curve: elliptic.P256(), Usage: bccsp.SM2KeyUsageEncrypt})
+	assert.NoError(t, err)
+	sm2K := k.(*sm2PrivateKey)
+	assert.Equal(t, elliptic.P256(), sm2K.privKey.Curve)
+	assert.True(t, elliptic.P256().IsOnCurve(sm2K.privKey.X, sm2K.privKey.Y))
+	assert.Equal(t, bccsp.SM2KeyUsageEncrypt, sm2K.Usage())
+
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+	assert.Equal(t, bccsp.SM2KeyUsageEncrypt, pk.(bccsp.SM2UsageKey).Usage())
+
+	_, err = (&sm2Signer{}).Sign(k, []byte("Hello World"), nil)
+	assert.EqualError(t, err, "SM2 key is tagged for encryption and must not be used for signing")
+
+	k, err = kg.KeyGen(&bccsp.SM2KeyGenOpts{CurveOID: utils.OIDNamedCurveSM2, Usage: bccsp.SM2KeyUsageSign})
+	assert.NoError(t, err)
+	assert.Equal(t, sm2.GetSm2P256V1(), k.(*sm2PrivateKey).privKey.Curve)
+	assert.Equal(t, bccsp.SM2KeyUsageSign, k.(*sm2PrivateKey).Usage())
+
+	k, err = kg.KeyGen(&bccsp.SM2KeyGenOpts{Usage: bccsp.SM2KeyUsageSign})
+	assert.NoError(t, err)
+	assert.Equal(t, bccsp.SM2KeyUsageSign, k.(*sm2PrivateKey).Usage())
+
+	_, err = kg.KeyGen(&bccsp.SM2KeyGenOpts{CurveOID: asn1.ObjectIdentifier{1, 2, 3}})
+	assert.EqualError(t, err, "Failed generating SM2 key: [unsupported SM2 curve OID [1.2.3]]")
+}
+
 func TestAESKeyGenerator(t *testing.T) {
 	t.Parallel()
 
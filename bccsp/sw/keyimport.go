@@ -8,6 +8,7 @@ package sw
 
 import (
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -93,7 +94,7 @@ func (*ecdsaPKIXPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts
 		return nil, errors.New("Failed casting to ECDSA public key. Invalid raw material.")
 	}
 
-	return &ecdsaPublicKey{ecdsaPK}, nil
+	return &ecdsaPublicKey{pubKey: ecdsaPK}, nil
 }
 
 type sm2PKIXPublicKeyImportOptsKeyImporter struct{}
@@ -113,7 +114,7 @@ func (*sm2PKIXPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bc
 		return nil, fmt.Errorf("Failed converting PKIX to SM2 public key [%s]", err)
 	}
 
-	return &sm2PublicKey{sm2PK}, nil
+	return &sm2PublicKey{pubKey: sm2PK}, nil
 }
 
 type ecdsaPrivateKeyImportOptsKeyImporter struct{}
@@ -138,7 +139,7 @@ func (*ecdsaPrivateKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bcc
 		return nil, errors.New("Failed casting to ECDSA private key. Invalid raw material.")
 	}
 
-	return &ecdsaPrivateKey{ecdsaSK}, nil
+	return &ecdsaPrivateKey{privKey: ecdsaSK, attrs: newKeyAttributes(bccsp.KeyOriginImported)}, nil
 }
 
 type sm2PrivateKeyImportOptsKeyImporter struct{}
@@ -158,7 +159,32 @@ func (*sm2PrivateKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp
 		return nil, fmt.Errorf("Failed converting PKIX to SM2 public key [%s]", err)
 	}
 
-	return &sm2PrivateKey{sm2Priv}, nil
+	return &sm2PrivateKey{privKey: sm2Priv, attrs: newKeyAttributes(bccsp.KeyOriginImported)}, nil
+}
+
+type sm2PrivateKeyRawImportOptsKeyImporter struct{}
+
+func (*sm2PrivateKeyRawImportOptsKeyImporter) KeyImport(raw interface{}, opts bccsp.KeyImportOpts) (bccsp.Key, error) {
+	d, ok := raw.([]byte)
+	if !ok {
+		return nil, errors.New("invalid raw material for SM2 raw private key import, expected byte array")
+	}
+
+	if len(d) == 0 {
+		return nil, errors.New("invalid raw, it must not be nil")
+	}
+
+	rawOpts, ok := opts.(*bccsp.SM2PrivateKeyRawImportOpts)
+	if !ok {
+		return nil, errors.New("invalid opts, expected *bccsp.SM2PrivateKeyRawImportOpts")
+	}
+
+	sm2Priv, err := utils.SM2PrivateKeyFromRaw(d, rawOpts.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("Failed converting raw scalar to SM2 private key [%s]", err)
+	}
+
+	return &sm2PrivateKey{privKey: sm2Priv, attrs: newKeyAttributes(bccsp.KeyOriginImported)}, nil
 }
 
 type ecdsaGoPublicKeyImportOptsKeyImporter struct{}
@@ -169,7 +195,7 @@ func (*ecdsaGoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bc
 		return nil, errors.New("Invalid raw material. Expected *ecdsa.PublicKey.")
 	}
 
-	return &ecdsaPublicKey{lowLevelKey}, nil
+	return &ecdsaPublicKey{pubKey: lowLevelKey}, nil
 }
 
 type sm2GoPublicKeyImportOptsKeyImporter struct{}
@@ -180,7 +206,20 @@ func (*sm2GoPublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bccs
 		return nil, errors.New("invalid raw material. Expected *sm2.PublicKey")
 	}
 
-	return &sm2PublicKey{lowLevelKey}, nil
+	return &sm2PublicKey{pubKey: lowLevelKey}, nil
+}
+
+// isSM2Curve returns true if c has the domain parameters of the SM2 recommended curve.
+func isSM2Curve(c elliptic.Curve) bool {
+	if c == nil {
+		return false
+	}
+	params, sm2Params := c.Params(), sm2.GetSm2P256V1().Params()
+	return params.P.Cmp(sm2Params.P) == 0 &&
+		params.N.Cmp(sm2Params.N) == 0 &&
+		params.B.Cmp(sm2Params.B) == 0 &&
+		params.Gx.Cmp(sm2Params.Gx) == 0 &&
+		params.Gy.Cmp(sm2Params.Gy) == 0
 }
 
 type x509PublicKeyImportOptsKeyImporter struct {
@@ -195,8 +234,14 @@ func (ki *x509PublicKeyImportOptsKeyImporter) KeyImport(raw interface{}, opts bc
 
 	pk := x509Cert.PublicKey
 
-	switch pk.(type) {
+	switch k := pk.(type) {
 	case *ecdsa.PublicKey:
+		// Some x509 implementations surface SM2 keys as ECDSA keys on the SM2 curve
+		if isSM2Curve(k.Curve) {
+			return ki.bccsp.KeyImporters[reflect.TypeOf(&bccsp.SM2GoPublicKeyImportOpts{})].KeyImport(
+				&sm2.PublicKey{Curve: sm2.GetSm2P256V1(), X: k.X, Y: k.Y},
+				&bccsp.SM2GoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
+		}
 		return ki.bccsp.KeyImporters[reflect.TypeOf(&bccsp.ECDSAGoPublicKeyImportOpts{})].KeyImport(
 			pk,
 			&bccsp.ECDSAGoPublicKeyImportOpts{Temporary: opts.Ephemeral()})
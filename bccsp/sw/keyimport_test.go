@@ -17,6 +17,8 @@ limitations under the License.
 package sw
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
@@ -24,6 +26,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	mocks2 "github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/mocks"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw/mocks"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
@@ -304,3 +307,78 @@ func TestX509PublicKeyImportOptsKeyImporter(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Certificate's public key type not recognized. Supported keys: [ECDSA]")
 }
+
+func TestGMProviderImportsECDSAKeys(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	// ECDSA keys generated by the provider must stay on P-256
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, elliptic.P256(), k.(*ecdsaPrivateKey).privKey.Curve)
+
+	ecdsaK, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	digest := []byte("Hello World")
+	signature, err := signECDSA(ecdsaK, digest, nil)
+	assert.NoError(t, err)
+
+	der, err := utils.PublicKeyToDER(&ecdsaK.PublicKey)
+	assert.NoError(t, err)
+	pk, err := csp.KeyImport(der, &bccsp.ECDSAPKIXPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	valid, err := csp.Verify(pk, signature, digest, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	pk, err = csp.KeyImport(&x509.Certificate{PublicKey: &ecdsaK.PublicKey}, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	valid, err = csp.Verify(pk, signature, digest, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// SM2 keys surfaced as ECDSA keys on the SM2 curve are imported as SM2 keys
+	sm2K, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	cert := &x509.Certificate{PublicKey: &ecdsa.PublicKey{Curve: sm2K.Curve, X: sm2K.X, Y: sm2K.Y}}
+	pk, err = csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	_, ok := pk.(*sm2PublicKey)
+	assert.True(t, ok)
+}
+
+func TestSM2PrivateKeyRawImportOptsKeyImporter(t *testing.T) {
+	t.Parallel()
+
+	ki := sm2PrivateKeyRawImportOptsKeyImporter{}
+
+	_, err := ki.KeyImport("Hello World", &bccsp.SM2PrivateKeyRawImportOpts{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid raw material for SM2 raw private key import, expected byte array")
+
+	_, err = ki.KeyImport([]byte(nil), &bccsp.SM2PrivateKeyRawImportOpts{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid raw, it must not be nil")
+
+	_, err = ki.KeyImport([]byte{0}, &mocks2.KeyImportOpts{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid opts, expected *bccsp.SM2PrivateKeyRawImportOpts")
+
+	_, err = ki.KeyImport([]byte{0}, &bccsp.SM2PrivateKeyRawImportOpts{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed converting raw scalar to SM2 private key")
+
+	sm2K, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	d := make([]byte, sm2.KeyBytes)
+	dBytes := sm2K.D.Bytes()
+	copy(d[len(d)-len(dBytes):], dBytes)
+	k, err := ki.KeyImport(d, &bccsp.SM2PrivateKeyRawImportOpts{
+		PublicKey: elliptic.Marshal(sm2K.Curve, sm2K.X, sm2K.Y),
+	})
+	assert.NoError(t, err)
+	assert.True(t, k.Private())
+	assert.Equal(t, (&sm2PrivateKey{privKey: sm2K}).SKI(), k.SKI())
+}
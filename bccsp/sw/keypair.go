@@ -0,0 +1,164 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+// keyUsage returns the usage k was tagged with, SM2KeyUsageAny if none.
+func keyUsage(k bccsp.Key) bccsp.SM2KeyUsage {
+	if uk, ok := k.(bccsp.SM2UsageKey); ok {
+		return uk.Usage()
+	}
+	return bccsp.SM2KeyUsageAny
+}
+
+// checkKeyPair checks that signKey and encKey may be paired as the signing
+// and the encryption key of a dual certificate identity.
+func checkKeyPair(signKey, encKey bccsp.Key) error {
+	if signKey == nil || encKey == nil {
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "invalid key. It must be different from nil")
+	}
+	if !signKey.Private() || !encKey.Private() {
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "only private keys can be paired")
+	}
+	if keyUsage(signKey) == bccsp.SM2KeyUsageEncrypt {
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "signing key %x is tagged for encryption", signKey.SKI())
+	}
+	if keyUsage(encKey) == bccsp.SM2KeyUsageSign {
+		return bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "encryption key %x is tagged for signing", encKey.SKI())
+	}
+	return nil
+}
+
+// PairKeys records in the attributes of signKey and encKey that they are
+// the signing and the encryption key of the same identity.
+func (ks *fileBasedKeyStore) PairKeys(signKey, encKey bccsp.Key) error {
+	if ks.readOnly {
+		return bccsp.Errorf(bccsp.ErrCodeReadOnlyKeyStore, "read only KeyStore")
+	}
+	if err := checkKeyPair(signKey, encKey); err != nil {
+		return err
+	}
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	signAlias, encAlias := hex.EncodeToString(signKey.SKI()), hex.EncodeToString(encKey.SKI())
+	for _, pair := range []struct {
+		alias, paired string
+		usage         bccsp.SM2KeyUsage
+	}{
+		{signAlias, encAlias, bccsp.SM2KeyUsageSign},
+		{encAlias, signAlias, bccsp.SM2KeyUsageEncrypt},
+	} {
+		if ks.getSuffix(pair.alias) != "sk" {
+			return bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "private key with SKI %s not found in %s", pair.alias, ks.path)
+		}
+		attrs := ks.loadKeyAttributes(pair.alias)
+		if attrs == nil {
+			attrs = map[string]string{}
+		}
+		attrs[bccsp.KeyAttrUsage] = pair.usage.String()
+		attrs[bccsp.KeyAttrPairedKey] = pair.paired
+		if err := ks.writeKeyAttributes(pair.alias, attrs); err != nil {
+			return fmt.Errorf("failed pairing key %s [%s]", pair.alias, err)
+		}
+	}
+	return nil
+}
+
+// GetPairedKey returns the key with the given usage of the identity owning
+// the key whose SKI is ski.
+func (ks *fileBasedKeyStore) GetPairedKey(ski []byte, usage bccsp.SM2KeyUsage) (bccsp.Key, error) {
+	k, err := ks.GetKey(ski)
+	if err != nil {
+		return nil, err
+	}
+	if keyUsage(k) == usage {
+		return k, nil
+	}
+
+	paired := ks.loadKeyAttributes(hex.EncodeToString(ski))[bccsp.KeyAttrPairedKey]
+	if paired == "" {
+		return nil, bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "key %x has no paired key", ski)
+	}
+	pairedSKI, err := hex.DecodeString(paired)
+	if err != nil {
+		return nil, fmt.Errorf("invalid paired key of %x [%s]", ski, err)
+	}
+	k, err = ks.GetKey(pairedSKI)
+	if err != nil {
+		return nil, err
+	}
+	if keyUsage(k) != usage {
+		return nil, bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "key %x has no paired %s key", ski, usage)
+	}
+	return k, nil
+}
+
+// inmemoryKeyPair is a signing and encryption key pair of an inmemoryKeyStore.
+type inmemoryKeyPair struct {
+	sign, enc string
+}
+
+// PairKeys records that signKey and encKey are the signing and the
+// encryption key of the same identity.
+func (ks *inmemoryKeyStore) PairKeys(signKey, encKey bccsp.Key) error {
+	if err := checkKeyPair(signKey, encKey); err != nil {
+		return err
+	}
+
+	pair := inmemoryKeyPair{sign: string(signKey.SKI()), enc: string(encKey.SKI())}
+
+	ks.m.Lock()
+	defer ks.m.Unlock()
+
+	for _, ski := range []string{pair.sign, pair.enc} {
+		if _, found := ks.keys[ski]; !found {
+			return bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "no key found for ski %x", ski)
+		}
+	}
+	if ks.pairs == nil {
+		ks.pairs = map[string]inmemoryKeyPair{}
+	}
+	ks.pairs[pair.sign] = pair
+	ks.pairs[pair.enc] = pair
+	return nil
+}
+
+// GetPairedKey returns the key with the given usage of the identity owning
+// the key whose SKI is ski.
+func (ks *inmemoryKeyStore) GetPairedKey(ski []byte, usage bccsp.SM2KeyUsage) (bccsp.Key, error) {
+	if len(ski) == 0 {
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "ski is nil or empty")
+	}
+
+	ks.m.RLock()
+	defer ks.m.RUnlock()
+
+	pair, found := ks.pairs[string(ski)]
+	if !found {
+		if k, found := ks.keys[string(ski)]; found && keyUsage(k) == usage {
+			return k, nil
+		}
+		return nil, bccsp.Errorf(bccsp.ErrCodeKeyNotFound, "no %s key found for ski %x", usage, ski)
+	}
+
+	switch usage {
+	case bccsp.SM2KeyUsageSign:
+		return ks.keys[pair.sign], nil
+	case bccsp.SM2KeyUsageEncrypt:
+		return ks.keys[pair.enc], nil
+	default:
+		return nil, bccsp.Errorf(bccsp.ErrCodeInvalidArgument, "invalid key usage %d", usage)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPairedKeyStores(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	require.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+	fileKS, err := NewFileBasedKeyStore(nil, ksPath, false)
+	require.NoError(t, err)
+
+	for name, ks := range map[string]bccsp.KeyStore{
+		"file":     fileKS,
+		"inmemory": NewInMemoryKeyStore(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			pks, ok := ks.(bccsp.PairedKeyStore)
+			require.True(t, ok)
+
+			signKey, err := (&sm2KeyGenerator{}).KeyGen(&bccsp.SM2KeyGenOpts{Usage: bccsp.SM2KeyUsageSign})
+			require.NoError(t, err)
+			encKey, err := (&sm2KeyGenerator{}).KeyGen(&bccsp.SM2KeyGenOpts{Usage: bccsp.SM2KeyUsageEncrypt})
+			require.NoError(t, err)
+
+			err = pks.PairKeys(signKey, encKey)
+			assert.Equal(t, bccsp.ErrCodeKeyNotFound, bccsp.ErrorCodeOf(err))
+			require.NoError(t, ks.StoreKey(signKey))
+			require.NoError(t, ks.StoreKey(encKey))
+
+			err = pks.PairKeys(encKey, signKey)
+			assert.Equal(t, bccsp.ErrCodeInvalidArgument, bccsp.ErrorCodeOf(err))
+			require.NoError(t, pks.PairKeys(signKey, encKey))
+
+			for _, ski := range [][]byte{signKey.SKI(), encKey.SKI()} {
+				k, err := pks.GetPairedKey(ski, bccsp.SM2KeyUsageSign)
+				require.NoError(t, err)
+				assert.Equal(t, signKey.SKI(), k.SKI())
+				assert.Equal(t, bccsp.SM2KeyUsageSign, k.(bccsp.SM2UsageKey).Usage())
+
+				k, err = pks.GetPairedKey(ski, bccsp.SM2KeyUsageEncrypt)
+				require.NoError(t, err)
+				assert.Equal(t, encKey.SKI(), k.SKI())
+				assert.Equal(t, bccsp.SM2KeyUsageEncrypt, k.(bccsp.SM2UsageKey).Usage())
+			}
+
+			// Unpaired keys
+			other, err := (&sm2KeyGenerator{}).KeyGen(&bccsp.SM2KeyGenOpts{})
+			require.NoError(t, err)
+			require.NoError(t, ks.StoreKey(other))
+			_, err = pks.GetPairedKey(other.SKI(), bccsp.SM2KeyUsageEncrypt)
+			assert.Equal(t, bccsp.ErrCodeKeyNotFound, bccsp.ErrorCodeOf(err))
+		})
+	}
+}
+
+func TestKeyUsageSurvivesFileKeyStore(t *testing.T) {
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	require.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	require.NoError(t, err)
+
+	k, err := (&sm2KeyGenerator{}).KeyGen(&bccsp.SM2KeyGenOpts{Usage: bccsp.SM2KeyUsageEncrypt})
+	require.NoError(t, err)
+	assert.Equal(t, "encrypt", k.(bccsp.AttributedKey).KeyAttributes()[bccsp.KeyAttrUsage])
+	require.NoError(t, ks.StoreKey(k))
+
+	k2, err := ks.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.Equal(t, bccsp.SM2KeyUsageEncrypt, k2.(bccsp.SM2UsageKey).Usage())
+	_, err = (&sm2Signer{}).Sign(k2, []byte("digest"), nil)
+	assert.EqualError(t, err, "SM2 key is tagged for encryption and must not be used for signing")
+}
@@ -13,7 +13,6 @@ import (
 	"reflect"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
-	"github.com/paul-lee-attorney/gm/sm3"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/sha3"
 )
@@ -37,64 +36,104 @@ func NewDefaultSecurityLevelWithKeystore(keyStore bccsp.KeyStore) (bccsp.BCCSP,
 
 // NewWithParams returns a new instance of the software-based BCCSP
 // set at the passed security level, hash family and KeyStore.
-func NewWithParams(securityLevel int, hashFamily string, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+// Additional behaviour can be configured with opts.
+func NewWithParams(securityLevel int, hashFamily string, keyStore bccsp.KeyStore, opts ...Option) (bccsp.BCCSP, error) {
 	// Init config
 	conf := &config{}
 	err := conf.setSecurityLevel(securityLevel, hashFamily)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed initializing configuration at [%v,%v]", securityLevel, hashFamily)
 	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+
+	gm, err := newGMBackend(conf.gmBackend)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed initializing GM backend")
+	}
+	if _, pureGo := gm.(goGMBackend); !pureGo && (conf.sm2Precomp || conf.sm2FastVerify) {
+		logger.Warningf("SM2 precomputation and fast verification are ignored with the %s GM backend", conf.gmBackend)
+		conf.sm2Precomp, conf.sm2FastVerify = false, false
+	}
+	if hashFamily == "SM3" {
+		conf.hashFunction = gm.newSM3
+	}
+
+	if conf.skiConvention != nil && keyStore != nil {
+		keyStore = &skiConventionKeyStore{KeyStore: keyStore, convention: *conf.skiConvention}
+	}
+
+	var sm2Precomp *sm2PrecompCache
+	if conf.sm2Precomp {
+		sm2Precomp = newSM2PrecompCache(defaultSM2PrecompCacheSize)
+		if keyStore != nil {
+			keyStore = &sm2PrecompKeyStore{KeyStore: keyStore, cache: sm2Precomp}
+		}
+	}
+
+	// Nonces and IVs come from the pooled CSPRNG, keys from crypto/rand
+	nonceRand := conf.rand
+	if nonceRand == nil && !conf.noRandPool {
+		nonceRand = NewPooledRandReader(nil)
+	}
 
 	swbccsp, err := New(keyStore)
 	if err != nil {
 		return nil, err
 	}
+	swbccsp.hashFamily = hashFamily
+	swbccsp.skiConvention = conf.skiConvention
 
 	// Notice that errors are ignored here because some test will fail if one
 	// of the following call fails.
 
 	// Set the Encryptors
-	swbccsp.AddWrapper(reflect.TypeOf(&aesPrivateKey{}), &aescbcpkcs7Encryptor{})
+	swbccsp.AddWrapper(reflect.TypeOf(&aesPrivateKey{}), &aescbcpkcs7Encryptor{rand: nonceRand})
 
-	swbccsp.AddWrapper(reflect.TypeOf(&sm4PrivateKey{}), &sm4Encryptor{}) // sm4 encryptor
+	swbccsp.AddWrapper(reflect.TypeOf(&sm4PrivateKey{}), &sm4Encryptor{newCipher: conf.sm4NewCipher, rand: nonceRand}) // sm4 encryptor
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PublicKey{}), &sm2Encryptor{rand: nonceRand})                                // sm2 encryptor
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2Encryptor{rand: nonceRand})                               // sm2 encryptor
 
 	// Set the Decryptors
 	swbccsp.AddWrapper(reflect.TypeOf(&aesPrivateKey{}), &aescbcpkcs7Decryptor{})
 
-	swbccsp.AddWrapper(reflect.TypeOf(&sm4PrivateKey{}), &sm4Decryptor{}) // 	sm4 decryptor
+	swbccsp.AddWrapper(reflect.TypeOf(&sm4PrivateKey{}), &sm4Decryptor{newCipher: conf.sm4NewCipher}) // 	sm4 decryptor
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2Decryptor{})                             // sm2 decryptor
 
 	// Set the Signers
-	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaSigner{})
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaSigner{rand: conf.rand})
 
-	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2Signer{}) // sm2 signor
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2Signer{rand: nonceRand, precomp: sm2Precomp, gm: gm}) // sm2 signor
 
 	// Set the Verifiers
-	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaPrivateKeyVerifier{})
-	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPublicKey{}), &ecdsaPublicKeyKeyVerifier{})
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaPrivateKeyVerifier{asn1Mode: conf.asn1Mode})
+	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPublicKey{}), &ecdsaPublicKeyKeyVerifier{asn1Mode: conf.asn1Mode})
 
-	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2PrivateKeyVerifier{})  // sm2 Private Key Verifier
-	swbccsp.AddWrapper(reflect.TypeOf(&sm2PublicKey{}), &sm2PublicKeyKeyVerifier{}) // sm2 Public Key Verifier
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PrivateKey{}), &sm2PrivateKeyVerifier{precomputed: conf.sm2FastVerify, gm: gm, asn1Mode: conf.asn1Mode})  // sm2 Private Key Verifier
+	swbccsp.AddWrapper(reflect.TypeOf(&sm2PublicKey{}), &sm2PublicKeyKeyVerifier{precomputed: conf.sm2FastVerify, gm: gm, asn1Mode: conf.asn1Mode}) // sm2 Public Key Verifier
 
 	// Set the Hashers
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHAOpts{}), &hasher{hash: conf.hashFunction})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA256Opts{}), &hasher{hash: sha256.New})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA384Opts{}), &hasher{hash: sha512.New384})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA3_256Opts{}), &hasher{hash: sha3.New256})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA3_384Opts{}), &hasher{hash: sha3.New384})
+	// The hashers reuse hash states across calls
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHAOpts{}), newPooledHasher(conf.hashFunction))
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA256Opts{}), newPooledHasher(sha256.New))
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA384Opts{}), newPooledHasher(sha512.New384))
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA3_256Opts{}), newPooledHasher(sha3.New256))
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SHA3_384Opts{}), newPooledHasher(sha3.New384))
 
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM3Opts{}), &hasher{hash: sm3.New}) // SM3 hasher
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM3Opts{}), newPooledHasher(gm.newSM3)) // SM3 hasher
 
 	// Set the key generators
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAKeyGenOpts{}), &ecdsaKeyGenerator{curve: conf.ellipticCurve})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP256KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P256()})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP384KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P384()})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AESKeyGenOpts{}), &aesKeyGenerator{length: conf.aesBitLength})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES256KeyGenOpts{}), &aesKeyGenerator{length: 32})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES192KeyGenOpts{}), &aesKeyGenerator{length: 24})
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES128KeyGenOpts{}), &aesKeyGenerator{length: 16})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAKeyGenOpts{}), &ecdsaKeyGenerator{curve: conf.ellipticCurve, rand: conf.rand})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP256KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P256(), rand: conf.rand})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAP384KeyGenOpts{}), &ecdsaKeyGenerator{curve: elliptic.P384(), rand: conf.rand})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AESKeyGenOpts{}), &aesKeyGenerator{length: conf.aesBitLength, rand: conf.rand})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES256KeyGenOpts{}), &aesKeyGenerator{length: 32, rand: conf.rand})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES192KeyGenOpts{}), &aesKeyGenerator{length: 24, rand: conf.rand})
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.AES128KeyGenOpts{}), &aesKeyGenerator{length: 16, rand: conf.rand})
 
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2KeyGenOpts{}), &sm2KeyGenerator{})           // sm2 key generator
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM4KeyGenOpts{}), &sm4KeyGenerator{length: 16}) // sm4 key generator
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2KeyGenOpts{}), &sm2KeyGenerator{rand: conf.rand})             // sm2 key generator
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM4KeyGenOpts{}), &sm4KeyGenerator{length: 16, rand: conf.rand}) // sm4 key generator
 
 	// Set the key deriver
 	swbccsp.AddWrapper(reflect.TypeOf(&ecdsaPrivateKey{}), &ecdsaPrivateKeyKeyDeriver{})
@@ -113,9 +152,11 @@ func NewWithParams(securityLevel int, hashFamily string, keyStore bccsp.KeyStore
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.ECDSAGoPublicKeyImportOpts{}), &ecdsaGoPublicKeyImportOptsKeyImporter{})
 	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.X509PublicKeyImportOpts{}), &x509PublicKeyImportOptsKeyImporter{bccsp: swbccsp})
 
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM4ImportKeyOpts{}), &sm4ImportKeyOptsKeyImporter{})                 // sm4 key importor
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2PrivateKeyImportOpts{}), &sm2PrivateKeyImportOptsKeyImporter{})   // sm2 private key importor
-	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2GoPublicKeyImportOpts{}), &sm2GoPublicKeyImportOptsKeyImporter{}) // sm2 public key importor
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM4ImportKeyOpts{}), &sm4ImportKeyOptsKeyImporter{})                     // sm4 key importor
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2PrivateKeyImportOpts{}), &sm2PrivateKeyImportOptsKeyImporter{})       // sm2 private key importor
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2GoPublicKeyImportOpts{}), &sm2GoPublicKeyImportOptsKeyImporter{})     // sm2 public key importor
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2PKIXPublicKeyImportOpts{}), &sm2PKIXPublicKeyImportOptsKeyImporter{}) // sm2 PKIX public key importor
+	swbccsp.AddWrapper(reflect.TypeOf(&bccsp.SM2PrivateKeyRawImportOpts{}), &sm2PrivateKeyRawImportOptsKeyImporter{}) // sm2 raw private key importor
 
 	return swbccsp, nil
 }
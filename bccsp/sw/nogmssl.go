@@ -0,0 +1,17 @@
+// +build !gmssl !cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import "errors"
+
+// newGmSSLBackend always fails, since the GmSSL backend requires cgo and
+// the gmssl build tag.
+func newGmSSLBackend() (gmBackend, error) {
+	return nil, errors.New("GmSSL support is not available, rebuild with cgo and the gmssl build tag")
+}
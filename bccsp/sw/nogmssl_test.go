@@ -0,0 +1,20 @@
+// +build !gmssl !cgo
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGmSSLBackendNotAvailable(t *testing.T) {
+	_, err := NewWithParams(256, "SM3", NewDummyKeyStore(), WithGMBackend(GMBackendGmSSL))
+	assert.EqualError(t, err, "Failed initializing GM backend: GmSSL support is not available, rebuild with cgo and the gmssl build tag")
+}
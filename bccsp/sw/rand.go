@@ -0,0 +1,261 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/pkg/errors"
+)
+
+// randOrDefault returns r, or crypto/rand.Reader if r is nil.
+func randOrDefault(r io.Reader) io.Reader {
+	if r == nil {
+		return rand.Reader
+	}
+	return r
+}
+
+// NewDeviceRandReader returns an entropy source that reads from the
+// passed device file, e.g. a hardware TRNG exposed as /dev/hwrng.
+func NewDeviceRandReader(path string) (io.Reader, error) {
+	if path == "" {
+		return nil, errors.New("Invalid device path. It must not be empty.")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed opening random device [%s]", path)
+	}
+
+	return &deviceRandReader{f: f}, nil
+}
+
+type deviceRandReader struct {
+	mutex sync.Mutex
+	f     *os.File
+}
+
+func (d *deviceRandReader) Read(p []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return io.ReadFull(d.f, p)
+}
+
+// drbgReseedInterval is the number of requests after which
+// the DRBG pulls fresh entropy from its seed source.
+const drbgReseedInterval = 1 << 16
+
+// NewSM3DRBG returns a deterministic random bit generator (HMAC_DRBG of
+// NIST SP 800-90A instantiated with SM3) seeded and periodically reseeded
+// from entropy, which is typically an HSM or hardware TRNG.
+func NewSM3DRBG(entropy io.Reader) (io.Reader, error) {
+	if entropy == nil {
+		return nil, errors.New("Invalid entropy source. It must not be nil.")
+	}
+
+	d := &hmacDRBG{
+		hash:    sm3.New,
+		entropy: entropy,
+	}
+	size := d.hash().Size()
+	d.k = make([]byte, size)
+	d.v = make([]byte, size)
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	if err := d.reseed(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+type hmacDRBG struct {
+	mutex   sync.Mutex
+	hash    func() hash.Hash
+	entropy io.Reader
+	k       []byte
+	v       []byte
+	counter int
+}
+
+func (d *hmacDRBG) reseed() error {
+	seed := make([]byte, 2*d.hash().Size())
+	if _, err := io.ReadFull(d.entropy, seed); err != nil {
+		return errors.Wrap(err, "Failed reading DRBG seed")
+	}
+	d.update(seed)
+	d.counter = 0
+	return nil
+}
+
+func (d *hmacDRBG) update(data []byte) {
+	for _, b := range []byte{0x00, 0x01} {
+		m := hmac.New(d.hash, d.k)
+		m.Write(d.v)
+		m.Write([]byte{b})
+		m.Write(data)
+		d.k = m.Sum(nil)
+
+		m = hmac.New(d.hash, d.k)
+		m.Write(d.v)
+		d.v = m.Sum(nil)
+
+		if len(data) == 0 {
+			return
+		}
+	}
+}
+
+func (d *hmacDRBG) Read(p []byte) (int, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.counter >= drbgReseedInterval {
+		if err := d.reseed(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	for n < len(p) {
+		m := hmac.New(d.hash, d.k)
+		m.Write(d.v)
+		d.v = m.Sum(nil)
+		n += copy(p[n:], d.v)
+	}
+	d.update(nil)
+	d.counter++
+
+	return n, nil
+}
+
+const (
+	// pooledRandBufferSize is the number of bytes a generator of the
+	// pooled reader produces at once.
+	pooledRandBufferSize = 4096
+	// pooledRandReseedBytes and pooledRandReseedInterval bound the output
+	// of a generator and its age before it pulls a fresh key from its seed
+	// source.
+	pooledRandReseedBytes    = 1 << 20
+	pooledRandReseedInterval = time.Minute
+)
+
+// NewPooledRandReader returns a CSPRNG for the nonces and IVs of signatures
+// and encryptions which reads its seed source, crypto/rand if source is
+// nil, once per megabyte of output or minute instead of once per request,
+// so that busy nodes do not issue a getrandom system call per signature.
+//
+// The reader keeps a pool of AES-256-CTR generators, one per concurrent
+// caller, which rekey from their own output every time they refill their
+// buffer (fast key erasure) and erase the bytes they return, so that the
+// state of a generator does not reveal its past outputs.
+func NewPooledRandReader(source io.Reader) io.Reader {
+	return &pooledRandReader{source: randOrDefault(source)}
+}
+
+type pooledRandReader struct {
+	source io.Reader
+	pool   sync.Pool
+}
+
+func (r *pooledRandReader) Read(p []byte) (int, error) {
+	g, ok := r.pool.Get().(*randGenerator)
+	if !ok {
+		g = &randGenerator{source: r.source}
+	}
+	if err := g.read(p); err != nil {
+		// A generator which failed to reseed is dropped
+		return 0, err
+	}
+	r.pool.Put(g)
+	return len(p), nil
+}
+
+// randGenerator produces the AES-CTR keystream of a key replaced by the
+// first 32 bytes of every buffer it generates.
+type randGenerator struct {
+	source    io.Reader
+	block     cipher.Block
+	buf       [pooledRandBufferSize]byte
+	off       int
+	generated int
+	seeded    time.Time
+}
+
+func (g *randGenerator) read(p []byte) error {
+	if g.block == nil || g.generated >= pooledRandReseedBytes || time.Since(g.seeded) >= pooledRandReseedInterval {
+		if err := g.reseed(); err != nil {
+			return err
+		}
+	}
+
+	g.generated += len(p)
+	for len(p) > 0 {
+		if g.off == len(g.buf) {
+			g.refill()
+		}
+		n := copy(p, g.buf[g.off:])
+		used := g.buf[g.off : g.off+n]
+		for i := range used {
+			used[i] = 0
+		}
+		g.off += n
+		p = p[n:]
+	}
+	return nil
+}
+
+func (g *randGenerator) reseed() error {
+	var key [32]byte
+	if _, err := io.ReadFull(g.source, key[:]); err != nil {
+		return errors.Wrap(err, "Failed reading random generator seed")
+	}
+	g.rekey(key[:])
+	g.generated = 0
+	g.seeded = time.Now()
+	return nil
+}
+
+func (g *randGenerator) rekey(key []byte) {
+	// AES accepts any 32-byte key
+	g.block, _ = aes.NewCipher(key)
+	for i := range key {
+		key[i] = 0
+	}
+	g.refill()
+}
+
+// refill generates a buffer of keystream and replaces the key with its
+// first bytes, which are never returned.
+func (g *randGenerator) refill() {
+	for i := range g.buf {
+		g.buf[i] = 0
+	}
+	var iv [aes.BlockSize]byte
+	cipher.NewCTR(g.block, iv[:]).XORKeyStream(g.buf[:], g.buf[:])
+
+	var key [32]byte
+	copy(key[:], g.buf[:])
+	g.block, _ = aes.NewCipher(key[:])
+	for i := range key {
+		key[i] = 0
+		g.buf[i] = 0
+	}
+	g.off = len(key)
+}
@@ -0,0 +1,255 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceRandReader(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewDeviceRandReader("")
+	assert.EqualError(t, err, "Invalid device path. It must not be empty.")
+
+	_, err = NewDeviceRandReader("/path/does/not/exist")
+	assert.Error(t, err)
+
+	r, err := NewDeviceRandReader("/dev/urandom")
+	assert.NoError(t, err)
+
+	buf := make([]byte, 64)
+	n, err := r.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 64, n)
+}
+
+func TestSM3DRBG(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSM3DRBG(nil)
+	assert.EqualError(t, err, "Invalid entropy source. It must not be nil.")
+
+	// The same seed must yield the same stream
+	seed := bytes.Repeat([]byte{0x42}, 64)
+	d1, err := NewSM3DRBG(bytes.NewReader(seed))
+	assert.NoError(t, err)
+	d2, err := NewSM3DRBG(bytes.NewReader(seed))
+	assert.NoError(t, err)
+
+	out1 := make([]byte, 100)
+	out2 := make([]byte, 100)
+	_, err = d1.Read(out1)
+	assert.NoError(t, err)
+	_, err = d2.Read(out2)
+	assert.NoError(t, err)
+	assert.Equal(t, out1, out2)
+
+	_, err = d1.Read(out2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, out1, out2)
+}
+
+func TestNewWithRand(t *testing.T) {
+	t.Parallel()
+
+	drbg, err := NewSM3DRBG(rand.Reader)
+	assert.NoError(t, err)
+
+	csp, err := NewWithParams(256, "SM3", NewDummyKeyStore(), WithRand(drbg))
+	assert.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{
+		&bccsp.SM2KeyGenOpts{Temporary: true},
+		&bccsp.ECDSAP256KeyGenOpts{Temporary: true},
+	} {
+		k, err := csp.KeyGen(opts)
+		assert.NoError(t, err)
+
+		digest := []byte("Hello World")
+		signature, err := csp.Sign(k, digest, nil)
+		assert.NoError(t, err)
+
+		valid, err := csp.Verify(k, signature, digest, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+
+	k, err := csp.KeyGen(&bccsp.SM4KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, k)
+}
+
+// countingReader counts the reads of its source, failing once fail is set.
+type countingReader struct {
+	mutex sync.Mutex
+	reads int
+	fail  bool
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.fail {
+		return 0, errors.New("no entropy")
+	}
+	c.reads++
+	return rand.Read(p)
+}
+
+func (c *countingReader) count() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.reads
+}
+
+func TestPooledRandReader(t *testing.T) {
+	t.Parallel()
+
+	source := &countingReader{}
+	r := NewPooledRandReader(source)
+
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		nonce := make([]byte, 12+i%40)
+		n, err := r.Read(nonce)
+		assert.NoError(t, err)
+		assert.Equal(t, len(nonce), n)
+		assert.False(t, seen[string(nonce)])
+		seen[string(nonce)] = true
+	}
+	// Reads larger than the buffer span several refills
+	large := make([]byte, 3*pooledRandBufferSize+5)
+	_, err := io.ReadFull(r, large)
+	assert.NoError(t, err)
+	assert.NotEqual(t, make([]byte, 100), large[len(large)-100:])
+
+	// A generator reads its source once per reseed only
+	g := &randGenerator{source: source}
+	reads := source.count()
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, g.read(make([]byte, 32)))
+	}
+	assert.Equal(t, reads+1, source.count())
+
+	// A failing source fails the reseed, and the generator is dropped
+	reads = source.count()
+	g.generated = pooledRandReseedBytes
+	assert.NoError(t, g.read(make([]byte, 16)))
+	assert.Equal(t, reads+1, source.count())
+	g.seeded = time.Now().Add(-pooledRandReseedInterval)
+	source.fail = true
+	assert.EqualError(t, g.read(make([]byte, 16)), "Failed reading random generator seed: no entropy")
+	_, err = NewPooledRandReader(source).Read(make([]byte, 16))
+	assert.Error(t, err)
+}
+
+func TestPooledRandReaderErasure(t *testing.T) {
+	t.Parallel()
+
+	g := &randGenerator{source: rand.Reader}
+	out := make([]byte, 100)
+	assert.NoError(t, g.read(out))
+
+	// The bytes returned and the key material are erased from the buffer
+	assert.Equal(t, make([]byte, g.off), g.buf[:g.off])
+	assert.Equal(t, 32+len(out), g.off)
+	assert.NotEqual(t, make([]byte, 32), g.buf[g.off:g.off+32])
+}
+
+func TestPooledRandReaderConcurrency(t *testing.T) {
+	t.Parallel()
+
+	r := NewPooledRandReader(nil)
+	var mutex sync.Mutex
+	seen := map[string]bool{}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				nonce := make([]byte, 16)
+				_, err := r.Read(nonce)
+				assert.NoError(t, err)
+				mutex.Lock()
+				assert.False(t, seen[string(nonce)])
+				seen[string(nonce)] = true
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithoutRandPool(t *testing.T) {
+	t.Parallel()
+
+	pooled, err := NewWithParams(256, "SM3", NewDummyKeyStore())
+	assert.NoError(t, err)
+	signer := pooled.(*CSP).Signers[reflect.TypeOf(&sm2PrivateKey{})].(*sm2Signer)
+	assert.IsType(t, &pooledRandReader{}, signer.rand)
+	encryptor := pooled.(*CSP).Encryptors[reflect.TypeOf(&sm4PrivateKey{})].(*sm4Encryptor)
+	assert.IsType(t, &pooledRandReader{}, encryptor.rand)
+
+	direct, err := NewWithParams(256, "SM3", NewDummyKeyStore(), WithoutRandPool())
+	assert.NoError(t, err)
+	signer = direct.(*CSP).Signers[reflect.TypeOf(&sm2PrivateKey{})].(*sm2Signer)
+	assert.Nil(t, signer.rand)
+	encryptor = direct.(*CSP).Encryptors[reflect.TypeOf(&sm4PrivateKey{})].(*sm4Encryptor)
+	assert.Nil(t, encryptor.rand)
+
+	for _, csp := range []bccsp.BCCSP{pooled, direct} {
+		k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+		signature, err := csp.Sign(k, []byte("Hello World"), nil)
+		assert.NoError(t, err)
+		valid, err := csp.Verify(k, signature, []byte("Hello World"), nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+
+		k, err = csp.KeyGen(&bccsp.SM4KeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+		ciphertext, err := csp.Encrypt(k, []byte("Hello World"), &bccsp.AEADOpts{})
+		assert.NoError(t, err)
+		plaintext, err := csp.Decrypt(k, ciphertext, &bccsp.AEADOpts{})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("Hello World"), plaintext)
+	}
+}
+
+func BenchmarkRandReader(b *testing.B) {
+	for _, bm := range []struct {
+		name string
+		r    io.Reader
+	}{
+		{"crypto/rand", rand.Reader},
+		{"Pooled", NewPooledRandReader(nil)},
+	} {
+		r := bm.r
+		b.Run(bm.name, func(b *testing.B) {
+			b.RunParallel(func(pb *testing.PB) {
+				nonce := make([]byte, 32)
+				for pb.Next() {
+					if _, err := r.Read(nonce); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -17,10 +17,12 @@ limitations under the License.
 package sw
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	mocks2 "github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/mocks"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw/mocks"
 	"github.com/stretchr/testify/assert"
@@ -61,3 +63,52 @@ func TestSign(t *testing.T) {
 	assert.Nil(t, value)
 	assert.Contains(t, err.Error(), expectedErr.Error())
 }
+
+func TestSignCtx(t *testing.T) {
+	t.Parallel()
+
+	expectedKey := &mocks2.MockKey{}
+	expectetDigest := []byte{1, 2, 3, 4}
+	expectedOpts := &mocks2.SignerOpts{}
+	expectetValue := []byte{0, 1, 2, 3, 4}
+
+	signers := make(map[reflect.Type]Signer)
+	signers[reflect.TypeOf(&mocks2.MockKey{})] = &mocks.Signer{
+		KeyArg:    expectedKey,
+		DigestArg: expectetDigest,
+		OptsArg:   expectedOpts,
+		Value:     expectetValue,
+		Err:       nil,
+	}
+	csp := &CSP{Signers: signers}
+
+	var hooked []*bccsp.SignRequestMetadata
+	err := csp.AddSignHook(func(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts, signature []byte, err error) {
+		md, _ := bccsp.SignRequestMetadataFromContext(ctx)
+		hooked = append(hooked, md)
+	})
+	assert.NoError(t, err)
+	assert.EqualError(t, csp.AddSignHook(nil), "hook cannot be nil")
+
+	md := &bccsp.SignRequestMetadata{ChannelID: "mychannel", TxID: "tx1"}
+	ctx := bccsp.WithSignRequestMetadata(context.Background(), md)
+	value, err := csp.SignCtx(ctx, expectedKey, expectetDigest, expectedOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, expectetValue, value)
+
+	value, err = bccsp.SignCtx(ctx, csp, expectedKey, expectetDigest, expectedOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, expectetValue, value)
+
+	value, err = csp.Sign(expectedKey, expectetDigest, expectedOpts)
+	assert.NoError(t, err)
+	assert.Equal(t, expectetValue, value)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = csp.SignCtx(cancelled, expectedKey, expectetDigest, expectedOpts)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	assert.Equal(t, []*bccsp.SignRequestMetadata{md, md, nil, md}, hooked)
+}
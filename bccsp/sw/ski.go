@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"bytes"
+	"sync/atomic"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+)
+
+// skiConventionKey is implemented by the asymmetric keys, whose SKI follows
+// the convention of the CSP they belong to. initSKIConvention sets the
+// convention of keys which do not follow one yet, such as keys found in a
+// KeyStore under a legacy convention.
+type skiConventionKey interface {
+	initSKIConvention(c utils.SKIConvention)
+}
+
+// publicKeySKI returns the SKI of pub with convention c, or with the
+// convention of the process when c is nil.
+func publicKeySKI(pub interface{}, c *utils.SKIConvention) []byte {
+	if c == nil {
+		return utils.SKI(pub)
+	}
+	ski, err := utils.ComputeSKI(pub, *c)
+	if err != nil {
+		return nil
+	}
+	return ski
+}
+
+// skiMemo memoizes the SKI of an asymmetric key, which is computed on every
+// signature, by hashing the public key, together with the convention it
+// was derived with. The zero value is ready to use.
+type skiMemo struct {
+	v atomic.Value // *memoizedSKI
+}
+
+type memoizedSKI struct {
+	convention utils.SKIConvention
+	ski        []byte
+}
+
+// get returns the SKI of pub like publicKeySKI, deriving it only when the
+// convention differs from the one of the memoized SKI.
+func (m *skiMemo) get(pub interface{}, c *utils.SKIConvention) []byte {
+	convention := utils.GetSKIConvention()
+	if c != nil {
+		convention = *c
+	}
+	if s, ok := m.v.Load().(*memoizedSKI); ok && s.convention == convention {
+		return s.ski
+	}
+
+	ski := publicKeySKI(pub, &convention)
+	// The SKI is shared by the callers: appending to it must not write
+	// past its length.
+	ski = ski[:len(ski):len(ski)]
+	m.v.Store(&memoizedSKI{convention: convention, ski: ski})
+	return ski
+}
+
+// withSKIConvention sets convention c on k, when c is set and k does not
+// follow a convention yet.
+func withSKIConvention(k bccsp.Key, c *utils.SKIConvention) bccsp.Key {
+	if c == nil {
+		return k
+	}
+	if kk, ok := k.(skiConventionKey); ok {
+		kk.initSKIConvention(*c)
+	}
+	return k
+}
+
+// storedSKIConvention sets on k, loaded from a KeyStore by ski, the
+// convention ski was derived with, so that keys stored before the
+// convention of the provider changed keep the SKI they are stored by.
+func storedSKIConvention(k bccsp.Key, pub interface{}, ski []byte) bccsp.Key {
+	c := utils.GetSKIConvention()
+	if !bytes.Equal(publicKeySKI(pub, &c), ski) {
+		if matched, ok := utils.MatchSKI(pub, ski); ok {
+			c = matched
+		}
+	}
+	return withSKIConvention(k, &c)
+}
+
+// skiConventionKeyStore sets the SKI convention of the CSP on the keys
+// loaded from the underlying KeyStore, so that GetKey(ski) returns a key
+// whose SKI is ski. Keys the KeyStore found under another convention keep
+// that convention.
+type skiConventionKeyStore struct {
+	bccsp.KeyStore
+	convention utils.SKIConvention
+}
+
+func (ks *skiConventionKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	k, err := ks.KeyStore.GetKey(ski)
+	if err != nil {
+		return nil, err
+	}
+	return withSKIConvention(k, &ks.convention), nil
+}
@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSKIConvention(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewInMemoryKeyStore(), WithSKIConvention(utils.SKISM3))
+	require.NoError(t, err)
+
+	for _, opts := range []bccsp.KeyGenOpts{&bccsp.ECDSAP256KeyGenOpts{}, &bccsp.SM2KeyGenOpts{}} {
+		k, err := csp.KeyGen(opts)
+		require.NoError(t, err)
+
+		pub, err := k.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+		require.NoError(t, err)
+		ski, err := utils.ComputeSKI(pub, utils.SKISM3)
+		require.NoError(t, err)
+		assert.Equal(t, ski, k.SKI())
+
+		pk, err := k.PublicKey()
+		require.NoError(t, err)
+		assert.Equal(t, ski, pk.SKI())
+
+		k, err = csp.GetKey(ski)
+		require.NoError(t, err)
+		assert.Equal(t, ski, k.SKI())
+	}
+}
+
+func TestSKIConventionLegacyKeyStore(t *testing.T) {
+	t.Parallel()
+
+	ksPath, err := ioutil.TempDir("", "bccspks")
+	require.NoError(t, err)
+	defer os.RemoveAll(ksPath)
+
+	// Keys stored with the SHA-256 SKIs of the fabric convention
+	ks, err := NewFileBasedKeyStore(nil, ksPath, false)
+	require.NoError(t, err)
+	legacy, err := NewWithParams(256, "SM3", ks, WithSKIConvention(utils.SKIFabric))
+	require.NoError(t, err)
+	k, err := legacy.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+	legacySKI := k.SKI()
+
+	// are found by a provider hashing with SM3 only, by either SKI
+	ks, err = NewFileBasedKeyStore(nil, ksPath, false)
+	require.NoError(t, err)
+	csp, err := NewWithParams(256, "SM3", ks, WithSKIConvention(utils.SKISM3))
+	require.NoError(t, err)
+
+	pub, err := k.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+	require.NoError(t, err)
+	ski, err := utils.ComputeSKI(pub, utils.SKISM3)
+	require.NoError(t, err)
+	require.NotEqual(t, legacySKI, ski)
+
+	for _, s := range [][]byte{legacySKI, ski} {
+		k, err := csp.GetKey(s)
+		require.NoError(t, err)
+		assert.Equal(t, s, k.SKI())
+		assert.True(t, k.Private())
+
+		signature, err := csp.Sign(k, []byte("digest"), nil)
+		require.NoError(t, err)
+		valid, err := legacy.Verify(k, signature, []byte("digest"), nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	}
+}
+
+func TestSKIMemo(t *testing.T) {
+	// testing.AllocsPerRun panics in parallel tests
+	ks := NewInMemoryKeyStore()
+	csp, err := NewWithParams(256, "SM3", ks)
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	require.NoError(t, err)
+	pub, err := k.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+	require.NoError(t, err)
+
+	// Computed once, then neither hashed nor encoded again
+	ski := k.SKI()
+	assert.Equal(t, utils.SKI(pub), ski)
+	assert.Zero(t, testing.AllocsPerRun(10, func() { k.SKI() }))
+	assert.Zero(t, testing.AllocsPerRun(10, func() { ks.GetKey(ski) }))
+
+	// Appending to the SKI of one caller does not change the SKI of others
+	_ = append(k.SKI(), 0xff)
+	assert.Equal(t, ski, k.SKI())
+
+	// The SKI is derived again when the convention changes
+	var m skiMemo
+	for _, c := range []utils.SKIConvention{utils.SKIFabric, utils.SKITruncatedSHA256, utils.SKIFabric} {
+		expected, err := utils.ComputeSKI(pub, c)
+		require.NoError(t, err)
+		assert.Equal(t, expected, m.get(pub, &c))
+	}
+}
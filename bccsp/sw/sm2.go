@@ -16,7 +16,12 @@ limitations under the License.
 package sw
 
 import (
+	"errors"
+	"fmt"
+	"io"
+
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/paul-lee-attorney/gm/sm2"
 )
 
@@ -38,20 +43,182 @@ func verifySM2(k *sm2.PublicKey, signature, digest []byte, opts bccsp.SignerOpts
 	return valid, nil
 }
 
-type sm2Signer struct{}
+// verifySM2Precomputed 与verifySM2结果相同, 但使用生成元的预计算表与
+// Jacobian坐标计算验签方程中的多标量乘法, 耗时不到verifySM2的一半。
+func verifySM2Precomputed(k *sm2.PublicKey, signature, digest []byte) (valid bool, err error) {
+	r, s, err := utils.UnmarshalECDSASignature(signature)
+	if err != nil {
+		return false, nil
+	}
+	return utils.SM2Verify(k, nil, digest, r, s), nil
+}
+
+// signSM2WithRand 使用指定的随机数源生成SM2签名, 返回DER编码的签名。
+func signSM2WithRand(prng io.Reader, k *sm2.PrivateKey, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
+	r, s, err := utils.SM2SignWithRand(prng, k, nil, digest)
+	if err != nil {
+		return nil, err
+	}
+	return utils.MarshalECDSASignature(r, s)
+}
+
+// encodeSM2Signature 将DER编码的签名按opts要求转换编码格式
+func encodeSM2Signature(der []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if bccsp.SM2SignatureEncodingOf(opts) == bccsp.SM2SignatureRaw {
+		return utils.SM2SignatureDERToRaw(der)
+	}
+	return der, nil
+}
+
+// decodeSM2Signature 将opts所指定编码格式的签名转换为DER编码,
+// 宽松解析模式mode下同时规范化非DER编码的签名
+func decodeSM2Signature(signature []byte, opts bccsp.SignerOpts, mode utils.ASN1Mode) ([]byte, error) {
+	switch bccsp.SM2SignatureEncodingOf(opts) {
+	case bccsp.SM2SignatureRaw:
+		return utils.SM2SignatureRawToDER(signature)
+	case bccsp.SM2SignatureAny:
+		if _, _, err := utils.UnmarshalECDSASignatureWithMode(signature, mode); err == nil {
+			return utils.NormalizeSignatureWithMode(signature, mode)
+		}
+		return utils.SM2SignatureRawToDER(signature)
+	default:
+		return utils.NormalizeSignatureWithMode(signature, mode)
+	}
+}
 
-func (s *sm2Signer) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
-	return signSM2(k.(*sm2PrivateKey).privKey, digest, opts)
+type sm2Signer struct {
+	rand    io.Reader        // 为nil时使用sm2库内置的随机数源
+	precomp *sm2PrecompCache // 为nil时不使用预计算
+	gm      gmBackend        // 为nil时使用纯Go实现
 }
 
-type sm2PrivateKeyVerifier struct{}
+func (s *sm2Signer) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	if k.(*sm2PrivateKey).usage == bccsp.SM2KeyUsageEncrypt {
+		return nil, errors.New("SM2 key is tagged for encryption and must not be used for signing")
+	}
+
+	signature, err := s.sign(k, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	return encodeSM2Signature(signature, opts)
+}
+
+func (s *sm2Signer) sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) (signature []byte, err error) {
+	if s.precomp != nil {
+		sk := k.(*sm2PrivateKey)
+		pre, err := s.precomp.get(sk)
+		if err != nil {
+			return nil, err
+		}
+		r, ss, err := utils.SM2SignPrecomputed(randOrDefault(s.rand), sk.privKey, pre, digest)
+		if err != nil {
+			return nil, err
+		}
+		return utils.MarshalECDSASignature(r, ss)
+	}
+	return gmBackendOrDefault(s.gm).sm2Sign(s.rand, k.(*sm2PrivateKey).privKey, digest)
+}
+
+// gmBackendOrDefault 返回gm, gm为nil时返回纯Go实现
+func gmBackendOrDefault(gm gmBackend) gmBackend {
+	if gm == nil {
+		return goGMBackend{}
+	}
+	return gm
+}
+
+type sm2PrivateKeyVerifier struct {
+	precomputed bool            // 是否使用预计算的多标量乘法验签
+	gm          gmBackend       // 为nil时使用纯Go实现
+	asn1Mode    *utils.ASN1Mode // 为nil时使用进程的解析模式
+}
 
 func (v *sm2PrivateKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (valid bool, err error) {
-	return verifySM2(&(k.(*sm2PrivateKey).privKey.PublicKey), signature, digest, opts)
+	der, err := decodeSM2Signature(signature, opts, asn1ModeOrDefault(v.asn1Mode))
+	if err != nil {
+		return false, err
+	}
+	if v.precomputed {
+		return verifySM2Precomputed(&(k.(*sm2PrivateKey).privKey.PublicKey), der, digest)
+	}
+	return gmBackendOrDefault(v.gm).sm2Verify(&(k.(*sm2PrivateKey).privKey.PublicKey), der, digest), nil
 }
 
-type sm2PublicKeyKeyVerifier struct{}
+type sm2PublicKeyKeyVerifier struct {
+	precomputed bool            // 是否使用预计算的多标量乘法验签
+	gm          gmBackend       // 为nil时使用纯Go实现
+	asn1Mode    *utils.ASN1Mode // 为nil时使用进程的解析模式
+}
 
 func (v *sm2PublicKeyKeyVerifier) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (valid bool, err error) {
-	return verifySM2(k.(*sm2PublicKey).pubKey, signature, digest, opts)
+	der, err := decodeSM2Signature(signature, opts, asn1ModeOrDefault(v.asn1Mode))
+	if err != nil {
+		return false, err
+	}
+	if v.precomputed {
+		return verifySM2Precomputed(k.(*sm2PublicKey).pubKey, der, digest)
+	}
+	return gmBackendOrDefault(v.gm).sm2Verify(k.(*sm2PublicKey).pubKey, der, digest), nil
+}
+
+// sm2EncrypterOpts 解析SM2加密选项, nil表示使用默认选项(无标签)
+func sm2EncrypterOpts(opts interface{}) (*bccsp.SM2EncrypterOpts, error) {
+	switch o := opts.(type) {
+	case nil:
+		return &bccsp.SM2EncrypterOpts{}, nil
+	case *bccsp.SM2EncrypterOpts:
+		return o, nil
+	case bccsp.SM2EncrypterOpts:
+		return &o, nil
+	default:
+		return nil, fmt.Errorf("Mode not recognized [%s]", opts)
+	}
+}
+
+type sm2Encryptor struct {
+	rand io.Reader // 为nil时使用crypto/rand
+}
+
+// Encrypt encrypts plaintext with the SM2 public key k, or with the public
+// part of the private key k.
+func (e *sm2Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) ([]byte, error) {
+	o, err := sm2EncrypterOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var pub *sm2.PublicKey
+	var usage bccsp.SM2KeyUsage
+	switch kk := k.(type) {
+	case *sm2PublicKey:
+		pub, usage = kk.pubKey, kk.usage
+	case *sm2PrivateKey:
+		pub, usage = &kk.privKey.PublicKey, kk.usage
+	}
+	if usage == bccsp.SM2KeyUsageSign {
+		return nil, errors.New("SM2 key is tagged for signing and must not be used for encryption")
+	}
+
+	prng := o.PRNG
+	if prng == nil {
+		prng = e.rand
+	}
+	return utils.SM2Encrypt(randOrDefault(prng), pub, plaintext, o.Label)
+}
+
+type sm2Decryptor struct{}
+
+// Decrypt decrypts ciphertext with the SM2 private key k.
+func (*sm2Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	o, err := sm2EncrypterOpts(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sk := k.(*sm2PrivateKey)
+	if sk.usage == bccsp.SM2KeyUsageSign {
+		return nil, errors.New("SM2 key is tagged for signing and must not be used for encryption")
+	}
+	return utils.SM2Decrypt(sk.privKey, ciphertext, o.Label)
 }
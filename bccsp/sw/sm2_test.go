@@ -0,0 +1,180 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/signer"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSM2SignatureEncoding(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewInMemoryKeyStore())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	digest := []byte("Hello World")
+	raw, err := csp.Sign(k, digest, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureRaw})
+	assert.NoError(t, err)
+	assert.Len(t, raw, 64)
+
+	valid, err := csp.Verify(pk, raw, digest, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureRaw})
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	valid, err = csp.Verify(k, raw, digest, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureAny})
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	der, err := utils.SM2SignatureRawToDER(raw)
+	assert.NoError(t, err)
+	valid, err = csp.Verify(pk, der, digest, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+	valid, err = csp.Verify(pk, der, digest, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureAny})
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	der, err = csp.Sign(k, digest, &bccsp.SM2SignerOpts{})
+	assert.NoError(t, err)
+	_, _, err = utils.UnmarshalECDSASignature(der)
+	assert.NoError(t, err)
+
+	_, err = csp.Verify(pk, der, digest, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureRaw})
+	assert.Error(t, err)
+}
+
+func TestSM2PKIXPublicKeyImport(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewInMemoryKeyStore())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	der, err := utils.MarshalPKIXSM2PublicKey(&k.(*sm2PrivateKey).privKey.PublicKey)
+	assert.NoError(t, err)
+
+	pk, err := csp.KeyImport(der, &bccsp.SM2PKIXPublicKeyImportOpts{Temporary: true})
+	assert.NoError(t, err)
+	assert.Equal(t, k.SKI(), pk.SKI())
+
+	digest := []byte("Hello World")
+	signature, err := csp.Sign(k, digest, nil)
+	assert.NoError(t, err)
+	valid, err := csp.Verify(pk, signature, digest, nil)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestSM2CryptoSigner(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewInMemoryKeyStore())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	cryptoSigner, err := signer.New(csp, k)
+	assert.NoError(t, err)
+	pub, ok := cryptoSigner.Public().(*sm2.PublicKey)
+	assert.True(t, ok)
+	assert.Equal(t, &k.(*sm2PrivateKey).privKey.PublicKey, pub)
+
+	msg := []byte("Hello World")
+	signature, err := cryptoSigner.Sign(rand.Reader, msg, &bccsp.SM2SignerOpts{})
+	assert.NoError(t, err)
+	assert.True(t, sm2.Verify(pub, nil, msg, signature))
+}
+
+func TestSM2FastVerify(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewInMemoryKeyStore())
+	assert.NoError(t, err)
+	fast, err := NewWithParams(256, "SM3", NewInMemoryKeyStore(), WithSM2FastVerify())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	digest := []byte("Hello World")
+	signature, err := csp.Sign(k, digest, nil)
+	assert.NoError(t, err)
+
+	for _, key := range []bccsp.Key{k, pk} {
+		valid, err := fast.Verify(key, signature, digest, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = fast.Verify(key, signature, []byte("Hello World!"), nil)
+		assert.NoError(t, err)
+		assert.False(t, valid)
+
+		valid, err = fast.Verify(key, []byte{0, 1, 2}, digest, nil)
+		assert.NoError(t, err)
+		assert.False(t, valid)
+
+		valid, err = fast.Verify(key, []byte{0, 1, 2}, digest, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureAny})
+		assert.Error(t, err)
+		assert.False(t, valid)
+	}
+}
+
+func TestSM2Encryption(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewInMemoryKeyStore())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true, Usage: bccsp.SM2KeyUsageEncrypt})
+	assert.NoError(t, err)
+	pk, err := k.PublicKey()
+	assert.NoError(t, err)
+
+	msg := []byte("Hello World")
+	opts := &bccsp.SM2EncrypterOpts{Label: []byte("pvtdata")}
+	ct, err := csp.Encrypt(pk, msg, opts)
+	assert.NoError(t, err)
+
+	pt, err := csp.Decrypt(k, ct, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	_, err = csp.Decrypt(k, ct, nil)
+	assert.Error(t, err)
+
+	ct, err = csp.Encrypt(k, msg, nil)
+	assert.NoError(t, err)
+	pt, err = csp.Decrypt(k, ct, bccsp.SM2EncrypterOpts{})
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+
+	_, err = csp.Encrypt(pk, msg, &bccsp.AESCBCPKCS7ModeOpts{})
+	assert.Error(t, err)
+
+	// Signing keys are refused
+	sk, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true, Usage: bccsp.SM2KeyUsageSign})
+	assert.NoError(t, err)
+	_, err = csp.Encrypt(sk, msg, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "SM2 key is tagged for signing and must not be used for encryption")
+}
@@ -16,16 +16,20 @@ limitations under the License.
 package sw
 
 import (
-	"crypto/elliptic"
+	"crypto"
 	"errors"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/paul-lee-attorney/gm/sm2"
-	"github.com/paul-lee-attorney/gm/sm3"
 )
 
 type sm2PrivateKey struct {
 	privKey *sm2.PrivateKey
+	usage   bccsp.SM2KeyUsage
+	attrs   map[string]string
+	skiConv *utils.SKIConvention
+	ski     skiMemo
 }
 
 // Bytes converts this key to its byte representation,
@@ -41,13 +45,7 @@ func (k *sm2PrivateKey) SKI() (ski []byte) {
 		return nil
 	}
 
-	//Marshall the public key
-	raw := elliptic.Marshal(k.privKey.Curve, k.privKey.X, k.privKey.Y)
-
-	// Hash it
-	hash := sm3.New()
-	hash.Write(raw)
-	return hash.Sum(nil)
+	return k.ski.get(&k.privKey.PublicKey, k.skiConv)
 }
 
 // Symmetric returns true if this key is a symmetric key,
@@ -65,11 +63,19 @@ func (k *sm2PrivateKey) Private() bool {
 // PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
 // This method returns an error in symmetric key schemes.
 func (k *sm2PrivateKey) PublicKey() (bccsp.Key, error) {
-	return &sm2PublicKey{&k.privKey.PublicKey}, nil
+	return &sm2PublicKey{pubKey: &k.privKey.PublicKey, usage: k.usage, skiConv: k.skiConv}, nil
+}
+
+// Usage returns the usage the key was generated for.
+func (k *sm2PrivateKey) Usage() bccsp.SM2KeyUsage {
+	return k.usage
 }
 
 type sm2PublicKey struct {
-	pubKey *sm2.PublicKey
+	pubKey  *sm2.PublicKey
+	usage   bccsp.SM2KeyUsage
+	skiConv *utils.SKIConvention
+	ski     skiMemo
 }
 
 // Bytes converts this key to its byte representation,
@@ -85,13 +91,7 @@ func (k *sm2PublicKey) SKI() (ski []byte) {
 		return nil
 	}
 
-	// Marshall the public key
-	raw := elliptic.Marshal(k.pubKey.Curve, k.pubKey.X, k.pubKey.Y)
-
-	// Hash it
-	hash := sm3.New()
-	hash.Write(raw)
-	return hash.Sum(nil)
+	return k.ski.get(k.pubKey, k.skiConv)
 }
 
 // Symmetric returns true if this key is a symmetric key,
@@ -111,3 +111,30 @@ func (k *sm2PublicKey) Private() bool {
 func (k *sm2PublicKey) PublicKey() (bccsp.Key, error) {
 	return k, nil
 }
+
+// Usage returns the usage the key was generated for.
+func (k *sm2PublicKey) Usage() bccsp.SM2KeyUsage {
+	return k.usage
+}
+
+// CryptoPublicKey returns the public part of this key as *sm2.PublicKey.
+func (k *sm2PrivateKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return &k.privKey.PublicKey, nil
+}
+
+// CryptoPublicKey returns this key as *sm2.PublicKey.
+func (k *sm2PublicKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.pubKey, nil
+}
+
+func (k *sm2PrivateKey) initSKIConvention(c utils.SKIConvention) {
+	if k.skiConv == nil {
+		k.skiConv = &c
+	}
+}
+
+func (k *sm2PublicKey) initSKIConvention(c utils.SKIConvention) {
+	if k.skiConv == nil {
+		k.skiConv = &c
+	}
+}
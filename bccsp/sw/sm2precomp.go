@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+)
+
+// defaultSM2PrecompCacheSize is the number of private keys whose signing
+// precomputation is cached.
+const defaultSM2PrecompCacheSize = 1024
+
+// sm2PrecompCache caches the SM2 signing precomputation of private keys by
+// SKI. The least recently used keys are evicted beyond its size.
+type sm2PrecompCache struct {
+	size int
+
+	// most recently used keys first
+	mutex sync.Mutex
+	items *list.List
+	table map[string]*list.Element
+}
+
+type sm2PrecompEntry struct {
+	ski string
+	pre *utils.SM2Precomputed
+}
+
+func newSM2PrecompCache(size int) *sm2PrecompCache {
+	return &sm2PrecompCache{
+		size:  size,
+		items: list.New(),
+		table: make(map[string]*list.Element),
+	}
+}
+
+// get returns the precomputation of k, computing and caching it if needed.
+func (c *sm2PrecompCache) get(k *sm2PrivateKey) (*utils.SM2Precomputed, error) {
+	ski := string(k.SKI())
+
+	if pre, ok := c.lookup(ski); ok {
+		return pre, nil
+	}
+
+	pre, err := utils.NewSM2Precomputed(k.privKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.add(ski, pre)
+
+	return pre, nil
+}
+
+func (c *sm2PrecompCache) lookup(ski string) (*utils.SM2Precomputed, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.table[ski]
+	if !ok {
+		return nil, false
+	}
+	c.items.MoveToFront(elem)
+	return elem.Value.(*sm2PrecompEntry).pre, true
+}
+
+func (c *sm2PrecompCache) add(ski string, pre *utils.SM2Precomputed) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.table[ski]; ok {
+		c.items.MoveToFront(elem)
+		return
+	}
+	c.table[ski] = c.items.PushFront(&sm2PrecompEntry{ski: ski, pre: pre})
+	if c.items.Len() > c.size {
+		victim := c.items.Back()
+		c.items.Remove(victim)
+		delete(c.table, victim.Value.(*sm2PrecompEntry).ski)
+	}
+}
+
+// sm2PrecompKeyStore warms the precomputation cache whenever
+// an SM2 private key is loaded from the underlying KeyStore.
+type sm2PrecompKeyStore struct {
+	bccsp.KeyStore
+	cache *sm2PrecompCache
+}
+
+func (ks *sm2PrecompKeyStore) GetKey(ski []byte) (bccsp.Key, error) {
+	k, err := ks.KeyStore.GetKey(ski)
+	if err != nil {
+		return nil, err
+	}
+
+	if sk, ok := k.(*sm2PrivateKey); ok {
+		if _, err := ks.cache.get(sk); err != nil {
+			logger.Warningf("Failed SM2 signing precomputation for key [%x]: [%s]", ski, err)
+		}
+	}
+
+	return k, nil
+}
@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSM2Precomputation(t *testing.T) {
+	t.Parallel()
+
+	ks := NewInMemoryKeyStore()
+	csp, err := NewWithParams(256, "SM3", ks, WithSM2Precomputation())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	assert.NoError(t, err)
+
+	// Loading the key from the keystore warms the cache
+	k, err = csp.GetKey(k.SKI())
+	assert.NoError(t, err)
+
+	signer := csp.(*CSP).Signers[reflect.TypeOf(&sm2PrivateKey{})].(*sm2Signer)
+	_, cached := signer.precomp.lookup(string(k.SKI()))
+	assert.True(t, cached)
+
+	digest := []byte("Hello World")
+	for i := 0; i < 5; i++ {
+		signature, err := csp.Sign(k, digest, nil)
+		assert.NoError(t, err)
+
+		valid, err := csp.Verify(k, signature, digest, nil)
+		assert.NoError(t, err)
+		assert.True(t, valid)
+	}
+}
+
+func TestSM2PrecompCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewDummyKeyStore())
+	assert.NoError(t, err)
+	c := newSM2PrecompCache(2)
+
+	var keys []*sm2PrivateKey
+	for i := 0; i < 3; i++ {
+		k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+		assert.NoError(t, err)
+		keys = append(keys, k.(*sm2PrivateKey))
+	}
+
+	for _, k := range keys[:2] {
+		_, err := c.get(k)
+		assert.NoError(t, err)
+	}
+	// Using the first key makes the second one the least recently used
+	_, err = c.get(keys[0])
+	assert.NoError(t, err)
+	_, err = c.get(keys[2])
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, c.items.Len())
+	for i, expected := range []bool{true, false, true} {
+		_, cached := c.lookup(string(keys[i].SKI()))
+		assert.Equal(t, expected, cached, "key %d", i)
+	}
+}
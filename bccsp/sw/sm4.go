@@ -17,44 +17,102 @@ limitations under the License.
 package sw
 
 import (
+	"crypto/cipher"
 	"errors"
+	"fmt"
+	"io"
 
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
-	"github.com/paul-lee-attorney/gm/sm4"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm4"
 )
 
+// sm4NewCipher 为默认的基于查表实现的SM4分组密码构造函数, 其GCM及CTR模式在支持
+// AES-NI、GFNI或ARMv8加密扩展的CPU上以SIMD指令同时加密多个分组
+func sm4NewCipher(key []byte) (cipher.Block, error) {
+	return sm4.NewCipher(key)
+}
+
 // SM4Encrypt encrypt the srouce message into cypher message of the same length
 func SM4Encrypt(key, src []byte) ([]byte, error) {
+	return sm4EncryptWith(sm4NewCipher, key, src)
+}
+
+// SM4Decrypt decrypt the cypher message into plain text with the private key
+func SM4Decrypt(key, src []byte) ([]byte, error) {
+	return sm4DecryptWith(sm4NewCipher, key, src)
+}
+
+func sm4EncryptWith(newCipher func([]byte) (cipher.Block, error), key, src []byte) ([]byte, error) {
 	dst := make([]byte, len(src))
-	c, err := sm4.NewCipher(key)
+	c, err := newCipher(key)
 	if err != nil {
 		return nil, errors.New("Error incurred upon new cipher stage")
 	}
+	if len(src) != c.BlockSize() {
+		return nil, fmt.Errorf("invalid SM4 block length [%d], must be %d bytes", len(src), c.BlockSize())
+	}
 	c.Encrypt(dst, src)
 	return dst, nil
 }
 
-// SM4Decrypt decrypt the cypher message into plain text with the private key
-func SM4Decrypt(key, src []byte) ([]byte, error) {
+func sm4DecryptWith(newCipher func([]byte) (cipher.Block, error), key, src []byte) ([]byte, error) {
 	dst := make([]byte, len(src))
-	c, err := sm4.NewCipher(key)
+	c, err := newCipher(key)
 	if err != nil {
 		return nil, errors.New("Error incurred upon new cipher stage")
 	}
+	if len(src) != c.BlockSize() {
+		return nil, fmt.Errorf("invalid SM4 block length [%d], must be %d bytes", len(src), c.BlockSize())
+	}
 	c.Decrypt(dst, src)
 	return dst, nil
 }
 
-type sm4Encryptor struct{}
+// sm4CipherOrDefault 返回配置的SM4实现, 未配置时使用默认的查表实现
+func sm4CipherOrDefault(newCipher func([]byte) (cipher.Block, error)) func([]byte) (cipher.Block, error) {
+	if newCipher == nil {
+		return sm4NewCipher
+	}
+	return newCipher
+}
+
+type sm4Encryptor struct {
+	newCipher func([]byte) (cipher.Block, error)
+	rand      io.Reader // GCM nonce的随机数源, 为nil时使用crypto/rand
+}
 
 // Implement method of Encrypt for the interface of Encryptor
-func (*sm4Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) (ciphertext []byte, err error) {
-	return SM4Encrypt(k.(*sm4PrivateKey).privKey, plaintext)
+func (e *sm4Encryptor) Encrypt(k bccsp.Key, plaintext []byte, opts bccsp.EncrypterOpts) (ciphertext []byte, err error) {
+	switch o := opts.(type) {
+	case *bccsp.AEADOpts:
+		// SM4 in GCM mode
+		block, err := sm4CipherOrDefault(e.newCipher)(k.(*sm4PrivateKey).privKey)
+		if err != nil {
+			return nil, err
+		}
+		return gcmSeal(block, plaintext, o, e.rand)
+	case bccsp.AEADOpts:
+		return e.Encrypt(k, plaintext, &o)
+	}
+	return sm4EncryptWith(sm4CipherOrDefault(e.newCipher), k.(*sm4PrivateKey).privKey, plaintext)
 }
 
-type sm4Decryptor struct{}
+type sm4Decryptor struct {
+	newCipher func([]byte) (cipher.Block, error)
+}
 
 // Implement method of Decrypt for the interface of Decryptor
-func (*sm4Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error) {
-	return SM4Decrypt(k.(*sm4PrivateKey).privKey, ciphertext)
+func (d *sm4Decryptor) Decrypt(k bccsp.Key, ciphertext []byte, opts bccsp.DecrypterOpts) (plaintext []byte, err error) {
+	switch o := opts.(type) {
+	case *bccsp.AEADOpts:
+		// SM4 in GCM mode
+		block, err := sm4CipherOrDefault(d.newCipher)(k.(*sm4PrivateKey).privKey)
+		if err != nil {
+			return nil, err
+		}
+		return gcmOpen(block, ciphertext, o)
+	case bccsp.AEADOpts:
+		return d.Decrypt(k, ciphertext, &o)
+	}
+	return sm4DecryptWith(sm4CipherOrDefault(d.newCipher), k.(*sm4PrivateKey).privKey, ciphertext)
 }
@@ -0,0 +1,89 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package sw
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSM4ConstantTimeCipher(t *testing.T) {
+	t.Parallel()
+
+	// GB/T 32907 Appendix A.1
+	key, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	expected, _ := hex.DecodeString("681edf34d206965e86b3e94f536e4246")
+
+	c, err := newSM4ConstantTimeCipher(key)
+	assert.NoError(t, err)
+	assert.Equal(t, sm4BlockSize, c.BlockSize())
+
+	ct := make([]byte, sm4BlockSize)
+	c.Encrypt(ct, key)
+	assert.Equal(t, expected, ct)
+
+	pt := make([]byte, sm4BlockSize)
+	c.Decrypt(pt, ct)
+	assert.Equal(t, key, pt)
+
+	_, err = newSM4ConstantTimeCipher(key[:8])
+	assert.EqualError(t, err, "invalid SM4 key length [8], must be 16 bytes")
+}
+
+func TestSM4ConstantTimeMatchesDefault(t *testing.T) {
+	t.Parallel()
+
+	key, err := GetRandomBytes(16)
+	assert.NoError(t, err)
+	src, err := GetRandomBytes(16)
+	assert.NoError(t, err)
+
+	expected, err := SM4Encrypt(key, src)
+	assert.NoError(t, err)
+	ct, err := sm4EncryptWith(newSM4ConstantTimeCipher, key, src)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, ct)
+
+	pt, err := sm4DecryptWith(newSM4ConstantTimeCipher, key, ct)
+	assert.NoError(t, err)
+	assert.Equal(t, src, pt)
+}
+
+func TestSM4BlockLength(t *testing.T) {
+	t.Parallel()
+
+	key, err := GetRandomBytes(16)
+	assert.NoError(t, err)
+
+	for _, src := range [][]byte{nil, []byte("private data"), []byte("0123456789abcdef0")} {
+		_, err = SM4Encrypt(key, src)
+		assert.EqualError(t, err, fmt.Sprintf("invalid SM4 block length [%d], must be 16 bytes", len(src)))
+		_, err = SM4Decrypt(key, src)
+		assert.EqualError(t, err, fmt.Sprintf("invalid SM4 block length [%d], must be 16 bytes", len(src)))
+	}
+}
+
+func TestNewWithConstantTimeSM4(t *testing.T) {
+	t.Parallel()
+
+	csp, err := NewWithParams(256, "SM3", NewDummyKeyStore(), WithConstantTimeSM4())
+	assert.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM4KeyGenOpts{Temporary: true})
+	assert.NoError(t, err)
+
+	msg := []byte("0123456789abcdef")
+	ct, err := csp.Encrypt(k, msg, nil)
+	assert.NoError(t, err)
+	pt, err := csp.Decrypt(k, ct, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, msg, pt)
+}
@@ -66,3 +66,8 @@ func (k *sm4PrivateKey) Private() bool {
 func (k *sm4PrivateKey) PublicKey() (bccsp.Key, error) {
 	return nil, errors.New("Cannot call this method on a symmetric key")
 }
+
+// Algorithm returns the key algorithm identifier.
+func (k *sm4PrivateKey) Algorithm() string {
+	return bccsp.SM4
+}
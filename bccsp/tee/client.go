@@ -0,0 +1,97 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tee
+
+import (
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// client calls the enclave host, redialing once the connection is lost.
+type client struct {
+	dial    func() (net.Conn, error)
+	timeout time.Duration
+
+	mutex sync.Mutex
+	rpc   *rpc.Client
+}
+
+func newClient(address string, timeout time.Duration) *client {
+	network := "tcp"
+	if strings.HasPrefix(address, "/") || strings.HasPrefix(address, "@") {
+		network = "unix"
+	}
+	return &client{
+		dial: func() (net.Conn, error) {
+			return net.DialTimeout(network, address, timeout)
+		},
+		timeout: timeout,
+	}
+}
+
+func (c *client) conn() (*rpc.Client, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.rpc == nil {
+		conn, err := c.dial()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed connecting to enclave host")
+		}
+		c.rpc = jsonrpc.NewClient(conn)
+	}
+	return c.rpc, nil
+}
+
+// drop closes rc unless another caller replaced it already.
+func (c *client) drop(rc *rpc.Client) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.rpc == rc {
+		c.rpc.Close()
+		c.rpc = nil
+	}
+}
+
+// call invokes method of the enclave host, retrying once on a fresh
+// connection if the current one was shut down.
+func (c *client) call(method string, args, reply interface{}) error {
+	for attempt := 0; ; attempt++ {
+		rc, err := c.conn()
+		if err != nil {
+			return err
+		}
+
+		call := rc.Go(ServiceName+"."+method, args, reply, make(chan *rpc.Call, 1))
+		select {
+		case <-call.Done:
+			err = call.Error
+		case <-time.After(c.timeout):
+			c.drop(rc)
+			return errors.Errorf("enclave %s timed out after %s", method, c.timeout)
+		}
+
+		if err == rpc.ErrShutdown && attempt == 0 {
+			c.drop(rc)
+			continue
+		}
+		if err != nil {
+			if _, ok := err.(rpc.ServerError); !ok {
+				c.drop(rc)
+			}
+			return errors.Wrapf(err, "enclave %s failed", method)
+		}
+		return nil
+	}
+}
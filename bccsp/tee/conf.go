@@ -0,0 +1,41 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tee
+
+import "time"
+
+// TEEOpts contains options for the TEEFactory, whose BCCSP delegates the
+// private key operations to an enclave (SGX or TrustZone) through the
+// host process running it.
+type TEEOpts struct {
+	// Default algorithms of the software fallback
+	SecLevel   int    `mapstructure:"security" json:"security"`
+	HashFamily string `mapstructure:"hash" json:"hash"`
+
+	// Address of the enclave host, a unix socket path or host:port
+	Address string `mapstructure:"address" json:"address"`
+	// Timeout bounds each call to the enclave host
+	Timeout time.Duration `mapstructure:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Measurements lists the hex encoded enclave measurements (MRENCLAVE,
+	// or the trusted application hash) keys are accepted from. If empty,
+	// any measurement is accepted and left to the relying party to check.
+	Measurements []string `mapstructure:"measurements,omitempty" json:"measurements,omitempty"`
+}
+
+// Key attributes carrying the remote attestation evidence of enclave keys.
+const (
+	// KeyAttrAttestationType is the evidence format, e.g. sgx-dcap or psa-token
+	KeyAttrAttestationType = "attestationtype"
+	// KeyAttrAttestationEvidence is the base64 encoded evidence, binding the
+	// public key to the enclave through its report data
+	KeyAttrAttestationEvidence = "attestationevidence"
+	// KeyAttrMeasurement is the hex encoded measurement of the enclave
+	KeyAttrMeasurement = "measurement"
+)
+
+const defaultTimeout = 5 * time.Second
@@ -0,0 +1,247 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tee
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("bccsp_tee")
+
+// New returns a new instance of the enclave-based BCCSP. SM2 and ECDSA
+// signing keys are generated and used inside the enclave served by the
+// host at opts.Address, all other operations are served by a software
+// BCCSP set at the passed security level, hash family and KeyStore.
+func New(opts TEEOpts, keyStore bccsp.KeyStore) (bccsp.BCCSP, error) {
+	if opts.Address == "" {
+		return nil, errors.New("Invalid options. Address must be set")
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	return newWithClient(opts, keyStore, newClient(opts.Address, opts.Timeout))
+}
+
+func newWithClient(opts TEEOpts, keyStore bccsp.KeyStore, c *client) (*impl, error) {
+	// Check KeyStore
+	if keyStore == nil {
+		return nil, errors.New("Invalid bccsp.KeyStore instance. It must be different from nil")
+	}
+
+	swCSP, err := sw.NewWithParams(opts.SecLevel, opts.HashFamily, keyStore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed initializing fallback SW BCCSP")
+	}
+
+	measurements := map[string]bool{}
+	for _, m := range opts.Measurements {
+		raw, err := hex.DecodeString(m)
+		if err != nil {
+			return nil, errors.Errorf("Invalid measurement [%s]", m)
+		}
+		measurements[hex.EncodeToString(raw)] = true
+	}
+
+	return &impl{
+		BCCSP:        swCSP,
+		client:       c,
+		secLevel:     opts.SecLevel,
+		measurements: measurements,
+		keys:         map[string]*teeKey{},
+	}, nil
+}
+
+type impl struct {
+	bccsp.BCCSP
+
+	client   *client
+	secLevel int
+
+	// measurements is the set of accepted enclave measurements, empty if
+	// any is accepted
+	measurements map[string]bool
+
+	// keys maps the hex encoded SKI to the keys seen from the enclave
+	keysMutex sync.RWMutex
+	keys      map[string]*teeKey
+}
+
+// bindKey checks the enclave measurement of reply and records the key.
+// The SKI is derived the same way the software provider derives it from
+// the public key, and must match the one reported by the enclave host.
+func (csp *impl) bindKey(reply *KeyReply) (*teeKey, error) {
+	if len(csp.measurements) != 0 && !csp.measurements[hex.EncodeToString(reply.Measurement)] {
+		return nil, errors.Errorf("Enclave measurement [%x] is not accepted", reply.Measurement)
+	}
+
+	pub, err := utils.DERToPublicKey(reply.PublicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed parsing enclave public key")
+	}
+
+	var opts bccsp.KeyImportOpts
+	switch pub.(type) {
+	case *sm2.PublicKey:
+		opts = &bccsp.SM2GoPublicKeyImportOpts{Temporary: true}
+	case *ecdsa.PublicKey:
+		opts = &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true}
+	default:
+		return nil, errors.Errorf("Unsupported public key type [%T]", pub)
+	}
+	pk, err := csp.BCCSP.KeyImport(pub, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed importing enclave public key")
+	}
+	if !bytes.Equal(pk.SKI(), reply.SKI) {
+		return nil, errors.Errorf("Enclave reported SKI [%x] for a key with SKI [%x]", reply.SKI, pk.SKI())
+	}
+
+	k := &teeKey{
+		pub:             pk,
+		cryptoPub:       pub,
+		attestationType: reply.AttestationType,
+		evidence:        reply.Evidence,
+		measurement:     reply.Measurement,
+	}
+	csp.keysMutex.Lock()
+	csp.keys[hex.EncodeToString(pk.SKI())] = k
+	csp.keysMutex.Unlock()
+	return k, nil
+}
+
+// KeyGen generates a key using opts. SM2 and ECDSA signing keys are
+// generated in the enclave, all other keys by the software fallback.
+func (csp *impl) KeyGen(opts bccsp.KeyGenOpts) (bccsp.Key, error) {
+	// Validate arguments
+	if opts == nil {
+		return nil, errors.New("Invalid Opts parameter. It must not be nil")
+	}
+
+	var alg string
+	switch o := opts.(type) {
+	case *bccsp.SM2KeyGenOpts:
+		if o.Curve != nil || o.CurveOID != nil || o.Usage == bccsp.SM2KeyUsageEncrypt {
+			return csp.BCCSP.KeyGen(opts)
+		}
+		alg = AlgSM2
+	case *bccsp.ECDSAKeyGenOpts:
+		alg = AlgECDSAP256
+		if csp.secLevel == 384 {
+			alg = AlgECDSAP384
+		}
+	case *bccsp.ECDSAP256KeyGenOpts:
+		alg = AlgECDSAP256
+	case *bccsp.ECDSAP384KeyGenOpts:
+		alg = AlgECDSAP384
+	default:
+		return csp.BCCSP.KeyGen(opts)
+	}
+
+	var reply KeyReply
+	if err := csp.client.call("GenerateKey", &GenerateKeyArgs{Algorithm: alg, Ephemeral: opts.Ephemeral()}, &reply); err != nil {
+		return nil, err
+	}
+	k, err := csp.bindKey(&reply)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Generated %s key in enclave, SKI %x", alg, k.SKI())
+	return k, nil
+}
+
+// GetKey returns the key this CSP associates to
+// the Subject Key Identifier ski. Keys unknown to the enclave are looked
+// up in the local KeyStore.
+func (csp *impl) GetKey(ski []byte) (bccsp.Key, error) {
+	csp.keysMutex.RLock()
+	k, ok := csp.keys[hex.EncodeToString(ski)]
+	csp.keysMutex.RUnlock()
+	if ok {
+		return k, nil
+	}
+
+	var reply KeyReply
+	err := csp.client.call("GetKey", &KeyArgs{SKI: ski}, &reply)
+	if err == nil {
+		return csp.bindKey(&reply)
+	}
+	logger.Debugf("Key %x not found in enclave [%s], trying local key store", ski, err)
+	return csp.BCCSP.GetKey(ski)
+}
+
+// Sign signs digest using key k.
+// The opts argument should be appropriate for the primitive used.
+func (csp *impl) Sign(k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	// Validate arguments
+	if k == nil {
+		return nil, errors.New("Invalid Key. It must not be nil")
+	}
+	if len(digest) == 0 {
+		return nil, errors.New("Invalid digest. Cannot be empty")
+	}
+
+	key, ok := k.(*teeKey)
+	if !ok {
+		return csp.BCCSP.Sign(k, digest, opts)
+	}
+
+	switch pub := key.cryptoPub.(type) {
+	case *sm2.PublicKey:
+		za, err := utils.SM2ZA(pub, nil)
+		if err != nil {
+			return nil, err
+		}
+		h := sm3.New()
+		h.Write(za)
+		h.Write(digest)
+
+		sig, err := csp.sign(key, h.Sum(nil))
+		if err != nil {
+			return nil, err
+		}
+		if bccsp.SM2SignatureEncodingOf(opts) == bccsp.SM2SignatureRaw {
+			return utils.SM2SignatureDERToRaw(sig)
+		}
+		return sig, nil
+	case *ecdsa.PublicKey:
+		sig, err := csp.sign(key, digest)
+		if err != nil {
+			return nil, err
+		}
+		return utils.SignatureToLowS(pub, sig)
+	default:
+		return nil, errors.Errorf("Unsupported public key type [%T]", key.cryptoPub)
+	}
+}
+
+func (csp *impl) sign(k *teeKey, digest []byte) ([]byte, error) {
+	var reply SignReply
+	if err := csp.client.call("Sign", &SignArgs{SKI: k.SKI(), Digest: digest}, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Signature, nil
+}
+
+// Verify verifies signature against key k and digest. Verification
+// always happens locally with the public key.
+func (csp *impl) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	if key, ok := k.(*teeKey); ok {
+		k = key.pub
+	}
+	return csp.BCCSP.Verify(k, signature, digest, opts)
+}
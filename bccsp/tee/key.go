@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tee
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+// teeKey is a private key sealed by an enclave, described by its public
+// key and the attestation evidence of the enclave.
+type teeKey struct {
+	pub       bccsp.Key
+	cryptoPub crypto.PublicKey
+
+	attestationType string
+	evidence        []byte
+	measurement     []byte
+}
+
+// Bytes converts this key to its byte representation,
+// if this operation is allowed.
+func (k *teeKey) Bytes() ([]byte, error) {
+	return nil, errors.New("Not supported.")
+}
+
+// SKI returns the subject key identifier of this key.
+func (k *teeKey) SKI() []byte {
+	return k.pub.SKI()
+}
+
+// Symmetric returns true if this key is a symmetric key,
+// false if this key is asymmetric
+func (k *teeKey) Symmetric() bool {
+	return false
+}
+
+// Private returns true if this key is a private key,
+// false otherwise.
+func (k *teeKey) Private() bool {
+	return true
+}
+
+// PublicKey returns the corresponding public key part of an asymmetric public/private key pair.
+// This method returns an error in symmetric key schemes.
+func (k *teeKey) PublicKey() (bccsp.Key, error) {
+	return k.pub, nil
+}
+
+// CryptoPublicKey returns the public key, an *ecdsa.PublicKey or an *sm2.PublicKey.
+func (k *teeKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.cryptoPub, nil
+}
+
+// KeyAttributes returns the attributes of this key, including the
+// attestation evidence. Enclave keys are never exportable.
+func (k *teeKey) KeyAttributes() map[string]string {
+	attrs := map[string]string{
+		bccsp.KeyAttrProvider:   "TEE",
+		bccsp.KeyAttrExportable: "false",
+	}
+	if k.attestationType != "" {
+		attrs[KeyAttrAttestationType] = k.attestationType
+	}
+	if len(k.evidence) != 0 {
+		attrs[KeyAttrAttestationEvidence] = base64.StdEncoding.EncodeToString(k.evidence)
+	}
+	if len(k.measurement) != 0 {
+		attrs[KeyAttrMeasurement] = hex.EncodeToString(k.measurement)
+	}
+	return attrs
+}
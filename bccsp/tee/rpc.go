@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tee
+
+// The enclave host serves the methods below as a net/rpc service named
+// ServiceName, with the JSON-RPC codec of net/rpc/jsonrpc. Host processes
+// written in other languages only have to speak JSON-RPC 1.0.
+//
+//	Enclave.GenerateKey(GenerateKeyArgs) KeyReply
+//	Enclave.GetKey(KeyArgs) KeyReply
+//	Enclave.Sign(SignArgs) SignReply
+const ServiceName = "Enclave"
+
+// Algorithms of the keys generated in the enclave.
+const (
+	AlgSM2       = "SM2"
+	AlgECDSAP256 = "ECDSAP256"
+	AlgECDSAP384 = "ECDSAP384"
+)
+
+// GenerateKeyArgs asks the enclave to generate and seal a key pair.
+type GenerateKeyArgs struct {
+	Algorithm string
+	Ephemeral bool
+}
+
+// KeyArgs identifies a key sealed by the enclave.
+type KeyArgs struct {
+	SKI []byte
+}
+
+// KeyReply describes a key held by the enclave. PublicKey is encoded in
+// PKIX, ASN.1 DER form. Evidence is the attestation evidence of the
+// enclave, whose report data is the SHA-256 digest of PublicKey.
+type KeyReply struct {
+	SKI             []byte
+	PublicKey       []byte
+	AttestationType string
+	Evidence        []byte
+	Measurement     []byte
+}
+
+// SignArgs asks for an ASN.1 DER signature of Digest. For SM2 keys
+// Digest is e = SM3(Z_A || M).
+type SignArgs struct {
+	SKI    []byte
+	Digest []byte
+}
+
+// SignReply holds the signature computed by the enclave.
+type SignReply struct {
+	Signature []byte
+}
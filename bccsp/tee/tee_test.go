@@ -0,0 +1,286 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tee
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testMeasurement = []byte{0xde, 0xad, 0xbe, 0xef}
+	testEvidence    = []byte("quote")
+)
+
+// enclave simulates an enclave host, keeping its keys in memory.
+type enclave struct {
+	mutex sync.Mutex
+	keys  map[string]interface{}
+}
+
+func (e *enclave) GenerateKey(args *GenerateKeyArgs, reply *KeyReply) error {
+	var priv interface{}
+	var err error
+	switch args.Algorithm {
+	case AlgSM2:
+		priv, err = utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	case AlgECDSAP256:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgECDSAP384:
+		priv, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	default:
+		return errors.New("unsupported algorithm")
+	}
+	if err != nil {
+		return err
+	}
+
+	ski := describe(priv, reply)
+	e.mutex.Lock()
+	e.keys[hex.EncodeToString(ski)] = priv
+	e.mutex.Unlock()
+	return nil
+}
+
+func (e *enclave) GetKey(args *KeyArgs, reply *KeyReply) error {
+	e.mutex.Lock()
+	priv, ok := e.keys[hex.EncodeToString(args.SKI)]
+	e.mutex.Unlock()
+	if !ok {
+		return errors.New("key not found")
+	}
+	describe(priv, reply)
+	return nil
+}
+
+func (e *enclave) Sign(args *SignArgs, reply *SignReply) error {
+	e.mutex.Lock()
+	priv, ok := e.keys[hex.EncodeToString(args.SKI)]
+	e.mutex.Unlock()
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	var err error
+	switch k := priv.(type) {
+	case *sm2.PrivateKey:
+		reply.Signature, err = signSM2Digest(k, args.Digest)
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		if r, s, err = ecdsa.Sign(rand.Reader, k, args.Digest); err == nil {
+			reply.Signature, err = utils.MarshalECDSASignature(r, s)
+		}
+	}
+	return err
+}
+
+func describe(priv interface{}, reply *KeyReply) []byte {
+	switch k := priv.(type) {
+	case *sm2.PrivateKey:
+		reply.PublicKey, _ = utils.MarshalPKIXSM2PublicKey(&k.PublicKey)
+		h := sm3.New()
+		h.Write(elliptic.Marshal(k.Curve, k.X, k.Y))
+		reply.SKI = h.Sum(nil)
+	case *ecdsa.PrivateKey:
+		reply.PublicKey, _ = x509.MarshalPKIXPublicKey(&k.PublicKey)
+		h := sha256.Sum256(elliptic.Marshal(k.Curve, k.X, k.Y))
+		reply.SKI = h[:]
+	}
+	reply.AttestationType = "sgx-dcap"
+	reply.Evidence = testEvidence
+	reply.Measurement = testMeasurement
+	return reply.SKI
+}
+
+// signSM2Digest signs e = SM3(Z_A || M) as an enclave does.
+func signSM2Digest(priv *sm2.PrivateKey, e []byte) ([]byte, error) {
+	n := priv.Curve.Params().N
+	one := big.NewInt(1)
+	for {
+		k, err := rand.Int(rand.Reader, new(big.Int).Sub(n, one))
+		if err != nil {
+			return nil, err
+		}
+		k.Add(k, one)
+		x1, _ := priv.Curve.ScalarBaseMult(k.Bytes())
+
+		r := new(big.Int).Add(new(big.Int).SetBytes(e), x1)
+		r.Mod(r, n)
+		if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+			continue
+		}
+
+		s := new(big.Int).Mul(r, priv.D)
+		s.Sub(k, s)
+		s.Mul(s, new(big.Int).ModInverse(new(big.Int).Add(priv.D, one), n))
+		s.Mod(s, n)
+		if s.Sign() == 0 {
+			continue
+		}
+		return utils.MarshalECDSASignature(r, s)
+	}
+}
+
+func startEnclave(t *testing.T) (string, *enclave) {
+	e := &enclave{keys: map[string]interface{}{}}
+	server := rpc.NewServer()
+	require.NoError(t, server.RegisterName(ServiceName, e))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+		}
+	}()
+	return lis.Addr().String(), e
+}
+
+func TestNewInvalidOpts(t *testing.T) {
+	_, err := New(TEEOpts{SecLevel: 256, HashFamily: "SM3"}, sw.NewDummyKeyStore())
+	assert.EqualError(t, err, "Invalid options. Address must be set")
+
+	_, err = New(TEEOpts{SecLevel: 256, HashFamily: "SM3", Address: "127.0.0.1:1"}, nil)
+	assert.EqualError(t, err, "Invalid bccsp.KeyStore instance. It must be different from nil")
+
+	_, err = New(TEEOpts{SecLevel: 256, HashFamily: "SM3", Address: "127.0.0.1:1", Measurements: []string{"zz"}}, sw.NewDummyKeyStore())
+	assert.EqualError(t, err, "Invalid measurement [zz]")
+}
+
+func TestSM2KeyGenSignVerify(t *testing.T) {
+	addr, _ := startEnclave(t)
+	csp, err := New(TEEOpts{SecLevel: 256, HashFamily: "SM3", Address: addr, Measurements: []string{"DEADBEEF"}}, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	assert.True(t, k.Private())
+	_, err = k.Bytes()
+	assert.Error(t, err)
+
+	attrs := k.(bccsp.AttributedKey).KeyAttributes()
+	assert.Equal(t, "TEE", attrs[bccsp.KeyAttrProvider])
+	assert.Equal(t, "false", attrs[bccsp.KeyAttrExportable])
+	assert.Equal(t, "sgx-dcap", attrs[KeyAttrAttestationType])
+	assert.Equal(t, base64.StdEncoding.EncodeToString(testEvidence), attrs[KeyAttrAttestationEvidence])
+	assert.Equal(t, "deadbeef", attrs[KeyAttrMeasurement])
+	assert.True(t, bccsp.IsNonExportable(k))
+
+	msg := []byte("hello enclave")
+	sig, err := csp.Sign(k, msg, nil)
+	require.NoError(t, err)
+	pub, err := k.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+	require.NoError(t, err)
+	assert.True(t, sm2.Verify(pub.(*sm2.PublicKey), nil, msg, sig))
+
+	valid, err := csp.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	raw, err := csp.Sign(k, msg, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureRaw})
+	require.NoError(t, err)
+	assert.Len(t, raw, 64)
+
+	// Encryption keys stay in software
+	enc, err := csp.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true, Usage: bccsp.SM2KeyUsageEncrypt})
+	require.NoError(t, err)
+	_, ok := enc.(*teeKey)
+	assert.False(t, ok)
+}
+
+func TestECDSAKeyGenSignVerify(t *testing.T) {
+	addr, _ := startEnclave(t)
+	csp, err := New(TEEOpts{SecLevel: 384, HashFamily: "SHA2", Address: addr}, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	k, err := csp.KeyGen(&bccsp.ECDSAKeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pub, err := k.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+	require.NoError(t, err)
+	assert.Equal(t, elliptic.P384(), pub.(*ecdsa.PublicKey).Curve)
+
+	digest := sha256.Sum256([]byte("hello enclave"))
+	sig, err := csp.Sign(k, digest[:], nil)
+	require.NoError(t, err)
+	r, s, err := utils.UnmarshalECDSASignature(sig)
+	require.NoError(t, err)
+	assert.True(t, ecdsa.Verify(pub.(*ecdsa.PublicKey), digest[:], r, s))
+	lowS, err := utils.IsLowS(pub.(*ecdsa.PublicKey), s)
+	require.NoError(t, err)
+	assert.True(t, lowS)
+
+	valid, err := csp.Verify(k, sig, digest[:], nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+}
+
+func TestGetKey(t *testing.T) {
+	addr, _ := startEnclave(t)
+	opts := TEEOpts{SecLevel: 256, HashFamily: "SM3", Address: addr}
+	csp, err := New(opts, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	k, err := csp.KeyGen(&bccsp.ECDSAP256KeyGenOpts{})
+	require.NoError(t, err)
+
+	// A new provider finds the key sealed by the enclave
+	csp, err = New(opts, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	k2, err := csp.GetKey(k.SKI())
+	require.NoError(t, err)
+	assert.Equal(t, k.SKI(), k2.SKI())
+	assert.True(t, k2.Private())
+
+	_, err = csp.GetKey([]byte{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestMeasurementNotAccepted(t *testing.T) {
+	addr, _ := startEnclave(t)
+	csp, err := New(TEEOpts{SecLevel: 256, HashFamily: "SM3", Address: addr, Measurements: []string{"00"}}, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	_, err = csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	assert.EqualError(t, err, "Enclave measurement [deadbeef] is not accepted")
+}
+
+func TestEnclaveHostUnavailable(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	lis.Close()
+
+	csp, err := New(TEEOpts{SecLevel: 256, HashFamily: "SM3", Address: addr}, sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	_, err = csp.KeyGen(&bccsp.SM2KeyGenOpts{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed connecting to enclave host")
+}
@@ -0,0 +1,296 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+var logger = flogging.MustGetLogger("bccsp_utils")
+
+// ASN1Mode selects how strictly signatures and other GM structures are
+// parsed.
+type ASN1Mode int32
+
+const (
+	// ASN1Strict accepts DER only, the default
+	ASN1Strict ASN1Mode = iota
+	// ASN1Lenient also accepts the BER deviations some CAs and devices
+	// emit: non-minimal lengths and integers, indefinite lengths, integers
+	// missing their sign byte and trailing data. Deviations are logged.
+	ASN1Lenient
+)
+
+var asn1Mode int32
+
+// SetASN1Mode sets the parsing mode of the process.
+func SetASN1Mode(mode ASN1Mode) {
+	atomic.StoreInt32(&asn1Mode, int32(mode))
+}
+
+// GetASN1Mode returns the parsing mode of the process.
+func GetASN1Mode() ASN1Mode {
+	return ASN1Mode(atomic.LoadInt32(&asn1Mode))
+}
+
+// ParseASN1Mode returns the mode named "strict" or "lenient", strict if
+// name is empty.
+func ParseASN1Mode(name string) (ASN1Mode, error) {
+	switch strings.ToLower(name) {
+	case "", "strict":
+		return ASN1Strict, nil
+	case "lenient":
+		return ASN1Lenient, nil
+	default:
+		return ASN1Strict, fmt.Errorf("unknown ASN.1 parsing mode [%s]", name)
+	}
+}
+
+// NormalizeSignature returns the DER encoding of an ECDSA or SM2
+// signature. In strict mode sig is returned as is: parsers reject it later
+// if it is not DER. In lenient mode BER-ish signatures are re-encoded.
+func NormalizeSignature(sig []byte) ([]byte, error) {
+	return NormalizeSignatureWithMode(sig, GetASN1Mode())
+}
+
+// NormalizeSignatureWithMode is NormalizeSignature in mode rather than in
+// the mode of the process.
+func NormalizeSignatureWithMode(sig []byte, mode ASN1Mode) ([]byte, error) {
+	if mode != ASN1Lenient {
+		return sig, nil
+	}
+	// DER signatures with unsigned R or S read as negative and are re-encoded
+	parsed := new(ECDSASignature)
+	if rest, err := asn1.Unmarshal(sig, parsed); err == nil && len(rest) == 0 && parsed.R.Sign() == 1 && parsed.S.Sign() == 1 {
+		return sig, nil
+	}
+
+	r, s, err := unmarshalLenientSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalECDSASignature(r, s)
+}
+
+// NormalizeDER re-encodes ber, whose tag-length-value structure may use
+// indefinite or non-minimal lengths and non-minimal integers, as DER. The
+// deviations found are returned. Normalizing a signed structure changes
+// the signed bytes, so it only helps when the signature covers DER.
+func NormalizeDER(ber []byte) ([]byte, []string, error) {
+	p := &berParser{data: ber}
+	der, err := p.element(0)
+	if err != nil {
+		return nil, p.deviations, err
+	}
+	if p.pos != len(ber) {
+		p.deviate("%d bytes of trailing data", len(ber)-p.pos)
+	}
+	return der, p.deviations, nil
+}
+
+// unmarshalLenientSignature reads SEQUENCE { r INTEGER, s INTEGER }
+// leniently, treating both integers as unsigned.
+func unmarshalLenientSignature(sig []byte) (*big.Int, *big.Int, error) {
+	p := &berParser{data: sig, unsignedInts: true}
+	der, err := p.element(0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed unmashalling signature [%s]", err)
+	}
+	if p.pos != len(sig) {
+		p.deviate("%d bytes of trailing data", len(sig)-p.pos)
+	}
+
+	parsed := new(ECDSASignature)
+	if rest, err := asn1.Unmarshal(der, parsed); err != nil || len(rest) != 0 {
+		return nil, nil, fmt.Errorf("failed unmashalling signature [%v]", err)
+	}
+	if parsed.R == nil || parsed.S == nil || parsed.R.Sign() != 1 || parsed.S.Sign() != 1 {
+		return nil, nil, errors.New("invalid signature, R and S must be larger than zero")
+	}
+	if len(p.deviations) != 0 {
+		logger.Warningf("Accepted non-DER signature: %s", strings.Join(p.deviations, ", "))
+	}
+	return parsed.R, parsed.S, nil
+}
+
+// maxBERDepth bounds the nesting of constructed elements.
+const maxBERDepth = 32
+
+type berParser struct {
+	data []byte
+	pos  int
+	// unsignedInts reads integers with their high bit set as positive
+	unsignedInts bool
+	deviations   []string
+}
+
+func (p *berParser) deviate(format string, args ...interface{}) {
+	p.deviations = append(p.deviations, fmt.Sprintf(format, args...))
+}
+
+// element parses the element at pos and returns its DER encoding.
+func (p *berParser) element(depth int) ([]byte, error) {
+	if depth > maxBERDepth {
+		return nil, errors.New("asn1: structure nested too deeply")
+	}
+	if p.pos >= len(p.data) {
+		return nil, errors.New("asn1: truncated element")
+	}
+
+	// Identifier, possibly in high tag number form
+	start := p.pos
+	b := p.data[p.pos]
+	p.pos++
+	constructed := b&0x20 != 0
+	tag := int(b & 0x1f)
+	if tag == 0x1f {
+		tag = 0
+		for {
+			if p.pos >= len(p.data) {
+				return nil, errors.New("asn1: truncated tag")
+			}
+			c := p.data[p.pos]
+			p.pos++
+			tag = tag<<7 | int(c&0x7f)
+			if c&0x80 == 0 {
+				break
+			}
+			if tag > 1<<24 {
+				return nil, errors.New("asn1: tag too large")
+			}
+		}
+	}
+	identifier := append([]byte{}, p.data[start:p.pos]...)
+	universal := b&0xc0 == 0
+
+	length, indefinite, err := p.length()
+	if err != nil {
+		return nil, err
+	}
+
+	if !constructed {
+		if indefinite {
+			return nil, errors.New("asn1: indefinite length of a primitive element")
+		}
+		content := p.data[p.pos : p.pos+length]
+		p.pos += length
+		if universal && tag == asn1.TagInteger {
+			content = p.integer(content)
+		}
+		return encodeTLV(identifier, content), nil
+	}
+
+	var content []byte
+	if indefinite {
+		p.deviate("indefinite length")
+		for {
+			if p.pos+2 <= len(p.data) && p.data[p.pos] == 0 && p.data[p.pos+1] == 0 {
+				p.pos += 2
+				break
+			}
+			child, err := p.element(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, child...)
+		}
+	} else {
+		end := p.pos + length
+		sub := &berParser{data: p.data[:end], pos: p.pos, unsignedInts: p.unsignedInts}
+		for sub.pos < end {
+			child, err := sub.element(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			content = append(content, child...)
+		}
+		p.deviations = append(p.deviations, sub.deviations...)
+		p.pos = end
+	}
+	return encodeTLV(identifier, content), nil
+}
+
+// length parses a length, recording non-minimal encodings.
+func (p *berParser) length() (int, bool, error) {
+	if p.pos >= len(p.data) {
+		return 0, false, errors.New("asn1: truncated length")
+	}
+	b := p.data[p.pos]
+	p.pos++
+	if b < 0x80 {
+		return p.checkLength(int(b))
+	}
+	if b == 0x80 {
+		return 0, true, nil
+	}
+
+	n := int(b & 0x7f)
+	if n > 4 || p.pos+n > len(p.data) {
+		return 0, false, errors.New("asn1: invalid length")
+	}
+	length := 0
+	for i := 0; i < n; i++ {
+		length = length<<8 | int(p.data[p.pos])
+		p.pos++
+	}
+	if length < 0x80 || (n > 1 && length < 1<<(8*(n-1))) {
+		p.deviate("non-minimal length")
+	}
+	return p.checkLength(length)
+}
+
+func (p *berParser) checkLength(length int) (int, bool, error) {
+	if length < 0 || length > len(p.data)-p.pos {
+		return 0, false, errors.New("asn1: length exceeds data")
+	}
+	return length, false, nil
+}
+
+// integer returns the minimal encoding of an integer content.
+func (p *berParser) integer(content []byte) []byte {
+	if len(content) == 0 {
+		p.deviate("empty integer")
+		return []byte{0}
+	}
+	if p.unsignedInts && content[0]&0x80 != 0 {
+		p.deviate("integer without sign byte")
+		return append([]byte{0}, content...)
+	}
+
+	trimmed := content
+	for len(trimmed) > 1 && (trimmed[0] == 0 && trimmed[1]&0x80 == 0 || trimmed[0] == 0xff && trimmed[1]&0x80 != 0) {
+		trimmed = trimmed[1:]
+	}
+	if len(trimmed) != len(content) {
+		p.deviate("non-minimal integer")
+	}
+	return trimmed
+}
+
+func encodeTLV(identifier, content []byte) []byte {
+	out := append([]byte{}, identifier...)
+	l := len(content)
+	switch {
+	case l < 0x80:
+		out = append(out, byte(l))
+	default:
+		var lb []byte
+		for v := l; v > 0; v >>= 8 {
+			lb = append([]byte{byte(v)}, lb...)
+		}
+		out = append(out, 0x80|byte(len(lb)))
+		out = append(out, lb...)
+	}
+	return append(out, content...)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseASN1Mode(t *testing.T) {
+	mode, err := ParseASN1Mode("")
+	assert.NoError(t, err)
+	assert.Equal(t, ASN1Strict, mode)
+	mode, err = ParseASN1Mode("Lenient")
+	assert.NoError(t, err)
+	assert.Equal(t, ASN1Lenient, mode)
+	_, err = ParseASN1Mode("loose")
+	assert.EqualError(t, err, "unknown ASN.1 parsing mode [loose]")
+}
+
+func TestLenientSignatures(t *testing.T) {
+	defer SetASN1Mode(ASN1Strict)
+
+	r := big.NewInt(0x85)
+	s := big.NewInt(0x0102)
+	der, err := MarshalECDSASignature(r, s)
+	require.NoError(t, err)
+
+	for name, ber := range map[string][]byte{
+		// r misses its sign byte
+		"unsigned integer": {0x30, 0x07, 0x02, 0x01, 0x85, 0x02, 0x02, 0x01, 0x02},
+		// s encoded as 00 01 02
+		"non-minimal integer": {0x30, 0x09, 0x02, 0x02, 0x00, 0x85, 0x02, 0x03, 0x00, 0x01, 0x02},
+		// sequence length in long form
+		"non-minimal length": {0x30, 0x81, 0x08, 0x02, 0x02, 0x00, 0x85, 0x02, 0x02, 0x01, 0x02},
+		"indefinite length":  {0x30, 0x80, 0x02, 0x02, 0x00, 0x85, 0x02, 0x02, 0x01, 0x02, 0x00, 0x00},
+		"trailing data":      append(append([]byte{}, der...), 0x00),
+	} {
+		SetASN1Mode(ASN1Strict)
+		_, _, err := UnmarshalECDSASignature(ber)
+		if name != "trailing data" {
+			assert.Error(t, err, name)
+		}
+		normalized, err := NormalizeSignature(ber)
+		assert.NoError(t, err, name)
+		assert.Equal(t, ber, normalized, name)
+
+		SetASN1Mode(ASN1Lenient)
+		pr, ps, err := UnmarshalECDSASignature(ber)
+		require.NoError(t, err, name)
+		assert.Equal(t, r, pr, name)
+		assert.Equal(t, s, ps, name)
+
+		normalized, err = NormalizeSignature(ber)
+		require.NoError(t, err, name)
+		assert.Equal(t, der, normalized, name)
+	}
+
+	SetASN1Mode(ASN1Lenient)
+	for _, bad := range [][]byte{
+		{0x30, 0x05, 0x02, 0x01, 0x01},
+		{0x30, 0x80, 0x02, 0x01, 0x01},
+		{0x30, 0x06, 0x02, 0x01, 0x00, 0x02, 0x01, 0x01},
+		{0x04, 0x80, 0x00, 0x00},
+	} {
+		_, _, err := UnmarshalECDSASignature(bad)
+		assert.Error(t, err, "% x", bad)
+	}
+}
+
+func TestNormalizeDER(t *testing.T) {
+	// SEQUENCE (indefinite) { INTEGER 00 05, SEQUENCE (long form) { NULL } }
+	ber := []byte{0x30, 0x80, 0x02, 0x02, 0x00, 0x05, 0x30, 0x81, 0x02, 0x05, 0x00, 0x00, 0x00}
+	der, deviations, err := NormalizeDER(ber)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x30, 0x07, 0x02, 0x01, 0x05, 0x30, 0x02, 0x05, 0x00}, der)
+	assert.ElementsMatch(t, []string{"indefinite length", "non-minimal integer", "non-minimal length"}, deviations)
+
+	der, deviations, err = NormalizeDER(der)
+	require.NoError(t, err)
+	assert.Empty(t, deviations)
+	assert.Equal(t, []byte{0x30, 0x07, 0x02, 0x01, 0x05, 0x30, 0x02, 0x05, 0x00}, der)
+
+	// Negative integers keep their sign
+	der, _, err = NormalizeDER([]byte{0x02, 0x02, 0xff, 0x85})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x02, 0x01, 0x85}, der)
+
+	nested := []byte{}
+	for i := 0; i < maxBERDepth+2; i++ {
+		nested = append(nested, 0x30, 0x80)
+	}
+	_, _, err = NormalizeDER(nested)
+	assert.Error(t, err)
+}
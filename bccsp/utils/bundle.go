@@ -0,0 +1,322 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// BlockKind classifies the blocks of a PEM bundle.
+type BlockKind string
+
+const (
+	BlockCertificate BlockKind = "CERTIFICATE"
+	BlockPrivateKey  BlockKind = "PRIVATE KEY"
+	BlockPublicKey   BlockKind = "PUBLIC KEY"
+	BlockCRL         BlockKind = "CRL"
+	BlockCSR         BlockKind = "CSR"
+	BlockParameters  BlockKind = "PARAMETERS"
+	BlockUnknown     BlockKind = "UNKNOWN"
+)
+
+// Key algorithms of bundle entries.
+const (
+	AlgorithmSM2   = "SM2"
+	AlgorithmECDSA = "ECDSA"
+)
+
+// BundleEntry is a block of a PEM bundle and what it was parsed into.
+type BundleEntry struct {
+	// Index of the block in the bundle, from 0
+	Index int
+	Block *pem.Block
+	Kind  BlockKind
+	// Algorithm is AlgorithmSM2 or AlgorithmECDSA for keys and
+	// certificates, empty for other blocks or unsupported keys
+	Algorithm string
+
+	// Certificate is set for certificates crypto/x509 can parse, which
+	// excludes SM2 ones
+	Certificate *x509.Certificate
+	// Subject and Issuer are the DER encoded names of a certificate
+	Subject, Issuer []byte
+	// PublicKey is the public key of a certificate, CSR or key block
+	PublicKey interface{}
+	// PrivateKey is set for private key blocks
+	PrivateKey interface{}
+	// CRL is set for CRL blocks
+	CRL *pkix.CertificateList
+	// Encrypted private keys are left as is when no password was given
+	Encrypted bool
+	// Err is the parsing error of the block
+	Err error
+}
+
+// KeyPair is a certificate and the private key matching it.
+type KeyPair struct {
+	Certificate *BundleEntry
+	PrivateKey  *BundleEntry
+}
+
+// Bundle is the classified content of a PEM bundle.
+type Bundle struct {
+	Entries []*BundleEntry
+	// Pairs are the certificates whose private key is in the bundle
+	Pairs []KeyPair
+}
+
+// certificate and tbsCertificate read the fields of a certificate needed
+// for SM2 certificates, which crypto/x509 does not parse.
+type certificate struct {
+	TBSCertificate tbsCertificate
+	SignatureAlgo  pkix.AlgorithmIdentifier
+	Signature      asn1.BitString
+}
+
+type tbsCertificate struct {
+	Version      int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber *big.Int
+	SignatureAlg pkix.AlgorithmIdentifier
+	Issuer       asn1.RawValue
+	Validity     asn1.RawValue
+	Subject      asn1.RawValue
+	PublicKey    asn1.RawValue
+}
+
+// ParsePEMBundle splits raw into its PEM blocks, in any order, classifies
+// and parses each of them, and pairs private keys with certificates. pwd
+// decrypts encrypted private keys, if set. Blocks that fail to parse are
+// kept with their error; only bundles without any PEM block fail.
+func ParsePEMBundle(raw []byte, pwd []byte) (*Bundle, error) {
+	b := &Bundle{}
+	for rest := raw; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		e := &BundleEntry{Index: len(b.Entries), Block: block}
+		e.parse(pwd)
+		b.Entries = append(b.Entries, e)
+	}
+	if len(b.Entries) == 0 {
+		return nil, errors.New("no PEM block found")
+	}
+
+	b.pair()
+	return b, nil
+}
+
+func (e *BundleEntry) parse(pwd []byte) {
+	t := e.Block.Type
+	switch {
+	case t == "CERTIFICATE" || t == "TRUSTED CERTIFICATE":
+		e.Kind = BlockCertificate
+		e.parseCertificate()
+	case t == "X509 CRL":
+		e.Kind = BlockCRL
+		e.CRL, e.Err = x509.ParseDERCRL(e.Block.Bytes)
+	case t == "CERTIFICATE REQUEST" || t == "NEW CERTIFICATE REQUEST":
+		e.Kind = BlockCSR
+		e.parseCSR()
+	case t == "EC PARAMETERS":
+		e.Kind = BlockParameters
+	case strings.HasSuffix(t, "PUBLIC KEY"):
+		e.Kind = BlockPublicKey
+		e.PublicKey, e.Err = PEMtoPublicKey(pem.EncodeToMemory(e.Block), pwd)
+	case strings.HasSuffix(t, "PRIVATE KEY"):
+		e.Kind = BlockPrivateKey
+		e.Encrypted = t == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(e.Block)
+		if e.Encrypted && len(pwd) == 0 {
+			return
+		}
+		e.PrivateKey, e.Err = PEMtoPrivateKey(pem.EncodeToMemory(e.Block), pwd)
+		if e.Err == nil {
+			e.PublicKey, e.Err = publicKeyOf(e.PrivateKey)
+		}
+	default:
+		e.Kind = BlockUnknown
+	}
+
+	if e.PublicKey != nil {
+		e.Algorithm = algorithmOf(e.PublicKey)
+	}
+}
+
+func (e *BundleEntry) parseCertificate() {
+	var cert certificate
+	if rest, err := asn1.Unmarshal(e.Block.Bytes, &cert); err != nil {
+		e.Err = fmt.Errorf("failed to parse certificate [%s]", err)
+		return
+	} else if len(rest) != 0 {
+		e.Err = errors.New("trailing data after certificate")
+		return
+	}
+	e.Subject = cert.TBSCertificate.Subject.FullBytes
+	e.Issuer = cert.TBSCertificate.Issuer.FullBytes
+
+	if c, err := x509.ParseCertificate(e.Block.Bytes); err == nil {
+		e.Certificate = c
+	}
+	e.PublicKey, e.Err = DERToPublicKey(cert.TBSCertificate.PublicKey.FullBytes)
+}
+
+func (e *BundleEntry) parseCSR() {
+	// CertificationRequestInfo ::= SEQUENCE { version, subject, subjectPKInfo, ... }
+	var csr struct {
+		Info struct {
+			Version   int
+			Subject   asn1.RawValue
+			PublicKey asn1.RawValue
+		}
+	}
+	if _, err := asn1.Unmarshal(e.Block.Bytes, &csr); err != nil {
+		e.Err = fmt.Errorf("failed to parse certificate request [%s]", err)
+		return
+	}
+	e.Subject = csr.Info.Subject.FullBytes
+	e.PublicKey, e.Err = DERToPublicKey(csr.Info.PublicKey.FullBytes)
+}
+
+func publicKeyOf(priv interface{}) (interface{}, error) {
+	switch k := priv.(type) {
+	case *sm2.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", priv)
+	}
+}
+
+func algorithmOf(pub interface{}) string {
+	switch pub.(type) {
+	case *sm2.PublicKey:
+		return AlgorithmSM2
+	case *ecdsa.PublicKey:
+		return AlgorithmECDSA
+	default:
+		return ""
+	}
+}
+
+// pair matches every private key with the certificates of its public key.
+func (b *Bundle) pair() {
+	for _, cert := range b.Certificates() {
+		for _, key := range b.PrivateKeys() {
+			if samePublicKey(cert.PublicKey, key.PublicKey) {
+				b.Pairs = append(b.Pairs, KeyPair{Certificate: cert, PrivateKey: key})
+			}
+		}
+	}
+}
+
+func samePublicKey(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	da, err := ConvertKey(a, KeyFormatPKIX)
+	if err != nil {
+		return false
+	}
+	db, err := ConvertKey(b, KeyFormatPKIX)
+	return err == nil && bytes.Equal(da, db)
+}
+
+func (b *Bundle) ofKind(kind BlockKind) []*BundleEntry {
+	var entries []*BundleEntry
+	for _, e := range b.Entries {
+		if e.Kind == kind && e.Err == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Certificates returns the certificates parsed without error.
+func (b *Bundle) Certificates() []*BundleEntry {
+	return b.ofKind(BlockCertificate)
+}
+
+// PrivateKeys returns the decrypted private keys parsed without error.
+func (b *Bundle) PrivateKeys() []*BundleEntry {
+	var keys []*BundleEntry
+	for _, e := range b.ofKind(BlockPrivateKey) {
+		if e.PrivateKey != nil {
+			keys = append(keys, e)
+		}
+	}
+	return keys
+}
+
+// CRLs returns the CRLs parsed without error.
+func (b *Bundle) CRLs() []*BundleEntry {
+	return b.ofKind(BlockCRL)
+}
+
+// Validate reports the blocks that failed to parse, encrypted keys that
+// could not be decrypted, private keys matching no certificate and CRLs
+// issued by none of the certificates of the bundle.
+func (b *Bundle) Validate() error {
+	var problems []string
+	for _, e := range b.Entries {
+		switch {
+		case e.Err != nil:
+			problems = append(problems, fmt.Sprintf("block %d (%s): %s", e.Index, e.Block.Type, e.Err))
+		case e.Kind == BlockUnknown:
+			problems = append(problems, fmt.Sprintf("block %d: unknown type %s", e.Index, e.Block.Type))
+		case e.Kind == BlockPrivateKey && e.PrivateKey == nil:
+			problems = append(problems, fmt.Sprintf("block %d: encrypted private key, password required", e.Index))
+		}
+	}
+
+	for _, key := range b.PrivateKeys() {
+		if !b.paired(key) {
+			problems = append(problems, fmt.Sprintf("block %d: private key matches no certificate", key.Index))
+		}
+	}
+
+	for _, crl := range b.CRLs() {
+		issuer := crl.CRL.TBSCertList.Issuer
+		issued := false
+		for _, cert := range b.Certificates() {
+			var subject pkix.RDNSequence
+			if _, err := asn1.Unmarshal(cert.Subject, &subject); err == nil && subject.String() == issuer.String() {
+				issued = true
+				break
+			}
+		}
+		if !issued {
+			problems = append(problems, fmt.Sprintf("block %d: CRL issuer %s is not in the bundle", crl.Index, issuer))
+		}
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("invalid PEM bundle: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (b *Bundle) paired(key *BundleEntry) bool {
+	for _, p := range b.Pairs {
+		if p.PrivateKey == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ecdsaCertificate(t *testing.T, key *ecdsa.PrivateKey, cn string) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// sm2Certificate encodes a certificate of key crypto/x509 cannot parse.
+// Its signature is not checked by bundles and left empty.
+func sm2Certificate(t *testing.T, key *sm2.PrivateKey, cn string) []byte {
+	spki, err := MarshalPKIXSM2PublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	name, err := asn1.Marshal(pkix.Name{CommonName: cn}.ToRDNSequence())
+	require.NoError(t, err)
+	validity, err := asn1.Marshal(struct{ NotBefore, NotAfter time.Time }{time.Now().UTC(), time.Now().UTC().Add(time.Hour)})
+	require.NoError(t, err)
+
+	algo := pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}}
+	der, err := asn1.Marshal(certificate{
+		TBSCertificate: tbsCertificate{
+			Version:      2,
+			SerialNumber: big.NewInt(2),
+			SignatureAlg: algo,
+			Issuer:       asn1.RawValue{FullBytes: name},
+			Validity:     asn1.RawValue{FullBytes: validity},
+			Subject:      asn1.RawValue{FullBytes: name},
+			PublicKey:    asn1.RawValue{FullBytes: spki},
+		},
+		SignatureAlgo: algo,
+		Signature:     asn1.BitString{Bytes: []byte{0}, BitLength: 8},
+	})
+	require.NoError(t, err)
+	return der
+}
+
+func TestParsePEMBundle(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecCert := ecdsaCertificate(t, ecKey, "ecdsa")
+	sm2Key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	crl, err := ecCert.CreateCRL(rand.Reader, ecKey, nil, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	ecKeyPEM, err := PrivateKeyToPEM(ecKey, nil)
+	require.NoError(t, err)
+	sm2KeyPEM, err := PrivateKeyToEncryptedPKCS8PEM(sm2Key, []byte("pwd"), nil)
+	require.NoError(t, err)
+	sm2PubPEM, err := SM2PublicKeyToGmSSLPEM(&sm2Key.PublicKey)
+	require.NoError(t, err)
+
+	// Keys before certificates, CRL in between
+	var raw []byte
+	raw = append(raw, sm2KeyPEM...)
+	raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl})...)
+	raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ecCert.Raw})...)
+	raw = append(raw, ecKeyPEM...)
+	raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: sm2Certificate(t, sm2Key, "sm2")})...)
+	raw = append(raw, sm2PubPEM...)
+
+	b, err := ParsePEMBundle(raw, []byte("pwd"))
+	require.NoError(t, err)
+	require.Len(t, b.Entries, 6)
+	kinds := []BlockKind{BlockPrivateKey, BlockCRL, BlockCertificate, BlockPrivateKey, BlockCertificate, BlockPublicKey}
+	for i, e := range b.Entries {
+		assert.Equal(t, i, e.Index)
+		assert.Equal(t, kinds[i], e.Kind)
+		assert.NoError(t, e.Err)
+	}
+	assert.Equal(t, AlgorithmSM2, b.Entries[0].Algorithm)
+	assert.True(t, b.Entries[0].Encrypted)
+	assert.NotNil(t, b.Entries[2].Certificate)
+	assert.Equal(t, AlgorithmECDSA, b.Entries[2].Algorithm)
+	assert.Nil(t, b.Entries[4].Certificate)
+	assert.Equal(t, AlgorithmSM2, b.Entries[4].Algorithm)
+	assert.Equal(t, &sm2Key.PublicKey, b.Entries[4].PublicKey)
+
+	assert.Len(t, b.Certificates(), 2)
+	assert.Len(t, b.PrivateKeys(), 2)
+	assert.Len(t, b.CRLs(), 1)
+	require.Len(t, b.Pairs, 2)
+	assert.Equal(t, KeyPair{Certificate: b.Entries[2], PrivateKey: b.Entries[3]}, b.Pairs[0])
+	assert.Equal(t, KeyPair{Certificate: b.Entries[4], PrivateKey: b.Entries[0]}, b.Pairs[1])
+	assert.NoError(t, b.Validate())
+
+	// Without the password the SM2 key is kept encrypted and unpaired
+	b, err = ParsePEMBundle(raw, nil)
+	require.NoError(t, err)
+	assert.Nil(t, b.Entries[0].PrivateKey)
+	assert.Len(t, b.Pairs, 1)
+	assert.EqualError(t, b.Validate(), "invalid PEM bundle: block 0: encrypted private key, password required")
+}
+
+func TestValidateBundle(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherCert := ecdsaCertificate(t, otherKey, "other")
+	crl, err := otherCert.CreateCRL(rand.Reader, otherKey, nil, time.Now(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	ecKeyPEM, err := PrivateKeyToPEM(ecKey, nil)
+	require.NoError(t, err)
+
+	var raw []byte
+	raw = append(raw, ecKeyPEM...)
+	raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("junk")})...)
+	raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl})...)
+	raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: []byte("x")})...)
+	raw = append(raw, pem.EncodeToMemory(&pem.Block{Type: "DH PARAMETERS", Bytes: []byte("x")})...)
+
+	b, err := ParsePEMBundle(raw, nil)
+	require.NoError(t, err)
+	assert.Error(t, b.Entries[1].Err)
+	assert.Equal(t, BlockUnknown, b.Entries[4].Kind)
+	assert.Empty(t, b.Pairs)
+
+	err = b.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "block 1 (CERTIFICATE): failed to parse certificate")
+	assert.Contains(t, err.Error(), "block 4: unknown type DH PARAMETERS")
+	assert.Contains(t, err.Error(), "block 0: private key matches no certificate")
+	assert.Contains(t, err.Error(), "block 2: CRL issuer CN=other is not in the bundle")
+
+	_, err = ParsePEMBundle([]byte("no PEM here"), nil)
+	assert.EqualError(t, err, "no PEM block found")
+}
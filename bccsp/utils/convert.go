@@ -0,0 +1,328 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// KeyFormat is an encoding of a key produced by ConvertKey.
+type KeyFormat string
+
+const (
+	// KeyFormatSEC1 is the ECPrivateKey of SEC 1, DER encoded
+	KeyFormatSEC1 KeyFormat = "SEC1"
+	// KeyFormatPKCS8 is the unencrypted PrivateKeyInfo of PKCS#8, DER encoded
+	KeyFormatPKCS8 KeyFormat = "PKCS8"
+	// KeyFormatPKIX is the SubjectPublicKeyInfo of the public key, DER encoded
+	KeyFormatPKIX KeyFormat = "PKIX"
+	// KeyFormatRaw is the big-endian scalar of a private key, or the
+	// uncompressed point of a public key
+	KeyFormatRaw KeyFormat = "RAW"
+	// KeyFormatJWK is the JSON Web Key of RFC 7517. SM2 keys use the curve
+	// name "SM2" of the GM JOSE drafts.
+	KeyFormatJWK KeyFormat = "JWK"
+)
+
+// JWKCurveSM2 is the JWK curve name of sm2p256v1.
+const JWKCurveSM2 = "SM2"
+
+// JWK is a JSON Web Key of an elliptic curve key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// ConvertKey encodes key in format. key is an *sm2.PrivateKey,
+// *sm2.PublicKey, *ecdsa.PrivateKey or *ecdsa.PublicKey, or any encoding
+// ParseKey reads. Private keys are converted to their public key for
+// KeyFormatPKIX; public keys cannot be converted to private formats.
+// 密钥格式转换: SEC1, PKCS#8, PKIX, 原始字节与JWK之间互相转换.
+func ConvertKey(key interface{}, format KeyFormat) ([]byte, error) {
+	if raw, ok := key.([]byte); ok {
+		var err error
+		if key, err = ParseKey(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case KeyFormatSEC1:
+		switch k := key.(type) {
+		case *sm2.PrivateKey:
+			return MarshalSM2PrivateKey(k)
+		case *ecdsa.PrivateKey:
+			return x509.MarshalECPrivateKey(k)
+		}
+	case KeyFormatPKCS8:
+		switch k := key.(type) {
+		case *sm2.PrivateKey:
+			return MarshalPKCS8SM2PrivateKey(k)
+		case *ecdsa.PrivateKey:
+			return x509.MarshalPKCS8PrivateKey(k)
+		}
+	case KeyFormatPKIX:
+		switch k := key.(type) {
+		case *sm2.PrivateKey:
+			return MarshalPKIXSM2PublicKey(&k.PublicKey)
+		case *sm2.PublicKey:
+			return MarshalPKIXSM2PublicKey(k)
+		case *ecdsa.PrivateKey:
+			return x509.MarshalPKIXPublicKey(&k.PublicKey)
+		case *ecdsa.PublicKey:
+			return x509.MarshalPKIXPublicKey(k)
+		}
+	case KeyFormatRaw:
+		switch k := key.(type) {
+		case *sm2.PrivateKey:
+			return padBytes(k.D.Bytes(), sm2.KeyBytes), nil
+		case *sm2.PublicKey:
+			return elliptic.Marshal(k.Curve, k.X, k.Y), nil
+		case *ecdsa.PrivateKey:
+			return padBytes(k.D.Bytes(), curveByteLen(k.Curve)), nil
+		case *ecdsa.PublicKey:
+			return elliptic.Marshal(k.Curve, k.X, k.Y), nil
+		}
+	case KeyFormatJWK:
+		jwk, err := KeyToJWK(key)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(jwk)
+	default:
+		return nil, fmt.Errorf("unknown key format [%s]", format)
+	}
+	return nil, fmt.Errorf("cannot encode %T as %s", key, format)
+}
+
+// ConvertKeyToPEM encodes key in format, SEC1, PKCS8 or PKIX, as a PEM
+// block of the type OpenSSL uses for it.
+func ConvertKeyToPEM(key interface{}, format KeyFormat) ([]byte, error) {
+	var blockType string
+	switch format {
+	case KeyFormatSEC1:
+		blockType = "EC PRIVATE KEY"
+	case KeyFormatPKCS8:
+		blockType = "PRIVATE KEY"
+	case KeyFormatPKIX:
+		blockType = "PUBLIC KEY"
+	default:
+		return nil, fmt.Errorf("key format [%s] has no PEM encoding", format)
+	}
+
+	der, err := ConvertKey(key, format)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), nil
+}
+
+// ParseKey reads an SM2 or ECDSA private or public key encoded as PEM,
+// DER in SEC 1, PKCS#8 or PKIX form, or JWK. Encrypted keys are refused.
+// Raw keys carry no curve and are read with ParseRawKey.
+func ParseKey(raw []byte) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, errors.New("Invalid key. It must be different from nil")
+	}
+
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) != 0 && trimmed[0] == '{' {
+		jwk := &JWK{}
+		if err := json.Unmarshal(trimmed, jwk); err != nil {
+			return nil, fmt.Errorf("failed to parse JWK [%s]", err)
+		}
+		return JWKToKey(jwk)
+	}
+
+	if block, err := firstKeyBlock(raw); err == nil {
+		if block.Type == "ENCRYPTED PRIVATE KEY" || x509.IsEncryptedPEMBlock(block) {
+			return nil, errors.New("Encrypted Key. Decrypt it with PEMtoPrivateKey")
+		}
+		raw = block.Bytes
+	}
+
+	if key, err := ParseGmSSLSM2PrivateKey(raw); err == nil {
+		return key, nil
+	}
+	if key, err := DERToPrivateKey(raw); err == nil {
+		return key, nil
+	}
+	if key, err := DERToPublicKey(raw); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unrecognized key encoding")
+}
+
+// ParseRawKey reads a raw private scalar, or uncompressed public point if
+// private is false, on curve, which is sm2p256v1 if nil.
+func ParseRawKey(raw []byte, curve elliptic.Curve, private bool) (interface{}, error) {
+	if curve == nil || curve == sm2.GetSm2P256V1() {
+		if private {
+			return SM2PrivateKeyFromRaw(raw, nil)
+		}
+		x, y := elliptic.Unmarshal(sm2.GetSm2P256V1(), raw)
+		if x == nil {
+			return nil, errors.New("invalid SM2 public key, failed to unmarshal elliptic curve point")
+		}
+		return &sm2.PublicKey{Curve: sm2.GetSm2P256V1(), X: x, Y: y}, nil
+	}
+
+	if !private {
+		x, y := elliptic.Unmarshal(curve, raw)
+		if x == nil {
+			return nil, errors.New("invalid public key, failed to unmarshal elliptic curve point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return ecdsaPrivateKeyFromRaw(raw, curve)
+}
+
+func ecdsaPrivateKeyFromRaw(d []byte, curve elliptic.Curve) (*ecdsa.PrivateKey, error) {
+	if len(d) != curveByteLen(curve) {
+		return nil, fmt.Errorf("invalid private key length [%d], must be %d bytes", len(d), curveByteLen(curve))
+	}
+	k := new(big.Int).SetBytes(d)
+	if k.Sign() <= 0 || k.Cmp(curve.Params().N) >= 0 {
+		return nil, errors.New("invalid private key value")
+	}
+
+	priv := &ecdsa.PrivateKey{D: k}
+	priv.Curve = curve
+	priv.X, priv.Y = curve.ScalarBaseMult(d)
+	return priv, nil
+}
+
+func curveByteLen(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
+
+var jwkCurves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+func jwkCurveName(curve elliptic.Curve) (string, error) {
+	for name, c := range jwkCurves {
+		if c == curve {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("curve %s has no JWK name", curve.Params().Name)
+}
+
+// KeyToJWK returns the JWK of an SM2 or ECDSA private or public key.
+func KeyToJWK(key interface{}) (*JWK, error) {
+	var curve elliptic.Curve
+	var x, y, d *big.Int
+	isSM2 := false
+	switch k := key.(type) {
+	case *sm2.PrivateKey:
+		curve, x, y, d, isSM2 = k.Curve, k.X, k.Y, k.D, true
+	case *sm2.PublicKey:
+		curve, x, y, isSM2 = k.Curve, k.X, k.Y, true
+	case *ecdsa.PrivateKey:
+		curve, x, y, d = k.Curve, k.X, k.Y, k.D
+	case *ecdsa.PublicKey:
+		curve, x, y = k.Curve, k.X, k.Y
+	default:
+		return nil, fmt.Errorf("cannot encode %T as JWK", key)
+	}
+
+	jwk := &JWK{Kty: "EC", Crv: JWKCurveSM2}
+	if !isSM2 {
+		name, err := jwkCurveName(curve)
+		if err != nil {
+			return nil, err
+		}
+		jwk.Crv = name
+	}
+
+	size := curveByteLen(curve)
+	jwk.X = base64.RawURLEncoding.EncodeToString(padBytes(x.Bytes(), size))
+	jwk.Y = base64.RawURLEncoding.EncodeToString(padBytes(y.Bytes(), size))
+	if d != nil {
+		jwk.D = base64.RawURLEncoding.EncodeToString(padBytes(d.Bytes(), size))
+	}
+	return jwk, nil
+}
+
+// JWKToKey returns the key of jwk, checking that its point is on the curve
+// and matches the private scalar if any.
+func JWKToKey(jwk *JWK) (interface{}, error) {
+	if jwk.Kty != "EC" {
+		return nil, fmt.Errorf("unsupported JWK key type [%s]", jwk.Kty)
+	}
+
+	isSM2 := jwk.Crv == JWKCurveSM2
+	curve := elliptic.Curve(sm2.GetSm2P256V1())
+	if !isSM2 {
+		var ok bool
+		if curve, ok = jwkCurves[jwk.Crv]; !ok {
+			return nil, fmt.Errorf("unsupported JWK curve [%s]", jwk.Crv)
+		}
+	}
+
+	size := curveByteLen(curve)
+	decode := func(name, v string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(b) != size {
+			return nil, fmt.Errorf("invalid JWK parameter [%s]", name)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	x, err := decode("x", jwk.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decode("y", jwk.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("JWK point is not on the curve")
+	}
+	point := elliptic.Marshal(curve, x, y)
+
+	if jwk.D == "" {
+		if isSM2 {
+			return &sm2.PublicKey{Curve: curve, X: x, Y: y}, nil
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	d, err := base64.RawURLEncoding.DecodeString(jwk.D)
+	if err != nil || len(d) != size {
+		return nil, errors.New("invalid JWK parameter [d]")
+	}
+	if isSM2 {
+		return SM2PrivateKeyFromRaw(d, point)
+	}
+	priv, err := ecdsaPrivateKeyFromRaw(d, curve)
+	if err != nil {
+		return nil, err
+	}
+	if priv.X.Cmp(x) != 0 || priv.Y.Cmp(y) != 0 {
+		return nil, errors.New("JWK public key does not match the private key")
+	}
+	return priv, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertKey(t *testing.T) {
+	sm2Key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	for _, key := range []interface{}{sm2Key, ecKey} {
+		for _, format := range []KeyFormat{KeyFormatSEC1, KeyFormatPKCS8, KeyFormatJWK} {
+			encoded, err := ConvertKey(key, format)
+			require.NoError(t, err, "%T to %s", key, format)
+
+			parsed, err := ParseKey(encoded)
+			require.NoError(t, err, "%T from %s", key, format)
+			assert.IsType(t, key, parsed)
+
+			// Convert the encoded form directly
+			again, err := ConvertKey(encoded, format)
+			require.NoError(t, err)
+			if format != KeyFormatJWK {
+				assert.Equal(t, encoded, again)
+			}
+		}
+
+		pkix, err := ConvertKey(key, KeyFormatPKIX)
+		require.NoError(t, err)
+		pub, err := ParseKey(pkix)
+		require.NoError(t, err)
+		_, err = ConvertKey(pub, KeyFormatPKCS8)
+		assert.Error(t, err)
+
+		raw, err := ConvertKey(pub, KeyFormatRaw)
+		require.NoError(t, err)
+		assert.Equal(t, byte(4), raw[0])
+	}
+
+	raw, err := ConvertKey(sm2Key, KeyFormatRaw)
+	require.NoError(t, err)
+	assert.Len(t, raw, sm2.KeyBytes)
+	parsed, err := ParseRawKey(raw, nil, true)
+	require.NoError(t, err)
+	assert.Equal(t, sm2Key.X, parsed.(*sm2.PrivateKey).X)
+
+	raw, err = ConvertKey(ecKey, KeyFormatRaw)
+	require.NoError(t, err)
+	assert.Len(t, raw, 48)
+	parsed, err = ParseRawKey(raw, elliptic.P384(), true)
+	require.NoError(t, err)
+	assert.Equal(t, ecKey.X, parsed.(*ecdsa.PrivateKey).X)
+
+	pem, err := ConvertKeyToPEM(sm2Key, KeyFormatPKCS8)
+	require.NoError(t, err)
+	fromPEM, err := PEMtoPrivateKey(pem, nil)
+	require.NoError(t, err)
+	assert.Equal(t, sm2Key.D, fromPEM.(*sm2.PrivateKey).D)
+
+	_, err = ConvertKeyToPEM(sm2Key, KeyFormatJWK)
+	assert.EqualError(t, err, "key format [JWK] has no PEM encoding")
+	_, err = ConvertKey(sm2Key, "XML")
+	assert.EqualError(t, err, "unknown key format [XML]")
+}
+
+func TestJWK(t *testing.T) {
+	sm2Key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+
+	jwk, err := KeyToJWK(sm2Key)
+	require.NoError(t, err)
+	assert.Equal(t, "EC", jwk.Kty)
+	assert.Equal(t, JWKCurveSM2, jwk.Crv)
+	assert.Len(t, jwk.X, 43)
+	assert.NotEmpty(t, jwk.D)
+
+	pubJWK, err := KeyToJWK(&sm2Key.PublicKey)
+	require.NoError(t, err)
+	assert.Empty(t, pubJWK.D)
+	pub, err := JWKToKey(pubJWK)
+	require.NoError(t, err)
+	assert.IsType(t, &sm2.PublicKey{}, pub)
+
+	// Private scalar of another key
+	other, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	otherJWK, err := KeyToJWK(other)
+	require.NoError(t, err)
+	jwk.D = otherJWK.D
+	_, err = JWKToKey(jwk)
+	assert.Error(t, err)
+
+	// Point off the curve
+	jwk.X, jwk.D = otherJWK.X, ""
+	_, err = JWKToKey(jwk)
+	assert.EqualError(t, err, "JWK point is not on the curve")
+
+	_, err = JWKToKey(&JWK{Kty: "RSA"})
+	assert.EqualError(t, err, "unsupported JWK key type [RSA]")
+	_, err = JWKToKey(&JWK{Kty: "EC", Crv: "secp256k1"})
+	assert.EqualError(t, err, "unsupported JWK curve [secp256k1]")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	encoded, err := ConvertKey(ecKey, KeyFormatJWK)
+	require.NoError(t, err)
+	var fields map[string]string
+	require.NoError(t, json.Unmarshal(encoded, &fields))
+	assert.Equal(t, "P-256", fields["crv"])
+}
@@ -41,10 +41,19 @@ func MarshalECDSASignature(r, s *big.Int) ([]byte, error) {
 }
 
 func UnmarshalECDSASignature(raw []byte) (*big.Int, *big.Int, error) {
+	return UnmarshalECDSASignatureWithMode(raw, GetASN1Mode())
+}
+
+// UnmarshalECDSASignatureWithMode is UnmarshalECDSASignature in mode rather
+// than in the mode of the process.
+func UnmarshalECDSASignatureWithMode(raw []byte, mode ASN1Mode) (*big.Int, *big.Int, error) {
 	// Unmarshal
 	sig := new(ECDSASignature)
 	_, err := asn1.Unmarshal(raw, sig)
 	if err != nil {
+		if mode == ASN1Lenient {
+			return unmarshalLenientSignature(raw)
+		}
 		return nil, nil, fmt.Errorf("failed unmashalling signature [%s]", err)
 	}
 
@@ -56,6 +65,12 @@ func UnmarshalECDSASignature(raw []byte) (*big.Int, *big.Int, error) {
 		return nil, nil, errors.New("invalid signature, S must be different from nil")
 	}
 
+	// Lenient signers may encode R or S as unsigned integers, which DER
+	// reads as negative
+	if mode == ASN1Lenient && (sig.R.Sign() != 1 || sig.S.Sign() != 1) {
+		return unmarshalLenientSignature(raw)
+	}
+
 	if sig.R.Sign() != 1 {
 		return nil, nil, errors.New("invalid signature, R must be larger than zero")
 	}
@@ -0,0 +1,212 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/elliptic"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// GmSSL and OpenSSL 3 encode SM2 keys either like EC keys, with algorithm
+// id-ecPublicKey and parameter sm2p256v1, or with the algorithm id-sm2
+// itself, the same OID as the curve, and optional parameters. The private
+// key inside PKCS#8 usually omits the curve, which the PKCS#8 algorithm
+// identifier already carries.
+// GmSSL/OpenSSL 3 生成的SM2密钥结构的兼容解析与输出.
+
+// isSM2Algorithm reports whether algo identifies an SM2 key.
+func isSM2Algorithm(algo pkix.AlgorithmIdentifier) bool {
+	if algo.Algorithm.Equal(oidSM2P256V1) {
+		params := algo.Parameters.FullBytes
+		if len(params) == 0 || algo.Parameters.Tag == asn1.TagNull {
+			return true
+		}
+		oid := new(asn1.ObjectIdentifier)
+		_, err := asn1.Unmarshal(params, oid)
+		return err == nil && oid.Equal(oidSM2P256V1)
+	}
+	if algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		oid := new(asn1.ObjectIdentifier)
+		rest, err := asn1.Unmarshal(algo.Parameters.FullBytes, oid)
+		return err == nil && len(rest) == 0 && oid.Equal(oidSM2P256V1)
+	}
+	return false
+}
+
+// ParseGmSSLSM2PrivateKey parses an SM2 private key in PKCS#8 or SEC 1
+// form, as written by GmSSL or OpenSSL 3.
+func ParseGmSSLSM2PrivateKey(der []byte) (*sm2.PrivateKey, error) {
+	var p8 pkcs8
+	if rest, err := asn1.Unmarshal(der, &p8); err == nil && len(rest) == 0 && p8.Algo.Algorithm != nil {
+		if !isSM2Algorithm(p8.Algo) {
+			return nil, fmt.Errorf("PKCS#8 wrapped key is not an SM2 key: %v", p8.Algo.Algorithm)
+		}
+		return parseSEC1SM2PrivateKey(p8.PrivateKey, true)
+	}
+	return parseSEC1SM2PrivateKey(der, false)
+}
+
+// parseSEC1SM2PrivateKey parses an ECPrivateKey of SEC 1 on sm2p256v1. The
+// curve may only be omitted if the key was wrapped in PKCS#8.
+func parseSEC1SM2PrivateKey(der []byte, wrapped bool) (*sm2.PrivateKey, error) {
+	var privKey ecPrivateKey
+	if rest, err := asn1.Unmarshal(der, &privKey); err != nil {
+		return nil, errors.New("failed to parse EC private key: " + err.Error())
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after EC private key")
+	}
+	if privKey.Version != 1 {
+		return nil, fmt.Errorf("unknown EC private key version %d", privKey.Version)
+	}
+	if privKey.NamedCurveOID == nil {
+		if !wrapped {
+			return nil, errors.New("EC private key without curve")
+		}
+	} else if !privKey.NamedCurveOID.Equal(oidSM2P256V1) {
+		return nil, fmt.Errorf("EC private key curve %v is not sm2p256v1", privKey.NamedCurveOID)
+	}
+
+	curve := sm2.GetSm2P256V1()
+	d := new(big.Int).SetBytes(privKey.PrivateKey)
+	if d.Sign() == 0 || d.Cmp(curve.Params().N) >= 0 {
+		return nil, errors.New("invalid elliptic curve private key value")
+	}
+
+	priv := &sm2.PrivateKey{D: d}
+	priv.Curve = curve
+	priv.X, priv.Y = curve.ScalarBaseMult(d.Bytes())
+
+	// The public key is optional, but must match when present
+	if len(privKey.PublicKey.Bytes) != 0 {
+		x, y := elliptic.Unmarshal(curve, privKey.PublicKey.RightAlign())
+		if x == nil || x.Cmp(priv.X) != 0 || y.Cmp(priv.Y) != 0 {
+			return nil, errors.New("EC private key does not match its public key")
+		}
+	}
+	return priv, nil
+}
+
+// ParseGmSSLSM2PublicKey parses an SM2 SubjectPublicKeyInfo identified by
+// id-ecPublicKey or id-sm2.
+func ParseGmSSLSM2PublicKey(der []byte) (*sm2.PublicKey, error) {
+	var pki publicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &pki); err != nil || len(rest) != 0 {
+		return nil, errors.New("failed to parse SM2 public key")
+	}
+	if !isSM2Algorithm(pki.Algorithm) {
+		return nil, fmt.Errorf("public key is not an SM2 key: %v", pki.Algorithm.Algorithm)
+	}
+
+	curve := sm2.GetSm2P256V1()
+	x, y := elliptic.Unmarshal(curve, pki.PublicKey.RightAlign())
+	if x == nil {
+		return nil, errors.New("x509: failed to unmarshal elliptic curve point")
+	}
+	return &sm2.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// PEMtoSM2PrivateKey reads the SM2 private key of a PEM file written by
+// GmSSL, OpenSSL 3 or this package: EC PRIVATE KEY, PRIVATE KEY,
+// ENCRYPTED PRIVATE KEY and SM2 PRIVATE KEY blocks are accepted, after
+// an optional EC PARAMETERS block.
+func PEMtoSM2PrivateKey(raw []byte, pwd []byte) (*sm2.PrivateKey, error) {
+	block, err := firstKeyBlock(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY", "PRIVATE KEY":
+		if block.Headers["Proc-Type"] != "" {
+			key, err = PEMtoPrivateKey(pem.EncodeToMemory(block), pwd)
+		} else {
+			key, err = ParseGmSSLSM2PrivateKey(block.Bytes)
+		}
+	case "ENCRYPTED PRIVATE KEY":
+		key, err = ParseEncryptedPKCS8PrivateKey(block.Bytes, pwd)
+	case "SM2 PRIVATE KEY":
+		key, err = PEMtoPrivateKey(pem.EncodeToMemory(block), pwd)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block type [%s]", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k, ok := key.(*sm2.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("PEM holds a %T, not an SM2 private key", key)
+	}
+	return k, nil
+}
+
+// firstKeyBlock returns the first PEM block of raw which is not EC
+// PARAMETERS, as written in front of keys by openssl ecparam -genkey.
+func firstKeyBlock(raw []byte) (*pem.Block, error) {
+	for rest := raw; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("Failed decoding PEM. Block must be different from nil. [% x]", raw)
+		}
+		if block.Type != "EC PARAMETERS" {
+			return block, nil
+		}
+	}
+}
+
+// SM2PrivateKeyToGmSSLPEM encodes key as a PKCS#8 PRIVATE KEY PEM block,
+// or an ENCRYPTED PRIVATE KEY block under SM4 if pwd is set, both
+// readable by GmSSL and OpenSSL 3.
+func SM2PrivateKeyToGmSSLPEM(key *sm2.PrivateKey, pwd []byte) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("Invalid sm2 private key. It must be different from nil")
+	}
+	if len(pwd) != 0 {
+		return PrivateKeyToEncryptedPKCS8PEM(key, pwd, nil)
+	}
+
+	der, err := MarshalPKCS8SM2PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// SM2PrivateKeyToSEC1PEM encodes key as an EC PRIVATE KEY PEM block.
+func SM2PrivateKeyToSEC1PEM(key *sm2.PrivateKey) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("Invalid sm2 private key. It must be different from nil")
+	}
+
+	der, err := MarshalSM2PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// SM2PublicKeyToGmSSLPEM encodes pub as a PUBLIC KEY PEM block.
+func SM2PublicKeyToGmSSLPEM(pub *sm2.PublicKey) ([]byte, error) {
+	if pub == nil {
+		return nil, errors.New("Invalid sm2 public key. It must be different from nil")
+	}
+
+	der, err := MarshalPKIXSM2PublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
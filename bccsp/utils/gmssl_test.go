@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gmsslPKCS8 wraps key the way OpenSSL 3 does: the curve is only given by
+// the PKCS#8 algorithm, identified by algo.
+func gmsslPKCS8(t *testing.T, key *sm2.PrivateKey, algo pkix.AlgorithmIdentifier) []byte {
+	d := make([]byte, sm2.KeyBytes)
+	b := key.D.Bytes()
+	copy(d[len(d)-len(b):], b)
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: d,
+		PublicKey:  asn1.BitString{Bytes: elliptic.Marshal(key.Curve, key.X, key.Y)},
+	})
+	require.NoError(t, err)
+	der, err := asn1.Marshal(pkcs8{Algo: algo, PrivateKey: inner})
+	require.NoError(t, err)
+	return der
+}
+
+func curveParams(t *testing.T) asn1.RawValue {
+	raw, err := asn1.Marshal(oidSM2P256V1)
+	require.NoError(t, err)
+	return asn1.RawValue{FullBytes: raw}
+}
+
+func TestGmSSLPrivateKeys(t *testing.T) {
+	key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+
+	for name, algo := range map[string]pkix.AlgorithmIdentifier{
+		"id-ecPublicKey":    {Algorithm: oidPublicKeyECDSA, Parameters: curveParams(t)},
+		"id-sm2":            {Algorithm: oidSM2P256V1},
+		"id-sm2 with curve": {Algorithm: oidSM2P256V1, Parameters: curveParams(t)},
+	} {
+		raw := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: gmsslPKCS8(t, key, algo)})
+
+		parsed, err := PEMtoSM2PrivateKey(raw, nil)
+		require.NoError(t, err, name)
+		assert.Equal(t, key.D, parsed.D, name)
+		assert.Equal(t, key.X, parsed.X, name)
+
+		generic, err := PEMtoPrivateKey(raw, nil)
+		require.NoError(t, err, name)
+		assert.IsType(t, &sm2.PrivateKey{}, generic, name)
+	}
+
+	// openssl ecparam -genkey writes the parameters first
+	sec1, err := SM2PrivateKeyToSEC1PEM(key)
+	require.NoError(t, err)
+	params := pem.EncodeToMemory(&pem.Block{Type: "EC PARAMETERS", Bytes: curveParams(t).FullBytes})
+	parsed, err := PEMtoSM2PrivateKey(append(params, sec1...), nil)
+	require.NoError(t, err)
+	assert.Equal(t, key.D, parsed.D)
+	generic, err := PEMtoPrivateKey(append(params, sec1...), nil)
+	require.NoError(t, err)
+	assert.IsType(t, &sm2.PrivateKey{}, generic)
+
+	// SEC 1 keys must name their curve
+	der, err := asn1.Marshal(ecPrivateKey{Version: 1, PrivateKey: key.D.Bytes()})
+	require.NoError(t, err)
+	_, err = ParseGmSSLSM2PrivateKey(der)
+	assert.EqualError(t, err, "EC private key without curve")
+}
+
+func TestGmSSLPrivateKeyMismatch(t *testing.T) {
+	key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	other, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+
+	der, err := asn1.Marshal(ecPrivateKey{
+		Version:       1,
+		PrivateKey:    key.D.Bytes(),
+		NamedCurveOID: oidSM2P256V1,
+		PublicKey:     asn1.BitString{Bytes: elliptic.Marshal(other.Curve, other.X, other.Y)},
+	})
+	require.NoError(t, err)
+	_, err = ParseGmSSLSM2PrivateKey(der)
+	assert.EqualError(t, err, "EC private key does not match its public key")
+
+	p8 := gmsslPKCS8(t, key, pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyECDSA, Parameters: asn1.RawValue{FullBytes: mustMarshal(t, oidNamedCurveP256)}})
+	_, err = ParseGmSSLSM2PrivateKey(p8)
+	assert.Error(t, err)
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	raw, err := asn1.Marshal(v)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestGmSSLExport(t *testing.T) {
+	key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+
+	raw, err := SM2PrivateKeyToGmSSLPEM(key, nil)
+	require.NoError(t, err)
+	block, _ := pem.Decode(raw)
+	require.NotNil(t, block)
+	assert.Equal(t, "PRIVATE KEY", block.Type)
+	parsed, err := PEMtoSM2PrivateKey(raw, nil)
+	require.NoError(t, err)
+	assert.Equal(t, key.D, parsed.D)
+
+	raw, err = SM2PrivateKeyToGmSSLPEM(key, []byte("password"))
+	require.NoError(t, err)
+	block, _ = pem.Decode(raw)
+	require.NotNil(t, block)
+	assert.Equal(t, "ENCRYPTED PRIVATE KEY", block.Type)
+	parsed, err = PEMtoSM2PrivateKey(raw, []byte("password"))
+	require.NoError(t, err)
+	assert.Equal(t, key.D, parsed.D)
+
+	raw, err = SM2PublicKeyToGmSSLPEM(&key.PublicKey)
+	require.NoError(t, err)
+	block, _ = pem.Decode(raw)
+	require.NotNil(t, block)
+	assert.Equal(t, "PUBLIC KEY", block.Type)
+	pub, err := PEMtoPublicKey(raw, nil)
+	require.NoError(t, err)
+	require.IsType(t, &sm2.PublicKey{}, pub)
+	assert.Equal(t, key.X, pub.(*sm2.PublicKey).X)
+
+	_, err = SM2PrivateKeyToGmSSLPEM(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestGmSSLPublicKeys(t *testing.T) {
+	key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+
+	point := elliptic.Marshal(key.Curve, key.X, key.Y)
+	der, err := asn1.Marshal(pkixPublicKey{
+		Algo:      pkix.AlgorithmIdentifier{Algorithm: oidSM2P256V1},
+		BitString: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	require.NoError(t, err)
+
+	pub, err := ParseGmSSLSM2PublicKey(der)
+	require.NoError(t, err)
+	assert.Equal(t, key.X, pub.X)
+	assert.Equal(t, key.Y, pub.Y)
+
+	generic, err := DERToPublicKey(der)
+	require.NoError(t, err)
+	assert.IsType(t, &sm2.PublicKey{}, generic)
+}
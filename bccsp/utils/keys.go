@@ -320,13 +320,21 @@ func PEMtoPrivateKey(raw []byte, pwd []byte) (interface{}, error) {
 	if len(raw) == 0 {
 		return nil, errors.New("Invalid PEM. It must be different from nil")
 	}
-	block, _ := pem.Decode(raw)
-	if block == nil {
-		return nil, fmt.Errorf("Failed decoding PEM. Block must be different from nil. [% x]", raw)
+	block, err := firstKeyBlock(raw)
+	if err != nil {
+		return nil, err
 	}
 
 	// TODO: derive from header the type of the key
 
+	if block.Type == "ENCRYPTED PRIVATE KEY" {
+		key, err := ParseEncryptedPKCS8PrivateKey(block.Bytes, pwd)
+		if err != nil {
+			return nil, fmt.Errorf("Failed PKCS#8 decryption [%s]", err)
+		}
+		return key, nil
+	}
+
 	if x509.IsEncryptedPEMBlock(block) {
 		if len(pwd) == 0 {
 			return nil, errors.New("Encrypted Key. Need a password")
@@ -367,6 +375,10 @@ func PEMtoPrivateKey(raw []byte, pwd []byte) (interface{}, error) {
 
 	cert, err := DERToPrivateKey(block.Bytes)
 	if err != nil {
+		// SM2 keys of GmSSL and OpenSSL 3
+		if key, gmErr := ParseGmSSLSM2PrivateKey(block.Bytes); gmErr == nil {
+			return key, nil
+		}
 		return nil, err
 	}
 	return cert, err
@@ -836,6 +848,9 @@ func DERToPublicKey(raw []byte) (pub interface{}, err error) {
 	if key, err := ParsePKIXSM2PublicKey(raw); err == nil {
 		return key, nil
 	}
+	if key, err := ParseGmSSLSM2PublicKey(raw); err == nil {
+		return key, nil
+	}
 
 	return nil, errors.New("Invalid key type. The DER must contain an ecdsa.PublicKey or sm2.PublicKey")
 
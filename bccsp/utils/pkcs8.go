@@ -0,0 +1,330 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/paul-lee-attorney/gm/sm4"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// PKCS#8 encryption ciphers.
+const (
+	PKCS8CipherSM4CBC = "SM4-CBC"
+	PKCS8CipherSM4GCM = "SM4-GCM"
+)
+
+const (
+	defaultPBKDF2Iterations = 10000
+	defaultPBKDF2SaltSize   = 16
+	// maxPBKDF2Iterations bounds the work a crafted key file can cause
+	maxPBKDF2Iterations = 10000000
+
+	sm4KeySize  = 16
+	gcmNonceLen = 12
+	gcmTagLen   = 16
+)
+
+var (
+	oidPBES2    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACSM3  = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401, 2}
+	oidSM4CBC   = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 2}
+	oidSM4GCM   = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 8}
+	asn1NullRaw = asn1.RawValue{Tag: asn1.TagNull}
+)
+
+// encryptedPrivateKeyInfo is the EncryptedPrivateKeyInfo of RFC 5958.
+type encryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params is the PBES2-params of RFC 8018.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params is the PBKDF2-params of RFC 8018. The PRF is required
+// here, since HMAC-SHA1 is not accepted.
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier
+}
+
+// gcmParams is the GCMParameters of RFC 5084.
+type gcmParams struct {
+	Nonce  []byte
+	ICVLen int `asn1:"optional,default:12"`
+}
+
+// PKCS8EncryptionOpts tunes the encryption of PKCS#8 private keys.
+type PKCS8EncryptionOpts struct {
+	// Cipher is PKCS8CipherSM4CBC, the default, or PKCS8CipherSM4GCM
+	Cipher string
+	// Iterations of PBKDF2, 10000 by default
+	Iterations int
+	// SaltSize in bytes, 16 by default
+	SaltSize int
+}
+
+// MarshalEncryptedPKCS8PrivateKey encodes an *sm2.PrivateKey or
+// *ecdsa.PrivateKey as a PKCS#8 EncryptedPrivateKeyInfo, encrypted with
+// PBES2 using PBKDF2 with HMAC-SM3 and SM4, as GmSSL does. A nil opts
+// selects SM4-CBC.
+// 使用口令, 以PBKDF2(HMAC-SM3)派生SM4密钥, 将私钥加密为PKCS#8格式.
+func MarshalEncryptedPKCS8PrivateKey(privateKey interface{}, pwd []byte, opts *PKCS8EncryptionOpts) ([]byte, error) {
+	if len(pwd) == 0 {
+		return nil, errors.New("Invalid password. It must be different from nil")
+	}
+	if opts == nil {
+		opts = &PKCS8EncryptionOpts{}
+	}
+
+	var der []byte
+	var err error
+	switch k := privateKey.(type) {
+	case *sm2.PrivateKey:
+		if k == nil {
+			return nil, errors.New("Invalid sm2 private key. It must be different from nil")
+		}
+		der, err = MarshalPKCS8SM2PrivateKey(k)
+	case *ecdsa.PrivateKey:
+		if k == nil {
+			return nil, errors.New("Invalid ecdsa private key. It must be different from nil")
+		}
+		der, err = x509.MarshalPKCS8PrivateKey(k)
+	default:
+		return nil, errors.New("Invalid key type. It must be *sm2.PrivateKey or *ecdsa.PrivateKey")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling private key to PKCS#8 [%s]", err)
+	}
+
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = defaultPBKDF2Iterations
+	}
+	saltSize := opts.SaltSize
+	if saltSize <= 0 {
+		saltSize = defaultPBKDF2SaltSize
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("cannot generate salt [%s]", err)
+	}
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: iterations,
+		KeyLength:      sm4KeySize,
+		PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACSM3, Parameters: asn1NullRaw},
+	})
+	if err != nil {
+		return nil, err
+	}
+	key := pbkdf2.Key(pwd, salt, iterations, sm4KeySize, sm3.New)
+
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var scheme pkix.AlgorithmIdentifier
+	var encrypted []byte
+	switch opts.Cipher {
+	case "", PKCS8CipherSM4CBC:
+		iv := make([]byte, block.BlockSize())
+		if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+			return nil, fmt.Errorf("cannot generate IV [%s]", err)
+		}
+		params, err := asn1.Marshal(iv)
+		if err != nil {
+			return nil, err
+		}
+		scheme = pkix.AlgorithmIdentifier{Algorithm: oidSM4CBC, Parameters: asn1.RawValue{FullBytes: params}}
+
+		encrypted = pkcs7Pad(der, block.BlockSize())
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+	case PKCS8CipherSM4GCM:
+		nonce := make([]byte, gcmNonceLen)
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, fmt.Errorf("cannot generate nonce [%s]", err)
+		}
+		params, err := asn1.Marshal(gcmParams{Nonce: nonce, ICVLen: gcmTagLen})
+		if err != nil {
+			return nil, err
+		}
+		scheme = pkix.AlgorithmIdentifier{Algorithm: oidSM4GCM, Parameters: asn1.RawValue{FullBytes: params}}
+
+		aead, err := cipher.NewGCMWithTagSize(block, gcmTagLen)
+		if err != nil {
+			return nil, err
+		}
+		encrypted = aead.Seal(nil, nonce, der, nil)
+	default:
+		return nil, fmt.Errorf("Unsupported PKCS#8 cipher [%s]", opts.Cipher)
+	}
+
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: pkix.AlgorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  scheme,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(encryptedPrivateKeyInfo{
+		Algo:          pkix.AlgorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}},
+		EncryptedData: encrypted,
+	})
+}
+
+// ParseEncryptedPKCS8PrivateKey decrypts a PKCS#8 EncryptedPrivateKeyInfo
+// produced by MarshalEncryptedPKCS8PrivateKey or GmSSL, and returns the
+// *sm2.PrivateKey or *ecdsa.PrivateKey it holds.
+// 解析以PBES2(PBKDF2 HMAC-SM3, SM4)加密的PKCS#8私钥.
+func ParseEncryptedPKCS8PrivateKey(der, pwd []byte) (interface{}, error) {
+	if len(pwd) == 0 {
+		return nil, errors.New("Encrypted Key. Need a password")
+	}
+
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted PKCS#8 [%s]", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("trailing data after encrypted PKCS#8")
+	}
+	if !info.Algo.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported PKCS#8 encryption algorithm %v", info.Algo.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algo.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed to parse PBES2 parameters [%s]", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %v", params.KeyDerivationFunc.Algorithm)
+	}
+
+	var kdfParams pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+		return nil, fmt.Errorf("failed to parse PBKDF2 parameters [%s]", err)
+	}
+	if !kdfParams.PRF.Algorithm.Equal(oidHMACSM3) {
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF %v", kdfParams.PRF.Algorithm)
+	}
+	if kdfParams.IterationCount <= 0 || kdfParams.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("invalid PBKDF2 iteration count %d", kdfParams.IterationCount)
+	}
+	if kdfParams.KeyLength != 0 && kdfParams.KeyLength != sm4KeySize {
+		return nil, fmt.Errorf("invalid PBKDF2 key length %d", kdfParams.KeyLength)
+	}
+	key := pbkdf2.Key(pwd, kdfParams.Salt, kdfParams.IterationCount, sm4KeySize, sm3.New)
+
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var decrypted []byte
+	scheme := params.EncryptionScheme
+	switch {
+	case scheme.Algorithm.Equal(oidSM4CBC):
+		var iv []byte
+		if _, err := asn1.Unmarshal(scheme.Parameters.FullBytes, &iv); err != nil || len(iv) != block.BlockSize() {
+			return nil, errors.New("invalid SM4-CBC IV")
+		}
+		if len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+			return nil, errors.New("invalid SM4-CBC ciphertext length")
+		}
+		decrypted = make([]byte, len(info.EncryptedData))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(decrypted, info.EncryptedData)
+		if decrypted, err = pkcs7Unpad(decrypted, block.BlockSize()); err != nil {
+			return nil, errors.New("decryption failed, wrong password?")
+		}
+	case scheme.Algorithm.Equal(oidSM4GCM):
+		var gp gcmParams
+		if _, err := asn1.Unmarshal(scheme.Parameters.FullBytes, &gp); err != nil || len(gp.Nonce) == 0 {
+			return nil, errors.New("invalid SM4-GCM parameters")
+		}
+		if gp.ICVLen < 12 || gp.ICVLen > 16 {
+			return nil, fmt.Errorf("invalid SM4-GCM tag length %d", gp.ICVLen)
+		}
+		aead, err := newGCM(block, len(gp.Nonce), gp.ICVLen)
+		if err != nil {
+			return nil, err
+		}
+		if decrypted, err = aead.Open(nil, gp.Nonce, info.EncryptedData, nil); err != nil {
+			return nil, errors.New("decryption failed, wrong password?")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#8 encryption scheme %v", scheme.Algorithm)
+	}
+
+	if k, err := ParsePKCS8SM2PrivateKey(decrypted); err == nil {
+		return k, nil
+	}
+	return DERToPrivateKey(decrypted)
+}
+
+// PrivateKeyToEncryptedPKCS8PEM encodes privateKey as an ENCRYPTED PRIVATE
+// KEY PEM block, see MarshalEncryptedPKCS8PrivateKey.
+func PrivateKeyToEncryptedPKCS8PEM(privateKey interface{}, pwd []byte, opts *PKCS8EncryptionOpts) ([]byte, error) {
+	der, err := MarshalEncryptedPKCS8PrivateKey(privateKey, pwd, opts)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der}), nil
+}
+
+func newGCM(block cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if nonceSize == gcmNonceLen {
+		return cipher.NewGCMWithTagSize(block, tagSize)
+	}
+	if tagSize != gcmTagLen {
+		return nil, fmt.Errorf("unsupported SM4-GCM nonce size %d with tag length %d", nonceSize, tagSize)
+	}
+	return cipher.NewGCMWithNonceSize(block, nonceSize)
+}
+
+func pkcs7Pad(src []byte, blockSize int) []byte {
+	pad := blockSize - len(src)%blockSize
+	return append(append([]byte{}, src...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+}
+
+func pkcs7Unpad(src []byte, blockSize int) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, errors.New("invalid padding")
+	}
+	pad := int(src[len(src)-1])
+	if pad == 0 || pad > blockSize || pad > len(src) {
+		return nil, errors.New("invalid padding")
+	}
+	for _, b := range src[len(src)-pad:] {
+		if int(b) != pad {
+			return nil, errors.New("invalid padding")
+		}
+	}
+	return src[:len(src)-pad], nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/pem"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptedPKCS8(t *testing.T) {
+	sm2Key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	pwd := []byte("password")
+
+	for _, key := range []interface{}{sm2Key, ecKey} {
+		for _, c := range []string{"", PKCS8CipherSM4CBC, PKCS8CipherSM4GCM} {
+			der, err := MarshalEncryptedPKCS8PrivateKey(key, pwd, &PKCS8EncryptionOpts{Cipher: c, Iterations: 1000})
+			require.NoError(t, err)
+
+			parsed, err := ParseEncryptedPKCS8PrivateKey(der, pwd)
+			require.NoError(t, err, "cipher %q", c)
+			assert.Equal(t, key, parsed)
+
+			_, err = ParseEncryptedPKCS8PrivateKey(der, []byte("wrong"))
+			assert.Error(t, err)
+			_, err = ParseEncryptedPKCS8PrivateKey(der, nil)
+			assert.EqualError(t, err, "Encrypted Key. Need a password")
+		}
+	}
+
+	_, err = MarshalEncryptedPKCS8PrivateKey(sm2Key, pwd, &PKCS8EncryptionOpts{Cipher: "AES-256-CBC"})
+	assert.EqualError(t, err, "Unsupported PKCS#8 cipher [AES-256-CBC]")
+	_, err = MarshalEncryptedPKCS8PrivateKey(sm2Key, nil, nil)
+	assert.Error(t, err)
+	_, err = MarshalEncryptedPKCS8PrivateKey("key", pwd, nil)
+	assert.Error(t, err)
+}
+
+func TestEncryptedPKCS8Structure(t *testing.T) {
+	key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+
+	der, err := MarshalEncryptedPKCS8PrivateKey(key, []byte("password"), nil)
+	require.NoError(t, err)
+
+	var info encryptedPrivateKeyInfo
+	_, err = asn1.Unmarshal(der, &info)
+	require.NoError(t, err)
+	assert.Equal(t, oidPBES2, info.Algo.Algorithm)
+
+	var params pbes2Params
+	_, err = asn1.Unmarshal(info.Algo.Parameters.FullBytes, &params)
+	require.NoError(t, err)
+	assert.Equal(t, oidPBKDF2, params.KeyDerivationFunc.Algorithm)
+	assert.Equal(t, oidSM4CBC, params.EncryptionScheme.Algorithm)
+
+	var kdf pbkdf2Params
+	_, err = asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf)
+	require.NoError(t, err)
+	assert.Equal(t, oidHMACSM3, kdf.PRF.Algorithm)
+	assert.Equal(t, defaultPBKDF2Iterations, kdf.IterationCount)
+	assert.Len(t, kdf.Salt, defaultPBKDF2SaltSize)
+	assert.Equal(t, sm4KeySize, kdf.KeyLength)
+
+	// Refuse iteration counts meant to stall the parser
+	kdf.IterationCount = maxPBKDF2Iterations + 1
+	params.KeyDerivationFunc.Parameters.FullBytes, err = asn1.Marshal(kdf)
+	require.NoError(t, err)
+	info.Algo.Parameters.FullBytes, err = asn1.Marshal(params)
+	require.NoError(t, err)
+	der, err = asn1.Marshal(info)
+	require.NoError(t, err)
+	_, err = ParseEncryptedPKCS8PrivateKey(der, []byte("password"))
+	assert.EqualError(t, err, "invalid PBKDF2 iteration count 10000001")
+}
+
+func TestEncryptedPKCS8PEM(t *testing.T) {
+	key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	pwd := []byte("password")
+
+	raw, err := PrivateKeyToEncryptedPKCS8PEM(key, pwd, &PKCS8EncryptionOpts{Cipher: PKCS8CipherSM4GCM})
+	require.NoError(t, err)
+	block, _ := pem.Decode(raw)
+	require.NotNil(t, block)
+	assert.Equal(t, "ENCRYPTED PRIVATE KEY", block.Type)
+
+	parsed, err := PEMtoPrivateKey(raw, pwd)
+	require.NoError(t, err)
+	assert.Equal(t, key, parsed)
+
+	_, err = PEMtoPrivateKey(raw, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"sync/atomic"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// SKIConvention is a way of deriving the subject key identifier of a
+// public key. Toolchains differ: Fabric hashes the uncompressed point with
+// SM3 for SM2 and SHA-256 for ECDSA, OpenSSL and crypto/x509 use SHA-1 of
+// the point (RFC 5280 method 1), and some GM CAs hash the whole
+// SubjectPublicKeyInfo.
+type SKIConvention int32
+
+const (
+	// SKIFabric is SM3 of the point for SM2 keys and SHA-256 of the point
+	// for ECDSA keys, the default
+	SKIFabric SKIConvention = iota
+	// SKISHA1 is SHA-1 of the point, RFC 5280 section 4.2.1.2 method 1
+	SKISHA1
+	// SKISHA1SPKI is SHA-1 of the DER encoded SubjectPublicKeyInfo
+	SKISHA1SPKI
+	// SKISM3 is SM3 of the point, whatever the algorithm of the key
+	SKISM3
+	// SKISM3SPKI is SM3 of the DER encoded SubjectPublicKeyInfo
+	SKISM3SPKI
+	// SKISHA256 is SHA-256 of the point, whatever the algorithm of the key
+	SKISHA256
+	// SKITruncatedSHA256 is the leftmost 160 bits of SHA-256 of the point,
+	// RFC 7093 method 1
+	SKITruncatedSHA256
+)
+
+var skiConventionNames = []string{"fabric", "sha1", "sha1-spki", "sm3", "sm3-spki", "sha256", "sha256-160"}
+
+// SKIConventions lists all conventions, in the order MatchSKI tries them.
+var SKIConventions = []SKIConvention{
+	SKIFabric, SKISHA1, SKISHA1SPKI, SKISM3, SKISM3SPKI, SKISHA256, SKITruncatedSHA256,
+}
+
+func (c SKIConvention) String() string {
+	if c < 0 || int(c) >= len(skiConventionNames) {
+		return fmt.Sprintf("SKIConvention(%d)", int32(c))
+	}
+	return skiConventionNames[c]
+}
+
+var skiConvention int32
+
+// SetSKIConvention sets the convention SKI uses in the process.
+func SetSKIConvention(c SKIConvention) {
+	atomic.StoreInt32(&skiConvention, int32(c))
+}
+
+// GetSKIConvention returns the convention SKI uses in the process.
+func GetSKIConvention() SKIConvention {
+	return SKIConvention(atomic.LoadInt32(&skiConvention))
+}
+
+// ParseSKIConvention returns the convention of the given name, SKIFabric
+// if name is empty.
+func ParseSKIConvention(name string) (SKIConvention, error) {
+	if name == "" {
+		return SKIFabric, nil
+	}
+	for i, n := range skiConventionNames {
+		if strings.EqualFold(name, n) {
+			return SKIConvention(i), nil
+		}
+	}
+	return SKIFabric, fmt.Errorf("unknown SKI convention [%s]", name)
+}
+
+// SKI returns the subject key identifier of an *sm2.PublicKey or
+// *ecdsa.PublicKey with the convention of the process, nil for other keys.
+func SKI(pub interface{}) []byte {
+	ski, err := ComputeSKI(pub, GetSKIConvention())
+	if err != nil {
+		return nil
+	}
+	return ski
+}
+
+// ComputeSKI returns the subject key identifier of an *sm2.PublicKey or
+// *ecdsa.PublicKey with convention c.
+func ComputeSKI(pub interface{}, c SKIConvention) ([]byte, error) {
+	var point []byte
+	isSM2 := false
+	switch k := pub.(type) {
+	case *sm2.PublicKey:
+		if k == nil {
+			return nil, errors.New("invalid sm2 public key. It must be different from nil")
+		}
+		point = elliptic.Marshal(k.Curve, k.X, k.Y)
+		isSM2 = true
+	case *ecdsa.PublicKey:
+		if k == nil {
+			return nil, errors.New("invalid ecdsa public key. It must be different from nil")
+		}
+		point = elliptic.Marshal(k.Curve, k.X, k.Y)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", pub)
+	}
+
+	switch c {
+	case SKIFabric:
+		if isSM2 {
+			return hashOf(sm3.New, point), nil
+		}
+		return hashOf(sha256.New, point), nil
+	case SKISHA1:
+		return hashOf(sha1.New, point), nil
+	case SKISM3:
+		return hashOf(sm3.New, point), nil
+	case SKISHA256:
+		return hashOf(sha256.New, point), nil
+	case SKITruncatedSHA256:
+		return hashOf(sha256.New, point)[:sha1.Size], nil
+	case SKISHA1SPKI, SKISM3SPKI:
+		spki, err := ConvertKey(pub, KeyFormatPKIX)
+		if err != nil {
+			return nil, err
+		}
+		if c == SKISHA1SPKI {
+			return hashOf(sha1.New, spki), nil
+		}
+		return hashOf(sm3.New, spki), nil
+	default:
+		return nil, fmt.Errorf("unknown SKI convention [%s]", c)
+	}
+}
+
+func hashOf(newHash func() hash.Hash, data []byte) []byte {
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// AllSKIs returns the subject key identifiers of pub with every convention.
+func AllSKIs(pub interface{}) (map[SKIConvention][]byte, error) {
+	skis := map[SKIConvention][]byte{}
+	for _, c := range SKIConventions {
+		ski, err := ComputeSKI(pub, c)
+		if err != nil {
+			return nil, err
+		}
+		skis[c] = ski
+	}
+	return skis, nil
+}
+
+// MatchSKI returns the first convention under which ski is the subject key
+// identifier of pub, such as the SubjectKeyId of a certificate issued by an
+// external CA.
+func MatchSKI(pub interface{}, ski []byte) (SKIConvention, bool) {
+	for _, c := range SKIConventions {
+		if s, err := ComputeSKI(pub, c); err == nil && bytes.Equal(s, ski) {
+			return c, true
+		}
+	}
+	return SKIFabric, false
+}
+
+// Fingerprint formats the subject key identifier of pub with convention c
+// as colon separated upper case hex, as openssl does.
+func Fingerprint(pub interface{}, c SKIConvention) (string, error) {
+	ski, err := ComputeSKI(pub, c)
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(ski))
+	for i := range ski {
+		parts[i] = strings.ToUpper(hex.EncodeToString(ski[i : i+1]))
+	}
+	return strings.Join(parts, ":"), nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeSKI(t *testing.T) {
+	sm2Key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	sm2Point := elliptic.Marshal(sm2Key.Curve, sm2Key.X, sm2Key.Y)
+	ecPoint := elliptic.Marshal(ecKey.Curve, ecKey.X, ecKey.Y)
+	ecSPKI, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+
+	ski, err := ComputeSKI(&sm2Key.PublicKey, SKIFabric)
+	require.NoError(t, err)
+	assert.Equal(t, hashOf(sm3.New, sm2Point), ski)
+	ski, err = ComputeSKI(&ecKey.PublicKey, SKIFabric)
+	require.NoError(t, err)
+	h := sha256.Sum256(ecPoint)
+	assert.Equal(t, h[:], ski)
+
+	ski, err = ComputeSKI(&ecKey.PublicKey, SKISHA1)
+	require.NoError(t, err)
+	h1 := sha1.Sum(ecPoint)
+	assert.Equal(t, h1[:], ski)
+	ski, err = ComputeSKI(&ecKey.PublicKey, SKISHA1SPKI)
+	require.NoError(t, err)
+	h1 = sha1.Sum(ecSPKI)
+	assert.Equal(t, h1[:], ski)
+	ski, err = ComputeSKI(&ecKey.PublicKey, SKITruncatedSHA256)
+	require.NoError(t, err)
+	assert.Equal(t, h[:20], ski)
+
+	skis, err := AllSKIs(&sm2Key.PublicKey)
+	require.NoError(t, err)
+	assert.Len(t, skis, len(SKIConventions))
+	assert.Equal(t, skis[SKIFabric], skis[SKISM3])
+
+	_, err = ComputeSKI("key", SKIFabric)
+	assert.EqualError(t, err, "unsupported public key type string")
+	_, err = ComputeSKI(&ecKey.PublicKey, SKIConvention(42))
+	assert.EqualError(t, err, "unknown SKI convention [SKIConvention(42)]")
+}
+
+func TestMatchSKI(t *testing.T) {
+	sm2Key, err := SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+
+	for _, c := range []SKIConvention{SKISHA1, SKISM3SPKI, SKITruncatedSHA256} {
+		ski, err := ComputeSKI(&sm2Key.PublicKey, c)
+		require.NoError(t, err)
+		matched, ok := MatchSKI(&sm2Key.PublicKey, ski)
+		assert.True(t, ok)
+		assert.Equal(t, c, matched)
+	}
+
+	_, ok := MatchSKI(&sm2Key.PublicKey, []byte("unknown"))
+	assert.False(t, ok)
+}
+
+func TestSKIConvention(t *testing.T) {
+	defer SetSKIConvention(SKIFabric)
+
+	c, err := ParseSKIConvention("")
+	assert.NoError(t, err)
+	assert.Equal(t, SKIFabric, c)
+	c, err = ParseSKIConvention("SHA1-SPKI")
+	assert.NoError(t, err)
+	assert.Equal(t, SKISHA1SPKI, c)
+	_, err = ParseSKIConvention("md5")
+	assert.EqualError(t, err, "unknown SKI convention [md5]")
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	SetSKIConvention(SKISHA1SPKI)
+	expected, err := ComputeSKI(&ecKey.PublicKey, SKISHA1SPKI)
+	require.NoError(t, err)
+	assert.Equal(t, expected, SKI(&ecKey.PublicKey))
+	assert.Nil(t, SKI("key"))
+}
+
+func TestFingerprint(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	fp, err := Fingerprint(&ecKey.PublicKey, SKISHA1)
+	require.NoError(t, err)
+	assert.Len(t, fp, 3*sha1.Size-1)
+	assert.Regexp(t, "^([0-9A-F]{2}:){19}[0-9A-F]{2}$", fp)
+}
@@ -0,0 +1,352 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// sm2DefaultUID 为GB/T 32918中规定的默认用户身份标识
+var sm2DefaultUID = []byte("1234567812345678")
+
+var one = new(big.Int).SetInt64(1)
+
+// OIDNamedCurveSM2 is the GM/T 0006 object identifier of the sm2p256v1 curve.
+var OIDNamedCurveSM2 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+
+// SM2CurveByOID returns the SM2 curve identified by oid.
+func SM2CurveByOID(oid asn1.ObjectIdentifier) (elliptic.Curve, error) {
+	if oid.Equal(OIDNamedCurveSM2) {
+		return sm2.GetSm2P256V1(), nil
+	}
+	return nil, fmt.Errorf("unsupported SM2 curve OID [%s]", oid)
+}
+
+// SM2GenerateKey generates an SM2 key pair on curve, drawing the
+// private key d from [1, n-2] as required by GB/T 32918.1.
+func SM2GenerateKey(rand io.Reader, curve elliptic.Curve) (*sm2.PrivateKey, error) {
+	if rand == nil {
+		return nil, errors.New("invalid random source, it must be different from nil")
+	}
+	if curve == nil {
+		return nil, errors.New("invalid curve, it must be different from nil")
+	}
+
+	n := curve.Params().N
+	d, err := randFieldElement(rand, new(big.Int).Sub(n, one))
+	if err != nil {
+		return nil, err
+	}
+
+	priv := new(sm2.PrivateKey)
+	priv.Curve = curve
+	priv.D = d
+	priv.X, priv.Y = curve.ScalarBaseMult(d.Bytes())
+	return priv, nil
+}
+
+// SM2ZA computes the user digest Z_A defined in GB/T 32918.2, i.e.
+// SM3(ENTL_A || ID_A || a || b || x_G || y_G || x_A || y_A).
+// If uid is empty, the default user identity is used.
+func SM2ZA(pub *sm2.PublicKey, uid []byte) ([]byte, error) {
+	if pub == nil || pub.Curve == nil {
+		return nil, errors.New("invalid SM2 public key, it must be different from nil")
+	}
+	if len(uid) == 0 {
+		uid = sm2DefaultUID
+	}
+	if len(uid) >= 8192 {
+		return nil, errors.New("invalid uid, it must be shorter than 8192 bytes")
+	}
+
+	params := pub.Curve.Params()
+	byteLen := (params.BitSize + 7) / 8
+	// SM2推荐曲线的参数a = p - 3
+	a := new(big.Int).Sub(params.P, big.NewInt(3))
+
+	entl := len(uid) * 8
+	h := sm3.New()
+	h.Write([]byte{byte(entl >> 8), byte(entl)})
+	h.Write(uid)
+	for _, v := range []*big.Int{a, params.B, params.Gx, params.Gy, pub.X, pub.Y} {
+		h.Write(padBytes(v.Bytes(), byteLen))
+	}
+	return h.Sum(nil), nil
+}
+
+// SM2PrivateKeyFromRaw builds an SM2 private key from its raw big-endian
+// scalar D, deriving the public key. If pub is not empty, it must be the
+// uncompressed point of the derived public key.
+func SM2PrivateKeyFromRaw(d, pub []byte) (*sm2.PrivateKey, error) {
+	if len(d) != sm2.KeyBytes {
+		return nil, fmt.Errorf("invalid SM2 private key length [%d], must be %d bytes", len(d), sm2.KeyBytes)
+	}
+
+	curve := sm2.GetSm2P256V1()
+	k := new(big.Int).SetBytes(d)
+	// GB/T 32918.1 要求私钥 d 取值于 [1, n-2]
+	if k.Sign() <= 0 || k.Cmp(new(big.Int).Sub(curve.Params().N, one)) >= 0 {
+		return nil, errors.New("invalid SM2 private key value")
+	}
+
+	priv := new(sm2.PrivateKey)
+	priv.Curve = curve
+	priv.D = k
+	priv.X, priv.Y = curve.ScalarBaseMult(d)
+
+	if len(pub) != 0 {
+		x, y := elliptic.Unmarshal(curve, pub)
+		if x == nil {
+			return nil, errors.New("invalid SM2 public key, failed to unmarshal elliptic curve point")
+		}
+		if x.Cmp(priv.X) != 0 || y.Cmp(priv.Y) != 0 {
+			return nil, errors.New("SM2 public key does not match the private key")
+		}
+	}
+
+	return priv, nil
+}
+
+// SM2SignWithRand signs msg with priv as defined in GB/T 32918.2, using
+// rand as the source for the per-signature nonce. msg is the original
+// message: it is hashed together with Z_A before signing.
+func SM2SignWithRand(rand io.Reader, priv *sm2.PrivateKey, uid, msg []byte) (r, s *big.Int, err error) {
+	if rand == nil {
+		return nil, nil, errors.New("invalid random source, it must be different from nil")
+	}
+	if priv == nil || priv.D == nil {
+		return nil, nil, errors.New("invalid SM2 private key, it must be different from nil")
+	}
+
+	za, err := SM2ZA(&priv.PublicKey, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	h := sm3.New()
+	h.Write(za)
+	h.Write(msg)
+	e := new(big.Int).SetBytes(h.Sum(nil))
+
+	dInv, err := sm2DInv(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return sm2SignDigest(rand, priv, dInv, e, priv.Curve.ScalarBaseMult)
+}
+
+// SM2Precomputed holds the values of an SM2 private key which do not change
+// from one signature to the next, so that they can be computed once when the
+// key is loaded instead of on every signature.
+type SM2Precomputed struct {
+	za   []byte
+	dInv *big.Int
+}
+
+// NewSM2Precomputed computes the signing values of priv for user identity uid.
+func NewSM2Precomputed(priv *sm2.PrivateKey, uid []byte) (*SM2Precomputed, error) {
+	if priv == nil || priv.D == nil {
+		return nil, errors.New("invalid SM2 private key, it must be different from nil")
+	}
+
+	za, err := SM2ZA(&priv.PublicKey, uid)
+	if err != nil {
+		return nil, err
+	}
+	dInv, err := sm2DInv(priv)
+	if err != nil {
+		return nil, err
+	}
+	return &SM2Precomputed{za: za, dInv: dInv}, nil
+}
+
+// SM2SignPrecomputed signs msg like SM2SignWithRand, reusing the values in
+// pre. The secret nonce k is multiplied by the constant-time scalar
+// multiplication of the curve, not with the fixed-base table of
+// verification, whose timing depends on the scalar.
+func SM2SignPrecomputed(rand io.Reader, priv *sm2.PrivateKey, pre *SM2Precomputed, msg []byte) (r, s *big.Int, err error) {
+	if rand == nil {
+		return nil, nil, errors.New("invalid random source, it must be different from nil")
+	}
+	if priv == nil || priv.D == nil {
+		return nil, nil, errors.New("invalid SM2 private key, it must be different from nil")
+	}
+	if pre == nil {
+		return nil, nil, errors.New("invalid precomputed values, they must be different from nil")
+	}
+
+	h := sm3.New()
+	h.Write(pre.za)
+	h.Write(msg)
+	e := new(big.Int).SetBytes(h.Sum(nil))
+
+	return sm2SignDigest(rand, priv, pre.dInv, e, priv.Curve.ScalarBaseMult)
+}
+
+// sm2DInv 计算 (1 + d)^-1 mod n
+func sm2DInv(priv *sm2.PrivateKey) (*big.Int, error) {
+	dInv := new(big.Int).Add(priv.D, one)
+	if dInv.ModInverse(dInv, priv.Curve.Params().N) == nil {
+		return nil, errors.New("invalid SM2 private key")
+	}
+	return dInv, nil
+}
+
+// sm2SignDigest 在已计算出的杂凑值e上完成SM2签名运算
+func sm2SignDigest(rand io.Reader, priv *sm2.PrivateKey, dInv, e *big.Int, baseMult func([]byte) (*big.Int, *big.Int)) (r, s *big.Int, err error) {
+	n := priv.Curve.Params().N
+
+	for {
+		k, err := randFieldElement(rand, n)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// k is padded to the byte length of n, so that its length does not
+		// depend on its value
+		x1, _ := baseMult(padBytes(k.Bytes(), (n.BitLen()+7)/8))
+		r = new(big.Int).Add(e, x1)
+		r.Mod(r, n)
+		if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+			continue
+		}
+
+		// s = (1 + d)^-1 * (k - r * d) mod n
+		s = new(big.Int).Mul(r, priv.D)
+		s.Sub(k, s)
+		s.Mul(s, dInv)
+		s.Mod(s, n)
+		if s.Sign() != 0 {
+			return r, s, nil
+		}
+	}
+}
+
+// randFieldElement returns a random element of [1, n-1] read from rand,
+// following the method of FIPS 186-4, B.4.1.
+func randFieldElement(rand io.Reader, n *big.Int) (*big.Int, error) {
+	b := make([]byte, (n.BitLen()+7)/8+8)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+
+	k := new(big.Int).SetBytes(b)
+	nMinusOne := new(big.Int).Sub(n, one)
+	k.Mod(k, nMinusOne)
+	k.Add(k, one)
+	return k, nil
+}
+
+// fixedBaseTable holds j * 16^i * G for every 4-bit window i of a scalar
+// and every digit j, so that k*G only needs one point addition per window.
+// Its lookups and additions depend on the scalar: it only serves the public
+// scalars of signature verification, never secret ones.
+type fixedBaseTable struct {
+	curve elliptic.Curve
+	x, y  [][16]*big.Int
+}
+
+var (
+	sm2BaseTableOnce sync.Once
+	sm2BaseTableInst *fixedBaseTable
+)
+
+// sm2BaseTable returns the fixed-base table of curve, building it on first use.
+func sm2BaseTable(curve elliptic.Curve) *fixedBaseTable {
+	sm2BaseTableOnce.Do(func() {
+		sm2BaseTableInst = newFixedBaseTable(curve)
+	})
+	return sm2BaseTableInst
+}
+
+func newFixedBaseTable(curve elliptic.Curve) *fixedBaseTable {
+	params := curve.Params()
+	windows := (params.N.BitLen() + 3) / 4
+
+	t := &fixedBaseTable{
+		curve: curve,
+		x:     make([][16]*big.Int, windows),
+		y:     make([][16]*big.Int, windows),
+	}
+
+	bx, by := params.Gx, params.Gy
+	for i := 0; i < windows; i++ {
+		t.x[i][1], t.y[i][1] = bx, by
+		for j := 2; j < 16; j++ {
+			t.x[i][j], t.y[i][j] = curve.Add(t.x[i][j-1], t.y[i][j-1], bx, by)
+		}
+		// 下一个窗口的基点为 16 * 当前基点
+		bx, by = curve.Double(t.x[i][8], t.y[i][8])
+	}
+
+	return t
+}
+
+// MarshalSM2RawSignature encodes r and s as the 64-byte concatenation r||s.
+func MarshalSM2RawSignature(r, s *big.Int) ([]byte, error) {
+	if r == nil || s == nil || r.Sign() <= 0 || s.Sign() <= 0 {
+		return nil, errors.New("invalid signature, r and s must be larger than zero")
+	}
+	if r.BitLen() > 8*sm2.KeyBytes || s.BitLen() > 8*sm2.KeyBytes {
+		return nil, errors.New("invalid signature, r and s must fit in 32 bytes")
+	}
+
+	raw := make([]byte, 2*sm2.KeyBytes)
+	copy(raw[:sm2.KeyBytes], padBytes(r.Bytes(), sm2.KeyBytes))
+	copy(raw[sm2.KeyBytes:], padBytes(s.Bytes(), sm2.KeyBytes))
+	return raw, nil
+}
+
+// UnmarshalSM2RawSignature decodes a 64-byte r||s signature.
+func UnmarshalSM2RawSignature(raw []byte) (r, s *big.Int, err error) {
+	if len(raw) != 2*sm2.KeyBytes {
+		return nil, nil, fmt.Errorf("invalid raw signature length [%d], must be %d bytes", len(raw), 2*sm2.KeyBytes)
+	}
+
+	r = new(big.Int).SetBytes(raw[:sm2.KeyBytes])
+	s = new(big.Int).SetBytes(raw[sm2.KeyBytes:])
+	if r.Sign() == 0 || s.Sign() == 0 {
+		return nil, nil, errors.New("invalid signature, r and s must be larger than zero")
+	}
+	return r, s, nil
+}
+
+// SM2SignatureDERToRaw converts an ASN.1 DER SM2 signature to r||s.
+func SM2SignatureDERToRaw(der []byte) ([]byte, error) {
+	r, s, err := UnmarshalECDSASignature(der)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalSM2RawSignature(r, s)
+}
+
+// SM2SignatureRawToDER converts an r||s SM2 signature to ASN.1 DER.
+func SM2SignatureRawToDER(raw []byte) ([]byte, error) {
+	r, s, err := UnmarshalSM2RawSignature(raw)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalECDSASignature(r, s)
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSM2SignWithRand(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	msg := []byte("Hello World")
+	r, s, err := SM2SignWithRand(rand.Reader, priv, nil, msg)
+	assert.NoError(t, err)
+	assert.True(t, sm2.VerifyByRS(&priv.PublicKey, nil, msg, r, s))
+
+	sig, err := MarshalECDSASignature(r, s)
+	assert.NoError(t, err)
+	assert.True(t, sm2.Verify(&priv.PublicKey, nil, msg, sig))
+
+	_, _, err = SM2SignWithRand(nil, priv, nil, msg)
+	assert.Error(t, err)
+
+	_, _, err = SM2SignWithRand(rand.Reader, nil, nil, msg)
+	assert.Error(t, err)
+}
+
+func TestSM2ZA(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	za, err := SM2ZA(&priv.PublicKey, nil)
+	assert.NoError(t, err)
+	zaDefault, err := SM2ZA(&priv.PublicKey, []byte("1234567812345678"))
+	assert.NoError(t, err)
+	assert.Equal(t, za, zaDefault)
+
+	other, err := SM2ZA(&priv.PublicKey, []byte("alice"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, za, other)
+
+	_, err = SM2ZA(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestSM2SignPrecomputed(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	pre, err := NewSM2Precomputed(priv, nil)
+	assert.NoError(t, err)
+
+	msg := []byte("Hello World")
+	for i := 0; i < 10; i++ {
+		r, s, err := SM2SignPrecomputed(rand.Reader, priv, pre, msg)
+		assert.NoError(t, err)
+		assert.True(t, sm2.VerifyByRS(&priv.PublicKey, nil, msg, r, s))
+	}
+
+	_, _, err = SM2SignPrecomputed(rand.Reader, priv, nil, msg)
+	assert.Error(t, err)
+
+	_, err = NewSM2Precomputed(nil, nil)
+	assert.Error(t, err)
+}
+
+func TestSM2PrivateKeyFromRaw(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	d := padBytes(priv.D.Bytes(), sm2.KeyBytes)
+	key, err := SM2PrivateKeyFromRaw(d, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, priv.D, key.D)
+	assert.Equal(t, priv.X, key.X)
+	assert.Equal(t, priv.Y, key.Y)
+
+	pub := elliptic.Marshal(priv.Curve, priv.X, priv.Y)
+	_, err = SM2PrivateKeyFromRaw(d, pub)
+	assert.NoError(t, err)
+
+	other, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	_, err = SM2PrivateKeyFromRaw(d, elliptic.Marshal(other.Curve, other.X, other.Y))
+	assert.EqualError(t, err, "SM2 public key does not match the private key")
+
+	_, err = SM2PrivateKeyFromRaw(d, []byte{4, 1, 2, 3})
+	assert.Error(t, err)
+
+	_, err = SM2PrivateKeyFromRaw(d[1:], nil)
+	assert.EqualError(t, err, "invalid SM2 private key length [31], must be 32 bytes")
+
+	_, err = SM2PrivateKeyFromRaw(make([]byte, sm2.KeyBytes), nil)
+	assert.EqualError(t, err, "invalid SM2 private key value")
+}
+
+func TestSM2RawSignature(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	msg := []byte("Hello World")
+	r, s, err := SM2SignWithRand(rand.Reader, priv, nil, msg)
+	assert.NoError(t, err)
+
+	raw, err := MarshalSM2RawSignature(r, s)
+	assert.NoError(t, err)
+	assert.Len(t, raw, 64)
+
+	r2, s2, err := UnmarshalSM2RawSignature(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r2)
+	assert.Equal(t, s, s2)
+
+	der, err := SM2SignatureRawToDER(raw)
+	assert.NoError(t, err)
+	r3, s3, err := UnmarshalECDSASignature(der)
+	assert.NoError(t, err)
+	assert.Equal(t, r, r3)
+	assert.Equal(t, s, s3)
+
+	raw2, err := SM2SignatureDERToRaw(der)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, raw2)
+
+	// Small values are left-padded
+	raw, err = MarshalSM2RawSignature(big.NewInt(1), big.NewInt(2))
+	assert.NoError(t, err)
+	assert.Equal(t, byte(1), raw[31])
+	assert.Equal(t, byte(2), raw[63])
+
+	_, err = MarshalSM2RawSignature(big.NewInt(0), big.NewInt(2))
+	assert.Error(t, err)
+	_, err = MarshalSM2RawSignature(new(big.Int).Lsh(one, 256), big.NewInt(2))
+	assert.Error(t, err)
+
+	_, _, err = UnmarshalSM2RawSignature(raw[:63])
+	assert.EqualError(t, err, "invalid raw signature length [63], must be 64 bytes")
+	_, _, err = UnmarshalSM2RawSignature(make([]byte, 64))
+	assert.Error(t, err)
+
+	_, err = SM2SignatureDERToRaw([]byte{0, 1, 2})
+	assert.Error(t, err)
+}
+
+func TestSM2GenerateKey(t *testing.T) {
+	curve, err := SM2CurveByOID(OIDNamedCurveSM2)
+	assert.NoError(t, err)
+	assert.Equal(t, sm2.GetSm2P256V1(), curve)
+
+	_, err = SM2CurveByOID([]int{1, 2, 840, 10045, 3, 1, 7})
+	assert.EqualError(t, err, "unsupported SM2 curve OID [1.2.840.10045.3.1.7]")
+
+	priv, err := SM2GenerateKey(rand.Reader, curve)
+	assert.NoError(t, err)
+	assert.True(t, curve.IsOnCurve(priv.X, priv.Y))
+
+	msg := []byte("Hello World")
+	r, s, err := SM2SignWithRand(rand.Reader, priv, nil, msg)
+	assert.NoError(t, err)
+	assert.True(t, sm2.VerifyByRS(&priv.PublicKey, nil, msg, r, s))
+
+	_, err = SM2GenerateKey(nil, curve)
+	assert.Error(t, err)
+	_, err = SM2GenerateKey(rand.Reader, nil)
+	assert.Error(t, err)
+}
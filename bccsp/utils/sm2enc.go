@@ -0,0 +1,137 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/elliptic"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/paul-lee-attorney/gm/sm3"
+)
+
+// sm3DigestSize is the size in bytes of an SM3 digest, i.e. of C3.
+const sm3DigestSize = 32
+
+// SM2Encrypt encrypts msg for pub as defined in GB/T 32918.4 and returns
+// C1 || C3 || C2. A non-empty label is appended to the KDF input
+// x2 || y2, binding the ciphertext to a purpose: decrypting it with a
+// different label fails. An empty label yields the standard scheme.
+func SM2Encrypt(rand io.Reader, pub *sm2.PublicKey, msg, label []byte) ([]byte, error) {
+	if rand == nil {
+		return nil, errors.New("invalid random source, it must be different from nil")
+	}
+	if pub == nil || pub.Curve == nil || pub.X == nil || pub.Y == nil {
+		return nil, errors.New("invalid SM2 public key, it must be different from nil")
+	}
+	if len(msg) == 0 {
+		return nil, errors.New("invalid message, it must not be empty")
+	}
+
+	curve := pub.Curve
+	size := (curve.Params().BitSize + 7) / 8
+	for {
+		k, err := randFieldElement(rand, curve.Params().N)
+		if err != nil {
+			return nil, err
+		}
+
+		x1, y1 := curve.ScalarBaseMult(k.Bytes())
+		x2, y2 := curve.ScalarMult(pub.X, pub.Y, k.Bytes())
+		x2b, y2b := padBytes(x2.Bytes(), size), padBytes(y2.Bytes(), size)
+
+		t := sm2KDF(len(msg), x2b, y2b, label)
+		if t == nil {
+			// t 全为0时需重新选取随机数k
+			continue
+		}
+
+		c1 := elliptic.Marshal(curve, x1, y1)
+		c3 := sm2C3(x2b, msg, y2b)
+		out := make([]byte, 0, len(c1)+len(c3)+len(msg))
+		out = append(out, c1...)
+		out = append(out, c3...)
+		for i := range msg {
+			out = append(out, msg[i]^t[i])
+		}
+		return out, nil
+	}
+}
+
+// SM2Decrypt decrypts a C1 || C3 || C2 ciphertext produced by SM2Encrypt
+// with the same label.
+func SM2Decrypt(priv *sm2.PrivateKey, ciphertext, label []byte) ([]byte, error) {
+	if priv == nil || priv.D == nil || priv.Curve == nil {
+		return nil, errors.New("invalid SM2 private key, it must be different from nil")
+	}
+
+	curve := priv.Curve
+	size := (curve.Params().BitSize + 7) / 8
+	c1Len := 1 + 2*size
+	if len(ciphertext) <= c1Len+sm3DigestSize {
+		return nil, errors.New("invalid SM2 ciphertext, it is too short")
+	}
+
+	x1, y1 := elliptic.Unmarshal(curve, ciphertext[:c1Len])
+	if x1 == nil {
+		return nil, errors.New("invalid SM2 ciphertext, C1 is not a point on the curve")
+	}
+	c3 := ciphertext[c1Len : c1Len+sm3DigestSize]
+	c2 := ciphertext[c1Len+sm3DigestSize:]
+
+	x2, y2 := curve.ScalarMult(x1, y1, priv.D.Bytes())
+	x2b, y2b := padBytes(x2.Bytes(), size), padBytes(y2.Bytes(), size)
+
+	t := sm2KDF(len(c2), x2b, y2b, label)
+	if t == nil {
+		return nil, errors.New("SM2 decryption failed")
+	}
+	msg := make([]byte, len(c2))
+	for i := range c2 {
+		msg[i] = c2[i] ^ t[i]
+	}
+
+	if subtle.ConstantTimeCompare(sm2C3(x2b, msg, y2b), c3) != 1 {
+		return nil, errors.New("SM2 decryption failed")
+	}
+	return msg, nil
+}
+
+// sm2KDF is the key derivation function of GB/T 32918.4 instantiated with
+// SM3 over the concatenation of z. It returns nil if the derived key is all
+// zeros.
+func sm2KDF(klen int, z ...[]byte) []byte {
+	out := make([]byte, 0, klen+sm3DigestSize)
+	var ct [4]byte
+	for i := uint32(1); len(out) < klen; i++ {
+		binary.BigEndian.PutUint32(ct[:], i)
+		h := sm3.New()
+		for _, b := range z {
+			h.Write(b)
+		}
+		h.Write(ct[:])
+		out = h.Sum(out)
+	}
+	out = out[:klen]
+
+	if new(big.Int).SetBytes(out).Sign() == 0 {
+		return nil
+	}
+	return out
+}
+
+func sm2C3(x2, msg, y2 []byte) []byte {
+	h := sm3.New()
+	h.Write(x2)
+	h.Write(msg)
+	h.Write(y2)
+	return h.Sum(nil)
+}
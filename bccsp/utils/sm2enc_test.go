@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSM2Encrypt(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	msg := []byte("a private data collection entry, longer than one SM3 digest")
+	for _, label := range [][]byte{nil, []byte("pvtdata-transfer")} {
+		ct, err := SM2Encrypt(rand.Reader, &priv.PublicKey, msg, label)
+		assert.NoError(t, err)
+		assert.Len(t, ct, 65+32+len(msg))
+
+		pt, err := SM2Decrypt(priv, ct, label)
+		assert.NoError(t, err)
+		assert.Equal(t, msg, pt)
+
+		// A different label must not decrypt
+		_, err = SM2Decrypt(priv, ct, []byte("other"))
+		assert.EqualError(t, err, "SM2 decryption failed")
+
+		ct[len(ct)-1] ^= 1
+		_, err = SM2Decrypt(priv, ct, label)
+		assert.EqualError(t, err, "SM2 decryption failed")
+	}
+
+	_, err = SM2Encrypt(rand.Reader, &priv.PublicKey, nil, nil)
+	assert.EqualError(t, err, "invalid message, it must not be empty")
+	_, err = SM2Encrypt(nil, &priv.PublicKey, msg, nil)
+	assert.Error(t, err)
+	_, err = SM2Encrypt(rand.Reader, nil, msg, nil)
+	assert.Error(t, err)
+
+	_, err = SM2Decrypt(priv, make([]byte, 97), nil)
+	assert.EqualError(t, err, "invalid SM2 ciphertext, it is too short")
+	_, err = SM2Decrypt(priv, make([]byte, 100), nil)
+	assert.EqualError(t, err, "invalid SM2 ciphertext, C1 is not a point on the curve")
+}
+
+func TestSM2KDF(t *testing.T) {
+	k := sm2KDF(70, []byte("z"))
+	assert.Len(t, k, 70)
+	assert.Equal(t, k[:32], sm2KDF(32, []byte("z")))
+	assert.NotEqual(t, k, sm2KDF(70, []byte("z"), []byte("label")))
+}
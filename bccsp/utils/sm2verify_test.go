@@ -0,0 +1,117 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSM2Verify(t *testing.T) {
+	msg := []byte("Hello World")
+	for i := 0; i < 20; i++ {
+		priv, err := sm2.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		pub := &priv.PublicKey
+
+		r, s, err := SM2SignWithRand(rand.Reader, priv, nil, msg)
+		assert.NoError(t, err)
+		assert.True(t, SM2Verify(pub, nil, msg, r, s))
+		assert.Equal(t, sm2.VerifyByRS(pub, nil, msg, r, s), SM2Verify(pub, nil, msg, r, s))
+
+		assert.False(t, SM2Verify(pub, nil, []byte("Hello World!"), r, s))
+		assert.False(t, SM2Verify(pub, []byte("another uid"), msg, r, s))
+		assert.False(t, SM2Verify(pub, nil, msg, r, new(big.Int).Add(s, one)))
+		assert.False(t, SM2Verify(pub, nil, msg, new(big.Int).Add(r, one), s))
+	}
+
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	pub := &priv.PublicKey
+	n := pub.Curve.Params().N
+	r, s, err := SM2SignWithRand(rand.Reader, priv, nil, msg)
+	assert.NoError(t, err)
+
+	assert.False(t, SM2Verify(pub, nil, msg, new(big.Int), s))
+	assert.False(t, SM2Verify(pub, nil, msg, r, new(big.Int)))
+	assert.False(t, SM2Verify(pub, nil, msg, new(big.Int).Add(r, n), s))
+	assert.False(t, SM2Verify(pub, nil, msg, r, new(big.Int).Add(s, n)))
+	// r + s = n
+	assert.False(t, SM2Verify(pub, nil, msg, r, new(big.Int).Sub(n, r)))
+	assert.False(t, SM2Verify(nil, nil, msg, r, s))
+	assert.False(t, SM2Verify(pub, nil, msg, nil, s))
+}
+
+func TestJacobianField(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	curve := priv.Curve
+	params := curve.Params()
+	f := newJacobianField(params.P)
+
+	for i := 0; i < 20; i++ {
+		k, err := randFieldElement(rand.Reader, params.N)
+		assert.NoError(t, err)
+
+		x, _ := curve.ScalarBaseMult(k.Bytes())
+		jx, ok := f.affineX(sm2BaseTable(curve).jacobianBaseMult(f, k.Bytes()))
+		assert.True(t, ok)
+		assert.Equal(t, x, jx)
+
+		x, _ = curve.ScalarMult(priv.X, priv.Y, k.Bytes())
+		jx, ok = f.affineX(f.scalarMult(priv.X, priv.Y, k.Bytes()))
+		assert.True(t, ok)
+		assert.Equal(t, x, jx)
+	}
+
+	// P + P is 2P, P + (-P) is the point at infinity
+	x, _ := curve.Double(priv.X, priv.Y)
+	p := f.point(priv.X, priv.Y)
+	f.add(p, f.point(priv.X, priv.Y))
+	jx, ok := f.affineX(p)
+	assert.True(t, ok)
+	assert.Equal(t, x, jx)
+
+	p = f.point(priv.X, priv.Y)
+	f.add(p, f.point(priv.X, new(big.Int).Sub(params.P, priv.Y)))
+	_, ok = f.affineX(p)
+	assert.False(t, ok)
+
+	// (n - 1)P + P is the point at infinity
+	p = f.scalarMult(priv.X, priv.Y, new(big.Int).Sub(params.N, one).Bytes())
+	f.add(p, f.point(priv.X, priv.Y))
+	_, ok = f.affineX(p)
+	assert.False(t, ok)
+}
+
+func benchmarkSM2Verify(b *testing.B, verify func(pub *sm2.PublicKey, uid, msg []byte, r, s *big.Int) bool) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	assert.NoError(b, err)
+	msg := []byte("Hello World")
+	r, s, err := SM2SignWithRand(rand.Reader, priv, nil, msg)
+	assert.NoError(b, err)
+	sm2BaseTable(priv.Curve)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !verify(&priv.PublicKey, nil, msg, r, s) {
+			b.Fatal("signature should be valid")
+		}
+	}
+}
+
+func BenchmarkSM2VerifyByRS(b *testing.B) {
+	benchmarkSM2Verify(b, sm2.VerifyByRS)
+}
+
+func BenchmarkSM2Verify(b *testing.B) {
+	benchmarkSM2Verify(b, SM2Verify)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifycache
+
+import (
+	"github.com/hyperledger/fabric/common/metrics"
+)
+
+var (
+	lookups = metrics.CounterOpts{
+		Namespace:    "bccsp",
+		Subsystem:    "verify_cache",
+		Name:         "lookups",
+		Help:         "The number of signature verifications looked up in the cache.",
+		LabelNames:   []string{"result"},
+		StatsdFormat: "%{#fqname}.%{result}",
+	}
+	evictions = metrics.CounterOpts{
+		Namespace: "bccsp",
+		Subsystem: "verify_cache",
+		Name:      "evictions",
+		Help:      "The number of verification results evicted from the cache to make room for new ones.",
+	}
+	entries = metrics.GaugeOpts{
+		Namespace: "bccsp",
+		Subsystem: "verify_cache",
+		Name:      "entries",
+		Help:      "The number of verification results held in the cache.",
+	}
+)
+
+type Metrics struct {
+	Lookups   metrics.Counter
+	Evictions metrics.Counter
+	Entries   metrics.Gauge
+}
+
+func NewMetrics(p metrics.Provider) *Metrics {
+	return &Metrics{
+		Lookups:   p.NewCounter(lookups),
+		Evictions: p.NewCounter(evictions),
+		Entries:   p.NewGauge(entries),
+	}
+}
@@ -0,0 +1,252 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifycache
+
+import (
+	"container/list"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+const defaultSize = 10000
+
+// VerifyCacheOpts configures the cache of signature verification results.
+type VerifyCacheOpts struct {
+	// Enabled turns the cache on
+	Enabled bool `mapstructure:"enabled" json:"enabled" yaml:"Enabled"`
+	// Size is the number of verification results held, 10000 by default
+	Size int `mapstructure:"size,omitempty" json:"size,omitempty" yaml:"Size"`
+
+	// MetricsProvider receives the cache metrics, they are disabled if nil
+	MetricsProvider metrics.Provider `json:"-" yaml:"-"`
+}
+
+// Cache is a BCCSP that remembers the signatures its underlying BCCSP
+// verified successfully, keyed by the public key, the digest and the
+// signature, so that verifying the same signature again, such as the
+// creator signatures of a config block validated by several components,
+// does not repeat the SM2 or ECDSA scalar multiplications. The least
+// recently used results are evicted beyond the size of the cache.
+//
+// Only successful verifications of SM2 and ECDSA keys exposing their public
+// key, with no options or SM2SignerOpts, are cached: invalid signatures cannot evict valid ones,
+// and failures of the underlying BCCSP are never remembered.
+type Cache struct {
+	// csp serves all operations that are not overridden
+	bccsp.BCCSP
+
+	size int
+
+	metricsMutex sync.RWMutex
+	metrics      *Metrics
+
+	// most recently used results first
+	mutex sync.Mutex
+	items *list.List
+	table map[[sha256.Size]byte]*list.Element
+}
+
+// New returns a Cache of the verifications of csp.
+func New(csp bccsp.BCCSP, opts VerifyCacheOpts) (*Cache, error) {
+	if csp == nil {
+		return nil, errors.New("Invalid BCCSP instance. It must be different from nil")
+	}
+	if opts.Size < 0 {
+		return nil, errors.Errorf("Invalid cache size [%d]. It must be positive", opts.Size)
+	}
+
+	c := &Cache{
+		BCCSP: csp,
+		size:  opts.Size,
+		items: list.New(),
+		table: make(map[[sha256.Size]byte]*list.Element),
+	}
+	if c.size == 0 {
+		c.size = defaultSize
+	}
+
+	p := opts.MetricsProvider
+	if p == nil {
+		p = &disabled.Provider{}
+	}
+	c.metrics = NewMetrics(p)
+
+	return c, nil
+}
+
+// SetMetricsProvider redirects the metrics to p, for processes creating
+// their metrics provider after the BCCSP.
+func (c *Cache) SetMetricsProvider(p metrics.Provider) {
+	c.metricsMutex.Lock()
+	defer c.metricsMutex.Unlock()
+	c.metrics = NewMetrics(p)
+}
+
+func (c *Cache) getMetrics() *Metrics {
+	c.metricsMutex.RLock()
+	defer c.metricsMutex.RUnlock()
+	return c.metrics
+}
+
+// Unwrap returns the BCCSP whose verifications are cached.
+func (c *Cache) Unwrap() bccsp.BCCSP {
+	return c.BCCSP
+}
+
+// Verify verifies signature against key k and digest, returning the
+// result of an earlier successful verification when the cache holds it.
+func (c *Cache) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	key, ok := cacheKey(k, signature, digest, opts)
+	if !ok {
+		return c.BCCSP.Verify(k, signature, digest, opts)
+	}
+
+	m := c.getMetrics()
+	if c.get(key) {
+		m.Lookups.With("result", "hit").Add(1)
+		return true, nil
+	}
+	m.Lookups.With("result", "miss").Add(1)
+
+	valid, err := c.BCCSP.Verify(k, signature, digest, opts)
+	if err == nil && valid {
+		c.add(key, m)
+	}
+	return valid, err
+}
+
+// SignCtx signs digest using key k, handing ctx to the underlying BCCSP
+// when it implements bccsp.ContextSigner.
+func (c *Cache) SignCtx(ctx context.Context, k bccsp.Key, digest []byte, opts bccsp.SignerOpts) ([]byte, error) {
+	return bccsp.SignCtx(ctx, c.BCCSP, k, digest, opts)
+}
+
+// Len returns the number of verification results held.
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.table)
+}
+
+// Purge removes all verification results, e.g. after keys were revoked.
+func (c *Cache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.items.Init()
+	c.table = make(map[[sha256.Size]byte]*list.Element)
+	c.getMetrics().Entries.Set(0)
+}
+
+func (c *Cache) get(key [sha256.Size]byte) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elem, ok := c.table[key]
+	if ok {
+		c.items.MoveToFront(elem)
+	}
+	return ok
+}
+
+func (c *Cache) add(key [sha256.Size]byte, m *Metrics) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elem, ok := c.table[key]; ok {
+		c.items.MoveToFront(elem)
+		return
+	}
+	c.table[key] = c.items.PushFront(key)
+	if c.items.Len() > c.size {
+		victim := c.items.Back()
+		c.items.Remove(victim)
+		delete(c.table, victim.Value.([sha256.Size]byte))
+		m.Evictions.Add(1)
+	}
+	m.Entries.Set(float64(len(c.table)))
+}
+
+// cacheKey returns the SHA-256 digest of the public key of k, digest,
+// signature and the signature encoding of opts, each prefixed with its
+// length, or false if the verification must not be cached. Unlike the SKI,
+// which another key may claim, the public key cannot be shared.
+func cacheKey(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) ([sha256.Size]byte, bool) {
+	algorithm, curve, point, ok := publicKey(k)
+	if !ok {
+		return [sha256.Size]byte{}, false
+	}
+	var encoding bccsp.SM2SignatureEncoding
+	switch o := opts.(type) {
+	case nil:
+	case *bccsp.SM2SignerOpts:
+		encoding = o.Encoding
+	default:
+		return [sha256.Size]byte{}, false
+	}
+
+	h := sha256.New()
+	var length [8]byte
+	for _, b := range [][]byte{[]byte(algorithm), []byte(curve), point, digest, signature} {
+		binary.BigEndian.PutUint64(length[:], uint64(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	binary.BigEndian.PutUint64(length[:], uint64(encoding))
+	h.Write(length[:])
+
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key, true
+}
+
+// publicKey returns the algorithm, the curve name and the uncompressed point
+// of the public key of k, or false if k is not an SM2 or ECDSA key exposing
+// its public key.
+func publicKey(k bccsp.Key) (string, string, []byte, bool) {
+	if k == nil || k.Symmetric() {
+		return "", "", nil, false
+	}
+	if k.Private() {
+		var err error
+		if k, err = k.PublicKey(); err != nil {
+			return "", "", nil, false
+		}
+	}
+	cpk, ok := k.(bccsp.CryptoPublicKeyer)
+	if !ok {
+		return "", "", nil, false
+	}
+	pub, err := cpk.CryptoPublicKey()
+	if err != nil {
+		return "", "", nil, false
+	}
+
+	switch pub := pub.(type) {
+	case *sm2.PublicKey:
+		if pub == nil || pub.Curve == nil {
+			return "", "", nil, false
+		}
+		return bccsp.SM2, pub.Curve.Params().Name, elliptic.Marshal(pub.Curve, pub.X, pub.Y), true
+	case *ecdsa.PublicKey:
+		if pub == nil || pub.Curve == nil {
+			return "", "", nil, false
+		}
+		return bccsp.ECDSA, pub.Curve.Params().Name, elliptic.Marshal(pub.Curve, pub.X, pub.Y), true
+	default:
+		return "", "", nil, false
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verifycache
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDevice = errors.New("device error")
+
+// device counts the verifications it serves, and fails them when down.
+type device struct {
+	bccsp.BCCSP
+	verifications int
+	down          bool
+}
+
+func (d *device) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	d.verifications++
+	if d.down {
+		return false, errDevice
+	}
+	return d.BCCSP.Verify(k, signature, digest, opts)
+}
+
+func newTestCache(t *testing.T, opts VerifyCacheOpts) (*Cache, *device) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	d := &device{BCCSP: csp}
+	c, err := New(d, opts)
+	require.NoError(t, err)
+	return c, d
+}
+
+func TestNewInvalidArgs(t *testing.T) {
+	_, err := New(nil, VerifyCacheOpts{})
+	assert.EqualError(t, err, "Invalid BCCSP instance. It must be different from nil")
+
+	c, _ := newTestCache(t, VerifyCacheOpts{})
+	_, err = New(c, VerifyCacheOpts{Size: -1})
+	assert.EqualError(t, err, "Invalid cache size [-1]. It must be positive")
+}
+
+func TestVerify(t *testing.T) {
+	c, d := newTestCache(t, VerifyCacheOpts{Enabled: true})
+
+	p := &metricsfakes.Provider{}
+	counter := &metricsfakes.Counter{}
+	counter.WithReturns(counter)
+	gauge := &metricsfakes.Gauge{}
+	p.NewCounterReturns(counter)
+	p.NewGaugeReturns(gauge)
+	c.SetMetricsProvider(p)
+
+	k, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	pub, err := k.PublicKey()
+	require.NoError(t, err)
+	msg := []byte("block data")
+	sig, err := c.Sign(k, msg, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		valid, err := c.Verify(pub, sig, msg, nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	}
+	assert.Equal(t, 1, d.verifications)
+	assert.Equal(t, 1, c.Len())
+
+	// The private key has the same public key
+	valid, err := c.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 1, d.verifications)
+
+	require.Equal(t, 4, counter.WithCallCount())
+	assert.Equal(t, []string{"result", "miss"}, counter.WithArgsForCall(0))
+	assert.Equal(t, []string{"result", "hit"}, counter.WithArgsForCall(1))
+	require.Equal(t, 1, gauge.SetCallCount())
+	assert.Equal(t, float64(1), gauge.SetArgsForCall(0))
+
+	// Another digest, signature or encoding is verified again
+	valid, err = c.Verify(pub, sig, []byte("tampered"), nil)
+	require.NoError(t, err)
+	assert.False(t, valid)
+	valid, err = c.Verify(pub, sig, []byte("tampered"), nil)
+	require.NoError(t, err)
+	assert.False(t, valid)
+	assert.Equal(t, 3, d.verifications)
+
+	valid, err = c.Verify(pub, sig, msg, &bccsp.SM2SignerOpts{Encoding: bccsp.SM2SignatureAny})
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 4, d.verifications)
+	assert.Equal(t, 2, c.Len())
+
+	// Failures of the underlying BCCSP are not cached
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+	d.down = true
+	_, err = c.Verify(pub, sig, msg, nil)
+	assert.Equal(t, errDevice, err)
+	d.down = false
+	valid, err = c.Verify(pub, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, 6, d.verifications)
+}
+
+// skiKey is a key that claims the SKI of another key.
+type skiKey struct {
+	bccsp.Key
+	ski []byte
+}
+
+func (k *skiKey) SKI() []byte { return k.ski }
+
+func (k *skiKey) CryptoPublicKey() (crypto.PublicKey, error) {
+	return k.Key.(bccsp.CryptoPublicKeyer).CryptoPublicKey()
+}
+
+func TestVerifyKeyedByPublicKey(t *testing.T) {
+	c, d := newTestCache(t, VerifyCacheOpts{Enabled: true})
+
+	k, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	other, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	msg := []byte("block data")
+	sig, err := c.Sign(k, msg, nil)
+	require.NoError(t, err)
+
+	valid, err := c.Verify(k, sig, msg, nil)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// Another public key under the same SKI is not a hit
+	otherPub, err := other.PublicKey()
+	require.NoError(t, err)
+	valid, _ = c.Verify(&skiKey{Key: otherPub, ski: k.SKI()}, sig, msg, nil)
+	assert.False(t, valid)
+	assert.Equal(t, 2, d.verifications)
+}
+
+func TestVerifyUncached(t *testing.T) {
+	c, d := newTestCache(t, VerifyCacheOpts{Enabled: true})
+
+	k, err := c.KeyGen(&bccsp.ECDSAP256KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	digest, err := c.Hash([]byte("block data"), &bccsp.SHA256Opts{})
+	require.NoError(t, err)
+	sig, err := c.Sign(k, digest, nil)
+	require.NoError(t, err)
+
+	// Options other than SM2SignerOpts may affect the verification
+	for i := 0; i < 2; i++ {
+		valid, err := c.Verify(k, sig, digest, crypto.SHA256)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	}
+	assert.Equal(t, 2, d.verifications)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestEviction(t *testing.T) {
+	c, d := newTestCache(t, VerifyCacheOpts{Enabled: true, Size: 2})
+
+	k, err := c.KeyGen(&bccsp.SM2KeyGenOpts{Temporary: true})
+	require.NoError(t, err)
+	var msgs, sigs [][]byte
+	for _, msg := range []string{"block 1", "block 2", "block 3"} {
+		sig, err := c.Sign(k, []byte(msg), nil)
+		require.NoError(t, err)
+		msgs = append(msgs, []byte(msg))
+		sigs = append(sigs, sig)
+	}
+
+	verify := func(i int) {
+		valid, err := c.Verify(k, sigs[i], msgs[i], nil)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	}
+	verify(0)
+	verify(1)
+	verify(0)
+	// Block 2 is the least recently used
+	verify(2)
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 3, d.verifications)
+
+	verify(0)
+	verify(2)
+	assert.Equal(t, 3, d.verifications)
+	verify(1)
+	assert.Equal(t, 4, d.verifications)
+}
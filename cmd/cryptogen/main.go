@@ -7,17 +7,20 @@ package main
 
 import (
 	"bytes"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/hyperledger/fabric/internal/cryptogen/ca"
 	"github.com/hyperledger/fabric/internal/cryptogen/csp"
 	"github.com/hyperledger/fabric/internal/cryptogen/metadata"
 	"github.com/hyperledger/fabric/internal/cryptogen/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509/certlog"
 
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 	yaml "gopkg.in/yaml.v2"
@@ -67,13 +70,15 @@ type UsersSpec struct {
 }
 
 type OrgSpec struct {
-	Name          string       `yaml:"Name"`
-	Domain        string       `yaml:"Domain"`
-	EnableNodeOUs bool         `yaml:"EnableNodeOUs"`
-	CA            NodeSpec     `yaml:"CA"`
-	Template      NodeTemplate `yaml:"Template"`
-	Specs         []NodeSpec   `yaml:"Specs"`
-	Users         UsersSpec    `yaml:"Users"`
+	Name           string       `yaml:"Name"`
+	Domain         string       `yaml:"Domain"`
+	EnableNodeOUs  bool         `yaml:"EnableNodeOUs"`
+	KeyAlgorithm   string       `yaml:"KeyAlgorithm"`
+	IntermediateCA bool         `yaml:"IntermediateCA"`
+	CA             NodeSpec     `yaml:"CA"`
+	Template       NodeTemplate `yaml:"Template"`
+	Specs          []NodeSpec   `yaml:"Specs"`
+	Users          UsersSpec    `yaml:"Users"`
 }
 
 type Config struct {
@@ -110,6 +115,24 @@ PeerOrgs:
     Domain: org1.example.com
     EnableNodeOUs: false
 
+    # ---------------------------------------------------------------------------
+    # "KeyAlgorithm"
+    # ---------------------------------------------------------------------------
+    # ECDSA or SM2, the value of the keyalg flag by default. SM2 organizations
+    # get SM2 keys, certificates signed with SM2 over SM3, and the encryption
+    # certificates and keys of dual certificate identities, in msp/enccerts,
+    # and of GMTLS, in tls/server-enc.crt and tls/server-enc.key.
+    # ---------------------------------------------------------------------------
+    # KeyAlgorithm: SM2
+
+    # ---------------------------------------------------------------------------
+    # "IntermediateCA"
+    # ---------------------------------------------------------------------------
+    # Set to true for the certificates of the organization to be issued by an
+    # intermediate CA, in ca, itself issued by a root CA, in rootca.
+    # ---------------------------------------------------------------------------
+    # IntermediateCA: true
+
     # ---------------------------------------------------------------------------
     # "CA"
     # ---------------------------------------------------------------------------
@@ -206,6 +229,8 @@ var (
 	gen           = app.Command("generate", "Generate key material")
 	outputDir     = gen.Flag("output", "The output directory in which to place artifacts").Default("crypto-config").String()
 	genConfigFile = gen.Flag("config", "The configuration template to use").File()
+	genAuditLog   = gen.Flag("auditlog", "The audit log in which to record the issued certificates").String()
+	genKeyAlg     = gen.Flag("keyalg", "The key algorithm of organizations which do not set KeyAlgorithm, ECDSA or SM2").Default(csp.ECDSA).String()
 
 	showtemplate = app.Command("showtemplate", "Show the default configuration template")
 
@@ -213,18 +238,32 @@ var (
 	ext           = app.Command("extend", "Extend existing network")
 	inputDir      = ext.Flag("input", "The input directory in which existing network place").Default("crypto-config").String()
 	extConfigFile = ext.Flag("config", "The configuration template to use").File()
+	extAuditLog   = ext.Flag("auditlog", "The audit log in which to record the issued certificates").String()
+	extKeyAlg     = ext.Flag("keyalg", "The key algorithm of new organizations which do not set KeyAlgorithm, ECDSA or SM2").Default(csp.ECDSA).String()
 )
 
+// defaultKeyAlg is the key algorithm of organizations which do not set one
+var defaultKeyAlg = csp.ECDSA
+
+// auditLog records the issued certificates when the auditlog flag is set
+var auditLog *certlog.Log
+
 func main() {
 	kingpin.Version("0.0.1")
 	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
 
 	// "generate" command
 	case gen.FullCommand():
+		defaultKeyAlg = *genKeyAlg
+		openAuditLog(*genAuditLog)
 		generate()
+		closeAuditLog()
 
 	case ext.FullCommand():
+		defaultKeyAlg = *extKeyAlg
+		openAuditLog(*extAuditLog)
 		extend()
+		closeAuditLog()
 
 		// "showtemplate" command
 	case showtemplate.FullCommand():
@@ -308,8 +347,10 @@ func extendPeerOrg(orgSpec OrgSpec) {
 	caDir := filepath.Join(orgDir, "ca")
 	tlscaDir := filepath.Join(orgDir, "tlsca")
 
-	signCA := getCA(caDir, orgSpec, orgSpec.CA.CommonName)
-	tlsCA := getCA(tlscaDir, orgSpec, "tls"+orgSpec.CA.CommonName)
+	signCA := getCA(caDir, filepath.Join(orgDir, "rootca"), orgSpec, orgSpec.CA.CommonName)
+	tlsCA := getCA(tlscaDir, "", orgSpec, "tls"+orgSpec.CA.CommonName)
+	signCA.AuditLog = auditLog
+	tlsCA.AuditLog = auditLog
 
 	generateNodes(peersDir, orgSpec.Specs, signCA, tlsCA, msp.PEER, orgSpec.EnableNodeOUs)
 
@@ -357,8 +398,10 @@ func extendOrdererOrg(orgSpec OrgSpec) {
 		return
 	}
 
-	signCA := getCA(caDir, orgSpec, orgSpec.CA.CommonName)
-	tlsCA := getCA(tlscaDir, orgSpec, "tls"+orgSpec.CA.CommonName)
+	signCA := getCA(caDir, filepath.Join(orgDir, "rootca"), orgSpec, orgSpec.CA.CommonName)
+	tlsCA := getCA(tlscaDir, "", orgSpec, "tls"+orgSpec.CA.CommonName)
+	signCA.AuditLog = auditLog
+	tlsCA.AuditLog = auditLog
 
 	generateNodes(orderersDir, orgSpec.Specs, signCA, tlsCA, msp.ORDERER, orgSpec.EnableNodeOUs)
 
@@ -469,6 +512,15 @@ func renderNodeSpec(domain string, spec *NodeSpec) error {
 }
 
 func renderOrgSpec(orgSpec *OrgSpec, prefix string) error {
+	// Default and check the key algorithm
+	if len(orgSpec.KeyAlgorithm) == 0 {
+		orgSpec.KeyAlgorithm = defaultKeyAlg
+	}
+	orgSpec.KeyAlgorithm = strings.ToUpper(orgSpec.KeyAlgorithm)
+	if orgSpec.KeyAlgorithm != csp.ECDSA && orgSpec.KeyAlgorithm != csp.SM2 {
+		return fmt.Errorf("Unsupported key algorithm %s, it must be %s or %s", orgSpec.KeyAlgorithm, csp.ECDSA, csp.SM2)
+	}
+
 	// First process all of our templated nodes
 	for i := 0; i < orgSpec.Template.Count; i++ {
 		data := HostnameData{
@@ -518,24 +570,24 @@ func generatePeerOrg(baseDir string, orgSpec OrgSpec) {
 	fmt.Println(orgName)
 	// generate CAs
 	orgDir := filepath.Join(baseDir, "peerOrganizations", orgName)
-	caDir := filepath.Join(orgDir, "ca")
 	tlsCADir := filepath.Join(orgDir, "tlsca")
 	mspDir := filepath.Join(orgDir, "msp")
 	peersDir := filepath.Join(orgDir, "peers")
 	usersDir := filepath.Join(orgDir, "users")
 	adminCertsDir := filepath.Join(mspDir, "admincerts")
 	// generate signing CA
-	signCA, err := ca.NewCA(caDir, orgName, orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	signCA, err := newSignCA(orgDir, orgSpec)
 	if err != nil {
 		fmt.Printf("Error generating signCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
 	}
 	// generate TLS CA
-	tlsCA, err := ca.NewCA(tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	tlsCA, err := ca.NewCAWithKeyAlgorithm(orgSpec.KeyAlgorithm, tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
 	if err != nil {
 		fmt.Printf("Error generating tlsCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
 	}
+	recordCA(tlsCA)
 
 	err = msp.GenerateVerifyingMSP(mspDir, signCA, tlsCA, orgSpec.EnableNodeOUs)
 	if err != nil {
@@ -635,24 +687,24 @@ func generateOrdererOrg(baseDir string, orgSpec OrgSpec) {
 
 	// generate CAs
 	orgDir := filepath.Join(baseDir, "ordererOrganizations", orgName)
-	caDir := filepath.Join(orgDir, "ca")
 	tlsCADir := filepath.Join(orgDir, "tlsca")
 	mspDir := filepath.Join(orgDir, "msp")
 	orderersDir := filepath.Join(orgDir, "orderers")
 	usersDir := filepath.Join(orgDir, "users")
 	adminCertsDir := filepath.Join(mspDir, "admincerts")
 	// generate signing CA
-	signCA, err := ca.NewCA(caDir, orgName, orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	signCA, err := newSignCA(orgDir, orgSpec)
 	if err != nil {
 		fmt.Printf("Error generating signCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
 	}
 	// generate TLS CA
-	tlsCA, err := ca.NewCA(tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	tlsCA, err := ca.NewCAWithKeyAlgorithm(orgSpec.KeyAlgorithm, tlsCADir, orgName, "tls"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
 	if err != nil {
 		fmt.Printf("Error generating tlsCA for org %s:\n%v\n", orgName, err)
 		os.Exit(1)
 	}
+	recordCA(tlsCA)
 
 	err = msp.GenerateVerifyingMSP(mspDir, signCA, tlsCA, orgSpec.EnableNodeOUs)
 	if err != nil {
@@ -718,18 +770,88 @@ func copyFile(src, dst string) error {
 	return cerr
 }
 
+func openAuditLog(path string) {
+	if path == "" {
+		return
+	}
+	var err error
+	auditLog, err = certlog.Open(path)
+	if err != nil {
+		fmt.Printf("Error opening audit log %s:\n%v\n", path, err)
+		os.Exit(1)
+	}
+}
+
+func closeAuditLog() {
+	if auditLog == nil {
+		return
+	}
+	size := auditLog.Size()
+	root, _ := auditLog.RootHash(size)
+	if err := auditLog.Close(); err != nil {
+		fmt.Printf("Error closing audit log:\n%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Audit log tree size %d, root hash %x\n", size, root)
+}
+
+// recordCA records the certificate of a newly created CA in the audit log,
+// and has the CA record the certificates it signs.
+func recordCA(c *ca.CA) {
+	if auditLog == nil {
+		return
+	}
+	if _, err := auditLog.Append(c.SignCert); err != nil {
+		fmt.Printf("Error recording CA %s in the audit log:\n%v\n", c.Name, err)
+		os.Exit(1)
+	}
+	c.AuditLog = auditLog
+}
+
+// newSignCA creates the signing CA of an organization in orgDir/ca. When
+// the organization asks for an intermediate CA, it is issued by a root CA
+// created in orgDir/rootca.
+func newSignCA(orgDir string, orgSpec OrgSpec) (*ca.CA, error) {
+	caDir := filepath.Join(orgDir, "ca")
+	if !orgSpec.IntermediateCA {
+		signCA, err := ca.NewCAWithKeyAlgorithm(orgSpec.KeyAlgorithm, caDir, orgSpec.Domain, orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+		if err != nil {
+			return nil, err
+		}
+		recordCA(signCA)
+		return signCA, nil
+	}
+
+	rootCA, err := ca.NewCAWithKeyAlgorithm(orgSpec.KeyAlgorithm, filepath.Join(orgDir, "rootca"), orgSpec.Domain, "root"+orgSpec.CA.CommonName, orgSpec.CA.Country, orgSpec.CA.Province, orgSpec.CA.Locality, orgSpec.CA.OrganizationalUnit, orgSpec.CA.StreetAddress, orgSpec.CA.PostalCode)
+	if err != nil {
+		return nil, err
+	}
+	recordCA(rootCA)
+	return rootCA.NewIntermediateCA(caDir, orgSpec.CA.CommonName)
+}
+
 func printVersion() {
 	fmt.Println(metadata.GetVersionInfo())
 }
 
-func getCA(caDir string, spec OrgSpec, name string) *ca.CA {
-	priv, _ := csp.LoadPrivateKey(caDir)
+// getCA loads the CA in caDir, an intermediate CA issued by the root CA in
+// rootCADir when that directory exists.
+func getCA(caDir, rootCADir string, spec OrgSpec, name string) *ca.CA {
+	signer, _ := csp.LoadSigner(caDir)
 	cert, _ := ca.LoadCertificateECDSA(caDir)
 
+	var rootCert *x509.Certificate
+	if rootCADir != "" {
+		if _, err := os.Stat(rootCADir); err == nil {
+			rootCert, _ = ca.LoadCertificateECDSA(rootCADir)
+		}
+	}
+
 	return &ca.CA{
 		Name:               name,
-		Signer:             priv,
+		Signer:             signer,
 		SignCert:           cert,
+		RootCert:           rootCert,
 		Country:            spec.CA.Country,
 		Province:           spec.CA.Province,
 		Locality:           spec.CA.Locality,
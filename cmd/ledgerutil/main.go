@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric/internal/ledgerutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// command line flags
+var (
+	app = kingpin.New("ledgerutil", "Utility for verifying the block files of Hyperledger Fabric ledgers")
+
+	verify          = app.Command("verify", "Verify the hash chain and the block signatures of the block files of a channel ledger")
+	verifyLedgerDir = verify.Arg("ledgerDir", "The directory of the block files of the channel, e.g. /var/hyperledger/production/ledgersData/chains/chains/mychannel").Required().ExistingDir()
+	verifyOutput    = verify.Flag("output", "A file to write the JSON report to").Default(os.Stdout.Name()).OpenFile(os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+)
+
+func main() {
+	app.HelpFlag.Short('h')
+
+	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	// "verify" command
+	case verify.FullCommand():
+		defer (*verifyOutput).Close()
+		valid, err := verifyBlockfiles(*verifyLedgerDir, *verifyOutput)
+		if err != nil {
+			app.Fatalf("Error verifying block files: %s", err)
+		}
+		if !valid {
+			fmt.Fprintln(os.Stderr, "Integrity problems found in the block files")
+			os.Exit(1)
+		}
+	}
+}
+
+func verifyBlockfiles(ledgerDir string, output *os.File) (bool, error) {
+	report, err := ledgerutil.VerifyBlockfiles(ledgerDir, factory.GetDefault())
+	if err != nil {
+		return false, err
+	}
+	b, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		return false, err
+	}
+	if _, err := fmt.Fprintln(output, string(b)); err != nil {
+		return false, err
+	}
+	return report.Valid, nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger/fabric/internal/peer/chaincode"
 	"github.com/hyperledger/fabric/internal/peer/channel"
 	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/hyperledger/fabric/internal/peer/keys"
 	"github.com/hyperledger/fabric/internal/peer/lifecycle"
 	"github.com/hyperledger/fabric/internal/peer/node"
 	"github.com/hyperledger/fabric/internal/peer/version"
@@ -48,6 +49,7 @@ func main() {
 	mainCmd.AddCommand(chaincode.Cmd(nil, cryptoProvider))
 	mainCmd.AddCommand(channel.Cmd(nil))
 	mainCmd.AddCommand(lifecycle.Cmd(cryptoProvider))
+	mainCmd.AddCommand(keys.Cmd())
 
 	// On failure Cobra prints the usage message and error string, so we only
 	// need to exit with a non-0 status
@@ -9,6 +9,8 @@ package capabilities
 import (
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -28,8 +30,62 @@ const (
 
 	// ChannelV2_0 is the capabilities string for standard new non-backwards compatible fabric v2.0 channel capabilities.
 	ChannelV2_0 = "V2_0"
+
+	// ChannelSM2Only is the capabilities string for channels whose identities must use SM2 keys and SM2 with SM3 signatures.
+	ChannelSM2Only = "SM2_ONLY"
+
+	// ChannelNoSHA1 is the capabilities string for channels whose identities must not be certified with SHA-1 signatures.
+	ChannelNoSHA1 = "NO_SHA1"
+
+	// ChannelMinECKey256 is the capabilities string for channels whose identities must use SM2 or ECDSA keys of at least 256 bits.
+	ChannelMinECKey256 = "MIN_EC_KEY_256"
+
+	// ChannelMinECKey384 is the capabilities string for channels whose identities must use SM2 or ECDSA keys of at least 384 bits.
+	ChannelMinECKey384 = "MIN_EC_KEY_384"
+
+	// ChannelMinRSAKey2048 is the capabilities string for channels whose identities must use RSA keys of at least 2048 bits.
+	ChannelMinRSAKey2048 = "MIN_RSA_KEY_2048"
+
+	// ChannelGMAlgorithms is the capabilities string for channels whose config may declare the SM3 hashing algorithm and the signature algorithms allowed to their identities.
+	ChannelGMAlgorithms = "GM_ALGORITHMS"
+
+	// ChannelSignatureSM2 is the capabilities string for channels whose identities may use SM2 with SM3 signatures.
+	ChannelSignatureSM2 = "SIGNATURE_SM2"
+
+	// ChannelSignatureECDSA is the capabilities string for channels whose identities may use ECDSA signatures.
+	ChannelSignatureECDSA = "SIGNATURE_ECDSA"
+
+	// ChannelSignatureRSA is the capabilities string for channels whose identities may use RSA signatures.
+	ChannelSignatureRSA = "SIGNATURE_RSA"
+
+	// ChannelSignatureEd25519 is the capabilities string for channels whose identities may use Ed25519 signatures.
+	ChannelSignatureEd25519 = "SIGNATURE_ED25519"
 )
 
+// signatureCapabilities maps the capabilities strings of the allowed
+// signature algorithms to their families.
+var signatureCapabilities = map[string]gmx509.SignatureAlgorithm{
+	ChannelSignatureSM2:     gmx509.SignatureSM2,
+	ChannelSignatureECDSA:   gmx509.SignatureECDSA,
+	ChannelSignatureRSA:     gmx509.SignatureRSA,
+	ChannelSignatureEd25519: gmx509.SignatureEd25519,
+}
+
+// SignatureAlgorithmCapability returns the capabilities string allowing
+// the signature algorithm named name, e.g. SM2.
+func SignatureAlgorithmCapability(name string) (string, error) {
+	algorithm, err := gmx509.ParseSignatureAlgorithm(name)
+	if err != nil {
+		return "", err
+	}
+	for capability, a := range signatureCapabilities {
+		if a == algorithm {
+			return capability, nil
+		}
+	}
+	return "", errors.Errorf("no capability for signature algorithm %s", name)
+}
+
 // ChannelProvider provides capabilities information for channel level config.
 type ChannelProvider struct {
 	*registry
@@ -38,6 +94,9 @@ type ChannelProvider struct {
 	v142 bool
 	v143 bool
 	v20  bool
+
+	algorithmPolicy gmx509.AlgorithmPolicy
+	gmAlgorithms    bool
 }
 
 // NewChannelProvider creates a channel capabilities provider.
@@ -49,6 +108,22 @@ func NewChannelProvider(capabilities map[string]*cb.Capability) *ChannelProvider
 	_, cp.v142 = capabilities[ChannelV1_4_2]
 	_, cp.v143 = capabilities[ChannelV1_4_3]
 	_, cp.v20 = capabilities[ChannelV2_0]
+	_, cp.algorithmPolicy.SM2Only = capabilities[ChannelSM2Only]
+	_, cp.algorithmPolicy.NoSHA1 = capabilities[ChannelNoSHA1]
+	if _, ok := capabilities[ChannelMinECKey384]; ok {
+		cp.algorithmPolicy.MinECKeySize = 384
+	} else if _, ok := capabilities[ChannelMinECKey256]; ok {
+		cp.algorithmPolicy.MinECKeySize = 256
+	}
+	if _, ok := capabilities[ChannelMinRSAKey2048]; ok {
+		cp.algorithmPolicy.MinRSAKeySize = 2048
+	}
+	for capability, algorithm := range signatureCapabilities {
+		if _, ok := capabilities[capability]; ok {
+			cp.algorithmPolicy.SignatureAlgorithms |= algorithm
+		}
+	}
+	_, cp.gmAlgorithms = capabilities[ChannelGMAlgorithms]
 	return cp
 }
 
@@ -61,6 +136,10 @@ func (cp *ChannelProvider) Type() string {
 func (cp *ChannelProvider) HasCapability(capability string) bool {
 	switch capability {
 	// Add new capability names here
+	case ChannelSM2Only, ChannelNoSHA1, ChannelMinECKey256, ChannelMinECKey384, ChannelMinRSAKey2048:
+		return true
+	case ChannelGMAlgorithms, ChannelSignatureSM2, ChannelSignatureECDSA, ChannelSignatureRSA, ChannelSignatureEd25519:
+		return true
 	case ChannelV2_0:
 		return true
 	case ChannelV1_4_3:
@@ -99,3 +178,17 @@ func (cp *ChannelProvider) ConsensusTypeMigration() bool {
 func (cp *ChannelProvider) OrgSpecificOrdererEndpoints() bool {
 	return cp.v142 || cp.v143 || cp.v20
 }
+
+// AlgorithmPolicy returns the restrictions on the algorithms of identities
+// the channel requires, to be enforced with msp.SetAlgorithmPolicy.
+func (cp *ChannelProvider) AlgorithmPolicy() gmx509.AlgorithmPolicy {
+	return cp.algorithmPolicy
+}
+
+// GMAlgorithms returns true if the channel config may declare the SM3
+// hashing algorithm and restrict the signature algorithms of identities.
+// Binaries which do not support the capability reject such configs
+// instead of hashing or validating them differently from the others.
+func (cp *ChannelProvider) GMAlgorithms() bool {
+	return cp.gmAlgorithms
+}
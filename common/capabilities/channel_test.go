@@ -11,6 +11,7 @@ import (
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -100,6 +101,47 @@ func TestChannelV20(t *testing.T) {
 	assert.True(t, cp.OrgSpecificOrdererEndpoints())
 }
 
+func TestChannelAlgorithmPolicy(t *testing.T) {
+	cp := NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0: {},
+	})
+	assert.False(t, cp.AlgorithmPolicy().Enabled())
+
+	cp = NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0:          {},
+		ChannelSM2Only:       {},
+		ChannelNoSHA1:        {},
+		ChannelMinECKey256:   {},
+		ChannelMinECKey384:   {},
+		ChannelMinRSAKey2048: {},
+	})
+	assert.NoError(t, cp.Supported())
+	assert.Equal(t, gmx509.AlgorithmPolicy{SM2Only: true, NoSHA1: true, MinECKeySize: 384, MinRSAKeySize: 2048}, cp.AlgorithmPolicy())
+}
+
+func TestChannelGMAlgorithms(t *testing.T) {
+	cp := NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0: {},
+	})
+	assert.False(t, cp.GMAlgorithms())
+
+	cp = NewChannelProvider(map[string]*cb.Capability{
+		ChannelV2_0:           {},
+		ChannelGMAlgorithms:   {},
+		ChannelSignatureSM2:   {},
+		ChannelSignatureECDSA: {},
+	})
+	assert.NoError(t, cp.Supported())
+	assert.True(t, cp.GMAlgorithms())
+	assert.Equal(t, gmx509.AlgorithmPolicy{SignatureAlgorithms: gmx509.SignatureSM2 | gmx509.SignatureECDSA}, cp.AlgorithmPolicy())
+
+	capability, err := SignatureAlgorithmCapability("sm2")
+	assert.NoError(t, err)
+	assert.Equal(t, ChannelSignatureSM2, capability)
+	_, err = SignatureAlgorithmCapability("DSA")
+	assert.EqualError(t, err, "gmx509: unknown signature algorithm DSA")
+}
+
 func TestChannelNotSupported(t *testing.T) {
 	cp := NewChannelProvider(map[string]*cb.Capability{
 		ChannelV1_1:           {},
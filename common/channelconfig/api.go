@@ -135,6 +135,9 @@ type ChannelCapabilities interface {
 
 	// OrgSpecificOrdererEndpoints return true if the channel config processing allows orderer orgs to specify their own endpoints
 	OrgSpecificOrdererEndpoints() bool
+
+	// GMAlgorithms returns true if the channel config may declare the SM3 hashing algorithm and the allowed signature algorithms
+	GMAlgorithms() bool
 }
 
 // ApplicationCapabilities defines the capabilities for the application portion of a channel
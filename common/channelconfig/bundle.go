@@ -79,6 +79,16 @@ func (b *Bundle) ConfigtxValidator() configtx.Validator {
 // ValidateNew checks if a new bundle's contained configuration is valid to be derived from the current bundle.
 // This allows checks of the nature "Make sure that the consensus type did not change".
 func (b *Bundle) ValidateNew(nb Resources) error {
+	// Prevent changes of the hashing algorithm of the headers and the data of blocks,
+	// which is the hashing algorithm of the config only under the GM algorithms capability
+	if ncc, ok := nb.ChannelConfig().(*ChannelConfig); ok {
+		oldHashingAlgorithm := b.channelConfig.blockHashingAlgorithmName()
+		newHashingAlgorithm := ncc.blockHashingAlgorithmName()
+		if oldHashingAlgorithm != newHashingAlgorithm {
+			return errors.Errorf("attempted to change hashing algorithm from %s to %s", oldHashingAlgorithm, newHashingAlgorithm)
+		}
+	}
+
 	if oc, ok := b.OrdererConfig(); ok {
 		noc, ok := nb.OrdererConfig()
 		if !ok {
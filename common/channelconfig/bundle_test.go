@@ -243,6 +243,34 @@ func TestValidateNew(t *testing.T) {
 		assert.Error(t, err)
 		assert.Regexp(t, "consortium consortium1 org org3 attempted to change MSP ID from", err.Error())
 	})
+
+	t.Run("HashingAlgorithmChange", func(t *testing.T) {
+		bundle := func(hashingAlgorithm string, capabilities ...string) *Bundle {
+			c := &cb.Capabilities{Capabilities: map[string]*cb.Capability{}}
+			for _, capability := range capabilities {
+				c.Capabilities[capability] = &cb.Capability{}
+			}
+			return &Bundle{
+				channelConfig: &ChannelConfig{
+					protos: &ChannelProtos{
+						HashingAlgorithm: &cb.HashingAlgorithm{Name: hashingAlgorithm},
+						Capabilities:     c,
+					},
+				},
+			}
+		}
+
+		// Blocks are hashed with SHA-256 without the GM algorithms capability
+		err := bundle("SHA256").ValidateNew(bundle("SHA3_256"))
+		assert.NoError(t, err)
+		err = bundle("SHA3_256").ValidateNew(bundle("SHA256", "GM_ALGORITHMS"))
+		assert.NoError(t, err)
+
+		err = bundle("SHA256", "GM_ALGORITHMS").ValidateNew(bundle("SM3", "GM_ALGORITHMS"))
+		assert.EqualError(t, err, "attempted to change hashing algorithm from SHA256 to SM3")
+		err = bundle("SHA3_256").ValidateNew(bundle("SHA3_256", "GM_ALGORITHMS"))
+		assert.EqualError(t, err, "attempted to change hashing algorithm from SHA256 to SHA3_256")
+	})
 }
 
 func TestValidateNewWithConsensusMigration(t *testing.T) {
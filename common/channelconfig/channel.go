@@ -143,6 +143,32 @@ func (cc *ChannelConfig) HashingAlgorithm() func(input []byte) []byte {
 	return cc.hashingAlgorithm
 }
 
+// blockHashingAlgorithmName returns the name of the hashing algorithm of
+// the blocks, or an empty string for configs which were not deserialized.
+func (cc *ChannelConfig) blockHashingAlgorithmName() string {
+	if cc == nil || cc.protos == nil {
+		return ""
+	}
+	if !capabilities.NewChannelProvider(cc.protos.Capabilities.GetCapabilities()).GMAlgorithms() {
+		return bccsp.SHA256
+	}
+	return cc.protos.HashingAlgorithm.GetName()
+}
+
+// BlockHashingAlgorithm returns the hashing algorithm of the headers and
+// the data of the blocks of the channel whose config is cc. It is the
+// hashing algorithm of the config under the GM algorithms capability, and
+// SHA-256 otherwise, or nil when cc is nil.
+func BlockHashingAlgorithm(cc Channel) func(input []byte) []byte {
+	if cc == nil {
+		return nil
+	}
+	if c := cc.Capabilities(); c != nil && c.GMAlgorithms() {
+		return cc.HashingAlgorithm()
+	}
+	return util.ComputeSHA256
+}
+
 // BlockDataHashingStructure returns the width to use when forming the block data hashing structure
 func (cc *ChannelConfig) BlockDataHashingStructureWidth() uint32 {
 	return cc.protos.BlockDataHashingStructure.Width
@@ -177,6 +203,10 @@ func (cc *ChannelConfig) Validate(channelCapabilities ChannelCapabilities) error
 		}
 	}
 
+	if err := cc.validateGMAlgorithms(channelCapabilities); err != nil {
+		return err
+	}
+
 	if !channelCapabilities.OrgSpecificOrdererEndpoints() {
 		return cc.validateOrdererAddresses()
 	}
@@ -185,15 +215,28 @@ func (cc *ChannelConfig) Validate(channelCapabilities ChannelCapabilities) error
 }
 
 func (cc *ChannelConfig) validateHashingAlgorithm() error {
-	switch cc.protos.HashingAlgorithm.Name {
-	case bccsp.SHA256:
-		cc.hashingAlgorithm = util.ComputeSHA256
-	case bccsp.SHA3_256:
-		cc.hashingAlgorithm = util.ComputeSHA3256
-	default:
-		return fmt.Errorf("Unknown hashing algorithm type: %s", cc.protos.HashingAlgorithm.Name)
+	hashingAlgorithm, err := util.HashingAlgorithm(cc.protos.HashingAlgorithm.Name)
+	if err != nil {
+		return err
 	}
+	cc.hashingAlgorithm = hashingAlgorithm
+	return nil
+}
 
+// validateGMAlgorithms rejects the SM3 hashing algorithm and restrictions
+// of the signature algorithms unless the channel requires the GM algorithms
+// capability, so that peers and orderers which predate it refuse the config
+// rather than hash blocks or validate identities differently.
+func (cc *ChannelConfig) validateGMAlgorithms(channelCapabilities ChannelCapabilities) error {
+	if channelCapabilities.GMAlgorithms() {
+		return nil
+	}
+	if cc.protos.HashingAlgorithm.Name == bccsp.SM3 {
+		return errors.Errorf("hashing algorithm %s requires the %s channel capability", bccsp.SM3, capabilities.ChannelGMAlgorithms)
+	}
+	if signatureAlgorithms := capabilities.NewChannelProvider(cc.protos.Capabilities.Capabilities).AlgorithmPolicy().SignatureAlgorithms; signatureAlgorithms != 0 {
+		return errors.Errorf("signature algorithms %s require the %s channel capability", signatureAlgorithms, capabilities.ChannelGMAlgorithms)
+	}
 	return nil
 }
 
@@ -12,8 +12,9 @@ import (
 	"testing"
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
-	"github.com/hyperledger/fabric/bccsp"
+	"github.com/hyperledger/fabric/common/capabilities"
 	"github.com/hyperledger/fabric/common/util"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
 	"github.com/stretchr/testify/assert"
 )
@@ -52,6 +53,65 @@ func TestHashingAlgorithm(t *testing.T) {
 
 	assert.Equal(t, reflect.ValueOf(util.ComputeSHA3256).Pointer(), reflect.ValueOf(cc.HashingAlgorithm()).Pointer(),
 		"Unexpected hashing algorithm returned")
+
+	cc = &ChannelConfig{protos: &ChannelProtos{HashingAlgorithm: &cb.HashingAlgorithm{Name: bccsp.SM3}}}
+	assert.NoError(t, cc.validateHashingAlgorithm(), "Allowed hashing algorith SM3 supplied")
+
+	assert.Equal(t, reflect.ValueOf(util.ComputeSM3).Pointer(), reflect.ValueOf(cc.HashingAlgorithm()).Pointer(),
+		"Unexpected hashing algorithm returned")
+}
+
+func TestGMAlgorithms(t *testing.T) {
+	newChannelConfig := func(hashingAlgorithm string, caps ...string) *ChannelConfig {
+		capabilityMap := map[string]*cb.Capability{}
+		for _, c := range caps {
+			capabilityMap[c] = &cb.Capability{}
+		}
+		return &ChannelConfig{protos: &ChannelProtos{
+			HashingAlgorithm: &cb.HashingAlgorithm{Name: hashingAlgorithm},
+			Capabilities:     &cb.Capabilities{Capabilities: capabilityMap},
+		}}
+	}
+
+	cc := newChannelConfig(bccsp.SHA256)
+	assert.NoError(t, cc.validateGMAlgorithms(cc.Capabilities()))
+
+	cc = newChannelConfig(bccsp.SM3)
+	assert.EqualError(t, cc.validateGMAlgorithms(cc.Capabilities()), "hashing algorithm SM3 requires the GM_ALGORITHMS channel capability")
+
+	cc = newChannelConfig(bccsp.SHA256, capabilities.ChannelSignatureSM2)
+	assert.EqualError(t, cc.validateGMAlgorithms(cc.Capabilities()), "signature algorithms SM2 require the GM_ALGORITHMS channel capability")
+
+	cc = newChannelConfig(bccsp.SM3, capabilities.ChannelGMAlgorithms, capabilities.ChannelSignatureSM2)
+	assert.NoError(t, cc.validateGMAlgorithms(cc.Capabilities()))
+}
+
+func TestBlockHashingAlgorithm(t *testing.T) {
+	newChannelConfig := func(caps ...string) *ChannelConfig {
+		capabilityMap := map[string]*cb.Capability{}
+		for _, c := range caps {
+			capabilityMap[c] = &cb.Capability{}
+		}
+		return &ChannelConfig{
+			protos: &ChannelProtos{
+				HashingAlgorithm: &cb.HashingAlgorithm{Name: bccsp.SM3},
+				Capabilities:     &cb.Capabilities{Capabilities: capabilityMap},
+			},
+			hashingAlgorithm: util.ComputeSM3,
+		}
+	}
+
+	assert.Nil(t, BlockHashingAlgorithm(nil))
+
+	cc := newChannelConfig()
+	assert.Equal(t, bccsp.SHA256, cc.blockHashingAlgorithmName())
+	assert.Equal(t, reflect.ValueOf(util.ComputeSHA256).Pointer(), reflect.ValueOf(BlockHashingAlgorithm(cc)).Pointer(),
+		"Unexpected block hashing algorithm returned")
+
+	cc = newChannelConfig(capabilities.ChannelGMAlgorithms)
+	assert.Equal(t, bccsp.SM3, cc.blockHashingAlgorithmName())
+	assert.Equal(t, reflect.ValueOf(util.ComputeSM3).Pointer(), reflect.ValueOf(BlockHashingAlgorithm(cc)).Pointer(),
+		"Unexpected block hashing algorithm returned")
 }
 
 func TestBlockDataHashingStructure(t *testing.T) {
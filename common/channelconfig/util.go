@@ -73,13 +73,20 @@ func ConsortiumValue(name string) *StandardConfigValue {
 	}
 }
 
-// HashingAlgorithm returns the only currently valid hashing algorithm.
+// HashingAlgorithm returns the default hashing algorithm.
 // It is a value for the /Channel group.
 func HashingAlgorithmValue() *StandardConfigValue {
+	return HashingAlgorithmNameValue(defaultHashingAlgorithm)
+}
+
+// HashingAlgorithmNameValue returns the config definition for the hashing algorithm
+// named name, e.g. SM3 for channels with the GM algorithms capability.
+// It is a value for the /Channel group.
+func HashingAlgorithmNameValue(name string) *StandardConfigValue {
 	return &StandardConfigValue{
 		key: HashingAlgorithmKey,
 		value: &cb.HashingAlgorithm{
-			Name: defaultHashingAlgorithm,
+			Name: name,
 		},
 	}
 }
@@ -0,0 +1,79 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"bytes"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// NewConfigSignature signs configUpdate, the marshaled ConfigUpdate of a
+// ConfigUpdateEnvelope, with signer. The signature is detached: it can be
+// produced where the key of signer is held, such as a host with an HSM or
+// an air-gapped keystore, and added to the envelope afterwards with
+// AddConfigSignatures.
+func NewConfigSignature(configUpdate []byte, signer protoutil.Signer) (*cb.ConfigSignature, error) {
+	sigHeader, err := protoutil.NewSignatureHeader(signer)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed creating signature header")
+	}
+
+	configSig := &cb.ConfigSignature{
+		SignatureHeader: protoutil.MarshalOrPanic(sigHeader),
+	}
+	configSig.Signature, err = signer.Sign(bytes.Join([][]byte{configSig.SignatureHeader, configUpdate}, nil))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed signing config update")
+	}
+	return configSig, nil
+}
+
+// AddConfigSignatures adds the detached signatures configSigs to
+// configUpdateEnv. The signatures of identities which already signed the
+// config update are left out. When deserializer is not nil, such as the
+// MSP manager of the channel, every signature is verified against the
+// config update with the identity of its creator, ECDSA or SM2, before it
+// is added.
+func AddConfigSignatures(configUpdateEnv *cb.ConfigUpdateEnvelope, deserializer msp.IdentityDeserializer, configSigs ...*cb.ConfigSignature) error {
+	signers := make(map[string]struct{})
+	for _, configSig := range configUpdateEnv.Signatures {
+		sigHeader, err := protoutil.UnmarshalSignatureHeader(configSig.SignatureHeader)
+		if err != nil {
+			return errors.WithMessage(err, "failed unmarshaling signature header of the config update")
+		}
+		signers[string(sigHeader.Creator)] = struct{}{}
+	}
+
+	for i, configSig := range configSigs {
+		sigHeader, err := protoutil.UnmarshalSignatureHeader(configSig.SignatureHeader)
+		if err != nil {
+			return errors.WithMessagef(err, "failed unmarshaling signature header of signature %d", i)
+		}
+		if _, signed := signers[string(sigHeader.Creator)]; signed {
+			logger.Debugf("Skipping signature %d, its creator already signed the config update", i)
+			continue
+		}
+
+		if deserializer != nil {
+			id, err := deserializer.DeserializeIdentity(sigHeader.Creator)
+			if err != nil {
+				return errors.WithMessagef(err, "failed deserializing the creator of signature %d", i)
+			}
+			if err := id.Verify(bytes.Join([][]byte{configSig.SignatureHeader, configUpdateEnv.ConfigUpdate}, nil), configSig.Signature); err != nil {
+				return errors.WithMessagef(err, "signature %d of %s does not sign the config update", i, id.GetIdentifier().Mspid)
+			}
+		}
+
+		configUpdateEnv.Signatures = append(configUpdateEnv.Signatures, configSig)
+		signers[string(sigHeader.Creator)] = struct{}{}
+	}
+	return nil
+}
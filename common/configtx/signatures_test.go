@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configtx
+
+import (
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/msp/mgmt"
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSignatures(t *testing.T) {
+	require.NoError(t, msptesttools.LoadMSPSetupForTesting())
+	csp := factory.GetDefault()
+	signer := mgmt.GetLocalSigningIdentityOrPanic(csp)
+	deserializer := mgmt.GetLocalMSP(csp)
+
+	configUpdate := protoutil.MarshalOrPanic(&cb.ConfigUpdate{ChannelId: "mychannel"})
+	configSig, err := NewConfigSignature(configUpdate, signer)
+	require.NoError(t, err)
+
+	t.Run("Verified signature", func(t *testing.T) {
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{ConfigUpdate: configUpdate}
+		require.NoError(t, AddConfigSignatures(configUpdateEnv, deserializer, configSig))
+		assert.Equal(t, []*cb.ConfigSignature{configSig}, configUpdateEnv.Signatures)
+
+		signedData, err := protoutil.ConfigUpdateEnvelopeAsSignedData(configUpdateEnv)
+		require.NoError(t, err)
+		creator, err := signer.Serialize()
+		require.NoError(t, err)
+		assert.Equal(t, creator, signedData[0].Identity)
+
+		// a second signature of the same creator is left out
+		otherSig, err := NewConfigSignature(configUpdate, signer)
+		require.NoError(t, err)
+		require.NoError(t, AddConfigSignatures(configUpdateEnv, deserializer, otherSig))
+		assert.Len(t, configUpdateEnv.Signatures, 1)
+	})
+
+	t.Run("Signature of another update", func(t *testing.T) {
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{
+			ConfigUpdate: protoutil.MarshalOrPanic(&cb.ConfigUpdate{ChannelId: "otherchannel"}),
+		}
+		err := AddConfigSignatures(configUpdateEnv, deserializer, configSig)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "signature 0 of SampleOrg does not sign the config update")
+		assert.Empty(t, configUpdateEnv.Signatures)
+
+		// signatures are not verified without a deserializer
+		require.NoError(t, AddConfigSignatures(configUpdateEnv, nil, configSig))
+		assert.Len(t, configUpdateEnv.Signatures, 1)
+	})
+
+	t.Run("Bad signature header", func(t *testing.T) {
+		configUpdateEnv := &cb.ConfigUpdateEnvelope{ConfigUpdate: configUpdate}
+		err := AddConfigSignatures(configUpdateEnv, nil, &cb.ConfigSignature{SignatureHeader: []byte("garbage")})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed unmarshaling signature header of signature 0")
+	})
+}
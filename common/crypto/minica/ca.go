@@ -0,0 +1,342 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package minica is a small certificate authority issuing, renewing and
+// revoking SM2 and ECDSA certificates, meant for integration tests and
+// development networks in place of static certificate fixtures.
+package minica
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509/certlog"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+// KeyType is the algorithm of the keys of the CA and of the certificates
+// it issues.
+type KeyType int
+
+const (
+	// SM2 keys, with certificates signed with SM2 over SM3
+	SM2 KeyType = iota
+	// ECDSA keys on P-256, with certificates signed with ECDSA over SHA-256
+	ECDSA
+)
+
+const (
+	defaultCAValidity   = 10 * 365 * 24 * time.Hour
+	defaultCertValidity = 365 * 24 * time.Hour
+	defaultCRLValidity  = 7 * 24 * time.Hour
+)
+
+// CertKeyPair is a certificate and its private key.
+type CertKeyPair struct {
+	// Cert is the certificate, PEM encoded
+	Cert []byte
+	// Key is the private key, PEM encoded
+	Key []byte
+
+	Certificate *x509.Certificate
+	// PrivateKey is an *sm2.PrivateKey or an *ecdsa.PrivateKey
+	PrivateKey interface{}
+}
+
+// Options are the options of a CA.
+type Options struct {
+	KeyType    KeyType
+	CommonName string
+	// Organization is the organization of the subjects of the CA and of
+	// the certificates it issues
+	Organization string
+	// Validity is the validity of the CA certificate, 10 years if zero
+	Validity time.Duration
+}
+
+// Request is a certificate request to a CA.
+type Request struct {
+	// KeyType is the algorithm of the key of the certificate, which may
+	// differ from the one of the CA
+	KeyType             KeyType
+	CommonName          string
+	OrganizationalUnits []string
+	// Hosts are the DNS names and IP addresses of the certificate
+	Hosts []string
+	// KeyUsage is x509.KeyUsageDigitalSignature if zero
+	KeyUsage    x509.KeyUsage
+	ExtKeyUsage []x509.ExtKeyUsage
+	// Validity is the validity of the certificate, a year if zero
+	Validity time.Duration
+}
+
+// CA is a certificate authority. It is safe for concurrent use.
+type CA struct {
+	*CertKeyPair
+	organization string
+
+	mutex     sync.Mutex
+	requests  map[string]Request
+	revoked   []pkix.RevokedCertificate
+	crlNumber int64
+
+	// AuditLog, if set, records the certificates the CA issues
+	AuditLog *certlog.Log
+}
+
+// New creates a self-signed root CA.
+func New(opts Options) (*CA, error) {
+	return newCA(opts, nil)
+}
+
+// NewIntermediateCA creates an intermediate CA certified by ca.
+func (ca *CA) NewIntermediateCA(opts Options) (*CA, error) {
+	return newCA(opts, ca)
+}
+
+func newCA(opts Options, parent *CA) (*CA, error) {
+	priv, pub, err := newKey(opts.KeyType)
+	if err != nil {
+		return nil, err
+	}
+
+	validity := opts.Validity
+	if validity == 0 {
+		validity = defaultCAValidity
+	}
+	template, err := newTemplate(validity)
+	if err != nil {
+		return nil, err
+	}
+	template.Subject = pkix.Name{CommonName: opts.CommonName}
+	if opts.Organization != "" {
+		template.Subject.Organization = []string{opts.Organization}
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	template.SubjectKeyId = utils.SKI(pub)
+
+	ca := &CA{organization: opts.Organization, requests: map[string]Request{}}
+	if parent == nil {
+		ca.CertKeyPair, err = sign(template, template, pub, priv, priv)
+	} else {
+		ca.CertKeyPair, err = parent.sign(template, pub, priv)
+	}
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to create CA %s", opts.CommonName)
+	}
+	return ca, nil
+}
+
+// CertBytes returns the certificate of the CA in PEM encoding.
+func (ca *CA) CertBytes() []byte {
+	return ca.Cert
+}
+
+// Issue issues a certificate for a new key.
+func (ca *CA) Issue(req Request) (*CertKeyPair, error) {
+	priv, pub, err := newKey(req.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	return ca.issue(req, pub, priv)
+}
+
+// Renew issues a new certificate with the same subject, extensions and key
+// as kp, which remains valid until it expires or is revoked.
+func (ca *CA) Renew(kp *CertKeyPair) (*CertKeyPair, error) {
+	if kp == nil || kp.Certificate == nil {
+		return nil, errors.New("invalid certificate, it must be different from nil")
+	}
+	ca.mutex.Lock()
+	req, ok := ca.requests[kp.Certificate.SerialNumber.String()]
+	ca.mutex.Unlock()
+	if !ok {
+		return nil, errors.Errorf("certificate %s was not issued by CA %s", kp.Certificate.SerialNumber, ca.Certificate.Subject.CommonName)
+	}
+
+	pub, err := publicKey(kp.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return ca.issue(req, pub, kp.PrivateKey)
+}
+
+func (ca *CA) issue(req Request, pub, priv interface{}) (*CertKeyPair, error) {
+	validity := req.Validity
+	if validity == 0 {
+		validity = defaultCertValidity
+	}
+	template, err := newTemplate(validity)
+	if err != nil {
+		return nil, err
+	}
+	template.Subject = pkix.Name{CommonName: req.CommonName, OrganizationalUnit: req.OrganizationalUnits}
+	if ca.organization != "" {
+		template.Subject.Organization = []string{ca.organization}
+	}
+	template.KeyUsage = req.KeyUsage
+	if template.KeyUsage == 0 {
+		template.KeyUsage = x509.KeyUsageDigitalSignature
+	}
+	template.ExtKeyUsage = req.ExtKeyUsage
+	for _, host := range req.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	kp, err := ca.sign(template, pub, priv)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to issue certificate for %s", req.CommonName)
+	}
+	ca.mutex.Lock()
+	ca.requests[template.SerialNumber.String()] = req
+	ca.mutex.Unlock()
+	return kp, nil
+}
+
+// sign certifies the public key pub of the holder of priv with template.
+func (ca *CA) sign(template *x509.Certificate, pub, priv interface{}) (*CertKeyPair, error) {
+	template.AuthorityKeyId = ca.Certificate.SubjectKeyId
+	kp, err := sign(template, ca.Certificate, pub, priv, ca.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if ca.AuditLog != nil {
+		if _, err := ca.AuditLog.Append(kp.Certificate); err != nil {
+			return nil, errors.WithMessage(err, "failed to record certificate in the audit log")
+		}
+	}
+	return kp, nil
+}
+
+// Revoke revokes cert, with reason as its CRL reason code.
+func (ca *CA) Revoke(cert *x509.Certificate, reason int) error {
+	if cert == nil {
+		return errors.New("invalid certificate, it must be different from nil")
+	}
+	if err := gmx509.CheckSignatureFrom(cert, ca.Certificate); err != nil {
+		return errors.WithMessagef(err, "certificate %s was not issued by CA %s", cert.SerialNumber, ca.Certificate.Subject.CommonName)
+	}
+	entry := pkix.RevokedCertificate{SerialNumber: cert.SerialNumber, RevocationTime: time.Now().UTC()}
+	if reason != 0 {
+		ext, err := gmx509.ReasonCodeExtension(reason)
+		if err != nil {
+			return err
+		}
+		entry.Extensions = []pkix.Extension{ext}
+	}
+
+	ca.mutex.Lock()
+	defer ca.mutex.Unlock()
+	for _, rc := range ca.revoked {
+		if rc.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return errors.Errorf("certificate %s is already revoked", cert.SerialNumber)
+		}
+	}
+	ca.revoked = append(ca.revoked, entry)
+	return nil
+}
+
+// CRL returns a new CRL of the certificates revoked by the CA, PEM encoded,
+// valid for a week.
+func (ca *CA) CRL() ([]byte, error) {
+	ca.mutex.Lock()
+	ca.crlNumber++
+	template := &gmx509.RevocationList{
+		Number:              big.NewInt(ca.crlNumber),
+		ThisUpdate:          time.Now(),
+		NextUpdate:          time.Now().Add(defaultCRLValidity),
+		RevokedCertificates: append([]pkix.RevokedCertificate{}, ca.revoked...),
+	}
+	ca.mutex.Unlock()
+
+	der, err := gmx509.CreateRevocationList(rand.Reader, template, ca.Certificate, ca.PrivateKey)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create CRL")
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), nil
+}
+
+func newKey(keyType KeyType) (priv, pub interface{}, err error) {
+	switch keyType {
+	case SM2:
+		k, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, &k.PublicKey, nil
+	case ECDSA:
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return k, &k.PublicKey, nil
+	default:
+		return nil, nil, errors.Errorf("unsupported key type %d", keyType)
+	}
+}
+
+func publicKey(priv interface{}) (interface{}, error) {
+	switch k := priv.(type) {
+	case *sm2.PrivateKey:
+		return &k.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, nil
+	default:
+		return nil, errors.Errorf("unsupported private key type %T", priv)
+	}
+}
+
+func newTemplate(validity time.Duration) (*x509.Certificate, error) {
+	sn, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	return &x509.Certificate{
+		SerialNumber: sn,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validity),
+	}, nil
+}
+
+// sign creates the certificate of template signed by the key signerPriv of
+// parent, for the key pair pub and priv.
+func sign(template, parent *x509.Certificate, pub, priv, signerPriv interface{}) (*CertKeyPair, error) {
+	der, err := gmx509.CreateCertificate(rand.Reader, template, parent, pub, signerPriv)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := gmx509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	key, err := utils.PrivateKeyToPEM(priv, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &CertKeyPair{
+		Cert:        pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:         key,
+		Certificate: cert,
+		PrivateKey:  priv,
+	}, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package minica
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueAndRenew(t *testing.T) {
+	for _, caKeyType := range []KeyType{SM2, ECDSA} {
+		root, err := New(Options{KeyType: caKeyType, CommonName: "ca.org1", Organization: "org1"})
+		require.NoError(t, err)
+		inter, err := root.NewIntermediateCA(Options{KeyType: SM2, CommonName: "ica.org1", Organization: "org1"})
+		require.NoError(t, err)
+
+		opts := gmx509.VerifyOptions{Roots: gmx509.NewCertPool(), Intermediates: gmx509.NewCertPool()}
+		opts.Roots.AddCert(root.Certificate)
+		opts.Intermediates.AddCert(inter.Certificate)
+
+		for _, keyType := range []KeyType{SM2, ECDSA} {
+			kp, err := inter.Issue(Request{
+				KeyType:             keyType,
+				CommonName:          "peer0.org1",
+				OrganizationalUnits: []string{"peer"},
+				Hosts:               []string{"peer0.org1", "127.0.0.1"},
+				ExtKeyUsage:         []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"peer0.org1"}, kp.Certificate.DNSNames)
+			assert.Len(t, kp.Certificate.IPAddresses, 1)
+			assert.Equal(t, []string{"org1"}, kp.Certificate.Subject.Organization)
+			chains, err := gmx509.Verify(kp.Certificate, opts)
+			require.NoError(t, err)
+			assert.Len(t, chains[0], 3)
+
+			block, _ := pem.Decode(kp.Key)
+			require.NotNil(t, block)
+
+			renewed, err := inter.Renew(kp)
+			require.NoError(t, err)
+			assert.NotEqual(t, kp.Certificate.SerialNumber, renewed.Certificate.SerialNumber)
+			assert.Equal(t, kp.Certificate.Subject.String(), renewed.Certificate.Subject.String())
+			assert.Equal(t, kp.Certificate.RawSubjectPublicKeyInfo, renewed.Certificate.RawSubjectPublicKeyInfo)
+			_, err = gmx509.Verify(renewed.Certificate, opts)
+			assert.NoError(t, err)
+
+			_, err = root.Renew(kp)
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestRevokeAndCRL(t *testing.T) {
+	ca, err := New(Options{KeyType: SM2, CommonName: "ca.org1"})
+	require.NoError(t, err)
+	_, isSM2 := ca.PrivateKey.(*sm2.PrivateKey)
+	assert.True(t, isSM2)
+
+	kp1, err := ca.Issue(Request{CommonName: "user1"})
+	require.NoError(t, err)
+	kp2, err := ca.Issue(Request{KeyType: ECDSA, CommonName: "user2"})
+	require.NoError(t, err)
+
+	require.NoError(t, ca.Revoke(kp1.Certificate, 1))
+	assert.EqualError(t, ca.Revoke(kp1.Certificate, 1), "certificate "+kp1.Certificate.SerialNumber.String()+" is already revoked")
+
+	other, err := New(Options{KeyType: ECDSA, CommonName: "ca.org2"})
+	require.NoError(t, err)
+	assert.Error(t, other.Revoke(kp2.Certificate, 0))
+
+	pemCRL, err := ca.CRL()
+	require.NoError(t, err)
+	block, _ := pem.Decode(pemCRL)
+	require.NotNil(t, block)
+	crl, err := x509.ParseCRL(block.Bytes)
+	require.NoError(t, err)
+	require.NoError(t, gmx509.CheckCRLSignature(crl, ca.Certificate))
+	require.Len(t, crl.TBSCertList.RevokedCertificates, 1)
+	assert.Equal(t, kp1.Certificate.SerialNumber, crl.TBSCertList.RevokedCertificates[0].SerialNumber)
+	reason, err := gmx509.RevocationReason(crl.TBSCertList.RevokedCertificates[0])
+	require.NoError(t, err)
+	assert.Equal(t, 1, reason)
+
+	// Every CRL has a new number
+	require.NoError(t, ca.Revoke(kp2.Certificate, 0))
+	pemCRL, err = ca.CRL()
+	require.NoError(t, err)
+	block, _ = pem.Decode(pemCRL)
+	crl, err = x509.ParseCRL(block.Bytes)
+	require.NoError(t, err)
+	assert.Len(t, crl.TBSCertList.RevokedCertificates, 2)
+	number, err := gmx509.CRLNumber(crl)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), number.Int64())
+}
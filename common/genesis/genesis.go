@@ -7,7 +7,10 @@ SPDX-License-Identifier: Apache-2.0
 package genesis
 
 import (
+	"fmt"
+
 	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/protoutil"
 )
 
@@ -44,7 +47,12 @@ func (f *factory) Block(channelID string) *cb.Block {
 
 	block := protoutil.NewBlock(0, nil)
 	block.Data = &cb.BlockData{Data: [][]byte{protoutil.MarshalOrPanic(envelope)}}
-	block.Header.DataHash = protoutil.BlockDataHash(block.Data)
+	// The genesis block is hashed with the hashing algorithm of the blocks it sets
+	hashingAlgorithm, err := util.BlockHashingAlgorithm(block)
+	if err != nil {
+		panic(fmt.Sprintf("Error determining the hashing algorithm of the genesis block: %s", err))
+	}
+	block.Header.DataHash = protoutil.BlockDataHashWith(block.Data, hashingAlgorithm)
 	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = protoutil.MarshalOrPanic(&cb.Metadata{
 		Value: protoutil.MarshalOrPanic(&cb.LastConfig{Index: 0}),
 	})
@@ -7,10 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package genesis
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/golang/protobuf/proto"
 	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
 )
@@ -45,3 +47,20 @@ func TestFactory(t *testing.T) {
 		assert.Equal(t, uint64(0), lastConfig.Index)
 	})
 }
+
+func TestFactoryHashingAlgorithm(t *testing.T) {
+	channelGroup := protoutil.NewConfigGroup()
+	channelGroup.Values["HashingAlgorithm"] = &cb.ConfigValue{
+		Value: protoutil.MarshalOrPanic(&cb.HashingAlgorithm{Name: "SM3"}),
+	}
+	block := NewFactoryImpl(channelGroup).Block("testchannelid")
+	assert.Equal(t, protoutil.BlockDataHash(block.Data), block.Header.DataHash)
+
+	channelGroup.Values["Capabilities"] = &cb.ConfigValue{
+		Value: protoutil.MarshalOrPanic(&cb.Capabilities{
+			Capabilities: map[string]*cb.Capability{"GM_ALGORITHMS": {}},
+		}),
+	}
+	block = NewFactoryImpl(channelGroup).Block("testchannelid")
+	assert.Equal(t, util.ComputeSM3(bytes.Join(block.Data.Data, nil)), block.Header.DataHash)
+}
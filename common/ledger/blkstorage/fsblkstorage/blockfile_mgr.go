@@ -21,6 +21,7 @@ import (
 	"github.com/hyperledger/fabric/common/ledger/blkstorage"
 	"github.com/hyperledger/fabric/common/ledger/util"
 	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	commonutil "github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
 )
@@ -44,6 +45,9 @@ type blockfileMgr struct {
 	cpInfoCond        *sync.Cond
 	currentFileWriter *blockfileWriter
 	bcInfo            atomic.Value
+	// hashingAlgorithm hashes the block headers, as configured in the
+	// genesis block of the channel under the GM_ALGORITHMS capability
+	hashingAlgorithm func([]byte) []byte
 }
 
 /*
@@ -151,13 +155,16 @@ func newBlockfileMgr(id string, conf *Conf, indexConfig *blkstorage.IndexConfig,
 		PreviousBlockHash: nil}
 
 	if !cpInfo.isChainEmpty {
+		if mgr.hashingAlgorithm, err = loadHashingAlgorithm(rootDir); err != nil {
+			panic(fmt.Sprintf("Could not determine the hashing algorithm of the blocks: %s", err))
+		}
 		//If start up is a restart of an existing storage, sync the index from block storage and update BlockchainInfo for external API's
 		mgr.syncIndex()
 		lastBlockHeader, err := mgr.retrieveBlockHeaderByNumber(cpInfo.lastBlockNumber)
 		if err != nil {
 			panic(fmt.Sprintf("Could not retrieve header of the last block form file: %s", err))
 		}
-		lastBlockHash := protoutil.BlockHeaderHash(lastBlockHeader)
+		lastBlockHash := mgr.blockHeaderHash(lastBlockHeader)
 		previousBlockHash := lastBlockHeader.PreviousHash
 		bcInfo = &common.BlockchainInfo{
 			Height:            cpInfo.lastBlockNumber + 1,
@@ -208,6 +215,34 @@ func syncCPInfoFromFS(rootDir string, cpInfo *checkpointInfo) {
 	logger.Debugf("Checkpoint after updates by scanning the last file segment:%s", cpInfo)
 }
 
+// loadHashingAlgorithm returns the hashing algorithm of the blocks set by
+// the genesis block, the first block of the first block file in rootDir.
+func loadHashingAlgorithm(rootDir string) (func([]byte) []byte, error) {
+	stream, err := newBlockfileStream(rootDir, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.close()
+	blockBytes, err := stream.nextBlockBytes()
+	if err != nil {
+		return nil, err
+	}
+	if blockBytes == nil {
+		return nil, errors.New("genesis block not found")
+	}
+	genesisBlock, err := deserializeBlock(blockBytes)
+	if err != nil {
+		return nil, err
+	}
+	return commonutil.BlockHashingAlgorithm(genesisBlock)
+}
+
+// blockHeaderHash returns the hash of the block header with the hashing
+// algorithm of the channel.
+func (mgr *blockfileMgr) blockHeaderHash(header *common.BlockHeader) []byte {
+	return protoutil.BlockHeaderHashWith(header, mgr.hashingAlgorithm)
+}
+
 func deriveBlockfilePath(rootDir string, suffixNum int) string {
 	return rootDir + "/" + blockfilePrefix + fmt.Sprintf("%06d", suffixNum)
 }
@@ -256,11 +291,18 @@ func (mgr *blockfileMgr) addBlock(block *common.Block) error {
 			bcInfo.CurrentBlockHash, block.Header.PreviousHash,
 		)
 	}
+	if block.Header.Number == 0 {
+		hashingAlgorithm, err := commonutil.BlockHashingAlgorithm(block)
+		if err != nil {
+			return errors.WithMessage(err, "error determining the hashing algorithm of the genesis block")
+		}
+		mgr.hashingAlgorithm = hashingAlgorithm
+	}
 	blockBytes, info, err := serializeBlock(block)
 	if err != nil {
 		return errors.WithMessage(err, "error serializing block")
 	}
-	blockHash := protoutil.BlockHeaderHash(block.Header)
+	blockHash := mgr.blockHeaderHash(block.Header)
 	//Get the location / offset where each transaction starts in the block and where the block ends
 	txOffsets := info.txOffsets
 	currentOffset := mgr.cpInfo.latestFileChunksize
@@ -408,7 +450,7 @@ func (mgr *blockfileMgr) syncIndex() error {
 		}
 
 		//Update the blockIndexInfo with what was actually stored in file system
-		blockIdxInfo.blockHash = protoutil.BlockHeaderHash(info.blockHeader)
+		blockIdxInfo.blockHash = mgr.blockHeaderHash(info.blockHeader)
 		blockIdxInfo.blockNum = info.blockHeader.Number
 		blockIdxInfo.flp = &fileLocPointer{fileSuffixNum: blockPlacementInfo.fileNum,
 			locPointer: locPointer{offset: int(blockPlacementInfo.blockStartOffset)}}
@@ -16,6 +16,7 @@ import (
 	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/util"
 	ledgerutil "github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
@@ -344,6 +345,63 @@ func TestBlockfileMgrRestart(t *testing.T) {
 	assert.Equal(t, expectedHeight, blkfileMgrWrapper.blockfileMgr.getBlockchainInfo().Height)
 }
 
+func TestBlockfileMgrHashingAlgorithm(t *testing.T) {
+	env := newTestEnv(t, NewConf(testPath(), 0))
+	defer env.Cleanup()
+	ledgerid := "testLedger"
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, ledgerid)
+
+	gb := constructGenesisBlockWithHashingAlgorithm(ledgerid, "SM3")
+	blocks := []*common.Block{gb}
+	for i := 1; i < 5; i++ {
+		previousHash := util.ComputeSM3(protoutil.BlockHeaderBytes(blocks[i-1].Header))
+		block := testutil.ConstructBlock(t, uint64(i), previousHash, [][]byte{[]byte("simulation results")}, false)
+		block.Header.DataHash = protoutil.BlockDataHashWith(block.Data, util.ComputeSM3)
+		blocks = append(blocks, block)
+	}
+	blkfileMgrWrapper.addBlocks(blocks)
+
+	lastBlockHash := util.ComputeSM3(protoutil.BlockHeaderBytes(blocks[4].Header))
+	assert.Equal(t, lastBlockHash, blkfileMgrWrapper.blockfileMgr.getBlockchainInfo().CurrentBlockHash)
+	b, err := blkfileMgrWrapper.blockfileMgr.retrieveBlockByHash(lastBlockHash)
+	assert.NoError(t, err)
+	assert.Equal(t, blocks[4], b)
+	blkfileMgrWrapper.close()
+
+	// The hashing algorithm is loaded from the genesis block on restart
+	blkfileMgrWrapper = newTestBlockfileWrapper(env, ledgerid)
+	defer blkfileMgrWrapper.close()
+	assert.Equal(t, lastBlockHash, blkfileMgrWrapper.blockfileMgr.getBlockchainInfo().CurrentBlockHash)
+	block := testutil.ConstructBlock(t, 5, lastBlockHash, [][]byte{[]byte("simulation results")}, false)
+	assert.NoError(t, blkfileMgrWrapper.blockfileMgr.addBlock(block))
+}
+
+func constructGenesisBlockWithHashingAlgorithm(ledgerid, hashingAlgorithm string) *common.Block {
+	config := &common.ConfigEnvelope{
+		Config: &common.Config{
+			ChannelGroup: &common.ConfigGroup{
+				Values: map[string]*common.ConfigValue{
+					"HashingAlgorithm": {Value: protoutil.MarshalOrPanic(&common.HashingAlgorithm{Name: hashingAlgorithm})},
+					"Capabilities": {Value: protoutil.MarshalOrPanic(&common.Capabilities{
+						Capabilities: map[string]*common.Capability{"GM_ALGORITHMS": {}},
+					})},
+				},
+			},
+		},
+	}
+	payload := &common.Payload{
+		Header: protoutil.MakePayloadHeader(
+			protoutil.MakeChannelHeader(common.HeaderType_CONFIG, 0, ledgerid, 0),
+			protoutil.MakeSignatureHeader(nil, nil),
+		),
+		Data: protoutil.MarshalOrPanic(config),
+	}
+	gb := protoutil.NewBlock(0, nil)
+	gb.Data.Data = [][]byte{protoutil.MarshalOrPanic(&common.Envelope{Payload: protoutil.MarshalOrPanic(payload)})}
+	gb.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = ledgerutil.NewTxValidationFlagsSetValue(1, peer.TxValidationCode_VALID)
+	return gb
+}
+
 func TestBlockfileMgrFileRolling(t *testing.T) {
 	blocks := testutil.ConstructTestBlocks(t, 200)
 	size := 0
@@ -120,6 +120,11 @@ func (r *rollbackMgr) deleteIndexEntriesRange(startBlkNum, endBlkNum uint64) err
 		return err
 	}
 
+	hashingAlgorithm, err := loadHashingAlgorithm(r.ledgerDir)
+	if err != nil {
+		return err
+	}
+
 	stream, err := newBlockStream(r.ledgerDir, lp.fileSuffixNum, int64(lp.offset), -1)
 	if err != nil {
 		return err
@@ -136,7 +141,7 @@ func (r *rollbackMgr) deleteIndexEntriesRange(startBlkNum, endBlkNum uint64) err
 		if err != nil {
 			return err
 		}
-		addIndexEntriesToBeDeleted(batch, blockInfo, r.indexStore)
+		addIndexEntriesToBeDeleted(batch, blockInfo, r.indexStore, hashingAlgorithm)
 		numberOfBlocksToRetrieve--
 	}
 
@@ -144,9 +149,9 @@ func (r *rollbackMgr) deleteIndexEntriesRange(startBlkNum, endBlkNum uint64) err
 	return r.indexStore.db.WriteBatch(batch, true)
 }
 
-func addIndexEntriesToBeDeleted(batch *leveldbhelper.UpdateBatch, blockInfo *serializedBlockInfo, indexStore *blockIndex) error {
+func addIndexEntriesToBeDeleted(batch *leveldbhelper.UpdateBatch, blockInfo *serializedBlockInfo, indexStore *blockIndex, hashingAlgorithm func([]byte) []byte) error {
 	if indexStore.isAttributeIndexed(blkstorage.IndexableAttrBlockHash) {
-		batch.Delete(constructBlockHashKey(protoutil.BlockHeaderHash(blockInfo.blockHeader)))
+		batch.Delete(constructBlockHashKey(protoutil.BlockHeaderHashWith(blockInfo.blockHeader, hashingAlgorithm)))
 	}
 
 	if indexStore.isAttributeIndexed(blkstorage.IndexableAttrBlockNum) {
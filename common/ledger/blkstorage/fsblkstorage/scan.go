@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"path/filepath"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/pkg/errors"
+)
+
+// BlockLocation is the location of a block in the block files of a ledger
+type BlockLocation struct {
+	// File is the name of the block file
+	File string
+	// Offset is the offset of the block in the file
+	Offset int64
+}
+
+// ScanBlockfiles reads the blocks of the block files in ledgerDir, in the
+// order they were appended, and calls process with each block and its
+// location. Scanning stops at the first error process returns. A partial
+// block at the end of the last file, left by a crash while appending, is
+// reported as ErrUnexpectedEndOfBlockfile.
+func ScanBlockfiles(ledgerDir string, process func(block *common.Block, location BlockLocation) error) error {
+	lastFileNum, err := retrieveLastFileSuffix(ledgerDir)
+	if err != nil {
+		return err
+	}
+	if lastFileNum == -1 {
+		return errors.Errorf("no block files found in %s", ledgerDir)
+	}
+
+	stream, err := newBlockStream(ledgerDir, 0, 0, lastFileNum)
+	if err != nil {
+		return err
+	}
+	defer stream.close()
+
+	for {
+		blockBytes, placementInfo, err := stream.nextBlockBytesAndPlacementInfo()
+		if err != nil {
+			return err
+		}
+		if blockBytes == nil {
+			return nil
+		}
+		location := BlockLocation{
+			File:   filepath.Base(deriveBlockfilePath(ledgerDir, placementInfo.fileNum)),
+			Offset: placementInfo.blockStartOffset,
+		}
+		block, err := deserializeBlock(blockBytes)
+		if err != nil {
+			return errors.WithMessagef(err, "error deserializing block at offset %d of %s", location.Offset, location.File)
+		}
+		if err := process(block, location); err != nil {
+			return err
+		}
+	}
+}
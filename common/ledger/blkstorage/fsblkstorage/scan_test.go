@@ -0,0 +1,69 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanBlockfiles(t *testing.T) {
+	env := newTestEnv(t, NewConf(testPath(), 0))
+	defer env.Cleanup()
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, "testLedger")
+	blocks := testutil.ConstructTestBlocks(t, 10)
+	blkfileMgrWrapper.addBlocks(blocks[:5])
+	blkfileMgrWrapper.blockfileMgr.moveToNextFile()
+	blkfileMgrWrapper.addBlocks(blocks[5:])
+	blkfileMgrWrapper.close()
+
+	ledgerDir := env.provider.conf.getLedgerBlockDir("testLedger")
+	var scanned []*common.Block
+	var locations []BlockLocation
+	err := ScanBlockfiles(ledgerDir, func(block *common.Block, location BlockLocation) error {
+		scanned = append(scanned, block)
+		locations = append(locations, location)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, scanned, len(blocks))
+	for i := range blocks {
+		require.True(t, proto.Equal(blocks[i], scanned[i]))
+	}
+	require.Equal(t, BlockLocation{File: "blockfile_000000", Offset: 0}, locations[0])
+	require.Equal(t, BlockLocation{File: "blockfile_000001", Offset: 0}, locations[5])
+
+	// Scanning stops at the first error
+	count := 0
+	err = ScanBlockfiles(ledgerDir, func(block *common.Block, location BlockLocation) error {
+		count++
+		return errors.New("stop")
+	})
+	require.EqualError(t, err, "stop")
+	require.Equal(t, 1, count)
+
+	// A partial block at the end of the last file is reported
+	lastFile := deriveBlockfilePath(ledgerDir, 1)
+	fileInfo, err := os.Stat(lastFile)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(lastFile, fileInfo.Size()-1))
+	err = ScanBlockfiles(ledgerDir, func(*common.Block, BlockLocation) error { return nil })
+	require.Equal(t, ErrUnexpectedEndOfBlockfile, err)
+
+	emptyDir, err := ioutil.TempDir("", "scan")
+	require.NoError(t, err)
+	defer os.RemoveAll(emptyDir)
+	err = ScanBlockfiles(emptyDir, func(*common.Block, BlockLocation) error { return nil })
+	require.EqualError(t, err, "no block files found in "+emptyDir)
+}
@@ -42,6 +42,12 @@ func (nfei *NotFoundErrorIterator) Close() {}
 // XXX This will need to be modified to accept marshaled envelopes
 //     to accommodate non-deterministic marshaling
 func CreateNextBlock(rl Reader, messages []*cb.Envelope) *cb.Block {
+	return CreateNextBlockWith(rl, messages, nil)
+}
+
+// CreateNextBlockWith is CreateNextBlock for a ledger whose blocks are hashed
+// with the given hashing algorithm, or with SHA-256 if it is nil
+func CreateNextBlockWith(rl Reader, messages []*cb.Envelope, hash func([]byte) []byte) *cb.Block {
 	var nextBlockNumber uint64
 	var previousBlockHash []byte
 	var err error
@@ -57,7 +63,7 @@ func CreateNextBlock(rl Reader, messages []*cb.Envelope) *cb.Block {
 			panic("Error seeking to newest block for chain with non-zero height")
 		}
 		nextBlockNumber = block.Header.Number + 1
-		previousBlockHash = protoutil.BlockHeaderHash(block.Header)
+		previousBlockHash = protoutil.BlockHeaderHashWith(block.Header, hash)
 	}
 
 	data := &cb.BlockData{
@@ -72,7 +78,7 @@ func CreateNextBlock(rl Reader, messages []*cb.Envelope) *cb.Block {
 	}
 
 	block := protoutil.NewBlock(nextBlockNumber, previousBlockHash)
-	block.Header.DataHash = protoutil.BlockDataHash(data)
+	block.Header.DataHash = protoutil.BlockDataHashWith(data, hash)
 	block.Data = data
 
 	return block
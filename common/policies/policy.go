@@ -361,8 +361,8 @@ func (pm *ManagerImpl) GetPolicy(id string) (Policy, bool) {
 // checks the validity of the signature and of the signer and returns a
 // slice of associated identities. The returned identities are deduplicated.
 func SignatureSetToValidIdentities(signedData []*protoutil.SignedData, identityDeserializer mspi.IdentityDeserializer) []mspi.Identity {
-	idMap := map[string]struct{}{}
-	identities := make([]mspi.Identity, 0, len(signedData))
+	signersByKey := map[string]*signer{}
+	var signers []*signer
 
 	for i, sd := range signedData {
 		identity, err := identityDeserializer.DeserializeIdentity(sd.Identity)
@@ -373,23 +373,24 @@ func SignatureSetToValidIdentities(signedData []*protoutil.SignedData, identityD
 
 		key := identity.GetIdentifier().Mspid + identity.GetIdentifier().Id
 
-		// We check if this identity has already appeared before doing a signature check, to ensure that
-		// someone cannot force us to waste time checking the same signature thousands of times
-		if _, ok := idMap[key]; ok {
-			logger.Warningf("De-duplicating identity [%s] at index %d in signature set", key, i)
-			continue
+		// The signatures of an identity are verified until one is valid
+		s, ok := signersByKey[key]
+		if !ok {
+			s = &signer{key: key, data: signedData, valid: -1}
+			signersByKey[key] = s
+			signers = append(signers, s)
 		}
+		s.indices = append(s.indices, i)
+		s.identities = append(s.identities, identity)
+	}
 
-		err = identity.Verify(sd.Data, sd.Signature)
-		if err != nil {
-			logger.Warningf("signature for identity %d is invalid: %s", i, err)
-			continue
+	if pool := getVerifierPool(); pool != nil && len(signers) > 1 {
+		pool.run(pool.batch(signers))
+	} else {
+		for _, s := range signers {
+			s.verify()
 		}
-		logger.Debugf("signature for identity %d validated", i)
-
-		idMap[key] = struct{}{}
-		identities = append(identities, identity)
 	}
 
-	return identities
+	return validIdentities(signers)
 }
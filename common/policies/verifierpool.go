@@ -0,0 +1,189 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+
+	mspi "github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+)
+
+// DefaultVerifierBatchSize is the number of signers whose signatures a
+// worker of a VerifierPool verifies in one task, unless they are SM2 ones.
+const DefaultVerifierBatchSize = 4
+
+// VerifierPool verifies the signatures of the signature sets evaluated by
+// policies on a fixed number of goroutines, shared by all the evaluations
+// of the process, instead of one after the other.
+//
+// SM2 signatures, which take several times longer to verify than ECDSA
+// ones, are verified one signer per task so that they spread over all the
+// workers. The other signatures are verified in batches, so that the
+// handoff to a worker does not cost more than the verifications.
+type VerifierPool struct {
+	batchSize int
+	tasks     chan func()
+}
+
+// NewVerifierPool starts a pool of the given number of workers, as many as
+// the CPUs if workers is not positive, verifying batchSize signers per task,
+// DefaultVerifierBatchSize if batchSize is not positive.
+func NewVerifierPool(workers, batchSize int) *VerifierPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultVerifierBatchSize
+	}
+
+	p := &VerifierPool{
+		batchSize: batchSize,
+		tasks:     make(chan func()),
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+// Stop stops the workers. The pool must not be used afterwards.
+func (p *VerifierPool) Stop() {
+	close(p.tasks)
+}
+
+// run runs the batches on the workers and waits for them to complete. A
+// batch no worker is free to take is run by the calling goroutine, which
+// would be waiting otherwise.
+func (p *VerifierPool) run(batches [][]*signer) {
+	var wg sync.WaitGroup
+	wg.Add(len(batches))
+	for _, batch := range batches {
+		batch := batch
+		task := func() {
+			defer wg.Done()
+			for _, s := range batch {
+				s.verify()
+			}
+		}
+		select {
+		case p.tasks <- task:
+		default:
+			task()
+		}
+	}
+	wg.Wait()
+}
+
+// batch groups signers into the batches of a task.
+func (p *VerifierPool) batch(signers []*signer) [][]*signer {
+	var batches [][]*signer
+	var batch []*signer
+	for _, s := range signers {
+		if s.sm2() {
+			batches = append(batches, []*signer{s})
+			continue
+		}
+		batch = append(batch, s)
+		if len(batch) == p.batchSize {
+			batches = append(batches, batch)
+			batch = nil
+		}
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+var (
+	verifierPool      *VerifierPool
+	verifierPoolMutex sync.RWMutex
+)
+
+// SetVerifierPool sets the pool verifying the signature sets evaluated by
+// policies. With a nil pool, signatures are verified by the goroutine
+// evaluating the policy.
+func SetVerifierPool(p *VerifierPool) {
+	verifierPoolMutex.Lock()
+	defer verifierPoolMutex.Unlock()
+	verifierPool = p
+}
+
+func getVerifierPool() *VerifierPool {
+	verifierPoolMutex.RLock()
+	defer verifierPoolMutex.RUnlock()
+	return verifierPool
+}
+
+// sm2Identity is implemented by the identities of the MSPs which know
+// whether their key is an SM2 key.
+type sm2Identity interface {
+	IsSM2() bool
+}
+
+// signer holds the signatures of one identity in a signature set, in the
+// order of the set.
+type signer struct {
+	key        string
+	indices    []int
+	identities []mspi.Identity
+	data       []*protoutil.SignedData
+	// valid is the index of the first valid signature, -1 if none is
+	valid    int
+	identity mspi.Identity
+}
+
+func (s *signer) sm2() bool {
+	id, ok := s.identities[0].(sm2Identity)
+	return ok && id.IsSM2()
+}
+
+// verify sets valid to the index of the first valid signature of the
+// signer. Signatures following a valid one are not verified, to ensure
+// that someone cannot force us to waste time checking the same signature
+// thousands of times.
+func (s *signer) verify() {
+	for n, i := range s.indices {
+		sd := s.data[i]
+		if err := s.identities[n].Verify(sd.Data, sd.Signature); err != nil {
+			logger.Warningf("signature for identity %d is invalid: %s", i, err)
+			continue
+		}
+		logger.Debugf("signature for identity %d validated", i)
+
+		s.valid, s.identity = i, s.identities[n]
+		for _, dup := range s.indices[n+1:] {
+			logger.Warningf("De-duplicating identity [%s] at index %d in signature set", s.key, dup)
+		}
+		return
+	}
+}
+
+// validIdentities returns the identities of the signers with a valid
+// signature, in the order of their first valid signature.
+func validIdentities(signers []*signer) []mspi.Identity {
+	var valid []*signer
+	for _, s := range signers {
+		if s.valid >= 0 {
+			valid = append(valid, s)
+		}
+	}
+	sort.Slice(valid, func(i, j int) bool { return valid[i].valid < valid[j].valid })
+
+	identities := make([]mspi.Identity, 0, len(valid))
+	for _, s := range valid {
+		identities = append(identities, s.identity)
+	}
+	return identities
+}
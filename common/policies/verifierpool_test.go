@@ -0,0 +1,119 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/policies/mocks"
+	mspi "github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type sm2MockIdentity struct {
+	*mocks.Identity
+}
+
+func (id *sm2MockIdentity) IsSM2() bool { return true }
+
+// signatureSet returns signatures of the identities with the given names,
+// where the invalid ones have an invalid signature, and a deserializer of
+// the identities, SM2 ones if sm2 is set.
+func signatureSet(names []string, invalid map[int]bool, sm2 bool) ([]*protoutil.SignedData, *mocks.IdentityDeserializer) {
+	var sd []*protoutil.SignedData
+	for i, name := range names {
+		sig := "valid"
+		if invalid[i] {
+			sig = "invalid"
+		}
+		sd = append(sd, &protoutil.SignedData{
+			Data:      []byte(fmt.Sprintf("data%d", i)),
+			Identity:  []byte(name),
+			Signature: []byte(sig),
+		})
+	}
+
+	identities := map[string]mspi.Identity{}
+	for _, name := range names {
+		id := &mocks.Identity{}
+		id.GetIdentifierReturns(&mspi.IdentityIdentifier{Id: name, Mspid: "mspid"})
+		id.VerifyStub = func(_, sig []byte) error {
+			if string(sig) != "valid" {
+				return errors.New("bad signature")
+			}
+			return nil
+		}
+		identities[name] = id
+		if sm2 {
+			identities[name] = &sm2MockIdentity{Identity: id}
+		}
+	}
+	ds := &mocks.IdentityDeserializer{}
+	ds.DeserializeIdentityStub = func(raw []byte) (mspi.Identity, error) {
+		return identities[string(raw)], nil
+	}
+	return sd, ds
+}
+
+func identityIDs(identities []mspi.Identity) []string {
+	var ids []string
+	for _, id := range identities {
+		ids = append(ids, id.GetIdentifier().Id)
+	}
+	return ids
+}
+
+func TestSignatureSetToValidIdentitiesPool(t *testing.T) {
+	names := []string{"a", "b", "a", "c", "b", "d", "e", "c", "f"}
+	// the first signature of b is invalid, and the only one of f
+	invalid := map[int]bool{1: true, 8: true}
+
+	serialSD, serialDS := signatureSet(names, invalid, false)
+	expected := identityIDs(SignatureSetToValidIdentities(serialSD, serialDS))
+	assert.Equal(t, []string{"a", "c", "b", "d", "e"}, expected)
+
+	pool := NewVerifierPool(3, 2)
+	defer pool.Stop()
+	SetVerifierPool(pool)
+	defer SetVerifierPool(nil)
+
+	for _, sm2 := range []bool{false, true} {
+		sd, ds := signatureSet(names, invalid, sm2)
+		assert.Equal(t, expected, identityIDs(SignatureSetToValidIdentities(sd, ds)))
+
+		// the signatures following a valid one of the same identity are not verified
+		verifications := 0
+		for _, name := range []string{"a", "b", "c"} {
+			id, _ := ds.DeserializeIdentity([]byte(name))
+			if sm2 {
+				id = id.(*sm2MockIdentity).Identity
+			}
+			verifications += id.(*mocks.Identity).VerifyCallCount()
+		}
+		assert.Equal(t, 4, verifications)
+	}
+}
+
+func TestVerifierPoolBatch(t *testing.T) {
+	pool := NewVerifierPool(1, 2)
+	defer pool.Stop()
+
+	ecdsa := func() *signer { return &signer{identities: []mspi.Identity{&mocks.Identity{}}} }
+	sm2 := func() *signer { return &signer{identities: []mspi.Identity{&sm2MockIdentity{}}} }
+
+	signers := []*signer{ecdsa(), sm2(), ecdsa(), ecdsa(), sm2()}
+	batches := pool.batch(signers)
+	assert.Equal(t, [][]*signer{
+		{signers[1]},
+		{signers[0], signers[2]},
+		{signers[4]},
+		{signers[3]},
+	}, batches)
+}
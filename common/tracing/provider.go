@@ -0,0 +1,63 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/jaeger"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Config configures the export of the spans.
+type Config struct {
+	// Provider is disabled or jaeger
+	Provider string
+	// Endpoint is the URL of the Jaeger collector, e.g.
+	// http://localhost:14268/api/traces
+	Endpoint string
+	// SampleRatio is the fraction of the requests traced, between 0 and 1
+	SampleRatio float64
+}
+
+// Init installs the exporter of the spans of the process named
+// serviceName, e.g. peer, according to c. The returned function flushes
+// and stops the export.
+func Init(serviceName string, c Config) (func(context.Context) error, error) {
+	switch c.Provider {
+	case "", "disabled":
+		return func(context.Context) error { return nil }, nil
+	case "jaeger":
+	default:
+		return nil, errors.Errorf("unknown tracing provider [%s], it must be disabled or jaeger", c.Provider)
+	}
+
+	if c.Endpoint == "" {
+		return nil, errors.New("the Jaeger collector endpoint is required")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return nil, errors.Errorf("invalid tracing sample ratio [%g], it must be between 0 and 1", c.SampleRatio)
+	}
+
+	exporter, err := jaeger.New(jaeger.WithCollectorEndpoint(jaeger.WithEndpoint(c.Endpoint)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating the Jaeger exporter")
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
@@ -0,0 +1,43 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestInit(t *testing.T) {
+	stop, err := Init("peer", Config{Provider: "disabled"})
+	require.NoError(t, err)
+	require.NoError(t, stop(context.Background()))
+
+	_, err = Init("peer", Config{Provider: "zipkin"})
+	require.EqualError(t, err, "unknown tracing provider [zipkin], it must be disabled or jaeger")
+
+	_, err = Init("peer", Config{Provider: "jaeger"})
+	require.EqualError(t, err, "the Jaeger collector endpoint is required")
+
+	_, err = Init("peer", Config{Provider: "jaeger", Endpoint: "http://localhost:14268/api/traces", SampleRatio: 2})
+	require.EqualError(t, err, "invalid tracing sample ratio [2], it must be between 0 and 1")
+
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	stop, err = Init("peer", Config{Provider: "jaeger", Endpoint: "http://localhost:14268/api/traces", SampleRatio: 1})
+	require.NoError(t, err)
+	defer stop(context.Background())
+
+	ctx, span := Start(context.Background(), "parent")
+	require.True(t, span.SpanContext().IsSampled())
+	_, child := Start(ctx, "child")
+	require.Equal(t, span.SpanContext().TraceID(), child.SpanContext().TraceID())
+	End(child, errors.New("invalid signature"))
+	End(span, nil)
+}
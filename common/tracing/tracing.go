@@ -0,0 +1,38 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package tracing records OpenTelemetry spans around the cryptographic
+// steps of the request paths, e.g. the verification of the signatures of
+// a proposal or of the transactions of a block, so that their latency can
+// be told apart from the state database and the network. Spans are
+// dropped unless Init installed an exporter.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the spans of this package.
+const instrumentationName = "github.com/paul-lee-attorney/fabric-2.1-gm"
+
+// Start starts a span named name, child of the span of ctx if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends span, marking it failed with err if err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -13,6 +13,9 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/capabilities"
+	"github.com/hyperledger/fabric/protoutil"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
 )
@@ -35,6 +38,87 @@ func ComputeSHA3256(data []byte) (hash []byte) {
 	return
 }
 
+// ComputeSM3 returns SM3 on data
+func ComputeSM3(data []byte) (hash []byte) {
+	hash, err := factory.GetDefault().Hash(data, &bccsp.SM3Opts{})
+	if err != nil {
+		panic(fmt.Errorf("Failed computing SM3 on [% x]", data))
+	}
+	return
+}
+
+// HashingAlgorithm returns the function computing the hashing algorithm
+// named name in channel configs, i.e. SHA256, SHA3_256 or SM3.
+func HashingAlgorithm(name string) (func(data []byte) []byte, error) {
+	switch name {
+	case bccsp.SHA256:
+		return ComputeSHA256, nil
+	case bccsp.SHA3_256:
+		return ComputeSHA3256, nil
+	case bccsp.SM3:
+		return ComputeSM3, nil
+	default:
+		return nil, fmt.Errorf("Unknown hashing algorithm type: %s", name)
+	}
+}
+
+// BlockHashingAlgorithm returns the hashing algorithm of the channel whose
+// genesis block, or any later config block, is configBlock, which hashes
+// the headers and the data of its blocks. The blocks are hashed with the
+// hashing algorithm of the channel config only under the GM_ALGORITHMS
+// channel capability, and with SHA-256 otherwise, as are the blocks of
+// channels whose genesis block is not a config block.
+func BlockHashingAlgorithm(configBlock *common.Block) (func(data []byte) []byte, error) {
+	name, err := BlockHashingAlgorithmName(configBlock)
+	if err != nil {
+		return nil, err
+	}
+	return HashingAlgorithm(name)
+}
+
+// BlockHashingAlgorithmName returns the name of the hashing algorithm
+// returned by BlockHashingAlgorithm.
+func BlockHashingAlgorithmName(configBlock *common.Block) (string, error) {
+	if !isConfigBlock(configBlock) {
+		return bccsp.SHA256, nil
+	}
+	channelCapabilities, err := protoutil.GetChannelCapabilitiesFromBlock(configBlock)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := channelCapabilities[capabilities.ChannelGMAlgorithms]; !ok {
+		return bccsp.SHA256, nil
+	}
+	return protoutil.GetHashingAlgorithmFromBlock(configBlock)
+}
+
+// isConfigBlock returns true if the block carries a config transaction.
+func isConfigBlock(block *common.Block) bool {
+	envelope, err := protoutil.ExtractEnvelope(block, 0)
+	if err != nil {
+		return false
+	}
+	chdr, err := protoutil.ChannelHeader(envelope)
+	if err != nil {
+		return false
+	}
+	return common.HeaderType(chdr.Type) == common.HeaderType_CONFIG
+}
+
+// ComputeHashFromReader streams the content of r through the hash function
+// selected by opts and returns the digest, so that large payloads such as
+// chaincode packages do not have to be buffered in memory first.
+func ComputeHashFromReader(r io.Reader, opts bccsp.HashOpts) ([]byte, error) {
+	h, err := factory.GetDefault().GetHash(opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
 // GenerateBytesUUID returns a UUID based on RFC 4122 returning the generated bytes
 func GenerateBytesUUID() []byte {
 	uuid := make([]byte, 16)
@@ -20,6 +20,10 @@ import (
 	"bytes"
 	"testing"
 	"time"
+
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 )
 
 func TestComputeSHA256(t *testing.T) {
@@ -40,6 +44,91 @@ func TestComputeSHA3256(t *testing.T) {
 	}
 }
 
+func TestComputeSM3(t *testing.T) {
+	if !bytes.Equal(ComputeSM3([]byte("foobar")), ComputeSM3([]byte("foobar"))) {
+		t.Fatalf("Expected hashes to match, but they did not match")
+	}
+	if bytes.Equal(ComputeSM3([]byte("foobar1")), ComputeSM3([]byte("foobar2"))) {
+		t.Fatalf("Expected hashes to be different, but they match")
+	}
+}
+
+func TestHashingAlgorithm(t *testing.T) {
+	for name, expected := range map[string]func([]byte) []byte{
+		bccsp.SHA256:   ComputeSHA256,
+		bccsp.SHA3_256: ComputeSHA3256,
+		bccsp.SM3:      ComputeSM3,
+	} {
+		hash, err := HashingAlgorithm(name)
+		if err != nil {
+			t.Fatalf("Failed getting hashing algorithm %s: %s", name, err)
+		}
+		if !bytes.Equal(hash([]byte("foobar")), expected([]byte("foobar"))) {
+			t.Fatalf("Expected %s hashes to match, but they did not match", name)
+		}
+	}
+
+	if _, err := HashingAlgorithm("MD5"); err == nil {
+		t.Fatalf("Expected an error for an unknown hashing algorithm")
+	}
+}
+
+// configBlock returns a config block whose channel config declares the
+// hashing algorithm name and the channel capabilities.
+func configBlock(name string, capabilities ...string) *common.Block {
+	channelGroup := protoutil.NewConfigGroup()
+	channelGroup.Values["HashingAlgorithm"] = &common.ConfigValue{
+		Value: protoutil.MarshalOrPanic(&common.HashingAlgorithm{Name: name}),
+	}
+	if len(capabilities) > 0 {
+		c := &common.Capabilities{Capabilities: map[string]*common.Capability{}}
+		for _, capability := range capabilities {
+			c.Capabilities[capability] = &common.Capability{}
+		}
+		channelGroup.Values["Capabilities"] = &common.ConfigValue{Value: protoutil.MarshalOrPanic(c)}
+	}
+	payload := &common.Payload{
+		Header: protoutil.MakePayloadHeader(protoutil.MakeChannelHeader(common.HeaderType_CONFIG, 0, "testchannel", 0), &common.SignatureHeader{}),
+		Data:   protoutil.MarshalOrPanic(&common.ConfigEnvelope{Config: &common.Config{ChannelGroup: channelGroup}}),
+	}
+	block := protoutil.NewBlock(0, nil)
+	block.Data.Data = [][]byte{protoutil.MarshalOrPanic(&common.Envelope{Payload: protoutil.MarshalOrPanic(payload)})}
+	return block
+}
+
+func TestBlockHashingAlgorithmName(t *testing.T) {
+	for _, tt := range []struct {
+		block    *common.Block
+		expected string
+	}{
+		{protoutil.NewBlock(0, nil), bccsp.SHA256},
+		{configBlock(bccsp.SHA256), bccsp.SHA256},
+		{configBlock(bccsp.SHA3_256), bccsp.SHA256},
+		{configBlock(bccsp.SM3, "V2_0"), bccsp.SHA256},
+		{configBlock(bccsp.SM3, "V2_0", "GM_ALGORITHMS"), bccsp.SM3},
+		{configBlock(bccsp.SHA3_256, "GM_ALGORITHMS"), bccsp.SHA3_256},
+	} {
+		name, err := BlockHashingAlgorithmName(tt.block)
+		if err != nil {
+			t.Fatalf("Failed getting the block hashing algorithm: %s", err)
+		}
+		if name != tt.expected {
+			t.Fatalf("Expected block hashing algorithm %s, got %s", tt.expected, name)
+		}
+	}
+}
+
+func TestComputeHashFromReader(t *testing.T) {
+	data := bytes.Repeat([]byte("foobar"), 100000)
+	hash, err := ComputeHashFromReader(bytes.NewReader(data), &bccsp.SM3Opts{})
+	if err != nil {
+		t.Fatalf("Failed computing hash from reader: %s", err)
+	}
+	if !bytes.Equal(hash, ComputeSM3(data)) {
+		t.Fatalf("Expected streamed hash to match, but it did not")
+	}
+}
+
 func TestUUIDGeneration(t *testing.T) {
 	uuid := GenerateUUID()
 	if len(uuid) != 36 {
@@ -0,0 +1,19 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package entities provides chaincode with encryption and signing
+// entities built on BCCSP, such as SM4 encrypters and SM2 signers,
+// together with functions encrypting and signing the values chaincode
+// puts to the ledger, so that chaincode does not embed its own crypto.
+//
+// Keys are typically passed to chaincode in the transient map of the
+// proposal, so that they are not recorded in the ledger:
+//
+//	tMap, _ := stub.GetTransient()
+//	ent, err := entities.NewSM4EncrypterEntity("ID", factory.GetDefault(), tMap["ENCKEY"])
+//	...
+//	err = entities.EncryptAndPutState(stub, ent, key, value)
+package entities
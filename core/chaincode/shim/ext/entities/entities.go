@@ -0,0 +1,337 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package entities
+
+import (
+	"encoding/pem"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/pkg/errors"
+)
+
+/**********************/
+/* Struct definitions */
+/**********************/
+
+// BCCSPEntity is an implementation of the Entity interface
+// holding a BCCSP instance
+type BCCSPEntity struct {
+	IDstr string
+	BCCSP bccsp.BCCSP
+}
+
+// BCCSPSignerEntity is an implementation of the SignerEntity interface
+type BCCSPSignerEntity struct {
+	BCCSPEntity
+	SKey  bccsp.Key
+	SOpts bccsp.SignerOpts
+	// HFunc is the hash function messages are hashed with before being
+	// signed. Messages are signed as they are when it is nil, as SM2
+	// signatures hash the message together with the identity of the signer.
+	HFunc bccsp.HashOpts
+}
+
+// BCCSPEncrypterEntity is an implementation of the EncrypterEntity interface
+type BCCSPEncrypterEntity struct {
+	BCCSPEntity
+	EKey  bccsp.Key
+	EOpts bccsp.EncrypterOpts
+	DOpts bccsp.DecrypterOpts
+}
+
+// BCCSPEncrypterSignerEntity is an implementation of the EncrypterSignerEntity interface
+type BCCSPEncrypterSignerEntity struct {
+	BCCSPEncrypterEntity
+	BCCSPSignerEntity
+}
+
+/****************/
+/* Constructors */
+/****************/
+
+// NewSM4EncrypterEntity returns an encrypter entity that is
+// capable of performing SM4 encryption in GCM mode using the
+// supplied 16-byte key. The random nonce of each encryption is
+// prepended to the ciphertext
+func NewSM4EncrypterEntity(ID string, b bccsp.BCCSP, key []byte) (*BCCSPEncrypterEntity, error) {
+	if b == nil {
+		return nil, errors.New("nil BCCSP")
+	}
+
+	k, err := b.KeyImport(key, &bccsp.SM4ImportKeyOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "bccspInst.KeyImport failed")
+	}
+
+	return NewEncrypterEntity(ID, b, k, &bccsp.AEADOpts{}, &bccsp.AEADOpts{})
+}
+
+// NewEncrypterEntity returns an EncrypterEntity that is capable
+// of performing encryption using i) the supplied BCCSP instance;
+// ii) the supplied encryption key and iii) the supplied encryption
+// and decryption options. The identifier of the entity is supplied
+// as an argument as well - it's the caller's responsibility to
+// choose it in a way that it is meaningful
+func NewEncrypterEntity(ID string, bccsp bccsp.BCCSP, eKey bccsp.Key, eOpts bccsp.EncrypterOpts, dOpts bccsp.DecrypterOpts) (*BCCSPEncrypterEntity, error) {
+	if ID == "" {
+		return nil, errors.New("NewEntity error: empty ID")
+	}
+
+	if bccsp == nil {
+		return nil, errors.New("NewEntity error: nil bccsp")
+	}
+
+	if eKey == nil {
+		return nil, errors.New("NewEntity error: nil keys")
+	}
+
+	return &BCCSPEncrypterEntity{
+		BCCSPEntity: BCCSPEntity{
+			IDstr: ID,
+			BCCSP: bccsp,
+		},
+		EKey:  eKey,
+		EOpts: eOpts,
+		DOpts: dOpts,
+	}, nil
+}
+
+// NewSM2SignerEntity returns a signer entity that is capable of
+// signing using SM2 with the supplied PEM encoded PKCS#8 private key
+func NewSM2SignerEntity(ID string, b bccsp.BCCSP, signKeyBytes []byte) (*BCCSPSignerEntity, error) {
+	if b == nil {
+		return nil, errors.New("nil BCCSP")
+	}
+
+	bl, _ := pem.Decode(signKeyBytes)
+	if bl == nil {
+		return nil, errors.New("pem.Decode returns nil")
+	}
+
+	signKey, err := b.KeyImport(bl.Bytes, &bccsp.SM2PrivateKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "bccspInst.KeyImport failed")
+	}
+
+	return NewSignerEntity(ID, b, signKey, &bccsp.SM2SignerOpts{}, nil)
+}
+
+// NewSM2VerifierEntity returns a verifier entity that is capable of
+// verifying SM2 signatures with the supplied PEM encoded PKIX public key
+func NewSM2VerifierEntity(ID string, b bccsp.BCCSP, signKeyBytes []byte) (*BCCSPSignerEntity, error) {
+	if b == nil {
+		return nil, errors.New("nil BCCSP")
+	}
+
+	bl, _ := pem.Decode(signKeyBytes)
+	if bl == nil {
+		return nil, errors.New("pem.Decode returns nil")
+	}
+
+	pub, err := utils.ParsePKIXSM2PublicKey(bl.Bytes)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed parsing SM2 public key")
+	}
+
+	signKey, err := b.KeyImport(pub, &bccsp.SM2GoPublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "bccspInst.KeyImport failed")
+	}
+
+	return NewSignerEntity(ID, b, signKey, &bccsp.SM2SignerOpts{}, nil)
+}
+
+// NewSignerEntity returns a SignerEntity
+func NewSignerEntity(ID string, bccsp bccsp.BCCSP, sKey bccsp.Key, sOpts bccsp.SignerOpts, hOpts bccsp.HashOpts) (*BCCSPSignerEntity, error) {
+	if ID == "" {
+		return nil, errors.New("NewEntity error: empty ID")
+	}
+
+	if bccsp == nil {
+		return nil, errors.New("NewEntity error: nil bccsp")
+	}
+
+	if sKey == nil {
+		return nil, errors.New("NewEntity error: nil key")
+	}
+
+	return &BCCSPSignerEntity{
+		BCCSPEntity: BCCSPEntity{
+			IDstr: ID,
+			BCCSP: bccsp,
+		},
+		SKey:  sKey,
+		SOpts: sOpts,
+		HFunc: hOpts,
+	}, nil
+}
+
+// NewSM4EncrypterSM2SignerEntity returns an encrypter entity that is
+// capable of performing SM4 encryption in GCM mode using the supplied
+// 16-byte key, and of signing using SM2 with the supplied PEM encoded
+// PKCS#8 private key
+func NewSM4EncrypterSM2SignerEntity(ID string, b bccsp.BCCSP, encKeyBytes, signKeyBytes []byte) (*BCCSPEncrypterSignerEntity, error) {
+	if b == nil {
+		return nil, errors.New("nil BCCSP")
+	}
+
+	encEntity, err := NewSM4EncrypterEntity(ID, b, encKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signEntity, err := NewSM2SignerEntity(ID, b, signKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewEncrypterSignerEntity(ID, b, encEntity.EKey, signEntity.SKey, encEntity.EOpts, encEntity.DOpts, signEntity.SOpts, signEntity.HFunc)
+}
+
+// NewEncrypterSignerEntity returns an EncrypterSignerEntity
+// (which is also an EncrypterEntity) that is capable of
+// both encrypting and signing arbitrary messages.
+func NewEncrypterSignerEntity(ID string, bccsp bccsp.BCCSP, eKey, sKey bccsp.Key, eOpts bccsp.EncrypterOpts, dOpts bccsp.DecrypterOpts, sOpts bccsp.SignerOpts, hOpts bccsp.HashOpts) (*BCCSPEncrypterSignerEntity, error) {
+	if ID == "" {
+		return nil, errors.New("NewEntity error: empty ID")
+	}
+
+	if bccsp == nil {
+		return nil, errors.New("NewEntity error: nil bccsp")
+	}
+
+	if eKey == nil || sKey == nil {
+		return nil, errors.New("NewEntity error: nil keys")
+	}
+
+	return &BCCSPEncrypterSignerEntity{
+		BCCSPEncrypterEntity: BCCSPEncrypterEntity{
+			BCCSPEntity: BCCSPEntity{
+				IDstr: ID,
+				BCCSP: bccsp,
+			},
+			EKey:  eKey,
+			EOpts: eOpts,
+			DOpts: dOpts,
+		},
+		BCCSPSignerEntity: BCCSPSignerEntity{
+			BCCSPEntity: BCCSPEntity{
+				IDstr: ID,
+				BCCSP: bccsp,
+			},
+			SKey:  sKey,
+			SOpts: sOpts,
+			HFunc: hOpts,
+		},
+	}, nil
+}
+
+/***********/
+/* Methods */
+/***********/
+
+// ID returns the identifier of the entity
+func (e *BCCSPEntity) ID() string {
+	return e.IDstr
+}
+
+// Encrypt encrypts plaintext with the key of the entity
+func (e *BCCSPEncrypterEntity) Encrypt(plaintext []byte) ([]byte, error) {
+	return e.BCCSP.Encrypt(e.EKey, plaintext, e.EOpts)
+}
+
+// Decrypt decrypts ciphertext with the key of the entity
+func (e *BCCSPEncrypterEntity) Decrypt(ciphertext []byte) ([]byte, error) {
+	return e.BCCSP.Decrypt(e.EKey, ciphertext, e.DOpts)
+}
+
+// Equals returns true if the entities have the same ID
+func (e *BCCSPEncrypterEntity) Equals(other Entity) bool {
+	return len(e.ID()) > 0 && e.ID() == other.ID()
+}
+
+// Public returns the entity itself, as symmetric keys have no public part
+func (e *BCCSPEncrypterEntity) Public() (Entity, error) {
+	return e, nil
+}
+
+// Equals returns true if the entities have the same ID
+func (e *BCCSPSignerEntity) Equals(other Entity) bool {
+	return len(e.ID()) > 0 && e.ID() == other.ID()
+}
+
+// Public returns a verifier entity holding the public key of the entity
+func (e *BCCSPSignerEntity) Public() (Entity, error) {
+	var err error
+	eprime := *e
+	eprime.SKey, err = e.SKey.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &eprime, nil
+}
+
+// Sign signs msg with the key of the entity
+func (e *BCCSPSignerEntity) Sign(msg []byte) ([]byte, error) {
+	digest, err := e.digest(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.BCCSP.Sign(e.SKey, digest, e.SOpts)
+}
+
+// Verify verifies the signature over msg with the key of the entity
+func (e *BCCSPSignerEntity) Verify(signature, msg []byte) (bool, error) {
+	digest, err := e.digest(msg)
+	if err != nil {
+		return false, err
+	}
+
+	return e.BCCSP.Verify(e.SKey, signature, digest, e.SOpts)
+}
+
+// digest returns the hash of msg, or msg itself when the
+// entity has no hash function
+func (e *BCCSPSignerEntity) digest(msg []byte) ([]byte, error) {
+	if e.HFunc == nil {
+		return msg, nil
+	}
+
+	h, err := e.BCCSP.Hash(msg, e.HFunc)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed computing digest")
+	}
+
+	return h, nil
+}
+
+// ID returns the identifier of the entity
+func (e *BCCSPEncrypterSignerEntity) ID() string {
+	return e.BCCSPEncrypterEntity.ID()
+}
+
+// Equals returns true if the entities have the same ID
+func (e *BCCSPEncrypterSignerEntity) Equals(other Entity) bool {
+	return e.BCCSPEncrypterEntity.Equals(other) && e.BCCSPSignerEntity.Equals(other)
+}
+
+// Public returns an entity holding the encryption key and the public
+// signing key of the entity
+func (e *BCCSPEncrypterSignerEntity) Public() (Entity, error) {
+	signerEntity, err := e.BCCSPSignerEntity.Public()
+	if err != nil {
+		return nil, err
+	}
+
+	return &BCCSPEncrypterSignerEntity{
+		BCCSPEncrypterEntity: e.BCCSPEncrypterEntity,
+		BCCSPSignerEntity:    *signerEntity.(*BCCSPSignerEntity),
+	}, nil
+}
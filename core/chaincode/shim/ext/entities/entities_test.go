@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package entities
+
+import (
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSM2KeyPEMs(t *testing.T) (priv, pub []byte) {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	priv, err = utils.PrivateKeyToPEM(key, nil)
+	require.NoError(t, err)
+	pub, err = utils.PublicKeyToPEM(&key.PublicKey, nil)
+	require.NoError(t, err)
+	return priv, pub
+}
+
+func TestSM4EncrypterEntity(t *testing.T) {
+	b, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	ent, err := NewSM4EncrypterEntity("ID", b, []byte("0123456789abcdef"))
+	require.NoError(t, err)
+
+	ciphertext, err := ent.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), "secret")
+	plaintext, err := ent.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	// a different key does not decrypt
+	other, err := NewSM4EncrypterEntity("ID2", b, []byte("fedcba9876543210"))
+	require.NoError(t, err)
+	_, err = other.Decrypt(ciphertext)
+	assert.Error(t, err)
+	assert.False(t, ent.Equals(other))
+
+	public, err := ent.Public()
+	require.NoError(t, err)
+	assert.True(t, public.Equals(ent))
+
+	_, err = NewSM4EncrypterEntity("ID", b, []byte("short"))
+	assert.Error(t, err)
+	_, err = NewSM4EncrypterEntity("", b, []byte("0123456789abcdef"))
+	assert.EqualError(t, err, "NewEntity error: empty ID")
+	_, err = NewSM4EncrypterEntity("ID", nil, []byte("0123456789abcdef"))
+	assert.EqualError(t, err, "nil BCCSP")
+}
+
+func TestSM2SignerEntity(t *testing.T) {
+	b, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	priv, pub := newSM2KeyPEMs(t)
+
+	signer, err := NewSM2SignerEntity("ID", b, priv)
+	require.NoError(t, err)
+	verifier, err := NewSM2VerifierEntity("ID", b, pub)
+	require.NoError(t, err)
+
+	msg := &SignedMessage{ID: []byte("ID"), Payload: []byte("payload")}
+	require.NoError(t, msg.Sign(signer))
+	valid, err := msg.Verify(verifier)
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// the public entity of the signer verifies too
+	public, err := signer.Public()
+	require.NoError(t, err)
+	valid, err = msg.Verify(public.(Signer))
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	msg.Payload = []byte("tampered")
+	valid, err = msg.Verify(verifier)
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	_, err = NewSM2SignerEntity("ID", b, []byte("not a PEM"))
+	assert.EqualError(t, err, "pem.Decode returns nil")
+	_, err = NewSM2VerifierEntity("ID", b, []byte("not a PEM"))
+	assert.EqualError(t, err, "pem.Decode returns nil")
+	_, err = NewSM2VerifierEntity("ID", b, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{0}}))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed parsing SM2 public key")
+}
+
+func TestStateFunctions(t *testing.T) {
+	b, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	priv, _ := newSM2KeyPEMs(t)
+
+	ent, err := NewSM4EncrypterSM2SignerEntity("ID", b, []byte("0123456789abcdef"), priv)
+	require.NoError(t, err)
+
+	stub := shimtest.NewMockStub("enccc", nil)
+	stub.MockTransactionStart("tx1")
+	require.NoError(t, EncryptAndPutState(stub, ent, "key1", []byte("value1")))
+	require.NoError(t, SignEncryptAndPutState(stub, ent, "key2", []byte("value2")))
+	stub.MockTransactionEnd("tx1")
+
+	stored, err := stub.GetState("key1")
+	require.NoError(t, err)
+	assert.NotEqual(t, []byte("value1"), stored)
+
+	value, err := GetStateAndDecrypt(stub, ent, "key1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value1"), value)
+
+	value, err = GetStateDecryptAndVerify(stub, ent, "key2")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value2"), value)
+
+	// values which are not signed do not verify
+	_, err = GetStateDecryptAndVerify(stub, ent, "key1")
+	assert.Error(t, err)
+
+	_, err = GetStateAndDecrypt(stub, ent, "missing")
+	assert.EqualError(t, err, "no ciphertext to decrypt")
+}
@@ -0,0 +1,60 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package entities
+
+// Entity is the basic interface for all crypto entities
+// that are used by the library to obtain cc-level encryption
+type Entity interface {
+	// ID returns an identifier for the entity
+	ID() string
+
+	// Equals compares two entities and returns true if they are equal
+	Equals(Entity) bool
+
+	// Public returns the public version of this entity
+	// in case the entity is private. Public returns itself otherwise
+	Public() (Entity, error)
+}
+
+// Signer is an interface that provides basic sign/verify capabilities
+type Signer interface {
+	// Sign returns a signature of the supplied message (or an error)
+	Sign(msg []byte) (signature []byte, err error)
+
+	// Verify checks whether the supplied signature
+	// over the supplied message is valid according to this interface
+	Verify(signature, msg []byte) (valid bool, err error)
+}
+
+// Encrypter is an interface that provides basic encrypt/decrypt capabilities
+type Encrypter interface {
+	// Encrypt returns the ciphertext for the supplied plaintext message
+	Encrypt(plaintext []byte) (ciphertext []byte, err error)
+
+	// Decrypt returns the plaintext for the supplied ciphertext message
+	Decrypt(ciphertext []byte) (plaintext []byte, err error)
+}
+
+// SignerEntity is an entity that is capable of signing
+type SignerEntity interface {
+	Signer
+	Entity
+}
+
+// EncrypterEntity is an entity that is capable of encryption
+type EncrypterEntity interface {
+	Encrypter
+	Entity
+}
+
+// EncrypterSignerEntity is an entity that is capable of
+// signing and encryption
+type EncrypterSignerEntity interface {
+	Encrypter
+	Signer
+	Entity
+}
@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package entities
+
+import (
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/pkg/errors"
+)
+
+// GetStateAndDecrypt retrieves the value associated to key,
+// decrypts it with the supplied entity and returns the result
+// of the decryption
+func GetStateAndDecrypt(stub shim.ChaincodeStubInterface, ent Encrypter, key string) ([]byte, error) {
+	// at first we retrieve the ciphertext from the ledger
+	ciphertext, err := stub.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// GetState will return a nil slice if the key does not exist.
+	// Note that the chaincode logic may want to distinguish between
+	// nil slice (key doesn't exist in state db) and empty slice
+	// (key found in state db but value is empty). We do not
+	// distinguish the case here
+	if len(ciphertext) == 0 {
+		return nil, errors.New("no ciphertext to decrypt")
+	}
+
+	return ent.Decrypt(ciphertext)
+}
+
+// EncryptAndPutState encrypts the supplied value using the
+// supplied entity and puts it to the ledger associated to
+// the supplied key
+func EncryptAndPutState(stub shim.ChaincodeStubInterface, ent Encrypter, key string, value []byte) error {
+	// at first we use the supplied entity to encrypt the value
+	ciphertext, err := ent.Encrypt(value)
+	if err != nil {
+		return err
+	}
+
+	return stub.PutState(key, ciphertext)
+}
+
+// GetStateDecryptAndVerify retrieves the value associated to key,
+// decrypts it with the supplied entity, verifies the signature
+// over it and returns the result of the decryption in case of
+// success
+func GetStateDecryptAndVerify(stub shim.ChaincodeStubInterface, ent EncrypterSignerEntity, key string) ([]byte, error) {
+	// here we retrieve and decrypt the state associated to key
+	val, err := GetStateAndDecrypt(stub, ent, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// we unmarshal a SignedMessage from the decrypted state
+	msg := &SignedMessage{}
+	err = msg.FromBytes(val)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling signed message")
+	}
+
+	// we verify the signature
+	ok, err := msg.Verify(ent)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.New("invalid signature")
+	}
+
+	return msg.Payload, nil
+}
+
+// SignEncryptAndPutState signs the supplied value, encrypts
+// the supplied value together with its signature using the
+// supplied entity and puts it to the ledger associated to
+// the supplied key
+func SignEncryptAndPutState(stub shim.ChaincodeStubInterface, ent EncrypterSignerEntity, key string, value []byte) error {
+	// here we create a SignedMessage, set its payload
+	// to value and the ID of the entity and
+	// sign it with the entity
+	msg := &SignedMessage{Payload: value, ID: []byte(ent.ID())}
+	err := msg.Sign(ent)
+	if err != nil {
+		return err
+	}
+
+	// here we serialize the SignedMessage
+	b, err := msg.ToBytes()
+	if err != nil {
+		return err
+	}
+
+	// here we encrypt the serialized version and put it to the ledger
+	return EncryptAndPutState(stub, ent, key, b)
+}
@@ -6,7 +6,10 @@ SPDX-License-Identifier: Apache-2.0
 
 package plugin
 
-import validation "github.com/hyperledger/fabric/core/handlers/validation/api"
+import (
+	validation "github.com/hyperledger/fabric/core/handlers/validation/api"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
 
 // Name defines the name of the plugin as it appears in the configuration
 type Name string
@@ -32,3 +35,13 @@ type SerializedPolicy []byte
 func (sp SerializedPolicy) Bytes() []byte {
 	return sp
 }
+
+// CryptoProvider provides the BCCSP of the peer to plugins
+type CryptoProvider struct {
+	BCCSP bccsp.BCCSP
+}
+
+// CSP returns the BCCSP of the peer
+func (cp *CryptoProvider) CSP() bccsp.BCCSP {
+	return cp.BCCSP
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/pkg/errors"
 )
 
@@ -77,7 +78,8 @@ type PluginValidator struct {
 	vp.Mapper
 	QueryExecutorCreator
 	msp.IdentityDeserializer
-	capabilities vc.Capabilities
+	capabilities   vc.Capabilities
+	cryptoProvider bccsp.BCCSP
 }
 
 //go:generate mockery -dir . -name Capabilities -case underscore -output mocks/
@@ -95,13 +97,14 @@ type IdentityDeserializer interface {
 }
 
 // NewPluginValidator creates a new PluginValidator
-func NewPluginValidator(pm vp.Mapper, qec QueryExecutorCreator, deserializer msp.IdentityDeserializer, capabilities vc.Capabilities) *PluginValidator {
+func NewPluginValidator(pm vp.Mapper, qec QueryExecutorCreator, deserializer msp.IdentityDeserializer, capabilities vc.Capabilities, cryptoProvider bccsp.BCCSP) *PluginValidator {
 	return &PluginValidator{
 		capabilities:         capabilities,
 		pluginChannelMapping: make(map[vp.Name]*pluginsByChannel),
 		Mapper:               pm,
 		QueryExecutorCreator: qec,
 		IdentityDeserializer: deserializer,
+		cryptoProvider:       cryptoProvider,
 	}
 }
 
@@ -181,7 +184,11 @@ func (pbc *pluginsByChannel) createPluginIfAbsent(channel string) (validation.Pl
 func (pbc *pluginsByChannel) initPlugin(plugin validation.Plugin, channel string) (validation.Plugin, error) {
 	pe := &PolicyEvaluator{IdentityDeserializer: pbc.pv.IdentityDeserializer}
 	sf := &StateFetcherImpl{QueryExecutorCreator: pbc.pv}
-	if err := plugin.Init(pe, sf, pbc.pv.capabilities, &legacyCollectionInfoProvider{}); err != nil {
+	dependencies := []validation.Dependency{pe, sf, pbc.pv.capabilities, &legacyCollectionInfoProvider{}}
+	if pbc.pv.cryptoProvider != nil {
+		dependencies = append(dependencies, &vp.CryptoProvider{BCCSP: pbc.pv.cryptoProvider})
+	}
+	if err := plugin.Init(dependencies...); err != nil {
 		return nil, errors.Wrap(err, "failed initializing plugin")
 	}
 	return plugin, nil
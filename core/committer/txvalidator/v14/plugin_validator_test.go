@@ -30,7 +30,7 @@ func TestValidateWithPlugin(t *testing.T) {
 	qec := &mocks.QueryExecutorCreator{}
 	deserializer := &mocks.IdentityDeserializer{}
 	capabilites := &mocks.Capabilities{}
-	v := txvalidator.NewPluginValidator(pm, qec, deserializer, capabilites)
+	v := txvalidator.NewPluginValidator(pm, qec, deserializer, capabilites, nil)
 	ctx := &txvalidator.Context{
 		Namespace: "mycc",
 		VSCCName:  "vscc",
@@ -99,7 +99,7 @@ func TestSamplePlugin(t *testing.T) {
 
 	txnData, _ := proto.Marshal(&transaction)
 
-	v := txvalidator.NewPluginValidator(pm, qec, deserializer, capabilites)
+	v := txvalidator.NewPluginValidator(pm, qec, deserializer, capabilites, nil)
 	acceptAllPolicyBytes, _ := proto.Marshal(policydsl.AcceptAllPolicy)
 	ctx := &txvalidator.Context{
 		Namespace: "mycc",
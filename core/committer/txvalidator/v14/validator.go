@@ -97,7 +97,7 @@ type blockValidationResult struct {
 // NewTxValidator creates new transactions validator
 func NewTxValidator(channelID string, sem Semaphore, cr ChannelResources, pm plugin.Mapper, cryptoProvider bccsp.BCCSP) *TxValidator {
 	// Encapsulates interface implementation
-	pluginValidator := NewPluginValidator(pm, cr.Ledger(), &dynamicDeserializer{cr: cr}, &dynamicCapabilities{cr: cr})
+	pluginValidator := NewPluginValidator(pm, cr.Ledger(), &dynamicDeserializer{cr: cr}, &dynamicCapabilities{cr: cr}, cryptoProvider)
 	return &TxValidator{
 		ChannelID:        channelID,
 		Semaphore:        sem,
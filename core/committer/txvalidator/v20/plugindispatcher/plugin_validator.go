@@ -23,6 +23,7 @@ import (
 	"github.com/hyperledger/fabric/core/policy"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/pkg/errors"
 )
 
@@ -82,6 +83,7 @@ type PluginValidator struct {
 	capabilities vc.Capabilities
 	policies.ChannelPolicyManagerGetter
 	CollectionResources
+	cryptoProvider bccsp.BCCSP
 }
 
 //go:generate mockery -dir . -name Capabilities -case underscore -output mocks/
@@ -112,7 +114,7 @@ type PolicyManager interface {
 }
 
 // NewPluginValidator creates a new PluginValidator.
-func NewPluginValidator(pm txvalidatorplugin.Mapper, qec QueryExecutorCreator, deserializer msp.IdentityDeserializer, capabilities vc.Capabilities, cpmg policies.ChannelPolicyManagerGetter, cor CollectionResources) *PluginValidator {
+func NewPluginValidator(pm txvalidatorplugin.Mapper, qec QueryExecutorCreator, deserializer msp.IdentityDeserializer, capabilities vc.Capabilities, cpmg policies.ChannelPolicyManagerGetter, cor CollectionResources, cryptoProvider bccsp.BCCSP) *PluginValidator {
 	return &PluginValidator{
 		capabilities:               capabilities,
 		pluginChannelMapping:       make(map[txvalidatorplugin.Name]*pluginsByChannel),
@@ -121,6 +123,7 @@ func NewPluginValidator(pm txvalidatorplugin.Mapper, qec QueryExecutorCreator, d
 		IdentityDeserializer:       deserializer,
 		ChannelPolicyManagerGetter: cpmg,
 		CollectionResources:        cor,
+		cryptoProvider:             cryptoProvider,
 	}
 }
 
@@ -205,7 +208,11 @@ func (pbc *pluginsByChannel) initPlugin(plugin validation.Plugin, channel string
 
 	pe := &PolicyEvaluatorWrapper{IdentityDeserializer: pbc.pv.IdentityDeserializer, PolicyEvaluator: pp}
 	sf := &StateFetcherImpl{QueryExecutorCreator: pbc.pv}
-	if err := plugin.Init(pe, sf, pbc.pv.capabilities, pbc.pv.CollectionResources); err != nil {
+	dependencies := []validation.Dependency{pe, sf, pbc.pv.capabilities, pbc.pv.CollectionResources}
+	if pbc.pv.cryptoProvider != nil {
+		dependencies = append(dependencies, &txvalidatorplugin.CryptoProvider{BCCSP: pbc.pv.cryptoProvider})
+	}
+	if err := plugin.Init(dependencies...); err != nil {
 		return nil, errors.Wrap(err, "failed initializing plugin")
 	}
 	return plugin, nil
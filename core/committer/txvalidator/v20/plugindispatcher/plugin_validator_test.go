@@ -19,8 +19,10 @@ import (
 	"github.com/hyperledger/fabric/core/committer/txvalidator/v20/plugindispatcher/mocks"
 	"github.com/hyperledger/fabric/core/committer/txvalidator/v20/testdata"
 	validation "github.com/hyperledger/fabric/core/handlers/validation/api"
+	cryptoapi "github.com/hyperledger/fabric/core/handlers/validation/api/crypto"
 	"github.com/hyperledger/fabric/msp"
 	. "github.com/hyperledger/fabric/msp/mocks"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -35,7 +37,7 @@ func TestValidateWithPlugin(t *testing.T) {
 	mcpmg := &mocks.ChannelPolicyManagerGetter{}
 	mcpmg.On("Manager", "").Return(&mocks.PolicyManager{})
 
-	v := plugindispatcher.NewPluginValidator(pm, qec, deserializer, capabilities, mcpmg, nil)
+	v := plugindispatcher.NewPluginValidator(pm, qec, deserializer, capabilities, mcpmg, nil, nil)
 	ctx := &plugindispatcher.Context{
 		Namespace:  "mycc",
 		PluginName: "vscc",
@@ -71,6 +73,31 @@ func TestValidateWithPlugin(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestValidateWithPluginCryptoProvider(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	pm := make(plugin.MapBasedMapper)
+	mcpmg := &mocks.ChannelPolicyManagerGetter{}
+	mcpmg.On("Manager", "").Return(&mocks.PolicyManager{})
+	v := plugindispatcher.NewPluginValidator(pm, &mocks.QueryExecutorCreator{}, &mocks.IdentityDeserializer{}, &mocks.Capabilities{}, mcpmg, nil, cryptoProvider)
+
+	factory := &mocks.PluginFactory{}
+	p := &mocks.Plugin{}
+	p.On("Init", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	p.On("Validate", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	factory.On("New").Return(p)
+	pm["vscc"] = factory
+
+	err = v.ValidateWithPlugin(&plugindispatcher.Context{Namespace: "mycc", PluginName: "vscc"})
+	assert.NoError(t, err)
+	// Ensure the BCCSP of the peer was passed to Init() as the last dependency
+	p.AssertCalled(t, "Init", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.MatchedBy(func(dep validation.Dependency) bool {
+		cp, isCryptoProvider := dep.(cryptoapi.CryptoProvider)
+		return isCryptoProvider && cp.CSP() == cryptoProvider
+	}))
+}
+
 func TestSamplePlugin(t *testing.T) {
 	pm := make(plugin.MapBasedMapper)
 
@@ -107,7 +134,7 @@ func TestSamplePlugin(t *testing.T) {
 
 	txnData, _ := proto.Marshal(&transaction)
 
-	v := plugindispatcher.NewPluginValidator(pm, qec, deserializer, capabilities, mcpmg, nil)
+	v := plugindispatcher.NewPluginValidator(pm, qec, deserializer, capabilities, mcpmg, nil, nil)
 	acceptAllPolicyBytes, _ := proto.Marshal(&peer.ApplicationPolicy{Type: &peer.ApplicationPolicy_SignaturePolicy{SignaturePolicy: policydsl.AcceptAllPolicy}})
 	ctx := &plugindispatcher.Context{
 		Namespace:  "mycc",
@@ -20,6 +20,7 @@ import (
 	commonerrors "github.com/hyperledger/fabric/common/errors"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/committer/txvalidator/plugin"
 	"github.com/hyperledger/fabric/core/committer/txvalidator/v20/plugindispatcher"
 	"github.com/hyperledger/fabric/core/common/validation"
@@ -29,6 +30,7 @@ import (
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // Semaphore provides to the validator means for synchronisation
@@ -117,6 +119,8 @@ type TxValidator struct {
 var logger = flogging.MustGetLogger("committer.txvalidator")
 
 type blockValidationRequest struct {
+	// ctx carries the span of the validation of the block
+	ctx   context.Context
 	block *common.Block
 	d     []byte
 	tIdx  int
@@ -142,7 +146,7 @@ func NewTxValidator(
 	cryptoProvider bccsp.BCCSP,
 ) *TxValidator {
 	// Encapsulates interface implementation
-	pluginValidator := plugindispatcher.NewPluginValidator(pm, ler, &dynamicDeserializer{cr: cr}, &dynamicCapabilities{cr: cr}, channelPolicyManagerGetter, cor)
+	pluginValidator := plugindispatcher.NewPluginValidator(pm, ler, &dynamicDeserializer{cr: cr}, &dynamicCapabilities{cr: cr}, channelPolicyManagerGetter, cor, cryptoProvider)
 	return &TxValidator{
 		ChannelID:        channelID,
 		Semaphore:        sem,
@@ -185,6 +189,13 @@ func (v *TxValidator) Validate(block *common.Block) error {
 	startValidation := time.Now() // timer to log Validate block duration
 	logger.Debugf("[%s] START Block Validation for block [%d]", v.ChannelID, block.Header.Number)
 
+	ctx, span := tracing.Start(context.Background(), "txvalidator.Validate",
+		attribute.String("channel", v.ChannelID),
+		attribute.Int64("block", int64(block.Header.Number)),
+		attribute.Int("transactions", len(block.Data.Data)),
+	)
+	defer span.End()
+
 	// Initialize trans as valid here, then set invalidation reason code upon invalidation below
 	txsfltr := ledgerUtil.NewTxValidationFlags(len(block.Data.Data))
 	// array of txids
@@ -200,6 +211,7 @@ func (v *TxValidator) Validate(block *common.Block) error {
 				defer v.Semaphore.Release()
 
 				v.validateTx(&blockValidationRequest{
+					ctx:   ctx,
 					d:     data,
 					block: block,
 					tIdx:  index,
@@ -327,7 +339,15 @@ func (v *TxValidator) validateTx(req *blockValidationRequest, results chan<- *bl
 		var err error
 		var txResult peer.TxValidationCode
 
-		if payload, txResult = validation.ValidateTransaction(env, v.CryptoProvider); txResult != peer.TxValidationCode_VALID {
+		ctx, span := tracing.Start(req.ctx, "txvalidator.ValidateTx", attribute.Int("index", tIdx))
+		defer span.End()
+
+		// hashes the transaction and verifies the signature of its creator
+		_, checkSpan := tracing.Start(ctx, "txvalidator.ValidateTransaction")
+		payload, txResult = validation.ValidateTransaction(env, v.CryptoProvider)
+		checkSpan.SetAttributes(attribute.String("code", txResult.String()))
+		checkSpan.End()
+		if txResult != peer.TxValidationCode_VALID {
 			logger.Errorf("Invalid transaction with index %d", tIdx)
 			results <- &blockValidationResult{
 				tIdx:           tIdx,
@@ -362,16 +382,24 @@ func (v *TxValidator) validateTx(req *blockValidationRequest, results chan<- *bl
 
 			txID = chdr.TxId
 
+			span.SetAttributes(attribute.String("txid", txID))
+
 			// Check duplicate transactions
+			_, dupSpan := tracing.Start(ctx, "txvalidator.CheckTxIdDupsLedger")
 			erroneousResultEntry := v.checkTxIdDupsLedger(tIdx, chdr, v.LedgerResources)
+			dupSpan.End()
 			if erroneousResultEntry != nil {
 				results <- erroneousResultEntry
 				return
 			}
 
 			// Validate tx with plugins
+			// the plugins verify the endorsements against the policies
 			logger.Debug("Validating transaction with plugins")
+			_, dispatchSpan := tracing.Start(ctx, "txvalidator.Dispatch")
 			err, cde := v.Dispatcher.Dispatch(tIdx, payload, d, block)
+			dispatchSpan.SetAttributes(attribute.String("code", cde.String()))
+			tracing.End(dispatchSpan, err)
 			if err != nil {
 				logger.Errorf("Dispatch for transaction txId = %s returned error: %s", txID, err)
 				switch err.(type) {
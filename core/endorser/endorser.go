@@ -17,6 +17,7 @@ import (
 	pb "github.com/hyperledger/fabric-protos-go/peer"
 	"github.com/hyperledger/fabric-protos-go/transientstore"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/lifecycle"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
@@ -25,6 +26,7 @@ import (
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"go.uber.org/zap"
 )
 
@@ -247,10 +249,12 @@ func (e *Endorser) SimulateProposal(txParams *ccprovider.TransactionParams, chai
 }
 
 // preProcess checks the tx proposal headers, uniqueness and ACL
-func (e *Endorser) preProcess(up *UnpackedProposal, channel *Channel) error {
-	// at first, we check whether the message is valid
-
+func (e *Endorser) preProcess(ctx context.Context, up *UnpackedProposal, channel *Channel) error {
+	// at first, we check whether the message is valid, which hashes the
+	// proposal and verifies the signature of its creator
+	_, span := tracing.Start(ctx, "endorser.ValidateProposal")
 	err := up.Validate(channel.IdentityDeserializer)
+	tracing.End(span, err)
 	if err != nil {
 		e.Metrics.ProposalValidationFailed.Add(1)
 		return errors.WithMessage(err, "error validating proposal")
@@ -272,7 +276,10 @@ func (e *Endorser) preProcess(up *UnpackedProposal, channel *Channel) error {
 
 	// Here we handle uniqueness check and ACLs for proposals targeting a chain
 	// Notice that ValidateProposalMessage has already verified that TxID is computed properly
-	if _, err = e.Support.GetTransactionByID(up.ChannelHeader.ChannelId, up.ChannelHeader.TxId); err == nil {
+	_, span = tracing.Start(ctx, "endorser.CheckDuplicate")
+	_, err = e.Support.GetTransactionByID(up.ChannelHeader.ChannelId, up.ChannelHeader.TxId)
+	span.End()
+	if err == nil {
 		// increment failure due to duplicate transactions. Useful for catching replay attacks in
 		// addition to benign retries
 		e.Metrics.DuplicateTxsFailure.With(meterLabels...).Add(1)
@@ -283,7 +290,10 @@ func (e *Endorser) preProcess(up *UnpackedProposal, channel *Channel) error {
 	// for system chaincodes are checked elsewhere
 	if !e.Support.IsSysCC(up.ChaincodeName) {
 		// check that the proposal complies with the Channel's writers
-		if err = e.Support.CheckACL(up.ChannelHeader.ChannelId, up.SignedProposal); err != nil {
+		_, span = tracing.Start(ctx, "endorser.CheckACL")
+		err = e.Support.CheckACL(up.ChannelHeader.ChannelId, up.SignedProposal)
+		tracing.End(span, err)
+		if err != nil {
 			e.Metrics.ProposalACLCheckFailed.With(meterLabels...).Add(1)
 			return err
 		}
@@ -298,6 +308,9 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 	startTime := time.Now()
 	e.Metrics.ProposalsReceived.Add(1)
 
+	ctx, span := tracing.Start(ctx, "endorser.ProcessProposal")
+	defer span.End()
+
 	addr := util.ExtractRemoteAddress(ctx)
 	endorserLogger.Debug("request from", addr)
 
@@ -309,6 +322,11 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 		e.Metrics.ProposalValidationFailed.Add(1)
 		return &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: err.Error()}}, err
 	}
+	span.SetAttributes(
+		attribute.String("channel", up.ChannelID()),
+		attribute.String("chaincode", up.ChaincodeName),
+		attribute.String("txid", up.TxID()),
+	)
 
 	var channel *Channel
 	if up.ChannelID() != "" {
@@ -323,7 +341,7 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 	}
 
 	// 0 -- check and validate
-	err = e.preProcess(up, channel)
+	err = e.preProcess(ctx, up, channel)
 	if err != nil {
 		return &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: err.Error()}}, err
 	}
@@ -337,7 +355,7 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 		e.Metrics.ProposalDuration.With(meterLabels...).Observe(time.Since(startTime).Seconds())
 	}()
 
-	pResp, err := e.ProcessProposalSuccessfullyOrError(up)
+	pResp, err := e.ProcessProposalSuccessfullyOrError(ctx, up)
 	if err != nil {
 		return &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: err.Error()}}, nil
 	}
@@ -354,7 +372,7 @@ func (e *Endorser) ProcessProposal(ctx context.Context, signedProp *pb.SignedPro
 	return pResp, nil
 }
 
-func (e *Endorser) ProcessProposalSuccessfullyOrError(up *UnpackedProposal) (*pb.ProposalResponse, error) {
+func (e *Endorser) ProcessProposalSuccessfullyOrError(ctx context.Context, up *UnpackedProposal) (*pb.ProposalResponse, error) {
 	txParams := &ccprovider.TransactionParams{
 		ChannelID:  up.ChannelHeader.ChannelId,
 		TxID:       up.ChannelHeader.TxId,
@@ -394,7 +412,9 @@ func (e *Endorser) ProcessProposalSuccessfullyOrError(up *UnpackedProposal) (*pb
 	}
 
 	// 1 -- simulate
+	_, span := tracing.Start(ctx, "endorser.SimulateProposal")
 	res, simulationResult, ccevent, err := e.SimulateProposal(txParams, up.ChaincodeName, up.Input)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, errors.WithMessage(err, "error in simulation")
 	}
@@ -446,7 +466,10 @@ func (e *Endorser) ProcessProposalSuccessfullyOrError(up *UnpackedProposal) (*pb
 	logger.Debugf("escc for chaincode %s is %s", up.ChaincodeName, escc)
 
 	// Note, mPrpBytes is the same as prpBytes by default endorsement plugin, but others could change it.
+	// The plugin signs the response with the key of the peer.
+	_, span = tracing.Start(ctx, "endorser.Endorse", attribute.String("escc", escc))
 	endorsement, mPrpBytes, err := e.Support.EndorseWithPlugin(escc, up.ChannelID(), prpBytes, up.SignedProposal)
+	tracing.End(span, err)
 	if err != nil {
 		meterLabels = append(meterLabels, "chaincodeerror", strconv.FormatBool(false))
 		e.Metrics.EndorsementsFailed.With(meterLabels...).Add(1)
@@ -14,6 +14,7 @@ import (
 	endorsement "github.com/hyperledger/fabric/core/handlers/endorsement/api"
 	endorsement3 "github.com/hyperledger/fabric/core/handlers/endorsement/api/identities"
 	"github.com/hyperledger/fabric/core/transientstore"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/pkg/errors"
 )
 
@@ -74,6 +75,8 @@ type PluginSupport struct {
 	endorsement3.SigningIdentityFetcher
 	PluginMapper
 	TransientStoreRetriever
+	// CryptoProvider is passed to the plugins as a dependency, when set
+	CryptoProvider bccsp.BCCSP
 }
 
 // NewPluginEndorser endorses with using a plugin
@@ -84,6 +87,7 @@ func NewPluginEndorser(ps *PluginSupport) *PluginEndorser {
 		pluginChannelMapping:    make(map[PluginName]*pluginsByChannel),
 		ChannelStateRetriever:   ps.ChannelStateRetriever,
 		TransientStoreRetriever: ps.TransientStoreRetriever,
+		CryptoProvider:          ps.CryptoProvider,
 	}
 }
 
@@ -138,6 +142,10 @@ func (pbc *pluginsByChannel) initPlugin(plugin endorsement.Plugin, channel strin
 	}
 	// Add the SigningIdentityFetcher as a dependency
 	dependencies = append(dependencies, pbc.pe.SigningIdentityFetcher)
+	// Add the BCCSP of the peer as a dependency
+	if pbc.pe.CryptoProvider != nil {
+		dependencies = append(dependencies, &cryptoProvider{csp: pbc.pe.CryptoProvider})
+	}
 	err = plugin.Init(dependencies...)
 	if err != nil {
 		return nil, err
@@ -153,6 +161,17 @@ type PluginEndorser struct {
 	ChannelStateRetriever
 	endorsement3.SigningIdentityFetcher
 	TransientStoreRetriever
+	CryptoProvider bccsp.BCCSP
+}
+
+// cryptoProvider provides the BCCSP of the peer to plugins
+type cryptoProvider struct {
+	csp bccsp.BCCSP
+}
+
+// CSP returns the BCCSP of the peer
+func (cp *cryptoProvider) CSP() bccsp.BCCSP {
+	return cp.csp
 }
 
 // EndorseWithPlugin endorses the response with a plugin
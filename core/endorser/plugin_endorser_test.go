@@ -19,10 +19,12 @@ import (
 	"github.com/hyperledger/fabric/core/endorser/fake"
 	"github.com/hyperledger/fabric/core/endorser/mocks"
 	endorsement "github.com/hyperledger/fabric/core/handlers/endorsement/api"
+	cryptoapi "github.com/hyperledger/fabric/core/handlers/endorsement/api/crypto"
 	. "github.com/hyperledger/fabric/core/handlers/endorsement/api/state"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/transientstore"
 	"github.com/hyperledger/fabric/gossip/privdata"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -143,6 +145,33 @@ func TestPluginEndorserGreenPath(t *testing.T) {
 	plugin.AssertCalled(t, "Init", sif)
 }
 
+func TestPluginEndorserCryptoProvider(t *testing.T) {
+	csp, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+
+	pluginMapper := &mocks.PluginMapper{}
+	pluginFactory := &mocks.PluginFactory{}
+	plugin := &mocks.Plugin{}
+	plugin.On("Endorse", mock.Anything, mock.Anything).Return(&peer.Endorsement{}, []byte{1, 2, 3}, nil)
+	pluginMapper.On("PluginFactoryByName", endorser.PluginName("plugin")).Return(pluginFactory)
+	plugin.On("Init", mock.Anything, mock.Anything).Return(nil).Once()
+	pluginFactory.On("New").Return(plugin).Once()
+	sif := &mocks.SigningIdentityFetcher{}
+	pluginEndorser := endorser.NewPluginEndorser(&endorser.PluginSupport{
+		SigningIdentityFetcher: sif,
+		PluginMapper:           pluginMapper,
+		CryptoProvider:         csp,
+	})
+
+	_, _, err = pluginEndorser.EndorseWithPlugin("plugin", "", nil, nil)
+	assert.NoError(t, err)
+	// Ensure the BCCSP of the peer was passed to Init() along with the SigningIdentityFetcher
+	plugin.AssertCalled(t, "Init", sif, mock.MatchedBy(func(dep endorsement.Dependency) bool {
+		cp, isCryptoProvider := dep.(cryptoapi.CryptoProvider)
+		return isCryptoProvider && cp.CSP() == csp
+	}))
+}
+
 func TestPluginEndorserErrors(t *testing.T) {
 	pluginMapper := &mocks.PluginMapper{}
 	pluginFactory := &mocks.PluginFactory{}
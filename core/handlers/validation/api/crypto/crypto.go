@@ -0,0 +1,21 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package validation
+
+import (
+	validation "github.com/hyperledger/fabric/core/handlers/validation/api"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+// CryptoProvider gives plugins access to the BCCSP the peer is configured with,
+// so that they can verify, decrypt or hash with the algorithms and keys of the
+// peer, such as SM2, SM4 and SM3, without initializing a BCCSP of their own.
+type CryptoProvider interface {
+	validation.Dependency
+	// CSP returns the BCCSP of the peer
+	CSP() bccsp.BCCSP
+}
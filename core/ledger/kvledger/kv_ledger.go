@@ -23,6 +23,7 @@ import (
 	"github.com/hyperledger/fabric/core/ledger/kvledger/bookkeeping"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/history"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/txmgr"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/txmgr/lockbasedtxmgr"
 	"github.com/hyperledger/fabric/core/ledger/ledgerstorage"
@@ -45,6 +46,10 @@ type kvLedger struct {
 	blockAPIsRWLock        *sync.RWMutex
 	stats                  *ledgerStats
 	commitHash             []byte
+	// hashingAlgorithm computes the commit hashes, as configured in the
+	// genesis block of the channel under the GM_ALGORITHMS capability
+	hashingAlgorithm func([]byte) []byte
+	stateDB          privacyenabledstate.DB
 }
 
 // newKVLedger constructs new `KVLedger`
@@ -65,7 +70,7 @@ func newKVLedger(
 	logger.Debugf("Creating KVLedger ledgerID=%s: ", ledgerID)
 	// Create a kvLedger for this chain/ledger, which encapsulates the underlying
 	// id store, blockstore, txmgr (state database), history database
-	l := &kvLedger{ledgerID: ledgerID, blockStore: blockStore, historyDB: historyDB, blockAPIsRWLock: &sync.RWMutex{}}
+	l := &kvLedger{ledgerID: ledgerID, blockStore: blockStore, historyDB: historyDB, blockAPIsRWLock: &sync.RWMutex{}, stateDB: versionedDB}
 
 	btlPolicy := pvtdatapolicy.ConstructBTLPolicy(&collectionInfoRetriever{ledgerID, l, ccInfoProvider})
 
@@ -90,6 +95,10 @@ func newKVLedger(
 		return nil, err
 	}
 
+	if err := l.loadHashingAlgorithm(); err != nil {
+		return nil, err
+	}
+
 	// TODO Move the function `GetChaincodeEventListener` to ledger interface and
 	// this functionality of registering for events to ledgermgmt package so that this
 	// is reused across other future ledger implementations
@@ -153,6 +162,42 @@ func (l *kvLedger) initBlockStore(btlPolicy pvtdatapolicy.BTLPolicy) {
 	l.blockStore.Init(btlPolicy)
 }
 
+// loadHashingAlgorithm sets the hashing algorithm of the channel from the
+// genesis block in the block store, if any.
+func (l *kvLedger) loadHashingAlgorithm() error {
+	bcInfo, err := l.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+	if bcInfo.Height == 0 {
+		return nil
+	}
+	genesisBlock, err := l.GetBlockByNumber(0)
+	if err != nil {
+		return err
+	}
+	return l.setHashingAlgorithm(genesisBlock)
+}
+
+func (l *kvLedger) setHashingAlgorithm(genesisBlock *common.Block) error {
+	name, err := util.BlockHashingAlgorithmName(genesisBlock)
+	if err != nil {
+		return errors.WithMessage(err, "error determining the hashing algorithm of the genesis block")
+	}
+	hashingAlgorithm, err := util.HashingAlgorithm(name)
+	if err != nil {
+		return errors.WithMessage(err, "error determining the hashing algorithm of the genesis block")
+	}
+	l.hashingAlgorithm = hashingAlgorithm
+	// the integrity digests of the state values follow the hashing algorithm of the channel
+	if integrityProtected, ok := l.stateDB.(statedb.IntegrityProtected); ok {
+		if err := integrityProtected.SetIntegrityHashingAlgorithm(name); err != nil {
+			return errors.WithMessage(err, "error setting the hashing algorithm of the state database")
+		}
+	}
+	return nil
+}
+
 func (l *kvLedger) lastPersistedCommitHash() ([]byte, error) {
 	bcInfo, err := l.GetBlockchainInfo()
 	if err != nil {
@@ -417,6 +462,12 @@ func (l *kvLedger) CommitLegacy(pvtdataAndBlock *ledger.BlockAndPvtData, commitO
 	block := pvtdataAndBlock.Block
 	blockNo := pvtdataAndBlock.Block.Header.Number
 
+	if blockNo == 0 {
+		if err := l.setHashingAlgorithm(block); err != nil {
+			return err
+		}
+	}
+
 	startBlockProcessing := time.Now()
 	if commitOpts.FetchPvtDataFromLedger {
 		// when we reach here, it means that the pvtdata store has the
@@ -538,7 +589,7 @@ func (l *kvLedger) addBlockCommitHash(block *common.Block, updateBatchBytes []by
 	valueBytes = append(valueBytes, updateBatchBytes...)
 	valueBytes = append(valueBytes, l.commitHash...)
 
-	l.commitHash = util.ComputeSHA256(valueBytes)
+	l.commitHash = l.hashingAlgorithm(valueBytes)
 	block.Metadata.Metadata[common.BlockMetadataIndex_COMMIT_HASH] = protoutil.MarshalOrPanic(&common.Metadata{Value: l.commitHash})
 }
 
@@ -49,6 +49,7 @@ type CommonStorageDBProvider struct {
 	statedb.VersionedDBProvider
 	HealthCheckRegistry ledger.HealthCheckRegistry
 	bookkeepingProvider bookkeeping.Provider
+	integrityDigests    bool
 }
 
 // NewCommonStorageDBProvider constructs an instance of DBProvider
@@ -74,7 +75,12 @@ func NewCommonStorageDBProvider(
 		}
 	}
 
-	dbProvider := &CommonStorageDBProvider{vdbProvider, healthCheckRegistry, bookkeeperProvider}
+	dbProvider := &CommonStorageDBProvider{
+		VersionedDBProvider: vdbProvider,
+		HealthCheckRegistry: healthCheckRegistry,
+		bookkeepingProvider: bookkeeperProvider,
+		integrityDigests:    stateDBConf != nil && stateDBConf.IntegrityDigests,
+	}
 
 	err = dbProvider.RegisterHealthChecker()
 	if err != nil {
@@ -100,7 +106,11 @@ func (p *CommonStorageDBProvider) GetDBHandle(id string) (DB, error) {
 	}
 	bookkeeper := p.bookkeepingProvider.GetDBHandle(id, bookkeeping.MetadataPresenceIndicator)
 	metadataHint := newMetadataHint(bookkeeper)
-	return NewCommonStorageDB(vdb, id, metadataHint)
+	return &CommonStorageDB{
+		VersionedDB:      vdb,
+		metadataHint:     metadataHint,
+		integrityDigests: p.integrityDigests,
+	}, nil
 }
 
 // Close implements function from interface DBProvider
@@ -112,13 +122,14 @@ func (p *CommonStorageDBProvider) Close() {
 // both the public and private data
 type CommonStorageDB struct {
 	statedb.VersionedDB
-	metadataHint *metadataHint
+	metadataHint     *metadataHint
+	integrityDigests bool
 }
 
 // NewCommonStorageDB wraps a VersionedDB instance. The public data is managed directly by the wrapped versionedDB.
 // For managing the hashed data and private data, this implementation creates separate namespaces in the wrapped db
 func NewCommonStorageDB(vdb statedb.VersionedDB, ledgerid string, metadataHint *metadataHint) (DB, error) {
-	return &CommonStorageDB{vdb, metadataHint}, nil
+	return &CommonStorageDB{VersionedDB: vdb, metadataHint: metadataHint}, nil
 }
 
 // IsBulkOptimizable implements corresponding function in interface DB
@@ -127,6 +138,16 @@ func (s *CommonStorageDB) IsBulkOptimizable() bool {
 	return ok
 }
 
+// SetIntegrityHashingAlgorithm implements function from interface statedb.IntegrityProtected.
+// The hashing algorithm is set on the wrapped db when integrity digests are enabled
+func (s *CommonStorageDB) SetIntegrityHashingAlgorithm(name string) error {
+	integrityProtected, ok := s.VersionedDB.(statedb.IntegrityProtected)
+	if !s.integrityDigests || !ok {
+		return nil
+	}
+	return integrityProtected.SetIntegrityHashingAlgorithm(name)
+}
+
 // LoadCommittedVersionsOfPubAndHashedKeys implements corresponding function in interface DB
 func (s *CommonStorageDB) LoadCommittedVersionsOfPubAndHashedKeys(pubKeys []*statedb.CompositeKey,
 	hashedKeys []*HashedCompositeKey) error {
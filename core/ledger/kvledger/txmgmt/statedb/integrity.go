@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statedb
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+
+	commonutil "github.com/hyperledger/fabric/common/util"
+	"github.com/pkg/errors"
+)
+
+// integrityDigestSep separates the name of the hashing algorithm of an
+// integrity digest from the hex encoded digest.
+const integrityDigestSep = ":"
+
+// ComputeIntegrityDigest returns the integrity digest of the value vv of key
+// in namespace ns, computed with the hashing algorithm named name in channel
+// configs. The digest names its hashing algorithm, so that values remain
+// verifiable after the hashing algorithm of the database changes.
+func ComputeIntegrityDigest(name, ns, key string, vv *VersionedValue) (string, error) {
+	hash, err := commonutil.HashingAlgorithm(name)
+	if err != nil {
+		return "", err
+	}
+	return name + integrityDigestSep + hex.EncodeToString(hash(integrityDigestInput(ns, key, vv))), nil
+}
+
+// VerifyIntegrityDigest verifies that digest, as returned by
+// ComputeIntegrityDigest, is the integrity digest of the value vv of key in
+// namespace ns.
+func VerifyIntegrityDigest(digest, ns, key string, vv *VersionedValue) error {
+	split := strings.SplitN(digest, integrityDigestSep, 2)
+	if len(split) != 2 {
+		return errors.Errorf("malformed integrity digest [%s] for key [%s] in namespace [%s]", digest, key, ns)
+	}
+	expected, err := ComputeIntegrityDigest(split[0], ns, key, vv)
+	if err != nil {
+		return errors.WithMessagef(err, "failed verifying integrity digest for key [%s] in namespace [%s]", key, ns)
+	}
+	if expected != digest {
+		return errors.Errorf("integrity digest mismatch for key [%s] in namespace [%s], the state database is corrupted", key, ns)
+	}
+	return nil
+}
+
+// integrityDigestInput encodes the namespace, key, value, metadata and
+// version the integrity digest covers, each prefixed with its length.
+func integrityDigestInput(ns, key string, vv *VersionedValue) []byte {
+	var input []byte
+	var versionBytes []byte
+	if vv.Version != nil {
+		versionBytes = vv.Version.ToBytes()
+	}
+	for _, field := range [][]byte{[]byte(ns), []byte(key), vv.Value, vv.Metadata, versionBytes} {
+		var length [binary.MaxVarintLen64]byte
+		n := binary.PutUvarint(length[:], uint64(len(field)))
+		input = append(input, length[:n]...)
+		input = append(input, field...)
+	}
+	return input
+}
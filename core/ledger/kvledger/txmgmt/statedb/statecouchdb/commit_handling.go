@@ -134,6 +134,11 @@ func (vdb *VersionedDB) buildCommittersForNs(ns string, nsUpdates map[string]*st
 	i := 0
 	for key, vv := range nsUpdates {
 		kv := &keyValue{key: key, revision: revisions[key], VersionedValue: vv}
+		if vdb.integrityAlgorithm != "" && vv.Value != nil {
+			if kv.integrityDigest, err = computeIntegrityDigest(vdb.integrityAlgorithm, ns, key, vv); err != nil {
+				return nil, err
+			}
+		}
 		couchDoc, err := keyValToCouchDoc(kv)
 		if err != nil {
 			return nil, err
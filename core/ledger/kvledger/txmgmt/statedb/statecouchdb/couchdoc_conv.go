@@ -30,6 +30,8 @@ type keyValue struct {
 	key      string
 	revision string
 	*statedb.VersionedValue
+	// integrityDigest is the integrity digest stored alongside the value, if any
+	integrityDigest string
 }
 
 type jsonValue map[string]interface{}
@@ -84,7 +86,8 @@ func couchDocToKeyValue(doc *couchdb.CouchDoc) (*keyValue, error) {
 	key := jsonResult[idField].(string)
 	// create the return version from the version field in the JSON
 
-	returnVersion, returnMetadata, err := decodeVersionAndMetadata(jsonResult[versionField].(string))
+	verAndMetadata, integrityDigest := splitIntegrityDigest(jsonResult[versionField].(string))
+	returnVersion, returnMetadata, err := decodeVersionAndMetadata(verAndMetadata)
 	if err != nil {
 		return nil, err
 	}
@@ -113,11 +116,13 @@ func couchDocToKeyValue(doc *couchdb.CouchDoc) (*keyValue, error) {
 		}
 	}
 	return &keyValue{
-		key, revision,
-		&statedb.VersionedValue{
+		key:      key,
+		revision: revision,
+		VersionedValue: &statedb.VersionedValue{
 			Value:    returnValue,
 			Metadata: returnMetadata,
 			Version:  returnVersion},
+		integrityDigest: integrityDigest,
 	}, nil
 }
 
@@ -155,7 +160,7 @@ func keyValToCouchDoc(kv *keyValue) (*couchdb.CouchDoc, error) {
 		return nil, err
 	}
 	// add the (version + metadata), id, revision, and delete marker (if needed)
-	jsonMap[versionField] = verAndMetadata
+	jsonMap[versionField] = appendIntegrityDigest(verAndMetadata, kv.integrityDigest)
 	jsonMap[idField] = key
 	if kv.revision != "" {
 		jsonMap[revField] = kv.revision
@@ -272,3 +277,34 @@ func removeJSONRevision(jsonValue *[]byte) error {
 	}
 	return err
 }
+
+// computeIntegrityDigest computes the integrity digest of the value vv of key
+// in namespace ns with the hashing algorithm named hashingAlgorithm.
+func computeIntegrityDigest(hashingAlgorithm, ns, key string, vv *statedb.VersionedValue) (string, error) {
+	return statedb.ComputeIntegrityDigest(hashingAlgorithm, ns, key, integrityDigestValue(vv))
+}
+
+// verifyIntegrityDigest verifies the integrity digest stored alongside the
+// value of kv in namespace ns, if any.
+func verifyIntegrityDigest(ns string, kv *keyValue) error {
+	if kv.integrityDigest == "" {
+		return nil
+	}
+	return statedb.VerifyIntegrityDigest(kv.integrityDigest, ns, kv.key, integrityDigestValue(kv.VersionedValue))
+}
+
+// integrityDigestValue returns vv with the value the integrity digest is
+// computed on. JSON values are stored as documents, whose fields and
+// numbers do not read back as they were written, hence the digest of JSON
+// values is computed on their canonical encoding.
+func integrityDigestValue(vv *statedb.VersionedValue) *statedb.VersionedValue {
+	var jsonVal map[string]interface{}
+	if json.Unmarshal(vv.Value, &jsonVal) != nil || jsonVal == nil {
+		return vv
+	}
+	canonical, err := json.Marshal(jsonVal)
+	if err != nil {
+		return vv
+	}
+	return &statedb.VersionedValue{Value: canonical, Metadata: vv.Metadata, Version: vv.Version}
+}
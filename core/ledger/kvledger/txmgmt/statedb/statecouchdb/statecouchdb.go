@@ -16,6 +16,7 @@ import (
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger/dataformat"
 	"github.com/hyperledger/fabric/common/metrics"
+	commonutil "github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
@@ -174,6 +175,9 @@ type VersionedDB struct {
 	mux                sync.RWMutex
 	redoLogger         *redoLogger
 	cache              *statedb.Cache
+	// integrityAlgorithm is the hashing algorithm of the integrity digests
+	// stored alongside the values, if any
+	integrityAlgorithm string
 }
 
 // newVersionedDB constructs an instance of VersionedDB
@@ -420,6 +424,9 @@ func (vdb *VersionedDB) readFromDB(namespace, key string) (*keyValue, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := verifyIntegrityDigest(namespace, kv); err != nil {
+		return nil, err
+	}
 	return kv, nil
 }
 
@@ -791,6 +798,15 @@ func (vdb *VersionedDB) ensureFullCommitAndRecordSavepoint(height *version.Heigh
 	return nil
 }
 
+// SetIntegrityHashingAlgorithm implements method in IntegrityProtected interface
+func (vdb *VersionedDB) SetIntegrityHashingAlgorithm(name string) error {
+	if _, err := commonutil.HashingAlgorithm(name); err != nil {
+		return err
+	}
+	vdb.integrityAlgorithm = name
+	return nil
+}
+
 // GetLatestSavePoint implements method in VersionedDB interface
 func (vdb *VersionedDB) GetLatestSavePoint() (*version.Height, error) {
 	var err error
@@ -860,6 +876,9 @@ type queryDefinition struct {
 	endKey             string
 	query              string
 	internalQueryLimit int32
+	// fieldsProjected is set when the query returns some fields of the
+	// documents only, whose integrity digests cannot be verified
+	fieldsProjected bool
 }
 
 type paginationInfo struct {
@@ -875,7 +894,7 @@ type resultsInfo struct {
 
 func newQueryScanner(namespace string, db *couchdb.CouchDatabase, query string, internalQueryLimit,
 	limit int32, bookmark, startKey, endKey string) (*queryScanner, error) {
-	scanner := &queryScanner{namespace, db, &queryDefinition{startKey, endKey, query, internalQueryLimit}, &paginationInfo{-1, limit, bookmark}, &resultsInfo{0, nil}}
+	scanner := &queryScanner{namespace, db, &queryDefinition{startKey, endKey, query, internalQueryLimit, queryProjectsFields(query)}, &paginationInfo{-1, limit, bookmark}, &resultsInfo{0, nil}}
 	var err error
 	// query is defined, then execute the query and return the records and bookmark
 	if scanner.queryDefinition.query != "" {
@@ -926,12 +945,31 @@ func (scanner *queryScanner) Next() (statedb.QueryResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	if !scanner.queryDefinition.fieldsProjected {
+		if err := verifyIntegrityDigest(scanner.namespace, kv); err != nil {
+			return nil, err
+		}
+	}
 	scanner.resultsInfo.totalRecordsReturned++
 	return &statedb.VersionedKV{
 		CompositeKey:   statedb.CompositeKey{Namespace: scanner.namespace, Key: key},
 		VersionedValue: *kv.VersionedValue}, nil
 }
 
+// queryProjectsFields tells whether query restricts the returned documents
+// to some of their fields.
+func queryProjectsFields(query string) bool {
+	if query == "" {
+		return false
+	}
+	jsonQueryMap := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(query), &jsonQueryMap); err != nil {
+		return false
+	}
+	_, ok := jsonQueryMap["fields"]
+	return ok
+}
+
 func (scanner *queryScanner) Close() {
 	scanner = nil
 }
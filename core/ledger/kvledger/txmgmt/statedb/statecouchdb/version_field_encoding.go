@@ -8,6 +8,7 @@ package statecouchdb
 
 import (
 	"encoding/base64"
+	"strings"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/statecouchdb/msgs"
@@ -30,7 +31,32 @@ func encodeVersionAndMetadata(version *version.Height, metadata []byte) (string,
 	return base64.StdEncoding.EncodeToString(msgBytes), nil
 }
 
+// integrityDigestSep separates the integrity digest appended to the
+// version field from the encoded version and metadata. It is not part of
+// the base64 alphabet.
+const integrityDigestSep = "."
+
+// appendIntegrityDigest appends digest, if any, to the encoded version and
+// metadata of the version field.
+func appendIntegrityDigest(encodedstr, digest string) string {
+	if digest == "" {
+		return encodedstr
+	}
+	return encodedstr + integrityDigestSep + digest
+}
+
+// splitIntegrityDigest splits the version field into the encoded version and
+// metadata, and the integrity digest appended to them, if any.
+func splitIntegrityDigest(encodedstr string) (string, string) {
+	split := strings.SplitN(encodedstr, integrityDigestSep, 2)
+	if len(split) == 1 {
+		return encodedstr, ""
+	}
+	return split[0], split[1]
+}
+
 func decodeVersionAndMetadata(encodedstr string) (*version.Height, []byte, error) {
+	encodedstr, _ = splitIntegrityDigest(encodedstr)
 	versionFieldBytes, err := base64.StdEncoding.DecodeString(encodedstr)
 	if err != nil {
 		return nil, nil, err
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package statecouchdb
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -45,3 +46,38 @@ func testEncodeDecode(t *testing.T, v *statedb.VersionedValue) {
 	assert.Equal(t, v.Version, ver)
 	assert.Equal(t, v.Metadata, metadata)
 }
+
+func TestIntegrityDigest(t *testing.T) {
+	vv := &statedb.VersionedValue{
+		Value:    []byte(`{"asset_name":"marble1","size":35,"color":"blue"}`),
+		Version:  version.NewHeight(1, 2),
+		Metadata: []byte("sample-metadata"),
+	}
+	kv := &keyValue{key: "key1", VersionedValue: vv}
+	digest, err := computeIntegrityDigest("SM3", "ns", "key1", vv)
+	assert.NoError(t, err)
+	kv.integrityDigest = digest
+
+	couchDoc, err := keyValToCouchDoc(kv)
+	assert.NoError(t, err)
+	readKV, err := couchDocToKeyValue(couchDoc)
+	assert.NoError(t, err)
+	assert.Equal(t, digest, readKV.integrityDigest)
+	assert.Equal(t, vv.Version, readKV.Version)
+	assert.Equal(t, vv.Metadata, readKV.Metadata)
+	// the JSON value reads back with reordered fields, yet verifies
+	assert.NotEqual(t, vv.Value, readKV.Value)
+	assert.NoError(t, verifyIntegrityDigest("ns", readKV))
+
+	// the version field of documents with digests still decodes
+	doc := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(couchDoc.JSONValue, &doc))
+	assert.Contains(t, doc[versionField], "."+digest)
+	ver, metadata, err := decodeVersionAndMetadata(doc[versionField].(string))
+	assert.NoError(t, err)
+	assert.Equal(t, vv.Version, ver)
+	assert.Equal(t, vv.Metadata, metadata)
+
+	readKV.Value = []byte(`{"asset_name":"marble1","size":36,"color":"blue"}`)
+	assert.EqualError(t, verifyIntegrityDigest("ns", readKV), "integrity digest mismatch for key [key1] in namespace [ns], the state database is corrupted")
+}
@@ -88,6 +88,14 @@ type IndexCapable interface {
 	ProcessIndexesForChaincodeDeploy(namespace string, fileEntries []*ccprovider.TarFileEntry) error
 }
 
+//IntegrityProtected interface provides additional functions for
+//databases capable of storing integrity digests alongside the values
+type IntegrityProtected interface {
+	// SetIntegrityHashingAlgorithm sets the hashing algorithm of the digests
+	// stored alongside the values written afterwards
+	SetIntegrityHashingAlgorithm(name string) error
+}
+
 // CompositeKey encloses Namespace and Key components
 type CompositeKey struct {
 	Namespace string
@@ -12,6 +12,7 @@ import (
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger/dataformat"
 	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	commonutil "github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
 	"github.com/pkg/errors"
@@ -60,11 +61,14 @@ func (provider *VersionedDBProvider) Close() {
 type versionedDB struct {
 	db     *leveldbhelper.DBHandle
 	dbName string
+	// integrityAlgorithm is the hashing algorithm of the integrity digests
+	// stored alongside the values, if any
+	integrityAlgorithm string
 }
 
 // newVersionedDB constructs an instance of VersionedDB
 func newVersionedDB(db *leveldbhelper.DBHandle, dbName string) *versionedDB {
-	return &versionedDB{db, dbName}
+	return &versionedDB{db: db, dbName: dbName}
 }
 
 // Open implements method in VersionedDB interface
@@ -98,7 +102,7 @@ func (vdb *versionedDB) GetState(namespace string, key string) (*statedb.Version
 	if dbVal == nil {
 		return nil, nil
 	}
-	return decodeValue(dbVal)
+	return decodeAndVerifyValue(namespace, key, dbVal)
 }
 
 // GetVersion implements method in VersionedDB interface
@@ -184,7 +188,7 @@ func (vdb *versionedDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version
 			if vv.Value == nil {
 				dbBatch.Delete(dataKey)
 			} else {
-				encodedVal, err := encodeValue(vv)
+				encodedVal, err := encodeValueWithDigest(ns, k, vv, vdb.integrityAlgorithm)
 				if err != nil {
 					return err
 				}
@@ -206,6 +210,15 @@ func (vdb *versionedDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version
 	return nil
 }
 
+// SetIntegrityHashingAlgorithm implements method in IntegrityProtected interface
+func (vdb *versionedDB) SetIntegrityHashingAlgorithm(name string) error {
+	if _, err := commonutil.HashingAlgorithm(name); err != nil {
+		return err
+	}
+	vdb.integrityAlgorithm = name
+	return nil
+}
+
 // GetLatestSavePoint implements method in VersionedDB interface
 func (vdb *versionedDB) GetLatestSavePoint() (*version.Height, error) {
 	versionBytes, err := vdb.db.Get(savePointKey)
@@ -257,7 +270,7 @@ func (scanner *kvScanner) Next() (statedb.QueryResult, error) {
 	dbValCopy := make([]byte, len(dbVal))
 	copy(dbValCopy, dbVal)
 	_, key := decodeDataKey(dbKey)
-	vv, err := decodeValue(dbValCopy)
+	vv, err := decodeAndVerifyValue(scanner.namespace, key, dbValCopy)
 	if err != nil {
 		return nil, err
 	}
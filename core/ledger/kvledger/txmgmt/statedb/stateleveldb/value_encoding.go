@@ -7,12 +7,20 @@ SPDX-License-Identifier: Apache-2.0
 package stateleveldb
 
 import (
+	"bytes"
+
 	proto "github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb/msgs"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/pkg/errors"
 )
 
+// integrityDigestMarker starts the values stored along with an integrity
+// digest, which is terminated by another marker. As protobuf field numbers
+// start at 1, no encoded value starts with a zero byte.
+var integrityDigestMarker = []byte{0x00}
+
 // encodeValue encodes the value, version, and metadata
 func encodeValue(v *statedb.VersionedValue) ([]byte, error) {
 	return proto.Marshal(
@@ -43,3 +51,43 @@ func decodeValue(encodedValue []byte) (*statedb.VersionedValue, error) {
 	}
 	return &statedb.VersionedValue{Version: ver, Value: val, Metadata: metadata}, nil
 }
+
+// encodeValueWithDigest encodes the value vv of key in namespace ns, along
+// with its integrity digest computed with the hashing algorithm named
+// hashingAlgorithm. No digest is stored when hashingAlgorithm is empty.
+func encodeValueWithDigest(ns, key string, vv *statedb.VersionedValue, hashingAlgorithm string) ([]byte, error) {
+	encodedValue, err := encodeValue(vv)
+	if err != nil || hashingAlgorithm == "" {
+		return encodedValue, err
+	}
+	digest, err := statedb.ComputeIntegrityDigest(hashingAlgorithm, ns, key, vv)
+	if err != nil {
+		return nil, err
+	}
+	encoded := append([]byte{}, integrityDigestMarker...)
+	encoded = append(encoded, digest...)
+	encoded = append(encoded, integrityDigestMarker...)
+	return append(encoded, encodedValue...), nil
+}
+
+// decodeAndVerifyValue decodes the value of key in namespace ns, and
+// verifies its integrity digest if one is stored along with it.
+func decodeAndVerifyValue(ns, key string, encodedValue []byte) (*statedb.VersionedValue, error) {
+	if !bytes.HasPrefix(encodedValue, integrityDigestMarker) {
+		return decodeValue(encodedValue)
+	}
+	encodedValue = encodedValue[len(integrityDigestMarker):]
+	end := bytes.Index(encodedValue, integrityDigestMarker)
+	if end < 0 {
+		return nil, errors.Errorf("unterminated integrity digest for key [%s] in namespace [%s]", key, ns)
+	}
+	digest := string(encodedValue[:end])
+	vv, err := decodeValue(encodedValue[end+len(integrityDigestMarker):])
+	if err != nil {
+		return nil, err
+	}
+	if err := statedb.VerifyIntegrityDigest(digest, ns, key, vv); err != nil {
+		return nil, err
+	}
+	return vv, nil
+}
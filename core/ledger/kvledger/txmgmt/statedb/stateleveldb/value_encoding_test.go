@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package stateleveldb
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -51,3 +52,37 @@ func testEncodeDecodeVersionedValues(t *testing.T, v *statedb.VersionedValue) {
 	assert.NoError(t, err)
 	assert.Equal(t, v, decodedVal)
 }
+
+func TestEncodeDecodeValuesWithDigest(t *testing.T) {
+	v := &statedb.VersionedValue{
+		Value:    []byte("value1"),
+		Version:  version.NewHeight(1, 2),
+		Metadata: []byte("sample-metadata"),
+	}
+
+	// values stored without digest
+	encodedVal, err := encodeValueWithDigest("ns", "key1", v, "")
+	assert.NoError(t, err)
+	decodedVal, err := decodeAndVerifyValue("ns", "key1", encodedVal)
+	assert.NoError(t, err)
+	assert.Equal(t, v, decodedVal)
+
+	encodedVal, err = encodeValueWithDigest("ns", "key1", v, "SM3")
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(encodedVal, []byte("\x00SM3:")))
+	decodedVal, err = decodeAndVerifyValue("ns", "key1", encodedVal)
+	assert.NoError(t, err)
+	assert.Equal(t, v, decodedVal)
+
+	// the digest binds the value to its key
+	_, err = decodeAndVerifyValue("ns", "key2", encodedVal)
+	assert.EqualError(t, err, "integrity digest mismatch for key [key2] in namespace [ns], the state database is corrupted")
+
+	// corrupted value
+	corrupted := bytes.Replace(encodedVal, []byte("value1"), []byte("value2"), 1)
+	_, err = decodeAndVerifyValue("ns", "key1", corrupted)
+	assert.EqualError(t, err, "integrity digest mismatch for key [key1] in namespace [ns], the state database is corrupted")
+
+	_, err = encodeValueWithDigest("ns", "key1", v, "MD5")
+	assert.EqualError(t, err, "Unknown hashing algorithm type: MD5")
+}
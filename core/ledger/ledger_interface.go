@@ -54,6 +54,10 @@ type StateDBConfig struct {
 	// CouchDB is the configuration for CouchDB.  It is used when StateDatabase
 	// is set to "CouchDB".
 	CouchDB *couchdb.Config
+	// IntegrityDigests enables storing the digests of the state values,
+	// computed with the hashing algorithm of the channel, alongside the
+	// values. The digests are verified when the values are read back.
+	IntegrityDigests bool
 }
 
 // PrivateDataConfig is a structure used to configure a private data storage provider.
@@ -0,0 +1,120 @@
+/*
+Copyright IBM Corp All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"encoding/pem"
+	"net/http"
+
+	mspproto "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/common/policydsl"
+	"github.com/hyperledger/fabric/core/middleware"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/pkg/errors"
+)
+
+// Authorization restricts endpoints of the operations server to the clients
+// whose TLS certificate, as an identity of an MSP, satisfies a policy. GM
+// deployments so lock down the admin endpoints to SM2 certificates, which
+// clients present over GMTLS or TLS 1.3 SM.
+type Authorization struct {
+	// Policies maps the patterns endpoints are registered with, such as
+	// /logspec or /metrics, to the policies of their clients
+	Policies map[string]policies.Policy
+	// Deserializer deserializes client certificates into identities
+	Deserializer msp.IdentityDeserializer
+	// MSPID is the MSP of the identities of client certificates
+	MSPID string
+}
+
+// NewAuthorization returns the Authorization of endpoints to the policies
+// of endpointPolicies, such as OR('Org1MSP.admin'), with client certificates
+// as identities of the local MSP.
+func NewAuthorization(endpointPolicies map[string]string, localMSP msp.MSP) (*Authorization, error) {
+	mspID, err := localMSP.GetIdentifier()
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to get the identifier of the local MSP")
+	}
+
+	provider := cauthdsl.NewPolicyProvider(localMSP)
+	endpoints := map[string]policies.Policy{}
+	for pattern, expression := range endpointPolicies {
+		envelope, err := policydsl.FromString(expression)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid policy of operations endpoint %s", pattern)
+		}
+		policy, _, err := provider.NewPolicy(protoutil.MarshalOrPanic(envelope))
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid policy of operations endpoint %s", pattern)
+		}
+		endpoints[pattern] = policy
+	}
+
+	return &Authorization{
+		Policies:     endpoints,
+		Deserializer: localMSP,
+		MSPID:        mspID,
+	}, nil
+}
+
+func (a *Authorization) policy(pattern string) policies.Policy {
+	if a == nil {
+		return nil
+	}
+	return a.Policies[pattern]
+}
+
+type requirePolicy struct {
+	authorization *Authorization
+	policy        policies.Policy
+	pattern       string
+	logger        Logger
+	next          http.Handler
+}
+
+// RequirePolicy is used to ensure that the verified TLS client certificate
+// of requests satisfies the policy of the endpoint registered with pattern.
+func (a *Authorization) RequirePolicy(pattern string, logger Logger) middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return &requirePolicy{
+			authorization: a,
+			policy:        a.policy(pattern),
+			pattern:       pattern,
+			logger:        logger,
+			next:          next,
+		}
+	}
+}
+
+func (r *requirePolicy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.authorize(req.TLS.VerifiedChains[0][0].Raw); err != nil {
+		r.logger.Warnf("Access to operations endpoint %s denied to %s: %s", r.pattern, req.RemoteAddr, err)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	r.next.ServeHTTP(w, req)
+}
+
+func (r *requirePolicy) authorize(certDER []byte) error {
+	serializedIdentity := protoutil.MarshalOrPanic(&mspproto.SerializedIdentity{
+		Mspid:   r.authorization.MSPID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}),
+	})
+	identity, err := r.authorization.Deserializer.DeserializeIdentity(serializedIdentity)
+	if err != nil {
+		return errors.WithMessage(err, "failed to deserialize client certificate")
+	}
+	return r.policy.EvaluateIdentities([]msp.Identity{identity})
+}
@@ -0,0 +1,221 @@
+/*
+Copyright IBM Corp All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	"github.com/golang/protobuf/proto"
+	mspproto "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/core/operations"
+	"github.com/hyperledger/fabric/core/operations/fakes"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/msp/mocks"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("Authorization", func() {
+	var (
+		fakeLogger   *fakes.Logger
+		localMSP     *mocks.MockMSP
+		identity     *mocks.MockIdentity
+		certDER      []byte
+		handler      http.Handler
+		nextCalled   bool
+		serializedID []byte
+	)
+
+	BeforeEach(func() {
+		fakeLogger = &fakes.Logger{}
+		identity = &mocks.MockIdentity{ID: "client"}
+		identity.On("GetIdentifier").Return(&msp.IdentityIdentifier{Mspid: "SampleOrg", Id: "client"})
+		localMSP = &mocks.MockMSP{}
+		localMSP.On("GetIdentifier").Return("SampleOrg", nil)
+		localMSP.On("DeserializeIdentity", mock.Anything).Run(func(args mock.Arguments) {
+			serializedID = args.Get(0).([]byte)
+		}).Return(identity, nil)
+		certDER = []byte("client certificate")
+		nextCalled = false
+		serializedID = nil
+	})
+
+	JustBeforeEach(func() {
+		authorization, err := operations.NewAuthorization(map[string]string{"/logspec": "OR('SampleOrg.admin')"}, localMSP)
+		Expect(err).NotTo(HaveOccurred())
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { nextCalled = true })
+		handler = authorization.RequirePolicy("/logspec", fakeLogger)(next)
+	})
+
+	request := func(verifiedChains [][]*x509.Certificate) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/logspec", nil)
+		req.TLS = &tls.ConnectionState{VerifiedChains: verifiedChains}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	Context("when the client certificate satisfies the policy", func() {
+		BeforeEach(func() {
+			identity.On("SatisfiesPrincipal", mock.Anything).Return(nil)
+		})
+
+		It("delegates to the next handler", func() {
+			resp := request([][]*x509.Certificate{{{Raw: certDER}}})
+			Expect(resp.Code).To(Equal(http.StatusOK))
+			Expect(nextCalled).To(BeTrue())
+		})
+
+		It("deserializes the client certificate as an identity of the MSP", func() {
+			request([][]*x509.Certificate{{{Raw: certDER}}})
+			sID := &mspproto.SerializedIdentity{}
+			Expect(proto.Unmarshal(serializedID, sID)).To(Succeed())
+			Expect(sID.Mspid).To(Equal("SampleOrg"))
+			Expect(sID.IdBytes).To(Equal(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})))
+		})
+	})
+
+	Context("when the client certificate does not satisfy the policy", func() {
+		BeforeEach(func() {
+			identity.On("SatisfiesPrincipal", mock.Anything).Return(errors.New("not an admin"))
+		})
+
+		It("rejects the request as forbidden", func() {
+			resp := request([][]*x509.Certificate{{{Raw: certDER}}})
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+			Expect(nextCalled).To(BeFalse())
+			Expect(fakeLogger.WarnfCallCount()).To(Equal(1))
+			msg, args := fakeLogger.WarnfArgsForCall(0)
+			Expect(fmt.Sprintf(msg, args...)).To(Equal("Access to operations endpoint /logspec denied to 192.0.2.1:1234: signature set did not satisfy policy"))
+		})
+	})
+
+	Context("when the client certificate cannot be deserialized", func() {
+		BeforeEach(func() {
+			localMSP = &mocks.MockMSP{}
+			localMSP.On("GetIdentifier").Return("SampleOrg", nil)
+			localMSP.On("DeserializeIdentity", mock.Anything).Return(identity, errors.New("unknown authority"))
+		})
+
+		It("rejects the request as forbidden", func() {
+			resp := request([][]*x509.Certificate{{{Raw: certDER}}})
+			Expect(resp.Code).To(Equal(http.StatusForbidden))
+			Expect(nextCalled).To(BeFalse())
+			msg, args := fakeLogger.WarnfArgsForCall(0)
+			Expect(fmt.Sprintf(msg, args...)).To(HaveSuffix("failed to deserialize client certificate: unknown authority"))
+		})
+	})
+
+	Context("when the client certificate is not verified", func() {
+		It("rejects the request as unauthorized", func() {
+			resp := request(nil)
+			Expect(resp.Code).To(Equal(http.StatusUnauthorized))
+			Expect(nextCalled).To(BeFalse())
+		})
+	})
+
+	Context("when a policy is invalid", func() {
+		It("returns an error", func() {
+			_, err := operations.NewAuthorization(map[string]string{"/metrics": "OR('SampleOrg.admin'"}, localMSP)
+			Expect(err).To(MatchError(ContainSubstring("invalid policy of operations endpoint /metrics")))
+		})
+	})
+
+	Context("when the identifier of the MSP cannot be retrieved", func() {
+		BeforeEach(func() {
+			localMSP = &mocks.MockMSP{}
+			localMSP.On("GetIdentifier").Return("", errors.New("no identifier"))
+		})
+
+		It("returns an error", func() {
+			_, err := operations.NewAuthorization(nil, localMSP)
+			Expect(err).To(MatchError("failed to get the identifier of the local MSP: no identifier"))
+		})
+	})
+})
+
+var _ = Describe("System with Authorization", func() {
+	var (
+		tempDir      string
+		client       *http.Client
+		unauthClient *http.Client
+		identity     *mocks.MockIdentity
+		system       *operations.System
+	)
+
+	BeforeEach(func() {
+		var err error
+		tempDir, err = ioutil.TempDir("", "opsauth")
+		Expect(err).NotTo(HaveOccurred())
+
+		generateCertificates(tempDir)
+		client = newHTTPClient(tempDir, true)
+		unauthClient = newHTTPClient(tempDir, false)
+
+		identity = &mocks.MockIdentity{ID: "client"}
+		identity.On("GetIdentifier").Return(&msp.IdentityIdentifier{Mspid: "SampleOrg", Id: "client"})
+		identity.On("SatisfiesPrincipal", mock.Anything).Return(errors.New("not an admin"))
+		localMSP := &mocks.MockMSP{}
+		localMSP.On("GetIdentifier").Return("SampleOrg", nil)
+		localMSP.On("DeserializeIdentity", mock.Anything).Return(identity, nil)
+		authorization, err := operations.NewAuthorization(map[string]string{"/healthz": "OR('SampleOrg.admin')"}, localMSP)
+		Expect(err).NotTo(HaveOccurred())
+
+		system = operations.NewSystem(operations.Options{
+			Logger:        &fakes.Logger{},
+			ListenAddress: "127.0.0.1:0",
+			Metrics: operations.MetricsOptions{
+				Provider: "disabled",
+			},
+			TLS: operations.TLS{
+				Enabled:           true,
+				CertFile:          filepath.Join(tempDir, "server-cert.pem"),
+				KeyFile:           filepath.Join(tempDir, "server-key.pem"),
+				ClientCACertFiles: []string{filepath.Join(tempDir, "client-ca.pem")},
+			},
+			Authorization: authorization,
+			Version:       "test-version",
+		})
+		Expect(system.Start()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tempDir)
+		system.Stop()
+	})
+
+	It("requires a client certificate for endpoints with a policy", func() {
+		resp, err := unauthClient.Get(fmt.Sprintf("https://%s/healthz", system.Addr()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+		resp.Body.Close()
+	})
+
+	It("forbids clients whose certificate does not satisfy the policy", func() {
+		resp, err := client.Get(fmt.Sprintf("https://%s/healthz", system.Addr()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusForbidden))
+		resp.Body.Close()
+	})
+
+	It("leaves endpoints without a policy unchanged", func() {
+		resp, err := unauthClient.Get(fmt.Sprintf("https://%s/version", system.Addr()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		resp.Body.Close()
+	})
+})
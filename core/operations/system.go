@@ -27,6 +27,7 @@ import (
 	"github.com/hyperledger/fabric/common/metrics/statsd/goruntime"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/middleware"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -55,6 +56,9 @@ type Options struct {
 	Metrics       MetricsOptions
 	TLS           TLS
 	Version       string
+	// Authorization, when not nil, restricts endpoints to the clients
+	// satisfying their policy
+	Authorization *Authorization
 }
 
 type System struct {
@@ -144,6 +148,13 @@ func (s *System) RegisterChecker(component string, checker healthz.HealthChecker
 	return s.healthHandler.RegisterChecker(component, checker)
 }
 
+// RegisterHandler registers the admin handler of pattern. The handler
+// requires a client certificate when TLS is enabled, which must satisfy
+// the policy of pattern if any.
+func (s *System) RegisterHandler(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, s.handlerChain(pattern, handler, s.options.TLS.Enabled))
+}
+
 func (s *System) initializeServer() {
 	s.mux = http.NewServeMux()
 	s.httpServer = &http.Server{
@@ -151,14 +162,21 @@ func (s *System) initializeServer() {
 		Handler:      s.mux,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 2 * time.Minute,
+		ConnContext:  withGMTLSConn,
 	}
 }
 
-func (s *System) handlerChain(h http.Handler, secure bool) http.Handler {
-	if secure {
-		return middleware.NewChain(middleware.RequireCert(), middleware.WithRequestID(util.GenerateUUID)).Handler(h)
+func (s *System) handlerChain(pattern string, h http.Handler, secure bool) http.Handler {
+	middlewares := []middleware.Middleware{gmtlsState}
+	authorized := s.options.Authorization.policy(pattern) != nil
+	if secure || authorized {
+		middlewares = append(middlewares, middleware.RequireCert())
 	}
-	return middleware.NewChain(middleware.WithRequestID(util.GenerateUUID)).Handler(h)
+	if authorized {
+		middlewares = append(middlewares, s.options.Authorization.RequirePolicy(pattern, s.logger))
+	}
+	middlewares = append(middlewares, middleware.WithRequestID(util.GenerateUUID))
+	return middleware.NewChain(middlewares...).Handler(h)
 }
 
 func (s *System) initializeMetricsProvider() error {
@@ -180,7 +198,7 @@ func (s *System) initializeMetricsProvider() error {
 	case "prometheus":
 		s.Provider = &prometheus.Provider{}
 		s.versionGauge = versionGauge(s.Provider)
-		s.mux.Handle("/metrics", s.handlerChain(promhttp.Handler(), s.options.TLS.Enabled))
+		s.mux.Handle("/metrics", s.handlerChain("/metrics", promhttp.Handler(), s.options.TLS.Enabled))
 		return nil
 
 	default:
@@ -195,12 +213,12 @@ func (s *System) initializeMetricsProvider() error {
 }
 
 func (s *System) initializeLoggingHandler() {
-	s.mux.Handle("/logspec", s.handlerChain(httpadmin.NewSpecHandler(), s.options.TLS.Enabled))
+	s.mux.Handle("/logspec", s.handlerChain("/logspec", httpadmin.NewSpecHandler(), s.options.TLS.Enabled))
 }
 
 func (s *System) initializeHealthCheckHandler() {
 	s.healthHandler = healthz.NewHealthHandler()
-	s.mux.Handle("/healthz", s.handlerChain(s.healthHandler, false))
+	s.mux.Handle("/healthz", s.handlerChain("/healthz", s.healthHandler, false))
 }
 
 func (s *System) initializeVersionInfoHandler() {
@@ -208,7 +226,7 @@ func (s *System) initializeVersionInfoHandler() {
 		CommitSHA: metadata.CommitSHA,
 		Version:   metadata.Version,
 	}
-	s.mux.Handle("/version", s.handlerChain(versionInfo, false))
+	s.mux.Handle("/version", s.handlerChain("/version", versionInfo, false))
 }
 
 func (s *System) startMetricsTickers() error {
@@ -241,6 +259,13 @@ func (s *System) listen() (net.Listener, error) {
 	if err != nil {
 		return nil, err
 	}
+	gmtlsConfig, err := s.options.TLS.GMTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if gmtlsConfig != nil {
+		return gmtls.NewListener(listener, gmtlsConfig), nil
+	}
 	tlsConfig, err := s.options.TLS.Config()
 	if err != nil {
 		return nil, err
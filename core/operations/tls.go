@@ -7,11 +7,17 @@ SPDX-License-Identifier: Apache-2.0
 package operations
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/pem"
 	"io/ioutil"
+	"net"
+	"net/http"
 
 	"github.com/hyperledger/fabric/internal/pkg/comm"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 )
 
 type TLS struct {
@@ -20,6 +26,19 @@ type TLS struct {
 	KeyFile            string
 	ClientCertRequired bool
 	ClientCACertFiles  []string
+	// GMTLS serves GMTLS (GM/T 0024) rather than TLS 1.2, with CertFile and
+	// KeyFile as the SM2 signing certificate and key
+	GMTLS bool
+	// EncCertFile and EncKeyFile are the SM2 encryption certificate and
+	// key of GMTLS
+	EncCertFile string
+	EncKeyFile  string
+	// SMTLS13 serves TLS 1.3 with the SM cipher suites of RFC 8998 rather
+	// than TLS 1.2, with CertFile and KeyFile as the SM2 certificate and key
+	SMTLS13 bool
+	// SMCipherSuites are the TLS 1.3 SM cipher suites, in order of
+	// preference; both when empty
+	SMCipherSuites []uint16
 }
 
 func (t TLS) Config() (*tls.Config, error) {
@@ -52,3 +71,96 @@ func (t TLS) Config() (*tls.Config, error) {
 
 	return tlsConfig, nil
 }
+
+// GMTLSConfig returns the configuration of the GMTLS or TLS 1.3 SM listener
+// of the operations server, or nil when it serves neither.
+func (t TLS) GMTLSConfig() (*gmtls.Config, error) {
+	if !t.Enabled || (!t.GMTLS && !t.SMTLS13) {
+		return nil, nil
+	}
+
+	sign, err := loadGMTLSKeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	caCertPool := gmx509.NewCertPool()
+	for _, caPath := range t.ClientCACertFiles {
+		caPem, err := ioutil.ReadFile(caPath)
+		if err != nil {
+			return nil, err
+		}
+		for block, rest := pem.Decode(caPem); block != nil; block, rest = pem.Decode(rest) {
+			if cert, err := gmx509.ParseCertificate(block.Bytes); err == nil {
+				caCertPool.AddCert(cert)
+			}
+		}
+	}
+	gmtlsConfig := &gmtls.Config{
+		SignCertificate: sign,
+		ClientCAs:       caCertPool,
+		Version:         gmtls.VersionGMTLS,
+	}
+	if t.SMTLS13 {
+		gmtlsConfig.Version = gmtls.VersionTLS13
+		gmtlsConfig.CipherSuites = t.SMCipherSuites
+	} else {
+		gmtlsConfig.EncCertificate, err = loadGMTLSKeyPair(t.EncCertFile, t.EncKeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.ClientCertRequired {
+		gmtlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		gmtlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return gmtlsConfig, nil
+}
+
+func loadGMTLSKeyPair(certFile, keyFile string) (*gmtls.Certificate, error) {
+	certPem, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	keyPem, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := gmtls.X509KeyPair(certPem, keyPem)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+type gmtlsConnKey struct{}
+
+// withGMTLSConn adds the GMTLS or TLS 1.3 SM connection c to the context of
+// the requests received over it.
+func withGMTLSConn(ctx context.Context, c net.Conn) context.Context {
+	if conn, ok := c.(*gmtls.Conn); ok {
+		return context.WithValue(ctx, gmtlsConnKey{}, conn)
+	}
+	return ctx
+}
+
+// gmtlsState sets the TLS connection state of the requests received over
+// GMTLS or TLS 1.3 SM, which net/http only sets for crypto/tls connections,
+// so that client certificates are checked alike.
+func gmtlsState(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if conn, ok := req.Context().Value(gmtlsConnKey{}).(*gmtls.Conn); ok && req.TLS == nil {
+			state := conn.ConnectionState()
+			req.TLS = &tls.ConnectionState{
+				Version:           state.Version,
+				HandshakeComplete: state.HandshakeComplete,
+				CipherSuite:       state.CipherSuite,
+				ServerName:        state.ServerName,
+				PeerCertificates:  state.PeerCertificates,
+				VerifiedChains:    state.VerifiedChains,
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
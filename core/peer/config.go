@@ -72,6 +72,15 @@ type Config struct {
 	// transaction validation in parallel. If omitted, it defaults to number of
 	// hardware threads on the machine.
 	ValidatorPoolSize int
+	// ValidatorSignaturePoolSize indicates the number of goroutines that will
+	// verify the signatures of the signature sets evaluated against
+	// policies, such as endorsements, in parallel. If omitted, it defaults to
+	// number of hardware threads on the machine.
+	ValidatorSignaturePoolSize int
+	// ValidatorSignatureBatchSize is the number of signers whose signatures
+	// are verified one after the other by a goroutine of the signature pool,
+	// SM2 signers excepted. If omitted, policies.DefaultVerifierBatchSize.
+	ValidatorSignatureBatchSize int
 
 	// ----- Peer Delivery Client Keepalive -----
 	// DeliveryClient Keepalive settings for communication with ordering nodes.
@@ -176,6 +185,24 @@ type Config struct {
 	// OperationsTLSClientRootCAs provides the path to PEM encoded ca certiricates to
 	// trust for client authentication.
 	OperationsTLSClientRootCAs []string
+	// OperationsTLSGMTLSEnabled serves GMTLS (GM/T 0024) rather than TLS 1.2 on the
+	// operations server, with the SM2 signing certificate and key.
+	OperationsTLSGMTLSEnabled bool
+	// OperationsTLSEncCertFile provides the path to PEM encoded SM2 encryption
+	// certificate of GMTLS for the operations server.
+	OperationsTLSEncCertFile string
+	// OperationsTLSEncKeyFile provides the path to PEM encoded SM2 encryption key
+	// of GMTLS for the operations server.
+	OperationsTLSEncKeyFile string
+	// OperationsTLSSMTLS13Enabled serves TLS 1.3 with the SM cipher suites of RFC
+	// 8998 rather than TLS 1.2 on the operations server.
+	OperationsTLSSMTLS13Enabled bool
+	// OperationsTLSSMCipherSuites are the TLS 1.3 SM cipher suites of the
+	// operations server.
+	OperationsTLSSMCipherSuites []uint16
+	// OperationsPolicies maps the endpoints of the operations server to the
+	// policies the client certificates of their requests must satisfy.
+	OperationsPolicies map[string]string
 
 	// ----- Metrics config -----
 	// TODO: create separate sub-struct for Metrics config.
@@ -252,6 +279,11 @@ func (c *Config) load() error {
 	if c.ValidatorPoolSize <= 0 {
 		c.ValidatorPoolSize = runtime.NumCPU()
 	}
+	c.ValidatorSignaturePoolSize = viper.GetInt("peer.validatorSignaturePoolSize")
+	if c.ValidatorSignaturePoolSize <= 0 {
+		c.ValidatorSignaturePoolSize = runtime.NumCPU()
+	}
+	c.ValidatorSignatureBatchSize = viper.GetInt("peer.validatorSignatureBatchSize")
 
 	c.DeliverClientKeepaliveOptions = comm.DefaultKeepaliveOptions
 	if viper.IsSet("peer.keepalive.deliveryClient.interval") {
@@ -295,6 +327,17 @@ func (c *Config) load() error {
 	for _, rca := range viper.GetStringSlice("operations.tls.clientRootCAs.files") {
 		c.OperationsTLSClientRootCAs = append(c.OperationsTLSClientRootCAs, config.TranslatePath(configDir, rca))
 	}
+	c.OperationsTLSGMTLSEnabled = viper.GetBool("operations.tls.gmtls.enabled")
+	c.OperationsTLSEncCertFile = config.GetPath("operations.tls.gmtls.encCert.file")
+	c.OperationsTLSEncKeyFile = config.GetPath("operations.tls.gmtls.encKey.file")
+	c.OperationsTLSSMTLS13Enabled = viper.GetBool("operations.tls.smtls13.enabled")
+	c.OperationsTLSSMCipherSuites, err = comm.SMCipherSuites(viper.GetStringSlice("operations.tls.smtls13.cipherSuites"))
+	if err != nil {
+		return fmt.Errorf("invalid operations TLS 1.3 SM cipher suites: %s", err)
+	}
+	if policies := viper.GetStringMapString("operations.authorization.policies"); len(policies) > 0 {
+		c.OperationsPolicies = policies
+	}
 
 	c.MetricsProvider = viper.GetString("metrics.provider")
 	c.StatsdNetwork = viper.GetString("metrics.statsd.network")
@@ -363,6 +406,41 @@ func GetServerConfig() (comm.ServerConfig, error) {
 		}
 		serverConfig.SecOpts.Certificate = serverCert
 		serverConfig.SecOpts.Key = serverKey
+		serverConfig.SecOpts.UseGMTLS = viper.GetBool("peer.tls.gmtls.enabled")
+		if serverConfig.SecOpts.UseGMTLS {
+			encKey, err := ioutil.ReadFile(config.GetPath("peer.tls.gmtls.encKey.file"))
+			if err != nil {
+				return serverConfig, fmt.Errorf("error loading GMTLS encryption key (%s)", err)
+			}
+			encCert, err := ioutil.ReadFile(config.GetPath("peer.tls.gmtls.encCert.file"))
+			if err != nil {
+				return serverConfig, fmt.Errorf("error loading GMTLS encryption certificate (%s)", err)
+			}
+			serverConfig.SecOpts.EncCertificate = encCert
+			serverConfig.SecOpts.EncKey = encKey
+		}
+		serverConfig.SecOpts.UseSMTLS13 = viper.GetBool("peer.tls.smtls13.enabled")
+		if serverConfig.SecOpts.UseSMTLS13 {
+			cipherSuites, err := comm.SMCipherSuites(viper.GetStringSlice("peer.tls.smtls13.cipherSuites"))
+			if err != nil {
+				return serverConfig, fmt.Errorf("error loading TLS 1.3 SM cipher suites (%s)", err)
+			}
+			serverConfig.SecOpts.SMCipherSuites = cipherSuites
+		}
+		serverConfig.SecOpts.DualStack = viper.GetBool("peer.tls.dualStack.enabled")
+		if serverConfig.SecOpts.DualStack {
+			standardKey, err := ioutil.ReadFile(config.GetPath("peer.tls.dualStack.key.file"))
+			if err != nil {
+				return serverConfig, fmt.Errorf("error loading dual-stack TLS key (%s)", err)
+			}
+			standardCert, err := ioutil.ReadFile(config.GetPath("peer.tls.dualStack.cert.file"))
+			if err != nil {
+				return serverConfig, fmt.Errorf("error loading dual-stack TLS certificate (%s)", err)
+			}
+			serverConfig.SecOpts.StandardCertificate = standardCert
+			serverConfig.SecOpts.StandardKey = standardKey
+		}
+		serverConfig.SecOpts.PinnedCertificates = viper.GetStringSlice("peer.tls.pinnedCertificates")
 		serverConfig.SecOpts.RequireClientCert = viper.GetBool("peer.tls.clientAuthRequired")
 		if serverConfig.SecOpts.RequireClientCert {
 			var clientRoots [][]byte
@@ -257,6 +257,8 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.chaincodeListenAddress", "0.0.0.0:7052")
 	viper.Set("peer.chaincodeAddress", "0.0.0.0:7052")
 	viper.Set("peer.validatorPoolSize", 1)
+	viper.Set("peer.validatorSignaturePoolSize", 2)
+	viper.Set("peer.validatorSignatureBatchSize", 8)
 
 	viper.Set("vm.endpoint", "unix:///var/run/docker.sock")
 	viper.Set("vm.docker.tls.enabled", false)
@@ -314,6 +316,8 @@ func TestGlobalConfig(t *testing.T) {
 		ChaincodeListenAddress:                "0.0.0.0:7052",
 		ChaincodeAddress:                      "0.0.0.0:7052",
 		ValidatorPoolSize:                     1,
+		ValidatorSignaturePoolSize:            2,
+		ValidatorSignatureBatchSize:           8,
 		DeliverClientKeepaliveOptions:         comm.DefaultKeepaliveOptions,
 
 		VMEndpoint:           "unix:///var/run/docker.sock",
@@ -367,6 +371,7 @@ func TestGlobalConfigDefault(t *testing.T) {
 		AuthenticationTimeWindow:      15 * time.Minute,
 		PeerAddress:                   "localhost:8080",
 		ValidatorPoolSize:             runtime.NumCPU(),
+		ValidatorSignaturePoolSize:    runtime.NumCPU(),
 		VMNetworkMode:                 "host",
 		DeliverClientKeepaliveOptions: comm.DefaultKeepaliveOptions,
 	}
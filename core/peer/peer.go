@@ -460,6 +460,15 @@ func (p *Peer) GetPolicyManager(cid string) policies.Manager {
 	return nil
 }
 
+// GetHashingAlgorithm returns the hashing algorithm of the blocks of the channel with channel ID.
+// Note that this call returns nil if channel cid has not been created.
+func (p *Peer) GetHashingAlgorithm(cid string) func([]byte) []byte {
+	if c := p.Channel(cid); c != nil {
+		return channelconfig.BlockHashingAlgorithm(c.Resources().ChannelConfig())
+	}
+	return nil
+}
+
 // initChannel takes care to initialize channel after peer joined, for example deploys system CCs
 func (p *Peer) initChannel(cid string) {
 	if p.channelInitializer != nil {
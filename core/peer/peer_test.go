@@ -58,7 +58,7 @@ func NewTestPeer(t *testing.T) (*Peer, func()) {
 	assert.NoError(t, err)
 	signer := mgmt.GetLocalSigningIdentityOrPanic(cryptoProvider)
 
-	messageCryptoService := peergossip.NewMCS(&mocks.ChannelPolicyManagerGetter{}, signer, mgmt.NewDeserializersManager(cryptoProvider), cryptoProvider)
+	messageCryptoService := peergossip.NewMCS(&mocks.ChannelPolicyManagerGetter{}, signer, mgmt.NewDeserializersManager(cryptoProvider), cryptoProvider, nil, nil)
 	secAdv := peergossip.NewSecurityAdvisor(mgmt.NewDeserializersManager(cryptoProvider))
 	defaultSecureDialOpts := func() []grpc.DialOption { return []grpc.DialOption{grpc.WithInsecure()} }
 	defaultDeliverClientDialOpts := []grpc.DialOption{grpc.WithBlock()}
@@ -90,6 +90,7 @@ func NewTestPeer(t *testing.T) (*Peer, func()) {
 			ReConnectBackoffThreshold:   deliverservice.DefaultReConnectBackoffThreshold,
 			ReconnectTotalTimeThreshold: deliverservice.DefaultReConnectTotalTimeThreshold,
 		},
+		nil,
 	)
 	require.NoError(t, err, "failed to create gossip service")
 
@@ -212,6 +213,16 @@ func TestCreateChannel(t *testing.T) {
 		t.Fatal("got a bogus PolicyManager")
 	}
 
+	// Correct hashing algorithm
+	if peerInstance.GetHashingAlgorithm(testChainID) == nil {
+		t.Fatal("failed to get the hashing algorithm")
+	}
+
+	// Bad hashing algorithm
+	if peerInstance.GetHashingAlgorithm("BogusChain") != nil {
+		t.Fatal("got a bogus hashing algorithm")
+	}
+
 	channels := peerInstance.GetChannelsInfo()
 	if len(channels) != 1 {
 		t.Fatalf("incorrect number of channels")
@@ -70,4 +70,7 @@ type Peer struct {
 	AliveMessage     *protoext.SignedGossipMessage
 	StateInfoMessage *protoext.SignedGossipMessage
 	Identity         []byte
+	// SignatureAlgorithm is the algorithm of the certificate of the peer,
+	// AlgorithmSM2 or AlgorithmECDSA, or empty if it is not known
+	SignatureAlgorithm string
 }
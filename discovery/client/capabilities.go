@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"crypto/ecdsa"
+	"encoding/pem"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/discovery"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// Algorithms of the certificates of peers and orderers, as reported in
+// Peer.SignatureAlgorithm and in CryptoCapabilities.
+const (
+	AlgorithmSM2   = "SM2"
+	AlgorithmECDSA = "ECDSA"
+)
+
+// CryptoCapabilities describes the algorithms the nodes of an organization
+// sign and secure their connections with, so that clients can prefer the
+// nodes matching their own crypto stack while a network migrates from ECDSA
+// to SM2.
+type CryptoCapabilities struct {
+	// SignatureAlgorithms lists the algorithms of the CA certificates
+	// the identities of the organization are issued by.
+	SignatureAlgorithms []string `json:",omitempty"`
+	// TLSAlgorithms lists the algorithms of the TLS CA certificates of
+	// the organization. Nodes with SM2 TLS certificates serve GMTLS.
+	TLSAlgorithms []string `json:",omitempty"`
+}
+
+// OrgCapabilities returns the crypto capabilities of the organizations of
+// a channel, by MSP ID, as derived from the MSPs of the config result. The
+// orderer organizations are the ones of config.Orderers. Organizations
+// whose certificates are of no known algorithm are left out.
+func OrgCapabilities(config *discovery.ConfigResult) map[string]*CryptoCapabilities {
+	res := make(map[string]*CryptoCapabilities)
+	for mspID, conf := range config.GetMsps() {
+		capabilities := &CryptoCapabilities{
+			SignatureAlgorithms: certAlgorithms(conf.GetRootCerts(), conf.GetIntermediateCerts()),
+			TLSAlgorithms:       certAlgorithms(conf.GetTlsRootCerts(), conf.GetTlsIntermediateCerts()),
+		}
+		if len(capabilities.SignatureAlgorithms) == 0 && len(capabilities.TLSAlgorithms) == 0 {
+			continue
+		}
+		res[mspID] = capabilities
+	}
+	return res
+}
+
+// IdentityAlgorithm returns the algorithm of the certificate of the given
+// serialized identity, or an empty string if it is not a certificate of
+// a known algorithm.
+func IdentityAlgorithm(identity []byte) string {
+	sID := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(identity, sID); err != nil {
+		return ""
+	}
+	return certAlgorithm(sID.IdBytes)
+}
+
+func certAlgorithms(pemCerts ...[][]byte) []string {
+	algorithms := make(map[string]struct{})
+	for _, certs := range pemCerts {
+		for _, cert := range certs {
+			if alg := certAlgorithm(cert); alg != "" {
+				algorithms[alg] = struct{}{}
+			}
+		}
+	}
+	var res []string
+	for alg := range algorithms {
+		res = append(res, alg)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func certAlgorithm(pemCert []byte) string {
+	block, _ := pem.Decode(pemCert)
+	if block == nil {
+		return ""
+	}
+	cert, err := gmx509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return ""
+	}
+	switch cert.PublicKey.(type) {
+	case *sm2.PublicKey:
+		return AlgorithmSM2
+	case *ecdsa.PublicKey:
+		return AlgorithmECDSA
+	default:
+		return ""
+	}
+}
+
+// PrioritiesBySignatureAlgorithm returns a PrioritySelector that selects
+// peers signing with the given algorithm over the other peers, and peers
+// of the same algorithm by descending height.
+func PrioritiesBySignatureAlgorithm(algorithm string) PrioritySelector {
+	return &bySignatureAlgorithm{algorithm: algorithm}
+}
+
+type bySignatureAlgorithm struct {
+	algorithm string
+}
+
+func (ba *bySignatureAlgorithm) Compare(left Peer, right Peer) Priority {
+	leftMatches := left.SignatureAlgorithm == ba.algorithm
+	rightMatches := right.SignatureAlgorithm == ba.algorithm
+	if leftMatches && !rightMatches {
+		return 1
+	}
+	if rightMatches && !leftMatches {
+		return -1
+	}
+	return PrioritiesByHeight.Compare(left, right)
+}
+
+// ExcludeSignatureAlgorithms returns an ExclusionFilter that excludes the
+// peers signing with the given algorithms.
+func ExcludeSignatureAlgorithms(algorithms ...string) ExclusionFilter {
+	m := make(map[string]struct{})
+	for _, alg := range algorithms {
+		m[alg] = struct{}{}
+	}
+	return selectionFunc(func(p Peer) bool {
+		_, excluded := m[p.SignatureAlgorithm]
+		return excluded
+	})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/discovery"
+	"github.com/hyperledger/fabric-protos-go/gossip"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	gprotoext "github.com/hyperledger/fabric/gossip/protoext"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfSignedCert returns a PEM encoded certificate of an SM2 or ECDSA key
+func selfSignedCert(t *testing.T, algorithm string) []byte {
+	var pub, priv interface{}
+	switch algorithm {
+	case AlgorithmSM2:
+		key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+		require.NoError(t, err)
+		pub, priv = &key.PublicKey, key
+	case AlgorithmECDSA:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		pub, priv = &key.PublicKey, key
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ca"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func serializedIdentity(t *testing.T, mspID string, idBytes []byte) []byte {
+	b, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: idBytes})
+	require.NoError(t, err)
+	return b
+}
+
+func TestIdentityAlgorithm(t *testing.T) {
+	assert.Equal(t, AlgorithmSM2, IdentityAlgorithm(serializedIdentity(t, "Org1MSP", selfSignedCert(t, AlgorithmSM2))))
+	assert.Equal(t, AlgorithmECDSA, IdentityAlgorithm(serializedIdentity(t, "Org1MSP", selfSignedCert(t, AlgorithmECDSA))))
+	assert.Empty(t, IdentityAlgorithm(serializedIdentity(t, "Org1MSP", []byte("p0"))))
+	assert.Empty(t, IdentityAlgorithm([]byte{1, 2, 3}))
+}
+
+func TestOrgCapabilities(t *testing.T) {
+	sm2CA := selfSignedCert(t, AlgorithmSM2)
+	ecdsaCA := selfSignedCert(t, AlgorithmECDSA)
+
+	config := &discovery.ConfigResult{
+		Msps: map[string]*msp.FabricMSPConfig{
+			// An organization migrating to SM2 identities, still serving TLS with ECDSA
+			"Org1MSP": {
+				RootCerts:         [][]byte{ecdsaCA},
+				IntermediateCerts: [][]byte{sm2CA},
+				TlsRootCerts:      [][]byte{ecdsaCA},
+			},
+			"OrdererMSP": {
+				RootCerts:    [][]byte{sm2CA},
+				TlsRootCerts: [][]byte{sm2CA},
+			},
+			"Org2MSP": nil,
+		},
+	}
+
+	assert.Equal(t, map[string]*CryptoCapabilities{
+		"Org1MSP": {
+			SignatureAlgorithms: []string{AlgorithmECDSA, AlgorithmSM2},
+			TLSAlgorithms:       []string{AlgorithmECDSA},
+		},
+		"OrdererMSP": {
+			SignatureAlgorithms: []string{AlgorithmSM2},
+			TLSAlgorithms:       []string{AlgorithmSM2},
+		},
+	}, OrgCapabilities(config))
+}
+
+func TestSelectionBySignatureAlgorithm(t *testing.T) {
+	peer := func(algorithm string, height uint64) *Peer {
+		stateInfo := &gprotoext.SignedGossipMessage{
+			GossipMessage: &gossip.GossipMessage{
+				Content: &gossip.GossipMessage_StateInfo{
+					StateInfo: &gossip.StateInfo{
+						Properties: &gossip.Properties{LedgerHeight: height},
+					},
+				},
+			},
+		}
+		return &Peer{SignatureAlgorithm: algorithm, StateInfoMessage: stateInfo}
+	}
+
+	p1 := peer(AlgorithmECDSA, 20)
+	p2 := peer(AlgorithmSM2, 10)
+	p3 := peer(AlgorithmSM2, 15)
+	p4 := peer("", 30)
+
+	assert.Equal(t, Endorsers{p2, p3}, Endorsers{p1, p2, p3, p4}.Filter(ExcludeSignatureAlgorithms(AlgorithmECDSA, "")))
+	assert.Equal(t, Endorsers{p3, p2, p4, p1}, Endorsers{p1, p2, p3, p4}.Sort(PrioritiesBySignatureAlgorithm(AlgorithmSM2)))
+}
@@ -429,10 +429,11 @@ func peersForChannel(membersRes *discovery.PeerMembershipResult, qt protoext.Que
 				return nil, errors.Wrap(err, "failed validating alive message")
 			}
 			peers = append(peers, &Peer{
-				MSPID:            org,
-				Identity:         peer.Identity,
-				AliveMessage:     aliveMsg,
-				StateInfoMessage: stateInfoMsg,
+				MSPID:              org,
+				Identity:           peer.Identity,
+				AliveMessage:       aliveMsg,
+				StateInfoMessage:   stateInfoMsg,
+				SignatureAlgorithm: IdentityAlgorithm(peer.Identity),
 			})
 		}
 	}
@@ -548,10 +549,11 @@ func endorser(peer *discovery.Peer, chaincode, channel string) (*Peer, error) {
 		return nil, errors.Wrap(err, "failed unmarshaling peer's identity")
 	}
 	return &Peer{
-		Identity:         peer.Identity,
-		StateInfoMessage: stateInfMsg,
-		AliveMessage:     aliveMsg,
-		MSPID:            sID.Mspid,
+		Identity:           peer.Identity,
+		StateInfoMessage:   stateInfMsg,
+		AliveMessage:       aliveMsg,
+		MSPID:              sID.Mspid,
+		SignatureAlgorithm: certAlgorithm(sID.IdBytes),
 	}, nil
 }
 
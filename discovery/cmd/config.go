@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 
+	discprotos "github.com/hyperledger/fabric-protos-go/discovery"
 	"github.com/hyperledger/fabric/cmd/common"
 	discovery "github.com/hyperledger/fabric/discovery/client"
 	"github.com/pkg/errors"
@@ -73,7 +74,17 @@ func (parser *ConfigResponseParser) ParseResponse(channel string, res ServiceRes
 	if err != nil {
 		return err
 	}
-	jsonBytes, _ := json.MarshalIndent(chanConf, "", "\t")
+	jsonBytes, _ := json.MarshalIndent(&channelConfig{
+		ConfigResult: chanConf,
+		Capabilities: discovery.OrgCapabilities(chanConf),
+	}, "", "\t")
 	fmt.Fprintln(parser.Writer, string(jsonBytes))
 	return nil
 }
+
+// channelConfig is the config of a channel, along with the crypto
+// capabilities of its organizations
+type channelConfig struct {
+	*discprotos.ConfigResult
+	Capabilities map[string]*discovery.CryptoCapabilities `json:"capabilities,omitempty"`
+}
@@ -219,10 +219,11 @@ func parseEndorsementDescriptors(descriptors []*EndorsementDescriptor) []endorse
 }
 
 type endorser struct {
-	MSPID        string
-	LedgerHeight uint64
-	Endpoint     string
-	Identity     string
+	MSPID              string
+	LedgerHeight       uint64
+	Endpoint           string
+	Identity           string
+	SignatureAlgorithm string `json:",omitempty"`
 }
 
 type endorsermentDescriptor struct {
@@ -235,10 +236,11 @@ func endorserFromRaw(p *Peer) endorser {
 	sId := &msp.SerializedIdentity{}
 	proto.Unmarshal(p.Identity, sId)
 	return endorser{
-		MSPID:        sId.Mspid,
-		Endpoint:     endpointFromEnvelope(p.MembershipInfo),
-		LedgerHeight: ledgerHeightFromEnvelope(p.StateInfo),
-		Identity:     string(sId.IdBytes),
+		MSPID:              sId.Mspid,
+		Endpoint:           endpointFromEnvelope(p.MembershipInfo),
+		LedgerHeight:       ledgerHeightFromEnvelope(p.StateInfo),
+		Identity:           string(sId.IdBytes),
+		SignatureAlgorithm: discovery.IdentityAlgorithm(p.Identity),
 	}
 }
 
@@ -112,17 +112,19 @@ func assemblePeers(peers []*discovery.Peer, withChannelState bool) interface{} {
 }
 
 type channelPeer struct {
-	MSPID        string
-	LedgerHeight uint64
-	Endpoint     string
-	Identity     string
-	Chaincodes   []string
+	MSPID              string
+	LedgerHeight       uint64
+	Endpoint           string
+	Identity           string
+	SignatureAlgorithm string `json:",omitempty"`
+	Chaincodes         []string
 }
 
 type localPeer struct {
-	MSPID    string
-	Endpoint string
-	Identity string
+	MSPID              string
+	Endpoint           string
+	Identity           string
+	SignatureAlgorithm string `json:",omitempty"`
 }
 
 type peerLister interface {
@@ -157,11 +159,12 @@ func rawPeerToChannelPeer(p *discovery.Peer) channelPeer {
 	sID := &msp.SerializedIdentity{}
 	proto.Unmarshal(p.Identity, sID)
 	return channelPeer{
-		MSPID:        p.MSPID,
-		Endpoint:     endpoint,
-		LedgerHeight: ledgerHeight,
-		Identity:     string(sID.IdBytes),
-		Chaincodes:   ccs,
+		MSPID:              p.MSPID,
+		Endpoint:           endpoint,
+		LedgerHeight:       ledgerHeight,
+		Identity:           string(sID.IdBytes),
+		SignatureAlgorithm: p.SignatureAlgorithm,
+		Chaincodes:         ccs,
 	}
 }
 
@@ -173,8 +176,9 @@ func rawPeerToLocalPeer(p *discovery.Peer) localPeer {
 	sID := &msp.SerializedIdentity{}
 	proto.Unmarshal(p.Identity, sID)
 	return localPeer{
-		MSPID:    p.MSPID,
-		Endpoint: endpoint,
-		Identity: string(sID.IdBytes),
+		MSPID:              p.MSPID,
+		Endpoint:           endpoint,
+		Identity:           string(sID.IdBytes),
+		SignatureAlgorithm: p.SignatureAlgorithm,
 	}
 }
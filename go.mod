@@ -4,6 +4,10 @@ go 1.14
 
 replace github.com/paul-lee-attorney/fabric-2.1-gm => ./
 
+replace github.com/hyperledger/fabric => ./
+
+replace github.com/paul-lee-attorney/fabric-2.1-gm/bccsp => ./bccsp
+
 replace github.com/paul-lee-attorney/gm => ./../gm
 
 require (
@@ -35,12 +39,16 @@ require (
 	github.com/spf13/cobra v1.1.1
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
 	github.com/sykesm/zap-logfmt v0.0.4
 	github.com/syndtr/goleveldb v1.0.0
 	github.com/tedsuo/ifrit v0.0.0-20191009134036-9a97d0632f00
 	github.com/willf/bitset v1.1.11
 	go.etcd.io/etcd v3.3.25+incompatible
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/exporters/jaeger v1.0.0
+	go.opentelemetry.io/otel/sdk v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
 	go.uber.org/zap v1.16.0
 	golang.org/x/tools v0.0.0-20201028224754-2c115999a7f0
 	google.golang.org/grpc v1.33.1
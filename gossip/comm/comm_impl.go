@@ -62,27 +62,28 @@ func NewCommInstance(s *grpc.Server, certs *common.TLSCertificates, idStore iden
 	commMetrics *metrics.CommMetrics, config CommConfig, dialOpts ...grpc.DialOption) (Comm, error) {
 
 	commInst := &commImpl{
-		sa:              sa,
-		pubSub:          util.NewPubSub(),
-		PKIID:           idStore.GetPKIidOfCert(peerIdentity),
-		idMapper:        idStore,
-		logger:          util.GetLogger(util.CommLogger, ""),
-		peerIdentity:    peerIdentity,
-		opts:            dialOpts,
-		secureDialOpts:  secureDialOpts,
-		msgPublisher:    NewChannelDemultiplexer(),
-		lock:            &sync.Mutex{},
-		deadEndpoints:   make(chan common.PKIidType, 100),
-		identityChanges: make(chan common.PKIidType, 1),
-		stopping:        int32(0),
-		exitChan:        make(chan struct{}),
-		subscriptions:   make([]chan protoext.ReceivedMessage, 0),
-		tlsCerts:        certs,
-		metrics:         commMetrics,
-		dialTimeout:     config.DialTimeout,
-		connTimeout:     config.ConnTimeout,
-		recvBuffSize:    config.RecvBuffSize,
-		sendBuffSize:    config.SendBuffSize,
+		sa:               sa,
+		pubSub:           util.NewPubSub(),
+		PKIID:            idStore.GetPKIidOfCert(peerIdentity),
+		idMapper:         idStore,
+		logger:           util.GetLogger(util.CommLogger, ""),
+		peerIdentity:     peerIdentity,
+		opts:             dialOpts,
+		secureDialOpts:   secureDialOpts,
+		msgPublisher:     NewChannelDemultiplexer(),
+		lock:             &sync.Mutex{},
+		deadEndpoints:    make(chan common.PKIidType, 100),
+		identityChanges:  make(chan common.PKIidType, 1),
+		stopping:         int32(0),
+		exitChan:         make(chan struct{}),
+		subscriptions:    make([]chan protoext.ReceivedMessage, 0),
+		tlsCerts:         certs,
+		metrics:          commMetrics,
+		dialTimeout:      config.DialTimeout,
+		connTimeout:      config.ConnTimeout,
+		recvBuffSize:     config.RecvBuffSize,
+		sendBuffSize:     config.SendBuffSize,
+		hashingAlgorithm: config.HashingAlgorithm,
 	}
 
 	connConfig := ConnConfig{
@@ -103,6 +104,9 @@ type CommConfig struct {
 	ConnTimeout  time.Duration // Connection timeout
 	RecvBuffSize int           // Buffer size of received messages
 	SendBuffSize int           // Buffer size of sending messages
+	// HashingAlgorithm hashes the TLS certificates of the handshake,
+	// SHA-256 when nil
+	HashingAlgorithm func(data []byte) []byte
 }
 
 type commImpl struct {
@@ -129,6 +133,8 @@ type commImpl struct {
 	connTimeout     time.Duration
 	recvBuffSize    int
 	sendBuffSize    int
+	// hashingAlgorithm hashes the TLS certificates of the handshake
+	hashingAlgorithm func(data []byte) []byte
 }
 
 func (c *commImpl) createConnection(endpoint string, expectedPKIID common.PKIidType) (*connection, error) {
@@ -411,7 +417,7 @@ func extractRemoteAddress(stream stream) string {
 func (c *commImpl) authenticateRemotePeer(stream stream, initiator, isProbe bool) (*protoext.ConnectionInfo, error) {
 	ctx := stream.Context()
 	remoteAddress := extractRemoteAddress(stream)
-	remoteCertHash := extractCertificateHashFromContext(ctx)
+	remoteCertHash := extractCertificateHashWith(ctx, c.hashingAlgorithm)
 	var err error
 	var cMsg *protoext.SignedGossipMessage
 	useTLS := c.tlsCerts != nil
@@ -422,7 +428,7 @@ func (c *commImpl) authenticateRemotePeer(stream stream, initiator, isProbe bool
 		if initiator {
 			certReference = c.tlsCerts.TLSClientCert
 		}
-		selfCertHash = certHashFromRawCert(certReference.Load().(*tls.Certificate).Certificate[0])
+		selfCertHash = certHashWith(certReference.Load().(*tls.Certificate).Certificate[0], c.hashingAlgorithm)
 	}
 
 	signer := func(msg []byte) ([]byte, error) {
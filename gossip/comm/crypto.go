@@ -15,14 +15,29 @@ import (
 )
 
 func certHashFromRawCert(rawCert []byte) []byte {
+	return certHashWith(rawCert, nil)
+}
+
+// certHashWith returns the hash of the certificate with hash, the hashing
+// algorithm of gossip, or with SHA-256 when hash is nil
+func certHashWith(rawCert []byte, hash func([]byte) []byte) []byte {
 	if len(rawCert) == 0 {
 		return nil
 	}
-	return util.ComputeSHA256(rawCert)
+	if hash == nil {
+		return util.ComputeSHA256(rawCert)
+	}
+	return hash(rawCert)
 }
 
 // ExtractCertificateHash extracts the hash of the certificate from the stream
 func extractCertificateHashFromContext(ctx context.Context) []byte {
+	return extractCertificateHashWith(ctx, nil)
+}
+
+// extractCertificateHashWith extracts the hash, computed with hash, of the
+// certificate from the stream
+func extractCertificateHashWith(ctx context.Context, hash func([]byte) []byte) []byte {
 	pr, extracted := peer.FromContext(ctx)
 	if !extracted {
 		return nil
@@ -42,5 +57,5 @@ func extractCertificateHashFromContext(ctx context.Context) []byte {
 		return nil
 	}
 	raw := certs[0].Raw
-	return certHashFromRawCert(raw)
+	return certHashWith(raw, hash)
 }
@@ -50,6 +50,8 @@ type Config struct {
 	RequestWaitTime             time.Duration
 	ResponseWaitTime            time.Duration
 	MsgExpirationTimeout        time.Duration
+	// HashingAlgorithm computes the MACs of the channel, SHA-256 when nil
+	HashingAlgorithm func(data []byte) []byte
 }
 
 // GossipChannel defines an object that deals with all channel-related messages
@@ -744,7 +746,7 @@ func (gc *gossipChannel) handleStateInfSnapshot(m *proto.GossipMessage, sender c
 			return
 		}
 
-		expectedMAC := GenerateMAC(si.PkiId, gc.chainID)
+		expectedMAC := gc.generateMAC(si.PkiId)
 		if !bytes.Equal(si.Channel_MAC, expectedMAC) {
 			gc.logger.Warning("Channel", chanName, ": StateInfo message", stateInf,
 				", has an invalid MAC. Expected", expectedMAC, ", got", si.Channel_MAC, ", sent from", sender)
@@ -843,7 +845,7 @@ func (gc *gossipChannel) verifyMsg(msg protoext.ReceivedMessage) bool {
 
 	if protoext.IsStateInfoMsg(m.GossipMessage) {
 		si := m.GetStateInfo()
-		expectedMAC := GenerateMAC(si.PkiId, gc.chainID)
+		expectedMAC := gc.generateMAC(si.PkiId)
 		if !bytes.Equal(expectedMAC, si.Channel_MAC) {
 			gc.logger.Warning("Message contains wrong channel MAC(", si.Channel_MAC, "), expected", expectedMAC)
 			return false
@@ -853,7 +855,7 @@ func (gc *gossipChannel) verifyMsg(msg protoext.ReceivedMessage) bool {
 
 	if protoext.IsStateInfoPullRequestMsg(m.GossipMessage) {
 		sipr := m.GetStateInfoPullReq()
-		expectedMAC := GenerateMAC(msg.GetConnectionInfo().ID, gc.chainID)
+		expectedMAC := gc.generateMAC(msg.GetConnectionInfo().ID)
 		if !bytes.Equal(expectedMAC, sipr.Channel_MAC) {
 			gc.logger.Warning("Message contains wrong channel MAC(", sipr.Channel_MAC, "), expected", expectedMAC)
 			return false
@@ -874,7 +876,7 @@ func (gc *gossipChannel) createStateInfoRequest() (*protoext.SignedGossipMessage
 		Nonce: 0,
 		Content: &proto.GossipMessage_StateInfoPullReq{
 			StateInfoPullReq: &proto.StateInfoPullRequest{
-				Channel_MAC: GenerateMAC(gc.pkiID, gc.chainID),
+				Channel_MAC: gc.generateMAC(gc.pkiID),
 			},
 		},
 	})
@@ -921,7 +923,7 @@ func (gc *gossipChannel) updateStateInfo(msg *protoext.SignedGossipMessage) {
 
 func (gc *gossipChannel) updateProperties(ledgerHeight uint64, chaincodes []*proto.Chaincode, leftChannel bool) {
 	stateInfMsg := &proto.StateInfo{
-		Channel_MAC: GenerateMAC(gc.pkiID, gc.chainID),
+		Channel_MAC: gc.generateMAC(gc.pkiID),
 		PkiId:       gc.pkiID,
 		Timestamp: &proto.PeerTime{
 			IncNum: gc.incTime,
@@ -1034,11 +1036,25 @@ func (cache *stateInfoCache) Stop() {
 // GenerateMAC returns a byte slice that is derived from the peer's PKI-ID
 // and a channel name
 func GenerateMAC(pkiID common.PKIidType, channelID common.ChannelID) []byte {
+	return GenerateMACWith(pkiID, channelID, nil)
+}
+
+// GenerateMACWith returns the MAC of the peer's PKI-ID and the channel name
+// computed with hash, the hashing algorithm of gossip, or with SHA-256 when
+// hash is nil
+func GenerateMACWith(pkiID common.PKIidType, channelID common.ChannelID, hash func([]byte) []byte) []byte {
 	// Hash is computed on (PKI-ID || channel ID)
 	var preImage []byte
 	preImage = append(preImage, []byte(pkiID)...)
 	preImage = append(preImage, []byte(channelID)...)
-	return common_utils.ComputeSHA256(preImage)
+	if hash == nil {
+		return common_utils.ComputeSHA256(preImage)
+	}
+	return hash(preImage)
+}
+
+func (gc *gossipChannel) generateMAC(pkiID common.PKIidType) []byte {
+	return GenerateMACWith(pkiID, gc.chainID, gc.GetConf().HashingAlgorithm)
 }
 
 //membershipTracker is a struct for tracking changes in peers of the channel
@@ -293,6 +293,18 @@ func configureAdapter(adapter *gossipAdapterMock, members ...discovery.NetworkMe
 	adapter.On("GetOrgOfPeer", mock.Anything).Return(api.OrgIdentityType(nil))
 }
 
+func TestGenerateMACWith(t *testing.T) {
+	t.Parallel()
+
+	pkiID := common.PKIidType("pkiID")
+	hash := func(data []byte) []byte {
+		return append([]byte("hash:"), data...)
+	}
+	assert.Equal(t, GenerateMAC(pkiID, channelA), GenerateMACWith(pkiID, channelA, nil))
+	assert.Equal(t, []byte("hash:pkiIDA"), GenerateMACWith(pkiID, channelA, hash))
+	assert.NotEqual(t, GenerateMAC(pkiID, channelA), GenerateMACWith(pkiID, channelA, hash))
+}
+
 func TestBadInput(t *testing.T) {
 	cs := &cryptoService{}
 	adapter := new(gossipAdapterMock)
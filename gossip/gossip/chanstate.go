@@ -78,7 +78,7 @@ func (cs *channelState) getGossipChannelByMAC(receivedMAC []byte, pkiID common.P
 	cs.RLock()
 	defer cs.RUnlock()
 	for chanName, gc := range cs.channels {
-		mac := channel.GenerateMAC(pkiID, common.ChannelID(chanName))
+		mac := channel.GenerateMACWith(pkiID, common.ChannelID(chanName), cs.g.hashingAlgorithm)
 		if bytes.Equal(mac, receivedMAC) {
 			return gc
 		}
@@ -132,6 +132,7 @@ func (ga *gossipAdapterImpl) GetConf() channel.Config {
 		RequestWaitTime:             ga.conf.RequestWaitTime,
 		ResponseWaitTime:            ga.conf.ResponseWaitTime,
 		MsgExpirationTimeout:        ga.conf.MsgExpirationTimeout,
+		HashingAlgorithm:            ga.hashingAlgorithm,
 	}
 }
 
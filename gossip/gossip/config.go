@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"time"
 
+	commonutil "github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/gossip/comm"
 	"github.com/hyperledger/fabric/gossip/common"
 	"github.com/hyperledger/fabric/gossip/discovery"
@@ -93,6 +94,11 @@ type Config struct {
 	AliveExpirationCheckInterval time.Duration
 	// ReconnectInterval is the Reconnect interval.
 	ReconnectInterval time.Duration
+
+	// HashingAlgorithm is the hashing algorithm of the channel MACs and of the
+	// TLS certificates exchanged in handshakes, SHA256 when empty. All the
+	// peers of the network must use the same hashing algorithm.
+	HashingAlgorithm string
 }
 
 // GlobalConfig builds a Config from the given endpoint, certificate and bootstrap peers.
@@ -142,6 +148,19 @@ func (c *Config) loadConfig(endpoint string, certs *common.TLSCertificates, boot
 	c.AliveExpirationTimeout = util.GetDurationOrDefault("peer.gossip.aliveExpirationTimeout", 5*c.AliveTimeInterval)
 	c.AliveExpirationCheckInterval = c.AliveExpirationTimeout / 10
 	c.ReconnectInterval = util.GetDurationOrDefault("peer.gossip.reconnectInterval", c.AliveExpirationTimeout)
+	c.HashingAlgorithm = viper.GetString("peer.gossip.hashingAlgorithm")
+	if _, err := c.hashingAlgorithm(); err != nil {
+		return err
+	}
 
 	return nil
 }
+
+// hashingAlgorithm returns the hash function of the hashing algorithm of
+// gossip, computed by the default BCCSP, or nil for SHA-256.
+func (c *Config) hashingAlgorithm() (func([]byte) []byte, error) {
+	if c.HashingAlgorithm == "" {
+		return nil, nil
+	}
+	return commonutil.HashingAlgorithm(c.HashingAlgorithm)
+}
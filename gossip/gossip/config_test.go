@@ -55,6 +55,7 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.gossip.aliveTimeInterval", "20s")
 	viper.Set("peer.gossip.aliveExpirationTimeout", "21s")
 	viper.Set("peer.gossip.reconnectInterval", "22s")
+	viper.Set("peer.gossip.hashingAlgorithm", "SM3")
 
 	coreConfig, err := gossip.GlobalConfig(endpoint, nil, bootstrap...)
 	assert.NoError(t, err)
@@ -95,12 +96,21 @@ func TestGlobalConfig(t *testing.T) {
 		AliveExpirationTimeout:       21 * time.Second,
 		AliveExpirationCheckInterval: 21 * time.Second / 10, // AliveExpirationTimeout / 10
 		ReconnectInterval:            22 * time.Second,
+		HashingAlgorithm:             "SM3",
 	}
 
 	assert.Equal(t, expectedConfig, coreConfig)
 
 }
 
+func TestGlobalConfigHashingAlgorithm(t *testing.T) {
+	viper.Reset()
+	viper.Set("peer.gossip.hashingAlgorithm", "MD5")
+
+	_, err := gossip.GlobalConfig("0.0.0.0:7051", nil)
+	assert.EqualError(t, err, "Unknown hashing algorithm type: MD5")
+}
+
 func TestGlobalConfigDefaults(t *testing.T) {
 	viper.Reset()
 	endpoint := "0.0.0.0:7051"
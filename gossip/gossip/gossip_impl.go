@@ -66,6 +66,7 @@ type Node struct {
 	stateInfoMsgStore msgstore.MessageStore
 	certPuller        pull.Mediator
 	gossipMetrics     *metrics.GossipMetrics
+	hashingAlgorithm  func(data []byte) []byte
 }
 
 // New creates a gossip instance attached to a gRPC server
@@ -92,6 +93,11 @@ func New(conf *Config, s *grpc.Server, sa api.SecurityAdvisor,
 		includeIdentityPeriod: time.Now().Add(conf.PublishCertPeriod),
 		gossipMetrics:         gossipMetrics,
 	}
+	g.hashingAlgorithm, err = conf.hashingAlgorithm()
+	if err != nil {
+		lgr.Error("Failed obtaining the hashing algorithm:", err)
+		return nil
+	}
 	g.stateInfoMsgStore = g.newStateInfoMsgStore()
 
 	g.idMapper = identity.NewIdentityMapper(mcs, selfIdentity, func(pkiID common.PKIidType, identity api.PeerIdentityType) {
@@ -100,10 +106,11 @@ func New(conf *Config, s *grpc.Server, sa api.SecurityAdvisor,
 	}, sa)
 
 	commConfig := comm.CommConfig{
-		DialTimeout:  conf.DialTimeout,
-		ConnTimeout:  conf.ConnTimeout,
-		RecvBuffSize: conf.RecvBuffSize,
-		SendBuffSize: conf.SendBuffSize,
+		DialTimeout:      conf.DialTimeout,
+		ConnTimeout:      conf.ConnTimeout,
+		RecvBuffSize:     conf.RecvBuffSize,
+		SendBuffSize:     conf.SendBuffSize,
+		HashingAlgorithm: g.hashingAlgorithm,
 	}
 	g.comm, err = comm.NewCommInstance(s, conf.TLSCerts, g.idMapper, selfIdentity, secureDialOpts, sa,
 		gossipMetrics.CommMetrics, commConfig)
@@ -30,6 +30,24 @@ type PrivdataConfig struct {
 	ReconciliationEnabled bool
 	// ImplicitCollectionDisseminationPolicy specifies the dissemination  policy for the peer's own implicit collection.
 	ImplicitCollDisseminationPolicy ImplicitCollectionDisseminationPolicy
+	// Encryption configures the encryption of the private data the peer disseminates.
+	Encryption PvtDataEncryption
+}
+
+// PvtDataEncryption configures the encryption of the private data the peer
+// disseminates with SM4 keys wrapped to the SM2 encryption certificates of
+// the member organizations of the collections.
+type PvtDataEncryption struct {
+	// Enabled is a flag that indicates whether private data is disseminated encrypted or not.
+	Enabled bool
+	// Organizations lists the encryption certificates of the organizations.
+	Organizations []OrgEncryptionCert
+}
+
+// OrgEncryptionCert is the SM2 encryption certificate of an organization.
+type OrgEncryptionCert struct {
+	MSPID              string `mapstructure:"mspID"`
+	EncryptionCertFile string `mapstructure:"encryptionCertFile"`
 }
 
 // ImplicitCollectionDisseminationPolicy specifies the dissemination  policy for the peer's own implicit collection.
@@ -85,4 +103,9 @@ func (c *PrivdataConfig) loadPrivDataConfig() {
 
 	c.ImplicitCollDisseminationPolicy.RequiredPeerCount = requiredPeerCount
 	c.ImplicitCollDisseminationPolicy.MaxPeerCount = maxPeerCount
+
+	c.Encryption.Enabled = viper.GetBool("peer.gossip.pvtData.encryption.enabled")
+	if err := viper.UnmarshalKey("peer.gossip.pvtData.encryption.organizations", &c.Encryption.Organizations); err != nil {
+		panic(fmt.Sprintf("could not unmarshal peer.gossip.pvtData.encryption.organizations: %s", err))
+	}
 }
@@ -23,6 +23,10 @@ func TestGlobalConfig(t *testing.T) {
 	viper.Set("peer.gossip.pvtData.reconciliationEnabled", true)
 	viper.Set("peer.gossip.pvtData.implicitCollectionDisseminationPolicy.requiredPeerCount", 2)
 	viper.Set("peer.gossip.pvtData.implicitCollectionDisseminationPolicy.maxPeerCount", 3)
+	viper.Set("peer.gossip.pvtData.encryption.enabled", true)
+	viper.Set("peer.gossip.pvtData.encryption.organizations", []map[string]interface{}{
+		{"mspID": "Org1MSP", "encryptionCertFile": "/org1/enc.pem"},
+	})
 
 	coreConfig := privdata.GlobalConfig()
 
@@ -34,6 +38,12 @@ func TestGlobalConfig(t *testing.T) {
 			RequiredPeerCount: 2,
 			MaxPeerCount:      3,
 		},
+		Encryption: privdata.PvtDataEncryption{
+			Enabled: true,
+			Organizations: []privdata.OrgEncryptionCert{
+				{MSPID: "Org1MSP", EncryptionCertFile: "/org1/enc.pem"},
+			},
+		},
 	}
 
 	assert.Equal(t, coreConfig, expectedConfig)
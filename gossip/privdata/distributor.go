@@ -79,6 +79,7 @@ type distributorImpl struct {
 	CollectionAccessFactory
 	pushAckTimeout time.Duration
 	metrics        *metrics.PrivdataMetrics
+	cipher         *PvtDataCipher
 }
 
 //go:generate mockery -dir . -name CollectionAccessFactory -case underscore -output ./mocks/
@@ -122,15 +123,17 @@ func NewCollectionAccessFactory(factory IdentityDeserializerFactory) CollectionA
 }
 
 // NewDistributor a constructor for private data distributor capable to send
-// private read write sets for underlying collection
+// private read write sets for underlying collection. The read write sets are
+// encrypted with cipher, unless it is nil.
 func NewDistributor(chainID string, gossip gossipAdapter, factory CollectionAccessFactory,
-	metrics *metrics.PrivdataMetrics, pushAckTimeout time.Duration) PvtDataDistributor {
+	metrics *metrics.PrivdataMetrics, pushAckTimeout time.Duration, cipher *PvtDataCipher) PvtDataDistributor {
 	return &distributorImpl{
 		chainID:                 chainID,
 		gossipAdapter:           gossip,
 		CollectionAccessFactory: factory,
 		pushAckTimeout:          pushAckTimeout,
 		metrics:                 metrics,
+		cipher:                  cipher,
 	}
 }
 
@@ -181,6 +184,14 @@ func (d *distributorImpl) computeDisseminationPlan(txID string,
 				return nil, errors.Errorf("No collection access policy filter computed for %v", collectionName)
 			}
 
+			if d.cipher != nil {
+				rwSet, err := d.cipher.Encrypt(d.chainID, namespace, collectionName, txID, colAP.MemberOrgs(), collection.Rwset)
+				if err != nil {
+					return nil, errors.WithMessagef(err, "could not encrypt private data for chaincode %s and collection %s", namespace, collectionName)
+				}
+				collection = &rwset.CollectionPvtReadWriteSet{CollectionName: collectionName, Rwset: rwSet}
+			}
+
 			pvtDataMsg, err := d.createPrivateDataMessage(txID, namespace, collection, &peer.CollectionConfigPackage{Config: []*peer.CollectionConfig{colCP}}, blkHt)
 			if err != nil {
 				return nil, errors.WithStack(err)
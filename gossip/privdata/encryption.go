@@ -0,0 +1,312 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privdata
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	commonutil "github.com/hyperledger/fabric/common/util"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/pkg/errors"
+)
+
+// encryptedRWSetPrefix starts the encrypted private write sets. As protobuf
+// field numbers start at 1, no marshaled write set starts with a zero byte.
+var encryptedRWSetPrefix = []byte("\x00SM4PVT\x01")
+
+// pvtDataKeyLabel binds the SM2 wrapped collection keys to private data
+// dissemination.
+var pvtDataKeyLabel = []byte("fabric private data")
+
+// encryptedRWSet is the encrypted private write set of a collection. The
+// SM4 key of the collection it is encrypted with is wrapped to the SM2
+// encryption certificate of each member organization of the collection.
+type encryptedRWSet struct {
+	KeyID      []byte
+	Recipients []wrappedKey
+	Ciphertext []byte
+}
+
+// wrappedKey is a collection key encrypted with the public key of the
+// encryption certificate whose SM3 fingerprint is Recipient.
+type wrappedKey struct {
+	Recipient []byte
+	Key       []byte
+}
+
+// Decrypter decrypts with the private key of an SM2 encryption certificate,
+// as the msp.DualCertSigningIdentity of peers do.
+type Decrypter interface {
+	// GetEncryptionCertificate returns the encryption certificate
+	GetEncryptionCertificate() *x509.Certificate
+
+	// Decrypt decrypts ciphertext with the private key of the encryption certificate
+	Decrypt(ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error)
+}
+
+// orgEncryptionKey is the public key of the encryption certificate of an
+// organization.
+type orgEncryptionKey struct {
+	fingerprint []byte
+	key         bccsp.Key
+}
+
+// collectionKey is the SM4 key the private write sets of a collection are
+// encrypted with, along with its wrappings for the member organizations.
+type collectionKey struct {
+	id         []byte
+	key        bccsp.Key
+	memberOrgs string
+	recipients []wrappedKey
+}
+
+// PvtDataCipher encrypts the private write sets of collections with per
+// collection SM4 keys, and wraps the keys to the SM2 encryption certificates
+// of the member organizations of the collections. The wrapped keys travel
+// with the write sets, so that the peers of member organizations can decrypt
+// them with the private key of their encryption certificate.
+type PvtDataCipher struct {
+	csp        bccsp.BCCSP
+	decrypter  Decrypter
+	orgKeys    map[string]orgEncryptionKey
+	selfFinger []byte
+
+	lock sync.Mutex
+	// collectionKeys are the keys this peer encrypts with, by collection
+	collectionKeys map[string]*collectionKey
+	// receivedKeys are the keys unwrapped by this peer, by key ID
+	receivedKeys map[string]bccsp.Key
+}
+
+// NewPvtDataCipher creates a PvtDataCipher wrapping collection keys to the
+// encryption certificates of the organizations of conf, and unwrapping them
+// with decrypter.
+func NewPvtDataCipher(conf PvtDataEncryption, decrypter Decrypter, csp bccsp.BCCSP) (*PvtDataCipher, error) {
+	encCert := decrypter.GetEncryptionCertificate()
+	if encCert == nil {
+		return nil, errors.New("the signing identity of the peer has no encryption certificate")
+	}
+
+	c := &PvtDataCipher{
+		csp:            csp,
+		decrypter:      decrypter,
+		orgKeys:        map[string]orgEncryptionKey{},
+		selfFinger:     commonutil.ComputeSM3(encCert.Raw),
+		collectionKeys: map[string]*collectionKey{},
+		receivedKeys:   map[string]bccsp.Key{},
+	}
+
+	for _, org := range conf.Organizations {
+		cert, err := loadEncryptionCert(org.EncryptionCertFile)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed loading the encryption certificate of organization %s", org.MSPID)
+		}
+		key, err := csp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed importing the encryption certificate of organization %s", org.MSPID)
+		}
+		c.orgKeys[org.MSPID] = orgEncryptionKey{
+			fingerprint: commonutil.ComputeSM3(cert.Raw),
+			key:         key,
+		}
+	}
+
+	return c, nil
+}
+
+func loadEncryptionCert(file string) (*x509.Certificate, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in %s", file)
+	}
+	return gmx509.ParseCertificate(block.Bytes)
+}
+
+// Encrypt encrypts the private write set of collection of namespace, for
+// transaction txID of channel, to the member organizations memberOrgs of
+// the collection.
+func (c *PvtDataCipher) Encrypt(channel, namespace, collection, txID string, memberOrgs map[string]struct{}, rwSet []byte) ([]byte, error) {
+	ck, err := c.collectionKey(channel+"/"+namespace+"/"+collection, memberOrgs)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed obtaining the key of collection %s of namespace %s", collection, namespace)
+	}
+
+	ciphertext, err := c.csp.Encrypt(ck.key, rwSet, &bccsp.AEADOpts{
+		AdditionalData: additionalData(channel, namespace, collection, txID),
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed encrypting private data of collection %s of namespace %s", collection, namespace)
+	}
+
+	der, err := asn1.Marshal(encryptedRWSet{
+		KeyID:      ck.id,
+		Recipients: ck.recipients,
+		Ciphertext: ciphertext,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling encrypted private data")
+	}
+
+	return append(append([]byte{}, encryptedRWSetPrefix...), der...), nil
+}
+
+// collectionKey returns the key of the collection identified by id, wrapped
+// to memberOrgs. A new key is generated the first time it is requested, and
+// whenever the member organizations change, so that organizations leaving
+// the collection cannot decrypt the private data written afterwards.
+func (c *PvtDataCipher) collectionKey(id string, memberOrgs map[string]struct{}) (*collectionKey, error) {
+	orgs := make([]string, 0, len(memberOrgs))
+	for org := range memberOrgs {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if ck, exists := c.collectionKeys[id]; exists && ck.memberOrgs == strings.Join(orgs, ",") {
+		return ck, nil
+	}
+
+	ck, err := c.newCollectionKey(orgs)
+	if err != nil {
+		return nil, err
+	}
+	c.collectionKeys[id] = ck
+	return ck, nil
+}
+
+// newCollectionKey generates a new SM4 key and wraps it to orgs.
+func (c *PvtDataCipher) newCollectionKey(orgs []string) (*collectionKey, error) {
+	id := make([]byte, 16)
+	raw := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, errors.Wrap(err, "failed generating key ID")
+	}
+	if _, err := rand.Read(raw); err != nil {
+		return nil, errors.Wrap(err, "failed generating SM4 key")
+	}
+	key, err := c.csp.KeyImport(raw, &bccsp.SM4ImportKeyOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed importing SM4 key")
+	}
+
+	var recipients []wrappedKey
+	for _, org := range orgs {
+		orgKey, found := c.orgKeys[org]
+		if !found {
+			return nil, errors.Errorf("no encryption certificate configured for organization %s", org)
+		}
+		wrapped, err := c.csp.Encrypt(orgKey.key, raw, &bccsp.SM2EncrypterOpts{Label: pvtDataKeyLabel})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed wrapping key to organization %s", org)
+		}
+		recipients = append(recipients, wrappedKey{Recipient: orgKey.fingerprint, Key: wrapped})
+	}
+
+	return &collectionKey{
+		id:         id,
+		key:        key,
+		memberOrgs: strings.Join(orgs, ","),
+		recipients: recipients,
+	}, nil
+}
+
+// Decrypt decrypts the private write set of collection of namespace, for
+// transaction txID of channel. Write sets which are not encrypted are
+// returned as they are.
+func (c *PvtDataCipher) Decrypt(channel, namespace, collection, txID string, rwSet []byte) ([]byte, error) {
+	if !bytes.HasPrefix(rwSet, encryptedRWSetPrefix) {
+		return rwSet, nil
+	}
+
+	ers := &encryptedRWSet{}
+	if _, err := asn1.Unmarshal(rwSet[len(encryptedRWSetPrefix):], ers); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling encrypted private data")
+	}
+
+	key, err := c.receivedKey(ers)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed obtaining the key of collection %s of namespace %s", collection, namespace)
+	}
+
+	plaintext, err := c.csp.Decrypt(key, ers.Ciphertext, &bccsp.AEADOpts{
+		AdditionalData: additionalData(channel, namespace, collection, txID),
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed decrypting private data of collection %s of namespace %s", collection, namespace)
+	}
+	return plaintext, nil
+}
+
+// receivedKey returns the key ers is encrypted with, unwrapping it with the
+// private key of the encryption certificate of the peer if it was not
+// unwrapped yet.
+func (c *PvtDataCipher) receivedKey(ers *encryptedRWSet) (bccsp.Key, error) {
+	id := hex.EncodeToString(ers.KeyID)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if key, exists := c.receivedKeys[id]; exists {
+		return key, nil
+	}
+
+	for _, recipient := range ers.Recipients {
+		if !bytes.Equal(recipient.Recipient, c.selfFinger) {
+			continue
+		}
+		raw, err := c.decrypter.Decrypt(recipient.Key, &bccsp.SM2EncrypterOpts{Label: pvtDataKeyLabel})
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed unwrapping key")
+		}
+		key, err := c.csp.KeyImport(raw, &bccsp.SM4ImportKeyOpts{Temporary: true})
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed importing SM4 key")
+		}
+		c.receivedKeys[id] = key
+		return key, nil
+	}
+
+	return nil, errors.New("key is not wrapped to the encryption certificate of this peer")
+}
+
+// DecryptTxPvtRWSet decrypts in place the private write sets of all
+// collections of txPvtRWSet.
+func (c *PvtDataCipher) DecryptTxPvtRWSet(channel, txID string, txPvtRWSet *rwset.TxPvtReadWriteSet) error {
+	for _, ns := range txPvtRWSet.GetNsPvtRwset() {
+		for _, col := range ns.CollectionPvtRwset {
+			plaintext, err := c.Decrypt(channel, ns.Namespace, col.CollectionName, txID, col.Rwset)
+			if err != nil {
+				return err
+			}
+			col.Rwset = plaintext
+		}
+	}
+	return nil
+}
+
+// additionalData binds the ciphertext of a private write set to its
+// channel, namespace, collection and transaction.
+func additionalData(channel, namespace, collection, txID string) []byte {
+	return []byte(strings.Join([]string{channel, namespace, collection, txID}, "\x00"))
+}
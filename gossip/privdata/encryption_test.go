@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privdata
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sm2Decrypter struct {
+	cert *x509.Certificate
+	key  *sm2.PrivateKey
+}
+
+func (d *sm2Decrypter) GetEncryptionCertificate() *x509.Certificate {
+	return d.cert
+}
+
+func (d *sm2Decrypter) Decrypt(ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	return utils.SM2Decrypt(d.key, ciphertext, opts.(*bccsp.SM2EncrypterOpts).Label)
+}
+
+// newEncryptionIdentity returns the decrypter of a new self-signed SM2
+// encryption certificate of org, and the file the certificate is written to.
+func newEncryptionIdentity(t *testing.T, dir, org string) (*sm2Decrypter, string) {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "enc." + org},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDataEncipherment,
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := gmx509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, org+".pem")
+	err = ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	require.NoError(t, err)
+	return &sm2Decrypter{cert: cert, key: key}, file
+}
+
+func TestPvtDataCipher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pvtdatacipher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	org1, org1File := newEncryptionIdentity(t, dir, "Org1MSP")
+	org2, org2File := newEncryptionIdentity(t, dir, "Org2MSP")
+	org3, _ := newEncryptionIdentity(t, dir, "Org3MSP")
+
+	conf := PvtDataEncryption{
+		Enabled: true,
+		Organizations: []OrgEncryptionCert{
+			{MSPID: "Org1MSP", EncryptionCertFile: org1File},
+			{MSPID: "Org2MSP", EncryptionCertFile: org2File},
+		},
+	}
+	sender, err := NewPvtDataCipher(conf, org1, csp)
+	require.NoError(t, err)
+	receiver, err := NewPvtDataCipher(conf, org2, csp)
+	require.NoError(t, err)
+	outsider, err := NewPvtDataCipher(conf, org3, csp)
+	require.NoError(t, err)
+
+	members := map[string]struct{}{"Org1MSP": {}, "Org2MSP": {}}
+	rwSet := []byte("rws-pre-image")
+
+	ciphertext, err := sender.Encrypt("testchannel", "ns1", "col1", "tx1", members, rwSet)
+	require.NoError(t, err)
+	assert.NotContains(t, string(ciphertext), string(rwSet))
+
+	t.Run("Member organization", func(t *testing.T) {
+		plaintext, err := receiver.Decrypt("testchannel", "ns1", "col1", "tx1", ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, rwSet, plaintext)
+
+		txPvtRWSet := &rwset.TxPvtReadWriteSet{
+			NsPvtRwset: []*rwset.NsPvtReadWriteSet{{
+				Namespace: "ns1",
+				CollectionPvtRwset: []*rwset.CollectionPvtReadWriteSet{
+					{CollectionName: "col1", Rwset: ciphertext},
+				},
+			}},
+		}
+		err = receiver.DecryptTxPvtRWSet("testchannel", "tx1", txPvtRWSet)
+		require.NoError(t, err)
+		assert.Equal(t, rwSet, txPvtRWSet.NsPvtRwset[0].CollectionPvtRwset[0].Rwset)
+	})
+
+	t.Run("Other transaction", func(t *testing.T) {
+		_, err := receiver.Decrypt("testchannel", "ns1", "col1", "tx2", ciphertext)
+		assert.Contains(t, err.Error(), "failed decrypting private data of collection col1 of namespace ns1")
+	})
+
+	t.Run("Non member organization", func(t *testing.T) {
+		_, err := outsider.Decrypt("testchannel", "ns1", "col1", "tx1", ciphertext)
+		assert.EqualError(t, err, "failed obtaining the key of collection col1 of namespace ns1: key is not wrapped to the encryption certificate of this peer")
+	})
+
+	t.Run("Plaintext", func(t *testing.T) {
+		plaintext, err := receiver.Decrypt("testchannel", "ns1", "col1", "tx1", rwSet)
+		require.NoError(t, err)
+		assert.Equal(t, rwSet, plaintext)
+	})
+
+	t.Run("Organization without encryption certificate", func(t *testing.T) {
+		_, err := sender.Encrypt("testchannel", "ns1", "col2", "tx1", map[string]struct{}{"Org3MSP": {}}, rwSet)
+		assert.EqualError(t, err, "failed obtaining the key of collection col2 of namespace ns1: no encryption certificate configured for organization Org3MSP")
+	})
+
+	t.Run("Member organizations change", func(t *testing.T) {
+		keyID := func(ciphertext []byte) []byte {
+			ers := &encryptedRWSet{}
+			_, err := asn1.Unmarshal(ciphertext[len(encryptedRWSetPrefix):], ers)
+			require.NoError(t, err)
+			return ers.KeyID
+		}
+
+		same, err := sender.Encrypt("testchannel", "ns1", "col1", "tx2", members, rwSet)
+		require.NoError(t, err)
+		assert.Equal(t, keyID(ciphertext), keyID(same))
+
+		rotated, err := sender.Encrypt("testchannel", "ns1", "col1", "tx3", map[string]struct{}{"Org1MSP": {}}, rwSet)
+		require.NoError(t, err)
+		assert.NotEqual(t, keyID(ciphertext), keyID(rotated))
+
+		_, err = receiver.Decrypt("testchannel", "ns1", "col1", "tx3", rotated)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewPvtDataCipher(t *testing.T) {
+	csp, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	_, err = NewPvtDataCipher(PvtDataEncryption{}, &sm2Decrypter{}, csp)
+	assert.EqualError(t, err, "the signing identity of the peer has no encryption certificate")
+
+	dir, err := ioutil.TempDir("", "pvtdatacipher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	self, _ := newEncryptionIdentity(t, dir, "Org1MSP")
+
+	_, err = NewPvtDataCipher(PvtDataEncryption{
+		Organizations: []OrgEncryptionCert{{MSPID: "Org2MSP", EncryptionCertFile: filepath.Join(dir, "missing.pem")}},
+	}, self, csp)
+	assert.Contains(t, err.Error(), "failed loading the encryption certificate of organization Org2MSP")
+}
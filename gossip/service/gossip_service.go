@@ -173,6 +173,7 @@ type GossipService struct {
 	metrics         *gossipmetrics.GossipMetrics
 	serviceConfig   *ServiceConfig
 	privdataConfig  *gossipprivdata.PrivdataConfig
+	pvtDataCipher   *gossipprivdata.PvtDataCipher
 }
 
 // This is an implementation of api.JoinChannelMessage.
@@ -216,6 +217,7 @@ func New(
 	serviceConfig *ServiceConfig,
 	privdataConfig *gossipprivdata.PrivdataConfig,
 	deliverServiceConfig *deliverservice.DeliverServiceConfig,
+	pvtDataCipher *gossipprivdata.PvtDataCipher,
 ) (*GossipService, error) {
 	serializedIdentity, err := peerIdentity.Serialize()
 	if err != nil {
@@ -252,6 +254,7 @@ func New(
 		metrics:        gossipMetrics,
 		serviceConfig:  serviceConfig,
 		privdataConfig: privdataConfig,
+		pvtDataCipher:  pvtDataCipher,
 	}, nil
 }
 
@@ -278,6 +281,22 @@ func (g *GossipService) DistributePrivateData(channelID string, txID string, pri
 	return nil
 }
 
+// decryptingCoordinator is a Coordinator decrypting the private data it
+// stores with the PvtDataCipher of the peer.
+type decryptingCoordinator struct {
+	gossipprivdata.Coordinator
+	channelID string
+	cipher    *gossipprivdata.PvtDataCipher
+}
+
+// StorePvtData decrypts privData and stores it into the transient store
+func (c *decryptingCoordinator) StorePvtData(txID string, privData *tspb.TxPvtReadWriteSetWithConfigInfo, blkHeight uint64) error {
+	if err := c.cipher.DecryptTxPvtRWSet(c.channelID, txID, privData.GetPvtRwset()); err != nil {
+		return errors.WithMessagef(err, "failed to decrypt private data of txID %s", txID)
+	}
+	return c.Coordinator.StorePvtData(txID, privData, blkHeight)
+}
+
 // NewConfigEventer creates a ConfigProcessor which the channelconfig.BundleSource can ultimately route config updates to
 func (g *GossipService) NewConfigEventer() ConfigProcessor {
 	return newConfigEventer(g)
@@ -337,18 +356,24 @@ func (g *GossipService) InitializeChannel(channelID string, ordererSource *order
 	g.privateHandlers[channelID] = privateHandler{
 		support:     support,
 		coordinator: coordinator,
-		distributor: gossipprivdata.NewDistributor(channelID, g, collectionAccessFactory, g.metrics.PrivdataMetrics, pushAckTimeout),
+		distributor: gossipprivdata.NewDistributor(channelID, g, collectionAccessFactory, g.metrics.PrivdataMetrics, pushAckTimeout, g.pvtDataCipher),
 		reconciler:  reconciler,
 	}
 	g.privateHandlers[channelID].reconciler.Start()
 
+	// Private data received from other peers is decrypted before it is stored
+	var ledger gossipprivdata.Coordinator = coordinator
+	if g.pvtDataCipher != nil {
+		ledger = &decryptingCoordinator{Coordinator: coordinator, channelID: channelID, cipher: g.pvtDataCipher}
+	}
+
 	blockingMode := !g.serviceConfig.NonBlockingCommitMode
 	stateConfig := state.GlobalConfig()
 	g.chains[channelID] = state.NewGossipStateProvider(
 		flogging.MustGetLogger(util.StateLogger),
 		channelID,
 		servicesAdapter,
-		coordinator,
+		ledger,
 		g.metrics.StateMetrics,
 		blockingMode,
 		stateConfig)
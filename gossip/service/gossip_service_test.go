@@ -8,6 +8,7 @@ package service
 
 import (
 	"bytes"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -16,6 +17,7 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/ledger/rwset"
 	"github.com/hyperledger/fabric-protos-go/peer"
 	transientstore2 "github.com/hyperledger/fabric-protos-go/transientstore"
 	"github.com/hyperledger/fabric/common/channelconfig"
@@ -44,8 +46,10 @@ import (
 	"github.com/hyperledger/fabric/internal/pkg/peer/orderers"
 	"github.com/hyperledger/fabric/msp/mgmt"
 	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
@@ -114,7 +118,7 @@ func TestInitGossipService(t *testing.T) {
 	msptesttools.LoadMSPSetupForTesting()
 	signer := mgmt.GetLocalSigningIdentityOrPanic(cryptoProvider)
 
-	messageCryptoService := peergossip.NewMCS(&mocks.ChannelPolicyManagerGetter{}, signer, mgmt.NewDeserializersManager(cryptoProvider), cryptoProvider)
+	messageCryptoService := peergossip.NewMCS(&mocks.ChannelPolicyManagerGetter{}, signer, mgmt.NewDeserializersManager(cryptoProvider), cryptoProvider, nil, nil)
 	secAdv := peergossip.NewSecurityAdvisor(mgmt.NewDeserializersManager(cryptoProvider))
 	gossipConfig, err := gossip.GlobalConfig(endpoint, nil)
 	assert.NoError(t, err)
@@ -139,6 +143,7 @@ func TestInitGossipService(t *testing.T) {
 			ReConnectBackoffThreshold:   deliverservice.DefaultReConnectBackoffThreshold,
 			ReconnectTotalTimeThreshold: deliverservice.DefaultReConnectTotalTimeThreshold,
 		},
+		nil,
 	)
 	assert.NoError(t, err)
 
@@ -899,6 +904,7 @@ func TestInvalidInitialization(t *testing.T) {
 			ReConnectBackoffThreshold:   deliverservice.DefaultReConnectBackoffThreshold,
 			ReconnectTotalTimeThreshold: deliverservice.DefaultReConnectTotalTimeThreshold,
 		},
+		nil,
 	)
 	assert.NoError(t, err)
 	gService := gossipService
@@ -944,6 +950,7 @@ func TestChannelConfig(t *testing.T) {
 			ReConnectBackoffThreshold:   deliverservice.DefaultReConnectBackoffThreshold,
 			ReconnectTotalTimeThreshold: deliverservice.DefaultReConnectTotalTimeThreshold,
 		},
+		nil,
 	)
 	assert.NoError(t, err)
 	gService := gossipService
@@ -971,3 +978,58 @@ func TestChannelConfig(t *testing.T) {
 	gService.updateAnchors(mc)
 	assert.True(t, gService.amIinChannel(string(orgInChannelA), mc))
 }
+
+type encryptionIdentity struct{}
+
+func (*encryptionIdentity) GetEncryptionCertificate() *x509.Certificate {
+	return &x509.Certificate{Raw: []byte("encryption certificate")}
+}
+
+func (*encryptionIdentity) Decrypt(ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	return nil, errors.New("no private key")
+}
+
+type recordingCoordinator struct {
+	privdata.Coordinator
+	stored []*transientstore2.TxPvtReadWriteSetWithConfigInfo
+}
+
+func (c *recordingCoordinator) StorePvtData(txID string, privData *transientstore2.TxPvtReadWriteSetWithConfigInfo, blkHeight uint64) error {
+	c.stored = append(c.stored, privData)
+	return nil
+}
+
+func TestDecryptingCoordinator(t *testing.T) {
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	cipher, err := privdata.NewPvtDataCipher(privdata.PvtDataEncryption{}, &encryptionIdentity{}, cryptoProvider)
+	require.NoError(t, err)
+
+	pvtData := func(rwSet []byte) *transientstore2.TxPvtReadWriteSetWithConfigInfo {
+		return &transientstore2.TxPvtReadWriteSetWithConfigInfo{
+			PvtRwset: &rwset.TxPvtReadWriteSet{
+				NsPvtRwset: []*rwset.NsPvtReadWriteSet{{
+					Namespace: "ns",
+					CollectionPvtRwset: []*rwset.CollectionPvtReadWriteSet{{
+						CollectionName: "col",
+						Rwset:          rwSet,
+					}},
+				}},
+			},
+		}
+	}
+
+	inner := &recordingCoordinator{}
+	coordinator := &decryptingCoordinator{Coordinator: inner, channelID: "A", cipher: cipher}
+
+	// Write sets which are not encrypted are stored as they are
+	require.NoError(t, coordinator.StorePvtData("tx1", pvtData([]byte("rwset")), 1))
+	require.Len(t, inner.stored, 1)
+	assert.Equal(t, []byte("rwset"), inner.stored[0].PvtRwset.NsPvtRwset[0].CollectionPvtRwset[0].Rwset)
+
+	// Encrypted write sets go through the cipher, and are not stored unless decrypted
+	err = coordinator.StorePvtData("tx2", pvtData([]byte("\x00SM4PVT\x01garbage")), 1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt private data of txID tx2")
+	assert.Len(t, inner.stored, 1)
+}
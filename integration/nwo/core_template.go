@@ -131,6 +131,8 @@ peer:
       vscc:
         name: DefaultValidation
   validatorPoolSize:
+  validatorSignaturePoolSize:
+  validatorSignatureBatchSize:
   discovery:
     enabled: true
     authCacheEnabled: true
@@ -29,28 +29,30 @@ type Logging struct {
 }
 
 type Peer struct {
-	ID                     string          `yaml:"id,omitempty"`
-	NetworkID              string          `yaml:"networkId,omitempty"`
-	ListenAddress          string          `yaml:"listenAddress,omitempty"`
-	ChaincodeListenAddress string          `yaml:"ChaincodeListenAddress,omitempty"`
-	ChaincodeAddress       string          `yaml:"chaincodeAddress,omitempty"`
-	Address                string          `yaml:"address,omitempty"`
-	AddressAutoDetect      bool            `yaml:"addressAutoDetect"`
-	Keepalive              *Keepalive      `yaml:"keepalive,omitempty"`
-	Gossip                 *Gossip         `yaml:"gossip,omitempty"`
-	Events                 *Events         `yaml:"events,omitempty"`
-	TLS                    *TLS            `yaml:"tls,omitempty"`
-	Authentication         *Authentication `yaml:"authentication,omitempty"`
-	FileSystemPath         string          `yaml:"fileSystemPath,omitempty"`
-	BCCSP                  *BCCSP          `yaml:"BCCSP,omitempty"`
-	MSPConfigPath          string          `yaml:"mspConfigPath,omitempty"`
-	LocalMSPID             string          `yaml:"localMspId,omitempty"`
-	Deliveryclient         *DeliveryClient `yaml:"deliveryclient,omitempty"`
-	LocalMspType           string          `yaml:"localMspType,omitempty"`
-	Handlers               *Handlers       `yaml:"handlers,omitempty"`
-	ValidatorPoolSize      int             `yaml:"validatorPoolSize,omitempty"`
-	Discovery              *Discovery      `yaml:"discovery,omitempty"`
-	Limits                 *Limits         `yaml:"limits,omitempty"`
+	ID                          string          `yaml:"id,omitempty"`
+	NetworkID                   string          `yaml:"networkId,omitempty"`
+	ListenAddress               string          `yaml:"listenAddress,omitempty"`
+	ChaincodeListenAddress      string          `yaml:"ChaincodeListenAddress,omitempty"`
+	ChaincodeAddress            string          `yaml:"chaincodeAddress,omitempty"`
+	Address                     string          `yaml:"address,omitempty"`
+	AddressAutoDetect           bool            `yaml:"addressAutoDetect"`
+	Keepalive                   *Keepalive      `yaml:"keepalive,omitempty"`
+	Gossip                      *Gossip         `yaml:"gossip,omitempty"`
+	Events                      *Events         `yaml:"events,omitempty"`
+	TLS                         *TLS            `yaml:"tls,omitempty"`
+	Authentication              *Authentication `yaml:"authentication,omitempty"`
+	FileSystemPath              string          `yaml:"fileSystemPath,omitempty"`
+	BCCSP                       *BCCSP          `yaml:"BCCSP,omitempty"`
+	MSPConfigPath               string          `yaml:"mspConfigPath,omitempty"`
+	LocalMSPID                  string          `yaml:"localMspId,omitempty"`
+	Deliveryclient              *DeliveryClient `yaml:"deliveryclient,omitempty"`
+	LocalMspType                string          `yaml:"localMspType,omitempty"`
+	Handlers                    *Handlers       `yaml:"handlers,omitempty"`
+	ValidatorPoolSize           int             `yaml:"validatorPoolSize,omitempty"`
+	ValidatorSignaturePoolSize  int             `yaml:"validatorSignaturePoolSize,omitempty"`
+	ValidatorSignatureBatchSize int             `yaml:"validatorSignatureBatchSize,omitempty"`
+	Discovery                   *Discovery      `yaml:"discovery,omitempty"`
+	Limits                      *Limits         `yaml:"limits,omitempty"`
 
 	ExtraProperties map[string]interface{} `yaml:",inline,omitempty"`
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	pb "github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric/common/capabilities"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/genesis"
@@ -21,6 +22,7 @@ import (
 	"github.com/hyperledger/fabric/internal/pkg/identity"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/pkg/errors"
 )
 
@@ -135,7 +137,11 @@ func NewChannelGroup(conf *genesisconfig.Profile) (*cb.ConfigGroup, error) {
 		return nil, errors.Wrapf(err, "error adding policies to channel group")
 	}
 
-	addValue(channelGroup, channelconfig.HashingAlgorithmValue(), channelconfig.AdminsPolicyKey)
+	hashingAlgorithm := channelconfig.HashingAlgorithmValue()
+	if conf.HashingAlgorithm != "" {
+		hashingAlgorithm = channelconfig.HashingAlgorithmNameValue(conf.HashingAlgorithm)
+	}
+	addValue(channelGroup, hashingAlgorithm, channelconfig.AdminsPolicyKey)
 	addValue(channelGroup, channelconfig.BlockDataHashingStructureValue(), channelconfig.AdminsPolicyKey)
 	if conf.Orderer != nil && len(conf.Orderer.Addresses) > 0 {
 		addValue(channelGroup, channelconfig.OrdererAddressesValue(conf.Orderer.Addresses), ordererAdminsPolicyName)
@@ -145,11 +151,14 @@ func NewChannelGroup(conf *genesisconfig.Profile) (*cb.ConfigGroup, error) {
 		addValue(channelGroup, channelconfig.ConsortiumValue(conf.Consortium), channelconfig.AdminsPolicyKey)
 	}
 
-	if len(conf.Capabilities) > 0 {
-		addValue(channelGroup, channelconfig.CapabilitiesValue(conf.Capabilities), channelconfig.AdminsPolicyKey)
+	channelCapabilities, err := gmAlgorithmsCapabilities(conf)
+	if err != nil {
+		return nil, err
+	}
+	if len(channelCapabilities) > 0 {
+		addValue(channelGroup, channelconfig.CapabilitiesValue(channelCapabilities), channelconfig.AdminsPolicyKey)
 	}
 
-	var err error
 	if conf.Orderer != nil {
 		channelGroup.Groups[channelconfig.OrdererGroupKey], err = NewOrdererGroup(conf.Orderer)
 		if err != nil {
@@ -175,6 +184,30 @@ func NewChannelGroup(conf *genesisconfig.Profile) (*cb.ConfigGroup, error) {
 	return channelGroup, nil
 }
 
+// gmAlgorithmsCapabilities returns the channel capabilities of conf, along
+// with the GM algorithms capability and the signature algorithm
+// capabilities when conf declares the SM3 hashing algorithm or signature
+// algorithms.
+func gmAlgorithmsCapabilities(conf *genesisconfig.Profile) (map[string]bool, error) {
+	if conf.HashingAlgorithm != bccsp.SM3 && len(conf.SignatureAlgorithms) == 0 {
+		return conf.Capabilities, nil
+	}
+
+	channelCapabilities := map[string]bool{}
+	for capability, required := range conf.Capabilities {
+		channelCapabilities[capability] = required
+	}
+	channelCapabilities[capabilities.ChannelGMAlgorithms] = true
+	for _, name := range conf.SignatureAlgorithms {
+		capability, err := capabilities.SignatureAlgorithmCapability(name)
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid signature algorithms")
+		}
+		channelCapabilities[capability] = true
+	}
+	return channelCapabilities, nil
+}
+
 // NewOrdererGroup returns the orderer component of the channel configuration.  It defines parameters of the ordering service
 // about how large blocks should be, how frequently they should be emitted, etc. as well as the organizations of the ordering network.
 // It sets the mod_policy of all elements to "Admins".  This group is always present in any channel configuration.
@@ -275,6 +275,44 @@ var _ = Describe("Encoder", func() {
 			})
 		})
 
+		Context("when the GM algorithms are declared", func() {
+			BeforeEach(func() {
+				conf.HashingAlgorithm = "SM3"
+				conf.SignatureAlgorithms = []string{"SM2", "ecdsa"}
+			})
+
+			It("sets the hashing algorithm and requires the GM algorithms capabilities", func() {
+				cg, err := encoder.NewChannelGroup(conf)
+				Expect(err).NotTo(HaveOccurred())
+
+				ha := &cb.HashingAlgorithm{}
+				err = proto.Unmarshal(cg.Values["HashingAlgorithm"].Value, ha)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(ha.Name).To(Equal("SM3"))
+
+				caps := &cb.Capabilities{}
+				err = proto.Unmarshal(cg.Values["Capabilities"].Value, caps)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(caps.Capabilities).To(HaveLen(4))
+				Expect(caps.Capabilities).To(HaveKey("FakeCapability"))
+				Expect(caps.Capabilities).To(HaveKey("GM_ALGORITHMS"))
+				Expect(caps.Capabilities).To(HaveKey("SIGNATURE_SM2"))
+				Expect(caps.Capabilities).To(HaveKey("SIGNATURE_ECDSA"))
+				Expect(conf.Capabilities).To(HaveLen(1))
+			})
+
+			Context("when a signature algorithm is unknown", func() {
+				BeforeEach(func() {
+					conf.SignatureAlgorithms = []string{"DSA"}
+				})
+
+				It("returns an error", func() {
+					_, err := encoder.NewChannelGroup(conf)
+					Expect(err).To(MatchError("invalid signature algorithms: gmx509: unknown signature algorithm DSA"))
+				})
+			})
+		})
+
 		Context("when the orderer addresses are omitted", func() {
 			BeforeEach(func() {
 				conf.Orderer.Addresses = []string{}
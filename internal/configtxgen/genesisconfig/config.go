@@ -89,6 +89,13 @@ type Profile struct {
 	Consortiums  map[string]*Consortium `yaml:"Consortiums"`
 	Capabilities map[string]bool        `yaml:"Capabilities"`
 	Policies     map[string]*Policy     `yaml:"Policies"`
+
+	// HashingAlgorithm and SignatureAlgorithms declare the hashing
+	// algorithm of the channel, SHA256 by default, and the signature
+	// algorithms allowed to its identities, all by default. SM3 and
+	// SignatureAlgorithms require the GM_ALGORITHMS channel capability.
+	HashingAlgorithm    string   `yaml:"HashingAlgorithm"`
+	SignatureAlgorithms []string `yaml:"SignatureAlgorithms"`
 }
 
 // Policy encodes a channel config policy
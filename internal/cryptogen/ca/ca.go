@@ -23,6 +23,10 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric/internal/cryptogen/csp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509/certlog"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/pkg/errors"
 )
 
@@ -36,6 +40,11 @@ type CA struct {
 	PostalCode         string
 	Signer             crypto.Signer
 	SignCert           *x509.Certificate
+	// RootCert is the certificate of the root CA which issued SignCert,
+	// when this is an intermediate CA
+	RootCert *x509.Certificate
+	// AuditLog, if set, records the certificates the CA signs
+	AuditLog *certlog.Log
 }
 
 // NewCA creates an instance of CA and saves the signing key pair in
@@ -51,6 +60,24 @@ func NewCA(
 	streetAddress,
 	postalCode string,
 ) (*CA, error) {
+	return NewCAWithKeyAlgorithm(csp.ECDSA, baseDir, org, name, country, province, locality, orgUnit, streetAddress, postalCode)
+}
+
+// NewCAWithKeyAlgorithm creates an instance of CA with a key pair of
+// algorithm keyAlg, ECDSA or SM2, and saves the signing key pair in
+// baseDir/name. SM2 CAs sign certificates with SM2 over SM3.
+func NewCAWithKeyAlgorithm(
+	keyAlg,
+	baseDir,
+	org,
+	name,
+	country,
+	province,
+	locality,
+	orgUnit,
+	streetAddress,
+	postalCode string,
+) (*CA, error) {
 
 	var ca *CA
 
@@ -59,21 +86,12 @@ func NewCA(
 		return nil, err
 	}
 
-	priv, err := csp.GeneratePrivateKey(baseDir)
+	signer, err := csp.GenerateSigner(baseDir, keyAlg)
 	if err != nil {
 		return nil, err
 	}
 
-	template := x509Template()
-	//this is a CA
-	template.IsCA = true
-	template.KeyUsage |= x509.KeyUsageDigitalSignature |
-		x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign |
-		x509.KeyUsageCRLSign
-	template.ExtKeyUsage = []x509.ExtKeyUsage{
-		x509.ExtKeyUsageClientAuth,
-		x509.ExtKeyUsageServerAuth,
-	}
+	template := caTemplate()
 
 	//set the organization for the subject
 	subject := subjectTemplateAdditional(country, province, locality, orgUnit, streetAddress, postalCode)
@@ -81,24 +99,22 @@ func NewCA(
 	subject.CommonName = name
 
 	template.Subject = subject
-	template.SubjectKeyId = computeSKI(priv)
+	template.SubjectKeyId = computeSKI(signer.Public())
 
-	x509Cert, err := genCertificateECDSA(
+	x509Cert, err := genCertificate(
 		baseDir,
 		name,
 		&template,
 		&template,
-		&priv.PublicKey,
-		priv,
+		signer.Public(),
+		signer,
 	)
 	if err != nil {
 		return nil, err
 	}
 	ca = &CA{
-		Name: name,
-		Signer: &csp.ECDSASigner{
-			PrivateKey: priv,
-		},
+		Name:               name,
+		Signer:             signer,
 		SignCert:           x509Cert,
 		Country:            country,
 		Province:           province,
@@ -111,6 +127,80 @@ func NewCA(
 	return ca, err
 }
 
+// NewIntermediateCA creates an instance of CA whose certificate is issued
+// by ca, with a key pair of the algorithm of the key of ca, and saves the
+// signing key pair in baseDir/name
+func (ca *CA) NewIntermediateCA(baseDir, name string) (*CA, error) {
+	err := os.MkdirAll(baseDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := csp.GenerateSigner(baseDir, ca.KeyAlgorithm())
+	if err != nil {
+		return nil, err
+	}
+
+	template := caTemplate()
+
+	subject := subjectTemplateAdditional(
+		ca.Country,
+		ca.Province,
+		ca.Locality,
+		ca.OrganizationalUnit,
+		ca.StreetAddress,
+		ca.PostalCode,
+	)
+	subject.Organization = ca.SignCert.Subject.Organization
+	subject.CommonName = name
+
+	template.Subject = subject
+	template.SubjectKeyId = computeSKI(signer.Public())
+
+	x509Cert, err := genCertificate(
+		baseDir,
+		name,
+		&template,
+		ca.SignCert,
+		signer.Public(),
+		ca.Signer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if ca.AuditLog != nil {
+		if _, err := ca.AuditLog.Append(x509Cert); err != nil {
+			return nil, errors.WithMessagef(err, "failed to record certificate %s in the audit log", name)
+		}
+	}
+
+	return &CA{
+		Name:               name,
+		Country:            ca.Country,
+		Province:           ca.Province,
+		Locality:           ca.Locality,
+		OrganizationalUnit: ca.OrganizationalUnit,
+		StreetAddress:      ca.StreetAddress,
+		PostalCode:         ca.PostalCode,
+		Signer:             signer,
+		SignCert:           x509Cert,
+		RootCert:           ca.SignCert,
+		AuditLog:           ca.AuditLog,
+	}, nil
+}
+
+// KeyAlgorithm returns the algorithm of the key of the CA, csp.SM2 or
+// csp.ECDSA.
+func (ca *CA) KeyAlgorithm() string {
+	if ca.Signer != nil {
+		if _, ok := ca.Signer.Public().(*sm2.PublicKey); ok {
+			return csp.SM2
+		}
+	}
+	return csp.ECDSA
+}
+
 // SignCertificate creates a signed certificate based on a built-in template
 // and saves it in baseDir/name
 func (ca *CA) SignCertificate(
@@ -118,7 +208,7 @@ func (ca *CA) SignCertificate(
 	name string,
 	orgUnits,
 	alternateNames []string,
-	pub *ecdsa.PublicKey,
+	pub interface{},
 	ku x509.KeyUsage,
 	eku []x509.ExtKeyUsage,
 ) (*x509.Certificate, error) {
@@ -151,7 +241,7 @@ func (ca *CA) SignCertificate(
 		}
 	}
 
-	cert, err := genCertificateECDSA(
+	cert, err := genCertificate(
 		baseDir,
 		name,
 		&template,
@@ -164,17 +254,30 @@ func (ca *CA) SignCertificate(
 		return nil, err
 	}
 
+	if ca.AuditLog != nil {
+		if _, err := ca.AuditLog.Append(cert); err != nil {
+			return nil, errors.WithMessagef(err, "failed to record certificate %s in the audit log", name)
+		}
+	}
+
 	return cert, nil
 }
 
 // compute Subject Key Identifier
-func computeSKI(privKey *ecdsa.PrivateKey) []byte {
-	// Marshall the public key
-	raw := elliptic.Marshal(privKey.Curve, privKey.PublicKey.X, privKey.PublicKey.Y)
-
-	// Hash it
-	hash := sha256.Sum256(raw)
-	return hash[:]
+func computeSKI(pub crypto.PublicKey) []byte {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		// Marshall the public key
+		raw := elliptic.Marshal(k.Curve, k.X, k.Y)
+
+		// Hash it
+		hash := sha256.Sum256(raw)
+		return hash[:]
+	case *sm2.PublicKey:
+		return utils.SKI(k)
+	default:
+		return nil
+	}
 }
 
 // default template for X509 subject
@@ -241,18 +344,33 @@ func x509Template() x509.Certificate {
 
 }
 
-// generate a signed X509 certificate using ECDSA
-func genCertificateECDSA(
+// default template for X509 CA certificates
+func caTemplate() x509.Certificate {
+	template := x509Template()
+	//this is a CA
+	template.IsCA = true
+	template.KeyUsage |= x509.KeyUsageDigitalSignature |
+		x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign |
+		x509.KeyUsageCRLSign
+	template.ExtKeyUsage = []x509.ExtKeyUsage{
+		x509.ExtKeyUsageClientAuth,
+		x509.ExtKeyUsageServerAuth,
+	}
+	return template
+}
+
+// generate a signed X509 certificate using ECDSA, or SM2 over SM3
+func genCertificate(
 	baseDir,
 	name string,
 	template,
 	parent *x509.Certificate,
-	pub *ecdsa.PublicKey,
+	pub interface{},
 	priv interface{},
 ) (*x509.Certificate, error) {
 
 	//create the x509 public cert
-	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	certBytes, err := gmx509.CreateCertificate(rand.Reader, template, parent, pub, priv)
 	if err != nil {
 		return nil, err
 	}
@@ -270,14 +388,14 @@ func genCertificateECDSA(
 		return nil, err
 	}
 
-	x509Cert, err := x509.ParseCertificate(certBytes)
+	x509Cert, err := gmx509.ParseCertificate(certBytes)
 	if err != nil {
 		return nil, err
 	}
 	return x509Cert, nil
 }
 
-// LoadCertificateECDSA load a ecdsa cert from a file in cert path
+// LoadCertificateECDSA load a ecdsa or sm2 cert from a file in cert path
 func LoadCertificateECDSA(certPath string) (*x509.Certificate, error) {
 	var cert *x509.Certificate
 	var err error
@@ -292,7 +410,7 @@ func LoadCertificateECDSA(certPath string) (*x509.Certificate, error) {
 			if block == nil || block.Type != "CERTIFICATE" {
 				return errors.Errorf("%s: wrong PEM encoding", path)
 			}
-			cert, err = x509.ParseCertificate(block.Bytes)
+			cert, err = gmx509.ParseCertificate(block.Bytes)
 			if err != nil {
 				return errors.Errorf("%s: wrong DER encoding", path)
 			}
@@ -16,6 +16,8 @@ import (
 
 	"github.com/hyperledger/fabric/internal/cryptogen/ca"
 	"github.com/hyperledger/fabric/internal/cryptogen/csp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -254,6 +256,66 @@ func TestGenerateSignCertificate(t *testing.T) {
 
 }
 
+func TestNewSM2CA(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "ca-test")
+	require.NoError(t, err, "failed to create test directory")
+	defer os.RemoveAll(testDir)
+
+	rootCA, err := ca.NewCAWithKeyAlgorithm(
+		csp.SM2,
+		filepath.Join(testDir, "ca"),
+		testCAName,
+		testCAName,
+		testCountry,
+		testProvince,
+		testLocality,
+		testOrganizationalUnit,
+		testStreetAddress,
+		testPostalCode,
+	)
+	require.NoError(t, err, "Error generating CA")
+	assert.Equal(t, csp.SM2, rootCA.KeyAlgorithm())
+	assert.IsType(t, &sm2.PublicKey{}, rootCA.SignCert.PublicKey)
+	assert.True(t, gmx509.IsSM2Signed(rootCA.SignCert))
+	assert.NotEmpty(t, rootCA.SignCert.SubjectKeyId)
+
+	certDir := filepath.Join(testDir, "certs")
+	require.NoError(t, os.MkdirAll(certDir, 0755))
+	priv, err := csp.GenerateSM2PrivateKey(certDir)
+	require.NoError(t, err)
+	cert, err := rootCA.SignCertificate(certDir, testName, nil, nil, &priv.PublicKey,
+		x509.KeyUsageDigitalSignature, []x509.ExtKeyUsage{})
+	require.NoError(t, err, "Failed to generate signed certificate")
+	assert.NoError(t, gmx509.CheckSignatureFrom(cert, rootCA.SignCert))
+
+	loadedCert, err := ca.LoadCertificateECDSA(filepath.Join(testDir, "ca"))
+	assert.NoError(t, err)
+	assert.Equal(t, rootCA.SignCert.Raw, loadedCert.Raw)
+
+	_, err = ca.NewCAWithKeyAlgorithm("RSA", filepath.Join(testDir, "rsa"), testCAName, testCAName, "", "", "", "", "", "")
+	assert.EqualError(t, err, "unsupported key algorithm RSA")
+}
+
+func TestNewIntermediateCA(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "ca-test")
+	require.NoError(t, err, "failed to create test directory")
+	defer os.RemoveAll(testDir)
+
+	for _, keyAlg := range []string{csp.ECDSA, csp.SM2} {
+		rootCA, err := ca.NewCAWithKeyAlgorithm(keyAlg, filepath.Join(testDir, keyAlg, "rootca"), testCAName, testCAName, testCountry, testProvince, testLocality, testOrganizationalUnit, testStreetAddress, testPostalCode)
+		require.NoError(t, err, "Error generating CA")
+
+		intermediateCA, err := rootCA.NewIntermediateCA(filepath.Join(testDir, keyAlg, "ca"), testCA2Name)
+		require.NoError(t, err, "Error generating intermediate CA")
+		assert.Equal(t, keyAlg, intermediateCA.KeyAlgorithm())
+		assert.Equal(t, rootCA.SignCert, intermediateCA.RootCert)
+		assert.True(t, intermediateCA.SignCert.IsCA)
+		assert.Equal(t, testCA2Name, intermediateCA.SignCert.Subject.CommonName)
+		assert.Equal(t, []string{testCAName}, intermediateCA.SignCert.Subject.Organization)
+		assert.NoError(t, gmx509.CheckSignatureFrom(intermediateCA.SignCert, rootCA.SignCert))
+	}
+}
+
 func checkForFile(file string) bool {
 	if _, err := os.Stat(file); os.IsNotExist(err) {
 		return false
@@ -20,9 +20,18 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/pkg/errors"
 )
 
+// Algorithms of the generated key pairs
+const (
+	ECDSA = "ECDSA"
+	SM2   = "SM2"
+)
+
 // LoadPrivateKey loads a private key from a file in keystorePath.  It looks
 // for a file ending in "_sk" and expects a PEM-encoded PKCS8 EC private key.
 func LoadPrivateKey(keystorePath string) (*ecdsa.PrivateKey, error) {
@@ -98,6 +107,101 @@ func GeneratePrivateKey(keystorePath string) (*ecdsa.PrivateKey, error) {
 	return priv, err
 }
 
+// GenerateSM2PrivateKey creates an SM2 private key and stores it in
+// keystorePath.
+func GenerateSM2PrivateKey(keystorePath string) (*sm2.PrivateKey, error) {
+	return generateSM2PrivateKey(filepath.Join(keystorePath, "priv_sk"))
+}
+
+// GenerateSM2EncryptionKey creates the SM2 private key of an encryption
+// certificate and stores it in keystorePath, as enc_sk so that it does not
+// replace the signing key.
+func GenerateSM2EncryptionKey(keystorePath string) (*sm2.PrivateKey, error) {
+	return generateSM2PrivateKey(filepath.Join(keystorePath, "enc_sk"))
+}
+
+func generateSM2PrivateKey(keyFile string) (*sm2.PrivateKey, error) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to generate private key")
+	}
+
+	pemEncoded, err := utils.PrivateKeyToPEM(priv, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to marshal private key")
+	}
+
+	err = ioutil.WriteFile(keyFile, pemEncoded, 0600)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to save private key to file %s", keyFile)
+	}
+
+	return priv, nil
+}
+
+// GenerateSigner creates a private key of algorithm keyAlg, ECDSA or SM2,
+// stores it in keystorePath and returns its signer.
+func GenerateSigner(keystorePath, keyAlg string) (crypto.Signer, error) {
+	switch keyAlg {
+	case ECDSA:
+		priv, err := GeneratePrivateKey(keystorePath)
+		if err != nil {
+			return nil, err
+		}
+		return &ECDSASigner{PrivateKey: priv}, nil
+	case SM2:
+		priv, err := GenerateSM2PrivateKey(keystorePath)
+		if err != nil {
+			return nil, err
+		}
+		return &SM2Signer{PrivateKey: priv}, nil
+	default:
+		return nil, errors.Errorf("unsupported key algorithm %s", keyAlg)
+	}
+}
+
+// LoadSigner loads the signer of the ECDSA or SM2 private key of a file
+// ending in "_sk" in keystorePath.
+func LoadSigner(keystorePath string) (crypto.Signer, error) {
+	var signer crypto.Signer
+
+	walkFunc := func(path string, info os.FileInfo, pathErr error) error {
+		if !strings.HasSuffix(path, "_sk") {
+			return nil
+		}
+
+		rawKey, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		key, err := utils.PEMtoPrivateKey(rawKey, nil)
+		if err != nil {
+			return errors.WithMessage(err, path)
+		}
+
+		switch k := key.(type) {
+		case *ecdsa.PrivateKey:
+			signer = &ECDSASigner{PrivateKey: k}
+		case *sm2.PrivateKey:
+			signer = &SM2Signer{PrivateKey: k}
+		default:
+			return errors.Errorf("%s: unsupported private key type %T", path, key)
+		}
+		return nil
+	}
+
+	err := filepath.Walk(keystorePath, walkFunc)
+	if err != nil {
+		return nil, err
+	}
+	if signer == nil {
+		return nil, errors.Errorf("no private key found in %s", keystorePath)
+	}
+
+	return signer, nil
+}
+
 /**
 ECDSA signer implements the crypto.Signer interface for ECDSA keys.  The
 Sign method ensures signatures are created with Low S values since Fabric
@@ -155,3 +259,20 @@ func toLowS(key ecdsa.PublicKey, sig ECDSASignature) ECDSASignature {
 type ECDSASignature struct {
 	R, S *big.Int
 }
+
+// SM2Signer implements the crypto.Signer interface for SM2 keys. Sign
+// signs the message itself, with SM3 and the default user identity, as
+// gmx509 expects from the signers of certificates.
+type SM2Signer struct {
+	PrivateKey *sm2.PrivateKey
+}
+
+// Public returns the sm2.PublicKey associated with PrivateKey.
+func (s *SM2Signer) Public() crypto.PublicKey {
+	return &s.PrivateKey.PublicKey
+}
+
+// Sign signs msg with SM2 over SM3.
+func (s *SM2Signer) Sign(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return gmx509.SignSM2(rand, s.PrivateKey, msg)
+}
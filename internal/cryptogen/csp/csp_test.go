@@ -21,6 +21,8 @@ import (
 	"testing"
 
 	"github.com/hyperledger/fabric/internal/cryptogen/csp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -158,6 +160,76 @@ func TestECDSASigner(t *testing.T) {
 	assert.True(t, ok, "Expected valid signature")
 }
 
+func TestGenerateSM2PrivateKey(t *testing.T) {
+	testDir, err := ioutil.TempDir("", "csp-test")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %s", err)
+	}
+	defer os.RemoveAll(testDir)
+
+	priv, err := csp.GenerateSM2PrivateKey(testDir)
+	assert.NoError(t, err, "Failed to generate private key")
+	assert.True(t, checkForFile(filepath.Join(testDir, "priv_sk")),
+		"Expected to find private key file")
+
+	encPriv, err := csp.GenerateSM2EncryptionKey(testDir)
+	assert.NoError(t, err, "Failed to generate encryption key")
+	assert.True(t, checkForFile(filepath.Join(testDir, "enc_sk")),
+		"Expected to find encryption key file")
+	assert.NotEqual(t, priv.D, encPriv.D)
+
+	_, err = csp.GenerateSM2PrivateKey("notExist")
+	assert.Contains(t, err.Error(), "no such file or directory")
+}
+
+func TestLoadSigner(t *testing.T) {
+	for _, keyAlg := range []string{csp.ECDSA, csp.SM2} {
+		t.Run(keyAlg, func(t *testing.T) {
+			testDir, err := ioutil.TempDir("", "csp-test")
+			if err != nil {
+				t.Fatalf("Failed to create test directory: %s", err)
+			}
+			defer os.RemoveAll(testDir)
+
+			signer, err := csp.GenerateSigner(testDir, keyAlg)
+			assert.NoError(t, err, "Failed to generate signer")
+
+			loaded, err := csp.LoadSigner(testDir)
+			assert.NoError(t, err, "Failed to load signer")
+			assert.IsType(t, signer, loaded)
+			assert.Equal(t, signer.Public(), loaded.Public())
+		})
+	}
+
+	_, err := csp.GenerateSigner(os.TempDir(), "RSA")
+	assert.EqualError(t, err, "unsupported key algorithm RSA")
+
+	testDir, err := ioutil.TempDir("", "csp-test")
+	if err != nil {
+		t.Fatalf("Failed to create test directory: %s", err)
+	}
+	defer os.RemoveAll(testDir)
+	_, err = csp.LoadSigner(testDir)
+	assert.EqualError(t, err, "no private key found in "+testDir)
+}
+
+func TestSM2Signer(t *testing.T) {
+	priv, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %s", err)
+	}
+
+	signer := csp.SM2Signer{
+		PrivateKey: priv,
+	}
+	assert.Equal(t, &priv.PublicKey, signer.Public())
+
+	msg := []byte("hello world")
+	sig, err := signer.Sign(rand.Reader, msg, nil)
+	assert.NoError(t, err, "Failed to create signature")
+	assert.NoError(t, gmx509.VerifySM2(&priv.PublicKey, msg, sig))
+}
+
 func checkForFile(file string) bool {
 	if _, err := os.Stat(file); os.IsNotExist(err) {
 		return false
@@ -70,7 +70,7 @@ func GenerateLocalMSP(
 	keystore := filepath.Join(mspDir, "keystore")
 
 	// generate private key
-	priv, err := csp.GeneratePrivateKey(keystore)
+	signer, err := csp.GenerateSigner(keystore, signCA.KeyAlgorithm())
 	if err != nil {
 		return err
 	}
@@ -85,7 +85,7 @@ func GenerateLocalMSP(
 		name,
 		ous,
 		nil,
-		&priv.PublicKey,
+		signer.Public(),
 		x509.KeyUsageDigitalSignature,
 		[]x509.ExtKeyUsage{},
 	)
@@ -93,13 +93,24 @@ func GenerateLocalMSP(
 		return err
 	}
 
+	// SM2 identities also get an encryption certificate, as GM/T 0024
+	// requires, whose key goes into the keystore
+	if signCA.KeyAlgorithm() == csp.SM2 {
+		encCertsDir := filepath.Join(mspDir, "enccerts")
+		err = os.MkdirAll(encCertsDir, 0755)
+		if err != nil {
+			return err
+		}
+		_, err = generateEncryptionCert(keystore, encCertsDir, name, ous, nil, signCA)
+		if err != nil {
+			return err
+		}
+	}
+
 	// write artifacts to MSP folders
 
 	// the signing CA certificate goes into cacerts
-	err = x509Export(
-		filepath.Join(mspDir, "cacerts", x509Filename(signCA.Name)),
-		signCA.SignCert,
-	)
+	caFile, err := exportCACerts(mspDir, signCA)
 	if err != nil {
 		return err
 	}
@@ -115,7 +126,7 @@ func GenerateLocalMSP(
 	// generate config.yaml if required
 	if nodeOUs {
 
-		exportConfig(mspDir, filepath.Join("cacerts", x509Filename(signCA.Name)), true)
+		exportConfig(mspDir, caFile, true)
 	}
 
 	// the signing identity goes into admincerts.
@@ -137,7 +148,7 @@ func GenerateLocalMSP(
 	*/
 
 	// generate private key
-	tlsPrivKey, err := csp.GeneratePrivateKey(tlsDir)
+	tlsSigner, err := csp.GenerateSigner(tlsDir, tlsCA.KeyAlgorithm())
 	if err != nil {
 		return err
 	}
@@ -148,7 +159,7 @@ func GenerateLocalMSP(
 		name,
 		nil,
 		sans,
-		&tlsPrivKey.PublicKey,
+		tlsSigner.Public(),
 		x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment,
 		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth,
 			x509.ExtKeyUsageClientAuth},
@@ -177,6 +188,24 @@ func GenerateLocalMSP(
 		return err
 	}
 
+	// SM2 nodes also get the encryption certificate and key of GMTLS
+	if tlsCA.KeyAlgorithm() == csp.SM2 {
+		_, err = generateEncryptionCert(tlsDir, tlsDir, name, nil, sans, tlsCA)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(filepath.Join(tlsDir, x509Filename(name)),
+			filepath.Join(tlsDir, tlsFilePrefix+"-enc.crt"))
+		if err != nil {
+			return err
+		}
+		err = os.Rename(filepath.Join(tlsDir, "enc_sk"),
+			filepath.Join(tlsDir, tlsFilePrefix+"-enc.key"))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -193,10 +222,7 @@ func GenerateVerifyingMSP(
 		return err
 	}
 	// the signing CA certificate goes into cacerts
-	err = x509Export(
-		filepath.Join(baseDir, "cacerts", x509Filename(signCA.Name)),
-		signCA.SignCert,
-	)
+	caFile, err := exportCACerts(baseDir, signCA)
 	if err != nil {
 		return err
 	}
@@ -211,7 +237,7 @@ func GenerateVerifyingMSP(
 
 	// generate config.yaml if required
 	if nodeOUs {
-		exportConfig(baseDir, "cacerts/"+x509Filename(signCA.Name), true)
+		exportConfig(baseDir, caFile, true)
 	}
 
 	// create a throwaway cert to act as an admin cert
@@ -229,7 +255,7 @@ func GenerateVerifyingMSP(
 	if err != nil {
 		return errors.WithMessage(err, "failed to create keystore directory")
 	}
-	priv, err := csp.GeneratePrivateKey(ksDir)
+	signer, err := csp.GenerateSigner(ksDir, signCA.KeyAlgorithm())
 	if err != nil {
 		return err
 	}
@@ -238,7 +264,7 @@ func GenerateVerifyingMSP(
 		signCA.Name,
 		nil,
 		nil,
-		&priv.PublicKey,
+		signer.Public(),
 		x509.KeyUsageDigitalSignature,
 		[]x509.ExtKeyUsage{},
 	)
@@ -249,6 +275,60 @@ func GenerateVerifyingMSP(
 	return nil
 }
 
+// generateEncryptionCert creates the SM2 encryption key pair of a dual
+// certificate identity, with the key in keystore as enc_sk and the
+// certificate, issued by issuer to the subject of the signing certificate,
+// in certDir.
+func generateEncryptionCert(
+	keystore,
+	certDir,
+	name string,
+	orgUnits,
+	alternateNames []string,
+	issuer *ca.CA,
+) (*x509.Certificate, error) {
+	priv, err := csp.GenerateSM2EncryptionKey(keystore)
+	if err != nil {
+		return nil, err
+	}
+
+	return issuer.SignCertificate(
+		certDir,
+		name,
+		orgUnits,
+		alternateNames,
+		&priv.PublicKey,
+		x509.KeyUsageKeyEncipherment|x509.KeyUsageDataEncipherment|x509.KeyUsageKeyAgreement,
+		[]x509.ExtKeyUsage{},
+	)
+}
+
+// exportCACerts writes the certificate of signCA into cacerts, or, for an
+// intermediate CA, the certificate of its root CA into cacerts and its own
+// into intermediatecerts. It returns the path, relative to mspDir, of the
+// certificate of signCA.
+func exportCACerts(mspDir string, signCA *ca.CA) (string, error) {
+	if signCA.RootCert == nil {
+		caFile := filepath.Join("cacerts", x509Filename(signCA.Name))
+		return caFile, x509Export(filepath.Join(mspDir, caFile), signCA.SignCert)
+	}
+
+	err := x509Export(
+		filepath.Join(mspDir, "cacerts", x509Filename(signCA.RootCert.Subject.CommonName)),
+		signCA.RootCert,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(filepath.Join(mspDir, "intermediatecerts"), 0755)
+	if err != nil {
+		return "", err
+	}
+	caFile := filepath.Join("intermediatecerts", x509Filename(signCA.Name))
+	return caFile, x509Export(filepath.Join(mspDir, caFile), signCA.SignCert)
+}
+
 func createFolderStructure(rootDir string, local bool) error {
 
 	var folders []string
@@ -12,8 +12,10 @@ import (
 	"testing"
 
 	"github.com/hyperledger/fabric/internal/cryptogen/ca"
+	"github.com/hyperledger/fabric/internal/cryptogen/csp"
 	"github.com/hyperledger/fabric/internal/cryptogen/msp"
 	fabricmsp "github.com/hyperledger/fabric/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
 )
@@ -127,6 +129,58 @@ func TestGenerateLocalMSPWithoutNodeOU(t *testing.T) {
 	testGenerateLocalMSP(t, false)
 }
 
+func TestGenerateLocalMSPWithSM2(t *testing.T) {
+	cleanup(testDir)
+	defer cleanup(testDir)
+
+	caDir := filepath.Join(testDir, "ca")
+	rootCADir := filepath.Join(testDir, "rootca")
+	tlsCADir := filepath.Join(testDir, "tlsca")
+	mspDir := filepath.Join(testDir, "msp")
+	tlsDir := filepath.Join(testDir, "tls")
+
+	rootCA, err := ca.NewCAWithKeyAlgorithm(csp.SM2, rootCADir, testCAOrg, "root"+testCAName, testCountry, testProvince, testLocality, testOrganizationalUnit, testStreetAddress, testPostalCode)
+	assert.NoError(t, err, "Error generating CA")
+	signCA, err := rootCA.NewIntermediateCA(caDir, testCAName)
+	assert.NoError(t, err, "Error generating intermediate CA")
+	tlsCA, err := ca.NewCAWithKeyAlgorithm(csp.SM2, tlsCADir, testCAOrg, testCAName, testCountry, testProvince, testLocality, testOrganizationalUnit, testStreetAddress, testPostalCode)
+	assert.NoError(t, err, "Error generating CA")
+
+	err = msp.GenerateLocalMSP(testDir, testName, nil, signCA, tlsCA, msp.PEER, true)
+	assert.NoError(t, err, "Failed to generate local MSP")
+
+	for _, file := range []string{
+		filepath.Join(mspDir, "cacerts", "root"+testCAName+"-cert.pem"),
+		filepath.Join(mspDir, "intermediatecerts", testCAName+"-cert.pem"),
+		filepath.Join(mspDir, "signcerts", testName+"-cert.pem"),
+		filepath.Join(mspDir, "enccerts", testName+"-cert.pem"),
+		filepath.Join(mspDir, "keystore", "priv_sk"),
+		filepath.Join(mspDir, "keystore", "enc_sk"),
+		filepath.Join(tlsDir, "server.crt"),
+		filepath.Join(tlsDir, "server.key"),
+		filepath.Join(tlsDir, "server-enc.crt"),
+		filepath.Join(tlsDir, "server-enc.key"),
+	} {
+		assert.Equal(t, true, checkForFile(file),
+			"Expected to find file "+file)
+	}
+
+	// the GMTLS key pairs load
+	for _, prefix := range []string{"server", "server-enc"} {
+		certPEM, err := ioutil.ReadFile(filepath.Join(tlsDir, prefix+".crt"))
+		assert.NoError(t, err)
+		keyPEM, err := ioutil.ReadFile(filepath.Join(tlsDir, prefix+".key"))
+		assert.NoError(t, err)
+		_, err = gmtls.X509KeyPair(certPEM, keyPEM)
+		assert.NoError(t, err)
+	}
+
+	// and so does the dual certificate MSP
+	conf, err := fabricmsp.GetLocalMspConfig(mspDir, nil, testName)
+	assert.NoError(t, err, "Failed to read local MSP config")
+	assert.NotNil(t, conf)
+}
+
 func testGenerateVerifyingMSP(t *testing.T, nodeOUs bool) {
 	caDir := filepath.Join(testDir, "ca")
 	tlsCADir := filepath.Join(testDir, "tlsca")
@@ -0,0 +1,262 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
+	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+// Algorithms of the signatures counted in Report.Signatures
+const (
+	AlgorithmSM2     = "SM2"
+	AlgorithmECDSA   = "ECDSA"
+	AlgorithmUnknown = "unknown"
+)
+
+// Report is the result of the verification of the block files of the
+// ledger of a channel
+type Report struct {
+	ChannelID string `json:"channelID"`
+	// HashingAlgorithm is the hashing algorithm of the blocks set by the
+	// genesis block, which the hash chain is verified with
+	HashingAlgorithm string `json:"hashingAlgorithm"`
+	// Blocks is the number of blocks read from the block files
+	Blocks uint64 `json:"blocks"`
+	// Signatures counts the verified block signatures, by algorithm
+	Signatures map[string]uint64 `json:"signatures"`
+	// Valid is true when no problem was found
+	Valid    bool       `json:"valid"`
+	Problems []*Problem `json:"problems,omitempty"`
+}
+
+// Problem is an integrity problem found in a block
+type Problem struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	File        string `json:"file"`
+	Offset      int64  `json:"offset"`
+	Description string `json:"description"`
+}
+
+// blockfileVerifier verifies the blocks of a ledger in the order they were
+// appended
+type blockfileVerifier struct {
+	csp          bccsp.BCCSP
+	report       *Report
+	hash         func([]byte) []byte
+	bundle       *channelconfig.Bundle
+	nextNumber   uint64
+	previousHash []byte
+	lastLocation fsblkstorage.BlockLocation
+}
+
+// VerifyBlockfiles verifies the integrity of the block files in ledgerDir,
+// the directory of the ledger of a channel in the block store of a peer or
+// an orderer. It verifies that the blocks chain with the hashing algorithm
+// configured in the genesis block, SHA-256 or SM3, that their data hashes
+// match their data, and that every block signature following the genesis
+// block is valid and issued by an orderer of the channel config in effect,
+// be it an ECDSA or an SM2 signature.
+func VerifyBlockfiles(ledgerDir string, csp bccsp.BCCSP) (*Report, error) {
+	v := &blockfileVerifier{
+		csp: csp,
+		report: &Report{
+			Signatures: map[string]uint64{},
+		},
+	}
+
+	err := fsblkstorage.ScanBlockfiles(ledgerDir, v.verifyBlock)
+	if errors.Cause(err) == fsblkstorage.ErrUnexpectedEndOfBlockfile {
+		v.addProblem(v.nextNumber, fsblkstorage.BlockLocation{File: v.lastLocation.File}, "partial block at the end of the block files")
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v.report.Valid = len(v.report.Problems) == 0
+	return v.report, nil
+}
+
+func (v *blockfileVerifier) verifyBlock(block *cb.Block, location fsblkstorage.BlockLocation) error {
+	v.lastLocation = location
+	v.report.Blocks++
+	if v.report.Blocks == 1 {
+		if block.Header == nil || block.Header.Number != 0 {
+			return errors.Errorf("the first block in %s is not a genesis block", location.File)
+		}
+		if err := v.initChannel(block); err != nil {
+			return errors.WithMessagef(err, "failed reading the channel config of the genesis block in %s", location.File)
+		}
+	}
+	if block.Header == nil || block.Data == nil {
+		v.addProblem(v.nextNumber, location, "block has no header or no data")
+		v.nextNumber++
+		v.previousHash = nil
+		return nil
+	}
+
+	number := block.Header.Number
+	if number != v.nextNumber {
+		v.addProblem(number, location, fmt.Sprintf("expected block [%d]", v.nextNumber))
+	}
+	if number > 0 && !bytes.Equal(block.Header.PreviousHash, v.previousHash) {
+		v.addProblem(number, location, "previous hash does not match the hash of the previous block")
+	}
+	if !bytes.Equal(protoutil.BlockDataHashWith(block.Data, v.hash), block.Header.DataHash) {
+		v.addProblem(number, location, "data hash does not match the block data")
+	}
+	if number > 0 {
+		v.verifySignatures(block, location)
+	}
+
+	if number > 0 && protoutil.IsConfigBlock(block) {
+		if err := v.updateConfig(block); err != nil {
+			v.addProblem(number, location, fmt.Sprintf("invalid config block: %s", err))
+		}
+	}
+
+	v.nextNumber = number + 1
+	v.previousHash = protoutil.BlockHeaderHashWith(block.Header, v.hash)
+	return nil
+}
+
+// initChannel reads the channel ID, the hashing algorithm and the channel
+// config the signatures are verified against from the genesis block.
+func (v *blockfileVerifier) initChannel(genesisBlock *cb.Block) error {
+	channelID, err := protoutil.GetChainIDFromBlock(genesisBlock)
+	if err != nil {
+		return err
+	}
+	v.report.ChannelID = channelID
+
+	if !protoutil.IsConfigBlock(genesisBlock) {
+		return errors.New("genesis block is not a config block")
+	}
+	if v.report.HashingAlgorithm, err = util.BlockHashingAlgorithmName(genesisBlock); err != nil {
+		return err
+	}
+	if v.hash, err = util.HashingAlgorithm(v.report.HashingAlgorithm); err != nil {
+		return err
+	}
+	return v.updateConfig(genesisBlock)
+}
+
+func (v *blockfileVerifier) updateConfig(configBlock *cb.Block) error {
+	env, err := protoutil.ExtractEnvelope(configBlock, 0)
+	if err != nil {
+		return errors.WithMessage(err, "failed extracting config envelope")
+	}
+	bundle, err := channelconfig.NewBundleFromEnvelope(env, v.csp)
+	if err != nil {
+		return errors.WithMessage(err, "failed building channel config")
+	}
+	v.bundle = bundle
+	return nil
+}
+
+// verifySignatures verifies each signature of block with the MSPs of the
+// channel config, and that the signatures satisfy the block validation
+// policy of the channel.
+func (v *blockfileVerifier) verifySignatures(block *cb.Block, location fsblkstorage.BlockLocation) {
+	number := block.Header.Number
+	metadata, err := protoutil.GetMetadataFromBlock(block, cb.BlockMetadataIndex_SIGNATURES)
+	if err != nil {
+		v.addProblem(number, location, fmt.Sprintf("failed reading signatures: %s", err))
+		return
+	}
+	if len(metadata.Signatures) == 0 {
+		v.addProblem(number, location, "block is not signed")
+		return
+	}
+
+	var signatureSet []*protoutil.SignedData
+	for i, metadataSignature := range metadata.Signatures {
+		shdr, err := protoutil.UnmarshalSignatureHeader(metadataSignature.SignatureHeader)
+		if err != nil {
+			v.addProblem(number, location, fmt.Sprintf("failed reading signature header %d: %s", i, err))
+			continue
+		}
+		data := util.ConcatenateBytes(metadata.Value, metadataSignature.SignatureHeader, protoutil.BlockHeaderBytes(block.Header))
+		algorithm := identityAlgorithm(shdr.Creator)
+
+		identity, err := v.bundle.MSPManager().DeserializeIdentity(shdr.Creator)
+		if err != nil {
+			v.addProblem(number, location, fmt.Sprintf("signer %d is not a member of the channel: %s", i, err))
+			continue
+		}
+		if err := identity.Verify(data, metadataSignature.Signature); err != nil {
+			v.addProblem(number, location, fmt.Sprintf("%s signature %d of %s is invalid: %s", algorithm, i, identity.GetIdentifier().Mspid, err))
+			continue
+		}
+		v.report.Signatures[algorithm]++
+
+		signatureSet = append(signatureSet, &protoutil.SignedData{
+			Identity:  shdr.Creator,
+			Data:      data,
+			Signature: metadataSignature.Signature,
+		})
+	}
+
+	policy, ok := v.bundle.PolicyManager().GetPolicy(policies.BlockValidation)
+	if !ok {
+		v.addProblem(number, location, "channel config has no block validation policy")
+		return
+	}
+	if err := policy.EvaluateSignedData(signatureSet); err != nil {
+		v.addProblem(number, location, fmt.Sprintf("signatures do not satisfy the block validation policy: %s", err))
+	}
+}
+
+func (v *blockfileVerifier) addProblem(number uint64, location fsblkstorage.BlockLocation, description string) {
+	v.report.Problems = append(v.report.Problems, &Problem{
+		BlockNumber: number,
+		File:        location.File,
+		Offset:      location.Offset,
+		Description: description,
+	})
+}
+
+// identityAlgorithm returns the algorithm of the certificate of the
+// serialized identity.
+func identityAlgorithm(serializedIdentity []byte) string {
+	sID := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(serializedIdentity, sID); err != nil {
+		return AlgorithmUnknown
+	}
+	block, _ := pem.Decode(sID.IdBytes)
+	if block == nil {
+		return AlgorithmUnknown
+	}
+	cert, err := gmx509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return AlgorithmUnknown
+	}
+	switch cert.PublicKey.(type) {
+	case *sm2.PublicKey:
+		return AlgorithmSM2
+	case *ecdsa.PublicKey:
+		return AlgorithmECDSA
+	default:
+		return AlgorithmUnknown
+	}
+}
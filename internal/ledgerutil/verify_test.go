@@ -0,0 +1,190 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerutil
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
+	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
+	"github.com/hyperledger/fabric/msp/mgmt"
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signer interface {
+	Sign(message []byte) ([]byte, error)
+	Serialize() ([]byte, error)
+}
+
+// signedBlock returns block number of the chain, signed by signer
+func signedBlock(t *testing.T, number uint64, previous *cb.Block, hash func([]byte) []byte, signer signer) *cb.Block {
+	env, err := protoutil.CreateSignedEnvelope(cb.HeaderType_MESSAGE, "mychannel", nil, &cb.Metadata{}, 0, 0)
+	require.NoError(t, err)
+	block := protoutil.NewBlock(number, protoutil.BlockHeaderHashWith(previous.Header, hash))
+	block.Data.Data = [][]byte{protoutil.MarshalOrPanic(env)}
+	block.Header.DataHash = protoutil.BlockDataHashWith(block.Data, hash)
+
+	creator, err := signer.Serialize()
+	require.NoError(t, err)
+	shdr := protoutil.MarshalOrPanic(&cb.SignatureHeader{Creator: creator, Nonce: []byte("nonce")})
+	signature, err := signer.Sign(util.ConcatenateBytes(nil, shdr, protoutil.BlockHeaderBytes(block.Header)))
+	require.NoError(t, err)
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = protoutil.MarshalOrPanic(&cb.Metadata{
+		Signatures: []*cb.MetadataSignature{{SignatureHeader: shdr, Signature: signature}},
+	})
+	return block
+}
+
+// writeLedger writes blocks to a block store in dir and returns the
+// directory of the block files of the ledger
+func writeLedger(t *testing.T, dir string, blocks []*cb.Block) string {
+	indexConfig := &blkstorage.IndexConfig{AttrsToIndex: []blkstorage.IndexableAttr{blkstorage.IndexableAttrBlockNum}}
+	provider, err := fsblkstorage.NewProvider(fsblkstorage.NewConf(dir, 0), indexConfig, &disabled.Provider{})
+	require.NoError(t, err)
+	defer provider.Close()
+	store, err := provider.CreateBlockStore("mychannel")
+	require.NoError(t, err)
+	for _, block := range blocks {
+		require.NoError(t, store.AddBlock(block))
+	}
+	store.Shutdown()
+	return filepath.Join(dir, fsblkstorage.ChainsDir, "mychannel")
+}
+
+func TestVerifyBlockfiles(t *testing.T) {
+	require.NoError(t, msptesttools.LoadMSPSetupForTesting())
+	csp := factory.GetDefault()
+	signer := mgmt.GetLocalSigningIdentityOrPanic(csp)
+
+	config := genesisconfig.Load(genesisconfig.SampleSingleMSPSoloProfile, configtest.GetDevConfigDir())
+	genesisBlock := encoder.New(config).GenesisBlockForChannel("mychannel")
+	hashingAlgorithm, err := util.BlockHashingAlgorithmName(genesisBlock)
+	require.NoError(t, err)
+	hash, err := util.HashingAlgorithm(hashingAlgorithm)
+	require.NoError(t, err)
+
+	blocks := []*cb.Block{genesisBlock}
+	for i := uint64(1); i <= 3; i++ {
+		blocks = append(blocks, signedBlock(t, i, blocks[i-1], hash, signer))
+	}
+
+	t.Run("Valid ledger", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ledgerutil")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		report, err := VerifyBlockfiles(writeLedger(t, dir, blocks), csp)
+		require.NoError(t, err)
+		assert.Equal(t, &Report{
+			ChannelID:        "mychannel",
+			HashingAlgorithm: hashingAlgorithm,
+			Blocks:           4,
+			Signatures:       map[string]uint64{AlgorithmECDSA: 3},
+			Valid:            true,
+		}, report)
+	})
+
+	t.Run("Tampered ledger", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ledgerutil")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		block1 := signedBlock(t, 1, genesisBlock, hash, signer)
+		block2 := signedBlock(t, 2, block1, hash, signer)
+		// the data of block 1 is altered after it was hashed
+		block1.Data.Data = append(block1.Data.Data, []byte("tampered"))
+		ledgerDir := writeLedger(t, dir, []*cb.Block{genesisBlock, block1, block2})
+
+		// the last block was partially written
+		blockfile := filepath.Join(ledgerDir, "blockfile_000000")
+		fileInfo, err := os.Stat(blockfile)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(blockfile, fileInfo.Size()-1))
+
+		report, err := VerifyBlockfiles(ledgerDir, csp)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, uint64(2), report.Blocks)
+		require.Len(t, report.Problems, 2)
+		assert.Equal(t, uint64(1), report.Problems[0].BlockNumber)
+		assert.Equal(t, "blockfile_000000", report.Problems[0].File)
+		assert.Equal(t, "data hash does not match the block data", report.Problems[0].Description)
+		assert.Equal(t, uint64(2), report.Problems[1].BlockNumber)
+		assert.Equal(t, "partial block at the end of the block files", report.Problems[1].Description)
+	})
+
+	t.Run("Invalid signature", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ledgerutil")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		block1 := signedBlock(t, 1, genesisBlock, hash, signer)
+		block2 := signedBlock(t, 2, block1, hash, signer)
+		block2.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = block1.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES]
+
+		report, err := VerifyBlockfiles(writeLedger(t, dir, []*cb.Block{genesisBlock, block1, block2}), csp)
+		require.NoError(t, err)
+		assert.False(t, report.Valid)
+		assert.Equal(t, map[string]uint64{AlgorithmECDSA: 1}, report.Signatures)
+		require.Len(t, report.Problems, 2)
+		assert.Contains(t, report.Problems[0].Description, "ECDSA signature 0 of SampleOrg is invalid")
+		assert.Contains(t, report.Problems[1].Description, "signatures do not satisfy the block validation policy")
+	})
+
+	t.Run("No block files", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "ledgerutil")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		_, err = VerifyBlockfiles(dir, csp)
+		assert.EqualError(t, err, "no block files found in "+dir)
+	})
+}
+
+func TestIdentityAlgorithm(t *testing.T) {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "orderer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	sID := protoutil.MarshalOrPanic(&msp.SerializedIdentity{
+		Mspid:   "OrdererMSP",
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+	assert.Equal(t, AlgorithmSM2, identityAlgorithm(sID))
+
+	sID = protoutil.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "OrdererMSP", IdBytes: []byte("identity")})
+	assert.Equal(t, AlgorithmUnknown, identityAlgorithm(sID))
+}
@@ -42,7 +42,13 @@ var (
 	timeout       time.Duration
 
 	// fetch related variables
-	bestEffort bool
+	bestEffort       bool
+	trustedBlockPath string
+
+	// signconfigtx and mergeconfigtx related variables
+	signatureOutput string
+	signatureFiles  []string
+	configBlockPath string
 )
 
 // Cmd returns the cobra command for Node
@@ -55,6 +61,7 @@ func Cmd(cf *ChannelCmdFactory) *cobra.Command {
 	channelCmd.AddCommand(listCmd(cf))
 	channelCmd.AddCommand(updateCmd(cf))
 	channelCmd.AddCommand(signconfigtxCmd(cf))
+	channelCmd.AddCommand(mergeconfigtxCmd(cf))
 	channelCmd.AddCommand(getinfoCmd(cf))
 
 	return channelCmd
@@ -81,6 +88,10 @@ func resetFlags() {
 	flags.StringVarP(&outputBlock, "outputBlock", "", common.UndefinedParamValue, `The path to write the genesis block for the channel. (default ./<channelID>.block)`)
 	flags.DurationVarP(&timeout, "timeout", "t", 10*time.Second, "Channel creation timeout")
 	flags.BoolVarP(&bestEffort, "bestEffort", "", false, "Whether fetch requests should ignore errors and return blocks on a best effort basis")
+	flags.StringVarP(&signatureOutput, "signatureOutput", "", "", "Path to write the signature of the config update to, instead of signing the configtx update file in place")
+	flags.StringSliceVarP(&signatureFiles, "signature", "s", nil, "Path to a file containing a config update signature written by 'signconfigtx --signatureOutput'. Can be repeated or comma separated")
+	flags.StringVarP(&configBlockPath, "configBlock", "", common.UndefinedParamValue, "Path to file containing the latest config block of the channel, whose MSPs the merged signatures are verified against")
+	flags.StringVarP(&trustedBlockPath, "trustedBlock", "", common.UndefinedParamValue, "Path to file containing a trusted config block of the channel, such as its genesis block, whose channel config fetched blocks are verified against")
 }
 
 func attachFlags(cmd *cobra.Command, names []string) {
@@ -96,8 +107,8 @@ func attachFlags(cmd *cobra.Command, names []string) {
 
 var channelCmd = &cobra.Command{
 	Use:   "channel",
-	Short: "Operate a channel: create|fetch|join|list|update|signconfigtx|getinfo.",
-	Long:  "Operate a channel: create|fetch|join|list|update|signconfigtx|getinfo.",
+	Short: "Operate a channel: create|fetch|join|list|update|signconfigtx|mergeconfigtx|getinfo.",
+	Long:  "Operate a channel: create|fetch|join|list|update|signconfigtx|mergeconfigtx|getinfo.",
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		common.InitCmd(cmd, args)
 		common.SetOrdererEnv(cmd, args)
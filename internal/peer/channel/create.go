@@ -14,7 +14,6 @@ import (
 	"github.com/golang/protobuf/proto"
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/common/configtx"
-	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
 	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
 	"github.com/hyperledger/fabric/internal/peer/common"
@@ -81,6 +80,24 @@ func createChannelFromConfigTx(configTxFileName string) (*cb.Envelope, error) {
 }
 
 func sanityCheckAndSignConfigTx(envConfigUpdate *cb.Envelope, signer identity.SignerSerializer) (*cb.Envelope, error) {
+	configUpdateEnv, err := sanityCheckConfigTx(envConfigUpdate)
+	if err != nil {
+		return nil, err
+	}
+
+	configSig, err := configtx.NewConfigSignature(configUpdateEnv.ConfigUpdate, signer)
+	if err != nil {
+		return nil, err
+	}
+
+	configUpdateEnv.Signatures = append(configUpdateEnv.Signatures, configSig)
+
+	return protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG_UPDATE, channelID, signer, configUpdateEnv, 0, 0)
+}
+
+// sanityCheckConfigTx checks that envConfigUpdate is a config update
+// transaction of the channel and returns its config update envelope.
+func sanityCheckConfigTx(envConfigUpdate *cb.Envelope) (*cb.ConfigUpdateEnvelope, error) {
 	payload, err := protoutil.UnmarshalPayload(envConfigUpdate.Payload)
 	if err != nil {
 		return nil, InvalidCreateTx("bad payload")
@@ -118,23 +135,7 @@ func sanityCheckAndSignConfigTx(envConfigUpdate *cb.Envelope, signer identity.Si
 		return nil, InvalidCreateTx("Bad config update env")
 	}
 
-	sigHeader, err := protoutil.NewSignatureHeader(signer)
-	if err != nil {
-		return nil, err
-	}
-
-	configSig := &cb.ConfigSignature{
-		SignatureHeader: protoutil.MarshalOrPanic(sigHeader),
-	}
-
-	configSig.Signature, err = signer.Sign(util.ConcatenateBytes(configSig.SignatureHeader, configUpdateEnv.ConfigUpdate))
-	if err != nil {
-		return nil, err
-	}
-
-	configUpdateEnv.Signatures = append(configUpdateEnv.Signatures, configSig)
-
-	return protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG_UPDATE, channelID, signer, configUpdateEnv, 0, 0)
+	return configUpdateEnv, nil
 }
 
 func sendCreateChainTransaction(cf *ChannelCmdFactory) error {
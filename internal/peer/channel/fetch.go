@@ -16,6 +16,8 @@ import (
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/internal/peer/common"
 	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -31,6 +33,7 @@ func fetchCmd(cf *ChannelCmdFactory) *cobra.Command {
 	flagList := []string{
 		"channelID",
 		"bestEffort",
+		"trustedBlock",
 	}
 	attachFlags(fetchCmd, flagList)
 
@@ -63,6 +66,18 @@ func fetch(cmd *cobra.Command, args []string, cf *ChannelCmdFactory) error {
 		}
 	}
 
+	if trustedBlockPath != common.UndefinedParamValue {
+		verifier, err := newTrustedBlockVerifier(trustedBlockPath)
+		if err != nil {
+			return err
+		}
+		dc, ok := cf.DeliverClient.(*common.DeliverClient)
+		if !ok {
+			return errors.New("deliver client does not support block verification")
+		}
+		dc.Verifier = verifier
+	}
+
 	var block *cb.Block
 
 	switch args[0] {
@@ -111,3 +126,21 @@ func fetch(cmd *cobra.Command, args []string, cf *ChannelCmdFactory) error {
 
 	return nil
 }
+
+// newTrustedBlockVerifier returns a verifier of the blocks fetched against
+// the channel config of the config block in file.
+func newTrustedBlockVerifier(file string) (*common.ConfigBlockVerifier, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading trusted block %s", file)
+	}
+	block := &cb.Block{}
+	if err := proto.Unmarshal(b, block); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling trusted block %s", file)
+	}
+	verifier, err := common.NewConfigBlockVerifier(block, factory.GetDefault())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "error loading trusted block %s", file)
+	}
+	return verifier, nil
+}
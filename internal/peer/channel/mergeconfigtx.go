@@ -0,0 +1,126 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channel
+
+import (
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/configtx"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func mergeconfigtxCmd(cf *ChannelCmdFactory) *cobra.Command {
+	mergeconfigtxCmd := &cobra.Command{
+		Use:   "mergeconfigtx",
+		Short: "Merges signatures into a configtx update.",
+		Long:  "Merges the config update signatures written by 'signconfigtx --signatureOutput' into the supplied configtx update file in place on the filesystem. Requires '-f' and '-s'.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return merge(cmd, args, cf)
+		},
+	}
+	flagList := []string{
+		"file",
+		"signature",
+		"configBlock",
+	}
+	attachFlags(mergeconfigtxCmd, flagList)
+
+	return mergeconfigtxCmd
+}
+
+func merge(cmd *cobra.Command, args []string, cf *ChannelCmdFactory) error {
+	if channelTxFile == "" {
+		return InvalidCreateTx("No configtx file name supplied")
+	}
+	if len(signatureFiles) == 0 {
+		return errors.New("no signature file supplied")
+	}
+	// Parsing of the command line is done so silence cmd usage
+	cmd.SilenceUsage = true
+
+	fileData, err := ioutil.ReadFile(channelTxFile)
+	if err != nil {
+		return ConfigTxFileNotFound(err.Error())
+	}
+
+	ctxEnv, err := protoutil.UnmarshalEnvelope(fileData)
+	if err != nil {
+		return err
+	}
+
+	configUpdateEnv, err := sanityCheckConfigTx(ctxEnv)
+	if err != nil {
+		return err
+	}
+
+	var configSigs []*cb.ConfigSignature
+	for _, file := range signatureFiles {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return errors.Wrapf(err, "error reading signature %s", file)
+		}
+		configSig := &cb.ConfigSignature{}
+		if err := proto.Unmarshal(b, configSig); err != nil {
+			return errors.Wrapf(err, "error unmarshaling signature %s", file)
+		}
+		configSigs = append(configSigs, configSig)
+	}
+
+	var deserializer msp.IdentityDeserializer
+	if configBlockPath != common.UndefinedParamValue {
+		deserializer, err = channelMSPManager(configBlockPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := configtx.AddConfigSignatures(configUpdateEnv, deserializer, configSigs...); err != nil {
+		return err
+	}
+
+	// The envelope is left unsigned, as the config update is signed again
+	// by the submitter of 'peer channel update' or 'peer channel create'
+	mCtxEnv, err := protoutil.CreateSignedEnvelope(cb.HeaderType_CONFIG_UPDATE, channelID, nil, configUpdateEnv, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(channelTxFile, protoutil.MarshalOrPanic(mCtxEnv), 0660)
+}
+
+// channelMSPManager returns the MSP manager of the channel config of the
+// config block in file.
+func channelMSPManager(file string) (msp.MSPManager, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading config block %s", file)
+	}
+	block := &cb.Block{}
+	if err := proto.Unmarshal(b, block); err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling config block %s", file)
+	}
+	if !protoutil.IsConfigBlock(block) {
+		return nil, errors.Errorf("block %s is not a config block", file)
+	}
+	env, err := protoutil.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "error extracting config envelope of %s", file)
+	}
+	bundle, err := channelconfig.NewBundleFromEnvelope(env, factory.GetDefault())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "error loading channel config of %s", file)
+	}
+	return bundle.MSPManager(), nil
+}
@@ -0,0 +1,132 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channel
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
+	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeConfigtx(t *testing.T) {
+	defer resetFlags()
+	InitMSP()
+
+	dir, err := ioutil.TempDir("", "mergeconfigtxtest-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configtxFile := filepath.Join(dir, mockChannel)
+	_, err = createTxFile(configtxFile, cb.HeaderType_CONFIG_UPDATE, mockChannel)
+	require.NoError(t, err)
+
+	signer, err := common.GetDefaultSigner()
+	require.NoError(t, err)
+	mockCF := &ChannelCmdFactory{
+		Signer: signer,
+	}
+
+	// sign the config update offline, leaving the configtx file unchanged
+	resetFlags()
+	signatureFile := filepath.Join(dir, "signature")
+	cmd := signconfigtxCmd(mockCF)
+	AddFlags(cmd)
+	cmd.SetArgs([]string{"-f", configtxFile, "--signatureOutput", signatureFile})
+	require.NoError(t, cmd.Execute())
+	assert.FileExists(t, signatureFile)
+	assert.Empty(t, configUpdateSignatures(t, configtxFile))
+
+	config := genesisconfig.Load(genesisconfig.SampleSingleMSPSoloProfile, configtest.GetDevConfigDir())
+	configBlockFile := filepath.Join(dir, "config.block")
+	genesisBlock := encoder.New(config).GenesisBlockForChannel("mockchannel")
+	require.NoError(t, ioutil.WriteFile(configBlockFile, protoutil.MarshalOrPanic(genesisBlock), 0644))
+
+	resetFlags()
+	cmd = mergeconfigtxCmd(mockCF)
+	AddFlags(cmd)
+	cmd.SetArgs([]string{"-f", configtxFile, "-s", signatureFile, "--configBlock", configBlockFile})
+	require.NoError(t, cmd.Execute())
+	assert.Len(t, configUpdateSignatures(t, configtxFile), 1)
+
+	// merging the signature again leaves a single signature
+	resetFlags()
+	cmd = mergeconfigtxCmd(mockCF)
+	AddFlags(cmd)
+	cmd.SetArgs([]string{"-f", configtxFile, "-s", signatureFile})
+	require.NoError(t, cmd.Execute())
+	assert.Len(t, configUpdateSignatures(t, configtxFile), 1)
+}
+
+func TestMergeConfigtxInvalidSignature(t *testing.T) {
+	defer resetFlags()
+	InitMSP()
+
+	dir, err := ioutil.TempDir("", "mergeconfigtxtest-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	configtxFile := filepath.Join(dir, mockChannel)
+	_, err = createTxFile(configtxFile, cb.HeaderType_CONFIG_UPDATE, mockChannel)
+	require.NoError(t, err)
+
+	signer, err := common.GetDefaultSigner()
+	require.NoError(t, err)
+	mockCF := &ChannelCmdFactory{
+		Signer: signer,
+	}
+
+	config := genesisconfig.Load(genesisconfig.SampleSingleMSPSoloProfile, configtest.GetDevConfigDir())
+	configBlockFile := filepath.Join(dir, "config.block")
+	genesisBlock := encoder.New(config).GenesisBlockForChannel("mockchannel")
+	require.NoError(t, ioutil.WriteFile(configBlockFile, protoutil.MarshalOrPanic(genesisBlock), 0644))
+
+	sigHeader, err := protoutil.NewSignatureHeader(signer)
+	require.NoError(t, err)
+	signatureFile := filepath.Join(dir, "signature")
+	require.NoError(t, ioutil.WriteFile(signatureFile, protoutil.MarshalOrPanic(&cb.ConfigSignature{
+		SignatureHeader: protoutil.MarshalOrPanic(sigHeader),
+		Signature:       []byte("signature"),
+	}), 0644))
+
+	resetFlags()
+	cmd := mergeconfigtxCmd(mockCF)
+	AddFlags(cmd)
+	cmd.SetArgs([]string{"-f", configtxFile, "-s", signatureFile, "--configBlock", configBlockFile})
+	err = cmd.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "signature 0 of SampleOrg does not sign the config update")
+	assert.Empty(t, configUpdateSignatures(t, configtxFile))
+}
+
+func TestMergeConfigtxMissingSignatureFlag(t *testing.T) {
+	defer resetFlags()
+	InitMSP()
+
+	cmd := mergeconfigtxCmd(&ChannelCmdFactory{})
+	AddFlags(cmd)
+	cmd.SetArgs([]string{"-f", "configtx"})
+
+	assert.EqualError(t, cmd.Execute(), "no signature file supplied")
+}
+
+func configUpdateSignatures(t *testing.T, configtxFile string) []*cb.ConfigSignature {
+	env, err := createChannelFromConfigTx(configtxFile)
+	require.NoError(t, err)
+	configUpdateEnv, err := sanityCheckConfigTx(env)
+	require.NoError(t, err)
+	return configUpdateEnv.Signatures
+}
@@ -9,6 +9,8 @@ package channel
 import (
 	"io/ioutil"
 
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/configtx"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/spf13/cobra"
 )
@@ -17,13 +19,14 @@ func signconfigtxCmd(cf *ChannelCmdFactory) *cobra.Command {
 	signconfigtxCmd := &cobra.Command{
 		Use:   "signconfigtx",
 		Short: "Signs a configtx update.",
-		Long:  "Signs the supplied configtx update file in place on the filesystem. Requires '-f'.",
+		Long:  "Signs the supplied configtx update file in place on the filesystem. Requires '-f'. With '--signatureOutput', the file is left unchanged and the signature is written to a separate file instead, to be merged with 'mergeconfigtx'.",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return sign(cmd, args, cf)
 		},
 	}
 	flagList := []string{
 		"file",
+		"signatureOutput",
 	}
 	attachFlags(signconfigtxCmd, flagList)
 
@@ -55,6 +58,10 @@ func sign(cmd *cobra.Command, args []string, cf *ChannelCmdFactory) error {
 		return err
 	}
 
+	if signatureOutput != "" {
+		return signDetached(ctxEnv, cf)
+	}
+
 	sCtxEnv, err := sanityCheckAndSignConfigTx(ctxEnv, cf.Signer)
 	if err != nil {
 		return err
@@ -64,3 +71,21 @@ func sign(cmd *cobra.Command, args []string, cf *ChannelCmdFactory) error {
 
 	return ioutil.WriteFile(channelTxFile, sCtxEnvData, 0660)
 }
+
+// signDetached writes the signature of the config update of ctxEnv to
+// signatureOutput, so that config updates can be signed where the signing
+// key is kept, e.g. in an HSM or an air-gapped keystore, without exchanging
+// the whole config update transaction.
+func signDetached(ctxEnv *cb.Envelope, cf *ChannelCmdFactory) error {
+	configUpdateEnv, err := sanityCheckConfigTx(ctxEnv)
+	if err != nil {
+		return err
+	}
+
+	configSig, err := configtx.NewConfigSignature(configUpdateEnv.ConfigUpdate, cf.Signer)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(signatureOutput, protoutil.MarshalOrPanic(configSig), 0660)
+}
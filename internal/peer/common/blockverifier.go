@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"bytes"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+)
+
+// DeliverVerifier verifies the seek requests a DeliverClient sends and the
+// blocks it receives
+type DeliverVerifier interface {
+	// VerifySeekEnvelope verifies that the seek request is signed by an
+	// identity the deliver service grants access to the channel
+	VerifySeekEnvelope(env *cb.Envelope) error
+
+	// VerifyBlock verifies that the block is signed by the orderers of
+	// the channel
+	VerifyBlock(block *cb.Block) error
+}
+
+// ConfigBlockVerifier verifies blocks and seek requests against the channel
+// config of a trusted config block, such as the genesis block of the
+// channel. Signatures are verified by the MSPs of the channel config with
+// the configured BCCSP provider, so that the SM2 signatures of the orderers
+// and clients of GM networks are verified as the peers verify them. The
+// channel config is updated with the config blocks verified afterwards.
+type ConfigBlockVerifier struct {
+	channelID string
+	csp       bccsp.BCCSP
+	bundle    *channelconfig.Bundle
+	// number is the number of the config block the bundle was built from
+	number uint64
+}
+
+// NewConfigBlockVerifier creates a ConfigBlockVerifier trusting the channel
+// config of configBlock.
+func NewConfigBlockVerifier(configBlock *cb.Block, csp bccsp.BCCSP) (*ConfigBlockVerifier, error) {
+	v := &ConfigBlockVerifier{csp: csp}
+	if err := v.updateConfig(configBlock); err != nil {
+		return nil, err
+	}
+	v.channelID = v.bundle.ConfigtxValidator().ChannelID()
+	return v, nil
+}
+
+func (v *ConfigBlockVerifier) updateConfig(configBlock *cb.Block) error {
+	if configBlock.Header == nil || !protoutil.IsConfigBlock(configBlock) {
+		return errors.New("block is not a config block")
+	}
+	env, err := protoutil.ExtractEnvelope(configBlock, 0)
+	if err != nil {
+		return errors.WithMessage(err, "failed extracting config envelope")
+	}
+	bundle, err := channelconfig.NewBundleFromEnvelope(env, v.csp)
+	if err != nil {
+		return errors.WithMessage(err, "failed building channel config")
+	}
+	v.bundle = bundle
+	v.number = configBlock.Header.Number
+	return nil
+}
+
+// VerifySeekEnvelope verifies that env is signed by an identity satisfying
+// the readers policy of the channel, which the deliver service evaluates
+// before delivering blocks.
+func (v *ConfigBlockVerifier) VerifySeekEnvelope(env *cb.Envelope) error {
+	signedData, err := protoutil.EnvelopeAsSignedData(env)
+	if err != nil {
+		return errors.WithMessage(err, "failed extracting signature of seek request")
+	}
+	policy, _ := v.bundle.PolicyManager().GetPolicy(policies.ChannelReaders)
+	if err := policy.EvaluateSignedData(signedData); err != nil {
+		return errors.WithMessagef(err, "seek request does not satisfy the readers policy of channel %s", v.channelID)
+	}
+	return nil
+}
+
+// VerifyBlock verifies that the data hash of block matches its data, and
+// that block is signed according to the block validation policy of the
+// channel. Config blocks following the trusted config block update the
+// channel config the blocks following them are verified against.
+func (v *ConfigBlockVerifier) VerifyBlock(block *cb.Block) error {
+	if block.Header == nil {
+		return errors.New("block has no header")
+	}
+	channelID, err := protoutil.GetChainIDFromBlock(block)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting channel ID of block [%d]", block.Header.Number)
+	}
+	if channelID != v.channelID {
+		return errors.Errorf("block [%d] is of channel %s, expected %s", block.Header.Number, channelID, v.channelID)
+	}
+
+	hashingAlgorithm := channelconfig.BlockHashingAlgorithm(v.bundle.ChannelConfig())
+	if !bytes.Equal(protoutil.BlockDataHashWith(block.Data, hashingAlgorithm), block.Header.DataHash) {
+		return errors.Errorf("data hash of block [%d] does not match its data", block.Header.Number)
+	}
+
+	metadata, err := protoutil.GetMetadataFromBlock(block, cb.BlockMetadataIndex_SIGNATURES)
+	if err != nil {
+		return errors.WithMessagef(err, "failed getting signatures of block [%d]", block.Header.Number)
+	}
+	var signatureSet []*protoutil.SignedData
+	for _, metadataSignature := range metadata.Signatures {
+		shdr, err := protoutil.UnmarshalSignatureHeader(metadataSignature.SignatureHeader)
+		if err != nil {
+			return errors.WithMessagef(err, "failed unmarshaling signature header of block [%d]", block.Header.Number)
+		}
+		signatureSet = append(signatureSet, &protoutil.SignedData{
+			Identity:  shdr.Creator,
+			Data:      util.ConcatenateBytes(metadata.Value, metadataSignature.SignatureHeader, protoutil.BlockHeaderBytes(block.Header)),
+			Signature: metadataSignature.Signature,
+		})
+	}
+	policy, _ := v.bundle.PolicyManager().GetPolicy(policies.BlockValidation)
+	if err := policy.EvaluateSignedData(signatureSet); err != nil {
+		return errors.WithMessagef(err, "block [%d] does not satisfy the block validation policy of channel %s", block.Header.Number, v.channelID)
+	}
+
+	if block.Header.Number > v.number && protoutil.IsConfigBlock(block) {
+		return v.updateConfig(block)
+	}
+	return nil
+}
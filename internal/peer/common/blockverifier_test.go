@@ -0,0 +1,131 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"testing"
+
+	cb "github.com/hyperledger/fabric-protos-go/common"
+	ab "github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
+	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
+	"github.com/hyperledger/fabric/internal/peer/common/mock"
+	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signedBlock(t *testing.T, number uint64, hash func([]byte) []byte, signer Signer) *cb.Block {
+	env, err := protoutil.CreateSignedEnvelope(cb.HeaderType_MESSAGE, "mychannel", nil, &cb.Metadata{}, 0, 0)
+	require.NoError(t, err)
+	block := protoutil.NewBlock(number, nil)
+	block.Data.Data = [][]byte{protoutil.MarshalOrPanic(env)}
+	block.Header.DataHash = protoutil.BlockDataHashWith(block.Data, hash)
+
+	creator, err := signer.Serialize()
+	require.NoError(t, err)
+	shdr := protoutil.MarshalOrPanic(&cb.SignatureHeader{Creator: creator, Nonce: []byte("nonce")})
+	signature, err := signer.Sign(util.ConcatenateBytes(nil, shdr, protoutil.BlockHeaderBytes(block.Header)))
+	require.NoError(t, err)
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = protoutil.MarshalOrPanic(&cb.Metadata{
+		Signatures: []*cb.MetadataSignature{{SignatureHeader: shdr, Signature: signature}},
+	})
+	return block
+}
+
+func TestConfigBlockVerifier(t *testing.T) {
+	InitMSP()
+	signer, err := GetDefaultSigner()
+	require.NoError(t, err)
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	config := genesisconfig.Load(genesisconfig.SampleSingleMSPSoloProfile, configtest.GetDevConfigDir())
+	genesisBlock := encoder.New(config).GenesisBlockForChannel("mychannel")
+
+	v, err := NewConfigBlockVerifier(genesisBlock, cryptoProvider)
+	require.NoError(t, err)
+	hash := v.bundle.ChannelConfig().HashingAlgorithm()
+
+	t.Run("Valid block", func(t *testing.T) {
+		assert.NoError(t, v.VerifyBlock(signedBlock(t, 1, hash, signer)))
+	})
+
+	t.Run("Tampered data", func(t *testing.T) {
+		block := signedBlock(t, 1, hash, signer)
+		block.Data.Data = append(block.Data.Data, []byte("tampered"))
+		assert.EqualError(t, v.VerifyBlock(block), "data hash of block [1] does not match its data")
+	})
+
+	t.Run("Unsigned block", func(t *testing.T) {
+		block := signedBlock(t, 1, hash, signer)
+		block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = protoutil.MarshalOrPanic(&cb.Metadata{})
+		err := v.VerifyBlock(block)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "block [1] does not satisfy the block validation policy of channel mychannel")
+	})
+
+	t.Run("Seek request", func(t *testing.T) {
+		env, err := seekHelper("mychannel", seekNewest, nil, signer, false)
+		require.NoError(t, err)
+		assert.NoError(t, v.VerifySeekEnvelope(env))
+
+		env, err = seekHelper("mychannel", seekNewest, nil, nil, false)
+		require.NoError(t, err)
+		err = v.VerifySeekEnvelope(env)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "seek request does not satisfy the readers policy of channel mychannel")
+	})
+
+	t.Run("Not a config block", func(t *testing.T) {
+		_, err := NewConfigBlockVerifier(signedBlock(t, 1, hash, signer), cryptoProvider)
+		assert.EqualError(t, err, "block is not a config block")
+	})
+}
+
+func TestDeliverClientVerifier(t *testing.T) {
+	InitMSP()
+	signer, err := GetDefaultSigner()
+	require.NoError(t, err)
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+
+	config := genesisconfig.Load(genesisconfig.SampleSingleMSPSoloProfile, configtest.GetDevConfigDir())
+	genesisBlock := encoder.New(config).GenesisBlockForChannel("mychannel")
+	v, err := NewConfigBlockVerifier(genesisBlock, cryptoProvider)
+	require.NoError(t, err)
+
+	mockClient := &mock.DeliverService{}
+	o := &DeliverClient{
+		Signer:    signer,
+		Service:   mockClient,
+		ChannelID: "mychannel",
+		Verifier:  v,
+	}
+
+	block := signedBlock(t, 1, v.bundle.ChannelConfig().HashingAlgorithm(), signer)
+	mockClient.RecvReturnsOnCall(0, &ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: block}}, nil)
+	mockClient.RecvReturnsOnCall(1, &ab.DeliverResponse{Type: &ab.DeliverResponse_Status{Status: cb.Status_SUCCESS}}, nil)
+	received, err := o.GetSpecifiedBlock(1)
+	require.NoError(t, err)
+	assert.Equal(t, block, received)
+
+	block.Data.Data = append(block.Data.Data, []byte("tampered"))
+	mockClient.RecvReturnsOnCall(2, &ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: block}}, nil)
+	_, err = o.GetSpecifiedBlock(1)
+	assert.EqualError(t, err, "received block failed verification: data hash of block [1] does not match its data")
+
+	// seek requests which the deliver service would reject are not sent
+	o.Signer = nil
+	_, err = o.GetNewestBlock()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error getting newest block: seek request failed verification")
+	assert.Equal(t, 2, mockClient.SendCallCount())
+}
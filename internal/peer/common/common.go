@@ -132,13 +132,9 @@ func InitCrypto(mspMgrConfigDir, localMSPID, localMSPType string) error {
 	}
 
 	// Init the BCCSP
-	SetBCCSPKeystorePath()
-	bccspConfig := factory.GetDefaultOpts()
-	if config := viper.Get("peer.BCCSP"); config != nil {
-		err = mapstructure.Decode(config, bccspConfig)
-		if err != nil {
-			return errors.WithMessage(err, "could not decode peer BCCSP configuration")
-		}
+	bccspConfig, err := GetBCCSPConfig()
+	if err != nil {
+		return err
 	}
 
 	err = mspmgmt.LoadLocalMspWithType(mspMgrConfigDir, bccspConfig, localMSPID, localMSPType)
@@ -149,11 +145,29 @@ func InitCrypto(mspMgrConfigDir, localMSPID, localMSPType string) error {
 	return nil
 }
 
-// SetBCCSPKeystorePath sets the file keystore path for the SW BCCSP provider
-// to an absolute path relative to the config file
+// GetBCCSPConfig returns the BCCSP configuration of the peer, with the
+// paths of file keystores made absolute
+func GetBCCSPConfig() (*factory.FactoryOpts, error) {
+	SetBCCSPKeystorePath()
+	bccspConfig := factory.GetDefaultOpts()
+	if config := viper.Get("peer.BCCSP"); config != nil {
+		err := mapstructure.Decode(config, bccspConfig)
+		if err != nil {
+			return nil, errors.WithMessage(err, "could not decode peer BCCSP configuration")
+		}
+	}
+	return bccspConfig, nil
+}
+
+// SetBCCSPKeystorePath sets the file keystore paths for the SW and GM BCCSP
+// providers to absolute paths relative to the config file
 func SetBCCSPKeystorePath() {
 	viper.Set("peer.BCCSP.SW.FileKeyStore.KeyStore",
 		config.GetPath("peer.BCCSP.SW.FileKeyStore.KeyStore"))
+	if viper.IsSet("peer.BCCSP.GM.KeyStore.Path") {
+		viper.Set("peer.BCCSP.GM.KeyStore.Path",
+			config.GetPath("peer.BCCSP.GM.KeyStore.Path"))
+	}
 }
 
 // GetDefaultSigner return a default Signer(Default/PEER) for cli
@@ -312,6 +326,12 @@ func InitCmd(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	// key management creates the material of the local MSP
+	if strings.HasPrefix(cmd.CommandPath(), "peer keys") {
+		mainLogger.Debug("peer keys does not need to init crypto")
+		return
+	}
+
 	// Init the MSP
 	var mspMgrConfigDir = config.GetPath("peer.mspConfigPath")
 	var mspID = viper.GetString("peer.localMspId")
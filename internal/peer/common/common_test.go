@@ -91,6 +91,7 @@ func TestInitCrypto(t *testing.T) {
 
 func TestSetBCCSPKeystorePath(t *testing.T) {
 	cfgKey := "peer.BCCSP.SW.FileKeyStore.KeyStore"
+	gmCfgKey := "peer.BCCSP.GM.KeyStore.Path"
 	cfgPath := "./testdata"
 	absPath, _ := filepath.Abs(cfgPath)
 	keystorePath := "/msp/keystore"
@@ -101,12 +102,14 @@ func TestSetBCCSPKeystorePath(t *testing.T) {
 	common.SetBCCSPKeystorePath()
 	t.Log(viper.GetString(cfgKey))
 	assert.Equal(t, "", viper.GetString(cfgKey))
+	assert.False(t, viper.IsSet(gmCfgKey))
 
 	viper.Reset()
 	_ = common.InitConfig("absolute")
 	common.SetBCCSPKeystorePath()
 	t.Log(viper.GetString(cfgKey))
 	assert.Equal(t, keystorePath, viper.GetString(cfgKey))
+	assert.Equal(t, keystorePath, viper.GetString(gmCfgKey))
 
 	viper.Reset()
 	_ = common.InitConfig("relative")
@@ -114,6 +117,8 @@ func TestSetBCCSPKeystorePath(t *testing.T) {
 	t.Log(viper.GetString(cfgKey))
 	assert.Equal(t, filepath.Join(absPath, keystorePath),
 		viper.GetString(cfgKey))
+	assert.Equal(t, filepath.Join(absPath, keystorePath),
+		viper.GetString(gmCfgKey))
 
 	viper.Reset()
 	os.Unsetenv("FABRIC_CFG_PATH")
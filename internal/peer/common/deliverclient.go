@@ -40,6 +40,9 @@ type DeliverClient struct {
 	ChannelID   string
 	TLSCertHash []byte
 	BestEffort  bool
+	// Verifier, when set, verifies the seek requests before they are sent
+	// and the blocks as they are received
+	Verifier DeliverVerifier
 }
 
 func (d *DeliverClient) seekSpecified(blockNumber uint64) error {
@@ -50,17 +53,27 @@ func (d *DeliverClient) seekSpecified(blockNumber uint64) error {
 			},
 		},
 	}
-	env := seekHelper(d.ChannelID, seekPosition, d.TLSCertHash, d.Signer, d.BestEffort)
-	return d.Service.Send(env)
+	return d.seek(seekPosition)
 }
 
 func (d *DeliverClient) seekOldest() error {
-	env := seekHelper(d.ChannelID, seekOldest, d.TLSCertHash, d.Signer, d.BestEffort)
-	return d.Service.Send(env)
+	return d.seek(seekOldest)
 }
 
 func (d *DeliverClient) seekNewest() error {
-	env := seekHelper(d.ChannelID, seekNewest, d.TLSCertHash, d.Signer, d.BestEffort)
+	return d.seek(seekNewest)
+}
+
+func (d *DeliverClient) seek(position *ab.SeekPosition) error {
+	env, err := seekHelper(d.ChannelID, position, d.TLSCertHash, d.Signer, d.BestEffort)
+	if err != nil {
+		return err
+	}
+	if d.Verifier != nil {
+		if err := d.Verifier.VerifySeekEnvelope(env); err != nil {
+			return errors.WithMessage(err, "seek request failed verification")
+		}
+	}
 	return d.Service.Send(env)
 }
 
@@ -75,6 +88,11 @@ func (d *DeliverClient) readBlock() (*cb.Block, error) {
 		return nil, errors.Errorf("can't read the block: %v", t)
 	case *ab.DeliverResponse_Block:
 		logger.Infof("Received block: %v", t.Block.Header.Number)
+		if d.Verifier != nil {
+			if err := d.Verifier.VerifyBlock(t.Block); err != nil {
+				return nil, errors.WithMessage(err, "received block failed verification")
+			}
+		}
 		if resp, err := d.Service.Recv(); err != nil { // Flush the success message
 			logger.Errorf("Failed to flush success message: %s", err)
 		} else if status := resp.GetStatus(); status != cb.Status_SUCCESS {
@@ -129,7 +147,7 @@ func seekHelper(
 	tlsCertHash []byte,
 	signer identity.SignerSerializer,
 	bestEffort bool,
-) *cb.Envelope {
+) (*cb.Envelope, error) {
 	seekInfo := &ab.SeekInfo{
 		Start:    position,
 		Stop:     position,
@@ -150,11 +168,10 @@ func seekHelper(
 		tlsCertHash,
 	)
 	if err != nil {
-		logger.Errorf("Error signing envelope:  %s", err)
-		return nil
+		return nil, errors.WithMessage(err, "error signing seek request")
 	}
 
-	return env
+	return env, nil
 }
 
 type ordererDeliverService struct {
@@ -104,20 +104,22 @@ func TestDeliverClientErrors(t *testing.T) {
 
 func TestSeekHelper(t *testing.T) {
 	t.Run("Standard", func(t *testing.T) {
-		env := seekHelper("channel-id", &ab.SeekPosition{}, nil, nil, false)
+		env, err := seekHelper("channel-id", &ab.SeekPosition{}, nil, nil, false)
+		assert.NoError(t, err)
 		assert.NotNil(t, env)
 		seekInfo := &ab.SeekInfo{}
-		_, err := protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_DELIVER_SEEK_INFO, seekInfo)
+		_, err = protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_DELIVER_SEEK_INFO, seekInfo)
 		assert.NoError(t, err)
 		assert.Equal(t, seekInfo.Behavior, ab.SeekInfo_BLOCK_UNTIL_READY)
 		assert.Equal(t, seekInfo.ErrorResponse, ab.SeekInfo_STRICT)
 	})
 
 	t.Run("BestEffort", func(t *testing.T) {
-		env := seekHelper("channel-id", &ab.SeekPosition{}, nil, nil, true)
+		env, err := seekHelper("channel-id", &ab.SeekPosition{}, nil, nil, true)
+		assert.NoError(t, err)
 		assert.NotNil(t, env)
 		seekInfo := &ab.SeekInfo{}
-		_, err := protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_DELIVER_SEEK_INFO, seekInfo)
+		_, err = protoutil.UnmarshalEnvelopeOfType(env, cb.HeaderType_DELIVER_SEEK_INFO, seekInfo)
 		assert.NoError(t, err)
 		assert.Equal(t, seekInfo.ErrorResponse, ab.SeekInfo_BEST_EFFORT)
 	})
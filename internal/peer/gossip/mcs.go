@@ -32,6 +32,10 @@ type Hasher interface {
 	Hash(msg []byte, opts bccsp.HashOpts) (hash []byte, err error)
 }
 
+// ChannelHashingAlgorithmGetter returns the hashing algorithm of the blocks
+// of a channel, or nil when they are hashed with SHA-256.
+type ChannelHashingAlgorithmGetter func(channelID string) func(data []byte) []byte
+
 // MSPMessageCryptoService implements the MessageCryptoService interface
 // using the peer MSPs (local and channel-related)
 //
@@ -46,6 +50,8 @@ type MSPMessageCryptoService struct {
 	localSigner                identity.SignerSerializer
 	deserializer               mgmt.DeserializersManager
 	hasher                     Hasher
+	pkiIDHashOpts              bccsp.HashOpts
+	hashingAlgorithmGetter     ChannelHashingAlgorithmGetter
 }
 
 // NewMCS creates a new instance of MSPMessageCryptoService
@@ -54,17 +60,28 @@ type MSPMessageCryptoService struct {
 // 1. a policies.ChannelPolicyManagerGetter that gives access to the policy manager of a given channel via the Manager method.
 // 2. an instance of identity.SignerSerializer
 // 3. an identity deserializer manager
+// 4. the hasher computing the PKI-IDs of identities
+// 5. the hash options of the PKI-IDs, SHA2-256 when nil
+// 6. the getter of the hashing algorithm of the blocks of channels, which
+// are hashed with SHA2-256 when nil
 func NewMCS(
 	channelPolicyManagerGetter policies.ChannelPolicyManagerGetter,
 	localSigner identity.SignerSerializer,
 	deserializer mgmt.DeserializersManager,
 	hasher Hasher,
+	pkiIDHashOpts bccsp.HashOpts,
+	hashingAlgorithmGetter ChannelHashingAlgorithmGetter,
 ) *MSPMessageCryptoService {
+	if pkiIDHashOpts == nil {
+		pkiIDHashOpts = &bccsp.SHA256Opts{}
+	}
 	return &MSPMessageCryptoService{
 		channelPolicyManagerGetter: channelPolicyManagerGetter,
 		localSigner:                localSigner,
 		deserializer:               deserializer,
 		hasher:                     hasher,
+		pkiIDHashOpts:              pkiIDHashOpts,
+		hashingAlgorithmGetter:     hashingAlgorithmGetter,
 	}
 }
 
@@ -82,8 +99,9 @@ func (s *MSPMessageCryptoService) ValidateIdentity(peerIdentity api.PeerIdentity
 
 // GetPKIidOfCert returns the PKI-ID of a peer's identity
 // If any error occurs, the method return nil
-// The PKid of a peer is computed as the SHA2-256 of peerIdentity which
-// is supposed to be the serialized version of MSP identity.
+// The PKid of a peer is computed as the hash, with the PKI-ID hash options
+// of the MCS, of peerIdentity which is supposed to be the serialized
+// version of MSP identity.
 // This method does not validate peerIdentity.
 // This validation is supposed to be done appropriately during the execution flow.
 func (s *MSPMessageCryptoService) GetPKIidOfCert(peerIdentity api.PeerIdentityType) common.PKIidType {
@@ -109,7 +127,7 @@ func (s *MSPMessageCryptoService) GetPKIidOfCert(peerIdentity api.PeerIdentityTy
 	raw := append(mspIDRaw, sid.IdBytes...)
 
 	// Hash
-	digest, err := s.hasher.Hash(raw, &bccsp.SHA256Opts{})
+	digest, err := s.hasher.Hash(raw, s.pkiIDHashOpts)
 	if err != nil {
 		mcsLogger.Errorf("Failed computing digest of serialized identity %s: [%s]", peerIdentity, err)
 		return nil
@@ -153,7 +171,11 @@ func (s *MSPMessageCryptoService) VerifyBlock(chainID common.ChannelID, seqNum u
 
 	// - Verify that Header.DataHash is equal to the hash of block.Data
 	// This is to ensure that the header is consistent with the data carried by this block
-	if !bytes.Equal(protoutil.BlockDataHash(block.Data), block.Header.DataHash) {
+	var hashingAlgorithm func([]byte) []byte
+	if s.hashingAlgorithmGetter != nil {
+		hashingAlgorithm = s.hashingAlgorithmGetter(channelID)
+	}
+	if !bytes.Equal(protoutil.BlockDataHashWith(block.Data, hashingAlgorithm), block.Header.DataHash) {
 		return fmt.Errorf("Header.DataHash is different from Hash(block.Data) for block with id [%d] on channel [%s]", block.Header.Number, chainID)
 	}
 
@@ -26,6 +26,7 @@ import (
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/msp/mgmt"
 	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -56,6 +57,8 @@ func TestPKIidOfCert(t *testing.T) {
 		signer,
 		deserializersManager,
 		cryptoProvider,
+		nil,
+		nil,
 	)
 
 	peerIdentity := []byte("Alice")
@@ -85,11 +88,38 @@ func TestPKIidOfCert(t *testing.T) {
 	assert.Equal(t, 2+fieldsThatStartWithXXX, v.NumField())
 }
 
+func TestPKIidOfCertWithHashOpts(t *testing.T) {
+	deserializersManager := &mocks.DeserializersManager{
+		LocalDeserializer: &mocks.IdentityDeserializer{Identity: []byte("Alice"), Msg: []byte("msg1"), Mock: mock.Mock{}},
+	}
+	signer := &mocks.SignerSerializer{}
+	signer.SerializeReturns([]byte("Alice"), nil)
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	msgCryptoService := NewMCS(
+		&mocks.ChannelPolicyManagerGetterWithManager{},
+		signer,
+		deserializersManager,
+		cryptoProvider,
+		&bccsp.SM3Opts{},
+		nil,
+	)
+
+	peerIdentity := []byte("Alice")
+	pkid := msgCryptoService.GetPKIidOfCert(peerIdentity)
+
+	id, err := deserializersManager.Deserialize(peerIdentity)
+	assert.NoError(t, err, "Failed getting validated identity from [% x]", []byte(peerIdentity))
+	digest, err := cryptoProvider.Hash(append([]byte(id.Mspid), id.IdBytes...), &bccsp.SM3Opts{})
+	assert.NoError(t, err)
+	assert.Equal(t, digest, []byte(pkid), "PKID must be the SM3 of peerIdentity")
+}
+
 func TestPKIidOfNil(t *testing.T) {
 	signer := &mocks.SignerSerializer{}
 	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
 	assert.NoError(t, err)
-	msgCryptoService := NewMCS(&mocks.ChannelPolicyManagerGetter{}, signer, mgmt.NewDeserializersManager(cryptoProvider), cryptoProvider)
+	msgCryptoService := NewMCS(&mocks.ChannelPolicyManagerGetter{}, signer, mgmt.NewDeserializersManager(cryptoProvider), cryptoProvider, nil, nil)
 
 	pkid := msgCryptoService.GetPKIidOfCert(nil)
 	// Check pkid is not nil
@@ -112,6 +142,8 @@ func TestValidateIdentity(t *testing.T) {
 		signer,
 		deserializersManager,
 		cryptoProvider,
+		nil,
+		nil,
 	)
 
 	err = msgCryptoService.ValidateIdentity([]byte("Alice"))
@@ -149,6 +181,8 @@ func TestSign(t *testing.T) {
 		signer,
 		mgmt.NewDeserializersManager(cryptoProvider),
 		cryptoProvider,
+		nil,
+		nil,
 	)
 
 	msg := []byte("Hello World!!!")
@@ -185,6 +219,8 @@ func TestVerify(t *testing.T) {
 			},
 		},
 		cryptoProvider,
+		nil,
+		nil,
 	)
 
 	msg := []byte("msg1")
@@ -243,6 +279,8 @@ func TestVerifyBlock(t *testing.T) {
 			},
 		},
 		cryptoProvider,
+		nil,
+		nil,
 	)
 
 	// - Prepare testing valid block, Alice signs it.
@@ -275,7 +313,54 @@ func TestVerifyBlock(t *testing.T) {
 	assert.Error(t, msgCryptoService.VerifyBlock([]byte("C"), 42, &common.Block{}))
 }
 
+func TestVerifyBlockWithHashingAlgorithm(t *testing.T) {
+	aliceSigner := &mocks.SignerSerializer{}
+	aliceSigner.SerializeReturns([]byte("Alice"), nil)
+	policyManagerGetter := &mocks.ChannelPolicyManagerGetterWithManager{
+		Managers: map[string]policies.Manager{
+			"C": &mocks.ChannelPolicyManager{
+				Policy: &mocks.Policy{Deserializer: &mocks.IdentityDeserializer{Identity: []byte("Alice"), Msg: []byte("msg1"), Mock: mock.Mock{}}},
+			},
+		},
+	}
+
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	assert.NoError(t, err)
+	msgCryptoService := NewMCS(
+		policyManagerGetter,
+		aliceSigner,
+		&mocks.DeserializersManager{
+			LocalDeserializer: &mocks.IdentityDeserializer{Identity: []byte("Alice"), Msg: []byte("msg1"), Mock: mock.Mock{}},
+		},
+		cryptoProvider,
+		nil,
+		func(channelID string) func([]byte) []byte {
+			return util.ComputeSM3
+		},
+	)
+
+	// - Blocks of the channel are hashed with SM3
+	blockRaw, msg := mockBlockWithHashingAlgorithm(t, "C", 42, aliceSigner, util.ComputeSM3)
+	policyManagerGetter.Managers["C"].(*mocks.ChannelPolicyManager).Policy.(*mocks.Policy).Deserializer.(*mocks.IdentityDeserializer).Msg = msg
+	assert.NoError(t, msgCryptoService.VerifyBlock([]byte("C"), 42, blockRaw))
+
+	// - but not with SHA2-256
+	blockRaw, msg = mockBlock(t, "C", 42, aliceSigner, nil)
+	policyManagerGetter.Managers["C"].(*mocks.ChannelPolicyManager).Policy.(*mocks.Policy).Deserializer.(*mocks.IdentityDeserializer).Msg = msg
+	err = msgCryptoService.VerifyBlock([]byte("C"), 42, blockRaw)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Header.DataHash is different from Hash(block.Data)")
+}
+
 func mockBlock(t *testing.T, channel string, seqNum uint64, localSigner *mocks.SignerSerializer, dataHash []byte) (*common.Block, []byte) {
+	return mockBlockWith(t, channel, seqNum, localSigner, dataHash, nil)
+}
+
+func mockBlockWithHashingAlgorithm(t *testing.T, channel string, seqNum uint64, localSigner *mocks.SignerSerializer, hashingAlgorithm func([]byte) []byte) (*common.Block, []byte) {
+	return mockBlockWith(t, channel, seqNum, localSigner, nil, hashingAlgorithm)
+}
+
+func mockBlockWith(t *testing.T, channel string, seqNum uint64, localSigner *mocks.SignerSerializer, dataHash []byte, hashingAlgorithm func([]byte) []byte) (*common.Block, []byte) {
 	block := protoutil.NewBlock(seqNum, nil)
 
 	// Add a fake transaction to the block referring channel "C"
@@ -288,7 +373,7 @@ func mockBlock(t *testing.T, channel string, seqNum uint64, localSigner *mocks.S
 	if len(dataHash) != 0 {
 		block.Header.DataHash = dataHash
 	} else {
-		block.Header.DataHash = protoutil.BlockDataHash(block.Data)
+		block.Header.DataHash = protoutil.BlockDataHashWith(block.Data, hashingAlgorithm)
 	}
 
 	// Add signer's signature to the block
@@ -360,6 +445,8 @@ func TestExpiration(t *testing.T) {
 		&mocks.SignerSerializer{},
 		deserializersManager,
 		cryptoProvider,
+		nil,
+		nil,
 	)
 
 	// Green path I check the expiration date is as expected
@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keys
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"net"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/signer"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// csrRequest holds the subject of a certificate signing request
+type csrRequest struct {
+	CommonName         string
+	Organization       []string
+	OrganizationalUnit []string
+	Hosts              []string
+}
+
+func csrCmd() *cobra.Command {
+	var ski, output string
+	var req csrRequest
+	cmd := &cobra.Command{
+		Use:   "csr",
+		Short: "Generates a certificate signing request.",
+		Long:  "Generates a PEM encoded certificate signing request signed with a private key of the keystore of the peer. Requests of SM2 keys are signed with SM2 over SM3.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			csp, err := getCSP()
+			if err != nil {
+				return err
+			}
+			return generateCSR(cmd.OutOrStdout(), csp, ski, req, output)
+		},
+	}
+	flags := cmd.Flags()
+	flags.StringVarP(&ski, "ski", "s", "", "SKI of the key, as printed by generate")
+	flags.StringVar(&req.CommonName, "cn", "", "Common name of the subject of the request")
+	flags.StringSliceVar(&req.Organization, "org", nil, "Organizations of the subject of the request")
+	flags.StringSliceVar(&req.OrganizationalUnit, "orgUnit", nil, "Organizational units of the subject of the request")
+	flags.StringSliceVar(&req.Hosts, "hosts", nil, "Host names and IP addresses the certificate is requested for")
+	flags.StringVarP(&output, "output", "o", "", "File the request is written to, standard output if empty")
+	return cmd
+}
+
+// generateCSR writes a certificate signing request for req, signed with the
+// private key whose SKI is ski, to output, or to out when output is empty
+func generateCSR(out io.Writer, csp bccsp.BCCSP, ski string, req csrRequest, output string) error {
+	if req.CommonName == "" {
+		return errors.New("the common name of the request must be provided")
+	}
+	key, err := getPrivateKey(csp, ski)
+	if err != nil {
+		return err
+	}
+	s, err := signer.New(csp, key)
+	if err != nil {
+		return errors.WithMessage(err, "failed creating signer")
+	}
+
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:         req.CommonName,
+			Organization:       req.Organization,
+			OrganizationalUnit: req.OrganizationalUnit,
+		},
+	}
+	for _, host := range req.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := gmx509.CreateCertificateRequest(rand.Reader, template, s)
+	if err != nil {
+		return errors.Wrap(err, "failed creating certificate signing request")
+	}
+	return writeOutput(out, output, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
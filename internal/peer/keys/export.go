@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keys
+
+import (
+	"io"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/signer"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func exportCmd() *cobra.Command {
+	var ski, output string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Exports a public key.",
+		Long:  "Exports the PEM encoded public key of a private key of the keystore of the peer.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			csp, err := getCSP()
+			if err != nil {
+				return err
+			}
+			return exportPublicKey(cmd.OutOrStdout(), csp, ski, output)
+		},
+	}
+	cmd.Flags().StringVarP(&ski, "ski", "s", "", "SKI of the key, as printed by generate")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File the public key is written to, standard output if empty")
+	return cmd
+}
+
+// exportPublicKey writes the public key of the private key whose SKI is ski
+// to output, or to out when output is empty
+func exportPublicKey(out io.Writer, csp bccsp.BCCSP, ski, output string) error {
+	key, err := getPrivateKey(csp, ski)
+	if err != nil {
+		return err
+	}
+	s, err := signer.New(csp, key)
+	if err != nil {
+		return errors.WithMessage(err, "failed getting public key")
+	}
+	raw, err := utils.PublicKeyToPEM(s.Public(), nil)
+	if err != nil {
+		return errors.WithMessage(err, "failed encoding public key")
+	}
+	return writeOutput(out, output, raw)
+}
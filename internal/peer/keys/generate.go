@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keys
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func generateCmd() *cobra.Command {
+	var algorithm string
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generates a key.",
+		Long:  "Generates a private key into the keystore of the peer, and prints its SKI.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			csp, err := getCSP()
+			if err != nil {
+				return err
+			}
+			return generate(cmd.OutOrStdout(), csp, algorithm)
+		},
+	}
+	cmd.Flags().StringVarP(&algorithm, "algorithm", "a", "SM2", "Algorithm of the key, SM2 or ECDSA")
+	return cmd
+}
+
+// generate generates a private key with algorithm, and writes its SKI to out
+func generate(out io.Writer, csp bccsp.BCCSP, algorithm string) error {
+	var opts bccsp.KeyGenOpts
+	switch strings.ToUpper(algorithm) {
+	case bccsp.SM2:
+		opts = &bccsp.SM2KeyGenOpts{Temporary: false}
+	case bccsp.ECDSA:
+		opts = &bccsp.ECDSAP256KeyGenOpts{Temporary: false}
+	default:
+		return errors.Errorf("unsupported key algorithm [%s], must be SM2 or ECDSA", algorithm)
+	}
+
+	key, err := csp.KeyGen(opts)
+	if err != nil {
+		return errors.WithMessagef(err, "failed generating %s key", algorithm)
+	}
+	fmt.Fprintln(out, hex.EncodeToString(key.SKI()))
+	return nil
+}
@@ -0,0 +1,62 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keys
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func importCertCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "import-cert <certificate file>",
+		Short: "Imports a signing certificate.",
+		Long:  "Imports a PEM encoded certificate, issued for a private key of the keystore of the peer, as the signing certificate of the local MSP.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			csp, err := getCSP()
+			if err != nil {
+				return err
+			}
+			return importCert(csp, args[0], mspConfigPath())
+		},
+	}
+}
+
+// importCert writes the certificate of file to the signcerts directory of
+// the MSP of mspDir, after checking that its private key is in the keystore
+func importCert(csp bccsp.BCCSP, file, mspDir string) error {
+	block, pub, err := parseCertificate(file)
+	if err != nil {
+		return err
+	}
+	pubKey, err := importPublicKey(csp, pub)
+	if err != nil {
+		return err
+	}
+	if _, err := getPrivateKey(csp, hex.EncodeToString(pubKey.SKI())); err != nil {
+		return errors.WithMessagef(err, "the private key of the certificate of %s was not found in the keystore", file)
+	}
+
+	signcerts := filepath.Join(mspDir, "signcerts")
+	if err := os.MkdirAll(signcerts, 0755); err != nil {
+		return errors.Wrapf(err, "failed creating %s", signcerts)
+	}
+	certFile := filepath.Join(signcerts, "cert.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(block), 0644); err != nil {
+		return errors.Wrapf(err, "failed writing %s", certFile)
+	}
+	logger.Infof("Imported the certificate of %s to %s", file, certFile)
+	return nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keys
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/config"
+	"github.com/hyperledger/fabric/internal/peer/common"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	keysFuncName = "keys"
+	keysCmdDes   = "Manage the keys and certificates of the local MSP: generate|ski|export|csr|import-cert."
+)
+
+var logger = flogging.MustGetLogger("keysCmd")
+
+// Cmd returns the cobra command for Keys
+func Cmd() *cobra.Command {
+	keysCmd.AddCommand(generateCmd())
+	keysCmd.AddCommand(skiCmd())
+	keysCmd.AddCommand(exportCmd())
+	keysCmd.AddCommand(csrCmd())
+	keysCmd.AddCommand(importCertCmd())
+	return keysCmd
+}
+
+var keysCmd = &cobra.Command{
+	Use:              keysFuncName,
+	Short:            fmt.Sprint(keysCmdDes),
+	Long:             fmt.Sprint(keysCmdDes),
+	PersistentPreRun: common.InitCmd,
+}
+
+// mspConfigPath returns the directory of the local MSP
+func mspConfigPath() string {
+	return config.GetPath("peer.mspConfigPath")
+}
+
+// getCSP returns the BCCSP of the peer. As for the local MSP, keys are
+// stored in the keystore directory of the MSP unless another keystore is
+// configured.
+func getCSP() (bccsp.BCCSP, error) {
+	bccspConfig, err := common.GetBCCSPConfig()
+	if err != nil {
+		return nil, err
+	}
+	bccspConfig = msp.SetupBCCSPKeystoreConfig(bccspConfig, filepath.Join(mspConfigPath(), "keystore"))
+
+	csp, err := factory.GetBCCSPFromOpts(bccspConfig)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed initializing BCCSP")
+	}
+	return csp, nil
+}
+
+// getPrivateKey returns the private key whose SKI is the hex string ski
+func getPrivateKey(csp bccsp.BCCSP, ski string) (bccsp.Key, error) {
+	raw, err := hex.DecodeString(ski)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.Errorf("invalid SKI [%s]", ski)
+	}
+	key, err := csp.GetKey(raw)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed getting key with SKI [%s]", ski)
+	}
+	if !key.Private() {
+		return nil, errors.Errorf("key with SKI [%s] is not a private key", ski)
+	}
+	return key, nil
+}
+
+// parseCertificate parses the PEM encoded certificate of file
+func parseCertificate(file string) (*pem.Block, interface{}, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed reading %s", file)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, errors.Errorf("no PEM encoded certificate found in %s", file)
+	}
+	cert, err := gmx509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed parsing certificate of %s", file)
+	}
+	return block, cert.PublicKey, nil
+}
+
+// parsePublicKey returns the public key of file, which is either a PEM
+// encoded certificate or a PEM encoded public key
+func parsePublicKey(file string) (interface{}, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading %s", file)
+	}
+	if block, _ := pem.Decode(raw); block != nil && block.Type == "CERTIFICATE" {
+		_, pub, err := parseCertificate(file)
+		return pub, err
+	}
+	pub, err := utils.PEMtoPublicKey(raw, nil)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed parsing public key of %s", file)
+	}
+	return pub, nil
+}
+
+// importPublicKey imports pub as a temporary key of csp
+func importPublicKey(csp bccsp.BCCSP, pub interface{}) (bccsp.Key, error) {
+	var opts bccsp.KeyImportOpts
+	switch pub.(type) {
+	case *sm2.PublicKey:
+		opts = &bccsp.SM2GoPublicKeyImportOpts{Temporary: true}
+	case *ecdsa.PublicKey:
+		opts = &bccsp.ECDSAGoPublicKeyImportOpts{Temporary: true}
+	default:
+		return nil, errors.Errorf("unsupported public key type [%T]", pub)
+	}
+	key, err := csp.KeyImport(pub, opts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed importing public key")
+	}
+	return key, nil
+}
+
+// writeOutput writes raw to file, or to out when no file is given
+func writeOutput(out io.Writer, file string, raw []byte) error {
+	if file == "" {
+		_, err := out.Write(raw)
+		return err
+	}
+	if err := ioutil.WriteFile(file, raw, 0644); err != nil {
+		return errors.Wrapf(err, "failed writing %s", file)
+	}
+	logger.Infof("Wrote %s", file)
+	return nil
+}
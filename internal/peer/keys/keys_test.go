@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keys
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/signer"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCSP(t *testing.T) (bccsp.BCCSP, string, func()) {
+	mspDir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	viper.Set("peer.mspConfigPath", mspDir)
+
+	csp, err := getCSP()
+	require.NoError(t, err)
+	return csp, mspDir, func() {
+		viper.Set("peer.mspConfigPath", "")
+		os.RemoveAll(mspDir)
+	}
+}
+
+// selfSignedCert writes to dir a certificate of pub signed by priv
+func selfSignedCert(t *testing.T, dir string, pub, priv interface{}) string {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "peer0.org1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, template, pub, priv)
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "cert.pem")
+	require.NoError(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	return file
+}
+
+func TestKeyLifecycle(t *testing.T) {
+	csp, mspDir, cleanup := newTestCSP(t)
+	defer cleanup()
+	workDir, err := ioutil.TempDir("", "keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+
+	// Keys are generated into the keystore of the MSP
+	out := &bytes.Buffer{}
+	require.NoError(t, generate(out, csp, "sm2"))
+	ski := strings.TrimSpace(out.String())
+	files, err := ioutil.ReadDir(filepath.Join(mspDir, "keystore"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	// The exported public key has the SKI of the key
+	pubFile := filepath.Join(workDir, "pub.pem")
+	require.NoError(t, exportPublicKey(nil, csp, ski, pubFile))
+	out.Reset()
+	require.NoError(t, printSKI(out, csp, pubFile))
+	assert.Equal(t, ski, strings.TrimSpace(out.String()))
+
+	// The request is signed with SM2
+	out.Reset()
+	req := csrRequest{CommonName: "peer0.org1", Organization: []string{"org1"}, Hosts: []string{"peer0.org1", "127.0.0.1"}}
+	require.NoError(t, generateCSR(out, csp, ski, req, ""))
+	block, _ := pem.Decode(out.Bytes())
+	require.NotNil(t, block)
+	csr, err := gmx509.ParseCertificateRequest(block.Bytes)
+	require.NoError(t, err)
+	assert.NoError(t, gmx509.CheckCertificateRequestSignature(csr))
+	assert.IsType(t, &sm2.PublicKey{}, csr.PublicKey)
+	assert.Equal(t, "peer0.org1", csr.Subject.CommonName)
+	assert.Equal(t, []string{"org1"}, csr.Subject.Organization)
+	assert.Equal(t, []string{"peer0.org1"}, csr.DNSNames)
+	assert.Len(t, csr.IPAddresses, 1)
+
+	// The certificate issued for the key becomes the signing certificate
+	key, err := getPrivateKey(csp, ski)
+	require.NoError(t, err)
+	s, err := signer.New(csp, key)
+	require.NoError(t, err)
+	certFile := selfSignedCert(t, workDir, csr.PublicKey, s)
+	out.Reset()
+	require.NoError(t, printSKI(out, csp, certFile))
+	assert.Equal(t, ski, strings.TrimSpace(out.String()))
+	require.NoError(t, importCert(csp, certFile, mspDir))
+	imported, err := ioutil.ReadFile(filepath.Join(mspDir, "signcerts", "cert.pem"))
+	require.NoError(t, err)
+	raw, err := ioutil.ReadFile(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, raw, imported)
+}
+
+func TestGenerateECDSA(t *testing.T) {
+	csp, _, cleanup := newTestCSP(t)
+	defer cleanup()
+
+	out := &bytes.Buffer{}
+	require.NoError(t, generate(out, csp, "ECDSA"))
+	ski := strings.TrimSpace(out.String())
+
+	out.Reset()
+	require.NoError(t, exportPublicKey(out, csp, ski, ""))
+	pub, err := utils.PEMtoPublicKey(out.Bytes(), nil)
+	require.NoError(t, err)
+	key, err := importPublicKey(csp, pub)
+	require.NoError(t, err)
+	assert.Equal(t, ski, hex.EncodeToString(key.SKI()))
+
+	err = generate(out, csp, "RSA")
+	assert.EqualError(t, err, "unsupported key algorithm [RSA], must be SM2 or ECDSA")
+}
+
+func TestKeyErrors(t *testing.T) {
+	csp, mspDir, cleanup := newTestCSP(t)
+	defer cleanup()
+
+	err := exportPublicKey(nil, csp, "not hex", "")
+	assert.EqualError(t, err, "invalid SKI [not hex]")
+
+	err = generateCSR(nil, csp, "0102", csrRequest{}, "")
+	assert.EqualError(t, err, "the common name of the request must be provided")
+
+	// The private key of a foreign certificate is not in the keystore
+	foreign, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	certFile := selfSignedCert(t, mspDir, &foreign.PublicKey, foreign)
+	err = importCert(csp, certFile, mspDir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "was not found in the keystore")
+	_, err = os.Stat(filepath.Join(mspDir, "signcerts"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,46 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package keys
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/spf13/cobra"
+)
+
+func skiCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "ski <certificate|public key file>",
+		Short: "Prints the SKI of a key.",
+		Long:  "Prints the SKI the keystore of the peer identifies the key of a PEM encoded certificate or public key with.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			csp, err := getCSP()
+			if err != nil {
+				return err
+			}
+			return printSKI(cmd.OutOrStdout(), csp, args[0])
+		},
+	}
+}
+
+// printSKI writes to out the SKI of the certificate or public key of file
+func printSKI(out io.Writer, csp bccsp.BCCSP, file string) error {
+	pub, err := parsePublicKey(file)
+	if err != nil {
+		return err
+	}
+	key, err := importPublicKey(csp, pub)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, hex.EncodeToString(key.SKI()))
+	return nil
+}
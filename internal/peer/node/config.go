@@ -46,8 +46,9 @@ func ledgerConfig() *ledger.Config {
 	conf := &ledger.Config{
 		RootFSPath: rootFSPath,
 		StateDBConfig: &ledger.StateDBConfig{
-			StateDatabase: viper.GetString("ledger.state.stateDatabase"),
-			CouchDB:       &couchdb.Config{},
+			StateDatabase:    viper.GetString("ledger.state.stateDatabase"),
+			CouchDB:          &couchdb.Config{},
+			IntegrityDigests: viper.GetBool("ledger.state.integrityDigests"),
 		},
 		PrivateDataConfig: &ledger.PrivateDataConfig{
 			MaxBatchSize:    collElgProcMaxDbBatchSize,
@@ -104,6 +104,7 @@ func TestLedgerConfig(t *testing.T) {
 				"ledger.state.couchDBConfig.warmIndexesAfterNBlocks": 5,
 				"ledger.state.couchDBConfig.createGlobalChangesDB":   true,
 				"ledger.state.couchDBConfig.cacheSize":               64,
+				"ledger.state.integrityDigests":                      true,
 				"ledger.pvtdataStore.collElgProcMaxDbBatchSize":      50000,
 				"ledger.pvtdataStore.collElgProcDbBatchesInterval":   10000,
 				"ledger.pvtdataStore.purgeInterval":                  1000,
@@ -127,6 +128,7 @@ func TestLedgerConfig(t *testing.T) {
 						RedoLogPath:             "/peerfs/ledgersData/couchdbRedoLogs",
 						UserCacheSizeMBs:        64,
 					},
+					IntegrityDigests: true,
 				},
 				PrivateDataConfig: &ledger.PrivateDataConfig{
 					MaxBatchSize:    50000,
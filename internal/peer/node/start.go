@@ -8,6 +8,7 @@ package node
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -39,6 +40,7 @@ import (
 	"github.com/hyperledger/fabric/common/metrics"
 	"github.com/hyperledger/fabric/common/policies"
 	"github.com/hyperledger/fabric/common/policydsl"
+	"github.com/hyperledger/fabric/common/tracing"
 	"github.com/hyperledger/fabric/core/aclmgmt"
 	"github.com/hyperledger/fabric/core/cclifecycle"
 	"github.com/hyperledger/fabric/core/chaincode"
@@ -93,7 +95,9 @@ import (
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/msp/mgmt"
 	"github.com/hyperledger/fabric/protoutil"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -216,7 +220,10 @@ func serve(args []string) error {
 		return mgmt.GetManagerForChain(chainID)
 	}
 
-	opsSystem := newOperationsSystem(coreConfig)
+	opsSystem, err := newOperationsSystem(coreConfig)
+	if err != nil {
+		return errors.WithMessage(err, "failed to initialize operations subsystem")
+	}
 	err = opsSystem.Start()
 	if err != nil {
 		return errors.WithMessage(err, "failed to initialize operations subsystem")
@@ -227,6 +234,35 @@ func serve(args []string) error {
 	logObserver := floggingmetrics.NewObserver(metricsProvider)
 	flogging.SetObserver(logObserver)
 
+	stopTracing, err := tracing.Init("peer", tracing.Config{
+		Provider:    viper.GetString("tracing.provider"),
+		Endpoint:    viper.GetString("tracing.jaeger.endpoint"),
+		SampleRatio: viper.GetFloat64("tracing.sampleRatio"),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to initialize tracing")
+	}
+	defer stopTracing(context.Background())
+
+	if selfTest := factory.GetSelfTest(); selfTest != nil {
+		selfTest.SetMetricsProvider(metricsProvider)
+		if err := opsSystem.RegisterChecker("bccsp", selfTest); err != nil {
+			logger.Panicf("failed to register bccsp health check: %s", err)
+		}
+	}
+	if verifyCache := factory.GetVerifyCache(); verifyCache != nil {
+		verifyCache.SetMetricsProvider(metricsProvider)
+	}
+	if operationMetrics := factory.GetMetrics(); operationMetrics != nil {
+		operationMetrics.SetMetricsProvider(metricsProvider)
+	}
+	for _, circuitBreaker := range factory.GetBreakers() {
+		circuitBreaker.SetMetricsProvider(metricsProvider)
+	}
+	msp.SetMetricsProvider(metricsProvider)
+
+	opsSystem.RegisterHandler("/msp/local/reload", mgmt.NewReloadHandler())
+
 	mspID := coreConfig.LocalMSPID
 
 	membershipInfoProvider := privdata.NewMembershipInfoProvider(mspID, createSelfSignedData(), identityDeserializerFactory)
@@ -278,6 +314,39 @@ func serve(args []string) error {
 			logger.Fatalf("Failed to set TLS client certificate (%s)", err)
 		}
 		cs.SetClientCertificate(clientCert)
+
+		// peers talk GMTLS to each other when the peer listener does
+		if serverConfig.SecOpts.UseGMTLS {
+			sign, err := gmtls.X509KeyPair(serverConfig.SecOpts.Certificate, serverConfig.SecOpts.Key)
+			if err != nil {
+				logger.Fatalf("Failed to load the GMTLS signing certificate (%s)", err)
+			}
+			enc, err := gmtls.X509KeyPair(serverConfig.SecOpts.EncCertificate, serverConfig.SecOpts.EncKey)
+			if err != nil {
+				logger.Fatalf("Failed to load the GMTLS encryption certificate (%s)", err)
+			}
+			cs.SetGMTLSCertificates(&sign, &enc)
+		}
+		// and TLS 1.3 with the SM cipher suites likewise
+		if serverConfig.SecOpts.UseSMTLS13 {
+			cert, err := gmtls.X509KeyPair(serverConfig.SecOpts.Certificate, serverConfig.SecOpts.Key)
+			if err != nil {
+				logger.Fatalf("Failed to load the TLS 1.3 SM certificate (%s)", err)
+			}
+			cs.SetSMTLS13Certificate(&cert, serverConfig.SecOpts.SMCipherSuites)
+		}
+		// dual-stack peers fall back to TLS 1.2 with the standard certificate
+		if serverConfig.SecOpts.DualStack {
+			standardCert, err := tls.X509KeyPair(serverConfig.SecOpts.StandardCertificate, serverConfig.SecOpts.StandardKey)
+			if err != nil {
+				logger.Fatalf("Failed to load the dual-stack TLS certificate (%s)", err)
+			}
+			cs.SetClientCertificate(standardCert)
+			cs.SetDualStack(true)
+		}
+		if err := cs.SetPinnedCertificates(serverConfig.SecOpts.PinnedCertificates); err != nil {
+			logger.Fatalf("Failed to load the pinned TLS certificates (%s)", err)
+		}
 	}
 
 	transientStoreProvider, err := transientstore.NewStoreProvider(
@@ -442,10 +511,25 @@ func serve(args []string) error {
 		logger.Fatalf("Failed to create peer server (%s)", err)
 	}
 
+	if serverConfig.SecOpts.UseTLS {
+		tlsReloader := &comm.TLSReloader{
+			CertFile:  coreconfig.GetPath("peer.tls.cert.file"),
+			KeyFile:   coreconfig.GetPath("peer.tls.key.file"),
+			Server:    peerServer,
+			SwapGMTLS: []func(*gmtls.Certificate){cs.SetGMTLSSignCertificate},
+		}
+		// the server keypair is the client one unless configured apart
+		if viper.GetString("peer.tls.clientCert.file") == "" {
+			tlsReloader.SwapTLS = append(tlsReloader.SwapTLS, cs.SetClientCertificate)
+		}
+		opsSystem.RegisterHandler("/tls/reload", comm.NewTLSReloadHandler(tlsReloader))
+	}
+
 	// FIXME: Creating the gossip service has the side effect of starting a bunch
 	// of go routines and registration with the grpc server.
 	gossipService, err := initGossipService(
 		policyMgr,
+		peergossip.ChannelHashingAlgorithmGetter(peerInstance.GetHashingAlgorithm),
 		metricsProvider,
 		peerServer,
 		signingIdentity,
@@ -725,6 +809,7 @@ func serve(args []string) error {
 		TransientStoreRetriever: peerInstance,
 		PluginMapper:            pluginMapper,
 		SigningIdentityFetcher:  signingIdentityFetcher,
+		CryptoProvider:          factory.GetDefault(),
 	})
 	endorserSupport.PluginEndorser = pluginEndorser
 	channelFetcher := endorserChannelAdapter{
@@ -760,6 +845,10 @@ func serve(args []string) error {
 		gossipService.UpdateChaincodes(chaincodes.AsChaincodes(), gossipcommon.ChannelID(channel))
 	}))
 
+	// the signatures evaluated against policies, such as endorsements, are
+	// verified on a pool shared by all the channels
+	policies.SetVerifierPool(policies.NewVerifierPool(coreConfig.ValidatorSignaturePoolSize, coreConfig.ValidatorSignatureBatchSize))
+
 	// this brings up all the channels
 	peerInstance.Initialize(
 		func(cid string) {
@@ -1150,6 +1239,7 @@ func secureDialOpts(credSupport *comm.CredentialSupport) func() []grpc.DialOptio
 // 4. Init gossip related struct.
 func initGossipService(
 	policyMgr policies.ChannelPolicyManagerGetter,
+	hashingAlgorithmGetter peergossip.ChannelHashingAlgorithmGetter,
 	metricsProvider metrics.Provider,
 	peerServer *comm.GRPCServer,
 	signer msp.SigningIdentity,
@@ -1172,13 +1262,6 @@ func initGossipService(
 		certs.TLSClientCert.Store(&clientCert)
 	}
 
-	messageCryptoService := peergossip.NewMCS(
-		policyMgr,
-		signer,
-		mgmt.NewDeserializersManager(factory.GetDefault()),
-		factory.GetDefault(),
-	)
-	secAdv := peergossip.NewSecurityAdvisor(mgmt.NewDeserializersManager(factory.GetDefault()))
 	bootstrap := viper.GetStringSlice("peer.gossip.bootstrap")
 
 	serviceConfig := service.GlobalConfig()
@@ -1190,6 +1273,36 @@ func initGossipService(
 		return nil, errors.Wrap(err, "failed obtaining gossip config")
 	}
 
+	// PKI-IDs are hashed with the hashing algorithm of gossip
+	var pkiIDHashOpts bccsp.HashOpts
+	if gossipConfig.HashingAlgorithm != "" {
+		pkiIDHashOpts, err = bccsp.GetHashOpt(gossipConfig.HashingAlgorithm)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed obtaining gossip hashing algorithm")
+		}
+	}
+	messageCryptoService := peergossip.NewMCS(
+		policyMgr,
+		signer,
+		mgmt.NewDeserializersManager(factory.GetDefault()),
+		factory.GetDefault(),
+		pkiIDHashOpts,
+		hashingAlgorithmGetter,
+	)
+	secAdv := peergossip.NewSecurityAdvisor(mgmt.NewDeserializersManager(factory.GetDefault()))
+
+	var pvtDataCipher *gossipprivdata.PvtDataCipher
+	if privdataConfig.Encryption.Enabled {
+		decrypter, ok := signer.(msp.DualCertSigningIdentity)
+		if !ok {
+			return nil, errors.New("private data encryption requires the signing identity of the peer to have an encryption certificate")
+		}
+		pvtDataCipher, err = gossipprivdata.NewPvtDataCipher(privdataConfig.Encryption, decrypter, factory.GetDefault())
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed initializing private data encryption")
+		}
+	}
+
 	return gossipservice.New(
 		signer,
 		gossipmetrics.NewGossipMetrics(metricsProvider),
@@ -1204,10 +1317,20 @@ func initGossipService(
 		serviceConfig,
 		privdataConfig,
 		deliverServiceConfig,
+		pvtDataCipher,
 	)
 }
 
-func newOperationsSystem(coreConfig *peer.Config) *operations.System {
+func newOperationsSystem(coreConfig *peer.Config) (*operations.System, error) {
+	var authorization *operations.Authorization
+	if len(coreConfig.OperationsPolicies) != 0 {
+		var err error
+		authorization, err = operations.NewAuthorization(coreConfig.OperationsPolicies, mgmt.GetLocalMSP(factory.GetDefault()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return operations.NewSystem(operations.Options{
 		Logger:        flogging.MustGetLogger("peer.operations"),
 		ListenAddress: coreConfig.OperationsListenAddress,
@@ -1226,9 +1349,15 @@ func newOperationsSystem(coreConfig *peer.Config) *operations.System {
 			KeyFile:            coreConfig.OperationsTLSKeyFile,
 			ClientCertRequired: coreConfig.OperationsTLSClientAuthRequired,
 			ClientCACertFiles:  coreConfig.OperationsTLSClientRootCAs,
+			GMTLS:              coreConfig.OperationsTLSGMTLSEnabled,
+			EncCertFile:        coreConfig.OperationsTLSEncCertFile,
+			EncKeyFile:         coreConfig.OperationsTLSEncKeyFile,
+			SMTLS13:            coreConfig.OperationsTLSSMTLS13Enabled,
+			SMCipherSuites:     coreConfig.OperationsTLSSMCipherSuites,
 		},
-		Version: metadata.Version,
-	})
+		Authorization: authorization,
+		Version:       metadata.Version,
+	}), nil
 }
 
 func getDockerHostConfig() *docker.HostConfig {
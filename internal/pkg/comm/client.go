@@ -10,8 +10,10 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"sync"
 	"time"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/keepalive"
@@ -20,6 +22,12 @@ import (
 type GRPCClient struct {
 	// TLS configuration used by the grpc.ClientConn
 	tlsConfig *tls.Config
+	// GMTLS configuration used by the grpc.ClientConn instead of tlsConfig,
+	// or along with it by dual-stack clients
+	gmtlsConfig *gmtls.Config
+	// Authorities of the servers dual-stack clients found to only speak TLS
+	// 1.2
+	tls12Servers sync.Map
 	// Options for setting up new connections
 	dialOpts []grpc.DialOption
 	// Duration for which to block while established a new connection
@@ -68,6 +76,19 @@ func (client *GRPCClient) parseSecureOptions(opts SecureOptions) error {
 	if !opts.UseTLS {
 		return nil
 	}
+	verify, err := peerCertificateVerifier(opts)
+	if err != nil {
+		return err
+	}
+	opts.VerifyCertificate = verify
+	if opts.UseGMTLS || opts.UseSMTLS13 {
+		if err := client.parseGMTLSOptions(opts); err != nil || !opts.DualStack {
+			return err
+		}
+		// dual-stack clients present the standard certificate over TLS 1.2
+		opts.Certificate = opts.StandardCertificate
+		opts.Key = opts.StandardKey
+	}
 
 	client.tlsConfig = &tls.Config{
 		VerifyPeerCertificate: opts.VerifyCertificate,
@@ -110,25 +131,77 @@ func (client *GRPCClient) parseSecureOptions(opts SecureOptions) error {
 	return nil
 }
 
+// parseGMTLSOptions sets up the GMTLS configuration of the client, or its
+// TLS 1.3 one with the SM cipher suites
+func (client *GRPCClient) parseGMTLSOptions(opts SecureOptions) error {
+	roots, err := gmtlsCertPool(opts.ServerRootCAs)
+	if err != nil {
+		commLogger.Debugf("error adding root certificate: %v", err)
+		return errors.WithMessage(err, "error adding root certificate")
+	}
+	client.gmtlsConfig = &gmtls.Config{
+		RootCAs:               roots,
+		VerifyPeerCertificate: opts.VerifyCertificate,
+	}
+	if err := setGMTLSProtocol(client.gmtlsConfig, opts); err != nil {
+		return err
+	}
+	if opts.RequireClientCert {
+		sign, enc, err := gmtlsCertificates(opts)
+		if err != nil {
+			return errors.WithMessage(err, "failed to load client certificate")
+		}
+		client.gmtlsConfig.SignCertificate = sign
+		client.gmtlsConfig.EncCertificate = enc
+	}
+	if opts.TimeShift > 0 {
+		client.gmtlsConfig.Time = func() time.Time {
+			return time.Now().Add((-1) * opts.TimeShift)
+		}
+	}
+	return nil
+}
+
 // Certificate returns the tls.Certificate used to make TLS connections
-// when client certificates are required by the server
+// when client certificates are required by the server. Over GMTLS, it is
+// the signing certificate.
 func (client *GRPCClient) Certificate() tls.Certificate {
 	cert := tls.Certificate{}
 	if client.tlsConfig != nil && len(client.tlsConfig.Certificates) > 0 {
 		cert = client.tlsConfig.Certificates[0]
 	}
+	if client.gmtlsConfig != nil && client.gmtlsConfig.SignCertificate != nil {
+		sign := client.gmtlsConfig.SignCertificate
+		cert = tls.Certificate{
+			Certificate: sign.Certificate,
+			PrivateKey:  sign.PrivateKey,
+			Leaf:        sign.Leaf,
+		}
+	}
 	return cert
 }
 
+// SetGMTLSCertificate sets the signing certificate of GMTLS, or the
+// certificate of TLS 1.3 SM, used by new connections when client
+// certificates are required by the server.
+func (client *GRPCClient) SetGMTLSCertificate(cert *gmtls.Certificate) {
+	if client.gmtlsConfig != nil {
+		client.gmtlsConfig.SignCertificate = cert
+	}
+}
+
 // TLSEnabled is a flag indicating whether to use TLS for client
 // connections
 func (client *GRPCClient) TLSEnabled() bool {
-	return client.tlsConfig != nil
+	return client.tlsConfig != nil || client.gmtlsConfig != nil
 }
 
 // MutualTLSRequired is a flag indicating whether the client
 // must send a certificate when making TLS connections
 func (client *GRPCClient) MutualTLSRequired() bool {
+	if client.gmtlsConfig != nil {
+		return client.gmtlsConfig.SignCertificate != nil
+	}
 	return client.tlsConfig != nil &&
 		len(client.tlsConfig.Certificates) > 0
 }
@@ -149,6 +222,16 @@ func (client *GRPCClient) SetServerRootCAs(serverRoots [][]byte) error {
 
 	// NOTE: if no serverRoots are specified, the current cert pool will be
 	// replaced with an empty one
+	if client.gmtlsConfig != nil {
+		certPool, err := gmtlsCertPool(serverRoots)
+		if err != nil {
+			return errors.WithMessage(err, "error adding root certificate")
+		}
+		client.gmtlsConfig.RootCAs = certPool
+		if client.tlsConfig == nil {
+			return nil
+		}
+	}
 	certPool := x509.NewCertPool()
 	for _, root := range serverRoots {
 		err := AddPemToCertPool(root, certPool)
@@ -186,7 +269,28 @@ func (client *GRPCClient) NewConnection(address string, tlsOptions ...TLSOption)
 	// immediately before creating a connection in order to allow
 	// SetServerRootCAs / SetMaxRecvMsgSize / SetMaxSendMsgSize
 	//  to take effect on a per connection basis
-	if client.tlsConfig != nil {
+	if client.gmtlsConfig != nil && client.tlsConfig != nil {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(
+			&dualStackClientCreds{
+				tlsCreds: &DynamicClientCredentials{
+					TLSConfig:  client.tlsConfig,
+					TLSOptions: tlsOptions,
+				},
+				gmtlsCreds: &GMTLSClientCredentials{
+					GMTLSConfig: client.gmtlsConfig,
+					TLSOptions:  tlsOptions,
+				},
+				tls12Servers: &client.tls12Servers,
+			},
+		))
+	} else if client.gmtlsConfig != nil {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(
+			&GMTLSClientCredentials{
+				GMTLSConfig: client.gmtlsConfig,
+				TLSOptions:  tlsOptions,
+			},
+		))
+	} else if client.tlsConfig != nil {
 		dialOpts = append(dialOpts, grpc.WithTransportCredentials(
 			&DynamicClientCredentials{
 				TLSConfig:  client.tlsConfig,
@@ -110,6 +110,33 @@ type SecureOptions struct {
 	CipherSuites []uint16
 	// TimeShift makes TLS handshakes time sampling shift to the past by a given duration
 	TimeShift time.Duration
+	// Whether or not TLS connections use GMTLS (GM/T 0024) rather than
+	// TLS 1.2, with Certificate and Key as the SM2 signing certificate and key
+	UseGMTLS bool
+	// PEM-encoded SM2 encryption certificate to be used for GMTLS
+	EncCertificate []byte
+	// PEM-encoded private key of EncCertificate
+	EncKey []byte
+	// Whether or not TLS connections use TLS 1.3 with the SM cipher suites
+	// of RFC 8998 rather than TLS 1.2, with Certificate and Key as the SM2
+	// certificate and key
+	UseSMTLS13 bool
+	// SMCipherSuites are the RFC 8998 cipher suites of TLS 1.3 connections,
+	// in order of preference; both when empty
+	SMCipherSuites []uint16
+	// Whether or not GMTLS or TLS 1.3 SM listeners also accept TLS 1.2
+	// connections on the same port, sniffing the protocol of each client,
+	// and clients fall back to TLS 1.2 for servers rejecting the SM protocol
+	DualStack bool
+	// PEM-encoded X509 certificate used for TLS 1.2 connections by
+	// dual-stack listeners and clients
+	StandardCertificate []byte
+	// PEM-encoded private key of StandardCertificate
+	StandardKey []byte
+	// PinnedCertificates are the SM3 fingerprints, in hex, of the only TLS
+	// certificates accepted from remote nodes once their chain is verified;
+	// any certificate the CAs issued is accepted when empty
+	PinnedCertificates []string
 }
 
 // KeepaliveOptions is used to set the gRPC keepalive settings for both
@@ -14,6 +14,8 @@ import (
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"google.golang.org/grpc/credentials"
 )
 
@@ -25,6 +27,13 @@ type CredentialSupport struct {
 	appRootCAsByChain map[string][][]byte
 	serverRootCAs     [][]byte
 	clientCert        tls.Certificate
+	gmtlsSignCert     *gmtls.Certificate
+	gmtlsEncCert      *gmtls.Certificate
+	gmtlsVersion      uint16
+	smCipherSuites    []uint16
+	dualStack         bool
+	tls12Servers      sync.Map
+	verifyCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
 }
 
 // NewCredentialSupport creates a CredentialSupport instance.
@@ -50,6 +59,59 @@ func (cs *CredentialSupport) GetClientCertificate() tls.Certificate {
 	return cs.clientCert
 }
 
+// SetGMTLSCertificates makes the credentials of remote peer endpoints
+// GMTLS ones, presenting the signing and the encryption certificates.
+func (cs *CredentialSupport) SetGMTLSCertificates(sign, enc *gmtls.Certificate) {
+	cs.mutex.Lock()
+	cs.gmtlsSignCert = sign
+	cs.gmtlsEncCert = enc
+	cs.gmtlsVersion = gmtls.VersionGMTLS
+	cs.mutex.Unlock()
+}
+
+// SetGMTLSSignCertificate replaces the signing certificate of GMTLS, or the
+// certificate of TLS 1.3 SM, presented to remote peer endpoints.
+func (cs *CredentialSupport) SetGMTLSSignCertificate(cert *gmtls.Certificate) {
+	cs.mutex.Lock()
+	cs.gmtlsSignCert = cert
+	cs.mutex.Unlock()
+}
+
+// SetSMTLS13Certificate makes the credentials of remote peer endpoints
+// TLS 1.3 ones with the SM cipher suites of RFC 8998, presenting cert.
+func (cs *CredentialSupport) SetSMTLS13Certificate(cert *gmtls.Certificate, cipherSuites []uint16) {
+	cs.mutex.Lock()
+	cs.gmtlsSignCert = cert
+	cs.gmtlsEncCert = nil
+	cs.gmtlsVersion = gmtls.VersionTLS13
+	cs.smCipherSuites = cipherSuites
+	cs.mutex.Unlock()
+}
+
+// SetDualStack makes the GMTLS or TLS 1.3 SM credentials of remote peer
+// endpoints fall back to TLS 1.2, with the client certificate, for the
+// endpoints rejecting the SM protocol.
+func (cs *CredentialSupport) SetDualStack(dualStack bool) {
+	cs.mutex.Lock()
+	cs.dualStack = dualStack
+	cs.mutex.Unlock()
+}
+
+// SetPinnedCertificates makes the credentials of remote peer endpoints
+// reject the TLS certificates whose SM3 fingerprint is not one of
+// fingerprints, in addition to verifying their chain. No certificate is
+// pinned when fingerprints is empty.
+func (cs *CredentialSupport) SetPinnedCertificates(fingerprints []string) error {
+	verify, err := peerCertificateVerifier(SecureOptions{PinnedCertificates: fingerprints})
+	if err != nil {
+		return err
+	}
+	cs.mutex.Lock()
+	cs.verifyCertificate = verify
+	cs.mutex.Unlock()
+	return nil
+}
+
 // GetPeerCredentials returns gRPC transport credentials for use by gRPC
 // clients which communicate with remote peer endpoints.
 func (cs *CredentialSupport) GetPeerCredentials() credentials.TransportCredentials {
@@ -62,6 +124,33 @@ func (cs *CredentialSupport) GetPeerCredentials() credentials.TransportCredentia
 		appRootCAs = append(appRootCAs, appRootCA...)
 	}
 
+	var gmtlsCreds *GMTLSClientCredentials
+	if cs.gmtlsSignCert != nil {
+		certPool := gmx509.NewCertPool()
+		for _, appRootCA := range appRootCAs {
+			certs, _, err := pemToX509Certs(appRootCA)
+			if err != nil {
+				commLogger.Warningf("Failed adding certificates to peer's client GMTLS trust pool: %s", err)
+			}
+			for _, cert := range certs {
+				certPool.AddCert(cert)
+			}
+		}
+		gmtlsCreds = &GMTLSClientCredentials{
+			GMTLSConfig: &gmtls.Config{
+				SignCertificate:       cs.gmtlsSignCert,
+				EncCertificate:        cs.gmtlsEncCert,
+				Version:               cs.gmtlsVersion,
+				CipherSuites:          cs.smCipherSuites,
+				RootCAs:               certPool,
+				VerifyPeerCertificate: cs.verifyCertificate,
+			},
+		}
+		if !cs.dualStack {
+			return gmtlsCreds
+		}
+	}
+
 	certPool := x509.NewCertPool()
 	for _, appRootCA := range appRootCAs {
 		err := AddPemToCertPool(appRootCA, certPool)
@@ -70,10 +159,19 @@ func (cs *CredentialSupport) GetPeerCredentials() credentials.TransportCredentia
 		}
 	}
 
-	return credentials.NewTLS(&tls.Config{
-		Certificates: []tls.Certificate{cs.clientCert},
-		RootCAs:      certPool,
-	})
+	tlsConfig := &tls.Config{
+		Certificates:          []tls.Certificate{cs.clientCert},
+		RootCAs:               certPool,
+		VerifyPeerCertificate: cs.verifyCertificate,
+	}
+	if gmtlsCreds != nil {
+		return &dualStackClientCreds{
+			tlsCreds:     &DynamicClientCredentials{TLSConfig: tlsConfig},
+			gmtlsCreds:   gmtlsCreds,
+			tls12Servers: &cs.tls12Servers,
+		}
+	}
+	return credentials.NewTLS(tlsConfig)
 }
 
 func (cs *CredentialSupport) AppRootCAsByChain() map[string][][]byte {
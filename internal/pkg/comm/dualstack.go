@@ -0,0 +1,261 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"google.golang.org/grpc/credentials"
+)
+
+// Security protocols of connections, as they are reported in metrics.
+const (
+	protocolTLS     = "tls"
+	protocolGMTLS   = "gmtls"
+	protocolSMTLS13 = "smtls13"
+)
+
+const (
+	recordTypeHandshake      = 22
+	recordHeaderLen          = 5
+	maxPlaintextLen          = 16384
+	handshakeTypeClientHello = 1
+)
+
+// NewDualStackServerTransportCredentials returns the
+// grpc/credentials.TransportCredentials of a server accepting both TLS 1.2
+// connections with tlsCreds, and GMTLS or TLS 1.3 SM connections with
+// gmtlsCreds, on one listener. It eases the migration of networks to the
+// SM algorithms, as clients move from one protocol to the other.
+// Handshakes are counted by protocol on handshakes when it is not nil.
+func NewDualStackServerTransportCredentials(
+	tlsCreds credentials.TransportCredentials,
+	gmtlsCreds credentials.TransportCredentials,
+	handshakes metrics.Counter,
+	logger *flogging.FabricLogger) credentials.TransportCredentials {
+	return &dualStackServerCreds{
+		tlsCreds:   tlsCreds,
+		gmtlsCreds: gmtlsCreds,
+		handshakes: handshakes,
+		logger:     logger}
+}
+
+// dualStackServerCreds is an implementation of
+// grpc/credentials.TransportCredentials selecting the credentials of each
+// connection from the first record the client sends.
+type dualStackServerCreds struct {
+	tlsCreds   credentials.TransportCredentials
+	gmtlsCreds credentials.TransportCredentials
+	handshakes metrics.Counter
+	logger     *flogging.FabricLogger
+}
+
+// ClientHandShake is not implemented for `dualStackServerCreds`.
+func (dc *dualStackServerCreds) ClientHandshake(context.Context,
+	string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, ErrClientHandshakeNotImplemented
+}
+
+// ServerHandshake sniffs the protocol of the client and does the handshake
+// of its credentials.
+func (dc *dualStackServerCreds) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	conn := &sniffedConn{
+		Conn:   rawConn,
+		reader: bufio.NewReaderSize(rawConn, recordHeaderLen+maxPlaintextLen),
+	}
+	protocol, err := sniffProtocol(conn.reader)
+	if err != nil {
+		if dc.logger != nil {
+			dc.logger.With("remote address",
+				rawConn.RemoteAddr().String()).Errorf("Failed to read the first record of the handshake: %s", err)
+		}
+		return nil, nil, err
+	}
+
+	creds := dc.tlsCreds
+	if protocol != protocolTLS {
+		creds = dc.gmtlsCreds
+	}
+	secureConn, authInfo, err := creds.ServerHandshake(conn)
+	if dc.handshakes != nil {
+		status := "success"
+		if err != nil {
+			status = "failure"
+		}
+		dc.handshakes.With("protocol", protocol, "status", status).Add(1)
+	}
+	return secureConn, authInfo, err
+}
+
+// Info provides the ProtocolInfo of the GMTLS credentials, which the
+// listener migrates to.
+func (dc *dualStackServerCreds) Info() credentials.ProtocolInfo {
+	return dc.gmtlsCreds.Info()
+}
+
+// Clone makes a copy of this TransportCredentials.
+func (dc *dualStackServerCreds) Clone() credentials.TransportCredentials {
+	return NewDualStackServerTransportCredentials(dc.tlsCreds.Clone(), dc.gmtlsCreds.Clone(), dc.handshakes, dc.logger)
+}
+
+// OverrideServerName overrides the server name used to verify the hostname
+// on the returned certificates from the server.
+func (dc *dualStackServerCreds) OverrideServerName(string) error {
+	return ErrOverrideHostnameNotSupported
+}
+
+// dualStackClientCreds are the grpc/credentials.TransportCredentials of
+// clients holding both an SM2 certificate, for GMTLS or TLS 1.3 SM, and an
+// ECDSA one, for TLS 1.2, while a consortium migrates to the SM algorithms.
+// The SM protocol is tried first, and servers rejecting its version are
+// connected to again over TLS 1.2. The servers which only speak TLS 1.2 are
+// remembered by authority, the server name of SNI, so that later
+// connections pick the right certificate at once.
+type dualStackClientCreds struct {
+	tlsCreds   credentials.TransportCredentials
+	gmtlsCreds credentials.TransportCredentials
+	// tls12Servers holds the authorities of servers only speaking TLS 1.2
+	tls12Servers *sync.Map
+}
+
+// ClientHandshake does the handshake of the protocol the server speaks.
+func (dc *dualStackClientCreds) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	creds, fallback := dc.gmtlsCreds, dc.tlsCreds
+	if _, ok := dc.tls12Servers.Load(authority); ok {
+		creds, fallback = fallback, creds
+	}
+	conn, authInfo, err := creds.ClientHandshake(ctx, authority, rawConn)
+	if err == nil || !protocolRejected(err) {
+		return conn, authInfo, err
+	}
+
+	// The handshake closed rawConn, so the other protocol takes a new one
+	var dialer net.Dialer
+	addr := rawConn.RemoteAddr()
+	retryConn, err := dialer.DialContext(ctx, addr.Network(), addr.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, authInfo, err = fallback.ClientHandshake(ctx, authority, retryConn)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fallback == dc.tlsCreds {
+		dc.tls12Servers.Store(authority, struct{}{})
+	} else {
+		dc.tls12Servers.Delete(authority)
+	}
+	return conn, authInfo, nil
+}
+
+func (dc *dualStackClientCreds) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, ErrServerHandshakeNotImplemented
+}
+
+func (dc *dualStackClientCreds) Info() credentials.ProtocolInfo {
+	return dc.gmtlsCreds.Info()
+}
+
+func (dc *dualStackClientCreds) Clone() credentials.TransportCredentials {
+	return &dualStackClientCreds{
+		tlsCreds:     dc.tlsCreds.Clone(),
+		gmtlsCreds:   dc.gmtlsCreds.Clone(),
+		tls12Servers: dc.tls12Servers,
+	}
+}
+
+func (dc *dualStackClientCreds) OverrideServerName(name string) error {
+	if err := dc.tlsCreds.OverrideServerName(name); err != nil {
+		return err
+	}
+	return dc.gmtlsCreds.OverrideServerName(name)
+}
+
+// protocolRejected reports whether a handshake failed for the server not
+// speaking the protocol of the client. Both TLS and GMTLS servers answer
+// the ClientHello of another protocol with a protocol_version alert.
+func protocolRejected(err error) bool {
+	return strings.Contains(err.Error(), "protocol version not supported")
+}
+
+// sniffedConn is a net.Conn whose reads go through the reader its first
+// record was peeked from.
+type sniffedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *sniffedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// sniffProtocol peeks at the first record of a connection and returns the
+// protocol of the client: GMTLS records have their own version, and TLS
+// 1.3 SM clients offer the cipher suites of RFC 8998 in their ClientHello.
+// Any other record is left to fail the TLS handshake.
+func sniffProtocol(r *bufio.Reader) (string, error) {
+	header, err := r.Peek(recordHeaderLen)
+	if err != nil {
+		return "", err
+	}
+	if header[0] != recordTypeHandshake {
+		return protocolTLS, nil
+	}
+	if binary.BigEndian.Uint16(header[1:]) == gmtls.VersionGMTLS {
+		return protocolGMTLS, nil
+	}
+	n := int(binary.BigEndian.Uint16(header[3:]))
+	if n > maxPlaintextLen {
+		return protocolTLS, nil
+	}
+	record, err := r.Peek(recordHeaderLen + n)
+	if err != nil {
+		return "", err
+	}
+	if offersSMCipherSuite(record[recordHeaderLen:]) {
+		return protocolSMTLS13, nil
+	}
+	return protocolTLS, nil
+}
+
+// offersSMCipherSuite reports whether the ClientHello at the start of a
+// handshake fragment offers TLS_SM4_GCM_SM3 or TLS_SM4_CCM_SM3. A
+// ClientHello split across records has its cipher suites in the first one.
+func offersSMCipherSuite(fragment []byte) bool {
+	// handshake header, legacy version and random
+	const fixedLen = 4 + 2 + 32
+	if len(fragment) < fixedLen+1 || fragment[0] != handshakeTypeClientHello {
+		return false
+	}
+	p := fragment[fixedLen:]
+	sessionIDLen := int(p[0])
+	p = p[1:]
+	if len(p) < sessionIDLen+2 {
+		return false
+	}
+	p = p[sessionIDLen:]
+	n := int(binary.BigEndian.Uint16(p))
+	p = p[2:]
+	if len(p) < n {
+		n = len(p)
+	}
+	for i := 0; i+1 < n; i += 2 {
+		switch binary.BigEndian.Uint16(p[i:]) {
+		case gmtls.TLS_SM4_GCM_SM3, gmtls.TLS_SM4_CCM_SM3:
+			return true
+		}
+	}
+	return false
+}
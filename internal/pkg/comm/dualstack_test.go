@@ -0,0 +1,247 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/hyperledger/fabric/internal/pkg/comm/testpb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readTestCert(t *testing.T, name string) []byte {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "certs", name))
+	require.NoError(t, err)
+	return b
+}
+
+// dualStackOptions adds the ECDSA certificates of the test data to the SM
+// secure options of a dual-stack listener, and returns the options of a
+// TLS 1.2 client of it.
+func dualStackOptions(t *testing.T, secOpts *SecureOptions) SecureOptions {
+	caPEM := readTestCert(t, "Org1-cert.pem")
+	secOpts.DualStack = true
+	secOpts.StandardCertificate = readTestCert(t, "Org1-server1-cert.pem")
+	secOpts.StandardKey = readTestCert(t, "Org1-server1-key.pem")
+	secOpts.ClientRootCAs = append(secOpts.ClientRootCAs, caPEM)
+
+	return SecureOptions{
+		UseTLS:            true,
+		RequireClientCert: true,
+		Certificate:       readTestCert(t, "Org1-client1-cert.pem"),
+		Key:               readTestCert(t, "Org1-client1-key.pem"),
+		ServerRootCAs:     [][]byte{caPEM},
+	}
+}
+
+func TestDualStack(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+
+	tests := []struct {
+		name     string
+		secOpts  func(*testing.T) SecureOptions
+		protocol string
+	}{
+		{name: "GMTLS", secOpts: ca.secureOptions, protocol: protocolGMTLS},
+		{name: "SMTLS13", secOpts: ca.smTLS13Options, protocol: protocolSMTLS13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverOpts := tt.secOpts(t)
+			tlsOpts := dualStackOptions(t, &serverOpts)
+			handshakes := &metricsfakes.Counter{}
+			handshakes.WithReturns(handshakes)
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			srv, err := NewGRPCServerFromListener(lis, ServerConfig{
+				SecOpts: serverOpts,
+				ServerStatsHandler: &ServerStatsHandler{
+					OpenConnCounter:           &metricsfakes.Counter{},
+					ClosedConnCounter:         &metricsfakes.Counter{},
+					DualStackHandshakeCounter: handshakes,
+				},
+			})
+			require.NoError(t, err)
+			assert.True(t, srv.MutualTLSRequired())
+			service := &gmtlsEmptyServer{clientCerts: make(chan *x509.Certificate, 1)}
+			testpb.RegisterEmptyServiceServer(srv.Server(), service)
+			go srv.Start()
+			defer srv.Stop()
+
+			// Both SM and TLS 1.2 clients connect on the same port
+			for i, secOpts := range []SecureOptions{tt.secOpts(t), tlsOpts} {
+				client, err := NewGRPCClient(ClientConfig{SecOpts: secOpts, Timeout: time.Second})
+				require.NoError(t, err)
+				conn, err := client.NewConnection(lis.Addr().String())
+				require.NoError(t, err)
+				_, err = testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), new(testpb.Empty))
+				require.NoError(t, err)
+				conn.Close()
+
+				clientCert := <-service.clientCerts
+				require.NotNil(t, clientCert)
+				assert.Equal(t, client.Certificate().Certificate[0], clientCert.Raw)
+				assert.Equal(t, i+1, handshakes.AddCallCount())
+			}
+			require.Equal(t, 2, handshakes.WithCallCount())
+			assert.Equal(t, []string{"protocol", tt.protocol, "status", "success"}, handshakes.WithArgsForCall(0))
+			assert.Equal(t, []string{"protocol", protocolTLS, "status", "success"}, handshakes.WithArgsForCall(1))
+
+			// TLS 1.2 clients not trusting the standard certificate fail
+			tlsOpts.ServerRootCAs = [][]byte{ca.certPEM}
+			untrusted, err := NewGRPCClient(ClientConfig{SecOpts: tlsOpts, Timeout: 500 * time.Millisecond})
+			require.NoError(t, err)
+			_, err = untrusted.NewConnection(lis.Addr().String())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDualStackMissingStandardCertificate(t *testing.T) {
+	secOpts := newGMTLSTestCA(t).secureOptions(t)
+	dualStackOptions(t, &secOpts)
+	secOpts.StandardKey = nil
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	_, err = NewGRPCServerFromListener(lis, ServerConfig{SecOpts: secOpts})
+	assert.EqualError(t, err, "serverConfig.SecOpts must contain both StandardKey and StandardCertificate when DualStack is true")
+}
+
+func TestDualStackClient(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+	caPEM := readTestCert(t, "Org1-cert.pem")
+
+	dualStackServerOpts := ca.secureOptions(t)
+	dualStackOptions(t, &dualStackServerOpts)
+	servers := []struct {
+		name    string
+		secOpts SecureOptions
+		tls12   bool
+	}{
+		{
+			name: "TLS",
+			secOpts: SecureOptions{
+				UseTLS:            true,
+				RequireClientCert: true,
+				Certificate:       readTestCert(t, "Org1-server1-cert.pem"),
+				Key:               readTestCert(t, "Org1-server1-key.pem"),
+				ClientRootCAs:     [][]byte{caPEM},
+			},
+			tls12: true,
+		},
+		{name: "GMTLS", secOpts: ca.secureOptions(t)},
+		{name: "DualStack", secOpts: dualStackServerOpts},
+	}
+
+	clientOpts := ca.secureOptions(t)
+	clientOpts.DualStack = true
+	clientOpts.StandardCertificate = readTestCert(t, "Org1-client1-cert.pem")
+	clientOpts.StandardKey = readTestCert(t, "Org1-client1-key.pem")
+	clientOpts.ServerRootCAs = append(clientOpts.ServerRootCAs, caPEM)
+	client, err := NewGRPCClient(ClientConfig{SecOpts: clientOpts, Timeout: time.Second})
+	require.NoError(t, err)
+	assert.True(t, client.MutualTLSRequired())
+	standardCert, _ := pem.Decode(clientOpts.StandardCertificate)
+	require.NotNil(t, standardCert)
+
+	for _, server := range servers {
+		t.Run(server.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			srv, err := NewGRPCServerFromListener(lis, ServerConfig{SecOpts: server.secOpts})
+			require.NoError(t, err)
+			service := &gmtlsEmptyServer{clientCerts: make(chan *x509.Certificate, 1)}
+			testpb.RegisterEmptyServiceServer(srv.Server(), service)
+			go srv.Start()
+			defer srv.Stop()
+
+			// The second connection reuses the protocol the first found
+			for i := 0; i < 2; i++ {
+				conn, err := client.NewConnection(lis.Addr().String())
+				require.NoError(t, err)
+				_, err = testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), new(testpb.Empty))
+				require.NoError(t, err)
+				conn.Close()
+
+				clientCert := <-service.clientCerts
+				require.NotNil(t, clientCert)
+				if server.tls12 {
+					assert.Equal(t, standardCert.Bytes, clientCert.Raw)
+				} else {
+					assert.Equal(t, client.Certificate().Certificate[0], clientCert.Raw)
+				}
+				_, ok := client.tls12Servers.Load(lis.Addr().String())
+				assert.Equal(t, server.tls12, ok)
+			}
+		})
+	}
+}
+
+func TestDualStackPeerCredentials(t *testing.T) {
+	cs := NewCredentialSupport()
+	cs.SetSMTLS13Certificate(&gmtls.Certificate{}, nil)
+	assert.IsType(t, &GMTLSClientCredentials{}, cs.GetPeerCredentials())
+
+	cs.SetDualStack(true)
+	creds := cs.GetPeerCredentials()
+	assert.IsType(t, &dualStackClientCreds{}, creds)
+	assert.Equal(t, "1.3", creds.Info().SecurityVersion)
+}
+
+func TestSniffProtocol(t *testing.T) {
+	// clientHello returns the first record of a client offering suites
+	clientHello := func(version uint16, suites ...uint16) []byte {
+		body := []byte{0x03, 0x03}
+		body = append(body, make([]byte, 32)...)
+		body = append(body, 0)
+		n := 2 * len(suites)
+		body = append(body, byte(n>>8), byte(n))
+		for _, suite := range suites {
+			body = append(body, byte(suite>>8), byte(suite))
+		}
+		msg := append([]byte{handshakeTypeClientHello, 0, byte(len(body) >> 8), byte(len(body))}, body...)
+		return append([]byte{recordTypeHandshake, byte(version >> 8), byte(version), byte(len(msg) >> 8), byte(len(msg))}, msg...)
+	}
+
+	tests := []struct {
+		name     string
+		record   []byte
+		protocol string
+	}{
+		{"GMTLS", clientHello(gmtls.VersionGMTLS, gmtls.ECC_SM4_CBC_SM3), protocolGMTLS},
+		{"SMTLS13", clientHello(0x0301, 0x1301, gmtls.TLS_SM4_CCM_SM3), protocolSMTLS13},
+		{"TLS", clientHello(0x0301, 0xc02b, 0xc02f), protocolTLS},
+		{"NotHandshake", []byte{21, 3, 3, 0, 2, 2, 40}, protocolTLS},
+		{"TruncatedClientHello", []byte{22, 3, 1, 0, 3, handshakeTypeClientHello, 0, 0}, protocolTLS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol, err := sniffProtocol(bufio.NewReader(bytes.NewReader(tt.record)))
+			require.NoError(t, err)
+			assert.Equal(t, tt.protocol, protocol)
+		})
+	}
+
+	// Connections closed before a record header fail
+	_, err := sniffProtocol(bufio.NewReader(bytes.NewReader([]byte{22, 3})))
+	assert.Error(t, err)
+}
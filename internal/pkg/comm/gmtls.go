@@ -0,0 +1,289 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/credentials"
+)
+
+// GMTLSConfig is the GMTLS configuration of a GRPCServer, which can be
+// updated while the server runs.
+type GMTLSConfig struct {
+	config *gmtls.Config
+	lock   sync.RWMutex
+}
+
+func NewGMTLSConfig(config *gmtls.Config) *GMTLSConfig {
+	return &GMTLSConfig{
+		config: config,
+	}
+}
+
+func (g *GMTLSConfig) Config() gmtls.Config {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	if g.config != nil {
+		return *g.config.Clone()
+	}
+
+	return gmtls.Config{}
+}
+
+func (g *GMTLSConfig) AddClientRootCA(cert *x509.Certificate) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if g.config.ClientCAs == nil {
+		g.config.ClientCAs = gmx509.NewCertPool()
+	}
+	g.config.ClientCAs.AddCert(cert)
+}
+
+func (g *GMTLSConfig) SetClientCAs(certPool *gmx509.CertPool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.config.ClientCAs = certPool
+}
+
+// SetSignCertificate replaces the signing certificate of GMTLS, or the
+// certificate of TLS 1.3 SM.
+func (g *GMTLSConfig) SetSignCertificate(cert *gmtls.Certificate) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.config.SignCertificate = cert
+}
+
+// gmtlsAuthInfo returns the AuthInfo of a GMTLS connection. It is a
+// credentials.TLSInfo, whose state holds the signing certificate of the
+// peer first, so that the TLS certificate binding and the extraction of
+// certificates from gRPC contexts work over GMTLS as over TLS.
+func gmtlsAuthInfo(state gmtls.ConnectionState) credentials.AuthInfo {
+	return credentials.TLSInfo{
+		State: tls.ConnectionState{
+			Version:           state.Version,
+			HandshakeComplete: state.HandshakeComplete,
+			CipherSuite:       state.CipherSuite,
+			ServerName:        state.ServerName,
+			PeerCertificates:  state.PeerCertificates,
+			VerifiedChains:    state.VerifiedChains,
+		},
+	}
+}
+
+// gmtlsProtocolInfo returns the ProtocolInfo of the GMTLS credentials of
+// a protocol version.
+func gmtlsProtocolInfo(version uint16) credentials.ProtocolInfo {
+	if version == gmtls.VersionTLS13 {
+		return credentials.ProtocolInfo{
+			SecurityProtocol: "tls",
+			SecurityVersion:  "1.3",
+		}
+	}
+	return credentials.ProtocolInfo{
+		SecurityProtocol: "gmtls",
+		SecurityVersion:  "1.1",
+	}
+}
+
+// NewGMTLSServerTransportCredentials returns the
+// grpc/credentials.TransportCredentials of a server accepting GMTLS
+// connections.
+func NewGMTLSServerTransportCredentials(
+	serverConfig *GMTLSConfig,
+	logger *flogging.FabricLogger) credentials.TransportCredentials {
+	return &gmtlsServerCreds{
+		serverConfig: serverConfig,
+		logger:       logger}
+}
+
+// gmtlsServerCreds is an implementation of
+// grpc/credentials.TransportCredentials for GMTLS servers.
+type gmtlsServerCreds struct {
+	serverConfig *GMTLSConfig
+	logger       *flogging.FabricLogger
+}
+
+// ClientHandShake is not implemented for `gmtlsServerCreds`.
+func (sc *gmtlsServerCreds) ClientHandshake(context.Context,
+	string, net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, ErrClientHandshakeNotImplemented
+}
+
+// ServerHandshake does the GMTLS handshake for servers.
+func (sc *gmtlsServerCreds) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	serverConfig := sc.serverConfig.Config()
+
+	conn := gmtls.Server(rawConn, &serverConfig)
+	if err := conn.Handshake(); err != nil {
+		if sc.logger != nil {
+			sc.logger.With("remote address",
+				conn.RemoteAddr().String()).Errorf("GMTLS handshake failed with error %s", err)
+		}
+		return nil, nil, err
+	}
+	return conn, gmtlsAuthInfo(conn.ConnectionState()), nil
+}
+
+// Info provides the ProtocolInfo of this TransportCredentials.
+func (sc *gmtlsServerCreds) Info() credentials.ProtocolInfo {
+	return gmtlsProtocolInfo(sc.serverConfig.Config().Version)
+}
+
+// Clone makes a copy of this TransportCredentials.
+func (sc *gmtlsServerCreds) Clone() credentials.TransportCredentials {
+	config := sc.serverConfig.Config()
+	return NewGMTLSServerTransportCredentials(NewGMTLSConfig(&config), sc.logger)
+}
+
+// OverrideServerName overrides the server name used to verify the hostname
+// on the returned certificates from the server.
+func (sc *gmtlsServerCreds) OverrideServerName(string) error {
+	return ErrOverrideHostnameNotSupported
+}
+
+// GMTLSClientCredentials are the grpc/credentials.TransportCredentials of
+// GMTLS clients. TLSOptions apply to the server name only, as GMTLS roots
+// are no x509.CertPool.
+type GMTLSClientCredentials struct {
+	GMTLSConfig *gmtls.Config
+	TLSOptions  []TLSOption
+}
+
+func (gc *GMTLSClientCredentials) latestConfig() *gmtls.Config {
+	config := gc.GMTLSConfig.Clone()
+	tlsConfig := &tls.Config{ServerName: config.ServerName}
+	for _, tlsOption := range gc.TLSOptions {
+		tlsOption(tlsConfig)
+	}
+	config.ServerName = tlsConfig.ServerName
+	return config
+}
+
+// ClientHandshake does the GMTLS handshake for clients, checking the
+// certificate of the server against the host of authority unless a server
+// name is set.
+func (gc *GMTLSClientCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	config := gc.latestConfig()
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(authority)
+		if err != nil {
+			host = authority
+		}
+		config.ServerName = host
+	}
+
+	conn := gmtls.Client(rawConn, config)
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := conn.Handshake(); err != nil {
+		rawConn.Close()
+		return nil, nil, errors.WithMessage(err, "GMTLS handshake failed")
+	}
+	return conn, gmtlsAuthInfo(conn.ConnectionState()), nil
+}
+
+func (gc *GMTLSClientCredentials) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, ErrServerHandshakeNotImplemented
+}
+
+func (gc *GMTLSClientCredentials) Info() credentials.ProtocolInfo {
+	return gmtlsProtocolInfo(gc.GMTLSConfig.Version)
+}
+
+func (gc *GMTLSClientCredentials) Clone() credentials.TransportCredentials {
+	return &GMTLSClientCredentials{GMTLSConfig: gc.latestConfig()}
+}
+
+func (gc *GMTLSClientCredentials) OverrideServerName(name string) error {
+	gc.GMTLSConfig.ServerName = name
+	return nil
+}
+
+// gmtlsCertificates parses the signing and the encryption certificates and
+// keys of GMTLS from secure options. TLS 1.3 has no encryption certificate.
+func gmtlsCertificates(opts SecureOptions) (*gmtls.Certificate, *gmtls.Certificate, error) {
+	if opts.UseSMTLS13 {
+		if opts.Key == nil || opts.Certificate == nil {
+			return nil, nil, errors.New("Key and Certificate are required when using TLS 1.3 SM cipher suites")
+		}
+		sign, err := gmtls.X509KeyPair(opts.Certificate, opts.Key)
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to load the TLS 1.3 SM certificate")
+		}
+		return &sign, nil, nil
+	}
+	if opts.Key == nil || opts.Certificate == nil || opts.EncKey == nil || opts.EncCertificate == nil {
+		return nil, nil, errors.New("Key, Certificate, EncKey and EncCertificate are required when using GMTLS")
+	}
+	sign, err := gmtls.X509KeyPair(opts.Certificate, opts.Key)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to load the GMTLS signing certificate")
+	}
+	enc, err := gmtls.X509KeyPair(opts.EncCertificate, opts.EncKey)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to load the GMTLS encryption certificate")
+	}
+	return &sign, &enc, nil
+}
+
+// setGMTLSProtocol sets the protocol of config from secure options:
+// GM/T 0024, or TLS 1.3 with the SM cipher suites of RFC 8998.
+func setGMTLSProtocol(config *gmtls.Config, opts SecureOptions) error {
+	if opts.UseGMTLS && opts.UseSMTLS13 {
+		return errors.New("UseGMTLS and UseSMTLS13 are mutually exclusive")
+	}
+	if opts.UseSMTLS13 {
+		config.Version = gmtls.VersionTLS13
+		config.CipherSuites = opts.SMCipherSuites
+	}
+	return nil
+}
+
+// SMCipherSuites returns the RFC 8998 cipher suites of TLS 1.3 named
+// names, TLS_SM4_GCM_SM3 or TLS_SM4_CCM_SM3.
+func SMCipherSuites(names []string) ([]uint16, error) {
+	var suites []uint16
+	for _, name := range names {
+		suite, ok := gmtls.CipherSuiteID(name)
+		if !ok || suite == gmtls.ECC_SM4_CBC_SM3 {
+			return nil, errors.Errorf("unknown TLS 1.3 SM cipher suite %s", name)
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+// gmtlsCertPool returns the pool of the PEM-encoded certificates of roots.
+func gmtlsCertPool(roots [][]byte) (*gmx509.CertPool, error) {
+	pool := gmx509.NewCertPool()
+	for _, root := range roots {
+		certs, _, err := pemToX509Certs(root)
+		if err != nil {
+			return nil, err
+		}
+		for _, cert := range certs {
+			pool.AddCert(cert)
+		}
+	}
+	return pool, nil
+}
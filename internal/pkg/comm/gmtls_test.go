@@ -0,0 +1,231 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/internal/pkg/comm/testpb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type gmtlsTestCA struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *sm2.PrivateKey
+	serial  int64
+}
+
+func newGMTLSTestCA(t *testing.T) *gmtlsTestCA {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tlsca.org1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := gmx509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &gmtlsTestCA{
+		cert:    cert,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:     key,
+		serial:  1,
+	}
+}
+
+// issue returns the PEM certificate and key of usage for the loopback
+// address.
+func (ca *gmtlsTestCA) issue(t *testing.T, usage x509.KeyUsage) ([]byte, []byte) {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: "peer0.org1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     usage,
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyPEM, err := utils.SM2PrivateKeyToSEC1PEM(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM
+}
+
+// secureOptions returns GMTLS secure options with a signing and an
+// encryption certificate.
+func (ca *gmtlsTestCA) secureOptions(t *testing.T) SecureOptions {
+	signCert, signKey := ca.issue(t, x509.KeyUsageDigitalSignature)
+	encCert, encKey := ca.issue(t, x509.KeyUsageKeyEncipherment)
+	return SecureOptions{
+		UseTLS:            true,
+		UseGMTLS:          true,
+		RequireClientCert: true,
+		Certificate:       signCert,
+		Key:               signKey,
+		EncCertificate:    encCert,
+		EncKey:            encKey,
+		ServerRootCAs:     [][]byte{ca.certPEM},
+		ClientRootCAs:     [][]byte{ca.certPEM},
+	}
+}
+
+// smTLS13Options returns secure options of TLS 1.3 with the SM cipher
+// suites.
+func (ca *gmtlsTestCA) smTLS13Options(t *testing.T) SecureOptions {
+	cert, key := ca.issue(t, x509.KeyUsageDigitalSignature)
+	return SecureOptions{
+		UseTLS:            true,
+		UseSMTLS13:        true,
+		RequireClientCert: true,
+		Certificate:       cert,
+		Key:               key,
+		ServerRootCAs:     [][]byte{ca.certPEM},
+		ClientRootCAs:     [][]byte{ca.certPEM},
+	}
+}
+
+type gmtlsEmptyServer struct {
+	clientCerts chan *x509.Certificate
+}
+
+func (s *gmtlsEmptyServer) EmptyCall(ctx context.Context, _ *testpb.Empty) (*testpb.Empty, error) {
+	s.clientCerts <- ExtractCertificateFromContext(ctx)
+	return new(testpb.Empty), nil
+}
+
+func (s *gmtlsEmptyServer) EmptyStream(testpb.EmptyService_EmptyStreamServer) error {
+	return nil
+}
+
+func TestGMTLS(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv, err := NewGRPCServerFromListener(lis, ServerConfig{SecOpts: ca.secureOptions(t)})
+	require.NoError(t, err)
+	assert.True(t, srv.TLSEnabled())
+	assert.True(t, srv.MutualTLSRequired())
+	service := &gmtlsEmptyServer{clientCerts: make(chan *x509.Certificate, 1)}
+	testpb.RegisterEmptyServiceServer(srv.Server(), service)
+	go srv.Start()
+	defer srv.Stop()
+
+	client, err := NewGRPCClient(ClientConfig{SecOpts: ca.secureOptions(t), Timeout: time.Second})
+	require.NoError(t, err)
+	assert.True(t, client.TLSEnabled())
+	assert.True(t, client.MutualTLSRequired())
+	conn, err := client.NewConnection(lis.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), new(testpb.Empty))
+	require.NoError(t, err)
+
+	// The server sees the signing certificate of the client
+	clientCert := <-service.clientCerts
+	require.NotNil(t, clientCert)
+	assert.Equal(t, client.Certificate().Certificate[0], clientCert.Raw)
+
+	// TLS clients cannot connect to GMTLS listeners
+	secOpts := ca.secureOptions(t)
+	secOpts.UseGMTLS = false
+	tlsClient, err := NewGRPCClient(ClientConfig{SecOpts: secOpts, Timeout: 500 * time.Millisecond})
+	require.NoError(t, err)
+	_, err = tlsClient.NewConnection(lis.Addr().String())
+	assert.Error(t, err)
+
+	// Clients not trusting the server fail the handshake
+	untrusted, err := NewGRPCClient(ClientConfig{SecOpts: newGMTLSTestCA(t).secureOptions(t), Timeout: 500 * time.Millisecond})
+	require.NoError(t, err)
+	_, err = untrusted.NewConnection(lis.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestGMTLSMissingEncryptionCertificate(t *testing.T) {
+	secOpts := newGMTLSTestCA(t).secureOptions(t)
+	secOpts.EncKey = nil
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	_, err = NewGRPCServerFromListener(lis, ServerConfig{SecOpts: secOpts})
+	assert.EqualError(t, err, "serverConfig.SecOpts: Key, Certificate, EncKey and EncCertificate are required when using GMTLS")
+
+	_, err = NewGRPCClient(ClientConfig{SecOpts: secOpts})
+	assert.EqualError(t, err, "failed to load client certificate: Key, Certificate, EncKey and EncCertificate are required when using GMTLS")
+}
+
+func TestSMTLS13(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv, err := NewGRPCServerFromListener(lis, ServerConfig{SecOpts: ca.smTLS13Options(t)})
+	require.NoError(t, err)
+	assert.True(t, srv.TLSEnabled())
+	assert.True(t, srv.MutualTLSRequired())
+	service := &gmtlsEmptyServer{clientCerts: make(chan *x509.Certificate, 1)}
+	testpb.RegisterEmptyServiceServer(srv.Server(), service)
+	go srv.Start()
+	defer srv.Stop()
+
+	secOpts := ca.smTLS13Options(t)
+	secOpts.SMCipherSuites = []uint16{gmtls.TLS_SM4_CCM_SM3}
+	client, err := NewGRPCClient(ClientConfig{SecOpts: secOpts, Timeout: time.Second})
+	require.NoError(t, err)
+	conn, err := client.NewConnection(lis.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), new(testpb.Empty))
+	require.NoError(t, err)
+
+	clientCert := <-service.clientCerts
+	require.NotNil(t, clientCert)
+	assert.Equal(t, client.Certificate().Certificate[0], clientCert.Raw)
+
+	// GMTLS clients cannot connect to TLS 1.3 listeners
+	gmtlsClient, err := NewGRPCClient(ClientConfig{SecOpts: ca.secureOptions(t), Timeout: 500 * time.Millisecond})
+	require.NoError(t, err)
+	_, err = gmtlsClient.NewConnection(lis.Addr().String())
+	assert.Error(t, err)
+}
+
+func TestSMTLS13Options(t *testing.T) {
+	secOpts := newGMTLSTestCA(t).smTLS13Options(t)
+	secOpts.UseGMTLS = true
+	_, err := NewGRPCClient(ClientConfig{SecOpts: secOpts})
+	assert.EqualError(t, err, "UseGMTLS and UseSMTLS13 are mutually exclusive")
+
+	suites, err := SMCipherSuites([]string{"TLS_SM4_CCM_SM3", "TLS_SM4_GCM_SM3"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{gmtls.TLS_SM4_CCM_SM3, gmtls.TLS_SM4_GCM_SM3}, suites)
+	_, err = SMCipherSuites([]string{"ECC_SM4_CBC_SM3"})
+	assert.EqualError(t, err, "unknown TLS 1.3 SM cipher suite ECC_SM4_CBC_SM3")
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"crypto/tls"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"google.golang.org/grpc/credentials"
+)
+
+// handshakeMetricsCreds are the grpc/credentials.TransportCredentials of a
+// server reporting, for each connection, the security protocol and cipher
+// suite its handshake negotiated, whether the session was resumed, and how
+// long the handshake took. Operators can so check which connections use the
+// SM algorithms as a network migrates to them.
+type handshakeMetricsCreds struct {
+	credentials.TransportCredentials
+	handshakes metrics.Counter
+	duration   metrics.Histogram
+}
+
+// withHandshakeMetrics returns creds reporting the handshake metrics of
+// statsHandler, or creds as is when it has none.
+func withHandshakeMetrics(creds credentials.TransportCredentials, statsHandler *ServerStatsHandler) credentials.TransportCredentials {
+	if statsHandler == nil || statsHandler.HandshakeCounter == nil || statsHandler.HandshakeDuration == nil {
+		return creds
+	}
+	return &handshakeMetricsCreds{
+		TransportCredentials: creds,
+		handshakes:           statsHandler.HandshakeCounter,
+		duration:             statsHandler.HandshakeDuration,
+	}
+}
+
+// ServerHandshake does the handshake of the credentials and reports it.
+func (hc *handshakeMetricsCreds) ServerHandshake(rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	start := time.Now()
+	conn, authInfo, err := hc.TransportCredentials.ServerHandshake(rawConn)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		protocol := infoProtocol(hc.Info())
+		hc.duration.With("protocol", protocol, "status", "failure").Observe(elapsed.Seconds())
+		return conn, authInfo, err
+	}
+	protocol, cipherSuite, resumed := connectionSecurity(authInfo)
+	hc.duration.With("protocol", protocol, "status", "success").Observe(elapsed.Seconds())
+	hc.handshakes.With(
+		"protocol", protocol,
+		"cipher_suite", cipherSuite,
+		"resumed", strconv.FormatBool(resumed),
+	).Add(1)
+	return conn, authInfo, nil
+}
+
+// Clone makes a copy of this TransportCredentials.
+func (hc *handshakeMetricsCreds) Clone() credentials.TransportCredentials {
+	return &handshakeMetricsCreds{
+		TransportCredentials: hc.TransportCredentials.Clone(),
+		handshakes:           hc.handshakes,
+		duration:             hc.duration,
+	}
+}
+
+// connectionSecurity returns the security protocol, the name of the cipher
+// suite and the resumption status of the connection of authInfo.
+func connectionSecurity(authInfo credentials.AuthInfo) (protocol, cipherSuite string, resumed bool) {
+	tlsInfo, ok := authInfo.(credentials.TLSInfo)
+	if !ok {
+		return authInfo.AuthType(), "", false
+	}
+	state := tlsInfo.State
+
+	cipherSuite = gmtls.CipherSuiteName(state.CipherSuite)
+	switch {
+	case state.Version == gmtls.VersionGMTLS:
+		protocol = protocolGMTLS
+	case cipherSuite != "":
+		protocol = protocolSMTLS13
+	default:
+		protocol = protocolTLS
+		cipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	}
+	// GMTLS and TLS 1.3 SM sessions are never resumed
+	return protocol, cipherSuite, state.DidResume
+}
+
+// infoProtocol returns the security protocol of credentials whose handshake
+// failed, that of the SM credentials for dual-stack listeners.
+func infoProtocol(info credentials.ProtocolInfo) string {
+	switch {
+	case info.SecurityProtocol == "gmtls":
+		return protocolGMTLS
+	case info.SecurityProtocol == "tls" && info.SecurityVersion == "1.3":
+		return protocolSMTLS13
+	default:
+		return info.SecurityProtocol
+	}
+}
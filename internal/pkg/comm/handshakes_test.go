@@ -0,0 +1,150 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/hyperledger/fabric/internal/pkg/comm/testpb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestHandshakeMetrics(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+	caPEM := readTestCert(t, "Org1-cert.pem")
+	tlsOptions := func(t *testing.T) (SecureOptions, SecureOptions) {
+		server := SecureOptions{
+			UseTLS:      true,
+			Certificate: readTestCert(t, "Org1-server1-cert.pem"),
+			Key:         readTestCert(t, "Org1-server1-key.pem"),
+		}
+		client := SecureOptions{
+			UseTLS:        true,
+			ServerRootCAs: [][]byte{caPEM},
+		}
+		return server, client
+	}
+	smOptions := func(secOpts func(*testing.T) SecureOptions) func(*testing.T) (SecureOptions, SecureOptions) {
+		return func(t *testing.T) (SecureOptions, SecureOptions) {
+			return secOpts(t), secOpts(t)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		secOpts     func(*testing.T) (server, client SecureOptions)
+		protocol    string
+		cipherSuite string
+	}{
+		{name: "TLS", secOpts: tlsOptions, protocol: protocolTLS},
+		{name: "GMTLS", secOpts: smOptions(ca.secureOptions), protocol: protocolGMTLS, cipherSuite: "ECC_SM4_CBC_SM3"},
+		{name: "SMTLS13", secOpts: smOptions(ca.smTLS13Options), protocol: protocolSMTLS13, cipherSuite: "TLS_SM4_GCM_SM3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverOpts, clientOpts := tt.secOpts(t)
+			handshakes := &metricsfakes.Counter{}
+			handshakes.WithReturns(handshakes)
+			duration := &metricsfakes.Histogram{}
+			duration.WithReturns(duration)
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			srv, err := NewGRPCServerFromListener(lis, ServerConfig{
+				SecOpts: serverOpts,
+				ServerStatsHandler: &ServerStatsHandler{
+					OpenConnCounter:   &metricsfakes.Counter{},
+					ClosedConnCounter: &metricsfakes.Counter{},
+					HandshakeCounter:  handshakes,
+					HandshakeDuration: duration,
+				},
+			})
+			require.NoError(t, err)
+			testpb.RegisterEmptyServiceServer(srv.Server(), &gmtlsEmptyServer{clientCerts: make(chan *x509.Certificate, 1)})
+			go srv.Start()
+			defer srv.Stop()
+
+			client, err := NewGRPCClient(ClientConfig{SecOpts: clientOpts, Timeout: time.Second})
+			require.NoError(t, err)
+			conn, err := client.NewConnection(lis.Addr().String())
+			require.NoError(t, err)
+			var p peer.Peer
+			_, err = testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), new(testpb.Empty), grpc.Peer(&p))
+			require.NoError(t, err)
+			conn.Close()
+
+			// TLS clients and servers negotiate TLS 1.3 when the platform supports it
+			cipherSuite := tt.cipherSuite
+			if cipherSuite == "" {
+				cipherSuite = tls.CipherSuiteName(p.AuthInfo.(credentials.TLSInfo).State.CipherSuite)
+			}
+			require.Equal(t, 1, handshakes.AddCallCount())
+			assert.Equal(t, []string{"protocol", tt.protocol, "cipher_suite", cipherSuite, "resumed", "false"}, handshakes.WithArgsForCall(0))
+			require.Equal(t, 1, duration.ObserveCallCount())
+			assert.Equal(t, []string{"protocol", tt.protocol, "status", "success"}, duration.WithArgsForCall(0))
+			assert.True(t, duration.ObserveArgsForCall(0) > 0)
+
+			// Failed handshakes are observed with the protocol of the listener
+			rawConn, err := net.Dial("tcp", lis.Addr().String())
+			require.NoError(t, err)
+			rawConn.Write([]byte("not a handshake record"))
+			rawConn.Close()
+			assert.Eventually(t, func() bool { return duration.ObserveCallCount() == 2 }, time.Second, 10*time.Millisecond)
+			assert.Equal(t, []string{"protocol", tt.protocol, "status", "failure"}, duration.WithArgsForCall(1))
+			assert.Equal(t, 1, handshakes.AddCallCount())
+		})
+	}
+}
+
+func TestConnectionSecurity(t *testing.T) {
+	tests := []struct {
+		name        string
+		state       tls.ConnectionState
+		protocol    string
+		cipherSuite string
+		resumed     bool
+	}{
+		{
+			name:        "GMTLS",
+			state:       tls.ConnectionState{Version: gmtls.VersionGMTLS, CipherSuite: gmtls.ECC_SM4_CBC_SM3},
+			protocol:    protocolGMTLS,
+			cipherSuite: "ECC_SM4_CBC_SM3",
+		},
+		{
+			name:        "SMTLS13",
+			state:       tls.ConnectionState{Version: gmtls.VersionTLS13, CipherSuite: gmtls.TLS_SM4_CCM_SM3},
+			protocol:    protocolSMTLS13,
+			cipherSuite: "TLS_SM4_CCM_SM3",
+		},
+		{
+			name:        "ResumedTLS",
+			state:       tls.ConnectionState{Version: tls.VersionTLS12, CipherSuite: tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, DidResume: true},
+			protocol:    protocolTLS,
+			cipherSuite: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			resumed:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol, cipherSuite, resumed := connectionSecurity(credentials.TLSInfo{State: tt.state})
+			assert.Equal(t, tt.protocol, protocol)
+			assert.Equal(t, tt.cipherSuite, cipherSuite)
+			assert.Equal(t, tt.resumed, resumed)
+		})
+	}
+}
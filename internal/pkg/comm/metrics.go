@@ -22,11 +22,42 @@ var (
 		Name:      "conn_closed",
 		Help:      "gRPC connections closed. Open minus closed is the active number of connections.",
 	}
+
+	dualStackHandshakeCounterOpts = metrics.CounterOpts{
+		Namespace:    "grpc",
+		Subsystem:    "comm",
+		Name:         "dual_stack_handshakes",
+		Help:         "Handshakes of dual-stack listeners by security protocol.",
+		LabelNames:   []string{"protocol", "status"},
+		StatsdFormat: "%{#fqname}.%{protocol}.%{status}",
+	}
+
+	tlsHandshakeCounterOpts = metrics.CounterOpts{
+		Namespace:    "grpc",
+		Subsystem:    "comm",
+		Name:         "tls_handshakes",
+		Help:         "TLS handshakes completed by security protocol, cipher suite and session resumption.",
+		LabelNames:   []string{"protocol", "cipher_suite", "resumed"},
+		StatsdFormat: "%{#fqname}.%{protocol}.%{cipher_suite}.%{resumed}",
+	}
+
+	tlsHandshakeDurationOpts = metrics.HistogramOpts{
+		Namespace:    "grpc",
+		Subsystem:    "comm",
+		Name:         "tls_handshake_duration",
+		Help:         "The time to complete a TLS handshake, in seconds.",
+		Buckets:      []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		LabelNames:   []string{"protocol", "status"},
+		StatsdFormat: "%{#fqname}.%{protocol}.%{status}",
+	}
 )
 
 func NewServerStatsHandler(p metrics.Provider) *ServerStatsHandler {
 	return &ServerStatsHandler{
-		OpenConnCounter:   p.NewCounter(openConnCounterOpts),
-		ClosedConnCounter: p.NewCounter(closedConnCounterOpts),
+		OpenConnCounter:           p.NewCounter(openConnCounterOpts),
+		ClosedConnCounter:         p.NewCounter(closedConnCounterOpts),
+		DualStackHandshakeCounter: p.NewCounter(dualStackHandshakeCounterOpts),
+		HandshakeCounter:          p.NewCounter(tlsHandshakeCounterOpts),
+		HandshakeDuration:         p.NewHistogram(tlsHandshakeDurationOpts),
 	}
 }
@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm3"
+	"github.com/pkg/errors"
+)
+
+// CertificateFingerprint returns the SM3 fingerprint of a DER-encoded
+// certificate, in lower case hex.
+func CertificateFingerprint(der []byte) string {
+	h := sm3.New()
+	h.Write(der)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseFingerprint returns the canonical form of an SM3 fingerprint given
+// in hex, with or without colons between the bytes.
+func parseFingerprint(fingerprint string) (string, error) {
+	b, err := hex.DecodeString(strings.Replace(fingerprint, ":", "", -1))
+	if err != nil || len(b) != sm3.Size {
+		return "", errors.Errorf("invalid SM3 certificate fingerprint %s", fingerprint)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// peerCertificateVerifier returns the function verifying the certificates
+// of remote nodes after their chain is validated: the leaf must have one
+// of the pinned fingerprints of opts, if any, and opts.VerifyCertificate
+// must accept the certificates.
+func peerCertificateVerifier(opts SecureOptions) (func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error, error) {
+	if len(opts.PinnedCertificates) == 0 {
+		return opts.VerifyCertificate, nil
+	}
+	pins := make(map[string]struct{}, len(opts.PinnedCertificates))
+	for _, fingerprint := range opts.PinnedCertificates {
+		pin, err := parseFingerprint(fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		pins[pin] = struct{}{}
+	}
+
+	verify := opts.VerifyCertificate
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		// Whether remote nodes must present a certificate is up to the
+		// handshake
+		if len(rawCerts) > 0 {
+			fingerprint := CertificateFingerprint(rawCerts[0])
+			if _, pinned := pins[fingerprint]; !pinned {
+				subject := "unparsable certificate"
+				if cert, err := gmx509.ParseCertificate(rawCerts[0]); err == nil {
+					subject = cert.Subject.String()
+				}
+				return errors.Errorf("TLS certificate of %s with SM3 fingerprint %s is not pinned", subject, fingerprint)
+			}
+		}
+		if verify != nil {
+			return verify(rawCerts, verifiedChains)
+		}
+		return nil
+	}, nil
+}
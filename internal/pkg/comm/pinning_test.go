@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/internal/pkg/comm/testpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pemFingerprint returns the SM3 fingerprint of a PEM-encoded certificate.
+func pemFingerprint(t *testing.T, certPEM []byte) string {
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	return CertificateFingerprint(block.Bytes)
+}
+
+func TestPeerCertificateVerifier(t *testing.T) {
+	certPEM := readTestCert(t, "Org1-server1-cert.pem")
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	fingerprint := CertificateFingerprint(block.Bytes)
+	assert.Len(t, fingerprint, 64)
+
+	// Fingerprints may have colons and upper case digits
+	var colons []string
+	for i := 0; i < len(fingerprint); i += 2 {
+		colons = append(colons, strings.ToUpper(fingerprint[i:i+2]))
+	}
+	verify, err := peerCertificateVerifier(SecureOptions{PinnedCertificates: []string{strings.Join(colons, ":")}})
+	require.NoError(t, err)
+	assert.NoError(t, verify([][]byte{block.Bytes}, nil))
+	assert.NoError(t, verify(nil, nil))
+
+	other, _ := pem.Decode(readTestCert(t, "Org1-server2-cert.pem"))
+	require.NotNil(t, other)
+	err = verify([][]byte{other.Bytes}, nil)
+	require.Error(t, err)
+	assert.Regexp(t, "^TLS certificate of .*CN=.* with SM3 fingerprint "+CertificateFingerprint(other.Bytes)+" is not pinned$", err.Error())
+
+	// The verification of the options follows the pins
+	var verified int
+	verify, err = peerCertificateVerifier(SecureOptions{
+		PinnedCertificates: []string{fingerprint},
+		VerifyCertificate: func([][]byte, [][]*x509.Certificate) error {
+			verified++
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, verify([][]byte{block.Bytes}, nil))
+	assert.Error(t, verify([][]byte{other.Bytes}, nil))
+	assert.Equal(t, 1, verified)
+
+	for _, invalid := range []string{"not hex", fingerprint[:62], fingerprint + "00"} {
+		_, err = peerCertificateVerifier(SecureOptions{PinnedCertificates: []string{invalid}})
+		assert.EqualError(t, err, "invalid SM3 certificate fingerprint "+invalid)
+	}
+}
+
+func TestPinnedCertificates(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+	caPEM := readTestCert(t, "Org1-cert.pem")
+	tlsOptions := func(t *testing.T) (SecureOptions, SecureOptions) {
+		server := SecureOptions{
+			UseTLS:            true,
+			RequireClientCert: true,
+			Certificate:       readTestCert(t, "Org1-server1-cert.pem"),
+			Key:               readTestCert(t, "Org1-server1-key.pem"),
+			ClientRootCAs:     [][]byte{caPEM},
+		}
+		client := SecureOptions{
+			UseTLS:            true,
+			RequireClientCert: true,
+			Certificate:       readTestCert(t, "Org1-client1-cert.pem"),
+			Key:               readTestCert(t, "Org1-client1-key.pem"),
+			ServerRootCAs:     [][]byte{caPEM},
+		}
+		return server, client
+	}
+	gmtlsOptions := func(t *testing.T) (SecureOptions, SecureOptions) {
+		return ca.secureOptions(t), ca.secureOptions(t)
+	}
+
+	tests := []struct {
+		name    string
+		secOpts func(*testing.T) (server, client SecureOptions)
+	}{
+		{name: "TLS", secOpts: tlsOptions},
+		{name: "GMTLS", secOpts: gmtlsOptions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverOpts, clientOpts := tt.secOpts(t)
+			serverPin, clientPin := pemFingerprint(t, serverOpts.Certificate), pemFingerprint(t, clientOpts.Certificate)
+
+			// start listens with the certificate of client pinned, if pinned
+			start := func(pinned bool) (string, func()) {
+				secOpts := serverOpts
+				if pinned {
+					secOpts.PinnedCertificates = []string{clientPin}
+				} else {
+					secOpts.PinnedCertificates = []string{serverPin}
+				}
+				lis, err := net.Listen("tcp", "127.0.0.1:0")
+				require.NoError(t, err)
+				srv, err := NewGRPCServerFromListener(lis, ServerConfig{SecOpts: secOpts})
+				require.NoError(t, err)
+				testpb.RegisterEmptyServiceServer(srv.Server(), &gmtlsEmptyServer{clientCerts: make(chan *x509.Certificate, 1)})
+				go srv.Start()
+				return lis.Addr().String(), srv.Stop
+			}
+			// connect connects with the certificate of the server pinned, if pinned
+			connect := func(address string, pinned bool) error {
+				secOpts := clientOpts
+				if pinned {
+					secOpts.PinnedCertificates = []string{serverPin}
+				} else {
+					secOpts.PinnedCertificates = []string{clientPin}
+				}
+				client, err := NewGRPCClient(ClientConfig{SecOpts: secOpts, Timeout: 500 * time.Millisecond})
+				require.NoError(t, err)
+				conn, err := client.NewConnection(address)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				_, err = testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), new(testpb.Empty))
+				return err
+			}
+
+			address, stop := start(true)
+			defer stop()
+			assert.NoError(t, connect(address, true))
+			assert.Error(t, connect(address, false))
+
+			address, stop = start(false)
+			defer stop()
+			assert.Error(t, connect(address, true))
+		})
+	}
+
+	// Invalid fingerprints are rejected by servers and clients
+	secOpts, _ := tlsOptions(t)
+	secOpts.PinnedCertificates = []string{"00"}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	_, err = NewGRPCServerFromListener(lis, ServerConfig{SecOpts: secOpts})
+	assert.EqualError(t, err, "serverConfig.SecOpts: invalid SM3 certificate fingerprint 00")
+	_, err = NewGRPCClient(ClientConfig{SecOpts: secOpts})
+	assert.EqualError(t, err, "invalid SM3 certificate fingerprint 00")
+}
+
+func TestPinnedPeerCredentials(t *testing.T) {
+	cs := NewCredentialSupport()
+	assert.EqualError(t, cs.SetPinnedCertificates([]string{"00"}), "invalid SM3 certificate fingerprint 00")
+	assert.Nil(t, cs.verifyCertificate)
+
+	require.NoError(t, cs.SetPinnedCertificates([]string{pemFingerprint(t, readTestCert(t, "Org1-server1-cert.pem"))}))
+	assert.NotNil(t, cs.verifyCertificate)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/pkg/errors"
+)
+
+// TLSReloader reloads the TLS certificate of a GRPCServer from the files it
+// was loaded from at start, so that certificates renewed on disk, such as
+// SM2 certificates re-enrolled by an orchestration system, are used without
+// restarting the node.
+type TLSReloader struct {
+	// CertFile and KeyFile are the PEM files of the certificate and its key
+	CertFile string
+	KeyFile  string
+	// Server is the server whose certificate is reloaded. The certificate
+	// is the SM2 signing certificate of GMTLS, or of TLS 1.3 SM, when the
+	// server uses them.
+	Server *GRPCServer
+	// SwapTLS hot-swaps a reloaded TLS certificate into the credentials
+	// using it, such as CredentialSupport.SetClientCertificate
+	SwapTLS []func(cert tls.Certificate)
+	// SwapGMTLS hot-swaps a reloaded SM2 certificate into the credentials
+	// using it, such as CredentialSupport.SetGMTLSSignCertificate
+	SwapGMTLS []func(cert *gmtls.Certificate)
+	// Logger specifies the logger the reloader will use
+	Logger *flogging.FabricLogger
+}
+
+// Reload loads the certificate and key, and swaps them into the server and
+// the credentials. Connections already established are left untouched. The
+// certificate in use is kept if the files do not hold a valid pair.
+func (r *TLSReloader) Reload() error {
+	if r.Server == nil || !r.Server.TLSEnabled() {
+		return errors.New("TLS is not enabled")
+	}
+	certPEM, err := ioutil.ReadFile(r.CertFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read TLS certificate %s", r.CertFile)
+	}
+	keyPEM, err := ioutil.ReadFile(r.KeyFile)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read TLS key %s", r.KeyFile)
+	}
+
+	var leaf *x509.Certificate
+	if r.Server.gmtls != nil {
+		cert, err := gmtls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return errors.WithMessage(err, "failed to load SM2 TLS certificate")
+		}
+		if err := checkValidity(cert.Leaf); err != nil {
+			return err
+		}
+		r.Server.SetGMTLSCertificate(&cert)
+		for _, swap := range r.SwapGMTLS {
+			swap(&cert)
+		}
+		leaf = cert.Leaf
+	} else {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return errors.Wrap(err, "failed to load TLS certificate")
+		}
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return errors.Wrap(err, "failed to parse TLS certificate")
+		}
+		if err := checkValidity(cert.Leaf); err != nil {
+			return err
+		}
+		r.Server.SetServerCertificate(cert)
+		for _, swap := range r.SwapTLS {
+			swap(cert)
+		}
+		leaf = cert.Leaf
+	}
+
+	r.logger().Infof("Reloaded TLS certificate, serial number %s, valid until %s", leaf.SerialNumber, leaf.NotAfter)
+	return nil
+}
+
+func (r *TLSReloader) logger() *flogging.FabricLogger {
+	if r.Logger == nil {
+		return flogging.MustGetLogger("comm.reload")
+	}
+	return r.Logger
+}
+
+// checkValidity returns an error if cert is not valid at present.
+func checkValidity(cert *x509.Certificate) error {
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return errors.Errorf("TLS certificate is only valid from %s until %s", cert.NotBefore, cert.NotAfter)
+	}
+	return nil
+}
+
+// NewTLSReloadHandler returns the admin handler that reloads the TLS
+// certificate of reloader on POST requests.
+func NewTLSReloadHandler(reloader *TLSReloader) *TLSReloadHandler {
+	return &TLSReloadHandler{
+		Reload: reloader.Reload,
+		Logger: flogging.MustGetLogger("comm.httpadmin"),
+	}
+}
+
+type TLSReloadHandler struct {
+	Reload func() error
+	Logger *flogging.FabricLogger
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+func (h *TLSReloadHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		h.sendError(resp, http.StatusBadRequest, fmt.Errorf("invalid request method: %s", req.Method))
+		return
+	}
+
+	if err := h.Reload(); err != nil {
+		h.Logger.Errorw("failed to reload the TLS certificate", "error", err)
+		h.sendError(resp, http.StatusInternalServerError, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TLSReloadHandler) sendError(resp http.ResponseWriter, code int, err error) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	if err := json.NewEncoder(resp).Encode(&ErrorResponse{Error: err.Error()}); err != nil {
+		h.Logger.Errorw("failed to encode payload", "error", err)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKeyPair writes certPEM and keyPEM to the files of reloader.
+func writeKeyPair(t *testing.T, reloader *TLSReloader, certPEM, keyPEM []byte) {
+	require.NoError(t, ioutil.WriteFile(reloader.CertFile, certPEM, 0600))
+	require.NoError(t, ioutil.WriteFile(reloader.KeyFile, keyPEM, 0600))
+}
+
+func certDER(t *testing.T, certPEM []byte) []byte {
+	block, _ := pem.Decode(certPEM)
+	require.NotNil(t, block)
+	return block.Bytes
+}
+
+func TestTLSReloaderGMTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsreload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newGMTLSTestCA(t)
+	secOpts := ca.secureOptions(t)
+	srv, err := NewGRPCServer("127.0.0.1:0", ServerConfig{SecOpts: secOpts})
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	var swapped *gmtls.Certificate
+	reloader := &TLSReloader{
+		CertFile:  filepath.Join(dir, "server.crt"),
+		KeyFile:   filepath.Join(dir, "server.key"),
+		Server:    srv,
+		SwapGMTLS: []func(*gmtls.Certificate){func(cert *gmtls.Certificate) { swapped = cert }},
+	}
+
+	// A renewed certificate replaces the one in use
+	certPEM, keyPEM := ca.issue(t, x509.KeyUsageDigitalSignature)
+	writeKeyPair(t, reloader, certPEM, keyPEM)
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, certDER(t, certPEM), srv.ServerCertificate().Certificate[0])
+	require.NotNil(t, swapped)
+	assert.Equal(t, certDER(t, certPEM), swapped.Certificate[0])
+
+	// A certificate which does not match its key is not used
+	otherCertPEM, _ := ca.issue(t, x509.KeyUsageDigitalSignature)
+	writeKeyPair(t, reloader, otherCertPEM, keyPEM)
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, certDER(t, certPEM), srv.ServerCertificate().Certificate[0])
+
+	// Neither are missing files
+	require.NoError(t, os.Remove(reloader.KeyFile))
+	err = reloader.Reload()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to read TLS key")
+}
+
+func TestTLSReloaderTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsreload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	kp, err := ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+	srv, err := NewGRPCServer("127.0.0.1:0", ServerConfig{SecOpts: SecureOptions{UseTLS: true, Certificate: kp.Cert, Key: kp.Key}})
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	var swapped tls.Certificate
+	reloader := &TLSReloader{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+		Server:   srv,
+		SwapTLS:  []func(tls.Certificate){func(cert tls.Certificate) { swapped = cert }},
+	}
+
+	kp, err = ca.NewServerCertKeyPair("127.0.0.1")
+	require.NoError(t, err)
+	writeKeyPair(t, reloader, kp.Cert, kp.Key)
+	require.NoError(t, reloader.Reload())
+	assert.Equal(t, certDER(t, kp.Cert), srv.ServerCertificate().Certificate[0])
+	assert.Equal(t, certDER(t, kp.Cert), swapped.Certificate[0])
+}
+
+func TestTLSReloaderTLSDisabled(t *testing.T) {
+	srv, err := NewGRPCServer("127.0.0.1:0", ServerConfig{})
+	require.NoError(t, err)
+	defer srv.Stop()
+
+	reloader := &TLSReloader{Server: srv}
+	assert.EqualError(t, reloader.Reload(), "TLS is not enabled")
+}
+
+func TestTLSReloadHandler(t *testing.T) {
+	var reloadErr error
+	handler := NewTLSReloadHandler(&TLSReloader{})
+	handler.Reload = func() error { return reloadErr }
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/tls/reload", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.JSONEq(t, `{"error":"invalid request method: GET"}`, resp.Body.String())
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/tls/reload", nil))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	reloadErr = errors.New("no certificate")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/tls/reload", nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	assert.JSONEq(t, `{"error":"no certificate"}`, resp.Body.String())
+}
@@ -0,0 +1,196 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRenewBefore is how long before it expires a TLS certificate
+	// is rotated by default
+	DefaultRenewBefore = 7 * 24 * time.Hour
+	// DefaultRotationRetryInterval is the time between failed rotations
+	// by default
+	DefaultRotationRetryInterval = 10 * time.Minute
+)
+
+// CertificateIssuer issues a certificate for the public key of a new SM2
+// TLS key, replacing the certificate current. It returns the PEM-encoded
+// certificate chain, leaf first. Issuers typically enroll with the CA of the
+// organization.
+type CertificateIssuer func(publicKey *sm2.PublicKey, current *x509.Certificate) ([]byte, error)
+
+// CertRotatorConfig defines the parameters of a CertRotator.
+type CertRotatorConfig struct {
+	// Certificate is the SM2 TLS certificate in use
+	Certificate *gmtls.Certificate
+	// Issuer issues the certificates of new keys
+	Issuer CertificateIssuer
+	// RenewBefore is how long before it expires the certificate is
+	// rotated; DefaultRenewBefore when zero
+	RenewBefore time.Duration
+	// RetryInterval is the time between failed rotations;
+	// DefaultRotationRetryInterval when zero
+	RetryInterval time.Duration
+	// Install, when not nil, persists the PEM-encoded certificate and key
+	// of each rotation, such as to the files the node loads at start. The
+	// new certificate is not used when it fails.
+	Install func(certPEM, keyPEM []byte) error
+	// Swap hot-swaps the certificate of the credentials using it, such as
+	// GRPCServer.SetGMTLSCertificate. Connections already established are
+	// left untouched.
+	Swap []func(cert *gmtls.Certificate)
+	// Logger specifies the logger the rotator will use
+	Logger *flogging.FabricLogger
+}
+
+// CertRotator rotates an SM2 TLS certificate as it nears expiration: it
+// generates a new key, has the issuer certify it, installs the pair and
+// swaps it into the credentials using the certificate.
+type CertRotator struct {
+	config CertRotatorConfig
+	lock   sync.Mutex
+	cert   *gmtls.Certificate
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewCertRotator creates a CertRotator of the certificate of config.
+func NewCertRotator(config CertRotatorConfig) (*CertRotator, error) {
+	if config.Certificate == nil || config.Certificate.Leaf == nil {
+		return nil, errors.New("a parsed certificate is required to rotate")
+	}
+	if config.Issuer == nil {
+		return nil, errors.New("a certificate issuer is required to rotate")
+	}
+	if config.RenewBefore <= 0 {
+		config.RenewBefore = DefaultRenewBefore
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultRotationRetryInterval
+	}
+	if config.Logger == nil {
+		config.Logger = flogging.MustGetLogger("comm.rotation")
+	}
+	return &CertRotator{
+		config: config,
+		cert:   config.Certificate,
+	}, nil
+}
+
+// Certificate returns the certificate in use.
+func (r *CertRotator) Certificate() *gmtls.Certificate {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.cert
+}
+
+// RenewalTime returns the time the certificate in use is due for rotation.
+func (r *CertRotator) RenewalTime() time.Time {
+	return r.Certificate().Leaf.NotAfter.Add(-r.config.RenewBefore)
+}
+
+// Rotate replaces the certificate in use with a new key and certificate.
+func (r *CertRotator) Rotate() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	if err != nil {
+		return errors.WithMessage(err, "failed to generate SM2 TLS key")
+	}
+	keyPEM, err := utils.SM2PrivateKeyToSEC1PEM(key)
+	if err != nil {
+		return errors.WithMessage(err, "failed to encode SM2 TLS key")
+	}
+	certPEM, err := r.config.Issuer(&key.PublicKey, r.cert.Leaf)
+	if err != nil {
+		return errors.WithMessage(err, "failed to issue TLS certificate")
+	}
+	cert, err := gmtls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return errors.WithMessage(err, "issued TLS certificate is invalid")
+	}
+	if !cert.Leaf.NotAfter.After(r.cert.Leaf.NotAfter) {
+		return errors.Errorf("issued TLS certificate expires at %s, not after the current one", cert.Leaf.NotAfter)
+	}
+	if r.config.Install != nil {
+		if err := r.config.Install(certPEM, keyPEM); err != nil {
+			return errors.WithMessage(err, "failed to install TLS certificate")
+		}
+	}
+
+	for _, swap := range r.config.Swap {
+		swap(&cert)
+	}
+	r.cert = &cert
+	r.config.Logger.Infof("Rotated TLS certificate, serial number %s, now valid until %s",
+		cert.Leaf.SerialNumber, cert.Leaf.NotAfter)
+	return nil
+}
+
+// Start rotates the certificate whenever it reaches its renewal time, until
+// Stop is called. Failed rotations are retried.
+func (r *CertRotator) Start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.stop != nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	go r.run(r.stop, r.done)
+}
+
+// Stop stops the rotations started by Start.
+func (r *CertRotator) Stop() {
+	r.lock.Lock()
+	stop, done := r.stop, r.done
+	r.stop, r.done = nil, nil
+	r.lock.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (r *CertRotator) run(stop, done chan struct{}) {
+	defer close(done)
+	wait := time.Until(r.RenewalTime())
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := r.Rotate(); err != nil {
+			r.config.Logger.Errorf("Failed to rotate TLS certificate expiring at %s, retrying in %s: %s",
+				r.Certificate().Leaf.NotAfter, r.config.RetryInterval, err)
+			wait = r.config.RetryInterval
+			continue
+		}
+		if wait = time.Until(r.RenewalTime()); wait <= 0 {
+			r.config.Logger.Warningf("Rotated TLS certificate expires within %s, rotating again in %s",
+				r.config.RenewBefore, r.config.RetryInterval)
+			wait = r.config.RetryInterval
+		}
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package comm
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/internal/pkg/comm/testpb"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// issuer returns a CertificateIssuer of the CA, issuing certificates valid
+// for validity and counting them in issued.
+func (ca *gmtlsTestCA) issuer(t *testing.T, validity time.Duration, issued *int32) CertificateIssuer {
+	return func(publicKey *sm2.PublicKey, current *x509.Certificate) ([]byte, error) {
+		serial := atomic.AddInt32(issued, 1)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(100 + int64(serial)),
+			Subject:      current.Subject,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(validity),
+			KeyUsage:     current.KeyUsage,
+			IPAddresses:  current.IPAddresses,
+		}
+		der, err := gmx509.CreateCertificate(rand.Reader, template, ca.cert, publicKey, ca.key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+	}
+}
+
+func TestCertRotator(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+	secOpts := ca.smTLS13Options(t)
+	cert, err := gmtls.X509KeyPair(secOpts.Certificate, secOpts.Key)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv, err := NewGRPCServerFromListener(lis, ServerConfig{SecOpts: secOpts})
+	require.NoError(t, err)
+	testpb.RegisterEmptyServiceServer(srv.Server(), &gmtlsEmptyServer{clientCerts: make(chan *x509.Certificate, 3)})
+	go srv.Start()
+	defer srv.Stop()
+
+	client, err := NewGRPCClient(ClientConfig{SecOpts: ca.smTLS13Options(t), Timeout: time.Second})
+	require.NoError(t, err)
+	serverCert := func(conn *grpc.ClientConn) []byte {
+		var p peer.Peer
+		_, err := testpb.NewEmptyServiceClient(conn).EmptyCall(context.Background(), new(testpb.Empty), grpc.Peer(&p))
+		require.NoError(t, err)
+		return p.AuthInfo.(credentials.TLSInfo).State.PeerCertificates[0].Raw
+	}
+	before, err := client.NewConnection(lis.Addr().String())
+	require.NoError(t, err)
+	defer before.Close()
+	assert.Equal(t, cert.Certificate[0], serverCert(before))
+
+	var issued int32
+	var installedCert, installedKey []byte
+	rotator, err := NewCertRotator(CertRotatorConfig{
+		Certificate: &cert,
+		Issuer:      ca.issuer(t, 2*time.Hour, &issued),
+		Install: func(certPEM, keyPEM []byte) error {
+			installedCert, installedKey = certPEM, keyPEM
+			return nil
+		},
+		Swap: []func(*gmtls.Certificate){srv.SetGMTLSCertificate},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, cert.Leaf.NotAfter.Add(-DefaultRenewBefore), rotator.RenewalTime())
+
+	require.NoError(t, rotator.Rotate())
+	rotated := rotator.Certificate()
+	assert.NotEqual(t, cert.Certificate[0], rotated.Certificate[0])
+	installed, err := gmtls.X509KeyPair(installedCert, installedKey)
+	require.NoError(t, err)
+	assert.Equal(t, rotated.Certificate, installed.Certificate)
+	assert.Equal(t, rotated.Certificate[0], srv.ServerCertificate().Certificate[0])
+
+	// Established connections are kept, and new ones use the new certificate
+	assert.Equal(t, cert.Certificate[0], serverCert(before))
+	after, err := client.NewConnection(lis.Addr().String())
+	require.NoError(t, err)
+	defer after.Close()
+	assert.Equal(t, rotated.Certificate[0], serverCert(after))
+}
+
+func TestCertRotatorFailures(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+	certPEM, keyPEM := ca.issue(t, x509.KeyUsageDigitalSignature)
+	cert, err := gmtls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	_, err = NewCertRotator(CertRotatorConfig{Issuer: ca.issuer(t, time.Hour, new(int32))})
+	assert.EqualError(t, err, "a parsed certificate is required to rotate")
+	_, err = NewCertRotator(CertRotatorConfig{Certificate: &cert})
+	assert.EqualError(t, err, "a certificate issuer is required to rotate")
+
+	tests := []struct {
+		name    string
+		issuer  CertificateIssuer
+		install func(certPEM, keyPEM []byte) error
+		err     string
+	}{
+		{
+			name: "IssuerFailure",
+			issuer: func(*sm2.PublicKey, *x509.Certificate) ([]byte, error) {
+				return nil, errors.New("enrollment denied")
+			},
+			err: "failed to issue TLS certificate: enrollment denied",
+		},
+		{
+			name: "KeyMismatch",
+			issuer: func(*sm2.PublicKey, *x509.Certificate) ([]byte, error) {
+				return certPEM, nil
+			},
+			err: "issued TLS certificate is invalid: gmtls: private key does not match public key",
+		},
+		{
+			name:   "NotRenewed",
+			issuer: ca.issuer(t, time.Minute, new(int32)),
+			err:    "issued TLS certificate expires at",
+		},
+		{
+			name:   "InstallFailure",
+			issuer: ca.issuer(t, 2*time.Hour, new(int32)),
+			install: func(certPEM, keyPEM []byte) error {
+				return errors.New("read-only file system")
+			},
+			err: "failed to install TLS certificate: read-only file system",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			swapped := false
+			rotator, err := NewCertRotator(CertRotatorConfig{
+				Certificate: &cert,
+				Issuer:      tt.issuer,
+				Install:     tt.install,
+				Swap:        []func(*gmtls.Certificate){func(*gmtls.Certificate) { swapped = true }},
+			})
+			require.NoError(t, err)
+			err = rotator.Rotate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+			assert.False(t, swapped)
+			assert.Equal(t, &cert, rotator.Certificate())
+		})
+	}
+}
+
+func TestCertRotatorStartStop(t *testing.T) {
+	ca := newGMTLSTestCA(t)
+	certPEM, keyPEM := ca.issue(t, x509.KeyUsageDigitalSignature)
+	cert, err := gmtls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+
+	// The certificate is due for rotation at once, and so is the next one,
+	// which is then rotated again only after the retry interval
+	var issued int32
+	swaps := make(chan *gmtls.Certificate, 1)
+	rotator, err := NewCertRotator(CertRotatorConfig{
+		Certificate:   &cert,
+		Issuer:        ca.issuer(t, 2*time.Hour, &issued),
+		RenewBefore:   3 * time.Hour,
+		RetryInterval: time.Hour,
+		Swap:          []func(*gmtls.Certificate){func(c *gmtls.Certificate) { swaps <- c }},
+	})
+	require.NoError(t, err)
+	rotator.Start()
+	rotator.Start()
+
+	select {
+	case rotated := <-swaps:
+		assert.Equal(t, rotated, rotator.Certificate())
+	case <-time.After(5 * time.Second):
+		t.Fatal("certificate was not rotated")
+	}
+	rotator.Stop()
+	rotator.Stop()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&issued))
+}
@@ -15,8 +15,12 @@ import (
 	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmtls"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
@@ -38,6 +42,8 @@ type GRPCServer struct {
 	clientRootCAs map[string]*x509.Certificate
 	// TLS configuration used by the grpc server
 	tls *TLSConfig
+	// GMTLS configuration used by the grpc server instead of tls
+	gmtls *GMTLSConfig
 	// Server for gRPC Health Check Protocol.
 	healthServer *health.Server
 }
@@ -69,8 +75,20 @@ func NewGRPCServerFromListener(listener net.Listener, serverConfig ServerConfig)
 	//set up our server options
 	var serverOpts []grpc.ServerOption
 
+	verify, err := peerCertificateVerifier(serverConfig.SecOpts)
+	if err != nil {
+		return nil, errors.WithMessage(err, "serverConfig.SecOpts")
+	}
+	serverConfig.SecOpts.VerifyCertificate = verify
 	secureConfig := serverConfig.SecOpts
-	if secureConfig.UseTLS {
+	if secureConfig.UseTLS && (secureConfig.UseGMTLS || secureConfig.UseSMTLS13) {
+		creds, err := grpcServer.newGMTLSCredentials(serverConfig)
+		if err != nil {
+			return nil, err
+		}
+		creds = withHandshakeMetrics(creds, serverConfig.ServerStatsHandler)
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	} else if secureConfig.UseTLS {
 		//both key and cert are required
 		if secureConfig.Key != nil && secureConfig.Certificate != nil {
 			//load server public and private keys
@@ -124,6 +142,7 @@ func NewGRPCServerFromListener(listener net.Listener, serverConfig ServerConfig)
 
 			// create credentials and add to server options
 			creds := NewServerTransportCredentials(grpcServer.tls, serverConfig.Logger)
+			creds = withHandshakeMetrics(creds, serverConfig.ServerStatsHandler)
 			serverOpts = append(serverOpts, grpc.Creds(creds))
 		} else {
 			return nil, errors.New("serverConfig.SecOpts must contain both Key and Certificate when UseTLS is true")
@@ -175,6 +194,21 @@ func (gServer *GRPCServer) SetServerCertificate(cert tls.Certificate) {
 	gServer.serverCertificate.Store(cert)
 }
 
+// SetGMTLSCertificate assigns the signing certificate of GMTLS, or the
+// certificate of TLS 1.3 SM, to be the server certificate. Connections
+// already established keep the certificate they were made with.
+func (gServer *GRPCServer) SetGMTLSCertificate(cert *gmtls.Certificate) {
+	if gServer.gmtls == nil {
+		return
+	}
+	gServer.gmtls.SetSignCertificate(cert)
+	gServer.serverCertificate.Store(tls.Certificate{
+		Certificate: cert.Certificate,
+		PrivateKey:  cert.PrivateKey,
+		Leaf:        cert.Leaf,
+	})
+}
+
 // Address returns the listen address for this GRPCServer instance
 func (gServer *GRPCServer) Address() string {
 	return gServer.address
@@ -198,12 +232,15 @@ func (gServer *GRPCServer) ServerCertificate() tls.Certificate {
 // TLSEnabled is a flag indicating whether or not TLS is enabled for the
 // GRPCServer instance
 func (gServer *GRPCServer) TLSEnabled() bool {
-	return gServer.tls != nil
+	return gServer.tls != nil || gServer.gmtls != nil
 }
 
 // MutualTLSRequired is a flag indicating whether or not client certificates
 // are required for this GRPCServer instance
 func (gServer *GRPCServer) MutualTLSRequired() bool {
+	if gServer.gmtls != nil {
+		return gServer.gmtls.Config().ClientAuth == tls.RequireAndVerifyClientCert
+	}
 	return gServer.TLSEnabled() &&
 		gServer.tls.Config().ClientAuth == tls.RequireAndVerifyClientCert
 }
@@ -244,8 +281,13 @@ func (gServer *GRPCServer) appendClientRootCA(clientRoot []byte) error {
 	}
 
 	for i, cert := range certs {
-		//first add to the ClientCAs
-		gServer.tls.AddClientRootCA(cert)
+		//first add to the ClientCAs, of both protocols of dual-stack servers
+		if gServer.gmtls != nil {
+			gServer.gmtls.AddClientRootCA(cert)
+		}
+		if gServer.tls != nil {
+			gServer.tls.AddClientRootCA(cert)
+		}
 		//add it to our clientRootCAs map using subject as key
 		gServer.clientRootCAs[subjects[i]] = cert
 	}
@@ -272,13 +314,103 @@ func (gServer *GRPCServer) SetClientRootCAs(clientRoots [][]byte) error {
 		}
 	}
 
+	gServer.clientRootCAs = clientRootCAs
+	if gServer.gmtls != nil {
+		certPool := gmx509.NewCertPool()
+		for _, clientRoot := range clientRootCAs {
+			certPool.AddCert(clientRoot)
+		}
+		gServer.gmtls.SetClientCAs(certPool)
+		if gServer.tls == nil {
+			return nil
+		}
+	}
+
 	//create a new CertPool and populate with the new clientRootCAs
 	certPool := x509.NewCertPool()
 	for _, clientRoot := range clientRootCAs {
 		certPool.AddCert(clientRoot)
 	}
-
-	gServer.clientRootCAs = clientRootCAs
 	gServer.tls.SetClientCAs(certPool)
 	return nil
 }
+
+// newGMTLSCredentials returns the credentials of a server accepting GMTLS
+// connections, or TLS 1.3 ones with the SM cipher suites, rather than TLS
+// 1.2 ones. Dual-stack servers accept TLS 1.2 connections as well.
+func (gServer *GRPCServer) newGMTLSCredentials(serverConfig ServerConfig) (credentials.TransportCredentials, error) {
+	secureConfig := serverConfig.SecOpts
+	sign, enc, err := gmtlsCertificates(secureConfig)
+	if err != nil {
+		return nil, errors.WithMessage(err, "serverConfig.SecOpts")
+	}
+	gServer.serverCertificate.Store(tls.Certificate{
+		Certificate: sign.Certificate,
+		PrivateKey:  sign.PrivateKey,
+		Leaf:        sign.Leaf,
+	})
+	config := &gmtls.Config{
+		SignCertificate:       sign,
+		EncCertificate:        enc,
+		VerifyPeerCertificate: secureConfig.VerifyCertificate,
+		ClientAuth:            tls.RequestClientCert,
+	}
+	if err := setGMTLSProtocol(config, secureConfig); err != nil {
+		return nil, errors.WithMessage(err, "serverConfig.SecOpts")
+	}
+	if secureConfig.TimeShift > 0 {
+		timeShift := secureConfig.TimeShift
+		config.Time = func() time.Time {
+			return time.Now().Add((-1) * timeShift)
+		}
+	}
+	gServer.gmtls = NewGMTLSConfig(config)
+	if secureConfig.DualStack {
+		if secureConfig.StandardKey == nil || secureConfig.StandardCertificate == nil {
+			return nil, errors.New("serverConfig.SecOpts must contain both StandardKey and StandardCertificate when DualStack is true")
+		}
+		cert, err := tls.X509KeyPair(secureConfig.StandardCertificate, secureConfig.StandardKey)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to load the standard TLS certificate")
+		}
+		if len(secureConfig.CipherSuites) == 0 {
+			secureConfig.CipherSuites = DefaultTLSCipherSuites
+		}
+		gServer.tls = NewTLSConfig(&tls.Config{
+			VerifyPeerCertificate:  secureConfig.VerifyCertificate,
+			Certificates:           []tls.Certificate{cert},
+			SessionTicketsDisabled: true,
+			CipherSuites:           secureConfig.CipherSuites,
+			Time:                   config.Time,
+			ClientAuth:             tls.RequestClientCert,
+		})
+	}
+	if secureConfig.RequireClientCert {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		gServer.clientRootCAs = make(map[string]*x509.Certificate)
+		config.ClientCAs = gmx509.NewCertPool()
+		if gServer.tls != nil {
+			gServer.tls.config.ClientAuth = tls.RequireAndVerifyClientCert
+			gServer.tls.config.ClientCAs = x509.NewCertPool()
+		}
+		for _, clientRootCA := range secureConfig.ClientRootCAs {
+			if err := gServer.appendClientRootCA(clientRootCA); err != nil {
+				return nil, err
+			}
+		}
+	}
+	creds := NewGMTLSServerTransportCredentials(gServer.gmtls, serverConfig.Logger)
+	if gServer.tls == nil {
+		return creds, nil
+	}
+	var handshakes metrics.Counter
+	if serverConfig.ServerStatsHandler != nil {
+		handshakes = serverConfig.ServerStatsHandler.DualStackHandshakeCounter
+	}
+	return NewDualStackServerTransportCredentials(
+		NewServerTransportCredentials(gServer.tls, serverConfig.Logger),
+		creds,
+		handshakes,
+		serverConfig.Logger,
+	), nil
+}
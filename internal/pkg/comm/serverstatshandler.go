@@ -16,6 +16,15 @@ import (
 type ServerStatsHandler struct {
 	OpenConnCounter   metrics.Counter
 	ClosedConnCounter metrics.Counter
+	// DualStackHandshakeCounter counts the handshakes of dual-stack
+	// listeners by security protocol
+	DualStackHandshakeCounter metrics.Counter
+	// HandshakeCounter counts the TLS, GMTLS and TLS 1.3 SM handshakes
+	// completed by protocol, cipher suite and session resumption
+	HandshakeCounter metrics.Counter
+	// HandshakeDuration observes the time handshakes take by protocol and
+	// status
+	HandshakeDuration metrics.Histogram
 }
 
 func (h *ServerStatsHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
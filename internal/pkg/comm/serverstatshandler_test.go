@@ -56,6 +56,8 @@ func TestConnMetricsGRPCServer(t *testing.T) {
 			return openConn
 		case "conn_closed":
 			return closedConn
+		case "dual_stack_handshakes", "tls_handshakes":
+			return &metricsfakes.Counter{}
 		default:
 			panic("unknown counter")
 		}
@@ -15,6 +15,7 @@ import (
 	"net"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
@@ -45,7 +46,7 @@ func pemToX509Certs(pemCerts []byte) ([]*x509.Certificate, []string, error) {
 			break
 		}
 
-		cert, err := x509.ParseCertificate(block.Bytes)
+		cert, err := gmx509.ParseCertificate(block.Bytes)
 		if err != nil {
 			return nil, []string{}, err
 		}
@@ -0,0 +1,51 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/x509"
+	"sync"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+)
+
+var (
+	algorithmPolicyMutex sync.RWMutex
+	algorithmPolicy      gmx509.AlgorithmPolicy
+)
+
+// SetAlgorithmPolicy makes MSPs reject the identities whose certification
+// chain uses algorithms policy forbids, and the signatures of identities
+// whose key does, typically the policy that the algorithm capabilities of
+// a channel require. Identities are validated once: those validated before
+// keep their result.
+func SetAlgorithmPolicy(policy gmx509.AlgorithmPolicy) {
+	algorithmPolicyMutex.Lock()
+	defer algorithmPolicyMutex.Unlock()
+	algorithmPolicy = policy
+}
+
+func getAlgorithmPolicy() gmx509.AlgorithmPolicy {
+	algorithmPolicyMutex.RLock()
+	defer algorithmPolicyMutex.RUnlock()
+	return algorithmPolicy
+}
+
+// checkAlgorithmPolicy returns an error if a certificate of chain uses an
+// algorithm the algorithm policy forbids.
+func checkAlgorithmPolicy(chain []*x509.Certificate) error {
+	policy := getAlgorithmPolicy()
+	if !policy.Enabled() {
+		return nil
+	}
+	for _, cert := range chain {
+		if err := policy.CheckCertificate(cert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlgorithmPolicy(t *testing.T) {
+	defer SetAlgorithmPolicy(gmx509.AlgorithmPolicy{})
+
+	ca := newSM2TestCA(t)
+	peerCert, peerKey := ca.issue(t, 2, "peer0.org1", "peer")
+	clientCert, _ := ca.issue(t, 3, "user1.org1", "client")
+	thisMSP := ca.setupMSP(t, bccsp.SM3, peerCert, peerKey)
+	id, err := thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	msg := []byte("proposal")
+	sig, err := id.Sign(msg)
+	require.NoError(t, err)
+
+	deserialize := func() Identity {
+		serialized, err := proto.Marshal(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: clientCert})
+		require.NoError(t, err)
+		client, err := thisMSP.DeserializeIdentity(serialized)
+		require.NoError(t, err)
+		return client
+	}
+
+	SetAlgorithmPolicy(gmx509.AlgorithmPolicy{SM2Only: true, NoSHA1: true, MinECKeySize: 256})
+	assert.NoError(t, thisMSP.Validate(deserialize()))
+	assert.NoError(t, id.Verify(msg, sig))
+
+	SetAlgorithmPolicy(gmx509.AlgorithmPolicy{MinECKeySize: 384})
+	err = thisMSP.Validate(deserialize())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the certification chain uses a forbidden algorithm")
+	err = id.Verify(msg, sig)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the signature uses a forbidden algorithm")
+}
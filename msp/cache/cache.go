@@ -7,9 +7,12 @@ SPDX-License-Identifier: Apache-2.0
 package cache
 
 import (
+	"sync/atomic"
+
 	pmsp "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/paul-lee-attorney/gm/sm3"
 	"github.com/pkg/errors"
 )
 
@@ -21,14 +24,26 @@ const (
 
 var mspLogger = flogging.MustGetLogger("msp")
 
+// generation is incremented by Invalidate. Cached MSPs drop the results
+// cached under an older generation.
+var generation uint64
+
+// Invalidate drops the deserialized identities, validation results and
+// principal results cached by every cached MSP. It is meant for changes
+// outside of the MSP configuration, such as a new revocation checker or
+// updated revocation lists, since Setup already drops them.
+func Invalidate() {
+	atomic.AddUint64(&generation, 1)
+}
+
 func New(o msp.MSP) (msp.MSP, error) {
 	mspLogger.Debugf("Creating Cache-MSP instance")
 	if o == nil {
 		return nil, errors.Errorf("Invalid passed MSP. It must be different from nil.")
 	}
 
-	theMsp := &cachedMSP{MSP: o}
-	theMsp.deserializeIdentityCache = newSecondChanceCache(deserializeIdentityCacheSize)
+	theMsp := &cachedMSP{MSP: o, generation: atomic.LoadUint64(&generation)}
+	theMsp.deserializeIdentityCache = newLRUCache(deserializeIdentityCacheSize)
 	theMsp.satisfiesPrincipalCache = newSecondChanceCache(satisfiesPrincipalCacheSize)
 	theMsp.validateIdentityCache = newSecondChanceCache(validateIdentityCacheSize)
 
@@ -36,10 +51,14 @@ func New(o msp.MSP) (msp.MSP, error) {
 }
 
 type cachedMSP struct {
+	// generation of the cached results, first for 64-bit alignment
+	generation uint64
+
 	msp.MSP
 
-	// cache for DeserializeIdentity.
-	deserializeIdentityCache *secondChanceCache
+	// cache for DeserializeIdentity, keyed by the SM3 digest of the
+	// serialized identity
+	deserializeIdentityCache *lruCache
 
 	// cache for validateIdentity
 	validateIdentityCache *secondChanceCache
@@ -62,8 +81,20 @@ func (id *cachedIdentity) Validate() error {
 	return id.cache.Validate(id.Identity)
 }
 
+// identityCacheKey returns the key of a serialized identity in the
+// DeserializeIdentity cache, its SM3 digest, which is much shorter than
+// the certificate it contains.
+func identityCacheKey(serializedIdentity []byte) string {
+	h := sm3.New()
+	h.Write(serializedIdentity)
+	return string(h.Sum(nil))
+}
+
 func (c *cachedMSP) DeserializeIdentity(serializedIdentity []byte) (msp.Identity, error) {
-	id, ok := c.deserializeIdentityCache.get(string(serializedIdentity))
+	c.checkGeneration()
+
+	key := identityCacheKey(serializedIdentity)
+	id, ok := c.deserializeIdentityCache.get(key)
 	if ok {
 		return &cachedIdentity{
 			cache:    c,
@@ -73,7 +104,7 @@ func (c *cachedMSP) DeserializeIdentity(serializedIdentity []byte) (msp.Identity
 
 	id, err := c.MSP.DeserializeIdentity(serializedIdentity)
 	if err == nil {
-		c.deserializeIdentityCache.add(string(serializedIdentity), id)
+		c.deserializeIdentityCache.add(key, id)
 		return &cachedIdentity{
 			cache:    c,
 			Identity: id.(msp.Identity),
@@ -89,6 +120,8 @@ func (c *cachedMSP) Setup(config *pmsp.MSPConfig) error {
 }
 
 func (c *cachedMSP) Validate(id msp.Identity) error {
+	c.checkGeneration()
+
 	identifier := id.GetIdentifier()
 	key := string(identifier.Mspid + ":" + identifier.Id)
 
@@ -107,6 +140,8 @@ func (c *cachedMSP) Validate(id msp.Identity) error {
 }
 
 func (c *cachedMSP) SatisfiesPrincipal(id msp.Identity, principal *pmsp.MSPPrincipal) error {
+	c.checkGeneration()
+
 	identifier := id.GetIdentifier()
 	identityKey := string(identifier.Mspid + ":" + identifier.Id)
 	principalKey := string(principal.PrincipalClassification) + string(principal.Principal)
@@ -128,9 +163,18 @@ func (c *cachedMSP) SatisfiesPrincipal(id msp.Identity, principal *pmsp.MSPPrinc
 }
 
 func (c *cachedMSP) cleanCache() error {
-	c.deserializeIdentityCache = newSecondChanceCache(deserializeIdentityCacheSize)
-	c.satisfiesPrincipalCache = newSecondChanceCache(satisfiesPrincipalCacheSize)
-	c.validateIdentityCache = newSecondChanceCache(validateIdentityCacheSize)
+	c.deserializeIdentityCache.purge()
+	c.satisfiesPrincipalCache.purge()
+	c.validateIdentityCache.purge()
 
 	return nil
 }
+
+// checkGeneration cleans the cache if Invalidate was called since it was
+// last cleaned.
+func (c *cachedMSP) checkGeneration() {
+	current := atomic.LoadUint64(&generation)
+	if old := atomic.LoadUint64(&c.generation); old != current && atomic.CompareAndSwapUint64(&c.generation, old, current) {
+		c.cleanCache()
+	}
+}
@@ -152,7 +152,7 @@ func TestDeserializeIdentity(t *testing.T) {
 
 	mockMSP.AssertExpectations(t)
 	// Check the cache
-	_, ok := wrappedMSP.(*cachedMSP).deserializeIdentityCache.get(string(serializedIdentity))
+	_, ok := wrappedMSP.(*cachedMSP).deserializeIdentityCache.get(identityCacheKey(serializedIdentity))
 	assert.True(t, ok)
 
 	// Check the same object is returned
@@ -170,7 +170,7 @@ func TestDeserializeIdentity(t *testing.T) {
 	assert.Contains(t, err.Error(), "Invalid identity")
 	mockMSP.AssertExpectations(t)
 
-	_, ok = wrappedMSP.(*cachedMSP).deserializeIdentityCache.get(string(serializedIdentity))
+	_, ok = wrappedMSP.(*cachedMSP).deserializeIdentityCache.get(identityCacheKey(serializedIdentity))
 	assert.False(t, ok)
 }
 
@@ -321,3 +321,26 @@ func TestSatisfiesPrincipal(t *testing.T) {
 	assert.NotNil(t, v)
 	assert.Contains(t, "Invalid", v.(error).Error())
 }
+
+func TestInvalidate(t *testing.T) {
+	mockMSP := &mocks.MockMSP{}
+	i, err := New(mockMSP)
+	assert.NoError(t, err)
+
+	serializedIdentity := []byte{1, 2, 3}
+	mockMSP.On("DeserializeIdentity", serializedIdentity).Return(&mocks.MockIdentity{ID: "Alice"}, nil)
+	_, err = i.DeserializeIdentity(serializedIdentity)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i.(*cachedMSP).deserializeIdentityCache.len())
+
+	// The cache is dropped when it is next used
+	Invalidate()
+	assert.Equal(t, 1, i.(*cachedMSP).deserializeIdentityCache.len())
+	i.(*cachedMSP).checkGeneration()
+	assert.Equal(t, 0, i.(*cachedMSP).deserializeIdentityCache.len())
+
+	_, err = i.DeserializeIdentity(serializedIdentity)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i.(*cachedMSP).deserializeIdentityCache.len())
+	mockMSP.AssertNumberOfCalls(t, "DeserializeIdentity", 2)
+}
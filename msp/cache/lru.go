@@ -0,0 +1,83 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache holds key-value items with a limited size. When the number of
+// cached items exceeds the limit, the least recently used item is purged.
+type lruCache struct {
+	size int
+
+	// most recently used items first
+	items *list.List
+	table map[string]*list.Element
+
+	// get moves items in the list, so it takes the lock too
+	lock sync.Mutex
+}
+
+type lruItem struct {
+	key   string
+	value interface{}
+}
+
+func newLRUCache(cacheSize int) *lruCache {
+	return &lruCache{
+		size:  cacheSize,
+		items: list.New(),
+		table: make(map[string]*list.Element),
+	}
+}
+
+func (cache *lruCache) len() int {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	return len(cache.table)
+}
+
+func (cache *lruCache) get(key string) (interface{}, bool) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	elem, ok := cache.table[key]
+	if !ok {
+		return nil, false
+	}
+	cache.items.MoveToFront(elem)
+	return elem.Value.(*lruItem).value, true
+}
+
+func (cache *lruCache) add(key string, value interface{}) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if elem, ok := cache.table[key]; ok {
+		elem.Value.(*lruItem).value = value
+		cache.items.MoveToFront(elem)
+		return
+	}
+
+	cache.table[key] = cache.items.PushFront(&lruItem{key: key, value: value})
+	if cache.items.Len() > cache.size {
+		victim := cache.items.Back()
+		cache.items.Remove(victim)
+		delete(cache.table, victim.Value.(*lruItem).key)
+	}
+}
+
+func (cache *lruCache) purge() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	cache.items.Init()
+	cache.table = make(map[string]*list.Element)
+}
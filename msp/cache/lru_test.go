@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache(2)
+
+	cache.add("a", "xyz")
+	cache.add("b", "123")
+	obj, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "xyz", obj.(string))
+
+	// b is the least recently used
+	cache.add("c", "777")
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+
+	cache.add("c", "456")
+	obj, ok = cache.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, "456", obj.(string))
+	assert.Equal(t, 2, cache.len())
+
+	cache.add("d", "555")
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+
+	cache.purge()
+	assert.Equal(t, 0, cache.len())
+	_, ok = cache.get("c")
+	assert.False(t, ok)
+}
+
+func TestLRUCacheConcurrent(t *testing.T) {
+	cache := newLRUCache(25)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("%d", i%50)
+			cache.add(key, i)
+			cache.get(key)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 25, cache.len())
+}
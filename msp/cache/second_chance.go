@@ -112,3 +112,12 @@ func (cache *secondChanceCache) add(key string, value interface{}) {
 		cache.position = (cache.position + 1) % size
 	}
 }
+
+func (cache *secondChanceCache) purge() {
+	cache.rwlock.Lock()
+	defer cache.rwlock.Unlock()
+
+	cache.position = 0
+	cache.items = make([]*cacheItem, len(cache.items))
+	cache.table = make(map[string]*cacheItem)
+}
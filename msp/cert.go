@@ -27,6 +27,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
 	"github.com/pkg/errors"
 )
@@ -81,7 +82,12 @@ func sanitizeECDSASignedCert(cert *x509.Certificate, parentCert *x509.Certificat
 		return nil, errors.New("parent certificate must be different from nil")
 	}
 
-	expectedSig, err := utils.SignatureToLowS(parentCert.PublicKey.(*ecdsa.PublicKey), cert.Signature)
+	parentKey, ok := parentCert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("ECDSA signed certificate issued by a %T key", parentCert.PublicKey)
+	}
+
+	expectedSig, err := utils.SignatureToLowS(parentKey, cert.Signature)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +118,7 @@ func sanitizeECDSASignedCert(cert *x509.Certificate, parentCert *x509.Certificat
 	}
 
 	// 4. parse newRaw to get an x509 certificate
-	return x509.ParseCertificate(newRaw)
+	return gmx509.ParseCertificate(newRaw)
 }
 
 func certFromX509Cert(cert *x509.Certificate) (certificate, error) {
@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/x509"
+	"sync"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+)
+
+var (
+	chainPolicyMutex sync.RWMutex
+	chainPolicy      = gmx509.ChainMixed
+)
+
+// SetChainPolicy sets how MSPs let the certification chains of their
+// members and TLS CAs mix SM2 with other algorithms. Chains mixing them,
+// such as GM intermediates cross-signed by an ECDSA root, are accepted
+// by default; gmx509.ChainGMOnly enforces full GM compliance.
+func SetChainPolicy(policy gmx509.ChainPolicy) {
+	chainPolicyMutex.Lock()
+	defer chainPolicyMutex.Unlock()
+	chainPolicy = policy
+}
+
+func getChainPolicy() gmx509.ChainPolicy {
+	chainPolicyMutex.RLock()
+	defer chainPolicyMutex.RUnlock()
+	return chainPolicy
+}
+
+// filterChainsByPolicy drops the chains the chain policy forbids.
+func filterChainsByPolicy(chains [][]*x509.Certificate) ([][]*x509.Certificate, error) {
+	policy := getChainPolicy()
+	var allowed [][]*x509.Certificate
+	var err error
+	for _, chain := range chains {
+		if err = gmx509.CheckChainPolicy(chain, policy); err == nil {
+			allowed = append(allowed, chain)
+		}
+	}
+	if len(allowed) == 0 {
+		return nil, err
+	}
+	return allowed, nil
+}
+
+// newGMVerifyOptions returns gmx509 options verifying chains up to roots
+// through intermediates under the chain policy.
+func newGMVerifyOptions(roots, intermediates []*x509.Certificate) *gmx509.VerifyOptions {
+	opts := &gmx509.VerifyOptions{Roots: gmx509.NewCertPool(), Intermediates: gmx509.NewCertPool()}
+	for _, cert := range roots {
+		opts.Roots.AddCert(cert)
+	}
+	for _, cert := range intermediates {
+		opts.Intermediates.AddCert(cert)
+	}
+	return opts
+}
+
+// gmVerifyOptions returns the gmx509 counterpart of opts, built from the
+// same certificates, or nil if there is none.
+func (msp *bccspmsp) gmVerifyOptions(opts x509.VerifyOptions) *gmx509.VerifyOptions {
+	var gmOpts *gmx509.VerifyOptions
+	switch {
+	case msp.opts != nil && opts.Roots == msp.opts.Roots:
+		gmOpts = msp.gmOpts
+	case msp.tlsOpts != nil && opts.Roots == msp.tlsOpts.Roots:
+		gmOpts = msp.tlsGMOpts
+	}
+	if gmOpts == nil {
+		return nil
+	}
+
+	o := *gmOpts
+	o.CurrentTime = opts.CurrentTime
+	o.KeyUsages = opts.KeyUsages
+	o.Policy = getChainPolicy()
+	return &o
+}
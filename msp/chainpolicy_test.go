@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMixedChainPolicy(t *testing.T) {
+	defer SetChainPolicy(gmx509.ChainMixed)
+
+	// An SM2 leaf issued by an ECDSA root
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca.org1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	root, err := x509.ParseCertificate(rootDER)
+	require.NoError(t, err)
+
+	leafKey, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	leafDER, err := gmx509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "peer0.org1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, root, &leafKey.PublicKey, rootKey)
+	require.NoError(t, err)
+
+	fmspconf := &msp.FabricMSPConfig{
+		Name:      "Org1MSP",
+		RootCerts: [][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER})},
+		CryptoConfig: &msp.FabricCryptoConfig{
+			SignatureHashFamily:            bccsp.SHA2,
+			IdentityIdentifierHashFunction: bccsp.SHA256,
+		},
+	}
+	raw, err := proto.Marshal(fmspconf)
+	require.NoError(t, err)
+	cryptoProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	thisMSP, err := newBccspMsp(MSPv1_0, cryptoProvider)
+	require.NoError(t, err)
+	require.NoError(t, thisMSP.Setup(&msp.MSPConfig{Config: raw, Type: int32(FABRIC)}))
+
+	serialized, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   "Org1MSP",
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+	})
+	require.NoError(t, err)
+	id, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.NoError(t, thisMSP.Validate(id))
+
+	// The chain is already built to sanitize the certificate
+	SetChainPolicy(gmx509.ChainUniform)
+	_, err = thisMSP.DeserializeIdentity(serialized)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the certificate chain mixes GM and non-GM algorithms")
+}
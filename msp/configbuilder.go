@@ -55,6 +55,9 @@ type Configuration struct {
 	// NodeOUs enables the MSP to tell apart clients, peers and orderers based
 	// on the identity's OU.
 	NodeOUs *NodeOUs `yaml:"NodeOUs,omitempty"`
+	// SignatureHashFamily is the hash family of the identities' signatures,
+	// SHA2 by default. With SM3, SM2 identities sign with SM3WithSM2.
+	SignatureHashFamily string `yaml:"SignatureHashFamily,omitempty"`
 }
 
 func readFile(file string) ([]byte, error) {
@@ -124,6 +127,7 @@ const (
 	cacerts              = "cacerts"
 	admincerts           = "admincerts"
 	signcerts            = "signcerts"
+	enccerts             = "enccerts"
 	keystore             = "keystore"
 	intermediatecerts    = "intermediatecerts"
 	crlsfolder           = "crls"
@@ -150,6 +154,21 @@ func SetupBCCSPKeystoreConfig(bccspConfig *factory.FactoryOpts, keystoreDir stri
 		}
 	}
 
+	if bccspConfig.ProviderName == "GM" || bccspConfig.GmOpts != nil {
+		if bccspConfig.GmOpts == nil {
+			bccspConfig.GmOpts = factory.GetDefaultGMOpts(keystoreDir)
+		}
+
+		// Only override the path of file key stores if it was left empty
+		gmKeyStore := bccspConfig.GmOpts.KeyStore
+		switch {
+		case gmKeyStore == nil:
+			bccspConfig.GmOpts.KeyStore = &factory.GMKeyStoreOpts{Backend: factory.GMKeyStoreFile, Path: keystoreDir}
+		case gmKeyStore.Path == "" && (gmKeyStore.Backend == "" || gmKeyStore.Backend == factory.GMKeyStoreFile):
+			gmKeyStore.Path = keystoreDir
+		}
+	}
+
 	return bccspConfig
 }
 
@@ -188,7 +207,21 @@ func GetLocalMspConfig(dir string, bccspConfig *factory.FactoryOpts, ID string)
 	   signing cert
 	*/
 
-	sigid := &msp.SigningIdentityInfo{PublicSigner: signcert[0], PrivateSigner: nil}
+	publicSigner := signcert[0]
+
+	// The encryption certificate of a dual certificate identity follows
+	// its signing certificate
+	enccertDir := filepath.Join(dir, enccerts)
+	enccert, err := getPemMaterialFromDir(enccertDir)
+	if os.IsNotExist(err) {
+		mspLogger.Debugf("Encryption certs folder not found at [%s]. Skipping. [%s]", enccertDir, err)
+	} else if err != nil {
+		return nil, errors.WithMessagef(err, "failed loading encryption certs at [%s]", enccertDir)
+	} else if len(enccert) != 0 {
+		publicSigner = append(append([]byte{}, publicSigner...), enccert[0]...)
+	}
+
+	sigid := &msp.SigningIdentityInfo{PublicSigner: publicSigner, PrivateSigner: nil}
 
 	return getMspConfig(dir, ID, sigid)
 }
@@ -260,6 +293,7 @@ func getMspConfig(dir string, ID string, sigid *msp.SigningIdentityInfo) (*msp.M
 	// otherwise skip it
 	var ouis []*msp.FabricOUIdentifier
 	var nodeOUs *msp.FabricNodeOUs
+	signatureHashFamily := bccsp.SHA2
 	_, err = os.Stat(configFile)
 	if err == nil {
 		// load the file, if there is a failure in loading it then
@@ -275,6 +309,14 @@ func getMspConfig(dir string, ID string, sigid *msp.SigningIdentityInfo) (*msp.M
 			return nil, errors.Wrapf(err, "failed unmarshalling configuration file at [%s]", configFile)
 		}
 
+		switch configuration.SignatureHashFamily {
+		case "":
+		case bccsp.SHA2, bccsp.SHA3, bccsp.SM3:
+			signatureHashFamily = configuration.SignatureHashFamily
+		default:
+			return nil, errors.Errorf("invalid signature hash family [%s] in configuration file at [%s]", configuration.SignatureHashFamily, configFile)
+		}
+
 		// Prepare OrganizationalUnitIdentifiers
 		if len(configuration.OrganizationalUnitIdentifiers) > 0 {
 			for _, ouID := range configuration.OrganizationalUnitIdentifiers {
@@ -336,7 +378,7 @@ func getMspConfig(dir string, ID string, sigid *msp.SigningIdentityInfo) (*msp.M
 
 	// Set FabricCryptoConfig
 	cryptoConfig := &msp.FabricCryptoConfig{
-		SignatureHashFamily:            bccsp.SHA2,
+		SignatureHashFamily:            signatureHashFamily,
 		IdentityIdentifierHashFunction: bccsp.SHA256,
 	}
 
@@ -12,9 +12,13 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSetupBCCSPKeystoreConfig(t *testing.T) {
@@ -82,6 +86,32 @@ func TestSetupBCCSPKeystoreConfig(t *testing.T) {
 	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
 	assert.NotNil(t, rtnConfig.SwOpts.FileKeystore)
 	assert.Equal(t, rtnConfig.SwOpts.FileKeystore.KeyStorePath, keystoreDir)
+
+	// Case 4 : Check with 'GM' as default provider
+	// Case 4-1 : without GmOpts
+	bccspConfig = &factory.FactoryOpts{
+		ProviderName: "GM",
+	}
+	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
+	assert.Equal(t, rtnConfig.GmOpts, factory.GetDefaultGMOpts(keystoreDir))
+
+	// Case 4-2 : without GmOpts.KeyStore
+	bccspConfig.GmOpts = &factory.GmOpts{
+		HashFamily: "SM3",
+		SecLevel:   256,
+	}
+	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
+	assert.Equal(t, rtnConfig.GmOpts.KeyStore, &factory.GMKeyStoreOpts{Backend: "file", Path: keystoreDir})
+
+	// Case 4-3 : with an in-memory key store
+	bccspConfig.GmOpts.KeyStore = &factory.GMKeyStoreOpts{Backend: "inmem"}
+	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
+	assert.Equal(t, rtnConfig.GmOpts.KeyStore.Path, "")
+
+	// Case 4-4 : with GmOpts.KeyStore.Path
+	bccspConfig.GmOpts.KeyStore = &factory.GMKeyStoreOpts{Path: "/var/keystore"}
+	rtnConfig = SetupBCCSPKeystoreConfig(bccspConfig, keystoreDir)
+	assert.Equal(t, rtnConfig.GmOpts.KeyStore.Path, "/var/keystore")
 }
 
 func TestGetLocalMspConfig(t *testing.T) {
@@ -95,6 +125,33 @@ func TestGetLocalMspConfigFails(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestGetMspConfigSignatureHashFamily(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "fabric-msp-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	err = os.Symlink(filepath.Join(configtest.GetDevMspDir(), "cacerts"), filepath.Join(tempDir, "cacerts"))
+	require.NoError(t, err)
+
+	conf, err := GetVerifyingMspConfig(tempDir, "SampleOrg", ProviderTypeToString(FABRIC))
+	require.NoError(t, err)
+	fmspconf := &msp.FabricMSPConfig{}
+	require.NoError(t, proto.Unmarshal(conf.Config, fmspconf))
+	assert.Equal(t, bccsp.SHA2, fmspconf.CryptoConfig.SignatureHashFamily)
+
+	err = ioutil.WriteFile(filepath.Join(tempDir, configfilename), []byte("SignatureHashFamily: SM3\n"), 0644)
+	require.NoError(t, err)
+	conf, err = GetVerifyingMspConfig(tempDir, "SampleOrg", ProviderTypeToString(FABRIC))
+	require.NoError(t, err)
+	require.NoError(t, proto.Unmarshal(conf.Config, fmspconf))
+	assert.Equal(t, bccsp.SM3, fmspconf.CryptoConfig.SignatureHashFamily)
+
+	err = ioutil.WriteFile(filepath.Join(tempDir, configfilename), []byte("SignatureHashFamily: MD5\n"), 0644)
+	require.NoError(t, err)
+	_, err = GetVerifyingMspConfig(tempDir, "SampleOrg", ProviderTypeToString(FABRIC))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid signature hash family [MD5]")
+}
+
 func TestGetPemMaterialFromDirWithFile(t *testing.T) {
 	tempFile, err := ioutil.TempFile("", "fabric-msp-test")
 	assert.NoError(t, err)
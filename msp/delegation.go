@@ -0,0 +1,258 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/pkg/errors"
+)
+
+// OIDExtensionDelegation identifies the critical extension of delegation
+// certificates, next to the attribute extension of Fabric CA
+// (1.2.3.4.5.6.7.8.1). Its value is the sequence of the UTF8 strings of
+// the scopes the delegation grants.
+var OIDExtensionDelegation = asn1.ObjectIdentifier{1, 2, 3, 4, 5, 6, 7, 8, 2}
+
+// MSPPrincipalDelegation classifies the principals satisfied by delegated
+// identities, whose Principal is a marshalled DelegationPrincipal. It lies
+// out of the classifications of the MSPPrincipal protobuf, and requires
+// MSP v1.4.3 or higher.
+const MSPPrincipalDelegation m.MSPPrincipal_Classification = 100
+
+// DefaultMaxDelegationLifetime is the default maximum validity period of
+// delegation certificates.
+const DefaultMaxDelegationLifetime = 24 * time.Hour
+
+// DelegationPrincipal is the principal of the identities delegated by an
+// identity satisfying Delegator. It is the Principal of MSPPrincipals
+// classified as MSPPrincipalDelegation, hence the protobuf format.
+type DelegationPrincipal struct {
+	// Delegator is the principal the delegating identity satisfies
+	Delegator *m.MSPPrincipal `protobuf:"bytes,1,opt,name=delegator,proto3"`
+
+	// Scope is the scope the delegation must grant, any scope if empty
+	Scope string `protobuf:"bytes,2,opt,name=scope,proto3"`
+}
+
+// Reset resets
+func (p *DelegationPrincipal) Reset() { *p = DelegationPrincipal{} }
+
+// String converts to string
+func (p *DelegationPrincipal) String() string { return proto.CompactTextString(p) }
+
+// ProtoMessage just exists to make proto happy
+func (*DelegationPrincipal) ProtoMessage() {}
+
+var (
+	delegationMutex       sync.RWMutex
+	maxDelegationLifetime = DefaultMaxDelegationLifetime
+)
+
+// SetMaxDelegationLifetime sets the maximum validity period of the
+// delegation certificates MSPs accept and Delegate issues.
+func SetMaxDelegationLifetime(lifetime time.Duration) {
+	delegationMutex.Lock()
+	defer delegationMutex.Unlock()
+	maxDelegationLifetime = lifetime
+}
+
+func getMaxDelegationLifetime() time.Duration {
+	delegationMutex.RLock()
+	defer delegationMutex.RUnlock()
+	return maxDelegationLifetime
+}
+
+// Delegate issues a delegation certificate of pub, signed by delegator
+// and granting scopes for lifetime, and returns the serialized identity
+// it delegates, which signs with the private key of pub. Delegated
+// identities only satisfy delegation principals, so that SDK clients can
+// act for a long-lived admin identity with bounded exposure.
+func Delegate(delegator SigningIdentity, pub interface{}, scopes []string, lifetime time.Duration) ([]byte, error) {
+	id, ok := delegator.(*signingidentity)
+	if !ok {
+		return nil, errors.Errorf("identity type %T cannot delegate", delegator)
+	}
+	if id.delegator != nil {
+		return nil, errors.New("a delegated identity cannot delegate")
+	}
+	if lifetime <= 0 || lifetime > getMaxDelegationLifetime() {
+		return nil, errors.Errorf("invalid delegation lifetime %s, the maximum is %s", lifetime, getMaxDelegationLifetime())
+	}
+
+	ext, err := asn1.Marshal(scopes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshalling the delegation scopes")
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed generating the serial number")
+	}
+	notBefore := time.Now().Truncate(time.Second)
+	notAfter := notBefore.Add(lifetime)
+	if notAfter.After(id.cert.NotAfter) {
+		notAfter = id.cert.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber:    serial,
+		Subject:         pkix.Name{CommonName: id.cert.Subject.CommonName},
+		NotBefore:       notBefore,
+		NotAfter:        notAfter,
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{{Id: OIDExtensionDelegation, Critical: true, Value: ext}},
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, id.cert, pub, id.signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed creating the delegation certificate")
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: id.cert.Raw})...)
+	return NewSerializedIdentity(id.id.Mspid, certPEM)
+}
+
+// delegationScopes returns the scopes the delegation extension of cert
+// grants, and whether cert has one.
+func delegationScopes(cert *x509.Certificate) ([]string, bool, error) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(OIDExtensionDelegation) {
+			continue
+		}
+		var scopes []string
+		if rest, err := asn1.Unmarshal(ext.Value, &scopes); err != nil {
+			return nil, true, errors.Wrap(err, "failed to parse the delegation extension")
+		} else if len(rest) != 0 {
+			return nil, true, errors.New("trailing data after the delegation extension")
+		}
+		return scopes, true, nil
+	}
+	return nil, false, nil
+}
+
+// deserializeDelegatedIdentity returns the identity of the delegation
+// certificate cert, whose delegator's certificate is the PEM block of
+// rest.
+func (msp *bccspmsp) deserializeDelegatedIdentity(cert *x509.Certificate, scopes []string, rest []byte) (Identity, error) {
+	bl, _ := pem.Decode(rest)
+	if bl == nil {
+		return nil, errors.New("could not decode the PEM structure of the delegator")
+	}
+	delegatorCert, err := gmx509.ParseCertificate(bl.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parseCertificate failed for the delegator")
+	}
+	if _, delegated, _ := delegationScopes(delegatorCert); delegated {
+		return nil, errors.New("a delegated identity cannot delegate")
+	}
+
+	delegatorKey, err := msp.bccsp.KeyImport(delegatorCert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to import the delegator's public key")
+	}
+	delegator, err := newIdentity(delegatorCert, delegatorKey, msp)
+	if err != nil {
+		return nil, err
+	}
+
+	// The delegator is the parent to sanitize the signature against
+	if isECDSASignedCert(cert) {
+		cert, err = sanitizeECDSASignedCert(cert, delegatorCert)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pub, err := msp.bccsp.KeyImport(cert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to import certificate's public key")
+	}
+	id, err := newSanitizedIdentity(cert, pub, msp)
+	if err != nil {
+		return nil, err
+	}
+	id.delegator = delegator.(*identity)
+	id.scopes = scopes
+	return id, nil
+}
+
+// validateDelegation checks that the delegator of id is valid, that it
+// signed the delegation certificate and that the delegation is in force.
+func (msp *bccspmsp) validateDelegation(id *identity) error {
+	if err := msp.validateIdentity(id.delegator); err != nil {
+		return errors.WithMessage(err, "the delegator is not valid")
+	}
+
+	cert, delegatorCert := id.cert, id.delegator.cert
+	if cert.IsCA {
+		return errors.New("a delegation certificate cannot be a CA")
+	}
+	if !bytes.Equal(cert.RawIssuer, delegatorCert.RawSubject) {
+		return errors.New("the delegation certificate was not issued by the delegator")
+	}
+	if err := gmx509.CheckSignatureBy(cert, delegatorCert); err != nil {
+		return errors.Wrap(err, "the delegation certificate is not signed by the delegator")
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return errors.Errorf("the delegation is only valid from %s to %s", cert.NotBefore, cert.NotAfter)
+	}
+	if max := getMaxDelegationLifetime(); cert.NotAfter.Sub(cert.NotBefore) > max {
+		return errors.Errorf("the delegation is valid for longer than %s", max)
+	}
+	if cert.NotAfter.After(delegatorCert.NotAfter) {
+		return errors.New("the delegation outlives the delegator")
+	}
+
+	return checkAlgorithmPolicy([]*x509.Certificate{cert})
+}
+
+func isDelegated(id Identity) bool {
+	bid, ok := id.(*identity)
+	return ok && bid.delegator != nil
+}
+
+// satisfiesDelegationPrincipal returns nil if id is delegated for the
+// scope of principal by an identity satisfying its delegator principal.
+func (msp *bccspmsp) satisfiesDelegationPrincipal(id Identity, principal *m.MSPPrincipal) error {
+	if msp.GetVersion() < MSPv1_4_3 {
+		return errors.Errorf("invalid principal type %d", int32(principal.PrincipalClassification))
+	}
+	dp := &DelegationPrincipal{}
+	if err := proto.Unmarshal(principal.Principal, dp); err != nil {
+		return errors.Wrap(err, "could not unmarshal DelegationPrincipal from principal")
+	}
+	if dp.Delegator == nil {
+		return errors.New("no delegator principal in DelegationPrincipal")
+	}
+
+	bid, ok := id.(*identity)
+	if !ok || bid.delegator == nil {
+		return errors.New("the identity is not delegated")
+	}
+	if err := msp.Validate(bid); err != nil {
+		return errors.Wrapf(err, "the delegated identity is not valid under this MSP [%s]", msp.name)
+	}
+	if dp.Scope != "" && !containsString(bid.scopes, dp.Scope) {
+		return errors.Errorf("the delegation does not grant scope [%s]", dp.Scope)
+	}
+	if err := msp.SatisfiesPrincipal(bid.delegator, dp.Delegator); err != nil {
+		return errors.WithMessage(err, "the delegator does not satisfy the principal")
+	}
+	return nil
+}
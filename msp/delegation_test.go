@@ -0,0 +1,117 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func delegationPrincipal(t *testing.T, role msp.MSPRole_MSPRoleType, scope string) *msp.MSPPrincipal {
+	principalBytes, err := proto.Marshal(&DelegationPrincipal{Delegator: rolePrincipal(t, role), Scope: scope})
+	require.NoError(t, err)
+	return &msp.MSPPrincipal{
+		PrincipalClassification: MSPPrincipalDelegation,
+		Principal:               principalBytes,
+	}
+}
+
+func TestDelegation(t *testing.T) {
+	ca := newSM2TestCA(t)
+	adminCert, adminKey := ca.issue(t, 2, "admin.org1", "admin")
+	peerCert, _ := ca.issue(t, 3, "peer0.org1", "peer")
+	thisMSP := ca.setupMSP(t, bccsp.SM3, adminCert, adminKey)
+	admin, err := thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	_, err = Delegate(admin, &key.PublicKey, []string{"channel:mychannel"}, 2*DefaultMaxDelegationLifetime)
+	assert.EqualError(t, err, "invalid delegation lifetime 48h0m0s, the maximum is 24h0m0s")
+	serialized, err := Delegate(admin, &key.PublicKey, []string{"channel:mychannel"}, time.Hour)
+	require.NoError(t, err)
+
+	delegate, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.NoError(t, delegate.Validate())
+	assert.Nil(t, delegate.GetOrganizationalUnits())
+	reserialized, err := delegate.Serialize()
+	require.NoError(t, err)
+	assert.Equal(t, serialized, reserialized)
+
+	// The SDK signs with the delegated key
+	msg := []byte("proposal")
+	sig, err := gmx509.SignSM2(rand.Reader, key, msg)
+	require.NoError(t, err)
+	assert.NoError(t, delegate.Verify(msg, sig))
+	adminSig, err := admin.Sign(msg)
+	require.NoError(t, err)
+	assert.Error(t, delegate.Verify(msg, adminSig))
+
+	// Delegated identities only satisfy delegation principals
+	assert.NoError(t, delegate.SatisfiesPrincipal(delegationPrincipal(t, msp.MSPRole_ADMIN, "channel:mychannel")))
+	assert.NoError(t, delegate.SatisfiesPrincipal(delegationPrincipal(t, msp.MSPRole_ADMIN, "")))
+	err = delegate.SatisfiesPrincipal(delegationPrincipal(t, msp.MSPRole_ADMIN, "channel:other"))
+	assert.EqualError(t, err, "the delegation does not grant scope [channel:other]")
+	err = delegate.SatisfiesPrincipal(delegationPrincipal(t, msp.MSPRole_PEER, "channel:mychannel"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the delegator does not satisfy the principal")
+	err = delegate.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_MEMBER))
+	assert.EqualError(t, err, "a delegated identity only satisfies delegation principals")
+	adminID := admin.GetPublicVersion()
+	assert.NoError(t, adminID.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_ADMIN)))
+	err = adminID.SatisfiesPrincipal(delegationPrincipal(t, msp.MSPRole_ADMIN, ""))
+	assert.EqualError(t, err, "the identity is not delegated")
+
+	// A delegation presented with another delegator is rejected
+	sID := &msp.SerializedIdentity{}
+	require.NoError(t, proto.Unmarshal(serialized, sID))
+	block, _ := pem.Decode(sID.IdBytes)
+	forged, err := NewSerializedIdentity("Org1MSP", append(pem.EncodeToMemory(block), peerCert...))
+	require.NoError(t, err)
+	delegate, err = thisMSP.DeserializeIdentity(forged)
+	require.NoError(t, err)
+	err = delegate.Validate()
+	assert.EqualError(t, err, "the delegation certificate was not issued by the delegator")
+
+	// A delegation without its delegator is rejected
+	withoutDelegator, err := NewSerializedIdentity("Org1MSP", pem.EncodeToMemory(block))
+	require.NoError(t, err)
+	_, err = thisMSP.DeserializeIdentity(withoutDelegator)
+	assert.EqualError(t, err, "could not decode the PEM structure of the delegator")
+}
+
+func TestDelegationLifetime(t *testing.T) {
+	defer SetMaxDelegationLifetime(DefaultMaxDelegationLifetime)
+
+	ca := newSM2TestCA(t)
+	adminCert, adminKey := ca.issue(t, 2, "admin.org1", "admin")
+	thisMSP := ca.setupMSP(t, bccsp.SM3, adminCert, adminKey)
+	admin, err := thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	serialized, err := Delegate(admin, &key.PublicKey, nil, 30*time.Minute)
+	require.NoError(t, err)
+
+	// MSPs reject the delegations outlasting the maximum lifetime
+	SetMaxDelegationLifetime(time.Minute)
+	delegate, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.EqualError(t, delegate.Validate(), "the delegation is valid for longer than 1m0s")
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+)
+
+// DualCertSigningIdentity is a SigningIdentity with a separate encryption
+// certificate and key, as GM/T 0024 requires. The signing certificate is
+// the one the identity is serialized with.
+type DualCertSigningIdentity interface {
+	SigningIdentity
+
+	// GetEncryptionCertificate returns the encryption certificate of this identity
+	GetEncryptionCertificate() *x509.Certificate
+
+	// Decrypt decrypts ciphertext with the private key of the encryption certificate
+	Decrypt(ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error)
+}
+
+type dualcertsigningidentity struct {
+	*signingidentity
+
+	// encCert is the encryption certificate of this identity
+	encCert *x509.Certificate
+
+	// encKey is the private key of encCert
+	encKey bccsp.Key
+}
+
+// GetEncryptionCertificate returns the encryption certificate of this identity
+func (id *dualcertsigningidentity) GetEncryptionCertificate() *x509.Certificate {
+	return id.encCert
+}
+
+// Decrypt decrypts ciphertext with the private key of the encryption certificate
+func (id *dualcertsigningidentity) Decrypt(ciphertext []byte, opts bccsp.DecrypterOpts) ([]byte, error) {
+	return id.msp.bccsp.Decrypt(id.encKey, ciphertext, opts)
+}
+
+// encryptionCertPEM returns the PEM block following the signing certificate
+// in publicSigner, which holds the encryption certificate of dual
+// certificate identities, or nil.
+func encryptionCertPEM(publicSigner []byte) []byte {
+	_, rest := pem.Decode(publicSigner)
+	rest = bytes.TrimSpace(rest)
+	if len(rest) == 0 {
+		return nil
+	}
+	return rest
+}
+
+// getDualCertSigningIdentity completes sid with the encryption certificate
+// in encCertPEM and its private key, found in the BCCSP keystore.
+func (msp *bccspmsp) getDualCertSigningIdentity(sid *signingidentity, encCertPEM []byte) (SigningIdentity, error) {
+	encCert, err := msp.getCertFromPem(encCertPEM)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid encryption certificate")
+	}
+	if !bytes.Equal(encCert.RawSubject, sid.cert.RawSubject) {
+		return nil, errors.New("the subjects of the signing and encryption certificates differ")
+	}
+	if encCert.KeyUsage != 0 && encCert.KeyUsage&(x509.KeyUsageKeyEncipherment|x509.KeyUsageDataEncipherment|x509.KeyUsageKeyAgreement) == 0 {
+		return nil, errors.New("the encryption certificate is not valid for encipherment")
+	}
+
+	encPub, err := msp.bccsp.KeyImport(encCert, &bccsp.X509PublicKeyImportOpts{Temporary: true})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to import the public key of the encryption certificate")
+	}
+	encKey, err := msp.bccsp.GetKey(encPub.SKI())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "could not find the private key of the encryption certificate, SKI [%s]", hex.EncodeToString(encPub.SKI()))
+	}
+	if uk, ok := encKey.(bccsp.SM2UsageKey); ok && uk.Usage() == bccsp.SM2KeyUsageSign {
+		return nil, errors.New("the private key of the encryption certificate is tagged for signing")
+	}
+
+	encID, err := newIdentity(encCert, encPub, msp)
+	if err != nil {
+		return nil, err
+	}
+	// The encryption certificate is only required to chain to the CAs of
+	// this MSP, as encryption certificates seldom carry the node OUs.
+	if _, err := msp.getCertificationChainForBCCSPIdentity(encID.(*identity)); err != nil {
+		return nil, errors.WithMessage(err, "the encryption certificate is not valid")
+	}
+
+	return &dualcertsigningidentity{signingidentity: sid, encCert: encCert, encKey: encKey}, nil
+}
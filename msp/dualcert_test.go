@@ -0,0 +1,150 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dualCertTestCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	csp  bccsp.BCCSP
+}
+
+func newDualCertTestCA(t *testing.T) *dualCertTestCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca.org1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	csp, err := sw.NewWithParams(256, "SHA2", sw.NewInMemoryKeyStore())
+	require.NoError(t, err)
+	return &dualCertTestCA{cert: cert, key: key, csp: csp}
+}
+
+// issue returns a PEM certificate for a new key, stored in the keystore
+func (ca *dualCertTestCA) issue(t *testing.T, serial int64, cn string, usage x509.KeyUsage) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	_, err = ca.csp.KeyImport(der, &bccsp.ECDSAPrivateKeyImportOpts{})
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     usage,
+	}
+	der, err = x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func (ca *dualCertTestCA) setupMSP(t *testing.T, publicSigner []byte) (MSP, error) {
+	fmspconf := &msp.FabricMSPConfig{
+		Name:      "Org1MSP",
+		RootCerts: [][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})},
+		SigningIdentity: &msp.SigningIdentityInfo{
+			PublicSigner: publicSigner,
+		},
+		CryptoConfig: &msp.FabricCryptoConfig{
+			SignatureHashFamily:            bccsp.SHA2,
+			IdentityIdentifierHashFunction: bccsp.SHA256,
+		},
+	}
+	raw, err := proto.Marshal(fmspconf)
+	require.NoError(t, err)
+
+	thisMSP, err := newBccspMsp(MSPv1_0, ca.csp)
+	require.NoError(t, err)
+	return thisMSP, thisMSP.Setup(&msp.MSPConfig{Config: raw, Type: int32(FABRIC)})
+}
+
+func TestDualCertSigningIdentity(t *testing.T) {
+	ca := newDualCertTestCA(t)
+	signCert := ca.issue(t, 2, "peer0.org1", x509.KeyUsageDigitalSignature)
+	encCert := ca.issue(t, 3, "peer0.org1", x509.KeyUsageKeyEncipherment|x509.KeyUsageDataEncipherment)
+
+	thisMSP, err := ca.setupMSP(t, append(append([]byte{}, signCert...), encCert...))
+	require.NoError(t, err)
+	id, err := thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	dualID, ok := id.(DualCertSigningIdentity)
+	require.True(t, ok)
+
+	block, _ := pem.Decode(encCert)
+	assert.Equal(t, block.Bytes, dualID.GetEncryptionCertificate().Raw)
+
+	// The identity is serialized with its signing certificate
+	serialized, err := id.Serialize()
+	require.NoError(t, err)
+	sID := &msp.SerializedIdentity{}
+	require.NoError(t, proto.Unmarshal(serialized, sID))
+	assert.Equal(t, signCert, sID.IdBytes)
+
+	sig, err := id.Sign([]byte("message"))
+	require.NoError(t, err)
+	assert.NoError(t, id.Verify([]byte("message"), sig))
+
+	// Single certificate identities are not dual certificate identities
+	thisMSP, err = ca.setupMSP(t, signCert)
+	require.NoError(t, err)
+	id, err = thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	_, ok = id.(DualCertSigningIdentity)
+	assert.False(t, ok)
+}
+
+func TestDualCertSigningIdentityErrors(t *testing.T) {
+	ca := newDualCertTestCA(t)
+	signCert := ca.issue(t, 2, "peer0.org1", x509.KeyUsageDigitalSignature)
+
+	encCert := ca.issue(t, 3, "peer1.org1", x509.KeyUsageKeyEncipherment)
+	_, err := ca.setupMSP(t, append(append([]byte{}, signCert...), encCert...))
+	assert.EqualError(t, err, "the subjects of the signing and encryption certificates differ")
+
+	encCert = ca.issue(t, 4, "peer0.org1", x509.KeyUsageDigitalSignature)
+	_, err = ca.setupMSP(t, append(append([]byte{}, signCert...), encCert...))
+	assert.EqualError(t, err, "the encryption certificate is not valid for encipherment")
+
+	// Issued by another CA
+	other := newDualCertTestCA(t)
+	other.csp = ca.csp
+	encCert = other.issue(t, 5, "peer0.org1", x509.KeyUsageKeyEncipherment)
+	_, err = ca.setupMSP(t, append(append([]byte{}, signCert...), encCert...))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the encryption certificate is not valid")
+}
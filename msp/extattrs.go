@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ExtensionAttributes are the identity attributes a certificate extension
+// carries.
+type ExtensionAttributes struct {
+	// OrganizationalUnits are matched like the organizational units of the
+	// subject. A role granted by the extension is reported as the
+	// organizational unit identifier of the matching node OU.
+	OrganizationalUnits []string
+}
+
+// ExtensionParser extracts the identity attributes from a certificate
+// extension, the private extensions of a CA vendor for instance.
+type ExtensionParser func(ext pkix.Extension) (*ExtensionAttributes, error)
+
+var (
+	extensionParsersMutex sync.RWMutex
+	extensionParsers      = map[string]ExtensionParser{}
+)
+
+// RegisterExtensionParser makes MSPs extract identity attributes from the
+// certificate extensions identified by oid with parser, so that principals
+// can match the attributes the extensions carry. Registered extensions
+// marked critical no longer fail validation. A nil parser unregisters oid.
+func RegisterExtensionParser(oid asn1.ObjectIdentifier, parser ExtensionParser) {
+	extensionParsersMutex.Lock()
+	defer extensionParsersMutex.Unlock()
+
+	if parser == nil {
+		delete(extensionParsers, oid.String())
+		return
+	}
+	extensionParsers[oid.String()] = parser
+}
+
+func getExtensionParser(oid asn1.ObjectIdentifier) ExtensionParser {
+	extensionParsersMutex.RLock()
+	defer extensionParsersMutex.RUnlock()
+	return extensionParsers[oid.String()]
+}
+
+// StringsExtensionParser parses extensions whose value is a string or a
+// sequence of strings, each an organizational unit.
+func StringsExtensionParser(ext pkix.Extension) (*ExtensionAttributes, error) {
+	var units []string
+	if _, err := asn1.Unmarshal(ext.Value, &units); err == nil {
+		return &ExtensionAttributes{OrganizationalUnits: units}, nil
+	}
+	var unit string
+	if rest, err := asn1.Unmarshal(ext.Value, &unit); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse extension %s", ext.Id)
+	} else if len(rest) != 0 {
+		return nil, errors.Errorf("trailing data after extension %s", ext.Id)
+	}
+	return &ExtensionAttributes{OrganizationalUnits: []string{unit}}, nil
+}
+
+// extensionOrganizationalUnits returns the organizational units the
+// registered extensions of cert carry.
+func extensionOrganizationalUnits(cert *x509.Certificate) ([]string, error) {
+	var units []string
+	for _, ext := range cert.Extensions {
+		parser := getExtensionParser(ext.Id)
+		if parser == nil {
+			continue
+		}
+		attrs, err := parser(ext)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to parse the attributes of extension %s", ext.Id)
+		}
+		if attrs != nil {
+			units = append(units, attrs.OrganizationalUnits...)
+		}
+	}
+	return units, nil
+}
+
+// withHandledExtensions returns cert, or a copy of cert without the
+// registered extensions and the handled ones among its unhandled critical
+// extensions.
+func withHandledExtensions(cert *x509.Certificate, handled ...asn1.ObjectIdentifier) *x509.Certificate {
+	if len(cert.UnhandledCriticalExtensions) == 0 {
+		return cert
+	}
+	var unhandled []asn1.ObjectIdentifier
+	for _, oid := range cert.UnhandledCriticalExtensions {
+		if getExtensionParser(oid) == nil && !containsOID(handled, oid) {
+			unhandled = append(unhandled, oid)
+		}
+	}
+	if len(unhandled) == len(cert.UnhandledCriticalExtensions) {
+		return cert
+	}
+	c := *cert
+	c.UnhandledCriticalExtensions = unhandled
+	return &c
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsOID(oids []asn1.ObjectIdentifier, oid asn1.ObjectIdentifier) bool {
+	for _, o := range oids {
+		if o.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRoleExtensionOID = asn1.ObjectIdentifier{1, 2, 156, 112562, 2, 1, 1, 99}
+
+func TestStringsExtensionParser(t *testing.T) {
+	value, err := asn1.Marshal([]string{"peer", "org1"})
+	require.NoError(t, err)
+	attrs, err := StringsExtensionParser(pkix.Extension{Id: testRoleExtensionOID, Value: value})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"peer", "org1"}, attrs.OrganizationalUnits)
+
+	value, err = asn1.MarshalWithParams("admin", "utf8")
+	require.NoError(t, err)
+	attrs, err = StringsExtensionParser(pkix.Extension{Id: testRoleExtensionOID, Value: value})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, attrs.OrganizationalUnits)
+
+	_, err = StringsExtensionParser(pkix.Extension{Id: testRoleExtensionOID, Value: []byte{0x02, 0x01, 0x01}})
+	assert.Error(t, err)
+}
+
+func TestExtensionAttributesPrincipal(t *testing.T) {
+	defer RegisterExtensionParser(testRoleExtensionOID, nil)
+
+	ca := newDualCertTestCA(t)
+	fmspconf := &msp.FabricMSPConfig{
+		Name:      "Org1MSP",
+		RootCerts: [][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})},
+		CryptoConfig: &msp.FabricCryptoConfig{
+			SignatureHashFamily:            bccsp.SHA2,
+			IdentityIdentifierHashFunction: bccsp.SHA256,
+		},
+		FabricNodeOus: &msp.FabricNodeOUs{
+			Enable:             true,
+			ClientOuIdentifier: &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "client"},
+			PeerOuIdentifier:   &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "peer"},
+		},
+	}
+	raw, err := proto.Marshal(fmspconf)
+	require.NoError(t, err)
+	thisMSP, err := newBccspMsp(MSPv1_1, ca.csp)
+	require.NoError(t, err)
+	require.NoError(t, thisMSP.Setup(&msp.MSPConfig{Config: raw, Type: int32(FABRIC)}))
+
+	// The role is in a critical private extension, not in the subject
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	value, err := asn1.Marshal([]string{"peer"})
+	require.NoError(t, err)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "peer0.org1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{
+			{Id: testRoleExtensionOID, Critical: true, Value: value},
+		},
+	}, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	serialized, err := proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   "Org1MSP",
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+	require.NoError(t, err)
+
+	RegisterExtensionParser(testRoleExtensionOID, StringsExtensionParser)
+	id, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	ous := id.GetOrganizationalUnits()
+	require.Len(t, ous, 1)
+	assert.Equal(t, "peer", ous[0].OrganizationalUnitIdentifier)
+	assert.NoError(t, thisMSP.Validate(id))
+
+	role, err := proto.Marshal(&msp.MSPRole{MspIdentifier: "Org1MSP", Role: msp.MSPRole_PEER})
+	require.NoError(t, err)
+	assert.NoError(t, thisMSP.SatisfiesPrincipal(id, &msp.MSPPrincipal{
+		PrincipalClassification: msp.MSPPrincipal_ROLE,
+		Principal:               role,
+	}))
+
+	// Without its parser, the extension carries no attribute
+	RegisterExtensionParser(testRoleExtensionOID, nil)
+	id, err = thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.Empty(t, id.GetOrganizationalUnits())
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/pkg/errors"
 	"go.uber.org/zap/zapcore"
 )
@@ -50,6 +51,13 @@ type identity struct {
 	// validationErr contains the validation error for this
 	// instance. It can be read if validated is true
 	validationErr error
+
+	// delegator is the identity that issued the delegation
+	// certificate cert, nil if this instance is not delegated
+	delegator *identity
+
+	// scopes are the scopes the delegation grants
+	scopes []string
 }
 
 func newIdentity(cert *x509.Certificate, pk bccsp.Key, msp *bccspmsp) (Identity, error) {
@@ -63,6 +71,16 @@ func newIdentity(cert *x509.Certificate, pk bccsp.Key, msp *bccspmsp) (Identity,
 		return nil, err
 	}
 
+	id, err := newSanitizedIdentity(cert, pk, msp)
+	if err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// newSanitizedIdentity returns the identity of cert, whose signature has
+// already been sanitized.
+func newSanitizedIdentity(cert *x509.Certificate, pk bccsp.Key, msp *bccspmsp) (*identity, error) {
 	// Compute identity identifier
 
 	// Use the hash of the identity's certificate as id in the IdentityIdentifier
@@ -121,7 +139,8 @@ func (o OUIDs) String() string {
 
 // GetOrganizationalUnits returns the OU for this instance
 func (id *identity) GetOrganizationalUnits() []*OUIdentifier {
-	if id.cert == nil {
+	// delegated identities only satisfy delegation principals
+	if id.cert == nil || id.delegator != nil {
 		return nil
 	}
 
@@ -132,8 +151,24 @@ func (id *identity) GetOrganizationalUnits() []*OUIdentifier {
 		return nil
 	}
 
+	units := id.cert.Subject.OrganizationalUnit
+	extUnits, err := extensionOrganizationalUnits(id.cert)
+	if err != nil {
+		mspIdentityLogger.Errorf("Failed getting organizational units from the extensions of [%v]: [%+v]", id, err)
+	}
+	nodeUnits, err := id.msp.extensionNodeOUs(id.cert)
+	if err != nil {
+		mspIdentityLogger.Errorf("Failed getting node OUs from the extensions of [%v]: [%+v]", id, err)
+	}
+	extUnits = append(extUnits, nodeUnits...)
+	for _, unit := range extUnits {
+		if !containsString(units, unit) {
+			units = append(units[:len(units):len(units)], unit)
+		}
+	}
+
 	var res []*OUIdentifier
-	for _, unit := range id.cert.Subject.OrganizationalUnit {
+	for _, unit := range units {
 		res = append(res, &OUIdentifier{
 			OrganizationalUnitIdentifier: unit,
 			CertifiersIdentifier:         cid,
@@ -148,6 +183,12 @@ func (id *identity) Anonymous() bool {
 	return false
 }
 
+// IsSM2 returns true if the certificate of this identity has an SM2 key
+func (id *identity) IsSM2() bool {
+	_, ok := id.cert.PublicKey.(*sm2.PublicKey)
+	return ok
+}
+
 // NewSerializedIdentity returns a serialized identity
 // having as content the passed mspID and x509 certificate in PEM format.
 // This method does not check the validity of certificate nor
@@ -169,15 +210,16 @@ func NewSerializedIdentity(mspID string, certPEM []byte) ([]byte, error) {
 func (id *identity) Verify(msg []byte, sig []byte) error {
 	// mspIdentityLogger.Infof("Verifying signature")
 
-	// Compute Hash
-	hashOpt, err := id.getHashOpt(id.msp.cryptoConfig.SignatureHashFamily)
-	if err != nil {
-		return errors.WithMessage(err, "failed getting hash function options")
+	if policy := getAlgorithmPolicy(); policy.Enabled() {
+		if err := policy.CheckPublicKey(id.cert.PublicKey); err != nil {
+			return id.verifyFailed(VerifyFailureAlgorithm, "", errors.WithMessage(err, "the signature uses a forbidden algorithm"))
+		}
 	}
 
-	digest, err := id.msp.bccsp.Hash(msg, hashOpt)
+	// Compute Hash
+	digest, err := id.digest(msg)
 	if err != nil {
-		return errors.WithMessage(err, "failed computing digest")
+		return id.verifyFailed(VerifyFailureProviderError, "", err)
 	}
 
 	if mspIdentityLogger.IsEnabledFor(zapcore.DebugLevel) {
@@ -187,9 +229,10 @@ func (id *identity) Verify(msg []byte, sig []byte) error {
 
 	valid, err := id.msp.bccsp.Verify(id.pk, sig, digest, nil)
 	if err != nil {
-		return errors.WithMessage(err, "could not determine the validity of the signature")
+		return id.verifyFailed(classifyProviderError(err), "", errors.WithMessage(err, "could not determine the validity of the signature"))
 	} else if !valid {
-		return errors.New("The signature is invalid")
+		category, hint := id.classifyInvalidSignature(digest, sig)
+		return id.verifyFailed(category, hint, errors.New("The signature is invalid"))
 	}
 
 	return nil
@@ -204,6 +247,10 @@ func (id *identity) Serialize() ([]byte, error) {
 	if pemBytes == nil {
 		return nil, errors.New("encoding of identity failed")
 	}
+	// the certificate of the delegator follows a delegation certificate
+	if id.delegator != nil {
+		pemBytes = append(pemBytes, pem.EncodeToMemory(&pem.Block{Bytes: id.delegator.cert.Raw, Type: "CERTIFICATE"})...)
+	}
 
 	// We serialize identities by prepending the MSPID and appending the ASN.1 DER content of the cert
 	sId := &msp.SerializedIdentity{Mspid: id.id.Mspid, IdBytes: pemBytes}
@@ -221,10 +268,33 @@ func (id *identity) getHashOpt(hashFamily string) (bccsp.HashOpts, error) {
 		return bccsp.GetHashOpt(bccsp.SHA256)
 	case bccsp.SHA3:
 		return bccsp.GetHashOpt(bccsp.SHA3_256)
+	case bccsp.SM3:
+		return bccsp.GetHashOpt(bccsp.SM3)
 	}
 	return nil, errors.Errorf("hash familiy not recognized [%s]", hashFamily)
 }
 
+// digest returns what this identity signs in place of msg. With the SM3
+// signature hash family, SM2 identities sign msg itself: the SM2 signer
+// hashes it with SM3 and the default user identity, which is SM3WithSM2
+// as GM/T 0009 and the SDKs compute it.
+func (id *identity) digest(msg []byte) ([]byte, error) {
+	if _, ok := id.cert.PublicKey.(*sm2.PublicKey); ok && id.msp.cryptoConfig.SignatureHashFamily == bccsp.SM3 {
+		return msg, nil
+	}
+
+	hashOpt, err := id.getHashOpt(id.msp.cryptoConfig.SignatureHashFamily)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed getting hash function options")
+	}
+
+	digest, err := id.msp.bccsp.Hash(msg, hashOpt)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed computing digest")
+	}
+	return digest, nil
+}
+
 type signingidentity struct {
 	// we embed everything from a base identity
 	identity
@@ -255,14 +325,9 @@ func (id *signingidentity) Sign(msg []byte) ([]byte, error) {
 	//mspIdentityLogger.Infof("Signing message")
 
 	// Compute Hash
-	hashOpt, err := id.getHashOpt(id.msp.cryptoConfig.SignatureHashFamily)
-	if err != nil {
-		return nil, errors.WithMessage(err, "failed getting hash function options")
-	}
-
-	digest, err := id.msp.bccsp.Hash(msg, hashOpt)
+	digest, err := id.digest(msg)
 	if err != nil {
-		return nil, errors.WithMessage(err, "failed computing digest")
+		return nil, err
 	}
 
 	if len(msg) < 32 {
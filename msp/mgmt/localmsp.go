@@ -0,0 +1,212 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mgmt
+
+import (
+	"sync"
+	"time"
+
+	pmsp "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/pkg/errors"
+)
+
+// reloadableMSP is the local MSP. Reloading it sets up a new MSP instance
+// from the local MSP directory and swaps it in under the write lock, so
+// that the signing certificate, the keystore, the CA certificates and the
+// CRLs can be rotated without restarting the process.
+type reloadableMSP struct {
+	lock       sync.RWMutex
+	msp        msp.MSP
+	signer     msp.SigningIdentity
+	bccsp      bccsp.BCCSP
+	loadConfig func() (*pmsp.MSPConfig, error)
+}
+
+func newReloadableMSP(cryptoProvider bccsp.BCCSP) *reloadableMSP {
+	return &reloadableMSP{
+		msp:   loadLocaMSP(cryptoProvider),
+		bccsp: cryptoProvider,
+	}
+}
+
+func (r *reloadableMSP) current() msp.MSP {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.msp
+}
+
+func (r *reloadableMSP) currentSigner() msp.SigningIdentity {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.signer
+}
+
+func (r *reloadableMSP) setLoadConfig(loadConfig func() (*pmsp.MSPConfig, error)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.loadConfig = loadConfig
+}
+
+// reload sets up a new MSP instance with the configuration read again from
+// the local MSP directory and replaces the current one with it. The current
+// one stays in place if anything fails.
+func (r *reloadableMSP) reload() error {
+	r.lock.RLock()
+	loadConfig := r.loadConfig
+	r.lock.RUnlock()
+	if loadConfig == nil {
+		return errors.New("the local MSP was not loaded from a directory")
+	}
+
+	conf, err := loadConfig()
+	if err != nil {
+		return errors.WithMessage(err, "failed reading the local MSP configuration")
+	}
+	mspInst := loadLocaMSP(r.bccsp)
+	if err := mspInst.Setup(conf); err != nil {
+		return errors.WithMessage(err, "failed setting up the local MSP")
+	}
+	signer, err := mspInst.GetDefaultSigningIdentity()
+	if err != nil {
+		return errors.WithMessage(err, "failed getting the signing identity of the local MSP")
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.msp = mspInst
+	r.signer = signer
+	return nil
+}
+
+// Setup sets up the current MSP instance; the local MSP is set up once,
+// it is replaced rather than set up again on reload.
+func (r *reloadableMSP) Setup(conf *pmsp.MSPConfig) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err := r.msp.Setup(conf); err != nil {
+		return err
+	}
+	if signer, err := r.msp.GetDefaultSigningIdentity(); err == nil {
+		r.signer = signer
+	}
+	return nil
+}
+
+func (r *reloadableMSP) DeserializeIdentity(serializedIdentity []byte) (msp.Identity, error) {
+	return r.current().DeserializeIdentity(serializedIdentity)
+}
+
+func (r *reloadableMSP) IsWellFormed(identity *pmsp.SerializedIdentity) error {
+	return r.current().IsWellFormed(identity)
+}
+
+func (r *reloadableMSP) GetVersion() msp.MSPVersion {
+	return r.current().GetVersion()
+}
+
+func (r *reloadableMSP) GetType() msp.ProviderType {
+	return r.current().GetType()
+}
+
+func (r *reloadableMSP) GetIdentifier() (string, error) {
+	return r.current().GetIdentifier()
+}
+
+func (r *reloadableMSP) GetSigningIdentity(identifier *msp.IdentityIdentifier) (msp.SigningIdentity, error) {
+	return r.current().GetSigningIdentity(identifier)
+}
+
+// GetDefaultSigningIdentity returns a signing identity that follows the
+// reloads of the local MSP, as the components holding it never ask again.
+func (r *reloadableMSP) GetDefaultSigningIdentity() (msp.SigningIdentity, error) {
+	if r.currentSigner() == nil {
+		return r.current().GetDefaultSigningIdentity()
+	}
+	return &reloadingSigningIdentity{msp: r}, nil
+}
+
+func (r *reloadableMSP) GetTLSRootCerts() [][]byte {
+	return r.current().GetTLSRootCerts()
+}
+
+func (r *reloadableMSP) GetTLSIntermediateCerts() [][]byte {
+	return r.current().GetTLSIntermediateCerts()
+}
+
+func (r *reloadableMSP) Validate(id msp.Identity) error {
+	return r.current().Validate(r.unwrap(id))
+}
+
+func (r *reloadableMSP) SatisfiesPrincipal(id msp.Identity, principal *pmsp.MSPPrincipal) error {
+	return r.current().SatisfiesPrincipal(r.unwrap(id), principal)
+}
+
+// unwrap returns the identity of the current MSP instance in place of the
+// default signing identity, which the MSP instances do not recognize.
+func (r *reloadableMSP) unwrap(id msp.Identity) msp.Identity {
+	if _, ok := id.(*reloadingSigningIdentity); ok {
+		return r.currentSigner()
+	}
+	return id
+}
+
+// reloadingSigningIdentity is the default signing identity of the local
+// MSP as of the last reload. A message serialized before a reload and
+// signed after it carries the previous certificate and fails verification.
+type reloadingSigningIdentity struct {
+	msp *reloadableMSP
+}
+
+func (id *reloadingSigningIdentity) current() msp.SigningIdentity {
+	return id.msp.currentSigner()
+}
+
+func (id *reloadingSigningIdentity) ExpiresAt() time.Time {
+	return id.current().ExpiresAt()
+}
+
+func (id *reloadingSigningIdentity) GetIdentifier() *msp.IdentityIdentifier {
+	return id.current().GetIdentifier()
+}
+
+func (id *reloadingSigningIdentity) GetMSPIdentifier() string {
+	return id.current().GetMSPIdentifier()
+}
+
+func (id *reloadingSigningIdentity) Validate() error {
+	return id.current().Validate()
+}
+
+func (id *reloadingSigningIdentity) GetOrganizationalUnits() []*msp.OUIdentifier {
+	return id.current().GetOrganizationalUnits()
+}
+
+func (id *reloadingSigningIdentity) Anonymous() bool {
+	return id.current().Anonymous()
+}
+
+func (id *reloadingSigningIdentity) Verify(msg []byte, sig []byte) error {
+	return id.current().Verify(msg, sig)
+}
+
+func (id *reloadingSigningIdentity) Serialize() ([]byte, error) {
+	return id.current().Serialize()
+}
+
+func (id *reloadingSigningIdentity) SatisfiesPrincipal(principal *pmsp.MSPPrincipal) error {
+	return id.current().SatisfiesPrincipal(principal)
+}
+
+func (id *reloadingSigningIdentity) Sign(msg []byte) ([]byte, error) {
+	return id.current().Sign(msg)
+}
+
+func (id *reloadingSigningIdentity) GetPublicVersion() msp.Identity {
+	return id.current().GetPublicVersion()
+}
@@ -0,0 +1,107 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mgmt
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/config/configtest"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func copyDir(t *testing.T, src, dst string) {
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dst, rel), 0755)
+		}
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dst, rel), raw, info.Mode())
+	})
+	require.NoError(t, err)
+}
+
+func TestReloadLocalMsp(t *testing.T) {
+	defer func(saved *reloadableMSP) { localMsp = saved }(localMsp)
+	localMsp = nil
+	assert.EqualError(t, ReloadLocalMsp(), "the local MSP is not loaded")
+
+	dir, err := ioutil.TempDir("", "localmsp")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	copyDir(t, configtest.GetDevMspDir(), dir)
+
+	localMSP := GetLocalMSP(factory.GetDefault())
+	assert.EqualError(t, ReloadLocalMsp(), "the local MSP was not loaded from a directory")
+	require.NoError(t, LoadLocalMsp(dir, nil, "SampleOrg"))
+	signer, err := localMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	before := localMsp.currentSigner()
+
+	require.NoError(t, ReloadLocalMsp())
+	assert.False(t, before == localMsp.currentSigner())
+	assert.NoError(t, localMSP.Validate(signer))
+	msg := []byte("proposal")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	serialized, err := signer.Serialize()
+	require.NoError(t, err)
+	id, err := localMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.NoError(t, id.Verify(msg, sig))
+
+	// The local MSP stays in place when the new material is broken
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "signcerts")))
+	err = ReloadLocalMsp()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed reading the local MSP configuration")
+	_, err = signer.Sign(msg)
+	assert.NoError(t, err)
+}
+
+func TestReloadHandler(t *testing.T) {
+	var reloadErr error
+	handler := &ReloadHandler{
+		Reload: func() error { return reloadErr },
+		Logger: flogging.MustGetLogger("test"),
+	}
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/msp/local/reload", nil))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/msp/local/reload", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	reloadErr = errors.New("no signing certificate")
+	resp = httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/msp/local/reload", nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	var errResp ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, "no signing certificate", errResp.Error)
+}
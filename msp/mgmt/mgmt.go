@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"sync"
 
+	pmsp "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/msp/cache"
@@ -25,12 +26,10 @@ func LoadLocalMspWithType(dir string, bccspConfig *factory.FactoryOpts, mspID, m
 		return errors.New("the local MSP must have an ID")
 	}
 
-	conf, err := msp.GetLocalMspConfigWithType(dir, bccspConfig, mspID, mspType)
-	if err != nil {
-		return err
+	loadConfig := func() (*pmsp.MSPConfig, error) {
+		return msp.GetLocalMspConfigWithType(dir, bccspConfig, mspID, mspType)
 	}
-
-	return GetLocalMSP(factory.GetDefault()).Setup(conf)
+	return loadLocalMspConfig(loadConfig)
 }
 
 // LoadLocalMsp loads the local MSP from the specified directory
@@ -39,12 +38,43 @@ func LoadLocalMsp(dir string, bccspConfig *factory.FactoryOpts, mspID string) er
 		return errors.New("the local MSP must have an ID")
 	}
 
-	conf, err := msp.GetLocalMspConfig(dir, bccspConfig, mspID)
+	loadConfig := func() (*pmsp.MSPConfig, error) {
+		return msp.GetLocalMspConfig(dir, bccspConfig, mspID)
+	}
+	return loadLocalMspConfig(loadConfig)
+}
+
+func loadLocalMspConfig(loadConfig func() (*pmsp.MSPConfig, error)) error {
+	conf, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
-	return GetLocalMSP(factory.GetDefault()).Setup(conf)
+	localMSP := getLocalMSP(factory.GetDefault())
+	if err := localMSP.Setup(conf); err != nil {
+		return err
+	}
+	localMSP.setLoadConfig(loadConfig)
+	return nil
+}
+
+// ReloadLocalMsp reads the local MSP again from the directory it was loaded
+// from, so that its signing certificate, keystore, CA certificates and CRLs
+// can be rotated while the process runs. Identities and signatures being
+// checked keep the material they started with.
+func ReloadLocalMsp() error {
+	m.Lock()
+	localMSP := localMsp
+	m.Unlock()
+	if localMSP == nil {
+		return errors.New("the local MSP is not loaded")
+	}
+
+	if err := localMSP.reload(); err != nil {
+		return err
+	}
+	mspLogger.Infof("Reloaded the local MSP")
+	return nil
 }
 
 // FIXME: AS SOON AS THE CHAIN MANAGEMENT CODE IS COMPLETE,
@@ -53,7 +83,7 @@ func LoadLocalMsp(dir string, bccspConfig *factory.FactoryOpts, mspID string) er
 // HOWEVER IN THE INTERIM, THESE HELPER FUNCTIONS ARE REQUIRED
 
 var m sync.Mutex
-var localMsp msp.MSP
+var localMsp *reloadableMSP
 var mspMap map[string]msp.MSPManager = make(map[string]msp.MSPManager)
 var mspLogger = flogging.MustGetLogger("msp")
 
@@ -132,6 +162,10 @@ func XXXSetMSPManager(chainID string, manager msp.MSPManager) {
 
 // GetLocalMSP returns the local msp (and creates it if it doesn't exist)
 func GetLocalMSP(cryptoProvider bccsp.BCCSP) msp.MSP {
+	return getLocalMSP(cryptoProvider)
+}
+
+func getLocalMSP(cryptoProvider bccsp.BCCSP) *reloadableMSP {
 	m.Lock()
 	defer m.Unlock()
 
@@ -139,7 +173,7 @@ func GetLocalMSP(cryptoProvider bccsp.BCCSP) msp.MSP {
 		return localMsp
 	}
 
-	localMsp = loadLocaMSP(cryptoProvider)
+	localMsp = newReloadableMSP(cryptoProvider)
 
 	return localMsp
 }
@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package mgmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewReloadHandler returns the admin handler that reloads the local MSP on
+// POST requests.
+func NewReloadHandler() *ReloadHandler {
+	return &ReloadHandler{
+		Reload: ReloadLocalMsp,
+		Logger: flogging.MustGetLogger("msp.httpadmin"),
+	}
+}
+
+type ReloadHandler struct {
+	Reload func() error
+	Logger *flogging.FabricLogger
+}
+
+func (h *ReloadHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		h.sendError(resp, http.StatusBadRequest, fmt.Errorf("invalid request method: %s", req.Method))
+		return
+	}
+
+	if err := h.Reload(); err != nil {
+		h.Logger.Errorw("failed to reload the local MSP", "error", err)
+		h.sendError(resp, http.StatusInternalServerError, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ReloadHandler) sendError(resp http.ResponseWriter, code int, err error) {
+	resp.Header().Set("Content-Type", "application/json")
+	resp.WriteHeader(code)
+	if err := json.NewEncoder(resp).Encode(&ErrorResponse{Error: err.Error()}); err != nil {
+		h.Logger.Errorw("failed to encode payload", "error", err)
+	}
+}
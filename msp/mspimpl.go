@@ -17,8 +17,10 @@ import (
 	m "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/factory"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/signer"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/gm/sm2"
 	"github.com/pkg/errors"
 )
 
@@ -86,6 +88,13 @@ type bccspmsp struct {
 	// verification options for MSP members
 	opts *x509.VerifyOptions
 
+	// verification options for TLS CAs
+	tlsOpts *x509.VerifyOptions
+
+	// verification options for the chains of MSP members and of TLS
+	// CAs with SM2 keys or signatures, which opts and tlsOpts cannot verify
+	gmOpts, tlsGMOpts *gmx509.VerifyOptions
+
 	// list of certificate revocation lists
 	CRL []*pkix.CertificateList
 
@@ -173,7 +182,7 @@ func (msp *bccspmsp) getCertFromPem(idBytes []byte) (*x509.Certificate, error) {
 
 	// get a cert
 	var cert *x509.Certificate
-	cert, err := x509.ParseCertificate(pemCert.Bytes)
+	cert, err := gmx509.ParseCertificate(pemCert.Bytes)
 	if err != nil {
 		return nil, errors.Wrap(err, "getCertFromPem error: failed to parse x509 cert")
 	}
@@ -213,6 +222,17 @@ func (msp *bccspmsp) getSigningIdentityFromConf(sidInfo *m.SigningIdentityInfo)
 		return nil, err
 	}
 
+	// Fail fast when the provider cannot sign with the key of the identity
+	if c, ok := bccsp.GetCapabilities(msp.bccsp); ok {
+		algorithm := bccsp.ECDSA
+		if _, ok := idPub.(*identity).cert.PublicKey.(*sm2.PublicKey); ok {
+			algorithm = bccsp.SM2
+		}
+		if err := c.RequireSignatureAlgorithm(algorithm); err != nil {
+			return nil, errors.WithMessage(err, "getIdentityFromBytes error")
+		}
+	}
+
 	// Find the matching private key in the BCCSP keystore
 	privKey, err := msp.bccsp.GetKey(pubKey.SKI())
 	// Less Secure: Attempt to import Private Key from KeyInfo, if BCCSP was not able to find the key
@@ -226,7 +246,11 @@ func (msp *bccspmsp) getSigningIdentityFromConf(sidInfo *m.SigningIdentityInfo)
 		if pemKey == nil {
 			return nil, errors.Errorf("%s: wrong PEM encoding", sidInfo.PrivateSigner.KeyIdentifier)
 		}
-		privKey, err = msp.bccsp.KeyImport(pemKey.Bytes, &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true})
+		var keyImportOpts bccsp.KeyImportOpts = &bccsp.ECDSAPrivateKeyImportOpts{Temporary: true}
+		if _, ok := idPub.(*identity).cert.PublicKey.(*sm2.PublicKey); ok {
+			keyImportOpts = &bccsp.SM2PrivateKeyImportOpts{Temporary: true}
+		}
+		privKey, err = msp.bccsp.KeyImport(pemKey.Bytes, keyImportOpts)
 		if err != nil {
 			return nil, errors.WithMessage(err, "getIdentityFromBytes error: Failed to import EC private key")
 		}
@@ -238,7 +262,16 @@ func (msp *bccspmsp) getSigningIdentityFromConf(sidInfo *m.SigningIdentityInfo)
 		return nil, errors.WithMessage(err, "getIdentityFromBytes error: Failed initializing bccspCryptoSigner")
 	}
 
-	return newSigningIdentity(idPub.(*identity).cert, idPub.(*identity).pk, peerSigner, msp)
+	sid, err := newSigningIdentity(idPub.(*identity).cert, idPub.(*identity).pk, peerSigner, msp)
+	if err != nil {
+		return nil, err
+	}
+
+	// A second certificate is the encryption certificate of a dual certificate identity
+	if encCertPEM := encryptionCertPEM(sidInfo.PublicSigner); encCertPEM != nil {
+		return msp.getDualCertSigningIdentity(sid.(*signingidentity), encCertPEM)
+	}
+	return sid, nil
 }
 
 // Setup sets up the internal data structures
@@ -400,15 +433,24 @@ func (msp *bccspmsp) DeserializeIdentity(serializedID []byte) (Identity, error)
 // deserializeIdentityInternal returns an identity given its byte-level representation
 func (msp *bccspmsp) deserializeIdentityInternal(serializedIdentity []byte) (Identity, error) {
 	// This MSP will always deserialize certs this way
-	bl, _ := pem.Decode(serializedIdentity)
+	bl, rest := pem.Decode(serializedIdentity)
 	if bl == nil {
 		return nil, errors.New("could not decode the PEM structure")
 	}
-	cert, err := x509.ParseCertificate(bl.Bytes)
+	cert, err := gmx509.ParseCertificate(bl.Bytes)
 	if err != nil {
 		return nil, errors.Wrap(err, "parseCertificate failed")
 	}
 
+	// Delegation certificates are followed by the certificate of the delegator
+	scopes, delegated, err := delegationScopes(cert)
+	if err != nil {
+		return nil, err
+	}
+	if delegated {
+		return msp.deserializeDelegatedIdentity(cert, scopes, rest)
+	}
+
 	// Now we have the certificate; make sure that its fields
 	// (e.g. the Issuer.OU or the Subject.OU) match with the
 	// MSP id that this MSP has; otherwise it might be an attack
@@ -431,10 +473,20 @@ func (msp *bccspmsp) SatisfiesPrincipal(id Identity, principal *m.MSPPrincipal)
 		return err
 	}
 	for _, principal := range principals {
-		err = msp.internalSatisfiesPrincipalInternalFunc(id, principal)
+		switch {
+		case principal.PrincipalClassification == MSPPrincipalDelegation:
+			err = msp.satisfiesDelegationPrincipal(id, principal)
+		case isDelegated(id):
+			err = errors.New("a delegated identity only satisfies delegation principals")
+		default:
+			err = msp.internalSatisfiesPrincipalInternalFunc(id, principal)
+		}
 		if err != nil {
 			return err
 		}
+		if err = msp.checkRevocationStatus(id, principal); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -722,7 +774,13 @@ func (msp *bccspmsp) getUniqueValidationChain(cert *x509.Certificate, opts x509.
 	if msp.opts == nil {
 		return nil, errors.New("the supplied identity has no verify options")
 	}
-	validationChains, err := cert.Verify(opts)
+	validationChains, err := withHandledExtensions(cert, msp.nodeOUExtensions()...).Verify(opts)
+	if err == nil {
+		validationChains, err = filterChainsByPolicy(validationChains)
+	} else if gmOpts := msp.gmVerifyOptions(opts); gmOpts != nil {
+		// crypto/x509 cannot verify SM2 signatures, let gmx509 try
+		validationChains, err = gmx509.Verify(cert, *gmOpts)
+	}
 	if err != nil {
 		return nil, errors.WithMessage(err, "the supplied identity is not valid")
 	}
@@ -837,6 +895,6 @@ func (msp *bccspmsp) IsWellFormed(identity *m.SerializedIdentity) error {
 	if bl.Type != "CERTIFICATE" && bl.Type != "" {
 		return errors.Errorf("pem type is %s, should be 'CERTIFICATE' or missing", bl.Type)
 	}
-	_, err := x509.ParseCertificate(bl.Bytes)
+	_, err := gmx509.ParseCertificate(bl.Bytes)
 	return err
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	m "github.com/hyperledger/fabric-protos-go/msp"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	errors "github.com/pkg/errors"
 )
 
@@ -97,6 +98,21 @@ func (msp *bccspmsp) setupCrypto(conf *m.FabricMSPConfig) error {
 		mspLogger.Debugf("CryptoConfig.IdentityIdentifierHashFunction was nil. Move to defaults.")
 	}
 
+	// The keys of a signing identity are in the provider the MSP was created with
+	if conf.SigningIdentity == nil {
+		if csp := getHashFamilyProvider(msp.cryptoConfig.SignatureHashFamily); csp != nil {
+			mspLogger.Debugf("Using the BCCSP provider of the %s hash family", msp.cryptoConfig.SignatureHashFamily)
+			msp.bccsp = csp
+		}
+	}
+
+	// Fail fast when the provider cannot compute the identity identifiers
+	if c, ok := bccsp.GetCapabilities(msp.bccsp); ok {
+		if err := c.RequireHash(msp.cryptoConfig.IdentityIdentifierHashFunction); err != nil {
+			return errors.WithMessage(err, "invalid identity identifier hash function")
+		}
+	}
+
 	return nil
 }
 
@@ -112,12 +128,14 @@ func (msp *bccspmsp) setupCAs(conf *m.FabricMSPConfig) error {
 	// CA certificates. After their sanitization is done, the opts
 	// will be recreated using the sanitized certs.
 	msp.opts = &x509.VerifyOptions{Roots: x509.NewCertPool(), Intermediates: x509.NewCertPool()}
+	var roots, intermediates []*x509.Certificate
 	for _, v := range conf.RootCerts {
 		cert, err := msp.getCertFromPem(v)
 		if err != nil {
 			return err
 		}
 		msp.opts.Roots.AddCert(cert)
+		roots = append(roots, cert)
 	}
 	for _, v := range conf.IntermediateCerts {
 		cert, err := msp.getCertFromPem(v)
@@ -125,7 +143,9 @@ func (msp *bccspmsp) setupCAs(conf *m.FabricMSPConfig) error {
 			return err
 		}
 		msp.opts.Intermediates.AddCert(cert)
+		intermediates = append(intermediates, cert)
 	}
+	msp.gmOpts = newGMVerifyOptions(roots, intermediates)
 
 	// Load root and intermediate CA identities
 	// Recall that when an identity is created, its certificate gets sanitized
@@ -152,12 +172,16 @@ func (msp *bccspmsp) setupCAs(conf *m.FabricMSPConfig) error {
 
 	// root CA and intermediate CA certificates are sanitized, they can be re-imported
 	msp.opts = &x509.VerifyOptions{Roots: x509.NewCertPool(), Intermediates: x509.NewCertPool()}
+	roots, intermediates = nil, nil
 	for _, id := range msp.rootCerts {
 		msp.opts.Roots.AddCert(id.(*identity).cert)
+		roots = append(roots, id.(*identity).cert)
 	}
 	for _, id := range msp.intermediateCerts {
 		msp.opts.Intermediates.AddCert(id.(*identity).cert)
+		intermediates = append(intermediates, id.(*identity).cert)
 	}
+	msp.gmOpts = newGMVerifyOptions(roots, intermediates)
 
 	return nil
 }
@@ -203,6 +227,10 @@ func (msp *bccspmsp) setupCRLs(conf *m.FabricMSPConfig) error {
 			return errors.Wrap(err, "could not parse RevocationList")
 		}
 
+		if gmx509.IsCRLExpired(crl, time.Now()) {
+			mspLogger.Warningf("RevocationList %d is past its next update [%s], its revocations still apply", i, crl.TBSCertList.NextUpdate)
+		}
+
 		// TODO: pre-verify the signature on the CRL and create a map
 		//       of CA certs to respective CRLs so that later upon
 		//       validation we can already look up the CRL given the
@@ -450,6 +478,9 @@ func (msp *bccspmsp) setupTLSCAs(conf *m.FabricMSPConfig) error {
 		opts.Intermediates.AddCert(cert)
 	}
 
+	msp.tlsOpts = opts
+	msp.tlsGMOpts = newGMVerifyOptions(rootCerts, intermediateCerts)
+
 	// ensure that our CAs are properly formed and that they are valid
 	for _, cert := range append(append([]*x509.Certificate{}, rootCerts...), intermediateCerts...) {
 		if cert == nil {
@@ -15,6 +15,7 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/pkg/errors"
 )
 
@@ -29,6 +30,11 @@ func (msp *bccspmsp) validateIdentity(id *identity) error {
 
 	id.validated = true
 
+	if id.delegator != nil {
+		id.validationErr = msp.validateDelegation(id)
+		return id.validationErr
+	}
+
 	validationChain, err := msp.getCertificationChainForBCCSPIdentity(id)
 	if err != nil {
 		id.validationErr = errors.WithMessage(err, "could not obtain certification chain")
@@ -41,6 +47,12 @@ func (msp *bccspmsp) validateIdentity(id *identity) error {
 		return id.validationErr
 	}
 
+	err = checkAlgorithmPolicy(validationChain)
+	if err != nil {
+		id.validationErr = errors.WithMessage(err, "the certification chain uses a forbidden algorithm")
+		return id.validationErr
+	}
+
 	err = msp.internalValidateIdentityOusFunc(id)
 	if err != nil {
 		id.validationErr = errors.WithMessage(err, "could not validate identity's OUs")
@@ -98,7 +110,11 @@ func (msp *bccspmsp) validateCertAgainstChain(cert *x509.Certificate, validation
 	}
 
 	// check whether one of the CRLs we have has this cert's
-	// SKI as its AuthorityKeyIdentifier
+	// SKI as its AuthorityKeyIdentifier. When several of them list the
+	// cert, the one with the highest CRL number wins, so that a delta CRL
+	// can release a cert put on hold with the removeFromCRL reason.
+	var revoked bool
+	var latest *big.Int
 	for _, crl := range msp.CRL {
 		aki, err := getAuthorityKeyIdentifierFromCrl(crl)
 		if err != nil {
@@ -106,36 +122,65 @@ func (msp *bccspmsp) validateCertAgainstChain(cert *x509.Certificate, validation
 		}
 
 		// check if the SKI of the cert that signed us matches the AKI of any of the CRLs
-		if bytes.Equal(aki, SKI) {
-			// we have a CRL, check whether the serial number is revoked
-			for _, rc := range crl.TBSCertList.RevokedCertificates {
-				if rc.SerialNumber.Cmp(cert.SerialNumber) == 0 {
-					// We have found a CRL whose AKI matches the SKI of
-					// the CA (root or intermediate) that signed the
-					// certificate that is under validation. As a
-					// precaution, we verify that said CA is also the
-					// signer of this CRL.
-					err = validationChain[1].CheckCRLSignature(crl)
-					if err != nil {
-						// the CA cert that signed the certificate
-						// that is under validation did not sign the
-						// candidate CRL - skip
-						mspLogger.Warningf("Invalid signature over the identified CRL, error %+v", err)
-						continue
-					}
+		if !bytes.Equal(aki, SKI) {
+			continue
+		}
 
-					// A CRL also includes a time of revocation so that
-					// the CA can say "this cert is to be revoked starting
-					// from this time"; however here we just assume that
-					// revocation applies instantaneously from the time
-					// the MSP config is committed and used so we will not
-					// make use of that field
-					return errors.New("The certificate has been revoked")
-				}
+		// we have a CRL, check whether the serial number is revoked
+		for _, rc := range crl.TBSCertList.RevokedCertificates {
+			if rc.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+				continue
 			}
+
+			// We have found a CRL whose AKI matches the SKI of
+			// the CA (root or intermediate) that signed the
+			// certificate that is under validation. As a
+			// precaution, we verify that said CA is also the
+			// signer of this CRL.
+			err = gmx509.CheckCRLSignature(crl, validationChain[1])
+			if err != nil {
+				// the CA cert that signed the certificate
+				// that is under validation did not sign the
+				// candidate CRL - skip
+				mspLogger.Warningf("Invalid signature over the identified CRL, error %+v", err)
+				break
+			}
+			if gmx509.IsCRLExpired(crl, time.Now()) {
+				mspLogger.Warningf("Applying CRL of CA [%x] past its next update [%s]", SKI, crl.TBSCertList.NextUpdate)
+			}
+
+			number, err := gmx509.CRLNumber(crl)
+			if err != nil {
+				mspLogger.Warningf("Invalid number of the identified CRL, error %+v", err)
+				break
+			}
+			if number == nil {
+				number = new(big.Int)
+			}
+			if latest != nil && number.Cmp(latest) < 0 {
+				break
+			}
+			reason, err := gmx509.RevocationReason(rc)
+			if err != nil {
+				mspLogger.Warningf("Invalid entry in the identified CRL, error %+v", err)
+				break
+			}
+
+			// A CRL also includes a time of revocation so that
+			// the CA can say "this cert is to be revoked starting
+			// from this time"; however here we just assume that
+			// revocation applies instantaneously from the time
+			// the MSP config is committed and used so we will not
+			// make use of that field
+			latest = number
+			revoked = reason != gmx509.CRLReasonRemoveFromCRL || !gmx509.IsDeltaCRL(crl)
+			break
 		}
 	}
 
+	if revoked {
+		return errors.New("The certificate has been revoked")
+	}
 	return nil
 }
 
@@ -0,0 +1,120 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"sync"
+
+	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+// NodeOUExtensionProfile tells apart the clients, peers, admins and
+// orderers of an MSP by a certificate extension, for the CAs which put
+// the type of the certificate holder in a private extension and cannot
+// issue certificates with the node OUs in their subject.
+type NodeOUExtensionProfile struct {
+	// Extension identifies the certificate extension
+	Extension asn1.ObjectIdentifier
+	// Parser extracts the values of the extension as organizational
+	// units, StringsExtensionParser if nil
+	Parser ExtensionParser
+	// Roles maps the values of the extension to the role they grant
+	Roles map[string]m.MSPRole_MSPRoleType
+}
+
+var (
+	nodeOUProfilesMutex sync.RWMutex
+	nodeOUProfiles      = map[string]*NodeOUExtensionProfile{}
+)
+
+// SetNodeOUExtensionProfile makes the MSP identified by mspID classify
+// identities by the extension of profile too, when its NodeOUs are
+// enabled: the value of the extension grants the identity the
+// organizational unit identifier of the node OU of its role, as if it
+// were in the subject. The extension no longer fails validation when it
+// is critical. A nil profile removes the profile of mspID.
+func SetNodeOUExtensionProfile(mspID string, profile *NodeOUExtensionProfile) {
+	nodeOUProfilesMutex.Lock()
+	defer nodeOUProfilesMutex.Unlock()
+
+	if profile == nil {
+		delete(nodeOUProfiles, mspID)
+		return
+	}
+	nodeOUProfiles[mspID] = profile
+}
+
+func getNodeOUExtensionProfile(mspID string) *NodeOUExtensionProfile {
+	nodeOUProfilesMutex.RLock()
+	defer nodeOUProfilesMutex.RUnlock()
+	return nodeOUProfiles[mspID]
+}
+
+// nodeOUExtensions returns the extensions of the node OU profile of this
+// MSP, if any.
+func (msp *bccspmsp) nodeOUExtensions() []asn1.ObjectIdentifier {
+	profile := getNodeOUExtensionProfile(msp.name)
+	if profile == nil {
+		return nil
+	}
+	return []asn1.ObjectIdentifier{profile.Extension}
+}
+
+// extensionNodeOUs returns the organizational unit identifiers of the
+// node OUs the node OU profile of this MSP grants to cert.
+func (msp *bccspmsp) extensionNodeOUs(cert *x509.Certificate) ([]string, error) {
+	profile := getNodeOUExtensionProfile(msp.name)
+	if profile == nil || !msp.ouEnforcement {
+		return nil, nil
+	}
+	parser := profile.Parser
+	if parser == nil {
+		parser = StringsExtensionParser
+	}
+
+	var units []string
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(profile.Extension) {
+			continue
+		}
+		attrs, err := parser(ext)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to parse the node OU extension %s", ext.Id)
+		}
+		if attrs == nil {
+			continue
+		}
+		for _, value := range attrs.OrganizationalUnits {
+			role, ok := profile.Roles[value]
+			if !ok {
+				continue
+			}
+			if nodeOU := msp.nodeOU(role); nodeOU != nil && !containsString(units, nodeOU.OrganizationalUnitIdentifier) {
+				units = append(units, nodeOU.OrganizationalUnitIdentifier)
+			}
+		}
+	}
+	return units, nil
+}
+
+// nodeOU returns the node OU of role, nil if it is not defined.
+func (msp *bccspmsp) nodeOU(role m.MSPRole_MSPRoleType) *OUIdentifier {
+	switch role {
+	case m.MSPRole_CLIENT:
+		return msp.clientOU
+	case m.MSPRole_PEER:
+		return msp.peerOU
+	case m.MSPRole_ADMIN:
+		return msp.adminOU
+	case m.MSPRole_ORDERER:
+		return msp.ordererOU
+	}
+	return nil
+}
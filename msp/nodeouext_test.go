@@ -0,0 +1,117 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCertTypeOID = asn1.ObjectIdentifier{1, 2, 156, 112562, 2, 1, 1, 98}
+
+// parseCertType parses a certificate type extension, an INTEGER
+func parseCertType(ext pkix.Extension) (*ExtensionAttributes, error) {
+	var certType int
+	if _, err := asn1.Unmarshal(ext.Value, &certType); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the certificate type")
+	}
+	return &ExtensionAttributes{OrganizationalUnits: []string{strconv.Itoa(certType)}}, nil
+}
+
+func TestNodeOUExtensionProfile(t *testing.T) {
+	SetNodeOUExtensionProfile("Org1MSP", &NodeOUExtensionProfile{
+		Extension: testCertTypeOID,
+		Parser:    parseCertType,
+		Roles:     map[string]msp.MSPRole_MSPRoleType{"1": msp.MSPRole_PEER, "2": msp.MSPRole_ADMIN},
+	})
+	defer SetNodeOUExtensionProfile("Org1MSP", nil)
+
+	ca := newSM2TestCA(t)
+	setupMSP := func(name string) MSP {
+		raw, err := proto.Marshal(&msp.FabricMSPConfig{
+			Name:      name,
+			RootCerts: [][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})},
+			FabricNodeOus: &msp.FabricNodeOUs{
+				Enable:              true,
+				ClientOuIdentifier:  &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "client"},
+				PeerOuIdentifier:    &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "peer"},
+				AdminOuIdentifier:   &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "admin"},
+				OrdererOuIdentifier: &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "orderer"},
+			},
+			CryptoConfig: &msp.FabricCryptoConfig{
+				SignatureHashFamily:            bccsp.SM3,
+				IdentityIdentifierHashFunction: bccsp.SM3,
+			},
+		})
+		require.NoError(t, err)
+		cryptoProvider, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+		require.NoError(t, err)
+		thisMSP, err := newBccspMsp(MSPv1_4_3, cryptoProvider)
+		require.NoError(t, err)
+		require.NoError(t, thisMSP.Setup(&msp.MSPConfig{Config: raw, Type: int32(FABRIC)}))
+		return thisMSP
+	}
+
+	// The CA cannot issue custom OUs, the certificate type is critical
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	value, err := asn1.Marshal(2)
+	require.NoError(t, err)
+	der, err := gmx509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "admin.org1", OrganizationalUnit: []string{"operations"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtraExtensions: []pkix.Extension{
+			{Id: testCertTypeOID, Critical: true, Value: value},
+		},
+	}, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	thisMSP := setupMSP("Org1MSP")
+	serialized, err := proto.Marshal(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: certPEM})
+	require.NoError(t, err)
+	id, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	var units []string
+	for _, ou := range id.GetOrganizationalUnits() {
+		units = append(units, ou.OrganizationalUnitIdentifier)
+	}
+	assert.Equal(t, []string{"operations", "admin"}, units)
+	assert.NoError(t, thisMSP.Validate(id))
+	assert.NoError(t, id.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_ADMIN)))
+	assert.Error(t, id.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_PEER)))
+
+	// The profile is set for Org1MSP only
+	otherMSP := setupMSP("Org2MSP")
+	serialized, err = proto.Marshal(&msp.SerializedIdentity{Mspid: "Org2MSP", IdBytes: certPEM})
+	require.NoError(t, err)
+	id, err = otherMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	require.Len(t, id.GetOrganizationalUnits(), 1)
+	assert.Error(t, otherMSP.Validate(id))
+}
@@ -0,0 +1,42 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"sync"
+
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+)
+
+var (
+	hashFamilyProvidersMutex sync.RWMutex
+	hashFamilyProviders      = map[string]bccsp.BCCSP{}
+)
+
+// SetHashFamilyProvider binds a BCCSP provider to a signature hash family.
+// MSPs whose crypto configuration declares that family, in the channel
+// configuration or in their config.yaml, validate identities with csp in
+// place of the BCCSP they were created with. This lets a node, say with a
+// PKCS11 default provider, validate the ECDSA identities of a SHA2
+// organization and the SM2 identities of an SM3 organization on the same
+// channel. Local MSPs keep the provider holding their signing keys.
+// A nil csp unbinds the family.
+func SetHashFamilyProvider(family string, csp bccsp.BCCSP) {
+	hashFamilyProvidersMutex.Lock()
+	defer hashFamilyProvidersMutex.Unlock()
+	if csp == nil {
+		delete(hashFamilyProviders, family)
+		return
+	}
+	hashFamilyProviders[family] = csp
+}
+
+func getHashFamilyProvider(family string) bccsp.BCCSP {
+	hashFamilyProvidersMutex.RLock()
+	defer hashFamilyProvidersMutex.RUnlock()
+	return hashFamilyProviders[family]
+}
@@ -0,0 +1,80 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"encoding/pem"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingCSP struct {
+	bccsp.BCCSP
+	verified int
+}
+
+func (csp *countingCSP) Verify(k bccsp.Key, signature, digest []byte, opts bccsp.SignerOpts) (bool, error) {
+	csp.verified++
+	return csp.BCCSP.Verify(k, signature, digest, opts)
+}
+
+func TestHashFamilyProvider(t *testing.T) {
+	gmProvider, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	csp := &countingCSP{BCCSP: gmProvider}
+	SetHashFamilyProvider(bccsp.SM3, csp)
+	defer SetHashFamilyProvider(bccsp.SM3, nil)
+
+	// Local MSPs hold signing keys and keep their provider
+	ca := newSM2TestCA(t)
+	peerCert, peerKey := ca.issue(t, 2, "peer0.org1", "peer")
+	signingMSP := ca.setupMSP(t, bccsp.SM3, peerCert, peerKey)
+	signer, err := signingMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	msg := []byte("proposal")
+	sig, err := signer.Sign(msg)
+	require.NoError(t, err)
+	assert.NoError(t, signer.Verify(msg, sig))
+	assert.Equal(t, 0, csp.verified)
+	serialized, err := signer.Serialize()
+	require.NoError(t, err)
+
+	newVerifyingMSP := func(hashFamily string) MSP {
+		raw, err := proto.Marshal(&msp.FabricMSPConfig{
+			Name:      "Org1MSP",
+			RootCerts: [][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})},
+			CryptoConfig: &msp.FabricCryptoConfig{
+				SignatureHashFamily:            hashFamily,
+				IdentityIdentifierHashFunction: bccsp.SM3,
+			},
+		})
+		require.NoError(t, err)
+		defaultProvider, err := sw.NewDefaultSecurityLevelWithKeystore(sw.NewDummyKeyStore())
+		require.NoError(t, err)
+		thisMSP, err := newBccspMsp(MSPv1_4_3, defaultProvider)
+		require.NoError(t, err)
+		require.NoError(t, thisMSP.Setup(&msp.MSPConfig{Config: raw, Type: int32(FABRIC)}))
+		return thisMSP
+	}
+
+	id, err := newVerifyingMSP(bccsp.SM3).DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.NoError(t, id.Verify(msg, sig))
+	assert.Equal(t, 1, csp.verified)
+
+	// Other hash families keep the default provider, and SHA2 signatures
+	id, err = newVerifyingMSP(bccsp.SHA2).DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.Error(t, id.Verify(msg, sig))
+	assert.Equal(t, 1, csp.verified)
+}
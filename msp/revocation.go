@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/x509"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	m "github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/pkg/errors"
+)
+
+// RevocationChecker obtains the current revocation status of a
+// certificate from its issuer, for instance over OCSP with a
+// gmx509.OCSPChecker.
+type RevocationChecker interface {
+	CheckRevocation(cert, issuer *x509.Certificate) error
+}
+
+var (
+	revocationMutex   sync.RWMutex
+	revocationChecker RevocationChecker
+	revocationRoles   map[m.MSPRole_MSPRoleType]bool
+)
+
+// SetRevocationChecker makes MSPs require a good revocation status from
+// checker for the identities satisfying a role principal with one of
+// roles, such as m.MSPRole_ADMIN and m.MSPRole_PEER for admins and
+// endorsers, on top of their CRLs. A nil checker disables the check.
+func SetRevocationChecker(checker RevocationChecker, roles ...m.MSPRole_MSPRoleType) {
+	revocationMutex.Lock()
+	defer revocationMutex.Unlock()
+
+	revocationChecker = checker
+	revocationRoles = map[m.MSPRole_MSPRoleType]bool{}
+	for _, role := range roles {
+		revocationRoles[role] = true
+	}
+}
+
+// checkRevocationStatus queries the revocation checker about id if it
+// satisfied principal and the role of principal requires it.
+func (msp *bccspmsp) checkRevocationStatus(id Identity, principal *m.MSPPrincipal) error {
+	revocationMutex.RLock()
+	checker, roles := revocationChecker, revocationRoles
+	revocationMutex.RUnlock()
+
+	if checker == nil || principal.PrincipalClassification != m.MSPPrincipal_ROLE {
+		return nil
+	}
+	mspRole := &m.MSPRole{}
+	if err := proto.Unmarshal(principal.Principal, mspRole); err != nil {
+		return errors.Wrap(err, "could not unmarshal MSPRole from principal")
+	}
+	if !roles[mspRole.Role] {
+		return nil
+	}
+
+	bid, ok := id.(*identity)
+	if !ok {
+		return errors.New("invalid identity type, expected *identity")
+	}
+	chain, err := msp.getCertificationChainForBCCSPIdentity(bid)
+	if err != nil {
+		return errors.WithMessage(err, "could not obtain certification chain")
+	}
+	if len(chain) < 2 {
+		// a root CA certificate has no issuer to ask
+		return nil
+	}
+
+	if err := checker.CheckRevocation(chain[0], chain[1]); err != nil {
+		return errors.WithMessagef(err, "no good revocation status for the %s identity of MSP %s", mspRole.Role, msp.name)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sw"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sm2TestCA struct {
+	cert *x509.Certificate
+	key  *sm2.PrivateKey
+}
+
+func newSM2TestCA(t *testing.T) *sm2TestCA {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca.org1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := gmx509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := gmx509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &sm2TestCA{cert: cert, key: key}
+}
+
+// issue returns the PEM certificate and private key of a new SM2 identity
+func (ca *sm2TestCA) issue(t *testing.T, serial int64, cn, ou string) (certPEM, keyPEM []byte) {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	keyPEM, err = utils.PrivateKeyToPEM(key, nil)
+	require.NoError(t, err)
+
+	der, err := gmx509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, OrganizationalUnit: []string{ou}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM
+}
+
+func (ca *sm2TestCA) setupMSP(t *testing.T, hashFamily string, certPEM, keyPEM []byte) MSP {
+	fmspconf := &msp.FabricMSPConfig{
+		Name:      "Org1MSP",
+		RootCerts: [][]byte{pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw})},
+		SigningIdentity: &msp.SigningIdentityInfo{
+			PublicSigner:  certPEM,
+			PrivateSigner: &msp.KeyInfo{KeyIdentifier: "peer0", KeyMaterial: keyPEM},
+		},
+		FabricNodeOus: &msp.FabricNodeOUs{
+			Enable:              true,
+			ClientOuIdentifier:  &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "client"},
+			PeerOuIdentifier:    &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "peer"},
+			AdminOuIdentifier:   &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "admin"},
+			OrdererOuIdentifier: &msp.FabricOUIdentifier{OrganizationalUnitIdentifier: "orderer"},
+		},
+		CryptoConfig: &msp.FabricCryptoConfig{
+			SignatureHashFamily:            hashFamily,
+			IdentityIdentifierHashFunction: bccsp.SM3,
+		},
+	}
+	raw, err := proto.Marshal(fmspconf)
+	require.NoError(t, err)
+
+	// The private key is not in the keystore, it is imported from KeyMaterial
+	cryptoProvider, err := sw.NewWithParams(256, "SM3", sw.NewDummyKeyStore())
+	require.NoError(t, err)
+	thisMSP, err := newBccspMsp(MSPv1_4_3, cryptoProvider)
+	require.NoError(t, err)
+	require.NoError(t, thisMSP.Setup(&msp.MSPConfig{Config: raw, Type: int32(FABRIC)}))
+	return thisMSP
+}
+
+func rolePrincipal(t *testing.T, role msp.MSPRole_MSPRoleType) *msp.MSPPrincipal {
+	principalBytes, err := proto.Marshal(&msp.MSPRole{Role: role, MspIdentifier: "Org1MSP"})
+	require.NoError(t, err)
+	return &msp.MSPPrincipal{
+		PrincipalClassification: msp.MSPPrincipal_ROLE,
+		Principal:               principalBytes,
+	}
+}
+
+func TestSM2Identities(t *testing.T) {
+	ca := newSM2TestCA(t)
+	peerCert, peerKey := ca.issue(t, 2, "peer0.org1", "peer")
+	adminCert, _ := ca.issue(t, 3, "admin.org1", "admin")
+	thisMSP := ca.setupMSP(t, bccsp.SM3, peerCert, peerKey)
+
+	id, err := thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	assert.NoError(t, thisMSP.Validate(id))
+	msg := []byte("proposal")
+	sig, err := id.Sign(msg)
+	require.NoError(t, err)
+	assert.NoError(t, id.Verify(msg, sig))
+	assert.Error(t, id.Verify([]byte("other"), sig))
+
+	// SDKs verify the signature as SM3WithSM2 over the message
+	block, _ := pem.Decode(peerCert)
+	cert, err := gmx509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.NoError(t, gmx509.VerifySM2(cert.PublicKey, msg, sig))
+
+	// The serialized identity carries the certificate as issued
+	serialized, err := id.Serialize()
+	require.NoError(t, err)
+	sID := &msp.SerializedIdentity{}
+	require.NoError(t, proto.Unmarshal(serialized, sID))
+	assert.Equal(t, "Org1MSP", sID.Mspid)
+	assert.Equal(t, peerCert, sID.IdBytes)
+	peer, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.NoError(t, peer.Verify(msg, sig))
+	assert.NoError(t, peer.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_PEER)))
+	assert.Error(t, peer.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_ADMIN)))
+
+	serialized, err = proto.Marshal(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: adminCert})
+	require.NoError(t, err)
+	admin, err := thisMSP.DeserializeIdentity(serialized)
+	require.NoError(t, err)
+	assert.NoError(t, admin.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_ADMIN)))
+	assert.NoError(t, admin.SatisfiesPrincipal(rolePrincipal(t, msp.MSPRole_MEMBER)))
+	ous := admin.GetOrganizationalUnits()
+	require.Len(t, ous, 1)
+	assert.Equal(t, "admin", ous[0].OrganizationalUnitIdentifier)
+}
+
+func TestSM2IdentitiesSHA2(t *testing.T) {
+	ca := newSM2TestCA(t)
+	peerCert, peerKey := ca.issue(t, 2, "peer0.org1", "peer")
+	thisMSP := ca.setupMSP(t, bccsp.SHA2, peerCert, peerKey)
+
+	// SM2 identities keep signing SHA-256 digests with the SHA2 family
+	id, err := thisMSP.GetDefaultSigningIdentity()
+	require.NoError(t, err)
+	msg := []byte("proposal")
+	sig, err := id.Sign(msg)
+	require.NoError(t, err)
+	assert.NoError(t, id.Verify(msg, sig))
+	block, _ := pem.Decode(peerCert)
+	cert, err := gmx509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	assert.Error(t, gmx509.VerifySM2(cert.PublicKey, msg, sig))
+}
@@ -0,0 +1,176 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/metrics"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+)
+
+// Categories of the signature verification failures. They tell attacks,
+// e.g. forged signatures, from interoperability issues with other SDKs,
+// e.g. a different SM2 user identity.
+const (
+	// VerifyFailureAlgorithm is a signature by a key of an algorithm the
+	// algorithm policy forbids or the BCCSP does not support
+	VerifyFailureAlgorithm = "algorithm"
+	// VerifyFailureExpiredCertificate is an invalid signature of an
+	// identity whose certificate is expired or not yet valid, typically a
+	// client signing with a renewed key and presenting its old certificate
+	VerifyFailureExpiredCertificate = "expired_certificate"
+	// VerifyFailureUserID is an SM2 signature that verifies with another
+	// Z_A than the one of the default user identity: the Z_A of the empty
+	// user identity, the Z_A over the SM3 digest of the input, or none
+	VerifyFailureUserID = "user_id"
+	// VerifyFailureMalformedSignature is a signature that is not ASN.1 DER
+	VerifyFailureMalformedSignature = "malformed_signature"
+	// VerifyFailureInvalidSignature is any other invalid signature
+	VerifyFailureInvalidSignature = "invalid_signature"
+	// VerifyFailureProviderError is a failure of the BCCSP or of the
+	// hashing of the message
+	VerifyFailureProviderError = "provider_error"
+)
+
+var verificationFailures = metrics.CounterOpts{
+	Namespace:    "msp",
+	Name:         "signature_verification_failures",
+	Help:         "The number of signatures of identities that failed verification, by category of failure.",
+	LabelNames:   []string{"mspid", "category"},
+	StatsdFormat: "%{#fqname}.%{mspid}.%{category}",
+}
+
+var (
+	verifyMetricsMutex   sync.RWMutex
+	verificationFailureC metrics.Counter = (&disabled.Provider{}).NewCounter(verificationFailures)
+)
+
+// SetMetricsProvider makes identities count their signature verification
+// failures with p.
+func SetMetricsProvider(p metrics.Provider) {
+	verifyMetricsMutex.Lock()
+	defer verifyMetricsMutex.Unlock()
+	verificationFailureC = p.NewCounter(verificationFailures)
+}
+
+func getVerificationFailures() metrics.Counter {
+	verifyMetricsMutex.RLock()
+	defer verifyMetricsMutex.RUnlock()
+	return verificationFailureC
+}
+
+// VerifyFailure is the error returned by Verify when a signature fails
+// verification. Its message is the one of the underlying error.
+type VerifyFailure struct {
+	// Category is one of the VerifyFailure categories
+	Category string
+	err      error
+}
+
+func (e *VerifyFailure) Error() string {
+	return e.err.Error()
+}
+
+// Cause returns the underlying error.
+func (e *VerifyFailure) Cause() error {
+	return e.err
+}
+
+// Unwrap returns the underlying error.
+func (e *VerifyFailure) Unwrap() error {
+	return e.err
+}
+
+// verifyFailed counts and logs the verification failure err of a signature
+// of id in category, and returns it as a VerifyFailure. hint describes the
+// likely cause, if known.
+func (id *identity) verifyFailed(category, hint string, err error) error {
+	getVerificationFailures().With("mspid", id.id.Mspid, "category", category).Add(1)
+
+	kvPairs := []interface{}{"mspid", id.id.Mspid, "category", category, "subject", id.cert.Subject.String(), "error", err.Error()}
+	if hint != "" {
+		kvPairs = append(kvPairs, "hint", hint)
+	}
+	mspIdentityLogger.Warnw("Signature verification failed", kvPairs...)
+
+	return &VerifyFailure{Category: category, err: err}
+}
+
+// classifyInvalidSignature returns the category of the signature sig that
+// does not verify against digest, the input the BCCSP verified, and a hint
+// of its cause. The alternatives are only tried once a signature is
+// rejected, so valid signatures take no extra time.
+func (id *identity) classifyInvalidSignature(digest, sig []byte) (string, string) {
+	r, s, err := utils.UnmarshalECDSASignature(sig)
+	if err != nil {
+		if len(sig) == 2*sm2.KeyBytes {
+			return VerifyFailureMalformedSignature, "the signature looks like a raw r||s signature, it must be ASN.1 DER encoded"
+		}
+		return VerifyFailureMalformedSignature, ""
+	}
+
+	if pub, ok := id.cert.PublicKey.(*sm2.PublicKey); ok {
+		// sm2.VerifyByRS uses the default user identity for a nil uid only
+		if sm2.VerifyByRS(pub, []byte{}, digest, r, s) {
+			return VerifyFailureUserID, "the signer used the empty SM2 user identity instead of the default one"
+		}
+		hashed := sm3.Sum(digest)
+		if sm2.VerifyByRS(pub, nil, hashed[:], r, s) {
+			return VerifyFailureUserID, "the signer signed the SM3 digest of the input instead of the input"
+		}
+		if verifyWithoutZA(pub, hashed[:], r, s) {
+			return VerifyFailureUserID, "the signer did not prepend Z_A to the input"
+		}
+	}
+
+	if now := time.Now(); now.Before(id.cert.NotBefore) || now.After(id.cert.NotAfter) {
+		return VerifyFailureExpiredCertificate, "the certificate is valid from " + id.cert.NotBefore.String() + " to " + id.cert.NotAfter.String()
+	}
+
+	return VerifyFailureInvalidSignature, ""
+}
+
+// verifyWithoutZA verifies the SM2 signature (r, s) whose digest e is the
+// SM3 digest of the input alone, as signers omitting Z_A compute it.
+func verifyWithoutZA(pub *sm2.PublicKey, e []byte, r, s *big.Int) bool {
+	n := pub.Curve.Params().N
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+
+	x1, y1 := pub.Curve.ScalarBaseMult(s.Bytes())
+	x2, y2 := pub.Curve.ScalarMult(pub.X, pub.Y, t.Bytes())
+	x, _ := pub.Curve.Add(x1, y1, x2, y2)
+
+	expected := new(big.Int).SetBytes(e)
+	expected.Add(expected, x)
+	expected.Mod(expected, n)
+	return expected.Cmp(r) == 0
+}
+
+// classifyProviderError returns the category of the error err of the BCCSP
+// verifying a signature.
+func classifyProviderError(err error) string {
+	switch bccsp.ErrorCodeOf(err) {
+	case bccsp.ErrCodeUnsupportedAlgorithm, bccsp.ErrCodeUnsupportedKeyType:
+		return VerifyFailureAlgorithm
+	default:
+		return VerifyFailureProviderError
+	}
+}
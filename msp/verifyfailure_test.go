@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msp
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/metrics/metricsfakes"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/sm3"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/utils"
+	"github.com/paul-lee-attorney/gm/sm2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issueSM2Key returns the PEM certificate of a new SM2 client identity
+// valid from notBefore to notAfter, and its private key.
+func (ca *sm2TestCA) issueSM2Key(t *testing.T, serial int64, notBefore, notAfter time.Time) ([]byte, *sm2.PrivateKey) {
+	key, err := utils.SM2GenerateKey(rand.Reader, sm2.GetSm2P256V1())
+	require.NoError(t, err)
+	der, err := gmx509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "user1.org1", OrganizationalUnit: []string{"client"}},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), key
+}
+
+func TestVerifyFailureCategories(t *testing.T) {
+	counter := &metricsfakes.Counter{}
+	counter.WithReturns(counter)
+	provider := &metricsfakes.Provider{}
+	provider.NewCounterReturns(counter)
+	SetMetricsProvider(provider)
+	defer SetMetricsProvider(&disabled.Provider{})
+	defer SetAlgorithmPolicy(gmx509.AlgorithmPolicy{})
+
+	ca := newSM2TestCA(t)
+	peerCert, peerKey := ca.issue(t, 2, "peer0.org1", "peer")
+	thisMSP := ca.setupMSP(t, bccsp.SM3, peerCert, peerKey)
+
+	deserialize := func(certPEM []byte) Identity {
+		serialized, err := proto.Marshal(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: certPEM})
+		require.NoError(t, err)
+		id, err := thisMSP.DeserializeIdentity(serialized)
+		require.NoError(t, err)
+		return id
+	}
+	now := time.Now()
+	clientCert, clientKey := ca.issueSM2Key(t, 3, now.Add(-time.Hour), now.Add(time.Hour))
+	client := deserialize(clientCert)
+	expiredCert, expiredKey := ca.issueSM2Key(t, 4, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	expired := deserialize(expiredCert)
+
+	msg := []byte("proposal")
+	sig, err := sm2.Sign(clientKey, nil, msg)
+	require.NoError(t, err)
+	require.NoError(t, client.Verify(msg, sig))
+	assert.Zero(t, counter.AddCallCount())
+
+	rawSig, err := utils.SM2SignatureDERToRaw(sig)
+	require.NoError(t, err)
+	emptyUIDSig, err := sm2.Sign(clientKey, []byte{}, msg)
+	require.NoError(t, err)
+	digest := sm3.Sum(msg)
+	preHashedSig, err := sm2.Sign(clientKey, nil, digest[:])
+	require.NoError(t, err)
+	otherSig, err := sm2.Sign(clientKey, nil, []byte("other"))
+	require.NoError(t, err)
+	expiredSig, err := sm2.Sign(expiredKey, nil, []byte("other"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		id       Identity
+		sig      []byte
+		category string
+	}{
+		{"malformed", client, rawSig, VerifyFailureMalformedSignature},
+		{"empty user identity", client, emptyUIDSig, VerifyFailureUserID},
+		{"pre-hashed", client, preHashedSig, VerifyFailureUserID},
+		{"invalid", client, otherSig, VerifyFailureInvalidSignature},
+		{"expired", expired, expiredSig, VerifyFailureExpiredCertificate},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.id.Verify(msg, tt.sig)
+			assert.EqualError(t, err, "The signature is invalid")
+			var failure *VerifyFailure
+			require.True(t, errors.As(err, &failure))
+			assert.Equal(t, tt.category, failure.Category)
+
+			require.Equal(t, i+1, counter.AddCallCount())
+			assert.Equal(t, float64(1), counter.AddArgsForCall(i))
+			assert.Equal(t, []string{"mspid", "Org1MSP", "category", tt.category}, counter.WithArgsForCall(i))
+		})
+	}
+
+	SetAlgorithmPolicy(gmx509.AlgorithmPolicy{MinECKeySize: 384})
+	err = client.Verify(msg, sig)
+	assert.Contains(t, err.Error(), "the signature uses a forbidden algorithm")
+	var failure *VerifyFailure
+	require.True(t, errors.As(err, &failure))
+	assert.Equal(t, VerifyFailureAlgorithm, failure.Category)
+}
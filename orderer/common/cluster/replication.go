@@ -15,6 +15,7 @@ import (
 
 	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/hyperledger/fabric/internal/pkg/identity"
 	"github.com/hyperledger/fabric/orderer/common/localconfig"
@@ -97,6 +98,9 @@ type Replicator struct {
 	BootBlock                       *common.Block
 	AmIPartOfChannel                SelfMembershipPredicate
 	LedgerFactory                   LedgerFactory
+	// VerifierRetriever retrieves the verifiers of the application channels,
+	// which know the hashing algorithm of their blocks
+	VerifierRetriever VerifierRetriever
 }
 
 // IsReplicationNeeded returns whether replication is needed,
@@ -221,7 +225,8 @@ func (r *Replicator) pullChannelBlocks(channel string, puller *BlockPuller, late
 		return ErrRetryCountExhausted
 	}
 	r.appendBlock(nextBlock, ledger, channel)
-	actualPrevHash := protoutil.BlockHeaderHash(nextBlock.Header)
+	hashingAlgorithm := r.hashingAlgorithm(channel)
+	actualPrevHash := protoutil.BlockHeaderHashWith(nextBlock.Header, hashingAlgorithm)
 
 	for seq := uint64(nextBlockToPull + 1); seq < latestHeight; seq++ {
 		block := puller.PullBlock(seq)
@@ -233,9 +238,9 @@ func (r *Replicator) pullChannelBlocks(channel string, puller *BlockPuller, late
 			return errors.Errorf("block header mismatch on sequence %d, expected %x, got %x",
 				block.Header.Number, actualPrevHash, reportedPrevHash)
 		}
-		actualPrevHash = protoutil.BlockHeaderHash(block.Header)
+		actualPrevHash = protoutil.BlockHeaderHashWith(block.Header, hashingAlgorithm)
 		if channel == r.SystemChannel && block.Header.Number == r.BootBlock.Header.Number {
-			r.compareBootBlockWithSystemChannelLastConfigBlock(block)
+			r.compareBootBlockWithSystemChannelLastConfigBlock(block, hashingAlgorithm)
 			r.appendBlock(block, ledger, channel)
 			// No need to pull further blocks from the system channel
 			return nil
@@ -245,6 +250,22 @@ func (r *Replicator) pullChannelBlocks(channel string, puller *BlockPuller, late
 	return nil
 }
 
+// hashingAlgorithm returns the hashing algorithm of the blocks of the given channel,
+// or nil to hash them with SHA-256 if it is unknown.
+func (r *Replicator) hashingAlgorithm(channel string) func([]byte) []byte {
+	if channel == r.SystemChannel {
+		hashingAlgorithm, err := util.BlockHashingAlgorithm(r.BootBlock)
+		if err != nil {
+			r.Logger.Panicf("Failed determining the hashing algorithm of the system channel: %v", err)
+		}
+		return hashingAlgorithm
+	}
+	if r.VerifierRetriever == nil {
+		return nil
+	}
+	return BlockHashingAlgorithm(r.VerifierRetriever.RetrieveVerifier(channel))
+}
+
 func (r *Replicator) appendBlock(block *common.Block, ledger LedgerWriter, channel string) {
 	height := ledger.Height()
 	if height > block.Header.Number {
@@ -257,12 +278,12 @@ func (r *Replicator) appendBlock(block *common.Block, ledger LedgerWriter, chann
 	r.Logger.Infof("Committed block [%d] for channel %s", block.Header.Number, channel)
 }
 
-func (r *Replicator) compareBootBlockWithSystemChannelLastConfigBlock(block *common.Block) {
+func (r *Replicator) compareBootBlockWithSystemChannelLastConfigBlock(block *common.Block, hashingAlgorithm func([]byte) []byte) {
 	// Overwrite the received block's data hash
-	block.Header.DataHash = protoutil.BlockDataHash(block.Data)
+	block.Header.DataHash = protoutil.BlockDataHashWith(block.Data, hashingAlgorithm)
 
-	bootBlockHash := protoutil.BlockHeaderHash(r.BootBlock.Header)
-	retrievedBlockHash := protoutil.BlockHeaderHash(block.Header)
+	bootBlockHash := protoutil.BlockHeaderHashWith(r.BootBlock.Header, hashingAlgorithm)
+	retrievedBlockHash := protoutil.BlockHeaderHashWith(block.Header, hashingAlgorithm)
 	if bytes.Equal(bootBlockHash, retrievedBlockHash) {
 		return
 	}
@@ -392,7 +413,7 @@ func BlockPullerFromConfigBlock(conf PullerConfig, block *common.Block, verifier
 			if verifier == nil {
 				return errors.Errorf("couldn't acquire verifier for channel %s", channel)
 			}
-			return VerifyBlocks(blocks, verifier)
+			return VerifyBlocks(blocks, verifier, BlockHashingAlgorithm(verifier))
 		},
 		MaxTotalBufferBytes: conf.MaxTotalBufferBytes,
 		Endpoints:           endpoints,
@@ -539,6 +560,10 @@ func (gbs GenesisBlocks) Names() []string {
 func (ci *ChainInspector) Channels() []ChannelGenesisBlock {
 	channels := make(map[string]ChannelGenesisBlock)
 	lastConfigBlockNum := ci.LastConfigBlock.Header.Number
+	hashingAlgorithm, err := util.BlockHashingAlgorithm(ci.LastConfigBlock)
+	if err != nil {
+		ci.Logger.Panicf("Failed determining the hashing algorithm of the system channel: %v", err)
+	}
 	var block *common.Block
 	var prevHash []byte
 	for seq := uint64(0); seq < lastConfigBlockNum; seq++ {
@@ -548,7 +573,7 @@ func (ci *ChainInspector) Channels() []ChannelGenesisBlock {
 		}
 		ci.validateHashPointer(block, prevHash)
 		// Set the previous hash for the next iteration
-		prevHash = protoutil.BlockHeaderHash(block.Header)
+		prevHash = protoutil.BlockHeaderHashWith(block.Header, hashingAlgorithm)
 
 		channel, gb, err := ExtractGenesisBlock(ci.Logger, block)
 		if err != nil {
@@ -574,7 +599,7 @@ func (ci *ChainInspector) Channels() []ChannelGenesisBlock {
 	// We don't need to verify the entire chain of all blocks we pulled,
 	// because the block puller calls VerifyBlockHash on all blocks it pulls.
 	last2Blocks := []*common.Block{block, ci.LastConfigBlock}
-	if err := VerifyBlockHash(1, last2Blocks); err != nil {
+	if err := VerifyBlockHash(1, last2Blocks, hashingAlgorithm); err != nil {
 		ci.Logger.Panic("System channel pulled doesn't match the boot last config block:", err)
 	}
 
@@ -669,9 +694,15 @@ func ExtractGenesisBlock(logger *flogging.FabricLogger, block *common.Block) (st
 
 	blockdata := &common.BlockData{Data: [][]byte{payload.Data}}
 	b := &common.Block{
-		Header:   &common.BlockHeader{DataHash: protoutil.BlockDataHash(blockdata)},
+		Header:   &common.BlockHeader{},
 		Data:     blockdata,
 		Metadata: metadata,
 	}
+	// The genesis block of the channel is hashed with the hashing algorithm it sets
+	hashingAlgorithm, err := util.BlockHashingAlgorithm(b)
+	if err != nil {
+		return "", nil, err
+	}
+	b.Header.DataHash = protoutil.BlockDataHashWith(blockdata, hashingAlgorithm)
 	return chdr.ChannelId, b, nil
 }
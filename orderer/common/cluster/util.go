@@ -28,6 +28,7 @@ import (
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 )
@@ -188,6 +189,24 @@ type BlockVerifier interface {
 	VerifyBlockSignature(sd []*protoutil.SignedData, config *common.ConfigEnvelope) error
 }
 
+// BlockHasher is implemented by the BlockVerifiers which know the hashing
+// algorithm of the blocks of their channel.
+type BlockHasher interface {
+	// BlockHashingAlgorithm returns the hashing algorithm of the headers
+	// and the data of the blocks.
+	BlockHashingAlgorithm() func([]byte) []byte
+}
+
+// BlockHashingAlgorithm returns the hashing algorithm of the blocks the
+// given BlockVerifier verifies, or nil to hash them with SHA-256 if it
+// doesn't know it.
+func BlockHashingAlgorithm(verifier BlockVerifier) func([]byte) []byte {
+	if hasher, ok := verifier.(BlockHasher); ok {
+		return hasher.BlockHashingAlgorithm()
+	}
+	return nil
+}
+
 // BlockSequenceVerifier verifies that the given consecutive sequence
 // of blocks is valid.
 type BlockSequenceVerifier func(blocks []*common.Block, channel string) error
@@ -199,7 +218,8 @@ type Dialer interface {
 
 // VerifyBlocks verifies the given consecutive sequence of blocks is valid,
 // and returns nil if it's valid, else an error.
-func VerifyBlocks(blockBuff []*common.Block, signatureVerifier BlockVerifier) error {
+// The blocks are hashed with the given hashing algorithm, or with SHA-256 if it is nil.
+func VerifyBlocks(blockBuff []*common.Block, signatureVerifier BlockVerifier, hash func([]byte) []byte) error {
 	if len(blockBuff) == 0 {
 		return errors.New("buffer is empty")
 	}
@@ -207,7 +227,7 @@ func VerifyBlocks(blockBuff []*common.Block, signatureVerifier BlockVerifier) er
 	// Equal to the hash in the header
 	// Equal to the previous hash in the succeeding block
 	for i := range blockBuff {
-		if err := VerifyBlockHash(i, blockBuff); err != nil {
+		if err := VerifyBlockHash(i, blockBuff, hash); err != nil {
 			return err
 		}
 	}
@@ -288,7 +308,8 @@ func ConfigFromBlock(block *common.Block) (*common.ConfigEnvelope, error) {
 
 // VerifyBlockHash verifies the hash chain of the block with the given index
 // among the blocks of the given block buffer.
-func VerifyBlockHash(indexInBuffer int, blockBuff []*common.Block) error {
+// The blocks are hashed with the given hashing algorithm, or with SHA-256 if it is nil.
+func VerifyBlockHash(indexInBuffer int, blockBuff []*common.Block, hash func([]byte) []byte) error {
 	if len(blockBuff) <= indexInBuffer {
 		return errors.Errorf("index %d out of bounds (total %d blocks)", indexInBuffer, len(blockBuff))
 	}
@@ -297,7 +318,7 @@ func VerifyBlockHash(indexInBuffer int, blockBuff []*common.Block) error {
 		return errors.New("missing block header")
 	}
 	seq := block.Header.Number
-	dataHash := protoutil.BlockDataHash(block.Data)
+	dataHash := protoutil.BlockDataHashWith(block.Data, hash)
 	// Verify data hash matches the hash in the header
 	if !bytes.Equal(dataHash, block.Header.DataHash) {
 		computedHash := hex.EncodeToString(dataHash)
@@ -316,9 +337,10 @@ func VerifyBlockHash(indexInBuffer int, blockBuff []*common.Block) error {
 		if prevSeq+1 != currSeq {
 			return errors.Errorf("sequences %d and %d were received consecutively", prevSeq, currSeq)
 		}
-		if !bytes.Equal(block.Header.PreviousHash, protoutil.BlockHeaderHash(prevBlock.Header)) {
+		prevHash := protoutil.BlockHeaderHashWith(prevBlock.Header, hash)
+		if !bytes.Equal(block.Header.PreviousHash, prevHash) {
 			claimedPrevHash := hex.EncodeToString(block.Header.PreviousHash)
-			actualPrevHash := hex.EncodeToString(protoutil.BlockHeaderHash(prevBlock.Header))
+			actualPrevHash := hex.EncodeToString(prevHash)
 			return errors.Errorf("block [%d]'s hash (%s) mismatches block [%d]'s prev block hash (%s)",
 				prevSeq, actualPrevHash, currSeq, claimedPrevHash)
 		}
@@ -381,7 +403,7 @@ func (ep EndpointCriteria) String() string {
 			if bl == nil {
 				break
 			}
-			cert, err := x509.ParseCertificate(bl.Bytes)
+			cert, err := gmx509.ParseCertificate(bl.Bytes)
 			if err != nil {
 				break
 			}
@@ -598,10 +620,11 @@ func (bva *BlockVerifierAssembler) VerifierFromConfig(configuration *common.Conf
 	policyMgr := bundle.PolicyManager()
 
 	return &BlockValidationPolicyVerifier{
-		Logger:    bva.Logger,
-		PolicyMgr: policyMgr,
-		Channel:   channel,
-		BCCSP:     bva.BCCSP,
+		Logger:           bva.Logger,
+		PolicyMgr:        policyMgr,
+		Channel:          channel,
+		BCCSP:            bva.BCCSP,
+		HashingAlgorithm: channelconfig.BlockHashingAlgorithm(bundle.ChannelConfig()),
 	}, nil
 }
 
@@ -611,6 +634,14 @@ type BlockValidationPolicyVerifier struct {
 	Channel   string
 	PolicyMgr policies.Manager
 	BCCSP     bccsp.BCCSP
+	// HashingAlgorithm hashes the headers and the data of the blocks of the channel,
+	// or is nil to hash them with SHA-256
+	HashingAlgorithm func([]byte) []byte
+}
+
+// BlockHashingAlgorithm returns the hashing algorithm of the blocks of the channel.
+func (bv *BlockValidationPolicyVerifier) BlockHashingAlgorithm() func([]byte) []byte {
+	return bv.HashingAlgorithm
 }
 
 // VerifyBlockSignature verifies the signed data associated to a block, optionally with the given config envelope.
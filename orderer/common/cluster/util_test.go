@@ -27,6 +27,7 @@ import (
 	"github.com/hyperledger/fabric/common/crypto/tlsgen"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/config/configtest"
 	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
 	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
@@ -250,7 +251,7 @@ func TestVerifyBlockHash(t *testing.T) {
 
 	verify := func(blockchain []*common.Block) error {
 		for i := 0; i < len(blockchain); i++ {
-			err := cluster.VerifyBlockHash(i, blockchain)
+			err := cluster.VerifyBlockHash(i, blockchain, nil)
 			if err != nil {
 				return err
 			}
@@ -263,13 +264,17 @@ func TestVerifyBlockHash(t *testing.T) {
 
 	twoBlocks := createBlockChain(2, 3)
 	twoBlocks[0].Header = nil
-	assert.EqualError(t, cluster.VerifyBlockHash(1, twoBlocks), "previous block header is nil")
+	assert.EqualError(t, cluster.VerifyBlockHash(1, twoBlocks, nil), "previous block header is nil")
 
 	// Index out of bounds
 	blockchain := createBlockChain(start, end)
-	err := cluster.VerifyBlockHash(100, blockchain)
+	err := cluster.VerifyBlockHash(100, blockchain, nil)
 	assert.EqualError(t, err, "index 100 out of bounds (total 21 blocks)")
 
+	// The blocks are hashed with SHA-256, not with the given hashing algorithm
+	err = cluster.VerifyBlockHash(0, blockchain, util.ComputeSM3)
+	assert.Contains(t, err.Error(), "doesn't match claimed hash")
+
 	for _, testCase := range []struct {
 		name                string
 		mutateBlockSequence func([]*common.Block) []*common.Block
@@ -322,6 +327,15 @@ func TestVerifyBlockHash(t *testing.T) {
 	}
 }
 
+func TestBlockHashingAlgorithm(t *testing.T) {
+	assert.Nil(t, cluster.BlockHashingAlgorithm(nil))
+	assert.Nil(t, cluster.BlockHashingAlgorithm(&mocks.BlockVerifier{}))
+
+	verifier := &cluster.BlockValidationPolicyVerifier{HashingAlgorithm: util.ComputeSM3}
+	hash := cluster.BlockHashingAlgorithm(verifier)
+	assert.Equal(t, util.ComputeSM3([]byte("foo")), hash([]byte("foo")))
+}
+
 func TestVerifyBlocks(t *testing.T) {
 	var sigSet1 []*protoutil.SignedData
 	var sigSet2 []*protoutil.SignedData
@@ -513,7 +527,7 @@ func TestVerifyBlocks(t *testing.T) {
 			if testCase.configureVerifier != nil {
 				testCase.configureVerifier(verifier)
 			}
-			err := cluster.VerifyBlocks(blockchain, verifier)
+			err := cluster.VerifyBlocks(blockchain, verifier, nil)
 			if testCase.expectedError != "" {
 				assert.EqualError(t, err, testCase.expectedError)
 			} else {
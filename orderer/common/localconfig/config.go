@@ -76,6 +76,19 @@ type Cluster struct {
 	SendBufferSize                       int
 	CertExpirationWarningThreshold       time.Duration
 	TLSHandshakeTimeShift                time.Duration
+	GMTLS                                ClusterGMTLS
+}
+
+// ClusterGMTLS contains the configuration of GMTLS (GM/T 0024) between
+// ordering service nodes, which replaces TLS when enabled. The client and
+// server certificates and keys of Cluster are then the SM2 signing
+// certificates and keys.
+type ClusterGMTLS struct {
+	Enabled              bool
+	ClientEncCertificate string
+	ClientEncPrivateKey  string
+	ServerEncCertificate string
+	ServerEncPrivateKey  string
 }
 
 // Keepalive contains configuration for gRPC servers.
@@ -94,6 +107,37 @@ type TLS struct {
 	ClientAuthRequired    bool
 	ClientRootCAs         []string
 	TLSHandshakeTimeShift time.Duration
+	GMTLS                 GMTLS
+	SMTLS13               SMTLS13
+	DualStack             DualStack
+	PinnedCertificates    []string
+}
+
+// GMTLS contains the configuration of GMTLS (GM/T 0024), which replaces TLS
+// when enabled. Certificate and PrivateKey of TLS are then the SM2 signing
+// certificate and key.
+type GMTLS struct {
+	Enabled        bool
+	EncCertificate string
+	EncPrivateKey  string
+}
+
+// SMTLS13 contains the configuration of TLS 1.3 with the SM cipher suites
+// of RFC 8998, which replaces TLS 1.2 when enabled. Certificate and
+// PrivateKey of TLS are then the SM2 certificate and key.
+type SMTLS13 struct {
+	Enabled      bool
+	CipherSuites []string
+}
+
+// DualStack contains the configuration of listeners accepting TLS 1.2
+// connections besides GMTLS or TLS 1.3 SM ones, on the same port, while
+// clients migrate. Certificate and PrivateKey are presented to TLS 1.2
+// clients.
+type DualStack struct {
+	Enabled     bool
+	Certificate string
+	PrivateKey  string
 }
 
 // SASLPlain contains configuration for SASL/PLAIN authentication
@@ -190,6 +234,14 @@ type Debug struct {
 type Operations struct {
 	ListenAddress string
 	TLS           TLS
+	Authorization OperationsAuthorization
+}
+
+// OperationsAuthorization maps operations endpoints, such as /logspec, to
+// the signature policies their TLS client certificates must satisfy as
+// identities of the local MSP.
+type OperationsAuthorization struct {
+	Policies map[string]string
 }
 
 // Operations confiures the metrics provider for the orderer.
@@ -352,12 +404,22 @@ func (c *TopLevel) completeInitialization(configDir string) {
 		if c.General.Cluster.ClientCertificate != "" {
 			coreconfig.TranslatePathInPlace(configDir, &c.General.Cluster.ClientCertificate)
 		}
+		if c.General.Cluster.GMTLS.ClientEncPrivateKey != "" {
+			coreconfig.TranslatePathInPlace(configDir, &c.General.Cluster.GMTLS.ClientEncPrivateKey)
+		}
+		if c.General.Cluster.GMTLS.ClientEncCertificate != "" {
+			coreconfig.TranslatePathInPlace(configDir, &c.General.Cluster.GMTLS.ClientEncCertificate)
+		}
 		c.General.Cluster.RootCAs = translateCAs(configDir, c.General.Cluster.RootCAs)
 		// Translate any paths for general TLS configuration
 		c.General.TLS.RootCAs = translateCAs(configDir, c.General.TLS.RootCAs)
 		c.General.TLS.ClientRootCAs = translateCAs(configDir, c.General.TLS.ClientRootCAs)
 		coreconfig.TranslatePathInPlace(configDir, &c.General.TLS.PrivateKey)
 		coreconfig.TranslatePathInPlace(configDir, &c.General.TLS.Certificate)
+		coreconfig.TranslatePathInPlace(configDir, &c.General.TLS.GMTLS.EncPrivateKey)
+		coreconfig.TranslatePathInPlace(configDir, &c.General.TLS.GMTLS.EncCertificate)
+		coreconfig.TranslatePathInPlace(configDir, &c.General.TLS.DualStack.PrivateKey)
+		coreconfig.TranslatePathInPlace(configDir, &c.General.TLS.DualStack.Certificate)
 		coreconfig.TranslatePathInPlace(configDir, &c.General.BootstrapFile)
 		coreconfig.TranslatePathInPlace(configDir, &c.General.LocalMSPDir)
 		// Translate file ledger location
@@ -26,6 +26,7 @@ type blockWriterSupport interface {
 	Update(*newchannelconfig.Bundle)
 	CreateBundle(channelID string, config *cb.Config) (*newchannelconfig.Bundle, error)
 	SharedConfig() newchannelconfig.Orderer
+	ChannelConfig() newchannelconfig.Channel
 }
 
 // BlockWriter efficiently writes the blockchain to disk.
@@ -38,6 +39,7 @@ type BlockWriter struct {
 	lastConfigBlockNum uint64
 	lastConfigSeq      uint64
 	lastBlock          *cb.Block
+	hashingAlgorithm   func([]byte) []byte
 	committingBlock    sync.Mutex
 }
 
@@ -47,6 +49,8 @@ func newBlockWriter(lastBlock *cb.Block, r *Registrar, support blockWriterSuppor
 		lastConfigSeq: support.Sequence(),
 		lastBlock:     lastBlock,
 		registrar:     r,
+		// The hashing algorithm of a channel never changes
+		hashingAlgorithm: newchannelconfig.BlockHashingAlgorithm(support.ChannelConfig()),
 	}
 
 	// If this is the genesis block, the lastconfig field may be empty, and, the last config block is necessarily block 0
@@ -65,7 +69,7 @@ func newBlockWriter(lastBlock *cb.Block, r *Registrar, support blockWriterSuppor
 
 // CreateNextBlock creates a new block with the next block number, and the given contents.
 func (bw *BlockWriter) CreateNextBlock(messages []*cb.Envelope) *cb.Block {
-	previousBlockHash := protoutil.BlockHeaderHash(bw.lastBlock.Header)
+	previousBlockHash := protoutil.BlockHeaderHashWith(bw.lastBlock.Header, bw.hashingAlgorithm)
 
 	data := &cb.BlockData{
 		Data: make([][]byte, len(messages)),
@@ -80,7 +84,7 @@ func (bw *BlockWriter) CreateNextBlock(messages []*cb.Envelope) *cb.Block {
 	}
 
 	block := protoutil.NewBlock(bw.lastBlock.Header.Number+1, previousBlockHash)
-	block.Header.DataHash = protoutil.BlockDataHash(data)
+	block.Header.DataHash = protoutil.BlockDataHashWith(data, bw.hashingAlgorithm)
 	block.Data = data
 
 	return block
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package multichannel
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -21,6 +22,7 @@ import (
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	"github.com/hyperledger/fabric/common/ledger/blockledger/fileledger"
 	"github.com/hyperledger/fabric/common/metrics/disabled"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/config/configtest"
 	"github.com/hyperledger/fabric/internal/configtxgen/encoder"
 	"github.com/hyperledger/fabric/internal/configtxgen/genesisconfig"
@@ -60,6 +62,10 @@ func (mbws mockBlockWriterSupport) SharedConfig() newchannelconfig.Orderer {
 	return mbws.fakeConfig
 }
 
+func (mbws mockBlockWriterSupport) ChannelConfig() newchannelconfig.Channel {
+	return &mocks.ChannelConfig{}
+}
+
 func TestCreateBlock(t *testing.T) {
 	seedBlock := protoutil.NewBlock(7, []byte("lasthash"))
 	seedBlock.Data.Data = [][]byte{[]byte("somebytes")}
@@ -74,6 +80,20 @@ func TestCreateBlock(t *testing.T) {
 	assert.Equal(t, protoutil.BlockHeaderHash(seedBlock.Header), block.Header.PreviousHash)
 }
 
+func TestCreateBlockWithHashingAlgorithm(t *testing.T) {
+	seedBlock := protoutil.NewBlock(7, []byte("lasthash"))
+	seedBlock.Data.Data = [][]byte{[]byte("somebytes")}
+
+	bw := &BlockWriter{lastBlock: seedBlock, hashingAlgorithm: util.ComputeSM3}
+	block := bw.CreateNextBlock([]*cb.Envelope{
+		{Payload: []byte("some other bytes")},
+	})
+
+	assert.Equal(t, util.ComputeSM3(bytes.Join(block.Data.Data, nil)), block.Header.DataHash)
+	assert.Equal(t, util.ComputeSM3(protoutil.BlockHeaderBytes(seedBlock.Header)), block.Header.PreviousHash)
+	assert.NotEqual(t, protoutil.BlockHeaderHash(seedBlock.Header), block.Header.PreviousHash)
+}
+
 func TestBlockSignature(t *testing.T) {
 	dir, err := ioutil.TempDir("", "file-ledger")
 	require.NoError(t, err)
@@ -199,7 +199,7 @@ func (r *Registrar) Initialize(consenters map[string]consensus.Consenter) {
 				logger.Panicf("Error reading genesis block of system channel '%s'", channelID)
 			}
 			logger.Infof("Starting system channel '%s' with genesis block hash %x and orderer type %s",
-				channelID, protoutil.BlockHeaderHash(genesisBlock.Header), chain.SharedConfig().ConsensusType())
+				channelID, protoutil.BlockHeaderHashWith(genesisBlock.Header, channelconfig.BlockHashingAlgorithm(chain.ChannelConfig())), chain.SharedConfig().ConsensusType())
 
 			r.chains[channelID] = chain
 			r.systemChannelID = channelID
@@ -333,7 +333,8 @@ func (r *Registrar) newChain(configtx *cb.Envelope) {
 	ledgerResources := r.newLedgerResources(configtx)
 	// If we have no blocks, we need to create the genesis block ourselves.
 	if ledgerResources.Height() == 0 {
-		ledgerResources.Append(blockledger.CreateNextBlock(ledgerResources, []*cb.Envelope{configtx}))
+		hashingAlgorithm := channelconfig.BlockHashingAlgorithm(ledgerResources.ChannelConfig())
+		ledgerResources.Append(blockledger.CreateNextBlockWith(ledgerResources, []*cb.Envelope{configtx}, hashingAlgorithm))
 	}
 
 	// Copy the map to allow concurrent reads from broadcast/deliver while the new chainSupport is
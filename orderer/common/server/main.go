@@ -38,6 +38,7 @@ import (
 	"github.com/hyperledger/fabric/internal/pkg/comm"
 	"github.com/hyperledger/fabric/internal/pkg/identity"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/msp/mgmt"
 	"github.com/hyperledger/fabric/orderer/common/bootstrap/file"
 	"github.com/hyperledger/fabric/orderer/common/cluster"
 	"github.com/hyperledger/fabric/orderer/common/localconfig"
@@ -88,12 +89,13 @@ func Main() {
 
 	cryptoProvider := factory.GetDefault()
 
-	signer, signErr := loadLocalMSP(conf).GetDefaultSigningIdentity()
+	localMSP := loadLocalMSP(conf)
+	signer, signErr := localMSP.GetDefaultSigningIdentity()
 	if signErr != nil {
 		logger.Panicf("Failed to get local MSP identity: %s", signErr)
 	}
 
-	opsSystem := newOperationsSystem(conf.Operations, conf.Metrics)
+	opsSystem := newOperationsSystem(conf.Operations, conf.Metrics, localMSP)
 	if err = opsSystem.Start(); err != nil {
 		logger.Panicf("failed to initialize operations subsystem: %s", err)
 	}
@@ -102,8 +104,33 @@ func Main() {
 	logObserver := floggingmetrics.NewObserver(metricsProvider)
 	flogging.SetObserver(logObserver)
 
+	if selfTest := factory.GetSelfTest(); selfTest != nil {
+		selfTest.SetMetricsProvider(metricsProvider)
+		if err := opsSystem.RegisterChecker("bccsp", selfTest); err != nil {
+			logger.Panicf("failed to register bccsp health check: %s", err)
+		}
+	}
+	if verifyCache := factory.GetVerifyCache(); verifyCache != nil {
+		verifyCache.SetMetricsProvider(metricsProvider)
+	}
+	if operationMetrics := factory.GetMetrics(); operationMetrics != nil {
+		operationMetrics.SetMetricsProvider(metricsProvider)
+	}
+	for _, circuitBreaker := range factory.GetBreakers() {
+		circuitBreaker.SetMetricsProvider(metricsProvider)
+	}
+	msp.SetMetricsProvider(metricsProvider)
+	opsSystem.RegisterHandler("/msp/local/reload", mgmt.NewReloadHandler())
+
 	serverConfig := initializeServerConfig(conf, metricsProvider)
 	grpcServer := initializeGrpcServer(conf, serverConfig)
+	if serverConfig.SecOpts.UseTLS {
+		opsSystem.RegisterHandler("/tls/reload", comm.NewTLSReloadHandler(&comm.TLSReloader{
+			CertFile: conf.General.TLS.Certificate,
+			KeyFile:  conf.General.TLS.PrivateKey,
+			Server:   grpcServer,
+		}))
+	}
 	caMgr := &caManager{
 		appRootCAsByChain:     make(map[string][][]byte),
 		ordererRootCAsByChain: make(map[string][][]byte),
@@ -425,6 +452,18 @@ func configureClusterListener(conf *localconfig.TopLevel, generalConf comm.Serve
 		clientRootCAs = append(clientRootCAs, rootCACert)
 	}
 
+	var encCert, encKey []byte
+	if clusterConf.GMTLS.Enabled {
+		encCert, err = loadPEM(clusterConf.GMTLS.ServerEncCertificate)
+		if err != nil {
+			logger.Panicf("Failed to load cluster server GMTLS encryption certificate from '%s' (%s)", clusterConf.GMTLS.ServerEncCertificate, err)
+		}
+		encKey, err = loadPEM(clusterConf.GMTLS.ServerEncPrivateKey)
+		if err != nil {
+			logger.Panicf("Failed to load cluster server GMTLS encryption key from '%s' (%s)", clusterConf.GMTLS.ServerEncPrivateKey, err)
+		}
+	}
+
 	serverConf := comm.ServerConfig{
 		StreamInterceptors: generalConf.StreamInterceptors,
 		UnaryInterceptors:  generalConf.UnaryInterceptors,
@@ -440,6 +479,9 @@ func configureClusterListener(conf *localconfig.TopLevel, generalConf comm.Serve
 			Certificate:       cert,
 			UseTLS:            true,
 			Key:               key,
+			UseGMTLS:          clusterConf.GMTLS.Enabled,
+			EncCertificate:    encCert,
+			EncKey:            encKey,
 		},
 	}
 
@@ -494,13 +536,32 @@ func initializeClusterClientConfig(conf *localconfig.TopLevel) comm.ClientConfig
 	}
 
 	cc.SecOpts = comm.SecureOptions{
-		TimeShift:         timeShift,
-		RequireClientCert: true,
-		CipherSuites:      comm.DefaultTLSCipherSuites,
-		ServerRootCAs:     serverRootCAs,
-		Certificate:       certBytes,
-		Key:               keyBytes,
-		UseTLS:            true,
+		TimeShift:          timeShift,
+		RequireClientCert:  true,
+		CipherSuites:       comm.DefaultTLSCipherSuites,
+		ServerRootCAs:      serverRootCAs,
+		Certificate:        certBytes,
+		Key:                keyBytes,
+		UseTLS:             true,
+		PinnedCertificates: conf.General.TLS.PinnedCertificates,
+	}
+
+	if conf.General.Cluster.GMTLS.Enabled {
+		encCertFile := conf.General.Cluster.GMTLS.ClientEncCertificate
+		encCertBytes, err := ioutil.ReadFile(encCertFile)
+		if err != nil {
+			logger.Fatalf("Failed to load client GMTLS encryption certificate file '%s' (%s)", encCertFile, err)
+		}
+
+		encKeyFile := conf.General.Cluster.GMTLS.ClientEncPrivateKey
+		encKeyBytes, err := ioutil.ReadFile(encKeyFile)
+		if err != nil {
+			logger.Fatalf("Failed to load client GMTLS encryption key file '%s' (%s)", encKeyFile, err)
+		}
+
+		cc.SecOpts.UseGMTLS = true
+		cc.SecOpts.EncCertificate = encCertBytes
+		cc.SecOpts.EncKey = encKeyBytes
 	}
 
 	return cc
@@ -551,6 +612,48 @@ func initializeServerConfig(conf *localconfig.TopLevel, metricsProvider metrics.
 		secureOpts.Certificate = serverCertificate
 		secureOpts.ServerRootCAs = serverRootCAs
 		secureOpts.ClientRootCAs = clientRootCAs
+		secureOpts.PinnedCertificates = conf.General.TLS.PinnedCertificates
+		if conf.General.TLS.GMTLS.Enabled {
+			encCertificate, err := ioutil.ReadFile(conf.General.TLS.GMTLS.EncCertificate)
+			if err != nil {
+				logger.Fatalf("Failed to load GMTLS EncCertificate file '%s' (%s)",
+					conf.General.TLS.GMTLS.EncCertificate, err)
+			}
+			encKey, err := ioutil.ReadFile(conf.General.TLS.GMTLS.EncPrivateKey)
+			if err != nil {
+				logger.Fatalf("Failed to load GMTLS EncPrivateKey file '%s' (%s)",
+					conf.General.TLS.GMTLS.EncPrivateKey, err)
+			}
+			secureOpts.UseGMTLS = true
+			secureOpts.EncCertificate = encCertificate
+			secureOpts.EncKey = encKey
+			msg = "GM" + msg
+		}
+		if conf.General.TLS.SMTLS13.Enabled {
+			cipherSuites, err := comm.SMCipherSuites(conf.General.TLS.SMTLS13.CipherSuites)
+			if err != nil {
+				logger.Fatalf("Failed to load SMTLS13 CipherSuites (%s)", err)
+			}
+			secureOpts.UseSMTLS13 = true
+			secureOpts.SMCipherSuites = cipherSuites
+			msg += " 1.3 with SM cipher suites"
+		}
+		if conf.General.TLS.DualStack.Enabled {
+			standardCertificate, err := ioutil.ReadFile(conf.General.TLS.DualStack.Certificate)
+			if err != nil {
+				logger.Fatalf("Failed to load DualStack Certificate file '%s' (%s)",
+					conf.General.TLS.DualStack.Certificate, err)
+			}
+			standardKey, err := ioutil.ReadFile(conf.General.TLS.DualStack.PrivateKey)
+			if err != nil {
+				logger.Fatalf("Failed to load DualStack PrivateKey file '%s' (%s)",
+					conf.General.TLS.DualStack.PrivateKey, err)
+			}
+			secureOpts.DualStack = true
+			secureOpts.StandardCertificate = standardCertificate
+			secureOpts.StandardKey = standardKey
+			msg += " and TLS"
+		}
 		logger.Infof("Starting orderer with %s enabled", msg)
 	}
 	kaOpts := comm.DefaultKeepaliveOptions
@@ -668,29 +771,13 @@ func initializeGrpcServer(conf *localconfig.TopLevel, serverConfig comm.ServerCo
 }
 
 func loadLocalMSP(conf *localconfig.TopLevel) msp.MSP {
-	// MUST call GetLocalMspConfig first, so that default BCCSP is properly
-	// initialized prior to LoadByType.
-	mspConfig, err := msp.GetLocalMspConfig(conf.General.LocalMSPDir, conf.General.BCCSP, conf.General.LocalMSPID)
-	if err != nil {
-		logger.Panicf("Failed to get local msp config: %v", err)
-	}
-
-	typ := msp.ProviderTypeToString(msp.FABRIC)
-	opts, found := msp.Options[typ]
-	if !found {
-		logger.Panicf("MSP option for type %s is not found", typ)
-	}
-
-	localmsp, err := msp.New(opts, factory.GetDefault())
-	if err != nil {
+	// The local MSP is loaded by mgmt, which can reload it from
+	// LocalMSPDir to rotate the certificates of the orderer.
+	if err := mgmt.LoadLocalMsp(conf.General.LocalMSPDir, conf.General.BCCSP, conf.General.LocalMSPID); err != nil {
 		logger.Panicf("Failed to load local MSP: %v", err)
 	}
 
-	if err = localmsp.Setup(mspConfig); err != nil {
-		logger.Panicf("Failed to setup local msp with config: %v", err)
-	}
-
-	return localmsp
+	return mgmt.GetLocalMSP(factory.GetDefault())
 }
 
 //go:generate counterfeiter -o mocks/health_checker.go -fake-name HealthChecker . healthChecker
@@ -790,7 +877,24 @@ func initializeEtcdraftConsenter(
 	return raftConsenter
 }
 
-func newOperationsSystem(ops localconfig.Operations, metrics localconfig.Metrics) *operations.System {
+func newOperationsSystem(ops localconfig.Operations, metrics localconfig.Metrics, localMSP msp.MSP) *operations.System {
+	var smCipherSuites []uint16
+	if ops.TLS.SMTLS13.Enabled {
+		var err error
+		smCipherSuites, err = comm.SMCipherSuites(ops.TLS.SMTLS13.CipherSuites)
+		if err != nil {
+			logger.Panicf("Failed to load operations SMTLS13 CipherSuites (%s)", err)
+		}
+	}
+	var authorization *operations.Authorization
+	if len(ops.Authorization.Policies) != 0 {
+		var err error
+		authorization, err = operations.NewAuthorization(ops.Authorization.Policies, localMSP)
+		if err != nil {
+			logger.Panicf("Failed to load operations authorization policies: %s", err)
+		}
+	}
+
 	return operations.NewSystem(operations.Options{
 		Logger:        flogging.MustGetLogger("orderer.operations"),
 		ListenAddress: ops.ListenAddress,
@@ -809,8 +913,14 @@ func newOperationsSystem(ops localconfig.Operations, metrics localconfig.Metrics
 			KeyFile:            ops.TLS.PrivateKey,
 			ClientCertRequired: ops.TLS.ClientAuthRequired,
 			ClientCACertFiles:  ops.TLS.ClientRootCAs,
+			GMTLS:              ops.TLS.GMTLS.Enabled,
+			EncCertFile:        ops.TLS.GMTLS.EncCertificate,
+			EncKeyFile:         ops.TLS.GMTLS.EncPrivateKey,
+			SMTLS13:            ops.TLS.SMTLS13.Enabled,
+			SMCipherSuites:     smCipherSuites,
 		},
-		Version: metadata.Version,
+		Authorization: authorization,
+		Version:       metadata.Version,
 	})
 }
 
@@ -712,6 +712,29 @@ func TestConfigureClusterListener(t *testing.T) {
 			generalSrv:         &comm.GRPCServer{},
 			expectedLogEntries: []string{"Failed to load CA cert file 'bad' (I/O error)"},
 		},
+		{
+			name:        "invalid GMTLS encryption certificate",
+			generalConf: comm.ServerConfig{},
+			conf: &localconfig.TopLevel{
+				General: localconfig.General{
+					Cluster: localconfig.Cluster{
+						ListenAddress:     "127.0.0.1",
+						ListenPort:        5000,
+						ServerPrivateKey:  "key",
+						ServerCertificate: "cert",
+						RootCAs:           []string{"ca"},
+						GMTLS: localconfig.ClusterGMTLS{
+							Enabled:              true,
+							ServerEncCertificate: "bad",
+							ServerEncPrivateKey:  "key",
+						},
+					},
+				},
+			},
+			expectedPanic:      "Failed to load cluster server GMTLS encryption certificate from 'bad' (I/O error)",
+			generalSrv:         &comm.GRPCServer{},
+			expectedLogEntries: []string{"Failed to load cluster server GMTLS encryption certificate from 'bad' (I/O error)"},
+		},
 		{
 			name:        "bad listen address",
 			generalConf: comm.ServerConfig{},
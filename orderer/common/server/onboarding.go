@@ -69,13 +69,14 @@ func (ri *replicationInitiator) createReplicator(bootstrapBlock *common.Block, f
 	puller.RetryTimeout = ri.conf.General.Cluster.ReplicationRetryTimeout
 
 	replicator := &cluster.Replicator{
-		Filter:           filter,
-		LedgerFactory:    ri.lf,
-		SystemChannel:    systemChannelName,
-		BootBlock:        bootstrapBlock,
-		Logger:           ri.logger,
-		AmIPartOfChannel: consenterCert.IsConsenterOfChannel,
-		Puller:           puller,
+		Filter:            filter,
+		LedgerFactory:     ri.lf,
+		SystemChannel:     systemChannelName,
+		BootBlock:         bootstrapBlock,
+		Logger:            ri.logger,
+		AmIPartOfChannel:  consenterCert.IsConsenterOfChannel,
+		Puller:            puller,
+		VerifierRetriever: ri.verifierRetriever,
 		ChannelLister: &cluster.ChainInspector{
 			Logger:          ri.logger,
 			Puller:          puller,
@@ -19,6 +19,9 @@ type blockCreator struct {
 	hash   []byte
 	number uint64
 
+	// hashingAlgorithm hashes the blocks, or SHA-256 when nil
+	hashingAlgorithm func([]byte) []byte
+
 	logger *flogging.FabricLogger
 }
 
@@ -38,9 +41,9 @@ func (bc *blockCreator) createNextBlock(envs []*cb.Envelope) *cb.Block {
 	bc.number++
 
 	block := protoutil.NewBlock(bc.number, bc.hash)
-	block.Header.DataHash = protoutil.BlockDataHash(data)
+	block.Header.DataHash = protoutil.BlockDataHashWith(data, bc.hashingAlgorithm)
 	block.Data = data
 
-	bc.hash = protoutil.BlockHeaderHash(block.Header)
+	bc.hash = protoutil.BlockHeaderHashWith(block.Header, bc.hashingAlgorithm)
 	return block
 }
@@ -11,6 +11,7 @@ import (
 
 	cb "github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
@@ -40,3 +41,18 @@ func TestCreateNextBlock(t *testing.T) {
 	assert.Equal(t, protoutil.BlockDataHash(third.Data), third.Header.DataHash)
 	assert.Equal(t, protoutil.BlockHeaderHash(second.Header), third.Header.PreviousHash)
 }
+
+func TestCreateNextBlockWithHashingAlgorithm(t *testing.T) {
+	first := protoutil.NewBlock(0, []byte("firsthash"))
+	bc := &blockCreator{
+		hash:             protoutil.BlockHeaderHashWith(first.Header, util.ComputeSM3),
+		number:           first.Header.Number,
+		hashingAlgorithm: util.ComputeSM3,
+		logger:           flogging.NewFabricLogger(zap.NewNop()),
+	}
+
+	second := bc.createNextBlock([]*cb.Envelope{{Payload: []byte("some other bytes")}})
+	assert.Equal(t, util.ComputeSM3(second.Data.Data[0]), second.Header.DataHash)
+	assert.Equal(t, util.ComputeSM3(protoutil.BlockHeaderBytes(first.Header)), second.Header.PreviousHash)
+	assert.Equal(t, util.ComputeSM3(protoutil.BlockHeaderBytes(second.Header)), bc.hash)
+}
@@ -10,6 +10,7 @@ import (
 	"encoding/pem"
 
 	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/orderer/common/cluster"
 	"github.com/hyperledger/fabric/orderer/common/localconfig"
@@ -67,7 +68,7 @@ func NewBlockPuller(support consensus.ConsenterSupport,
 ) (BlockPuller, error) {
 
 	verifyBlockSequence := func(blocks []*common.Block, _ string) error {
-		return cluster.VerifyBlocks(blocks, support)
+		return cluster.VerifyBlocks(blocks, support, channelconfig.BlockHashingAlgorithm(support.ChannelConfig()))
 	}
 
 	stdDialer := &cluster.StandardDialer{
@@ -19,6 +19,7 @@ import (
 	"github.com/hyperledger/fabric-protos-go/common"
 	"github.com/hyperledger/fabric-protos-go/orderer"
 	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
+	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/orderer/common/cluster"
 	"github.com/hyperledger/fabric/orderer/consensus"
@@ -224,6 +225,12 @@ func NewChain(
 		storage.SnapshotCatchUpEntries = opts.SnapshotCatchUpEntries
 	}
 
+	if channelConfig := support.ChannelConfig(); channelConfig != nil {
+		if capabilities := channelConfig.Capabilities(); capabilities != nil {
+			storage.SnapshotChecksums = capabilities.GMAlgorithms()
+		}
+	}
+
 	sizeLimit := opts.SnapshotIntervalSize
 	if sizeLimit == 0 {
 		sizeLimit = DefaultSnapshotIntervalSize
@@ -719,10 +726,12 @@ func (c *Chain) run() {
 				}
 
 				c.logger.Infof("Start accepting requests as Raft leader at block [%d]", c.lastBlock.Header.Number)
+				hashingAlgorithm := c.hashingAlgorithm()
 				bc = &blockCreator{
-					hash:   protoutil.BlockHeaderHash(c.lastBlock.Header),
-					number: c.lastBlock.Header.Number,
-					logger: c.logger,
+					hash:             protoutil.BlockHeaderHashWith(c.lastBlock.Header, hashingAlgorithm),
+					number:           c.lastBlock.Header.Number,
+					hashingAlgorithm: hashingAlgorithm,
+					logger:           c.logger,
 				}
 				submitC = c.submitC
 				c.justElected = false
@@ -1088,6 +1097,13 @@ func (c *Chain) isConfig(env *common.Envelope) bool {
 	return h.Type == int32(common.HeaderType_CONFIG) || h.Type == int32(common.HeaderType_ORDERER_TRANSACTION)
 }
 
+// hashingAlgorithm returns the hashing algorithm of the blocks of the
+// channel, which never changes, or nil to hash blocks with SHA-256 when the
+// channel config is not available.
+func (c *Chain) hashingAlgorithm() func([]byte) []byte {
+	return channelconfig.BlockHashingAlgorithm(c.support.ChannelConfig())
+}
+
 func (c *Chain) configureComm() error {
 	// Reset unreachable map when communication is reconfigured
 	c.Node.unreachableLock.Lock()
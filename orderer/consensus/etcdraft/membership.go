@@ -14,6 +14,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric-protos-go/orderer/etcdraft"
 	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft"
 	"go.etcd.io/etcd/raft/raftpb"
@@ -122,16 +123,16 @@ func validateConsenterTLSCerts(c *etcdraft.Consenter, ordererConfig channelconfi
 		return errors.Wrap(err, "parsing tls server cert")
 	}
 
-	opts, err := createX509VerifyOptions(ordererConfig.Organizations())
+	roots, intermediates, err := tlsCACerts(ordererConfig.Organizations())
 	if err != nil {
 		return errors.WithMessage(err, "creating x509 verify options")
 	}
-	_, err = clientCert.Verify(opts)
+	err = verifyTLSCert(clientCert, roots, intermediates)
 	if err != nil {
 		return fmt.Errorf("verifying tls client cert with serial number %d: %v", clientCert.SerialNumber, err)
 	}
 
-	_, err = serverCert.Verify(opts)
+	err = verifyTLSCert(serverCert, roots, intermediates)
 	if err != nil {
 		return fmt.Errorf("verifying tls server cert with serial number %d: %v", serverCert.SerialNumber, err)
 	}
@@ -139,36 +140,62 @@ func validateConsenterTLSCerts(c *etcdraft.Consenter, ordererConfig channelconfi
 	return nil
 }
 
-func createX509VerifyOptions(orgs map[string]channelconfig.OrdererOrg) (x509.VerifyOptions, error) {
-	tlsRoots := x509.NewCertPool()
-	tlsIntermediates := x509.NewCertPool()
+// verifyTLSCert verifies cert up to one of roots. Certificates signed with
+// SM2, which crypto/x509 cannot verify, are verified with gmx509.
+func verifyTLSCert(cert *x509.Certificate, roots, intermediates []*x509.Certificate) error {
+	keyUsages := []x509.ExtKeyUsage{
+		x509.ExtKeyUsageClientAuth,
+		x509.ExtKeyUsageServerAuth,
+	}
 
+	if gmx509.IsSM2Signed(cert) {
+		opts := gmx509.VerifyOptions{
+			Roots:         gmx509.NewCertPool(),
+			Intermediates: gmx509.NewCertPool(),
+			KeyUsages:     keyUsages,
+		}
+		for _, c := range roots {
+			opts.Roots.AddCert(c)
+		}
+		for _, c := range intermediates {
+			opts.Intermediates.AddCert(c)
+		}
+		_, err := gmx509.Verify(cert, opts)
+		return err
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:         x509.NewCertPool(),
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     keyUsages,
+	}
+	for _, c := range roots {
+		opts.Roots.AddCert(c)
+	}
+	for _, c := range intermediates {
+		opts.Intermediates.AddCert(c)
+	}
+	_, err := cert.Verify(opts)
+	return err
+}
+
+// tlsCACerts returns the TLS root and intermediate certificates of orgs.
+func tlsCACerts(orgs map[string]channelconfig.OrdererOrg) (roots, intermediates []*x509.Certificate, err error) {
 	for _, org := range orgs {
 		rootCerts, err := parseCertificateListFromBytes(org.MSP().GetTLSRootCerts())
 		if err != nil {
-			return x509.VerifyOptions{}, errors.Wrap(err, "parsing tls root certs")
+			return nil, nil, errors.Wrap(err, "parsing tls root certs")
 		}
 		intermediateCerts, err := parseCertificateListFromBytes(org.MSP().GetTLSIntermediateCerts())
 		if err != nil {
-			return x509.VerifyOptions{}, errors.Wrap(err, "parsing tls intermediate certs")
+			return nil, nil, errors.Wrap(err, "parsing tls intermediate certs")
 		}
 
-		for _, cert := range rootCerts {
-			tlsRoots.AddCert(cert)
-		}
-		for _, cert := range intermediateCerts {
-			tlsIntermediates.AddCert(cert)
-		}
+		roots = append(roots, rootCerts...)
+		intermediates = append(intermediates, intermediateCerts...)
 	}
 
-	return x509.VerifyOptions{
-		Roots:         tlsRoots,
-		Intermediates: tlsIntermediates,
-		KeyUsages: []x509.ExtKeyUsage{
-			x509.ExtKeyUsageClientAuth,
-			x509.ExtKeyUsageServerAuth,
-		},
-	}, nil
+	return roots, intermediates, nil
 }
 
 func parseCertificateListFromBytes(certs [][]byte) ([]*x509.Certificate, error) {
@@ -192,7 +219,7 @@ func parseCertificateFromBytes(cert []byte) (*x509.Certificate, error) {
 		return &x509.Certificate{}, fmt.Errorf("no PEM data found in cert[% x]", cert)
 	}
 
-	certificate, err := x509.ParseCertificate(pemBlock.Bytes)
+	certificate, err := gmx509.ParseCertificate(pemBlock.Bytes)
 	if err != nil {
 		return &x509.Certificate{}, err
 	}
@@ -7,14 +7,18 @@ SPDX-License-Identifier: Apache-2.0
 package etcdraft
 
 import (
+	"bytes"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/util"
 	"github.com/pkg/errors"
 
 	"github.com/etcd-io/etcd/etcdserver/api/snap"
@@ -34,6 +38,10 @@ import (
 // purpose. This MUST be greater equal than 1.
 var MaxSnapshotFiles = 4
 
+// snapChecksumSuffix is the suffix of the files, next to snapshot files,
+// holding the hex encoded SM3 digest of the snapshot.
+const snapChecksumSuffix = ".sm3"
+
 // MemoryStorage is currently backed by etcd/raft.MemoryStorage. This interface is
 // defined to expose dependencies of fsm so that it may be swapped in the
 // future. TODO(jay) Add other necessary methods to this interface once we need
@@ -51,6 +59,11 @@ type MemoryStorage interface {
 type RaftStorage struct {
 	SnapshotCatchUpEntries uint64
 
+	// SnapshotChecksums makes the storage write the SM3 digest of each
+	// snapshot next to it. Snapshots are verified against their digest
+	// when loaded, whenever one is found.
+	SnapshotChecksums bool
+
 	walDir  string
 	snapDir string
 
@@ -78,7 +91,7 @@ func CreateStorage(
 		return nil, err
 	}
 
-	snapshot, err := sn.Load()
+	snapshot, err := loadSnapshot(lg, sn, snapDir)
 	if err != nil {
 		if err == snap.ErrNoSnapshot {
 			lg.Debugf("No snapshot found at %s", snapDir)
@@ -167,6 +180,48 @@ func ListSnapshots(logger *flogging.FabricLogger, snapDir string) []uint64 {
 	return snapshots
 }
 
+// loadSnapshot loads the newest intact snapshot of snapDir. A snapshot whose
+// SM3 digest does not match the one stored next to it is renamed as broken,
+// and the next newest snapshot is loaded instead.
+func loadSnapshot(lg *flogging.FabricLogger, sn *snap.Snapshotter, snapDir string) (*raftpb.Snapshot, error) {
+	for {
+		snapshot, err := sn.Load()
+		if err != nil {
+			return nil, err
+		}
+
+		fpath := filepath.Join(snapDir, snapFileName(*snapshot))
+		expected, err := ioutil.ReadFile(fpath + snapChecksumSuffix)
+		if os.IsNotExist(err) {
+			return snapshot, nil
+		}
+		if err != nil {
+			return nil, errors.Errorf("failed to read checksum of snapshot %s: %s", fpath, err)
+		}
+
+		data, err := snapshot.Marshal()
+		if err != nil {
+			return nil, errors.Errorf("failed to marshal snapshot %s: %s", fpath, err)
+		}
+		if bytes.Equal(bytes.TrimSpace(expected), []byte(hex.EncodeToString(util.ComputeSM3(data)))) {
+			return snapshot, nil
+		}
+
+		lg.Errorf("Snapshot file %s does not match its SM3 checksum", fpath)
+		broken := fpath + ".broken"
+		if err = os.Rename(fpath, broken); err != nil {
+			return nil, errors.Errorf("failed to rename corrupted snapshot file %s to %s: %s", fpath, broken, err)
+		}
+		lg.Debugf("Renaming corrupted snapshot file %s to %s", fpath, broken)
+	}
+}
+
+// snapFileName returns the name of the file snap.Snapshotter saves
+// snapshot in.
+func snapFileName(snapshot raftpb.Snapshot) string {
+	return fmt.Sprintf("%016x-%016x.snap", snapshot.Metadata.Term, snapshot.Metadata.Index)
+}
+
 func createSnapshotter(logger *flogging.FabricLogger, snapDir string) (*snap.Snapshotter, error) {
 	if err := os.MkdirAll(snapDir, os.ModePerm); err != nil {
 		return nil, errors.Errorf("failed to mkdir '%s' for snapshot: %s", snapDir, err)
@@ -290,6 +345,12 @@ func (rs *RaftStorage) saveSnap(snap raftpb.Snapshot) error {
 		return errors.Errorf("failed to save snapshot to disk: %s", err)
 	}
 
+	if rs.SnapshotChecksums {
+		if err := rs.saveSnapChecksum(snap); err != nil {
+			return err
+		}
+	}
+
 	rs.lg.Debugf("Releasing lock to wal files prior to %d", snap.Metadata.Index)
 	if err := rs.wal.ReleaseLockTo(snap.Metadata.Index); err != nil {
 		return err
@@ -298,6 +359,23 @@ func (rs *RaftStorage) saveSnap(snap raftpb.Snapshot) error {
 	return nil
 }
 
+// saveSnapChecksum writes the SM3 digest of snap, covering both its data
+// and metadata, next to the snapshot file.
+func (rs *RaftStorage) saveSnapChecksum(snap raftpb.Snapshot) error {
+	data, err := snap.Marshal()
+	if err != nil {
+		return errors.Errorf("failed to marshal snapshot: %s", err)
+	}
+
+	fpath := filepath.Join(rs.snapDir, snapFileName(snap)) + snapChecksumSuffix
+	checksum := []byte(hex.EncodeToString(util.ComputeSM3(data)))
+	if err := ioutil.WriteFile(fpath, checksum, fileutil.PrivateFileMode); err != nil {
+		return errors.Errorf("failed to save snapshot checksum to disk: %s", err)
+	}
+
+	return nil
+}
+
 // TakeSnapshot takes a snapshot at index i from MemoryStorage, and persists it to wal and disk.
 func (rs *RaftStorage) TakeSnapshot(i uint64, cs raftpb.ConfState, data []byte) error {
 	rs.lg.Debugf("Creating snapshot at index %d from MemoryStorage", i)
@@ -407,7 +485,14 @@ func (rs *RaftStorage) purgeSnap() {
 		return
 	}
 
-	rs.purge(files[:l-MaxSnapshotFiles]) // retain last MaxSnapshotFiles snapshot files
+	files = files[:l-MaxSnapshotFiles] // retain last MaxSnapshotFiles snapshot files
+	for _, file := range files {
+		if _, err := os.Stat(file + snapChecksumSuffix); err == nil {
+			files = append(files, file+snapChecksumSuffix)
+		}
+	}
+
+	rs.purge(files)
 }
 
 func (rs *RaftStorage) purge(files []string) {
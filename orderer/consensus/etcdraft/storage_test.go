@@ -395,3 +395,52 @@ func TestApplyOutOfDateSnapshot(t *testing.T) {
 		assertFileCount(t, 12, 1)
 	})
 }
+
+func TestSnapshotChecksums(t *testing.T) {
+	setup(t)
+	defer clean(t)
+
+	store.SnapshotChecksums = true
+
+	for i := 0; i < 10; i++ {
+		store.Store(
+			[]raftpb.Entry{{Index: uint64(i), Data: make([]byte, 100)}},
+			raftpb.HardState{},
+			raftpb.Snapshot{},
+		)
+	}
+
+	err = store.TakeSnapshot(uint64(3), raftpb.ConfState{Nodes: []uint64{1}}, make([]byte, 10))
+	assert.NoError(t, err)
+	err = store.TakeSnapshot(uint64(5), raftpb.ConfState{Nodes: []uint64{1}}, make([]byte, 10))
+	assert.NoError(t, err)
+
+	files, err := fileutil.ReadDir(snapDir)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fileCount(files, ".snap"))
+	assert.Equal(t, 2, fileCount(files, ".snap"+snapChecksumSuffix))
+
+	t.Run("Intact snapshot", func(t *testing.T) {
+		err = store.Close()
+		assert.NoError(t, err)
+		store, err = CreateStorage(logger, walDir, snapDir, raft.NewMemoryStorage())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(5), store.Snapshot().Metadata.Index)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		latest := filepath.Join(snapDir, snapFileName(store.Snapshot()))
+		err = ioutil.WriteFile(latest+snapChecksumSuffix, []byte("0badc0de"), 0600)
+		require.NoError(t, err)
+
+		err = store.Close()
+		assert.NoError(t, err)
+		store, err = CreateStorage(logger, walDir, snapDir, raft.NewMemoryStorage())
+		require.NoError(t, err)
+
+		// Snapshot not matching its checksum should've been renamed by CreateStorage
+		assert.Equal(t, uint64(3), store.Snapshot().Metadata.Index)
+		assertFileCount(t, 11, 1)
+		assert.FileExists(t, latest+".broken")
+	})
+}
@@ -8,7 +8,6 @@ package etcdraft
 
 import (
 	"bytes"
-	"crypto/x509"
 	"encoding/pem"
 	"time"
 
@@ -21,6 +20,7 @@ import (
 	"github.com/hyperledger/fabric/orderer/common/cluster"
 	"github.com/hyperledger/fabric/protoutil"
 	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp"
+	"github.com/paul-lee-attorney/fabric-2.1-gm/bccsp/gmx509"
 	"github.com/pkg/errors"
 	"go.etcd.io/etcd/raft"
 	"go.etcd.io/etcd/raft/raftpb"
@@ -259,7 +259,7 @@ func validateCert(pemData []byte, certRole string) error {
 		return errors.Errorf("%s TLS certificate is not PEM encoded: %s", certRole, string(pemData))
 	}
 
-	if _, err := x509.ParseCertificate(bl.Bytes); err != nil {
+	if _, err := gmx509.ParseCertificate(bl.Bytes); err != nil {
 		return errors.Errorf("%s TLS certificate has invalid ASN1 structure, %v: %s", certRole, err, string(pemData))
 	}
 	return nil
@@ -18,6 +18,16 @@ type ChannelCapabilities struct {
 	consensusTypeMigrationReturnsOnCall map[int]struct {
 		result1 bool
 	}
+	GMAlgorithmsStub        func() bool
+	gMAlgorithmsMutex       sync.RWMutex
+	gMAlgorithmsArgsForCall []struct {
+	}
+	gMAlgorithmsReturns struct {
+		result1 bool
+	}
+	gMAlgorithmsReturnsOnCall map[int]struct {
+		result1 bool
+	}
 	MSPVersionStub        func() msp.MSPVersion
 	mSPVersionMutex       sync.RWMutex
 	mSPVersionArgsForCall []struct {
@@ -104,6 +114,58 @@ func (fake *ChannelCapabilities) ConsensusTypeMigrationReturnsOnCall(i int, resu
 	}{result1}
 }
 
+func (fake *ChannelCapabilities) GMAlgorithms() bool {
+	fake.gMAlgorithmsMutex.Lock()
+	ret, specificReturn := fake.gMAlgorithmsReturnsOnCall[len(fake.gMAlgorithmsArgsForCall)]
+	fake.gMAlgorithmsArgsForCall = append(fake.gMAlgorithmsArgsForCall, struct {
+	}{})
+	fake.recordInvocation("GMAlgorithms", []interface{}{})
+	fake.gMAlgorithmsMutex.Unlock()
+	if fake.GMAlgorithmsStub != nil {
+		return fake.GMAlgorithmsStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.gMAlgorithmsReturns
+	return fakeReturns.result1
+}
+
+func (fake *ChannelCapabilities) GMAlgorithmsCallCount() int {
+	fake.gMAlgorithmsMutex.RLock()
+	defer fake.gMAlgorithmsMutex.RUnlock()
+	return len(fake.gMAlgorithmsArgsForCall)
+}
+
+func (fake *ChannelCapabilities) GMAlgorithmsCalls(stub func() bool) {
+	fake.gMAlgorithmsMutex.Lock()
+	defer fake.gMAlgorithmsMutex.Unlock()
+	fake.GMAlgorithmsStub = stub
+}
+
+func (fake *ChannelCapabilities) GMAlgorithmsReturns(result1 bool) {
+	fake.gMAlgorithmsMutex.Lock()
+	defer fake.gMAlgorithmsMutex.Unlock()
+	fake.GMAlgorithmsStub = nil
+	fake.gMAlgorithmsReturns = struct {
+		result1 bool
+	}{result1}
+}
+
+func (fake *ChannelCapabilities) GMAlgorithmsReturnsOnCall(i int, result1 bool) {
+	fake.gMAlgorithmsMutex.Lock()
+	defer fake.gMAlgorithmsMutex.Unlock()
+	fake.GMAlgorithmsStub = nil
+	if fake.gMAlgorithmsReturnsOnCall == nil {
+		fake.gMAlgorithmsReturnsOnCall = make(map[int]struct {
+			result1 bool
+		})
+	}
+	fake.gMAlgorithmsReturnsOnCall[i] = struct {
+		result1 bool
+	}{result1}
+}
+
 func (fake *ChannelCapabilities) MSPVersion() msp.MSPVersion {
 	fake.mSPVersionMutex.Lock()
 	ret, specificReturn := fake.mSPVersionReturnsOnCall[len(fake.mSPVersionArgsForCall)]
@@ -122,6 +184,8 @@ func (fake *ChannelCapabilities) MSPVersion() msp.MSPVersion {
 }
 
 func (fake *ChannelCapabilities) MSPVersionCallCount() int {
+	fake.gMAlgorithmsMutex.RLock()
+	defer fake.gMAlgorithmsMutex.RUnlock()
 	fake.mSPVersionMutex.RLock()
 	defer fake.mSPVersionMutex.RUnlock()
 	return len(fake.mSPVersionArgsForCall)
@@ -265,6 +329,8 @@ func (fake *ChannelCapabilities) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.consensusTypeMigrationMutex.RLock()
 	defer fake.consensusTypeMigrationMutex.RUnlock()
+	fake.gMAlgorithmsMutex.RLock()
+	defer fake.gMAlgorithmsMutex.RUnlock()
 	fake.mSPVersionMutex.RLock()
 	defer fake.mSPVersionMutex.RUnlock()
 	fake.orgSpecificOrdererEndpointsMutex.RLock()
@@ -67,6 +67,79 @@ func BlockDataHash(b *cb.BlockData) []byte {
 	return sum[:]
 }
 
+// BlockHeaderHashWith returns the hash of the block header with hash, the
+// hashing algorithm of the channel, or with SHA-256 when hash is nil.
+func BlockHeaderHashWith(b *cb.BlockHeader, hash func([]byte) []byte) []byte {
+	if hash == nil {
+		return BlockHeaderHash(b)
+	}
+	return hash(BlockHeaderBytes(b))
+}
+
+// BlockDataHashWith returns the hash of the block data with hash, the
+// hashing algorithm of the channel, or with SHA-256 when hash is nil.
+func BlockDataHashWith(b *cb.BlockData, hash func([]byte) []byte) []byte {
+	if hash == nil {
+		return BlockDataHash(b)
+	}
+	return hash(bytes.Join(b.Data, nil))
+}
+
+// GetHashingAlgorithmFromBlock returns the name of the hashing algorithm
+// of the channel config in the config block, such as the genesis block of
+// a channel.
+func GetHashingAlgorithmFromBlock(block *cb.Block) (string, error) {
+	configEnv, err := getConfigEnvelopeFromBlock(block)
+	if err != nil {
+		return "", err
+	}
+	if configEnv.Config == nil || configEnv.Config.ChannelGroup == nil {
+		return "", errors.New("config envelope has no channel group")
+	}
+	value, ok := configEnv.Config.ChannelGroup.Values["HashingAlgorithm"]
+	if !ok {
+		return "", errors.New("channel group has no hashing algorithm")
+	}
+	hashingAlgorithm := &cb.HashingAlgorithm{}
+	if err := proto.Unmarshal(value.Value, hashingAlgorithm); err != nil {
+		return "", errors.Wrap(err, "error unmarshaling hashing algorithm")
+	}
+	return hashingAlgorithm.Name, nil
+}
+
+// GetChannelCapabilitiesFromBlock returns the channel capabilities of the
+// channel config in the config block. A channel config without capabilities
+// yields none.
+func GetChannelCapabilitiesFromBlock(block *cb.Block) (map[string]*cb.Capability, error) {
+	configEnv, err := getConfigEnvelopeFromBlock(block)
+	if err != nil {
+		return nil, err
+	}
+	capabilities := &cb.Capabilities{}
+	if value, ok := configEnv.GetConfig().GetChannelGroup().GetValues()["Capabilities"]; ok {
+		if err := proto.Unmarshal(value.Value, capabilities); err != nil {
+			return nil, errors.Wrap(err, "error unmarshaling capabilities")
+		}
+	}
+	return capabilities.Capabilities, nil
+}
+
+func getConfigEnvelopeFromBlock(block *cb.Block) (*cb.ConfigEnvelope, error) {
+	envelope, err := ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to extract envelope")
+	}
+	payload, err := UnmarshalPayload(envelope.Payload)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to extract payload")
+	}
+	configEnv := &cb.ConfigEnvelope{}
+	if err := proto.Unmarshal(payload.Data, configEnv); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling config envelope")
+	}
+	return configEnv, nil
+}
+
 // GetChainIDFromBlockBytes returns chain ID given byte array which represents
 // the block
 func GetChainIDFromBlockBytes(bytes []byte) (string, error) {
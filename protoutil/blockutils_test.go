@@ -8,6 +8,7 @@ package protoutil_test
 
 import (
 	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/asn1"
 	"math"
 	"testing"
@@ -71,6 +72,47 @@ func TestGoodBlockHeaderBytes(t *testing.T) {
 	_ = protoutil.BlockHeaderBytes(goodBlockHeaderMaxNumber) // Should not panic
 }
 
+func TestBlockHashWith(t *testing.T) {
+	header := &common.BlockHeader{
+		Number:       1,
+		PreviousHash: []byte("foo"),
+		DataHash:     []byte("bar"),
+	}
+	data := &common.BlockData{Data: [][]byte{[]byte("foo"), []byte("bar")}}
+
+	assert.Equal(t, protoutil.BlockHeaderHash(header), protoutil.BlockHeaderHashWith(header, nil))
+	assert.Equal(t, protoutil.BlockDataHash(data), protoutil.BlockDataHashWith(data, nil))
+
+	hash := func(b []byte) []byte {
+		sum := sha512.Sum512(b)
+		return sum[:]
+	}
+	assert.Equal(t, hash(protoutil.BlockHeaderBytes(header)), protoutil.BlockHeaderHashWith(header, hash))
+	assert.Equal(t, hash([]byte("foobar")), protoutil.BlockDataHashWith(data, hash))
+}
+
+func TestGetHashingAlgorithmFromBlock(t *testing.T) {
+	gb, err := configtxtest.MakeGenesisBlock(testChannelID)
+	require.NoError(t, err)
+	name, err := protoutil.GetHashingAlgorithmFromBlock(gb)
+	assert.NoError(t, err)
+	assert.Equal(t, "SHA256", name)
+
+	_, err = protoutil.GetHashingAlgorithmFromBlock(&common.Block{Data: &common.BlockData{}})
+	assert.Error(t, err)
+}
+
+func TestGetChannelCapabilitiesFromBlock(t *testing.T) {
+	gb, err := configtxtest.MakeGenesisBlock(testChannelID)
+	require.NoError(t, err)
+	capabilities, err := protoutil.GetChannelCapabilitiesFromBlock(gb)
+	assert.NoError(t, err)
+	assert.Contains(t, capabilities, "V2_0")
+
+	_, err = protoutil.GetChannelCapabilitiesFromBlock(&common.Block{Data: &common.BlockData{}})
+	assert.Error(t, err)
+}
+
 func TestGetChainIDFromBlockBytes(t *testing.T) {
 	gb, err := configtxtest.MakeGenesisBlock(testChannelID)
 	assert.NoError(t, err, "Failed to create test configuration block")
@@ -58,7 +58,7 @@ generateHelpText \
         docs/wrappers/peer_lifecycle_chaincode_postscript.md \
         "${commands[@]}"
 
-commands=("peer channel" "peer channel create" "peer channel fetch" "peer channel getinfo" "peer channel join" "peer channel list" "peer channel signconfigtx" "peer channel update")
+commands=("peer channel" "peer channel create" "peer channel fetch" "peer channel getinfo" "peer channel join" "peer channel list" "peer channel mergeconfigtx" "peer channel signconfigtx" "peer channel update")
 generateHelpText \
         docs/source/commands/peerchannel.md \
         docs/wrappers/peer_channel_preamble.md \
@@ -72,6 +72,13 @@ generateHelpText \
         docs/wrappers/peer_node_postscript.md \
         "${commands[@]}"
 
+commands=("peer keys generate" "peer keys ski" "peer keys export" "peer keys csr" "peer keys import-cert")
+generateHelpText \
+        docs/source/commands/peerkeys.md \
+        docs/wrappers/peer_keys_preamble.md \
+        docs/wrappers/peer_keys_postscript.md \
+        "${commands[@]}"
+
 commands=("configtxgen")
 generateHelpText \
         docs/source/commands/configtxgen.md \
@@ -93,4 +100,11 @@ generateHelpText \
         docs/wrappers/configtxlator_postscript.md \
         "${commands[@]}"
 
+commands=("ledgerutil help" "ledgerutil verify")
+generateHelpText \
+        docs/source/commands/ledgerutil.md \
+        docs/wrappers/ledgerutil_preamble.md \
+        docs/wrappers/ledgerutil_postscript.md \
+        "${commands[@]}"
+
 exit